@@ -0,0 +1,292 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ani implements a decoder for Windows animated cursor (ANI) files.
+//
+// An ANI file is a RIFF stream, of form type "ACON", whose frames are each
+// an ICO or CUR file, decoded by the golang.org/x/image/ico package. A
+// sequence of steps shows the frames, in any order and possibly more than
+// once, each for its own duration.
+//
+// ANI files whose frames are raw bitmaps, instead of ICO or CUR files, are
+// not supported. Such files are rare.
+package ani // import "golang.org/x/image/ani"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/image/ico"
+	"golang.org/x/image/riff"
+)
+
+// A FormatError reports that the input is not a valid ANI file.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "ani: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// ANI feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "ani: unsupported feature: " + string(e)
+}
+
+var (
+	fccACON = riff.FourCC{'A', 'C', 'O', 'N'}
+	fccIART = riff.FourCC{'I', 'A', 'R', 'T'}
+	fccINAM = riff.FourCC{'I', 'N', 'A', 'M'}
+	fccINFO = riff.FourCC{'I', 'N', 'F', 'O'}
+	fccanih = riff.FourCC{'a', 'n', 'i', 'h'}
+	fccfram = riff.FourCC{'f', 'r', 'a', 'm'}
+	fccicon = riff.FourCC{'i', 'c', 'o', 'n'}
+	fccrate = riff.FourCC{'r', 'a', 't', 'e'}
+	fccseq  = riff.FourCC{'s', 'e', 'q', ' '}
+)
+
+const (
+	anihLen = 36
+
+	// afIcon is the anih flag for frames that are ICO or CUR files.
+	afIcon = 1 << 0
+
+	// maxSteps is the largest number of steps that a file may have.
+	maxSteps = 1 << 16
+)
+
+// ANI is the frames and timing of an animated cursor.
+type ANI struct {
+	// Frame holds the frames' images, and for CUR frames their hotspots.
+	// Each frame may hold the same image at several sizes.
+	Frame []*ico.ICO
+	// Step holds the indexes in Frame of the frames to show, in order.
+	Step []int
+	// Delay holds how long to show each step's frame, in jiffies: 60ths of
+	// a second.
+	Delay []int
+	// Title and Artist are the file's optional INFO metadata.
+	Title, Artist string
+}
+
+// file is the undecoded contents of an ANI file.
+type file struct {
+	frames        [][]byte
+	steps         []int
+	delays        []int
+	title, artist string
+}
+
+// readFile reads the chunks of an ANI file, without decoding its frames.
+func readFile(r io.Reader) (*file, error) {
+	formType, riffReader, err := riff.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if formType != fccACON {
+		return nil, FormatError("missing ACON form type")
+	}
+	var (
+		f                 file
+		anih, rate, seq   []byte
+		haveRate, haveSeq bool
+	)
+	for {
+		chunkID, chunkLen, chunkData, err := riffReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch chunkID {
+		case fccanih:
+			if chunkLen < anihLen {
+				return nil, FormatError("short anih chunk")
+			}
+			if anih, err = ioutil.ReadAll(chunkData); err != nil {
+				return nil, err
+			}
+		case fccrate:
+			if rate, err = ioutil.ReadAll(chunkData); err != nil {
+				return nil, err
+			}
+			haveRate = true
+		case fccseq:
+			if seq, err = ioutil.ReadAll(chunkData); err != nil {
+				return nil, err
+			}
+			haveSeq = true
+		case riff.LIST:
+			listType, list, err := riff.NewListReader(chunkLen, chunkData)
+			if err != nil {
+				return nil, err
+			}
+			switch listType {
+			case fccfram:
+				if f.frames, err = readFrames(list); err != nil {
+					return nil, err
+				}
+			case fccINFO:
+				if f.title, f.artist, err = readInfo(list); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if anih == nil {
+		return nil, FormatError("missing anih chunk")
+	}
+	numFrames := int(binary.LittleEndian.Uint32(anih[4:]))
+	numSteps := int(binary.LittleEndian.Uint32(anih[8:]))
+	defaultRate := int(binary.LittleEndian.Uint32(anih[28:]))
+	if binary.LittleEndian.Uint32(anih[32:])&afIcon == 0 {
+		return nil, UnsupportedError("raw bitmap frames")
+	}
+	if numFrames == 0 || numFrames != len(f.frames) {
+		return nil, FormatError("bad number of frames")
+	}
+	if !haveSeq {
+		// Without a seq chunk, the steps show each frame once, in order.
+		numSteps = numFrames
+	}
+	if numSteps == 0 || numSteps > maxSteps {
+		return nil, FormatError("bad number of steps")
+	}
+
+	f.steps = make([]int, numSteps)
+	if haveSeq {
+		if len(seq) != 4*numSteps {
+			return nil, FormatError("bad seq chunk length")
+		}
+		for i := range f.steps {
+			x := binary.LittleEndian.Uint32(seq[4*i:])
+			if x >= uint32(numFrames) {
+				return nil, FormatError("frame index out of range")
+			}
+			f.steps[i] = int(x)
+		}
+	} else {
+		for i := range f.steps {
+			f.steps[i] = i
+		}
+	}
+	f.delays = make([]int, numSteps)
+	if haveRate {
+		if len(rate) != 4*numSteps {
+			return nil, FormatError("bad rate chunk length")
+		}
+		for i := range f.delays {
+			f.delays[i] = int(binary.LittleEndian.Uint32(rate[4*i:]))
+		}
+	} else {
+		for i := range f.delays {
+			f.delays[i] = defaultRate
+		}
+	}
+	return &f, nil
+}
+
+// readFrames reads the icon chunks of a fram list.
+func readFrames(list *riff.Reader) ([][]byte, error) {
+	var frames [][]byte
+	for {
+		chunkID, _, chunkData, err := list.Next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunkID != fccicon {
+			continue
+		}
+		data, err := ioutil.ReadAll(chunkData)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, data)
+	}
+}
+
+// readInfo reads the title and artist of an INFO list.
+func readInfo(list *riff.Reader) (title, artist string, err error) {
+	for {
+		chunkID, _, chunkData, err := list.Next()
+		if err == io.EOF {
+			return title, artist, nil
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if chunkID != fccINAM && chunkID != fccIART {
+			continue
+		}
+		data, err := ioutil.ReadAll(chunkData)
+		if err != nil {
+			return "", "", err
+		}
+		// The strings are NUL-terminated.
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			data = data[:i]
+		}
+		if chunkID == fccINAM {
+			title = string(data)
+		} else {
+			artist = string(data)
+		}
+	}
+}
+
+// Decode reads an ANI file from r and returns the image of its first step:
+// the largest image of that step's frame, as ico.Decode returns it.
+func Decode(r io.Reader) (image.Image, error) {
+	f, err := readFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return ico.Decode(bytes.NewReader(f.frames[f.steps[0]]))
+}
+
+// DecodeConfig returns the color model and dimensions of the image that
+// Decode returns, without decoding it.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	f, err := readFile(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return ico.DecodeConfig(bytes.NewReader(f.frames[f.steps[0]]))
+}
+
+// DecodeAll reads an ANI file from r and returns all of its frames and
+// steps.
+func DecodeAll(r io.Reader) (*ANI, error) {
+	f, err := readFile(r)
+	if err != nil {
+		return nil, err
+	}
+	a := &ANI{
+		Frame:  make([]*ico.ICO, len(f.frames)),
+		Step:   f.steps,
+		Delay:  f.delays,
+		Title:  f.title,
+		Artist: f.artist,
+	}
+	for i, data := range f.frames {
+		if a.Frame[i], err = ico.DecodeAll(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func init() {
+	image.RegisterFormat("ani", "RIFF????ACON", Decode, DecodeConfig)
+}
@@ -0,0 +1,189 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ani
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/ico"
+)
+
+// chunk returns a RIFF chunk, padded to an even length.
+func chunk(id string, data ...[]byte) []byte {
+	b := []byte(id)
+	b = append(b, 0, 0, 0, 0)
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)-8))
+	if len(b)%2 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func u32s(v ...uint32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], x)
+	}
+	return b
+}
+
+// anih returns an anih chunk.
+func anih(numFrames, numSteps, rate, flags uint32) []byte {
+	return chunk("anih", u32s(anihLen, numFrames, numSteps, 0, 0, 0, 0, rate, flags))
+}
+
+// testCursor returns a CUR file of a size×size image of color c, with its
+// hotspot at (1, 2).
+func testCursor(t *testing.T, size int, c color.NRGBA) []byte {
+	m := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for i := 0; i < len(m.Pix); i += 4 {
+		copy(m.Pix[i:], []byte{c.R, c.G, c.B, c.A})
+	}
+	var buf bytes.Buffer
+	x := &ico.ICO{Image: []image.Image{m}, Hotspot: []image.Point{{1, 2}}}
+	if err := ico.EncodeAll(&buf, x, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeAll(t *testing.T) {
+	red := color.NRGBA{0xff, 0x00, 0x00, 0xff}
+	blue := color.NRGBA{0x00, 0x00, 0xff, 0xff}
+	data := chunk("RIFF",
+		[]byte("ACON"),
+		chunk("LIST", []byte("INFO"), chunk("INAM", []byte("Spinner\x00")), chunk("IART", []byte("Gopher\x00"))),
+		anih(2, 3, 10, afIcon|2),
+		chunk("rate", u32s(5, 6, 7)),
+		chunk("seq ", u32s(1, 0, 1)),
+		chunk("LIST", []byte("fram"),
+			chunk("icon", testCursor(t, 16, red)),
+			chunk("icon", testCursor(t, 8, blue))),
+	)
+
+	a, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if a.Title != "Spinner" || a.Artist != "Gopher" {
+		t.Errorf("info: got %q, %q, want %q, %q", a.Title, a.Artist, "Spinner", "Gopher")
+	}
+	if len(a.Frame) != 2 {
+		t.Fatalf("frames: got %d, want 2", len(a.Frame))
+	}
+	for i, want := range []color.NRGBA{red, blue} {
+		f := a.Frame[i]
+		if len(f.Image) != 1 || len(f.Hotspot) != 1 || f.Hotspot[0] != image.Pt(1, 2) {
+			t.Errorf("frame %d: got %d images and hotspots %v", i, len(f.Image), f.Hotspot)
+			continue
+		}
+		if got := color.NRGBAModel.Convert(f.Image[0].At(0, 0)); got != want {
+			t.Errorf("frame %d: got color %v, want %v", i, got, want)
+		}
+	}
+	if want := []int{1, 0, 1}; !equal(a.Step, want) {
+		t.Errorf("steps: got %v, want %v", a.Step, want)
+	}
+	if want := []int{5, 6, 7}; !equal(a.Delay, want) {
+		t.Errorf("delays: got %v, want %v", a.Delay, want)
+	}
+
+	// Decode returns the first step's image, which is that of frame 1.
+	m, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format != "ani" {
+		t.Fatalf("image.Decode: got %q, %v, want %q, nil", format, err, "ani")
+	}
+	if got := m.Bounds(); got != image.Rect(0, 0, 8, 8) {
+		t.Errorf("Decode: bounds: got %v, want %v", got, image.Rect(0, 0, 8, 8))
+	}
+	c, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil || c.Width != 8 || c.Height != 8 {
+		t.Errorf("DecodeConfig: got %v, %v", c, err)
+	}
+}
+
+func TestDecodeDefaultSequence(t *testing.T) {
+	data := chunk("RIFF",
+		[]byte("ACON"),
+		anih(2, 2, 10, afIcon),
+		chunk("LIST", []byte("fram"),
+			chunk("icon", testCursor(t, 4, color.NRGBA{A: 0xff})),
+			chunk("icon", testCursor(t, 4, color.NRGBA{A: 0xff}))),
+	)
+	a, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if want := []int{0, 1}; !equal(a.Step, want) {
+		t.Errorf("steps: got %v, want %v", a.Step, want)
+	}
+	if want := []int{10, 10}; !equal(a.Delay, want) {
+		t.Errorf("delays: got %v, want %v", a.Delay, want)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	icon := chunk("icon", testCursor(t, 4, color.NRGBA{A: 0xff}))
+	testCases := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{
+			"form type",
+			chunk("RIFF", []byte("WAVE"), anih(1, 1, 1, afIcon)),
+			FormatError("missing ACON form type"),
+		},
+		{
+			"missing anih",
+			chunk("RIFF", []byte("ACON"), chunk("LIST", []byte("fram"), icon)),
+			FormatError("missing anih chunk"),
+		},
+		{
+			"raw bitmaps",
+			chunk("RIFF", []byte("ACON"), anih(1, 1, 1, 0), chunk("LIST", []byte("fram"), icon)),
+			UnsupportedError("raw bitmap frames"),
+		},
+		{
+			"frame count",
+			chunk("RIFF", []byte("ACON"), anih(2, 2, 1, afIcon), chunk("LIST", []byte("fram"), icon)),
+			FormatError("bad number of frames"),
+		},
+		{
+			"seq index",
+			chunk("RIFF", []byte("ACON"), anih(1, 1, 1, afIcon), chunk("seq ", u32s(1)), chunk("LIST", []byte("fram"), icon)),
+			FormatError("frame index out of range"),
+		},
+		{
+			"rate length",
+			chunk("RIFF", []byte("ACON"), anih(1, 1, 1, afIcon), chunk("rate", u32s(1, 2)), chunk("LIST", []byte("fram"), icon)),
+			FormatError("bad rate chunk length"),
+		},
+	}
+	for _, tc := range testCases {
+		if _, err := DecodeAll(bytes.NewReader(tc.data)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
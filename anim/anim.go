@@ -0,0 +1,175 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package anim provides a common representation of animated images, such as
+// animated GIF, PNG (APNG) and WebP images, so that code that plays, converts
+// or resizes animations need not handle each format's conventions.
+//
+// Each frame of an Animation is drawn over a canvas, which is cleared to
+// transparent black at the start. A frame only covers part of the canvas if
+// its bounds are smaller, and after it is shown, its disposal method says
+// what happens to the canvas before the next frame is drawn. A Canvas applies
+// these rules to produce the image that is shown for each frame.
+package anim // import "golang.org/x/image/anim"
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// Disposal is what happens to a frame's rectangle of the canvas after the
+// frame is shown, before the next frame is drawn.
+type Disposal uint8
+
+// Disposal methods.
+const (
+	// DisposalNone leaves the canvas as is.
+	DisposalNone Disposal = iota
+	// DisposalBackground clears the frame's rectangle to transparent black.
+	DisposalBackground
+	// DisposalPrevious restores the canvas to what it was before the frame
+	// was drawn.
+	DisposalPrevious
+)
+
+// Blend is how a frame is drawn over the canvas.
+type Blend uint8
+
+// Blending methods.
+const (
+	// BlendOver alpha-blends the frame over the canvas.
+	BlendOver Blend = iota
+	// BlendSource replaces the canvas's pixels in the frame's rectangle.
+	BlendSource
+)
+
+// Frame is one frame of an Animation.
+type Frame struct {
+	// Image is the frame's pixels. Its bounds are its rectangle within the
+	// canvas.
+	Image image.Image
+	// Delay is how long the frame is shown for.
+	Delay time.Duration
+	// Disposal is the frame's disposal method.
+	Disposal Disposal
+	// Blend is the frame's blending method.
+	Blend Blend
+}
+
+// Animation is an animated image.
+type Animation struct {
+	// Frames holds the successive frames.
+	Frames []Frame
+	// LoopCount is the number of times the animation is played. Zero means
+	// that it loops forever.
+	LoopCount int
+	// BackgroundColor is the suggested background color of the canvas, or
+	// nil. Players commonly ignore it, and a Canvas does not use it.
+	BackgroundColor color.Color
+	// Config is the color model and dimensions of the canvas, whose top-left
+	// corner is at the origin.
+	Config image.Config
+}
+
+// Bounds returns the bounds of a's canvas.
+func (a *Animation) Bounds() image.Rectangle {
+	return image.Rect(0, 0, a.Config.Width, a.Config.Height)
+}
+
+// Duration returns the time to play a once, the sum of its frames' delays.
+func (a *Animation) Duration() time.Duration {
+	d := time.Duration(0)
+	for _, f := range a.Frames {
+		d += f.Delay
+	}
+	return d
+}
+
+// Canvas composites the frames of an Animation, one at a time, in order.
+type Canvas struct {
+	a    *Animation
+	img  *image.RGBA
+	prev *image.RGBA
+	next int
+}
+
+// NewCanvas returns a Canvas that composites a's frames, starting with the
+// first.
+func NewCanvas(a *Animation) *Canvas {
+	return &Canvas{
+		a:   a,
+		img: image.NewRGBA(a.Bounds()),
+	}
+}
+
+// Next disposes of the previous frame, if any, draws the next frame and
+// returns the canvas, as it is shown for that frame. It returns nil after
+// the last frame.
+//
+// The returned image is reused by the following call to Next, and so must
+// be copied if it is needed for longer.
+func (c *Canvas) Next() *image.RGBA {
+	if c.next >= len(c.a.Frames) {
+		return nil
+	}
+	if c.next > 0 {
+		switch p := &c.a.Frames[c.next-1]; p.Disposal {
+		case DisposalBackground:
+			draw.Draw(c.img, p.Image.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case DisposalPrevious:
+			copy(c.img.Pix, c.prev.Pix)
+		}
+	}
+	f := &c.a.Frames[c.next]
+	if f.Disposal == DisposalPrevious {
+		if c.prev == nil {
+			c.prev = image.NewRGBA(c.img.Rect)
+		}
+		copy(c.prev.Pix, c.img.Pix)
+	}
+	op := draw.Over
+	if f.Blend == BlendSource {
+		op = draw.Src
+	}
+	b := f.Image.Bounds()
+	draw.Draw(c.img, b, f.Image, b.Min, op)
+	c.next++
+	return c.img
+}
+
+// Resize returns a scaled to width x height pixels by s.
+//
+// Each frame of the result is the whole canvas, as it is shown for the
+// corresponding frame of a, scaled, so that partial frames, and what their
+// disposal reveals of the frames before them, are scaled together instead of
+// leaving seams at the edges of the frames' rectangles. The resulting frames
+// are *image.RGBA images, with DisposalNone and BlendSource.
+func Resize(a *Animation, width, height int, s draw.Scaler, opts *draw.Options) *Animation {
+	dr := image.Rect(0, 0, width, height)
+	b := &Animation{
+		Frames:          make([]Frame, 0, len(a.Frames)),
+		LoopCount:       a.LoopCount,
+		BackgroundColor: a.BackgroundColor,
+		Config: image.Config{
+			ColorModel: color.RGBAModel,
+			Width:      width,
+			Height:     height,
+		},
+	}
+	c := NewCanvas(a)
+	for i, m := 0, c.Next(); m != nil; i, m = i+1, c.Next() {
+		dst := image.NewRGBA(dr)
+		s.Scale(dst, dr, m, m.Rect, draw.Src, opts)
+		b.Frames = append(b.Frames, Frame{
+			Image:    dst,
+			Delay:    a.Frames[i].Delay,
+			Disposal: DisposalNone,
+			Blend:    BlendSource,
+		})
+	}
+	return b
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package anim
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"time"
+
+	"golang.org/x/image/apng"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+var (
+	red   = color.RGBA{0xff, 0x00, 0x00, 0xff}
+	green = color.RGBA{0x00, 0xff, 0x00, 0xff}
+	blue  = color.RGBA{0x00, 0x00, 0xff, 0xff}
+)
+
+// paletted returns an r-sized image, all of whose pixels are c, or are
+// transparent if c is nil.
+func paletted(r image.Rectangle, c color.Color) *image.Paletted {
+	m := image.NewPaletted(r, color.Palette{color.Transparent, red, green, blue})
+	if c != nil {
+		draw.Draw(m, r, image.NewUniform(c), image.Point{}, draw.Src)
+	}
+	return m
+}
+
+// testGIF is a 4x4 animation whose frames are shown as:
+//
+//	0: all red.
+//	1: blue in the top-left 2x2, disposed of to the background.
+//	2: green at (3, 3), over red elsewhere, disposed of to the previous.
+//	3: a transparent frame, showing frame 2's canvas before frame 2.
+func testGIF() *gif.GIF {
+	return &gif.GIF{
+		Image: []*image.Paletted{
+			paletted(image.Rect(0, 0, 4, 4), red),
+			paletted(image.Rect(0, 0, 2, 2), blue),
+			paletted(image.Rect(3, 3, 4, 4), green),
+			paletted(image.Rect(1, 1, 2, 2), nil),
+		},
+		Delay:     []int{10, 20, 30, 40},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious, 0},
+		LoopCount: 2,
+	}
+}
+
+// wantCanvas returns the pixel at (x, y) of testGIF's canvas for frame i.
+func wantCanvas(i, x, y int) color.RGBA {
+	switch {
+	case i == 1 && x < 2 && y < 2:
+		return blue
+	case i >= 2 && x < 2 && y < 2:
+		return color.RGBA{}
+	case i == 2 && x == 3 && y == 3:
+		return green
+	}
+	return red
+}
+
+func TestCanvas(t *testing.T) {
+	a := FromGIF(testGIF())
+	if got, want := a.Bounds(), image.Rect(0, 0, 4, 4); got != want {
+		t.Fatalf("Bounds: got %v, want %v", got, want)
+	}
+	if got, want := a.LoopCount, 3; got != want {
+		t.Errorf("LoopCount: got %d, want %d", got, want)
+	}
+	if got, want := a.Duration(), time.Second; got != want {
+		t.Errorf("Duration: got %v, want %v", got, want)
+	}
+	c := NewCanvas(a)
+	for i := 0; ; i++ {
+		m := c.Next()
+		if m == nil {
+			if i != len(a.Frames) {
+				t.Fatalf("got %d frames, want %d", i, len(a.Frames))
+			}
+			break
+		}
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				if got, want := m.RGBAAt(x, y), wantCanvas(i, x, y); got != want {
+					t.Errorf("frame %d: (%d, %d): got %v, want %v", i, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestFromGIFLoopCount(t *testing.T) {
+	for _, tc := range []struct{ gif, want int }{{0, 0}, {-1, 1}, {1, 2}} {
+		g := testGIF()
+		g.LoopCount = tc.gif
+		if got := FromGIF(g).LoopCount; got != tc.want {
+			t.Errorf("gif.LoopCount %d: got %d, want %d", tc.gif, got, tc.want)
+		}
+	}
+}
+
+func TestFromWebP(t *testing.T) {
+	frame := func(r image.Rectangle, c color.Color) *image.NRGBA {
+		m := image.NewNRGBA(r)
+		draw.Draw(m, r, image.NewUniform(c), image.Point{}, draw.Src)
+		return m
+	}
+	w := &webp.WEBP{
+		Image: []image.Image{
+			frame(image.Rect(0, 0, 2, 1), red),
+			frame(image.Rect(0, 0, 1, 1), color.NRGBA{0, 0, 0xff, 0x80}),
+			frame(image.Rect(1, 0, 2, 1), color.NRGBA{0, 0xff, 0, 0x80}),
+		},
+		Duration:  []time.Duration{time.Second, time.Second, time.Second},
+		Disposal:  []byte{webp.DisposalNone, webp.DisposalBackground, webp.DisposalNone},
+		Blend:     []byte{webp.BlendAlpha, webp.BlendAlpha, webp.BlendNone},
+		LoopCount: 5,
+		Config:    image.Config{ColorModel: color.NRGBAModel, Width: 2, Height: 1},
+	}
+	a := FromWebP(w)
+	if a.LoopCount != 5 || a.Duration() != 3*time.Second {
+		t.Errorf("got LoopCount %d, Duration %v", a.LoopCount, a.Duration())
+	}
+	c := NewCanvas(a)
+	c.Next()
+	// Frame 1 is blended over red.
+	if got := c.Next().RGBAAt(0, 0); got.R < 0x70 || got.B < 0x70 || got.A != 0xff {
+		t.Errorf("frame 1: got %v, want purple", got)
+	}
+	// Frame 1's rectangle is cleared, and frame 2 replaces red.
+	m := c.Next()
+	if got := m.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("frame 2: (0, 0): got %v, want transparent", got)
+	}
+	if got, want := m.RGBAAt(1, 0), (color.RGBA{0, 0x80, 0, 0x80}); got != want {
+		t.Errorf("frame 2: (1, 0): got %v, want %v", got, want)
+	}
+}
+
+func TestResize(t *testing.T) {
+	a := FromGIF(testGIF())
+	b := Resize(a, 8, 8, draw.NearestNeighbor, nil)
+	if len(b.Frames) != len(a.Frames) || b.Bounds() != image.Rect(0, 0, 8, 8) || b.LoopCount != a.LoopCount {
+		t.Fatalf("got %d frames, bounds %v, LoopCount %d", len(b.Frames), b.Bounds(), b.LoopCount)
+	}
+	c := NewCanvas(b)
+	for i, f := range b.Frames {
+		if f.Delay != a.Frames[i].Delay {
+			t.Errorf("frame %d: got delay %v, want %v", i, f.Delay, a.Frames[i].Delay)
+		}
+		m := c.Next()
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				if got, want := m.RGBAAt(x, y), wantCanvas(i, x/2, y/2); got != want {
+					t.Errorf("frame %d: (%d, %d): got %v, want %v", i, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestFromAPNG(t *testing.T) {
+	g := testGIF()
+	p := &apng.APNG{
+		Dispose: []byte{apng.DisposeOpNone, apng.DisposeOpBackground, apng.DisposeOpPrevious, apng.DisposeOpNone},
+		Blend:   []byte{apng.BlendOpSource, apng.BlendOpSource, apng.BlendOpOver, apng.BlendOpOver},
+	}
+	for i, m := range g.Image {
+		p.Image = append(p.Image, m)
+		p.Delay = append(p.Delay, time.Duration(g.Delay[i])*10*time.Millisecond)
+	}
+	// The APNG's frames, written and read back, are shown as the GIF's.
+	var buf bytes.Buffer
+	if err := apng.EncodeAll(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	p, err := apng.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := FromAPNG(p)
+	if a.Duration() != time.Second || a.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Fatalf("got Duration %v, Bounds %v", a.Duration(), a.Bounds())
+	}
+	c := NewCanvas(a)
+	for i := range a.Frames {
+		m := c.Next()
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				if got, want := m.RGBAAt(x, y), wantCanvas(i, x, y); got != want {
+					t.Errorf("frame %d: (%d, %d): got %v, want %v", i, x, y, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package anim
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"time"
+
+	"golang.org/x/image/apng"
+	"golang.org/x/image/webp"
+)
+
+// FromGIF returns the Animation of g, such as one returned by
+// gif.DecodeAll. The frames share g's images.
+func FromGIF(g *gif.GIF) *Animation {
+	a := &Animation{
+		Frames: make([]Frame, len(g.Image)),
+		Config: g.Config,
+	}
+	for i, m := range g.Image {
+		f := &a.Frames[i]
+		f.Image = m
+		if i < len(g.Delay) {
+			f.Delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		if i < len(g.Disposal) {
+			switch g.Disposal[i] {
+			case gif.DisposalBackground:
+				f.Disposal = DisposalBackground
+			case gif.DisposalPrevious:
+				f.Disposal = DisposalPrevious
+			}
+		}
+	}
+	// GIF's LoopCount is the number of times the animation is repeated, with
+	// -1 meaning that it is played once.
+	switch {
+	case g.LoopCount < 0:
+		a.LoopCount = 1
+	case g.LoopCount > 0:
+		a.LoopCount = g.LoopCount + 1
+	}
+	if p, ok := g.Config.ColorModel.(color.Palette); ok && int(g.BackgroundIndex) < len(p) {
+		a.BackgroundColor = p[g.BackgroundIndex]
+	}
+	if a.Config.Width == 0 && a.Config.Height == 0 {
+		// As for gif.EncodeAll, a zero Config means that the canvas is just
+		// large enough for the frames.
+		var r image.Rectangle
+		for _, m := range g.Image {
+			r = r.Union(m.Rect)
+		}
+		a.Config.Width, a.Config.Height = r.Max.X, r.Max.Y
+	}
+	if a.Config.ColorModel == nil {
+		a.Config.ColorModel = color.RGBAModel
+	}
+	return a
+}
+
+// FromWebP returns the Animation of w, such as one returned by
+// webp.DecodeAll. The frames share w's images.
+func FromWebP(w *webp.WEBP) *Animation {
+	a := &Animation{
+		Frames:          make([]Frame, len(w.Image)),
+		LoopCount:       w.LoopCount,
+		BackgroundColor: w.BackgroundColor,
+		Config:          w.Config,
+	}
+	for i, m := range w.Image {
+		f := &a.Frames[i]
+		f.Image = m
+		if i < len(w.Duration) {
+			f.Delay = w.Duration[i]
+		}
+		if i < len(w.Disposal) && w.Disposal[i] == webp.DisposalBackground {
+			f.Disposal = DisposalBackground
+		}
+		if i < len(w.Blend) && w.Blend[i] == webp.BlendNone {
+			f.Blend = BlendSource
+		}
+	}
+	return a
+}
+
+// FromAPNG returns the Animation of p, such as one returned by
+// apng.DecodeAll. The frames share p's images.
+func FromAPNG(p *apng.APNG) *Animation {
+	a := &Animation{
+		Frames:    make([]Frame, len(p.Image)),
+		LoopCount: p.LoopCount,
+		Config:    p.Config,
+	}
+	for i, m := range p.Image {
+		f := &a.Frames[i]
+		f.Image = m
+		if i < len(p.Delay) {
+			f.Delay = p.Delay[i]
+		}
+		if i < len(p.Dispose) {
+			switch p.Dispose[i] {
+			case apng.DisposeOpBackground:
+				f.Disposal = DisposalBackground
+			case apng.DisposeOpPrevious:
+				f.Disposal = DisposalPrevious
+			}
+		}
+		// APNG frames are drawn with the source operator by default.
+		f.Blend = BlendSource
+		if i < len(p.Blend) && p.Blend[i] == apng.BlendOpOver {
+			f.Blend = BlendOver
+		}
+	}
+	if a.Config.Width == 0 && a.Config.Height == 0 && len(p.Image) > 0 {
+		a.Config.Width, a.Config.Height = p.Image[0].Bounds().Max.X, p.Image[0].Bounds().Max.Y
+	}
+	return a
+}
@@ -0,0 +1,272 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ansi renders images as terminal escape sequences, for command-line
+// tools that show inline previews.
+//
+// Two encodings are supported. EncodeHalfBlock writes rows of the Unicode
+// upper half block character, each cell showing two vertically stacked
+// pixels as its 24-bit foreground and background colors, which works in most
+// modern terminal emulators. EncodeSixel writes a DEC Sixel graphic, which
+// fewer terminals support but which shows the image at the terminal's full
+// pixel resolution, in up to 256 colors.
+package ansi // import "golang.org/x/image/ansi"
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"io"
+	"strconv"
+
+	"golang.org/x/image/draw"
+)
+
+// Options are the encoding parameters.
+//
+// A nil *Options means to use the default (zero) values of each field.
+type Options struct {
+	// Width and Height, if positive, are the largest size that the image is
+	// shown at. EncodeHalfBlock measures them in terminal cells, columns and
+	// rows, and EncodeSixel measures them in pixels. Larger images are scaled
+	// down to fit, keeping their aspect ratio. Images are never scaled up.
+	Width, Height int
+
+	// Scaler is how the image is scaled down. Nil means draw.ApproxBiLinear.
+	Scaler draw.Scaler
+
+	// Background is the color that transparent and translucent images are
+	// composited over. Nil means black.
+	Background color.Color
+
+	// Quantizer chooses the Sixel palette, of up to 256 colors. Nil means
+	// the fixed palette.Plan9 palette. It is ignored by EncodeHalfBlock,
+	// whose colors are not limited.
+	Quantizer draw.Quantizer
+
+	// Dither is whether EncodeSixel uses Floyd-Steinberg error diffusion to
+	// approximate the colors that are not in the palette.
+	Dither bool
+}
+
+// prepare returns m, scaled down to fit within maxW by maxH pixels, where
+// zero means no limit, and composited over the background.
+func prepare(m image.Image, maxW, maxH int, o *Options) *image.RGBA {
+	sr := m.Bounds()
+	w, h := sr.Dx(), sr.Dy()
+	if maxW > 0 && w > maxW {
+		w, h = maxW, max(1, h*maxW/w)
+	}
+	if maxH > 0 && h > maxH {
+		w, h = max(1, w*maxH/h), maxH
+	}
+
+	bg := o.Background
+	if bg == nil {
+		bg = color.Black
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Rect, image.NewUniform(bg), image.Point{}, draw.Src)
+	if w == sr.Dx() && h == sr.Dy() {
+		draw.Draw(dst, dst.Rect, m, sr.Min, draw.Over)
+		return dst
+	}
+	s := o.Scaler
+	if s == nil {
+		s = draw.ApproxBiLinear
+	}
+	s.Scale(dst, dst.Rect, m, sr, draw.Over, nil)
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// EncodeHalfBlock writes m to w as lines of text in which each character
+// cell shows two pixels, one above the other, in 24-bit color. Each line
+// ends with an attribute reset and a newline.
+func EncodeHalfBlock(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	rgba := prepare(m, o.Width, 2*o.Height, &o)
+	bw := bufio.NewWriter(w)
+	var buf []byte
+	for y := 0; y < rgba.Rect.Max.Y; y += 2 {
+		buf = buf[:0]
+		var fg, bg color.RGBA
+		first := true
+		for x := 0; x < rgba.Rect.Max.X; x++ {
+			top := rgba.RGBAAt(x, y)
+			if first || top != fg {
+				buf = appendSGR(buf, 38, top)
+				fg = top
+			}
+			if y+1 < rgba.Rect.Max.Y {
+				bottom := rgba.RGBAAt(x, y+1)
+				if first || bottom != bg {
+					buf = appendSGR(buf, 48, bottom)
+					bg = bottom
+				}
+			}
+			first = false
+			buf = append(buf, "▀"...)
+		}
+		buf = append(buf, "\x1b[0m\n"...)
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendSGR appends the Select Graphic Rendition escape sequence that sets
+// the 24-bit foreground (code 38) or background (code 48) color to c.
+func appendSGR(buf []byte, code int, c color.RGBA) []byte {
+	buf = append(buf, "\x1b["...)
+	buf = strconv.AppendInt(buf, int64(code), 10)
+	buf = append(buf, ";2;"...)
+	buf = strconv.AppendInt(buf, int64(c.R), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendInt(buf, int64(c.G), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendInt(buf, int64(c.B), 10)
+	return append(buf, 'm')
+}
+
+// EncodeSixel writes m to w as a DEC Sixel graphic, with a palette of up to
+// 256 colors.
+func EncodeSixel(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	rgba := prepare(m, o.Width, o.Height, &o)
+	var p color.Palette
+	if o.Quantizer != nil {
+		p = o.Quantizer.Quantize(make(color.Palette, 0, 256), rgba)
+	} else {
+		p = palette.Plan9
+	}
+	if len(p) > 256 {
+		p = p[:256]
+	}
+	pm := image.NewPaletted(rgba.Rect, p)
+	if o.Dither {
+		draw.FloydSteinberg.Draw(pm, pm.Rect, rgba, image.Point{})
+	} else {
+		draw.Draw(pm, pm.Rect, rgba, image.Point{}, draw.Src)
+	}
+
+	// The introducer's parameters select a 1:1 pixel aspect ratio, and the
+	// raster attributes give the image's size.
+	width, height := pm.Rect.Dx(), pm.Rect.Dy()
+	buf := []byte("\x1bP0;1;0q\"1;1;")
+	buf = strconv.AppendInt(buf, int64(width), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendInt(buf, int64(height), 10)
+	for i, c := range p {
+		r, g, b, _ := c.RGBA()
+		buf = append(buf, '#')
+		buf = strconv.AppendInt(buf, int64(i), 10)
+		buf = append(buf, ";2;"...)
+		buf = strconv.AppendInt(buf, int64(percent(r)), 10)
+		buf = append(buf, ';')
+		buf = strconv.AppendInt(buf, int64(percent(g)), 10)
+		buf = append(buf, ';')
+		buf = strconv.AppendInt(buf, int64(percent(b)), 10)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	// Each band of six rows is drawn once per color that it uses, with
+	// carriage returns between the colors.
+	var (
+		used  [256]bool
+		sixel = make([]byte, width)
+	)
+	for y := 0; y < height; y += 6 {
+		if y > 0 {
+			buf = append(buf[:0], '-')
+		} else {
+			buf = buf[:0]
+		}
+		for i := range used {
+			used[i] = false
+		}
+		for dy := 0; dy < 6 && y+dy < height; dy++ {
+			for _, c := range pm.Pix[(y+dy)*pm.Stride:][:width] {
+				used[c] = true
+			}
+		}
+		firstColor := true
+		for c := range used {
+			if !used[c] {
+				continue
+			}
+			for x := range sixel {
+				bits := byte(0)
+				for dy := 0; dy < 6 && y+dy < height; dy++ {
+					if pm.Pix[(y+dy)*pm.Stride+x] == uint8(c) {
+						bits |= 1 << uint(dy)
+					}
+				}
+				sixel[x] = '?' + bits
+			}
+			if !firstColor {
+				buf = append(buf, '$')
+			}
+			firstColor = false
+			buf = append(buf, '#')
+			buf = strconv.AppendInt(buf, int64(c), 10)
+			buf = appendRuns(buf, sixel)
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.WriteString("\x1b\\"); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// appendRuns appends the sixel characters s, run-length encoded, and without
+// any trailing empty sixels.
+func appendRuns(buf, s []byte) []byte {
+	for len(s) > 0 && s[len(s)-1] == '?' {
+		s = s[:len(s)-1]
+	}
+	for i := 0; i < len(s); {
+		j := i + 1
+		for j < len(s) && s[j] == s[i] {
+			j++
+		}
+		if n := j - i; n > 3 {
+			buf = append(buf, '!')
+			buf = strconv.AppendInt(buf, int64(n), 10)
+			buf = append(buf, s[i])
+		} else {
+			for ; n > 0; n-- {
+				buf = append(buf, s[i])
+			}
+		}
+		i = j
+	}
+	return buf
+}
+
+// percent converts a 16-bit color component to the 0 to 100 range of Sixel
+// color definitions.
+func percent(v uint32) uint32 {
+	return (v*100 + 0xffff/2) / 0xffff
+}
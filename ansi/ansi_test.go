@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ansi
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+var (
+	red   = color.RGBA{0xff, 0x00, 0x00, 0xff}
+	green = color.RGBA{0x00, 0xff, 0x00, 0xff}
+	blue  = color.RGBA{0x00, 0x00, 0xff, 0xff}
+)
+
+func TestEncodeHalfBlock(t *testing.T) {
+	// Rows of red, red, blue, with the right column's middle pixel green.
+	m := image.NewRGBA(image.Rect(10, 20, 12, 23))
+	for x := 10; x < 12; x++ {
+		m.SetRGBA(x, 20, red)
+		m.SetRGBA(x, 21, red)
+		m.SetRGBA(x, 22, blue)
+	}
+	m.SetRGBA(11, 21, green)
+
+	var buf bytes.Buffer
+	if err := EncodeHalfBlock(&buf, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"\x1b[38;2;255;0;0m\x1b[48;2;255;0;0m▀\x1b[48;2;0;255;0m▀\x1b[0m\n" +
+		"\x1b[38;2;0;0;255m▀▀\x1b[0m\n"
+	if got := buf.String(); got != want {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestEncodeHalfBlockSize(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 100, 40))
+	for _, tc := range []struct {
+		opts        *Options
+		cols, lines int
+	}{
+		{nil, 100, 20},
+		{&Options{Width: 50}, 50, 10},
+		{&Options{Height: 5}, 25, 5},
+		{&Options{Width: 200, Height: 200}, 100, 20},
+	} {
+		var buf bytes.Buffer
+		if err := EncodeHalfBlock(&buf, m, tc.opts); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != tc.lines {
+			t.Errorf("%+v: got %d lines, want %d", tc.opts, len(lines), tc.lines)
+			continue
+		}
+		if n := strings.Count(lines[0], "▀"); n != tc.cols {
+			t.Errorf("%+v: got %d columns, want %d", tc.opts, n, tc.cols)
+		}
+	}
+}
+
+// fixedQuantizer is a draw.Quantizer that always returns its palette.
+type fixedQuantizer color.Palette
+
+func (q fixedQuantizer) Quantize(p color.Palette, m image.Image) color.Palette {
+	return append(p, q...)
+}
+
+func TestEncodeSixel(t *testing.T) {
+	// A 5 by 7 image, red apart from a green column and a blue bottom row.
+	m := image.NewRGBA(image.Rect(0, 0, 5, 7))
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 5; x++ {
+			c := red
+			if y == 6 {
+				c = blue
+			} else if x == 1 {
+				c = green
+			}
+			m.SetRGBA(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	opts := &Options{Quantizer: fixedQuantizer{red, green, blue}}
+	if err := EncodeSixel(&buf, m, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1bP0;1;0q\"1;1;5;7" +
+		"#0;2;100;0;0#1;2;0;100;0#2;2;0;0;100" +
+		"#0~?~~~$#1?~" +
+		"-#2!5@" +
+		"\x1b\\"
+	if got := buf.String(); got != want {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestAppendRuns(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"???", ""},
+		{"~~~", "~~~"},
+		{"~~~~", "!4~"},
+		{"A??????B??", "A!6?B"},
+	} {
+		if got := string(appendRuns(nil, []byte(tc.in))); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
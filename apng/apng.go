@@ -0,0 +1,74 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package apng implements a decoder and encoder for animated PNG (APNG)
+// images.
+//
+// An APNG image is a PNG image whose extra chunks hold the frames of an
+// animation, each of which is compressed like the PNG image data. Decoders
+// that do not support APNG show the PNG image, called the default image,
+// which is usually the first frame.
+//
+// Each frame is decoded and encoded by the standard library's image/png
+// package, so frames have the same image types as PNG images.
+//
+// The APNG specification is at
+// https://wiki.mozilla.org/APNG_Specification
+package apng // import "golang.org/x/image/apng"
+
+import (
+	"image"
+	"time"
+)
+
+// Disposal methods, applied to a frame's rectangle of the canvas after the
+// frame is shown.
+const (
+	// DisposeOpNone leaves the canvas as is.
+	DisposeOpNone = 0x00
+	// DisposeOpBackground clears the frame's rectangle to transparent black.
+	DisposeOpBackground = 0x01
+	// DisposeOpPrevious restores the frame's rectangle to what it was before
+	// the frame was drawn.
+	DisposeOpPrevious = 0x02
+)
+
+// Blending methods.
+const (
+	// BlendOpSource replaces the canvas's pixels in the frame's rectangle.
+	BlendOpSource = 0x00
+	// BlendOpOver alpha-blends the frame over the canvas.
+	BlendOpOver = 0x01
+)
+
+// APNG represents the frames of an animated PNG image.
+type APNG struct {
+	// Image holds the successive frames. Each frame's bounds are its
+	// rectangle within the canvas. The first frame's bounds are the whole
+	// canvas.
+	Image []image.Image
+	// Delay holds the successive display times, one per frame.
+	Delay []time.Duration
+	// Dispose holds the successive disposal methods, one per frame. For
+	// EncodeAll, a nil Dispose means DisposeOpNone for every frame.
+	Dispose []byte
+	// Blend holds the successive blending methods, one per frame. For
+	// EncodeAll, a nil Blend means BlendOpSource for every frame.
+	Blend []byte
+	// LoopCount is the number of times the animation is played. Zero means
+	// that it loops forever.
+	LoopCount int
+	// Default is the default image, if it is not the first frame, or nil.
+	// Its bounds are the whole canvas.
+	Default image.Image
+	// Config is the canvas's color model and dimensions. For EncodeAll, a
+	// zero Config means that the canvas's dimensions are the first frame's
+	// bounds' Max point.
+	Config image.Config
+}
+
+// A FormatError reports that the input is not a valid APNG image.
+type FormatError string
+
+func (e FormatError) Error() string { return "apng: invalid format: " + string(e) }
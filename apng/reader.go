@@ -0,0 +1,306 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+const (
+	ihdrLen = 13
+	actlLen = 8
+	fctlLen = 26
+)
+
+// chunk is a PNG chunk, without its length and CRC.
+type chunk struct {
+	typ  string
+	data []byte
+}
+
+// readChunks splits b, a PNG stream, into its chunks, checking their CRCs.
+func readChunks(b []byte) ([]chunk, error) {
+	if len(b) < len(pngHeader) || string(b[:len(pngHeader)]) != pngHeader {
+		return nil, FormatError("not a PNG file")
+	}
+	b = b[len(pngHeader):]
+	var chunks []chunk
+	for len(b) > 0 {
+		if len(b) < 12 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		n := binary.BigEndian.Uint32(b)
+		if n > uint32(len(b)-12) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		c := b[4 : 8+n]
+		if crc32.ChecksumIEEE(c) != binary.BigEndian.Uint32(b[8+n:]) {
+			return nil, FormatError("invalid checksum")
+		}
+		chunks = append(chunks, chunk{string(c[:4]), c[4:]})
+		b = b[12+n:]
+		if chunks[len(chunks)-1].typ == "IEND" {
+			break
+		}
+	}
+	if len(chunks) == 0 || chunks[len(chunks)-1].typ != "IEND" {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return chunks, nil
+}
+
+// writeChunk appends the chunk with type typ and contents data to b.
+func writeChunk(b []byte, typ string, data []byte) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], uint32(len(data)))
+	b = append(b, buf[:]...)
+	i := len(b)
+	b = append(b, typ...)
+	b = append(b, data...)
+	binary.BigEndian.PutUint32(buf[:], crc32.ChecksumIEEE(b[i:]))
+	return append(b, buf[:]...)
+}
+
+// frameControl is the contents of an fcTL chunk, and the compressed data of
+// its frame. isDefault is whether the frame is the default image, whose data
+// is in IDAT chunks instead of fdAT chunks.
+type frameControl struct {
+	rect      image.Rectangle
+	delay     time.Duration
+	dispose   byte
+	blend     byte
+	idat      [][]byte
+	isDefault bool
+}
+
+// DecodeAll reads an APNG image from r and returns the sequential frames and
+// timing information. A PNG image that is not animated is returned as a
+// single frame.
+func DecodeAll(r io.Reader) (*APNG, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := readChunks(b)
+	if err != nil {
+		return nil, err
+	}
+	if chunks[0].typ != "IHDR" || len(chunks[0].data) != ihdrLen {
+		return nil, FormatError("missing IHDR chunk")
+	}
+	ihdr := chunks[0].data
+	width := int(binary.BigEndian.Uint32(ihdr[0:]))
+	height := int(binary.BigEndian.Uint32(ihdr[4:]))
+
+	var (
+		a         = &APNG{}
+		animated  bool
+		numFrames uint32
+		seq       uint32
+		// shared holds the chunks that apply to every frame, such as the
+		// palette.
+		shared []chunk
+		// idat holds the default image's data, and frames the frames' data.
+		idat   [][]byte
+		frames []frameControl
+	)
+	for _, c := range chunks[1:] {
+		switch c.typ {
+		case "PLTE", "tRNS":
+			if idat == nil {
+				shared = append(shared, c)
+			}
+		case "acTL":
+			if len(c.data) != actlLen || animated || idat != nil {
+				return nil, FormatError("invalid acTL chunk")
+			}
+			animated = true
+			numFrames = binary.BigEndian.Uint32(c.data[0:])
+			a.LoopCount = int(binary.BigEndian.Uint32(c.data[4:]))
+			if numFrames == 0 {
+				return nil, FormatError("no frames")
+			}
+		case "fcTL":
+			if !animated {
+				break
+			}
+			if len(c.data) != fctlLen || binary.BigEndian.Uint32(c.data) != seq {
+				return nil, FormatError("invalid fcTL chunk")
+			}
+			seq++
+			f, err := parseFrameControl(c.data[4:], width, height)
+			if err != nil {
+				return nil, err
+			}
+			if idat == nil && len(frames) == 0 {
+				// The default image is the first frame, and so covers the
+				// whole canvas.
+				if f.rect != image.Rect(0, 0, width, height) {
+					return nil, FormatError("first frame is not the whole canvas")
+				}
+				f.isDefault = true
+			}
+			frames = append(frames, f)
+		case "IDAT":
+			if len(frames) > 1 || (len(frames) == 1 && !frames[0].isDefault) {
+				return nil, FormatError("IDAT chunk after fcTL chunk")
+			}
+			idat = append(idat, c.data)
+			if len(frames) == 1 {
+				frames[0].idat = append(frames[0].idat, c.data)
+			}
+		case "fdAT":
+			if !animated {
+				break
+			}
+			if len(c.data) < 4 || binary.BigEndian.Uint32(c.data) != seq {
+				return nil, FormatError("invalid fdAT chunk")
+			}
+			seq++
+			if len(frames) == 0 || frames[len(frames)-1].isDefault {
+				return nil, FormatError("fdAT chunk without fcTL chunk")
+			}
+			f := &frames[len(frames)-1]
+			f.idat = append(f.idat, c.data[4:])
+		}
+	}
+	if idat == nil {
+		return nil, FormatError("missing IDAT chunk")
+	}
+
+	m, err := decodeFrame(ihdr, width, height, shared, idat)
+	if err != nil {
+		return nil, err
+	}
+	a.Config = image.Config{
+		ColorModel: m.ColorModel(),
+		Width:      width,
+		Height:     height,
+	}
+	if !animated {
+		a.Image = []image.Image{m}
+		a.Delay = []time.Duration{0}
+		a.Dispose = []byte{DisposeOpNone}
+		a.Blend = []byte{BlendOpSource}
+		return a, nil
+	}
+	if uint32(len(frames)) != numFrames {
+		return nil, FormatError("wrong number of frames")
+	}
+	a.Image = make([]image.Image, len(frames))
+	a.Delay = make([]time.Duration, len(frames))
+	a.Dispose = make([]byte, len(frames))
+	a.Blend = make([]byte, len(frames))
+	for i, f := range frames {
+		if i == 0 && f.isDefault {
+			a.Image[i] = m
+		} else {
+			if f.idat == nil {
+				return nil, FormatError("missing fdAT chunk")
+			}
+			fm, err := decodeFrame(ihdr, f.rect.Dx(), f.rect.Dy(), shared, f.idat)
+			if err != nil {
+				return nil, err
+			}
+			translate(fm, f.rect.Min)
+			a.Image[i] = fm
+		}
+		a.Delay[i] = f.delay
+		a.Dispose[i] = f.dispose
+		a.Blend[i] = f.blend
+	}
+	if !frames[0].isDefault {
+		a.Default = m
+	}
+	return a, nil
+}
+
+// parseFrameControl parses the contents of an fcTL chunk, after its sequence
+// number, for a canvas of the given dimensions.
+func parseFrameControl(b []byte, width, height int) (frameControl, error) {
+	var (
+		w        = binary.BigEndian.Uint32(b[0:])
+		h        = binary.BigEndian.Uint32(b[4:])
+		x        = binary.BigEndian.Uint32(b[8:])
+		y        = binary.BigEndian.Uint32(b[12:])
+		delayNum = binary.BigEndian.Uint16(b[16:])
+		delayDen = binary.BigEndian.Uint16(b[18:])
+		dispose  = b[20]
+		blend    = b[21]
+	)
+	if w == 0 || h == 0 || uint64(x)+uint64(w) > uint64(width) || uint64(y)+uint64(h) > uint64(height) {
+		return frameControl{}, FormatError("frame is outside the canvas")
+	}
+	if dispose > DisposeOpPrevious || blend > BlendOpOver {
+		return frameControl{}, FormatError("invalid frame disposal or blending")
+	}
+	// A zero denominator means hundredths of a second.
+	if delayDen == 0 {
+		delayDen = 100
+	}
+	return frameControl{
+		rect:    image.Rect(int(x), int(y), int(x+w), int(y+h)),
+		delay:   time.Duration(delayNum) * time.Second / time.Duration(delayDen),
+		dispose: dispose,
+		blend:   blend,
+	}, nil
+}
+
+// decodeFrame decodes a frame of the given dimensions, whose compressed data
+// is the concatenation of idat, as a PNG image with the canvas's IHDR
+// chunk's other fields and the shared chunks.
+func decodeFrame(ihdr []byte, w, h int, shared []chunk, idat [][]byte) (image.Image, error) {
+	n := len(pngHeader) + 12 + ihdrLen + 12
+	for _, c := range shared {
+		n += 12 + len(c.data)
+	}
+	for _, d := range idat {
+		n += 12 + len(d)
+	}
+	b := make([]byte, 0, n)
+	b = append(b, pngHeader...)
+	var buf [ihdrLen]byte
+	copy(buf[:], ihdr)
+	binary.BigEndian.PutUint32(buf[0:], uint32(w))
+	binary.BigEndian.PutUint32(buf[4:], uint32(h))
+	b = writeChunk(b, "IHDR", buf[:])
+	for _, c := range shared {
+		b = writeChunk(b, c.typ, c.data)
+	}
+	for _, d := range idat {
+		b = writeChunk(b, "IDAT", d)
+	}
+	b = writeChunk(b, "IEND", nil)
+	return png.Decode(bytes.NewReader(b))
+}
+
+// translate moves the bounds of m, an image returned by png.Decode, by p.
+func translate(m image.Image, p image.Point) {
+	switch m := m.(type) {
+	case *image.Gray:
+		m.Rect = m.Rect.Add(p)
+	case *image.Gray16:
+		m.Rect = m.Rect.Add(p)
+	case *image.RGBA:
+		m.Rect = m.Rect.Add(p)
+	case *image.RGBA64:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA64:
+		m.Rect = m.Rect.Add(p)
+	case *image.Paletted:
+		m.Rect = m.Rect.Add(p)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestDecodeAllPNG(t *testing.T) {
+	f, err := os.Open("../testdata/testpattern.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	a, err := DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if len(a.Image) != 1 || a.Default != nil {
+		t.Fatalf("got %d frames, default image %v, want 1, nil", len(a.Image), a.Default != nil)
+	}
+	if !sameImage(a.Image[0], want) {
+		t.Error("images differ")
+	}
+	if b := want.Bounds(); a.Config.Width != b.Dx() || a.Config.Height != b.Dy() {
+		t.Errorf("Config: got %dx%d, want %v", a.Config.Width, a.Config.Height, b.Size())
+	}
+}
+
+// chunkOffset returns the offset in b, an encoded APNG image, of the n'th
+// chunk of type typ.
+func chunkOffset(t *testing.T, b []byte, typ string, n int) int {
+	for i := len(pngHeader); i+8 <= len(b); {
+		if string(b[i+4:i+8]) == typ {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+		i += 12 + int(binary.BigEndian.Uint32(b[i:]))
+	}
+	t.Fatalf("no %s chunk", typ)
+	return 0
+}
+
+func TestDecodeAllErrors(t *testing.T) {
+	var buf bytes.Buffer
+	err := EncodeAll(&buf, &APNG{
+		Image: []image.Image{
+			gradient(image.Rect(0, 0, 4, 4), 0xff),
+			gradient(image.Rect(1, 1, 3, 3), 0xff),
+		},
+		Delay: []time.Duration{0, 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+	if _, err := DecodeAll(bytes.NewReader(valid)); err != nil {
+		t.Fatalf("valid: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		mutate func(b []byte) []byte
+	}{
+		{"truncated", func(b []byte) []byte {
+			return b[:len(b)-20]
+		}},
+		{"checksum", func(b []byte) []byte {
+			b[chunkOffset(t, b, "IDAT", 0)+8] ^= 0xff
+			return b
+		}},
+		{"sequence number", func(b []byte) []byte {
+			// Swap the second fcTL chunk's sequence number, keeping the
+			// chunk's checksum valid.
+			i := chunkOffset(t, b, "fcTL", 1)
+			n := int(binary.BigEndian.Uint32(b[i:]))
+			binary.BigEndian.PutUint32(b[i+8:], 7)
+			return append(writeChunk(b[:i:i], "fcTL", b[i+8:i+8+n]), b[i+12+n:]...)
+		}},
+		{"frame count", func(b []byte) []byte {
+			i := chunkOffset(t, b, "acTL", 0)
+			binary.BigEndian.PutUint32(b[i+8:], 3)
+			return append(writeChunk(b[:i:i], "acTL", b[i+8:i+16]), b[i+20:]...)
+		}},
+	}
+	for _, tc := range testCases {
+		b := tc.mutate(append([]byte(nil), valid...))
+		if _, err := DecodeAll(bytes.NewReader(b)); err == nil {
+			t.Errorf("%s: got nil error", tc.name)
+		}
+	}
+}
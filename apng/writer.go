@@ -0,0 +1,243 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"time"
+)
+
+// frameImage is an image whose PNG encoding has the color model model, and
+// an alpha channel unless opaque is true, so that the frames of an animation,
+// which share the default image's IHDR chunk, have the same color type.
+type frameImage struct {
+	image.Image
+	model  color.Model
+	opaque bool
+}
+
+func (m frameImage) ColorModel() color.Model { return m.model }
+
+func (m frameImage) Opaque() bool { return m.opaque }
+
+// encoded is a frame encoded as a PNG image.
+type encoded struct {
+	ihdr   []byte
+	shared []chunk
+	idat   [][]byte
+}
+
+// EncodeAll writes the images in a to w in APNG format.
+//
+// If every image is an *image.Paletted with the same palette, the frames are
+// encoded with that palette. Otherwise, they are encoded with 8 bits per
+// channel, or 16 if any of them has a 16-bit color model, and with an alpha
+// channel unless they are all opaque.
+func EncodeAll(w io.Writer, a *APNG) error {
+	n := len(a.Image)
+	if n == 0 {
+		return FormatError("no frames")
+	}
+	if len(a.Delay) != n {
+		return FormatError("mismatched image and delay lengths")
+	}
+	if (a.Dispose != nil && len(a.Dispose) != n) || (a.Blend != nil && len(a.Blend) != n) {
+		return FormatError("mismatched image and disposal or blending lengths")
+	}
+	if a.LoopCount < 0 || int64(a.LoopCount) > 0xffffffff {
+		return FormatError("invalid loop count")
+	}
+	canvas := image.Rectangle{Max: image.Point{a.Config.Width, a.Config.Height}}
+	if canvas.Empty() {
+		canvas.Max = a.Image[0].Bounds().Max
+	}
+	if a.Default != nil && a.Default.Bounds().Size() != canvas.Size() {
+		return FormatError("default image is not the whole canvas")
+	}
+	if a.Default == nil && a.Image[0].Bounds() != canvas {
+		return FormatError("first frame is not the whole canvas")
+	}
+	images := a.Image
+	if a.Default != nil {
+		images = append([]image.Image{a.Default}, images...)
+	}
+	convert := frameConverter(images)
+
+	enc := make([]encoded, len(images))
+	for i, m := range images {
+		b := m.Bounds()
+		if (i > 0 || a.Default == nil) && (b.Empty() || !b.In(canvas)) {
+			return FormatError("frame is outside the canvas")
+		}
+		e, err := encodeFrame(convert(m))
+		if err != nil {
+			return err
+		}
+		if i > 0 && !bytes.Equal(e.ihdr[8:], enc[0].ihdr[8:]) {
+			return FormatError("frames have different PNG color types")
+		}
+		enc[i] = e
+	}
+
+	var (
+		buf [fctlLen]byte
+		b   = []byte(pngHeader)
+		seq = uint32(0)
+	)
+	copy(buf[:ihdrLen], enc[0].ihdr)
+	binary.BigEndian.PutUint32(buf[0:], uint32(canvas.Dx()))
+	binary.BigEndian.PutUint32(buf[4:], uint32(canvas.Dy()))
+	b = writeChunk(b, "IHDR", buf[:ihdrLen])
+	binary.BigEndian.PutUint32(buf[0:], uint32(n))
+	binary.BigEndian.PutUint32(buf[4:], uint32(a.LoopCount))
+	b = writeChunk(b, "acTL", buf[:actlLen])
+	for _, c := range enc[0].shared {
+		b = writeChunk(b, c.typ, c.data)
+	}
+	if a.Default != nil {
+		for _, d := range enc[0].idat {
+			b = writeChunk(b, "IDAT", d)
+		}
+		enc = enc[1:]
+	}
+	for i, m := range a.Image {
+		r := m.Bounds()
+		num, den := delayFraction(a.Delay[i])
+		binary.BigEndian.PutUint32(buf[0:], seq)
+		binary.BigEndian.PutUint32(buf[4:], uint32(r.Dx()))
+		binary.BigEndian.PutUint32(buf[8:], uint32(r.Dy()))
+		binary.BigEndian.PutUint32(buf[12:], uint32(r.Min.X))
+		binary.BigEndian.PutUint32(buf[16:], uint32(r.Min.Y))
+		binary.BigEndian.PutUint16(buf[20:], num)
+		binary.BigEndian.PutUint16(buf[22:], den)
+		buf[24], buf[25] = DisposeOpNone, BlendOpSource
+		if a.Dispose != nil {
+			buf[24] = a.Dispose[i]
+		}
+		if a.Blend != nil {
+			buf[25] = a.Blend[i]
+		}
+		if buf[24] > DisposeOpPrevious || buf[25] > BlendOpOver {
+			return FormatError("invalid frame disposal or blending")
+		}
+		b = writeChunk(b, "fcTL", buf[:fctlLen])
+		seq++
+		for _, d := range enc[i].idat {
+			if i == 0 && a.Default == nil {
+				b = writeChunk(b, "IDAT", d)
+				continue
+			}
+			binary.BigEndian.PutUint32(buf[:4], seq)
+			b = writeChunk(b, "fdAT", append(buf[:4:4], d...))
+			seq++
+		}
+	}
+	b = writeChunk(b, "IEND", nil)
+	_, err := w.Write(b)
+	return err
+}
+
+// frameConverter returns a function that converts each of images to an
+// image that png.Encode encodes with the same color type as the others.
+func frameConverter(images []image.Image) func(image.Image) image.Image {
+	if p, ok := images[0].(*image.Paletted); ok {
+		same := true
+		for _, m := range images[1:] {
+			if q, ok := m.(*image.Paletted); !ok || !samePalette(p.Palette, q.Palette) {
+				same = false
+				break
+			}
+		}
+		if same {
+			return func(m image.Image) image.Image { return m }
+		}
+	}
+	model, opaque := color.NRGBAModel, true
+	for _, m := range images {
+		switch m.ColorModel() {
+		case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+			model = color.NRGBA64Model
+		}
+		if opaque && !isOpaque(m) {
+			opaque = false
+		}
+	}
+	return func(m image.Image) image.Image {
+		return frameImage{m, model, opaque}
+	}
+}
+
+// isOpaque returns whether every pixel of m is opaque.
+func isOpaque(m image.Image) bool {
+	if o, ok := m.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := m.At(x, y).RGBA(); a != 0xffff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func samePalette(p, q color.Palette) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		r0, g0, b0, a0 := p[i].RGBA()
+		r1, g1, b1, a1 := q[i].RGBA()
+		if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeFrame encodes m with png.Encode and returns its chunks.
+func encodeFrame(m image.Image) (encoded, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, m); err != nil {
+		return encoded{}, err
+	}
+	chunks, err := readChunks(buf.Bytes())
+	if err != nil {
+		return encoded{}, err
+	}
+	var e encoded
+	for _, c := range chunks {
+		switch c.typ {
+		case "IHDR":
+			e.ihdr = c.data
+		case "PLTE", "tRNS":
+			e.shared = append(e.shared, c)
+		case "IDAT":
+			e.idat = append(e.idat, c.data)
+		}
+	}
+	return e, nil
+}
+
+// delayFraction returns d as the numerator and denominator of a fraction of
+// a second, as milliseconds if they fit, or else as coarser units.
+func delayFraction(d time.Duration) (num, den uint16) {
+	if d < 0 {
+		return 0, 1
+	}
+	for _, unit := range []time.Duration{time.Millisecond, 10 * time.Millisecond, time.Second} {
+		if n := (d + unit/2) / unit; n <= 0xffff {
+			return uint16(n), uint16(time.Second / unit)
+		}
+	}
+	return 0xffff, 1
+}
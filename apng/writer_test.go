@@ -0,0 +1,192 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package apng
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"time"
+)
+
+// gradient returns an r-sized image with a gradient of colors, whose alpha
+// is a.
+func gradient(r image.Rectangle, a uint8) *image.NRGBA {
+	m := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			m.SetNRGBA(x, y, color.NRGBA{uint8(16 * x), uint8(16 * y), 0x40, a})
+		}
+	}
+	return m
+}
+
+// sameImage returns whether m0 and m1 have the same bounds and colors.
+func sameImage(m0, m1 image.Image) bool {
+	b := m0.Bounds()
+	if b != m1.Bounds() {
+		return false
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r0, g0, b0, a0 := m0.At(x, y).RGBA()
+			r1, g1, b1, a1 := m1.At(x, y).RGBA()
+			if r0>>8 != r1>>8 || g0>>8 != g1>>8 || b0>>8 != b1>>8 || a0>>8 != a1>>8 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestRoundTrip(t *testing.T) {
+	pal := color.Palette{color.Transparent, color.White, color.Black}
+	paletted := func(r image.Rectangle, i uint8) *image.Paletted {
+		m := image.NewPaletted(r, pal)
+		for j := range m.Pix {
+			m.Pix[j] = i
+		}
+		return m
+	}
+	testCases := []struct {
+		name string
+		a    *APNG
+	}{{
+		name: "mixed",
+		a: &APNG{
+			Image: []image.Image{
+				gradient(image.Rect(0, 0, 12, 10), 0xff),
+				gradient(image.Rect(2, 3, 7, 9), 0x80),
+				image.NewGray(image.Rect(11, 9, 12, 10)),
+			},
+			Delay:     []time.Duration{100 * time.Millisecond, time.Second, 90 * time.Second},
+			Dispose:   []byte{DisposeOpNone, DisposeOpBackground, DisposeOpPrevious},
+			Blend:     []byte{BlendOpSource, BlendOpOver, BlendOpSource},
+			LoopCount: 3,
+		},
+	}, {
+		name: "paletted",
+		a: &APNG{
+			Image: []image.Image{
+				paletted(image.Rect(0, 0, 8, 8), 1),
+				paletted(image.Rect(4, 4, 8, 8), 2),
+			},
+			Delay: []time.Duration{0, 20 * time.Millisecond},
+		},
+	}, {
+		name: "default",
+		a: &APNG{
+			Image: []image.Image{
+				gradient(image.Rect(1, 1, 4, 4), 0xff),
+			},
+			Delay:   []time.Duration{time.Second},
+			Default: gradient(image.Rect(0, 0, 5, 5), 0xff),
+			Config:  image.Config{Width: 5, Height: 5},
+		},
+	}}
+
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := EncodeAll(&buf, tc.a); err != nil {
+			t.Errorf("%s: EncodeAll: %v", tc.name, err)
+			continue
+		}
+		got, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%s: DecodeAll: %v", tc.name, err)
+			continue
+		}
+		if len(got.Image) != len(tc.a.Image) || got.LoopCount != tc.a.LoopCount {
+			t.Errorf("%s: got %d frames, LoopCount %d, want %d, %d",
+				tc.name, len(got.Image), got.LoopCount, len(tc.a.Image), tc.a.LoopCount)
+			continue
+		}
+		for i, m := range tc.a.Image {
+			if !sameImage(got.Image[i], m) {
+				t.Errorf("%s: frame %d: images differ", tc.name, i)
+			}
+			if got.Delay[i] != tc.a.Delay[i] {
+				t.Errorf("%s: frame %d: got delay %v, want %v", tc.name, i, got.Delay[i], tc.a.Delay[i])
+			}
+			if tc.a.Dispose != nil && got.Dispose[i] != tc.a.Dispose[i] {
+				t.Errorf("%s: frame %d: got dispose %d, want %d", tc.name, i, got.Dispose[i], tc.a.Dispose[i])
+			}
+			if tc.a.Blend != nil && got.Blend[i] != tc.a.Blend[i] {
+				t.Errorf("%s: frame %d: got blend %d, want %d", tc.name, i, got.Blend[i], tc.a.Blend[i])
+			}
+		}
+		if tc.a.Default != nil && !sameImage(got.Default, tc.a.Default) {
+			t.Errorf("%s: default images differ", tc.name)
+		}
+		if tc.a.Default == nil && got.Default != nil {
+			t.Errorf("%s: got a default image, want the first frame", tc.name)
+		}
+
+		// Decoders that do not support APNG see the default image.
+		m, err := png.Decode(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Errorf("%s: png.Decode: %v", tc.name, err)
+			continue
+		}
+		want := tc.a.Default
+		if want == nil {
+			want = tc.a.Image[0]
+		}
+		if !sameImage(m, want) {
+			t.Errorf("%s: png.Decode: images differ", tc.name)
+		}
+	}
+}
+
+func TestEncodeAllErrors(t *testing.T) {
+	full := gradient(image.Rect(0, 0, 4, 4), 0xff)
+	testCases := []struct {
+		name string
+		a    *APNG
+	}{
+		{"no frames", &APNG{}},
+		{"no delays", &APNG{Image: []image.Image{full}}},
+		{"partial first frame", &APNG{
+			Image:  []image.Image{gradient(image.Rect(1, 1, 4, 4), 0xff)},
+			Delay:  []time.Duration{0},
+			Config: image.Config{Width: 4, Height: 4},
+		}},
+		{"outside the canvas", &APNG{
+			Image: []image.Image{full, gradient(image.Rect(2, 2, 6, 6), 0xff)},
+			Delay: []time.Duration{0, 0},
+		}},
+		{"invalid dispose", &APNG{
+			Image:   []image.Image{full},
+			Delay:   []time.Duration{0},
+			Dispose: []byte{3},
+		}},
+	}
+	for _, tc := range testCases {
+		if err := EncodeAll(&bytes.Buffer{}, tc.a); err == nil {
+			t.Errorf("%s: got nil error", tc.name)
+		}
+	}
+}
+
+func TestDelayFraction(t *testing.T) {
+	testCases := []struct {
+		d        time.Duration
+		num, den uint16
+	}{
+		{0, 0, 1000},
+		{40 * time.Millisecond, 40, 1000},
+		{65535 * time.Millisecond, 65535, 1000},
+		{70 * time.Second, 7000, 100},
+		{time.Hour, 3600, 1},
+		{100 * time.Hour, 0xffff, 1},
+	}
+	for _, tc := range testCases {
+		if num, den := delayFraction(tc.d); num != tc.num || den != tc.den {
+			t.Errorf("%v: got %d/%d, want %d/%d", tc.d, num, den, tc.num, tc.den)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package binpack packs rectangles into fixed-size bins, such as the images
+// of a sprite sheet or the glyphs of a font atlas.
+//
+// A Packer places one rectangle at a time, so that it can be used both
+// offline, for a known set of rectangles, and online, for rectangles that
+// are only known as they are needed, such as the glyphs of a glyph cache.
+// Offline packing is tighter when the rectangles are inserted from the
+// largest to the smallest.
+package binpack // import "golang.org/x/image/binpack"
+
+import (
+	"image"
+)
+
+// Heuristic is the way a Packer chooses where to place each rectangle.
+type Heuristic int
+
+const (
+	// MaxRects keeps track of the maximal free rectangles of the bin and
+	// places each rectangle in the free rectangle that it fits best, leaving
+	// the shortest leftover side. It packs more tightly than Skyline, but is
+	// slower for many rectangles.
+	MaxRects Heuristic = iota
+	// Skyline keeps track of the skyline, the top edge of the placed
+	// rectangles, and places each rectangle as high as possible, then as far
+	// left as possible. The space below any overhang is never used.
+	Skyline
+)
+
+// Packer packs rectangles into a bin.
+type Packer struct {
+	width, height int
+	heuristic     Heuristic
+	// free holds the maximal free rectangles, for MaxRects.
+	free []image.Rectangle
+	// skyline holds the segments of the skyline, from left to right, for
+	// Skyline.
+	skyline []segment
+}
+
+// segment is a horizontal segment of a skyline, from x to x+w at height y.
+// Rectangles placed above the segment must have their top edge at or below
+// y, in image coordinates.
+type segment struct {
+	x, y, w int
+}
+
+// NewPacker returns a Packer for an empty bin of the given size.
+func NewPacker(width, height int, h Heuristic) *Packer {
+	p := &Packer{
+		width:     width,
+		height:    height,
+		heuristic: h,
+	}
+	p.Reset()
+	return p
+}
+
+// Size returns the size of the bin.
+func (p *Packer) Size() image.Point {
+	return image.Point{p.width, p.height}
+}
+
+// Reset empties the bin.
+func (p *Packer) Reset() {
+	p.free = p.free[:0]
+	p.skyline = p.skyline[:0]
+	if p.width <= 0 || p.height <= 0 {
+		return
+	}
+	switch p.heuristic {
+	case MaxRects:
+		p.free = append(p.free, image.Rect(0, 0, p.width, p.height))
+	case Skyline:
+		p.skyline = append(p.skyline, segment{0, 0, p.width})
+	}
+}
+
+// Insert places a rectangle of the given size in the bin. It returns the
+// rectangle's position, and whether there was room for it. Rectangles are
+// not rotated. Empty rectangles take no room, and are placed at the origin.
+func (p *Packer) Insert(width, height int) (r image.Rectangle, ok bool) {
+	if width < 0 || height < 0 || width > p.width || height > p.height {
+		return image.Rectangle{}, false
+	}
+	if width == 0 || height == 0 {
+		return image.Rect(0, 0, width, height), true
+	}
+	switch p.heuristic {
+	case MaxRects:
+		return p.insertMaxRects(width, height)
+	case Skyline:
+		return p.insertSkyline(width, height)
+	}
+	return image.Rectangle{}, false
+}
+
+// insertMaxRects implements Insert for MaxRects, with the "best short side
+// fit" rule of Jukka Jylänki's "A Thousand Ways to Pack the Bin".
+func (p *Packer) insertMaxRects(width, height int) (image.Rectangle, bool) {
+	best, bestShort, bestLong := -1, 0, 0
+	for i, f := range p.free {
+		dx, dy := f.Dx()-width, f.Dy()-height
+		if dx < 0 || dy < 0 {
+			continue
+		}
+		short, long := dx, dy
+		if short > long {
+			short, long = long, short
+		}
+		if best < 0 || short < bestShort || (short == bestShort && long < bestLong) {
+			best, bestShort, bestLong = i, short, long
+		}
+	}
+	if best < 0 {
+		return image.Rectangle{}, false
+	}
+	r := image.Rectangle{
+		Min: p.free[best].Min,
+		Max: p.free[best].Min.Add(image.Point{width, height}),
+	}
+
+	// Split every free rectangle that r overlaps into the up to four maximal
+	// rectangles around r.
+	free := make([]image.Rectangle, 0, len(p.free)+4)
+	for _, f := range p.free {
+		if !f.Overlaps(r) {
+			free = append(free, f)
+			continue
+		}
+		if r.Min.X > f.Min.X {
+			free = append(free, image.Rect(f.Min.X, f.Min.Y, r.Min.X, f.Max.Y))
+		}
+		if r.Max.X < f.Max.X {
+			free = append(free, image.Rect(r.Max.X, f.Min.Y, f.Max.X, f.Max.Y))
+		}
+		if r.Min.Y > f.Min.Y {
+			free = append(free, image.Rect(f.Min.X, f.Min.Y, f.Max.X, r.Min.Y))
+		}
+		if r.Max.Y < f.Max.Y {
+			free = append(free, image.Rect(f.Min.X, r.Max.Y, f.Max.X, f.Max.Y))
+		}
+	}
+
+	// Remove the free rectangles that are within another.
+	p.free = p.free[:0]
+	for i, f := range free {
+		contained := false
+		for j, g := range free {
+			// Of two equal rectangles, keep the first.
+			if i != j && f.In(g) && (f != g || j < i) {
+				contained = true
+				break
+			}
+		}
+		if !contained {
+			p.free = append(p.free, f)
+		}
+	}
+	return r, true
+}
+
+// insertSkyline implements Insert for Skyline, with the "bottom-left" rule
+// of Jukka Jylänki's "A Thousand Ways to Pack the Bin", where the bottom is
+// the top in image coordinates.
+func (p *Packer) insertSkyline(width, height int) (image.Rectangle, bool) {
+	best, bestY, bestW := -1, 0, 0
+	for i, s := range p.skyline {
+		y, ok := p.skylineFit(i, width, height)
+		if !ok {
+			continue
+		}
+		if best < 0 || y < bestY || (y == bestY && s.w < bestW) {
+			best, bestY, bestW = i, y, s.w
+		}
+	}
+	if best < 0 {
+		return image.Rectangle{}, false
+	}
+	x := p.skyline[best].x
+	r := image.Rect(x, bestY, x+width, bestY+height)
+
+	// Replace the segments below r with a segment along r's bottom edge.
+	skyline := make([]segment, 0, len(p.skyline)+1)
+	skyline = append(skyline, p.skyline[:best]...)
+	skyline = append(skyline, segment{x, r.Max.Y, width})
+	for _, s := range p.skyline[best:] {
+		if end := s.x + s.w; end > r.Max.X {
+			if s.x < r.Max.X {
+				s = segment{r.Max.X, s.y, end - r.Max.X}
+			}
+			skyline = append(skyline, s)
+		}
+	}
+
+	// Merge neighboring segments at the same height.
+	p.skyline = skyline[:1]
+	for _, s := range skyline[1:] {
+		if last := &p.skyline[len(p.skyline)-1]; last.y == s.y {
+			last.w += s.w
+		} else {
+			p.skyline = append(p.skyline, s)
+		}
+	}
+	return r, true
+}
+
+// skylineFit returns the top edge of a rectangle of the given size whose left
+// edge is that of the i'th skyline segment, and whether it fits in the bin.
+func (p *Packer) skylineFit(i, width, height int) (y int, ok bool) {
+	x := p.skyline[i].x
+	if x+width > p.width {
+		return 0, false
+	}
+	for end := x + width; i < len(p.skyline) && p.skyline[i].x < end; i++ {
+		if y < p.skyline[i].y {
+			y = p.skyline[i].y
+		}
+	}
+	if y+height > p.height {
+		return 0, false
+	}
+	return y, true
+}
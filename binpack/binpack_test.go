@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package binpack
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+var heuristics = []struct {
+	name string
+	h    Heuristic
+}{
+	{"MaxRects", MaxRects},
+	{"Skyline", Skyline},
+}
+
+func TestInsertRandom(t *testing.T) {
+	for _, hc := range heuristics {
+		rng := rand.New(rand.NewSource(1))
+		p := NewPacker(256, 256, hc.h)
+		bounds := image.Rect(0, 0, 256, 256)
+		var placed []image.Rectangle
+		area := 0
+		for i := 0; i < 1000; i++ {
+			w, h := 1+rng.Intn(32), 1+rng.Intn(32)
+			r, ok := p.Insert(w, h)
+			if !ok {
+				continue
+			}
+			if r.Dx() != w || r.Dy() != h {
+				t.Fatalf("%s: Insert(%d, %d): got %v", hc.name, w, h, r)
+			}
+			if !r.In(bounds) {
+				t.Fatalf("%s: %v is not within %v", hc.name, r, bounds)
+			}
+			for _, q := range placed {
+				if r.Overlaps(q) {
+					t.Fatalf("%s: %v overlaps %v", hc.name, r, q)
+				}
+			}
+			placed = append(placed, r)
+			area += w * h
+		}
+		// Both heuristics should fill most of the bin.
+		if got, want := float64(area)/(256*256), 0.8; got < want {
+			t.Errorf("%s: occupancy: got %.3f, want at least %.3f", hc.name, got, want)
+		}
+	}
+}
+
+func TestInsertExact(t *testing.T) {
+	for _, hc := range heuristics {
+		// Sixteen 16x16 squares fill a 64x64 bin exactly.
+		p := NewPacker(64, 64, hc.h)
+		for i := 0; i < 16; i++ {
+			if _, ok := p.Insert(16, 16); !ok {
+				t.Fatalf("%s: square #%d did not fit", hc.name, i)
+			}
+		}
+		if r, ok := p.Insert(1, 1); ok {
+			t.Errorf("%s: a full bin fit a 1x1 rectangle at %v", hc.name, r)
+		}
+		if _, ok := p.Insert(0, 5); !ok {
+			t.Errorf("%s: a full bin did not fit an empty rectangle", hc.name)
+		}
+
+		p.Reset()
+		if r, ok := p.Insert(64, 64); !ok || r != image.Rect(0, 0, 64, 64) {
+			t.Errorf("%s: after Reset: got %v, %t, want the whole bin", hc.name, r, ok)
+		}
+	}
+}
+
+func TestInsertTooLarge(t *testing.T) {
+	for _, hc := range heuristics {
+		p := NewPacker(64, 32, hc.h)
+		for _, size := range []image.Point{{65, 1}, {1, 33}, {-1, 1}} {
+			if r, ok := p.Insert(size.X, size.Y); ok {
+				t.Errorf("%s: Insert(%d, %d): got %v, want no fit", hc.name, size.X, size.Y, r)
+			}
+		}
+	}
+}
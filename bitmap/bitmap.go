@@ -0,0 +1,195 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bitmap implements bilevel images, whose pixels are either black or
+// white, packed 8 pixels per byte. They take an eighth of the memory of an
+// image.Gray, which matters for the large pages of fax and print pipelines.
+//
+// The layout is that of the golang.org/x/image/ccitt package's NewReader
+// output, and of uncompressed bilevel TIFF data: rows of bits, most
+// significant bit first, with 1 meaning white and 0 meaning black.
+package bitmap // import "golang.org/x/image/bitmap"
+
+import (
+	"image"
+	"image/color"
+)
+
+var (
+	// Black and White are the two colors of a bilevel image.
+	Black = color.Gray{0x00}
+	White = color.Gray{0xff}
+)
+
+// Model is the color model of bilevel images. It converts colors whose gray
+// level is less than half to Black, and the others to White. Transparent
+// colors, which are black when premultiplied, convert to Black.
+var Model color.Model = color.ModelFunc(bilevelModel)
+
+func bilevelModel(c color.Color) color.Color {
+	if isWhite(c) {
+		return White
+	}
+	return Black
+}
+
+// isWhite returns whether c converts to White.
+func isWhite(c color.Color) bool {
+	if g, ok := c.(color.Gray); ok {
+		return g.Y >= 0x80
+	}
+	return color.GrayModel.Convert(c).(color.Gray).Y >= 0x80
+}
+
+// Image is an in-memory bilevel image. Its At method returns color.Gray
+// values, either Black or White.
+//
+// The pixel at (x, y) is bit 7-(x&7), counting from the least significant
+// bit, of the byte at index (y-Rect.Min.Y)*Stride + (x>>3 - Rect.Min.X>>3) of
+// Pix. The bit's position within its byte depends only on x, not on
+// Rect.Min.X, so that SubImage can share the pixels of any rectangle, and an
+// image whose Rect.Min.X is not a multiple of 8 has unused bits at the start
+// of each row.
+type Image struct {
+	// Pix holds the image's pixels, as packed bits. A 1 bit is white and a 0
+	// bit is black.
+	Pix []uint8
+	// Stride is the Pix stride (in bytes) between vertically adjacent
+	// pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// New returns a new, all black, Image with the given bounds.
+func New(r image.Rectangle) *Image {
+	stride := rowBytes(r)
+	return &Image{
+		Pix:    make([]uint8, stride*r.Dy()),
+		Stride: stride,
+		Rect:   r,
+	}
+}
+
+// rowBytes returns the number of bytes that hold a row of r.
+func rowBytes(r image.Rectangle) int {
+	if r.Empty() {
+		return 0
+	}
+	return (r.Max.X-1)>>3 - r.Min.X>>3 + 1
+}
+
+// FromImage returns a new Image with the bounds and pixels of m, converted
+// by Model.
+func FromImage(m image.Image) *Image {
+	b := m.Bounds()
+	dst := New(b)
+	if g, ok := m.(*image.Gray); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			src := g.Pix[g.PixOffset(b.Min.X, y):]
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if src[x-b.Min.X] >= 0x80 {
+					dst.Pix[dst.PixOffset(x, y)] |= 0x80 >> uint(x&7)
+				}
+			}
+		}
+		return dst
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if isWhite(m.At(x, y)) {
+				dst.Pix[dst.PixOffset(x, y)] |= 0x80 >> uint(x&7)
+			}
+		}
+	}
+	return dst
+}
+
+func (p *Image) ColorModel() color.Model { return Model }
+
+func (p *Image) Bounds() image.Rectangle { return p.Rect }
+
+func (p *Image) At(x, y int) color.Color {
+	return p.GrayAt(x, y)
+}
+
+func (p *Image) GrayAt(x, y int) color.Gray {
+	if p.BitAt(x, y) {
+		return White
+	}
+	return Black
+}
+
+// BitAt returns whether the pixel at (x, y) is white. Pixels outside of the
+// image's bounds are black.
+func (p *Image) BitAt(x, y int) bool {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return false
+	}
+	return p.Pix[p.PixOffset(x, y)]&(0x80>>uint(x&7)) != 0
+}
+
+// PixOffset returns the index of the byte of Pix that holds the pixel at
+// (x, y).
+func (p *Image) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x>>3 - p.Rect.Min.X>>3)
+}
+
+// Set sets the pixel at (x, y) to c, converted by Model.
+func (p *Image) Set(x, y int, c color.Color) {
+	p.SetBit(x, y, isWhite(c))
+}
+
+// SetBit sets the pixel at (x, y) to white, if white is true, or black.
+func (p *Image) SetBit(x, y int, white bool) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i, mask := p.PixOffset(x, y), uint8(0x80>>uint(x&7))
+	if white {
+		p.Pix[i] |= mask
+	} else {
+		p.Pix[i] &^= mask
+	}
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *Image) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be
+	// inside either r1 or r2 if the intersection is empty. Without explicitly
+	// checking for this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &Image{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &Image{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque. A
+// bilevel image is always opaque.
+func (p *Image) Opaque() bool {
+	return true
+}
+
+// Gray returns a new image.Gray with the bounds and pixels of p, whose gray
+// levels are 0x00 or 0xff.
+func (p *Image) Gray() *image.Gray {
+	g := image.NewGray(p.Rect)
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		row := p.Pix[p.PixOffset(p.Rect.Min.X, y):]
+		dst := g.Pix[g.PixOffset(p.Rect.Min.X, y):]
+		for x := p.Rect.Min.X; x < p.Rect.Max.X; x++ {
+			if row[x>>3-p.Rect.Min.X>>3]&(0x80>>uint(x&7)) != 0 {
+				dst[x-p.Rect.Min.X] = 0xff
+			}
+		}
+	}
+	return g
+}
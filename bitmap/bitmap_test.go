@@ -0,0 +1,131 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bitmap
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSetAt(t *testing.T) {
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 16, 3),
+		image.Rect(3, 1, 13, 4),
+		image.Rect(-5, -2, 2, 2),
+	} {
+		m := New(r)
+		if got, want := m.Stride, rowBytes(r); got != want {
+			t.Errorf("%v: Stride: got %d, want %d", r, got, want)
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if (x+y)%3 == 0 {
+					m.Set(x, y, color.White)
+				}
+			}
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				want := Black
+				if (x+y)%3 == 0 {
+					want = White
+				}
+				if got := m.At(x, y); got != want {
+					t.Errorf("%v: At(%d, %d): got %v, want %v", r, x, y, got, want)
+				}
+			}
+		}
+		if m.BitAt(r.Max.X, r.Min.Y) || m.BitAt(r.Min.X-1, r.Min.Y) {
+			t.Errorf("%v: pixel outside of the bounds is white", r)
+		}
+	}
+}
+
+func TestRowBytes(t *testing.T) {
+	for _, tc := range []struct {
+		minX, maxX, want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0, 8, 1},
+		{0, 9, 2},
+		{7, 9, 2},
+		{8, 16, 1},
+		{3, 13, 2},
+		{-1, 1, 2},
+	} {
+		if got := rowBytes(image.Rect(tc.minX, 0, tc.maxX, 1)); got != tc.want {
+			t.Errorf("rowBytes(%d..%d): got %d, want %d", tc.minX, tc.maxX, got, tc.want)
+		}
+	}
+}
+
+func TestSubImage(t *testing.T) {
+	m := New(image.Rect(0, 0, 20, 10))
+	sub := m.SubImage(image.Rect(5, 2, 17, 7)).(*Image)
+	sub.SetBit(5, 2, true)
+	sub.SetBit(16, 6, true)
+	sub.SetBit(4, 2, true) // Outside of sub, so a no-op.
+	if !m.BitAt(5, 2) || !m.BitAt(16, 6) {
+		t.Error("SubImage does not share the pixels")
+	}
+	if m.BitAt(4, 2) {
+		t.Error("SetBit outside of the bounds changed the pixels")
+	}
+	if got := m.SubImage(image.Rect(30, 30, 40, 40)).Bounds(); !got.Empty() {
+		t.Errorf("empty SubImage: got bounds %v", got)
+	}
+}
+
+func TestFromImageGray(t *testing.T) {
+	r := image.Rect(3, 2, 14, 6)
+	src := image.NewGray(r)
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 37)
+	}
+	// The *image.Gray fast path and the generic path must agree, and Gray
+	// must undo FromImage.
+	nrgba := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			nrgba.Set(x, y, src.At(x, y))
+		}
+	}
+	m0, m1 := FromImage(src), FromImage(nrgba)
+	g := m0.Gray()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			want := src.GrayAt(x, y).Y >= 0x80
+			if got := m0.BitAt(x, y); got != want {
+				t.Errorf("Gray fast path: (%d, %d): got %t, want %t", x, y, got, want)
+			}
+			if got := m1.BitAt(x, y); got != want {
+				t.Errorf("generic path: (%d, %d): got %t, want %t", x, y, got, want)
+			}
+			if got := g.GrayAt(x, y) == White; got != want {
+				t.Errorf("Gray: (%d, %d): got %t, want %t", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestModel(t *testing.T) {
+	for _, tc := range []struct {
+		c    color.Color
+		want color.Gray
+	}{
+		{color.Black, Black},
+		{color.White, White},
+		{color.Gray{0x7f}, Black},
+		{color.Gray{0x80}, White},
+		{color.RGBA{0xff, 0xff, 0xff, 0xff}, White},
+		{color.Transparent, Black},
+	} {
+		if got := Model.Convert(tc.c); got != tc.want {
+			t.Errorf("Convert(%v): got %v, want %v", tc.c, got, tc.want)
+		}
+	}
+}
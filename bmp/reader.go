@@ -8,10 +8,15 @@
 package bmp // import "golang.org/x/image/bmp"
 
 import (
+	"bufio"
 	"errors"
 	"image"
 	"image/color"
 	"io"
+	"io/ioutil"
+	"math/bits"
+
+	"golang.org/x/image/imagelimit"
 )
 
 // ErrUnsupported means that the input BMP image uses a valid but unsupported
@@ -26,32 +31,128 @@ func readUint32(b []byte) uint32 {
 	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
 }
 
-// decodePaletted reads an 8 bit-per-pixel BMP image from r.
+// decodePaletted reads a 1, 2, 4 or 8 bit-per-pixel BMP image from r.
 // If topDown is false, the image rows will be read bottom-up.
-func decodePaletted(r io.Reader, c image.Config, topDown bool) (image.Image, error) {
+func decodePaletted(r io.Reader, c image.Config, bpp int, topDown bool) (image.Image, error) {
 	paletted := image.NewPaletted(image.Rect(0, 0, c.Width, c.Height), c.ColorModel.(color.Palette))
 	if c.Width == 0 || c.Height == 0 {
 		return paletted, nil
 	}
-	var tmp [4]byte
+	// Each row is 4-byte aligned.
+	b := make([]byte, ((c.Width*bpp+7)/8+3)&^3)
 	y0, y1, yDelta := c.Height-1, -1, -1
 	if topDown {
 		y0, y1, yDelta = 0, c.Height, +1
 	}
 	for y := y0; y != y1; y += yDelta {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
 		p := paletted.Pix[y*paletted.Stride : y*paletted.Stride+c.Width]
-		if _, err := io.ReadFull(r, p); err != nil {
+		if bpp == 8 {
+			copy(p, b)
+			continue
+		}
+		// Pixels are packed from the most significant bits of each byte.
+		mask := byte(1)<<uint(bpp) - 1
+		for x := range p {
+			bit := x * bpp
+			p[x] = b[bit/8] >> uint(8-bpp-bit%8) & mask
+		}
+	}
+	return paletted, nil
+}
+
+// decodeRLE reads an RLE8 or RLE4 compressed BMP image, whose bpp is 8 or 4
+// respectively, from r. The rows are always bottom-up. Pixels that the
+// compressed data skips over are left as the palette's first color.
+func decodeRLE(r io.Reader, c image.Config, bpp int) (image.Image, error) {
+	paletted := image.NewPaletted(image.Rect(0, 0, c.Width, c.Height), c.ColorModel.(color.Palette))
+	br := bufio.NewReader(r)
+	readByte := func() (byte, error) {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return b, err
+	}
+	// set sets the pixel at (x, y), in bottom-up coordinates, ignoring
+	// pixels outside of the image.
+	set := func(x, y int, index byte) {
+		if 0 <= x && x < c.Width && 0 <= y && y < c.Height {
+			paletted.Pix[(c.Height-1-y)*paletted.Stride+x] = index
+		}
+	}
+	x, y := 0, 0
+	for {
+		n, err := readByte()
+		if err != nil {
 			return nil, err
 		}
-		// Each row is 4-byte aligned.
-		if c.Width%4 != 0 {
-			_, err := io.ReadFull(r, tmp[:4-c.Width%4])
+		v, err := readByte()
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			// Encoded mode: n pixels of the index v, or of the two indexes
+			// in turn for RLE4.
+			for i := 0; i < int(n); i++ {
+				index := v
+				if bpp == 4 {
+					if i&1 == 0 {
+						index = v >> 4
+					} else {
+						index = v & 0x0f
+					}
+				}
+				set(x, y, index)
+				x++
+			}
+			continue
+		}
+		switch v {
+		case 0: // End of line.
+			x, y = 0, y+1
+		case 1: // End of bitmap.
+			return paletted, nil
+		case 2: // Delta.
+			dx, err := readByte()
+			if err != nil {
+				return nil, err
+			}
+			dy, err := readByte()
 			if err != nil {
 				return nil, err
 			}
+			x, y = x+int(dx), y+int(dy)
+		default:
+			// Absolute mode: v literal pixels, padded to a 2-byte boundary.
+			nBytes := int(v)
+			if bpp == 4 {
+				nBytes = (nBytes + 1) / 2
+			}
+			var buf [256]byte
+			lit := buf[:(nBytes+1)&^1]
+			if _, err := io.ReadFull(br, lit); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			for i := 0; i < int(v); i++ {
+				var index byte
+				if bpp == 8 {
+					index = lit[i]
+				} else if i&1 == 0 {
+					index = lit[i/2] >> 4
+				} else {
+					index = lit[i/2] & 0x0f
+				}
+				set(x, y, index)
+				x++
+			}
 		}
 	}
-	return paletted, nil
 }
 
 // decodeRGB reads a 24 bit-per-pixel BMP image from r.
@@ -107,38 +208,191 @@ func decodeNRGBA(r io.Reader, c image.Config, topDown bool) (image.Image, error)
 	return rgba, nil
 }
 
+// decodeBitFields reads a 16 or 32 bit-per-pixel BMP image, whose pixels'
+// channels are given by f's masks, from r. If f.topDown is false, the image
+// rows will be read bottom-up.
+func decodeBitFields(r io.Reader, c image.Config, f format) (image.Image, error) {
+	var channels [4]bitField
+	for i, m := range f.masks {
+		channels[i] = newBitField(m)
+	}
+	var (
+		dst image.Image
+		pix []byte
+		// stride is the destination stride.
+		stride int
+	)
+	if f.masks[3] != 0 {
+		m := image.NewNRGBA(image.Rect(0, 0, c.Width, c.Height))
+		dst, pix, stride = m, m.Pix, m.Stride
+	} else {
+		m := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+		dst, pix, stride = m, m.Pix, m.Stride
+	}
+	if c.Width == 0 || c.Height == 0 {
+		return dst, nil
+	}
+	// Each row is 4-byte aligned.
+	bytesPerPixel := f.bpp / 8
+	b := make([]byte, (bytesPerPixel*c.Width+3)&^3)
+	y0, y1, yDelta := c.Height-1, -1, -1
+	if f.topDown {
+		y0, y1, yDelta = 0, c.Height, +1
+	}
+	for y := y0; y != y1; y += yDelta {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		p := pix[y*stride : y*stride+c.Width*4]
+		for i, j := 0, 0; i < len(p); i, j = i+4, j+bytesPerPixel {
+			var v uint32
+			if bytesPerPixel == 2 {
+				v = uint32(readUint16(b[j:]))
+			} else {
+				v = readUint32(b[j:])
+			}
+			p[i+0] = channels[0].value(v)
+			p[i+1] = channels[1].value(v)
+			p[i+2] = channels[2].value(v)
+			p[i+3] = 0xFF
+			if f.masks[3] != 0 {
+				p[i+3] = channels[3].value(v)
+			}
+		}
+	}
+	return dst, nil
+}
+
+// bitField is a channel of a pixel, given by a contiguous mask.
+type bitField struct {
+	shift uint32
+	// max is the channel's largest value, after shifting.
+	max uint32
+}
+
+func newBitField(mask uint32) bitField {
+	if mask == 0 {
+		return bitField{}
+	}
+	shift := uint32(bits.TrailingZeros32(mask))
+	return bitField{shift, mask >> shift}
+}
+
+// value returns the channel of the pixel v, scaled to 8 bits.
+func (f bitField) value(v uint32) uint8 {
+	if f.max == 0 {
+		return 0
+	}
+	x := uint64(v>>f.shift) & uint64(f.max)
+	return uint8((x*0xFF + uint64(f.max)/2) / uint64(f.max))
+}
+
 // Decode reads a BMP image from r and returns it as an image.Image.
-// Limitation: The file must be 8, 24 or 32 bits per pixel.
+// Limitation: The file must be 1, 2, 4, 8, 16, 24 or 32 bits per pixel, and 4
+// and 8 bit-per-pixel files may be RLE4 and RLE8 compressed respectively.
 func Decode(r io.Reader) (image.Image, error) {
-	c, bpp, topDown, err := decodeConfig(r)
+	return decode(r, nil)
+}
+
+// DecodeOptions are optional parameters to DecodeWithOptions.
+type DecodeOptions struct {
+	// Limits, if non-nil, are the limits on the image to decode, which are
+	// checked before its pixels are allocated. DecodeWithOptions returns an
+	// *imagelimit.Error if the image exceeds them.
+	Limits *imagelimit.Limits
+}
+
+// DecodeWithOptions is like Decode but with optional parameters. A nil opts
+// is equivalent to a zero DecodeOptions.
+func DecodeWithOptions(r io.Reader, opts *DecodeOptions) (image.Image, error) {
+	var limits *imagelimit.Limits
+	if opts != nil {
+		limits = opts.Limits
+	}
+	return decode(r, limits)
+}
+
+func decode(r io.Reader, limits *imagelimit.Limits) (image.Image, error) {
+	c, f, err := decodeConfig(r)
 	if err != nil {
 		return nil, err
 	}
-	switch bpp {
-	case 8:
-		return decodePaletted(r, c, topDown)
+	if err := limits.CheckConfig(c); err != nil {
+		return nil, err
+	}
+	switch f.compression {
+	case biRLE8, biRLE4:
+		return decodeRLE(r, c, f.bpp)
+	case biBitFields:
+		return decodeBitFields(r, c, f)
+	}
+	switch f.bpp {
+	case 1, 2, 4, 8:
+		return decodePaletted(r, c, f.bpp, f.topDown)
 	case 24:
-		return decodeRGB(r, c, topDown)
+		return decodeRGB(r, c, f.topDown)
 	case 32:
-		return decodeNRGBA(r, c, topDown)
+		return decodeNRGBA(r, c, f.topDown)
 	}
 	panic("unreachable")
 }
 
 // DecodeConfig returns the color model and dimensions of a BMP image without
 // decoding the entire image.
-// Limitation: The file must be 8, 24 or 32 bits per pixel.
+// Limitation: The file must be 1, 2, 4, 8, 16, 24 or 32 bits per pixel, and 4
+// and 8 bit-per-pixel files may be RLE4 and RLE8 compressed respectively.
 func DecodeConfig(r io.Reader) (image.Config, error) {
-	config, _, _, err := decodeConfig(r)
+	config, _, err := decodeConfig(r)
 	return config, err
 }
 
-func decodeConfig(r io.Reader) (config image.Config, bitsPerPixel int, topDown bool, err error) {
+// DecodeReaderAt is like Decode but reads the size bytes of a BMP image from
+// r, such as an *os.File or a blob store supporting range reads.
+//
+// Decode already reads BMP files sequentially, without buffering, so this is
+// provided for symmetry with the tiff and webp packages' DecodeReaderAt.
+func DecodeReaderAt(r io.ReaderAt, size int64) (image.Image, error) {
+	return Decode(io.NewSectionReader(r, 0, size))
+}
+
+// DecodeConfigReaderAt is like DecodeConfig but reads the size bytes of a BMP
+// image from r.
+func DecodeConfigReaderAt(r io.ReaderAt, size int64) (image.Config, error) {
+	return DecodeConfig(io.NewSectionReader(r, 0, size))
+}
+
+// These are the compression methods of the BITMAPINFOHEADER.
+const (
+	biRGB            = 0
+	biRLE8           = 1
+	biRLE4           = 2
+	biBitFields      = 3
+	biAlphaBitFields = 6
+)
+
+// format is the way that the pixels of a BMP image are stored.
+type format struct {
+	bpp int
+	// compression is biRGB, biRLE8, biRLE4 or biBitFields. The
+	// biAlphaBitFields method is treated as biBitFields, and 16 bit-per-pixel
+	// biRGB images as biBitFields with 5 bits per channel.
+	compression uint32
+	topDown     bool
+	// masks are the red, green, blue and alpha masks of biBitFields images.
+	// A zero alpha mask means an opaque image.
+	masks [4]uint32
+}
+
+func decodeConfig(r io.Reader) (config image.Config, f format, err error) {
 	// We only support those BMP images that are a BITMAPFILEHEADER
-	// immediately followed by a BITMAPINFOHEADER.
+	// immediately followed by a BITMAPINFOHEADER or one of its extensions:
+	// the BITMAPV2INFOHEADER and BITMAPV3INFOHEADER, which add the color
+	// masks and the alpha mask, and the BITMAPV4HEADER and BITMAPV5HEADER.
 	const (
 		fileHeaderLen   = 14
 		infoHeaderLen   = 40
+		v2InfoHeaderLen = 52
+		v3InfoHeaderLen = 56
 		v4InfoHeaderLen = 108
 		v5InfoHeaderLen = 124
 	)
@@ -147,71 +401,153 @@ func decodeConfig(r io.Reader) (config image.Config, bitsPerPixel int, topDown b
 		if err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
-		return image.Config{}, 0, false, err
+		return image.Config{}, format{}, err
 	}
 	if string(b[:2]) != "BM" {
-		return image.Config{}, 0, false, errors.New("bmp: invalid format")
+		return image.Config{}, format{}, errors.New("bmp: invalid format")
 	}
 	offset := readUint32(b[10:14])
 	infoLen := readUint32(b[14:18])
-	if infoLen != infoHeaderLen && infoLen != v4InfoHeaderLen && infoLen != v5InfoHeaderLen {
-		return image.Config{}, 0, false, ErrUnsupported
+	switch infoLen {
+	case infoHeaderLen, v2InfoHeaderLen, v3InfoHeaderLen, v4InfoHeaderLen, v5InfoHeaderLen:
+	default:
+		return image.Config{}, format{}, ErrUnsupported
 	}
 	if _, err := io.ReadFull(r, b[fileHeaderLen+4:fileHeaderLen+infoLen]); err != nil {
 		if err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
-		return image.Config{}, 0, false, err
+		return image.Config{}, format{}, err
 	}
 	width := int(int32(readUint32(b[18:22])))
 	height := int(int32(readUint32(b[22:26])))
 	if height < 0 {
-		height, topDown = -height, true
+		height, f.topDown = -height, true
 	}
 	if width < 0 || height < 0 {
-		return image.Config{}, 0, false, ErrUnsupported
+		return image.Config{}, format{}, ErrUnsupported
 	}
-	// We only support 1 plane and 8, 24 or 32 bits per pixel and no
-	// compression.
-	planes, bpp, compression := readUint16(b[26:28]), readUint16(b[28:30]), readUint32(b[30:34])
-	// if compression is set to BITFIELDS, but the bitmask is set to the default bitmask
-	// that would be used if compression was set to 0, we can continue as if compression was 0
-	if compression == 3 && infoLen > infoHeaderLen &&
-		readUint32(b[54:58]) == 0xff0000 && readUint32(b[58:62]) == 0xff00 &&
-		readUint32(b[62:66]) == 0xff && readUint32(b[66:70]) == 0xff000000 {
-		compression = 0
+	// We only support 1 plane and 1, 2, 4, 8, 16, 24 or 32 bits per pixel,
+	// with no compression except for RLE4, RLE8 and bitfields.
+	planes, bpp := readUint16(b[26:28]), readUint16(b[28:30])
+	f.bpp, f.compression = int(bpp), readUint32(b[30:34])
+	if planes != 1 {
+		return image.Config{}, format{}, ErrUnsupported
 	}
-	if planes != 1 || compression != 0 {
-		return image.Config{}, 0, false, ErrUnsupported
+	// headerEnd is the end of the headers, and of the masks that follow a
+	// BITMAPINFOHEADER.
+	headerEnd := fileHeaderLen + infoLen
+	switch f.compression {
+	case biRGB:
+		if bpp == 16 {
+			f.compression = biBitFields
+			f.masks = [4]uint32{0x7c00, 0x03e0, 0x001f, 0}
+		}
+	case biRLE8, biRLE4:
+		// RLE compressed images are always bottom-up.
+		if (f.compression == biRLE8) != (bpp == 8) || (f.compression == biRLE4) != (bpp == 4) || f.topDown {
+			return image.Config{}, format{}, ErrUnsupported
+		}
+	case biBitFields, biAlphaBitFields:
+		if bpp != 16 && bpp != 32 {
+			return image.Config{}, format{}, ErrUnsupported
+		}
+		// The masks are part of the headers after the BITMAPINFOHEADER, and
+		// follow the BITMAPINFOHEADER otherwise.
+		nMasks := uint32(3)
+		if f.compression == biAlphaBitFields || infoLen >= v3InfoHeaderLen {
+			nMasks = 4
+		}
+		if infoLen == infoHeaderLen {
+			headerEnd += 4 * nMasks
+			if _, err := io.ReadFull(r, b[fileHeaderLen+infoLen:headerEnd]); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return image.Config{}, format{}, err
+			}
+		}
+		for i := uint32(0); i < nMasks; i++ {
+			f.masks[i] = readUint32(b[54+4*i:])
+		}
+		for _, m := range f.masks {
+			// Each mask must be contiguous.
+			if m >>= uint(bits.TrailingZeros32(m)); m&(m+1) != 0 {
+				return image.Config{}, format{}, ErrUnsupported
+			}
+		}
+		f.compression = biBitFields
+		// Bitfields with the default masks of 32 bit images are decoded as
+		// if they were not bitfields.
+		if bpp == 32 && f.masks == [4]uint32{0xff0000, 0xff00, 0xff, 0xff000000} {
+			f.compression = biRGB
+		}
+	default:
+		return image.Config{}, format{}, ErrUnsupported
 	}
 	switch bpp {
-	case 8:
-		if offset != fileHeaderLen+infoLen+256*4 {
-			return image.Config{}, 0, false, ErrUnsupported
+	case 1, 2, 4, 8:
+		// The palette has as many colors as the header says, or 1<<bpp if
+		// it says zero.
+		n := int(readUint32(b[46:50]))
+		if n == 0 {
+			n = 1 << bpp
+		}
+		if n > 1<<bpp {
+			return image.Config{}, format{}, ErrUnsupported
+		}
+		paletteEnd := headerEnd + uint32(n*4)
+		if offset < paletteEnd {
+			return image.Config{}, format{}, ErrUnsupported
 		}
-		_, err = io.ReadFull(r, b[:256*4])
+		_, err = io.ReadFull(r, b[:n*4])
 		if err != nil {
-			return image.Config{}, 0, false, err
+			return image.Config{}, format{}, err
 		}
-		pcm := make(color.Palette, 256)
+		if err := skip(r, offset-paletteEnd); err != nil {
+			return image.Config{}, format{}, err
+		}
+		// Indexes beyond a short palette are opaque black, as for PNG
+		// images.
+		pcm := make(color.Palette, 1<<bpp)
 		for i := range pcm {
+			if i >= n {
+				pcm[i] = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+				continue
+			}
 			// BMP images are stored in BGR order rather than RGB order.
 			// Every 4th byte is padding.
 			pcm[i] = color.RGBA{b[4*i+2], b[4*i+1], b[4*i+0], 0xFF}
 		}
-		return image.Config{ColorModel: pcm, Width: width, Height: height}, 8, topDown, nil
-	case 24:
-		if offset != fileHeaderLen+infoLen {
-			return image.Config{}, 0, false, ErrUnsupported
+		return image.Config{ColorModel: pcm, Width: width, Height: height}, f, nil
+	case 16, 24, 32:
+		if offset < headerEnd {
+			return image.Config{}, format{}, ErrUnsupported
 		}
-		return image.Config{ColorModel: color.RGBAModel, Width: width, Height: height}, 24, topDown, nil
-	case 32:
-		if offset != fileHeaderLen+infoLen {
-			return image.Config{}, 0, false, ErrUnsupported
+		if err := skip(r, offset-headerEnd); err != nil {
+			return image.Config{}, format{}, err
+		}
+		// Images with alpha are decoded as an *image.NRGBA.
+		cm := color.RGBAModel
+		if (f.compression == biRGB && bpp == 32) || (f.compression == biBitFields && f.masks[3] != 0) {
+			cm = color.NRGBAModel
 		}
-		return image.Config{ColorModel: color.RGBAModel, Width: width, Height: height}, 32, topDown, nil
+		return image.Config{ColorModel: cm, Width: width, Height: height}, f, nil
+	}
+	return image.Config{}, format{}, ErrUnsupported
+}
+
+// skip skips n bytes of r, such as the gap between the headers and the pixel
+// data.
+func skip(r io.Reader, n uint32) error {
+	if n == 0 {
+		return nil
+	}
+	_, err := io.CopyN(ioutil.Discard, r, int64(n))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
 	}
-	return image.Config{}, 0, false, ErrUnsupported
+	return err
 }
 
 func init() {
@@ -6,13 +6,17 @@ package bmp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"image"
+	"image/color"
 	"io"
 	"os"
 	"testing"
 
 	_ "image/png"
+
+	"golang.org/x/image/imagelimit"
 )
 
 const testdataDir = "../testdata/"
@@ -86,3 +90,357 @@ func TestEOF(t *testing.T) {
 		t.Errorf("Error should be io.ErrUnexpectedEOF on nil but got %v", err)
 	}
 }
+
+// bmpFile returns a BMP file with a BITMAPINFOHEADER, the given palette and
+// the given pixel data. A negative height means a top-down image.
+func bmpFile(width, height, bpp int, compression uint32, palette []color.RGBA, data []byte) []byte {
+	const headersLen = 14 + 40
+	offset := headersLen + 4*len(palette)
+	b := make([]byte, offset, offset+len(data))
+	copy(b, "BM")
+	binary.LittleEndian.PutUint32(b[2:], uint32(offset+len(data)))
+	binary.LittleEndian.PutUint32(b[10:], uint32(offset))
+	binary.LittleEndian.PutUint32(b[14:], 40)
+	binary.LittleEndian.PutUint32(b[18:], uint32(int32(width)))
+	binary.LittleEndian.PutUint32(b[22:], uint32(int32(height)))
+	binary.LittleEndian.PutUint16(b[26:], 1)
+	binary.LittleEndian.PutUint16(b[28:], uint16(bpp))
+	binary.LittleEndian.PutUint32(b[30:], compression)
+	binary.LittleEndian.PutUint32(b[34:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(b[46:], uint32(len(palette)))
+	for i, c := range palette {
+		b[headersLen+4*i+0] = c.B
+		b[headersLen+4*i+1] = c.G
+		b[headersLen+4*i+2] = c.R
+	}
+	return append(b, data...)
+}
+
+var testPalette = []color.RGBA{
+	{0x00, 0x00, 0x00, 0xff},
+	{0xff, 0x00, 0x00, 0xff},
+	{0x00, 0xff, 0x00, 0xff},
+	{0x00, 0x00, 0xff, 0xff},
+	{0xff, 0xff, 0x00, 0xff},
+}
+
+// TestDecodeLowBitDepth tests decoding 1, 2 and 4 bit-per-pixel images.
+func TestDecodeLowBitDepth(t *testing.T) {
+	testCases := []struct {
+		bpp     int
+		palette []color.RGBA
+		// data holds the rows, bottom-up, each padded to 4 bytes.
+		data []byte
+		// want holds the indexes of the pixels, top-down.
+		want []uint8
+	}{{
+		bpp:     1,
+		palette: testPalette[:2],
+		data: []byte{
+			0xa5, 0x80, 0, 0,
+			0x5a, 0x00, 0, 0,
+		},
+		want: []uint8{
+			0, 1, 0, 1, 1, 0, 1, 0, 0,
+			1, 0, 1, 0, 0, 1, 0, 1, 1,
+		},
+	}, {
+		bpp:     2,
+		palette: testPalette[:4],
+		data: []byte{
+			0x1b, 0xe4, 0xc0, 0,
+			0xe4, 0x1b, 0x00, 0,
+		},
+		want: []uint8{
+			3, 2, 1, 0, 0, 1, 2, 3, 0,
+			0, 1, 2, 3, 3, 2, 1, 0, 3,
+		},
+	}, {
+		// A short palette is padded with opaque black.
+		bpp:     4,
+		palette: testPalette[:3],
+		data: []byte{
+			0x01, 0x23, 0x45, 0x67, 0x80, 0, 0, 0,
+			0xfe, 0xdc, 0xba, 0x98, 0x70, 0, 0, 0,
+		},
+		want: []uint8{
+			15, 14, 13, 12, 11, 10, 9, 8, 7,
+			0, 1, 2, 3, 4, 5, 6, 7, 8,
+		},
+	}}
+
+	for _, tc := range testCases {
+		m, err := Decode(bytes.NewReader(bmpFile(9, 2, tc.bpp, biRGB, tc.palette, tc.data)))
+		if err != nil {
+			t.Errorf("bpp=%d: %v", tc.bpp, err)
+			continue
+		}
+		p, ok := m.(*image.Paletted)
+		if !ok {
+			t.Errorf("bpp=%d: got %T, want *image.Paletted", tc.bpp, m)
+			continue
+		}
+		if len(p.Palette) != 1<<uint(tc.bpp) {
+			t.Errorf("bpp=%d: got %d colors, want %d", tc.bpp, len(p.Palette), 1<<uint(tc.bpp))
+		}
+		if !bytes.Equal(p.Pix, tc.want) {
+			t.Errorf("bpp=%d: got %v, want %v", tc.bpp, p.Pix, tc.want)
+		}
+		for i, c := range tc.palette {
+			if p.Palette[i] != c {
+				t.Errorf("bpp=%d: color #%d: got %v, want %v", tc.bpp, i, p.Palette[i], c)
+			}
+		}
+		if c := p.Palette[len(p.Palette)-1]; len(tc.palette) < len(p.Palette) && c != (color.RGBA{0x00, 0x00, 0x00, 0xff}) {
+			t.Errorf("bpp=%d: padding color: got %v, want opaque black", tc.bpp, c)
+		}
+	}
+}
+
+// TestDecodeRLE tests decoding RLE8 and RLE4 compressed images, including
+// encoded, absolute, end of line and delta runs.
+func TestDecodeRLE(t *testing.T) {
+	testCases := []struct {
+		name        string
+		bpp         int
+		compression uint32
+		data        []byte
+		// want holds the indexes of the pixels, top-down.
+		want []uint8
+	}{{
+		name:        "RLE8",
+		bpp:         8,
+		compression: biRLE8,
+		data: []byte{
+			// Bottom row: 3 of index 1, then 3 literal pixels (padded).
+			0x03, 0x01, 0x00, 0x03, 0x02, 0x03, 0x04, 0x00,
+			0x00, 0x00, // End of line.
+			// Middle row: skip 2 pixels right and 1 row up.
+			0x00, 0x02, 0x02, 0x01,
+			// Top row: 4 of index 4.
+			0x04, 0x04,
+			0x00, 0x01, // End of bitmap.
+		},
+		want: []uint8{
+			0, 0, 4, 4, 4, 4,
+			0, 0, 0, 0, 0, 0,
+			1, 1, 1, 2, 3, 4,
+		},
+	}, {
+		name:        "RLE4",
+		bpp:         4,
+		compression: biRLE4,
+		data: []byte{
+			// Bottom row: 3 pixels alternating 1 and 2, then 3 literal
+			// pixels.
+			0x03, 0x12, 0x00, 0x03, 0x21, 0x30,
+			0x00, 0x00, // End of line.
+			// Middle row: 3 literal pixels, then 3 of index 4.
+			0x00, 0x03, 0x40, 0x30, 0x03, 0x44,
+			0x00, 0x00, // End of line.
+			// Top row: 7 pixels, one more than the width.
+			0x07, 0x21,
+			0x00, 0x01, // End of bitmap.
+		},
+		want: []uint8{
+			2, 1, 2, 1, 2, 1,
+			4, 0, 3, 4, 4, 4,
+			1, 2, 1, 2, 1, 3,
+		},
+	}}
+
+	for _, tc := range testCases {
+		data := bmpFile(6, 3, tc.bpp, tc.compression, testPalette, tc.data)
+		m, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if p := m.(*image.Paletted); !bytes.Equal(p.Pix, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, p.Pix, tc.want)
+		}
+
+		// Truncating the data before the end of bitmap is an error.
+		if _, err := Decode(bytes.NewReader(data[:len(data)-2])); err != io.ErrUnexpectedEOF {
+			t.Errorf("%s: truncated: got %v, want %v", tc.name, err, io.ErrUnexpectedEOF)
+		}
+		// RLE images cannot be top-down.
+		data = bmpFile(6, -3, tc.bpp, tc.compression, testPalette, tc.data)
+		if _, err := Decode(bytes.NewReader(data)); err != ErrUnsupported {
+			t.Errorf("%s: top-down: got %v, want %v", tc.name, err, ErrUnsupported)
+		}
+	}
+}
+
+// bitFieldsFile returns a BMP file with an infoLen byte long header, the
+// given masks and the given pixel data. The masks follow the header if it is
+// a BITMAPINFOHEADER, and are part of the header otherwise.
+func bitFieldsFile(width, height, bpp int, infoLen int, compression uint32, masks []uint32, data []byte) []byte {
+	offset := 14 + infoLen
+	if infoLen == 40 {
+		offset += 4 * len(masks)
+	}
+	b := make([]byte, offset, offset+len(data))
+	copy(b, "BM")
+	binary.LittleEndian.PutUint32(b[2:], uint32(offset+len(data)))
+	binary.LittleEndian.PutUint32(b[10:], uint32(offset))
+	binary.LittleEndian.PutUint32(b[14:], uint32(infoLen))
+	binary.LittleEndian.PutUint32(b[18:], uint32(int32(width)))
+	binary.LittleEndian.PutUint32(b[22:], uint32(int32(height)))
+	binary.LittleEndian.PutUint16(b[26:], 1)
+	binary.LittleEndian.PutUint16(b[28:], uint16(bpp))
+	binary.LittleEndian.PutUint32(b[30:], compression)
+	binary.LittleEndian.PutUint32(b[34:], uint32(len(data)))
+	for i, m := range masks {
+		binary.LittleEndian.PutUint32(b[54+4*i:], m)
+	}
+	return append(b, data...)
+}
+
+// TestDecodeBitFields tests decoding 16 and 32 bit-per-pixel images with
+// default and explicit color masks.
+func TestDecodeBitFields(t *testing.T) {
+	testCases := []struct {
+		name        string
+		bpp         int
+		infoLen     int
+		compression uint32
+		masks       []uint32
+		// data holds the two pixels of a 2x1 image, padded to 4 bytes.
+		data []byte
+		want []color.Color
+	}{{
+		name:        "555",
+		bpp:         16,
+		infoLen:     40,
+		compression: biRGB,
+		// 0x7c00 is full red and 0x03ff is full green and blue.
+		data: []byte{0x00, 0x7c, 0xff, 0x03},
+		want: []color.Color{
+			color.RGBA{0xff, 0x00, 0x00, 0xff},
+			color.RGBA{0x00, 0xff, 0xff, 0xff},
+		},
+	}, {
+		name:        "565",
+		bpp:         16,
+		infoLen:     40,
+		compression: biBitFields,
+		masks:       []uint32{0xf800, 0x07e0, 0x001f},
+		// 0x07e0 is full green and 0x8010 is half red and half blue.
+		data: []byte{0xe0, 0x07, 0x10, 0x80},
+		want: []color.Color{
+			color.RGBA{0x00, 0xff, 0x00, 0xff},
+			color.RGBA{0x84, 0x00, 0x84, 0xff},
+		},
+	}, {
+		name:        "4444 V3",
+		bpp:         16,
+		infoLen:     56,
+		compression: biBitFields,
+		masks:       []uint32{0x0f00, 0x00f0, 0x000f, 0xf000},
+		data:        []byte{0x00, 0x8f, 0x21, 0xf3},
+		want: []color.Color{
+			color.NRGBA{0xff, 0x00, 0x00, 0x88},
+			color.NRGBA{0x33, 0x22, 0x11, 0xff},
+		},
+	}, {
+		name:        "XRGB",
+		bpp:         32,
+		infoLen:     40,
+		compression: biBitFields,
+		masks:       []uint32{0xff0000, 0xff00, 0xff},
+		// The unused byte is ignored.
+		data: []byte{0x01, 0x02, 0x03, 0x00, 0x04, 0x05, 0x06, 0x07},
+		want: []color.Color{
+			color.RGBA{0x03, 0x02, 0x01, 0xff},
+			color.RGBA{0x06, 0x05, 0x04, 0xff},
+		},
+	}, {
+		name:        "RGBA alpha bitfields",
+		bpp:         32,
+		infoLen:     40,
+		compression: biAlphaBitFields,
+		masks:       []uint32{0xff000000, 0xff0000, 0xff00, 0xff},
+		data:        []byte{0x80, 0x03, 0x02, 0x01, 0xff, 0x06, 0x05, 0x04},
+		want: []color.Color{
+			color.NRGBA{0x01, 0x02, 0x03, 0x80},
+			color.NRGBA{0x04, 0x05, 0x06, 0xff},
+		},
+	}, {
+		name:        "BGRA V5",
+		bpp:         32,
+		infoLen:     124,
+		compression: biBitFields,
+		masks:       []uint32{0xff0000, 0xff00, 0xff, 0xff000000},
+		data:        []byte{0x01, 0x02, 0x03, 0x80, 0x04, 0x05, 0x06, 0x00},
+		want: []color.Color{
+			color.NRGBA{0x03, 0x02, 0x01, 0x80},
+			color.NRGBA{0x06, 0x05, 0x04, 0x00},
+		},
+	}}
+
+	for _, tc := range testCases {
+		data := bitFieldsFile(2, 1, tc.bpp, tc.infoLen, tc.compression, tc.masks, tc.data)
+		m, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		for x, want := range tc.want {
+			if got := m.At(x, 0); got != want {
+				t.Errorf("%s: pixel #%d: got %v (%T), want %v (%T)", tc.name, x, got, got, want, want)
+			}
+		}
+		c, err := DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: DecodeConfig: %v", tc.name, err)
+			continue
+		}
+		if got, want := c.ColorModel, m.ColorModel(); got != want {
+			t.Errorf("%s: DecodeConfig: got color model %v, want %v", tc.name, got, want)
+		}
+	}
+
+	// Masks must be contiguous.
+	data := bitFieldsFile(2, 1, 16, 40, biBitFields, []uint32{0xf00f, 0x07e0, 0x0010}, make([]byte, 4))
+	if _, err := Decode(bytes.NewReader(data)); err != ErrUnsupported {
+		t.Errorf("non-contiguous mask: got %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if _, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+		Limits: &imagelimit.Limits{MaxWidth: 30, MaxHeight: 20, MaxMemory: 30 * 20 * 4},
+	}); err != nil {
+		t.Errorf("within limits: %v", err)
+	}
+	for _, l := range []imagelimit.Limits{
+		{MaxWidth: 29},
+		{MaxHeight: 19},
+		{MaxPixels: 599},
+		{MaxMemory: 30*20*4 - 1},
+	} {
+		_, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Limits: &l})
+		if _, ok := err.(*imagelimit.Error); !ok {
+			t.Errorf("%+v: got %v, want an *imagelimit.Error", l, err)
+		}
+	}
+
+	// The limits are checked before the pixel data is read, so a header
+	// that declares a huge image is rejected without allocating its pixels.
+	huge := append([]byte(nil), data[:54]...)
+	binary.LittleEndian.PutUint32(huge[18:], 1<<20)
+	binary.LittleEndian.PutUint32(huge[22:], 1<<20)
+	_, err := DecodeWithOptions(bytes.NewReader(huge), &DecodeOptions{
+		Limits: &imagelimit.Limits{MaxPixels: 1 << 24},
+	})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "pixels" {
+		t.Errorf("huge: got %v, want a pixels *imagelimit.Error", err)
+	}
+}
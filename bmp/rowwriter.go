@@ -0,0 +1,155 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bmp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+
+	"golang.org/x/image/scanline"
+)
+
+// NewRowWriter returns a scanline.Writer that writes a width by height image
+// to w in BMP format, a band of rows at a time. The image is stored top-down,
+// so that each row is written as soon as it is available.
+//
+// The pixel format depends on model: color.GrayModel and color.Palette models
+// mean 8 bit paletted images, color.RGBAModel and color.NRGBAModel mean 32 bit
+// images with alpha, and other models mean 24 bit opaque images. Rows are
+// converted to that model as needed, except that the indexes of an
+// *image.Paletted are written as is.
+func NewRowWriter(w io.Writer, width, height int, model color.Model) (scanline.Writer, error) {
+	if width < 0 || height < 0 {
+		return nil, errors.New("bmp: negative bounds")
+	}
+	r := image.Rect(0, 0, width, 1)
+	z := &rowWriter{
+		w:      w,
+		width:  width,
+		height: height,
+	}
+	bpp := uint16(24)
+	var palette []byte
+	if p, ok := model.(color.Palette); ok {
+		bpp, palette = 8, bmpPalette(p)
+		z.row = image.NewPaletted(r, p)
+	} else {
+		switch model {
+		case color.GrayModel:
+			bpp, palette = 8, grayPalette()
+			z.row = image.NewGray(r)
+		case color.RGBAModel:
+			bpp = 32
+			z.row = image.NewRGBA(r)
+		case color.NRGBAModel:
+			bpp = 32
+			z.row = image.NewNRGBA(r)
+		default:
+			z.row = image.NewRGBA(r)
+		}
+	}
+	h, step := newHeader(width, -height, bpp, palette)
+	z.step, z.opaque = step, bpp == 24
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		return nil, err
+	}
+	if palette != nil {
+		if _, err := w.Write(palette); err != nil {
+			return nil, err
+		}
+	}
+	return z, nil
+}
+
+// rowWriter is the scanline.Writer returned by NewRowWriter.
+type rowWriter struct {
+	w             io.Writer
+	width, height int
+	step          int
+	opaque        bool
+	// row is a one row image of the type being encoded, for converting rows
+	// of other types.
+	row image.Image
+	// y is the number of rows written so far.
+	y   int
+	err error
+}
+
+func (z *rowWriter) WriteRows(m image.Image) error {
+	if z.err != nil {
+		return z.err
+	}
+	b := m.Bounds()
+	if b.Dx() != z.width {
+		return errors.New("bmp: row width does not match the image width")
+	}
+	if b.Dy() > z.height-z.y {
+		return errors.New("bmp: too many rows")
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if z.err = z.writeRow(m, y); z.err != nil {
+			return z.err
+		}
+		z.y++
+	}
+	return nil
+}
+
+// writeRow writes m's row y, converting it to z.row's type if necessary.
+func (z *rowWriter) writeRow(m image.Image, y int) error {
+	b := m.Bounds()
+	switch row := z.row.(type) {
+	case *image.Gray:
+		if m, ok := m.(*image.Gray); ok {
+			return encodePaletted(z.w, m.Pix[m.PixOffset(b.Min.X, y):], z.width, 1, m.Stride, z.step)
+		}
+		z.convert(m, y)
+		return encodePaletted(z.w, row.Pix, z.width, 1, row.Stride, z.step)
+	case *image.Paletted:
+		if m, ok := m.(*image.Paletted); ok {
+			return encodePaletted(z.w, m.Pix[m.PixOffset(b.Min.X, y):], z.width, 1, m.Stride, z.step)
+		}
+		z.convert(m, y)
+		return encodePaletted(z.w, row.Pix, z.width, 1, row.Stride, z.step)
+	case *image.NRGBA:
+		if m, ok := m.(*image.NRGBA); ok {
+			return encodeNRGBA(z.w, m.Pix[m.PixOffset(b.Min.X, y):], z.width, 1, m.Stride, z.step, false)
+		}
+		z.convert(m, y)
+		return encodeNRGBA(z.w, row.Pix, z.width, 1, row.Stride, z.step, false)
+	case *image.RGBA:
+		if m, ok := m.(*image.RGBA); ok {
+			return encodeRGBA(z.w, m.Pix[m.PixOffset(b.Min.X, y):], z.width, 1, m.Stride, z.step, z.opaque)
+		}
+		z.convert(m, y)
+		return encodeRGBA(z.w, row.Pix, z.width, 1, row.Stride, z.step, z.opaque)
+	}
+	panic("unreachable")
+}
+
+// convert sets z.row to m's row y.
+func (z *rowWriter) convert(m image.Image, y int) {
+	dst := z.row.(interface {
+		Set(x, y int, c color.Color)
+	})
+	b := m.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		dst.Set(x-b.Min.X, 0, m.At(x, y))
+	}
+}
+
+func (z *rowWriter) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.y != z.height {
+		return errors.New("bmp: not enough rows")
+	}
+	z.err = errors.New("bmp: RowWriter is closed")
+	return nil
+}
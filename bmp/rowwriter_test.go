@@ -0,0 +1,94 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bmp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestRowWriter(t *testing.T) {
+	src, err := openImage("yellow_rose-small.bmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := src.Bounds()
+	palette := color.Palette{color.Black, color.White, color.RGBA{0xff, 0, 0, 0xff}}
+	testCases := []struct {
+		desc  string
+		model color.Model
+		dst   draw.Image
+	}{
+		{"gray", color.GrayModel, image.NewGray(b)},
+		{"paletted", palette, image.NewPaletted(b, palette)},
+		{"rgba", color.RGBAModel, image.NewRGBA(b)},
+		{"nrgba", color.NRGBAModel, image.NewNRGBA(b)},
+		{"other", color.RGBA64Model, image.NewRGBA64(b)},
+	}
+	for _, tc := range testCases {
+		// want is what Encode writes for the image converted to the model.
+		draw.Draw(tc.dst, b, src, b.Min, draw.Src)
+		buf := new(bytes.Buffer)
+		if err := Encode(buf, tc.dst); err != nil {
+			t.Fatalf("%s: Encode: %v", tc.desc, err)
+		}
+		want, err := Decode(buf)
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tc.desc, err)
+		}
+
+		// Write the unconverted src, and the converted image, in bands of 5
+		// rows.
+		for _, m := range []image.Image{src, tc.dst} {
+			buf := new(bytes.Buffer)
+			z, err := NewRowWriter(buf, b.Dx(), b.Dy(), tc.model)
+			if err != nil {
+				t.Fatalf("%s: NewRowWriter: %v", tc.desc, err)
+			}
+			for y := b.Min.Y; y < b.Max.Y; y += 5 {
+				r := image.Rect(b.Min.X, y, b.Max.X, y+5).Intersect(b)
+				band := m.(interface {
+					SubImage(image.Rectangle) image.Image
+				}).SubImage(r)
+				if err := z.WriteRows(band); err != nil {
+					t.Fatalf("%s: WriteRows: %v", tc.desc, err)
+				}
+			}
+			if err := z.Close(); err != nil {
+				t.Fatalf("%s: Close: %v", tc.desc, err)
+			}
+			got, err := Decode(buf)
+			if err != nil {
+				t.Fatalf("%s: Decode: %v", tc.desc, err)
+			}
+			if err := compare(want, got); err != nil {
+				t.Errorf("%s, %T: %v", tc.desc, m, err)
+			}
+		}
+	}
+}
+
+func TestRowWriterErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 10, 10))
+	z, err := NewRowWriter(new(bytes.Buffer), 10, 10, color.GrayModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := z.WriteRows(m.SubImage(image.Rect(0, 0, 9, 5))); err == nil {
+		t.Error("wrong width: got nil error")
+	}
+	if err := z.WriteRows(m.SubImage(image.Rect(0, 0, 10, 5))); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.WriteRows(m); err == nil {
+		t.Error("too many rows: got nil error")
+	}
+	if err := z.Close(); err == nil {
+		t.Error("not enough rows: got nil error")
+	}
+}
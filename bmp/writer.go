@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"image"
+	"image/color"
 	"io"
 )
 
@@ -29,6 +30,26 @@ type header struct {
 	colorImportant  uint32
 }
 
+// v4Fields are the fields that a BITMAPV4HEADER adds to a BITMAPINFOHEADER.
+type v4Fields struct {
+	redMask, greenMask, blueMask, alphaMask uint32
+	csType                                  uint32
+	endpoints                               [36]byte
+	gammaRed, gammaGreen, gammaBlue         uint32
+}
+
+// lcsSRGB is the sRGB color space type, "sRGB" as a big-endian FourCC.
+const lcsSRGB = 0x73524742
+
+// Options are the encoding parameters.
+type Options struct {
+	// V4Header is whether to write a BITMAPV4HEADER instead of a
+	// BITMAPINFOHEADER. The BITMAPV4HEADER declares the sRGB color space,
+	// and the alpha channel of 32 bit images through its BI_BITFIELDS
+	// masks, without which some programs ignore that alpha channel.
+	V4Header bool
+}
+
 func encodePaletted(w io.Writer, pix []uint8, dx, dy, stride, step int) error {
 	var padding []byte
 	if dx < step {
@@ -158,86 +179,121 @@ func encode(w io.Writer, m image.Image, step int) error {
 	return nil
 }
 
-// Encode writes the image m to w in BMP format.
-func Encode(w io.Writer, m image.Image) error {
-	d := m.Bounds().Size()
-	if d.X < 0 || d.Y < 0 {
-		return errors.New("bmp: negative bounds")
+// newHeader returns the header, and the length in bytes of each row including
+// padding, of a width by height image with the given bits per pixel and
+// palette. A negative height means a top-down image.
+func newHeader(width, height int, bpp uint16, palette []byte) (*header, int) {
+	var step int
+	switch bpp {
+	case 8:
+		step = (width + 3) &^ 3
+	case 24:
+		step = (3*width + 3) &^ 3
+	default:
+		step = 4 * width
+	}
+	rows := height
+	if rows < 0 {
+		rows = -rows
 	}
 	h := &header{
 		sigBM:         [2]byte{'B', 'M'},
-		fileSize:      14 + 40,
-		pixOffset:     14 + 40,
+		fileSize:      14 + 40 + uint32(len(palette)+rows*step),
+		pixOffset:     14 + 40 + uint32(len(palette)),
 		dibHeaderSize: 40,
-		width:         uint32(d.X),
-		height:        uint32(d.Y),
+		width:         uint32(width),
+		height:        uint32(height),
 		colorPlane:    1,
+		bpp:           bpp,
+		imageSize:     uint32(rows * step),
 	}
+	return h, step
+}
 
-	var step int
+// grayPalette returns the BMP palette for an 8 bit grayscale image.
+func grayPalette() []byte {
+	palette := make([]byte, 1024)
+	for i := 0; i < 256; i++ {
+		palette[i*4+0] = uint8(i)
+		palette[i*4+1] = uint8(i)
+		palette[i*4+2] = uint8(i)
+		palette[i*4+3] = 0xFF
+	}
+	return palette
+}
+
+// bmpPalette returns the BMP palette for an 8 bit paletted image.
+func bmpPalette(p color.Palette) []byte {
+	palette := make([]byte, 1024)
+	for i := 0; i < len(p) && i < 256; i++ {
+		r, g, b, _ := p[i].RGBA()
+		palette[i*4+0] = uint8(b >> 8)
+		palette[i*4+1] = uint8(g >> 8)
+		palette[i*4+2] = uint8(r >> 8)
+		palette[i*4+3] = 0xFF
+	}
+	return palette
+}
+
+// Encode writes the image m to w in BMP format.
+func Encode(w io.Writer, m image.Image) error {
+	return EncodeWithOptions(w, m, nil)
+}
+
+// EncodeWithOptions is like Encode but with optional parameters. A nil opts
+// is equivalent to a zero Options.
+func EncodeWithOptions(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	d := m.Bounds().Size()
+	if d.X < 0 || d.Y < 0 {
+		return errors.New("bmp: negative bounds")
+	}
+
+	bpp := uint16(24)
 	var palette []byte
 	var opaque bool
 	switch m := m.(type) {
 	case *image.Gray:
-		step = (d.X + 3) &^ 3
-		palette = make([]byte, 1024)
-		for i := 0; i < 256; i++ {
-			palette[i*4+0] = uint8(i)
-			palette[i*4+1] = uint8(i)
-			palette[i*4+2] = uint8(i)
-			palette[i*4+3] = 0xFF
-		}
-		h.imageSize = uint32(d.Y * step)
-		h.fileSize += uint32(len(palette)) + h.imageSize
-		h.pixOffset += uint32(len(palette))
-		h.bpp = 8
-
+		bpp, palette = 8, grayPalette()
 	case *image.Paletted:
-		step = (d.X + 3) &^ 3
-		palette = make([]byte, 1024)
-		for i := 0; i < len(m.Palette) && i < 256; i++ {
-			r, g, b, _ := m.Palette[i].RGBA()
-			palette[i*4+0] = uint8(b >> 8)
-			palette[i*4+1] = uint8(g >> 8)
-			palette[i*4+2] = uint8(r >> 8)
-			palette[i*4+3] = 0xFF
-		}
-		h.imageSize = uint32(d.Y * step)
-		h.fileSize += uint32(len(palette)) + h.imageSize
-		h.pixOffset += uint32(len(palette))
-		h.bpp = 8
+		bpp, palette = 8, bmpPalette(m.Palette)
 	case *image.RGBA:
 		opaque = m.Opaque()
-		if opaque {
-			step = (3*d.X + 3) &^ 3
-			h.bpp = 24
-		} else {
-			step = 4 * d.X
-			h.bpp = 32
+		if !opaque {
+			bpp = 32
 		}
-		h.imageSize = uint32(d.Y * step)
-		h.fileSize += h.imageSize
 	case *image.NRGBA:
 		opaque = m.Opaque()
-		if opaque {
-			step = (3*d.X + 3) &^ 3
-			h.bpp = 24
-		} else {
-			step = 4 * d.X
-			h.bpp = 32
+		if !opaque {
+			bpp = 32
+		}
+	}
+	h, step := newHeader(d.X, d.Y, bpp, palette)
+	var v4 *v4Fields
+	if o.V4Header {
+		// The BITMAPV4HEADER is 108 bytes long instead of 40.
+		const extra = 108 - 40
+		h.fileSize += extra
+		h.pixOffset += extra
+		h.dibHeaderSize += extra
+		v4 = &v4Fields{csType: lcsSRGB}
+		if bpp == 32 {
+			h.compression = biBitFields
+			v4.redMask, v4.greenMask, v4.blueMask, v4.alphaMask = 0xff0000, 0xff00, 0xff, 0xff000000
 		}
-		h.imageSize = uint32(d.Y * step)
-		h.fileSize += h.imageSize
-	default:
-		step = (3*d.X + 3) &^ 3
-		h.imageSize = uint32(d.Y * step)
-		h.fileSize += h.imageSize
-		h.bpp = 24
 	}
 
 	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
 		return err
 	}
+	if v4 != nil {
+		if err := binary.Write(w, binary.LittleEndian, v4); err != nil {
+			return err
+		}
+	}
 	if palette != nil {
 		if err := binary.Write(w, binary.LittleEndian, palette); err != nil {
 			return err
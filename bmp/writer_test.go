@@ -141,3 +141,51 @@ func BenchmarkEncode(b *testing.B) {
 		Encode(ioutil.Discard, img)
 	}
 }
+
+func TestEncodeV4Header(t *testing.T) {
+	m0 := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := range m0.Pix {
+		m0.Pix[i] = uint8(40 * i)
+	}
+	var buf bytes.Buffer
+	if err := EncodeWithOptions(&buf, m0, &Options{V4Header: true}); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if got, want := readUint32(b[14:]), uint32(108); got != want {
+		t.Errorf("header length: got %d, want %d", got, want)
+	}
+	if got, want := readUint32(b[30:]), uint32(biBitFields); got != want {
+		t.Errorf("compression: got %d, want %d", got, want)
+	}
+	if got, want := readUint32(b[66:]), uint32(0xff000000); got != want {
+		t.Errorf("alpha mask: got %#x, want %#x", got, want)
+	}
+	if got, want := readUint32(b[10:]), uint32(14+108); got != want {
+		t.Errorf("pixel offset: got %d, want %d", got, want)
+	}
+	m1, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compare(m0, m1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Opaque images have a V4 header without masks.
+	buf.Reset()
+	opaque := image.NewGray(image.Rect(0, 0, 3, 2))
+	if err := EncodeWithOptions(&buf, opaque, &Options{V4Header: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readUint32(buf.Bytes()[30:]), uint32(biRGB); got != want {
+		t.Errorf("opaque: compression: got %d, want %d", got, want)
+	}
+	m1, err = Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compare(opaque, m1); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,7 +4,7 @@
 
 //go:generate go run gen.go
 
-// Package ccitt implements a CCITT (fax) image decoder.
+// Package ccitt implements a CCITT (fax) image decoder and encoder.
 package ccitt
 
 import (
@@ -13,6 +13,9 @@ import (
 	"image"
 	"io"
 	"math/bits"
+
+	"golang.org/x/image/bitmap"
+	"golang.org/x/image/imagelimit"
 )
 
 var (
@@ -59,6 +62,20 @@ type Options struct {
 	Align bool
 	// Invert means that black is the 1 bit or 0xFF byte, and white is 0.
 	Invert bool
+	// K is, for the Group3 sub-format, the K parameter of two-dimensional
+	// coding: when encoding, every K'th row is coded in one dimension and
+	// the others in two dimensions, relative to the row above. Zero means
+	// that every row is coded in one dimension, without the tag bit that
+	// two-dimensional coding adds after each EOL. When decoding, the tag
+	// bits say how each row is coded, so any positive K will do. It is
+	// ignored for the Group4 sub-format.
+	K int
+	// Limits, if non-nil, are the limits on the image that Decode returns,
+	// which are checked before its pixels are allocated. Decode returns an
+	// *imagelimit.Error if the image exceeds them. When the height is not
+	// known in advance, the rows are checked as they are decoded. It is
+	// ignored by the other functions, which do not allocate the image.
+	Limits *imagelimit.Limits
 }
 
 // maxWidth is the maximum (inclusive) supported width. This is a limitation of
@@ -297,8 +314,14 @@ type reader struct {
 	wi int
 
 	// These fields are copied from the *Options (which may be nil).
-	align  bool
-	invert bool
+	// twoDimensional is whether opts.K is positive.
+	align          bool
+	invert         bool
+	twoDimensional bool
+
+	// rowIs2D is whether the next Group3 row is coded in two dimensions, as
+	// given by the tag bit after the previous EOL.
+	rowIs2D bool
 
 	// atStartOfRow is whether we have just started the row. Some parts of the
 	// spec say to treat this situation as if "wi = -1".
@@ -413,6 +436,32 @@ func (z *reader) Read(p []byte) (int, error) {
 	return n, z.readErr
 }
 
+// WriteTo implements the io.WriterTo interface. Unlike io.Copy with an
+// arbitrary buffer, it only writes whole rows to w.
+func (z *reader) WriteTo(w io.Writer) (int64, error) {
+	rowBytes := (z.width + 7) / 8
+	if rowBytes == 0 {
+		rowBytes = 1
+	}
+	buf := make([]byte, rowBytes*((4096+rowBytes-1)/rowBytes))
+	total := int64(0)
+	for {
+		n, err := io.ReadFull(z, buf)
+		if n > 0 {
+			nw, werr := w.Write(buf[:n])
+			total += int64(nw)
+			if werr != nil {
+				return total, werr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return total, nil
+		} else if err != nil {
+			return total, err
+		}
+	}
+}
+
 func (z *reader) penColor() byte {
 	if z.penColorIsWhite {
 		return 0xFF
@@ -485,7 +534,22 @@ func (z *reader) finishDecode(alreadySeenEOL bool) error {
 }
 
 func (z *reader) decodeEOL() error {
-	return decodeEOL(&z.br)
+	if err := decodeEOL(&z.br); err != nil {
+		return err
+	}
+	if (z.subFormat != Group3) || !z.twoDimensional {
+		return nil
+	}
+	// With two-dimensional coding, the EOL is followed by the tag bit.
+	bit, err := z.br.nextBit()
+	if err != nil {
+		if err == io.EOF {
+			err = errIncompleteCode
+		}
+		return err
+	}
+	z.rowIs2D = bit == 0
+	return nil
 }
 
 func (z *reader) decodeRow(finalRow bool) error {
@@ -499,10 +563,16 @@ func (z *reader) decodeRow(finalRow bool) error {
 
 	switch z.subFormat {
 	case Group3:
-		for ; z.wi < len(z.curr); z.atStartOfRow = false {
-			if err := z.decodeRun(); err != nil {
+		if z.rowIs2D {
+			if err := z.decodeModes(); err != nil {
 				return err
 			}
+		} else {
+			for ; z.wi < len(z.curr); z.atStartOfRow = false {
+				if err := z.decodeRun(); err != nil {
+					return err
+				}
+			}
 		}
 		err := z.decodeEOL()
 		if finalRow && (err == errMissingEOL) {
@@ -512,28 +582,34 @@ func (z *reader) decodeRow(finalRow bool) error {
 		return err
 
 	case Group4:
-		for ; z.wi < len(z.curr); z.atStartOfRow = false {
-			mode, err := decode(&z.br, modeDecodeTable[:])
-			if err != nil {
-				return err
-			}
-			rm := readerMode{}
-			if mode < uint32(len(readerModes)) {
-				rm = readerModes[mode]
-			}
-			if rm.function == nil {
-				return errInvalidMode
-			}
-			if err := rm.function(z, rm.arg); err != nil {
-				return err
-			}
-		}
-		return nil
+		return z.decodeModes()
 	}
 
 	return errUnsupportedSubFormat
 }
 
+// decodeModes decodes a row coded in two dimensions, relative to the
+// previous row.
+func (z *reader) decodeModes() error {
+	for ; z.wi < len(z.curr); z.atStartOfRow = false {
+		mode, err := decode(&z.br, modeDecodeTable[:])
+		if err != nil {
+			return err
+		}
+		rm := readerMode{}
+		if mode < uint32(len(readerModes)) {
+			rm = readerModes[mode]
+		}
+		if rm.function == nil {
+			return errInvalidMode
+		}
+		if err := rm.function(z, rm.arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (z *reader) decodeRun() error {
 	table := blackDecodeTable[:]
 	if z.penColorIsWhite {
@@ -622,16 +698,22 @@ func (z *reader) findB(whichB bool) int {
 	if len(z.prev) != len(z.curr) {
 		return len(z.curr)
 	}
+	return findB(z.prev, z.wi, z.penColor(), z.atStartOfRow, whichB)
+}
 
-	i := z.wi
+// findB finds either the b1 or b2 value in the previous row prev, given the
+// a0 index and the color of the pixel there. It is shared by the reader and
+// the writer, so that they agree on every changing element.
+func findB(prev []byte, a0 int, penColor byte, atStartOfRow bool, whichB bool) int {
+	i := a0
 
-	if z.atStartOfRow {
+	if atStartOfRow {
 		// a0 is implicitly at -1, on a white pixel. b1 is the first black
 		// pixel in the previous row. b2 is the first white pixel after that.
-		for ; (i < len(z.prev)) && (z.prev[i] == 0xFF); i++ {
+		for ; (i < len(prev)) && (prev[i] == 0xFF); i++ {
 		}
 		if whichB == findB2 {
-			for ; (i < len(z.prev)) && (z.prev[i] == 0x00); i++ {
+			for ; (i < len(prev)) && (prev[i] == 0x00); i++ {
 			}
 		}
 		return i
@@ -639,21 +721,19 @@ func (z *reader) findB(whichB bool) int {
 
 	// As per figure 1 above, assume that the current pen color is white.
 	// First, walk past every contiguous black pixel in prev, starting at a0.
-	oppositeColor := ^z.penColor()
-	for ; (i < len(z.prev)) && (z.prev[i] == oppositeColor); i++ {
+	oppositeColor := ^penColor
+	for ; (i < len(prev)) && (prev[i] == oppositeColor); i++ {
 	}
 
 	// Then walk past every contiguous white pixel.
-	penColor := ^oppositeColor
-	for ; (i < len(z.prev)) && (z.prev[i] == penColor); i++ {
+	for ; (i < len(prev)) && (prev[i] == penColor); i++ {
 	}
 
 	// We're now at a black pixel (or at the end of the row). That's b1.
 	if whichB == findB2 {
 		// If we're looking for b2, walk past every contiguous black pixel
 		// again.
-		oppositeColor := ^penColor
-		for ; (i < len(z.prev)) && (z.prev[i] == oppositeColor); i++ {
+		for ; (i < len(prev)) && (prev[i] == oppositeColor); i++ {
 		}
 	}
 
@@ -740,6 +820,8 @@ func DecodeIntoGray(dst *image.Gray, r io.Reader, order Order, sf SubFormat, opt
 		align:     (opts != nil) && opts.Align,
 		invert:    (opts != nil) && opts.Invert,
 		width:     bounds.Dx(),
+
+		twoDimensional: (opts != nil) && (opts.K > 0),
 	}
 	if err := z.startDecode(); err != nil {
 		return err
@@ -769,6 +851,143 @@ func DecodeIntoGray(dst *image.Gray, r io.Reader, order Order, sf SubFormat, opt
 	return nil
 }
 
+// DecodeIntoBitmap decodes the CCITT-formatted data in r into dst, whose
+// width and height are those of the CCITT-formatted data. Unless opts.Invert
+// is set, the 1 bits of dst are white. The rows are decoded straight into
+// dst's packed pixels when dst.Rect.Min.X is a multiple of 8.
+func DecodeIntoBitmap(dst *bitmap.Image, r io.Reader, order Order, sf SubFormat, opts *Options) error {
+	bounds := dst.Bounds()
+	if (bounds.Dx() < 0) || (bounds.Dy() < 0) {
+		return errInvalidBounds
+	}
+	if bounds.Dx() > maxWidth {
+		return errUnsupportedWidth
+	}
+
+	z := NewReader(r, order, sf, bounds.Dx(), bounds.Dy(), opts)
+	rowBytes := (bounds.Dx() + 7) / 8
+	var row []byte
+	if bounds.Min.X&7 != 0 {
+		row = make([]byte, rowBytes)
+	}
+	for y := bounds.Min.Y; (y < bounds.Max.Y) && (rowBytes > 0); y++ {
+		p := dst.PixOffset(bounds.Min.X, y)
+		if row == nil {
+			// The final byte's trailing bits, beyond bounds.Max.X, are
+			// preserved.
+			last := dst.Pix[p+rowBytes-1]
+			if _, err := io.ReadFull(z, dst.Pix[p:p+rowBytes]); err != nil {
+				return err
+			}
+			if n := uint(bounds.Dx() & 7); n != 0 {
+				keep := uint8(0xff >> n)
+				dst.Pix[p+rowBytes-1] = dst.Pix[p+rowBytes-1]&^keep | last&keep
+			}
+			continue
+		}
+		if _, err := io.ReadFull(z, row); err != nil {
+			return err
+		}
+		for i := 0; i < bounds.Dx(); i++ {
+			dst.SetBit(bounds.Min.X+i, y, row[i>>3]&(0x80>>uint(i&7)) != 0)
+		}
+	}
+	// Consume the end-of-image codes.
+	if _, err := z.Read(make([]byte, 1)); err != io.EOF {
+		if err == nil {
+			err = errMissingEOL
+		}
+		return err
+	}
+	return nil
+}
+
+// Decode decodes the CCITT-formatted data in r into a new image of the given
+// width, whose pixels are 0x00 (black) or 0xFF (white), or the other way
+// around if opts.Invert is set.
+//
+// A negative height, such as passing AutoDetectHeight, means that the image
+// height is not known in advance, in which case the image has as many rows as
+// the data holds before its end-of-image codes. Either way, the rows are
+// decoded straight into the image, without going through an io.Reader.
+func Decode(r io.Reader, order Order, sf SubFormat, width int, height int, opts *Options) (*image.Gray, error) {
+	if width < 0 {
+		return nil, errInvalidBounds
+	}
+	var limits *imagelimit.Limits
+	if opts != nil {
+		limits = opts.Limits
+	}
+	if height >= 0 {
+		if err := limits.Check(width, height, 8); err != nil {
+			return nil, err
+		}
+		m := image.NewGray(image.Rect(0, 0, width, height))
+		if err := DecodeIntoGray(m, r, order, sf, opts); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if width > maxWidth {
+		return nil, errUnsupportedWidth
+	}
+	if err := limits.Check(width, 0, 8); err != nil {
+		return nil, err
+	}
+
+	z := reader{
+		br:        bitReader{r: r, order: order},
+		subFormat: sf,
+		align:     (opts != nil) && opts.Align,
+		invert:    (opts != nil) && opts.Invert,
+		width:     width,
+
+		twoDimensional: (opts != nil) && (opts.K > 0),
+	}
+	if err := z.startDecode(); err != nil {
+		return nil, err
+	}
+
+	pix, rows := []byte(nil), 0
+	for {
+		// As for reader.Read, see if the next code is an EOL, which starts the
+		// end-of-image codes.
+		if z.align && (z.subFormat == Group4) {
+			z.br.alignToByteBoundary()
+		}
+		if err := z.decodeEOL(); err == nil {
+			if err := z.finishDecode(true); err != nil {
+				return nil, err
+			}
+			break
+		} else if err != errMissingEOL {
+			return nil, err
+		}
+
+		if err := limits.Check(width, rows+1, 8); err != nil {
+			return nil, err
+		}
+		pix = append(pix, make([]byte, width)...)
+		z.curr = pix[rows*width:]
+		if err := z.decodeRow(false); err != nil {
+			return nil, err
+		}
+		// z.prev may point into a previous backing array of pix, which is
+		// fine, as it is only read.
+		z.curr, z.prev = nil, z.curr
+		rows++
+	}
+
+	if z.invert {
+		invertBytes(pix)
+	}
+	return &image.Gray{
+		Pix:    pix,
+		Stride: width,
+		Rect:   image.Rect(0, 0, width, rows),
+	}, nil
+}
+
 // NewReader returns an io.Reader that decodes the CCITT-formatted data in r.
 // The resultant byte stream is one bit per pixel (MSB first), with 1 meaning
 // white and 0 meaning black. Each row in the result is byte-aligned.
@@ -791,5 +1010,7 @@ func NewReader(r io.Reader, order Order, sf SubFormat, width int, height int, op
 		width:         width,
 		rowsRemaining: height,
 		readErr:       readErr,
+
+		twoDimensional: (opts != nil) && (opts.K > 0),
 	}
 }
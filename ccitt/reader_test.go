@@ -18,6 +18,9 @@ import (
 	"strings"
 	"testing"
 	"unsafe"
+
+	"golang.org/x/image/bitmap"
+	"golang.org/x/image/imagelimit"
 )
 
 func compareImages(t *testing.T, img0 image.Image, img1 image.Image) {
@@ -460,3 +463,140 @@ func testDecodeIntoGray(t *testing.T, fileName string, order Order, sf SubFormat
 
 	compareImages(t, got, want)
 }
+
+func TestDecode(t *testing.T) {
+	want, err := decodePNG("testdata/bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		fileName string
+		sf       SubFormat
+		align    bool
+	}{
+		{"testdata/bw-gopher.ccitt_group3", Group3, false},
+		{"testdata/bw-gopher-aligned.ccitt_group3", Group3, true},
+		{"testdata/bw-gopher.ccitt_group4", Group4, false},
+		{"testdata/bw-gopher-aligned.ccitt_group4", Group4, true},
+	} {
+		data, err := ioutil.ReadFile(tt.fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := &Options{Align: tt.align}
+		for _, height := range []int{55, AutoDetectHeight} {
+			got, err := Decode(bytes.NewReader(data), MSB, tt.sf, 153, height, opts)
+			if err != nil {
+				t.Fatalf("%s: height=%d: %v", tt.fileName, height, err)
+			}
+			compareImages(t, got, want)
+		}
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bw-gopher.ccitt_group4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, height := range []int{55, AutoDetectHeight} {
+		opts := &Options{Limits: &imagelimit.Limits{MaxPixels: 153 * 55}}
+		if _, err := Decode(bytes.NewReader(data), MSB, Group4, 153, height, opts); err != nil {
+			t.Errorf("height=%d: within limits: %v", height, err)
+		}
+		for _, l := range []imagelimit.Limits{
+			{MaxWidth: 152},
+			{MaxHeight: 54},
+			{MaxPixels: 153*55 - 1},
+			{MaxMemory: 153*55 - 1},
+		} {
+			opts.Limits = &l
+			_, err := Decode(bytes.NewReader(data), MSB, Group4, 153, height, opts)
+			if _, ok := err.(*imagelimit.Error); !ok {
+				t.Errorf("height=%d: %+v: got %v, want an *imagelimit.Error", height, l, err)
+			}
+		}
+	}
+
+	// A declared height is checked before decoding, however little data
+	// there is.
+	_, err = Decode(bytes.NewReader(nil), MSB, Group4, 1<<20, 1<<20, &Options{
+		Limits: &imagelimit.Limits{MaxMemory: 1 << 30},
+	})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "memory" {
+		t.Errorf("huge: got %v, want a memory *imagelimit.Error", err)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/bw-gopher.ccitt_group4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadAll(NewReader(bytes.NewReader(data), MSB, Group4, 153, 55, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReader(bytes.NewReader(data), MSB, Group4, 153, 55, nil)
+	if _, ok := r.(io.WriterTo); !ok {
+		t.Fatal("reader does not implement io.WriterTo")
+	}
+	var got bytes.Buffer
+	if n, err := io.Copy(&got, r); err != nil || n != int64(len(want)) {
+		t.Fatalf("io.Copy: got %d, %v, want %d, nil", n, err, len(want))
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Error("WriteTo and Read produced different output")
+	}
+}
+
+func TestDecodeIntoBitmap(t *testing.T) {
+	want, err := decodePNG("testdata/bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		fileName string
+		sf       SubFormat
+	}{
+		{"testdata/bw-gopher.ccitt_group3", Group3},
+		{"testdata/bw-gopher.ccitt_group4", Group4},
+	} {
+		data, err := ioutil.ReadFile(tt.fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The bitmaps are set to white beforehand, to check that the bits
+		// beyond the right edge are preserved. One has a left edge that is not
+		// a multiple of 8.
+		for _, minX := range []int{0, 3} {
+			r := image.Rect(minX, 0, minX+153, 55)
+			dst := bitmap.New(image.Rect(minX-8, 0, minX+153+8, 55))
+			for i := range dst.Pix {
+				dst.Pix[i] = 0xff
+			}
+			sub := dst.SubImage(r).(*bitmap.Image)
+			if err := DecodeIntoBitmap(sub, bytes.NewReader(data), MSB, tt.sf, nil); err != nil {
+				t.Fatalf("%s: minX=%d: %v", tt.fileName, minX, err)
+			}
+			compareImages(t, sub.Gray(), translate(want, r.Min))
+			for y := 0; y < 55; y++ {
+				if !dst.BitAt(r.Max.X, y) || !dst.BitAt(r.Min.X-1, y) {
+					t.Fatalf("%s: minX=%d: row %d: pixels beyond the edges were changed", tt.fileName, minX, y)
+				}
+			}
+		}
+	}
+}
+
+// translate returns a copy of m translated so that its bounds start at p.
+func translate(m image.Image, p image.Point) image.Image {
+	b := m.Bounds()
+	dst := image.NewGray(b.Sub(b.Min).Add(p))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(x-b.Min.X+p.X, y-b.Min.Y+p.Y, m.At(x, y))
+		}
+	}
+	return dst
+}
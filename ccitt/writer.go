@@ -6,7 +6,11 @@ package ccitt
 
 import (
 	"encoding/binary"
+	"image"
+	"image/color"
 	"io"
+
+	"golang.org/x/image/bitmap"
 )
 
 type bitWriter struct {
@@ -100,3 +104,227 @@ func (b *bitWriter) writeCode(bs bitString) error {
 	b.nBits = nBits
 	return nil
 }
+
+// eolCode is the 12-bit EOL code 0000_0000_0001.
+var eolCode = bitString{0x0001, 12}
+
+// Encode writes the image m to w as CCITT-formatted data, which DecodeIntoGray
+// and NewReader decode given the same order, sub-format and options. Pixels
+// whose gray level is less than half are black, and the others white.
+//
+// With the Group3 sub-format, rows are coded in one dimension (Modified
+// Huffman coding), unless opts.K is positive, in which case every K'th row is
+// coded in one dimension and the others in two dimensions (Modified READ
+// coding). With the Group4 sub-format, every row is coded in two dimensions
+// (Modified Modified READ coding), which is the most compact. The Invert
+// option only applies to decoding and is ignored.
+func Encode(w io.Writer, m image.Image, order Order, sf SubFormat, opts *Options) error {
+	if sf != Group3 && sf != Group4 {
+		return errUnsupportedSubFormat
+	}
+	bounds := m.Bounds()
+	if bounds.Dx() > maxWidth {
+		return errUnsupportedWidth
+	}
+	align := (opts != nil) && opts.Align
+	k := 0
+	if (opts != nil) && (sf == Group3) && (opts.K > 0) {
+		k = opts.K
+	}
+
+	b := &bitWriter{w: w, order: order}
+	// writeEOL writes an EOL and, with two-dimensional Group3 coding, the tag
+	// bit that says whether the next row is coded in two dimensions.
+	writeEOL := func(next2D bool) error {
+		if err := b.writeCode(eolCode); err != nil {
+			return err
+		}
+		if k == 0 {
+			return nil
+		}
+		tag := bitString{1, 1}
+		if next2D {
+			tag.bits = 0
+		}
+		return b.writeCode(tag)
+	}
+
+	if sf == Group3 {
+		if err := writeEOL(false); err != nil {
+			return err
+		}
+	}
+	// The row above the first row is implicitly all white.
+	curr, prev := make([]byte, bounds.Dx()), make([]byte, bounds.Dx())
+	for i := range prev {
+		prev[i] = 0xFF
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		bilevelRow(curr, m, y)
+		if align {
+			if err := b.alignToByteBoundary(); err != nil {
+				return err
+			}
+		}
+		n := y - bounds.Min.Y
+		if (sf == Group4) || ((k > 0) && (n%k != 0)) {
+			if err := writeModes(b, curr, prev); err != nil {
+				return err
+			}
+		} else {
+			if err := writeRuns(b, curr); err != nil {
+				return err
+			}
+		}
+		if sf == Group3 {
+			if err := writeEOL((k > 0) && ((n+1)%k != 0) && (y+1 < bounds.Max.Y)); err != nil {
+				return err
+			}
+		}
+		curr, prev = prev, curr
+	}
+
+	if sf == Group3 {
+		// The stream ends with a RTC (Return To Control) of 6 consecutive
+		// EOL's, the first of which ended the final row.
+		for i := 0; i < 5; i++ {
+			if err := writeEOL(false); err != nil {
+				return err
+			}
+		}
+	} else {
+		// The stream ends with an EOFB (End Of Facsimile Block) of 2
+		// consecutive EOL's.
+		if align {
+			if err := b.alignToByteBoundary(); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < 2; i++ {
+			if err := b.writeCode(eolCode); err != nil {
+				return err
+			}
+		}
+	}
+	return b.close()
+}
+
+// writeRuns codes row in one dimension, as a sequence of runs of alternating
+// colors, starting with a (possibly empty) white run.
+func writeRuns(b *bitWriter, row []byte) error {
+	penColorIsWhite := true
+	for i := 0; i < len(row); {
+		penColor := byte(0x00)
+		if penColorIsWhite {
+			penColor = 0xFF
+		}
+		j := i
+		for ; (j < len(row)) && (row[j] == penColor); j++ {
+		}
+		if err := writeRun(b, j-i, penColorIsWhite); err != nil {
+			return err
+		}
+		i, penColorIsWhite = j, !penColorIsWhite
+	}
+	return nil
+}
+
+// verticalModes are the modes for a1 being between 3 pixels left of b1 and 3
+// pixels right of b1.
+var verticalModes = [7]int{modeVL3, modeVL2, modeVL1, modeV0, modeVR1, modeVR2, modeVR3}
+
+// writeModes codes curr in two dimensions, relative to prev. It mirrors the
+// reader's decodeRow, using the same a0, a1, a2, b1 and b2 changing elements.
+func writeModes(b *bitWriter, curr []byte, prev []byte) error {
+	a0, penColor, atStartOfRow := 0, byte(0xFF), true
+	for ; a0 < len(curr); atStartOfRow = false {
+		a1 := a0
+		for ; (a1 < len(curr)) && (curr[a1] == penColor); a1++ {
+		}
+		b1 := findB(prev, a0, penColor, atStartOfRow, findB1)
+		b2 := findB(prev, a0, penColor, atStartOfRow, findB2)
+
+		switch {
+		case b2 < a1:
+			if err := b.writeCode(modeEncodeTable[modePass]); err != nil {
+				return err
+			}
+			a0 = b2
+
+		case (b1-3 <= a1) && (a1 <= b1+3):
+			if err := b.writeCode(modeEncodeTable[verticalModes[a1-b1+3]]); err != nil {
+				return err
+			}
+			a0, penColor = a1, ^penColor
+
+		default:
+			a2 := a1
+			for ; (a2 < len(curr)) && (curr[a2] != penColor); a2++ {
+			}
+			if err := b.writeCode(modeEncodeTable[modeH]); err != nil {
+				return err
+			}
+			if err := writeRun(b, a1-a0, penColor == 0xFF); err != nil {
+				return err
+			}
+			if err := writeRun(b, a2-a1, penColor != 0xFF); err != nil {
+				return err
+			}
+			a0 = a2
+		}
+	}
+	return nil
+}
+
+// bilevelRow sets dst to row y of m, with 0x00 for black pixels and 0xFF for
+// white pixels, like the reader's rows.
+func bilevelRow(dst []byte, m image.Image, y int) {
+	bounds := m.Bounds()
+	if b, ok := m.(*bitmap.Image); ok {
+		row := b.Pix[b.PixOffset(bounds.Min.X, y):]
+		for i := range dst {
+			x := bounds.Min.X + i
+			dst[i] = 0x00
+			if row[x>>3-bounds.Min.X>>3]&(0x80>>uint(x&7)) != 0 {
+				dst[i] = 0xFF
+			}
+		}
+		return
+	}
+	if g, ok := m.(*image.Gray); ok {
+		pix := g.Pix[g.PixOffset(bounds.Min.X, y):]
+		for i := range dst {
+			dst[i] = 0xFF
+			if pix[i] < 0x80 {
+				dst[i] = 0x00
+			}
+		}
+		return
+	}
+	for i := range dst {
+		dst[i] = 0xFF
+		if color.GrayModel.Convert(m.At(bounds.Min.X+i, y)).(color.Gray).Y < 0x80 {
+			dst[i] = 0x00
+		}
+	}
+}
+
+// writeRun writes the codes for a run of n white or black pixels: makeup codes
+// for multiples of 64 pixels, followed by a terminating code.
+func writeRun(b *bitWriter, n int, white bool) error {
+	table2, table3 := blackEncodeTable2[:], blackEncodeTable3[:]
+	if white {
+		table2, table3 = whiteEncodeTable2[:], whiteEncodeTable3[:]
+	}
+	for n >= 64 {
+		k := n / 64
+		if k > len(table3) {
+			k = len(table3)
+		}
+		if err := b.writeCode(table3[k-1]); err != nil {
+			return err
+		}
+		n -= 64 * k
+	}
+	return b.writeCode(table2[n])
+}
@@ -6,8 +6,14 @@ package ccitt
 
 import (
 	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
 	"reflect"
 	"testing"
+
+	"golang.org/x/image/bitmap"
 )
 
 func testEncode(t *testing.T, o Order) {
@@ -65,3 +71,167 @@ func testEncode(t *testing.T, o Order) {
 
 func TestEncodeLSB(t *testing.T) { testEncode(t, LSB) }
 func TestEncodeMSB(t *testing.T) { testEncode(t, MSB) }
+
+func TestEncodeGolden(t *testing.T) {
+	m, err := decodePNG("testdata/bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		fileName string
+		sf       SubFormat
+		align    bool
+	}{
+		{"testdata/bw-gopher.ccitt_group3", Group3, false},
+		{"testdata/bw-gopher-aligned.ccitt_group3", Group3, true},
+		{"testdata/bw-gopher.ccitt_group4", Group4, false},
+		{"testdata/bw-gopher-aligned.ccitt_group4", Group4, true},
+	} {
+		want, err := ioutil.ReadFile(tt.fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Encoding a bitmap.Image takes a different path than encoding an
+		// image.Gray, so check both.
+		for _, src := range []image.Image{m, bitmap.FromImage(m)} {
+			var got bytes.Buffer
+			if err := Encode(&got, src, MSB, tt.sf, &Options{Align: tt.align}); err != nil {
+				t.Fatalf("%s: %T: Encode: %v", tt.fileName, src, err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s: %T: encoded data differs", tt.fileName, src)
+			}
+		}
+	}
+}
+
+func TestEncodeRoundtrip(t *testing.T) {
+	// The image has runs longer than the largest makeup code, and rows that
+	// start with black.
+	m := image.NewGray(image.Rect(10, 20, 10+6000, 20+5))
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 6000; x++ {
+			if (x/(y*700+1))%3 == y%2 {
+				m.Pix[y*m.Stride+x] = 0xFF
+			}
+		}
+	}
+	testEncodeRoundtrip(t, m)
+}
+
+func TestEncodeRoundtripGopher(t *testing.T) {
+	m, err := decodePNG("testdata/bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := image.NewGray(m.Bounds())
+	for y := g.Rect.Min.Y; y < g.Rect.Max.Y; y++ {
+		for x := g.Rect.Min.X; x < g.Rect.Max.X; x++ {
+			if color.GrayModel.Convert(m.At(x, y)).(color.Gray).Y >= 0x80 {
+				g.SetGray(x, y, color.Gray{0xFF})
+			}
+		}
+	}
+	testEncodeRoundtrip(t, g)
+}
+
+// testEncodeRoundtrip encodes m, whose pixels are all 0x00 or 0xFF, with
+// every sub-format and option, and checks that it decodes to m, both by
+// DecodeIntoGray and by a NewReader that auto-detects the height.
+func testEncodeRoundtrip(t *testing.T, m *image.Gray) {
+	t.Helper()
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	rowBytes := (w + 7) / 8
+	for _, order := range []Order{LSB, MSB} {
+		for _, align := range []bool{false, true} {
+			for _, tc := range []struct {
+				sf SubFormat
+				k  int
+			}{
+				{Group3, 0},
+				{Group3, 1},
+				{Group3, 2},
+				{Group3, 4},
+				{Group4, 0},
+			} {
+				opts := &Options{Align: align, K: tc.k}
+				desc := fmt.Sprintf("order=%d, align=%t, sf=%d, k=%d", order, align, tc.sf, tc.k)
+				var buf bytes.Buffer
+				if err := Encode(&buf, m, order, tc.sf, opts); err != nil {
+					t.Fatalf("%s: Encode: %v", desc, err)
+				}
+				got := image.NewGray(image.Rect(0, 0, w, h))
+				if err := DecodeIntoGray(got, bytes.NewReader(buf.Bytes()), order, tc.sf, opts); err != nil {
+					t.Fatalf("%s: DecodeIntoGray: %v", desc, err)
+				}
+				if !equalRows(got, m) {
+					t.Errorf("%s: DecodeIntoGray: round trip differs", desc)
+				}
+
+				if align && (tc.sf == Group3) {
+					// When the height is auto-detected, the alignment
+					// padding at the start of a row, followed by the row's
+					// first code, can look like the EOL that starts the
+					// final RTC, depending on the image.
+					continue
+				}
+				r := NewReader(bytes.NewReader(buf.Bytes()), order, tc.sf, w, AutoDetectHeight, opts)
+				packed, err := ioutil.ReadAll(r)
+				if err != nil {
+					t.Fatalf("%s: NewReader: %v", desc, err)
+				}
+				if len(packed) != rowBytes*h {
+					t.Fatalf("%s: NewReader: got %d bytes, want %d", desc, len(packed), rowBytes*h)
+				}
+				for y := 0; y < h; y++ {
+					for x := 0; x < w; x++ {
+						bit := packed[y*rowBytes+x/8] >> (7 - uint(x%8)) & 1
+						if want := m.Pix[y*m.Stride+x] >> 7; bit != want {
+							t.Fatalf("%s: NewReader: pixel (%d, %d): got %d, want %d", desc, x, y, bit, want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func equalRows(a, b *image.Gray) bool {
+	w := a.Rect.Dx()
+	for y := 0; y < a.Rect.Dy(); y++ {
+		if !bytes.Equal(a.Pix[y*a.Stride:y*a.Stride+w], b.Pix[y*b.Stride:y*b.Stride+w]) {
+			return false
+		}
+	}
+	return true
+}
+
+// TestEncodeGroup4Size checks that two-dimensional coding is more compact
+// than one-dimensional coding for an image whose rows resemble each other.
+func TestEncodeGroup4Size(t *testing.T) {
+	m, err := decodePNG("testdata/bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g3, g3k4, g4 bytes.Buffer
+	if err := Encode(&g3, m, MSB, Group3, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := Encode(&g3k4, m, MSB, Group3, &Options{K: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Encode(&g4, m, MSB, Group4, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !(g4.Len() < g3k4.Len() && g3k4.Len() < g3.Len()) {
+		t.Errorf("got sizes %d (Group4), %d (Group3, K=4), %d (Group3), want increasing",
+			g4.Len(), g3k4.Len(), g3.Len())
+	}
+}
+
+func TestEncodeUnsupported(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 8, 8))
+	if err := Encode(ioutil.Discard, m, MSB, SubFormat(2), nil); err != errUnsupportedSubFormat {
+		t.Errorf("got %v, want %v", err, errUnsupportedSubFormat)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package demux
+
+// This file implements finding AV1 key frames. An AV1 temporal unit is a
+// sequence of Open Bitstream Units (OBUs), specified in section 5.3 of the
+// AV1 specification at https://aomediacodec.github.io/av1-spec/
+
+// OBU types, specified in section 6.2.2.
+const (
+	obuSequenceHeader = 1
+	obuFrameHeader    = 3
+	obuFrame          = 6
+)
+
+// av1State holds the sequence header fields that frame headers depend on.
+type av1State struct {
+	// reducedStillPictureHeader is whether frame headers are omitted
+	// because every frame is a key frame.
+	reducedStillPictureHeader bool
+}
+
+// leb128 decodes an unsigned LEB128 value, returning it and the number of
+// bytes read, or zero bytes if b does not hold a valid value.
+func leb128(b []byte) (value uint64, n int) {
+	for i := 0; i < 8 && i < len(b); i++ {
+		value |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// parseSequenceHeaders updates s from the sequence header OBUs in data, such
+// as the configuration OBUs of an AV1 codec configuration record.
+func (s *av1State) parseSequenceHeaders(data []byte) {
+	s.walk(data, func(obuType uint8, payload []byte) bool {
+		if obuType == obuSequenceHeader && len(payload) > 0 {
+			// The payload starts with a 3-bit seq_profile, a 1-bit
+			// still_picture and a 1-bit reduced_still_picture_header.
+			s.reducedStillPictureHeader = payload[0]&0x08 != 0
+		}
+		return true
+	})
+}
+
+// keyFrame returns whether the temporal unit data holds a key frame that is
+// shown, or is to be shown, rather than a re-shown earlier frame.
+func (s *av1State) keyFrame(data []byte) (key bool) {
+	s.walk(data, func(obuType uint8, payload []byte) bool {
+		switch obuType {
+		case obuSequenceHeader:
+			if len(payload) > 0 {
+				s.reducedStillPictureHeader = payload[0]&0x08 != 0
+			}
+		case obuFrameHeader, obuFrame:
+			// The frame header starts with a 1-bit show_existing_frame
+			// and a 2-bit frame_type, unless the sequence header says
+			// that every frame is a key frame.
+			key = s.reducedStillPictureHeader ||
+				(len(payload) > 0 && payload[0]&0x80 == 0 && payload[0]>>5&0x03 == 0)
+			return false
+		}
+		return true
+	})
+	return key
+}
+
+// walk calls f for each OBU in data, until f returns false.
+func (s *av1State) walk(data []byte, f func(obuType uint8, payload []byte) bool) {
+	for len(data) > 0 {
+		// The OBU header has a forbidden bit, a 4-bit type, an extension
+		// flag, a has_size_field flag and a reserved bit.
+		h := data[0]
+		obuType, i := h>>3&0x0f, 1
+		if h&0x04 != 0 {
+			i++
+		}
+		if i > len(data) {
+			return
+		}
+		size := uint64(len(data) - i)
+		if h&0x02 != 0 {
+			v, n := leb128(data[i:])
+			if n == 0 {
+				return
+			}
+			size, i = v, i+n
+		}
+		if size > uint64(len(data)-i) {
+			return
+		}
+		if !f(obuType, data[i:i+int(size)]) {
+			return
+		}
+		data = data[i+int(size):]
+	}
+}
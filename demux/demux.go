@@ -0,0 +1,189 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package demux implements a lightweight demuxer for the video frames of IVF
+// and WebM files, so that their key frames can be decoded as still images,
+// such as for a video's poster frame or thumbnail.
+//
+// It reads the first video track of a file, if its codec is VP8, VP8L or
+// AV1. Key frames of VP8 and VP8L video are decoded by the
+// golang.org/x/image/vp8 and golang.org/x/image/vp8l packages. AV1 frames
+// are returned as is, for a decoder outside of this repository.
+//
+// The IVF format is described at
+// https://wiki.multimedia.cx/index.php/Duck_IVF and the WebM format, a
+// subset of Matroska, at https://www.webmproject.org/docs/container/
+package demux // import "golang.org/x/image/demux"
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"time"
+
+	"golang.org/x/image/vp8"
+	"golang.org/x/image/vp8l"
+)
+
+// A FormatError reports that the input is not a valid IVF or WebM file.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "demux: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "demux: unsupported feature: " + string(e)
+}
+
+// maxFrameSize is the largest frame, or buffered WebM element, that is read.
+const maxFrameSize = 64 << 20
+
+// Codec is a video codec.
+type Codec string
+
+// These are the supported codecs.
+const (
+	VP8  Codec = "VP8"
+	VP8L Codec = "VP8L"
+	AV1  Codec = "AV1"
+)
+
+// Config describes the video track of a file.
+type Config struct {
+	Codec         Codec
+	Width, Height int
+	// CodecPrivate is the codec's initialization data, if any, such as the
+	// AV1 codec configuration record of WebM files.
+	CodecPrivate []byte
+}
+
+// Frame is a compressed video frame.
+type Frame struct {
+	// KeyFrame is whether the frame can be decoded without the frames
+	// before it.
+	KeyFrame bool
+	// Timestamp is the frame's presentation time.
+	Timestamp time.Duration
+	// Data is the frame's compressed data: a VP8 or VP8L frame, or an AV1
+	// temporal unit.
+	Data []byte
+}
+
+// demuxer is the container-specific part of a Reader.
+type demuxer interface {
+	next() (Frame, error)
+}
+
+// Reader reads the frames of the video track of an IVF or WebM file.
+type Reader struct {
+	config Config
+	d      demuxer
+}
+
+// NewReader returns a Reader that reads from r. It reads the file's header,
+// which determines whether it is an IVF or a WebM file.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	var x Reader
+	switch string(magic) {
+	case "DKIF":
+		x.d, x.config, err = newIVFReader(br)
+	case "\x1a\x45\xdf\xa3":
+		x.d, x.config, err = newWebMReader(br)
+	default:
+		return nil, FormatError("neither IVF nor WebM")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// Config returns the video track's configuration.
+func (r *Reader) Config() Config {
+	return r.config
+}
+
+// Next returns the next frame. It returns io.EOF at the end of the file.
+func (r *Reader) Next() (Frame, error) {
+	return r.d.next()
+}
+
+// NextKeyFrame returns the next key frame, skipping other frames. It returns
+// io.EOF at the end of the file.
+func (r *Reader) NextKeyFrame() (Frame, error) {
+	for {
+		f, err := r.d.next()
+		if err != nil || f.KeyFrame {
+			return f, err
+		}
+	}
+}
+
+// DecodeFrame decodes a key frame of a VP8 or VP8L video track.
+func DecodeFrame(c Config, f Frame) (image.Image, error) {
+	if !f.KeyFrame {
+		return nil, errors.New("demux: cannot decode a frame that is not a key frame")
+	}
+	switch c.Codec {
+	case VP8:
+		_, m, err := vp8.NewDecoder().Decode(f.Data)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	case VP8L:
+		return vp8l.Decode(bytes.NewReader(f.Data))
+	}
+	return nil, UnsupportedError("decoding " + string(c.Codec) + " frames")
+}
+
+// DecodeKeyFrame decodes the first key frame of an IVF or WebM file's VP8 or
+// VP8L video track.
+func DecodeKeyFrame(r io.Reader) (image.Image, error) {
+	x, err := NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if c := x.config.Codec; c != VP8 && c != VP8L {
+		return nil, UnsupportedError("decoding " + string(c) + " frames")
+	}
+	f, err := x.NextKeyFrame()
+	if err == io.EOF {
+		return nil, FormatError("no key frame")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return DecodeFrame(x.config, f)
+}
+
+// isKeyFrame returns whether data is a key frame of the codec c. av1 holds
+// the state needed to parse AV1 frames.
+func isKeyFrame(c Codec, data []byte, av1 *av1State) bool {
+	switch c {
+	case VP8:
+		// The frame tag is specified in section 9.1 of RFC 6386.
+		return len(data) > 0 && data[0]&1 == 0
+	case VP8L:
+		return true
+	case AV1:
+		return av1.keyFrame(data)
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package demux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/image/vp8"
+)
+
+// encodeKeyFrame returns a VP8 key frame of a w×h gradient.
+func encodeKeyFrame(t *testing.T, w, h int) []byte {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.Set(x, y, color.RGBA{uint8(8 * x), uint8(8 * y), 0x80, 0xff})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := vp8.Encode(buf, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// interFrame is the start of a VP8 frame that is not a key frame. The
+// demuxer only looks at its frame tag.
+var interFrame = []byte{0x01, 0x00, 0x00, 0x00}
+
+func ivfFile(fourcc string, w, h int, frames [][]byte) []byte {
+	var b []byte
+	b = append(b, "DKIF"...)
+	b = append(b, 0, 0, 32, 0)
+	b = append(b, fourcc...)
+	b = append(b, byte(w), byte(w>>8), byte(h), byte(h>>8))
+	// A time base of 1/30 seconds.
+	b = append(b, 30, 0, 0, 0, 1, 0, 0, 0)
+	b = append(b, byte(len(frames)), 0, 0, 0, 0, 0, 0, 0)
+	for i, f := range frames {
+		var fh [12]byte
+		binary.LittleEndian.PutUint32(fh[0:], uint32(len(f)))
+		binary.LittleEndian.PutUint64(fh[4:], uint64(i))
+		b = append(b, fh[:]...)
+		b = append(b, f...)
+	}
+	return b
+}
+
+// ebml returns an element with the given ID and data, using an 8-byte size.
+func ebml(id uint64, data ...[]byte) []byte {
+	var b []byte
+	for shift := 24; shift >= 0; shift -= 8 {
+		if c := byte(id >> uint(shift)); c != 0 || len(b) > 0 {
+			b = append(b, c)
+		}
+	}
+	n := 0
+	for _, d := range data {
+		n += len(d)
+	}
+	b = append(b, 0x01, 0, 0, 0, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	return b
+}
+
+func webmFile(codecID string, w, h int, frames [][]byte) []byte {
+	u := func(v int) []byte { return []byte{byte(v >> 8), byte(v)} }
+	audio := ebml(idTrackEntry,
+		ebml(idTrackNumber, []byte{1}),
+		ebml(idTrackType, []byte{2}),
+		ebml(idCodecID, []byte("A_VORBIS")),
+	)
+	video := ebml(idTrackEntry,
+		ebml(idTrackNumber, []byte{2}),
+		ebml(idTrackType, []byte{1}),
+		ebml(idCodecID, []byte(codecID)),
+		ebml(idVideo, ebml(idPixelWidth, u(w)), ebml(idPixelHeight, u(h))),
+	)
+	cluster := [][]byte{ebml(idTimecode, u(1000))}
+	for i, f := range frames {
+		// An audio block, then a video block, 40 timecode units apart.
+		cluster = append(cluster, ebml(idSimpleBlock, []byte{0x81, 0, 0, 0x80}, []byte("audio")))
+		flags := byte(0)
+		if f[0]&1 == 0 {
+			flags = 0x80
+		}
+		if i%2 == 0 {
+			cluster = append(cluster, ebml(idSimpleBlock, []byte{0x82}, u(40*i), []byte{flags}, f))
+		} else {
+			var ref []byte
+			if flags == 0 {
+				ref = ebml(idReferenceBlock, []byte{0xd8})
+			}
+			cluster = append(cluster, ebml(idBlockGroup, ebml(idBlock, []byte{0x82}, u(40*i), []byte{0}, f), ref))
+		}
+	}
+	return append(ebml(idEBML, ebml(idDocType, []byte("webm"))),
+		ebml(idSegment,
+			ebml(idInfo, ebml(idTimecodeScale, u(1000))),
+			ebml(0xec, []byte("void")),
+			ebml(idTracks, audio, video),
+			ebml(idCluster, cluster...),
+		)...)
+}
+
+func TestReader(t *testing.T) {
+	const w, h = 24, 20
+	key := encodeKeyFrame(t, w, h)
+	frames := [][]byte{interFrame, key, interFrame, key}
+	testCases := []struct {
+		name       string
+		file       []byte
+		timestamps []time.Duration
+	}{
+		{"ivf", ivfFile("VP80", w, h, frames), []time.Duration{
+			0, time.Second / 30, 2 * time.Second / 30, 3 * time.Second / 30,
+		}},
+		{"webm", webmFile("V_VP8", w, h, frames), []time.Duration{
+			1000 * time.Microsecond, 1040 * time.Microsecond, 1080 * time.Microsecond, 1120 * time.Microsecond,
+		}},
+	}
+	for _, tc := range testCases {
+		r, err := NewReader(bytes.NewReader(tc.file))
+		if err != nil {
+			t.Errorf("%s: NewReader: %v", tc.name, err)
+			continue
+		}
+		if got, want := r.Config(), (Config{Codec: VP8, Width: w, Height: h}); got.Codec != want.Codec ||
+			got.Width != want.Width || got.Height != want.Height {
+			t.Errorf("%s: Config: got %+v, want %+v", tc.name, got, want)
+		}
+		for i, want := range frames {
+			f, err := r.Next()
+			if err != nil {
+				t.Errorf("%s: frame #%d: %v", tc.name, i, err)
+				break
+			}
+			if !bytes.Equal(f.Data, want) {
+				t.Errorf("%s: frame #%d: data differs", tc.name, i)
+			}
+			if f.KeyFrame != (i%2 == 1) {
+				t.Errorf("%s: frame #%d: KeyFrame: got %t", tc.name, i, f.KeyFrame)
+			}
+			if f.Timestamp != tc.timestamps[i] {
+				t.Errorf("%s: frame #%d: Timestamp: got %v, want %v", tc.name, i, f.Timestamp, tc.timestamps[i])
+			}
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("%s: after the last frame: got %v, want io.EOF", tc.name, err)
+		}
+
+		m, err := DecodeKeyFrame(bytes.NewReader(tc.file))
+		if err != nil {
+			t.Errorf("%s: DecodeKeyFrame: %v", tc.name, err)
+			continue
+		}
+		if got := m.Bounds(); got != image.Rect(0, 0, w, h) {
+			t.Errorf("%s: DecodeKeyFrame: bounds: got %v", tc.name, got)
+		}
+	}
+}
+
+func TestDecodeKeyFrameErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		file []byte
+	}{
+		{"not a container", []byte("RIFF\x00\x00\x00\x00WEBP")},
+		{"no key frame", ivfFile("VP80", 16, 16, [][]byte{interFrame})},
+		{"AV1", ivfFile("AV01", 16, 16, nil)},
+		{"unknown codec", ivfFile("H264", 16, 16, nil)},
+		{"truncated", ivfFile("VP80", 16, 16, [][]byte{interFrame})[:40]},
+		{"unsupported WebM codec", webmFile("V_VP9", 16, 16, nil)},
+	}
+	for _, tc := range testCases {
+		if _, err := DecodeKeyFrame(bytes.NewReader(tc.file)); err == nil {
+			t.Errorf("%s: got nil error", tc.name)
+		}
+	}
+}
+
+func TestAV1KeyFrame(t *testing.T) {
+	// Each temporal unit starts with a temporal delimiter OBU.
+	const td = "\x12\x00"
+	testCases := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"key frame", td + "\x32\x01\x10", true},
+		{"inter frame", td + "\x32\x01\x30", false},
+		{"shown existing frame", td + "\x1a\x01\x80", false},
+		{"frame header with extension", td + "\x1e\x00\x01\x10", true},
+		{"reduced still picture header", td + "\x0a\x01\x08" + "\x32\x01\xff", true},
+		{"truncated", td + "\x32\x05\x10", false},
+	}
+	for _, tc := range testCases {
+		var s av1State
+		if got := s.keyFrame([]byte(tc.data)); got != tc.want {
+			t.Errorf("%s: got %t, want %t", tc.name, got, tc.want)
+		}
+	}
+}
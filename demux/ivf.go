@@ -0,0 +1,91 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package demux
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// ivfHeaderLen is the length of the IVF file header. The header's length
+// field may give a longer length.
+const ivfHeaderLen = 32
+
+// ivfReader reads the frames of an IVF file. Each frame has a 12-byte
+// header: the frame's length and its timestamp, in units of the time base.
+type ivfReader struct {
+	r     *bufio.Reader
+	codec Codec
+	// The time base is scale/rate seconds.
+	rate, scale uint32
+	av1         av1State
+}
+
+func newIVFReader(r *bufio.Reader) (*ivfReader, Config, error) {
+	var h [ivfHeaderLen]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, Config{}, err
+	}
+	if n := int(binary.LittleEndian.Uint16(h[6:])); n < ivfHeaderLen {
+		return nil, Config{}, FormatError("IVF header length")
+	} else if _, err := r.Discard(n - ivfHeaderLen); err != nil {
+		return nil, Config{}, io.ErrUnexpectedEOF
+	}
+	d := &ivfReader{
+		r:     r,
+		rate:  binary.LittleEndian.Uint32(h[16:]),
+		scale: binary.LittleEndian.Uint32(h[20:]),
+	}
+	switch string(h[8:12]) {
+	case "VP80":
+		d.codec = VP8
+	case "VP8L":
+		d.codec = VP8L
+	case "AV01":
+		d.codec = AV1
+	default:
+		return nil, Config{}, UnsupportedError("IVF codec " + string(h[8:12]))
+	}
+	return d, Config{
+		Codec:  d.codec,
+		Width:  int(binary.LittleEndian.Uint16(h[12:])),
+		Height: int(binary.LittleEndian.Uint16(h[14:])),
+	}, nil
+}
+
+func (d *ivfReader) next() (Frame, error) {
+	var h [12]byte
+	if _, err := io.ReadFull(d.r, h[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = FormatError("truncated IVF frame header")
+		}
+		return Frame{}, err
+	}
+	n := binary.LittleEndian.Uint32(h[0:])
+	if n > maxFrameSize {
+		return Frame{}, UnsupportedError("frame size")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return Frame{}, err
+	}
+	f := Frame{
+		KeyFrame: isKeyFrame(d.codec, data, &d.av1),
+		Data:     data,
+	}
+	if d.rate != 0 {
+		pts := float64(binary.LittleEndian.Uint64(h[4:]))
+		f.Timestamp = time.Duration(pts * float64(d.scale) / float64(d.rate) * float64(time.Second))
+	}
+	return f, nil
+}
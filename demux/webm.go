@@ -0,0 +1,441 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package demux
+
+import (
+	"bufio"
+	"io"
+	"time"
+)
+
+// EBML and Matroska element IDs, including their length markers, as listed
+// at https://www.matroska.org/technical/elements.html
+const (
+	idEBML           = 0x1a45dfa3
+	idDocType        = 0x4282
+	idSegment        = 0x18538067
+	idInfo           = 0x1549a966
+	idTimecodeScale  = 0x2ad7b1
+	idTracks         = 0x1654ae6b
+	idTrackEntry     = 0xae
+	idTrackNumber    = 0xd7
+	idTrackType      = 0x83
+	idCodecID        = 0x86
+	idCodecPrivate   = 0x63a2
+	idVideo          = 0xe0
+	idPixelWidth     = 0xb0
+	idPixelHeight    = 0xba
+	idCluster        = 0x1f43b675
+	idTimecode       = 0xe7
+	idSimpleBlock    = 0xa3
+	idBlockGroup     = 0xa0
+	idBlock          = 0xa1
+	idReferenceBlock = 0xfb
+)
+
+// unknownSize is the size of an element whose size is not known in advance,
+// such as a live stream's Segment or Cluster.
+const unknownSize = -1
+
+// webmReader reads the frames of a WebM file's first video track. It reads
+// the elements of the Segment and of each Cluster in turn, without seeking.
+type webmReader struct {
+	r     *bufio.Reader
+	codec Codec
+	track uint64
+	// timecodeScale is the duration, in nanoseconds, of a timecode unit.
+	timecodeScale int64
+	// clusterTime is the timecode of the current Cluster.
+	clusterTime int64
+	av1         av1State
+}
+
+func newWebMReader(r *bufio.Reader) (*webmReader, Config, error) {
+	d := &webmReader{
+		r:             r,
+		timecodeScale: 1000000,
+	}
+	id, size, err := d.readElementHeader()
+	if err != nil {
+		return nil, Config{}, err
+	}
+	header, err := d.readElement(size)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	docType := ""
+	if err := walkElements(header, func(id uint64, data []byte) error {
+		if id == idDocType {
+			docType = string(data)
+		}
+		return nil
+	}); err != nil {
+		return nil, Config{}, err
+	}
+	if docType != "webm" && docType != "matroska" {
+		return nil, Config{}, FormatError("EBML document type " + docType)
+	}
+
+	// The Segment's elements are read until the first Cluster, by which
+	// time the Info and Tracks elements have been read.
+	if id, size, err = d.readElementHeader(); err != nil {
+		return nil, Config{}, err
+	} else if id != idSegment {
+		return nil, Config{}, FormatError("missing Segment")
+	}
+	var c Config
+	for {
+		id, size, err = d.readElementHeader()
+		if err == io.EOF {
+			return nil, Config{}, FormatError("missing Cluster")
+		} else if err != nil {
+			return nil, Config{}, err
+		}
+		if id == idCluster {
+			break
+		}
+		if id != idInfo && id != idTracks {
+			if err := d.skipElement(size); err != nil {
+				return nil, Config{}, err
+			}
+			continue
+		}
+		data, err := d.readElement(size)
+		if err != nil {
+			return nil, Config{}, err
+		}
+		if id == idInfo {
+			err = walkElements(data, func(id uint64, data []byte) error {
+				if id == idTimecodeScale {
+					if d.timecodeScale = int64(readUint(data)); d.timecodeScale <= 0 {
+						return FormatError("TimecodeScale")
+					}
+				}
+				return nil
+			})
+		} else if d.track == 0 {
+			err = walkElements(data, func(id uint64, data []byte) error {
+				if id == idTrackEntry && d.track == 0 {
+					return d.parseTrackEntry(data, &c)
+				}
+				return nil
+			})
+		}
+		if err != nil {
+			return nil, Config{}, err
+		}
+	}
+	if d.track == 0 {
+		return nil, Config{}, FormatError("no video track")
+	}
+	if d.codec == AV1 && len(c.CodecPrivate) > 4 {
+		// The AV1 codec configuration record has a 4-byte header followed by
+		// configuration OBUs.
+		d.av1.parseSequenceHeaders(c.CodecPrivate[4:])
+	}
+	return d, c, nil
+}
+
+// parseTrackEntry sets d's track and c if the TrackEntry data describes a
+// video track.
+func (d *webmReader) parseTrackEntry(data []byte, c *Config) error {
+	var (
+		number, trackType uint64
+		codecID           string
+		t                 Config
+	)
+	err := walkElements(data, func(id uint64, data []byte) error {
+		switch id {
+		case idTrackNumber:
+			number = readUint(data)
+		case idTrackType:
+			trackType = readUint(data)
+		case idCodecID:
+			codecID = string(data)
+		case idCodecPrivate:
+			t.CodecPrivate = append([]byte(nil), data...)
+		case idVideo:
+			return walkElements(data, func(id uint64, data []byte) error {
+				switch id {
+				case idPixelWidth:
+					t.Width = int(readUint(data))
+				case idPixelHeight:
+					t.Height = int(readUint(data))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	const videoTrackType = 1
+	if trackType != videoTrackType {
+		return nil
+	}
+	switch codecID {
+	case "V_VP8":
+		t.Codec = VP8
+	case "V_AV1":
+		t.Codec = AV1
+	default:
+		return UnsupportedError("WebM codec " + codecID)
+	}
+	if number == 0 {
+		return FormatError("TrackNumber")
+	}
+	d.track, d.codec, *c = number, t.Codec, t
+	return nil
+}
+
+func (d *webmReader) next() (Frame, error) {
+	for {
+		id, size, err := d.readElementHeader()
+		if err != nil {
+			return Frame{}, err
+		}
+		switch id {
+		case idSegment, idCluster:
+			// Descend into the element.
+			continue
+		case idTimecode, idSimpleBlock, idBlockGroup:
+			// Read the element below.
+		default:
+			if err := d.skipElement(size); err != nil {
+				return Frame{}, err
+			}
+			continue
+		}
+		data, err := d.readElement(size)
+		if err != nil {
+			return Frame{}, err
+		}
+		switch id {
+		case idTimecode:
+			d.clusterTime = int64(readUint(data))
+		case idSimpleBlock:
+			const keyFlag = 0x80
+			if f, ok, err := d.parseBlock(data, func(flags byte) bool {
+				return flags&keyFlag != 0
+			}); ok || err != nil {
+				return f, err
+			}
+		case idBlockGroup:
+			var block []byte
+			referenced := false
+			if err := walkElements(data, func(id uint64, data []byte) error {
+				switch id {
+				case idBlock:
+					block = data
+				case idReferenceBlock:
+					referenced = true
+				}
+				return nil
+			}); err != nil {
+				return Frame{}, err
+			}
+			if block == nil {
+				return Frame{}, FormatError("BlockGroup without Block")
+			}
+			if f, ok, err := d.parseBlock(block, func(byte) bool {
+				return !referenced
+			}); ok || err != nil {
+				return f, err
+			}
+		}
+	}
+}
+
+// parseBlock parses the data of a Block or SimpleBlock, returning whether it
+// is a frame of the video track. keyFrame reports, from the block's flags,
+// whether the container marks the block as a key frame.
+func (d *webmReader) parseBlock(data []byte, keyFrame func(flags byte) bool) (f Frame, ok bool, err error) {
+	track, n := readVint(data)
+	if n == 0 || len(data) < n+3 {
+		return Frame{}, false, FormatError("Block header")
+	}
+	if track != d.track {
+		return Frame{}, false, nil
+	}
+	relTime := int64(int16(uint16(data[n])<<8 | uint16(data[n+1])))
+	flags := data[n+2]
+	if flags>>1&0x03 != 0 {
+		return Frame{}, false, UnsupportedError("Block lacing")
+	}
+	data = data[n+3:]
+	return Frame{
+		// AV1 key frames are found by parsing the frame, since the container
+		// also marks intra-only frames that are not key frames.
+		KeyFrame:  keyFrame(flags) && (d.codec != AV1 || isKeyFrame(d.codec, data, &d.av1)),
+		Timestamp: time.Duration((d.clusterTime + relTime) * d.timecodeScale),
+		Data:      data,
+	}, true, nil
+}
+
+// readElementHeader reads an element's ID and size. The size is
+// unknownSize if the element's size is unknown.
+func (d *webmReader) readElementHeader() (id uint64, size int64, err error) {
+	id, err = readVintFrom(d.r, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	s, err := readVintFrom(d.r, false)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if s == unknownSizeValue {
+		return id, unknownSize, nil
+	}
+	return id, int64(s), nil
+}
+
+// readElement reads the data of an element of the given size.
+func (d *webmReader) readElement(size int64) ([]byte, error) {
+	if size == unknownSize {
+		return nil, UnsupportedError("element of unknown size")
+	}
+	if size > maxFrameSize {
+		return nil, UnsupportedError("element size")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(d.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// skipElement skips the data of an element of the given size.
+func (d *webmReader) skipElement(size int64) error {
+	if size == unknownSize {
+		return UnsupportedError("element of unknown size")
+	}
+	for size > 0 {
+		n := size
+		if n > 1<<30 {
+			n = 1 << 30
+		}
+		m, err := d.r.Discard(int(n))
+		size -= int64(m)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// unknownSizeValue is the value of an 8-byte size with all of its data bits
+// set. Shorter all-ones sizes also mean an unknown size, and readVintFrom
+// maps them to this value.
+const unknownSizeValue = 1<<56 - 1
+
+// readVintFrom reads an EBML variable-length integer. The length marker is
+// kept for IDs and removed for sizes.
+func readVintFrom(r io.ByteReader, isID bool) (uint64, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	n := 1
+	for mask := byte(0x80); b&mask == 0; mask >>= 1 {
+		if n++; n > 8 {
+			return 0, FormatError("EBML variable-length integer")
+		}
+	}
+	marker := uint64(1) << uint(7*n)
+	v := uint64(b)
+	for i := 1; i < n; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		v = v<<8 | uint64(c)
+	}
+	if isID {
+		return v, nil
+	}
+	v &^= marker
+	if v == marker-1 {
+		return unknownSizeValue, nil
+	}
+	return v, nil
+}
+
+// readVint decodes an EBML variable-length integer, with its length marker
+// removed, from the start of b. It returns the number of bytes read, or zero
+// if b does not start with a valid integer.
+func readVint(b []byte) (v uint64, n int) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0
+	}
+	n = 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if len(b) < n {
+		return 0, 0
+	}
+	v = uint64(b[0]) & (0xff >> uint(n))
+	for i := 1; i < n; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, n
+}
+
+// readUint decodes a big-endian unsigned integer element.
+func readUint(data []byte) uint64 {
+	v := uint64(0)
+	for _, c := range data {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// walkElements calls f for each element in data, the data of a master
+// element.
+func walkElements(data []byte, f func(id uint64, data []byte) error) error {
+	for len(data) > 0 {
+		r := byteReader{data}
+		id, err := readVintFrom(&r, true)
+		if err != nil {
+			return FormatError("element ID")
+		}
+		size, err := readVintFrom(&r, false)
+		if err != nil || size > uint64(len(r.b)) {
+			return FormatError("element size")
+		}
+		if err := f(id, r.b[:size]); err != nil {
+			return err
+		}
+		data = r.b[size:]
+	}
+	return nil
+}
+
+// byteReader is an io.ByteReader of a byte slice.
+type byteReader struct {
+	b []byte
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	c := r.b[0]
+	r.b = r.b[1:]
+	return c, nil
+}
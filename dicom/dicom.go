@@ -0,0 +1,351 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dicom implements a decoder for the pixel data of DICOM images, the
+// format of medical imaging devices such as CT, MR and X-ray scanners.
+//
+// It decodes grayscale (MONOCHROME1 and MONOCHROME2) and color (RGB and
+// YBR_FULL) images whose pixel data is uncompressed, in any of the native
+// transfer syntaxes, or compressed with RLE Lossless. The pixel data of other
+// encapsulated transfer syntaxes is passed to the decoders registered with
+// the image package: JPEG Baseline is decoded by image/jpeg, which this
+// package imports, and formats such as JPEG 2000 need the program to import
+// a decoder for them.
+//
+// Grayscale images are decoded as *image.Gray16, after applying the modality
+// rescale and a window that maps the values of interest to the full output
+// range. Color images are decoded as *image.RGBA.
+//
+// The DICOM standard is at https://www.dicomstandard.org/current. This
+// package reads only the attributes that it needs to decode an image.
+package dicom // import "golang.org/x/image/dicom"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // Register the JPEG decoder, for JPEG Baseline images.
+	"io"
+	"math"
+	"strings"
+)
+
+// A FormatError reports that the input is not a valid DICOM image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "dicom: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "dicom: unsupported feature: " + string(e)
+}
+
+// Window is a linear VOI LUT function (PS3.3 C.11.2.1.2), which maps the
+// rescaled pixel values from Center-Width/2 to Center+Width/2 to the output
+// range, for display. Values outside of the window are clamped.
+type Window struct {
+	Center, Width float64
+}
+
+// apply returns the output, in [0, 1], of w for the rescaled value x.
+func (w Window) apply(x float64) float64 {
+	c, width := w.Center, w.Width
+	if width < 1 {
+		width = 1
+	}
+	switch {
+	case x <= c-0.5-(width-1)/2:
+		return 0
+	case x > c-0.5+(width-1)/2:
+		return 1
+	}
+	return (x-(c-0.5))/(width-1) + 0.5
+}
+
+// DecodeOptions are optional parameters to DecodeWithOptions.
+type DecodeOptions struct {
+	// Frame is the index of the frame to decode, for multi-frame images.
+	Frame int
+	// Window, if non-nil, is the window applied to grayscale images instead
+	// of the first one given by the file. If both are nil, the window spans
+	// the range of the frame's values.
+	Window *Window
+}
+
+// Decode reads the first frame of a DICOM image from r and returns it as an
+// image.Image.
+func Decode(r io.Reader) (image.Image, error) {
+	return DecodeWithOptions(r, nil)
+}
+
+// DecodeConfig returns the color model and dimensions of a DICOM image
+// without decoding its pixel data.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	d, err := readHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	c := image.Config{
+		ColorModel: color.RGBAModel,
+		Width:      d.cols,
+		Height:     d.rows,
+	}
+	if d.samples == 1 {
+		c.ColorModel = color.Gray16Model
+	}
+	return c, nil
+}
+
+// DecodeWithOptions is like Decode but with optional parameters. A nil opts
+// is equivalent to a zero DecodeOptions.
+func DecodeWithOptions(r io.Reader, opts *DecodeOptions) (image.Image, error) {
+	var o DecodeOptions
+	if opts != nil {
+		o = *opts
+	}
+	d, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	if o.Frame < 0 || o.Frame >= d.frames {
+		return nil, FormatError("frame out of range")
+	}
+
+	var (
+		pix   []byte
+		order = d.order
+	)
+	if d.pixelDataLen != undefinedLength {
+		if d.photometric == "YBR_FULL_422" {
+			return nil, UnsupportedError("uncompressed YBR_FULL_422")
+		}
+		if pix, err = d.readNativeFrame(o.Frame); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := d.readEncapsulatedFrame(o.Frame)
+		if err != nil {
+			return nil, err
+		}
+		if d.syntax != rleLossless {
+			return d.decodeDelegated(data, o.Window)
+		}
+		// RLE Lossless decodes to interleaved, little-endian samples.
+		pix, err = decodeRLE(data, d.rows*d.cols, d.samples, d.bitsAllocated/8)
+		if err != nil {
+			return nil, err
+		}
+		order, d.planar = binary.LittleEndian, 0
+	}
+
+	if d.samples == 3 {
+		return d.colorImage(pix), nil
+	}
+	raw := make([]int32, d.rows*d.cols)
+	shift := uint(d.highBit + 1 - d.bitsStored)
+	mask := uint32(1)<<uint(d.bitsStored) - 1
+	for i := range raw {
+		var v uint32
+		if d.bitsAllocated == 8 {
+			v = uint32(pix[i])
+		} else {
+			v = uint32(order.Uint16(pix[2*i:]))
+		}
+		raw[i] = d.storedValue(v >> shift & mask)
+	}
+	return d.grayImage(raw, o.Window), nil
+}
+
+// storedValue returns the stored value v, of d.bitsStored bits, as a signed
+// or unsigned value depending on d's PixelRepresentation.
+func (d *decoder) storedValue(v uint32) int32 {
+	if d.signed && v&(1<<uint(d.bitsStored-1)) != 0 {
+		return int32(v) - 1<<uint(d.bitsStored)
+	}
+	return int32(v)
+}
+
+// readNativeFrame reads the given frame of uncompressed pixel data.
+func (d *decoder) readNativeFrame(frame int) ([]byte, error) {
+	frameLen := int64(d.rows) * int64(d.cols) * int64(d.samples) * int64(d.bitsAllocated/8)
+	if int64(d.pixelDataLen) < int64(frame+1)*frameLen {
+		return nil, FormatError("not enough pixel data")
+	}
+	for skip := int64(frame) * frameLen; skip > 0; {
+		n := int64(math.MaxInt32)
+		if skip < n {
+			n = skip
+		}
+		if _, err := d.r.Discard(int(n)); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		skip -= n
+	}
+	return d.readValue(uint32(frameLen))
+}
+
+// readEncapsulatedFrame reads the fragments of encapsulated pixel data (PS3.5
+// section A.4) and returns the data of the given frame.
+func (d *decoder) readEncapsulatedFrame(frame int) ([]byte, error) {
+	// The first item is the Basic Offset Table, which holds the offset of
+	// each frame's first fragment, relative to the first fragment's item.
+	var (
+		offsetTable []uint32
+		fragments   [][]byte
+		offsets     []uint32
+		offset      uint32
+	)
+	for i := 0; ; i++ {
+		tag, _, length, err := d.readElementHeader()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if tag == tagSequenceDelimitation {
+			break
+		}
+		if tag != tagItem {
+			return nil, FormatError("bad pixel data item")
+		}
+		v, err := d.readValue(length)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			for j := 0; j+4 <= len(v); j += 4 {
+				offsetTable = append(offsetTable, d.order.Uint32(v[j:]))
+			}
+			continue
+		}
+		fragments = append(fragments, v)
+		offsets = append(offsets, offset)
+		offset += 8 + length
+	}
+
+	switch {
+	case len(offsetTable) > 0:
+		if frame >= len(offsetTable) {
+			return nil, FormatError("frame missing from the basic offset table")
+		}
+		start, end := offsetTable[frame], uint32(math.MaxUint32)
+		if frame+1 < len(offsetTable) {
+			end = offsetTable[frame+1]
+		}
+		var data []byte
+		for i, f := range fragments {
+			if start <= offsets[i] && offsets[i] < end {
+				data = append(data, f...)
+			}
+		}
+		if data == nil {
+			return nil, FormatError("bad basic offset table")
+		}
+		return data, nil
+	case len(fragments) == d.frames:
+		return fragments[frame], nil
+	case d.frames == 1:
+		return bytes.Join(fragments, nil), nil
+	}
+	return nil, UnsupportedError("multi-fragment frames without a basic offset table")
+}
+
+// decodeDelegated decodes the data of a frame of an encapsulated transfer
+// syntax with the image package's registered decoders, converting the result
+// as for uncompressed pixel data.
+func (d *decoder) decodeDelegated(data []byte, w *Window) (image.Image, error) {
+	m, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if err == image.ErrFormat {
+			err = UnsupportedError("transfer syntax " + d.syntax)
+		}
+		return nil, err
+	}
+	b := m.Bounds()
+	if d.samples == 3 {
+		dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(dst, dst.Bounds(), m, b.Min, draw.Src)
+		return dst, nil
+	}
+	d.rows, d.cols = b.Dy(), b.Dx()
+	shift := uint(16 - d.bitsStored)
+	raw := make([]int32, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			v := color.Gray16Model.Convert(m.At(x, y)).(color.Gray16).Y
+			raw = append(raw, d.storedValue(uint32(v)>>shift))
+		}
+	}
+	return d.grayImage(raw, w), nil
+}
+
+// grayImage returns the grayscale image of the stored values raw, after
+// applying the modality rescale and the window w, or d's window if w is nil.
+func (d *decoder) grayImage(raw []int32, w *Window) *image.Gray16 {
+	if w == nil {
+		w = d.window
+	}
+	if w == nil {
+		// Span the range of the rescaled values.
+		min, max := int32(math.MaxInt32), int32(math.MinInt32)
+		for _, v := range raw {
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+		lo, hi := float64(min)*d.slope+d.intercept, float64(max)*d.slope+d.intercept
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		w = &Window{Center: (lo+hi)/2 + 0.5, Width: hi - lo + 1}
+	}
+	invert := d.photometric == "MONOCHROME1"
+
+	m := image.NewGray16(image.Rect(0, 0, d.cols, d.rows))
+	for i, v := range raw {
+		y := w.apply(float64(v)*d.slope + d.intercept)
+		if invert {
+			y = 1 - y
+		}
+		g := uint16(y*0xffff + 0.5)
+		m.Pix[2*i+0] = uint8(g >> 8)
+		m.Pix[2*i+1] = uint8(g)
+	}
+	return m
+}
+
+// colorImage returns the color image of the 8 bit samples pix.
+func (d *decoder) colorImage(pix []byte) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, d.cols, d.rows))
+	n := d.rows * d.cols
+	ybr := strings.HasPrefix(d.photometric, "YBR")
+	for i := 0; i < n; i++ {
+		var r, g, b uint8
+		if d.planar == 1 {
+			r, g, b = pix[i], pix[n+i], pix[2*n+i]
+		} else {
+			r, g, b = pix[3*i+0], pix[3*i+1], pix[3*i+2]
+		}
+		if ybr {
+			r, g, b = color.YCbCrToRGB(r, g, b)
+		}
+		m.Pix[4*i+0] = r
+		m.Pix[4*i+1] = g
+		m.Pix[4*i+2] = b
+		m.Pix[4*i+3] = 0xff
+	}
+	return m
+}
+
+func init() {
+	image.RegisterFormat("dicom", strings.Repeat("?", 128)+"DICM", Decode, DecodeConfig)
+}
@@ -0,0 +1,401 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dicom
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// builder builds a DICOM file in a given transfer syntax.
+type builder struct {
+	syntax   string
+	order    binary.ByteOrder
+	explicit bool
+	data     bytes.Buffer
+}
+
+func newBuilder(syntax string) *builder {
+	b := &builder{
+		syntax:   syntax,
+		order:    binary.LittleEndian,
+		explicit: syntax != implicitVRLittleEndian,
+	}
+	if syntax == explicitVRBigEndian {
+		b.order = binary.BigEndian
+	}
+	return b
+}
+
+// header writes a data element header.
+func (b *builder) header(tag uint32, vr string, length uint32) {
+	writeHeader(&b.data, b.order, b.explicit, tag, vr, length)
+}
+
+func writeHeader(w io.Writer, order binary.ByteOrder, explicit bool, tag uint32, vr string, length uint32) {
+	binary.Write(w, order, uint16(tag>>16))
+	binary.Write(w, order, uint16(tag))
+	switch {
+	case tag>>16 == 0xfffe || !explicit:
+		binary.Write(w, order, length)
+	case vr == "OB" || vr == "OW" || vr == "SQ" || vr == "UN" || vr == "UT":
+		io.WriteString(w, vr+"\x00\x00")
+		binary.Write(w, order, length)
+	default:
+		io.WriteString(w, vr)
+		binary.Write(w, order, uint16(length))
+	}
+}
+
+// elem writes a data element, padding v to an even length.
+func (b *builder) elem(tag uint32, vr string, v []byte) {
+	if len(v)%2 != 0 {
+		pad := byte(' ')
+		if vr == "OB" || vr == "UI" {
+			pad = 0
+		}
+		v = append(v, pad)
+	}
+	b.header(tag, vr, uint32(len(v)))
+	b.data.Write(v)
+}
+
+func (b *builder) us(tag uint32, v int) {
+	p := make([]byte, 2)
+	b.order.PutUint16(p, uint16(v))
+	b.elem(tag, "US", p)
+}
+
+func (b *builder) str(tag uint32, vr, s string) {
+	b.elem(tag, vr, []byte(s))
+}
+
+// image writes the Image Pixel module attributes.
+func (b *builder) image(photometric string, rows, cols, bitsAllocated int) {
+	samples := 1
+	if photometric == "RGB" || photometric[:3] == "YBR" {
+		samples = 3
+	}
+	b.us(tagSamplesPerPixel, samples)
+	b.str(tagPhotometric, "CS", photometric)
+	b.us(tagRows, rows)
+	b.us(tagColumns, cols)
+	b.us(tagBitsAllocated, bitsAllocated)
+}
+
+// encapsulated writes encapsulated pixel data with an empty basic offset
+// table.
+func (b *builder) encapsulated(fragments ...[]byte) {
+	b.header(tagPixelData, "OB", undefinedLength)
+	b.header(tagItem, "", 0)
+	for _, f := range fragments {
+		b.header(tagItem, "", uint32(len(f)))
+		b.data.Write(f)
+	}
+	b.header(tagSequenceDelimitation, "", 0)
+}
+
+// bytes returns the file: the preamble, the File Meta Information and the
+// data set.
+func (b *builder) bytes() []byte {
+	f := new(bytes.Buffer)
+	f.Write(make([]byte, 128))
+	f.WriteString("DICM")
+	syntax := []byte(b.syntax)
+	if len(syntax)%2 != 0 {
+		syntax = append(syntax, 0)
+	}
+	writeHeader(f, binary.LittleEndian, true, 0x00020001, "OB", 2)
+	f.Write([]byte{0, 1})
+	writeHeader(f, binary.LittleEndian, true, tagTransferSyntax, "UI", uint32(len(syntax)))
+	f.Write(syntax)
+	if b.syntax == deflatedExplicitVRLittleEndian {
+		w, _ := flate.NewWriter(f, flate.DefaultCompression)
+		w.Write(b.data.Bytes())
+		w.Close()
+	} else {
+		f.Write(b.data.Bytes())
+	}
+	return f.Bytes()
+}
+
+// encodeRLE encodes segments of RLE Lossless data, each as a replicate run if
+// all of its bytes are the same, and otherwise as a literal run.
+func encodeRLE(segments ...[]byte) []byte {
+	out := make([]byte, rleHeaderLen)
+	binary.LittleEndian.PutUint32(out, uint32(len(segments)))
+	for i, s := range segments {
+		binary.LittleEndian.PutUint32(out[4+4*i:], uint32(len(out)))
+		if len(s) > 1 && bytes.Count(s, s[:1]) == len(s) {
+			out = append(out, byte(1-len(s)), s[0])
+		} else {
+			out = append(out, byte(len(s)-1))
+			out = append(out, s...)
+		}
+	}
+	return out
+}
+
+func checkGray16(t *testing.T, m image.Image, w, h int, want []uint16) {
+	t.Helper()
+	g, ok := m.(*image.Gray16)
+	if !ok {
+		t.Fatalf("got %T, want *image.Gray16", m)
+	}
+	if got := g.Bounds(); got != image.Rect(0, 0, w, h) {
+		t.Fatalf("got bounds %v, want %v", got, image.Rect(0, 0, w, h))
+	}
+	for i, v := range want {
+		if got := g.Gray16At(i%w, i/w).Y; got != v {
+			t.Errorf("pixel %d: got %#04x, want %#04x", i, got, v)
+		}
+	}
+}
+
+func TestMonochromeRescaleWindow(t *testing.T) {
+	for _, syntax := range []string{
+		implicitVRLittleEndian,
+		explicitVRLittleEndian,
+		explicitVRBigEndian,
+		deflatedExplicitVRLittleEndian,
+	} {
+		b := newBuilder(syntax)
+		b.image("MONOCHROME2", 2, 3, 16)
+		b.us(tagBitsStored, 12)
+		b.us(tagHighBit, 11)
+		b.us(tagPixelRepresentation, 1)
+		b.str(tagWindowCenter, "DS", "0.5\\40")
+		b.str(tagWindowWidth, "DS", "201\\80")
+		b.str(tagRescaleIntercept, "DS", "-100")
+		b.str(tagRescaleSlope, "DS", "2")
+		// The rescaled values are -2048, -100, -50, 0, 100 and 2046. The
+		// unused high bits are set, and must be ignored.
+		stored := []int{-974, 0, 25, 50, 100, 1073}
+		pix := make([]byte, 2*len(stored))
+		for i, s := range stored {
+			b.order.PutUint16(pix[2*i:], uint16(s)&0xfff|0xa000)
+		}
+		b.elem(tagPixelData, "OW", pix)
+
+		m, err := Decode(bytes.NewReader(b.bytes()))
+		if err != nil {
+			t.Fatalf("%s: %v", syntax, err)
+		}
+		checkGray16(t, m, 3, 2, []uint16{0, 0, 0x4000, 0x8000, 0xffff, 0xffff})
+	}
+}
+
+func TestMonochrome1DefaultWindow(t *testing.T) {
+	b := newBuilder(implicitVRLittleEndian)
+	b.image("MONOCHROME1", 1, 3, 8)
+	b.elem(tagPixelData, "OB", []byte{0, 255, 51, 0})
+	m, err := Decode(bytes.NewReader(b.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGray16(t, m, 3, 1, []uint16{0xffff, 0, 0xcccc})
+
+	// A window given as an option overrides the default one.
+	m, err = DecodeWithOptions(bytes.NewReader(b.bytes()), &DecodeOptions{Window: &Window{Center: 51.5, Width: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGray16(t, m, 3, 1, []uint16{0xffff, 0, 0xffff})
+}
+
+func TestColor(t *testing.T) {
+	testCases := []struct {
+		photometric string
+		planar      int
+		pix         []byte
+		want        []color.RGBA
+	}{
+		{"RGB", 0, []byte{1, 3, 5, 2, 4, 6}, []color.RGBA{{1, 3, 5, 0xff}, {2, 4, 6, 0xff}}},
+		{"RGB", 1, []byte{1, 2, 3, 4, 5, 6}, []color.RGBA{{1, 3, 5, 0xff}, {2, 4, 6, 0xff}}},
+		{"YBR_FULL", 0, []byte{0x80, 0x80, 0x80, 0xff, 0x80, 0x80}, []color.RGBA{{0x80, 0x80, 0x80, 0xff}, {0xff, 0xff, 0xff, 0xff}}},
+	}
+	for _, tc := range testCases {
+		b := newBuilder(explicitVRBigEndian)
+		b.image(tc.photometric, 1, 2, 8)
+		b.us(tagPlanarConfiguration, tc.planar)
+		b.elem(tagPixelData, "OB", tc.pix)
+		m, err := Decode(bytes.NewReader(b.bytes()))
+		if err != nil {
+			t.Fatalf("%s %d: %v", tc.photometric, tc.planar, err)
+		}
+		rgba, ok := m.(*image.RGBA)
+		if !ok {
+			t.Fatalf("%s %d: got %T, want *image.RGBA", tc.photometric, tc.planar, m)
+		}
+		for x, want := range tc.want {
+			if got := rgba.RGBAAt(x, 0); got != want {
+				t.Errorf("%s %d: pixel %d: got %v, want %v", tc.photometric, tc.planar, x, got, want)
+			}
+		}
+	}
+}
+
+func TestRLE(t *testing.T) {
+	// Two frames of 16 bit grayscale, with one segment for the high bytes and
+	// one for the low bytes.
+	b := newBuilder(rleLossless)
+	b.image("MONOCHROME2", 2, 2, 16)
+	b.str(tagNumberOfFrames, "IS", "2")
+	b.str(tagWindowCenter, "DS", "32768")
+	b.str(tagWindowWidth, "DS", "65536")
+	b.encapsulated(
+		encodeRLE([]byte{0x00, 0x00, 0x00, 0x00}, []byte{0x00, 0x00, 0x00, 0x00}),
+		encodeRLE([]byte{0x00, 0x00, 0x40, 0xff}, []byte{0x00, 0x12, 0x00, 0xff}),
+	)
+	m, err := DecodeWithOptions(bytes.NewReader(b.bytes()), &DecodeOptions{Frame: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The window maps each value v to about v+0.5.
+	checkGray16(t, m, 2, 2, []uint16{0x0000, 0x0012, 0x4000, 0xffff})
+
+	// An RGB frame, with one segment for each sample.
+	b = newBuilder(rleLossless)
+	b.image("RGB", 1, 3, 8)
+	b.encapsulated(encodeRLE([]byte{1, 2, 3}, []byte{4, 4, 4}, []byte{7, 8, 9}))
+	m, err = Decode(bytes.NewReader(b.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := m.(*image.RGBA)
+	for x, want := range []color.RGBA{{1, 4, 7, 0xff}, {2, 4, 8, 0xff}, {3, 4, 9, 0xff}} {
+		if got := rgba.RGBAAt(x, 0); got != want {
+			t.Errorf("RGB: pixel %d: got %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestJPEG(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 16, 8))
+	for i := range src.Pix {
+		src.Pix[i] = 0x60
+	}
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, src, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	b := newBuilder("1.2.840.10008.1.2.4.50")
+	b.image("MONOCHROME2", 8, 16, 8)
+	b.str(tagWindowCenter, "DS", "128")
+	b.str(tagWindowWidth, "DS", "256")
+	b.encapsulated(buf.Bytes())
+	m, err := Decode(bytes.NewReader(b.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ok := m.(*image.Gray16)
+	if !ok || g.Bounds() != src.Bounds() {
+		t.Fatalf("got %T with bounds %v, want *image.Gray16 with bounds %v", m, m.Bounds(), src.Bounds())
+	}
+	if got := g.Gray16At(3, 3).Y >> 8; got < 0x5f || got > 0x61 {
+		t.Errorf("got %#02x, want about 0x60", got)
+	}
+
+	// JPEG 2000 is not registered with the image package.
+	b = newBuilder("1.2.840.10008.1.2.4.90")
+	b.image("MONOCHROME2", 8, 16, 8)
+	b.encapsulated([]byte("\xff\x4f\xff\x51"))
+	if _, err := Decode(bytes.NewReader(b.bytes())); err == nil {
+		t.Error("JPEG 2000: got nil error")
+	} else if _, ok := err.(UnsupportedError); !ok {
+		t.Errorf("JPEG 2000: got %v, want an UnsupportedError", err)
+	}
+}
+
+func TestSkipSequences(t *testing.T) {
+	b := newBuilder(explicitVRLittleEndian)
+	b.str(0x00080060, "CS", "CT")
+	// A sequence of undefined length, holding an item of undefined length
+	// and an item of defined length.
+	b.header(0x00081140, "SQ", undefinedLength)
+	b.header(tagItem, "", undefinedLength)
+	b.str(0x00081150, "UI", "1.2.3")
+	b.header(0x00089215, "SQ", undefinedLength)
+	b.header(tagSequenceDelimitation, "", 0)
+	b.header(tagItemDelimitation, "", 0)
+	b.header(tagItem, "", 4)
+	b.data.Write([]byte{1, 2, 3, 4})
+	b.header(tagSequenceDelimitation, "", 0)
+	// A UN element of undefined length, which is in the Implicit VR
+	// syntax.
+	b.header(0x00091001, "UN", undefinedLength)
+	b.header(tagItem, "", undefinedLength)
+	writeHeader(&b.data, binary.LittleEndian, false, 0x00091002, "", 2)
+	b.data.Write([]byte{0, 0})
+	b.header(tagItemDelimitation, "", 0)
+	b.header(tagSequenceDelimitation, "", 0)
+	b.image("MONOCHROME2", 1, 2, 8)
+	b.elem(tagPixelData, "OB", []byte{0, 255})
+
+	m, err := Decode(bytes.NewReader(b.bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGray16(t, m, 2, 1, []uint16{0, 0xffff})
+}
+
+func TestDecodeConfig(t *testing.T) {
+	b := newBuilder(explicitVRLittleEndian)
+	b.image("RGB", 20, 30, 8)
+	b.elem(tagPixelData, "OB", make([]byte, 20*30*3))
+	data := b.bytes()
+
+	c, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Width != 30 || c.Height != 20 || c.ColorModel != color.RGBAModel {
+		t.Errorf("got %+v", c)
+	}
+
+	// The format is registered with the image package.
+	_, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || name != "dicom" {
+		t.Errorf("image.DecodeConfig: got %q, %v, want \"dicom\", nil", name, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	mono := func(syntax string, frames int, pix []byte) []byte {
+		b := newBuilder(syntax)
+		b.image("MONOCHROME2", 2, 2, 8)
+		b.str(tagNumberOfFrames, "IS", strconv.Itoa(frames))
+		b.elem(tagPixelData, "OB", pix)
+		return b.bytes()
+	}
+	valid := mono(explicitVRLittleEndian, 2, make([]byte, 8))
+	noPrefix := append([]byte(nil), valid...)
+	copy(noPrefix[128:], "DICN")
+
+	testCases := []struct {
+		desc  string
+		data  []byte
+		frame int
+	}{
+		{"missing prefix", noPrefix, 0},
+		{"truncated", valid[:len(valid)-1], 1},
+		{"frame out of range", valid, 2},
+		{"negative frame", valid, -1},
+		{"not enough pixel data", mono(explicitVRLittleEndian, 3, make([]byte, 8)), 2},
+		{"unknown transfer syntax", mono("1.2.3.4", 1, make([]byte, 4)), 0},
+	}
+	for _, tc := range testCases {
+		if _, err := DecodeWithOptions(bytes.NewReader(tc.data), &DecodeOptions{Frame: tc.frame}); err == nil {
+			t.Errorf("%s: got nil error", tc.desc)
+		}
+	}
+}
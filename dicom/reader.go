@@ -0,0 +1,416 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dicom
+
+import (
+	"bufio"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Tags, as the group number in the high 16 bits and the element number in the
+// low 16 bits (see PS3.6).
+const (
+	tagTransferSyntax      = 0x00020010
+	tagSamplesPerPixel     = 0x00280002
+	tagPhotometric         = 0x00280004
+	tagPlanarConfiguration = 0x00280006
+	tagNumberOfFrames      = 0x00280008
+	tagRows                = 0x00280010
+	tagColumns             = 0x00280011
+	tagBitsAllocated       = 0x00280100
+	tagBitsStored          = 0x00280101
+	tagHighBit             = 0x00280102
+	tagPixelRepresentation = 0x00280103
+	tagWindowCenter        = 0x00281050
+	tagWindowWidth         = 0x00281051
+	tagRescaleIntercept    = 0x00281052
+	tagRescaleSlope        = 0x00281053
+	tagPixelData           = 0x7fe00010
+
+	tagItem                 = 0xfffee000
+	tagItemDelimitation     = 0xfffee00d
+	tagSequenceDelimitation = 0xfffee0dd
+)
+
+// undefinedLength is the length of a sequence, item or encapsulated pixel data
+// element whose end is marked by a delimitation item instead.
+const undefinedLength = 0xffffffff
+
+// maxAttributeLen is the maximum length of the value of an attribute other
+// than the pixel data that is read, rather than skipped.
+const maxAttributeLen = 1024
+
+// Transfer syntax UIDs (see PS3.5 section 10). The UIDs of the encapsulated
+// syntaxes, such as JPEG and JPEG 2000, all begin with encapsulatedPrefix.
+const (
+	implicitVRLittleEndian         = "1.2.840.10008.1.2"
+	explicitVRLittleEndian         = "1.2.840.10008.1.2.1"
+	deflatedExplicitVRLittleEndian = "1.2.840.10008.1.2.1.99"
+	explicitVRBigEndian            = "1.2.840.10008.1.2.2"
+	rleLossless                    = "1.2.840.10008.1.2.5"
+	encapsulatedPrefix             = "1.2.840.10008.1.2.4."
+)
+
+// decoder reads the attributes of a DICOM file up to its pixel data.
+type decoder struct {
+	r        *bufio.Reader
+	order    binary.ByteOrder
+	explicit bool
+	syntax   string
+	tmp      [8]byte
+
+	// The attributes of the image, from the Image Pixel and VOI LUT modules
+	// (PS3.3 C.7.6.3 and C.11.2).
+	samples       int
+	photometric   string
+	planar        int
+	frames        int
+	rows, cols    int
+	bitsAllocated int
+	bitsStored    int
+	highBit       int
+	signed        bool
+	window        *Window
+	slope         float64
+	intercept     float64
+
+	// pixelDataLen is the length of the pixel data element.
+	pixelDataLen uint32
+}
+
+// readHeader reads the preamble and the File Meta Information (PS3.10
+// section 7.1) of r, and every data element before the pixel data, returning
+// a decoder positioned at the pixel data's value.
+func readHeader(r io.Reader) (*decoder, error) {
+	d := &decoder{
+		r:        bufio.NewReader(r),
+		order:    binary.LittleEndian,
+		explicit: true,
+		samples:  1,
+		frames:   1,
+		highBit:  -1,
+		slope:    1,
+	}
+	p := make([]byte, 132)
+	if _, err := io.ReadFull(d.r, p); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	if string(p[128:]) != "DICM" {
+		return nil, FormatError("missing DICM prefix")
+	}
+
+	// The File Meta Information is group 2, in the Explicit VR Little Endian
+	// transfer syntax.
+	for {
+		g, err := d.r.Peek(2)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if binary.LittleEndian.Uint16(g) != 2 {
+			break
+		}
+		tag, vr, length, err := d.readElementHeader()
+		if err != nil {
+			return nil, err
+		}
+		if tag != tagTransferSyntax {
+			if err := d.skipValue(vr, length); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		v, err := d.readValue(length)
+		if err != nil {
+			return nil, err
+		}
+		d.syntax = trim(v)
+	}
+
+	switch {
+	case d.syntax == implicitVRLittleEndian:
+		d.explicit = false
+	case d.syntax == explicitVRBigEndian:
+		d.order = binary.BigEndian
+	case d.syntax == deflatedExplicitVRLittleEndian:
+		d.r = bufio.NewReader(flate.NewReader(d.r))
+	case d.syntax == explicitVRLittleEndian, d.syntax == rleLossless, strings.HasPrefix(d.syntax, encapsulatedPrefix):
+	case d.syntax == "":
+		return nil, FormatError("missing transfer syntax")
+	default:
+		return nil, UnsupportedError("transfer syntax " + d.syntax)
+	}
+
+	for {
+		tag, vr, length, err := d.readElementHeader()
+		if err != nil {
+			if err == io.EOF {
+				err = FormatError("missing pixel data")
+			}
+			return nil, err
+		}
+		if tag == tagPixelData {
+			d.pixelDataLen = length
+			return d, d.check()
+		}
+		if err := d.readAttribute(tag, vr, length); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readElementHeader reads a data element's tag, value representation (VR)
+// and value length (PS3.5 section 7.1). The VR is empty for the Implicit VR
+// transfer syntax, and for items and delimitation items.
+func (d *decoder) readElementHeader() (tag uint32, vr string, length uint32, err error) {
+	if _, err = io.ReadFull(d.r, d.tmp[:4]); err != nil {
+		return 0, "", 0, err
+	}
+	tag = uint32(d.order.Uint16(d.tmp[0:2]))<<16 | uint32(d.order.Uint16(d.tmp[2:4]))
+	if tag>>16 == 0xfffe || !d.explicit {
+		if _, err = io.ReadFull(d.r, d.tmp[:4]); err != nil {
+			return 0, "", 0, unexpectedEOF(err)
+		}
+		return tag, "", d.order.Uint32(d.tmp[:4]), nil
+	}
+	if _, err = io.ReadFull(d.r, d.tmp[:4]); err != nil {
+		return 0, "", 0, unexpectedEOF(err)
+	}
+	vr = string(d.tmp[:2])
+	switch vr {
+	case "OB", "OD", "OF", "OL", "OV", "OW", "SQ", "SV", "UC", "UN", "UR", "UT", "UV":
+		// These VRs have two reserved bytes and a 32 bit length.
+		if _, err = io.ReadFull(d.r, d.tmp[4:8]); err != nil {
+			return 0, "", 0, unexpectedEOF(err)
+		}
+		return tag, vr, d.order.Uint32(d.tmp[4:8]), nil
+	}
+	return tag, vr, uint32(d.order.Uint16(d.tmp[2:4])), nil
+}
+
+// readValue reads a value of length bytes.
+func (d *decoder) readValue(length uint32) ([]byte, error) {
+	if length == undefinedLength {
+		return nil, FormatError("undefined length value")
+	}
+	// Reading through a LimitReader, rather than allocating length bytes up
+	// front, bounds the memory used for truncated or malicious input.
+	v, err := ioutil.ReadAll(io.LimitReader(d.r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if len(v) != int(length) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return v, nil
+}
+
+// skipValue skips a value of length bytes, or a sequence of undefined length.
+func (d *decoder) skipValue(vr string, length uint32) error {
+	if length != undefinedLength {
+		_, err := d.r.Discard(int(length))
+		return unexpectedEOF(err)
+	}
+	if vr == "UN" {
+		// A UN value of undefined length is a sequence in the Implicit VR
+		// Little Endian transfer syntax (PS3.5 section 6.2.2).
+		explicit, order := d.explicit, d.order
+		d.explicit, d.order = false, binary.LittleEndian
+		defer func() { d.explicit, d.order = explicit, order }()
+	}
+	return d.skipSequence()
+}
+
+// skipSequence skips the items of a sequence of undefined length, up to and
+// including its sequence delimitation item.
+func (d *decoder) skipSequence() error {
+	for {
+		tag, _, length, err := d.readElementHeader()
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		switch tag {
+		case tagSequenceDelimitation:
+			return nil
+		case tagItem:
+			if length != undefinedLength {
+				if _, err := d.r.Discard(int(length)); err != nil {
+					return unexpectedEOF(err)
+				}
+				continue
+			}
+			// The item's data elements end with an item delimitation item.
+			for {
+				tag, vr, length, err := d.readElementHeader()
+				if err != nil {
+					return unexpectedEOF(err)
+				}
+				if tag == tagItemDelimitation {
+					break
+				}
+				if err := d.skipValue(vr, length); err != nil {
+					return err
+				}
+			}
+		default:
+			return FormatError("bad sequence item")
+		}
+	}
+}
+
+// readAttribute reads the value of a data element, keeping it if it is one
+// of the attributes of the image.
+func (d *decoder) readAttribute(tag uint32, vr string, length uint32) error {
+	switch tag {
+	case tagSamplesPerPixel, tagPhotometric, tagPlanarConfiguration, tagNumberOfFrames,
+		tagRows, tagColumns, tagBitsAllocated, tagBitsStored, tagHighBit,
+		tagPixelRepresentation, tagWindowCenter, tagWindowWidth,
+		tagRescaleIntercept, tagRescaleSlope:
+	default:
+		return d.skipValue(vr, length)
+	}
+	if length > maxAttributeLen {
+		return FormatError("attribute too long")
+	}
+	v, err := d.readValue(length)
+	if err != nil {
+		return err
+	}
+
+	// us decodes a value of the US (unsigned short) VR.
+	us := func() (int, error) {
+		if len(v) < 2 {
+			return 0, FormatError("bad US value")
+		}
+		return int(d.order.Uint16(v)), nil
+	}
+	switch tag {
+	case tagSamplesPerPixel:
+		d.samples, err = us()
+	case tagPhotometric:
+		d.photometric = trim(v)
+	case tagPlanarConfiguration:
+		d.planar, err = us()
+	case tagNumberOfFrames:
+		d.frames, err = strconv.Atoi(firstValue(v))
+		if err != nil {
+			err = FormatError("bad NumberOfFrames")
+		}
+	case tagRows:
+		d.rows, err = us()
+	case tagColumns:
+		d.cols, err = us()
+	case tagBitsAllocated:
+		d.bitsAllocated, err = us()
+	case tagBitsStored:
+		d.bitsStored, err = us()
+	case tagHighBit:
+		d.highBit, err = us()
+	case tagPixelRepresentation:
+		var rep int
+		rep, err = us()
+		d.signed = rep == 1
+	case tagWindowCenter, tagWindowWidth:
+		var f float64
+		if f, err = decimal(v); err != nil {
+			break
+		}
+		if d.window == nil {
+			d.window = &Window{}
+		}
+		if tag == tagWindowCenter {
+			d.window.Center = f
+		} else {
+			d.window.Width = f
+		}
+	case tagRescaleIntercept:
+		d.intercept, err = decimal(v)
+	case tagRescaleSlope:
+		d.slope, err = decimal(v)
+	}
+	return err
+}
+
+// check checks that the image attributes describe an image that can be
+// decoded.
+func (d *decoder) check() error {
+	if d.rows <= 0 || d.cols <= 0 {
+		return FormatError("missing or zero Rows or Columns")
+	}
+	if d.frames <= 0 {
+		return FormatError("bad NumberOfFrames")
+	}
+	switch d.photometric {
+	case "MONOCHROME1", "MONOCHROME2":
+		if d.samples != 1 {
+			return FormatError("wrong SamplesPerPixel for " + d.photometric)
+		}
+		if d.bitsAllocated != 8 && d.bitsAllocated != 16 {
+			return UnsupportedError("BitsAllocated of " + strconv.Itoa(d.bitsAllocated))
+		}
+	case "RGB", "YBR_FULL", "YBR_FULL_422":
+		if d.samples != 3 {
+			return FormatError("wrong SamplesPerPixel for " + d.photometric)
+		}
+		if d.bitsAllocated != 8 {
+			return UnsupportedError("BitsAllocated of " + strconv.Itoa(d.bitsAllocated) + " for color")
+		}
+	case "":
+		return FormatError("missing PhotometricInterpretation")
+	default:
+		return UnsupportedError("PhotometricInterpretation " + d.photometric)
+	}
+	if d.bitsStored == 0 {
+		d.bitsStored = d.bitsAllocated
+	}
+	if d.highBit < 0 {
+		d.highBit = d.bitsStored - 1
+	}
+	if d.bitsStored > d.bitsAllocated || d.highBit >= d.bitsAllocated || d.highBit < d.bitsStored-1 {
+		return FormatError("bad BitsStored or HighBit")
+	}
+	if d.window != nil && d.window.Width < 1 {
+		// A window width must be at least 1 (PS3.3 C.11.2.1.2), so ignore
+		// an invalid or missing one.
+		d.window = nil
+	}
+	return nil
+}
+
+// trim returns the string value v without its padding.
+func trim(v []byte) string {
+	return strings.TrimRight(string(v), " \x00")
+}
+
+// firstValue returns the first of the backslash separated values of a string
+// value v, without padding.
+func firstValue(v []byte) string {
+	s := string(v)
+	if i := strings.IndexByte(s, '\\'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.Trim(s, " \x00")
+}
+
+// decimal decodes the first value of a DS (decimal string) value.
+func decimal(v []byte) (float64, error) {
+	f, err := strconv.ParseFloat(firstValue(v), 64)
+	if err != nil {
+		return 0, FormatError("bad decimal string")
+	}
+	return f, nil
+}
+
+// unexpectedEOF returns err, with io.EOF replaced by io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dicom
+
+import (
+	"encoding/binary"
+)
+
+// rleHeaderLen is the length in bytes of the header of an RLE Lossless frame,
+// which holds the number of segments and the offsets of up to 15 segments.
+const rleHeaderLen = 64
+
+// decodeRLE decodes a frame of RLE Lossless data (PS3.5 Annex G) of n pixels,
+// each of samples samples of bytesPerSample bytes. It returns the pixels'
+// samples interleaved, with multi-byte samples in little-endian order.
+func decodeRLE(data []byte, n, samples, bytesPerSample int) ([]byte, error) {
+	if len(data) < rleHeaderLen {
+		return nil, FormatError("short RLE header")
+	}
+	numSegments := int(binary.LittleEndian.Uint32(data[0:4]))
+	if numSegments != samples*bytesPerSample {
+		return nil, FormatError("wrong number of RLE segments")
+	}
+	stride := samples * bytesPerSample
+	dst := make([]byte, n*stride)
+	for s := 0; s < numSegments; s++ {
+		start := int(binary.LittleEndian.Uint32(data[4+4*s:]))
+		end := len(data)
+		if s+1 < numSegments {
+			end = int(binary.LittleEndian.Uint32(data[8+4*s:]))
+		}
+		if start < rleHeaderLen || start > end || end > len(data) {
+			return nil, FormatError("bad RLE segment offset")
+		}
+		// Each segment holds one byte of every pixel's sample, with the
+		// samples in order and the most significant bytes first.
+		sample, byteIndex := s/bytesPerSample, bytesPerSample-1-s%bytesPerSample
+		if err := unpackBits(dst[sample*bytesPerSample+byteIndex:], stride, n, data[start:end]); err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// unpackBits decodes n bytes of the PackBits-like segment src (PS3.5 section
+// G.3) into dst[0], dst[stride], dst[2*stride] and so on.
+func unpackBits(dst []byte, stride, n int, src []byte) error {
+	i := 0
+	for i < n && len(src) > 0 {
+		c := int8(src[0])
+		src = src[1:]
+		switch {
+		case c >= 0:
+			// Copy the next c+1 bytes literally.
+			k := int(c) + 1
+			if len(src) < k {
+				return FormatError("short RLE literal run")
+			}
+			for _, b := range src[:k] {
+				if i == n {
+					break
+				}
+				dst[i*stride] = b
+				i++
+			}
+			src = src[k:]
+		case c != -128:
+			// Repeat the next byte 1-c times.
+			if len(src) < 1 {
+				return FormatError("short RLE replicate run")
+			}
+			b := src[0]
+			src = src[1:]
+			for k := 1 - int(c); k > 0 && i < n; k-- {
+				dst[i*stride] = b
+				i++
+			}
+		}
+	}
+	if i < n {
+		return FormatError("not enough RLE data")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// TransformClip is like t.Transform but only affects the dst pixels inside
+// clip, such as the dirty rectangle of an interactive viewer after panning or
+// zooming. Those pixels are the same, up to rounding, as if t.Transform had
+// drawn the whole of sr transformed by m.
+//
+// For the NearestNeighbor and ApproxBiLinear interpolators and Kernels,
+// only the part of sr that affects the pixels inside clip is read: the
+// pixels whose centers map to within the interpolator's support of clip
+// mapped back to src space. Other Transformers, and an EdgeOp other than
+// EdgeNone, for which the edges of sr matter, read all of sr as usual.
+func TransformClip(t Transformer, dst Image, clip image.Rectangle, m f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if d, ok := subImage(dst, clip); ok {
+		dst = d
+	} else if o.DstMask == nil {
+		o.DstMask, o.DstMaskP = clip, image.Point{}
+	} else {
+		dst = &clippedImage{dst, clip}
+	}
+
+	if d2s, ok := m.Invert(); ok && o.EdgeOp == EdgeNone {
+		if margin, ok := transformMargin(t, &d2s); ok {
+			sr = sr.Intersect(transformRect(&d2s, &clip).Inset(-margin))
+			if sr.Empty() {
+				return
+			}
+		}
+	}
+	t.Transform(dst, m, src, sr, op, &o)
+}
+
+// subImage returns the result of dst's SubImage method, such as that of an
+// *image.RGBA, if it has one that returns an Image.
+func subImage(dst Image, r image.Rectangle) (Image, bool) {
+	s, ok := dst.(interface {
+		SubImage(image.Rectangle) image.Image
+	})
+	if !ok {
+		return nil, false
+	}
+	d, ok := s.SubImage(r).(Image)
+	return d, ok
+}
+
+// clippedImage is an Image whose bounds are clipped to r.
+type clippedImage struct {
+	Image
+	r image.Rectangle
+}
+
+func (m *clippedImage) Bounds() image.Rectangle { return m.Image.Bounds().Intersect(m.r) }
+
+// transformMargin returns how far, in src pixels, t reads beyond the src
+// point that a dst pixel's center maps to by d2s, and whether that is known.
+func transformMargin(t Transformer, d2s *f64.Aff3) (int, bool) {
+	switch t := t.(type) {
+	case nnInterpolator:
+		return 1, true
+	case ablInterpolator:
+		return 2, true
+	case *Kernel:
+		// As for Kernel.Transform, the support is broadened when shrinking.
+		scale := math.Max(1, math.Max(
+			math.Max(abs(d2s[0]), abs(d2s[1])),
+			math.Max(abs(d2s[3]), abs(d2s[4])),
+		))
+		return int(math.Ceil(t.Support*scale)) + 1, true
+	}
+	return 0, false
+}
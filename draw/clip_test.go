@@ -0,0 +1,89 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestTransformClip(t *testing.T) {
+	src := image.NewRGBA(image.Rect(3, 5, 43, 35))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 13)
+	}
+	clip := image.Rect(11, 7, 23, 19)
+
+	for _, tc := range []struct {
+		name string
+		m    f64.Aff3
+	}{
+		{"rotate", RotationMatrix(0.3, f64.Vec2{20, 20})},
+		{"up", f64.Aff3{1.7, 0.2, -4, -0.1, 1.5, 2}},
+		{"down", f64.Aff3{0.41, 0.1, 1.03, -0.05, 0.31, 3.07}},
+	} {
+		for _, q := range []Transformer{NearestNeighbor, ApproxBiLinear, CatmullRom, Box} {
+			want := image.NewRGBA(image.Rect(0, 0, 40, 40))
+			q.Transform(want, tc.m, src, src.Bounds(), Over, nil)
+
+			// An *image.RGBA dst is clipped by its SubImage method, and
+			// other dsts by a DstMask or by wrapping them.
+			for _, mask := range []image.Image{nil, image.Rect(0, 0, 40, 40)} {
+				for _, wrap := range []bool{false, true} {
+					got := image.NewRGBA(want.Rect)
+					var dst Image = got
+					if wrap {
+						dst = struct{ Image }{got}
+					}
+					TransformClip(q, dst, clip, tc.m, src, src.Bounds(), Over, &Options{DstMask: mask})
+					for y := got.Rect.Min.Y; y < got.Rect.Max.Y; y++ {
+						for x := got.Rect.Min.X; x < got.Rect.Max.X; x++ {
+							w := want.RGBAAt(x, y)
+							if !(image.Point{x, y}).In(clip) {
+								w = color.RGBA{}
+							}
+							// The pixels may differ by rounding, as the
+							// transforms use the clipped pixels' offsets.
+							if g := got.RGBAAt(x, y); !sameRGBA(g, w, 1) {
+								t.Fatalf("%s, %T, mask %v, wrap %t: (%d, %d): got %v, want %v",
+									tc.name, q, mask, wrap, x, y, g, w)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Pixels outside clip are unaffected.
+	dst := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for i := range dst.Pix {
+		dst.Pix[i] = uint8(i % 4 * 0x22)
+	}
+	before := append([]uint8(nil), dst.Pix...)
+	TransformClip(CatmullRom, dst, clip, f64.Scale(math.Sqrt2, 1), src, src.Bounds(), Src, nil)
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			i := dst.PixOffset(x, y)
+			if !(image.Point{x, y}).In(clip) && string(dst.Pix[i:i+4]) != string(before[i:i+4]) {
+				t.Fatalf("(%d, %d): outside clip: changed", x, y)
+			}
+		}
+	}
+}
+
+func sameRGBA(c0, c1 color.RGBA, delta int) bool {
+	for i, v0 := range [4]uint8{c0.R, c0.G, c0.B, c0.A} {
+		v1 := [4]uint8{c1.R, c1.G, c1.B, c1.A}[i]
+		if d := int(v0) - int(v1); d < -delta || d > delta {
+			return false
+		}
+	}
+	return true
+}
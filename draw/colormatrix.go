@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+)
+
+// ColorMatrix is an affine color transform, a 4x5 matrix in row-major order.
+// Each row gives one of the red, green, blue and alpha output channels as a
+// weighted sum of the input red, green, blue and alpha channels plus a
+// constant. The channels are non-alpha-premultiplied values in the range
+// [0, 1], and the outputs are clamped to that range.
+//
+// For example, this ColorMatrix swaps the red and blue channels:
+//
+//	draw.ColorMatrix{
+//		0, 0, 1, 0, 0,
+//		0, 1, 0, 0, 0,
+//		1, 0, 0, 0, 0,
+//		0, 0, 0, 1, 0,
+//	}
+type ColorMatrix [20]float64
+
+// Luma weights of the red, green and blue channels, the same as those of the
+// standard library's color.GrayModel.
+const (
+	lumaR = 0.299
+	lumaG = 0.587
+	lumaB = 0.114
+)
+
+// IdentityMatrix returns the ColorMatrix that leaves colors unchanged.
+func IdentityMatrix() *ColorMatrix {
+	return &ColorMatrix{
+		1, 0, 0, 0, 0,
+		0, 1, 0, 0, 0,
+		0, 0, 1, 0, 0,
+		0, 0, 0, 1, 0,
+	}
+}
+
+// GrayscaleMatrix returns the ColorMatrix that converts colors to their luma,
+// as color.GrayModel does.
+func GrayscaleMatrix() *ColorMatrix {
+	return SaturationMatrix(0)
+}
+
+// SaturationMatrix returns the ColorMatrix that scales the saturation of
+// colors by s. Zero means grayscale, one means unchanged and values greater
+// than one increase the saturation.
+func SaturationMatrix(s float64) *ColorMatrix {
+	r, g, b := (1-s)*lumaR, (1-s)*lumaG, (1-s)*lumaB
+	return &ColorMatrix{
+		r + s, g, b, 0, 0,
+		r, g + s, b, 0, 0,
+		r, g, b + s, 0, 0,
+		0, 0, 0, 1, 0,
+	}
+}
+
+// SepiaMatrix returns the ColorMatrix that gives colors a sepia tone.
+func SepiaMatrix() *ColorMatrix {
+	return &ColorMatrix{
+		0.393, 0.769, 0.189, 0, 0,
+		0.349, 0.686, 0.168, 0, 0,
+		0.272, 0.534, 0.131, 0, 0,
+		0, 0, 0, 1, 0,
+	}
+}
+
+// Mul returns the ColorMatrix that applies n and then m.
+func (m *ColorMatrix) Mul(n *ColorMatrix) *ColorMatrix {
+	p := &ColorMatrix{}
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 5; j++ {
+			v := 0.0
+			for k := 0; k < 4; k++ {
+				v += m[5*i+k] * n[5*k+j]
+			}
+			if j == 4 {
+				v += m[5*i+4]
+			}
+			p[5*i+j] = v
+		}
+	}
+	return p
+}
+
+// Apply returns c transformed by m.
+func (m *ColorMatrix) Apply(c color.Color) color.RGBA64 {
+	r, g, b, a := c.RGBA()
+	var in [4]float64
+	if a != 0 {
+		fa := float64(a)
+		in = [4]float64{float64(r) / fa, float64(g) / fa, float64(b) / fa, fa / 0xffff}
+	}
+	var out [4]float64
+	for i := range out {
+		v := m[5*i+0]*in[0] + m[5*i+1]*in[1] + m[5*i+2]*in[2] + m[5*i+3]*in[3] + m[5*i+4]
+		if v < 0 {
+			v = 0
+		} else if v > 1 {
+			v = 1
+		}
+		out[i] = v
+	}
+	oa := out[3] * 0xffff
+	return color.RGBA64{
+		R: uint16(out[0]*oa + 0.5),
+		G: uint16(out[1]*oa + 0.5),
+		B: uint16(out[2]*oa + 0.5),
+		A: uint16(oa + 0.5),
+	}
+}
+
+// colorMatrixImage is src with each pixel transformed by a ColorMatrix. It
+// lets the generic image.Image code paths apply the transform in the same
+// pass as scaling.
+type colorMatrixImage struct {
+	src image.Image
+	m   ColorMatrix
+}
+
+// applyColorMatrix returns src wrapped to apply o.ColorMatrix, if any, and
+// clears o.ColorMatrix so that it is only applied once.
+func applyColorMatrix(src image.Image, o *Options) image.Image {
+	if o.ColorMatrix == nil {
+		return src
+	}
+	m := &colorMatrixImage{src: src, m: *o.ColorMatrix}
+	o.ColorMatrix = nil
+	return m
+}
+
+func (m *colorMatrixImage) ColorModel() color.Model { return color.RGBA64Model }
+
+func (m *colorMatrixImage) Bounds() image.Rectangle { return m.src.Bounds() }
+
+func (m *colorMatrixImage) At(x, y int) color.Color { return m.m.Apply(m.src.At(x, y)) }
@@ -0,0 +1,117 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestColorMatrixApply(t *testing.T) {
+	swap := &ColorMatrix{
+		0, 0, 1, 0, 0,
+		0, 1, 0, 0, 0,
+		1, 0, 0, 0, 0,
+		0, 0, 0, 1, 0,
+	}
+	invert := &ColorMatrix{
+		-1, 0, 0, 0, 1,
+		0, -1, 0, 0, 1,
+		0, 0, -1, 0, 1,
+		0, 0, 0, 1, 0,
+	}
+	testCases := []struct {
+		desc string
+		m    *ColorMatrix
+		c    color.Color
+		want color.RGBA64
+	}{
+		{"identity", IdentityMatrix(), color.RGBA64{0x1000, 0x2000, 0x3000, 0x8000}, color.RGBA64{0x1000, 0x2000, 0x3000, 0x8000}},
+		{"swap", swap, color.RGBA64{0x1000, 0x2000, 0x3000, 0x8000}, color.RGBA64{0x3000, 0x2000, 0x1000, 0x8000}},
+		{"invert", invert, color.RGBA64{0x2000, 0x4000, 0x8000, 0x8000}, color.RGBA64{0x6000, 0x4000, 0x0000, 0x8000}},
+		{"gray", GrayscaleMatrix(), color.RGBA{0xff, 0x00, 0x00, 0xff}, color.RGBA64{0x4c8b, 0x4c8b, 0x4c8b, 0xffff}},
+		{"saturate", SaturationMatrix(2), color.RGBA{0x80, 0x80, 0x80, 0xff}, color.RGBA64{0x8080, 0x8080, 0x8080, 0xffff}},
+		{"clamp", SaturationMatrix(2), color.RGBA{0xff, 0x00, 0x00, 0xff}, color.RGBA64{0xffff, 0x0000, 0x0000, 0xffff}},
+		{"transparent", swap, color.Transparent, color.RGBA64{}},
+	}
+	for _, tc := range testCases {
+		if got := tc.m.Apply(tc.c); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+
+	// Mul applies its argument first.
+	got := invert.Mul(GrayscaleMatrix()).Apply(color.RGBA{0xff, 0x00, 0x00, 0xff})
+	want := color.RGBA64{0xb374, 0xb374, 0xb374, 0xffff}
+	if got != want {
+		t.Errorf("Mul: got %v, want %v", got, want)
+	}
+}
+
+// TestColorMatrix tests that applying a ColorMatrix during a Copy, Scale or
+// Transform is equivalent to applying it to the src image beforehand.
+func TestColorMatrix(t *testing.T) {
+	m := SepiaMatrix().Mul(SaturationMatrix(0.5))
+	m[18] = 0.75 // Scale the alpha channel.
+	src, err := srcNRGBA(image.Rect(0, 0, 40, 30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pre := image.NewRGBA64(src.Bounds())
+	for y := pre.Rect.Min.Y; y < pre.Rect.Max.Y; y++ {
+		for x := pre.Rect.Min.X; x < pre.Rect.Max.X; x++ {
+			pre.SetRGBA64(x, y, m.Apply(src.At(x, y)))
+		}
+	}
+	sr := image.Rect(2, 3, 37, 28)
+	s2d := f64.Aff3{0.8, 0.3, 5, -0.2, 1.1, 10}
+	proj := f64.Mat3{0.8, 0.3, 5, -0.2, 1.1, 10, 0.001, 0.002, 1}
+
+	funcs := map[string]func(dst Image, src image.Image, opts *Options){
+		"Copy": func(dst Image, src image.Image, opts *Options) {
+			Copy(dst, image.Point{5, 6}, src, sr, Over, opts)
+		},
+		"NNScale": func(dst Image, src image.Image, opts *Options) {
+			NearestNeighbor.Scale(dst, image.Rect(0, 0, 50, 40), src, sr, Over, opts)
+		},
+		"CRScale": func(dst Image, src image.Image, opts *Options) {
+			CatmullRom.Scale(dst, image.Rect(0, 0, 50, 40), src, sr, Src, opts)
+		},
+		"ABLTransform": func(dst Image, src image.Image, opts *Options) {
+			ApproxBiLinear.Transform(dst, s2d, src, sr, Over, opts)
+		},
+		"CRTransform": func(dst Image, src image.Image, opts *Options) {
+			CatmullRom.Transform(dst, s2d, src, sr, Over, opts)
+		},
+		"CRTransformEdge": func(dst Image, src image.Image, opts *Options) {
+			o := *opts
+			o.EdgeOp = EdgeMirror
+			CatmullRom.Transform(dst, s2d, src, sr, Over, &o)
+		},
+		"BLTransformProjective": func(dst Image, src image.Image, opts *Options) {
+			BiLinear.TransformProjective(dst, proj, src, sr, Over, opts)
+		},
+	}
+	for name, f := range funcs {
+		var dsts [2]*image.RGBA
+		for i := range dsts {
+			dsts[i] = image.NewRGBA(image.Rect(0, 0, 60, 50))
+			for j := range dsts[i].Pix {
+				dsts[i].Pix[j] = uint8(j * 7)
+			}
+		}
+		f(dsts[0], pre, &Options{})
+		f(dsts[1], src, &Options{ColorMatrix: m})
+		for j := range dsts[0].Pix {
+			if g, w := dsts[1].Pix[j], dsts[0].Pix[j]; g-w > 1 && w-g > 1 {
+				t.Errorf("%s: Pix[%d]: got %d, want %d", name, j, g, w)
+				break
+			}
+		}
+	}
+}
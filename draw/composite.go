@@ -0,0 +1,148 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+)
+
+// CompositeOp is a Porter-Duff operator, other than Over and Src, for Copy,
+// Scale and Transform. See the Options.CompositeOp field.
+//
+// Each operator's result is given for alpha-premultiplied colors, where s
+// and d are the src and dst colors and sa and da are their alphas, in the
+// range [0, 1].
+type CompositeOp int
+
+const (
+	// CompositeNone means to use the op argument, Over or Src.
+	CompositeNone CompositeOp = iota
+	// CompositeIn is s*da: the src where the dst is opaque.
+	CompositeIn
+	// CompositeOut is s*(1-da): the src where the dst is transparent.
+	CompositeOut
+	// CompositeAtop is s*da + d*(1-sa): the src over the dst, where the dst
+	// is opaque.
+	CompositeAtop
+	// CompositeXor is s*(1-da) + d*(1-sa): the src and dst where the other
+	// is transparent.
+	CompositeXor
+	// CompositePlus is min(1, s+d): the sum of the src and dst, such as for
+	// accumulating light or cross-fading.
+	CompositePlus
+	// CompositeDstOver is s*(1-da) + d: the dst over the src.
+	CompositeDstOver
+	// CompositeDstIn is d*sa: the dst where the src is opaque, such as for
+	// applying an alpha mask.
+	CompositeDstIn
+	// CompositeDstOut is d*(1-sa): the dst where the src is transparent,
+	// such as for erasing.
+	CompositeDstOut
+	// CompositeDstAtop is s*(1-da) + d*sa: the dst over the src, where the
+	// src is opaque.
+	CompositeDstAtop
+)
+
+// compositeDst returns whether opts asks for a CompositeOp.
+func compositeDst(opts *Options) bool {
+	return opts != nil && opts.CompositeOp > CompositeNone && opts.CompositeOp <= CompositeDstAtop
+}
+
+// drawComposited calls f to draw, with the Src operator, onto a temporary
+// image holding the pixels adr of dst, instead of onto dst itself, and then
+// composites the result onto dst with opts.CompositeOp, through any DstMask.
+// The pixels that f leaves unchanged are left unchanged in dst.
+func drawComposited(dst Image, adr image.Rectangle, opts *Options, f func(tmp Image, opts *Options)) {
+	o := *opts
+	adr, o.DstMask = clipAffectedDestRect(adr.Intersect(dst.Bounds()), o.DstMask, o.DstMaskP)
+	if adr.Empty() {
+		return
+	}
+	// The temporary pixels start as an invalid alpha-premultiplied color, a
+	// red greater than the alpha, that drawing with Src never produces, so
+	// that the pixels that f leaves unchanged can be told apart.
+	tmp := image.NewRGBA64(adr)
+	for i := 0; i < len(tmp.Pix); i += 8 {
+		tmp.Pix[i+0] = 0xff
+		tmp.Pix[i+1] = 0xff
+	}
+	mask, maskP, op := o.DstMask, o.DstMaskP, o.CompositeOp
+	o.DstMask, o.DstMaskP, o.CompositeOp = nil, image.Point{}, CompositeNone
+	o.DitherOp, o.RowsDone = DitherNone, nil
+	f(tmp, &o)
+
+	if d, ok := dst.(*image.RGBA); ok && mask == nil {
+		compositeRGBA(d, tmp, adr, op)
+		return
+	}
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		for x := adr.Min.X; x < adr.Max.X; x++ {
+			s := tmp.RGBA64At(x, y)
+			if s.R > s.A {
+				continue
+			}
+			dr, dg, db, da := dst.At(x, y).RGBA()
+			d := color.RGBA64{uint16(dr), uint16(dg), uint16(db), uint16(da)}
+			c := op.composite(s, d)
+			if mask != nil {
+				_, _, _, ma := mask.At(maskP.X+x, maskP.Y+y).RGBA()
+				c = color.RGBA64{
+					uint16((uint32(c.R)*ma + dr*(0xffff-ma)) / 0xffff),
+					uint16((uint32(c.G)*ma + dg*(0xffff-ma)) / 0xffff),
+					uint16((uint32(c.B)*ma + db*(0xffff-ma)) / 0xffff),
+					uint16((uint32(c.A)*ma + da*(0xffff-ma)) / 0xffff),
+				}
+			}
+			dst.Set(x, y, c)
+		}
+	}
+}
+
+// composite returns the result of op for the src color s and the dst color
+// d.
+func (op CompositeOp) composite(s, d color.RGBA64) color.RGBA64 {
+	if op == CompositePlus {
+		return color.RGBA64{
+			addSat16(s.R, d.R),
+			addSat16(s.G, d.G),
+			addSat16(s.B, d.B),
+			addSat16(s.A, d.A),
+		}
+	}
+	sa, da := uint32(s.A), uint32(d.A)
+	var fs, fd uint32
+	switch op {
+	case CompositeIn:
+		fs, fd = da, 0
+	case CompositeOut:
+		fs, fd = 0xffff-da, 0
+	case CompositeAtop:
+		fs, fd = da, 0xffff-sa
+	case CompositeXor:
+		fs, fd = 0xffff-da, 0xffff-sa
+	case CompositeDstOver:
+		fs, fd = 0xffff-da, 0xffff
+	case CompositeDstIn:
+		fs, fd = 0, sa
+	case CompositeDstOut:
+		fs, fd = 0, 0xffff-sa
+	case CompositeDstAtop:
+		fs, fd = 0xffff-da, sa
+	}
+	return color.RGBA64{
+		uint16((uint32(s.R)*fs + uint32(d.R)*fd) / 0xffff),
+		uint16((uint32(s.G)*fs + uint32(d.G)*fd) / 0xffff),
+		uint16((uint32(s.B)*fs + uint32(d.B)*fd) / 0xffff),
+		uint16((uint32(s.A)*fs + uint32(d.A)*fd) / 0xffff),
+	}
+}
+
+func addSat16(a, b uint16) uint16 {
+	if c := uint32(a) + uint32(b); c < 0xffff {
+		return uint16(c)
+	}
+	return 0xffff
+}
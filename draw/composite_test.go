@@ -0,0 +1,180 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+// compositeWant returns the result of op in floating point, for the
+// alpha-premultiplied src and dst colors s and d, scaled to [0, 1].
+func compositeWant(op CompositeOp, s, d [4]float64) [4]float64 {
+	sa, da := s[3], d[3]
+	var fs, fd float64
+	switch op {
+	case CompositeIn:
+		fs, fd = da, 0
+	case CompositeOut:
+		fs, fd = 1-da, 0
+	case CompositeAtop:
+		fs, fd = da, 1-sa
+	case CompositeXor:
+		fs, fd = 1-da, 1-sa
+	case CompositePlus:
+		fs, fd = 1, 1
+	case CompositeDstOver:
+		fs, fd = 1-da, 1
+	case CompositeDstIn:
+		fs, fd = 0, sa
+	case CompositeDstOut:
+		fs, fd = 0, 1-sa
+	case CompositeDstAtop:
+		fs, fd = 1-da, sa
+	}
+	var c [4]float64
+	for i := range c {
+		c[i] = s[i]*fs + d[i]*fd
+		if c[i] > 1 {
+			c[i] = 1
+		}
+	}
+	return c
+}
+
+func toFloats(c color.Color) [4]float64 {
+	r, g, b, a := c.RGBA()
+	return [4]float64{float64(r) / 0xffff, float64(g) / 0xffff, float64(b) / 0xffff, float64(a) / 0xffff}
+}
+
+func TestCompositeOps(t *testing.T) {
+	dstColors := []color.RGBA{
+		{0xff, 0x00, 0x00, 0xff},
+		{0x00, 0x60, 0x00, 0x80},
+		{0x00, 0x00, 0x00, 0x00},
+		{0x20, 0x40, 0x60, 0xc0},
+	}
+	srcColors := []color.RGBA{
+		{0x00, 0x00, 0xff, 0xff},
+		{0x40, 0x00, 0x40, 0x40},
+		{0x00, 0x00, 0x00, 0x00},
+		{0x80, 0x80, 0x80, 0x80},
+	}
+	n := len(dstColors) * len(srcColors)
+	src := image.NewRGBA(image.Rect(0, 0, n, 1))
+	for i := 0; i < n; i++ {
+		src.SetRGBA(i, 0, srcColors[i%len(srcColors)])
+	}
+	newDst := func() *image.RGBA {
+		m := image.NewRGBA(image.Rect(0, 0, n, 1))
+		for i := 0; i < n; i++ {
+			m.SetRGBA(i, 0, dstColors[i/len(srcColors)])
+		}
+		return m
+	}
+
+	for op := CompositeIn; op <= CompositeDstAtop; op++ {
+		// The *image.RGBA fast path and the generic path, for a dst type
+		// without one, give the same results, within rounding.
+		fast := newDst()
+		Copy(fast, image.Point{}, src, src.Rect, Over, &Options{CompositeOp: op})
+		slow := image.NewRGBA64(fast.Rect)
+		Copy(slow, image.Point{}, newDst(), fast.Rect, Src, nil)
+		Copy(slow, image.Point{}, src, src.Rect, Src, &Options{CompositeOp: op})
+
+		for i := 0; i < n; i++ {
+			s := srcColors[i%len(srcColors)]
+			d := dstColors[i/len(srcColors)]
+			want := compositeWant(op, toFloats(s), toFloats(d))
+			for _, m := range []image.Image{fast, slow} {
+				got := toFloats(m.At(i, 0))
+				for k := range got {
+					if diff := got[k] - want[k]; diff < -2.0/255 || diff > 2.0/255 {
+						t.Errorf("op %d: %T: src %v, dst %v: got %v, want %v", op, m, s, d, m.At(i, 0), want)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCompositeAffectedPixels(t *testing.T) {
+	// CompositeIn clears the dst pixels that the src is drawn to where the
+	// src is transparent, but leaves the other dst pixels unchanged.
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	src.SetRGBA(0, 0, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	gray := color.RGBA{0x80, 0x80, 0x80, 0xff}
+	for _, tc := range []struct {
+		name string
+		draw func(dst Image, opts *Options)
+	}{
+		{"Copy", func(dst Image, opts *Options) {
+			Copy(dst, image.Point{1, 1}, src, src.Rect, Over, opts)
+		}},
+		{"Scale", func(dst Image, opts *Options) {
+			NearestNeighbor.Scale(dst, image.Rect(1, 1, 3, 3), src, src.Rect, Over, opts)
+		}},
+		{"Transform", func(dst Image, opts *Options) {
+			ApproxBiLinear.Transform(dst, f64.Aff3{1, 0, 1, 0, 1, 1}, src, src.Rect, Over, opts)
+		}},
+		{"Kernel.Transform", func(dst Image, opts *Options) {
+			CatmullRom.Transform(dst, f64.Aff3{1, 0, 1.001, 0, 1, 1}, src, src.Rect, Over, opts)
+		}},
+	} {
+		for _, mask := range []image.Image{nil, image.Rect(0, 0, 2, 4)} {
+			dst := image.NewRGBA(image.Rect(0, 0, 4, 4))
+			Draw(dst, dst.Rect, image.NewUniform(gray), image.Point{}, Src)
+			tc.draw(dst, &Options{CompositeOp: CompositeIn, DstMask: mask})
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					want := gray
+					drawn := x >= 1 && x < 3 && y >= 1 && y < 3 && (mask == nil || x < 2)
+					if drawn && (x != 1 || y != 1) {
+						want = color.RGBA{}
+					}
+					if got := dst.RGBAAt(x, y); drawn && x == 1 && y == 1 {
+						if got.A != 0xff || got.R < 0xf0 {
+							t.Errorf("%s, mask %v: (%d, %d): got %v, want white", tc.name, mask, x, y, got)
+						}
+					} else if got != want {
+						t.Errorf("%s, mask %v: (%d, %d): got %v, want %v", tc.name, mask, x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestCompositeDstMask(t *testing.T) {
+	// A half-transparent DstMask pixel gives a result halfway between the
+	// dst and the operator's result.
+	dst := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	dst.SetRGBA(0, 0, color.RGBA{0x80, 0x00, 0x00, 0xff})
+	src := image.NewUniform(color.RGBA{0x00, 0x00, 0x80, 0x80})
+	mask := image.NewAlpha(dst.Rect)
+	mask.SetAlpha(0, 0, color.Alpha{0x80})
+	Copy(dst, image.Point{}, src, dst.Rect, Over, &Options{
+		CompositeOp: CompositeDstOut,
+		DstMask:     mask,
+	})
+	// DstOut leaves 0x7f/0xff of the dst, halfway to which is 0xc0/0xff.
+	want := color.RGBA{0x60, 0x00, 0x00, 0xc0}
+	if got := dst.RGBAAt(0, 0); got.R < want.R-1 || got.R > want.R+1 || got.A < want.A-1 || got.A > want.A+1 || got.B != 0 {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func BenchmarkComposite(b *testing.B) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	dst := image.NewRGBA(src.Rect)
+	opts := &Options{CompositeOp: CompositeAtop}
+	for i := 0; i < b.N; i++ {
+		Copy(dst, image.Point{}, src, src.Rect, Over, opts)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"math"
+)
+
+// Convolve convolves the part of the source image defined by src and sr with
+// the separable kernel whose horizontal and vertical weights are kx and ky,
+// and writes the result of a Porter-Duff composition to the part of the
+// destination image defined by dst and the translation of sr so that sr.Min
+// translates to dp.
+//
+// Each kernel has an odd length, and kx[i] weights the src pixel i-len(kx)/2
+// columns to the right, and similarly for ky and rows. The weights are
+// normalized to sum to 1, as for a Kernel, so each kernel must have a
+// non-zero sum. Near the edges of sr, the weights of the pixels outside sr
+// are dropped before normalizing. Negative weights, such as for sharpening,
+// are allowed, and the results are clamped. Convolve does nothing if either
+// kernel has an even length.
+//
+// The two passes are those of a Kernel's Scale method, with the same fast
+// paths and the same use of opts, such as for Concurrency and AntiRinging.
+// Float images are converted to and from 16 bits per channel.
+func Convolve(dst Image, dp image.Point, src image.Image, sr image.Rectangle, kx, ky []float64, op Op, opts *Options) {
+	if len(kx)%2 == 0 || len(ky)%2 == 0 {
+		return
+	}
+	z := &kernelScaler{
+		dw:         int32(sr.Dx()),
+		dh:         int32(sr.Dy()),
+		sw:         int32(sr.Dx()),
+		sh:         int32(sr.Dy()),
+		horizontal: newConvolveDistrib(kx, int32(sr.Dx())),
+		vertical:   newConvolveDistrib(ky, int32(sr.Dy())),
+	}
+	if opts != nil {
+		z.align = opts.Alignment
+	}
+	z.Scale(dst, sr.Add(dp.Sub(sr.Min)), src, sr, op, opts)
+}
+
+// newConvolveDistrib returns a distrib that convolves n columns (or rows)
+// with the kernel k.
+func newConvolveDistrib(k []float64, n int32) distrib {
+	r := int32(len(k) / 2)
+	sources := make([]source, n)
+	contribs := make([]contrib, 0, int(n)*len(k))
+	for x := range sources {
+		l := int32(len(contribs))
+		totalWeight := 0.0
+		for i, weight := range k {
+			coord := int32(x) + int32(i) - r
+			if coord < 0 || n <= coord || weight == 0 {
+				continue
+			}
+			totalWeight += weight
+			contribs = append(contribs, contrib{coord, weight})
+		}
+		totalWeight = 1 / totalWeight
+		sources[x] = source{
+			i:                  l,
+			j:                  int32(len(contribs)),
+			invTotalWeight:     totalWeight,
+			invTotalWeightFFFF: totalWeight / 0xffff,
+		}
+	}
+	return distrib{sources, contribs}
+}
+
+// GaussianBlur blurs the part of the source image defined by src and sr with
+// a Gaussian kernel whose standard deviation is sigma pixels, and writes the
+// result as for Convolve. The kernel is truncated at 3 standard deviations. A
+// non-positive sigma copies the pixels unblurred.
+func GaussianBlur(dst Image, dp image.Point, src image.Image, sr image.Rectangle, sigma float64, op Op, opts *Options) {
+	k := gaussianKernel(sigma)
+	Convolve(dst, dp, src, sr, k, k, op, opts)
+}
+
+// gaussianKernel returns the unnormalized weights of a Gaussian kernel with
+// standard deviation sigma.
+func gaussianKernel(sigma float64) []float64 {
+	if !(sigma > 0) {
+		return []float64{1}
+	}
+	r := int(math.Ceil(3 * sigma))
+	k := make([]float64, 2*r+1)
+	for i := range k {
+		d := float64(i - r)
+		k[i] = math.Exp(-d * d / (2 * sigma * sigma))
+	}
+	return k
+}
+
+// UnsharpMask sharpens the part of the source image defined by src and sr by
+// adding amount times the difference between each pixel and its GaussianBlur
+// with the given sigma, and writes the result as for Copy. Typical values are
+// a sigma of 0.5 to 2 and an amount of 0.5 to 1.5, such as for restoring the
+// sharpness of a thumbnail after scaling down.
+//
+// Like Convolve, it uses opts' Concurrency when blurring. The other fields of
+// opts are used as for Copy.
+func UnsharpMask(dst Image, dp image.Point, src image.Image, sr image.Rectangle, sigma, amount float64, op Op, opts *Options) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	// The src pixels are read twice, blurred and not, so apply any
+	// ColorMatrix and SrcMask to them once, first.
+	if o.ColorMatrix != nil || o.SrcMask != nil {
+		s := image.NewRGBA64(sr)
+		Copy(s, sr.Min, src, sr, Src, &Options{
+			SrcMask:     o.SrcMask,
+			SrcMaskP:    o.SrcMaskP,
+			ColorMatrix: o.ColorMatrix,
+		})
+		src, o.SrcMask, o.ColorMatrix = s, nil, nil
+	}
+
+	tmp := image.NewRGBA64(sr)
+	k := gaussianKernel(sigma)
+	Convolve(tmp, sr.Min, src, sr, k, k, Src, &Options{
+		Concurrency: o.Concurrency,
+		ScaleBuffer: o.ScaleBuffer,
+	})
+	sharpen(tmp, src, amount)
+	Copy(dst, dp, tmp, sr, op, &o)
+}
+
+// sharpen sets each pixel of b, the blurred src, to the src pixel plus amount
+// times the difference between them.
+func sharpen(b *image.RGBA64, src image.Image, amount float64) {
+	r := b.Rect
+	switch src := src.(type) {
+	case *image.RGBA:
+		if r.In(src.Rect) {
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				i, si := b.PixOffset(r.Min.X, y), src.PixOffset(r.Min.X, y)
+				for x := r.Min.X; x < r.Max.X; x, i, si = x+1, i+8, si+4 {
+					sharpenAt(b, i, [4]uint32{
+						uint32(src.Pix[si+0]) * 0x101,
+						uint32(src.Pix[si+1]) * 0x101,
+						uint32(src.Pix[si+2]) * 0x101,
+						uint32(src.Pix[si+3]) * 0x101,
+					}, amount)
+				}
+			}
+			return
+		}
+	case *image.Gray:
+		if r.In(src.Rect) {
+			for y := r.Min.Y; y < r.Max.Y; y++ {
+				i, si := b.PixOffset(r.Min.X, y), src.PixOffset(r.Min.X, y)
+				for x := r.Min.X; x < r.Max.X; x, i, si = x+1, i+8, si+1 {
+					v := uint32(src.Pix[si]) * 0x101
+					sharpenAt(b, i, [4]uint32{v, v, v, 0xffff}, amount)
+				}
+			}
+			return
+		}
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i := b.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, i = x+1, i+8 {
+			sr, sg, sb, sa := src.At(x, y).RGBA()
+			sharpenAt(b, i, [4]uint32{sr, sg, sb, sa}, amount)
+		}
+	}
+}
+
+// sharpenAt sets the pixel of b at the Pix offset i to s plus amount times
+// the difference between s and that pixel, clamped to a valid
+// alpha-premultiplied color.
+func sharpenAt(b *image.RGBA64, i int, s [4]uint32, amount float64) {
+	var v [4]float64
+	for k := range v {
+		bk := uint32(b.Pix[i+2*k])<<8 | uint32(b.Pix[i+2*k+1])
+		v[k] = float64(s[k]) + amount*(float64(s[k])-float64(bk))
+	}
+	v[3] = clamp(v[3], 0, 0xffff)
+	for k, f := range v {
+		u := uint16(clamp(f, 0, v[3]) + 0.5)
+		b.Pix[i+2*k+0] = uint8(u >> 8)
+		b.Pix[i+2*k+1] = uint8(u)
+	}
+}
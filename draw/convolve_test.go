@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestConvolve(t *testing.T) {
+	src := image.NewGray(image.Rect(10, 20, 15, 21))
+	copy(src.Pix, []uint8{0, 30, 60, 90, 120})
+
+	// The identity kernel copies the pixels.
+	dst := image.NewGray(image.Rect(0, 0, 5, 1))
+	Convolve(dst, image.Point{}, src, src.Bounds(), []float64{1}, []float64{1}, Src, nil)
+	if !bytes.Equal(dst.Pix, src.Pix) {
+		t.Errorf("identity: got %v, want %v", dst.Pix, src.Pix)
+	}
+
+	// A box kernel averages each pixel with its neighbors, and only those
+	// inside sr at the edges.
+	box := []float64{1, 1, 1}
+	want := []uint8{15, 30, 60, 90, 105}
+	for _, s := range []image.Image{src, &nonFastGray{src}} {
+		dst := image.NewGray(image.Rect(0, 0, 5, 1))
+		Convolve(dst, image.Point{}, s, src.Bounds(), box, []float64{1}, Src, nil)
+		if !bytes.Equal(dst.Pix, want) {
+			t.Errorf("%T: box: got %v, want %v", s, dst.Pix, want)
+		}
+	}
+
+	// Even-length kernels do nothing.
+	dst = image.NewGray(image.Rect(0, 0, 5, 1))
+	Convolve(dst, image.Point{}, src, src.Bounds(), []float64{1, 1}, []float64{1}, Src, nil)
+	if !bytes.Equal(dst.Pix, make([]uint8, 5)) {
+		t.Errorf("even: got %v, want zeroes", dst.Pix)
+	}
+}
+
+// nonFastGray hides an *image.Gray's type, so that it takes the slow paths.
+type nonFastGray struct {
+	image.Image
+}
+
+func TestGaussianBlur(t *testing.T) {
+	// A single white pixel spreads symmetrically over its neighbors.
+	src := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	src.SetRGBA(4, 4, color.RGBA{0xff, 0xff, 0xff, 0xff})
+	dst := image.NewRGBA(src.Bounds())
+	GaussianBlur(dst, image.Point{}, src, src.Bounds(), 1, Src, nil)
+	center := dst.RGBAAt(4, 4)
+	if center.R == 0 || center.R == 0xff || center.A != center.R {
+		t.Errorf("center: got %v", center)
+	}
+	for _, p := range []image.Point{{3, 4}, {5, 4}, {4, 3}, {4, 5}} {
+		if got := dst.RGBAAt(p.X, p.Y); got != dst.RGBAAt(3, 4) || got.R == 0 || got.R >= center.R {
+			t.Errorf("%v: got %v, center %v", p, got, center)
+		}
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{}) {
+		t.Errorf("corner: got %v, want transparent", got)
+	}
+
+	// A uniform image is unchanged, even at its edges.
+	gray := image.NewGray(image.Rect(0, 0, 6, 6))
+	for i := range gray.Pix {
+		gray.Pix[i] = 0x80
+	}
+	dstGray := image.NewGray(gray.Bounds())
+	GaussianBlur(dstGray, image.Point{}, gray, gray.Bounds(), 2, Src, &Options{Concurrency: 3})
+	if !bytes.Equal(dstGray.Pix, gray.Pix) {
+		t.Errorf("uniform: got %v", dstGray.Pix)
+	}
+}
+
+func TestUnsharpMask(t *testing.T) {
+	// A step from dark to light gray.
+	src := image.NewGray(image.Rect(0, 0, 8, 1))
+	copy(src.Pix, []uint8{0x40, 0x40, 0x40, 0x40, 0xc0, 0xc0, 0xc0, 0xc0})
+	for _, s := range []image.Image{src, &nonFastGray{src}} {
+		dst := image.NewGray(src.Bounds())
+		UnsharpMask(dst, image.Point{}, s, src.Bounds(), 1, 1, Src, nil)
+		// The step is steeper, and the flat parts away from it unchanged.
+		if dst.Pix[3] >= 0x40 || dst.Pix[4] <= 0xc0 {
+			t.Errorf("%T: the step was not sharpened: %v", s, dst.Pix)
+		}
+		if dst.Pix[0] != 0x40 || dst.Pix[7] != 0xc0 {
+			t.Errorf("%T: the flat parts changed: %v", s, dst.Pix)
+		}
+	}
+
+	// A zero amount copies the pixels.
+	dst := image.NewGray(src.Bounds())
+	UnsharpMask(dst, image.Point{}, src, src.Bounds(), 1, 0, Src, nil)
+	if !bytes.Equal(dst.Pix, src.Pix) {
+		t.Errorf("zero amount: got %v, want %v", dst.Pix, src.Pix)
+	}
+}
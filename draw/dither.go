@@ -0,0 +1,310 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"golang.org/x/image/math/f64"
+)
+
+// DitherOp is how Copy, Scale and Transform choose the colors of an
+// *image.Paletted dst. See the Options.DitherOp field.
+type DitherOp int
+
+const (
+	// DitherNone means that each dst pixel is the palette color nearest to
+	// the color drawn there.
+	DitherNone DitherOp = iota
+	// DitherFloydSteinberg means that the difference between the color drawn
+	// at each dst pixel and its nearest palette color is diffused to the
+	// pixels to its right and below, as for the FloydSteinberg Drawer.
+	DitherFloydSteinberg
+	// DitherBayer means ordered dithering: each dst pixel's color is offset
+	// according to its position in an 8x8 Bayer threshold matrix before
+	// taking the nearest palette color. It is less accurate than error
+	// diffusion, but its regular pattern changes less between similar
+	// images, such as the frames of an animation, and compresses better.
+	DitherBayer
+)
+
+// MedianCut is a Quantizer that chooses the palette by the median cut
+// algorithm: it repeatedly splits the box of colors, in RGBA space, with the
+// longest side times the number of pixels inside it, at the median pixel
+// along that side, and each palette color is the mean of a box's pixels.
+//
+// It appends up to cap(p) - len(p) colors to p, fewer if m has fewer distinct
+// colors, in which case the palette is exact.
+var MedianCut Quantizer = medianCut{}
+
+type medianCut struct{}
+
+// histEntry is a distinct, alpha-premultiplied, color of an image and the
+// number of its pixels with that color.
+type histEntry struct {
+	c [4]uint8
+	n int
+}
+
+// mcBox is a box of the median cut algorithm, the entries [i, j) of the
+// histogram.
+type mcBox struct {
+	i, j int
+	// axis is the channel along which the box is longest, and score is the
+	// length of that side times the number of pixels in the box.
+	axis  int
+	score float64
+}
+
+func (medianCut) Quantize(p color.Palette, m image.Image) color.Palette {
+	n := cap(p) - len(p)
+	if n <= 0 {
+		return p
+	}
+
+	b := m.Bounds()
+	counts := map[[4]uint8]int{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, bb, a := m.At(x, y).RGBA()
+			counts[[4]uint8{uint8(r >> 8), uint8(g >> 8), uint8(bb >> 8), uint8(a >> 8)}]++
+		}
+	}
+	hist := make([]histEntry, 0, len(counts))
+	for c, n := range counts {
+		hist = append(hist, histEntry{c, n})
+	}
+	// Sort the histogram so that the palette does not depend on the map's
+	// iteration order.
+	sort.Slice(hist, func(i, j int) bool {
+		ci, cj := hist[i].c, hist[j].c
+		for k := range ci {
+			if ci[k] != cj[k] {
+				return ci[k] < cj[k]
+			}
+		}
+		return false
+	})
+
+	if len(hist) <= n {
+		for _, e := range hist {
+			p = append(p, color.RGBA{e.c[0], e.c[1], e.c[2], e.c[3]})
+		}
+		return p
+	}
+
+	boxes := []mcBox{newMCBox(hist, 0, len(hist))}
+	for len(boxes) < n {
+		best := 0
+		for i := range boxes {
+			if boxes[i].score > boxes[best].score {
+				best = i
+			}
+		}
+		bx := boxes[best]
+		if bx.score == 0 {
+			break
+		}
+		es := hist[bx.i:bx.j]
+		axis := bx.axis
+		sort.SliceStable(es, func(i, j int) bool { return es[i].c[axis] < es[j].c[axis] })
+		// Split at the median pixel, leaving at least one entry on each
+		// side.
+		total := 0
+		for _, e := range es {
+			total += e.n
+		}
+		k, sum := 1, es[0].n
+		for k < len(es)-1 && 2*sum < total {
+			sum += es[k].n
+			k++
+		}
+		boxes[best] = newMCBox(hist, bx.i, bx.i+k)
+		boxes = append(boxes, newMCBox(hist, bx.i+k, bx.j))
+	}
+
+	for _, bx := range boxes {
+		var sum [4]float64
+		total := 0.0
+		for _, e := range hist[bx.i:bx.j] {
+			for k := range sum {
+				sum[k] += float64(e.c[k]) * float64(e.n)
+			}
+			total += float64(e.n)
+		}
+		var c [4]uint8
+		for k := range c {
+			c[k] = uint8(sum[k]/total + 0.5)
+		}
+		p = append(p, color.RGBA{c[0], c[1], c[2], c[3]})
+	}
+	return p
+}
+
+func newMCBox(hist []histEntry, i, j int) mcBox {
+	lo, hi := [4]uint8{0xff, 0xff, 0xff, 0xff}, [4]uint8{}
+	total := 0
+	for _, e := range hist[i:j] {
+		for k, v := range e.c {
+			if lo[k] > v {
+				lo[k] = v
+			}
+			if hi[k] < v {
+				hi[k] = v
+			}
+		}
+		total += e.n
+	}
+	bx := mcBox{i: i, j: j}
+	for k := range lo {
+		if s := float64(hi[k]-lo[k]) * float64(total); s > bx.score {
+			bx.axis, bx.score = k, s
+		}
+	}
+	return bx
+}
+
+// ditherDst returns dst as an *image.Paletted, if opts asks for it to be
+// dithered.
+func ditherDst(dst Image, opts *Options) (*image.Paletted, bool) {
+	if opts == nil || opts.DitherOp == DitherNone {
+		return nil, false
+	}
+	p, ok := dst.(*image.Paletted)
+	return p, ok && len(p.Palette) > 0
+}
+
+// transformAffected returns the dst pixels that transforming sr by s2d may
+// affect.
+func transformAffected(s2d *f64.Aff3, sr *image.Rectangle, opts *Options) image.Rectangle {
+	if opts.EdgeOp != EdgeNone {
+		return image.Rect(-1<<30, -1<<30, 1<<30, 1<<30)
+	}
+	return transformRect(s2d, sr)
+}
+
+// drawDithered calls f to draw onto a temporary image holding the pixels adr
+// of dst, instead of onto dst itself, and then dithers the result onto dst.
+// The pixels that f leaves unchanged keep their color index.
+func drawDithered(dst *image.Paletted, adr image.Rectangle, opts *Options, f func(tmp Image, opts *Options)) {
+	adr = adr.Intersect(dst.Rect)
+	if adr.Empty() {
+		return
+	}
+	tmp := image.NewRGBA64(adr)
+	Draw(tmp, adr, dst, adr.Min, Src)
+	o := *opts
+	o.DitherOp, o.RowsDone = DitherNone, nil
+	f(tmp, &o)
+
+	pal := make([][4]int32, len(dst.Palette))
+	for i, c := range dst.Palette {
+		r, g, b, a := c.RGBA()
+		pal[i] = [4]int32{int32(r), int32(g), int32(b), int32(a)}
+	}
+
+	// For DitherBayer, spread is how far apart the palette colors are,
+	// assuming that they are evenly spread through the RGB cube.
+	levels := math.Max(2, math.Round(math.Cbrt(float64(len(pal)))))
+	spread := 0xffff / (levels - 1)
+
+	// qErr holds the errors to diffuse to the current and next rows, offset
+	// by one pixel so that there is room on either side.
+	w := adr.Dx()
+	var qErr, qErrNext [][4]int32
+	if opts.DitherOp == DitherFloydSteinberg {
+		qErr = make([][4]int32, w+2)
+		qErrNext = make([][4]int32, w+2)
+	}
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		for x := adr.Min.X; x < adr.Max.X; x++ {
+			i := dst.PixOffset(x, y)
+			c := tmp.RGBA64At(x, y)
+			v := [4]int32{int32(c.R), int32(c.G), int32(c.B), int32(c.A)}
+			if idx := int(dst.Pix[i]); idx < len(pal) && v == pal[idx] {
+				continue
+			}
+
+			switch opts.DitherOp {
+			case DitherFloydSteinberg:
+				e := &qErr[x-adr.Min.X+1]
+				for k := range v {
+					v[k] = clampI32(v[k]+e[k]/16, 0, 0xffff)
+				}
+			case DitherBayer:
+				t := (float64(bayer8[y&7][x&7])+0.5)/64 - 0.5
+				d := int32(t * spread * float64(v[3]) / 0xffff)
+				for k := 0; k < 3; k++ {
+					v[k] = clampI32(v[k]+d, 0, v[3])
+				}
+			}
+
+			idx := nearest(pal, v)
+			dst.Pix[i] = uint8(idx)
+
+			if opts.DitherOp == DitherFloydSteinberg {
+				j := x - adr.Min.X + 1
+				for k := range v {
+					d := v[k] - pal[idx][k]
+					qErr[j+1][k] += d * 7
+					qErrNext[j-1][k] += d * 3
+					qErrNext[j+0][k] += d * 5
+					qErrNext[j+1][k] += d * 1
+				}
+			}
+		}
+		if qErr != nil {
+			qErr, qErrNext = qErrNext, qErr
+			for i := range qErrNext {
+				qErrNext[i] = [4]int32{}
+			}
+		}
+	}
+}
+
+// bayer8 is the 8x8 Bayer threshold matrix.
+var bayer8 = [8][8]uint8{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// nearest returns the index of the palette color nearest to v, by the sum of
+// squared differences.
+func nearest(pal [][4]int32, v [4]int32) int {
+	best, bestSum := 0, int64(math.MaxInt64)
+	for i, p := range pal {
+		sum := int64(0)
+		for k := range v {
+			d := int64(v[k] - p[k])
+			sum += d * d
+		}
+		if sum < bestSum {
+			best, bestSum = i, sum
+			if sum == 0 {
+				break
+			}
+		}
+	}
+	return best
+}
+
+func clampI32(x, min, max int32) int32 {
+	if x < min {
+		return min
+	}
+	if x > max {
+		return max
+	}
+	return x
+}
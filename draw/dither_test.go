@@ -0,0 +1,123 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestMedianCut(t *testing.T) {
+	// An image with fewer colors than the palette has room for gets an exact
+	// palette.
+	m := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	colors := []color.RGBA{{0xff, 0, 0, 0xff}, {0, 0x80, 0, 0xff}, {0, 0, 0, 0}}
+	for i := 0; i < 16; i++ {
+		m.SetRGBA(i%4, i/4, colors[i%3])
+	}
+	p := MedianCut.Quantize(make(color.Palette, 0, 256), m)
+	if len(p) != 3 {
+		t.Fatalf("exact: got %d colors, want 3", len(p))
+	}
+	for _, c := range colors {
+		if p[p.Index(c)] != color.Color(c) {
+			t.Errorf("exact: %v is not in the palette %v", c, p)
+		}
+	}
+
+	// A gradient of 256 grays, cut into 4, gets a palette color in the middle
+	// of each quarter.
+	g := image.NewGray(image.Rect(0, 0, 256, 1))
+	for i := range g.Pix {
+		g.Pix[i] = uint8(i)
+	}
+	p = MedianCut.Quantize(make(color.Palette, 1, 5), g)
+	if len(p) != 5 {
+		t.Fatalf("gradient: got %d colors, want 5", len(p))
+	}
+	for _, want := range []uint8{0x20, 0x60, 0xa0, 0xe0} {
+		y := color.GrayModel.Convert(p[1:].Convert(color.Gray{want})).(color.Gray).Y
+		if d := int(y) - int(want); d < -2 || d > 2 {
+			t.Errorf("gradient: nearest to %#02x: got %#02x", want, y)
+		}
+	}
+
+	if p := MedianCut.Quantize(make(color.Palette, 2), g); len(p) != 2 {
+		t.Errorf("full palette: got %d colors, want 2", len(p))
+	}
+}
+
+func TestDither(t *testing.T) {
+	gray := image.NewUniform(color.Gray{0x80})
+	bw := color.Palette{color.Black, color.White}
+
+	testCases := []struct {
+		name string
+		draw func(dst Image, opts *Options)
+	}{
+		{"Copy", func(dst Image, opts *Options) {
+			Copy(dst, image.Point{}, gray, dst.Bounds(), Src, opts)
+		}},
+		{"NearestNeighbor.Scale", func(dst Image, opts *Options) {
+			NearestNeighbor.Scale(dst, dst.Bounds(), gray, image.Rect(0, 0, 4, 4), Src, opts)
+		}},
+		{"CatmullRom.Scale", func(dst Image, opts *Options) {
+			CatmullRom.Scale(dst, dst.Bounds(), gray, image.Rect(0, 0, 4, 4), Src, opts)
+		}},
+		{"ApproxBiLinear.Transform", func(dst Image, opts *Options) {
+			ApproxBiLinear.Transform(dst, f64.Scale(2, 2), gray, image.Rect(0, 0, 8, 8), Src, opts)
+		}},
+		{"CatmullRom.TransformProjective", func(dst Image, opts *Options) {
+			m := f64.Mat3{2, 0, 0, 0, 2, 0, 0.001, 0, 1}
+			CatmullRom.TransformProjective(dst, m, gray, image.Rect(0, 0, 8, 8), Src, &Options{
+				DitherOp: opts.DitherOp,
+				EdgeOp:   EdgeClamp,
+			})
+		}},
+	}
+	for _, tc := range testCases {
+		for _, d := range []DitherOp{DitherNone, DitherFloydSteinberg, DitherBayer} {
+			dst := image.NewPaletted(image.Rect(0, 0, 16, 16), bw)
+			tc.draw(dst, &Options{DitherOp: d})
+			white := 0
+			for _, idx := range dst.Pix {
+				white += int(idx)
+			}
+			if d == DitherNone {
+				// 0x80 is slightly nearer to white than to black.
+				if white != len(dst.Pix) {
+					t.Errorf("%s, DitherNone: got %d white pixels, want %d", tc.name, white, len(dst.Pix))
+				}
+				continue
+			}
+			if white < len(dst.Pix)*2/5 || white > len(dst.Pix)*3/5 {
+				t.Errorf("%s, DitherOp %d: got %d white pixels of %d", tc.name, d, white, len(dst.Pix))
+			}
+		}
+	}
+}
+
+func TestDitherUnchangedPixels(t *testing.T) {
+	p := color.Palette{color.Black, color.White, color.Gray{0x80}}
+	dst := image.NewPaletted(image.Rect(0, 0, 8, 8), p)
+	for i := range dst.Pix {
+		dst.Pix[i] = 2
+	}
+	src := image.NewUniform(color.Gray{0x40})
+	Copy(dst, image.Point{2, 2}, src, image.Rect(0, 0, 4, 4), Src, &Options{DitherOp: DitherFloydSteinberg})
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			idx := dst.ColorIndexAt(x, y)
+			if in := (image.Point{x, y}).In(image.Rect(2, 2, 6, 6)); !in && idx != 2 {
+				t.Errorf("(%d, %d): outside the copied pixels: got index %d, want 2", x, y, idx)
+			} else if in && idx == 1 {
+				t.Errorf("(%d, %d): got white", x, y)
+			}
+		}
+	}
+}
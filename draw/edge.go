@@ -0,0 +1,176 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// EdgeOp is how a transform treats the src points outside of sr. See the
+// Options.EdgeOp field.
+type EdgeOp int
+
+const (
+	// EdgeNone means that dst pixels that map to outside of sr are
+	// unaffected.
+	EdgeNone EdgeOp = iota
+	// EdgeTransparent means that sr is surrounded by transparent black.
+	EdgeTransparent
+	// EdgeClamp means that sr's edge pixels extend infinitely outward.
+	EdgeClamp
+	// EdgeWrap means that sr repeats infinitely, like a tiled wallpaper.
+	EdgeWrap
+	// EdgeMirror means that sr repeats infinitely, with every other repeat
+	// flipped, so that adjacent copies are reflections of each other.
+	EdgeMirror
+	// EdgeConstant means that sr is surrounded by Options.EdgeColor.
+	EdgeConstant
+)
+
+// edgeImage is an unbounded image, for the EdgeOp modes other than EdgeNone,
+// that is src inside of sr, multiplied by any src mask, and the extension of
+// sr outside of it.
+type edgeImage struct {
+	src       image.Image
+	sr        image.Rectangle
+	edgeOp    EdgeOp
+	edgeColor color.Color
+	mask      image.Image
+	maskP     image.Point
+	bounds    image.Rectangle
+}
+
+func newEdgeImage(src image.Image, sr image.Rectangle, bounds image.Rectangle, o *Options) *edgeImage {
+	m := &edgeImage{
+		src:       src,
+		sr:        sr,
+		edgeOp:    o.EdgeOp,
+		edgeColor: o.EdgeColor,
+		mask:      o.SrcMask,
+		maskP:     o.SrcMaskP,
+		bounds:    bounds,
+	}
+	if m.edgeColor == nil {
+		m.edgeColor = color.Transparent
+	}
+	return m
+}
+
+func (m *edgeImage) ColorModel() color.Model { return color.RGBA64Model }
+
+func (m *edgeImage) Bounds() image.Rectangle { return m.bounds }
+
+func (m *edgeImage) At(x, y int) color.Color {
+	if !(image.Point{x, y}).In(m.sr) {
+		switch m.edgeOp {
+		case EdgeClamp:
+			x = clampEdge(x, m.sr.Min.X, m.sr.Max.X)
+			y = clampEdge(y, m.sr.Min.Y, m.sr.Max.Y)
+		case EdgeWrap:
+			x = wrapEdge(x, m.sr.Min.X, m.sr.Max.X)
+			y = wrapEdge(y, m.sr.Min.Y, m.sr.Max.Y)
+		case EdgeMirror:
+			x = mirrorEdge(x, m.sr.Min.X, m.sr.Max.X)
+			y = mirrorEdge(y, m.sr.Min.Y, m.sr.Max.Y)
+		case EdgeConstant:
+			return m.edgeColor
+		default:
+			return color.Transparent
+		}
+	}
+	c := m.src.At(x, y)
+	if m.mask == nil {
+		return c
+	}
+	_, _, _, ma := m.mask.At(m.maskP.X+x, m.maskP.Y+y).RGBA()
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{
+		uint16(r * ma / 0xffff),
+		uint16(g * ma / 0xffff),
+		uint16(b * ma / 0xffff),
+		uint16(a * ma / 0xffff),
+	}
+}
+
+func clampEdge(x, min, max int) int {
+	if x < min {
+		return min
+	}
+	if x >= max {
+		return max - 1
+	}
+	return x
+}
+
+func wrapEdge(x, min, max int) int {
+	n := max - min
+	x = (x - min) % n
+	if x < 0 {
+		x += n
+	}
+	return min + x
+}
+
+func mirrorEdge(x, min, max int) int {
+	n := max - min
+	x = (x - min) % (2 * n)
+	if x < 0 {
+		x += 2 * n
+	}
+	if x >= n {
+		x = 2*n - 1 - x
+	}
+	return min + x
+}
+
+// transformEdges implements the Transform methods of t when opts.EdgeOp is
+// not EdgeNone. It transforms an edgeImage, whose sr is large enough to
+// cover every dst pixel, instead of src.
+func transformEdges(t Transformer, dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	o := *opts
+	o.EdgeOp, o.EdgeColor = EdgeNone, nil
+	o.SrcMask, o.SrcMaskP = nil, image.Point{}
+
+	adr, _ := clipAffectedDestRect(dst.Bounds(), o.DstMask, o.DstMaskP)
+	if adr.Empty() || sr.Empty() {
+		return
+	}
+	d2s, ok := s2d.Invert()
+	if !ok {
+		return
+	}
+	support, sample := 1.0, projectiveSampler(ablSample)
+	switch t := t.(type) {
+	case nnInterpolator:
+		sample = nnSample
+	case *Kernel:
+		support, sample = t.Support, (&kernelSampler{q: t}).sample
+	}
+	scale := math.Max(1, math.Max(
+		math.Max(abs(d2s[0]), abs(d2s[1])),
+		math.Max(abs(d2s[3]), abs(d2s[4])),
+	))
+	// If a dst pixel covers more than sr, such as for a near-singular s2d,
+	// esr and the kernel footprints are practically unbounded. Sample each
+	// dst pixel's point instead, as warp does, with a footprint that is
+	// capped at about sr's size.
+	if scale > float64(sr.Dx()) || scale > float64(sr.Dy()) {
+		transformFloat(dst, s2d, src, sr, op, opts, sample)
+		return
+	}
+	esr := transformRect(&d2s, &adr)
+
+	// Pad esr by the interpolator's support, so that the dst pixels near
+	// adr's edges are not affected by esr's own edges.
+	margin := int(math.Ceil(support*scale)) + 2
+	esr = esr.Inset(-margin)
+
+	src = applyColorMatrix(src, &o)
+	t.Transform(dst, s2d, newEdgeImage(src, sr, esr, opts), esr, op, &o)
+}
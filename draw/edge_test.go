@@ -0,0 +1,172 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestEdgeIndex(t *testing.T) {
+	// sr spans [10, 14) along one axis.
+	testCases := []struct {
+		x                   int
+		clamp, wrap, mirror int
+	}{
+		{5, 10, 13, 13},
+		{6, 10, 10, 13},
+		{9, 10, 13, 10},
+		{10, 10, 10, 10},
+		{13, 13, 13, 13},
+		{14, 13, 10, 13},
+		{17, 13, 13, 10},
+		{18, 13, 10, 10},
+		{22, 13, 10, 13},
+	}
+	for _, tc := range testCases {
+		if got := clampEdge(tc.x, 10, 14); got != tc.clamp {
+			t.Errorf("clampEdge(%d): got %d, want %d", tc.x, got, tc.clamp)
+		}
+		if got := wrapEdge(tc.x, 10, 14); got != tc.wrap {
+			t.Errorf("wrapEdge(%d): got %d, want %d", tc.x, got, tc.wrap)
+		}
+		if got := mirrorEdge(tc.x, 10, 14); got != tc.mirror {
+			t.Errorf("mirrorEdge(%d): got %d, want %d", tc.x, got, tc.mirror)
+		}
+	}
+}
+
+func TestEdgeOp(t *testing.T) {
+	src, _ := srcRGBA(image.Rect(0, 0, 8, 6))
+	sr := image.Rect(1, 1, 7, 5)
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	gray := color.RGBA{0x40, 0x40, 0x40, 0x40}
+
+	testCases := []struct {
+		edgeOp EdgeOp
+		// want returns the color of the dst pixel (x, y), which maps to the
+		// src pixel (x-10, y-10).
+		want func(sx, sy int) color.RGBA
+	}{
+		{EdgeNone, func(sx, sy int) color.RGBA { return gray }},
+		{EdgeTransparent, func(sx, sy int) color.RGBA { return color.RGBA{} }},
+		{EdgeConstant, func(sx, sy int) color.RGBA { return red }},
+		{EdgeClamp, func(sx, sy int) color.RGBA {
+			return src.(*image.RGBA).RGBAAt(clampEdge(sx, 1, 7), clampEdge(sy, 1, 5))
+		}},
+		{EdgeWrap, func(sx, sy int) color.RGBA {
+			return src.(*image.RGBA).RGBAAt(wrapEdge(sx, 1, 7), wrapEdge(sy, 1, 5))
+		}},
+		{EdgeMirror, func(sx, sy int) color.RGBA {
+			return src.(*image.RGBA).RGBAAt(mirrorEdge(sx, 1, 7), mirrorEdge(sy, 1, 5))
+		}},
+	}
+
+	s2d := f64.Aff3{1, 0, 10, 0, 1, 10}
+	for _, tc := range testCases {
+		for _, transform := range []string{"affine", "projective"} {
+			dst := image.NewRGBA(image.Rect(0, 0, 30, 25))
+			for i := range dst.Pix {
+				dst.Pix[i] = 0x40
+			}
+			opts := &Options{EdgeOp: tc.edgeOp, EdgeColor: red}
+			if transform == "affine" {
+				NearestNeighbor.Transform(dst, s2d, src, sr, Src, opts)
+			} else {
+				// Use a projective transform that maps every dst pixel
+				// center to the same src point as s2d does, to within
+				// rounding error.
+				m := f64.Mat3{2, 0, 20, 0, 2, 20, 0, 1e-12, 2}
+				NearestNeighbor.(ProjectiveTransformer).TransformProjective(dst, m, src, sr, Src, opts)
+			}
+			for y := 0; y < 25; y++ {
+				for x := 0; x < 30; x++ {
+					sx, sy := x-10, y-10
+					want := tc.want(sx, sy)
+					if (image.Point{sx, sy}).In(sr) {
+						want = src.(*image.RGBA).RGBAAt(sx, sy)
+					}
+					if got := dst.RGBAAt(x, y); got != want {
+						t.Errorf("edgeOp=%d, %s: (%d, %d): got %v, want %v",
+							tc.edgeOp, transform, x, y, got, want)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestEdgeClampMatchesNone checks that, for the dst pixels that map to well
+// inside of sr, EdgeClamp matches EdgeNone. Closer to sr's edges, EdgeNone
+// kernels re-normalize their weights instead of clamping.
+func TestEdgeClampMatchesNone(t *testing.T) {
+	src, _ := srcRGBA(image.Rect(0, 0, 20, 20))
+	sr := image.Rect(2, 3, 18, 17)
+	s2d := RotationMatrix(0.3, f64.Vec2{10, 10})
+	for _, q := range []Interpolator{NearestNeighbor, ApproxBiLinear, CatmullRom} {
+		want := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		got := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		q.Transform(want, s2d, src, sr, Src, nil)
+		q.Transform(got, s2d, src, sr, Src, &Options{EdgeOp: EdgeClamp})
+		d2s, _ := s2d.Invert()
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				dxf, dyf := float64(x)+0.5, float64(y)+0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx), int(sy)}).In(sr.Inset(3)) {
+					continue
+				}
+				g, w := got.RGBAAt(x, y), want.RGBAAt(x, y)
+				if !close8(g.R, w.R) || !close8(g.G, w.G) || !close8(g.B, w.B) || !close8(g.A, w.A) {
+					t.Errorf("%T: (%d, %d): got %v, want %v", q, x, y, g, w)
+				}
+			}
+		}
+	}
+}
+
+// TestEdgeNearSingular checks that transforms whose dst pixels each cover a
+// vast area of the unbounded edge image, such as those of a near-singular
+// matrix or near a projective transform's horizon, finish quickly, without
+// allocating kernel weights for the whole area.
+func TestEdgeNearSingular(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range src.Pix {
+		src.Pix[i] = 0x80
+	}
+	want := color.RGBA{0x80, 0x80, 0x80, 0x80}
+	testCases := []struct {
+		name string
+		m    f64.Mat3
+		// rows is the number of dst rows that are drawn. The projective
+		// transform's horizon is at y = 9.5001 in dst space.
+		rows int
+	}{
+		{"affine", f64.Mat3{1, 1, 0, 1, 1 + 1e-6, 0, 0, 0, 1}, 16},
+		{"projective", f64.Mat3{1, 0, 0, 0, 1, 0, 0, 1 / 9.5001, 1}, 10},
+	}
+	for _, tc := range testCases {
+		for _, q := range []Interpolator{NearestNeighbor, ApproxBiLinear, BiLinear, CatmullRom} {
+			dst := image.NewRGBA(image.Rect(0, 0, 16, 16))
+			q.(ProjectiveTransformer).TransformProjective(dst, tc.m, src, src.Bounds(), Src, &Options{EdgeOp: EdgeClamp})
+			for y := 0; y < 16; y++ {
+				for x := 0; x < 16; x++ {
+					want := want
+					if y >= tc.rows {
+						want = color.RGBA{}
+					}
+					if got := dst.RGBAAt(x, y); !close8(got.R, want.R) || !close8(got.A, want.A) {
+						t.Fatalf("%s, %T: (%d, %d): got %v, want %v", tc.name, q, x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+}
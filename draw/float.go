@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+
+	"golang.org/x/image/floatimage"
+	"golang.org/x/image/math/f64"
+)
+
+// isFloat returns whether m is one of the floatimage package's types. Copy,
+// Scale and Transform keep such images' samples as floating-point values,
+// including those outside of the range [0, 1], instead of quantizing them to
+// 16 bits.
+//
+// A ColorMatrix still clamps the colors that it transforms, and the
+// Options.Concurrency field is ignored.
+func isFloat(m image.Image) bool {
+	switch m.(type) {
+	case *floatimage.GrayF32, *floatimage.RGBAF32:
+		return true
+	}
+	return false
+}
+
+// floatAt returns c as an alpha-premultiplied color in the range
+// [0.0, 65535.0], without clamping, if c is one of the floatimage package's
+// color types. Checking the color instead of the image also sees through
+// wrappers like an edgeImage.
+func floatAt(c interface{}) (r, g, b, a float64, ok bool) {
+	switch c := c.(type) {
+	case floatimage.GrayF32Color:
+		y := float64(c.Y) * 0xffff
+		return y, y, y, 0xffff, true
+	case floatimage.RGBAF32Color:
+		return float64(c.R) * 0xffff, float64(c.G) * 0xffff, float64(c.B) * 0xffff, float64(c.A) * 0xffff, true
+	}
+	return 0, 0, 0, 0, false
+}
+
+// setFloat composes the alpha-premultiplied color (r, g, b, a), in the range
+// [0.0, 1.0] and already multiplied by the dst mask value ma, with the pixel
+// at (x, y) of dst, which is one of the floatimage package's types.
+func setFloat(dst Image, x, y int, r, g, b, a, ma float64, op Op) {
+	pa1 := 1 - a
+	if op == Src {
+		pa1 = 1 - ma
+	}
+	switch dst := dst.(type) {
+	case *floatimage.RGBAF32:
+		if pa1 != 0 {
+			q := dst.RGBAF32At(x, y)
+			r += float64(q.R) * pa1
+			g += float64(q.G) * pa1
+			b += float64(q.B) * pa1
+			a += float64(q.A) * pa1
+		}
+		dst.SetRGBAF32(x, y, floatimage.RGBAF32Color{
+			R: float32(r),
+			G: float32(g),
+			B: float32(b),
+			A: float32(a),
+		})
+	case *floatimage.GrayF32:
+		// As with the color.Gray16Model, an alpha-premultiplied color is
+		// composed onto black.
+		l := lumaR*r + lumaG*g + lumaB*b
+		if pa1 != 0 {
+			l += float64(dst.GrayF32At(x, y).Y) * pa1
+		}
+		dst.SetGrayF32(x, y, floatimage.GrayF32Color{Y: float32(l)})
+	}
+}
+
+// scaleFloat implements the Scale methods when dst or src is one of the
+// floatimage package's types, calling sample for every affected dst pixel.
+func scaleFloat(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options, sample projectiveSampler) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	// Unlike Transform, Scale does not draw outside of dr.
+	o.EdgeOp, o.EdgeColor = EdgeNone, nil
+	xscale, xoffset := o.Alignment.mapping(dr.Dx(), sr.Dx())
+	yscale, yoffset := o.Alignment.mapping(dr.Dy(), sr.Dy())
+	f := func(dxf, dyf float64) (sx, sy, xs, ys float64, ok bool) {
+		// The dst pixel center (dxf, dyf) is that of the dst column and row
+		// (dxf-0.5, dyf-0.5) relative to dr.Min, and the center of src
+		// column i is at i+0.5.
+		sx = float64(sr.Min.X) + (dxf-0.5-float64(dr.Min.X)+xoffset)*xscale - xoffset + 0.5
+		sy = float64(sr.Min.Y) + (dyf-0.5-float64(dr.Min.Y)+yoffset)*yscale - yoffset + 0.5
+		return sx, sy, xscale, yscale, true
+	}
+
+	if o.RowsDone == nil {
+		warp(dst, dst.Bounds().Intersect(dr), src, sr, op, &o, sample, f)
+		return
+	}
+	// As for a Kernel's Scale, each band is the part of dr within dst's
+	// current bounds, which RowsDone may change to move dst on to later rows.
+	rowsDone := o.RowsDone
+	for y := dr.Min.Y; ; {
+		band := dst.Bounds().Intersect(dr)
+		if band.Min.Y < y {
+			band.Min.Y = y
+		}
+		if band.Empty() {
+			return
+		}
+		warp(dst, band, src, sr, op, &o, sample, f)
+		rowsDone(band.Min.Y, band.Max.Y)
+		y = band.Max.Y
+	}
+}
+
+// transformFloat implements the Transform methods when dst or src is one of
+// the floatimage package's types, calling sample for every affected dst
+// pixel.
+func transformFloat(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options, sample projectiveSampler) {
+	d2s, ok := s2d.Invert()
+	if !ok {
+		return
+	}
+	xscale := abs(d2s[0])
+	if s := abs(d2s[1]); xscale < s {
+		xscale = s
+	}
+	yscale := abs(d2s[3])
+	if s := abs(d2s[4]); yscale < s {
+		yscale = s
+	}
+	adr := dst.Bounds().Intersect(transformRect(&s2d, &sr))
+	warp(dst, adr, src, sr, op, opts, sample, func(dxf, dyf float64) (sx, sy, xs, ys float64, ok bool) {
+		sx = d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+		sy = d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+		return sx, sy, xscale, yscale, true
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/floatimage"
+	"golang.org/x/image/math/f64"
+)
+
+func closeF32(a, b float32) bool {
+	return math.Abs(float64(a)-float64(b)) < 1e-4
+}
+
+func TestFloatScaleTransform(t *testing.T) {
+	// The source color is out of range, as for a high dynamic range image,
+	// so it would be changed by quantizing it to 16 bits.
+	want := floatimage.RGBAF32Color{R: 4, G: 1.5, B: -0.25, A: 1}
+	src := floatimage.NewRGBAF32(image.Rect(0, 0, 3, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			src.SetRGBAF32(x, y, want)
+		}
+	}
+
+	check := func(name string, dst *floatimage.RGBAF32, r image.Rectangle) {
+		t.Helper()
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				got := dst.RGBAF32At(x, y)
+				if !closeF32(got.R, want.R) || !closeF32(got.G, want.G) || !closeF32(got.B, want.B) || !closeF32(got.A, want.A) {
+					t.Errorf("%s: (%d, %d): got %v, want %v", name, x, y, got, want)
+					return
+				}
+			}
+		}
+	}
+
+	interps := []struct {
+		name string
+		q    Interpolator
+	}{
+		{"nn", NearestNeighbor},
+		{"ab", ApproxBiLinear},
+		{"cr", CatmullRom},
+	}
+	for _, in := range interps {
+		for _, op := range []Op{Over, Src} {
+			dst := floatimage.NewRGBAF32(image.Rect(0, 0, 8, 8))
+			in.q.Scale(dst, image.Rect(1, 1, 7, 7), src, src.Bounds(), op, nil)
+			check(in.name+" Scale", dst, image.Rect(1, 1, 7, 7))
+			if got := dst.RGBAF32At(0, 0); got != (floatimage.RGBAF32Color{}) {
+				t.Errorf("%s Scale: outside dr: got %v", in.name, got)
+			}
+
+			dst = floatimage.NewRGBAF32(image.Rect(0, 0, 8, 8))
+			s2d := f64.Translate(2.5, 1.5).Mul(f64.Scale(1.5, 1.5))
+			in.q.Transform(dst, s2d, src, src.Bounds(), op, nil)
+			check(in.name+" Transform", dst, image.Rect(3, 2, 6, 5))
+		}
+	}
+
+	dst := floatimage.NewRGBAF32(image.Rect(0, 0, 4, 4))
+	Copy(dst, image.Point{1, 1}, src, image.Rect(0, 0, 2, 2), Src, nil)
+	check("Copy", dst, image.Rect(1, 1, 3, 3))
+}
+
+func TestFloatCompose(t *testing.T) {
+	src := floatimage.NewRGBAF32(image.Rect(0, 0, 1, 1))
+	src.SetRGBAF32(0, 0, floatimage.RGBAF32Color{R: 1.5, G: 0.5, B: 0, A: 0.5})
+
+	dst := floatimage.NewRGBAF32(image.Rect(0, 0, 1, 1))
+	dst.SetRGBAF32(0, 0, floatimage.RGBAF32Color{R: 2, G: 2, B: 2, A: 1})
+	Copy(dst, image.Point{}, src, src.Bounds(), Over, nil)
+	want := floatimage.RGBAF32Color{R: 2.5, G: 1.5, B: 1, A: 1}
+	if got := dst.RGBAF32At(0, 0); got != want {
+		t.Errorf("Over: got %v, want %v", got, want)
+	}
+
+	// A color is composed onto a GrayF32 dst by its luma.
+	gray := floatimage.NewGrayF32(image.Rect(0, 0, 1, 1))
+	gray.SetGrayF32(0, 0, floatimage.GrayF32Color{Y: 1})
+	Copy(gray, image.Point{}, src, src.Bounds(), Over, nil)
+	wantY := float32(0.5 + lumaR*1.5 + lumaG*0.5)
+	if got := gray.GrayF32At(0, 0).Y; !closeF32(got, wantY) {
+		t.Errorf("Over GrayF32: got %v, want %v", got, wantY)
+	}
+
+	// A 16-bit src is drawn onto a float dst with a mask.
+	mask := image.NewAlpha(image.Rect(0, 0, 1, 1))
+	mask.SetAlpha(0, 0, color.Alpha{0x80})
+	dst.SetRGBAF32(0, 0, floatimage.RGBAF32Color{R: 2, G: 2, B: 2, A: 1})
+	opaqueRed := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	opaqueRed.SetRGBA(0, 0, color.RGBA{0xff, 0, 0, 0xff})
+	NearestNeighbor.Scale(dst, dst.Bounds(), opaqueRed, opaqueRed.Bounds(), Src, &Options{DstMask: mask})
+	m := float32(0x8080) / 0xffff
+	want = floatimage.RGBAF32Color{R: m + 2*(1-m), G: 2 * (1 - m), B: 2 * (1 - m), A: 1}
+	if got := dst.RGBAF32At(0, 0); !closeF32(got.R, want.R) || !closeF32(got.G, want.G) || !closeF32(got.A, want.A) {
+		t.Errorf("Src with DstMask: got %v, want %v", got, want)
+	}
+}
@@ -35,6 +35,7 @@ func main() {
 	gen(w, "nnInterpolator", codeNNScaleLeaf, codeNNTransformLeaf)
 	gen(w, "ablInterpolator", codeABLScaleLeaf, codeABLTransformLeaf)
 	genKernel(w)
+	genComposite(w)
 
 	if *debug {
 		os.Stdout.Write(w.Bytes())
@@ -53,15 +54,31 @@ var (
 	// dsTypes are the (dst image type, src image type) pairs to generate
 	// scale_DType_SType implementations for. The last element in the slice
 	// should be the fallback pair ("Image", "image.Image").
-	//
-	// TODO: add *image.CMYK src type after Go 1.5 is released.
-	// An *image.CMYK is also alwaysOpaque.
 	dsTypes = []struct{ dType, sType string }{
+		{"*image.RGBA", "*image.CMYK"},
 		{"*image.RGBA", "*image.Gray"},
+		{"*image.RGBA", "*image.Gray16"},
 		{"*image.RGBA", "*image.NRGBA"},
 		{"*image.RGBA", "*image.RGBA"},
+		{"*image.RGBA", "*image.RGBA64"},
 		{"*image.RGBA", "*image.YCbCr"},
 		{"*image.RGBA", "image.Image"},
+		{"*image.Gray", "*image.CMYK"},
+		{"*image.Gray", "*image.Gray"},
+		{"*image.Gray", "*image.Gray16"},
+		{"*image.Gray", "image.Image"},
+		{"*image.Gray16", "*image.CMYK"},
+		{"*image.Gray16", "*image.Gray"},
+		{"*image.Gray16", "*image.Gray16"},
+		{"*image.Gray16", "image.Image"},
+		{"*image.NRGBA", "*image.NRGBA"},
+		{"*image.NRGBA", "*image.RGBA"},
+		{"*image.NRGBA", "image.Image"},
+		{"*image.RGBA64", "*image.NRGBA"},
+		{"*image.RGBA64", "*image.RGBA"},
+		{"*image.RGBA64", "*image.RGBA64"},
+		{"*image.RGBA64", "*image.YCbCr"},
+		{"*image.RGBA64", "image.Image"},
 		{"Image", "image.Image"},
 	}
 	dTypes, sTypes  []string
@@ -77,8 +94,10 @@ var (
 	// opaque. For these types, Over is equivalent to the faster Src, in the
 	// absence of a source mask.
 	alwaysOpaque = map[string]bool{
-		"*image.Gray":  true,
-		"*image.YCbCr": true,
+		"*image.CMYK":   true,
+		"*image.Gray":   true,
+		"*image.Gray16": true,
+		"*image.YCbCr":  true,
 	}
 )
 
@@ -155,6 +174,65 @@ func genKernel(w *bytes.Buffer) {
 	}
 }
 
+// compositeOps are the CompositeOp values to generate compositeRGBA_Op
+// implementations for, and the factors that the src and dst colors are
+// multiplied by, out of 0xffff. Plus is a saturating sum instead.
+var compositeOps = []struct{ op, fs, fd string }{
+	{"In", "da", "0"},
+	{"Out", "0xffff - da", "0"},
+	{"Atop", "da", "0xffff - sa"},
+	{"Xor", "0xffff - da", "0xffff - sa"},
+	{"Plus", "", ""},
+	{"DstOver", "0xffff - da", "0xffff"},
+	{"DstIn", "0", "sa"},
+	{"DstOut", "0", "0xffff - sa"},
+	{"DstAtop", "0xffff - da", "sa"},
+}
+
+func genComposite(w *bytes.Buffer) {
+	w.WriteString(codeCompositeRoot)
+	for _, c := range compositeOps {
+		fmt.Fprintf(w, "case Composite%s:\ncompositeRGBA_%s(dst, tmp, adr)\n", c.op, c.op)
+	}
+	w.WriteString("}\n}\n")
+
+	for _, c := range compositeOps {
+		var blend string
+		for _, ch := range []string{"r", "g", "b", "a"} {
+			switch {
+			case c.op == "Plus":
+				blend += fmt.Sprintf("o%s := s%s + d%s\nif o%s > 0xffff {\no%s = 0xffff\n}\n", ch, ch, ch, ch, ch)
+			case c.fs == "0":
+				blend += fmt.Sprintf("o%s := d%s * (%s) / 0xffff\n", ch, ch, c.fd)
+			case c.fd == "0":
+				blend += fmt.Sprintf("o%s := s%s * (%s) / 0xffff\n", ch, ch, c.fs)
+			case c.fd == "0xffff":
+				blend += fmt.Sprintf("o%s := s%s*(%s)/0xffff + d%s\n", ch, ch, c.fs, ch)
+			default:
+				blend += fmt.Sprintf("o%s := (s%s*(%s) + d%s*(%s)) / 0xffff\n", ch, ch, c.fs, ch, c.fd)
+			}
+		}
+		// Load the other src and dst channels that the blend uses.
+		load := ""
+		if c.fs != "0" {
+			load += "sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])\n"
+			load += "sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])\n"
+		}
+		if c.fd != "0" {
+			load += "dr := uint32(dst.Pix[d+0]) * 0x101\n"
+			load += "dg := uint32(dst.Pix[d+1]) * 0x101\n"
+			load += "db := uint32(dst.Pix[d+2]) * 0x101\n"
+		}
+		if c.fd != "0" || strings.Contains(c.fs, "da") {
+			load += "da := uint32(dst.Pix[d+3]) * 0x101\n"
+		}
+		code := strings.Replace(codeCompositeLeaf, "$op", c.op, -1)
+		code = strings.Replace(code, "$load", load, -1)
+		code = strings.Replace(code, "$blend", blend, -1)
+		w.WriteString(code)
+	}
+}
+
 func expn(w *bytes.Buffer, code string, d *data) {
 	if d.sType == "*image.YCbCr" && d.sratio == "" {
 		for _, sratio := range subsampleRatios {
@@ -220,6 +298,24 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 	case "op":
 		return prefix + d.op + suffix
 
+	case "scalePaletted":
+		if d.receiver != "nnInterpolator" {
+			return ";"
+		}
+		return "" +
+			"if scalePaletted(dst, dr, src, sr, op, &o) {\n" +
+			"return\n" +
+			"}"
+
+	case "sampler":
+		switch d.receiver {
+		case "nnInterpolator":
+			return prefix + "nnSample" + suffix
+		case "ablInterpolator":
+			return prefix + "ablSample" + suffix
+		}
+		return ""
+
 	case "switch":
 		return expnSwitch("", "", true, suffix)
 	case "switchD":
@@ -246,8 +342,14 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 		switch d.dType {
 		default:
 			return ";"
-		case "*image.RGBA":
+		case "*image.NRGBA", "*image.RGBA":
 			return "d := " + pixOffset("dst", "dr.Min.X+adr.Min.X", "dr.Min.Y+int(dy)", "*4", "*dst.Stride")
+		case "*image.Gray":
+			return "d := " + pixOffset("dst", "dr.Min.X+adr.Min.X", "dr.Min.Y+int(dy)", "", "*dst.Stride")
+		case "*image.Gray16":
+			return "d := " + pixOffset("dst", "dr.Min.X+adr.Min.X", "dr.Min.Y+int(dy)", "*2", "*dst.Stride")
+		case "*image.RGBA64":
+			return "d := " + pixOffset("dst", "dr.Min.X+adr.Min.X", "dr.Min.Y+int(dy)", "*8", "*dst.Stride")
 		}
 
 	case "preKernelOuter":
@@ -258,12 +360,40 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 			return "srcMask, smp := opts.SrcMask, opts.SrcMaskP"
 		}
 
+	case "preKernelXSIMD":
+		switch d.sType {
+		default:
+			return ";"
+		case "*image.RGBA":
+			return "" +
+				"if haveScaleSIMD {\n" +
+				"var p [4]float64\n" +
+				"pi := " + pixOffset("src", "sr.Min.X", "sr.Min.Y+int(y)", "*4", "*src.Stride") + "\n" +
+				"scaleXRGBASIMD(&p, src.Pix[pi:], z.horizontal.contribs[s.i:s.j])\n" +
+				"pr, pg, pb, pa = p[0], p[1], p[2], p[3]\n" +
+				"} else {"
+		}
+
+	case "postKernelXSIMD":
+		switch d.sType {
+		default:
+			return ";"
+		case "*image.RGBA":
+			return "}"
+		}
+
 	case "preKernelInner":
 		switch d.dType {
 		default:
 			return ";"
-		case "*image.RGBA":
+		case "*image.NRGBA", "*image.RGBA":
 			return "d := " + pixOffset("dst", "dr.Min.X+int(dx)", "dr.Min.Y+adr.Min.Y", "*4", "*dst.Stride")
+		case "*image.Gray":
+			return "d := " + pixOffset("dst", "dr.Min.X+int(dx)", "dr.Min.Y+adr.Min.Y", "", "*dst.Stride")
+		case "*image.Gray16":
+			return "d := " + pixOffset("dst", "dr.Min.X+int(dx)", "dr.Min.Y+adr.Min.Y", "*2", "*dst.Stride")
+		case "*image.RGBA64":
+			return "d := " + pixOffset("dst", "dr.Min.X+int(dx)", "dr.Min.Y+adr.Min.Y", "*8", "*dst.Stride")
 		}
 
 	case "blend":
@@ -279,11 +409,11 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 				"$3b = $0*$1b + $2*$3b\n"+
 				"$3a = $0*$1a + $2*$3a",
 			)
-		case "*image.Gray":
+		case "*image.Gray", "*image.Gray16":
 			return argf(args, ""+
 				"$3r = $0*$1r + $2*$3r",
 			)
-		case "*image.YCbCr":
+		case "*image.CMYK", "*image.YCbCr":
 			return argf(args, ""+
 				"$3r = $0*$1r + $2*$3r\n"+
 				"$3g = $0*$1g + $2*$3g\n"+
@@ -317,17 +447,24 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 
 		switch d.sType {
 		default:
+			if ignoresAlpha(d) {
+				return argf(args, ""+
+					"$0r := uint32($1r)\n"+
+					"$0g := uint32($1g)\n"+
+					"$0b := uint32($1b)",
+				)
+			}
 			return argf(args, ""+
 				"$0r := uint32($1r)\n"+
 				"$0g := uint32($1g)\n"+
 				"$0b := uint32($1b)\n"+
 				"$0a := uint32($1a)",
 			)
-		case "*image.Gray":
+		case "*image.Gray", "*image.Gray16":
 			return argf(args, ""+
 				"$0r := uint32($1r)",
 			)
-		case "*image.YCbCr":
+		case "*image.CMYK", "*image.YCbCr":
 			return argf(args, ""+
 				"$0r := uint32($1r)\n"+
 				"$0g := uint32($1g)\n"+
@@ -371,6 +508,31 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 					"dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*$2a1/0xffff + $2b) >> 8)\n"+
 					"dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*$2a1/0xffff + $2a) >> 8)",
 				)
+			case "*image.Gray":
+				return argf(args, ""+
+					luma("$2y", "$2r", "$2g", "$2b")+
+					"$2a1 := 0xffff - $2a\n"+
+					"dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*$2a1/0xffff + $2y) >> 8)",
+				)
+			case "*image.Gray16":
+				return argf(args, ""+
+					luma("$2y", "$2r", "$2g", "$2b")+
+					"$2a1 := 0xffff - $2a\n"+
+					"qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])\n"+
+					"out := qy*$2a1/0xffff + $2y\n"+
+					"dst.Pix[d+0] = uint8(out >> 8)\n"+
+					"dst.Pix[d+1] = uint8(out)",
+				)
+			case "*image.NRGBA":
+				return argf(args, ""+
+					"$2a1 := 0xffff - $2a\n"+
+					nrgbaOver("$2r", "$2g", "$2b", "$2a", "$2a1"),
+				)
+			case "*image.RGBA64":
+				return argf(args, ""+
+					"$2a1 := 0xffff - $2a\n"+
+					rgba64Over("$2r", "$2g", "$2b", "$2a", "$2a1"),
+				)
 			}
 
 		case "Src":
@@ -409,7 +571,7 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 						"dst.Pix[d+2] = uint8($2b >> 8)\n"+
 						"dst.Pix[d+3] = uint8($2a >> 8)",
 					)
-				case "*image.Gray":
+				case "*image.Gray", "*image.Gray16":
 					return argf(args, ""+
 						"out := uint8($2r >> 8)\n"+
 						"dst.Pix[d+0] = out\n"+
@@ -417,7 +579,7 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 						"dst.Pix[d+2] = out\n"+
 						"dst.Pix[d+3] = 0xff",
 					)
-				case "*image.YCbCr":
+				case "*image.CMYK", "*image.YCbCr":
 					return argf(args, ""+
 						"dst.Pix[d+0] = uint8($2r >> 8)\n"+
 						"dst.Pix[d+1] = uint8($2g >> 8)\n"+
@@ -425,6 +587,41 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 						"dst.Pix[d+3] = 0xff",
 					)
 				}
+			case "*image.Gray":
+				switch d.sType {
+				default:
+					return argf(args, ""+
+						luma("$2y", "$2r", "$2g", "$2b")+
+						"dst.Pix[d] = uint8($2y >> 8)",
+					)
+				case "*image.Gray", "*image.Gray16":
+					return argf(args, ""+
+						"dst.Pix[d] = uint8($2r >> 8)",
+					)
+				}
+			case "*image.Gray16":
+				switch d.sType {
+				default:
+					return argf(args, ""+
+						luma("$2y", "$2r", "$2g", "$2b")+
+						"dst.Pix[d+0] = uint8($2y >> 8)\n"+
+						"dst.Pix[d+1] = uint8($2y)",
+					)
+				case "*image.Gray", "*image.Gray16":
+					return argf(args, ""+
+						"dst.Pix[d+0] = uint8($2r >> 8)\n"+
+						"dst.Pix[d+1] = uint8($2r)",
+					)
+				}
+			case "*image.NRGBA":
+				return argf(args, nrgbaStore("$2r", "$2g", "$2b", "$2a"))
+			case "*image.RGBA64":
+				switch d.sType {
+				default:
+					return argf(args, rgba64Store("$2r", "$2g", "$2b", "$2a"))
+				case "*image.CMYK", "*image.YCbCr":
+					return argf(args, rgba64Store("$2r", "$2g", "$2b", "0xffff"))
+				}
 			}
 		}
 
@@ -473,6 +670,47 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 					"dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*$3a1/0xffff + $3b0) >> 8)\n"+
 					"dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*$3a1/0xffff + $3a0) >> 8)",
 				)
+			case "*image.Gray":
+				ret = argf(args, ""+
+					"$3r0 := uint32($2($3r * $4))\n"+
+					"$3g0 := uint32($2($3g * $4))\n"+
+					"$3b0 := uint32($2($3b * $4))\n"+
+					"$3a0 := uint32($2($3a * $4))\n"+
+					luma("$3y", "$3r0", "$3g0", "$3b0")+
+					"$3a1 := 0xffff - $3a0\n"+
+					"dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*$3a1/0xffff + $3y) >> 8)",
+				)
+			case "*image.Gray16":
+				ret = argf(args, ""+
+					"$3r0 := uint32($2($3r * $4))\n"+
+					"$3g0 := uint32($2($3g * $4))\n"+
+					"$3b0 := uint32($2($3b * $4))\n"+
+					"$3a0 := uint32($2($3a * $4))\n"+
+					luma("$3y", "$3r0", "$3g0", "$3b0")+
+					"$3a1 := 0xffff - $3a0\n"+
+					"qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])\n"+
+					"out := qy*$3a1/0xffff + $3y\n"+
+					"dst.Pix[d+0] = uint8(out >> 8)\n"+
+					"dst.Pix[d+1] = uint8(out)",
+				)
+			case "*image.NRGBA":
+				ret = argf(args, ""+
+					"$3r0 := uint32($2($3r * $4))\n"+
+					"$3g0 := uint32($2($3g * $4))\n"+
+					"$3b0 := uint32($2($3b * $4))\n"+
+					"$3a0 := uint32($2($3a * $4))\n"+
+					"$3a1 := 0xffff - $3a0\n"+
+					nrgbaOver("$3r0", "$3g0", "$3b0", "$3a0", "$3a1"),
+				)
+			case "*image.RGBA64":
+				ret = argf(args, ""+
+					"$3r0 := uint32($2($3r * $4))\n"+
+					"$3g0 := uint32($2($3g * $4))\n"+
+					"$3b0 := uint32($2($3b * $4))\n"+
+					"$3a0 := uint32($2($3a * $4))\n"+
+					"$3a1 := 0xffff - $3a0\n"+
+					rgba64Over("$3r0", "$3g0", "$3b0", "$3a0", "$3a1"),
+				)
 			}
 
 		case "Src":
@@ -511,7 +749,7 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 						"dst.Pix[d+2] = uint8($2($3b * $4) >> 8)\n"+
 						"dst.Pix[d+3] = uint8($2($3a * $4) >> 8)",
 					)
-				case "*image.Gray":
+				case "*image.Gray", "*image.Gray16":
 					ret = argf(args, ""+
 						"out := uint8($2($3r * $4) >> 8)\n"+
 						"dst.Pix[d+0] = out\n"+
@@ -519,7 +757,7 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 						"dst.Pix[d+2] = out\n"+
 						"dst.Pix[d+3] = 0xff",
 					)
-				case "*image.YCbCr":
+				case "*image.CMYK", "*image.YCbCr":
 					ret = argf(args, ""+
 						"dst.Pix[d+0] = uint8($2($3r * $4) >> 8)\n"+
 						"dst.Pix[d+1] = uint8($2($3g * $4) >> 8)\n"+
@@ -527,6 +765,65 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 						"dst.Pix[d+3] = 0xff",
 					)
 				}
+			case "*image.Gray":
+				switch d.sType {
+				default:
+					ret = argf(args, ""+
+						"$3r0 := uint32($2($3r * $4))\n"+
+						"$3g0 := uint32($2($3g * $4))\n"+
+						"$3b0 := uint32($2($3b * $4))\n"+
+						luma("$3y", "$3r0", "$3g0", "$3b0")+
+						"dst.Pix[d] = uint8($3y >> 8)",
+					)
+				case "*image.Gray", "*image.Gray16":
+					ret = argf(args, ""+
+						"dst.Pix[d] = uint8($2($3r * $4) >> 8)",
+					)
+				}
+			case "*image.Gray16":
+				switch d.sType {
+				default:
+					ret = argf(args, ""+
+						"$3r0 := uint32($2($3r * $4))\n"+
+						"$3g0 := uint32($2($3g * $4))\n"+
+						"$3b0 := uint32($2($3b * $4))\n"+
+						luma("$3y", "$3r0", "$3g0", "$3b0")+
+						"dst.Pix[d+0] = uint8($3y >> 8)\n"+
+						"dst.Pix[d+1] = uint8($3y)",
+					)
+				case "*image.Gray", "*image.Gray16":
+					ret = argf(args, ""+
+						"$3y := $2($3r * $4)\n"+
+						"dst.Pix[d+0] = uint8($3y >> 8)\n"+
+						"dst.Pix[d+1] = uint8($3y)",
+					)
+				}
+			case "*image.NRGBA":
+				ret = argf(args, ""+
+					"$3r0 := uint32($2($3r * $4))\n"+
+					"$3g0 := uint32($2($3g * $4))\n"+
+					"$3b0 := uint32($2($3b * $4))\n"+
+					"$3a0 := uint32($2($3a * $4))\n"+
+					nrgbaStore("$3r0", "$3g0", "$3b0", "$3a0"),
+				)
+			case "*image.RGBA64":
+				switch d.sType {
+				default:
+					ret = argf(args, ""+
+						"$3r0 := uint32($2($3r * $4))\n"+
+						"$3g0 := uint32($2($3g * $4))\n"+
+						"$3b0 := uint32($2($3b * $4))\n"+
+						"$3a0 := uint32($2($3a * $4))\n"+
+						rgba64Store("$3r0", "$3g0", "$3b0", "$3a0"),
+					)
+				case "*image.CMYK", "*image.YCbCr":
+					ret = argf(args, ""+
+						"$3r0 := uint32($2($3r * $4))\n"+
+						"$3g0 := uint32($2($3g * $4))\n"+
+						"$3b0 := uint32($2($3b * $4))\n"+
+						rgba64Store("$3r0", "$3g0", "$3b0", "0xffff"),
+					)
+				}
 			}
 		}
 
@@ -555,6 +852,13 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 		default:
 			log.Fatalf("bad sType %q", d.sType)
 		case "image.Image":
+			if dollar == "srcu" && ignoresAlpha(d) {
+				fmt.Fprintf(buf, ""+
+					"%sr, %sg, %sb, _ := src.At(%s, %s).RGBA()\n",
+					lhs, lhs, lhs, args[0], args[1],
+				)
+				break
+			}
 			fmt.Fprintf(buf, ""+
 				"%sr%s, %sg%s, %sb%s, %sa%s := src.At(%s, %s).RGBA()\n",
 				lhs, tmp, lhs, tmp, lhs, tmp, lhs, tmp, args[0], args[1],
@@ -582,6 +886,13 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 				lhs, pixOffset("src", args[0], args[1], "", "*src.Stride"),
 				lhs, tmp, lhs,
 			)
+		case "*image.Gray16":
+			fmt.Fprintf(buf, ""+
+				"%si := %s\n"+
+				"%sr%s := uint32(src.Pix[%si+0])<<8 | uint32(src.Pix[%si+1])\n",
+				lhs, pixOffset("src", args[0], args[1], "*2", "*src.Stride"),
+				lhs, tmp, lhs, lhs,
+			)
 		case "*image.NRGBA":
 			fmt.Fprintf(buf, ""+
 				"%si := %s\n"+
@@ -608,6 +919,26 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 				lhs, tmp, lhs,
 				lhs, tmp, lhs,
 			)
+		case "*image.RGBA64":
+			fmt.Fprintf(buf, ""+
+				"%si := %s\n"+
+				"%sr%s := uint32(src.Pix[%si+0])<<8 | uint32(src.Pix[%si+1])\n"+
+				"%sg%s := uint32(src.Pix[%si+2])<<8 | uint32(src.Pix[%si+3])\n"+
+				"%sb%s := uint32(src.Pix[%si+4])<<8 | uint32(src.Pix[%si+5])\n"+
+				"%sa%s := uint32(src.Pix[%si+6])<<8 | uint32(src.Pix[%si+7])\n",
+				lhs, pixOffset("src", args[0], args[1], "*8", "*src.Stride"),
+				lhs, tmp, lhs, lhs,
+				lhs, tmp, lhs, lhs,
+				lhs, tmp, lhs, lhs,
+				lhs, tmp, lhs, lhs,
+			)
+		case "*image.CMYK":
+			fmt.Fprintf(buf, ""+
+				"%si := %s\n"+
+				"%s\n",
+				lhs, pixOffset("src", args[0], args[1], "*4", "*src.Stride"),
+				cmykToRGB(lhs, tmp),
+			)
 		case "*image.YCbCr":
 			fmt.Fprintf(buf, ""+
 				"%si := %s\n"+
@@ -632,12 +963,12 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 					lhs, eqOp, lhs, extra,
 					lhs, eqOp, lhs, extra,
 				)
-			case "*image.Gray":
+			case "*image.Gray", "*image.Gray16":
 				fmt.Fprintf(buf, ""+
 					"%sr %s float64(%sru)%s\n",
 					lhs, eqOp, lhs, extra,
 				)
-			case "*image.YCbCr":
+			case "*image.CMYK", "*image.YCbCr":
 				fmt.Fprintf(buf, ""+
 					"%sr %s float64(%sru)%s\n"+
 					"%sg %s float64(%sgu)%s\n"+
@@ -652,31 +983,40 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 		return strings.TrimSpace(buf.String())
 
 	case "tweakD":
-		if d.dType == "*image.RGBA" {
+		switch d.dType {
+		case "*image.NRGBA", "*image.RGBA", "*image.Gray", "*image.Gray16", "*image.RGBA64":
 			return "d += dst.Stride"
 		}
 		return ";"
 
 	case "tweakDx":
-		if d.dType == "*image.RGBA" {
+		switch d.dType {
+		case "*image.NRGBA", "*image.RGBA":
 			return strings.Replace(prefix, "dx++", "dx, d = dx+1, d+4", 1)
+		case "*image.Gray":
+			return strings.Replace(prefix, "dx++", "dx, d = dx+1, d+1", 1)
+		case "*image.Gray16":
+			return strings.Replace(prefix, "dx++", "dx, d = dx+1, d+2", 1)
+		case "*image.RGBA64":
+			return strings.Replace(prefix, "dx++", "dx, d = dx+1, d+8", 1)
 		}
 		return prefix
 
 	case "tweakDy":
-		if d.dType == "*image.RGBA" {
+		switch d.dType {
+		case "*image.NRGBA", "*image.RGBA", "*image.Gray", "*image.Gray16", "*image.RGBA64":
 			return strings.Replace(prefix, "for dy, s", "for _, s", 1)
 		}
 		return prefix
 
 	case "tweakP":
 		switch d.sType {
-		case "*image.Gray":
+		case "*image.Gray", "*image.Gray16":
 			if strings.HasPrefix(strings.TrimSpace(prefix), "pa * ") {
 				return "1,"
 			}
 			return "pr,"
-		case "*image.YCbCr":
+		case "*image.CMYK", "*image.YCbCr":
 			if strings.HasPrefix(strings.TrimSpace(prefix), "pa * ") {
 				return "1,"
 			}
@@ -684,16 +1024,17 @@ func expnDollar(prefix, dollar, suffix string, d *data) string {
 		return prefix
 
 	case "tweakPr":
-		if d.sType == "*image.Gray" {
+		switch d.sType {
+		case "*image.Gray", "*image.Gray16":
 			return "pr *= s.invTotalWeightFFFF"
 		}
 		return ";"
 
 	case "tweakVarP":
 		switch d.sType {
-		case "*image.Gray":
+		case "*image.Gray", "*image.Gray16":
 			return strings.Replace(prefix, "var pr, pg, pb, pa", "var pr", 1)
-		case "*image.YCbCr":
+		case "*image.CMYK", "*image.YCbCr":
 			return strings.Replace(prefix, "var pr, pg, pb, pa", "var pr, pg, pb", 1)
 		}
 		return prefix
@@ -831,6 +1172,112 @@ func ycbcrToRGB(lhs, tmp string) string {
 	return s
 }
 
+func cmykToRGB(lhs, tmp string) string {
+	s := `
+		// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+		$w := 0xffff - uint32(src.Pix[$i+3])*0x101
+		$r@ := (0xffff - uint32(src.Pix[$i+0])*0x101) * $w / 0xffff
+		$g@ := (0xffff - uint32(src.Pix[$i+1])*0x101) * $w / 0xffff
+		$b@ := (0xffff - uint32(src.Pix[$i+2])*0x101) * $w / 0xffff
+	`
+	s = strings.Replace(s, "$", lhs, -1)
+	s = strings.Replace(s, "@", tmp, -1)
+	return s
+}
+
+// ignoresAlpha returns whether the generated code for d never reads the
+// source alpha. Src into a Gray or Gray16 destination, like those types'
+// color models, keeps only the premultiplied luma.
+func ignoresAlpha(d *data) bool {
+	if d.op != "Src" {
+		return false
+	}
+	return d.dType == "*image.Gray" || d.dType == "*image.Gray16"
+}
+
+// nrgbaOver returns code that composites the alpha-premultiplied 16-bit color
+// values r, g, b and a over the *image.NRGBA dst pixel at d, where a1 is
+// 0xffff - a. The dst pixel is premultiplied before blending and
+// un-premultiplied afterwards, the same as the generic At and Set code path.
+func nrgbaOver(r, g, b, a, a1 string) string {
+	return fmt.Sprintf(""+
+		"qa := uint32(dst.Pix[d+3]) * 0x101\n"+
+		"qr := uint32(dst.Pix[d+0]) * qa / 0xff\n"+
+		"qg := uint32(dst.Pix[d+1]) * qa / 0xff\n"+
+		"qb := uint32(dst.Pix[d+2]) * qa / 0xff\n"+
+		"%s",
+		nrgbaStore(
+			"qr*"+a1+"/0xffff + "+r,
+			"qg*"+a1+"/0xffff + "+g,
+			"qb*"+a1+"/0xffff + "+b,
+			"qa*"+a1+"/0xffff + "+a,
+		),
+	)
+}
+
+// nrgbaStore returns code that un-premultiplies the alpha-premultiplied 16-bit
+// color values r, g, b and a, and stores them in the *image.NRGBA dst pixel
+// at d, the same as image/color's NRGBAModel.
+func nrgbaStore(r, g, b, a string) string {
+	return fmt.Sprintf(""+
+		"or, og, ob, oa := %s, %s, %s, %s\n"+
+		"if oa == 0 {\n"+
+		"	dst.Pix[d+0] = 0\n"+
+		"	dst.Pix[d+1] = 0\n"+
+		"	dst.Pix[d+2] = 0\n"+
+		"	dst.Pix[d+3] = 0\n"+
+		"} else {\n"+
+		"	dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)\n"+
+		"	dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)\n"+
+		"	dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)\n"+
+		"	dst.Pix[d+3] = uint8(oa >> 8)\n"+
+		"}",
+		r, g, b, a,
+	)
+}
+
+// rgba64Over returns code that composites the alpha-premultiplied 16-bit
+// color values r, g, b and a over the *image.RGBA64 dst pixel at d, where a1
+// is 0xffff - a.
+func rgba64Over(r, g, b, a, a1 string) string {
+	return fmt.Sprintf(""+
+		"qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])\n"+
+		"qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])\n"+
+		"qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])\n"+
+		"qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])\n"+
+		"%s",
+		rgba64Store(
+			"qr*"+a1+"/0xffff + "+r,
+			"qg*"+a1+"/0xffff + "+g,
+			"qb*"+a1+"/0xffff + "+b,
+			"qa*"+a1+"/0xffff + "+a,
+		),
+	)
+}
+
+// rgba64Store returns code that stores the alpha-premultiplied 16-bit color
+// values r, g, b and a in the *image.RGBA64 dst pixel at d.
+func rgba64Store(r, g, b, a string) string {
+	return fmt.Sprintf(""+
+		"or, og, ob, oa := %s, %s, %s, uint32(%s)\n"+
+		"dst.Pix[d+0] = uint8(or >> 8)\n"+
+		"dst.Pix[d+1] = uint8(or)\n"+
+		"dst.Pix[d+2] = uint8(og >> 8)\n"+
+		"dst.Pix[d+3] = uint8(og)\n"+
+		"dst.Pix[d+4] = uint8(ob >> 8)\n"+
+		"dst.Pix[d+5] = uint8(ob)\n"+
+		"dst.Pix[d+6] = uint8(oa >> 8)\n"+
+		"dst.Pix[d+7] = uint8(oa)",
+		r, g, b, a,
+	)
+}
+
+// luma returns code that declares y as the 16-bit luma of the 16-bit color
+// values r, g and b, using the same formula as image/color's Gray16Model.
+func luma(y, r, g, b string) string {
+	return fmt.Sprintf("%s := (19595*%s + 38470*%s + 7471*%s + 1<<15) >> 16\n", y, r, g, b)
+}
+
 func split(s, sep string) (string, string) {
 	if i := strings.Index(s, sep); i >= 0 {
 		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+len(sep):])
@@ -875,6 +1322,35 @@ func relName(s string) string {
 }
 
 const (
+	codeCompositeRoot = `
+		// compositeRGBA composites the pixels adr of tmp onto dst with op,
+		// skipping those that drawComposited's f left unchanged.
+		func compositeRGBA(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle, op CompositeOp) {
+			switch op {
+	`
+
+	codeCompositeLeaf = `
+		func compositeRGBA_$op(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+			for y := adr.Min.Y; y < adr.Max.Y; y++ {
+				d := dst.PixOffset(adr.Min.X, y)
+				t := tmp.PixOffset(adr.Min.X, y)
+				for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+					sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+					sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+					if sr > sa {
+						continue
+					}
+					$load
+					$blend
+					dst.Pix[d+0] = uint8(or >> 8)
+					dst.Pix[d+1] = uint8(og >> 8)
+					dst.Pix[d+2] = uint8(ob >> 8)
+					dst.Pix[d+3] = uint8(oa >> 8)
+				}
+			}
+		}
+	`
+
 	codeRoot = `
 		func (z $receiver) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
 			// Try to simplify a Scale to a Copy when DstMask is not specified.
@@ -883,11 +1359,29 @@ const (
 				Copy(dst, dr.Min, src, sr, op, opts)
 				return
 			}
+			if compositeDst(opts) {
+				drawComposited(dst, dr, opts, func(tmp Image, o *Options) {
+					z.Scale(tmp, dr, src, sr, Src, o)
+				})
+				return
+			}
+			if p, ok := ditherDst(dst, opts); ok {
+				drawDithered(p, dr, opts, func(tmp Image, o *Options) {
+					z.Scale(tmp, dr, src, sr, op, o)
+				})
+				return
+			}
+			if isFloat(dst) || isFloat(src) {
+				scaleFloat(dst, dr, src, sr, op, opts, $sampler)
+				return
+			}
 
 			var o Options
 			if opts != nil {
 				o = *opts
 			}
+			src = applyColorMatrix(src, &o)
+			$scalePaletted
 
 			// adr is the affected destination pixels.
 			adr := dst.Bounds().Intersect(dr)
@@ -921,12 +1415,33 @@ const (
 		}
 
 		func (z $receiver) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+			if compositeDst(opts) {
+				drawComposited(dst, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+					z.Transform(tmp, s2d, src, sr, Src, o)
+				})
+				return
+			}
+			if p, ok := ditherDst(dst, opts); ok {
+				drawDithered(p, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+					z.Transform(tmp, s2d, src, sr, op, o)
+				})
+				return
+			}
+			if isFloat(dst) || isFloat(src) {
+				transformFloat(dst, s2d, src, sr, op, opts, $sampler)
+				return
+			}
+			if opts != nil && opts.EdgeOp != EdgeNone {
+				transformEdges(z, dst, s2d, src, sr, op, opts)
+				return
+			}
+
 			// Try to simplify a Transform to a Copy.
 			if s2d[0] == 1 && s2d[1] == 0 && s2d[3] == 0 && s2d[4] == 1 {
 				dx := int(s2d[2])
 				dy := int(s2d[5])
 				if float64(dx) == s2d[2] && float64(dy) == s2d[5] {
-					Copy(dst, image.Point{X: sr.Min.X + dx, Y: sr.Min.X + dy}, src, sr, op, opts)
+					Copy(dst, image.Point{X: sr.Min.X + dx, Y: sr.Min.Y + dy}, src, sr, op, opts)
 					return
 				}
 			}
@@ -935,6 +1450,7 @@ const (
 			if opts != nil {
 				o = *opts
 			}
+			src = applyColorMatrix(src, &o)
 
 			dr := transformRect(&s2d, &sr)
 			// adr is the affected destination pixels.
@@ -947,7 +1463,10 @@ const (
 				op = Src
 			}
 
-			d2s := invert(&s2d)
+			d2s, ok := s2d.Invert()
+			if !ok {
+				return
+			}
 			// bias is a translation of the mapping from dst coordinates to src
 			// coordinates such that the latter temporarily have non-negative X
 			// and Y coordinates. This allows us to write int(f) instead of
@@ -984,16 +1503,14 @@ const (
 
 	codeNNScaleLeaf = `
 		func (nnInterpolator) scale_$dTypeRN_$sTypeRN$sratio_$op(dst $dType, dr, adr image.Rectangle, src $sType, sr image.Rectangle, opts *Options) {
-			dw2 := uint64(dr.Dx()) * 2
-			dh2 := uint64(dr.Dy()) * 2
-			sw := uint64(sr.Dx())
-			sh := uint64(sr.Dy())
+			xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+			yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 			$preOuter
 			for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-				sy := (2*uint64(dy) + 1) * sh / dh2
+				sy := (2*uint64(dy)*yn + yo) / yd
 				$preInner
 				for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ { $tweakDx
-					sx := (2*uint64(dx) + 1) * sw / dw2
+					sx := (2*uint64(dx)*xn + xo) / xd
 					p := $srcu[sr.Min.X + int(sx), sr.Min.Y + int(sy)]
 					$outputu[dr.Min.X + int(dx), dr.Min.Y + int(dy), p]
 				}
@@ -1025,13 +1542,13 @@ const (
 		func (ablInterpolator) scale_$dTypeRN_$sTypeRN$sratio_$op(dst $dType, dr, adr image.Rectangle, src $sType, sr image.Rectangle, opts *Options) {
 			sw := int32(sr.Dx())
 			sh := int32(sr.Dy())
-			yscale := float64(sh) / float64(dr.Dy())
-			xscale := float64(sw) / float64(dr.Dx())
+			yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+			xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
 			swMinus1, shMinus1 := sw - 1, sh - 1
 			$preOuter
 
 			for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-				sy := (float64(dy)+0.5)*yscale - 0.5
+				sy := (float64(dy)+yoffset)*yscale - yoffset
 				// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
 				// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
 				// sx, below.
@@ -1049,7 +1566,7 @@ const (
 				$preInner
 
 				for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ { $tweakDx
-					sx := (float64(dx)+0.5)*xscale - 0.5
+					sx := (float64(dx)+xoffset)*xscale - xoffset
 					sx0 := int32(sx)
 					xFrac0 := sx - float64(sx0)
 					xFrac1 := 1 - xFrac0
@@ -1134,15 +1651,33 @@ const (
 
 	codeKernelRoot = `
 		func (z *kernelScaler) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
-			if z.dw != int32(dr.Dx()) || z.dh != int32(dr.Dy()) || z.sw != int32(sr.Dx()) || z.sh != int32(sr.Dy()) {
-				z.kernel.Scale(dst, dr, src, sr, op, opts)
-				return
-			}
-
 			var o Options
 			if opts != nil {
 				o = *opts
 			}
+			if z.dw != int32(dr.Dx()) || z.dh != int32(dr.Dy()) || z.sw != int32(sr.Dx()) || z.sh != int32(sr.Dy()) || z.align != o.Alignment {
+				z.kernel.Scale(dst, dr, src, sr, op, opts)
+				return
+			}
+			if compositeDst(opts) {
+				drawComposited(dst, dr, opts, func(tmp Image, o *Options) {
+					z.Scale(tmp, dr, src, sr, Src, o)
+				})
+				return
+			}
+			if p, ok := ditherDst(dst, opts); ok {
+				drawDithered(p, dr, opts, func(tmp Image, o *Options) {
+					z.Scale(tmp, dr, src, sr, op, o)
+				})
+				return
+			}
+			// A Convolve's kernelScaler has no kernel to sample float images
+			// with, and instead converts their pixels to and from 16 bits.
+			if z.kernel != nil && (isFloat(dst) || isFloat(src)) {
+				scaleFloat(dst, dr, src, sr, op, opts, (&kernelSampler{q: z.kernel}).sample)
+				return
+			}
+			src = applyColorMatrix(src, &o)
 
 			// adr is the affected destination pixels.
 			adr := dst.Bounds().Intersect(dr)
@@ -1156,7 +1691,7 @@ const (
 				op = Src
 			}
 
-			if _, ok := src.(*image.Uniform); ok && o.DstMask == nil && o.SrcMask == nil && sr.In(src.Bounds()) {
+			if _, ok := src.(*image.Uniform); ok && o.DstMask == nil && o.SrcMask == nil && o.RowsDone == nil && sr.In(src.Bounds()) {
 				Draw(dst, dr, src, src.Bounds().Min, op)
 				return
 			}
@@ -1165,7 +1700,9 @@ const (
 			// scaleX distributes the source image's columns over the temporary image.
 			// scaleY distributes the temporary image's rows over the destination image.
 			var tmp [][4]float64
-			if z.pool.New != nil {
+			if o.ScaleBuffer != nil {
+				tmp = o.ScaleBuffer.get(int(z.dw) * int(z.sh))
+			} else if z.pool.New != nil {
 				tmpp := z.pool.Get().(*[][4]float64)
 				defer z.pool.Put(tmpp)
 				tmp = *tmpp
@@ -1178,29 +1715,89 @@ const (
 			// the Pix fields directly without bounds checking.
 			//
 			// Similarly, the fast paths assume that the masks are nil.
-			if o.SrcMask != nil || !sr.In(src.Bounds()) {
-				z.scaleX_Image(tmp, src, sr, &o)
-			} else {
-				$switchS z.scaleX_$sTypeRN$sratio(tmp, src, sr, &o)
-			}
+			inBounds := sr.In(src.Bounds())
+			parallel(o.Concurrency, 0, z.sh, func(y0, y1 int32) {
+				if o.SrcMask != nil || !inBounds {
+					z.scaleX_Image(tmp, src, sr, y0, y1, &o)
+				} else {
+					$switchS z.scaleX_$sTypeRN$sratio(tmp, src, sr, y0, y1, &o)
+				}
+				if o.AntiRinging {
+					z.clampX(tmp, src, sr, y0, y1, &o)
+				}
+			})
 
-			if o.DstMask != nil {
-				switch op {
-				case Over:
-					z.scaleY_Image_Over(dst, dr, adr, tmp, &o)
-				case Src:
-					z.scaleY_Image_Src(dst, dr, adr, tmp, &o)
+			if o.RowsDone == nil {
+				z.scaleY(dst, dr, adr, tmp, op, &o)
+				return
+			}
+			for y := dr.Min.Y + adr.Min.Y; ; {
+				// Each band is the part of dr within dst's current bounds,
+				// which RowsDone may change to move dst on to later rows.
+				band := dst.Bounds().Intersect(dr)
+				if band.Min.Y < y {
+					band.Min.Y = y
 				}
-			} else {
-				$switchD z.scaleY_$dTypeRN_$op(dst, dr, adr, tmp, &o)
+				band, o.DstMask = clipAffectedDestRect(band, opts.DstMask, opts.DstMaskP)
+				if band.Empty() {
+					return
+				}
+				z.scaleY(dst, dr, band.Sub(dr.Min), tmp, op, &o)
+				o.RowsDone(band.Min.Y, band.Max.Y)
+				y = band.Max.Y
 			}
 		}
 
+		// scaleY distributes the rows of tmp over the dst pixels adr, which is
+		// relative to dr.Min.
+		func (z *kernelScaler) scaleY(dst Image, dr, adr image.Rectangle, tmp [][4]float64, op Op, o *Options) {
+			parallel(o.Concurrency, int32(adr.Min.Y), int32(adr.Max.Y), func(y0, y1 int32) {
+				adr := image.Rect(adr.Min.X, int(y0), adr.Max.X, int(y1))
+				if o.DstMask != nil {
+					switch op {
+					case Over:
+						z.scaleY_Image_Over(dst, dr, adr, tmp, o)
+					case Src:
+						z.scaleY_Image_Src(dst, dr, adr, tmp, o)
+					}
+				} else {
+					$switchD z.scaleY_$dTypeRN_$op(dst, dr, adr, tmp, o)
+				}
+			})
+		}
+
 		func (q *Kernel) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+			if compositeDst(opts) {
+				drawComposited(dst, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+					q.Transform(tmp, s2d, src, sr, Src, o)
+				})
+				return
+			}
+			if p, ok := ditherDst(dst, opts); ok {
+				drawDithered(p, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+					q.Transform(tmp, s2d, src, sr, op, o)
+				})
+				return
+			}
+			if isFloat(dst) || isFloat(src) {
+				transformFloat(dst, s2d, src, sr, op, opts, (&kernelSampler{q: q}).sample)
+				return
+			}
+			if opts != nil && opts.EdgeOp != EdgeNone {
+				transformEdges(q, dst, s2d, src, sr, op, opts)
+				return
+			}
+			q.transform(dst, s2d, src, sr, op, opts, nil)
+		}
+
+		// transform implements Transform. If c is non-nil, it holds the
+		// precomputed kernel weights for s2d and sr.
+		func (q *Kernel) transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options, c *kernelTransformer) {
 			var o Options
 			if opts != nil {
 				o = *opts
 			}
+			src = applyColorMatrix(src, &o)
 
 			dr := transformRect(&s2d, &sr)
 			// adr is the affected destination pixels.
@@ -1212,7 +1809,10 @@ const (
 			if op == Over && o.SrcMask == nil && opaque(src) {
 				op = Src
 			}
-			d2s := invert(&s2d)
+			d2s, ok := s2d.Invert()
+			if !ok {
+				return
+			}
 			// bias is a translation of the mapping from dst coordinates to src
 			// coordinates such that the latter temporarily have non-negative X
 			// and Y coordinates. This allows us to write int(f) instead of
@@ -1250,26 +1850,28 @@ const (
 			if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
 				switch op {
 				case Over:
-					q.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+					q.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
 				case Src:
-					q.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+					q.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
 				}
 			} else {
-				$switch q.transform_$dTypeRN_$sTypeRN$sratio_$op(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+				$switch q.transform_$dTypeRN_$sTypeRN$sratio_$op(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
 			}
 		}
 	`
 
 	codeKernelScaleLeafX = `
-		func (z *kernelScaler) scaleX_$sTypeRN$sratio(tmp [][4]float64, src $sType, sr image.Rectangle, opts *Options) {
-			t := 0
+		func (z *kernelScaler) scaleX_$sTypeRN$sratio(tmp [][4]float64, src $sType, sr image.Rectangle, y0, y1 int32, opts *Options) {
+			t := int(y0) * int(z.dw)
 			$preKernelOuter
-			for y := int32(0); y < z.sh; y++ {
+			for y := y0; y < y1; y++ {
 				for _, s := range z.horizontal.sources {
 					var pr, pg, pb, pa float64 $tweakVarP
+					$preKernelXSIMD
 					for _, c := range z.horizontal.contribs[s.i:s.j] {
 						p += $srcf[sr.Min.X + int(c.coord), sr.Min.Y + int(y)] * c.weight
 					}
+					$postKernelXSIMD
 					$tweakPr
 					tmp[t] = [4]float64{
 						pr * s.invTotalWeightFFFF, $tweakP
@@ -1290,12 +1892,21 @@ const (
 				$preKernelInner
 				for dy, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] { $tweakDy
 					var pr, pg, pb, pa float64
-					for _, c := range z.vertical.contribs[s.i:s.j] {
-						p := &tmp[c.coord*z.dw+dx]
-						pr += p[0] * c.weight
-						pg += p[1] * c.weight
-						pb += p[2] * c.weight
-						pa += p[3] * c.weight
+					if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+						var p [4]float64
+						scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+						pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+					} else {
+						for _, c := range z.vertical.contribs[s.i:s.j] {
+							p := &tmp[c.coord*z.dw+dx]
+							pr += p[0] * c.weight
+							pg += p[1] * c.weight
+							pb += p[2] * c.weight
+							pa += p[3] * c.weight
+						}
+					}
+					if opts.AntiRinging {
+						pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
 					}
 					$clampToAlpha
 					$outputf[dr.Min.X + int(dx), dr.Min.Y + int(adr.Min.Y + dy), ftou, p, s.invTotalWeight]
@@ -1306,7 +1917,7 @@ const (
 	`
 
 	codeKernelTransformLeaf = `
-		func (q *Kernel) transform_$dTypeRN_$sTypeRN$sratio_$op(dst $dType, dr, adr image.Rectangle, d2s *f64.Aff3, src $sType, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+		func (q *Kernel) transform_$dTypeRN_$sTypeRN$sratio_$op(dst $dType, dr, adr image.Rectangle, d2s *f64.Aff3, src $sType, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 			// When shrinking, broaden the effective kernel support so that we still
 			// visit every source pixel.
 			xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -1320,69 +1931,83 @@ const (
 				yKernelArgScale = 1 / yscale
 			}
 
-			xWeights := make([]float64, 1 + 2*int(math.Ceil(xHalfWidth)))
-			yWeights := make([]float64, 1 + 2*int(math.Ceil(yHalfWidth)))
+			var xWeights, yWeights []float64
+			if c == nil {
+				xWeights = make([]float64, 1 + 2*int(math.Ceil(xHalfWidth)))
+				yWeights = make([]float64, 1 + 2*int(math.Ceil(yHalfWidth)))
+			}
 
 			$preOuter
 			for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 				dyf := float64(dr.Min.Y + int(dy)) + 0.5
 				$preInner
 				for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ { $tweakDx
-					dxf := float64(dr.Min.X + int(dx)) + 0.5
-					sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-					sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-					if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-						continue
-					}
+					var ix, jx, iy, jy int
+					if c != nil {
+						s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+						if s.w < 0 {
+							continue
+						}
+						ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+						xWeights = c.weights[s.w:]
+						yWeights = xWeights[jx-ix:]
+					} else {
+						dxf := float64(dr.Min.X + int(dx)) + 0.5
+						sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+						sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+						if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+							continue
+						}
 
-					// TODO: adjust the bias so that we can use int(f) instead
-					// of math.Floor(f) and math.Ceil(f).
-					sx += float64(bias.X)
-					sx -= 0.5
-					ix := int(math.Floor(sx - xHalfWidth))
-					if ix < sr.Min.X {
-						ix = sr.Min.X
-					}
-					jx := int(math.Ceil(sx + xHalfWidth))
-					if jx > sr.Max.X {
-						jx = sr.Max.X
-					}
+						// TODO: adjust the bias so that we can use int(f) instead
+						// of math.Floor(f) and math.Ceil(f).
+						sx += float64(bias.X)
+						sx -= 0.5
+						ix = int(math.Floor(sx - xHalfWidth))
+						if ix < sr.Min.X {
+							ix = sr.Min.X
+						}
+						jx = int(math.Ceil(sx + xHalfWidth))
+						if jx > sr.Max.X {
+							jx = sr.Max.X
+						}
 
-					totalXWeight := 0.0
-					for kx := ix; kx < jx; kx++ {
-						xWeight := 0.0
-						if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-							xWeight = q.At(t)
+						totalXWeight := 0.0
+						for kx := ix; kx < jx; kx++ {
+							xWeight := 0.0
+							if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+								xWeight = q.At(t)
+							}
+							xWeights[kx - ix] = xWeight
+							totalXWeight += xWeight
+						}
+						for x := range xWeights[:jx-ix] {
+							xWeights[x] /= totalXWeight
 						}
-						xWeights[kx - ix] = xWeight
-						totalXWeight += xWeight
-					}
-					for x := range xWeights[:jx-ix] {
-						xWeights[x] /= totalXWeight
-					}
 
-					sy += float64(bias.Y)
-					sy -= 0.5
-					iy := int(math.Floor(sy - yHalfWidth))
-					if iy < sr.Min.Y {
-						iy = sr.Min.Y
-					}
-					jy := int(math.Ceil(sy + yHalfWidth))
-					if jy > sr.Max.Y {
-						jy = sr.Max.Y
-					}
+						sy += float64(bias.Y)
+						sy -= 0.5
+						iy = int(math.Floor(sy - yHalfWidth))
+						if iy < sr.Min.Y {
+							iy = sr.Min.Y
+						}
+						jy = int(math.Ceil(sy + yHalfWidth))
+						if jy > sr.Max.Y {
+							jy = sr.Max.Y
+						}
 
-					totalYWeight := 0.0
-					for ky := iy; ky < jy; ky++ {
-						yWeight := 0.0
-						if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-							yWeight = q.At(t)
+						totalYWeight := 0.0
+						for ky := iy; ky < jy; ky++ {
+							yWeight := 0.0
+							if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+								yWeight = q.At(t)
+							}
+							yWeights[ky - iy] = yWeight
+							totalYWeight += yWeight
+						}
+						for y := range yWeights[:jy-iy] {
+							yWeights[y] /= totalYWeight
 						}
-						yWeights[ky - iy] = yWeight
-						totalYWeight += yWeight
-					}
-					for y := range yWeights[:jy-iy] {
-						yWeights[y] /= totalYWeight
 					}
 
 					var pr, pg, pb, pa float64 $tweakVarP
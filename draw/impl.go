@@ -17,11 +17,31 @@ func (z nnInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr
 		Copy(dst, dr.Min, src, sr, op, opts)
 		return
 	}
+	if compositeDst(opts) {
+		drawComposited(dst, dr, opts, func(tmp Image, o *Options) {
+			z.Scale(tmp, dr, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, dr, opts, func(tmp Image, o *Options) {
+			z.Scale(tmp, dr, src, sr, op, o)
+		})
+		return
+	}
+	if isFloat(dst) || isFloat(src) {
+		scaleFloat(dst, dr, src, sr, op, opts, nnSample)
+		return
+	}
 
 	var o Options
 	if opts != nil {
 		o = *opts
 	}
+	src = applyColorMatrix(src, &o)
+	if scalePaletted(dst, dr, src, sr, op, &o) {
+		return
+	}
 
 	// adr is the affected destination pixels.
 	adr := dst.Bounds().Intersect(dr)
@@ -59,9 +79,41 @@ func (z nnInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr
 					z.scale_RGBA_NRGBA_Over(dst, dr, adr, src, sr, &o)
 				case *image.RGBA:
 					z.scale_RGBA_RGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA_RGBA64_Over(dst, dr, adr, src, sr, &o)
 				default:
 					z.scale_RGBA_Image_Over(dst, dr, adr, src, sr, &o)
 				}
+			case *image.Gray:
+				switch src := src.(type) {
+				default:
+					z.scale_Gray_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				default:
+					z.scale_Gray16_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_NRGBA_NRGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_NRGBA_RGBA_Over(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_NRGBA_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_RGBA64_NRGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_RGBA64_RGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA64_RGBA64_Over(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_RGBA64_Image_Over(dst, dr, adr, src, sr, &o)
+				}
 			default:
 				switch src := src.(type) {
 				default:
@@ -72,12 +124,18 @@ func (z nnInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr
 			switch dst := dst.(type) {
 			case *image.RGBA:
 				switch src := src.(type) {
+				case *image.CMYK:
+					z.scale_RGBA_CMYK_Src(dst, dr, adr, src, sr, &o)
 				case *image.Gray:
 					z.scale_RGBA_Gray_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray16:
+					z.scale_RGBA_Gray16_Src(dst, dr, adr, src, sr, &o)
 				case *image.NRGBA:
 					z.scale_RGBA_NRGBA_Src(dst, dr, adr, src, sr, &o)
 				case *image.RGBA:
 					z.scale_RGBA_RGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA_RGBA64_Src(dst, dr, adr, src, sr, &o)
 				case *image.YCbCr:
 					switch src.SubsampleRatio {
 					default:
@@ -94,6 +152,61 @@ func (z nnInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr
 				default:
 					z.scale_RGBA_Image_Src(dst, dr, adr, src, sr, &o)
 				}
+			case *image.Gray:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.scale_Gray_CMYK_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray:
+					z.scale_Gray_Gray_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray16:
+					z.scale_Gray_Gray16_Src(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_Gray_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.scale_Gray16_CMYK_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray:
+					z.scale_Gray16_Gray_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray16:
+					z.scale_Gray16_Gray16_Src(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_Gray16_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_NRGBA_NRGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_NRGBA_RGBA_Src(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_NRGBA_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_RGBA64_NRGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_RGBA64_RGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA64_RGBA64_Src(dst, dr, adr, src, sr, &o)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						z.scale_RGBA64_Image_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio444:
+						z.scale_RGBA64_YCbCr444_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio422:
+						z.scale_RGBA64_YCbCr422_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio420:
+						z.scale_RGBA64_YCbCr420_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio440:
+						z.scale_RGBA64_YCbCr440_Src(dst, dr, adr, src, sr, &o)
+					}
+				default:
+					z.scale_RGBA64_Image_Src(dst, dr, adr, src, sr, &o)
+				}
 			default:
 				switch src := src.(type) {
 				default:
@@ -105,12 +218,33 @@ func (z nnInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr
 }
 
 func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if compositeDst(opts) {
+		drawComposited(dst, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+			z.Transform(tmp, s2d, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+			z.Transform(tmp, s2d, src, sr, op, o)
+		})
+		return
+	}
+	if isFloat(dst) || isFloat(src) {
+		transformFloat(dst, s2d, src, sr, op, opts, nnSample)
+		return
+	}
+	if opts != nil && opts.EdgeOp != EdgeNone {
+		transformEdges(z, dst, s2d, src, sr, op, opts)
+		return
+	}
+
 	// Try to simplify a Transform to a Copy.
 	if s2d[0] == 1 && s2d[1] == 0 && s2d[3] == 0 && s2d[4] == 1 {
 		dx := int(s2d[2])
 		dy := int(s2d[5])
 		if float64(dx) == s2d[2] && float64(dy) == s2d[5] {
-			Copy(dst, image.Point{X: sr.Min.X + dx, Y: sr.Min.X + dy}, src, sr, op, opts)
+			Copy(dst, image.Point{X: sr.Min.X + dx, Y: sr.Min.Y + dy}, src, sr, op, opts)
 			return
 		}
 	}
@@ -119,6 +253,7 @@ func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr i
 	if opts != nil {
 		o = *opts
 	}
+	src = applyColorMatrix(src, &o)
 
 	dr := transformRect(&s2d, &sr)
 	// adr is the affected destination pixels.
@@ -131,7 +266,10 @@ func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr i
 		op = Src
 	}
 
-	d2s := invert(&s2d)
+	d2s, ok := s2d.Invert()
+	if !ok {
+		return
+	}
 	// bias is a translation of the mapping from dst coordinates to src
 	// coordinates such that the latter temporarily have non-negative X
 	// and Y coordinates. This allows us to write int(f) instead of
@@ -170,9 +308,41 @@ func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr i
 					z.transform_RGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
 				case *image.RGBA:
 					z.transform_RGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA_RGBA64_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
 				default:
 					z.transform_RGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
 				}
+			case *image.Gray:
+				switch src := src.(type) {
+				default:
+					z.transform_Gray_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				default:
+					z.transform_Gray16_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_NRGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_NRGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_NRGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_RGBA64_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_RGBA64_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA64_RGBA64_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_RGBA64_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
 			default:
 				switch src := src.(type) {
 				default:
@@ -183,12 +353,18 @@ func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr i
 			switch dst := dst.(type) {
 			case *image.RGBA:
 				switch src := src.(type) {
+				case *image.CMYK:
+					z.transform_RGBA_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
 				case *image.Gray:
 					z.transform_RGBA_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray16:
+					z.transform_RGBA_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
 				case *image.NRGBA:
 					z.transform_RGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
 				case *image.RGBA:
 					z.transform_RGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA_RGBA64_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
 				case *image.YCbCr:
 					switch src.SubsampleRatio {
 					default:
@@ -205,6 +381,61 @@ func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr i
 				default:
 					z.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
 				}
+			case *image.Gray:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.transform_Gray_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray:
+					z.transform_Gray_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray16:
+					z.transform_Gray_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_Gray_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.transform_Gray16_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray:
+					z.transform_Gray16_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray16:
+					z.transform_Gray16_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_Gray16_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_NRGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_NRGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_NRGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_RGBA64_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_RGBA64_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA64_RGBA64_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						z.transform_RGBA64_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio444:
+						z.transform_RGBA64_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio422:
+						z.transform_RGBA64_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio420:
+						z.transform_RGBA64_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio440:
+						z.transform_RGBA64_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					}
+				default:
+					z.transform_RGBA64_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
 			default:
 				switch src := src.(type) {
 				default:
@@ -215,16 +446,37 @@ func (z nnInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr i
 	}
 }
 
+func (nnInterpolator) scale_RGBA_CMYK_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.CMYK, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+			pr := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+			pg := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+			pb := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
 func (nnInterpolator) scale_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 			pr := uint32(src.Pix[pi]) * 0x101
 			out := uint8(pr >> 8)
@@ -236,16 +488,33 @@ func (nnInterpolator) scale_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectang
 	}
 }
 
+func (nnInterpolator) scale_RGBA_Gray16_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.Gray16, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*2
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
 func (nnInterpolator) scale_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pa := uint32(src.Pix[pi+3]) * 0x101
 			pr := uint32(src.Pix[pi+0]) * pa / 0xff
@@ -261,15 +530,13 @@ func (nnInterpolator) scale_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Recta
 }
 
 func (nnInterpolator) scale_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pa := uint32(src.Pix[pi+3]) * 0x101
 			pr := uint32(src.Pix[pi+0]) * pa / 0xff
@@ -284,15 +551,13 @@ func (nnInterpolator) scale_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectan
 }
 
 func (nnInterpolator) scale_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pr := uint32(src.Pix[pi+0]) * 0x101
 			pg := uint32(src.Pix[pi+1]) * 0x101
@@ -308,15 +573,13 @@ func (nnInterpolator) scale_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectan
 }
 
 func (nnInterpolator) scale_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pr := uint32(src.Pix[pi+0]) * 0x101
 			pg := uint32(src.Pix[pi+1]) * 0x101
@@ -330,16 +593,57 @@ func (nnInterpolator) scale_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectang
 	}
 }
 
+func (nnInterpolator) scale_RGBA_RGBA64_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA_RGBA64_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
 func (nnInterpolator) scale_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 			pj := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 
@@ -374,15 +678,13 @@ func (nnInterpolator) scale_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rec
 }
 
 func (nnInterpolator) scale_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 			pj := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx))/2 - src.Rect.Min.X/2)
 
@@ -417,15 +719,13 @@ func (nnInterpolator) scale_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rec
 }
 
 func (nnInterpolator) scale_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 			pj := ((sr.Min.Y+int(sy))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx))/2 - src.Rect.Min.X/2)
 
@@ -460,15 +760,13 @@ func (nnInterpolator) scale_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rec
 }
 
 func (nnInterpolator) scale_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 			pj := ((sr.Min.Y+int(sy))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 
@@ -503,15 +801,13 @@ func (nnInterpolator) scale_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rec
 }
 
 func (nnInterpolator) scale_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
 			pa1 := (0xffff - pa) * 0x101
 			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
@@ -523,15 +819,13 @@ func (nnInterpolator) scale_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Recta
 }
 
 func (nnInterpolator) scale_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (2*uint64(dx) + 1) * sw / dw2
+			sx := (2*uint64(dx)*xn + xo) / xd
 			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
 			dst.Pix[d+0] = uint8(pr >> 8)
 			dst.Pix[d+1] = uint8(pg >> 8)
@@ -541,223 +835,539 @@ func (nnInterpolator) scale_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectan
 	}
 }
 
-func (nnInterpolator) scale_Image_Image_Over(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
+func (nnInterpolator) scale_Gray_CMYK_Src(dst *image.Gray, dr, adr image.Rectangle, src *image.CMYK, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			sx := (2*uint64(dx) + 1) * sw / dw2
-			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx), smp.Y+sr.Min.Y+int(sy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
-			}
-			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
-			if dstMask != nil {
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
-			}
-			pa1 := 0xffff - pa
-			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+			pr := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+			pg := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+			pb := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
 		}
 	}
 }
 
-func (nnInterpolator) scale_Image_Image_Src(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	dw2 := uint64(dr.Dx()) * 2
-	dh2 := uint64(dr.Dy()) * 2
-	sw := uint64(sr.Dx())
-	sh := uint64(sr.Dy())
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
+func (nnInterpolator) scale_Gray_Gray_Src(dst *image.Gray, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (2*uint64(dy) + 1) * sh / dh2
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			sx := (2*uint64(dx) + 1) * sw / dw2
-			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx), smp.Y+sr.Min.Y+int(sy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
-			}
-			if dstMask != nil {
-				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
-				pa1 := 0xffff - ma
-				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
-			} else {
-				dstColorRGBA64.R = uint16(pr)
-				dstColorRGBA64.G = uint16(pg)
-				dstColorRGBA64.B = uint16(pb)
-				dstColorRGBA64.A = uint16(pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
-			}
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx) - src.Rect.Min.X)
+			pr := uint32(src.Pix[pi]) * 0x101
+			dst.Pix[d] = uint8(pr >> 8)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_Gray_Gray16_Src(dst *image.Gray, dr, adr image.Rectangle, src *image.Gray16, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
-			pr := uint32(src.Pix[pi]) * 0x101
-			out := uint8(pr >> 8)
-			dst.Pix[d+0] = out
-			dst.Pix[d+1] = out
-			dst.Pix[d+2] = out
-			dst.Pix[d+3] = 0xff
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*2
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			dst.Pix[d] = uint8(pr >> 8)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_Gray_Image_Over(dst *image.Gray, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			pa := uint32(src.Pix[pi+3]) * 0x101
-			pr := uint32(src.Pix[pi+0]) * pa / 0xff
-			pg := uint32(src.Pix[pi+1]) * pa / 0xff
-			pb := uint32(src.Pix[pi+2]) * pa / 0xff
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*pa1/0xffff + py) >> 8)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_Gray_Image_Src(dst *image.Gray, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, _ := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (nnInterpolator) scale_Gray16_CMYK_Src(dst *image.Gray16, dr, adr image.Rectangle, src *image.CMYK, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+			pr := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+			pg := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+			pb := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (nnInterpolator) scale_Gray16_Gray_Src(dst *image.Gray16, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx) - src.Rect.Min.X)
+			pr := uint32(src.Pix[pi]) * 0x101
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
+
+func (nnInterpolator) scale_Gray16_Gray16_Src(dst *image.Gray16, dr, adr image.Rectangle, src *image.Gray16, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*2
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
+
+func (nnInterpolator) scale_Gray16_Image_Over(dst *image.Gray16, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			out := qy*pa1/0xffff + py
+			dst.Pix[d+0] = uint8(out >> 8)
+			dst.Pix[d+1] = uint8(out)
+		}
+	}
+}
+
+func (nnInterpolator) scale_Gray16_Image_Src(dst *image.Gray16, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, _ := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (nnInterpolator) scale_NRGBA_NRGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pa := uint32(src.Pix[pi+3]) * 0x101
 			pr := uint32(src.Pix[pi+0]) * pa / 0xff
 			pg := uint32(src.Pix[pi+1]) * pa / 0xff
 			pb := uint32(src.Pix[pi+2]) * pa / 0xff
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_NRGBA_NRGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+		}
+	}
+}
+
+func (nnInterpolator) scale_NRGBA_RGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pr := uint32(src.Pix[pi+0]) * 0x101
 			pg := uint32(src.Pix[pi+1]) * 0x101
 			pb := uint32(src.Pix[pi+2]) * 0x101
 			pa := uint32(src.Pix[pi+3]) * 0x101
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_NRGBA_RGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
 			pr := uint32(src.Pix[pi+0]) * 0x101
 			pg := uint32(src.Pix[pi+1]) * 0x101
 			pb := uint32(src.Pix[pi+2]) * 0x101
 			pa := uint32(src.Pix[pi+3]) * 0x101
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_NRGBA_Image_Over(dst *image.NRGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		sy := (2*uint64(dy)*yn + yo) / yd
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			pj := (sy0-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+		}
+	}
+}
+
+func (nnInterpolator) scale_NRGBA_Image_Src(dst *image.NRGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_NRGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_NRGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_RGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_RGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_RGBA64_Over(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_RGBA64_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx)-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (nnInterpolator) scale_RGBA64_YCbCr444_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
+			pj := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 
 			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
 			pyy1 := int(src.Y[pi]) * 0x10101
@@ -781,27 +1391,29 @@ func (nnInterpolator) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image
 			} else if pb > 0xffff {
 				pb = 0xffff
 			}
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_RGBA64_YCbCr422_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			pj := (sy0-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
+			pj := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx))/2 - src.Rect.Min.X/2)
 
 			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
 			pyy1 := int(src.Y[pi]) * 0x10101
@@ -825,27 +1437,29 @@ func (nnInterpolator) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image
 			} else if pb > 0xffff {
 				pb = 0xffff
 			}
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_RGBA64_YCbCr420_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			pj := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
+			pj := ((sr.Min.Y+int(sy))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx))/2 - src.Rect.Min.X/2)
 
 			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
 			pyy1 := int(src.Y[pi]) * 0x10101
@@ -869,27 +1483,29 @@ func (nnInterpolator) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image
 			} else if pb > 0xffff {
 				pb = 0xffff
 			}
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_RGBA64_YCbCr440_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			pj := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pi := (sr.Min.Y+int(sy)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
+			pj := ((sr.Min.Y+int(sy))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx) - src.Rect.Min.X)
 
 			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
 			pyy1 := int(src.Y[pi]) * 0x10101
@@ -913,72 +1529,82 @@ func (nnInterpolator) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image
 			} else if pb > 0xffff {
 				pb = 0xffff
 			}
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_RGBA64_Image_Over(dst *image.RGBA64, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (nnInterpolator) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_RGBA64_Image_Src(dst *image.RGBA64, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+		sy := (2*uint64(dy)*yn + yo) / yd
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (nnInterpolator) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_Image_Image_Over(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	srcMask, smp := opts.SrcMask, opts.SrcMaskP
 	dstMask, dmp := opts.DstMask, opts.DstMaskP
 	dstColorRGBA64 := &color.RGBA64{}
 	dstColor := color.Color(dstColorRGBA64)
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		sy := (2*uint64(dy)*yn + yo) / yd
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
 			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx), smp.Y+sr.Min.Y+int(sy)).RGBA()
 				pr = pr * ma / 0xffff
 				pg = pg * ma / 0xffff
 				pb = pb * ma / 0xffff
@@ -1002,23 +1628,20 @@ func (nnInterpolator) transform_Image_Image_Over(dst Image, dr, adr image.Rectan
 	}
 }
 
-func (nnInterpolator) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) scale_Image_Image_Src(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
 	srcMask, smp := opts.SrcMask, opts.SrcMaskP
 	dstMask, dmp := opts.DstMask, opts.DstMaskP
 	dstColorRGBA64 := &color.RGBA64{}
 	dstColor := color.Color(dstColorRGBA64)
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		sy := (2*uint64(dy)*yn + yo) / yd
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
-			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
-			if !(image.Point{sx0, sy0}).In(sr) {
-				continue
-			}
-			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			sx := (2*uint64(dx)*xn + xo) / xd
+			pr, pg, pb, pa := src.At(sr.Min.X+int(sx), sr.Min.Y+int(sy)).RGBA()
 			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx), smp.Y+sr.Min.Y+int(sy)).RGBA()
 				pr = pr * ma / 0xffff
 				pg = pg * ma / 0xffff
 				pb = pb * ma / 0xffff
@@ -1048,366 +1671,190 @@ func (nnInterpolator) transform_Image_Image_Src(dst Image, dr, adr image.Rectang
 	}
 }
 
-func (z ablInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
-	// Try to simplify a Scale to a Copy when DstMask is not specified.
-	// If DstMask is not nil, Copy will call Scale back with same dr and sr, and cause stack overflow.
-	if dr.Size() == sr.Size() && (opts == nil || opts.DstMask == nil) {
-		Copy(dst, dr.Min, src, sr, op, opts)
-		return
-	}
-
-	var o Options
-	if opts != nil {
-		o = *opts
-	}
+func (nnInterpolator) transform_RGBA_CMYK_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
 
-	// adr is the affected destination pixels.
-	adr := dst.Bounds().Intersect(dr)
-	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
-	if adr.Empty() || sr.Empty() {
-		return
-	}
-	// Make adr relative to dr.Min.
-	adr = adr.Sub(dr.Min)
-	if op == Over && o.SrcMask == nil && opaque(src) {
-		op = Src
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+			pr := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+			pg := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+			pb := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
 	}
+}
 
-	// sr is the source pixels. If it extends beyond the src bounds,
-	// we cannot use the type-specific fast paths, as they access
-	// the Pix fields directly without bounds checking.
-	//
-	// Similarly, the fast paths assume that the masks are nil.
-	if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
-		switch op {
-		case Over:
-			z.scale_Image_Image_Over(dst, dr, adr, src, sr, &o)
-		case Src:
-			z.scale_Image_Image_Src(dst, dr, adr, src, sr, &o)
-		}
-	} else if _, ok := src.(*image.Uniform); ok {
-		Draw(dst, dr, src, src.Bounds().Min, op)
-	} else {
-		switch op {
-		case Over:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				switch src := src.(type) {
-				case *image.NRGBA:
-					z.scale_RGBA_NRGBA_Over(dst, dr, adr, src, sr, &o)
-				case *image.RGBA:
-					z.scale_RGBA_RGBA_Over(dst, dr, adr, src, sr, &o)
-				default:
-					z.scale_RGBA_Image_Over(dst, dr, adr, src, sr, &o)
-				}
-			default:
-				switch src := src.(type) {
-				default:
-					z.scale_Image_Image_Over(dst, dr, adr, src, sr, &o)
-				}
-			}
-		case Src:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				switch src := src.(type) {
-				case *image.Gray:
-					z.scale_RGBA_Gray_Src(dst, dr, adr, src, sr, &o)
-				case *image.NRGBA:
-					z.scale_RGBA_NRGBA_Src(dst, dr, adr, src, sr, &o)
-				case *image.RGBA:
-					z.scale_RGBA_RGBA_Src(dst, dr, adr, src, sr, &o)
-				case *image.YCbCr:
-					switch src.SubsampleRatio {
-					default:
-						z.scale_RGBA_Image_Src(dst, dr, adr, src, sr, &o)
-					case image.YCbCrSubsampleRatio444:
-						z.scale_RGBA_YCbCr444_Src(dst, dr, adr, src, sr, &o)
-					case image.YCbCrSubsampleRatio422:
-						z.scale_RGBA_YCbCr422_Src(dst, dr, adr, src, sr, &o)
-					case image.YCbCrSubsampleRatio420:
-						z.scale_RGBA_YCbCr420_Src(dst, dr, adr, src, sr, &o)
-					case image.YCbCrSubsampleRatio440:
-						z.scale_RGBA_YCbCr440_Src(dst, dr, adr, src, sr, &o)
-					}
-				default:
-					z.scale_RGBA_Image_Src(dst, dr, adr, src, sr, &o)
-				}
-			default:
-				switch src := src.(type) {
-				default:
-					z.scale_Image_Image_Src(dst, dr, adr, src, sr, &o)
-				}
+func (nnInterpolator) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			pr := uint32(src.Pix[pi]) * 0x101
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
 		}
 	}
 }
 
-func (z ablInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
-	// Try to simplify a Transform to a Copy.
-	if s2d[0] == 1 && s2d[1] == 0 && s2d[3] == 0 && s2d[4] == 1 {
-		dx := int(s2d[2])
-		dy := int(s2d[5])
-		if float64(dx) == s2d[2] && float64(dy) == s2d[5] {
-			Copy(dst, image.Point{X: sr.Min.X + dx, Y: sr.Min.X + dy}, src, sr, op, opts)
-			return
+func (nnInterpolator) transform_RGBA_Gray16_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
 		}
 	}
+}
 
-	var o Options
-	if opts != nil {
-		o = *opts
+func (nnInterpolator) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
 	}
+}
 
-	dr := transformRect(&s2d, &sr)
-	// adr is the affected destination pixels.
-	adr := dst.Bounds().Intersect(dr)
-	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
-	if adr.Empty() || sr.Empty() {
-		return
-	}
-	if op == Over && o.SrcMask == nil && opaque(src) {
-		op = Src
+func (nnInterpolator) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
 	}
+}
 
-	d2s := invert(&s2d)
-	// bias is a translation of the mapping from dst coordinates to src
-	// coordinates such that the latter temporarily have non-negative X
-	// and Y coordinates. This allows us to write int(f) instead of
-	// int(math.Floor(f)), since "round to zero" and "round down" are
-	// equivalent when f >= 0, but the former is much cheaper. The X--
-	// and Y-- are because the TransformLeaf methods have a "sx -= 0.5"
-	// adjustment.
-	bias := transformRect(&d2s, &adr).Min
-	bias.X--
-	bias.Y--
-	d2s[2] -= float64(bias.X)
-	d2s[5] -= float64(bias.Y)
-	// Make adr relative to dr.Min.
-	adr = adr.Sub(dr.Min)
-	// sr is the source pixels. If it extends beyond the src bounds,
-	// we cannot use the type-specific fast paths, as they access
-	// the Pix fields directly without bounds checking.
-	//
-	// Similarly, the fast paths assume that the masks are nil.
-	if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
-		switch op {
-		case Over:
-			z.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
-		case Src:
-			z.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-		}
-	} else if u, ok := src.(*image.Uniform); ok {
-		transform_Uniform(dst, dr, adr, &d2s, u, sr, bias, op)
-	} else {
-		switch op {
-		case Over:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				switch src := src.(type) {
-				case *image.NRGBA:
-					z.transform_RGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
-				case *image.RGBA:
-					z.transform_RGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
-				default:
-					z.transform_RGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
-				}
-			default:
-				switch src := src.(type) {
-				default:
-					z.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
-				}
-			}
-		case Src:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				switch src := src.(type) {
-				case *image.Gray:
-					z.transform_RGBA_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-				case *image.NRGBA:
-					z.transform_RGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-				case *image.RGBA:
-					z.transform_RGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-				case *image.YCbCr:
-					switch src.SubsampleRatio {
-					default:
-						z.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-					case image.YCbCrSubsampleRatio444:
-						z.transform_RGBA_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-					case image.YCbCrSubsampleRatio422:
-						z.transform_RGBA_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-					case image.YCbCrSubsampleRatio420:
-						z.transform_RGBA_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-					case image.YCbCrSubsampleRatio440:
-						z.transform_RGBA_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-					}
-				default:
-					z.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-				}
-			default:
-				switch src := src.(type) {
-				default:
-					z.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
-				}
+func (nnInterpolator) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s00ru := uint32(src.Pix[s00i]) * 0x101
-			s00r := float64(s00ru)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s10ru := uint32(src.Pix[s10i]) * 0x101
-			s10r := float64(s10ru)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s01ru := uint32(src.Pix[s01i]) * 0x101
-			s01r := float64(s01ru)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s11ru := uint32(src.Pix[s11i]) * 0x101
-			s11r := float64(s11ru)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11r = yFrac1*s10r + yFrac0*s11r
-			pr := uint32(s11r)
-			out := uint8(pr >> 8)
-			dst.Pix[d+0] = out
-			dst.Pix[d+1] = out
-			dst.Pix[d+2] = out
-			dst.Pix[d+3] = 0xff
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_RGBA64_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
-			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
-			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
-			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
-			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
-			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
-			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
-			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
-			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
 			pa1 := (0xffff - pa) * 0x101
 			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
 			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
@@ -1417,97 +1864,22 @@ func (ablInterpolator) scale_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rect
 	}
 }
 
-func (ablInterpolator) scale_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_RGBA64_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
-			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
-			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
-			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
-			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
-			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
-			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
-			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
-			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
 			dst.Pix[d+0] = uint8(pr >> 8)
 			dst.Pix[d+1] = uint8(pg >> 8)
 			dst.Pix[d+2] = uint8(pb >> 8)
@@ -1516,372 +1888,130 @@ func (ablInterpolator) scale_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Recta
 	}
 }
 
-func (ablInterpolator) scale_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := (sy0-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
 
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s00ru := uint32(src.Pix[s00i+0]) * 0x101
-			s00gu := uint32(src.Pix[s00i+1]) * 0x101
-			s00bu := uint32(src.Pix[s00i+2]) * 0x101
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s10ru := uint32(src.Pix[s10i+0]) * 0x101
-			s10gu := uint32(src.Pix[s10i+1]) * 0x101
-			s10bu := uint32(src.Pix[s10i+2]) * 0x101
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s01ru := uint32(src.Pix[s01i+0]) * 0x101
-			s01gu := uint32(src.Pix[s01i+1]) * 0x101
-			s01bu := uint32(src.Pix[s01i+2]) * 0x101
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s11ru := uint32(src.Pix[s11i+0]) * 0x101
-			s11gu := uint32(src.Pix[s11i+1]) * 0x101
-			s11bu := uint32(src.Pix[s11i+2]) * 0x101
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
+			}
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
+			}
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
+			}
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := (sy0-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
 
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s00ru := uint32(src.Pix[s00i+0]) * 0x101
-			s00gu := uint32(src.Pix[s00i+1]) * 0x101
-			s00bu := uint32(src.Pix[s00i+2]) * 0x101
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s10ru := uint32(src.Pix[s10i+0]) * 0x101
-			s10gu := uint32(src.Pix[s10i+1]) * 0x101
-			s10bu := uint32(src.Pix[s10i+2]) * 0x101
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
-			s01ru := uint32(src.Pix[s01i+0]) * 0x101
-			s01gu := uint32(src.Pix[s01i+1]) * 0x101
-			s01bu := uint32(src.Pix[s01i+2]) * 0x101
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
-			s11ru := uint32(src.Pix[s11i+0]) * 0x101
-			s11gu := uint32(src.Pix[s11i+1]) * 0x101
-			s11bu := uint32(src.Pix[s11i+2]) * 0x101
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
+			}
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
+			}
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
+			}
 			dst.Pix[d+0] = uint8(pr >> 8)
 			dst.Pix[d+1] = uint8(pg >> 8)
 			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+			dst.Pix[d+3] = 0xff
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
-			}
-
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s00j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
-			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
-			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
-			}
-
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s10j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
-			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
-			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
-			}
-
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s01j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
-			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s11j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
 
 			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
 			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
 			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
 			}
-
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
 			dst.Pix[d+0] = uint8(pr >> 8)
 			dst.Pix[d+1] = uint8(pg >> 8)
 			dst.Pix[d+2] = uint8(pb >> 8)
@@ -1890,3067 +2020,16332 @@ func (ablInterpolator) scale_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Re
 	}
 }
 
-func (ablInterpolator) scale_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
-			}
-
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s00j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
-			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
-			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s10j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
 
 			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
 			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
 			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
 			}
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s01j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
-			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+func (nnInterpolator) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s11j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
-			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
-			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+func (nnInterpolator) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
 			dst.Pix[d+0] = uint8(pr >> 8)
 			dst.Pix[d+1] = uint8(pg >> 8)
 			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			dst.Pix[d+3] = uint8(pa >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_Gray_CMYK_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+			pr := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+			pg := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+			pb := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
 		}
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+	}
+}
 
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+func (nnInterpolator) transform_Gray_Gray_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			pr := uint32(src.Pix[pi]) * 0x101
+			dst.Pix[d] = uint8(pr >> 8)
+		}
+	}
+}
 
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s00j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
-			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
-			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
+func (nnInterpolator) transform_Gray_Gray16_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			dst.Pix[d] = uint8(pr >> 8)
+		}
+	}
+}
 
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s10j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
-			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
-			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
+func (nnInterpolator) transform_Gray_Image_Over(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*pa1/0xffff + py) >> 8)
+		}
+	}
+}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s01j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
+func (nnInterpolator) transform_Gray_Image_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+			pr, pg, pb, _ := src.At(sx0, sy0).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (nnInterpolator) transform_Gray16_CMYK_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s11j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+			pr := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+			pg := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+			pb := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
-			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
-			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+func (nnInterpolator) transform_Gray16_Gray_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			pr := uint32(src.Pix[pi]) * 0x101
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
 
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
+func (nnInterpolator) transform_Gray16_Gray16_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
 			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			dst.Pix[d+1] = uint8(pr)
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
+func (nnInterpolator) transform_Gray16_Image_Over(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			out := qy*pa1/0xffff + py
+			dst.Pix[d+0] = uint8(out >> 8)
+			dst.Pix[d+1] = uint8(out)
+		}
+	}
+}
 
+func (nnInterpolator) transform_Gray16_Image_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pr, pg, pb, _ := src.At(sx0, sy0).RGBA()
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
 		}
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+	}
+}
 
+func (nnInterpolator) transform_NRGBA_NRGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
 
-			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s00j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
-			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
-			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
-			}
-
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s10j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
-			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
+func (nnInterpolator) transform_NRGBA_NRGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
+		}
+	}
+}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-			s01j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
+func (nnInterpolator) transform_NRGBA_RGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
+		}
+	}
+}
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-			s11j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
-			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
+func (nnInterpolator) transform_NRGBA_RGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_NRGBA_Image_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-
-			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) scale_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-
+func (nnInterpolator) transform_NRGBA_Image_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
-		}
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-
-			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) scale_Image_Image_Over(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
-
+func (nnInterpolator) transform_RGBA64_NRGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
+	}
+}
 
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+func (nnInterpolator) transform_RGBA64_NRGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			pr := uint32(src.Pix[pi+0]) * pa / 0xff
+			pg := uint32(src.Pix[pi+1]) * pa / 0xff
+			pb := uint32(src.Pix[pi+2]) * pa / 0xff
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
 
-			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy0)).RGBA()
-				s00ru = s00ru * ma / 0xffff
-				s00gu = s00gu * ma / 0xffff
-				s00bu = s00bu * ma / 0xffff
-				s00au = s00au * ma / 0xffff
-			}
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy0)).RGBA()
-				s10ru = s10ru * ma / 0xffff
-				s10gu = s10gu * ma / 0xffff
-				s10bu = s10bu * ma / 0xffff
-				s10au = s10au * ma / 0xffff
-			}
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy1)).RGBA()
-				s01ru = s01ru * ma / 0xffff
-				s01gu = s01gu * ma / 0xffff
-				s01bu = s01bu * ma / 0xffff
-				s01au = s01au * ma / 0xffff
-			}
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy1)).RGBA()
-				s11ru = s11ru * ma / 0xffff
-				s11gu = s11gu * ma / 0xffff
-				s11bu = s11bu * ma / 0xffff
-				s11au = s11au * ma / 0xffff
-			}
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
-			if dstMask != nil {
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
+func (nnInterpolator) transform_RGBA64_RGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
 			pa1 := 0xffff - pa
-			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (ablInterpolator) scale_Image_Image_Src(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
-	sw := int32(sr.Dx())
-	sh := int32(sr.Dy())
-	yscale := float64(sh) / float64(dr.Dy())
-	xscale := float64(sw) / float64(dr.Dx())
-	swMinus1, shMinus1 := sw-1, sh-1
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
+func (nnInterpolator) transform_RGBA64_RGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			pr := uint32(src.Pix[pi+0]) * 0x101
+			pg := uint32(src.Pix[pi+1]) * 0x101
+			pb := uint32(src.Pix[pi+2]) * 0x101
+			pa := uint32(src.Pix[pi+3]) * 0x101
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
 
+func (nnInterpolator) transform_RGBA64_RGBA64_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		sy := (float64(dy)+0.5)*yscale - 0.5
-		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
-		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
-		// sx, below.
-		sy0 := int32(sy)
-		yFrac0 := sy - float64(sy0)
-		yFrac1 := 1 - yFrac0
-		sy1 := sy0 + 1
-		if sy < 0 {
-			sy0, sy1 = 0, 0
-			yFrac0, yFrac1 = 0, 1
-		} else if sy1 > shMinus1 {
-			sy0, sy1 = shMinus1, shMinus1
-			yFrac0, yFrac1 = 1, 0
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
+			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
+	}
+}
 
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			sx := (float64(dx)+0.5)*xscale - 0.5
-			sx0 := int32(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx1 := sx0 + 1
-			if sx < 0 {
-				sx0, sx1 = 0, 0
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 > swMinus1 {
-				sx0, sx1 = swMinus1, swMinus1
-				xFrac0, xFrac1 = 1, 0
+func (nnInterpolator) transform_RGBA64_RGBA64_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			pr := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+			pg := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+			pb := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+			pa := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
 
-			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy0)).RGBA()
-				s00ru = s00ru * ma / 0xffff
-				s00gu = s00gu * ma / 0xffff
-				s00bu = s00bu * ma / 0xffff
-				s00au = s00au * ma / 0xffff
-			}
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy0)).RGBA()
-				s10ru = s10ru * ma / 0xffff
-				s10gu = s10gu * ma / 0xffff
-				s10bu = s10bu * ma / 0xffff
-				s10au = s10au * ma / 0xffff
+func (nnInterpolator) transform_RGBA64_YCbCr444_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy1)).RGBA()
-				s01ru = s01ru * ma / 0xffff
-				s01gu = s01gu * ma / 0xffff
-				s01bu = s01bu * ma / 0xffff
-				s01au = s01au * ma / 0xffff
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := (sy0-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
 			}
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy1)).RGBA()
-				s11ru = s11ru * ma / 0xffff
-				s11gu = s11gu * ma / 0xffff
-				s11bu = s11bu * ma / 0xffff
-				s11au = s11au * ma / 0xffff
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
 			}
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			if dstMask != nil {
-				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
-				pa1 := 0xffff - ma
-				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
-			} else {
-				dstColorRGBA64.R = uint16(pr)
-				dstColorRGBA64.G = uint16(pg)
-				dstColorRGBA64.B = uint16(pb)
-				dstColorRGBA64.A = uint16(pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
 			}
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) transform_RGBA64_YCbCr422_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
 			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
 				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := (sy0-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
 			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
 			}
-
-			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
-			s00ru := uint32(src.Pix[s00i]) * 0x101
-			s00r := float64(s00ru)
-			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
-			s10ru := uint32(src.Pix[s10i]) * 0x101
-			s10r := float64(s10ru)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
-			s01ru := uint32(src.Pix[s01i]) * 0x101
-			s01r := float64(s01ru)
-			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
-			s11ru := uint32(src.Pix[s11i]) * 0x101
-			s11r := float64(s11ru)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11r = yFrac1*s10r + yFrac0*s11r
-			pr := uint32(s11r)
-			out := uint8(pr >> 8)
-			dst.Pix[d+0] = out
-			dst.Pix[d+1] = out
-			dst.Pix[d+2] = out
-			dst.Pix[d+3] = 0xff
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
+			}
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) transform_RGBA64_YCbCr420_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
 			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
 				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
 			}
-
-			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
-			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
-			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
-			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
-			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
-			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
-			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
-			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
-			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
+			}
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
+			}
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) transform_RGBA64_YCbCr440_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
 			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
 				continue
 			}
+			pi := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			pj := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			pyy1 := int(src.Y[pi]) * 0x10101
+			pcb1 := int(src.Cb[pj]) - 128
+			pcr1 := int(src.Cr[pj]) - 128
+			pr := (pyy1 + 91881*pcr1) >> 8
+			pg := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+			pb := (pyy1 + 116130*pcb1) >> 8
+			if pr < 0 {
+				pr = 0
+			} else if pr > 0xffff {
+				pr = 0xffff
 			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+			if pg < 0 {
+				pg = 0
+			} else if pg > 0xffff {
+				pg = 0xffff
 			}
-
-			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
-			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
-			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
-			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
-			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
-			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
-			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
-			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
-			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+			if pb < 0 {
+				pb = 0
+			} else if pb > 0xffff {
+				pb = 0xffff
+			}
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) transform_RGBA64_Image_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
 			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
 				continue
 			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+func (nnInterpolator) transform_RGBA64_Image_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
+				continue
 			}
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
 
-			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s00ru := uint32(src.Pix[s00i+0]) * 0x101
-			s00gu := uint32(src.Pix[s00i+1]) * 0x101
-			s00bu := uint32(src.Pix[s00i+2]) * 0x101
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s10ru := uint32(src.Pix[s10i+0]) * 0x101
-			s10gu := uint32(src.Pix[s10i+1]) * 0x101
-			s10bu := uint32(src.Pix[s10i+2]) * 0x101
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s01ru := uint32(src.Pix[s01i+0]) * 0x101
-			s01gu := uint32(src.Pix[s01i+1]) * 0x101
-			s01bu := uint32(src.Pix[s01i+2]) * 0x101
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s11ru := uint32(src.Pix[s11i+0]) * 0x101
-			s11gu := uint32(src.Pix[s11i+1]) * 0x101
-			s11bu := uint32(src.Pix[s11i+2]) * 0x101
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
-		}
-	}
-}
-
-func (ablInterpolator) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
 			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
 				continue
 			}
-
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
 			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
+			if dstMask != nil {
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
 			}
-
-			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s00ru := uint32(src.Pix[s00i+0]) * 0x101
-			s00gu := uint32(src.Pix[s00i+1]) * 0x101
-			s00bu := uint32(src.Pix[s00i+2]) * 0x101
-			s00au := uint32(src.Pix[s00i+3]) * 0x101
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s10ru := uint32(src.Pix[s10i+0]) * 0x101
-			s10gu := uint32(src.Pix[s10i+1]) * 0x101
-			s10bu := uint32(src.Pix[s10i+2]) * 0x101
-			s10au := uint32(src.Pix[s10i+3]) * 0x101
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
-			s01ru := uint32(src.Pix[s01i+0]) * 0x101
-			s01gu := uint32(src.Pix[s01i+1]) * 0x101
-			s01bu := uint32(src.Pix[s01i+2]) * 0x101
-			s01au := uint32(src.Pix[s01i+3]) * 0x101
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
-			s11ru := uint32(src.Pix[s11i+0]) * 0x101
-			s11gu := uint32(src.Pix[s11i+1]) * 0x101
-			s11bu := uint32(src.Pix[s11i+2]) * 0x101
-			s11au := uint32(src.Pix[s11i+3]) * 0x101
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+			pa1 := 0xffff - pa
+			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (nnInterpolator) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
 			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+			sx0 := int(d2s[0]*dxf+d2s[1]*dyf+d2s[2]) + bias.X
+			sy0 := int(d2s[3]*dxf+d2s[4]*dyf+d2s[5]) + bias.Y
+			if !(image.Point{sx0, sy0}).In(sr) {
 				continue
 			}
-
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
+			pr, pg, pb, pa := src.At(sx0, sy0).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
 			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+			if dstMask != nil {
+				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
+				pa1 := 0xffff - ma
+				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			} else {
+				dstColorRGBA64.R = uint16(pr)
+				dstColorRGBA64.G = uint16(pg)
+				dstColorRGBA64.B = uint16(pb)
+				dstColorRGBA64.A = uint16(pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
 			}
+		}
+	}
+}
 
-			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s00j := (sy0-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
+func (z ablInterpolator) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	// Try to simplify a Scale to a Copy when DstMask is not specified.
+	// If DstMask is not nil, Copy will call Scale back with same dr and sr, and cause stack overflow.
+	if dr.Size() == sr.Size() && (opts == nil || opts.DstMask == nil) {
+		Copy(dst, dr.Min, src, sr, op, opts)
+		return
+	}
+	if compositeDst(opts) {
+		drawComposited(dst, dr, opts, func(tmp Image, o *Options) {
+			z.Scale(tmp, dr, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, dr, opts, func(tmp Image, o *Options) {
+			z.Scale(tmp, dr, src, sr, op, o)
+		})
+		return
+	}
+	if isFloat(dst) || isFloat(src) {
+		scaleFloat(dst, dr, src, sr, op, opts, ablSample)
+		return
+	}
+
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	src = applyColorMatrix(src, &o)
+
+	// adr is the affected destination pixels.
+	adr := dst.Bounds().Intersect(dr)
+	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
+	if adr.Empty() || sr.Empty() {
+		return
+	}
+	// Make adr relative to dr.Min.
+	adr = adr.Sub(dr.Min)
+	if op == Over && o.SrcMask == nil && opaque(src) {
+		op = Src
+	}
+
+	// sr is the source pixels. If it extends beyond the src bounds,
+	// we cannot use the type-specific fast paths, as they access
+	// the Pix fields directly without bounds checking.
+	//
+	// Similarly, the fast paths assume that the masks are nil.
+	if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
+		switch op {
+		case Over:
+			z.scale_Image_Image_Over(dst, dr, adr, src, sr, &o)
+		case Src:
+			z.scale_Image_Image_Src(dst, dr, adr, src, sr, &o)
+		}
+	} else if _, ok := src.(*image.Uniform); ok {
+		Draw(dst, dr, src, src.Bounds().Min, op)
+	} else {
+		switch op {
+		case Over:
+			switch dst := dst.(type) {
+			case *image.RGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_RGBA_NRGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_RGBA_RGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA_RGBA64_Over(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_RGBA_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.Gray:
+				switch src := src.(type) {
+				default:
+					z.scale_Gray_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				default:
+					z.scale_Gray16_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_NRGBA_NRGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_NRGBA_RGBA_Over(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_NRGBA_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_RGBA64_NRGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_RGBA64_RGBA_Over(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA64_RGBA64_Over(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_RGBA64_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			default:
+				switch src := src.(type) {
+				default:
+					z.scale_Image_Image_Over(dst, dr, adr, src, sr, &o)
+				}
+			}
+		case Src:
+			switch dst := dst.(type) {
+			case *image.RGBA:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.scale_RGBA_CMYK_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray:
+					z.scale_RGBA_Gray_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray16:
+					z.scale_RGBA_Gray16_Src(dst, dr, adr, src, sr, &o)
+				case *image.NRGBA:
+					z.scale_RGBA_NRGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_RGBA_RGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA_RGBA64_Src(dst, dr, adr, src, sr, &o)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						z.scale_RGBA_Image_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio444:
+						z.scale_RGBA_YCbCr444_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio422:
+						z.scale_RGBA_YCbCr422_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio420:
+						z.scale_RGBA_YCbCr420_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio440:
+						z.scale_RGBA_YCbCr440_Src(dst, dr, adr, src, sr, &o)
+					}
+				default:
+					z.scale_RGBA_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.Gray:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.scale_Gray_CMYK_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray:
+					z.scale_Gray_Gray_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray16:
+					z.scale_Gray_Gray16_Src(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_Gray_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.scale_Gray16_CMYK_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray:
+					z.scale_Gray16_Gray_Src(dst, dr, adr, src, sr, &o)
+				case *image.Gray16:
+					z.scale_Gray16_Gray16_Src(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_Gray16_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_NRGBA_NRGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_NRGBA_RGBA_Src(dst, dr, adr, src, sr, &o)
+				default:
+					z.scale_NRGBA_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.scale_RGBA64_NRGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA:
+					z.scale_RGBA64_RGBA_Src(dst, dr, adr, src, sr, &o)
+				case *image.RGBA64:
+					z.scale_RGBA64_RGBA64_Src(dst, dr, adr, src, sr, &o)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						z.scale_RGBA64_Image_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio444:
+						z.scale_RGBA64_YCbCr444_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio422:
+						z.scale_RGBA64_YCbCr422_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio420:
+						z.scale_RGBA64_YCbCr420_Src(dst, dr, adr, src, sr, &o)
+					case image.YCbCrSubsampleRatio440:
+						z.scale_RGBA64_YCbCr440_Src(dst, dr, adr, src, sr, &o)
+					}
+				default:
+					z.scale_RGBA64_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			default:
+				switch src := src.(type) {
+				default:
+					z.scale_Image_Image_Src(dst, dr, adr, src, sr, &o)
+				}
+			}
+		}
+	}
+}
+
+func (z ablInterpolator) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if compositeDst(opts) {
+		drawComposited(dst, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+			z.Transform(tmp, s2d, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+			z.Transform(tmp, s2d, src, sr, op, o)
+		})
+		return
+	}
+	if isFloat(dst) || isFloat(src) {
+		transformFloat(dst, s2d, src, sr, op, opts, ablSample)
+		return
+	}
+	if opts != nil && opts.EdgeOp != EdgeNone {
+		transformEdges(z, dst, s2d, src, sr, op, opts)
+		return
+	}
+
+	// Try to simplify a Transform to a Copy.
+	if s2d[0] == 1 && s2d[1] == 0 && s2d[3] == 0 && s2d[4] == 1 {
+		dx := int(s2d[2])
+		dy := int(s2d[5])
+		if float64(dx) == s2d[2] && float64(dy) == s2d[5] {
+			Copy(dst, image.Point{X: sr.Min.X + dx, Y: sr.Min.Y + dy}, src, sr, op, opts)
+			return
+		}
+	}
+
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	src = applyColorMatrix(src, &o)
+
+	dr := transformRect(&s2d, &sr)
+	// adr is the affected destination pixels.
+	adr := dst.Bounds().Intersect(dr)
+	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
+	if adr.Empty() || sr.Empty() {
+		return
+	}
+	if op == Over && o.SrcMask == nil && opaque(src) {
+		op = Src
+	}
+
+	d2s, ok := s2d.Invert()
+	if !ok {
+		return
+	}
+	// bias is a translation of the mapping from dst coordinates to src
+	// coordinates such that the latter temporarily have non-negative X
+	// and Y coordinates. This allows us to write int(f) instead of
+	// int(math.Floor(f)), since "round to zero" and "round down" are
+	// equivalent when f >= 0, but the former is much cheaper. The X--
+	// and Y-- are because the TransformLeaf methods have a "sx -= 0.5"
+	// adjustment.
+	bias := transformRect(&d2s, &adr).Min
+	bias.X--
+	bias.Y--
+	d2s[2] -= float64(bias.X)
+	d2s[5] -= float64(bias.Y)
+	// Make adr relative to dr.Min.
+	adr = adr.Sub(dr.Min)
+	// sr is the source pixels. If it extends beyond the src bounds,
+	// we cannot use the type-specific fast paths, as they access
+	// the Pix fields directly without bounds checking.
+	//
+	// Similarly, the fast paths assume that the masks are nil.
+	if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
+		switch op {
+		case Over:
+			z.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+		case Src:
+			z.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+		}
+	} else if u, ok := src.(*image.Uniform); ok {
+		transform_Uniform(dst, dr, adr, &d2s, u, sr, bias, op)
+	} else {
+		switch op {
+		case Over:
+			switch dst := dst.(type) {
+			case *image.RGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_RGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_RGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA_RGBA64_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_RGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.Gray:
+				switch src := src.(type) {
+				default:
+					z.transform_Gray_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				default:
+					z.transform_Gray16_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_NRGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_NRGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_NRGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_RGBA64_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_RGBA64_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA64_RGBA64_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_RGBA64_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			default:
+				switch src := src.(type) {
+				default:
+					z.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			}
+		case Src:
+			switch dst := dst.(type) {
+			case *image.RGBA:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.transform_RGBA_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray:
+					z.transform_RGBA_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray16:
+					z.transform_RGBA_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.NRGBA:
+					z.transform_RGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_RGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA_RGBA64_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						z.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio444:
+						z.transform_RGBA_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio422:
+						z.transform_RGBA_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio420:
+						z.transform_RGBA_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio440:
+						z.transform_RGBA_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					}
+				default:
+					z.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.Gray:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.transform_Gray_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray:
+					z.transform_Gray_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray16:
+					z.transform_Gray_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_Gray_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				case *image.CMYK:
+					z.transform_Gray16_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray:
+					z.transform_Gray16_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.Gray16:
+					z.transform_Gray16_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_Gray16_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_NRGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_NRGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				default:
+					z.transform_NRGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					z.transform_RGBA64_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA:
+					z.transform_RGBA64_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.RGBA64:
+					z.transform_RGBA64_RGBA64_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						z.transform_RGBA64_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio444:
+						z.transform_RGBA64_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio422:
+						z.transform_RGBA64_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio420:
+						z.transform_RGBA64_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					case image.YCbCrSubsampleRatio440:
+						z.transform_RGBA64_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+					}
+				default:
+					z.transform_RGBA64_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			default:
+				switch src := src.(type) {
+				default:
+					z.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, &o)
+				}
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_CMYK_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.CMYK, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s00w := 0xffff - uint32(src.Pix[s00i+3])*0x101
+			s00ru := (0xffff - uint32(src.Pix[s00i+0])*0x101) * s00w / 0xffff
+			s00gu := (0xffff - uint32(src.Pix[s00i+1])*0x101) * s00w / 0xffff
+			s00bu := (0xffff - uint32(src.Pix[s00i+2])*0x101) * s00w / 0xffff
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s10w := 0xffff - uint32(src.Pix[s10i+3])*0x101
+			s10ru := (0xffff - uint32(src.Pix[s10i+0])*0x101) * s10w / 0xffff
+			s10gu := (0xffff - uint32(src.Pix[s10i+1])*0x101) * s10w / 0xffff
+			s10bu := (0xffff - uint32(src.Pix[s10i+2])*0x101) * s10w / 0xffff
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s01w := 0xffff - uint32(src.Pix[s01i+3])*0x101
+			s01ru := (0xffff - uint32(src.Pix[s01i+0])*0x101) * s01w / 0xffff
+			s01gu := (0xffff - uint32(src.Pix[s01i+1])*0x101) * s01w / 0xffff
+			s01bu := (0xffff - uint32(src.Pix[s01i+2])*0x101) * s01w / 0xffff
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s11w := 0xffff - uint32(src.Pix[s11i+3])*0x101
+			s11ru := (0xffff - uint32(src.Pix[s11i+0])*0x101) * s11w / 0xffff
+			s11gu := (0xffff - uint32(src.Pix[s11i+1])*0x101) * s11w / 0xffff
+			s11bu := (0xffff - uint32(src.Pix[s11i+2])*0x101) * s11w / 0xffff
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00ru := uint32(src.Pix[s00i]) * 0x101
+			s00r := float64(s00ru)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10ru := uint32(src.Pix[s10i]) * 0x101
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01ru := uint32(src.Pix[s01i]) * 0x101
+			s01r := float64(s01ru)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11ru := uint32(src.Pix[s11i]) * 0x101
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_Gray16_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.Gray16, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*2
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00r := float64(s00ru)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*2
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*2
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01r := float64(s01ru)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*2
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_RGBA64_Over(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_RGBA64_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray_CMYK_Src(dst *image.Gray, dr, adr image.Rectangle, src *image.CMYK, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s00w := 0xffff - uint32(src.Pix[s00i+3])*0x101
+			s00ru := (0xffff - uint32(src.Pix[s00i+0])*0x101) * s00w / 0xffff
+			s00gu := (0xffff - uint32(src.Pix[s00i+1])*0x101) * s00w / 0xffff
+			s00bu := (0xffff - uint32(src.Pix[s00i+2])*0x101) * s00w / 0xffff
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s10w := 0xffff - uint32(src.Pix[s10i+3])*0x101
+			s10ru := (0xffff - uint32(src.Pix[s10i+0])*0x101) * s10w / 0xffff
+			s10gu := (0xffff - uint32(src.Pix[s10i+1])*0x101) * s10w / 0xffff
+			s10bu := (0xffff - uint32(src.Pix[s10i+2])*0x101) * s10w / 0xffff
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s01w := 0xffff - uint32(src.Pix[s01i+3])*0x101
+			s01ru := (0xffff - uint32(src.Pix[s01i+0])*0x101) * s01w / 0xffff
+			s01gu := (0xffff - uint32(src.Pix[s01i+1])*0x101) * s01w / 0xffff
+			s01bu := (0xffff - uint32(src.Pix[s01i+2])*0x101) * s01w / 0xffff
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s11w := 0xffff - uint32(src.Pix[s11i+3])*0x101
+			s11ru := (0xffff - uint32(src.Pix[s11i+0])*0x101) * s11w / 0xffff
+			s11gu := (0xffff - uint32(src.Pix[s11i+1])*0x101) * s11w / 0xffff
+			s11bu := (0xffff - uint32(src.Pix[s11i+2])*0x101) * s11w / 0xffff
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray_Gray_Src(dst *image.Gray, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00ru := uint32(src.Pix[s00i]) * 0x101
+			s00r := float64(s00ru)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10ru := uint32(src.Pix[s10i]) * 0x101
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01ru := uint32(src.Pix[s01i]) * 0x101
+			s01r := float64(s01ru)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11ru := uint32(src.Pix[s11i]) * 0x101
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d] = uint8(pr >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray_Gray16_Src(dst *image.Gray, dr, adr image.Rectangle, src *image.Gray16, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*2
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00r := float64(s00ru)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*2
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*2
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01r := float64(s01ru)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*2
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d] = uint8(pr >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray_Image_Over(dst *image.Gray, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*pa1/0xffff + py) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray_Image_Src(dst *image.Gray, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray16_CMYK_Src(dst *image.Gray16, dr, adr image.Rectangle, src *image.CMYK, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s00w := 0xffff - uint32(src.Pix[s00i+3])*0x101
+			s00ru := (0xffff - uint32(src.Pix[s00i+0])*0x101) * s00w / 0xffff
+			s00gu := (0xffff - uint32(src.Pix[s00i+1])*0x101) * s00w / 0xffff
+			s00bu := (0xffff - uint32(src.Pix[s00i+2])*0x101) * s00w / 0xffff
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s10w := 0xffff - uint32(src.Pix[s10i+3])*0x101
+			s10ru := (0xffff - uint32(src.Pix[s10i+0])*0x101) * s10w / 0xffff
+			s10gu := (0xffff - uint32(src.Pix[s10i+1])*0x101) * s10w / 0xffff
+			s10bu := (0xffff - uint32(src.Pix[s10i+2])*0x101) * s10w / 0xffff
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s01w := 0xffff - uint32(src.Pix[s01i+3])*0x101
+			s01ru := (0xffff - uint32(src.Pix[s01i+0])*0x101) * s01w / 0xffff
+			s01gu := (0xffff - uint32(src.Pix[s01i+1])*0x101) * s01w / 0xffff
+			s01bu := (0xffff - uint32(src.Pix[s01i+2])*0x101) * s01w / 0xffff
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s11w := 0xffff - uint32(src.Pix[s11i+3])*0x101
+			s11ru := (0xffff - uint32(src.Pix[s11i+0])*0x101) * s11w / 0xffff
+			s11gu := (0xffff - uint32(src.Pix[s11i+1])*0x101) * s11w / 0xffff
+			s11bu := (0xffff - uint32(src.Pix[s11i+2])*0x101) * s11w / 0xffff
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray16_Gray_Src(dst *image.Gray16, dr, adr image.Rectangle, src *image.Gray, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00ru := uint32(src.Pix[s00i]) * 0x101
+			s00r := float64(s00ru)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10ru := uint32(src.Pix[s10i]) * 0x101
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01ru := uint32(src.Pix[s01i]) * 0x101
+			s01r := float64(s01ru)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11ru := uint32(src.Pix[s11i]) * 0x101
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray16_Gray16_Src(dst *image.Gray16, dr, adr image.Rectangle, src *image.Gray16, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*2
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00r := float64(s00ru)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*2
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*2
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01r := float64(s01ru)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*2
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray16_Image_Over(dst *image.Gray16, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			out := qy*pa1/0xffff + py
+			dst.Pix[d+0] = uint8(out >> 8)
+			dst.Pix[d+1] = uint8(out)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Gray16_Image_Src(dst *image.Gray16, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (ablInterpolator) scale_NRGBA_NRGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_NRGBA_NRGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_NRGBA_RGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_NRGBA_RGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_NRGBA_Image_Over(dst *image.NRGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_NRGBA_Image_Src(dst *image.NRGBA, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_NRGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_NRGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.NRGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_RGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_RGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_RGBA64_Over(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_RGBA64_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.RGBA64, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx0)-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(sx1)-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_YCbCr444_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_YCbCr422_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_YCbCr420_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx0))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(sx1))/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_YCbCr440_Src(dst *image.RGBA64, dr, adr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s00j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sr.Min.Y+int(sy0)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s10j := ((sr.Min.Y+int(sy0))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+			s01j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx0) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sr.Min.Y+int(sy1)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+			s11j := ((sr.Min.Y+int(sy1))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(sx1) - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_Image_Over(dst *image.RGBA64, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_RGBA64_Image_Src(dst *image.RGBA64, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Image_Image_Over(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy0)).RGBA()
+				s00ru = s00ru * ma / 0xffff
+				s00gu = s00gu * ma / 0xffff
+				s00bu = s00bu * ma / 0xffff
+				s00au = s00au * ma / 0xffff
+			}
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy0)).RGBA()
+				s10ru = s10ru * ma / 0xffff
+				s10gu = s10gu * ma / 0xffff
+				s10bu = s10bu * ma / 0xffff
+				s10au = s10au * ma / 0xffff
+			}
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy1)).RGBA()
+				s01ru = s01ru * ma / 0xffff
+				s01gu = s01gu * ma / 0xffff
+				s01bu = s01bu * ma / 0xffff
+				s01au = s01au * ma / 0xffff
+			}
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy1)).RGBA()
+				s11ru = s11ru * ma / 0xffff
+				s11gu = s11gu * ma / 0xffff
+				s11bu = s11bu * ma / 0xffff
+				s11au = s11au * ma / 0xffff
+			}
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
+			if dstMask != nil {
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
+			}
+			pa1 := 0xffff - pa
+			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+		}
+	}
+}
+
+func (ablInterpolator) scale_Image_Image_Src(dst Image, dr, adr image.Rectangle, src image.Image, sr image.Rectangle, opts *Options) {
+	sw := int32(sr.Dx())
+	sh := int32(sr.Dy())
+	yscale, yoffset := opts.Alignment.mapping(dr.Dy(), int(sh))
+	xscale, xoffset := opts.Alignment.mapping(dr.Dx(), int(sw))
+	swMinus1, shMinus1 := sw-1, sh-1
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		sy := (float64(dy)+yoffset)*yscale - yoffset
+		// If sy < 0, we will clamp sy0 to 0 anyway, so it doesn't matter if
+		// we say int32(sy) instead of int32(math.Floor(sy)). Similarly for
+		// sx, below.
+		sy0 := int32(sy)
+		yFrac0 := sy - float64(sy0)
+		yFrac1 := 1 - yFrac0
+		sy1 := sy0 + 1
+		if sy < 0 {
+			sy0, sy1 = 0, 0
+			yFrac0, yFrac1 = 0, 1
+		} else if sy1 > shMinus1 {
+			sy0, sy1 = shMinus1, shMinus1
+			yFrac0, yFrac1 = 1, 0
+		}
+
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+			sx := (float64(dx)+xoffset)*xscale - xoffset
+			sx0 := int32(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx1 := sx0 + 1
+			if sx < 0 {
+				sx0, sx1 = 0, 0
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 > swMinus1 {
+				sx0, sx1 = swMinus1, swMinus1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy0)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy0)).RGBA()
+				s00ru = s00ru * ma / 0xffff
+				s00gu = s00gu * ma / 0xffff
+				s00bu = s00bu * ma / 0xffff
+				s00au = s00au * ma / 0xffff
+			}
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy0)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy0)).RGBA()
+				s10ru = s10ru * ma / 0xffff
+				s10gu = s10gu * ma / 0xffff
+				s10bu = s10bu * ma / 0xffff
+				s10au = s10au * ma / 0xffff
+			}
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sr.Min.X+int(sx0), sr.Min.Y+int(sy1)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx0), smp.Y+sr.Min.Y+int(sy1)).RGBA()
+				s01ru = s01ru * ma / 0xffff
+				s01gu = s01gu * ma / 0xffff
+				s01bu = s01bu * ma / 0xffff
+				s01au = s01au * ma / 0xffff
+			}
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sr.Min.X+int(sx1), sr.Min.Y+int(sy1)).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(sx1), smp.Y+sr.Min.Y+int(sy1)).RGBA()
+				s11ru = s11ru * ma / 0xffff
+				s11gu = s11gu * ma / 0xffff
+				s11bu = s11bu * ma / 0xffff
+				s11au = s11au * ma / 0xffff
+			}
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			if dstMask != nil {
+				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
+				pa1 := 0xffff - ma
+				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			} else {
+				dstColorRGBA64.R = uint16(pr)
+				dstColorRGBA64.G = uint16(pg)
+				dstColorRGBA64.B = uint16(pb)
+				dstColorRGBA64.A = uint16(pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_CMYK_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s00w := 0xffff - uint32(src.Pix[s00i+3])*0x101
+			s00ru := (0xffff - uint32(src.Pix[s00i+0])*0x101) * s00w / 0xffff
+			s00gu := (0xffff - uint32(src.Pix[s00i+1])*0x101) * s00w / 0xffff
+			s00bu := (0xffff - uint32(src.Pix[s00i+2])*0x101) * s00w / 0xffff
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s10w := 0xffff - uint32(src.Pix[s10i+3])*0x101
+			s10ru := (0xffff - uint32(src.Pix[s10i+0])*0x101) * s10w / 0xffff
+			s10gu := (0xffff - uint32(src.Pix[s10i+1])*0x101) * s10w / 0xffff
+			s10bu := (0xffff - uint32(src.Pix[s10i+2])*0x101) * s10w / 0xffff
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s01w := 0xffff - uint32(src.Pix[s01i+3])*0x101
+			s01ru := (0xffff - uint32(src.Pix[s01i+0])*0x101) * s01w / 0xffff
+			s01gu := (0xffff - uint32(src.Pix[s01i+1])*0x101) * s01w / 0xffff
+			s01bu := (0xffff - uint32(src.Pix[s01i+2])*0x101) * s01w / 0xffff
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s11w := 0xffff - uint32(src.Pix[s11i+3])*0x101
+			s11ru := (0xffff - uint32(src.Pix[s11i+0])*0x101) * s11w / 0xffff
+			s11gu := (0xffff - uint32(src.Pix[s11i+1])*0x101) * s11w / 0xffff
+			s11bu := (0xffff - uint32(src.Pix[s11i+2])*0x101) * s11w / 0xffff
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			s00ru := uint32(src.Pix[s00i]) * 0x101
+			s00r := float64(s00ru)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
+			s10ru := uint32(src.Pix[s10i]) * 0x101
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			s01ru := uint32(src.Pix[s01i]) * 0x101
+			s01r := float64(s01ru)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
+			s11ru := uint32(src.Pix[s11i]) * 0x101
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_Gray16_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00r := float64(s00ru)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*2
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01r := float64(s01ru)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*2
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			out := uint8(pr >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_RGBA64_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_RGBA64_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := (sy0-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := (sy0-src.Rect.Min.Y)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := (sy1-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := (sy1-src.Rect.Min.Y)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := (sy0-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := (sy0-src.Rect.Min.Y)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := (sy1-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := (sy1-src.Rect.Min.Y)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := (0xffff - pa) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pg >> 8)
+			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+3] = uint8(pa >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray_CMYK_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s00w := 0xffff - uint32(src.Pix[s00i+3])*0x101
+			s00ru := (0xffff - uint32(src.Pix[s00i+0])*0x101) * s00w / 0xffff
+			s00gu := (0xffff - uint32(src.Pix[s00i+1])*0x101) * s00w / 0xffff
+			s00bu := (0xffff - uint32(src.Pix[s00i+2])*0x101) * s00w / 0xffff
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s10w := 0xffff - uint32(src.Pix[s10i+3])*0x101
+			s10ru := (0xffff - uint32(src.Pix[s10i+0])*0x101) * s10w / 0xffff
+			s10gu := (0xffff - uint32(src.Pix[s10i+1])*0x101) * s10w / 0xffff
+			s10bu := (0xffff - uint32(src.Pix[s10i+2])*0x101) * s10w / 0xffff
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s01w := 0xffff - uint32(src.Pix[s01i+3])*0x101
+			s01ru := (0xffff - uint32(src.Pix[s01i+0])*0x101) * s01w / 0xffff
+			s01gu := (0xffff - uint32(src.Pix[s01i+1])*0x101) * s01w / 0xffff
+			s01bu := (0xffff - uint32(src.Pix[s01i+2])*0x101) * s01w / 0xffff
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s11w := 0xffff - uint32(src.Pix[s11i+3])*0x101
+			s11ru := (0xffff - uint32(src.Pix[s11i+0])*0x101) * s11w / 0xffff
+			s11gu := (0xffff - uint32(src.Pix[s11i+1])*0x101) * s11w / 0xffff
+			s11bu := (0xffff - uint32(src.Pix[s11i+2])*0x101) * s11w / 0xffff
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray_Gray_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			s00ru := uint32(src.Pix[s00i]) * 0x101
+			s00r := float64(s00ru)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
+			s10ru := uint32(src.Pix[s10i]) * 0x101
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			s01ru := uint32(src.Pix[s01i]) * 0x101
+			s01r := float64(s01ru)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
+			s11ru := uint32(src.Pix[s11i]) * 0x101
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d] = uint8(pr >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray_Gray16_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00r := float64(s00ru)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*2
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01r := float64(s01ru)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*2
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d] = uint8(pr >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray_Image_Over(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*pa1/0xffff + py) >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray_Image_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray16_CMYK_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s00w := 0xffff - uint32(src.Pix[s00i+3])*0x101
+			s00ru := (0xffff - uint32(src.Pix[s00i+0])*0x101) * s00w / 0xffff
+			s00gu := (0xffff - uint32(src.Pix[s00i+1])*0x101) * s00w / 0xffff
+			s00bu := (0xffff - uint32(src.Pix[s00i+2])*0x101) * s00w / 0xffff
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s10w := 0xffff - uint32(src.Pix[s10i+3])*0x101
+			s10ru := (0xffff - uint32(src.Pix[s10i+0])*0x101) * s10w / 0xffff
+			s10gu := (0xffff - uint32(src.Pix[s10i+1])*0x101) * s10w / 0xffff
+			s10bu := (0xffff - uint32(src.Pix[s10i+2])*0x101) * s10w / 0xffff
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s01w := 0xffff - uint32(src.Pix[s01i+3])*0x101
+			s01ru := (0xffff - uint32(src.Pix[s01i+0])*0x101) * s01w / 0xffff
+			s01gu := (0xffff - uint32(src.Pix[s01i+1])*0x101) * s01w / 0xffff
+			s01bu := (0xffff - uint32(src.Pix[s01i+2])*0x101) * s01w / 0xffff
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+
+			// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+			s11w := 0xffff - uint32(src.Pix[s11i+3])*0x101
+			s11ru := (0xffff - uint32(src.Pix[s11i+0])*0x101) * s11w / 0xffff
+			s11gu := (0xffff - uint32(src.Pix[s11i+1])*0x101) * s11w / 0xffff
+			s11bu := (0xffff - uint32(src.Pix[s11i+2])*0x101) * s11w / 0xffff
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray16_Gray_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			s00ru := uint32(src.Pix[s00i]) * 0x101
+			s00r := float64(s00ru)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
+			s10ru := uint32(src.Pix[s10i]) * 0x101
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0 - src.Rect.Min.X)
+			s01ru := uint32(src.Pix[s01i]) * 0x101
+			s01r := float64(s01ru)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1 - src.Rect.Min.X)
+			s11ru := uint32(src.Pix[s11i]) * 0x101
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray16_Gray16_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00r := float64(s00ru)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*2
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10r := float64(s10ru)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*2
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01r := float64(s01ru)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*2
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11r := float64(s11ru)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11r = yFrac1*s10r + yFrac0*s11r
+			pr := uint32(s11r)
+			dst.Pix[d+0] = uint8(pr >> 8)
+			dst.Pix[d+1] = uint8(pr)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray16_Image_Over(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			pa1 := 0xffff - pa
+			qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			out := qy*pa1/0xffff + py
+			dst.Pix[d+0] = uint8(out >> 8)
+			dst.Pix[d+1] = uint8(out)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Gray16_Image_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			py := (19595*pr + 38470*pg + 7471*pb + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (ablInterpolator) transform_NRGBA_NRGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_NRGBA_NRGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_NRGBA_RGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_NRGBA_RGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_NRGBA_Image_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, qa*pa1/0xffff+pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_NRGBA_Image_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, pa
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_NRGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_NRGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00ru := uint32(src.Pix[s00i+0]) * s00au / 0xff
+			s00gu := uint32(src.Pix[s00i+1]) * s00au / 0xff
+			s00bu := uint32(src.Pix[s00i+2]) * s00au / 0xff
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10ru := uint32(src.Pix[s10i+0]) * s10au / 0xff
+			s10gu := uint32(src.Pix[s10i+1]) * s10au / 0xff
+			s10bu := uint32(src.Pix[s10i+2]) * s10au / 0xff
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01ru := uint32(src.Pix[s01i+0]) * s01au / 0xff
+			s01gu := uint32(src.Pix[s01i+1]) * s01au / 0xff
+			s01bu := uint32(src.Pix[s01i+2]) * s01au / 0xff
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11ru := uint32(src.Pix[s11i+0]) * s11au / 0xff
+			s11gu := uint32(src.Pix[s11i+1]) * s11au / 0xff
+			s11bu := uint32(src.Pix[s11i+2]) * s11au / 0xff
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_RGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_RGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s00ru := uint32(src.Pix[s00i+0]) * 0x101
+			s00gu := uint32(src.Pix[s00i+1]) * 0x101
+			s00bu := uint32(src.Pix[s00i+2]) * 0x101
+			s00au := uint32(src.Pix[s00i+3]) * 0x101
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s10ru := uint32(src.Pix[s10i+0]) * 0x101
+			s10gu := uint32(src.Pix[s10i+1]) * 0x101
+			s10bu := uint32(src.Pix[s10i+2]) * 0x101
+			s10au := uint32(src.Pix[s10i+3]) * 0x101
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*4
+			s01ru := uint32(src.Pix[s01i+0]) * 0x101
+			s01gu := uint32(src.Pix[s01i+1]) * 0x101
+			s01bu := uint32(src.Pix[s01i+2]) * 0x101
+			s01au := uint32(src.Pix[s01i+3]) * 0x101
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*4
+			s11ru := uint32(src.Pix[s11i+0]) * 0x101
+			s11gu := uint32(src.Pix[s11i+1]) * 0x101
+			s11bu := uint32(src.Pix[s11i+2]) * 0x101
+			s11au := uint32(src.Pix[s11i+3]) * 0x101
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_RGBA64_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_RGBA64_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s00ru := uint32(src.Pix[s00i+0])<<8 | uint32(src.Pix[s00i+1])
+			s00gu := uint32(src.Pix[s00i+2])<<8 | uint32(src.Pix[s00i+3])
+			s00bu := uint32(src.Pix[s00i+4])<<8 | uint32(src.Pix[s00i+5])
+			s00au := uint32(src.Pix[s00i+6])<<8 | uint32(src.Pix[s00i+7])
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10i := (sy0-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s10ru := uint32(src.Pix[s10i+0])<<8 | uint32(src.Pix[s10i+1])
+			s10gu := uint32(src.Pix[s10i+2])<<8 | uint32(src.Pix[s10i+3])
+			s10bu := uint32(src.Pix[s10i+4])<<8 | uint32(src.Pix[s10i+5])
+			s10au := uint32(src.Pix[s10i+6])<<8 | uint32(src.Pix[s10i+7])
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01i := (sy1-src.Rect.Min.Y)*src.Stride + (sx0-src.Rect.Min.X)*8
+			s01ru := uint32(src.Pix[s01i+0])<<8 | uint32(src.Pix[s01i+1])
+			s01gu := uint32(src.Pix[s01i+2])<<8 | uint32(src.Pix[s01i+3])
+			s01bu := uint32(src.Pix[s01i+4])<<8 | uint32(src.Pix[s01i+5])
+			s01au := uint32(src.Pix[s01i+6])<<8 | uint32(src.Pix[s01i+7])
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11i := (sy1-src.Rect.Min.Y)*src.Stride + (sx1-src.Rect.Min.X)*8
+			s11ru := uint32(src.Pix[s11i+0])<<8 | uint32(src.Pix[s11i+1])
+			s11gu := uint32(src.Pix[s11i+2])<<8 | uint32(src.Pix[s11i+3])
+			s11bu := uint32(src.Pix[s11i+4])<<8 | uint32(src.Pix[s11i+5])
+			s11au := uint32(src.Pix[s11i+6])<<8 | uint32(src.Pix[s11i+7])
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_YCbCr444_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := (sy0-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := (sy0-src.Rect.Min.Y)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := (sy1-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := (sy1-src.Rect.Min.Y)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_YCbCr422_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := (sy0-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := (sy0-src.Rect.Min.Y)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := (sy1-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := (sy1-src.Rect.Min.Y)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_YCbCr420_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
+			} else if s00ru > 0xffff {
+				s00ru = 0xffff
+			}
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_YCbCr440_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s00j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s00yy1 := int(src.Y[s00i]) * 0x10101
+			s00cb1 := int(src.Cb[s00j]) - 128
+			s00cr1 := int(src.Cr[s00j]) - 128
+			s00ru := (s00yy1 + 91881*s00cr1) >> 8
+			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
+			s00bu := (s00yy1 + 116130*s00cb1) >> 8
+			if s00ru < 0 {
+				s00ru = 0
 			} else if s00ru > 0xffff {
 				s00ru = 0xffff
 			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
+			if s00gu < 0 {
+				s00gu = 0
+			} else if s00gu > 0xffff {
+				s00gu = 0xffff
+			}
+			if s00bu < 0 {
+				s00bu = 0
+			} else if s00bu > 0xffff {
+				s00bu = 0xffff
+			}
+
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s10j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s10yy1 := int(src.Y[s10i]) * 0x10101
+			s10cb1 := int(src.Cb[s10j]) - 128
+			s10cr1 := int(src.Cr[s10j]) - 128
+			s10ru := (s10yy1 + 91881*s10cr1) >> 8
+			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
+			s10bu := (s10yy1 + 116130*s10cb1) >> 8
+			if s10ru < 0 {
+				s10ru = 0
+			} else if s10ru > 0xffff {
+				s10ru = 0xffff
+			}
+			if s10gu < 0 {
+				s10gu = 0
+			} else if s10gu > 0xffff {
+				s10gu = 0xffff
+			}
+			if s10bu < 0 {
+				s10bu = 0
+			} else if s10bu > 0xffff {
+				s10bu = 0xffff
+			}
+
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
+			s01j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s01yy1 := int(src.Y[s01i]) * 0x10101
+			s01cb1 := int(src.Cb[s01j]) - 128
+			s01cr1 := int(src.Cr[s01j]) - 128
+			s01ru := (s01yy1 + 91881*s01cr1) >> 8
+			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
+			s01bu := (s01yy1 + 116130*s01cb1) >> 8
+			if s01ru < 0 {
+				s01ru = 0
+			} else if s01ru > 0xffff {
+				s01ru = 0xffff
+			}
+			if s01gu < 0 {
+				s01gu = 0
+			} else if s01gu > 0xffff {
+				s01gu = 0xffff
+			}
+			if s01bu < 0 {
+				s01bu = 0
+			} else if s01bu > 0xffff {
+				s01bu = 0xffff
+			}
+
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
+			s11j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + (sx1 - src.Rect.Min.X)
+
+			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+			s11yy1 := int(src.Y[s11i]) * 0x10101
+			s11cb1 := int(src.Cb[s11j]) - 128
+			s11cr1 := int(src.Cr[s11j]) - 128
+			s11ru := (s11yy1 + 91881*s11cr1) >> 8
+			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
+			s11bu := (s11yy1 + 116130*s11cb1) >> 8
+			if s11ru < 0 {
+				s11ru = 0
+			} else if s11ru > 0xffff {
+				s11ru = 0xffff
+			}
+			if s11gu < 0 {
+				s11gu = 0
+			} else if s11gu > 0xffff {
+				s11gu = 0xffff
+			}
+			if s11bu < 0 {
+				s11bu = 0
+			} else if s11bu > 0xffff {
+				s11bu = 0xffff
+			}
+
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			or, og, ob, oa := pr, pg, pb, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_Image_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			pa1 := 0xffff - pa
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr, qg*pa1/0xffff+pg, qb*pa1/0xffff+pb, uint32(qa*pa1/0xffff+pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_RGBA64_Image_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			or, og, ob, oa := pr, pg, pb, uint32(pa)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
+				s00ru = s00ru * ma / 0xffff
+				s00gu = s00gu * ma / 0xffff
+				s00bu = s00bu * ma / 0xffff
+				s00au = s00au * ma / 0xffff
+			}
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy0).RGBA()
+				s10ru = s10ru * ma / 0xffff
+				s10gu = s10gu * ma / 0xffff
+				s10bu = s10bu * ma / 0xffff
+				s10au = s10au * ma / 0xffff
+			}
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy1).RGBA()
+				s01ru = s01ru * ma / 0xffff
+				s01gu = s01gu * ma / 0xffff
+				s01bu = s01bu * ma / 0xffff
+				s01au = s01au * ma / 0xffff
+			}
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy1).RGBA()
+				s11ru = s11ru * ma / 0xffff
+				s11gu = s11gu * ma / 0xffff
+				s11bu = s11bu * ma / 0xffff
+				s11au = s11au * ma / 0xffff
+			}
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
+			if dstMask != nil {
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
+			}
+			pa1 := 0xffff - pa
+			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+		}
+	}
+}
+
+func (ablInterpolator) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+			dxf := float64(dr.Min.X+int(dx)) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+				continue
+			}
+
+			sx -= 0.5
+			sx0 := int(sx)
+			xFrac0 := sx - float64(sx0)
+			xFrac1 := 1 - xFrac0
+			sx0 += bias.X
+			sx1 := sx0 + 1
+			if sx0 < sr.Min.X {
+				sx0, sx1 = sr.Min.X, sr.Min.X
+				xFrac0, xFrac1 = 0, 1
+			} else if sx1 >= sr.Max.X {
+				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+				xFrac0, xFrac1 = 1, 0
+			}
+
+			sy -= 0.5
+			sy0 := int(sy)
+			yFrac0 := sy - float64(sy0)
+			yFrac1 := 1 - yFrac0
+			sy0 += bias.Y
+			sy1 := sy0 + 1
+			if sy0 < sr.Min.Y {
+				sy0, sy1 = sr.Min.Y, sr.Min.Y
+				yFrac0, yFrac1 = 0, 1
+			} else if sy1 >= sr.Max.Y {
+				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+				yFrac0, yFrac1 = 1, 0
+			}
+
+			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
+				s00ru = s00ru * ma / 0xffff
+				s00gu = s00gu * ma / 0xffff
+				s00bu = s00bu * ma / 0xffff
+				s00au = s00au * ma / 0xffff
+			}
+			s00r := float64(s00ru)
+			s00g := float64(s00gu)
+			s00b := float64(s00bu)
+			s00a := float64(s00au)
+			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy0).RGBA()
+				s10ru = s10ru * ma / 0xffff
+				s10gu = s10gu * ma / 0xffff
+				s10bu = s10bu * ma / 0xffff
+				s10au = s10au * ma / 0xffff
+			}
+			s10r := float64(s10ru)
+			s10g := float64(s10gu)
+			s10b := float64(s10bu)
+			s10a := float64(s10au)
+			s10r = xFrac1*s00r + xFrac0*s10r
+			s10g = xFrac1*s00g + xFrac0*s10g
+			s10b = xFrac1*s00b + xFrac0*s10b
+			s10a = xFrac1*s00a + xFrac0*s10a
+			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy1).RGBA()
+				s01ru = s01ru * ma / 0xffff
+				s01gu = s01gu * ma / 0xffff
+				s01bu = s01bu * ma / 0xffff
+				s01au = s01au * ma / 0xffff
+			}
+			s01r := float64(s01ru)
+			s01g := float64(s01gu)
+			s01b := float64(s01bu)
+			s01a := float64(s01au)
+			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
+			if srcMask != nil {
+				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy1).RGBA()
+				s11ru = s11ru * ma / 0xffff
+				s11gu = s11gu * ma / 0xffff
+				s11bu = s11bu * ma / 0xffff
+				s11au = s11au * ma / 0xffff
+			}
+			s11r := float64(s11ru)
+			s11g := float64(s11gu)
+			s11b := float64(s11bu)
+			s11a := float64(s11au)
+			s11r = xFrac1*s01r + xFrac0*s11r
+			s11g = xFrac1*s01g + xFrac0*s11g
+			s11b = xFrac1*s01b + xFrac0*s11b
+			s11a = xFrac1*s01a + xFrac0*s11a
+			s11r = yFrac1*s10r + yFrac0*s11r
+			s11g = yFrac1*s10g + yFrac0*s11g
+			s11b = yFrac1*s10b + yFrac0*s11b
+			s11a = yFrac1*s10a + yFrac0*s11a
+			pr := uint32(s11r)
+			pg := uint32(s11g)
+			pb := uint32(s11b)
+			pa := uint32(s11a)
+			if dstMask != nil {
+				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
+				pr = pr * ma / 0xffff
+				pg = pg * ma / 0xffff
+				pb = pb * ma / 0xffff
+				pa = pa * ma / 0xffff
+				pa1 := 0xffff - ma
+				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			} else {
+				dstColorRGBA64.R = uint16(pr)
+				dstColorRGBA64.G = uint16(pg)
+				dstColorRGBA64.B = uint16(pb)
+				dstColorRGBA64.A = uint16(pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			}
+		}
+	}
+}
+
+func (z *kernelScaler) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if z.dw != int32(dr.Dx()) || z.dh != int32(dr.Dy()) || z.sw != int32(sr.Dx()) || z.sh != int32(sr.Dy()) || z.align != o.Alignment {
+		z.kernel.Scale(dst, dr, src, sr, op, opts)
+		return
+	}
+	if compositeDst(opts) {
+		drawComposited(dst, dr, opts, func(tmp Image, o *Options) {
+			z.Scale(tmp, dr, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, dr, opts, func(tmp Image, o *Options) {
+			z.Scale(tmp, dr, src, sr, op, o)
+		})
+		return
+	}
+	// A Convolve's kernelScaler has no kernel to sample float images
+	// with, and instead converts their pixels to and from 16 bits.
+	if z.kernel != nil && (isFloat(dst) || isFloat(src)) {
+		scaleFloat(dst, dr, src, sr, op, opts, (&kernelSampler{q: z.kernel}).sample)
+		return
+	}
+	src = applyColorMatrix(src, &o)
+
+	// adr is the affected destination pixels.
+	adr := dst.Bounds().Intersect(dr)
+	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
+	if adr.Empty() || sr.Empty() {
+		return
+	}
+	// Make adr relative to dr.Min.
+	adr = adr.Sub(dr.Min)
+	if op == Over && o.SrcMask == nil && opaque(src) {
+		op = Src
+	}
+
+	if _, ok := src.(*image.Uniform); ok && o.DstMask == nil && o.SrcMask == nil && o.RowsDone == nil && sr.In(src.Bounds()) {
+		Draw(dst, dr, src, src.Bounds().Min, op)
+		return
+	}
+
+	// Create a temporary buffer:
+	// scaleX distributes the source image's columns over the temporary image.
+	// scaleY distributes the temporary image's rows over the destination image.
+	var tmp [][4]float64
+	if o.ScaleBuffer != nil {
+		tmp = o.ScaleBuffer.get(int(z.dw) * int(z.sh))
+	} else if z.pool.New != nil {
+		tmpp := z.pool.Get().(*[][4]float64)
+		defer z.pool.Put(tmpp)
+		tmp = *tmpp
+	} else {
+		tmp = z.makeTmpBuf()
+	}
+
+	// sr is the source pixels. If it extends beyond the src bounds,
+	// we cannot use the type-specific fast paths, as they access
+	// the Pix fields directly without bounds checking.
+	//
+	// Similarly, the fast paths assume that the masks are nil.
+	inBounds := sr.In(src.Bounds())
+	parallel(o.Concurrency, 0, z.sh, func(y0, y1 int32) {
+		if o.SrcMask != nil || !inBounds {
+			z.scaleX_Image(tmp, src, sr, y0, y1, &o)
+		} else {
+			switch src := src.(type) {
+			case *image.CMYK:
+				z.scaleX_CMYK(tmp, src, sr, y0, y1, &o)
+			case *image.Gray:
+				z.scaleX_Gray(tmp, src, sr, y0, y1, &o)
+			case *image.Gray16:
+				z.scaleX_Gray16(tmp, src, sr, y0, y1, &o)
+			case *image.NRGBA:
+				z.scaleX_NRGBA(tmp, src, sr, y0, y1, &o)
+			case *image.RGBA:
+				z.scaleX_RGBA(tmp, src, sr, y0, y1, &o)
+			case *image.RGBA64:
+				z.scaleX_RGBA64(tmp, src, sr, y0, y1, &o)
+			case *image.YCbCr:
+				switch src.SubsampleRatio {
+				default:
+					z.scaleX_Image(tmp, src, sr, y0, y1, &o)
+				case image.YCbCrSubsampleRatio444:
+					z.scaleX_YCbCr444(tmp, src, sr, y0, y1, &o)
+				case image.YCbCrSubsampleRatio422:
+					z.scaleX_YCbCr422(tmp, src, sr, y0, y1, &o)
+				case image.YCbCrSubsampleRatio420:
+					z.scaleX_YCbCr420(tmp, src, sr, y0, y1, &o)
+				case image.YCbCrSubsampleRatio440:
+					z.scaleX_YCbCr440(tmp, src, sr, y0, y1, &o)
+				}
+			default:
+				z.scaleX_Image(tmp, src, sr, y0, y1, &o)
+			}
+		}
+		if o.AntiRinging {
+			z.clampX(tmp, src, sr, y0, y1, &o)
+		}
+	})
+
+	if o.RowsDone == nil {
+		z.scaleY(dst, dr, adr, tmp, op, &o)
+		return
+	}
+	for y := dr.Min.Y + adr.Min.Y; ; {
+		// Each band is the part of dr within dst's current bounds,
+		// which RowsDone may change to move dst on to later rows.
+		band := dst.Bounds().Intersect(dr)
+		if band.Min.Y < y {
+			band.Min.Y = y
+		}
+		band, o.DstMask = clipAffectedDestRect(band, opts.DstMask, opts.DstMaskP)
+		if band.Empty() {
+			return
+		}
+		z.scaleY(dst, dr, band.Sub(dr.Min), tmp, op, &o)
+		o.RowsDone(band.Min.Y, band.Max.Y)
+		y = band.Max.Y
+	}
+}
+
+// scaleY distributes the rows of tmp over the dst pixels adr, which is
+// relative to dr.Min.
+func (z *kernelScaler) scaleY(dst Image, dr, adr image.Rectangle, tmp [][4]float64, op Op, o *Options) {
+	parallel(o.Concurrency, int32(adr.Min.Y), int32(adr.Max.Y), func(y0, y1 int32) {
+		adr := image.Rect(adr.Min.X, int(y0), adr.Max.X, int(y1))
+		if o.DstMask != nil {
+			switch op {
+			case Over:
+				z.scaleY_Image_Over(dst, dr, adr, tmp, o)
+			case Src:
+				z.scaleY_Image_Src(dst, dr, adr, tmp, o)
+			}
+		} else {
+			switch op {
+			case Over:
+				switch dst := dst.(type) {
+				case *image.RGBA:
+					z.scaleY_RGBA_Over(dst, dr, adr, tmp, o)
+				case *image.Gray:
+					z.scaleY_Gray_Over(dst, dr, adr, tmp, o)
+				case *image.Gray16:
+					z.scaleY_Gray16_Over(dst, dr, adr, tmp, o)
+				case *image.NRGBA:
+					z.scaleY_NRGBA_Over(dst, dr, adr, tmp, o)
+				case *image.RGBA64:
+					z.scaleY_RGBA64_Over(dst, dr, adr, tmp, o)
+				default:
+					z.scaleY_Image_Over(dst, dr, adr, tmp, o)
+				}
+			case Src:
+				switch dst := dst.(type) {
+				case *image.RGBA:
+					z.scaleY_RGBA_Src(dst, dr, adr, tmp, o)
+				case *image.Gray:
+					z.scaleY_Gray_Src(dst, dr, adr, tmp, o)
+				case *image.Gray16:
+					z.scaleY_Gray16_Src(dst, dr, adr, tmp, o)
+				case *image.NRGBA:
+					z.scaleY_NRGBA_Src(dst, dr, adr, tmp, o)
+				case *image.RGBA64:
+					z.scaleY_RGBA64_Src(dst, dr, adr, tmp, o)
+				default:
+					z.scaleY_Image_Src(dst, dr, adr, tmp, o)
+				}
+			}
+		}
+	})
+}
+
+func (q *Kernel) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if compositeDst(opts) {
+		drawComposited(dst, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+			q.Transform(tmp, s2d, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, transformAffected(&s2d, &sr, opts), opts, func(tmp Image, o *Options) {
+			q.Transform(tmp, s2d, src, sr, op, o)
+		})
+		return
+	}
+	if isFloat(dst) || isFloat(src) {
+		transformFloat(dst, s2d, src, sr, op, opts, (&kernelSampler{q: q}).sample)
+		return
+	}
+	if opts != nil && opts.EdgeOp != EdgeNone {
+		transformEdges(q, dst, s2d, src, sr, op, opts)
+		return
+	}
+	q.transform(dst, s2d, src, sr, op, opts, nil)
+}
+
+// transform implements Transform. If c is non-nil, it holds the
+// precomputed kernel weights for s2d and sr.
+func (q *Kernel) transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options, c *kernelTransformer) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	src = applyColorMatrix(src, &o)
+
+	dr := transformRect(&s2d, &sr)
+	// adr is the affected destination pixels.
+	adr := dst.Bounds().Intersect(dr)
+	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
+	if adr.Empty() || sr.Empty() {
+		return
+	}
+	if op == Over && o.SrcMask == nil && opaque(src) {
+		op = Src
+	}
+	d2s, ok := s2d.Invert()
+	if !ok {
+		return
+	}
+	// bias is a translation of the mapping from dst coordinates to src
+	// coordinates such that the latter temporarily have non-negative X
+	// and Y coordinates. This allows us to write int(f) instead of
+	// int(math.Floor(f)), since "round to zero" and "round down" are
+	// equivalent when f >= 0, but the former is much cheaper. The X--
+	// and Y-- are because the TransformLeaf methods have a "sx -= 0.5"
+	// adjustment.
+	bias := transformRect(&d2s, &adr).Min
+	bias.X--
+	bias.Y--
+	d2s[2] -= float64(bias.X)
+	d2s[5] -= float64(bias.Y)
+	// Make adr relative to dr.Min.
+	adr = adr.Sub(dr.Min)
+
+	if u, ok := src.(*image.Uniform); ok && o.DstMask != nil && o.SrcMask != nil && sr.In(src.Bounds()) {
+		transform_Uniform(dst, dr, adr, &d2s, u, sr, bias, op)
+		return
+	}
+
+	xscale := abs(d2s[0])
+	if s := abs(d2s[1]); xscale < s {
+		xscale = s
+	}
+	yscale := abs(d2s[3])
+	if s := abs(d2s[4]); yscale < s {
+		yscale = s
+	}
+
+	// sr is the source pixels. If it extends beyond the src bounds,
+	// we cannot use the type-specific fast paths, as they access
+	// the Pix fields directly without bounds checking.
+	//
+	// Similarly, the fast paths assume that the masks are nil.
+	if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
+		switch op {
+		case Over:
+			q.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+		case Src:
+			q.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+		}
+	} else {
+		switch op {
+		case Over:
+			switch dst := dst.(type) {
+			case *image.RGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					q.transform_RGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA:
+					q.transform_RGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA64:
+					q.transform_RGBA_RGBA64_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				default:
+					q.transform_RGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.Gray:
+				switch src := src.(type) {
+				default:
+					q.transform_Gray_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				default:
+					q.transform_Gray16_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					q.transform_NRGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA:
+					q.transform_NRGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				default:
+					q.transform_NRGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					q.transform_RGBA64_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA:
+					q.transform_RGBA64_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA64:
+					q.transform_RGBA64_RGBA64_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				default:
+					q.transform_RGBA64_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			default:
+				switch src := src.(type) {
+				default:
+					q.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			}
+		case Src:
+			switch dst := dst.(type) {
+			case *image.RGBA:
+				switch src := src.(type) {
+				case *image.CMYK:
+					q.transform_RGBA_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.Gray:
+					q.transform_RGBA_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.Gray16:
+					q.transform_RGBA_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.NRGBA:
+					q.transform_RGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA:
+					q.transform_RGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA64:
+					q.transform_RGBA_RGBA64_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						q.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio444:
+						q.transform_RGBA_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio422:
+						q.transform_RGBA_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio420:
+						q.transform_RGBA_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio440:
+						q.transform_RGBA_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					}
+				default:
+					q.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.Gray:
+				switch src := src.(type) {
+				case *image.CMYK:
+					q.transform_Gray_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.Gray:
+					q.transform_Gray_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.Gray16:
+					q.transform_Gray_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				default:
+					q.transform_Gray_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.Gray16:
+				switch src := src.(type) {
+				case *image.CMYK:
+					q.transform_Gray16_CMYK_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.Gray:
+					q.transform_Gray16_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.Gray16:
+					q.transform_Gray16_Gray16_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				default:
+					q.transform_Gray16_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.NRGBA:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					q.transform_NRGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA:
+					q.transform_NRGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				default:
+					q.transform_NRGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			case *image.RGBA64:
+				switch src := src.(type) {
+				case *image.NRGBA:
+					q.transform_RGBA64_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA:
+					q.transform_RGBA64_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.RGBA64:
+					q.transform_RGBA64_RGBA64_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				case *image.YCbCr:
+					switch src.SubsampleRatio {
+					default:
+						q.transform_RGBA64_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio444:
+						q.transform_RGBA64_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio422:
+						q.transform_RGBA64_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio420:
+						q.transform_RGBA64_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					case image.YCbCrSubsampleRatio440:
+						q.transform_RGBA64_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+					}
+				default:
+					q.transform_RGBA64_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			default:
+				switch src := src.(type) {
+				default:
+					q.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o, c)
+				}
+			}
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_CMYK(tmp [][4]float64, src *image.CMYK, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*4
+
+				// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+				pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+				pru := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+				pgu := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+				pbu := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_Gray(tmp [][4]float64, src *image.Gray, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+				pru := uint32(src.Pix[pi]) * 0x101
+				pr += float64(pru) * c.weight
+			}
+			pr *= s.invTotalWeightFFFF
+			tmp[t] = [4]float64{
+				pr,
+				pr,
+				pr,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_Gray16(tmp [][4]float64, src *image.Gray16, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*2
+				pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+				pr += float64(pru) * c.weight
+			}
+			pr *= s.invTotalWeightFFFF
+			tmp[t] = [4]float64{
+				pr,
+				pr,
+				pr,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_NRGBA(tmp [][4]float64, src *image.NRGBA, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb, pa float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*4
+				pau := uint32(src.Pix[pi+3]) * 0x101
+				pru := uint32(src.Pix[pi+0]) * pau / 0xff
+				pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+				pbu := uint32(src.Pix[pi+2]) * pau / 0xff
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+				pa += float64(pau) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				pa * s.invTotalWeightFFFF,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_RGBA(tmp [][4]float64, src *image.RGBA, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD {
+				var p [4]float64
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X-src.Rect.Min.X)*4
+				scaleXRGBASIMD(&p, src.Pix[pi:], z.horizontal.contribs[s.i:s.j])
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.horizontal.contribs[s.i:s.j] {
+					pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*4
+					pru := uint32(src.Pix[pi+0]) * 0x101
+					pgu := uint32(src.Pix[pi+1]) * 0x101
+					pbu := uint32(src.Pix[pi+2]) * 0x101
+					pau := uint32(src.Pix[pi+3]) * 0x101
+					pr += float64(pru) * c.weight
+					pg += float64(pgu) * c.weight
+					pb += float64(pbu) * c.weight
+					pa += float64(pau) * c.weight
+				}
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				pa * s.invTotalWeightFFFF,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_RGBA64(tmp [][4]float64, src *image.RGBA64, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb, pa float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*8
+				pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+				pgu := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+				pbu := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+				pau := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+				pa += float64(pau) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				pa * s.invTotalWeightFFFF,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_YCbCr444(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+				pj := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+
+				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+				pyy1 := int(src.Y[pi]) * 0x10101
+				pcb1 := int(src.Cb[pj]) - 128
+				pcr1 := int(src.Cr[pj]) - 128
+				pru := (pyy1 + 91881*pcr1) >> 8
+				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+				pbu := (pyy1 + 116130*pcb1) >> 8
+				if pru < 0 {
+					pru = 0
+				} else if pru > 0xffff {
+					pru = 0xffff
+				}
+				if pgu < 0 {
+					pgu = 0
+				} else if pgu > 0xffff {
+					pgu = 0xffff
+				}
+				if pbu < 0 {
+					pbu = 0
+				} else if pbu > 0xffff {
+					pbu = 0xffff
+				}
+
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_YCbCr422(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+				pj := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(c.coord))/2 - src.Rect.Min.X/2)
+
+				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+				pyy1 := int(src.Y[pi]) * 0x10101
+				pcb1 := int(src.Cb[pj]) - 128
+				pcr1 := int(src.Cr[pj]) - 128
+				pru := (pyy1 + 91881*pcr1) >> 8
+				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+				pbu := (pyy1 + 116130*pcb1) >> 8
+				if pru < 0 {
+					pru = 0
+				} else if pru > 0xffff {
+					pru = 0xffff
+				}
+				if pgu < 0 {
+					pgu = 0
+				} else if pgu > 0xffff {
+					pgu = 0xffff
+				}
+				if pbu < 0 {
+					pbu = 0
+				} else if pbu > 0xffff {
+					pbu = 0xffff
+				}
+
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_YCbCr420(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+				pj := ((sr.Min.Y+int(y))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(c.coord))/2 - src.Rect.Min.X/2)
+
+				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+				pyy1 := int(src.Y[pi]) * 0x10101
+				pcb1 := int(src.Cb[pj]) - 128
+				pcr1 := int(src.Cr[pj]) - 128
+				pru := (pyy1 + 91881*pcr1) >> 8
+				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+				pbu := (pyy1 + 116130*pcb1) >> 8
+				if pru < 0 {
+					pru = 0
+				} else if pru > 0xffff {
+					pru = 0xffff
+				}
+				if pgu < 0 {
+					pgu = 0
+				} else if pgu > 0xffff {
+					pgu = 0xffff
+				}
+				if pbu < 0 {
+					pbu = 0
+				} else if pbu > 0xffff {
+					pbu = 0xffff
+				}
+
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_YCbCr440(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+				pj := ((sr.Min.Y+int(y))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+
+				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+				pyy1 := int(src.Y[pi]) * 0x10101
+				pcb1 := int(src.Cb[pj]) - 128
+				pcr1 := int(src.Cr[pj]) - 128
+				pru := (pyy1 + 91881*pcr1) >> 8
+				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+				pbu := (pyy1 + 116130*pcb1) >> 8
+				if pru < 0 {
+					pru = 0
+				} else if pru > 0xffff {
+					pru = 0xffff
+				}
+				if pgu < 0 {
+					pgu = 0
+				} else if pgu > 0xffff {
+					pgu = 0xffff
+				}
+				if pbu < 0 {
+					pbu = 0
+				} else if pbu > 0xffff {
+					pbu = 0xffff
+				}
+
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				1,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleX_Image(tmp [][4]float64, src image.Image, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			var pr, pg, pb, pa float64
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pru, pgu, pbu, pau := src.At(sr.Min.X+int(c.coord), sr.Min.Y+int(y)).RGBA()
+				if srcMask != nil {
+					_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(c.coord), smp.Y+sr.Min.Y+int(y)).RGBA()
+					pru = pru * ma / 0xffff
+					pgu = pgu * ma / 0xffff
+					pbu = pbu * ma / 0xffff
+					pau = pau * ma / 0xffff
+				}
+				pr += float64(pru) * c.weight
+				pg += float64(pgu) * c.weight
+				pb += float64(pbu) * c.weight
+				pa += float64(pau) * c.weight
+			}
+			tmp[t] = [4]float64{
+				pr * s.invTotalWeightFFFF,
+				pg * s.invTotalWeightFFFF,
+				pb * s.invTotalWeightFFFF,
+				pa * s.invTotalWeightFFFF,
+			}
+			t++
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*4
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			pa1 := (0xffff - uint32(pa0)) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*4
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			dst.Pix[d+0] = uint8(ftou(pr*s.invTotalWeight) >> 8)
+			dst.Pix[d+1] = uint8(ftou(pg*s.invTotalWeight) >> 8)
+			dst.Pix[d+2] = uint8(ftou(pb*s.invTotalWeight) >> 8)
+			dst.Pix[d+3] = uint8(ftou(pa*s.invTotalWeight) >> 8)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_Gray_Over(dst *image.Gray, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + int(dx) - dst.Rect.Min.X)
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			pa1 := 0xffff - pa0
+			dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*pa1/0xffff + py) >> 8)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_Gray_Src(dst *image.Gray, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + int(dx) - dst.Rect.Min.X)
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_Gray16_Over(dst *image.Gray16, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*2
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			pa1 := 0xffff - pa0
+			qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			out := qy*pa1/0xffff + py
+			dst.Pix[d+0] = uint8(out >> 8)
+			dst.Pix[d+1] = uint8(out)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_Gray16_Src(dst *image.Gray16, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*2
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_NRGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*4
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			pa1 := 0xffff - pa0
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, qa*pa1/0xffff+pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_NRGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*4
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			or, og, ob, oa := pr0, pg0, pb0, pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_RGBA64_Over(dst *image.RGBA64, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*8
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			pa1 := 0xffff - pa0
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, uint32(qa*pa1/0xffff+pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_RGBA64_Src(dst *image.RGBA64, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*8
+		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
+			d += dst.Stride
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_Image_Over(dst Image, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		for dy, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
+			pr0 := uint32(ftou(pr * s.invTotalWeight))
+			pg0 := uint32(ftou(pg * s.invTotalWeight))
+			pb0 := uint32(ftou(pb * s.invTotalWeight))
+			pa0 := uint32(ftou(pa * s.invTotalWeight))
+			if dstMask != nil {
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
+				pr0 = pr0 * ma / 0xffff
+				pg0 = pg0 * ma / 0xffff
+				pb0 = pb0 * ma / 0xffff
+				pa0 = pa0 * ma / 0xffff
+			}
+			pa1 := 0xffff - pa0
+			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr0)
+			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg0)
+			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb0)
+			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa0)
+			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy), dstColor)
+		}
+	}
+}
+
+func (z *kernelScaler) scaleY_Image_Src(dst Image, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
+	dstMask, dmp := opts.DstMask, opts.DstMaskP
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
+		for dy, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+			var pr, pg, pb, pa float64
+			if haveScaleSIMD && s.j-s.i >= scaleYSIMDMinContribs {
+				var p [4]float64
+				scaleYSIMD(&p, tmp[dx:], z.vertical.contribs[s.i:s.j], int(z.dw))
+				pr, pg, pb, pa = p[0], p[1], p[2], p[3]
+			} else {
+				for _, c := range z.vertical.contribs[s.i:s.j] {
+					p := &tmp[c.coord*z.dw+dx]
+					pr += p[0] * c.weight
+					pg += p[1] * c.weight
+					pb += p[2] * c.weight
+					pa += p[3] * c.weight
+				}
+			}
+			if opts.AntiRinging {
+				pr, pg, pb, pa = z.clampY(tmp, dx, s, pr, pg, pb, pa)
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			if dstMask != nil {
+				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
+				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
+				pr := uint32(ftou(pr*s.invTotalWeight)) * ma / 0xffff
+				pg := uint32(ftou(pg*s.invTotalWeight)) * ma / 0xffff
+				pb := uint32(ftou(pb*s.invTotalWeight)) * ma / 0xffff
+				pa := uint32(ftou(pa*s.invTotalWeight)) * ma / 0xffff
+				pa1 := 0xffff - ma
+				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
+				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
+				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
+				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy), dstColor)
+			} else {
+				dstColorRGBA64.R = ftou(pr * s.invTotalWeight)
+				dstColorRGBA64.G = ftou(pg * s.invTotalWeight)
+				dstColorRGBA64.B = ftou(pb * s.invTotalWeight)
+				dstColorRGBA64.A = ftou(pa * s.invTotalWeight)
+				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy), dstColor)
+			}
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_CMYK_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+
+							// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+							pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+							pru := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+							pgu := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+							pbu := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
+			}
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx - src.Rect.Min.X)
+							pru := uint32(src.Pix[pi]) * 0x101
+							pr += float64(pru) * w
+						}
+					}
+				}
+			}
+			out := uint8(fffftou(pr) >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_Gray16_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*2
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pr += float64(pru) * w
+						}
+					}
+				}
+			}
+			out := uint8(fffftou(pr) >> 8)
+			dst.Pix[d+0] = out
+			dst.Pix[d+1] = out
+			dst.Pix[d+2] = out
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pru := uint32(src.Pix[pi+0]) * pau / 0xff
+							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := (0xffff - uint32(pa0)) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pru := uint32(src.Pix[pi+0]) * pau / 0xff
+							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
 			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
+			if pb > pa {
+				pb = pa
+			}
+
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pru := uint32(src.Pix[pi+0]) * 0x101
+							pgu := uint32(src.Pix[pi+1]) * 0x101
+							pbu := uint32(src.Pix[pi+2]) * 0x101
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := (0xffff - uint32(pa0)) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pru := uint32(src.Pix[pi+0]) * 0x101
+							pgu := uint32(src.Pix[pi+1]) * 0x101
+							pbu := uint32(src.Pix[pi+2]) * 0x101
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_RGBA64_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*8
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pgu := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+							pbu := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+							pau := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := (0xffff - uint32(pa0)) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_RGBA64_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s10j := (sy0-src.Rect.Min.Y)*src.CStride + (sx1 - src.Rect.Min.X)
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*8
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pgu := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+							pbu := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+							pau := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
+			if pr > pa {
+				pr = pa
 			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
+			if pg > pa {
+				pg = pa
 			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
+			if pb > pa {
+				pb = pa
 			}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s01j := (sy1-src.Rect.Min.Y)*src.CStride + (sx0 - src.Rect.Min.X)
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+		}
+	}
+}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
+func (q *Kernel) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
+
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.YStride + (kx - src.Rect.Min.X)
+							pj := (ky-src.Rect.Min.Y)*src.CStride + (kx - src.Rect.Min.X)
+
+							// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+							pyy1 := int(src.Y[pi]) * 0x10101
+							pcb1 := int(src.Cb[pj]) - 128
+							pcr1 := int(src.Cr[pj]) - 128
+							pru := (pyy1 + 91881*pcr1) >> 8
+							pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+							pbu := (pyy1 + 116130*pcb1) >> 8
+							if pru < 0 {
+								pru = 0
+							} else if pru > 0xffff {
+								pru = 0xffff
+							}
+							if pgu < 0 {
+								pgu = 0
+							} else if pgu > 0xffff {
+								pgu = 0xffff
+							}
+							if pbu < 0 {
+								pbu = 0
+							} else if pbu > 0xffff {
+								pbu = 0xffff
+							}
+
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
 			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
+
+func (q *Kernel) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s11j := (sy1-src.Rect.Min.Y)*src.CStride + (sx1 - src.Rect.Min.X)
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.YStride + (kx - src.Rect.Min.X)
+							pj := (ky-src.Rect.Min.Y)*src.CStride + ((kx)/2 - src.Rect.Min.X/2)
+
+							// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+							pyy1 := int(src.Y[pi]) * 0x10101
+							pcb1 := int(src.Cb[pj]) - 128
+							pcr1 := int(src.Cr[pj]) - 128
+							pru := (pyy1 + 91881*pcr1) >> 8
+							pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+							pbu := (pyy1 + 116130*pcb1) >> 8
+							if pru < 0 {
+								pru = 0
+							} else if pru > 0xffff {
+								pru = 0xffff
+							}
+							if pgu < 0 {
+								pgu = 0
+							} else if pgu > 0xffff {
+								pgu = 0xffff
+							}
+							if pbu < 0 {
+								pbu = 0
+							} else if pbu > 0xffff {
+								pbu = 0xffff
+							}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
-			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
-			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
 			}
-
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
 			dst.Pix[d+3] = 0xff
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (q *Kernel) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
-
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
-
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
-			}
-
-			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s00j := (sy0-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
-
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
-			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
-			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
-			}
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s10j := (sy0-src.Rect.Min.Y)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
-			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
-			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
-			}
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s01j := (sy1-src.Rect.Min.Y)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
-			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s11j := (sy1-src.Rect.Min.Y)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.YStride + (kx - src.Rect.Min.X)
+							pj := ((ky)/2-src.Rect.Min.Y/2)*src.CStride + ((kx)/2 - src.Rect.Min.X/2)
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
-			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
-			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
-			}
+							// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+							pyy1 := int(src.Y[pi]) * 0x10101
+							pcb1 := int(src.Cb[pj]) - 128
+							pcr1 := int(src.Cr[pj]) - 128
+							pru := (pyy1 + 91881*pcr1) >> 8
+							pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+							pbu := (pyy1 + 116130*pcb1) >> 8
+							if pru < 0 {
+								pru = 0
+							} else if pru > 0xffff {
+								pru = 0xffff
+							}
+							if pgu < 0 {
+								pgu = 0
+							} else if pgu > 0xffff {
+								pgu = 0xffff
+							}
+							if pbu < 0 {
+								pbu = 0
+							} else if pbu > 0xffff {
+								pbu = 0xffff
+							}
 
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
+			}
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
 			dst.Pix[d+3] = 0xff
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (q *Kernel) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
-			}
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
 
-			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s00j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
-			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
-			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s10j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.YStride + (kx - src.Rect.Min.X)
+							pj := ((ky)/2-src.Rect.Min.Y/2)*src.CStride + (kx - src.Rect.Min.X)
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
-			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
-			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
+							// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
+							pyy1 := int(src.Y[pi]) * 0x10101
+							pcb1 := int(src.Cb[pj]) - 128
+							pcr1 := int(src.Cr[pj]) - 128
+							pru := (pyy1 + 91881*pcr1) >> 8
+							pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
+							pbu := (pyy1 + 116130*pcb1) >> 8
+							if pru < 0 {
+								pru = 0
+							} else if pru > 0xffff {
+								pru = 0xffff
+							}
+							if pgu < 0 {
+								pgu = 0
+							} else if pgu > 0xffff {
+								pgu = 0xffff
+							}
+							if pbu < 0 {
+								pbu = 0
+							} else if pbu > 0xffff {
+								pbu = 0xffff
+							}
+
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
 			}
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = 0xff
+		}
+	}
+}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s01j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + ((sx0)/2 - src.Rect.Min.X/2)
+func (q *Kernel) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
-			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s11j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + ((sx1)/2 - src.Rect.Min.X/2)
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
+			if pr > pa {
+				pr = pa
 			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
+			if pg > pa {
+				pg = pa
 			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+			if pb > pa {
+				pb = pa
 			}
 
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := (0xffff - uint32(pa0)) * 0x101
+			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
+			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
+			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
+			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, opts *Options) {
+func (q *Kernel) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s00i := (sy0-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s00j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s00yy1 := int(src.Y[s00i]) * 0x10101
-			s00cb1 := int(src.Cb[s00j]) - 128
-			s00cr1 := int(src.Cr[s00j]) - 128
-			s00ru := (s00yy1 + 91881*s00cr1) >> 8
-			s00gu := (s00yy1 - 22554*s00cb1 - 46802*s00cr1) >> 8
-			s00bu := (s00yy1 + 116130*s00cb1) >> 8
-			if s00ru < 0 {
-				s00ru = 0
-			} else if s00ru > 0xffff {
-				s00ru = 0xffff
+			if pr > pa {
+				pr = pa
 			}
-			if s00gu < 0 {
-				s00gu = 0
-			} else if s00gu > 0xffff {
-				s00gu = 0xffff
+			if pg > pa {
+				pg = pa
 			}
-			if s00bu < 0 {
-				s00bu = 0
-			} else if s00bu > 0xffff {
-				s00bu = 0xffff
+			if pb > pa {
+				pb = pa
 			}
 
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s10i := (sy0-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s10j := ((sy0)/2-src.Rect.Min.Y/2)*src.CStride + (sx1 - src.Rect.Min.X)
+			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
+			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
+			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
+			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+		}
+	}
+}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s10yy1 := int(src.Y[s10i]) * 0x10101
-			s10cb1 := int(src.Cb[s10j]) - 128
-			s10cr1 := int(src.Cr[s10j]) - 128
-			s10ru := (s10yy1 + 91881*s10cr1) >> 8
-			s10gu := (s10yy1 - 22554*s10cb1 - 46802*s10cr1) >> 8
-			s10bu := (s10yy1 + 116130*s10cb1) >> 8
-			if s10ru < 0 {
-				s10ru = 0
-			} else if s10ru > 0xffff {
-				s10ru = 0xffff
-			}
-			if s10gu < 0 {
-				s10gu = 0
-			} else if s10gu > 0xffff {
-				s10gu = 0xffff
+func (q *Kernel) transform_Gray_CMYK_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			if s10bu < 0 {
-				s10bu = 0
-			} else if s10bu > 0xffff {
-				s10bu = 0xffff
+
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+
+							// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+							pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+							pru := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+							pgu := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+							pbu := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
 			}
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
+		}
+	}
+}
+
+func (q *Kernel) transform_Gray_Gray_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
 
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s01i := (sy1-src.Rect.Min.Y)*src.YStride + (sx0 - src.Rect.Min.X)
-			s01j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + (sx0 - src.Rect.Min.X)
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s01yy1 := int(src.Y[s01i]) * 0x10101
-			s01cb1 := int(src.Cb[s01j]) - 128
-			s01cr1 := int(src.Cr[s01j]) - 128
-			s01ru := (s01yy1 + 91881*s01cr1) >> 8
-			s01gu := (s01yy1 - 22554*s01cb1 - 46802*s01cr1) >> 8
-			s01bu := (s01yy1 + 116130*s01cb1) >> 8
-			if s01ru < 0 {
-				s01ru = 0
-			} else if s01ru > 0xffff {
-				s01ru = 0xffff
-			}
-			if s01gu < 0 {
-				s01gu = 0
-			} else if s01gu > 0xffff {
-				s01gu = 0xffff
-			}
-			if s01bu < 0 {
-				s01bu = 0
-			} else if s01bu > 0xffff {
-				s01bu = 0xffff
-			}
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s11i := (sy1-src.Rect.Min.Y)*src.YStride + (sx1 - src.Rect.Min.X)
-			s11j := ((sy1)/2-src.Rect.Min.Y/2)*src.CStride + (sx1 - src.Rect.Min.X)
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-			s11yy1 := int(src.Y[s11i]) * 0x10101
-			s11cb1 := int(src.Cb[s11j]) - 128
-			s11cr1 := int(src.Cr[s11j]) - 128
-			s11ru := (s11yy1 + 91881*s11cr1) >> 8
-			s11gu := (s11yy1 - 22554*s11cb1 - 46802*s11cr1) >> 8
-			s11bu := (s11yy1 + 116130*s11cb1) >> 8
-			if s11ru < 0 {
-				s11ru = 0
-			} else if s11ru > 0xffff {
-				s11ru = 0xffff
-			}
-			if s11gu < 0 {
-				s11gu = 0
-			} else if s11gu > 0xffff {
-				s11gu = 0xffff
-			}
-			if s11bu < 0 {
-				s11bu = 0
-			} else if s11bu > 0xffff {
-				s11bu = 0xffff
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = 0xff
+			var pr float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx - src.Rect.Min.X)
+							pru := uint32(src.Pix[pi]) * 0x101
+							pr += float64(pru) * w
+						}
+					}
+				}
+			}
+			dst.Pix[d] = uint8(fffftou(pr) >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+func (q *Kernel) transform_Gray_Gray16_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			pa1 := (0xffff - pa) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa) >> 8)
+			var pr float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*2
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pr += float64(pru) * w
+						}
+					}
+				}
+			}
+			dst.Pix[d] = uint8(fffftou(pr) >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
+func (q *Kernel) transform_Gray_Image_Over(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
 
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
 			}
 
-			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			dst.Pix[d+0] = uint8(pr >> 8)
-			dst.Pix[d+1] = uint8(pg >> 8)
-			dst.Pix[d+2] = uint8(pb >> 8)
-			dst.Pix[d+3] = uint8(pa >> 8)
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			pa1 := 0xffff - pa0
+			dst.Pix[d] = uint8((uint32(dst.Pix[d])*0x101*pa1/0xffff + py) >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
+func (q *Kernel) transform_Gray_Image_Src(dst *image.Gray, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X + adr.Min.X - dst.Rect.Min.X)
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+1 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
-			}
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
 
-			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
-				s00ru = s00ru * ma / 0xffff
-				s00gu = s00gu * ma / 0xffff
-				s00bu = s00bu * ma / 0xffff
-				s00au = s00au * ma / 0xffff
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy0).RGBA()
-				s10ru = s10ru * ma / 0xffff
-				s10gu = s10gu * ma / 0xffff
-				s10bu = s10bu * ma / 0xffff
-				s10au = s10au * ma / 0xffff
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy1).RGBA()
-				s01ru = s01ru * ma / 0xffff
-				s01gu = s01gu * ma / 0xffff
-				s01bu = s01bu * ma / 0xffff
-				s01au = s01au * ma / 0xffff
+
+			if pr > pa {
+				pr = pa
 			}
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy1).RGBA()
-				s11ru = s11ru * ma / 0xffff
-				s11gu = s11gu * ma / 0xffff
-				s11bu = s11bu * ma / 0xffff
-				s11au = s11au * ma / 0xffff
+			if pg > pa {
+				pg = pa
 			}
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
-			if dstMask != nil {
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
+			if pb > pa {
+				pb = pa
 			}
-			pa1 := 0xffff - pa
-			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			dst.Pix[d] = uint8(py >> 8)
 		}
 	}
 }
 
-func (ablInterpolator) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, opts *Options) {
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
+func (q *Kernel) transform_Gray16_CMYK_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.CMYK, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			sx -= 0.5
-			sx0 := int(sx)
-			xFrac0 := sx - float64(sx0)
-			xFrac1 := 1 - xFrac0
-			sx0 += bias.X
-			sx1 := sx0 + 1
-			if sx0 < sr.Min.X {
-				sx0, sx1 = sr.Min.X, sr.Min.X
-				xFrac0, xFrac1 = 0, 1
-			} else if sx1 >= sr.Max.X {
-				sx0, sx1 = sr.Max.X-1, sr.Max.X-1
-				xFrac0, xFrac1 = 1, 0
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			sy -= 0.5
-			sy0 := int(sy)
-			yFrac0 := sy - float64(sy0)
-			yFrac1 := 1 - yFrac0
-			sy0 += bias.Y
-			sy1 := sy0 + 1
-			if sy0 < sr.Min.Y {
-				sy0, sy1 = sr.Min.Y, sr.Min.Y
-				yFrac0, yFrac1 = 0, 1
-			} else if sy1 >= sr.Max.Y {
-				sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
-				yFrac0, yFrac1 = 1, 0
-			}
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
 
-			s00ru, s00gu, s00bu, s00au := src.At(sx0, sy0).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy0).RGBA()
-				s00ru = s00ru * ma / 0xffff
-				s00gu = s00gu * ma / 0xffff
-				s00bu = s00bu * ma / 0xffff
-				s00au = s00au * ma / 0xffff
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			s00r := float64(s00ru)
-			s00g := float64(s00gu)
-			s00b := float64(s00bu)
-			s00a := float64(s00au)
-			s10ru, s10gu, s10bu, s10au := src.At(sx1, sy0).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy0).RGBA()
-				s10ru = s10ru * ma / 0xffff
-				s10gu = s10gu * ma / 0xffff
-				s10bu = s10bu * ma / 0xffff
-				s10au = s10au * ma / 0xffff
+
+			var pr, pg, pb float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+
+							// This is an inline version of image/color/ycbcr.go's CMYK.RGBA method.
+							pw := 0xffff - uint32(src.Pix[pi+3])*0x101
+							pru := (0xffff - uint32(src.Pix[pi+0])*0x101) * pw / 0xffff
+							pgu := (0xffff - uint32(src.Pix[pi+1])*0x101) * pw / 0xffff
+							pbu := (0xffff - uint32(src.Pix[pi+2])*0x101) * pw / 0xffff
+
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+						}
+					}
+				}
 			}
-			s10r := float64(s10ru)
-			s10g := float64(s10gu)
-			s10b := float64(s10bu)
-			s10a := float64(s10au)
-			s10r = xFrac1*s00r + xFrac0*s10r
-			s10g = xFrac1*s00g + xFrac0*s10g
-			s10b = xFrac1*s00b + xFrac0*s10b
-			s10a = xFrac1*s00a + xFrac0*s10a
-			s01ru, s01gu, s01bu, s01au := src.At(sx0, sy1).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx0, smp.Y+sy1).RGBA()
-				s01ru = s01ru * ma / 0xffff
-				s01gu = s01gu * ma / 0xffff
-				s01bu = s01bu * ma / 0xffff
-				s01au = s01au * ma / 0xffff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (q *Kernel) transform_Gray16_Gray_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			s01r := float64(s01ru)
-			s01g := float64(s01gu)
-			s01b := float64(s01bu)
-			s01a := float64(s01au)
-			s11ru, s11gu, s11bu, s11au := src.At(sx1, sy1).RGBA()
-			if srcMask != nil {
-				_, _, _, ma := srcMask.At(smp.X+sx1, smp.Y+sy1).RGBA()
-				s11ru = s11ru * ma / 0xffff
-				s11gu = s11gu * ma / 0xffff
-				s11bu = s11bu * ma / 0xffff
-				s11au = s11au * ma / 0xffff
+
+			var pr float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx - src.Rect.Min.X)
+							pru := uint32(src.Pix[pi]) * 0x101
+							pr += float64(pru) * w
+						}
+					}
+				}
 			}
-			s11r := float64(s11ru)
-			s11g := float64(s11gu)
-			s11b := float64(s11bu)
-			s11a := float64(s11au)
-			s11r = xFrac1*s01r + xFrac0*s11r
-			s11g = xFrac1*s01g + xFrac0*s11g
-			s11b = xFrac1*s01b + xFrac0*s11b
-			s11a = xFrac1*s01a + xFrac0*s11a
-			s11r = yFrac1*s10r + yFrac0*s11r
-			s11g = yFrac1*s10g + yFrac0*s11g
-			s11b = yFrac1*s10b + yFrac0*s11b
-			s11a = yFrac1*s10a + yFrac0*s11a
-			pr := uint32(s11r)
-			pg := uint32(s11g)
-			pb := uint32(s11b)
-			pa := uint32(s11a)
-			if dstMask != nil {
-				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(dy)).RGBA()
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(dy)).RGBA()
-				pr = pr * ma / 0xffff
-				pg = pg * ma / 0xffff
-				pb = pb * ma / 0xffff
-				pa = pa * ma / 0xffff
-				pa1 := 0xffff - ma
-				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+			py := fffftou(pr)
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
+		}
+	}
+}
+
+func (q *Kernel) transform_Gray16_Gray16_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray16, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
 			} else {
-				dstColorRGBA64.R = uint16(pr)
-				dstColorRGBA64.G = uint16(pg)
-				dstColorRGBA64.B = uint16(pb)
-				dstColorRGBA64.A = uint16(pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(dy), dstColor)
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*2
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pr += float64(pru) * w
+						}
+					}
+				}
 			}
+			py := fffftou(pr)
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
 		}
 	}
 }
 
-func (z *kernelScaler) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
-	if z.dw != int32(dr.Dx()) || z.dh != int32(dr.Dy()) || z.sw != int32(sr.Dx()) || z.sh != int32(sr.Dy()) {
-		z.kernel.Scale(dst, dr, src, sr, op, opts)
-		return
+func (q *Kernel) transform_Gray16_Image_Over(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
 	}
-
-	var o Options
-	if opts != nil {
-		o = *opts
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
 	}
 
-	// adr is the affected destination pixels.
-	adr := dst.Bounds().Intersect(dr)
-	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
-	if adr.Empty() || sr.Empty() {
-		return
-	}
-	// Make adr relative to dr.Min.
-	adr = adr.Sub(dr.Min)
-	if op == Over && o.SrcMask == nil && opaque(src) {
-		op = Src
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
 	}
 
-	if _, ok := src.(*image.Uniform); ok && o.DstMask == nil && o.SrcMask == nil && sr.In(src.Bounds()) {
-		Draw(dst, dr, src, src.Bounds().Min, op)
-		return
-	}
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-	// Create a temporary buffer:
-	// scaleX distributes the source image's columns over the temporary image.
-	// scaleY distributes the temporary image's rows over the destination image.
-	var tmp [][4]float64
-	if z.pool.New != nil {
-		tmpp := z.pool.Get().(*[][4]float64)
-		defer z.pool.Put(tmpp)
-		tmp = *tmpp
-	} else {
-		tmp = z.makeTmpBuf()
-	}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-	// sr is the source pixels. If it extends beyond the src bounds,
-	// we cannot use the type-specific fast paths, as they access
-	// the Pix fields directly without bounds checking.
-	//
-	// Similarly, the fast paths assume that the masks are nil.
-	if o.SrcMask != nil || !sr.In(src.Bounds()) {
-		z.scaleX_Image(tmp, src, sr, &o)
-	} else {
-		switch src := src.(type) {
-		case *image.Gray:
-			z.scaleX_Gray(tmp, src, sr, &o)
-		case *image.NRGBA:
-			z.scaleX_NRGBA(tmp, src, sr, &o)
-		case *image.RGBA:
-			z.scaleX_RGBA(tmp, src, sr, &o)
-		case *image.YCbCr:
-			switch src.SubsampleRatio {
-			default:
-				z.scaleX_Image(tmp, src, sr, &o)
-			case image.YCbCrSubsampleRatio444:
-				z.scaleX_YCbCr444(tmp, src, sr, &o)
-			case image.YCbCrSubsampleRatio422:
-				z.scaleX_YCbCr422(tmp, src, sr, &o)
-			case image.YCbCrSubsampleRatio420:
-				z.scaleX_YCbCr420(tmp, src, sr, &o)
-			case image.YCbCrSubsampleRatio440:
-				z.scaleX_YCbCr440(tmp, src, sr, &o)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-		default:
-			z.scaleX_Image(tmp, src, sr, &o)
-		}
-	}
 
-	if o.DstMask != nil {
-		switch op {
-		case Over:
-			z.scaleY_Image_Over(dst, dr, adr, tmp, &o)
-		case Src:
-			z.scaleY_Image_Src(dst, dr, adr, tmp, &o)
-		}
-	} else {
-		switch op {
-		case Over:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				z.scaleY_RGBA_Over(dst, dr, adr, tmp, &o)
-			default:
-				z.scaleY_Image_Over(dst, dr, adr, tmp, &o)
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
-		case Src:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				z.scaleY_RGBA_Src(dst, dr, adr, tmp, &o)
-			default:
-				z.scaleY_Image_Src(dst, dr, adr, tmp, &o)
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
 			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			pa1 := 0xffff - pa0
+			qy := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			out := qy*pa1/0xffff + py
+			dst.Pix[d+0] = uint8(out >> 8)
+			dst.Pix[d+1] = uint8(out)
 		}
 	}
 }
 
-func (q *Kernel) Transform(dst Image, s2d f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
-	var o Options
-	if opts != nil {
-		o = *opts
-	}
-
-	dr := transformRect(&s2d, &sr)
-	// adr is the affected destination pixels.
-	adr := dst.Bounds().Intersect(dr)
-	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
-	if adr.Empty() || sr.Empty() {
-		return
+func (q *Kernel) transform_Gray16_Image_Src(dst *image.Gray16, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
 	}
-	if op == Over && o.SrcMask == nil && opaque(src) {
-		op = Src
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
 	}
-	d2s := invert(&s2d)
-	// bias is a translation of the mapping from dst coordinates to src
-	// coordinates such that the latter temporarily have non-negative X
-	// and Y coordinates. This allows us to write int(f) instead of
-	// int(math.Floor(f)), since "round to zero" and "round down" are
-	// equivalent when f >= 0, but the former is much cheaper. The X--
-	// and Y-- are because the TransformLeaf methods have a "sx -= 0.5"
-	// adjustment.
-	bias := transformRect(&d2s, &adr).Min
-	bias.X--
-	bias.Y--
-	d2s[2] -= float64(bias.X)
-	d2s[5] -= float64(bias.Y)
-	// Make adr relative to dr.Min.
-	adr = adr.Sub(dr.Min)
 
-	if u, ok := src.(*image.Uniform); ok && o.DstMask != nil && o.SrcMask != nil && sr.In(src.Bounds()) {
-		transform_Uniform(dst, dr, adr, &d2s, u, sr, bias, op)
-		return
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
 	}
 
-	xscale := abs(d2s[0])
-	if s := abs(d2s[1]); xscale < s {
-		xscale = s
-	}
-	yscale := abs(d2s[3])
-	if s := abs(d2s[4]); yscale < s {
-		yscale = s
-	}
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*2
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+2 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-	// sr is the source pixels. If it extends beyond the src bounds,
-	// we cannot use the type-specific fast paths, as they access
-	// the Pix fields directly without bounds checking.
-	//
-	// Similarly, the fast paths assume that the masks are nil.
-	if o.DstMask != nil || o.SrcMask != nil || !sr.In(src.Bounds()) {
-		switch op {
-		case Over:
-			q.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-		case Src:
-			q.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-		}
-	} else {
-		switch op {
-		case Over:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				switch src := src.(type) {
-				case *image.NRGBA:
-					q.transform_RGBA_NRGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-				case *image.RGBA:
-					q.transform_RGBA_RGBA_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-				default:
-					q.transform_RGBA_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
 				}
-			default:
-				switch src := src.(type) {
-				default:
-					q.transform_Image_Image_Over(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
 				}
-			}
-		case Src:
-			switch dst := dst.(type) {
-			case *image.RGBA:
-				switch src := src.(type) {
-				case *image.Gray:
-					q.transform_RGBA_Gray_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-				case *image.NRGBA:
-					q.transform_RGBA_NRGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-				case *image.RGBA:
-					q.transform_RGBA_RGBA_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-				case *image.YCbCr:
-					switch src.SubsampleRatio {
-					default:
-						q.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-					case image.YCbCrSubsampleRatio444:
-						q.transform_RGBA_YCbCr444_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-					case image.YCbCrSubsampleRatio422:
-						q.transform_RGBA_YCbCr422_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-					case image.YCbCrSubsampleRatio420:
-						q.transform_RGBA_YCbCr420_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
-					case image.YCbCrSubsampleRatio440:
-						q.transform_RGBA_YCbCr440_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
 					}
-				default:
-					q.transform_RGBA_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
 				}
-			default:
-				switch src := src.(type) {
-				default:
-					q.transform_Image_Image_Src(dst, dr, adr, &d2s, src, sr, bias, xscale, yscale, &o)
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-			}
-		}
-	}
-}
 
-func (z *kernelScaler) scaleX_Gray(tmp [][4]float64, src *image.Gray, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
-			var pr float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
-				pru := uint32(src.Pix[pi]) * 0x101
-				pr += float64(pru) * c.weight
-			}
-			pr *= s.invTotalWeightFFFF
-			tmp[t] = [4]float64{
-				pr,
-				pr,
-				pr,
-				1,
-			}
-			t++
-		}
-	}
-}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-func (z *kernelScaler) scaleX_NRGBA(tmp [][4]float64, src *image.NRGBA, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
-			var pr, pg, pb, pa float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*4
-				pau := uint32(src.Pix[pi+3]) * 0x101
-				pru := uint32(src.Pix[pi+0]) * pau / 0xff
-				pgu := uint32(src.Pix[pi+1]) * pau / 0xff
-				pbu := uint32(src.Pix[pi+2]) * pau / 0xff
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
-				pa += float64(pau) * c.weight
-			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				pa * s.invTotalWeightFFFF,
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			t++
-		}
-	}
-}
 
-func (z *kernelScaler) scaleX_RGBA(tmp [][4]float64, src *image.RGBA, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
 			var pr, pg, pb, pa float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.Stride + (sr.Min.X+int(c.coord)-src.Rect.Min.X)*4
-				pru := uint32(src.Pix[pi+0]) * 0x101
-				pgu := uint32(src.Pix[pi+1]) * 0x101
-				pbu := uint32(src.Pix[pi+2]) * 0x101
-				pau := uint32(src.Pix[pi+3]) * 0x101
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
-				pa += float64(pau) * c.weight
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				pa * s.invTotalWeightFFFF,
+
+			if pr > pa {
+				pr = pa
 			}
-			t++
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			py := (19595*pr0 + 38470*pg0 + 7471*pb0 + 1<<15) >> 16
+			dst.Pix[d+0] = uint8(py >> 8)
+			dst.Pix[d+1] = uint8(py)
 		}
 	}
 }
 
-func (z *kernelScaler) scaleX_YCbCr444(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
-			var pr, pg, pb float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
-				pj := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.CStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+func (q *Kernel) transform_NRGBA_NRGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
 
-				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-				pyy1 := int(src.Y[pi]) * 0x10101
-				pcb1 := int(src.Cb[pj]) - 128
-				pcr1 := int(src.Cr[pj]) - 128
-				pru := (pyy1 + 91881*pcr1) >> 8
-				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
-				pbu := (pyy1 + 116130*pcb1) >> 8
-				if pru < 0 {
-					pru = 0
-				} else if pru > 0xffff {
-					pru = 0xffff
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
 				}
-				if pgu < 0 {
-					pgu = 0
-				} else if pgu > 0xffff {
-					pgu = 0xffff
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
 				}
-				if pbu < 0 {
-					pbu = 0
-				} else if pbu > 0xffff {
-					pbu = 0xffff
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
 				}
 
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
-			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				1,
-			}
-			t++
-		}
-	}
-}
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
 
-func (z *kernelScaler) scaleX_YCbCr422(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
-			var pr, pg, pb float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
-				pj := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.CStride + ((sr.Min.X+int(c.coord))/2 - src.Rect.Min.X/2)
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-				pyy1 := int(src.Y[pi]) * 0x10101
-				pcb1 := int(src.Cb[pj]) - 128
-				pcr1 := int(src.Cr[pj]) - 128
-				pru := (pyy1 + 91881*pcr1) >> 8
-				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
-				pbu := (pyy1 + 116130*pcb1) >> 8
-				if pru < 0 {
-					pru = 0
-				} else if pru > 0xffff {
-					pru = 0xffff
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
 				}
-				if pgu < 0 {
-					pgu = 0
-				} else if pgu > 0xffff {
-					pgu = 0xffff
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				if pbu < 0 {
-					pbu = 0
-				} else if pbu > 0xffff {
-					pbu = 0xffff
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pru := uint32(src.Pix[pi+0]) * pau / 0xff
+							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
 				}
+			}
 
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
+			if pr > pa {
+				pr = pa
 			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				1,
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := 0xffff - pa0
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, qa*pa1/0xffff+pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-			t++
 		}
 	}
 }
 
-func (z *kernelScaler) scaleX_YCbCr420(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
-			var pr, pg, pb float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
-				pj := ((sr.Min.Y+int(y))/2-src.Rect.Min.Y/2)*src.CStride + ((sr.Min.X+int(c.coord))/2 - src.Rect.Min.X/2)
+func (q *Kernel) transform_NRGBA_NRGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
 
-				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-				pyy1 := int(src.Y[pi]) * 0x10101
-				pcb1 := int(src.Cb[pj]) - 128
-				pcr1 := int(src.Cr[pj]) - 128
-				pru := (pyy1 + 91881*pcr1) >> 8
-				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
-				pbu := (pyy1 + 116130*pcb1) >> 8
-				if pru < 0 {
-					pru = 0
-				} else if pru > 0xffff {
-					pru = 0xffff
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
 				}
-				if pgu < 0 {
-					pgu = 0
-				} else if pgu > 0xffff {
-					pgu = 0xffff
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
 				}
-				if pbu < 0 {
-					pbu = 0
-				} else if pbu > 0xffff {
-					pbu = 0xffff
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
 				}
 
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
+			var pr, pg, pb, pa float64
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pru := uint32(src.Pix[pi+0]) * pau / 0xff
+							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
+			}
+
+			if pr > pa {
+				pr = pa
 			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				1,
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-			t++
 		}
 	}
 }
 
-func (z *kernelScaler) scaleX_YCbCr440(tmp [][4]float64, src *image.YCbCr, sr image.Rectangle, opts *Options) {
-	t := 0
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
-			var pr, pg, pb float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pi := (sr.Min.Y+int(y)-src.Rect.Min.Y)*src.YStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
-				pj := ((sr.Min.Y+int(y))/2-src.Rect.Min.Y/2)*src.CStride + (sr.Min.X + int(c.coord) - src.Rect.Min.X)
+func (q *Kernel) transform_NRGBA_RGBA_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
 
-				// This is an inline version of image/color/ycbcr.go's YCbCr.RGBA method.
-				pyy1 := int(src.Y[pi]) * 0x10101
-				pcb1 := int(src.Cb[pj]) - 128
-				pcr1 := int(src.Cr[pj]) - 128
-				pru := (pyy1 + 91881*pcr1) >> 8
-				pgu := (pyy1 - 22554*pcb1 - 46802*pcr1) >> 8
-				pbu := (pyy1 + 116130*pcb1) >> 8
-				if pru < 0 {
-					pru = 0
-				} else if pru > 0xffff {
-					pru = 0xffff
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
 				}
-				if pgu < 0 {
-					pgu = 0
-				} else if pgu > 0xffff {
-					pgu = 0xffff
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
 				}
-				if pbu < 0 {
-					pbu = 0
-				} else if pbu > 0xffff {
-					pbu = 0xffff
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
 				}
 
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
-			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				1,
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
 			}
-			t++
-		}
-	}
-}
 
-func (z *kernelScaler) scaleX_Image(tmp [][4]float64, src image.Image, sr image.Rectangle, opts *Options) {
-	t := 0
-	srcMask, smp := opts.SrcMask, opts.SrcMaskP
-	for y := int32(0); y < z.sh; y++ {
-		for _, s := range z.horizontal.sources {
 			var pr, pg, pb, pa float64
-			for _, c := range z.horizontal.contribs[s.i:s.j] {
-				pru, pgu, pbu, pau := src.At(sr.Min.X+int(c.coord), sr.Min.Y+int(y)).RGBA()
-				if srcMask != nil {
-					_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(c.coord), smp.Y+sr.Min.Y+int(y)).RGBA()
-					pru = pru * ma / 0xffff
-					pgu = pgu * ma / 0xffff
-					pbu = pbu * ma / 0xffff
-					pau = pau * ma / 0xffff
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pru := uint32(src.Pix[pi+0]) * 0x101
+							pgu := uint32(src.Pix[pi+1]) * 0x101
+							pbu := uint32(src.Pix[pi+2]) * 0x101
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
 				}
-				pr += float64(pru) * c.weight
-				pg += float64(pgu) * c.weight
-				pb += float64(pbu) * c.weight
-				pa += float64(pau) * c.weight
 			}
-			tmp[t] = [4]float64{
-				pr * s.invTotalWeightFFFF,
-				pg * s.invTotalWeightFFFF,
-				pb * s.invTotalWeightFFFF,
-				pa * s.invTotalWeightFFFF,
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := 0xffff - pa0
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, qa*pa1/0xffff+pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-			t++
 		}
 	}
 }
 
-func (z *kernelScaler) scaleY_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
-	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*4
-		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+func (q *Kernel) transform_NRGBA_RGBA_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
 			var pr, pg, pb, pa float64
-			for _, c := range z.vertical.contribs[s.i:s.j] {
-				p := &tmp[c.coord*z.dw+dx]
-				pr += p[0] * c.weight
-				pg += p[1] * c.weight
-				pb += p[2] * c.weight
-				pa += p[3] * c.weight
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pru := uint32(src.Pix[pi+0]) * 0x101
+							pgu := uint32(src.Pix[pi+1]) * 0x101
+							pbu := uint32(src.Pix[pi+2]) * 0x101
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
 
 			if pr > pa {
@@ -4963,31 +18358,131 @@ func (z *kernelScaler) scaleY_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle
 				pb = pa
 			}
 
-			pr0 := uint32(ftou(pr * s.invTotalWeight))
-			pg0 := uint32(ftou(pg * s.invTotalWeight))
-			pb0 := uint32(ftou(pb * s.invTotalWeight))
-			pa0 := uint32(ftou(pa * s.invTotalWeight))
-			pa1 := (0xffff - uint32(pa0)) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
-			d += dst.Stride
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
 		}
 	}
 }
 
-func (z *kernelScaler) scaleY_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
-	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-		d := (dr.Min.Y+adr.Min.Y-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+int(dx)-dst.Rect.Min.X)*4
-		for _, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+func (q *Kernel) transform_NRGBA_Image_Over(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
 			var pr, pg, pb, pa float64
-			for _, c := range z.vertical.contribs[s.i:s.j] {
-				p := &tmp[c.coord*z.dw+dx]
-				pr += p[0] * c.weight
-				pg += p[1] * c.weight
-				pb += p[2] * c.weight
-				pa += p[3] * c.weight
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
 
 			if pr > pa {
@@ -5000,28 +18495,136 @@ func (z *kernelScaler) scaleY_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle,
 				pb = pa
 			}
 
-			dst.Pix[d+0] = uint8(ftou(pr*s.invTotalWeight) >> 8)
-			dst.Pix[d+1] = uint8(ftou(pg*s.invTotalWeight) >> 8)
-			dst.Pix[d+2] = uint8(ftou(pb*s.invTotalWeight) >> 8)
-			dst.Pix[d+3] = uint8(ftou(pa*s.invTotalWeight) >> 8)
-			d += dst.Stride
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := 0xffff - pa0
+			qa := uint32(dst.Pix[d+3]) * 0x101
+			qr := uint32(dst.Pix[d+0]) * qa / 0xff
+			qg := uint32(dst.Pix[d+1]) * qa / 0xff
+			qb := uint32(dst.Pix[d+2]) * qa / 0xff
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, qa*pa1/0xffff+pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
+			}
 		}
 	}
 }
 
-func (z *kernelScaler) scaleY_Image_Over(dst Image, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
-	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-		for dy, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+func (q *Kernel) transform_NRGBA_Image_Src(dst *image.NRGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
 			var pr, pg, pb, pa float64
-			for _, c := range z.vertical.contribs[s.i:s.j] {
-				p := &tmp[c.coord*z.dw+dx]
-				pr += p[0] * c.weight
-				pg += p[1] * c.weight
-				pb += p[2] * c.weight
-				pa += p[3] * c.weight
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pru, pgu, pbu, pau := src.At(kx, ky).RGBA()
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
 
 			if pr > pa {
@@ -5034,41 +18637,135 @@ func (z *kernelScaler) scaleY_Image_Over(dst Image, dr, adr image.Rectangle, tmp
 				pb = pa
 			}
 
-			qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
-			pr0 := uint32(ftou(pr * s.invTotalWeight))
-			pg0 := uint32(ftou(pg * s.invTotalWeight))
-			pb0 := uint32(ftou(pb * s.invTotalWeight))
-			pa0 := uint32(ftou(pa * s.invTotalWeight))
-			if dstMask != nil {
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
-				pr0 = pr0 * ma / 0xffff
-				pg0 = pg0 * ma / 0xffff
-				pb0 = pb0 * ma / 0xffff
-				pa0 = pa0 * ma / 0xffff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, pa0
+			if oa == 0 {
+				dst.Pix[d+0] = 0
+				dst.Pix[d+1] = 0
+				dst.Pix[d+2] = 0
+				dst.Pix[d+3] = 0
+			} else {
+				dst.Pix[d+0] = uint8((or * 0xffff / oa) >> 8)
+				dst.Pix[d+1] = uint8((og * 0xffff / oa) >> 8)
+				dst.Pix[d+2] = uint8((ob * 0xffff / oa) >> 8)
+				dst.Pix[d+3] = uint8(oa >> 8)
 			}
-			pa1 := 0xffff - pa0
-			dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr0)
-			dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg0)
-			dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb0)
-			dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa0)
-			dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy), dstColor)
 		}
 	}
 }
 
-func (z *kernelScaler) scaleY_Image_Src(dst Image, dr, adr image.Rectangle, tmp [][4]float64, opts *Options) {
-	dstMask, dmp := opts.DstMask, opts.DstMaskP
-	dstColorRGBA64 := &color.RGBA64{}
-	dstColor := color.Color(dstColorRGBA64)
-	for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-		for dy, s := range z.vertical.sources[adr.Min.Y:adr.Max.Y] {
+func (q *Kernel) transform_RGBA64_NRGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
+	// When shrinking, broaden the effective kernel support so that we still
+	// visit every source pixel.
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
+
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
+
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
+
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
+				}
+
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
+
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
+				}
+			}
+
 			var pr, pg, pb, pa float64
-			for _, c := range z.vertical.contribs[s.i:s.j] {
-				p := &tmp[c.coord*z.dw+dx]
-				pr += p[0] * c.weight
-				pg += p[1] * c.weight
-				pb += p[2] * c.weight
-				pa += p[3] * c.weight
+			for ky := iy; ky < jy; ky++ {
+				if yWeight := yWeights[ky-iy]; yWeight != 0 {
+					for kx := ix; kx < jx; kx++ {
+						if w := xWeights[kx-ix] * yWeight; w != 0 {
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pru := uint32(src.Pix[pi+0]) * pau / 0xff
+							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
+							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
+						}
+					}
+				}
 			}
 
 			if pr > pa {
@@ -5081,31 +18778,29 @@ func (z *kernelScaler) scaleY_Image_Src(dst Image, dr, adr image.Rectangle, tmp
 				pb = pa
 			}
 
-			if dstMask != nil {
-				qr, qg, qb, qa := dst.At(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
-				_, _, _, ma := dstMask.At(dmp.X+dr.Min.X+int(dx), dmp.Y+dr.Min.Y+int(adr.Min.Y+dy)).RGBA()
-				pr := uint32(ftou(pr*s.invTotalWeight)) * ma / 0xffff
-				pg := uint32(ftou(pg*s.invTotalWeight)) * ma / 0xffff
-				pb := uint32(ftou(pb*s.invTotalWeight)) * ma / 0xffff
-				pa := uint32(ftou(pa*s.invTotalWeight)) * ma / 0xffff
-				pa1 := 0xffff - ma
-				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr)
-				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg)
-				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb)
-				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy), dstColor)
-			} else {
-				dstColorRGBA64.R = ftou(pr * s.invTotalWeight)
-				dstColorRGBA64.G = ftou(pg * s.invTotalWeight)
-				dstColorRGBA64.B = ftou(pb * s.invTotalWeight)
-				dstColorRGBA64.A = ftou(pa * s.invTotalWeight)
-				dst.Set(dr.Min.X+int(dx), dr.Min.Y+int(adr.Min.Y+dy), dstColor)
-			}
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			pa1 := 0xffff - pa0
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, uint32(qa*pa1/0xffff+pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.Gray, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_NRGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5119,92 +18814,131 @@ func (q *Kernel) transform_RGBA_Gray_Src(dst *image.RGBA, dr, adr image.Rectangl
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
-			var pr float64
+			var pr, pg, pb, pa float64
 			for ky := iy; ky < jy; ky++ {
 				if yWeight := yWeights[ky-iy]; yWeight != 0 {
 					for kx := ix; kx < jx; kx++ {
 						if w := xWeights[kx-ix] * yWeight; w != 0 {
-							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx - src.Rect.Min.X)
-							pru := uint32(src.Pix[pi]) * 0x101
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pau := uint32(src.Pix[pi+3]) * 0x101
+							pru := uint32(src.Pix[pi+0]) * pau / 0xff
+							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
+							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
 							pr += float64(pru) * w
+							pg += float64(pgu) * w
+							pb += float64(pbu) * w
+							pa += float64(pau) * w
 						}
 					}
 				}
 			}
-			out := uint8(fffftou(pr) >> 8)
-			dst.Pix[d+0] = out
-			dst.Pix[d+1] = out
-			dst.Pix[d+2] = out
-			dst.Pix[d+3] = 0xff
+
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_RGBA_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5218,68 +18952,82 @@ func (q *Kernel) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectan
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
-	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
-		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
+		dyf := float64(dr.Min.Y+int(dy)) + 0.5
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -5288,10 +19036,10 @@ func (q *Kernel) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectan
 					for kx := ix; kx < jx; kx++ {
 						if w := xWeights[kx-ix] * yWeight; w != 0 {
 							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pru := uint32(src.Pix[pi+0]) * 0x101
+							pgu := uint32(src.Pix[pi+1]) * 0x101
+							pbu := uint32(src.Pix[pi+2]) * 0x101
 							pau := uint32(src.Pix[pi+3]) * 0x101
-							pru := uint32(src.Pix[pi+0]) * pau / 0xff
-							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
-							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
 							pr += float64(pru) * w
 							pg += float64(pgu) * w
 							pb += float64(pbu) * w
@@ -5315,16 +19063,25 @@ func (q *Kernel) transform_RGBA_NRGBA_Over(dst *image.RGBA, dr, adr image.Rectan
 			pg0 := uint32(fffftou(pg))
 			pb0 := uint32(fffftou(pb))
 			pa0 := uint32(fffftou(pa))
-			pa1 := (0xffff - uint32(pa0)) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+			pa1 := 0xffff - pa0
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, uint32(qa*pa1/0xffff+pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.NRGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_RGBA_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5338,68 +19095,82 @@ func (q *Kernel) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectang
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -5408,10 +19179,10 @@ func (q *Kernel) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectang
 					for kx := ix; kx < jx; kx++ {
 						if w := xWeights[kx-ix] * yWeight; w != 0 {
 							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
+							pru := uint32(src.Pix[pi+0]) * 0x101
+							pgu := uint32(src.Pix[pi+1]) * 0x101
+							pbu := uint32(src.Pix[pi+2]) * 0x101
 							pau := uint32(src.Pix[pi+3]) * 0x101
-							pru := uint32(src.Pix[pi+0]) * pau / 0xff
-							pgu := uint32(src.Pix[pi+1]) * pau / 0xff
-							pbu := uint32(src.Pix[pi+2]) * pau / 0xff
 							pr += float64(pru) * w
 							pg += float64(pgu) * w
 							pb += float64(pbu) * w
@@ -5431,15 +19202,24 @@ func (q *Kernel) transform_RGBA_NRGBA_Src(dst *image.RGBA, dr, adr image.Rectang
 				pb = pa
 			}
 
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_RGBA64_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5453,68 +19233,82 @@ func (q *Kernel) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectang
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -5522,11 +19316,11 @@ func (q *Kernel) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectang
 				if yWeight := yWeights[ky-iy]; yWeight != 0 {
 					for kx := ix; kx < jx; kx++ {
 						if w := xWeights[kx-ix] * yWeight; w != 0 {
-							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
-							pru := uint32(src.Pix[pi+0]) * 0x101
-							pgu := uint32(src.Pix[pi+1]) * 0x101
-							pbu := uint32(src.Pix[pi+2]) * 0x101
-							pau := uint32(src.Pix[pi+3]) * 0x101
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*8
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pgu := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+							pbu := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+							pau := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
 							pr += float64(pru) * w
 							pg += float64(pgu) * w
 							pb += float64(pbu) * w
@@ -5550,16 +19344,25 @@ func (q *Kernel) transform_RGBA_RGBA_Over(dst *image.RGBA, dr, adr image.Rectang
 			pg0 := uint32(fffftou(pg))
 			pb0 := uint32(fffftou(pb))
 			pa0 := uint32(fffftou(pa))
-			pa1 := (0xffff - uint32(pa0)) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+			pa1 := 0xffff - pa0
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, uint32(qa*pa1/0xffff+pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_RGBA64_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.RGBA64, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5573,68 +19376,82 @@ func (q *Kernel) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangl
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -5642,11 +19459,11 @@ func (q *Kernel) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangl
 				if yWeight := yWeights[ky-iy]; yWeight != 0 {
 					for kx := ix; kx < jx; kx++ {
 						if w := xWeights[kx-ix] * yWeight; w != 0 {
-							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*4
-							pru := uint32(src.Pix[pi+0]) * 0x101
-							pgu := uint32(src.Pix[pi+1]) * 0x101
-							pbu := uint32(src.Pix[pi+2]) * 0x101
-							pau := uint32(src.Pix[pi+3]) * 0x101
+							pi := (ky-src.Rect.Min.Y)*src.Stride + (kx-src.Rect.Min.X)*8
+							pru := uint32(src.Pix[pi+0])<<8 | uint32(src.Pix[pi+1])
+							pgu := uint32(src.Pix[pi+2])<<8 | uint32(src.Pix[pi+3])
+							pbu := uint32(src.Pix[pi+4])<<8 | uint32(src.Pix[pi+5])
+							pau := uint32(src.Pix[pi+6])<<8 | uint32(src.Pix[pi+7])
 							pr += float64(pru) * w
 							pg += float64(pgu) * w
 							pb += float64(pbu) * w
@@ -5666,15 +19483,24 @@ func (q *Kernel) transform_RGBA_RGBA_Src(dst *image.RGBA, dr, adr image.Rectangl
 				pb = pa
 			}
 
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_YCbCr444_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5688,68 +19514,82 @@ func (q *Kernel) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rect
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb float64
@@ -5790,15 +19630,23 @@ func (q *Kernel) transform_RGBA_YCbCr444_Src(dst *image.RGBA, dr, adr image.Rect
 					}
 				}
 			}
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = 0xff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_YCbCr422_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5812,68 +19660,82 @@ func (q *Kernel) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rect
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb float64
@@ -5914,15 +19776,23 @@ func (q *Kernel) transform_RGBA_YCbCr422_Src(dst *image.RGBA, dr, adr image.Rect
 					}
 				}
 			}
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = 0xff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_YCbCr420_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -5936,68 +19806,82 @@ func (q *Kernel) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rect
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb float64
@@ -6038,15 +19922,23 @@ func (q *Kernel) transform_RGBA_YCbCr420_Src(dst *image.RGBA, dr, adr image.Rect
 					}
 				}
 			}
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = 0xff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_YCbCr440_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src *image.YCbCr, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -6060,68 +19952,82 @@ func (q *Kernel) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rect
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb float64
@@ -6162,15 +20068,23 @@ func (q *Kernel) transform_RGBA_YCbCr440_Src(dst *image.RGBA, dr, adr image.Rect
 					}
 				}
 			}
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = 0xff
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(0xffff)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_Image_Over(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -6184,68 +20098,82 @@ func (q *Kernel) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectan
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -6277,16 +20205,25 @@ func (q *Kernel) transform_RGBA_Image_Over(dst *image.RGBA, dr, adr image.Rectan
 			pg0 := uint32(fffftou(pg))
 			pb0 := uint32(fffftou(pb))
 			pa0 := uint32(fffftou(pa))
-			pa1 := (0xffff - uint32(pa0)) * 0x101
-			dst.Pix[d+0] = uint8((uint32(dst.Pix[d+0])*pa1/0xffff + pr0) >> 8)
-			dst.Pix[d+1] = uint8((uint32(dst.Pix[d+1])*pa1/0xffff + pg0) >> 8)
-			dst.Pix[d+2] = uint8((uint32(dst.Pix[d+2])*pa1/0xffff + pb0) >> 8)
-			dst.Pix[d+3] = uint8((uint32(dst.Pix[d+3])*pa1/0xffff + pa0) >> 8)
+			pa1 := 0xffff - pa0
+			qr := uint32(dst.Pix[d+0])<<8 | uint32(dst.Pix[d+1])
+			qg := uint32(dst.Pix[d+2])<<8 | uint32(dst.Pix[d+3])
+			qb := uint32(dst.Pix[d+4])<<8 | uint32(dst.Pix[d+5])
+			qa := uint32(dst.Pix[d+6])<<8 | uint32(dst.Pix[d+7])
+			or, og, ob, oa := qr*pa1/0xffff+pr0, qg*pa1/0xffff+pg0, qb*pa1/0xffff+pb0, uint32(qa*pa1/0xffff+pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_RGBA64_Image_Src(dst *image.RGBA64, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -6300,68 +20237,82 @@ func (q *Kernel) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectang
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
-		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*4
-		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+4 {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+		d := (dr.Min.Y+int(dy)-dst.Rect.Min.Y)*dst.Stride + (dr.Min.X+adr.Min.X-dst.Rect.Min.X)*8
+		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx, d = dx+1, d+8 {
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -6389,15 +20340,24 @@ func (q *Kernel) transform_RGBA_Image_Src(dst *image.RGBA, dr, adr image.Rectang
 				pb = pa
 			}
 
-			dst.Pix[d+0] = uint8(fffftou(pr) >> 8)
-			dst.Pix[d+1] = uint8(fffftou(pg) >> 8)
-			dst.Pix[d+2] = uint8(fffftou(pb) >> 8)
-			dst.Pix[d+3] = uint8(fffftou(pa) >> 8)
+			pr0 := uint32(fffftou(pr))
+			pg0 := uint32(fffftou(pg))
+			pb0 := uint32(fffftou(pb))
+			pa0 := uint32(fffftou(pa))
+			or, og, ob, oa := pr0, pg0, pb0, uint32(pa0)
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(or)
+			dst.Pix[d+2] = uint8(og >> 8)
+			dst.Pix[d+3] = uint8(og)
+			dst.Pix[d+4] = uint8(ob >> 8)
+			dst.Pix[d+5] = uint8(ob)
+			dst.Pix[d+6] = uint8(oa >> 8)
+			dst.Pix[d+7] = uint8(oa)
 		}
 	}
 }
 
-func (q *Kernel) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -6411,8 +20371,11 @@ func (q *Kernel) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle,
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	srcMask, smp := opts.SrcMask, opts.SrcMaskP
 	dstMask, dmp := opts.DstMask, opts.DstMaskP
@@ -6421,61 +20384,72 @@ func (q *Kernel) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle,
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -6532,7 +20506,7 @@ func (q *Kernel) transform_Image_Image_Over(dst Image, dr, adr image.Rectangle,
 	}
 }
 
-func (q *Kernel) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options) {
+func (q *Kernel) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d2s *f64.Aff3, src image.Image, sr image.Rectangle, bias image.Point, xscale, yscale float64, opts *Options, c *kernelTransformer) {
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
 	xHalfWidth, xKernelArgScale := q.Support, 1.0
@@ -6546,8 +20520,11 @@ func (q *Kernel) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d
 		yKernelArgScale = 1 / yscale
 	}
 
-	xWeights := make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
-	yWeights := make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	var xWeights, yWeights []float64
+	if c == nil {
+		xWeights = make([]float64, 1+2*int(math.Ceil(xHalfWidth)))
+		yWeights = make([]float64, 1+2*int(math.Ceil(yHalfWidth)))
+	}
 
 	srcMask, smp := opts.SrcMask, opts.SrcMaskP
 	dstMask, dmp := opts.DstMask, opts.DstMaskP
@@ -6556,61 +20533,72 @@ func (q *Kernel) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d
 	for dy := int32(adr.Min.Y); dy < int32(adr.Max.Y); dy++ {
 		dyf := float64(dr.Min.Y+int(dy)) + 0.5
 		for dx := int32(adr.Min.X); dx < int32(adr.Max.X); dx++ {
-			dxf := float64(dr.Min.X+int(dx)) + 0.5
-			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
-			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
-			if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
-				continue
-			}
+			var ix, jx, iy, jy int
+			if c != nil {
+				s := &c.samples[int(dy)*dr.Dx()+int(dx)]
+				if s.w < 0 {
+					continue
+				}
+				ix, jx, iy, jy = int(s.ix), int(s.jx), int(s.iy), int(s.jy)
+				xWeights = c.weights[s.w:]
+				yWeights = xWeights[jx-ix:]
+			} else {
+				dxf := float64(dr.Min.X+int(dx)) + 0.5
+				sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+				sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+				if !(image.Point{int(sx) + bias.X, int(sy) + bias.Y}).In(sr) {
+					continue
+				}
 
-			// TODO: adjust the bias so that we can use int(f) instead
-			// of math.Floor(f) and math.Ceil(f).
-			sx += float64(bias.X)
-			sx -= 0.5
-			ix := int(math.Floor(sx - xHalfWidth))
-			if ix < sr.Min.X {
-				ix = sr.Min.X
-			}
-			jx := int(math.Ceil(sx + xHalfWidth))
-			if jx > sr.Max.X {
-				jx = sr.Max.X
-			}
+				// TODO: adjust the bias so that we can use int(f) instead
+				// of math.Floor(f) and math.Ceil(f).
+				sx += float64(bias.X)
+				sx -= 0.5
+				ix = int(math.Floor(sx - xHalfWidth))
+				if ix < sr.Min.X {
+					ix = sr.Min.X
+				}
+				jx = int(math.Ceil(sx + xHalfWidth))
+				if jx > sr.Max.X {
+					jx = sr.Max.X
+				}
 
-			totalXWeight := 0.0
-			for kx := ix; kx < jx; kx++ {
-				xWeight := 0.0
-				if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
-					xWeight = q.At(t)
+				totalXWeight := 0.0
+				for kx := ix; kx < jx; kx++ {
+					xWeight := 0.0
+					if t := abs((sx - float64(kx)) * xKernelArgScale); t < q.Support {
+						xWeight = q.At(t)
+					}
+					xWeights[kx-ix] = xWeight
+					totalXWeight += xWeight
+				}
+				for x := range xWeights[:jx-ix] {
+					xWeights[x] /= totalXWeight
 				}
-				xWeights[kx-ix] = xWeight
-				totalXWeight += xWeight
-			}
-			for x := range xWeights[:jx-ix] {
-				xWeights[x] /= totalXWeight
-			}
 
-			sy += float64(bias.Y)
-			sy -= 0.5
-			iy := int(math.Floor(sy - yHalfWidth))
-			if iy < sr.Min.Y {
-				iy = sr.Min.Y
-			}
-			jy := int(math.Ceil(sy + yHalfWidth))
-			if jy > sr.Max.Y {
-				jy = sr.Max.Y
-			}
+				sy += float64(bias.Y)
+				sy -= 0.5
+				iy = int(math.Floor(sy - yHalfWidth))
+				if iy < sr.Min.Y {
+					iy = sr.Min.Y
+				}
+				jy = int(math.Ceil(sy + yHalfWidth))
+				if jy > sr.Max.Y {
+					jy = sr.Max.Y
+				}
 
-			totalYWeight := 0.0
-			for ky := iy; ky < jy; ky++ {
-				yWeight := 0.0
-				if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
-					yWeight = q.At(t)
+				totalYWeight := 0.0
+				for ky := iy; ky < jy; ky++ {
+					yWeight := 0.0
+					if t := abs((sy - float64(ky)) * yKernelArgScale); t < q.Support {
+						yWeight = q.At(t)
+					}
+					yWeights[ky-iy] = yWeight
+					totalYWeight += yWeight
+				}
+				for y := range yWeights[:jy-iy] {
+					yWeights[y] /= totalYWeight
 				}
-				yWeights[ky-iy] = yWeight
-				totalYWeight += yWeight
-			}
-			for y := range yWeights[:jy-iy] {
-				yWeights[y] /= totalYWeight
 			}
 
 			var pr, pg, pb, pa float64
@@ -6668,3 +20656,300 @@ func (q *Kernel) transform_Image_Image_Src(dst Image, dr, adr image.Rectangle, d
 		}
 	}
 }
+
+// compositeRGBA composites the pixels adr of tmp onto dst with op,
+// skipping those that drawComposited's f left unchanged.
+func compositeRGBA(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle, op CompositeOp) {
+	switch op {
+	case CompositeIn:
+		compositeRGBA_In(dst, tmp, adr)
+	case CompositeOut:
+		compositeRGBA_Out(dst, tmp, adr)
+	case CompositeAtop:
+		compositeRGBA_Atop(dst, tmp, adr)
+	case CompositeXor:
+		compositeRGBA_Xor(dst, tmp, adr)
+	case CompositePlus:
+		compositeRGBA_Plus(dst, tmp, adr)
+	case CompositeDstOver:
+		compositeRGBA_DstOver(dst, tmp, adr)
+	case CompositeDstIn:
+		compositeRGBA_DstIn(dst, tmp, adr)
+	case CompositeDstOut:
+		compositeRGBA_DstOut(dst, tmp, adr)
+	case CompositeDstAtop:
+		compositeRGBA_DstAtop(dst, tmp, adr)
+	}
+}
+
+func compositeRGBA_In(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := sr * (da) / 0xffff
+			og := sg * (da) / 0xffff
+			ob := sb * (da) / 0xffff
+			oa := sa * (da) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_Out(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := sr * (0xffff - da) / 0xffff
+			og := sg * (0xffff - da) / 0xffff
+			ob := sb * (0xffff - da) / 0xffff
+			oa := sa * (0xffff - da) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_Atop(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := (sr*(da) + dr*(0xffff-sa)) / 0xffff
+			og := (sg*(da) + dg*(0xffff-sa)) / 0xffff
+			ob := (sb*(da) + db*(0xffff-sa)) / 0xffff
+			oa := (sa*(da) + da*(0xffff-sa)) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_Xor(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := (sr*(0xffff-da) + dr*(0xffff-sa)) / 0xffff
+			og := (sg*(0xffff-da) + dg*(0xffff-sa)) / 0xffff
+			ob := (sb*(0xffff-da) + db*(0xffff-sa)) / 0xffff
+			oa := (sa*(0xffff-da) + da*(0xffff-sa)) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_Plus(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := sr + dr
+			if or > 0xffff {
+				or = 0xffff
+			}
+			og := sg + dg
+			if og > 0xffff {
+				og = 0xffff
+			}
+			ob := sb + db
+			if ob > 0xffff {
+				ob = 0xffff
+			}
+			oa := sa + da
+			if oa > 0xffff {
+				oa = 0xffff
+			}
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_DstOver(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := sr*(0xffff-da)/0xffff + dr
+			og := sg*(0xffff-da)/0xffff + dg
+			ob := sb*(0xffff-da)/0xffff + db
+			oa := sa*(0xffff-da)/0xffff + da
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_DstIn(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := dr * (sa) / 0xffff
+			og := dg * (sa) / 0xffff
+			ob := db * (sa) / 0xffff
+			oa := da * (sa) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_DstOut(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := dr * (0xffff - sa) / 0xffff
+			og := dg * (0xffff - sa) / 0xffff
+			ob := db * (0xffff - sa) / 0xffff
+			oa := da * (0xffff - sa) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
+
+func compositeRGBA_DstAtop(dst *image.RGBA, tmp *image.RGBA64, adr image.Rectangle) {
+	for y := adr.Min.Y; y < adr.Max.Y; y++ {
+		d := dst.PixOffset(adr.Min.X, y)
+		t := tmp.PixOffset(adr.Min.X, y)
+		for x := adr.Min.X; x < adr.Max.X; x, d, t = x+1, d+4, t+8 {
+			sr := uint32(tmp.Pix[t+0])<<8 | uint32(tmp.Pix[t+1])
+			sa := uint32(tmp.Pix[t+6])<<8 | uint32(tmp.Pix[t+7])
+			if sr > sa {
+				continue
+			}
+			sg := uint32(tmp.Pix[t+2])<<8 | uint32(tmp.Pix[t+3])
+			sb := uint32(tmp.Pix[t+4])<<8 | uint32(tmp.Pix[t+5])
+			dr := uint32(dst.Pix[d+0]) * 0x101
+			dg := uint32(dst.Pix[d+1]) * 0x101
+			db := uint32(dst.Pix[d+2]) * 0x101
+			da := uint32(dst.Pix[d+3]) * 0x101
+
+			or := (sr*(0xffff-da) + dr*(sa)) / 0xffff
+			og := (sg*(0xffff-da) + dg*(sa)) / 0xffff
+			ob := (sb*(0xffff-da) + db*(sa)) / 0xffff
+			oa := (sa*(0xffff-da) + da*(sa)) / 0xffff
+
+			dst.Pix[d+0] = uint8(or >> 8)
+			dst.Pix[d+1] = uint8(og >> 8)
+			dst.Pix[d+2] = uint8(ob >> 8)
+			dst.Pix[d+3] = uint8(oa >> 8)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+)
+
+// MultiStep returns a Scaler that scales down by large factors in two
+// steps. While the src pixels are at least 4 times the dst width or height,
+// it halves them, and then it scales the result with q. The halvings are done
+// in a single pass that averages each block of 2x2, 4x4, 8x8 or more src
+// pixels, as the Box kernel would, and so does not alias.
+//
+// For example, scaling 8000x8000 pixels down to 200x200 first averages each
+// 32x32 block, to 250x250 pixels, so that q's much costlier weights are only
+// computed for the final 250x250 to 200x200 pass.
+//
+// The intermediate pixels are held in an *image.RGBA64, and so float src
+// images, and an Options.Alignment other than AlignCenters, are scaled in a
+// single pass with q instead.
+func MultiStep(q *Kernel) Scaler {
+	return multiStepScaler{q}
+}
+
+type multiStepScaler struct {
+	q *Kernel
+}
+
+func (z multiStepScaler) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	o.MultiStep = false
+	if o.Alignment != AlignCenters || isFloat(src) || dr.Empty() || sr.Empty() {
+		z.q.Scale(dst, dr, src, sr, op, &o)
+		return
+	}
+	// fx and fy are the number of src columns and rows in each block.
+	w, h, fx, fy := sr.Dx(), sr.Dy(), 1, 1
+	for w >= 4*dr.Dx() {
+		w, fx = (w+1)/2, fx*2
+	}
+	for h >= 4*dr.Dy() {
+		h, fy = (h+1)/2, fy*2
+	}
+	if fx > 1 || fy > 1 {
+		tmp := image.NewRGBA64(image.Rect(0, 0, w, h))
+		src = applyColorMatrix(src, &o)
+		reduce(tmp, src, sr, fx, fy, &o)
+		src, sr, o.SrcMask = tmp, tmp.Rect, nil
+	}
+	z.q.Scale(dst, dr, src, sr, op, &o)
+}
+
+// reduce sets each pixel of dst to the mean of the corresponding fx x fy
+// block of the src pixels sr. The blocks at the right and bottom edges of sr
+// may be smaller. Any opts.SrcMask is applied to the src pixels.
+func reduce(dst *image.RGBA64, src image.Image, sr image.Rectangle, fx, fy int, opts *Options) {
+	// The type-specific fast paths access the Pix fields directly, without
+	// bounds checking, and assume that the mask is nil.
+	fast := sr.In(src.Bounds()) && opts.SrcMask == nil
+	w := dst.Rect.Dx()
+	parallel(opts.Concurrency, 0, int32(dst.Rect.Dy()), func(y0, y1 int32) {
+		// sum holds the sums of each dst pixel's src pixels' channels.
+		sum := make([]uint64, 4*w)
+		for y := int(y0); y < int(y1); y++ {
+			for i := range sum {
+				sum[i] = 0
+			}
+			sy0 := sr.Min.Y + y*fy
+			sy1 := sy0 + fy
+			if sy1 > sr.Max.Y {
+				sy1 = sr.Max.Y
+			}
+			for sy := sy0; sy < sy1; sy++ {
+				reduceRow(sum, src, sr, sy, fx, fast, opts)
+			}
+			d := dst.PixOffset(dst.Rect.Min.X, dst.Rect.Min.Y+y)
+			for x := 0; x < w; x++ {
+				sx1 := sr.Min.X + (x+1)*fx
+				if sx1 > sr.Max.X {
+					sx1 = sr.Max.X
+				}
+				n := uint64(sy1-sy0) * uint64(sx1-sr.Min.X-x*fx)
+				for _, v := range sum[4*x : 4*x+4] {
+					v = (v + n/2) / n
+					dst.Pix[d+0] = uint8(v >> 8)
+					dst.Pix[d+1] = uint8(v)
+					d += 2
+				}
+			}
+		}
+	})
+}
+
+// reduceRow adds the alpha-premultiplied 16-bit colors of the src pixels sr
+// in row sy to sum, fx src pixels to each dst pixel, using the fast paths
+// for the most common image types if fast is true.
+func reduceRow(sum []uint64, src image.Image, sr image.Rectangle, sy, fx int, fast bool, opts *Options) {
+	if fast {
+		switch src := src.(type) {
+		case *image.RGBA:
+			s := src.Pix[src.PixOffset(sr.Min.X, sy):src.PixOffset(sr.Max.X, sy)]
+			for i, q := 0, 0; i < len(s); q += 4 {
+				var pr, pg, pb, pa uint64
+				for j := 0; j < fx && i < len(s); j, i = j+1, i+4 {
+					p := s[i : i+4 : i+4]
+					pr += uint64(p[0])
+					pg += uint64(p[1])
+					pb += uint64(p[2])
+					pa += uint64(p[3])
+				}
+				sum[q+0] += pr * 0x101
+				sum[q+1] += pg * 0x101
+				sum[q+2] += pb * 0x101
+				sum[q+3] += pa * 0x101
+			}
+			return
+		case *image.RGBA64:
+			s := src.Pix[src.PixOffset(sr.Min.X, sy):src.PixOffset(sr.Max.X, sy)]
+			for i, q := 0, 0; i < len(s); q += 4 {
+				var pr, pg, pb, pa uint64
+				for j := 0; j < fx && i < len(s); j, i = j+1, i+8 {
+					p := s[i : i+8 : i+8]
+					pr += uint64(p[0])<<8 | uint64(p[1])
+					pg += uint64(p[2])<<8 | uint64(p[3])
+					pb += uint64(p[4])<<8 | uint64(p[5])
+					pa += uint64(p[6])<<8 | uint64(p[7])
+				}
+				sum[q+0] += pr
+				sum[q+1] += pg
+				sum[q+2] += pb
+				sum[q+3] += pa
+			}
+			return
+		case *image.Gray:
+			s := src.Pix[src.PixOffset(sr.Min.X, sy):src.PixOffset(sr.Max.X, sy)]
+			for i, q := 0, 0; i < len(s); q += 4 {
+				var py, n uint64
+				for j := 0; j < fx && i < len(s); j, i = j+1, i+1 {
+					py += uint64(s[i])
+					n++
+				}
+				sum[q+0] += py * 0x101
+				sum[q+1] += py * 0x101
+				sum[q+2] += py * 0x101
+				sum[q+3] += n * 0xffff
+			}
+			return
+		}
+	}
+	for x := 0; x < sr.Dx(); x++ {
+		pr, pg, pb, pa := src.At(sr.Min.X+x, sy).RGBA()
+		if opts.SrcMask != nil {
+			_, _, _, ma := opts.SrcMask.At(opts.SrcMaskP.X+sr.Min.X+x, opts.SrcMaskP.Y+sy).RGBA()
+			pr = pr * ma / 0xffff
+			pg = pg * ma / 0xffff
+			pb = pb * ma / 0xffff
+			pa = pa * ma / 0xffff
+		}
+		q := 4 * (x / fx)
+		sum[q+0] += uint64(pr)
+		sum[q+1] += uint64(pg)
+		sum[q+2] += uint64(pb)
+		sum[q+3] += uint64(pa)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestMultiStep(t *testing.T) {
+	// A fine checkerboard, whose pixels average to mid-gray, and a
+	// horizontal gradient, at an odd size and away from the origin.
+	src := image.NewRGBA(image.Rect(5, 7, 5+203, 7+101))
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+			v := uint8(0)
+			if (x+y)%2 == 0 {
+				v = uint8(255 * (x - src.Rect.Min.X) / (src.Rect.Dx() - 1))
+			}
+			src.SetRGBA(x, y, color.RGBA{v, v, v, 0xff})
+		}
+	}
+	dr := image.Rect(0, 0, 10, 5)
+
+	want := image.NewRGBA(dr)
+	Box.Scale(want, dr, src, src.Bounds(), Src, nil)
+	for _, s := range []image.Image{src, &nonFastGray{src}} {
+		got := image.NewRGBA(dr)
+		MultiStep(CatmullRom).Scale(got, dr, s, src.Bounds(), Src, nil)
+		for i := range got.Pix {
+			if d := int(got.Pix[i]) - int(want.Pix[i]); d < -8 || d > 8 {
+				t.Errorf("%T: Pix[%d]: got %d, want %d (Box)", s, i, got.Pix[i], want.Pix[i])
+			}
+		}
+	}
+
+	// The Options field is equivalent.
+	got0 := image.NewRGBA(dr)
+	MultiStep(Lanczos3).Scale(got0, dr, src, src.Bounds(), Src, &Options{Concurrency: 2})
+	got1 := image.NewRGBA(dr)
+	Lanczos3.Scale(got1, dr, src, src.Bounds(), Src, &Options{MultiStep: true})
+	if !bytes.Equal(got0.Pix, got1.Pix) {
+		t.Errorf("Options.MultiStep: got %v, want %v", got1.Pix, got0.Pix)
+	}
+
+	// Scaling by small factors is a single pass.
+	dr = image.Rect(0, 0, 150, 80)
+	got0 = image.NewRGBA(dr)
+	MultiStep(CatmullRom).Scale(got0, dr, src, src.Bounds(), Src, nil)
+	got1 = image.NewRGBA(dr)
+	CatmullRom.Scale(got1, dr, src, src.Bounds(), Src, nil)
+	if !bytes.Equal(got0.Pix, got1.Pix) {
+		t.Errorf("small factor: MultiStep differs from a single pass")
+	}
+}
+
+func TestMultiStepSrcMask(t *testing.T) {
+	src := image.NewUniform(color.White)
+	// The mask is opaque on its left half only.
+	mask := image.NewAlpha(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 32; x++ {
+			mask.SetAlpha(x, y, color.Alpha{0xff})
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	MultiStep(CatmullRom).Scale(dst, dst.Rect, src, mask.Rect, Src, &Options{SrcMask: mask})
+	if got := dst.RGBAAt(0, 0); got.A != 0xff {
+		t.Errorf("left: got %v, want opaque", got)
+	}
+	if got := dst.RGBAAt(3, 0); got.A != 0 {
+		t.Errorf("right: got %v, want transparent", got)
+	}
+}
+
+func BenchmarkMultiStep(b *testing.B) {
+	src := image.NewRGBA(image.Rect(0, 0, 2000, 2000))
+	dst := image.NewRGBA(image.Rect(0, 0, 50, 50))
+	for _, tc := range []struct {
+		name string
+		s    Scaler
+	}{
+		{"Single", CatmullRom},
+		{"MultiStep", MultiStep(CatmullRom)},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				tc.s.Scale(dst, dst.Rect, src, src.Rect, Src, nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+
+	"golang.org/x/image/math/f64"
+)
+
+// OrientationTransform returns the transform that maps the src pixels sr,
+// stored with the given EXIF orientation, to upright, and the dst rectangle
+// that they map to, whose top-left corner is the origin. The orientation is
+// from 1 to 8, as for the EXIF Orientation tag, and other values are treated
+// as 1, meaning that sr is already upright. Orientations 5 to 8 swap the
+// width and height.
+//
+// The transform can be composed with others, such as a scale to make a
+// thumbnail, or passed as is to a Transformer's Transform method, for which
+// NearestNeighbor copies every pixel exactly.
+func OrientationTransform(orientation int, sr image.Rectangle) (s2d f64.Aff3, dr image.Rectangle) {
+	w, h := float64(sr.Dx()), float64(sr.Dy())
+	// m maps the point (u, v), relative to sr.Min, to dst space.
+	var m f64.Aff3
+	switch orientation {
+	default:
+		m = f64.Aff3{1, 0, 0, 0, 1, 0}
+	case 2: // Mirrored horizontally.
+		m = f64.Aff3{-1, 0, w, 0, 1, 0}
+	case 3: // Rotated 180 degrees.
+		m = f64.Aff3{-1, 0, w, 0, -1, h}
+	case 4: // Mirrored vertically.
+		m = f64.Aff3{1, 0, 0, 0, -1, h}
+	case 5: // Transposed.
+		m = f64.Aff3{0, 1, 0, 1, 0, 0}
+	case 6: // Needs rotating 90 degrees clockwise.
+		m = f64.Aff3{0, -1, h, 1, 0, 0}
+	case 7: // Transversed.
+		m = f64.Aff3{0, -1, h, -1, 0, w}
+	case 8: // Needs rotating 90 degrees counter-clockwise.
+		m = f64.Aff3{0, 1, 0, -1, 0, w}
+	}
+	dr = image.Rect(0, 0, sr.Dx(), sr.Dy())
+	if 5 <= orientation && orientation <= 8 {
+		dr = image.Rect(0, 0, sr.Dy(), sr.Dx())
+	}
+	return m.Mul(f64.Translate(float64(-sr.Min.X), float64(-sr.Min.Y))), dr
+}
+
+// Orient draws the src pixels sr, stored with the given EXIF orientation,
+// upright onto dst, with the top-left corner of the upright pixels at dp.
+// The orientation is as for OrientationTransform.
+//
+// The pixels are copied exactly, without interpolation.
+func Orient(dst Image, dp image.Point, src image.Image, sr image.Rectangle, orientation int, op Op, opts *Options) {
+	s2d, _ := OrientationTransform(orientation, sr)
+	s2d[2] += float64(dp.X)
+	s2d[5] += float64(dp.Y)
+	NearestNeighbor.Transform(dst, s2d, src, sr, op, opts)
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image, away from the origin: 0 1 2 / 3 4 5.
+	src := image.NewGray(image.Rect(10, 20, 13, 22))
+	copy(src.Pix, []uint8{0, 1, 2, 3, 4, 5})
+	want := map[int][]uint8{
+		1: {0, 1, 2, 3, 4, 5},
+		2: {2, 1, 0, 5, 4, 3},
+		3: {5, 4, 3, 2, 1, 0},
+		4: {3, 4, 5, 0, 1, 2},
+		5: {0, 3, 1, 4, 2, 5},
+		6: {3, 0, 4, 1, 5, 2},
+		7: {5, 2, 4, 1, 3, 0},
+		8: {2, 5, 1, 4, 0, 3},
+	}
+	for o := 0; o <= 9; o++ {
+		w, ok := want[o]
+		if !ok {
+			w = want[1]
+		}
+		_, dr := OrientationTransform(o, src.Bounds())
+		wantDR := image.Rect(0, 0, 3, 2)
+		if 5 <= o && o <= 8 {
+			wantDR = image.Rect(0, 0, 2, 3)
+		}
+		if dr != wantDR {
+			t.Errorf("orientation %d: dr: got %v, want %v", o, dr, wantDR)
+			continue
+		}
+
+		// Draw onto a larger dst, offset by dp.
+		dp := image.Point{1, 2}
+		dst := image.NewGray(dr.Add(dp).Inset(-1))
+		for i := range dst.Pix {
+			dst.Pix[i] = 0xff
+		}
+		Orient(dst, dp, src, src.Bounds(), o, Src, nil)
+		got := dst.SubImage(dr.Add(dp)).(*image.Gray)
+		var gotPix []uint8
+		for y := got.Rect.Min.Y; y < got.Rect.Max.Y; y++ {
+			i := got.PixOffset(got.Rect.Min.X, y)
+			gotPix = append(gotPix, got.Pix[i:i+got.Rect.Dx()]...)
+		}
+		if !bytes.Equal(gotPix, w) {
+			t.Errorf("orientation %d: got %v, want %v", o, gotPix, w)
+		}
+		// The border around the upright pixels is untouched.
+		if dst.Pix[0] != 0xff || dst.Pix[len(dst.Pix)-1] != 0xff {
+			t.Errorf("orientation %d: border changed", o)
+		}
+	}
+}
@@ -0,0 +1,463 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// ProjectiveTransformer is like Transformer but for projective transforms,
+// also known as perspective transforms or homographies, such as those that
+// correct the keystone distortion of a photographed document.
+//
+// If m is the matrix
+//
+//	m00 m01 m02
+//	m10 m11 m12
+//	m20 m21 m22
+//
+// then the src-space point (sx, sy) maps to the dst-space point
+// (m00*sx + m01*sy + m02, m10*sx + m11*sy + m12) divided by
+// w = m20*sx + m21*sy + m22. Parts of sr with a non-positive w, which lie on
+// or beyond the transform's horizon, are not drawn.
+//
+// The NearestNeighbor, ApproxBiLinear and Kernel (such as CatmullRom)
+// interpolators all implement ProjectiveTransformer. Transforms whose bottom
+// row is [0 0 1] are affine, and are passed on to their Transform methods.
+//
+// A ProjectiveTransformer is safe to use concurrently.
+type ProjectiveTransformer interface {
+	TransformProjective(dst Image, m f64.Mat3, src image.Image, sr image.Rectangle, op Op, opts *Options)
+}
+
+// TransformProjective implements the ProjectiveTransformer interface.
+func (z nnInterpolator) TransformProjective(dst Image, s2d f64.Mat3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if m, ok := affine(&s2d); ok {
+		z.Transform(dst, m, src, sr, op, opts)
+		return
+	}
+	transformProjective(dst, &s2d, src, sr, op, opts, nnSample)
+}
+
+// TransformProjective implements the ProjectiveTransformer interface.
+func (z ablInterpolator) TransformProjective(dst Image, s2d f64.Mat3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if m, ok := affine(&s2d); ok {
+		z.Transform(dst, m, src, sr, op, opts)
+		return
+	}
+	transformProjective(dst, &s2d, src, sr, op, opts, ablSample)
+}
+
+// TransformProjective implements the ProjectiveTransformer interface.
+func (q *Kernel) TransformProjective(dst Image, s2d f64.Mat3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if m, ok := affine(&s2d); ok {
+		q.Transform(dst, m, src, sr, op, opts)
+		return
+	}
+	k := &kernelSampler{q: q}
+	transformProjective(dst, &s2d, src, sr, op, opts, k.sample)
+}
+
+// Homography returns the projective transform that maps each of the four
+// points from[i] to to[i]. For example, to de-skew the photographed page whose
+// corners are the from points, clockwise from the top-left, into a w×h image,
+// use to points of (0, 0), (w, 0), (w, h) and (0, h).
+//
+// It returns !ok if either set of points has three collinear points, in
+// which case no such transform exists.
+func Homography(from, to [4]f64.Vec2) (m f64.Mat3, ok bool) {
+	a, ok := squareToQuad(&from)
+	if !ok {
+		return f64.Mat3{}, false
+	}
+	b, ok := squareToQuad(&to)
+	if !ok {
+		return f64.Mat3{}, false
+	}
+	ai, ok := a.Invert()
+	if !ok {
+		return f64.Mat3{}, false
+	}
+	return b.Mul(ai), true
+}
+
+// squareToQuad returns the projective transform that maps the corners of the
+// unit square, (0, 0), (1, 0), (1, 1) and (0, 1), to the four points of q.
+//
+// It follows section 2.2.3 of "Fundamentals of Texture Mapping and Image
+// Warping" by Paul Heckbert.
+func squareToQuad(q *[4]f64.Vec2) (m f64.Mat3, ok bool) {
+	x0, y0 := q[0][0], q[0][1]
+	x1, y1 := q[1][0], q[1][1]
+	x2, y2 := q[2][0], q[2][1]
+	x3, y3 := q[3][0], q[3][1]
+	sx := x0 - x1 + x2 - x3
+	sy := y0 - y1 + y2 - y3
+	dx1, dx2 := x1-x2, x3-x2
+	dy1, dy2 := y1-y2, y3-y2
+	det := dx1*dy2 - dx2*dy1
+	if det == 0 {
+		return f64.Mat3{}, false
+	}
+	g := (sx*dy2 - dx2*sy) / det
+	h := (dx1*sy - sx*dy1) / det
+	m = f64.Mat3{
+		x1 - x0 + g*x1, x3 - x0 + h*x3, x0,
+		y1 - y0 + g*y1, y3 - y0 + h*y3, y0,
+		g, h, 1,
+	}
+	// If three of the points are collinear, m is singular.
+	if _, ok := m.Invert(); !ok {
+		return f64.Mat3{}, false
+	}
+	return m, true
+}
+
+// affine returns m as an affine transform, if its bottom row is [0 0 c] for
+// some non-zero c.
+func affine(m *f64.Mat3) (f64.Aff3, bool) {
+	if m[6] != 0 || m[7] != 0 || m[8] == 0 {
+		return f64.Aff3{}, false
+	}
+	c := m[8]
+	return f64.Aff3{
+		m[0] / c, m[1] / c, m[2] / c,
+		m[3] / c, m[4] / c, m[5] / c,
+	}, true
+}
+
+// projectRect returns a rectangle that contains sr transformed by s2d, or
+// !ok if part of sr is on or beyond s2d's horizon, in which case the
+// transformed sr is unbounded.
+func projectRect(s2d *f64.Mat3, sr *image.Rectangle) (dr image.Rectangle, ok bool) {
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [...]image.Point{
+		{sr.Min.X, sr.Min.Y},
+		{sr.Max.X, sr.Min.Y},
+		{sr.Min.X, sr.Max.Y},
+		{sr.Max.X, sr.Max.Y},
+	} {
+		sxf, syf := float64(p.X), float64(p.Y)
+		w := s2d[6]*sxf + s2d[7]*syf + s2d[8]
+		if w <= 0 {
+			return image.Rectangle{}, false
+		}
+		dx := (s2d[0]*sxf + s2d[1]*syf + s2d[2]) / w
+		dy := (s2d[3]*sxf + s2d[4]*syf + s2d[5]) / w
+		minX, maxX = math.Min(minX, dx), math.Max(maxX, dx)
+		minY, maxY = math.Min(minY, dy), math.Max(maxY, dy)
+	}
+	// As for transformRect, the +1 adjustments are because an
+	// image.Rectangle is exclusive on the high end.
+	return image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Floor(maxX))+1, int(math.Floor(maxY))+1,
+	), true
+}
+
+// projectiveSampler returns the alpha-premultiplied color, in the range
+// [0.0, 65535.0], of src at the src-space point (sx, sy), which is in sr.
+// xscale and yscale are the number of src pixels per dst pixel, near that
+// point, along each axis.
+type projectiveSampler func(src image.Image, sr image.Rectangle, sx, sy, xscale, yscale float64, o *Options) (r, g, b, a float64)
+
+// transformProjective implements the TransformProjective methods, calling
+// sample for every affected dst pixel.
+func transformProjective(dst Image, s2d *f64.Mat3, src image.Image, sr image.Rectangle, op Op, opts *Options, sample projectiveSampler) {
+	d2s, ok := s2d.Invert()
+	if !ok {
+		return
+	}
+	// If sr is unbounded in dst space, every dst pixel is potentially
+	// affected.
+	adr := dst.Bounds()
+	if dr, ok := projectRect(s2d, &sr); ok {
+		adr = adr.Intersect(dr)
+	}
+	warp(dst, adr, src, sr, op, opts, sample, func(dxf, dyf float64) (sx, sy, xscale, yscale float64, ok bool) {
+		// The dst pixel center maps to (u/w, v/w) in src space. Since d2s
+		// is the exact inverse of s2d, w is positive for points that are in
+		// front of the horizon.
+		u := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+		v := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+		w := d2s[6]*dxf + d2s[7]*dyf + d2s[8]
+		if w <= 0 {
+			return 0, 0, 0, 0, false
+		}
+		sx, sy = u/w, v/w
+		// The partial derivatives of (sx, sy) with respect to (dx, dy) give
+		// the local scale, as the matrix elements do for an affine
+		// transform.
+		xscale = math.Max(abs(d2s[0]-sx*d2s[6]), abs(d2s[1]-sx*d2s[7])) / w
+		yscale = math.Max(abs(d2s[3]-sy*d2s[6]), abs(d2s[4]-sy*d2s[7])) / w
+		return sx, sy, xscale, yscale, true
+	})
+}
+
+// warpFunc returns the src-space point that the dst-space point (dxf, dyf)
+// maps to, and the number of src pixels per dst pixel near that point along
+// each axis, or !ok if it maps to no point.
+type warpFunc func(dxf, dyf float64) (sx, sy, xscale, yscale float64, ok bool)
+
+// warp draws the dst pixels within adr, whose centers map to src-space points
+// by f, calling sample for every such point within sr.
+func warp(dst Image, adr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options, sample projectiveSampler, f warpFunc) {
+	if compositeDst(opts) {
+		if opts.EdgeOp != EdgeNone {
+			adr = dst.Bounds()
+		}
+		drawComposited(dst, adr, opts, func(tmp Image, o *Options) {
+			warp(tmp, adr, src, sr, Src, o, sample, f)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		if opts.EdgeOp != EdgeNone {
+			adr = dst.Bounds()
+		}
+		drawDithered(p, adr, opts, func(tmp Image, o *Options) {
+			warp(tmp, adr, src, sr, op, o, sample, f)
+		})
+		return
+	}
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	src = applyColorMatrix(src, &o)
+
+	if sr.Empty() {
+		return
+	}
+	// If every dst pixel maps to a src pixel because of the EdgeOp, every
+	// dst pixel is potentially affected. Near a horizon, a dst pixel covers
+	// an unbounded part of the edge image, so the scales passed to sample,
+	// and so a kernel's footprint, are capped at about sr's size.
+	maxXScale, maxYScale := math.Inf(+1), math.Inf(+1)
+	if o.EdgeOp != EdgeNone {
+		maxXScale, maxYScale = float64(sr.Dx()+1), float64(sr.Dy()+1)
+		all := image.Rect(-1<<30, -1<<30, 1<<30, 1<<30)
+		src, sr = newEdgeImage(src, sr, all, &o), all
+		o.SrcMask, o.SrcMaskP = nil, image.Point{}
+		adr = dst.Bounds()
+	}
+	adr, o.DstMask = clipAffectedDestRect(adr, o.DstMask, o.DstMaskP)
+	if adr.Empty() || sr.Empty() {
+		return
+	}
+	if op == Over && o.SrcMask == nil && opaque(src) {
+		op = Src
+	}
+
+	dstMask, dmp := o.DstMask, o.DstMaskP
+	floatDst := isFloat(dst)
+	dstColorRGBA64 := &color.RGBA64{}
+	dstColor := color.Color(dstColorRGBA64)
+	for dy := adr.Min.Y; dy < adr.Max.Y; dy++ {
+		dyf := float64(dy) + 0.5
+		for dx := adr.Min.X; dx < adr.Max.X; dx++ {
+			dxf := float64(dx) + 0.5
+			sx, sy, xscale, yscale, ok := f(dxf, dyf)
+			if !ok {
+				continue
+			}
+			if !(image.Point{int(math.Floor(sx)), int(math.Floor(sy))}).In(sr) {
+				continue
+			}
+			xscale, yscale = math.Min(xscale, maxXScale), math.Min(yscale, maxYScale)
+
+			pr, pg, pb, pa := sample(src, sr, sx, sy, xscale, yscale, &o)
+			if floatDst {
+				ma := 1.0
+				if dstMask != nil {
+					_, _, _, ma32 := dstMask.At(dmp.X+dx, dmp.Y+dy).RGBA()
+					ma = float64(ma32) / 0xffff
+				}
+				ma1 := ma / 0xffff
+				setFloat(dst, dx, dy, pr*ma1, pg*ma1, pb*ma1, pa*ma1, ma, op)
+				continue
+			}
+			if pr > pa {
+				pr = pa
+			}
+			if pg > pa {
+				pg = pa
+			}
+			if pb > pa {
+				pb = pa
+			}
+			pr32 := uint32(fffftou(pr))
+			pg32 := uint32(fffftou(pg))
+			pb32 := uint32(fffftou(pb))
+			pa32 := uint32(fffftou(pa))
+
+			ma := uint32(0xffff)
+			if dstMask != nil {
+				_, _, _, ma = dstMask.At(dmp.X+dx, dmp.Y+dy).RGBA()
+				pr32 = pr32 * ma / 0xffff
+				pg32 = pg32 * ma / 0xffff
+				pb32 = pb32 * ma / 0xffff
+				pa32 = pa32 * ma / 0xffff
+			}
+			pa1 := 0xffff - pa32
+			if op == Src {
+				pa1 = 0xffff - ma
+			}
+			if pa1 == 0 {
+				dstColorRGBA64.R = uint16(pr32)
+				dstColorRGBA64.G = uint16(pg32)
+				dstColorRGBA64.B = uint16(pb32)
+				dstColorRGBA64.A = uint16(pa32)
+			} else {
+				qr, qg, qb, qa := dst.At(dx, dy).RGBA()
+				dstColorRGBA64.R = uint16(qr*pa1/0xffff + pr32)
+				dstColorRGBA64.G = uint16(qg*pa1/0xffff + pg32)
+				dstColorRGBA64.B = uint16(qb*pa1/0xffff + pb32)
+				dstColorRGBA64.A = uint16(qa*pa1/0xffff + pa32)
+			}
+			dst.Set(dx, dy, dstColor)
+		}
+	}
+}
+
+// srcAt returns the alpha-premultiplied color of src at (x, y), multiplied
+// by the source mask, if any.
+func srcAt(src image.Image, x, y int, o *Options) (r, g, b, a float64) {
+	c := src.At(x, y)
+	if r, g, b, a, ok := floatAt(c); ok {
+		if o.SrcMask != nil {
+			_, _, _, ma := o.SrcMask.At(o.SrcMaskP.X+x, o.SrcMaskP.Y+y).RGBA()
+			m := float64(ma) / 0xffff
+			r, g, b, a = r*m, g*m, b*m, a*m
+		}
+		return r, g, b, a
+	}
+	ru, gu, bu, au := c.RGBA()
+	if o.SrcMask != nil {
+		_, _, _, ma := o.SrcMask.At(o.SrcMaskP.X+x, o.SrcMaskP.Y+y).RGBA()
+		ru = ru * ma / 0xffff
+		gu = gu * ma / 0xffff
+		bu = bu * ma / 0xffff
+		au = au * ma / 0xffff
+	}
+	return float64(ru), float64(gu), float64(bu), float64(au)
+}
+
+func nnSample(src image.Image, sr image.Rectangle, sx, sy, xscale, yscale float64, o *Options) (r, g, b, a float64) {
+	return srcAt(src, int(math.Floor(sx)), int(math.Floor(sy)), o)
+}
+
+func ablSample(src image.Image, sr image.Rectangle, sx, sy, xscale, yscale float64, o *Options) (r, g, b, a float64) {
+	sx -= 0.5
+	sx0f := math.Floor(sx)
+	xFrac0 := sx - sx0f
+	xFrac1 := 1 - xFrac0
+	sx0 := int(sx0f)
+	sx1 := sx0 + 1
+	if sx0 < sr.Min.X {
+		sx0, sx1 = sr.Min.X, sr.Min.X
+		xFrac0, xFrac1 = 0, 1
+	} else if sx1 >= sr.Max.X {
+		sx0, sx1 = sr.Max.X-1, sr.Max.X-1
+		xFrac0, xFrac1 = 1, 0
+	}
+
+	sy -= 0.5
+	sy0f := math.Floor(sy)
+	yFrac0 := sy - sy0f
+	yFrac1 := 1 - yFrac0
+	sy0 := int(sy0f)
+	sy1 := sy0 + 1
+	if sy0 < sr.Min.Y {
+		sy0, sy1 = sr.Min.Y, sr.Min.Y
+		yFrac0, yFrac1 = 0, 1
+	} else if sy1 >= sr.Max.Y {
+		sy0, sy1 = sr.Max.Y-1, sr.Max.Y-1
+		yFrac0, yFrac1 = 1, 0
+	}
+
+	s00r, s00g, s00b, s00a := srcAt(src, sx0, sy0, o)
+	s10r, s10g, s10b, s10a := srcAt(src, sx1, sy0, o)
+	s01r, s01g, s01b, s01a := srcAt(src, sx0, sy1, o)
+	s11r, s11g, s11b, s11a := srcAt(src, sx1, sy1, o)
+	r = yFrac1*(xFrac1*s00r+xFrac0*s10r) + yFrac0*(xFrac1*s01r+xFrac0*s11r)
+	g = yFrac1*(xFrac1*s00g+xFrac0*s10g) + yFrac0*(xFrac1*s01g+xFrac0*s11g)
+	b = yFrac1*(xFrac1*s00b+xFrac0*s10b) + yFrac0*(xFrac1*s01b+xFrac0*s11b)
+	a = yFrac1*(xFrac1*s00a+xFrac0*s10a) + yFrac0*(xFrac1*s01a+xFrac0*s11a)
+	return r, g, b, a
+}
+
+// kernelSampler samples a src image with a Kernel, re-using its weight
+// buffers between pixels.
+type kernelSampler struct {
+	q                  *Kernel
+	xWeights, yWeights []float64
+}
+
+func (k *kernelSampler) sample(src image.Image, sr image.Rectangle, sx, sy, xscale, yscale float64, o *Options) (r, g, b, a float64) {
+	var ix, jx, iy, jy int
+	k.xWeights, ix, jx = k.weights(k.xWeights, sx, xscale, sr.Min.X, sr.Max.X)
+	k.yWeights, iy, jy = k.weights(k.yWeights, sy, yscale, sr.Min.Y, sr.Max.Y)
+	for ky := iy; ky < jy; ky++ {
+		yWeight := k.yWeights[ky-iy]
+		if yWeight == 0 {
+			continue
+		}
+		for kx := ix; kx < jx; kx++ {
+			if w := k.xWeights[kx-ix] * yWeight; w != 0 {
+				pr, pg, pb, pa := srcAt(src, kx, ky, o)
+				r += pr * w
+				g += pg * w
+				b += pb * w
+				a += pa * w
+			}
+		}
+	}
+	return r, g, b, a
+}
+
+// weights sets buf to the normalized kernel weights, along one axis, of the
+// src pixels from i to j (exclusive) that contribute to the point s, and
+// returns the (possibly re-allocated) buf, i and j. When shrinking, the
+// kernel's support is broadened so that every src pixel is still visited.
+func (k *kernelSampler) weights(buf []float64, s, scale float64, min, max int) (buf1 []float64, i, j int) {
+	halfWidth, argScale := k.q.Support, 1.0
+	if scale > 1 {
+		halfWidth *= scale
+		argScale = 1 / scale
+	}
+	s -= 0.5
+	i = int(math.Floor(s - halfWidth))
+	if i < min {
+		i = min
+	}
+	j = int(math.Ceil(s + halfWidth))
+	if j > max {
+		j = max
+	}
+	if n := j - i; cap(buf) < n {
+		buf = make([]float64, n)
+	} else {
+		buf = buf[:n]
+	}
+	total := 0.0
+	for kk := i; kk < j; kk++ {
+		w := 0.0
+		if t := abs((s - float64(kk)) * argScale); t < k.q.Support {
+			w = k.q.At(t)
+		}
+		buf[kk-i] = w
+		total += w
+	}
+	for x := range buf {
+		buf[x] /= total
+	}
+	return buf, i, j
+}
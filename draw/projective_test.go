@@ -0,0 +1,189 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestHomography(t *testing.T) {
+	from := [4]f64.Vec2{{12, 7}, {95, 20}, {88, 110}, {3, 90}}
+	to := [4]f64.Vec2{{0, 0}, {64, 0}, {64, 48}, {0, 48}}
+	m, ok := Homography(from, to)
+	if !ok {
+		t.Fatal("Homography: got !ok")
+	}
+	for i, p := range from {
+		w := m[6]*p[0] + m[7]*p[1] + m[8]
+		x := (m[0]*p[0] + m[1]*p[1] + m[2]) / w
+		y := (m[3]*p[0] + m[4]*p[1] + m[5]) / w
+		if math.Abs(x-to[i][0]) > 1e-9 || math.Abs(y-to[i][1]) > 1e-9 {
+			t.Errorf("point %d: got (%g, %g), want %v", i, x, y, to[i])
+		}
+	}
+
+	collinear := [4]f64.Vec2{{0, 0}, {1, 1}, {2, 2}, {0, 5}}
+	if _, ok := Homography(collinear, to); ok {
+		t.Error("collinear from: got ok, want !ok")
+	}
+	if _, ok := Homography(from, collinear); ok {
+		t.Error("collinear to: got ok, want !ok")
+	}
+}
+
+// TestTransformProjectiveAffine checks that the projective implementation,
+// given an affine transform, matches the affine Transform methods.
+func TestTransformProjectiveAffine(t *testing.T) {
+	src, _ := srcRGBA(image.Rect(0, 0, 30, 20))
+	sr := src.Bounds()
+	s2d := f64.Aff3{1.2, 0.4, 5, -0.3, 0.9, 18}
+	m := f64.Mat3{s2d[0], s2d[1], s2d[2], s2d[3], s2d[4], s2d[5], 0, 0, 1}
+
+	testCases := []struct {
+		name   string
+		q      Interpolator
+		sample projectiveSampler
+	}{
+		{"nn", NearestNeighbor, nnSample},
+		{"ab", ApproxBiLinear, ablSample},
+		{"cr", CatmullRom, (&kernelSampler{q: CatmullRom}).sample},
+	}
+	for _, tc := range testCases {
+		for _, op := range []Op{Over, Src} {
+			want := image.NewRGBA(image.Rect(0, 0, 50, 50))
+			got := image.NewRGBA(image.Rect(0, 0, 50, 50))
+			for i := range want.Pix {
+				want.Pix[i], got.Pix[i] = 0x40, 0x40
+			}
+			tc.q.Transform(want, s2d, src, sr, op, nil)
+			transformProjective(got, &m, src, sr, op, nil, tc.sample)
+			for y := 0; y < 50; y++ {
+				for x := 0; x < 50; x++ {
+					g, w := got.RGBAAt(x, y), want.RGBAAt(x, y)
+					if !close8(g.R, w.R) || !close8(g.G, w.G) || !close8(g.B, w.B) || !close8(g.A, w.A) {
+						t.Errorf("%s, op=%v, (%d, %d): got %v, want %v", tc.name, op, x, y, g, w)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func close8(a, b uint8) bool {
+	return a-b <= 1 || b-a <= 1
+}
+
+func TestTransformProjectiveKeystone(t *testing.T) {
+	src, _ := srcRGBA(image.Rect(0, 0, 40, 40))
+	sr := src.Bounds()
+	to := [4]f64.Vec2{{10, 5}, {70, 15}, {60, 75}, {5, 50}}
+	from := [4]f64.Vec2{{0, 0}, {40, 0}, {40, 40}, {0, 40}}
+	s2d, ok := Homography(from, to)
+	if !ok {
+		t.Fatal("Homography: got !ok")
+	}
+	d2s, _ := Homography(to, from)
+
+	dst := image.NewRGBA(image.Rect(0, 0, 80, 80))
+	NearestNeighbor.(ProjectiveTransformer).TransformProjective(dst, s2d, src, sr, Src, nil)
+
+	n := 0
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 80; x++ {
+			dxf, dyf := float64(x)+0.5, float64(y)+0.5
+			w := d2s[6]*dxf + d2s[7]*dyf + d2s[8]
+			sx := (d2s[0]*dxf + d2s[1]*dyf + d2s[2]) / w
+			sy := (d2s[3]*dxf + d2s[4]*dyf + d2s[5]) / w
+			// Skip pixels that are too close to a src pixel boundary for
+			// rounding errors not to matter.
+			if math.Abs(sx-math.Floor(sx+0.5)) < 1e-6 || math.Abs(sy-math.Floor(sy+0.5)) < 1e-6 {
+				continue
+			}
+			want := color.RGBA{}
+			if p := image.Pt(int(math.Floor(sx)), int(math.Floor(sy))); p.In(sr) {
+				want = src.(*image.RGBA).RGBAAt(p.X, p.Y)
+				n++
+			}
+			if got := dst.RGBAAt(x, y); got != want {
+				t.Fatalf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	if n == 0 {
+		t.Fatal("no pixels were drawn")
+	}
+}
+
+func TestTransformProjectiveHorizon(t *testing.T) {
+	// The line y = 10 in src space is mapped to infinity, so only the part
+	// of sr above it can be drawn.
+	src := image.NewUniform(color.RGBA{0xff, 0x00, 0x00, 0xff})
+	sr := image.Rect(0, 0, 20, 20)
+	m := f64.Mat3{
+		1, 0, 0,
+		0, 1, 0,
+		0, -0.1, 1,
+	}
+	for _, q := range []Interpolator{NearestNeighbor, ApproxBiLinear, CatmullRom} {
+		dst := image.NewRGBA(image.Rect(0, 0, 100, 100))
+		q.(ProjectiveTransformer).TransformProjective(dst, m, src, sr, Src, nil)
+		// The src point (x, y) maps to (x, y) / (1 - y/10), so the dst
+		// point (5.5, 2.5) comes from (4.4, 2) but (50.5, 2.5) comes from
+		// (40.4, 2), which is outside of sr.
+		if got := dst.RGBAAt(5, 2); got.A != 0xff {
+			t.Errorf("%T: (5, 2): got %v, want opaque", q, got)
+		}
+		if got := dst.RGBAAt(50, 2); got.A != 0 {
+			t.Errorf("%T: (50, 2): got %v, want transparent", q, got)
+		}
+	}
+}
+
+// TestTransformMapAffine checks that the TransformMap methods, given a Mapper
+// for an affine transform, match the TransformProjective methods.
+func TestTransformMapAffine(t *testing.T) {
+	src, _ := srcRGBA(image.Rect(0, 0, 30, 20))
+	sr := src.Bounds()
+	s2d := f64.Mat3{1.2, 0.4, 5, -0.3, 0.9, 18, 0, 0, 1}
+	d2s, _ := s2d.Invert()
+	m := MapperFunc(func(dx, dy float64) (sx, sy float64, ok bool) {
+		return d2s[0]*dx + d2s[1]*dy + d2s[2], d2s[3]*dx + d2s[4]*dy + d2s[5], true
+	})
+
+	testCases := []struct {
+		name string
+		q    Interpolator
+	}{
+		{"nn", NearestNeighbor},
+		{"ab", ApproxBiLinear},
+		{"cr", CatmullRom},
+	}
+	for _, tc := range testCases {
+		for _, op := range []Op{Over, Src} {
+			want := image.NewRGBA(image.Rect(0, 0, 50, 50))
+			got := image.NewRGBA(image.Rect(0, 0, 50, 50))
+			for i := range want.Pix {
+				want.Pix[i], got.Pix[i] = 0x40, 0x40
+			}
+			tc.q.(ProjectiveTransformer).TransformProjective(want, s2d, src, sr, op, nil)
+			tc.q.(MapTransformer).TransformMap(got, m, src, sr, op, nil)
+			for y := 0; y < 50; y++ {
+				for x := 0; x < 50; x++ {
+					g, w := got.RGBAAt(x, y), want.RGBAAt(x, y)
+					if !close8(g.R, w.R) || !close8(g.G, w.G) || !close8(g.B, w.B) || !close8(g.A, w.A) {
+						t.Errorf("%s, op=%v, (%d, %d): got %v, want %v", tc.name, op, x, y, g, w)
+						break
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Quality measures how well an Interpolator scales an image down, for
+// comparing kernels objectively. It is returned by MeasureQuality.
+type Quality struct {
+	// Aliasing is the RMS deviation from mid-gray, in the range [0, 0.5], of
+	// a scaled zone plate where its detail is finer than the destination can
+	// represent. An ideal interpolator filters such detail out, leaving flat
+	// mid-gray; what remains shows up as spurious moiré rings. Lower is
+	// better.
+	Aliasing float64
+
+	// Sharpness is the RMS contrast of a scaled zone plate, where its detail
+	// is coarse enough for the destination to represent, relative to that of
+	// an ideal interpolator. It is typically in the range [0, 1], and higher
+	// means less blurry.
+	Sharpness float64
+
+	// Ringing is the largest overshoot or undershoot of a scaled step edge,
+	// as a fraction of the step's height. Kernels with negative lobes, such
+	// as CatmullRom and Lanczos3, ring, which shows up as halos around edges.
+	// Lower is better.
+	Ringing float64
+}
+
+// qualitySize is the width and height of the source images that
+// MeasureQuality scales.
+const qualitySize = 512
+
+// MeasureQuality scales synthetic test images down by the given factor, such
+// as 3 for scaling 300 pixels down to 100, using q, and measures the quality
+// of the results.
+//
+// The factor must be greater than 1. Aliasing is only measurable for factors
+// above 1.2, and is zero otherwise.
+func MeasureQuality(q Interpolator, factor float64) Quality {
+	const n = qualitySize
+	m := int(math.Floor(0.5 + n/factor))
+	if m < 1 {
+		m = 1
+	}
+	// f is the actual factor, after rounding the destination size.
+	f := float64(n) / float64(m)
+
+	// The zone plate's phase at distance d from its center is k*d², so its
+	// frequency is k*d/π cycles per source pixel, reaching the source's
+	// Nyquist frequency of 0.5 at distance radius.
+	const radius = n / 2
+	k := math.Pi / (2 * radius)
+	zonePlate := func(dx, dy float64) float64 {
+		return 0.5 + 0.5*math.Cos(k*(dx*dx+dy*dy))
+	}
+	src := image.NewGray16(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := zonePlate(float64(x)+0.5-radius, float64(y)+0.5-radius)
+			src.SetGray16(x, y, color.Gray16{uint16(math.Floor(0.5 + 0xffff*v))})
+		}
+	}
+	dst := image.NewRGBA64(image.Rect(0, 0, m, m))
+	q.Scale(dst, dst.Bounds(), src, src.Bounds(), Src, nil)
+
+	// The destination's Nyquist frequency is reached at distance radius/f.
+	// Detail is measured well within the passband, and aliasing is measured
+	// beyond a transition band, but within the source's own passband.
+	var (
+		aliasSum, aliasN      float64
+		contrastSum, idealSum float64
+	)
+	for y := 0; y < m; y++ {
+		for x := 0; x < m; x++ {
+			dx := (float64(x)+0.5)*f - radius
+			dy := (float64(y)+0.5)*f - radius
+			d := math.Sqrt(dx*dx + dy*dy)
+			v := float64(dst.RGBA64At(x, y).R)/0xffff - 0.5
+			switch {
+			case d < 0.5*radius/f:
+				ideal := zonePlate(dx, dy) - 0.5
+				contrastSum += v * v
+				idealSum += ideal * ideal
+			case 1.2*radius/f < d && d < radius:
+				aliasSum += v * v
+				aliasN++
+			}
+		}
+	}
+	var ret Quality
+	if aliasN > 0 {
+		ret.Aliasing = math.Sqrt(aliasSum / aliasN)
+	}
+	if idealSum > 0 {
+		ret.Sharpness = math.Sqrt(contrastSum / idealSum)
+	}
+	ret.Ringing = measureRinging(q, n, m)
+	return ret
+}
+
+// measureRinging scales an n by n image of a vertical step edge, from
+// quarter-gray to three-quarters-gray, down to m by m, and returns the largest
+// overshoot or undershoot as a fraction of the step's height. The step is
+// between mid-grays so that the overshoot is not clipped.
+func measureRinging(q Interpolator, n, m int) float64 {
+	const lo, hi = 0x4000, 0xc000
+	src := image.NewGray16(image.Rect(0, 0, n, n))
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			v := uint16(lo)
+			if x >= n/2 {
+				v = hi
+			}
+			src.SetGray16(x, y, color.Gray16{v})
+		}
+	}
+	dst := image.NewRGBA64(image.Rect(0, 0, m, m))
+	q.Scale(dst, dst.Bounds(), src, src.Bounds(), Src, nil)
+
+	ringing := 0.0
+	y := m / 2
+	for x := 0; x < m; x++ {
+		v := float64(dst.RGBA64At(x, y).R)
+		if r := (v - hi) / (hi - lo); ringing < r {
+			ringing = r
+		}
+		if r := (lo - v) / (hi - lo); ringing < r {
+			ringing = r
+		}
+	}
+	return ringing
+}
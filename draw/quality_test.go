@@ -0,0 +1,31 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import "testing"
+
+func TestMeasureQuality(t *testing.T) {
+	nn := MeasureQuality(NearestNeighbor, 4)
+	bl := MeasureQuality(BiLinear, 4)
+	cr := MeasureQuality(CatmullRom, 4)
+
+	if nn.Aliasing < 4*cr.Aliasing {
+		t.Errorf("NearestNeighbor aliasing %.4f is not much worse than CatmullRom's %.4f", nn.Aliasing, cr.Aliasing)
+	}
+	if bl.Sharpness >= cr.Sharpness {
+		t.Errorf("BiLinear sharpness %.4f is not worse than CatmullRom's %.4f", bl.Sharpness, cr.Sharpness)
+	}
+	if bl.Ringing != 0 {
+		t.Errorf("BiLinear ringing: got %.4f, want 0", bl.Ringing)
+	}
+	if cr.Ringing <= 0 {
+		t.Errorf("CatmullRom ringing: got %.4f, want > 0", cr.Ringing)
+	}
+	for _, q := range []Quality{nn, bl, cr} {
+		if q.Aliasing < 0 || q.Aliasing > 0.5 || q.Sharpness < 0.5 || q.Sharpness > 1.1 {
+			t.Errorf("out of range: %+v", q)
+		}
+	}
+}
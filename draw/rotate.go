@@ -0,0 +1,144 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// Rotate90 rotates the part of the source image defined by src and sr by 90
+// degrees clockwise and writes the result of a Porter-Duff composition to
+// the destination image, such that the rotated sr's top-left corner is at
+// dp. The rotated rectangle has sr's width and height swapped.
+//
+// Like Copy, and unlike Rotate, it maps pixels exactly, without
+// interpolation.
+func Rotate90(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	h := float64(sr.Dy())
+	x0, y0 := float64(sr.Min.X), float64(sr.Min.Y)
+	NearestNeighbor.Transform(dst, f64.Aff3{
+		0, -1, float64(dp.X) + h + y0,
+		1, 0, float64(dp.Y) - x0,
+	}, src, sr, op, opts)
+}
+
+// Rotate180 is like Rotate90 but rotates by 180 degrees. The rotated
+// rectangle has sr's width and height.
+func Rotate180(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	w, h := float64(sr.Dx()), float64(sr.Dy())
+	x0, y0 := float64(sr.Min.X), float64(sr.Min.Y)
+	NearestNeighbor.Transform(dst, f64.Aff3{
+		-1, 0, float64(dp.X) + w + x0,
+		0, -1, float64(dp.Y) + h + y0,
+	}, src, sr, op, opts)
+}
+
+// Rotate270 is like Rotate90 but rotates by 270 degrees clockwise (90
+// degrees counter-clockwise).
+func Rotate270(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	w := float64(sr.Dx())
+	x0, y0 := float64(sr.Min.X), float64(sr.Min.Y)
+	NearestNeighbor.Transform(dst, f64.Aff3{
+		0, 1, float64(dp.X) - y0,
+		-1, 0, float64(dp.Y) + w + x0,
+	}, src, sr, op, opts)
+}
+
+// FlipH is like Rotate180 but mirrors sr left to right.
+func FlipH(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	w := float64(sr.Dx())
+	x0, y0 := float64(sr.Min.X), float64(sr.Min.Y)
+	NearestNeighbor.Transform(dst, f64.Aff3{
+		-1, 0, float64(dp.X) + w + x0,
+		0, 1, float64(dp.Y) - y0,
+	}, src, sr, op, opts)
+}
+
+// FlipV is like Rotate180 but mirrors sr top to bottom.
+func FlipV(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	h := float64(sr.Dy())
+	x0, y0 := float64(sr.Min.X), float64(sr.Min.Y)
+	NearestNeighbor.Transform(dst, f64.Aff3{
+		1, 0, float64(dp.X) - x0,
+		0, -1, float64(dp.Y) + h + y0,
+	}, src, sr, op, opts)
+}
+
+// RotationMatrix returns the affine transform that rotates by angle radians
+// clockwise about the point center. Clockwise is as displayed, with the Y
+// axis increasing down.
+//
+// Coordinates are continuous, so that the pixel (x, y) covers the square
+// from (x, y) to (x+1, y+1). For example, the center of a 100×50 image with
+// bounds starting at (0, 0) is (50, 25), not (49.5, 24.5).
+func RotationMatrix(angle float64, center f64.Vec2) f64.Aff3 {
+	sin, cos := math.Sincos(angle)
+	cx, cy := center[0], center[1]
+	return f64.Aff3{
+		cos, -sin, cx - cos*cx + sin*cy,
+		sin, cos, cy - sin*cx - cos*cy,
+	}
+}
+
+// RotatedBounds returns the smallest rectangle that contains sr after it is
+// rotated by angle radians clockwise about the point center. It is the
+// destination rectangle that Rotate can write to, and so can be used to
+// allocate the dst image.
+func RotatedBounds(sr image.Rectangle, angle float64, center f64.Vec2) image.Rectangle {
+	if sr.Empty() {
+		return image.Rectangle{}
+	}
+	m := RotationMatrix(angle, center)
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range [4]image.Point{
+		sr.Min, {sr.Max.X, sr.Min.Y}, sr.Max, {sr.Min.X, sr.Max.Y},
+	} {
+		sx, sy := float64(p.X), float64(p.Y)
+		dx := m[0]*sx + m[1]*sy + m[2]
+		dy := m[3]*sx + m[4]*sy + m[5]
+		minX, maxX = math.Min(minX, dx), math.Max(maxX, dx)
+		minY, maxY = math.Min(minY, dy), math.Max(maxY, dy)
+	}
+	// Round values within rounding error of an integer, such as those from
+	// rotating by a multiple of 90 degrees, to that integer, instead of
+	// growing the bounds by a pixel.
+	snap := func(x float64, round func(float64) float64) int {
+		if r := math.Floor(x + 0.5); math.Abs(x-r) < 1e-9 {
+			return int(r)
+		}
+		return int(round(x))
+	}
+	return image.Rect(
+		snap(minX, math.Floor), snap(minY, math.Floor),
+		snap(maxX, math.Ceil), snap(maxY, math.Ceil),
+	)
+}
+
+// Rotate rotates the part of the source image defined by src and sr by angle
+// radians clockwise about the point center, using the interpolator q, and
+// writes the result of a Porter-Duff composition to the destination image.
+// The point center is in both src and dst space: it is the one point that
+// the rotation does not move. The affected part of dst is (at most)
+// RotatedBounds(sr, angle, center).
+//
+// For example, to rotate all of src about its center into a new image:
+//
+//	sr := src.Bounds()
+//	center := f64.Vec2{
+//		float64(sr.Min.X+sr.Max.X) / 2,
+//		float64(sr.Min.Y+sr.Max.Y) / 2,
+//	}
+//	dst := image.NewRGBA(RotatedBounds(sr, angle, center))
+//	Rotate(dst, src, sr, angle, center, CatmullRom, Src, nil)
+//
+// For rotations by multiples of 90 degrees, Rotate90, Rotate180 and
+// Rotate270 are faster and exact.
+func Rotate(dst Image, src image.Image, sr image.Rectangle, angle float64, center f64.Vec2, q Interpolator, op Op, opts *Options) {
+	q.Transform(dst, RotationMatrix(angle, center), src, sr, op, opts)
+}
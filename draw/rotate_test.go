@@ -0,0 +1,102 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+func TestRotateExact(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 20, 15, 23))
+	fillPix(rand.New(rand.NewSource(0)), src.Pix)
+	sr := image.Rect(11, 20, 15, 23) // 4×3, not starting at src.Bounds().Min.
+	dp := image.Point{5, 7}
+	w, h := sr.Dx(), sr.Dy()
+
+	testCases := []struct {
+		name string
+		f    func(Image, image.Point, image.Image, image.Rectangle, Op, *Options)
+		size image.Point
+		// dstXY maps a sr-relative (u, v) to a dp-relative (x, y).
+		dstXY func(u, v int) (x, y int)
+	}{
+		{"Rotate90", Rotate90, image.Point{h, w}, func(u, v int) (int, int) { return h - 1 - v, u }},
+		{"Rotate180", Rotate180, image.Point{w, h}, func(u, v int) (int, int) { return w - 1 - u, h - 1 - v }},
+		{"Rotate270", Rotate270, image.Point{h, w}, func(u, v int) (int, int) { return v, w - 1 - u }},
+		{"FlipH", FlipH, image.Point{w, h}, func(u, v int) (int, int) { return w - 1 - u, v }},
+		{"FlipV", FlipV, image.Point{w, h}, func(u, v int) (int, int) { return u, h - 1 - v }},
+	}
+	for _, tc := range testCases {
+		dst := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		tc.f(dst, dp, src, sr, Src, nil)
+
+		want := image.NewRGBA(image.Rect(0, 0, 20, 20))
+		for v := 0; v < h; v++ {
+			for u := 0; u < w; u++ {
+				x, y := tc.dstXY(u, v)
+				want.Set(dp.X+x, dp.Y+y, src.At(sr.Min.X+u, sr.Min.Y+v))
+			}
+		}
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				if got, want := dst.RGBAAt(x, y), want.RGBAAt(x, y); got != want {
+					t.Errorf("%s: (%d, %d): got %v, want %v", tc.name, x, y, got, want)
+				}
+			}
+		}
+		if got := (image.Rectangle{dp, dp.Add(tc.size)}); !got.In(dst.Bounds()) {
+			t.Errorf("%s: bad test case size", tc.name)
+		}
+	}
+}
+
+func TestRotatedBounds(t *testing.T) {
+	sr := image.Rect(0, 0, 100, 50)
+	center := f64.Vec2{50, 25}
+	testCases := []struct {
+		angle float64
+		want  image.Rectangle
+	}{
+		{0, image.Rect(0, 0, 100, 50)},
+		{math.Pi / 2, image.Rect(25, -25, 75, 75)},
+		{math.Pi, image.Rect(0, 0, 100, 50)},
+		{-math.Pi / 2, image.Rect(25, -25, 75, 75)},
+		{math.Pi / 4, image.Rect(-4, -29, 104, 79)},
+	}
+	for _, tc := range testCases {
+		if got := RotatedBounds(sr, tc.angle, center); got != tc.want {
+			t.Errorf("angle %v: got %v, want %v", tc.angle, got, tc.want)
+		}
+	}
+}
+
+// TestRotateMatchesRotate90 tests that Rotate, by 90 degrees about the right
+// center, agrees with the exact Rotate90.
+func TestRotateMatchesRotate90(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 6, 4))
+	fillPix(rand.New(rand.NewSource(1)), src.Pix)
+	sr := src.Bounds()
+
+	// Rotating a 6×4 rectangle about (2, 2) maps it to (0, 0)-(4, 6).
+	center := f64.Vec2{2, 2}
+	dr := RotatedBounds(sr, math.Pi/2, center)
+	if want := image.Rect(0, 0, 4, 6); dr != want {
+		t.Fatalf("RotatedBounds: got %v, want %v", dr, want)
+	}
+	got := image.NewRGBA(dr)
+	Rotate(got, src, sr, math.Pi/2, center, NearestNeighbor, Src, nil)
+	want := image.NewRGBA(dr)
+	Rotate90(want, dr.Min, src, sr, Src, nil)
+	for i := range got.Pix {
+		if got.Pix[i] != want.Pix[i] {
+			t.Fatalf("Pix[%d]: got %#02x, want %#02x", i, got.Pix[i], want.Pix[i])
+		}
+	}
+}
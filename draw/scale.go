@@ -18,16 +18,40 @@ import (
 // Copy copies the part of the source image defined by src and sr and writes
 // the result of a Porter-Duff composition to the part of the destination image
 // defined by dst and the translation of sr so that sr.Min translates to dp.
+//
+// Copying between *image.Paletted images copies or maps their color indexes,
+// without converting each pixel's color. To copy between *image.YCbCr images
+// without converting to RGB, use CopyYCbCr.
 func Copy(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if compositeDst(opts) {
+		drawComposited(dst, sr.Add(dp.Sub(sr.Min)), opts, func(tmp Image, o *Options) {
+			Copy(tmp, dp, src, sr, Src, o)
+		})
+		return
+	}
+	if p, ok := ditherDst(dst, opts); ok {
+		drawDithered(p, sr.Add(dp.Sub(sr.Min)), opts, func(tmp Image, o *Options) {
+			Copy(tmp, dp, src, sr, op, o)
+		})
+		return
+	}
+	if isFloat(dst) || isFloat(src) {
+		scaleFloat(dst, sr.Add(dp.Sub(sr.Min)), src, sr, op, opts, nnSample)
+		return
+	}
 	var o Options
 	if opts != nil {
 		o = *opts
 	}
+	src = applyColorMatrix(src, &o)
 	dr := sr.Add(dp.Sub(sr.Min))
+	if scalePaletted(dst, dr, src, sr, op, &o) {
+		return
+	}
 	if o.DstMask == nil {
 		DrawMask(dst, dr, src, sr.Min, o.SrcMask, o.SrcMaskP.Add(sr.Min), op)
 	} else {
-		NearestNeighbor.Scale(dst, dr, src, sr, op, opts)
+		NearestNeighbor.Scale(dst, dr, src, sr, op, &o)
 	}
 }
 
@@ -35,6 +59,18 @@ func Copy(dst Image, dp image.Point, src image.Image, sr image.Rectangle, op Op,
 // the result of a Porter-Duff composition to the part of the destination image
 // defined by dst and dr.
 //
+// Source pixels are weighted and summed as alpha-premultiplied colors, so
+// that the color of fully transparent pixels does not bleed into their
+// neighbors, and the result is un-premultiplied when dst holds
+// non-premultiplied colors, such as an *image.NRGBA.
+//
+// An *image.RGBA64 holds alpha-premultiplied 16-bit colors, the precision of
+// the Scalers' and Transformers' own intermediate values, and has fast paths
+// as both a dst and a src image. It is the intermediate image to use when
+// chaining operations, such as scaling and then copying with a ColorMatrix,
+// so that the intermediate results are not quantized to 8 bits per channel
+// between steps.
+//
 // A Scaler is safe to use concurrently.
 type Scaler interface {
 	Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options)
@@ -60,6 +96,10 @@ type Transformer interface {
 // Options are optional parameters to Copy, Scale and Transform.
 //
 // A nil *Options means to use the default (zero) values of each field.
+//
+// The Porter-Duff operator, Over or Src, is not an option: it is the op
+// argument of each of those functions and methods. The other operators are
+// chosen by the CompositeOp field.
 type Options struct {
 	// Masks limit what parts of the dst image are drawn to and what parts of
 	// the src image are drawn from.
@@ -91,7 +131,160 @@ type Options struct {
 	SrcMask  image.Image
 	SrcMaskP image.Point
 
-	// TODO: a smooth vs sharp edges option, for arbitrary rotations?
+	// Concurrency is the maximum number of goroutines that a Kernel's Scale
+	// method, and the Scalers returned by its NewScaler method, use. The
+	// source and destination rows are split into bands that are processed in
+	// parallel, and the result is identical to that of scaling on a single
+	// goroutine. Zero or one means to use only the calling goroutine. Other
+	// interpolators, and Transform, ignore it.
+	//
+	// When greater than one, the dst image's Set method may be called
+	// concurrently for different pixels, which is safe for the standard
+	// library's image types.
+	Concurrency int
+
+	// EdgeOp is how Transform and TransformProjective treat the dst pixels
+	// that map to src points outside of sr. The default, EdgeNone, leaves
+	// them unaffected. Other values affect every dst pixel, such as for
+	// letterboxed or tiled output, and also smooth the edges of sr when
+	// interpolating, as pixels just inside sr are blended with those just
+	// outside. EdgeColor is the color outside of sr for EdgeConstant. Scale
+	// and Copy ignore these fields.
+	EdgeOp    EdgeOp
+	EdgeColor color.Color
+
+	// ColorMatrix, if non-nil, transforms the color of every src pixel, in
+	// the same pass as copying, scaling or transforming. It is applied before
+	// any SrcMask, and does not affect EdgeColor. It disables the fast paths
+	// for specific src image types.
+	ColorMatrix *ColorMatrix
+
+	// ScaleBuffer, if non-nil, holds the temporary buffer used by a Kernel's
+	// Scale method, and the Scalers returned by its NewScaler method, instead
+	// of allocating one per call. It is grown as needed and reused by later
+	// calls, such as when scaling every frame of a video. Other interpolators,
+	// and Transform, ignore it.
+	ScaleBuffer *ScaleBuffer
+
+	// RowsDone, if non-nil, is called by a Kernel's Scale method, and the
+	// Scalers returned by its NewScaler method, each time that the dst rows
+	// [y0, y1) are final, such as for a streaming image encoder. The rows are
+	// finished in bands, in increasing order of y. Each band is the part of dr
+	// within dst's bounds at the time, after the previous band.
+	//
+	// RowsDone may change dst's bounds to cover later rows, such as by
+	// setting an *image.RGBA's Rect, so that dst only needs to hold one band
+	// of pixels at a time, instead of the whole of dr. Scaling stops when
+	// dst's bounds do not cover any more of dr's rows. Other interpolators,
+	// and Transform, ignore it.
+	RowsDone func(y0, y1 int)
+
+	// AntiRinging is whether a Kernel's Scale method, and the Scalers
+	// returned by its NewScaler method, clamp each dst pixel to the minimum
+	// and maximum of the src pixels that contribute to it. This removes the
+	// halos that kernels with negative lobes, such as CatmullRom and
+	// Lanczos3, produce next to hard edges, such as in screenshots of user
+	// interfaces, at the cost of some sharpness and speed. The clamping is
+	// separable: each of the horizontal and vertical passes is clamped in
+	// turn. Other interpolators, and Transform, ignore it.
+	AntiRinging bool
+
+	// Alignment is how Scale and Copy map dst pixels to src pixels. The
+	// default, AlignCenters, maps the edges of dr to the edges of sr. Other
+	// values, such as for matching the output of other image libraries, are
+	// described at each Alignment constant. Transform and its variants
+	// ignore it, as their matrix defines the mapping.
+	Alignment Alignment
+
+	// DitherOp is how Copy, Scale, Transform and their variants choose the
+	// colors of an *image.Paletted dst, such as one whose palette was chosen
+	// by the MedianCut Quantizer. The default, DitherNone, takes the nearest
+	// palette color for each pixel. Other values draw onto a temporary
+	// image first, and then dither it onto dst, ignoring RowsDone. The dst
+	// pixels that are left unchanged keep their color index. Other dst
+	// types ignore it.
+	DitherOp DitherOp
+
+	// MultiStep is whether a Kernel's Scale method scales down by large
+	// factors in steps, as the Scalers returned by MultiStep do. It is
+	// usually much faster, with as good or better quality, when each dst
+	// pixel covers more than 4x4 src pixels. Other interpolators, and the
+	// Scalers returned by NewScaler, ignore it.
+	MultiStep bool
+
+	// CompositeOp, if not CompositeNone, is the Porter-Duff operator that
+	// Copy, Scale, Transform and their variants use instead of their op
+	// argument, such as CompositeIn to draw the src only where the dst is
+	// opaque. Like Over and Src, it only affects the dst pixels that the src
+	// is drawn to. The src is first drawn onto a temporary image, ignoring
+	// RowsDone, which is then composited onto dst, with fast paths for an
+	// *image.RGBA dst and no DstMask.
+	CompositeOp CompositeOp
+}
+
+// Alignment is how scaling maps dst coordinates to src coordinates.
+//
+// In each mode, the center of each dst pixel is mapped to a src point, and
+// that point is sampled: NearestNeighbor takes the src pixel whose center is
+// nearest, and the other interpolators blend the src pixels around it.
+type Alignment int
+
+const (
+	// AlignCenters maps the edges of dr to the edges of sr, so that the dst
+	// pixel centers are spread evenly over sr, half a dst pixel in from its
+	// edges. It matches Pillow, OpenCV's INTER_LINEAR, INTER_CUBIC and
+	// INTER_NEAREST_EXACT, PyTorch and TensorFlow with half_pixel_centers,
+	// and GPU texture sampling.
+	AlignCenters Alignment = iota
+	// AlignCorners maps the centers of the corner pixels of dr to the centers
+	// of the corner pixels of sr, so that the edges of sr are sampled exactly
+	// and the dst pixel centers are spread evenly between them. It matches
+	// PyTorch and TensorFlow with align_corners. A dr that is one pixel wide
+	// (or high) samples the first column (or row) of sr.
+	AlignCorners
+)
+
+// mapping returns the scale and offset such that, for a's mapping of dw dst
+// columns (or rows) to sw src columns (or rows), the center of dst column x
+// maps to the src point (x+offset)*scale - offset, in units where the center
+// of src column i is at i.
+func (a Alignment) mapping(dw, sw int) (scale, offset float64) {
+	if a == AlignCorners {
+		if dw <= 1 {
+			return 0, 0
+		}
+		return float64(sw-1) / float64(dw-1), 0
+	}
+	return float64(sw) / float64(dw), 0.5
+}
+
+// nnRatio returns n, o and d such that, for a's mapping of dw dst columns (or
+// rows) to sw src columns (or rows), the src column whose center is nearest
+// to that of dst column x is (2*x*n + o) / d.
+func (a Alignment) nnRatio(dw, sw int) (n, o, d uint64) {
+	if a == AlignCorners {
+		if dw <= 1 {
+			return 0, 0, 1
+		}
+		return uint64(sw - 1), uint64(dw - 1), 2 * uint64(dw-1)
+	}
+	return uint64(sw), uint64(sw), 2 * uint64(dw)
+}
+
+// ScaleBuffer is reusable scratch memory for scaling with a Kernel. See the
+// Options.ScaleBuffer field.
+//
+// The zero value is ready to use. A ScaleBuffer must not be used by more than
+// one Scale call at a time.
+type ScaleBuffer struct {
+	tmp [][4]float64
+}
+
+func (b *ScaleBuffer) get(n int) [][4]float64 {
+	if cap(b.tmp) < n {
+		b.tmp = make([][4]float64, n)
+	}
+	return b.tmp[:n]
 }
 
 // Interpolator is an interpolation algorithm, when dst and src pixels don't
@@ -99,7 +292,8 @@ type Options struct {
 //
 // Of the interpolators provided by this package:
 //	- NearestNeighbor is fast but usually looks worst.
-//	- CatmullRom is slow but usually looks best.
+//	- CatmullRom, Mitchell, Lanczos2 and Lanczos3 are slow but usually look
+//	  best.
 //	- ApproxBiLinear has reasonable speed and quality.
 //
 // The time taken depends on the size of dr. For kernel interpolators, the
@@ -123,24 +317,79 @@ type Kernel struct {
 
 // Scale implements the Scaler interface.
 func (q *Kernel) Scale(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) {
-	q.newScaler(dr.Dx(), dr.Dy(), sr.Dx(), sr.Dy(), false).Scale(dst, dr, src, sr, op, opts)
+	if opts != nil && opts.MultiStep {
+		MultiStep(q).Scale(dst, dr, src, sr, op, opts)
+		return
+	}
+	align := AlignCenters
+	if opts != nil {
+		align = opts.Alignment
+	}
+	q.newScaler(dr.Dx(), dr.Dy(), sr.Dx(), sr.Dy(), align, false).Scale(dst, dr, src, sr, op, opts)
 }
 
 // NewScaler returns a Scaler that is optimized for scaling multiple times with
-// the same fixed destination and source width and height.
+// the same fixed destination and source width and height. It is optimized
+// for the default AlignCenters; scaling with another Options.Alignment is
+// equivalent to calling q's Scale method.
 func (q *Kernel) NewScaler(dw, dh, sw, sh int) Scaler {
-	return q.newScaler(dw, dh, sw, sh, true)
+	return q.newScaler(dw, dh, sw, sh, AlignCenters, true)
+}
+
+// NewTransformer returns a Transformer that is optimized for transforming
+// multiple times with the same fixed transformation matrix m and source
+// rectangle sr, such as applying the same warp to every frame of a video.
+//
+// It precomputes the kernel weights for every destination pixel in the bounds
+// of sr transformed by m, so its memory use is proportional to that area and
+// to the kernel's support. Transforming with a different matrix or source
+// rectangle, or with an EdgeOp other than EdgeNone, is equivalent to calling
+// q's Transform method.
+func (q *Kernel) NewTransformer(m *f64.Aff3, sr image.Rectangle) Transformer {
+	z := &kernelTransformer{
+		kernel: q,
+		m:      *m,
+		sr:     sr,
+	}
+	z.precompute()
+	return z
+}
+
+// parallel calls f for consecutive bands of the rows [y0, y1) on up to n
+// goroutines, and waits for them to finish. It calls f(y0, y1) on the calling
+// goroutine if n <= 1.
+func parallel(n int, y0, y1 int32, f func(y0, y1 int32)) {
+	rows := int64(y1) - int64(y0)
+	if int64(n) > rows {
+		n = int(rows)
+	}
+	if n <= 1 {
+		f(y0, y1)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := int64(0); i < int64(n); i++ {
+		b0 := y0 + int32(rows*i/int64(n))
+		b1 := y0 + int32(rows*(i+1)/int64(n))
+		go func() {
+			defer wg.Done()
+			f(b0, b1)
+		}()
+	}
+	wg.Wait()
 }
 
-func (q *Kernel) newScaler(dw, dh, sw, sh int, usePool bool) Scaler {
+func (q *Kernel) newScaler(dw, dh, sw, sh int, align Alignment, usePool bool) Scaler {
 	z := &kernelScaler{
 		kernel:     q,
 		dw:         int32(dw),
 		dh:         int32(dh),
 		sw:         int32(sw),
 		sh:         int32(sh),
-		horizontal: newDistrib(q, int32(dw), int32(sw)),
-		vertical:   newDistrib(q, int32(dh), int32(sh)),
+		align:      align,
+		horizontal: newDistrib(q, int32(dw), int32(sw), align),
+		vertical:   newDistrib(q, int32(dh), int32(sh), align),
 	}
 	if usePool {
 		z.pool.New = func() interface{} {
@@ -168,6 +417,20 @@ var (
 	// difference can be significant.
 	ApproxBiLinear = Interpolator(ablInterpolator{})
 
+	// Box is the box kernel, which averages the source pixels under each
+	// destination pixel. It gives good quality results when scaling down, for
+	// generating thumbnails and mipmaps, and is faster than the other kernels,
+	// as it has the smallest support.
+	//
+	// Unlike ApproxBiLinear, which blends only 4 source pixels regardless of
+	// the scale factor, Box visits every source pixel, so it does not alias
+	// when scaling down by more than a factor of 2, at the cost of speed.
+	// For factors of 2 or less, ApproxBiLinear is faster and at least as
+	// good. When scaling up, Box is equivalent to NearestNeighbor.
+	Box = &Kernel{0.5, func(t float64) float64 {
+		return 1
+	}}
+
 	// BiLinear is the tent kernel. It is slow, but usually gives high quality
 	// results.
 	BiLinear = &Kernel{1, func(t float64) float64 {
@@ -187,9 +450,59 @@ var (
 		return ((-0.5*t+2.5)*t-4)*t + 2
 	}}
 
+	// Mitchell is the Mitchell-Netravali kernel, the cubic BC-spline kernel
+	// with parameters B=1/3 and C=1/3. It is very slow, but usually gives very
+	// high quality results, with less ringing but more blur than CatmullRom.
+	// It is the default filter for resizing in some other image processing
+	// software, such as ImageMagick.
+	Mitchell = NewBCSpline(1.0/3, 1.0/3)
+
+	// Lanczos2 is the Lanczos kernel with a = 2, a sinc function windowed by
+	// a wider sinc function. It is very slow, but usually gives very high
+	// quality results, similar to CatmullRom.
+	Lanczos2 = &Kernel{2, func(t float64) float64 {
+		return lanczos(t, 2)
+	}}
+
+	// Lanczos3 is the Lanczos kernel with a = 3. It is very slow, but usually
+	// gives very high quality results, sharper than Lanczos2 at the cost of
+	// more ringing near edges. It is a common choice for downscaling
+	// photographs.
+	Lanczos3 = &Kernel{3, func(t float64) float64 {
+		return lanczos(t, 3)
+	}}
+
 	// TODO: a Kaiser-Bessel kernel?
 )
 
+// NewBCSpline returns the cubic BC-spline kernel with the given B and C
+// parameters, as described by Mitchell and Netravali. For example, B=0 and
+// C=0.5 gives the CatmullRom kernel, B=1/3 and C=1/3 gives the Mitchell kernel
+// and B=1 and C=0 gives the blurry but ringing-free cubic B-spline kernel.
+// Kernels with B + 2*C = 1 are usually considered the most pleasing.
+func NewBCSpline(b, c float64) *Kernel {
+	// The kernel is the piecewise cubic
+	//	((12 - 9B - 6C)t³ + (-18 + 12B + 6C)t² + (6 - 2B)) / 6          for t < 1,
+	//	((-B - 6C)t³ + (6B + 30C)t² + (-12B - 48C)t + (8B + 24C)) / 6   for t < 2.
+	p0, p2, p3 := (6-2*b)/6, (-18+12*b+6*c)/6, (12-9*b-6*c)/6
+	q0, q1, q2, q3 := (8*b+24*c)/6, (-12*b-48*c)/6, (6*b+30*c)/6, (-b-6*c)/6
+	return &Kernel{2, func(t float64) float64 {
+		if t < 1 {
+			return (p3*t+p2)*t*t + p0
+		}
+		return ((q3*t+q2)*t+q1)*t + q0
+	}}
+}
+
+// lanczos returns the Lanczos kernel sinc(t) * sinc(t/a), for t in [0, a).
+func lanczos(t, a float64) float64 {
+	if t == 0 {
+		return 1
+	}
+	x := math.Pi * t
+	return a * math.Sin(x) * math.Sin(x/a) / (x * x)
+}
+
 type nnInterpolator struct{}
 
 type ablInterpolator struct{}
@@ -197,6 +510,7 @@ type ablInterpolator struct{}
 type kernelScaler struct {
 	kernel               *Kernel
 	dw, dh, sw, sh       int32
+	align                Alignment
 	horizontal, vertical distrib
 	pool                 sync.Pool
 }
@@ -205,6 +519,193 @@ func (z *kernelScaler) makeTmpBuf() [][4]float64 {
 	return make([][4]float64, z.dw*z.sh)
 }
 
+type kernelTransformer struct {
+	kernel *Kernel
+	m      f64.Aff3
+	sr     image.Rectangle
+	// samples hold the kernel weights of each pixel of the dst rectangle,
+	// transformRect(&m, &sr), in row-major order.
+	samples []kernelSample
+	weights []float64
+}
+
+// kernelSample is the range of src pixels, [ix, jx) × [iy, jy), that a dst
+// pixel samples. Its normalized weights are in kernelTransformer.weights:
+// (jx - ix) x weights starting at w, then (jy - iy) y weights. w is negative
+// if the dst pixel's center maps to outside of sr.
+type kernelSample struct {
+	ix, jx, iy, jy int32
+	w              int32
+}
+
+func (z *kernelTransformer) Transform(dst Image, m f64.Aff3, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	if _, ok := ditherDst(dst, opts); ok || compositeDst(opts) || m != z.m || sr != z.sr || (opts != nil && opts.EdgeOp != EdgeNone) || isFloat(dst) || isFloat(src) {
+		z.kernel.Transform(dst, m, src, sr, op, opts)
+		return
+	}
+	z.kernel.transform(dst, m, src, sr, op, opts, z)
+}
+
+// precompute sets z.samples and z.weights. Its computation matches the
+// Kernel.transform_etc methods, without their bias.
+func (z *kernelTransformer) precompute() {
+	q, sr := z.kernel, z.sr
+	dr := transformRect(&z.m, &sr)
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+	d2s, ok := z.m.Invert()
+	if !ok {
+		return
+	}
+	xscale := math.Max(abs(d2s[0]), abs(d2s[1]))
+	yscale := math.Max(abs(d2s[3]), abs(d2s[4]))
+	xHalfWidth, xKernelArgScale := q.Support, 1.0
+	if xscale > 1 {
+		xHalfWidth *= xscale
+		xKernelArgScale = 1 / xscale
+	}
+	yHalfWidth, yKernelArgScale := q.Support, 1.0
+	if yscale > 1 {
+		yHalfWidth *= yscale
+		yKernelArgScale = 1 / yscale
+	}
+
+	// weights computes the normalized weights of the src pixels [i, j) for
+	// the src coordinate s, appending them to z.weights.
+	weights := func(s, argScale float64, i, j int) {
+		w := len(z.weights)
+		total := 0.0
+		for k := i; k < j; k++ {
+			weight := 0.0
+			if t := abs((s - float64(k)) * argScale); t < q.Support {
+				weight = q.At(t)
+			}
+			z.weights = append(z.weights, weight)
+			total += weight
+		}
+		for k := range z.weights[w:] {
+			z.weights[w+k] /= total
+		}
+	}
+
+	z.samples = make([]kernelSample, 0, dr.Dx()*dr.Dy())
+	for dy := dr.Min.Y; dy < dr.Max.Y; dy++ {
+		dyf := float64(dy) + 0.5
+		for dx := dr.Min.X; dx < dr.Max.X; dx++ {
+			dxf := float64(dx) + 0.5
+			sx := d2s[0]*dxf + d2s[1]*dyf + d2s[2]
+			sy := d2s[3]*dxf + d2s[4]*dyf + d2s[5]
+			if !(image.Point{int(math.Floor(sx)), int(math.Floor(sy))}).In(sr) {
+				z.samples = append(z.samples, kernelSample{w: -1})
+				continue
+			}
+
+			sx -= 0.5
+			ix := int(math.Floor(sx - xHalfWidth))
+			if ix < sr.Min.X {
+				ix = sr.Min.X
+			}
+			jx := int(math.Ceil(sx + xHalfWidth))
+			if jx > sr.Max.X {
+				jx = sr.Max.X
+			}
+			sy -= 0.5
+			iy := int(math.Floor(sy - yHalfWidth))
+			if iy < sr.Min.Y {
+				iy = sr.Min.Y
+			}
+			jy := int(math.Ceil(sy + yHalfWidth))
+			if jy > sr.Max.Y {
+				jy = sr.Max.Y
+			}
+
+			z.samples = append(z.samples, kernelSample{
+				ix: int32(ix),
+				jx: int32(jx),
+				iy: int32(iy),
+				jy: int32(jy),
+				w:  int32(len(z.weights)),
+			})
+			weights(sx, xKernelArgScale, ix, jx)
+			weights(sy, yKernelArgScale, iy, jy)
+		}
+	}
+}
+
+// clampX clamps the values of tmp, for the rows [y0, y1) of sr, to the
+// minimum and maximum of the src pixels that contribute to each value, as
+// premultiplied by any SrcMask.
+func (z *kernelScaler) clampX(tmp [][4]float64, src image.Image, sr image.Rectangle, y0, y1 int32, opts *Options) {
+	t := int(y0) * int(z.dw)
+	srcMask, smp := opts.SrcMask, opts.SrcMaskP
+	for y := y0; y < y1; y++ {
+		for _, s := range z.horizontal.sources {
+			lo := [4]uint32{0xffff, 0xffff, 0xffff, 0xffff}
+			hi := [4]uint32{}
+			for _, c := range z.horizontal.contribs[s.i:s.j] {
+				pr, pg, pb, pa := src.At(sr.Min.X+int(c.coord), sr.Min.Y+int(y)).RGBA()
+				if srcMask != nil {
+					_, _, _, ma := srcMask.At(smp.X+sr.Min.X+int(c.coord), smp.Y+sr.Min.Y+int(y)).RGBA()
+					pr = pr * ma / 0xffff
+					pg = pg * ma / 0xffff
+					pb = pb * ma / 0xffff
+					pa = pa * ma / 0xffff
+				}
+				for i, v := range [4]uint32{pr, pg, pb, pa} {
+					if lo[i] > v {
+						lo[i] = v
+					}
+					if hi[i] < v {
+						hi[i] = v
+					}
+				}
+			}
+			p := &tmp[t]
+			for i := range p {
+				p[i] = clamp(p[i], float64(lo[i])/0xffff, float64(hi[i])/0xffff)
+			}
+			t++
+		}
+	}
+}
+
+// clampY returns the weighted sums pr, pg, pb and pa, of the column dx of
+// tmp's rows that s distributes over a dst pixel, clamped to the minimum and
+// maximum of those rows' values.
+func (z *kernelScaler) clampY(tmp [][4]float64, dx int32, s source, pr, pg, pb, pa float64) (float64, float64, float64, float64) {
+	if s.i == s.j {
+		return pr, pg, pb, pa
+	}
+	p0 := tmp[z.vertical.contribs[s.i].coord*z.dw+dx]
+	lo, hi := p0, p0
+	for _, c := range z.vertical.contribs[s.i+1 : s.j] {
+		p := &tmp[c.coord*z.dw+dx]
+		for i, v := range p {
+			lo[i] = math.Min(lo[i], v)
+			hi[i] = math.Max(hi[i], v)
+		}
+	}
+	// The sums are divided by the total weight, multiplying by
+	// s.invTotalWeight, after clamping.
+	tw := 1 / s.invTotalWeight
+	return clamp(pr, lo[0]*tw, hi[0]*tw),
+		clamp(pg, lo[1]*tw, hi[1]*tw),
+		clamp(pb, lo[2]*tw, hi[2]*tw),
+		clamp(pa, lo[3]*tw, hi[3]*tw)
+}
+
+// clamp returns f clamped to the range [lo, hi].
+func clamp(f, lo, hi float64) float64 {
+	if f < lo {
+		return lo
+	}
+	if f > hi {
+		return hi
+	}
+	return f
+}
+
 // source is a range of contribs, their inverse total weight, and that ITW
 // divided by 0xffff.
 type source struct {
@@ -229,9 +730,9 @@ type distrib struct {
 }
 
 // newDistrib returns a distrib that distributes sw source columns (or rows)
-// over dw destination columns (or rows).
-func newDistrib(q *Kernel, dw, sw int32) distrib {
-	scale := float64(sw) / float64(dw)
+// over dw destination columns (or rows), aligned by align.
+func newDistrib(q *Kernel, dw, sw int32, align Alignment) distrib {
+	scale, offset := align.mapping(int(dw), int(sw))
 	halfWidth, kernelArgScale := q.Support, 1.0
 	// When shrinking, broaden the effective kernel support so that we still
 	// visit every source pixel.
@@ -247,7 +748,7 @@ func newDistrib(q *Kernel, dw, sw int32) distrib {
 	// source column or row.
 	n, sources := int32(0), make([]source, dw)
 	for x := range sources {
-		center := (float64(x)+0.5)*scale - 0.5
+		center := (float64(x)+offset)*scale - offset
 		i := int32(math.Floor(center - halfWidth))
 		if i < 0 {
 			i = 0
@@ -324,42 +825,6 @@ func fffftou(f float64) uint16 {
 	return 0
 }
 
-// invert returns the inverse of m.
-//
-// TODO: move this into the f64 package, once we work out the convention for
-// matrix methods in that package: do they modify the receiver, take a dst
-// pointer argument, or return a new value?
-func invert(m *f64.Aff3) f64.Aff3 {
-	m00 := +m[3*1+1]
-	m01 := -m[3*0+1]
-	m02 := +m[3*1+2]*m[3*0+1] - m[3*1+1]*m[3*0+2]
-	m10 := -m[3*1+0]
-	m11 := +m[3*0+0]
-	m12 := +m[3*1+0]*m[3*0+2] - m[3*1+2]*m[3*0+0]
-
-	det := m00*m11 - m10*m01
-
-	return f64.Aff3{
-		m00 / det,
-		m01 / det,
-		m02 / det,
-		m10 / det,
-		m11 / det,
-		m12 / det,
-	}
-}
-
-func matMul(p, q *f64.Aff3) f64.Aff3 {
-	return f64.Aff3{
-		p[3*0+0]*q[3*0+0] + p[3*0+1]*q[3*1+0],
-		p[3*0+0]*q[3*0+1] + p[3*0+1]*q[3*1+1],
-		p[3*0+0]*q[3*0+2] + p[3*0+1]*q[3*1+2] + p[3*0+2],
-		p[3*1+0]*q[3*0+0] + p[3*1+1]*q[3*1+0],
-		p[3*1+0]*q[3*0+1] + p[3*1+1]*q[3*1+1],
-		p[3*1+0]*q[3*0+2] + p[3*1+1]*q[3*1+2] + p[3*1+2],
-	}
-}
-
 // transformRect returns a rectangle dr that contains sr transformed by s2d.
 func transformRect(s2d *f64.Aff3, sr *image.Rectangle) (dr image.Rectangle) {
 	ps := [...]image.Point{
@@ -523,3 +988,83 @@ func opaque(m image.Image) bool {
 	})
 	return ok && o.Opaque()
 }
+
+// scalePaletted is the fast path of Copy and of NearestNeighbor's Scale
+// between *image.Paletted images, such as sprites. With the same palette, it
+// copies the nearest source pixel's color index instead of converting it to
+// a color and back, which could pick a different index of the same color.
+// With different palettes, it maps each source index to the nearest
+// destination color once, instead of once per pixel. It returns whether it
+// applied: it does not apply with masks, for source pixels outside the
+// source bounds, or to compose a source with transparent pixels over the
+// destination.
+func scalePaletted(dst Image, dr image.Rectangle, src image.Image, sr image.Rectangle, op Op, opts *Options) bool {
+	d, ok := dst.(*image.Paletted)
+	if !ok || opts.DstMask != nil || opts.SrcMask != nil || len(d.Palette) == 0 {
+		return false
+	}
+	s, ok := src.(*image.Paletted)
+	if !ok || !sr.In(s.Rect) || (op == Over && !s.Opaque()) {
+		return false
+	}
+	// remap maps s's color indexes to d's, if their palettes differ.
+	var remap *[256]uint8
+	if !samePalette(d.Palette, s.Palette) {
+		remap = new([256]uint8)
+		for i := range remap {
+			if i < len(s.Palette) {
+				remap[i] = uint8(d.Palette.Index(s.Palette[i]))
+			}
+		}
+	}
+
+	// adr is the affected destination pixels, relative to dr.Min.
+	adr := d.Rect.Intersect(dr)
+	if adr.Empty() || sr.Empty() {
+		return true
+	}
+	adr = adr.Sub(dr.Min)
+
+	xn, xo, xd := opts.Alignment.nnRatio(dr.Dx(), sr.Dx())
+	yn, yo, yd := opts.Alignment.nnRatio(dr.Dy(), sr.Dy())
+	for dy := adr.Min.Y; dy < adr.Max.Y; dy++ {
+		sy := (2*uint64(dy)*yn + yo) / yd
+		si := (sr.Min.Y+int(sy)-s.Rect.Min.Y)*s.Stride + (sr.Min.X - s.Rect.Min.X)
+		di := d.PixOffset(dr.Min.X+adr.Min.X, dr.Min.Y+dy)
+		if dr.Dx() == sr.Dx() {
+			row := d.Pix[di : di+adr.Dx()]
+			copy(row, s.Pix[si+adr.Min.X:])
+			if remap != nil {
+				for i, c := range row {
+					row[i] = remap[c]
+				}
+			}
+			continue
+		}
+		for dx := adr.Min.X; dx < adr.Max.X; dx, di = dx+1, di+1 {
+			sx := (2*uint64(dx)*xn + xo) / xd
+			c := s.Pix[si+int(sx)]
+			if remap != nil {
+				c = remap[c]
+			}
+			d.Pix[di] = c
+		}
+	}
+	return true
+}
+
+// samePalette returns whether a and b hold the same colors, in the same
+// order.
+func samePalette(a, b color.Palette) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		r0, g0, b0, a0 := a[i].RGBA()
+		r1, g1, b1, a1 := b[i].RGBA()
+		if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+// +build gc
+// +build !noasm
+
+package draw
+
+// haveScaleSIMD is whether the kernelScaler uses the SIMD implementations of
+// its inner loops. Every amd64 CPU has SSE2, but it is a variable so that
+// tests can compare the SIMD and scalar implementations.
+var haveScaleSIMD = true
+
+// scaleYSIMDMinContribs is the fewest contribs for which scaleYSIMD is faster
+// than the scalar code, as calling it has some overhead. Scaling up with a
+// CatmullRom kernel has 4 contribs per destination pixel.
+const scaleYSIMDMinContribs = 5
+
+// scaleXRGBASIMD sets p to the sum, over contribs, of the RGBA pixel at pix[4 *
+// c.coord:], scaled from 8 to 16 bits per channel, times c.weight.
+//
+//go:noescape
+func scaleXRGBASIMD(p *[4]float64, pix []uint8, contribs []contrib)
+
+// scaleYSIMD sets p to the sum, over contribs, of tmp[c.coord * stride] times
+// c.weight.
+//
+//go:noescape
+func scaleYSIMD(p *[4]float64, tmp [][4]float64, contribs []contrib, stride int)
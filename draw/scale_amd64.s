@@ -0,0 +1,102 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+// +build gc
+// +build !noasm
+
+#include "textflag.h"
+
+// Each of these functions accumulates 4 float64 channels in two XMM
+// registers, X0 holding red and green and X1 holding blue and alpha. The
+// multiplications and additions are the same, and in the same order, as the
+// scalar Go code, so that, unless the compiler fuses the scalar code's
+// multiplications and additions, the results are identical.
+//
+// A contrib is 16 bytes: an int32 coord, 4 bytes of padding and a float64
+// weight.
+
+// func scaleXRGBASIMD(p *[4]float64, pix []uint8, contribs []contrib)
+TEXT ·scaleXRGBASIMD(SB), NOSPLIT, $0-56
+	MOVQ p+0(FP), DI
+	MOVQ pix_base+8(FP), SI
+	MOVQ contribs_base+32(FP), BX
+	MOVQ contribs_len+40(FP), CX
+
+	XORPS X0, X0
+	XORPS X1, X1
+	XORPS X7, X7
+
+xLoop:
+	CMPQ CX, $0
+	JEQ  xDone
+
+	// Load the pixel's 4 bytes and widen each byte b to the uint32 value
+	// b*0x101, by duplicating it into a uint16 and then zero-extending.
+	MOVLQSX   0(BX), AX
+	MOVL      (SI)(AX*4), X3
+	PUNPCKLBW X3, X3
+	PUNPCKLWL X7, X3
+
+	// Convert to float64s: red and green in X4, blue and alpha in X5.
+	CVTPL2PD X3, X4
+	PSHUFD   $0x4e, X3, X3
+	CVTPL2PD X3, X5
+
+	// Broadcast the weight, multiply and accumulate.
+	MOVSD    8(BX), X2
+	UNPCKLPD X2, X2
+	MULPD    X2, X4
+	MULPD    X2, X5
+	ADDPD    X4, X0
+	ADDPD    X5, X1
+
+	ADDQ $16, BX
+	DECQ CX
+	JMP  xLoop
+
+xDone:
+	MOVUPD X0, 0(DI)
+	MOVUPD X1, 16(DI)
+	RET
+
+// func scaleYSIMD(p *[4]float64, tmp [][4]float64, contribs []contrib, stride int)
+TEXT ·scaleYSIMD(SB), NOSPLIT, $0-64
+	MOVQ p+0(FP), DI
+	MOVQ tmp_base+8(FP), SI
+	MOVQ contribs_base+32(FP), BX
+	MOVQ contribs_len+40(FP), CX
+	MOVQ stride+56(FP), DX
+
+	// Convert the stride from elements to bytes. Each element is 32 bytes.
+	SHLQ $5, DX
+
+	XORPS X0, X0
+	XORPS X1, X1
+
+yLoop:
+	CMPQ CX, $0
+	JEQ  yDone
+
+	MOVLQSX 0(BX), AX
+	IMULQ   DX, AX
+
+	// Broadcast the weight, multiply and accumulate.
+	MOVSD    8(BX), X2
+	UNPCKLPD X2, X2
+	MOVUPD   0(SI)(AX*1), X3
+	MOVUPD   16(SI)(AX*1), X4
+	MULPD    X2, X3
+	MULPD    X2, X4
+	ADDPD    X3, X0
+	ADDPD    X4, X1
+
+	ADDQ $16, BX
+	DECQ CX
+	JMP  yLoop
+
+yDone:
+	MOVUPD X0, 0(DI)
+	MOVUPD X1, 16(DI)
+	RET
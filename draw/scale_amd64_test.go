@@ -0,0 +1,79 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !appengine
+// +build gc
+// +build !noasm
+
+package draw
+
+import (
+	"image"
+	"testing"
+)
+
+// TestScaleSIMD tests that the SIMD implementations of the kernelScaler's
+// inner loops match the scalar ones.
+func TestScaleSIMD(t *testing.T) {
+	defer func(b bool) { haveScaleSIMD = b }(haveScaleSIMD)
+
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcGray, srcNRGBA, srcRGBA, srcYCbCr,
+	}
+	drs := []image.Rectangle{
+		image.Rect(0, 0, 13, 11),  // Scale down.
+		image.Rect(2, 3, 91, 77),  // Scale up.
+		image.Rect(0, 0, 37, 290), // Scale down horizontally, up vertically.
+	}
+	for _, q := range []*Kernel{Box, BiLinear, CatmullRom} {
+		for _, srcFunc := range srcFuncs {
+			src, err := srcFunc(image.Rect(0, 0, 43, 39))
+			if err != nil {
+				t.Fatal(err)
+			}
+			sr := image.Rect(1, 2, 41, 38)
+			for _, dr := range drs {
+				for _, op := range []Op{Over, Src} {
+					var dsts [2]*image.RGBA
+					for i := range dsts {
+						haveScaleSIMD = i == 1
+						dsts[i] = image.NewRGBA(image.Rect(0, 0, 100, 300))
+						for j := range dsts[i].Pix {
+							dsts[i].Pix[j] = uint8(j * 7)
+						}
+						q.Scale(dsts[i], dr, src, sr, op, nil)
+					}
+					for j := range dsts[0].Pix {
+						if g, w := dsts[1].Pix[j], dsts[0].Pix[j]; g-w > 1 && w-g > 1 {
+							t.Errorf("q=%p, src %T, dr %v, op %v: Pix[%d]: got %d, want %d",
+								q, src, dr, op, j, g, w)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func benchScaleSIMD(b *testing.B, simd bool, w, h int, srcf func(image.Rectangle) (image.Image, error), q *Kernel) {
+	defer func(b bool) { haveScaleSIMD = b }(haveScaleSIMD)
+	haveScaleSIMD = simd
+	benchScale(b, w, h, Src, srcf, q)
+}
+
+func BenchmarkScaleCRLargeDownScalar(b *testing.B) {
+	benchScaleSIMD(b, false, 200, 150, srcLarge, CatmullRom)
+}
+func BenchmarkScaleCRLargeDownSIMD(b *testing.B) {
+	benchScaleSIMD(b, true, 200, 150, srcLarge, CatmullRom)
+}
+func BenchmarkScaleCRSrcRGBAScalar(b *testing.B) {
+	benchScaleSIMD(b, false, 200, 150, srcRGBA, CatmullRom)
+}
+func BenchmarkScaleCRSrcRGBASIMD(b *testing.B) {
+	benchScaleSIMD(b, true, 200, 150, srcRGBA, CatmullRom)
+}
+func BenchmarkScaleCRUpScalar(b *testing.B) { benchScaleSIMD(b, false, 800, 600, srcTux, CatmullRom) }
+func BenchmarkScaleCRUpSIMD(b *testing.B)   { benchScaleSIMD(b, true, 800, 600, srcTux, CatmullRom) }
@@ -0,0 +1,14 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !amd64 appengine !gc noasm
+
+package draw
+
+const haveScaleSIMD = false
+
+const scaleYSIMDMinContribs = 0
+
+func scaleXRGBASIMD(p *[4]float64, pix []uint8, contribs []contrib)              {}
+func scaleYSIMD(p *[4]float64, tmp [][4]float64, contribs []contrib, stride int) {}
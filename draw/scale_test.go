@@ -11,6 +11,7 @@ import (
 	"image"
 	"image/color"
 	"image/png"
+	"math"
 	"math/rand"
 	"os"
 	"reflect"
@@ -276,6 +277,716 @@ func fillPix(r *rand.Rand, pixs ...[]byte) {
 	}
 }
 
+func TestBCSpline(t *testing.T) {
+	cr := NewBCSpline(0, 0.5)
+	for i := 0; i < 40; i++ {
+		x := float64(i) / 20
+		if got, want := cr.At(x), CatmullRom.At(x); math.Abs(got-want) > 1e-12 {
+			t.Errorf("At(%v): got %v, want %v", x, got, want)
+		}
+	}
+	// Every BC-spline sums to 1 at unit spacing, regardless of the phase.
+	for i, q := range []*Kernel{Mitchell, NewBCSpline(1, 0), NewBCSpline(0.2, 0.4)} {
+		for _, phase := range []float64{0, 0.1, 0.25, 0.5} {
+			sum := q.At(phase) + q.At(1-phase) + q.At(1+phase) + q.At(2-phase)
+			if phase == 0 {
+				sum = q.At(0) + 2*q.At(1)
+			}
+			if math.Abs(sum-1) > 1e-12 {
+				t.Errorf("kernel #%d, phase %v: sum: got %v, want 1", i, phase, sum)
+			}
+		}
+	}
+	if got, want := Mitchell.At(0), 8.0/9; math.Abs(got-want) > 1e-12 {
+		t.Errorf("Mitchell.At(0): got %v, want %v", got, want)
+	}
+}
+
+func TestLanczos(t *testing.T) {
+	for _, q := range []*Kernel{Lanczos2, Lanczos3} {
+		if got := q.At(0); got != 1 {
+			t.Errorf("support %v: At(0): got %v, want 1", q.Support, got)
+		}
+		for i := 1; float64(i) < q.Support; i++ {
+			if got := q.At(float64(i)); math.Abs(got) > 1e-12 {
+				t.Errorf("support %v: At(%d): got %v, want 0", q.Support, i, got)
+			}
+		}
+		// The kernel is close to normalized: the weights at unit spacing sum
+		// to 1, regardless of the phase.
+		for _, phase := range []float64{0.1, 0.25, 0.5} {
+			sum := 0.0
+			for i := -int(q.Support); i <= int(q.Support); i++ {
+				if x := math.Abs(float64(i) + phase); x < q.Support {
+					sum += q.At(x)
+				}
+			}
+			if math.Abs(sum-1) > 0.05 {
+				t.Errorf("support %v, phase %v: sum: got %v, want ~1", q.Support, phase, sum)
+			}
+		}
+	}
+}
+
+func TestBox(t *testing.T) {
+	// Scaling down by an integer factor averages each block of src pixels.
+	src := image.NewGray(image.Rect(0, 0, 6, 3))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i * 13)
+	}
+	dst := image.NewGray(image.Rect(0, 0, 2, 1))
+	Box.Scale(dst, dst.Bounds(), src, src.Bounds(), Src, nil)
+	for x := 0; x < 2; x++ {
+		sum := 0
+		for sy := 0; sy < 3; sy++ {
+			for sx := 3 * x; sx < 3*x+3; sx++ {
+				sum += int(src.GrayAt(sx, sy).Y)
+			}
+		}
+		if got, want := dst.GrayAt(x, 0).Y, uint8((sum+4)/9); got != want {
+			t.Errorf("x=%d: got %d, want %d", x, got, want)
+		}
+	}
+
+	// Box aliases less than ApproxBiLinear when scaling down by more than 2.
+	box, ab := MeasureQuality(Box, 4), MeasureQuality(ApproxBiLinear, 4)
+	if box.Aliasing >= ab.Aliasing/2 {
+		t.Errorf("Box aliasing %.4f is not much better than ApproxBiLinear's %.4f", box.Aliasing, ab.Aliasing)
+	}
+	if box.Ringing != 0 {
+		t.Errorf("Box ringing: got %.4f, want 0", box.Ringing)
+	}
+}
+
+func TestScaleBuffer(t *testing.T) {
+	src, err := srcRGBA(image.Rect(0, 0, 37, 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &ScaleBuffer{}
+	for _, dr := range []image.Rectangle{
+		image.Rect(0, 0, 40, 40),
+		image.Rect(3, 5, 20, 17),
+	} {
+		var dsts [2]*image.RGBA
+		for i := range dsts {
+			dsts[i] = image.NewRGBA(image.Rect(0, 0, 40, 40))
+			var opts *Options
+			if i == 1 {
+				opts = &Options{ScaleBuffer: buf}
+			}
+			CatmullRom.Scale(dsts[i], dr, src, src.Bounds(), Src, opts)
+		}
+		if !bytes.Equal(dsts[0].Pix, dsts[1].Pix) {
+			t.Errorf("dr=%v: pixels differ", dr)
+		}
+	}
+	// The buffer was allocated for the first, larger, dr and then reused.
+	if got, want := cap(buf.tmp), 40*29; got != want {
+		t.Errorf("cap(buf.tmp): got %d, want %d", got, want)
+	}
+
+}
+
+func TestScaleRowsDone(t *testing.T) {
+	src, err := srcNRGBA(image.Rect(0, 0, 37, 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr := image.Rect(2, 3, 42, 53)
+	want := image.NewRGBA(image.Rect(0, 0, 45, 60))
+	CatmullRom.Scale(want, dr, src, src.Bounds(), Src, nil)
+
+	// Stream the rows through a band that is 7 rows high.
+	const bandHeight = 7
+	band := image.NewRGBA(image.Rect(0, 0, 45, bandHeight))
+	got := image.NewRGBA(want.Rect)
+	var calls [][2]int
+	opts := &Options{
+		Concurrency: 2,
+		RowsDone: func(y0, y1 int) {
+			calls = append(calls, [2]int{y0, y1})
+			for y := y0; y < y1; y++ {
+				copy(got.Pix[got.PixOffset(0, y):got.PixOffset(45, y)], band.Pix[band.PixOffset(0, y):])
+			}
+			band.Rect = image.Rect(0, y1, 45, y1+bandHeight)
+		},
+	}
+	CatmullRom.Scale(band, dr, src, src.Bounds(), Src, opts)
+
+	wantCalls := [][2]int{{3, 7}, {7, 14}, {14, 21}, {21, 28}, {28, 35}, {35, 42}, {42, 49}, {49, 53}}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("calls: got %v, want %v", calls, wantCalls)
+	}
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("pixels differ")
+	}
+}
+
+func TestScaleAntiRinging(t *testing.T) {
+	// The src image is a hard vertical edge between two shades of gray,
+	// with a lighter square in its middle.
+	const lo, hi = 0x40, 0xc0
+	src := image.NewGray(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			src.Pix[y*src.Stride+x] = lo
+			if x >= 8 || (5 <= x && x < 11 && 5 <= y && y < 11) {
+				src.Pix[y*src.Stride+x] = hi
+			}
+		}
+	}
+	for _, q := range []*Kernel{CatmullRom, Lanczos3} {
+		for _, dr := range []image.Rectangle{
+			image.Rect(0, 0, 53, 47),
+			image.Rect(0, 0, 11, 13),
+		} {
+			for _, antiRinging := range []bool{false, true} {
+				dst := image.NewRGBA(dr)
+				q.Scale(dst, dr, src, src.Bounds(), Src, &Options{AntiRinging: antiRinging})
+				rings := false
+				for i := 0; i < len(dst.Pix); i += 4 {
+					if v := dst.Pix[i]; v < lo || v > hi {
+						rings = true
+					}
+				}
+				if rings == antiRinging {
+					t.Errorf("kernel %p, dr=%v, AntiRinging=%t: got ringing %t", q, dr, antiRinging, rings)
+				}
+			}
+		}
+	}
+
+	// Kernels without negative lobes do not ring, so clamping does not
+	// change their results.
+	rgba, err := srcRGBA(image.Rect(0, 0, 37, 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []*Kernel{Box, BiLinear} {
+		var dsts [2]*image.RGBA
+		for i := range dsts {
+			dsts[i] = image.NewRGBA(image.Rect(0, 0, 40, 40))
+			q.Scale(dsts[i], image.Rect(2, 3, 39, 40), rgba, rgba.Bounds(), Src, &Options{AntiRinging: i == 1})
+		}
+		for i := range dsts[0].Pix {
+			if dsts[0].Pix[i] != dsts[1].Pix[i] {
+				t.Errorf("kernel %p: pixels differ at index %d: %#02x vs %#02x", q, i, dsts[0].Pix[i], dsts[1].Pix[i])
+				break
+			}
+		}
+	}
+}
+
+func TestScaleConcurrency(t *testing.T) {
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcCMYK, srcGray, srcNRGBA, srcRGBA, srcYCbCr,
+	}
+	dstMask := image.NewAlpha(image.Rect(0, 0, 40, 40))
+	for i := range dstMask.Pix {
+		dstMask.Pix[i] = uint8(7 * i)
+	}
+	for _, srcFunc := range srcFuncs {
+		src, err := srcFunc(image.Rect(0, 0, 37, 29))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, op := range []Op{Over, Src} {
+			for _, mask := range []image.Image{nil, dstMask} {
+				var want *image.RGBA
+				for _, concurrency := range []int{0, 2, 3, 64} {
+					dst := image.NewRGBA(image.Rect(0, 0, 40, 40))
+					for i := range dst.Pix {
+						dst.Pix[i] = uint8(i)
+					}
+					CatmullRom.Scale(dst, image.Rect(3, 2, 33, 39), src, image.Rect(1, 1, 36, 28), op, &Options{
+						DstMask:     mask,
+						Concurrency: concurrency,
+					})
+					if want == nil {
+						want = dst
+					} else if !bytes.Equal(dst.Pix, want.Pix) {
+						t.Errorf("src %T, op %v, mask %t, concurrency %d: result differs from serial scaling",
+							src, op, mask != nil, concurrency)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestKernelTransformer tests that a Kernel's precomputed Transformer matches
+// its Transform method.
+func TestKernelTransformer(t *testing.T) {
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcCMYK, srcGray, srcNRGBA, srcRGBA, srcYCbCr,
+	}
+	srcMask := image.NewAlpha(image.Rect(0, 0, 40, 40))
+	for i := range srcMask.Pix {
+		srcMask.Pix[i] = uint8(5 * i)
+	}
+	sr := image.Rect(2, 3, 35, 27)
+	ms := []f64.Aff3{
+		RotationMatrix(0.4, f64.Vec2{18, 15}),
+		{0.7, -0.2, 4, 0.3, 1.8, -6},
+	}
+	for _, q := range []*Kernel{BiLinear, CatmullRom} {
+		for _, m := range ms {
+			m := m
+			transformer := q.NewTransformer(&m, sr)
+			for _, srcFunc := range srcFuncs {
+				src, err := srcFunc(image.Rect(0, 0, 37, 29))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, op := range []Op{Over, Src} {
+					for _, mask := range []image.Image{nil, srcMask} {
+						want := image.NewRGBA(image.Rect(0, 0, 40, 50))
+						got := image.NewRGBA(image.Rect(0, 0, 40, 50))
+						for i := range want.Pix {
+							want.Pix[i], got.Pix[i] = uint8(i), uint8(i)
+						}
+						opts := &Options{SrcMask: mask}
+						q.Transform(want, m, src, sr, op, opts)
+						transformer.Transform(got, m, src, sr, op, opts)
+						for i := range got.Pix {
+							if g, w := got.Pix[i], want.Pix[i]; g-w > 1 && w-g > 1 {
+								t.Errorf("q=%p, m=%v, src %T, op %v, mask %t: Pix[%d]: got %d, want %d",
+									q, m, src, op, mask != nil, i, g, w)
+								break
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestTransformSingular tests that transforming with a singular matrix, which
+// maps sr to a line, draws nothing.
+func TestTransformSingular(t *testing.T) {
+	src, err := srcRGBA(image.Rect(0, 0, 10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := src.Bounds()
+	m := f64.Aff3{1, 2, 0, 2, 4, 0}
+	transformers := []Transformer{
+		NearestNeighbor, ApproxBiLinear, BiLinear, CatmullRom,
+		CatmullRom.NewTransformer(&m, sr),
+	}
+	for _, tr := range transformers {
+		for _, edgeOp := range []EdgeOp{EdgeNone, EdgeClamp, EdgeConstant} {
+			dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+			tr.Transform(dst, m, src, sr, Src, &Options{EdgeOp: edgeOp, EdgeColor: color.White})
+			for i, p := range dst.Pix {
+				if p != 0 {
+					t.Errorf("%T, EdgeOp %d: Pix[%d]: got %d, want 0", tr, edgeOp, i, p)
+					break
+				}
+			}
+		}
+	}
+}
+
+// TestCMYKFastPaths tests that the *image.CMYK source fast paths match the
+// generic code path.
+func TestCMYKFastPaths(t *testing.T) {
+	src, err := srcCMYK(image.Rect(0, 0, 37, 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := image.Rect(1, 2, 33, 27)
+	qs := []Interpolator{
+		NearestNeighbor,
+		ApproxBiLinear,
+		CatmullRom,
+	}
+	for _, q := range qs {
+		for _, op := range []Op{Over, Src} {
+			for _, transform := range []bool{false, true} {
+				var dsts [2]*image.RGBA
+				for i := range dsts {
+					dsts[i] = image.NewRGBA(image.Rect(0, 0, 40, 40))
+					for j := range dsts[i].Pix {
+						dsts[i].Pix[j] = uint8(j)
+					}
+				}
+				if transform {
+					m := transformMatrix(0.875, 8, 1)
+					q.Transform(dsts[0], m, src, sr, op, nil)
+					q.Transform(dsts[1], m, srcWrapper{src}, sr, op, nil)
+				} else {
+					dr := image.Rect(3, 2, 33, 39)
+					q.Scale(dsts[0], dr, src, sr, op, nil)
+					q.Scale(dsts[1], dr, srcWrapper{src}, sr, op, nil)
+				}
+				if !bytes.Equal(dsts[0].Pix, dsts[1].Pix) {
+					t.Errorf("q %T, op %v, transform %t: fast path differs from generic path", q, op, transform)
+				}
+			}
+		}
+	}
+}
+
+// TestNRGBAFastPaths tests that the *image.NRGBA destination fast paths,
+// which premultiply before blending and un-premultiply on write-out, match
+// the generic code path.
+func TestNRGBAFastPaths(t *testing.T) {
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcNRGBA, srcRGBA, srcUnif, srcYCbCr,
+	}
+	qs := []Interpolator{
+		NearestNeighbor,
+		ApproxBiLinear,
+		CatmullRom,
+	}
+	for _, srcFunc := range srcFuncs {
+		src, err := srcFunc(image.Rect(0, 0, 37, 29))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := image.Rect(1, 2, 33, 27)
+		for _, q := range qs {
+			for _, op := range []Op{Over, Src} {
+				for _, transform := range []bool{false, true} {
+					var dsts [2]*image.NRGBA
+					for i := range dsts {
+						dsts[i] = image.NewNRGBA(image.Rect(0, 0, 40, 40))
+						fillPix(rand.New(rand.NewSource(6)), dsts[i].Pix)
+					}
+					if transform {
+						m := transformMatrix(0.875, 8, 1)
+						q.Transform(dsts[0], m, src, sr, op, nil)
+						q.Transform(dstWrapper{dsts[1]}, m, src, sr, op, nil)
+					} else {
+						dr := image.Rect(3, 2, 33, 39)
+						q.Scale(dsts[0], dr, src, sr, op, nil)
+						q.Scale(dstWrapper{dsts[1]}, dr, src, sr, op, nil)
+					}
+					if !bytes.Equal(dsts[0].Pix, dsts[1].Pix) {
+						t.Errorf("src %T, q %T, op %v, transform %t: fast path differs from generic path",
+							src, q, op, transform)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestRGBA64FastPaths tests that the *image.RGBA64 destination and source
+// fast paths match the generic code path.
+func TestRGBA64FastPaths(t *testing.T) {
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcNRGBA, srcRGBA, srcRGBA64, srcUnif, srcYCbCr,
+	}
+	qs := []Interpolator{
+		NearestNeighbor,
+		ApproxBiLinear,
+		CatmullRom,
+	}
+	for _, srcFunc := range srcFuncs {
+		src, err := srcFunc(image.Rect(0, 0, 37, 29))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := image.Rect(1, 2, 33, 27)
+		for _, q := range qs {
+			for _, op := range []Op{Over, Src} {
+				for _, transform := range []bool{false, true} {
+					var dsts [2]*image.RGBA64
+					for i := range dsts {
+						dsts[i] = image.NewRGBA64(image.Rect(0, 0, 40, 40))
+						fillPix(rand.New(rand.NewSource(7)), dsts[i].Pix)
+						premultiplyRGBA64(dsts[i])
+					}
+					if transform {
+						m := transformMatrix(0.875, 8, 1)
+						q.Transform(dsts[0], m, src, sr, op, nil)
+						q.Transform(dstWrapper{dsts[1]}, m, src, sr, op, nil)
+					} else {
+						dr := image.Rect(3, 2, 33, 39)
+						q.Scale(dsts[0], dr, src, sr, op, nil)
+						q.Scale(dstWrapper{dsts[1]}, dr, src, sr, op, nil)
+					}
+					if !bytes.Equal(dsts[0].Pix, dsts[1].Pix) {
+						t.Errorf("src %T, q %T, op %v, transform %t: dst fast path differs from generic path",
+							src, q, op, transform)
+					}
+				}
+			}
+		}
+	}
+
+	src, err := srcRGBA64(image.Rect(0, 0, 37, 29))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr := image.Rect(1, 2, 33, 27)
+	for _, q := range qs {
+		for _, op := range []Op{Over, Src} {
+			var dsts [2]*image.RGBA
+			for i := range dsts {
+				dsts[i] = image.NewRGBA(image.Rect(0, 0, 40, 40))
+				for j := range dsts[i].Pix {
+					dsts[i].Pix[j] = uint8(j)
+				}
+			}
+			dr := image.Rect(3, 2, 33, 39)
+			q.Scale(dsts[0], dr, src, sr, op, nil)
+			q.Scale(dsts[1], dr, srcWrapper{src}, sr, op, nil)
+			if !bytes.Equal(dsts[0].Pix, dsts[1].Pix) {
+				t.Errorf("q %T, op %v: src fast path differs from generic path", q, op)
+			}
+		}
+	}
+}
+
+// TestRGBA64Intermediate tests that chaining operations through an
+// *image.RGBA64 keeps more than 8 bits of precision.
+func TestRGBA64Intermediate(t *testing.T) {
+	src := image.NewGray16(image.Rect(0, 0, 64, 1))
+	for x := 0; x < 64; x++ {
+		src.SetGray16(x, 0, color.Gray16{uint16(0x1000 + 3*x)})
+	}
+	mid := image.NewRGBA64(image.Rect(0, 0, 100, 1))
+	CatmullRom.Scale(mid, mid.Bounds(), src, src.Bounds(), Src, nil)
+	dst := image.NewRGBA64(image.Rect(0, 0, 64, 1))
+	CatmullRom.Scale(dst, dst.Bounds(), mid, mid.Bounds(), Src, nil)
+
+	// The src values are all within a single 8-bit step, so quantizing the
+	// intermediate image to 8 bits would flatten them.
+	lo, hi := dst.RGBA64At(0, 0).R, dst.RGBA64At(63, 0).R
+	if hi-lo < 0x80 {
+		t.Errorf("got values from %#04x to %#04x, want a range of at least 0x80", lo, hi)
+	}
+}
+
+func premultiplyRGBA64(m *image.RGBA64) {
+	for i := 0; i < len(m.Pix); i += 8 {
+		a := uint32(m.Pix[i+6])<<8 | uint32(m.Pix[i+7])
+		for j := 0; j < 6; j += 2 {
+			v := (uint32(m.Pix[i+j])<<8 | uint32(m.Pix[i+j+1])) * a / 0xffff
+			m.Pix[i+j+0] = uint8(v >> 8)
+			m.Pix[i+j+1] = uint8(v)
+		}
+	}
+}
+
+// TestNRGBANoBleed tests that scaling an NRGBA image whose transparent pixels
+// have a different color to its opaque pixels does not bleed that color into
+// the translucent edges.
+func TestNRGBANoBleed(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if x < 4 {
+				src.SetNRGBA(x, y, color.NRGBA{0xff, 0x00, 0x00, 0x00})
+			} else {
+				src.SetNRGBA(x, y, color.NRGBA{0x00, 0x00, 0xff, 0xff})
+			}
+		}
+	}
+	for _, q := range []Interpolator{ApproxBiLinear, BiLinear, CatmullRom} {
+		dst := image.NewNRGBA(image.Rect(0, 0, 21, 5))
+		q.Scale(dst, dst.Bounds(), src, src.Bounds(), Src, nil)
+		for x := 0; x < 21; x++ {
+			if c := dst.NRGBAAt(x, 2); c.A != 0 && (c.R > 1 || c.B < 0xfe) {
+				t.Errorf("q %T: x=%d: got %v, want pure blue", q, x, c)
+			}
+		}
+	}
+}
+
+// TestGrayFastPaths tests that the Gray and Gray16 destination fast paths
+// match the generic code path, to within rounding error.
+func TestGrayFastPaths(t *testing.T) {
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcCMYK, srcGray, srcGray16, srcNRGBA, srcRGBA, srcUnif, srcYCbCr,
+	}
+	newDsts := []func(image.Rectangle) Image{
+		func(r image.Rectangle) Image { return image.NewGray(r) },
+		func(r image.Rectangle) Image { return image.NewGray16(r) },
+	}
+	qs := []Interpolator{
+		NearestNeighbor,
+		ApproxBiLinear,
+		CatmullRom,
+	}
+	dr := image.Rect(2, 3, 35, 31)
+	for _, srcFunc := range srcFuncs {
+		src, err := srcFunc(image.Rect(0, 0, 37, 29))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := image.Rect(1, 2, 33, 27)
+		for _, newDst := range newDsts {
+			for _, q := range qs {
+				for _, op := range []Op{Over, Src} {
+					for _, transform := range []bool{false, true} {
+						dst0, dst1 := newDst(dr), newDst(dr)
+						for y := dr.Min.Y; y < dr.Max.Y; y++ {
+							for x := dr.Min.X; x < dr.Max.X; x++ {
+								c := color.Gray{uint8(5*x + 3*y)}
+								dst0.Set(x, y, c)
+								dst1.Set(x, y, c)
+							}
+						}
+						if transform {
+							m := transformMatrix(0.875, 8, 1)
+							q.Transform(dst0, m, src, sr, op, nil)
+							q.Transform(dstWrapper{dst1}, m, src, sr, op, nil)
+						} else {
+							q.Scale(dst0, dr, src, sr, op, nil)
+							q.Scale(dstWrapper{dst1}, dr, src, sr, op, nil)
+						}
+
+					loop:
+						for y := dr.Min.Y; y < dr.Max.Y; y++ {
+							for x := dr.Min.X; x < dr.Max.X; x++ {
+								g0 := color.Gray16Model.Convert(dst0.At(x, y)).(color.Gray16).Y
+								g1 := color.Gray16Model.Convert(dst1.At(x, y)).(color.Gray16).Y
+								if delta := int(g0) - int(g1); delta < -0x101 || 0x101 < delta {
+									t.Errorf("dst %T, src %T, q %T, op %v, transform %t: at (%d, %d): got %#04x, want %#04x",
+										dst0, src, q, op, transform, x, y, g0, g1)
+									break loop
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPalettedFastPath(t *testing.T) {
+	// The palette has duplicate colors, which converting colors to indexes
+	// would collapse.
+	palette := color.Palette{
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+		color.RGBA{0x00, 0x00, 0x00, 0xff},
+		color.RGBA{0xff, 0xff, 0xff, 0xff},
+		color.RGBA{0xff, 0x00, 0x00, 0xff},
+	}
+	src := image.NewPaletted(image.Rect(2, 1, 9, 6), palette)
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i % len(palette))
+	}
+	sr := image.Rect(3, 1, 9, 5)
+	for _, dr := range []image.Rectangle{
+		image.Rect(0, 0, 6, 4),
+		image.Rect(1, 2, 13, 10),
+		image.Rect(-3, 0, 14, 7),
+	} {
+		// The destination's palette has equal but separately allocated colors.
+		dst := image.NewPaletted(image.Rect(0, 0, 10, 8), append(color.Palette(nil), palette...))
+		dst.Palette[4] = color.NRGBA{0xff, 0x00, 0x00, 0xff}
+		if dr.Size() == sr.Size() {
+			Copy(dst, dr.Min, src, sr, Over, nil)
+		} else {
+			NearestNeighbor.Scale(dst, dr, src, sr, Src, nil)
+		}
+		adr := dst.Rect.Intersect(dr)
+		for y := adr.Min.Y; y < adr.Max.Y; y++ {
+			for x := adr.Min.X; x < adr.Max.X; x++ {
+				sx := sr.Min.X + (2*(x-dr.Min.X)+1)*sr.Dx()/(2*dr.Dx())
+				sy := sr.Min.Y + (2*(y-dr.Min.Y)+1)*sr.Dy()/(2*dr.Dy())
+				if got, want := dst.ColorIndexAt(x, y), src.ColorIndexAt(sx, sy); got != want {
+					t.Errorf("dr=%v: (%d, %d): got index %d, want %d", dr, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	// Over with a transparent source palette color does not copy indexes.
+	tsrc := image.NewPaletted(src.Rect, append(palette, color.RGBA{}))
+	copy(tsrc.Pix, src.Pix)
+	tsrc.Pix[0] = 5
+	dst := image.NewPaletted(image.Rect(0, 0, 14, 10), tsrc.Palette)
+	for i := range dst.Pix {
+		dst.Pix[i] = 4
+	}
+	NearestNeighbor.Scale(dst, dst.Rect, tsrc, tsrc.Rect, Over, nil)
+	if got := dst.ColorIndexAt(0, 0); got != 4 {
+		t.Errorf("Over a transparent pixel: got index %d, want 4", got)
+	}
+
+	// With a different palette, each index is mapped to the nearest color,
+	// as converting each pixel's color would.
+	other := color.Palette{palette[4], color.Gray{0x10}, palette[1], color.Gray{0xf0}}
+	got := image.NewPaletted(src.Rect, other)
+	Copy(got, src.Rect.Min, src, src.Rect, Src, nil)
+	want := image.NewPaletted(src.Rect, other)
+	Draw(want, want.Rect, struct{ image.Image }{src}, src.Rect.Min, Src)
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("different palettes: got %v, want %v", got.Pix, want.Pix)
+	}
+}
+
+func TestAlignCorners(t *testing.T) {
+	// The src is a linear gradient, 0x40 per pixel across and 0x10 per pixel
+	// down. With AlignCorners, scaling 3x2 to 5x3 samples every half pixel,
+	// from the first src pixel's center to the last one's.
+	src := image.NewGray(image.Rect(1, 1, 4, 3))
+	for y := 1; y < 3; y++ {
+		for x := 1; x < 4; x++ {
+			src.SetGray(x, y, color.Gray{uint8(0x40*(x-1) + 0x10*(y-1))})
+		}
+	}
+	dr := image.Rect(2, 1, 7, 4)
+	// nn is the nearest src column (or row) to each dst column (or row).
+	nn := []int{0, 1, 1, 2, 2}
+	opts := &Options{Alignment: AlignCorners}
+	testCases := []struct {
+		desc  string
+		scale func(dst Image)
+		nn    bool
+	}{
+		{"NearestNeighbor", func(dst Image) { NearestNeighbor.Scale(dst, dr, src, src.Rect, Src, opts) }, true},
+		{"ApproxBiLinear", func(dst Image) { ApproxBiLinear.Scale(dst, dr, src, src.Rect, Src, opts) }, false},
+		{"BiLinear", func(dst Image) { BiLinear.Scale(dst, dr, src, src.Rect, Src, opts) }, false},
+		{"BiLinear.NewScaler", func(dst Image) {
+			BiLinear.NewScaler(dr.Dx(), dr.Dy(), src.Rect.Dx(), src.Rect.Dy()).Scale(dst, dr, src, src.Rect, Src, opts)
+		}, false},
+	}
+	for _, tc := range testCases {
+		for _, dst := range []Image{image.NewGray(dr), image.NewRGBA(dr)} {
+			tc.scale(dst)
+			for y := 0; y < dr.Dy(); y++ {
+				for x := 0; x < dr.Dx(); x++ {
+					want := uint8(0x20*x + 0x08*y)
+					if tc.nn {
+						want = uint8(0x40*nn[x] + 0x10*nn[y])
+					}
+					if got := color.GrayModel.Convert(dst.At(dr.Min.X+x, dr.Min.Y+y)).(color.Gray).Y; got != want {
+						t.Errorf("%s, %T: (%d, %d): got %#02x, want %#02x", tc.desc, dst, x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	// The paletted fast path aligns the same way.
+	palette := make(color.Palette, 0x100)
+	for i := range palette {
+		palette[i] = color.Gray{uint8(i)}
+	}
+	psrc := image.NewPaletted(src.Rect, palette)
+	copy(psrc.Pix, src.Pix)
+	pdst := image.NewPaletted(dr, palette)
+	NearestNeighbor.Scale(pdst, dr, psrc, psrc.Rect, Src, opts)
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			if got, want := pdst.ColorIndexAt(dr.Min.X+x, dr.Min.Y+y), uint8(0x40*nn[x]+0x10*nn[y]); got != want {
+				t.Errorf("paletted: (%d, %d): got %#02x, want %#02x", x, y, got, want)
+			}
+		}
+	}
+}
+
 func TestInterpClipCommute(t *testing.T) {
 	src := image.NewNRGBA(image.Rect(0, 0, 20, 20))
 	fillPix(rand.New(rand.NewSource(0)), src.Pix)
@@ -286,6 +997,7 @@ func TestInterpClipCommute(t *testing.T) {
 		NearestNeighbor,
 		ApproxBiLinear,
 		CatmullRom,
+		Lanczos3,
 	}
 	for _, transform := range []bool{false, true} {
 		for _, q := range qs {
@@ -360,6 +1072,7 @@ func TestSrcTranslationInvariance(t *testing.T) {
 		NearestNeighbor,
 		ApproxBiLinear,
 		CatmullRom,
+		Lanczos3,
 	}
 	deltas := []image.Point{
 		{+0, +0},
@@ -386,10 +1099,7 @@ func TestSrcTranslationInvariance(t *testing.T) {
 				tsrc := &translatedImage{src, delta}
 				got := image.NewRGBA(image.Rect(0, 0, 20, 20))
 				if transform {
-					m := matMul(&m00, &f64.Aff3{
-						1, 0, -float64(delta.X),
-						0, 1, -float64(delta.Y),
-					})
+					m := m00.Mul(f64.Translate(-float64(delta.X), -float64(delta.Y)))
 					q.Transform(got, m, tsrc, sr.Add(delta), Over, nil)
 				} else {
 					q.Scale(got, got.Bounds(), tsrc, sr.Add(delta), Over, nil)
@@ -450,6 +1160,7 @@ func TestDstMask(t *testing.T) {
 		NearestNeighbor,
 		ApproxBiLinear,
 		CatmullRom,
+		Lanczos3,
 	}
 	for _, q := range qs {
 		dst := image.NewRGBA(image.Rect(0, 0, 3, 1))
@@ -508,6 +1219,7 @@ func TestRectDstMask(t *testing.T) {
 		NearestNeighbor,
 		ApproxBiLinear,
 		CatmullRom,
+		Lanczos3,
 	}
 	dstMaskPs := []image.Point{
 		{0, 0},
@@ -560,6 +1272,49 @@ func TestDstMaskSameSizeCopy(t *testing.T) {
 	})
 }
 
+// TestCopyOptions tests that Copy honors its Options, whether or not it takes
+// the DrawMask fast path, by comparing it to an identity Scale.
+func TestCopyOptions(t *testing.T) {
+	src, err := srcRGBA(image.Rect(0, 0, 20, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, 20, 20))
+	for i := range mask.Pix {
+		mask.Pix[i] = uint8(13 * i)
+	}
+	sr := image.Rect(2, 3, 17, 19)
+	dp := image.Point{4, 1}
+	dr := sr.Add(dp.Sub(sr.Min))
+
+	testCases := []struct {
+		desc string
+		opts Options
+	}{
+		{"none", Options{}},
+		{"src mask", Options{SrcMask: mask, SrcMaskP: image.Point{1, 2}}},
+		{"dst mask", Options{DstMask: mask, DstMaskP: image.Point{2, 1}}},
+		{"both masks", Options{SrcMask: mask, DstMask: mask}},
+	}
+	for _, op := range []Op{Over, Src} {
+		for _, tc := range testCases {
+			newDst := func() *image.RGBA {
+				m := image.NewRGBA(image.Rect(0, 0, 25, 25))
+				for i := range m.Pix {
+					m.Pix[i] = 0x40
+				}
+				return m
+			}
+			got, want := newDst(), newDst()
+			Copy(got, dp, src, sr, op, &tc.opts)
+			NearestNeighbor.Scale(want, dr, src, sr, op, &tc.opts)
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Errorf("op=%v, %s: Copy and identity Scale differ", op, tc.desc)
+			}
+		}
+	}
+}
+
 // The fooWrapper types wrap the dst or src image to avoid triggering the
 // type-specific fast path implementations.
 type (
@@ -567,12 +1322,24 @@ type (
 	srcWrapper struct{ image.Image }
 )
 
+func srcCMYK(boundsHint image.Rectangle) (image.Image, error) {
+	m := image.NewCMYK(boundsHint)
+	fillPix(rand.New(rand.NewSource(5)), m.Pix)
+	return m, nil
+}
+
 func srcGray(boundsHint image.Rectangle) (image.Image, error) {
 	m := image.NewGray(boundsHint)
 	fillPix(rand.New(rand.NewSource(0)), m.Pix)
 	return m, nil
 }
 
+func srcGray16(boundsHint image.Rectangle) (image.Image, error) {
+	m := image.NewGray16(boundsHint)
+	fillPix(rand.New(rand.NewSource(4)), m.Pix)
+	return m, nil
+}
+
 func srcNRGBA(boundsHint image.Rectangle) (image.Image, error) {
 	m := image.NewNRGBA(boundsHint)
 	fillPix(rand.New(rand.NewSource(1)), m.Pix)
@@ -592,6 +1359,13 @@ func srcRGBA(boundsHint image.Rectangle) (image.Image, error) {
 	return m, nil
 }
 
+func srcRGBA64(boundsHint image.Rectangle) (image.Image, error) {
+	m := image.NewRGBA64(boundsHint)
+	fillPix(rand.New(rand.NewSource(8)), m.Pix)
+	premultiplyRGBA64(m)
+	return m, nil
+}
+
 func srcUnif(boundsHint image.Rectangle) (image.Image, error) {
 	return image.NewUniform(color.RGBA64{0x1234, 0x5555, 0x9181, 0xbeef}), nil
 }
@@ -643,6 +1417,24 @@ func benchScale(b *testing.B, w int, h int, op Op, srcf func(image.Rectangle) (i
 	}
 }
 
+// benchScaleBuffer is like benchScale, but without NewScaler and with a
+// ScaleBuffer.
+func benchScaleBuffer(b *testing.B, w int, h int, srcf func(image.Rectangle) (image.Image, error), q *Kernel) {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	src, err := srcf(image.Rect(0, 0, 1024, 768))
+	if err != nil {
+		b.Fatal(err)
+	}
+	dr, sr := dst.Bounds(), src.Bounds()
+	opts := &Options{ScaleBuffer: &ScaleBuffer{}}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Scale(dst, dr, src, sr, Src, opts)
+	}
+}
+
 func benchTform(b *testing.B, w int, h int, op Op, srcf func(image.Rectangle) (image.Image, error), q Interpolator) {
 	dst := image.NewRGBA(image.Rect(0, 0, w, h))
 	src, err := srcf(image.Rect(0, 0, 1024, 768))
@@ -659,19 +1451,64 @@ func benchTform(b *testing.B, w int, h int, op Op, srcf func(image.Rectangle) (i
 	}
 }
 
-func BenchmarkScaleNNLargeDown(b *testing.B) { benchScale(b, 200, 150, Src, srcLarge, NearestNeighbor) }
-func BenchmarkScaleABLargeDown(b *testing.B) { benchScale(b, 200, 150, Src, srcLarge, ApproxBiLinear) }
-func BenchmarkScaleBLLargeDown(b *testing.B) { benchScale(b, 200, 150, Src, srcLarge, BiLinear) }
-func BenchmarkScaleCRLargeDown(b *testing.B) { benchScale(b, 200, 150, Src, srcLarge, CatmullRom) }
+// benchTransformer is like benchTform, but with a smaller source rectangle,
+// so that the transformed rectangle covers about the same area as dst. If
+// precompute is true, it uses q's NewTransformer.
+func benchTransformer(b *testing.B, op Op, srcf func(image.Rectangle) (image.Image, error), q *Kernel, precompute bool) {
+	dst := image.NewRGBA(image.Rect(0, 0, 200, 150))
+	src, err := srcf(image.Rect(0, 0, 1024, 768))
+	if err != nil {
+		b.Fatal(err)
+	}
+	sr := image.Rect(0, 0, 100, 75)
+	m := transformMatrix(1.75, 40, 10)
+	transformer := Transformer(q)
+	if precompute {
+		transformer = q.NewTransformer(&m, sr)
+	}
 
-func BenchmarkScaleNNDown(b *testing.B) { benchScale(b, 120, 80, Src, srcTux, NearestNeighbor) }
-func BenchmarkScaleABDown(b *testing.B) { benchScale(b, 120, 80, Src, srcTux, ApproxBiLinear) }
-func BenchmarkScaleBLDown(b *testing.B) { benchScale(b, 120, 80, Src, srcTux, BiLinear) }
-func BenchmarkScaleCRDown(b *testing.B) { benchScale(b, 120, 80, Src, srcTux, CatmullRom) }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		transformer.Transform(dst, m, src, sr, op, nil)
+	}
+}
+
+func BenchmarkScaleNNLargeDown(b *testing.B)  { benchScale(b, 200, 150, Src, srcLarge, NearestNeighbor) }
+func BenchmarkScaleABLargeDown(b *testing.B)  { benchScale(b, 200, 150, Src, srcLarge, ApproxBiLinear) }
+func BenchmarkScaleBLLargeDown(b *testing.B)  { benchScale(b, 200, 150, Src, srcLarge, BiLinear) }
+func BenchmarkScaleCRLargeDown(b *testing.B)  { benchScale(b, 200, 150, Src, srcLarge, CatmullRom) }
+func BenchmarkScaleBoxLargeDown(b *testing.B) { benchScale(b, 200, 150, Src, srcLarge, Box) }
+
+func BenchmarkScaleNNDown(b *testing.B)  { benchScale(b, 120, 80, Src, srcTux, NearestNeighbor) }
+func BenchmarkScaleABDown(b *testing.B)  { benchScale(b, 120, 80, Src, srcTux, ApproxBiLinear) }
+func BenchmarkScaleBLDown(b *testing.B)  { benchScale(b, 120, 80, Src, srcTux, BiLinear) }
+func BenchmarkScaleCRDown(b *testing.B)  { benchScale(b, 120, 80, Src, srcTux, CatmullRom) }
+func BenchmarkScaleBoxDown(b *testing.B) { benchScale(b, 120, 80, Src, srcTux, Box) }
+
+func BenchmarkScaleCRDownScaleBuffer(b *testing.B) { benchScaleBuffer(b, 120, 80, srcTux, CatmullRom) }
+
+func BenchmarkScaleNNUp(b *testing.B)         { benchScale(b, 800, 600, Src, srcTux, NearestNeighbor) }
+func BenchmarkScaleABUp(b *testing.B)         { benchScale(b, 800, 600, Src, srcTux, ApproxBiLinear) }
+func BenchmarkScaleBLUp(b *testing.B)         { benchScale(b, 800, 600, Src, srcTux, BiLinear) }
+func BenchmarkScaleLanczos3Down(b *testing.B) { benchScale(b, 120, 80, Src, srcTux, Lanczos3) }
+
+func BenchmarkScaleCRLargeDownConcurrent(b *testing.B) {
+	dst := image.NewRGBA(image.Rect(0, 0, 200, 150))
+	src, err := srcLarge(image.Rect(0, 0, 1024, 768))
+	if err != nil {
+		b.Fatal(err)
+	}
+	scaler := CatmullRom.NewScaler(200, 150, src.Bounds().Dx(), src.Bounds().Dy())
+	opts := &Options{Concurrency: 4}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scaler.Scale(dst, dst.Bounds(), src, src.Bounds(), Src, opts)
+	}
+}
 
-func BenchmarkScaleNNUp(b *testing.B) { benchScale(b, 800, 600, Src, srcTux, NearestNeighbor) }
-func BenchmarkScaleABUp(b *testing.B) { benchScale(b, 800, 600, Src, srcTux, ApproxBiLinear) }
-func BenchmarkScaleBLUp(b *testing.B) { benchScale(b, 800, 600, Src, srcTux, BiLinear) }
 func BenchmarkScaleCRUp(b *testing.B) { benchScale(b, 800, 600, Src, srcTux, CatmullRom) }
 
 func BenchmarkScaleNNSrcRGBA(b *testing.B) { benchScale(b, 200, 150, Src, srcRGBA, NearestNeighbor) }
@@ -683,9 +1520,15 @@ func BenchmarkScaleNNOverUnif(b *testing.B) { benchScale(b, 200, 150, Over, srcU
 func BenchmarkTformNNSrcRGBA(b *testing.B) { benchTform(b, 200, 150, Src, srcRGBA, NearestNeighbor) }
 func BenchmarkTformNNSrcUnif(b *testing.B) { benchTform(b, 200, 150, Src, srcUnif, NearestNeighbor) }
 
+func BenchmarkTformCRSrcRGBASmall(b *testing.B) { benchTransformer(b, Src, srcRGBA, CatmullRom, false) }
+func BenchmarkTformCRSrcRGBASmallPrecomputed(b *testing.B) {
+	benchTransformer(b, Src, srcRGBA, CatmullRom, true)
+}
+
 func BenchmarkTformNNOverRGBA(b *testing.B) { benchTform(b, 200, 150, Over, srcRGBA, NearestNeighbor) }
 func BenchmarkTformNNOverUnif(b *testing.B) { benchTform(b, 200, 150, Over, srcUnif, NearestNeighbor) }
 
+func BenchmarkScaleABSrcCMYK(b *testing.B)  { benchScale(b, 200, 150, Src, srcCMYK, ApproxBiLinear) }
 func BenchmarkScaleABSrcGray(b *testing.B)  { benchScale(b, 200, 150, Src, srcGray, ApproxBiLinear) }
 func BenchmarkScaleABSrcNRGBA(b *testing.B) { benchScale(b, 200, 150, Src, srcNRGBA, ApproxBiLinear) }
 func BenchmarkScaleABSrcRGBA(b *testing.B)  { benchScale(b, 200, 150, Src, srcRGBA, ApproxBiLinear) }
@@ -696,6 +1539,7 @@ func BenchmarkScaleABOverNRGBA(b *testing.B) { benchScale(b, 200, 150, Over, src
 func BenchmarkScaleABOverRGBA(b *testing.B)  { benchScale(b, 200, 150, Over, srcRGBA, ApproxBiLinear) }
 func BenchmarkScaleABOverYCbCr(b *testing.B) { benchScale(b, 200, 150, Over, srcYCbCr, ApproxBiLinear) }
 
+func BenchmarkTformABSrcCMYK(b *testing.B)  { benchTform(b, 200, 150, Src, srcCMYK, ApproxBiLinear) }
 func BenchmarkTformABSrcGray(b *testing.B)  { benchTform(b, 200, 150, Src, srcGray, ApproxBiLinear) }
 func BenchmarkTformABSrcNRGBA(b *testing.B) { benchTform(b, 200, 150, Src, srcNRGBA, ApproxBiLinear) }
 func BenchmarkTformABSrcRGBA(b *testing.B)  { benchTform(b, 200, 150, Src, srcRGBA, ApproxBiLinear) }
@@ -706,6 +1550,7 @@ func BenchmarkTformABOverNRGBA(b *testing.B) { benchTform(b, 200, 150, Over, src
 func BenchmarkTformABOverRGBA(b *testing.B)  { benchTform(b, 200, 150, Over, srcRGBA, ApproxBiLinear) }
 func BenchmarkTformABOverYCbCr(b *testing.B) { benchTform(b, 200, 150, Over, srcYCbCr, ApproxBiLinear) }
 
+func BenchmarkScaleCRSrcCMYK(b *testing.B)  { benchScale(b, 200, 150, Src, srcCMYK, CatmullRom) }
 func BenchmarkScaleCRSrcGray(b *testing.B)  { benchScale(b, 200, 150, Src, srcGray, CatmullRom) }
 func BenchmarkScaleCRSrcNRGBA(b *testing.B) { benchScale(b, 200, 150, Src, srcNRGBA, CatmullRom) }
 func BenchmarkScaleCRSrcRGBA(b *testing.B)  { benchScale(b, 200, 150, Src, srcRGBA, CatmullRom) }
@@ -716,6 +1561,7 @@ func BenchmarkScaleCROverNRGBA(b *testing.B) { benchScale(b, 200, 150, Over, src
 func BenchmarkScaleCROverRGBA(b *testing.B)  { benchScale(b, 200, 150, Over, srcRGBA, CatmullRom) }
 func BenchmarkScaleCROverYCbCr(b *testing.B) { benchScale(b, 200, 150, Over, srcYCbCr, CatmullRom) }
 
+func BenchmarkTformCRSrcCMYK(b *testing.B)  { benchTform(b, 200, 150, Src, srcCMYK, CatmullRom) }
 func BenchmarkTformCRSrcGray(b *testing.B)  { benchTform(b, 200, 150, Src, srcGray, CatmullRom) }
 func BenchmarkTformCRSrcNRGBA(b *testing.B) { benchTform(b, 200, 150, Src, srcNRGBA, CatmullRom) }
 func BenchmarkTformCRSrcRGBA(b *testing.B)  { benchTform(b, 200, 150, Src, srcRGBA, CatmullRom) }
@@ -0,0 +1,232 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"errors"
+	"image"
+)
+
+var (
+	errStreamSize     = errors.New("draw: StreamScaler rows have the wrong width")
+	errStreamTooLong  = errors.New("draw: StreamScaler pushed more than the source height")
+	errStreamTooShort = errors.New("draw: StreamScaler flushed before the source height was pushed")
+)
+
+// StreamScaler scales an image that is supplied a band of rows at a time, such
+// as a huge image that does not fit in memory once decoded. Only the source
+// rows within the kernel's support of the next destination row are held in
+// memory, horizontally scaled.
+//
+// Source rows are pushed in top to bottom order, and each destination row is
+// emitted as soon as the source rows that it depends on have been pushed. The
+// emitted pixels are the same as those of the Kernel's Scale method with the
+// Src operator and an *image.RGBA64 destination.
+type StreamScaler struct {
+	dw, dh, sw, sh int32
+
+	horizontal, vertical distrib
+
+	emit func(y int, row *image.RGBA64) error
+	err  error
+
+	// rows is a ring buffer of horizontally scaled source rows, each dw
+	// elements long. Source row y is at index y % nRows.
+	rows  [][4]float64
+	nRows int32
+	// src is the current source row, in alpha-premultiplied 16-bit color.
+	src [][4]float64
+	out *image.RGBA64
+
+	// y is the number of source rows pushed, and dy is the number of
+	// destination rows emitted.
+	y, dy int32
+	// last[dy] is the last source row that destination row dy depends on, or
+	// -1 if it depends on none.
+	last []int32
+}
+
+// NewStreamScaler returns a StreamScaler that scales a source image of the
+// given width and height to the given destination width and height.
+//
+// The emit function is called once for each destination row, in order, with
+// y being the row's index and row being an image whose bounds are
+// image.Rect(0, y, dw, y+1). The row image is reused, and must not be
+// retained after emit returns. If emit returns an error, scaling stops and
+// the error is returned by PushRows.
+func (q *Kernel) NewStreamScaler(dw, dh, sw, sh int, emit func(y int, row *image.RGBA64) error) *StreamScaler {
+	z := &StreamScaler{
+		dw:         int32(dw),
+		dh:         int32(dh),
+		sw:         int32(sw),
+		sh:         int32(sh),
+		horizontal: newDistrib(q, int32(dw), int32(sw), AlignCenters),
+		vertical:   newDistrib(q, int32(dh), int32(sh), AlignCenters),
+		emit:       emit,
+		src:        make([][4]float64, sw),
+		out:        image.NewRGBA64(image.Rect(0, 0, dw, 1)),
+		last:       make([]int32, dh),
+	}
+
+	// Find how many source rows must be held at once. When destination row
+	// dy is emitted, the rows from the first row that any later destination
+	// row depends on, up to the last row that dy depends on, are needed.
+	first := make([]int32, dh)
+	for dy, s := range z.vertical.sources {
+		first[dy], z.last[dy] = z.sh, -1
+		for _, c := range z.vertical.contribs[s.i:s.j] {
+			if first[dy] > c.coord {
+				first[dy] = c.coord
+			}
+			if z.last[dy] < c.coord {
+				z.last[dy] = c.coord
+			}
+		}
+	}
+	z.nRows = 1
+	for dy := len(first) - 1; dy >= 0; dy-- {
+		if dy+1 < len(first) && first[dy] > first[dy+1] {
+			first[dy] = first[dy+1]
+		}
+		if n := z.last[dy] - first[dy] + 1; z.nRows < n {
+			z.nRows = n
+		}
+	}
+	z.rows = make([][4]float64, int(z.nRows)*dw)
+	return z
+}
+
+// PushRows pushes the next src.Bounds().Dy() rows of the source image, which
+// must be src.Bounds().Dx() == sw pixels wide, and emits the destination rows
+// that they complete. The src image's bounds need not be at the source
+// rows' coordinates: rows are taken in order from the top of src.
+func (z *StreamScaler) PushRows(src image.Image) error {
+	if z.err != nil {
+		return z.err
+	}
+	b := src.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	if int32(b.Dx()) != z.sw {
+		z.err = errStreamSize
+		return z.err
+	}
+	if int64(b.Dy()) > int64(z.sh-z.y) {
+		z.err = errStreamTooLong
+		return z.err
+	}
+	for sy := b.Min.Y; sy < b.Max.Y; sy++ {
+		z.readRow(src, b.Min.X, sy)
+		z.scaleX(z.rows[int(z.y%z.nRows)*int(z.dw):][:z.dw])
+		z.y++
+		for z.dy < z.dh && z.last[z.dy] < z.y {
+			z.scaleY()
+			if err := z.emit(int(z.dy), z.out); err != nil {
+				z.err = err
+				return err
+			}
+			z.dy++
+		}
+	}
+	return nil
+}
+
+// Flush returns an error if fewer source rows than the source height have
+// been pushed, or if a previous PushRows call failed. Otherwise, all of the
+// destination rows have been emitted.
+func (z *StreamScaler) Flush() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.y != z.sh {
+		return errStreamTooShort
+	}
+	return nil
+}
+
+// readRow reads src's row sy, starting at column x0, into z.src.
+func (z *StreamScaler) readRow(src image.Image, x0, sy int) {
+	switch src := src.(type) {
+	case *image.Gray:
+		pix := src.Pix[src.PixOffset(x0, sy):]
+		for x := range z.src {
+			v := float64(uint32(pix[x]) * 0x101)
+			z.src[x] = [4]float64{v, v, v, 0xffff}
+		}
+	case *image.RGBA:
+		pix := src.Pix[src.PixOffset(x0, sy):]
+		for x := range z.src {
+			p := pix[4*x : 4*x+4]
+			z.src[x] = [4]float64{
+				float64(uint32(p[0]) * 0x101),
+				float64(uint32(p[1]) * 0x101),
+				float64(uint32(p[2]) * 0x101),
+				float64(uint32(p[3]) * 0x101),
+			}
+		}
+	default:
+		for x := range z.src {
+			r, g, b, a := src.At(x0+x, sy).RGBA()
+			z.src[x] = [4]float64{float64(r), float64(g), float64(b), float64(a)}
+		}
+	}
+}
+
+// scaleX distributes z.src's columns over tmp.
+func (z *StreamScaler) scaleX(tmp [][4]float64) {
+	for t, s := range z.horizontal.sources {
+		var pr, pg, pb, pa float64
+		for _, c := range z.horizontal.contribs[s.i:s.j] {
+			p := &z.src[c.coord]
+			pr += p[0] * c.weight
+			pg += p[1] * c.weight
+			pb += p[2] * c.weight
+			pa += p[3] * c.weight
+		}
+		tmp[t] = [4]float64{
+			pr * s.invTotalWeightFFFF,
+			pg * s.invTotalWeightFFFF,
+			pb * s.invTotalWeightFFFF,
+			pa * s.invTotalWeightFFFF,
+		}
+	}
+}
+
+// scaleY distributes the buffered rows over destination row z.dy, in z.out.
+func (z *StreamScaler) scaleY() {
+	s := z.vertical.sources[z.dy]
+	z.out.Rect = image.Rect(0, int(z.dy), int(z.dw), int(z.dy)+1)
+	for dx := int32(0); dx < z.dw; dx++ {
+		var pr, pg, pb, pa float64
+		for _, c := range z.vertical.contribs[s.i:s.j] {
+			p := &z.rows[(c.coord%z.nRows)*z.dw+dx]
+			pr += p[0] * c.weight
+			pg += p[1] * c.weight
+			pb += p[2] * c.weight
+			pa += p[3] * c.weight
+		}
+
+		if pr > pa {
+			pr = pa
+		}
+		if pg > pa {
+			pg = pa
+		}
+		if pb > pa {
+			pb = pa
+		}
+
+		r := ftou(pr * s.invTotalWeight)
+		g := ftou(pg * s.invTotalWeight)
+		b := ftou(pb * s.invTotalWeight)
+		a := ftou(pa * s.invTotalWeight)
+		d := z.out.Pix[8*dx : 8*dx+8]
+		d[0], d[1] = uint8(r>>8), uint8(r)
+		d[2], d[3] = uint8(g>>8), uint8(g)
+		d[4], d[5] = uint8(b>>8), uint8(b)
+		d[6], d[7] = uint8(a>>8), uint8(a)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestStreamScaler(t *testing.T) {
+	srcFuncs := []func(image.Rectangle) (image.Image, error){
+		srcGray, srcNRGBA, srcRGBA, srcYCbCr,
+	}
+	sizes := []image.Point{{9, 7}, {40, 30}, {64, 64}}
+	for _, srcFunc := range srcFuncs {
+		src, err := srcFunc(image.Rect(0, 0, 37, 29))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sr := src.Bounds()
+		for _, q := range []*Kernel{BiLinear, CatmullRom, Lanczos3} {
+			for _, size := range sizes {
+				want := image.NewRGBA64(image.Rect(0, 0, size.X, size.Y))
+				q.Scale(want, want.Bounds(), src, sr, Src, nil)
+
+				for _, band := range []int{1, 5, sr.Dy()} {
+					got := image.NewRGBA64(want.Bounds())
+					next := 0
+					z := q.NewStreamScaler(size.X, size.Y, sr.Dx(), sr.Dy(), func(y int, row *image.RGBA64) error {
+						if y != next {
+							t.Fatalf("emitted row %d, want %d", y, next)
+						}
+						next++
+						copy(got.Pix[got.PixOffset(0, y):], row.Pix)
+						return nil
+					})
+					for y := sr.Min.Y; y < sr.Max.Y; y += band {
+						r := image.Rect(sr.Min.X, y, sr.Max.X, y+band).Intersect(sr)
+						if err := z.PushRows(src.(subImager).SubImage(r)); err != nil {
+							t.Fatalf("PushRows: %v", err)
+						}
+					}
+					if err := z.Flush(); err != nil {
+						t.Fatalf("Flush: %v", err)
+					}
+					if !bytes.Equal(got.Pix, want.Pix) {
+						t.Errorf("src %T, size %v, band %d: StreamScaler differs from Scale", src, size, band)
+					}
+				}
+			}
+		}
+	}
+}
+
+type subImager interface {
+	SubImage(image.Rectangle) image.Image
+}
+
+func TestStreamScalerErrors(t *testing.T) {
+	emit := func(y int, row *image.RGBA64) error { return nil }
+
+	z := CatmullRom.NewStreamScaler(4, 4, 8, 8, emit)
+	if err := z.PushRows(image.NewRGBA(image.Rect(0, 0, 7, 1))); err == nil {
+		t.Error("wrong width: got nil error")
+	}
+
+	z = CatmullRom.NewStreamScaler(4, 4, 8, 8, emit)
+	if err := z.PushRows(image.NewRGBA(image.Rect(0, 0, 8, 5))); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Flush(); err == nil {
+		t.Error("too few rows: got nil error")
+	}
+	if err := z.PushRows(image.NewRGBA(image.Rect(0, 0, 8, 4))); err == nil {
+		t.Error("too many rows: got nil error")
+	}
+
+	errEmit := errors.New("emit")
+	z = CatmullRom.NewStreamScaler(4, 4, 8, 8, func(y int, row *image.RGBA64) error { return errEmit })
+	if err := z.PushRows(image.NewRGBA(image.Rect(0, 0, 8, 8))); err != errEmit {
+		t.Errorf("emit error: got %v, want %v", err, errEmit)
+	}
+	if err := z.Flush(); err != errEmit {
+		t.Errorf("Flush after emit error: got %v, want %v", err, errEmit)
+	}
+}
+
+// TestStreamScalerMemory tests that only the kernel's support window of
+// source rows is buffered.
+func TestStreamScalerMemory(t *testing.T) {
+	z := CatmullRom.NewStreamScaler(100, 100, 1000, 10000, func(int, *image.RGBA64) error { return nil })
+	// Scaling down 100 times vertically, CatmullRom's support of 2 covers
+	// about 400 source rows.
+	if z.nRows > 500 {
+		t.Errorf("got %d buffered rows, want at most 500", z.nRows)
+	}
+}
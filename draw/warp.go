@@ -0,0 +1,79 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"math"
+)
+
+// Mapper maps dst-space points to src-space points, for warps that are
+// neither affine nor projective, such as the correction of lens distortion.
+type Mapper interface {
+	// Map returns the src-space point that the dst-space point (dx, dy)
+	// maps to, or !ok if it maps to no point.
+	Map(dx, dy float64) (sx, sy float64, ok bool)
+}
+
+// MapperFunc is a Mapper that calls a function.
+type MapperFunc func(dx, dy float64) (sx, sy float64, ok bool)
+
+// Map implements the Mapper interface.
+func (f MapperFunc) Map(dx, dy float64) (sx, sy float64, ok bool) {
+	return f(dx, dy)
+}
+
+// MapTransformer is like Transformer but for arbitrary warps, given by a
+// Mapper from dst space to src space. Each dst pixel whose center maps to a
+// point within sr is drawn. As the warp cannot be inverted, every pixel of
+// dst is mapped.
+//
+// The local scale of the warp, which determines how much a Kernel is
+// broadened when shrinking, is estimated by mapping the centers of
+// neighboring dst pixels.
+//
+// The NearestNeighbor, ApproxBiLinear and Kernel (such as CatmullRom)
+// interpolators all implement MapTransformer.
+//
+// A MapTransformer is safe to use concurrently if its Mapper is.
+type MapTransformer interface {
+	TransformMap(dst Image, m Mapper, src image.Image, sr image.Rectangle, op Op, opts *Options)
+}
+
+// TransformMap implements the MapTransformer interface.
+func (z nnInterpolator) TransformMap(dst Image, m Mapper, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	warp(dst, dst.Bounds(), src, sr, op, opts, nnSample, mapperWarpFunc(m))
+}
+
+// TransformMap implements the MapTransformer interface.
+func (z ablInterpolator) TransformMap(dst Image, m Mapper, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	warp(dst, dst.Bounds(), src, sr, op, opts, ablSample, mapperWarpFunc(m))
+}
+
+// TransformMap implements the MapTransformer interface.
+func (q *Kernel) TransformMap(dst Image, m Mapper, src image.Image, sr image.Rectangle, op Op, opts *Options) {
+	k := &kernelSampler{q: q}
+	warp(dst, dst.Bounds(), src, sr, op, opts, k.sample, mapperWarpFunc(m))
+}
+
+// mapperWarpFunc returns a warpFunc that maps points by m, estimating the
+// local scale from the points that the next dst pixels along each axis map
+// to.
+func mapperWarpFunc(m Mapper) warpFunc {
+	return func(dxf, dyf float64) (sx, sy, xscale, yscale float64, ok bool) {
+		sx, sy, ok = m.Map(dxf, dyf)
+		if !ok {
+			return 0, 0, 0, 0, false
+		}
+		xscale, yscale = 1, 1
+		if sx1, sy1, ok := m.Map(dxf+1, dyf); ok {
+			xscale, yscale = abs(sx1-sx), abs(sy1-sy)
+		}
+		if sx1, sy1, ok := m.Map(dxf, dyf+1); ok {
+			xscale, yscale = math.Max(xscale, abs(sx1-sx)), math.Max(yscale, abs(sy1-sy))
+		}
+		return sx, sy, xscale, yscale, true
+	}
+}
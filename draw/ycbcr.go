@@ -0,0 +1,189 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+
+	"golang.org/x/image/math/f64"
+)
+
+// ChromaSiting is the position of the chroma samples of a subsampled YCbCr
+// image relative to its luma samples.
+type ChromaSiting int
+
+const (
+	// ChromaCenter means that each chroma sample is centered on the block
+	// of luma samples that it covers, as for JPEG and MPEG-1.
+	ChromaCenter ChromaSiting = iota
+	// ChromaLeft means that each chroma sample is horizontally co-sited
+	// with the left column of the block of luma samples that it covers, and
+	// vertically centered on it, as for MPEG-2, H.264 and HEVC.
+	ChromaLeft
+	// ChromaTopLeft means that each chroma sample is co-sited with the
+	// top-left luma sample of the block that it covers, as for the 4:2:0
+	// images of BT.2020 and BT.2100.
+	ChromaTopLeft
+)
+
+// YCbCrOptions are optional parameters to ScaleYCbCr. A nil *YCbCrOptions
+// means to use the default (zero) values of each field.
+type YCbCrOptions struct {
+	// Luma and Chroma interpolate the Y plane, and the Cb and Cr planes.
+	// Nil means CatmullRom for Luma and BiLinear for Chroma.
+	Luma, Chroma Interpolator
+	// SrcSiting and DstSiting are the chroma siting of src and dst. They
+	// only matter for the axes along which chroma is subsampled.
+	SrcSiting, DstSiting ChromaSiting
+}
+
+// ScaleYCbCr scales the sr part of src to the dr part of dst, which may have
+// different subsample ratios, one plane at a time.
+//
+// Each plane is resampled once, directly from src's plane, so that chroma
+// is not upsampled and then downsampled again. The chroma planes are
+// resampled so that each dst chroma sample takes the chroma of the point of
+// src under it, given the chroma siting of src and dst. Beyond the edges of
+// sr, the src chroma samples are clamped.
+func ScaleYCbCr(dst *image.YCbCr, dr image.Rectangle, src *image.YCbCr, sr image.Rectangle, opts *YCbCrOptions) {
+	var o YCbCrOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Luma == nil {
+		o.Luma = CatmullRom
+	}
+	if o.Chroma == nil {
+		o.Chroma = BiLinear
+	}
+	sr = sr.Intersect(src.Rect)
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+
+	o.Luma.Scale(lumaPlane(dst), dr, lumaPlane(src), sr, Src, nil)
+
+	dhs, dvs := subsampling(dst.SubsampleRatio)
+	shs, svs := subsampling(src.SubsampleRatio)
+	ax, bx := chromaMap(dr.Min.X, dr.Dx(), dhs, o.DstSiting != ChromaCenter, sr.Min.X, sr.Dx(), shs, o.SrcSiting != ChromaCenter)
+	ay, by := chromaMap(dr.Min.Y, dr.Dy(), dvs, o.DstSiting == ChromaTopLeft, sr.Min.Y, sr.Dy(), svs, o.SrcSiting == ChromaTopLeft)
+	// The map from dst chroma to src chroma is inverted, as Transform maps
+	// src to dst.
+	s2d := f64.Aff3{
+		1 / ax, 0, -bx / ax,
+		0, 1 / ay, -by / ay,
+	}
+	dcr := chromaRect(dr.Intersect(dst.Rect), dhs, dvs)
+	scr := chromaRect(sr, shs, svs)
+	opts1 := &Options{EdgeOp: EdgeClamp}
+	for _, p := range [][2][]uint8{{dst.Cb, src.Cb}, {dst.Cr, src.Cr}} {
+		d := chromaPlane(dst, p[0], dhs, dvs).SubImage(dcr).(*image.Gray)
+		s := chromaPlane(src, p[1], shs, svs)
+		o.Chroma.Transform(d, s2d, s, scr, Src, opts1)
+	}
+}
+
+// subsampling returns the number of luma samples per chroma sample along
+// each axis.
+func subsampling(r image.YCbCrSubsampleRatio) (h, v int) {
+	switch r {
+	case image.YCbCrSubsampleRatio422:
+		return 2, 1
+	case image.YCbCrSubsampleRatio420:
+		return 2, 2
+	case image.YCbCrSubsampleRatio440:
+		return 1, 2
+	case image.YCbCrSubsampleRatio411:
+		return 4, 1
+	case image.YCbCrSubsampleRatio410:
+		return 4, 2
+	}
+	return 1, 1
+}
+
+// chromaRect returns the rectangle of chroma samples that cover the luma
+// samples in r, as laid out by image.YCbCr.
+func chromaRect(r image.Rectangle, h, v int) image.Rectangle {
+	return image.Rect(r.Min.X/h, r.Min.Y/v, (r.Max.X+h-1)/h, (r.Max.Y+v-1)/v)
+}
+
+// lumaPlane returns the Y plane of m as an image.Gray.
+func lumaPlane(m *image.YCbCr) *image.Gray {
+	return &image.Gray{Pix: m.Y, Stride: m.YStride, Rect: m.Rect}
+}
+
+// chromaPlane returns pix, a chroma plane of m, as an image.Gray.
+func chromaPlane(m *image.YCbCr, pix []uint8, h, v int) *image.Gray {
+	return &image.Gray{Pix: pix, Stride: m.CStride, Rect: chromaRect(m.Rect, h, v)}
+}
+
+// chromaMap returns the coefficients of the map, c = a*u + b, from the dst
+// chroma coordinate u to the src chroma coordinate c, along one axis, for
+// the dst luma samples from d0 to d0+dn and the src ones from s0 to s0+sn.
+// In both, chroma sample i covers the luma samples from n*i to n*(i+1),
+// where n is the subsampling, and its center is at i+0.5. A co-sited sample
+// is at the center of the first luma sample that it covers.
+func chromaMap(d0, dn, dh int, dCosited bool, s0, sn, sh int, sCosited bool) (a, b float64) {
+	// A chroma coordinate c is at the luma coordinate n*c + p.
+	dp, sp := 0.0, 0.0
+	if dCosited {
+		dp = 0.5 - float64(dh)/2
+	}
+	if sCosited {
+		sp = 0.5 - float64(sh)/2
+	}
+	scale := float64(sn) / float64(dn)
+	a = float64(dh) * scale / float64(sh)
+	b = (float64(s0) + (dp-float64(d0))*scale - sp) / float64(sh)
+	return a, b
+}
+
+// CopyYCbCr copies the sr part of src to dst, translated so that sr.Min
+// translates to dp, without converting to and from RGB.
+//
+// If dst and src have the same subsample ratio, and dp and sr.Min are at the
+// same position within a chroma block, such as when both are even for
+// 4:2:0, then the Y, Cb and Cr samples are copied exactly. The chroma blocks
+// that are only partly inside sr, at its edges, are copied whole, and so
+// change the chroma of the dst pixels that they cover just outside of the
+// copied pixels. Otherwise, the Y samples are copied exactly and the chroma
+// planes are resampled as by ScaleYCbCr, ignoring opts' Luma.
+func CopyYCbCr(dst *image.YCbCr, dp image.Point, src *image.YCbCr, sr image.Rectangle, opts *YCbCrOptions) {
+	delta := dp.Sub(sr.Min)
+	dr := sr.Add(delta).Intersect(dst.Rect).Intersect(src.Rect.Add(delta))
+	if dr.Empty() {
+		return
+	}
+	sr = dr.Sub(delta)
+
+	h, v := subsampling(src.SubsampleRatio)
+	if dst.SubsampleRatio != src.SubsampleRatio || delta.X%h != 0 || delta.Y%v != 0 ||
+		dr.Min.X < 0 || dr.Min.Y < 0 || sr.Min.X < 0 || sr.Min.Y < 0 {
+		var o YCbCrOptions
+		if opts != nil {
+			o = *opts
+		}
+		o.Luma = NearestNeighbor
+		ScaleYCbCr(dst, dr, src, sr, &o)
+		return
+	}
+
+	for y := sr.Min.Y; y < sr.Max.Y; y++ {
+		di := dst.YOffset(dr.Min.X, y+delta.Y)
+		si := src.YOffset(sr.Min.X, y)
+		copy(dst.Y[di:di+dr.Dx()], src.Y[si:])
+	}
+	// The translation is a whole number of chroma samples, and the
+	// coordinates are non-negative, so that the luma samples in each chroma
+	// block of sr are all in the same chroma block of dst.
+	dcr, scr := chromaRect(dr, h, v), chromaRect(sr, h, v)
+	dc, sc := chromaRect(dst.Rect, h, v).Min, chromaRect(src.Rect, h, v).Min
+	for y := scr.Min.Y; y < scr.Max.Y; y++ {
+		di := (dcr.Min.Y+y-scr.Min.Y-dc.Y)*dst.CStride + (dcr.Min.X - dc.X)
+		si := (y-sc.Y)*src.CStride + (scr.Min.X - sc.X)
+		copy(dst.Cb[di:di+dcr.Dx()], src.Cb[si:])
+		copy(dst.Cr[di:di+dcr.Dx()], src.Cr[si:])
+	}
+}
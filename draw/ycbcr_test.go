@@ -0,0 +1,157 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestScaleYCbCrIdentity(t *testing.T) {
+	r := image.Rect(0, 0, 16, 12)
+	for _, ratio := range []image.YCbCrSubsampleRatio{
+		image.YCbCrSubsampleRatio444,
+		image.YCbCrSubsampleRatio422,
+		image.YCbCrSubsampleRatio420,
+		image.YCbCrSubsampleRatio440,
+		image.YCbCrSubsampleRatio411,
+		image.YCbCrSubsampleRatio410,
+	} {
+		src := image.NewYCbCr(r, ratio)
+		for i := range src.Y {
+			src.Y[i] = uint8(i * 7)
+		}
+		for i := range src.Cb {
+			src.Cb[i], src.Cr[i] = uint8(i*11), uint8(255-i*5)
+		}
+		for _, siting := range []ChromaSiting{ChromaCenter, ChromaLeft, ChromaTopLeft} {
+			dst := image.NewYCbCr(r, ratio)
+			ScaleYCbCr(dst, r, src, r, &YCbCrOptions{
+				Luma:      NearestNeighbor,
+				Chroma:    NearestNeighbor,
+				SrcSiting: siting,
+				DstSiting: siting,
+			})
+			if string(dst.Y) != string(src.Y) || string(dst.Cb) != string(src.Cb) || string(dst.Cr) != string(src.Cr) {
+				t.Errorf("ratio %v, siting %d: planes differ", ratio, siting)
+			}
+		}
+	}
+}
+
+// TestScaleYCbCrSiting checks that chroma is resampled at the position of
+// the dst chroma samples, by converting from 4:4:4 to 4:2:0 with chroma that
+// increases linearly with x and y.
+func TestScaleYCbCrSiting(t *testing.T) {
+	r := image.Rect(0, 0, 32, 32)
+	src := image.NewYCbCr(r, image.YCbCrSubsampleRatio444)
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			// The src chroma samples are at the luma centers, (x+0.5, y+0.5).
+			src.Cb[src.COffset(x, y)] = uint8(4 * x)
+			src.Cr[src.COffset(x, y)] = uint8(4 * y)
+		}
+	}
+	testCases := []struct {
+		siting ChromaSiting
+		// dx and dy are the offsets of the dst chroma samples from the
+		// top-left corner of the 2x2 luma blocks.
+		dx, dy float64
+	}{
+		{ChromaCenter, 1, 1},
+		{ChromaLeft, 0.5, 1},
+		{ChromaTopLeft, 0.5, 0.5},
+	}
+	for _, tc := range testCases {
+		dst := image.NewYCbCr(r, image.YCbCrSubsampleRatio420)
+		ScaleYCbCr(dst, r, src, r, &YCbCrOptions{DstSiting: tc.siting})
+		// Skip the edges, where the chroma is clamped.
+		for j := 2; j < 14; j++ {
+			for i := 2; i < 14; i++ {
+				wantCb := 4 * (2*float64(i) + tc.dx - 0.5)
+				wantCr := 4 * (2*float64(j) + tc.dy - 0.5)
+				k := dst.COffset(2*i, 2*j)
+				if math.Abs(float64(dst.Cb[k])-wantCb) > 1 || math.Abs(float64(dst.Cr[k])-wantCr) > 1 {
+					t.Fatalf("siting %d, chroma sample (%d, %d): got (%d, %d), want (%.1f, %.1f)",
+						tc.siting, i, j, dst.Cb[k], dst.Cr[k], wantCb, wantCr)
+				}
+			}
+		}
+	}
+}
+
+func TestScaleYCbCrUpscale(t *testing.T) {
+	// A uniform 4:2:0 image scaled up to 4:4:4 stays uniform, up to its
+	// edges.
+	src := image.NewYCbCr(image.Rect(0, 0, 10, 6), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = 0x40
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = 0x50, 0x60
+	}
+	dr := image.Rect(5, 5, 35, 23)
+	dst := image.NewYCbCr(image.Rect(0, 0, 40, 30), image.YCbCrSubsampleRatio444)
+	ScaleYCbCr(dst, dr, src, src.Rect, &YCbCrOptions{SrcSiting: ChromaLeft})
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			want := [3]uint8{0x40, 0x50, 0x60}
+			if !(image.Point{x, y}).In(dr) {
+				want = [3]uint8{}
+			}
+			got := [3]uint8{dst.Y[dst.YOffset(x, y)], dst.Cb[dst.COffset(x, y)], dst.Cr[dst.COffset(x, y)]}
+			if got != want {
+				t.Fatalf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestCopyYCbCr(t *testing.T) {
+	src := image.NewYCbCr(image.Rect(2, 4, 18, 16), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = uint8(i * 7)
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = uint8(i*11), uint8(255-i*5)
+	}
+	sr := image.Rect(4, 6, 14, 12)
+
+	// An aligned copy copies every sample exactly.
+	dst := image.NewYCbCr(image.Rect(0, 0, 20, 20), image.YCbCrSubsampleRatio420)
+	dp := image.Point{6, 2}
+	CopyYCbCr(dst, dp, src, sr, nil)
+	for y := sr.Min.Y; y < sr.Max.Y; y++ {
+		for x := sr.Min.X; x < sr.Max.X; x++ {
+			dx, dy := x+dp.X-sr.Min.X, y+dp.Y-sr.Min.Y
+			if got, want := dst.YCbCrAt(dx, dy), src.YCbCrAt(x, y); got != want {
+				t.Errorf("aligned: (%d, %d): got %v, want %v", dx, dy, got, want)
+			}
+		}
+	}
+	if got := dst.YCbCrAt(dp.X-1, dp.Y); got.Y != 0 {
+		t.Errorf("aligned: the pixel left of the copy changed: %v", got)
+	}
+
+	// An unaligned copy, or one to another subsample ratio, copies the luma
+	// exactly and resamples the chroma.
+	for _, ratio := range []image.YCbCrSubsampleRatio{
+		image.YCbCrSubsampleRatio420,
+		image.YCbCrSubsampleRatio444,
+	} {
+		dst := image.NewYCbCr(image.Rect(0, 0, 20, 20), ratio)
+		dp := image.Point{5, 3}
+		CopyYCbCr(dst, dp, src, sr, nil)
+		for y := sr.Min.Y; y < sr.Max.Y; y++ {
+			for x := sr.Min.X; x < sr.Max.X; x++ {
+				dx, dy := x+dp.X-sr.Min.X, y+dp.Y-sr.Min.Y
+				if got, want := dst.YCbCrAt(dx, dy).Y, src.YCbCrAt(x, y).Y; got != want {
+					t.Errorf("ratio %v: (%d, %d): got Y %d, want %d", ratio, dx, dy, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fax implements helpers for TIFF Class F (TIFF-F) files, the
+// profile of multi-page TIFF for facsimile documents that is used by fax
+// servers, modems and document management systems.
+//
+// Encode writes TIFF-F files and Validate checks that a TIFF file conforms to
+// the profile. TIFF-F files are decoded by the golang.org/x/image/tiff
+// package, such as with tiff.DecodeAll for all of their pages.
+//
+// TIFF Class F is defined by RFC 2306, at
+// https://tools.ietf.org/html/rfc2306
+package fax // import "golang.org/x/image/fax"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"golang.org/x/image/tiff"
+)
+
+// A FormatError reports that the input is not a valid TIFF Class F file.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "fax: invalid format: " + string(e)
+}
+
+// The widths, in pixels, of the pages of TIFF Class F files, for A4, B4 and
+// A3 paper at the horizontal resolution of 204 dots per inch.
+const (
+	WidthA4 = 1728
+	WidthB4 = 2048
+	WidthA3 = 2432
+)
+
+var widths = [...]int{WidthA4, WidthB4, WidthA3}
+
+// xResolution is the horizontal resolution of every page, in dots per inch.
+const xResolution = 204
+
+// Resolution is the vertical resolution of the pages of a fax.
+type Resolution int
+
+const (
+	// Standard is 98 lines per inch, or 3.85 lines per millimeter.
+	Standard Resolution = iota
+	// Fine is 196 lines per inch, or 7.7 lines per millimeter.
+	Fine
+	// Superfine is 391 lines per inch, or 15.4 lines per millimeter.
+	Superfine
+)
+
+// linesPerInch are the vertical resolutions of each Resolution.
+var linesPerInch = [...]float64{
+	Standard:  98,
+	Fine:      196,
+	Superfine: 391,
+}
+
+// Options are the encoding parameters.
+type Options struct {
+	// Resolution is the vertical resolution of every page.
+	Resolution Resolution
+}
+
+// Encode writes the images pages to w as a TIFF Class F file, with one page
+// per image, in order. The pages are coded in black and white, with the gray
+// levels less than half being black, with CCITT Group 3 one-dimensional
+// (Modified Huffman) coding, which all fax readers support.
+//
+// The pages are not scaled: their pixels should already have the aspect of
+// the resolution. A page narrower than WidthA3 is padded on the right with
+// white to the narrowest width that fits it. A wider page is an error.
+func Encode(w io.Writer, pages []image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Resolution < 0 || int(o.Resolution) >= len(linesPerInch) {
+		return fmt.Errorf("fax: invalid resolution %d", o.Resolution)
+	}
+	ms := make([]image.Image, len(pages))
+	for i, m := range pages {
+		b := m.Bounds()
+		width := 0
+		for _, w := range widths {
+			if b.Dx() <= w {
+				width = w
+				break
+			}
+		}
+		if width == 0 {
+			return fmt.Errorf("fax: page %d is wider than %d pixels", i, WidthA3)
+		}
+		ms[i] = m
+		if width != b.Dx() {
+			dst := image.NewGray(image.Rect(0, 0, width, b.Dy()))
+			draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+			draw.Draw(dst, dst.Bounds(), m, b.Min, draw.Over)
+			ms[i] = dst
+		}
+	}
+	return tiff.EncodeAll(w, ms, &tiff.Options{
+		Compression: tiff.CCITTGroup3,
+		MultiPage:   true,
+		Metadata: &tiff.Metadata{
+			XResolution:    xResolution,
+			YResolution:    linesPerInch[o.Resolution],
+			ResolutionUnit: tiff.ResolutionInch,
+		},
+	})
+}
+
+// TIFF tags and values checked by Validate.
+const (
+	tNewSubfileType            = 254
+	tImageWidth                = 256
+	tBitsPerSample             = 258
+	tCompression               = 259
+	tPhotometricInterpretation = 262
+	tSamplesPerPixel           = 277
+	tXResolution               = 282
+	tYResolution               = 283
+	tResolutionUnit            = 296
+	tPageNumber                = 297
+
+	dtShort    = 3
+	dtLong     = 4
+	dtRational = 5
+
+	cG3 = 3
+	cG4 = 4
+
+	subfilePage = 2
+
+	resPerInch = 2
+	resPerCM   = 3
+)
+
+// Validate checks that the TIFF file in r conforms to TIFF Class F: that it
+// is structurally sound, as checked by tiff.Validate, and that every page is
+// a black and white image, compressed with CCITT Group 3 or Group 4 coding,
+// of one of the widths and resolutions of fax, marked as a page and numbered
+// in order. It returns the number of pages.
+func Validate(r io.Reader) (int, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	dirs, err := tiff.Validate(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	// tiff.Validate has checked the header.
+	var v validator
+	v.data = data
+	switch string(data[:4]) {
+	case "II\x2A\x00":
+		v.byteOrder = binary.LittleEndian
+	case "MM\x00\x2A":
+		v.byteOrder = binary.BigEndian
+	default:
+		return 0, FormatError("not a classic TIFF file")
+	}
+	for i, dir := range dirs {
+		if err := v.validatePage(dir, i, len(dirs)); err != nil {
+			return 0, FormatError(fmt.Sprintf("page %d: %v", i, err))
+		}
+	}
+	return len(dirs), nil
+}
+
+// validator reads the values of the IFD entries of a TIFF file.
+type validator struct {
+	data      []byte
+	byteOrder binary.ByteOrder
+}
+
+// validatePage checks the IFD of page i of a file of n pages.
+func (v *validator) validatePage(dir tiff.Directory, i, n int) error {
+	entries := map[uint16]tiff.Entry{}
+	for _, e := range dir.Entries {
+		entries[e.Tag] = e
+	}
+	// value returns the single value of the tag's entry, or def if it is
+	// absent. A negative def means that the tag is required.
+	value := func(tag uint16, name string, def int64) (uint32, error) {
+		e, ok := entries[tag]
+		if !ok {
+			if def < 0 {
+				return 0, fmt.Errorf("missing %s", name)
+			}
+			return uint32(def), nil
+		}
+		vals, err := v.uints(e)
+		if err != nil || len(vals) != 1 {
+			return 0, fmt.Errorf("bad %s", name)
+		}
+		return vals[0], nil
+	}
+
+	for _, t := range []struct {
+		tag  uint16
+		name string
+		def  int64
+		want []uint32
+	}{
+		{tBitsPerSample, "BitsPerSample", 1, []uint32{1}},
+		{tSamplesPerPixel, "SamplesPerPixel", 1, []uint32{1}},
+		{tCompression, "Compression", 1, []uint32{cG3, cG4}},
+		{tPhotometricInterpretation, "PhotometricInterpretation", -1, []uint32{0}},
+		{tImageWidth, "ImageWidth", -1, []uint32{WidthA4, WidthB4, WidthA3}},
+		{tNewSubfileType, "NewSubfileType", 0, []uint32{subfilePage}},
+	} {
+		got, err := value(t.tag, t.name, t.def)
+		if err != nil {
+			return err
+		}
+		if !contains(t.want, got) {
+			return fmt.Errorf("%s is %d, want one of %v", t.name, got, t.want)
+		}
+	}
+
+	unit, err := value(tResolutionUnit, "ResolutionUnit", resPerInch)
+	if err != nil {
+		return err
+	}
+	scale := 1.0
+	switch unit {
+	case resPerInch:
+	case resPerCM:
+		scale = 2.54
+	default:
+		return fmt.Errorf("ResolutionUnit is %d, want inch or centimeter", unit)
+	}
+	x, err := v.rational(entries, tXResolution, "XResolution")
+	if err != nil {
+		return err
+	}
+	if !near(x*scale, xResolution) {
+		return fmt.Errorf("XResolution is %g dpi, want %d", x*scale, xResolution)
+	}
+	y, err := v.rational(entries, tYResolution, "YResolution")
+	if err != nil {
+		return err
+	}
+	ok := false
+	for _, want := range linesPerInch {
+		ok = ok || near(y*scale, want)
+	}
+	if !ok {
+		return fmt.Errorf("YResolution is %g dpi, want one of %v", y*scale, linesPerInch)
+	}
+
+	e, ok := entries[tPageNumber]
+	if !ok {
+		return fmt.Errorf("missing PageNumber")
+	}
+	pn, err := v.uints(e)
+	if err != nil || len(pn) != 2 {
+		return fmt.Errorf("bad PageNumber")
+	}
+	// A total of zero means that the number of pages is unknown.
+	if int(pn[0]) != i || (pn[1] != 0 && int(pn[1]) != n) {
+		return fmt.Errorf("PageNumber is %d of %d, want %d of %d", pn[0], pn[1], i, n)
+	}
+	return nil
+}
+
+// uints returns the values of e, which must be of the Short or Long type.
+func (v *validator) uints(e tiff.Entry) ([]uint32, error) {
+	size := 0
+	switch e.Type {
+	case dtShort:
+		size = 2
+	case dtLong:
+		size = 4
+	default:
+		return nil, fmt.Errorf("tag %d: bad type %d", e.Tag, e.Type)
+	}
+	// tiff.Validate has checked that the values lie within the file.
+	p := v.data[e.ValueOffset:]
+	vals := make([]uint32, e.Count)
+	for i := range vals {
+		if size == 2 {
+			vals[i] = uint32(v.byteOrder.Uint16(p[2*i:]))
+		} else {
+			vals[i] = v.byteOrder.Uint32(p[4*i:])
+		}
+	}
+	return vals, nil
+}
+
+// rational returns the single Rational value of the tag's entry, which must
+// be present.
+func (v *validator) rational(entries map[uint16]tiff.Entry, tag uint16, name string) (float64, error) {
+	e, ok := entries[tag]
+	if !ok {
+		return 0, fmt.Errorf("missing %s", name)
+	}
+	if e.Type != dtRational || e.Count != 1 {
+		return 0, fmt.Errorf("bad %s", name)
+	}
+	p := v.data[e.ValueOffset:]
+	num, den := v.byteOrder.Uint32(p[0:4]), v.byteOrder.Uint32(p[4:8])
+	if den == 0 {
+		return 0, fmt.Errorf("bad %s", name)
+	}
+	return float64(num) / float64(den), nil
+}
+
+// near returns whether the resolution x, in dots per inch, is that of want,
+// allowing for the rounding of resolutions given in metric units.
+func near(x, want float64) bool {
+	return math.Abs(x-want) <= 0.01*want
+}
+
+func contains(s []uint32, x uint32) bool {
+	for _, y := range s {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package fax
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/tiff"
+)
+
+func TestEncode(t *testing.T) {
+	// The first page is padded to WidthA4, and the second is already of
+	// WidthB4.
+	p0 := image.NewGray(image.Rect(10, 10, 110, 60))
+	for i := range p0.Pix {
+		p0.Pix[i] = 0xff
+	}
+	p0.SetGray(20, 30, color.Gray{})
+	p1 := image.NewRGBA(image.Rect(0, 0, WidthB4, 20))
+	pages := []image.Image{p0, p1}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, pages, &Options{Resolution: Fine}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := Validate(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if n != len(pages) {
+		t.Errorf("Validate: got %d pages, want %d", n, len(pages))
+	}
+
+	ms, err := tiff.DecodeAll(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != len(pages) {
+		t.Fatalf("got %d pages, want %d", len(ms), len(pages))
+	}
+	if got, want := ms[0].Bounds(), image.Rect(0, 0, WidthA4, 50); got != want {
+		t.Errorf("page 0: got bounds %v, want %v", got, want)
+	}
+	for _, pt := range []image.Point{{10, 20}, {0, 0}, {1000, 40}} {
+		want := uint8(0xff)
+		if pt == (image.Point{10, 20}) {
+			want = 0
+		}
+		if got := color.GrayModel.Convert(ms[0].At(pt.X, pt.Y)).(color.Gray).Y; got != want {
+			t.Errorf("page 0: pixel at %v: got %#02x, want %#02x", pt, got, want)
+		}
+	}
+	if got, want := ms[1].Bounds(), p1.Bounds(); got != want {
+		t.Errorf("page 1: got bounds %v, want %v", got, want)
+	}
+
+	meta, err := tiff.DecodeMetadata(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, y := meta.DPI(); x != 204 || y != 196 {
+		t.Errorf("got %v x %v dpi, want 204 x 196", x, y)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, WidthA3+1, 1))
+	if err := Encode(new(bytes.Buffer), []image.Image{m}, nil); err == nil {
+		t.Error("too wide: got nil error")
+	}
+	m = image.NewGray(image.Rect(0, 0, 1, 1))
+	if err := Encode(new(bytes.Buffer), []image.Image{m}, &Options{Resolution: 3}); err == nil {
+		t.Error("bad resolution: got nil error")
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	bilevel := image.NewGray(image.Rect(0, 0, WidthA4, 4))
+	for _, tc := range []struct {
+		name string
+		m    image.Image
+		opt  *tiff.Options
+		want string
+	}{
+		{"uncompressed", bilevel, nil, "BitsPerSample is 8"},
+		{"not a page", bilevel, &tiff.Options{Compression: tiff.CCITTGroup3}, "NewSubfileType is 0"},
+		{
+			"width",
+			image.NewGray(image.Rect(0, 0, 1000, 4)),
+			&tiff.Options{Compression: tiff.CCITTGroup3, MultiPage: true},
+			"ImageWidth is 1000",
+		},
+		{
+			"resolution",
+			bilevel,
+			&tiff.Options{Compression: tiff.CCITTGroup3, MultiPage: true},
+			"XResolution is 72 dpi",
+		},
+	} {
+		var buf bytes.Buffer
+		if err := tiff.Encode(&buf, tc.m, tc.opt); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		_, err := Validate(bytes.NewReader(buf.Bytes()))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got error %v, want one containing %q", tc.name, err, tc.want)
+		}
+	}
+}
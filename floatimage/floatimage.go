@@ -0,0 +1,268 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package floatimage implements images with 32-bit floating-point samples,
+// for high dynamic range and scientific imagery.
+//
+// Sample values are nominally in the range [0, 1], with 0 being black and 1
+// being white, but values outside of that range are allowed and are kept
+// when setting and getting pixels with the types' own color types. They are
+// clamped when converting to the image/color package's 16-bit colors.
+package floatimage // import "golang.org/x/image/floatimage"
+
+import (
+	"image"
+	"image/color"
+)
+
+// clamp returns x clamped to [0, 1] and scaled to [0, 0xffff].
+func clamp(x float32) uint32 {
+	switch {
+	case !(x > 0): // Also true for NaN.
+		return 0
+	case x >= 1:
+		return 0xffff
+	}
+	return uint32(x*0xffff + 0.5)
+}
+
+// GrayF32Color represents a floating-point grayscale color.
+type GrayF32Color struct {
+	Y float32
+}
+
+func (c GrayF32Color) RGBA() (r, g, b, a uint32) {
+	y := clamp(c.Y)
+	return y, y, y, 0xffff
+}
+
+// RGBAF32Color represents a floating-point, alpha-premultiplied color.
+type RGBAF32Color struct {
+	R, G, B, A float32
+}
+
+func (c RGBAF32Color) RGBA() (r, g, b, a uint32) {
+	r, g, b, a = clamp(c.R), clamp(c.G), clamp(c.B), clamp(c.A)
+	// Keep the premultiplied color valid after clamping.
+	if r > a {
+		r = a
+	}
+	if g > a {
+		g = a
+	}
+	if b > a {
+		b = a
+	}
+	return r, g, b, a
+}
+
+// Models for the floating-point color types.
+var (
+	GrayF32Model color.Model = color.ModelFunc(grayF32Model)
+	RGBAF32Model color.Model = color.ModelFunc(rgbaF32Model)
+)
+
+func grayF32Model(c color.Color) color.Color {
+	switch c := c.(type) {
+	case GrayF32Color:
+		return c
+	case RGBAF32Color:
+		return GrayF32Color{0.299*c.R + 0.587*c.G + 0.114*c.B}
+	}
+	r, g, b, _ := c.RGBA()
+	// These coefficients are those of the color.Gray16Model.
+	return GrayF32Color{(0.299*float32(r) + 0.587*float32(g) + 0.114*float32(b)) / 0xffff}
+}
+
+func rgbaF32Model(c color.Color) color.Color {
+	switch c := c.(type) {
+	case RGBAF32Color:
+		return c
+	case GrayF32Color:
+		return RGBAF32Color{c.Y, c.Y, c.Y, 1}
+	}
+	r, g, b, a := c.RGBA()
+	return RGBAF32Color{float32(r) / 0xffff, float32(g) / 0xffff, float32(b) / 0xffff, float32(a) / 0xffff}
+}
+
+// GrayF32 is an in-memory image whose At method returns GrayF32Color values.
+type GrayF32 struct {
+	// Pix holds the image's pixels, as gray values. The pixel at (x, y)
+	// starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*1].
+	Pix []float32
+	// Stride is the Pix stride (in samples) between vertically adjacent
+	// pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+func (p *GrayF32) ColorModel() color.Model { return GrayF32Model }
+
+func (p *GrayF32) Bounds() image.Rectangle { return p.Rect }
+
+func (p *GrayF32) At(x, y int) color.Color {
+	return p.GrayF32At(x, y)
+}
+
+func (p *GrayF32) GrayF32At(x, y int) GrayF32Color {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return GrayF32Color{}
+	}
+	return GrayF32Color{p.Pix[p.PixOffset(x, y)]}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *GrayF32) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*1
+}
+
+func (p *GrayF32) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.Pix[p.PixOffset(x, y)] = GrayF32Model.Convert(c).(GrayF32Color).Y
+}
+
+func (p *GrayF32) SetGrayF32(x, y int, c GrayF32Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.Pix[p.PixOffset(x, y)] = c.Y
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *GrayF32) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be
+	// inside either r1 or r2 if the intersection is empty. Without explicitly
+	// checking for this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &GrayF32{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &GrayF32{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *GrayF32) Opaque() bool {
+	return true
+}
+
+// NewGrayF32 returns a new GrayF32 image with the given bounds.
+func NewGrayF32(r image.Rectangle) *GrayF32 {
+	w, h := r.Dx(), r.Dy()
+	return &GrayF32{
+		Pix:    make([]float32, w*h),
+		Stride: w,
+		Rect:   r,
+	}
+}
+
+// RGBAF32 is an in-memory image whose At method returns RGBAF32Color
+// values.
+type RGBAF32 struct {
+	// Pix holds the image's pixels, in R, G, B, A order. The pixel at
+	// (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []float32
+	// Stride is the Pix stride (in samples) between vertically adjacent
+	// pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+func (p *RGBAF32) ColorModel() color.Model { return RGBAF32Model }
+
+func (p *RGBAF32) Bounds() image.Rectangle { return p.Rect }
+
+func (p *RGBAF32) At(x, y int) color.Color {
+	return p.RGBAF32At(x, y)
+}
+
+func (p *RGBAF32) RGBAF32At(x, y int) RGBAF32Color {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return RGBAF32Color{}
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	return RGBAF32Color{s[0], s[1], s[2], s[3]}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y).
+func (p *RGBAF32) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x-p.Rect.Min.X)*4
+}
+
+func (p *RGBAF32) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetRGBAF32(x, y, RGBAF32Model.Convert(c).(RGBAF32Color))
+}
+
+func (p *RGBAF32) SetRGBAF32(x, y int, c RGBAF32Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	s := p.Pix[i : i+4 : i+4] // Small cap improves performance, see https://golang.org/issue/27857
+	s[0] = c.R
+	s[1] = c.G
+	s[2] = c.B
+	s[3] = c.A
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *RGBAF32) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be
+	// inside either r1 or r2 if the intersection is empty. Without explicitly
+	// checking for this, the Pix[i:] expression below can panic.
+	if r.Empty() {
+		return &RGBAF32{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGBAF32{
+		Pix:    p.Pix[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *RGBAF32) Opaque() bool {
+	if p.Rect.Empty() {
+		return true
+	}
+	i0, i1 := 3, p.Rect.Dx()*4
+	for y := p.Rect.Min.Y; y < p.Rect.Max.Y; y++ {
+		for i := i0; i < i1; i += 4 {
+			if p.Pix[i] < 1 {
+				return false
+			}
+		}
+		i0 += p.Stride
+		i1 += p.Stride
+	}
+	return true
+}
+
+// NewRGBAF32 returns a new RGBAF32 image with the given bounds.
+func NewRGBAF32(r image.Rectangle) *RGBAF32 {
+	w, h := r.Dx(), r.Dy()
+	return &RGBAF32{
+		Pix:    make([]float32, 4*w*h),
+		Stride: 4 * w,
+		Rect:   r,
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package floatimage
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestGrayF32(t *testing.T) {
+	m := NewGrayF32(image.Rect(-1, -2, 3, 4))
+	m.SetGrayF32(0, 0, GrayF32Color{2.5})
+	if got := m.GrayF32At(0, 0); got.Y != 2.5 {
+		t.Errorf("got %v, want 2.5", got.Y)
+	}
+	if r, _, _, a := m.At(0, 0).RGBA(); r != 0xffff || a != 0xffff {
+		t.Errorf("clamped: got %#x, %#x, want 0xffff, 0xffff", r, a)
+	}
+	m.Set(1, 1, color.Gray{0x80})
+	if got, want := m.GrayF32At(1, 1).Y, float32(0x8080)/0xffff; math.Abs(float64(got-want)) > 1e-6 {
+		t.Errorf("Set: got %v, want %v", got, want)
+	}
+	if got := color.GrayModel.Convert(m.At(1, 1)).(color.Gray).Y; got != 0x80 {
+		t.Errorf("round trip: got %#x, want 0x80", got)
+	}
+
+	sub := m.SubImage(image.Rect(0, 0, 2, 2)).(*GrayF32)
+	if got := sub.GrayF32At(0, 0); got.Y != 2.5 {
+		t.Errorf("SubImage: got %v, want 2.5", got.Y)
+	}
+	if got := sub.GrayF32At(2, 2); got.Y != 0 {
+		t.Errorf("SubImage: out of bounds got %v, want 0", got.Y)
+	}
+	if !m.Opaque() {
+		t.Error("Opaque: got false, want true")
+	}
+}
+
+func TestRGBAF32(t *testing.T) {
+	m := NewRGBAF32(image.Rect(0, 0, 3, 2))
+	if m.Opaque() {
+		t.Error("Opaque: got true for a transparent image")
+	}
+	c := RGBAF32Color{0.25, -1, 4, 0.5}
+	m.SetRGBAF32(2, 1, c)
+	if got := m.RGBAF32At(2, 1); got != c {
+		t.Errorf("got %v, want %v", got, c)
+	}
+	// Clamping keeps the color alpha-premultiplied.
+	r, g, b, a := c.RGBA()
+	if r != 0x4000 || g != 0 || b != 0x8000 || a != 0x8000 {
+		t.Errorf("RGBA: got %#x, %#x, %#x, %#x", r, g, b, a)
+	}
+
+	m.Set(0, 0, color.NRGBA{0xff, 0, 0, 0x80})
+	want := RGBAF32Color{float32(0x8080) / 0xffff, 0, 0, float32(0x8080) / 0xffff}
+	if got := m.RGBAF32At(0, 0); got != want {
+		t.Errorf("Set: got %v, want %v", got, want)
+	}
+	if got := RGBAF32Model.Convert(GrayF32Color{0.5}); got != (RGBAF32Color{0.5, 0.5, 0.5, 1}) {
+		t.Errorf("gray: got %v", got)
+	}
+	if got := GrayF32Model.Convert(RGBAF32Color{1, 1, 1, 1}).(GrayF32Color); math.Abs(float64(got.Y-1)) > 1e-6 {
+		t.Errorf("white: got %v, want 1", got.Y)
+	}
+
+	sub := m.SubImage(image.Rect(2, 1, 5, 5)).(*RGBAF32)
+	if got := sub.Bounds(); got != image.Rect(2, 1, 3, 2) {
+		t.Errorf("SubImage: got bounds %v", got)
+	}
+	if got := sub.RGBAF32At(2, 1); got != c {
+		t.Errorf("SubImage: got %v, want %v", got, c)
+	}
+}
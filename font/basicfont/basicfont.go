@@ -9,9 +9,11 @@ package basicfont // import "golang.org/x/image/font/basicfont"
 
 import (
 	"image"
+	"unicode"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/rangetable"
 )
 
 // Range maps a contiguous range of runes to vertically adjacent sub-images of
@@ -70,6 +72,16 @@ type Face struct {
 	// Ranges map runes to sub-images of Mask. The rune ranges must not
 	// overlap, and must be in increasing rune order.
 	Ranges []Range
+
+	// Subpixel, if its Layout is not font.SubpixelNone, selects subpixel
+	// antialiasing: the Glyph method returns a *font.SubpixelMask of the
+	// glyph's pixels, filtered across their subpixels, one pixel wider (or
+	// higher, for a vertical layout) on either side of the glyph. For
+	// example, to draw Face7x13 with subpixel antialiasing:
+	//
+	//	f := *basicfont.Face7x13
+	//	f.Subpixel.Layout = font.SubpixelRGB
+	Subpixel font.Subpixel
 }
 
 func (f *Face) Close() error                   { return nil }
@@ -117,9 +129,46 @@ loop:
 		},
 	}
 
+	if f.Subpixel.Layout != font.SubpixelNone {
+		dr, mask = subpixelGlyph(f.Subpixel, f.Mask, dr, maskp)
+		return dr, mask, image.Point{}, fixed.I(f.Advance), true
+	}
 	return dr, f.Mask, maskp, fixed.I(f.Advance), true
 }
 
+// subpixelGlyph returns the subpixel mask for s, and its dst-space rectangle,
+// of the glyph whose dst-space rectangle is dr and whose pixels are those of
+// mask from maskp. Every subpixel of a pixel has that pixel's coverage, before
+// filtering.
+func subpixelGlyph(s font.Subpixel, mask image.Image, dr image.Rectangle, maskp image.Point) (image.Rectangle, *font.SubpixelMask) {
+	// The filter spreads the coverage of the glyph's outermost subpixels
+	// into the neighboring pixels.
+	sx, sy := 3, 1
+	pad := image.Point{1, 0}
+	if s.Layout.Vertical() {
+		sx, sy = 1, 3
+		pad = image.Point{0, 1}
+	}
+	w, h := dr.Dx(), dr.Dy()
+	a := image.NewAlpha(image.Rect(0, 0, (w+2*pad.X)*sx, (h+2*pad.Y)*sy))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, c := mask.At(maskp.X+x, maskp.Y+y).RGBA()
+			if c == 0 {
+				continue
+			}
+			for j := 0; j < sy; j++ {
+				for i := 0; i < sx; i++ {
+					a.Pix[a.PixOffset((x+pad.X)*sx+i, (y+pad.Y)*sy+j)] = uint8(c >> 8)
+				}
+			}
+		}
+	}
+	m := &font.SubpixelMask{}
+	m.SetFromAlpha(a, s)
+	return image.Rectangle{Min: dr.Min.Sub(pad), Max: dr.Max.Add(pad)}, m
+}
+
 func (f *Face) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
 	return fixed.R(0, -f.Ascent, f.Width, +f.Descent), fixed.I(f.Advance), true
 }
@@ -127,3 +176,25 @@ func (f *Face) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.In
 func (f *Face) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
 	return fixed.I(f.Advance), true
 }
+
+// HasGlyph satisfies the font.CoverageFace interface. It returns false for
+// runes that are drawn as the U+FFFD replacement character instead.
+func (f *Face) HasGlyph(r rune) bool {
+	for _, rng := range f.Ranges {
+		if rng.Low <= r && r < rng.High {
+			return true
+		}
+	}
+	return false
+}
+
+// Coverage satisfies the font.CoverageFace interface.
+func (f *Face) Coverage() *unicode.RangeTable {
+	var runes []rune
+	for _, rng := range f.Ranges {
+		for r := rng.Low; r < rng.High; r++ {
+			runes = append(runes, r)
+		}
+	}
+	return rangetable.New(runes...)
+}
@@ -7,8 +7,10 @@ package basicfont
 import (
 	"image"
 	"testing"
+	"unicode"
 
 	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
 )
 
 func TestMetrics(t *testing.T) {
@@ -17,3 +19,142 @@ func TestMetrics(t *testing.T) {
 		t.Errorf("Face7x13: Metrics: got %v want %v", got, want)
 	}
 }
+
+func TestBitmapFace(t *testing.T) {
+	// A 2-glyph sprite sheet: 'a' is a 3x4 glyph at (0, 0) and the
+	// replacement glyph is a 2x6 glyph at (3, 0).
+	sheet := image.NewAlpha(image.Rect(0, 0, 5, 6))
+	for i := range sheet.Pix {
+		sheet.Pix[i] = 0xff
+	}
+	f := NewBitmapFace(map[rune]Glyph{
+		'a':      {Mask: sheet.SubImage(image.Rect(0, 0, 3, 4)), Offset: image.Pt(0, -4), Advance: 4},
+		' ':      {Advance: 2},
+		'\ufffd': {Mask: sheet.SubImage(image.Rect(3, 0, 5, 6)), Offset: image.Pt(1, -5), Advance: 3},
+	}, &BitmapFaceOptions{
+		Kern: map[KernPair]int{{'a', 'a'}: -1},
+	})
+
+	want := font.Metrics{Height: fixed.I(6), Ascent: fixed.I(5), Descent: fixed.I(1), XHeight: fixed.I(5), CapHeight: fixed.I(5), CaretSlope: image.Point{X: 0, Y: 1}}
+	if got := f.Metrics(); got != want {
+		t.Errorf("Metrics: got %v want %v", got, want)
+	}
+
+	dr, mask, maskp, advance, ok := f.Glyph(fixed.P(10, 20), 'a')
+	if !ok || dr != image.Rect(10, 16, 13, 20) || maskp != image.Pt(0, 0) || advance != fixed.I(4) || mask == nil {
+		t.Errorf("Glyph('a'): got %v, %v, %v, %v", dr, maskp, advance, ok)
+	}
+	dr, _, maskp, advance, ok = f.Glyph(fixed.P(10, 20), 'z')
+	if !ok || dr != image.Rect(11, 15, 13, 21) || maskp != image.Pt(3, 0) || advance != fixed.I(3) {
+		t.Errorf("Glyph('z'): got %v, %v, %v, %v", dr, maskp, advance, ok)
+	}
+	dr, _, _, advance, ok = f.Glyph(fixed.P(10, 20), ' ')
+	if !ok || !dr.Empty() || advance != fixed.I(2) {
+		t.Errorf("Glyph(' '): got %v, %v, %v", dr, advance, ok)
+	}
+
+	if got, want := f.Kern('a', 'a'), -fixed.I(1); got != want {
+		t.Errorf("Kern: got %v want %v", got, want)
+	}
+	if got, want := font.MeasureString(f, "aa a"), fixed.I(4+4-1+2+4); got != want {
+		t.Errorf("MeasureString: got %v want %v", got, want)
+	}
+
+	dst := image.NewAlpha(image.Rect(0, 0, 20, 10))
+	d := font.Drawer{Dst: dst, Src: image.Opaque, Face: f, Dot: fixed.P(1, 8)}
+	d.DrawString("a")
+	if got := dst.AlphaAt(1, 4).A; got != 0xff {
+		t.Errorf("drawn pixel: got %#02x, want 0xff", got)
+	}
+	if got := dst.AlphaAt(1, 3).A; got != 0x00 {
+		t.Errorf("undrawn pixel: got %#02x, want 0x00", got)
+	}
+
+	noFallback := NewBitmapFace(map[rune]Glyph{'a': {Advance: 1}}, nil)
+	if _, ok := noFallback.GlyphAdvance('b'); ok {
+		t.Errorf("GlyphAdvance('b'): got ok, want !ok")
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	bf := NewBitmapFace(map[rune]Glyph{
+		'a': {Advance: 4},
+		'b': {Advance: 4},
+	}, nil)
+	for _, f := range []font.CoverageFace{Face7x13, bf} {
+		cov := f.Coverage()
+		for _, tc := range []struct {
+			r    rune
+			want bool
+		}{
+			{'a', true},
+			{'~', f == Face7x13},
+			{'é', false},
+		} {
+			if got := f.HasGlyph(tc.r); got != tc.want {
+				t.Errorf("%T: HasGlyph(%q): got %t, want %t", f, tc.r, got, tc.want)
+			}
+			if got := unicode.Is(cov, tc.r); got != tc.want {
+				t.Errorf("%T: Coverage contains %q: got %t, want %t", f, tc.r, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestSubpixel(t *testing.T) {
+	sheet := image.NewAlpha(image.Rect(0, 0, 2, 3))
+	for i := range sheet.Pix {
+		sheet.Pix[i] = uint8(0x30 * (i + 1))
+	}
+	face := *Face7x13
+	face.Subpixel = font.Subpixel{Layout: font.SubpixelRGB, Filter: &font.LCDFilterNone}
+	faces := []struct {
+		desc string
+		face font.Face
+		// pad is how far the subpixel mask extends beyond the glyph.
+		pad image.Point
+	}{
+		{"Face", &face, image.Pt(1, 0)},
+		{"BitmapFace", NewBitmapFace(map[rune]Glyph{
+			'a': {Mask: sheet, Offset: image.Pt(0, -3), Advance: 3},
+		}, &BitmapFaceOptions{
+			Subpixel: font.Subpixel{Layout: font.SubpixelVBGR, Filter: &font.LCDFilterNone},
+		}), image.Pt(0, 1)},
+	}
+	var plain [2]font.Face
+	plain[0] = Face7x13
+	plain[1] = NewBitmapFace(map[rune]Glyph{
+		'a': {Mask: sheet, Offset: image.Pt(0, -3), Advance: 3},
+	}, nil)
+	dot := fixed.P(10, 20)
+	for i, tc := range faces {
+		wantDr, wantMask, wantMaskp, _, _ := plain[i].Glyph(dot, 'a')
+		dr, mask, maskp, _, ok := tc.face.Glyph(dot, 'a')
+		if !ok {
+			t.Errorf("%s: Glyph: got !ok", tc.desc)
+			continue
+		}
+		if want := (image.Rectangle{wantDr.Min.Sub(tc.pad), wantDr.Max.Add(tc.pad)}); dr != want {
+			t.Errorf("%s: dr: got %v, want %v", tc.desc, dr, want)
+			continue
+		}
+		if _, ok := mask.(*font.SubpixelMask); !ok {
+			t.Errorf("%s: mask: got %T, want *font.SubpixelMask", tc.desc, mask)
+			continue
+		}
+		// Without a filter, every subpixel of a pixel has the pixel's
+		// coverage, and the padding is empty.
+		for y := dr.Min.Y; y < dr.Max.Y; y++ {
+			for x := dr.Min.X; x < dr.Max.X; x++ {
+				_, _, _, want := wantMask.At(wantMaskp.X+x-wantDr.Min.X, wantMaskp.Y+y-wantDr.Min.Y).RGBA()
+				if !(image.Point{x, y}).In(wantDr) {
+					want = 0
+				}
+				_, _, _, got := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y).RGBA()
+				if got>>8 != want>>8 {
+					t.Errorf("%s: (%d, %d): got %#02x, want %#02x", tc.desc, x, y, got>>8, want>>8)
+				}
+			}
+		}
+	}
+}
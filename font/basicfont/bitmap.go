@@ -0,0 +1,222 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package basicfont
+
+import (
+	"image"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/rangetable"
+)
+
+// Glyph is a single glyph of a BitmapFace.
+type Glyph struct {
+	// Mask is the glyph's mask image. Only the alpha channel is used. The
+	// glyph is the whole of Mask.Bounds(), so a sub-image of a larger sprite
+	// sheet, such as returned by an *image.RGBA's SubImage method, can be used
+	// directly, without copying.
+	//
+	// A nil Mask is valid, for glyphs such as a space that have an advance but
+	// no visible pixels.
+	Mask image.Image
+	// Offset is the position of the top-left corner of the glyph's mask
+	// relative to the dot, in pixels. For a glyph that sits on the baseline,
+	// Offset.Y is typically the negative of the glyph's height.
+	Offset image.Point
+	// Advance is the glyph advance, in pixels.
+	Advance int
+}
+
+// KernPair is a pair of runes, in drawing order, that have a kerning
+// adjustment.
+type KernPair struct {
+	R0, R1 rune
+}
+
+// BitmapFaceOptions are optional parameters to NewBitmapFace.
+//
+// A nil *BitmapFaceOptions means to use the default (zero) values of each
+// field.
+type BitmapFaceOptions struct {
+	// Height, Ascent and Descent are the face's metrics, in pixels. If Ascent
+	// and Descent are both zero, they are derived from the glyphs' extents. If
+	// Height is zero, it is Ascent plus Descent.
+	Height  int
+	Ascent  int
+	Descent int
+
+	// Kern holds kerning adjustments, in pixels. A positive value means to
+	// move the glyphs further apart.
+	Kern map[KernPair]int
+
+	// Fallback is the rune whose glyph is used for runes that have no glyph
+	// of their own. If zero, it is the Unicode replacement character U+FFFD.
+	// The fallback is not used if the face has no glyph for it either.
+	Fallback rune
+
+	// Subpixel, if its Layout is not font.SubpixelNone, selects subpixel
+	// antialiasing, as for a Face's Subpixel field.
+	Subpixel font.Subpixel
+}
+
+// BitmapFace is a font face whose glyphs are arbitrary images supplied at run
+// time, such as the sprites of a game's bitmap font, instead of being decoded
+// from a font file. Unlike a Face, its glyphs may have differing sizes and
+// advances.
+//
+// It is safe to use concurrently.
+type BitmapFace struct {
+	glyphs   map[rune]Glyph
+	kern     map[KernPair]fixed.Int26_6
+	fallback rune
+	subpixel font.Subpixel
+	metrics  font.Metrics
+}
+
+// NewBitmapFace returns a face whose glyphs are given by the glyphs map.
+//
+// The map (and opts.Kern) are copied, so later modifications to them do not
+// affect the face. The glyphs' mask images are not copied, and should not be
+// modified while the face is in use.
+func NewBitmapFace(glyphs map[rune]Glyph, opts *BitmapFaceOptions) *BitmapFace {
+	var o BitmapFaceOptions
+	if opts != nil {
+		o = *opts
+	}
+	f := &BitmapFace{
+		glyphs:   make(map[rune]Glyph, len(glyphs)),
+		fallback: o.Fallback,
+		subpixel: o.Subpixel,
+	}
+	if f.fallback == 0 {
+		f.fallback = '\ufffd'
+	}
+
+	maxAscent, maxDescent := 0, 0
+	for r, g := range glyphs {
+		f.glyphs[r] = g
+		if g.Mask == nil {
+			continue
+		}
+		if a := -g.Offset.Y; maxAscent < a {
+			maxAscent = a
+		}
+		if d := g.Offset.Y + g.Mask.Bounds().Dy(); maxDescent < d {
+			maxDescent = d
+		}
+	}
+
+	if len(o.Kern) != 0 {
+		f.kern = make(map[KernPair]fixed.Int26_6, len(o.Kern))
+		for k, v := range o.Kern {
+			f.kern[k] = fixed.I(v)
+		}
+	}
+
+	if o.Ascent == 0 && o.Descent == 0 {
+		o.Ascent, o.Descent = maxAscent, maxDescent
+	}
+	if o.Height == 0 {
+		o.Height = o.Ascent + o.Descent
+	}
+	f.metrics = font.Metrics{
+		Height:     fixed.I(o.Height),
+		Ascent:     fixed.I(o.Ascent),
+		Descent:    fixed.I(o.Descent),
+		XHeight:    fixed.I(o.Ascent),
+		CapHeight:  fixed.I(o.Ascent),
+		CaretSlope: image.Point{X: 0, Y: 1},
+	}
+	if g, ok := f.glyphs['x']; ok && g.Mask != nil {
+		f.metrics.XHeight = fixed.I(-g.Offset.Y)
+	}
+	if g, ok := f.glyphs['H']; ok && g.Mask != nil {
+		f.metrics.CapHeight = fixed.I(-g.Offset.Y)
+	}
+	return f
+}
+
+// lookup returns r's glyph, or the fallback glyph if r has no glyph.
+func (f *BitmapFace) lookup(r rune) (Glyph, bool) {
+	if g, ok := f.glyphs[r]; ok {
+		return g, true
+	}
+	g, ok := f.glyphs[f.fallback]
+	return g, ok
+}
+
+// emptyMask is the mask returned for glyphs with a nil Mask.
+var emptyMask = &image.Alpha{}
+
+func (f *BitmapFace) Close() error { return nil }
+
+func (f *BitmapFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	return f.kern[KernPair{r0, r1}]
+}
+
+func (f *BitmapFace) Metrics() font.Metrics { return f.metrics }
+
+func (f *BitmapFace) Glyph(dot fixed.Point26_6, r rune) (
+	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+
+	g, ok := f.lookup(r)
+	if !ok {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	if g.Mask == nil {
+		return image.Rectangle{}, emptyMask, image.Point{}, fixed.I(g.Advance), true
+	}
+
+	b := g.Mask.Bounds()
+	x := int(dot.X+32)>>6 + g.Offset.X
+	y := int(dot.Y+32)>>6 + g.Offset.Y
+	dr = image.Rectangle{
+		Min: image.Point{X: x, Y: y},
+		Max: image.Point{X: x + b.Dx(), Y: y + b.Dy()},
+	}
+	if f.subpixel.Layout != font.SubpixelNone {
+		dr, mask = subpixelGlyph(f.subpixel, g.Mask, dr, b.Min)
+		return dr, mask, image.Point{}, fixed.I(g.Advance), true
+	}
+	return dr, g.Mask, b.Min, fixed.I(g.Advance), true
+}
+
+func (f *BitmapFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	g, ok := f.lookup(r)
+	if !ok {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	if g.Mask == nil {
+		return fixed.Rectangle26_6{}, fixed.I(g.Advance), true
+	}
+	b := g.Mask.Bounds()
+	return fixed.R(g.Offset.X, g.Offset.Y, g.Offset.X+b.Dx(), g.Offset.Y+b.Dy()), fixed.I(g.Advance), true
+}
+
+func (f *BitmapFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	g, ok := f.lookup(r)
+	if !ok {
+		return 0, false
+	}
+	return fixed.I(g.Advance), true
+}
+
+// HasGlyph satisfies the font.CoverageFace interface. It returns false for
+// runes that are drawn as the fallback glyph instead.
+func (f *BitmapFace) HasGlyph(r rune) bool {
+	_, ok := f.glyphs[r]
+	return ok
+}
+
+// Coverage satisfies the font.CoverageFace interface.
+func (f *BitmapFace) Coverage() *unicode.RangeTable {
+	runes := make([]rune, 0, len(f.glyphs))
+	for r := range f.glyphs {
+		runes = append(runes, r)
+	}
+	return rangetable.New(runes...)
+}
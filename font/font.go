@@ -11,6 +11,7 @@ package font // import "golang.org/x/image/font"
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
 	"io"
 	"unicode/utf8"
@@ -27,7 +28,11 @@ import (
 // different faces, even if derived from the same font file.
 //
 // A Face is not safe for concurrent use by multiple goroutines, as its methods
-// may re-use implementation-specific caches and mask image buffers.
+// may re-use implementation-specific caches and mask image buffers. In
+// particular, the mask image returned by one Glyph call may be overwritten by
+// the next Glyph call. Implementations that are safe for concurrent use say so
+// in their documentation. Otherwise, use NewSafeFace to share a face between
+// goroutines, or give each goroutine its own Face (and Drawer).
 //
 // To create a Face, look to other packages that implement specific font file
 // formats.
@@ -70,10 +75,31 @@ type Face interface {
 	// Metrics returns the metrics for this Face.
 	Metrics() Metrics
 
-	// TODO: ColoredGlyph for various emoji?
 	// TODO: Ligatures? Shaping?
 }
 
+// ColorFace is a Face that can also provide color glyph images, such as for
+// emoji. A Drawer draws a ColorFace's color glyphs in their own colors, and
+// its other glyphs as alpha masks, like for any other Face.
+type ColorFace interface {
+	Face
+
+	// ColorGlyph returns the draw.Draw parameters (dr, src, sp) to draw r's
+	// color glyph at the sub-pixel destination location dot, and that
+	// glyph's advance width. The src image is alpha-premultiplied, and should
+	// be composited with draw.Over. Parts of the glyph that are drawn in the
+	// text's color, instead of a color of their own, are painted with fg.
+	//
+	// It returns !ok if the face does not contain a color glyph for r, in
+	// which case the caller should fall back to Glyph.
+	//
+	// Like the mask image returned by Glyph, the contents of the src image
+	// returned by one ColorGlyph call may change after the next Glyph or
+	// ColorGlyph call.
+	ColorGlyph(dot fixed.Point26_6, r rune, fg color.Color) (
+		dr image.Rectangle, src image.Image, sp image.Point, advance fixed.Int26_6, ok bool)
+}
+
 // Metrics holds the metrics for a Face. A visual depiction is at
 // https://developer.apple.com/library/mac/documentation/TextFonts/Conceptual/CocoaTextArchitecture/Art/glyph_metrics_2x.png
 type Metrics struct {
@@ -111,7 +137,9 @@ type Drawer struct {
 	Dst draw.Image
 	// Src is the source image.
 	Src image.Image
-	// Face provides the glyph mask images.
+	// Face provides the glyph mask images. If it is a ColorFace, its color
+	// glyphs are drawn in their own colors, and the parts of those glyphs
+	// that are in the text's color are painted with Src's color at the dot.
 	Face Face
 	// Dot is the baseline location to draw the next glyph. The majority of the
 	// affected pixels will be above and to the right of the dot, but some may
@@ -119,6 +147,29 @@ type Drawer struct {
 	// may affect pixels below and to the left of the dot.
 	Dot fixed.Point26_6
 
+	// LetterSpacing is added to the advance of every glyph, after any
+	// kerning, to spread out the text, or to tighten it if negative. It is
+	// also known as tracking.
+	LetterSpacing fixed.Int26_6
+	// DisableKerning is whether to ignore the Face's kerning between
+	// glyphs, such as for monospaced text.
+	DisableKerning bool
+	// GlyphFunc, if non-nil, is called for every glyph that DrawBytes and
+	// DrawString draw, before it is drawn, with the glyph's rune, its dot and
+	// its advance, not including kerning or LetterSpacing. If it returns
+	// false, the glyph is not drawn but the dot still advances past it, so
+	// that GlyphFunc can draw the glyph itself, such as with an outline, or
+	// only record where it is, such as for hit-testing. GlyphFunc may draw
+	// other things on Dst, such as the glyph's shadow, but must not change
+	// the Drawer.
+	GlyphFunc func(c rune, dot fixed.Point26_6, advance fixed.Int26_6) (draw bool)
+	// Subpixel is whether to draw the glyphs whose masks are a
+	// *SubpixelMask, from a face with subpixel antialiasing, with each color
+	// masked separately. It should only be set for opaque Dst images that
+	// are shown on an LCD panel whose subpixel layout is the face's.
+	// Otherwise, such masks give grayscale antialiasing.
+	Subpixel bool
+
 	// TODO: Clip image.Image?
 	// TODO: SrcP image.Point for Src images other than *image.Uniform? How
 	// does it get updated during DrawString?
@@ -147,18 +198,17 @@ func (d *Drawer) DrawBytes(s []byte) {
 	for len(s) > 0 {
 		c, size := utf8.DecodeRune(s)
 		s = s[size:]
-		if prevC >= 0 {
+		if prevC >= 0 && !d.DisableKerning {
 			d.Dot.X += d.Face.Kern(prevC, c)
 		}
-		dr, mask, maskp, advance, ok := d.Face.Glyph(d.Dot, c)
+		advance, ok := d.drawGlyph(c)
 		if !ok {
 			// TODO: is falling back on the U+FFFD glyph the responsibility of
 			// the Drawer or the Face?
 			// TODO: set prevC = '\ufffd'?
 			continue
 		}
-		draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
-		d.Dot.X += advance
+		d.Dot.X += advance + d.LetterSpacing
 		prevC = c
 	}
 }
@@ -167,28 +217,58 @@ func (d *Drawer) DrawBytes(s []byte) {
 func (d *Drawer) DrawString(s string) {
 	prevC := rune(-1)
 	for _, c := range s {
-		if prevC >= 0 {
+		if prevC >= 0 && !d.DisableKerning {
 			d.Dot.X += d.Face.Kern(prevC, c)
 		}
-		dr, mask, maskp, advance, ok := d.Face.Glyph(d.Dot, c)
+		advance, ok := d.drawGlyph(c)
 		if !ok {
 			// TODO: is falling back on the U+FFFD glyph the responsibility of
 			// the Drawer or the Face?
 			// TODO: set prevC = '\ufffd'?
 			continue
 		}
-		draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
-		d.Dot.X += advance
+		d.Dot.X += advance + d.LetterSpacing
 		prevC = c
 	}
 }
 
+// drawGlyph draws c's glyph at the dot, without advancing the dot.
+func (d *Drawer) drawGlyph(c rune) (advance fixed.Int26_6, ok bool) {
+	if d.GlyphFunc != nil {
+		advance, ok := d.Face.GlyphAdvance(c)
+		if !ok {
+			return 0, false
+		}
+		if !d.GlyphFunc(c, d.Dot, advance) {
+			return advance, true
+		}
+	}
+	if cf, isColor := d.Face.(ColorFace); isColor {
+		fg := d.Src.At(d.Dot.X.Floor(), d.Dot.Y.Floor())
+		dr, src, sp, advance, ok := cf.ColorGlyph(d.Dot, c, fg)
+		if ok {
+			draw.Draw(d.Dst, dr, src, sp, draw.Over)
+			return advance, true
+		}
+	}
+	dr, mask, maskp, advance, ok := d.Face.Glyph(d.Dot, c)
+	if !ok {
+		return 0, false
+	}
+	if sm, isSubpixel := mask.(*SubpixelMask); isSubpixel && d.Subpixel {
+		drawSubpixel(d.Dst, dr, d.Src, image.Point{}, sm, maskp)
+		return advance, true
+	}
+	draw.DrawMask(d.Dst, dr, d.Src, image.Point{}, mask, maskp, draw.Over)
+	return advance, true
+}
+
 // BoundBytes returns the bounding box of s, drawn at the drawer dot, as well as
 // the advance.
 //
 // It is equivalent to BoundBytes(string(s)) but may be more efficient.
 func (d *Drawer) BoundBytes(s []byte) (bounds fixed.Rectangle26_6, advance fixed.Int26_6) {
-	bounds, advance = BoundBytes(d.Face, s)
+	bounds, advance = BoundBytes(d.face(), s)
 	bounds.Min = bounds.Min.Add(d.Dot)
 	bounds.Max = bounds.Max.Add(d.Dot)
 	return
@@ -197,7 +277,7 @@ func (d *Drawer) BoundBytes(s []byte) (bounds fixed.Rectangle26_6, advance fixed
 // BoundString returns the bounding box of s, drawn at the drawer dot, as well
 // as the advance.
 func (d *Drawer) BoundString(s string) (bounds fixed.Rectangle26_6, advance fixed.Int26_6) {
-	bounds, advance = BoundString(d.Face, s)
+	bounds, advance = BoundString(d.face(), s)
 	bounds.Min = bounds.Min.Add(d.Dot)
 	bounds.Max = bounds.Max.Add(d.Dot)
 	return
@@ -207,12 +287,46 @@ func (d *Drawer) BoundString(s string) (bounds fixed.Rectangle26_6, advance fixe
 //
 // It is equivalent to MeasureString(string(s)) but may be more efficient.
 func (d *Drawer) MeasureBytes(s []byte) (advance fixed.Int26_6) {
-	return MeasureBytes(d.Face, s)
+	return MeasureBytes(d.face(), s)
 }
 
 // MeasureString returns how far dot would advance by drawing s.
 func (d *Drawer) MeasureString(s string) (advance fixed.Int26_6) {
-	return MeasureString(d.Face, s)
+	return MeasureString(d.face(), s)
+}
+
+// face returns d.Face, adjusted for d's LetterSpacing and DisableKerning, for
+// measuring text as d draws it.
+func (d *Drawer) face() Face {
+	if d.LetterSpacing == 0 && !d.DisableKerning {
+		return d.Face
+	}
+	return spacedFace{d.Face, d.LetterSpacing, d.DisableKerning}
+}
+
+// spacedFace is a Face with extra spacing after every glyph and, if noKern,
+// no kerning.
+type spacedFace struct {
+	Face
+	spacing fixed.Int26_6
+	noKern  bool
+}
+
+func (f spacedFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	bounds, advance, ok = f.Face.GlyphBounds(r)
+	return bounds, advance + f.spacing, ok
+}
+
+func (f spacedFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	advance, ok = f.Face.GlyphAdvance(r)
+	return advance + f.spacing, ok
+}
+
+func (f spacedFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	if f.noKern {
+		return 0
+	}
+	return f.Face.Kern(r0, r1)
 }
 
 // BoundBytes returns the bounding box of s with f, drawn at a dot equal to the
@@ -6,6 +6,7 @@ package font
 
 import (
 	"image"
+	"image/color"
 	"strings"
 	"testing"
 
@@ -63,3 +64,124 @@ func TestBound(t *testing.T) {
 		}
 	}
 }
+
+// colorToyFace is a ColorFace whose glyphs are 2×2 pixels. The glyph for 'c'
+// is a color glyph whose left column is red and whose right column is in the
+// foreground color.
+type colorToyFace struct {
+	toyFace
+}
+
+func (colorToyFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	x, y := dot.X.Floor(), dot.Y.Floor()
+	return image.Rect(x, y, x+2, y+2), image.Opaque, image.Point{}, toyAdvance, true
+}
+
+func (colorToyFace) ColorGlyph(dot fixed.Point26_6, r rune, fg color.Color) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	if r != 'c' {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	m := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for y := 0; y < 2; y++ {
+		m.Set(0, y, color.RGBA{0xff, 0x00, 0x00, 0xff})
+		m.Set(1, y, fg)
+	}
+	x, y := dot.X.Floor(), dot.Y.Floor()
+	return image.Rect(x, y, x+2, y+2), m, image.Point{}, toyAdvance, true
+}
+
+func TestDrawColorGlyph(t *testing.T) {
+	blue := color.RGBA{0x00, 0x00, 0xff, 0xff}
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	dst := image.NewRGBA(image.Rect(0, 0, 20, 2))
+	d := &Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(blue),
+		Face: colorToyFace{},
+	}
+	d.DrawString("ac")
+	if got, want := d.Dot.X, 2*toyAdvance; got != want {
+		t.Errorf("dot: got %v, want %v", got, want)
+	}
+	testCases := []struct {
+		x    int
+		want color.RGBA
+	}{
+		{0, blue},
+		{1, blue},
+		{2, color.RGBA{}},
+		{10, red},
+		{11, blue},
+		{12, color.RGBA{}},
+	}
+	for _, tc := range testCases {
+		if got := dst.RGBAAt(tc.x, 1); got != tc.want {
+			t.Errorf("x=%d: got %v, want %v", tc.x, got, tc.want)
+		}
+	}
+}
+
+// kernToyFace is a colorToyFace whose glyphs are kerned 2 pixels closer
+// together.
+type kernToyFace struct {
+	colorToyFace
+}
+
+func (kernToyFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	return fixed.I(-2)
+}
+
+func TestDrawerSpacing(t *testing.T) {
+	testCases := []struct {
+		disableKerning bool
+		wantDots       []int
+		wantAdvance    int
+	}{
+		{false, []int{0, 11, 22}, 35},
+		{true, []int{0, 13, 26}, 39},
+	}
+	for _, tc := range testCases {
+		dst := image.NewRGBA(image.Rect(0, 0, 40, 2))
+		var dots []int
+		d := &Drawer{
+			Dst:            dst,
+			Src:            image.White,
+			Face:           kernToyFace{},
+			LetterSpacing:  fixed.I(3),
+			DisableKerning: tc.disableKerning,
+			GlyphFunc: func(c rune, dot fixed.Point26_6, advance fixed.Int26_6) bool {
+				if advance != toyAdvance {
+					t.Errorf("disableKerning=%t: %q: advance: got %v, want %v", tc.disableKerning, c, advance, toyAdvance)
+				}
+				dots = append(dots, dot.X.Round())
+				return c != 'b'
+			},
+		}
+		if got := d.MeasureString("abx"); got != fixed.I(tc.wantAdvance) {
+			t.Errorf("disableKerning=%t: MeasureString: got %v, want %v", tc.disableKerning, got, fixed.I(tc.wantAdvance))
+		}
+		bounds, _ := d.BoundString("abx")
+		if got, want := bounds.Max.X, fixed.I(tc.wantDots[2]+6); got != want {
+			t.Errorf("disableKerning=%t: BoundString: got max x %v, want %v", tc.disableKerning, got, want)
+		}
+
+		d.DrawString("abx")
+		if got := d.Dot.X; got != fixed.I(tc.wantAdvance) {
+			t.Errorf("disableKerning=%t: dot: got %v, want %v", tc.disableKerning, got, fixed.I(tc.wantAdvance))
+		}
+		if len(dots) != len(tc.wantDots) {
+			t.Errorf("disableKerning=%t: GlyphFunc dots: got %v, want %v", tc.disableKerning, dots, tc.wantDots)
+			continue
+		}
+		for i, x := range tc.wantDots {
+			if dots[i] != x {
+				t.Errorf("disableKerning=%t: GlyphFunc dots: got %v, want %v", tc.disableKerning, dots, tc.wantDots)
+				break
+			}
+			// GlyphFunc returned false for the 'b', which is not drawn.
+			if got, want := dst.RGBAAt(x, 1).A != 0, i != 1; got != want {
+				t.Errorf("disableKerning=%t: glyph %d drawn: got %t, want %t", tc.disableKerning, i, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// cluster is the visual extent of a single rune of a line.
+type cluster struct {
+	// offset and size are the rune's byte offset into the line's text and
+	// its length in bytes.
+	offset, size int
+	// x0 and x1 are the rune's left and right edges, relative to the line's
+	// left edge.
+	x0, x1 fixed.Int26_6
+	// rtl is whether the rune's run is right-to-left.
+	rtl bool
+}
+
+// clusters returns the runes of the line made up of runs, in logical order,
+// and the line's metrics.
+//
+// Each rune extends from the dot before it to the dot before the next rune,
+// so that any kerning between two runes is split at the second rune's dot,
+// and consecutive runes of a run touch. Runes that the face has no glyph for
+// are empty.
+func clusters(runs []Run) ([]cluster, Line) {
+	l := MeasureLine(runs)
+	var cs []cluster
+	offset := 0
+	for i := range runs {
+		r, m := &runs[i], &l.Runs[i]
+		first := len(cs)
+		pen, prevC := fixed.Int26_6(0), rune(-1)
+		for j, c := range r.Text {
+			if prevC >= 0 {
+				pen += r.Face.Kern(prevC, c)
+			}
+			cs = append(cs, cluster{
+				offset: offset + j,
+				size:   utf8.RuneLen(c),
+				x0:     pen,
+				rtl:    r.rightToLeft(),
+			})
+			if a, ok := r.Face.GlyphAdvance(c); ok {
+				pen += a
+				prevC = c
+			}
+		}
+		// Set each rune's end to the next rune's start, then convert from
+		// the run's logical (pen) space to the line's visual space.
+		for k := first; k < len(cs); k++ {
+			if k+1 < len(cs) {
+				cs[k].x1 = cs[k+1].x0
+			} else {
+				cs[k].x1 = m.Advance
+			}
+			if cs[k].x1 < cs[k].x0 {
+				cs[k].x1 = cs[k].x0
+			}
+		}
+		for k := first; k < len(cs); k++ {
+			c := &cs[k]
+			if c.rtl {
+				c.x0, c.x1 = m.X+m.Advance-c.x1, m.X+m.Advance-c.x0
+			} else {
+				c.x0, c.x1 = m.X+c.x0, m.X+c.x1
+			}
+		}
+		offset += len(r.Text)
+	}
+	return cs, l
+}
+
+// Caret returns the caret rectangle for the byte offset i into the text of
+// the line made up of runs, as laid out by MeasureLine. Offsets are into the
+// concatenation of the runs' Text, in logical order. The rectangle is
+// relative to the dot at the start of the line's baseline, so its Min.Y and
+// Max.Y are the line's -Ascent and +Descent. It has zero width: callers
+// typically draw it one pixel wide, or slanted by the face's CaretSlope.
+//
+// The caret is at the leading edge of the rune that starts at or, for an
+// offset within a rune, contains i: its left edge in a left-to-right run and
+// its right edge in a right-to-left run. At the end of the text, the caret
+// is at the trailing edge of the last rune. Where runs of different
+// directions meet, this places the caret next to the rune that would be
+// inserted before, or deleted forwards.
+//
+// A font.Face maps each rune to its own glyph, without shaping, so there are
+// no ligatures, and carets are only placed between glyphs. Placing carets
+// within ligatures, such as from an OpenType font's GDEF ligature caret list,
+// needs a shaping layout engine.
+func Caret(runs []Run, i int) fixed.Rectangle26_6 {
+	cs, l := clusters(runs)
+	x := fixed.Int26_6(0)
+	if k := clusterAt(cs, i); k < len(cs) {
+		x = cs[k].x0
+		if cs[k].rtl {
+			x = cs[k].x1
+		}
+	} else if k > 0 {
+		x = cs[k-1].x1
+		if cs[k-1].rtl {
+			x = cs[k-1].x0
+		}
+	}
+	return fixed.Rectangle26_6{
+		Min: fixed.Point26_6{X: x, Y: -l.Ascent},
+		Max: fixed.Point26_6{X: x, Y: +l.Descent},
+	}
+}
+
+// Selection returns the rectangles that highlight the text from byte offset
+// start to byte offset end (exclusive) of the line made up of runs. Like
+// Caret's rectangle, they are relative to the dot at the start of the line's
+// baseline and span the line box vertically.
+//
+// Offsets within a rune are rounded down to that rune's start. Text that is
+// contiguous in logical order may not be contiguous visually, where runs of
+// different directions meet, so there may be more than one rectangle. They
+// are in visual order, from left to right, and do not touch.
+func Selection(runs []Run, start, end int) []fixed.Rectangle26_6 {
+	cs, l := clusters(runs)
+	lo, hi := clusterAt(cs, start), clusterAt(cs, end)
+	if lo >= hi {
+		return nil
+	}
+	sel := cs[lo:hi]
+
+	// Sort the selected runes visually, by their left edges. Insertion sort
+	// is fast for the typical, mostly sorted, input.
+	visual := make([]cluster, len(sel))
+	copy(visual, sel)
+	for a := 1; a < len(visual); a++ {
+		for b := a; b > 0 && visual[b].x0 < visual[b-1].x0; b-- {
+			visual[b], visual[b-1] = visual[b-1], visual[b]
+		}
+	}
+
+	var rects []fixed.Rectangle26_6
+	for _, c := range visual {
+		if c.x0 == c.x1 {
+			continue
+		}
+		if n := len(rects); n > 0 && rects[n-1].Max.X >= c.x0 {
+			if rects[n-1].Max.X < c.x1 {
+				rects[n-1].Max.X = c.x1
+			}
+			continue
+		}
+		rects = append(rects, fixed.Rectangle26_6{
+			Min: fixed.Point26_6{X: c.x0, Y: -l.Ascent},
+			Max: fixed.Point26_6{X: c.x1, Y: +l.Descent},
+		})
+	}
+	return rects
+}
+
+// clusterAt returns the index of the first cluster whose rune ends after the
+// byte offset i, or len(cs) if there is none.
+func clusterAt(cs []cluster, i int) int {
+	for k := range cs {
+		if i < cs[k].offset+cs[k].size {
+			return k
+		}
+	}
+	return len(cs)
+}
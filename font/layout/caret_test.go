@@ -0,0 +1,106 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// span returns the line box rectangle, for basicfont.Face7x13, from x0 to x1
+// pixels.
+func span(x0, x1 int) fixed.Rectangle26_6 {
+	return fixed.Rectangle26_6{
+		Min: fixed.Point26_6{X: fixed.I(x0), Y: -fixed.I(11)},
+		Max: fixed.Point26_6{X: fixed.I(x1), Y: +fixed.I(2)},
+	}
+}
+
+func TestCaretAndSelection(t *testing.T) {
+	face := basicfont.Face7x13
+	testCases := []struct {
+		desc string
+		runs []Run
+		// carets[i] is the caret's x, in pixels, for the byte offset i.
+		carets     []int
+		selections map[[2]int][]fixed.Rectangle26_6
+	}{{
+		desc:   "left to right",
+		runs:   []Run{{Face: face, Text: "abc"}},
+		carets: []int{0, 7, 14, 21},
+		selections: map[[2]int][]fixed.Rectangle26_6{
+			{0, 0}: nil,
+			{1, 3}: {span(7, 21)},
+			{2, 1}: nil,
+		},
+	}, {
+		// The visual order is "ab", "DC", "ef".
+		desc: "right to left within left to right",
+		runs: []Run{
+			{Face: face, Text: "ab"},
+			{Face: face, Text: "CD", Level: 1},
+			{Face: face, Text: "ef"},
+		},
+		carets: []int{0, 7, 28, 21, 28, 35, 42},
+		selections: map[[2]int][]fixed.Rectangle26_6{
+			{1, 3}: {span(7, 14), span(21, 28)},
+			{1, 5}: {span(7, 35)},
+			{2, 4}: {span(14, 28)},
+		},
+	}, {
+		// The visual order is "FE", "cd", "BA".
+		desc: "left to right within right to left",
+		runs: []Run{
+			{Face: face, Text: "AB", Level: 1},
+			{Face: face, Text: "cd", Level: 2},
+			{Face: face, Text: "EF", Level: 1},
+		},
+		carets: []int{42, 35, 14, 21, 14, 7, 0},
+		selections: map[[2]int][]fixed.Rectangle26_6{
+			{0, 6}: {span(0, 42)},
+			{1, 3}: {span(14, 21), span(28, 35)},
+		},
+	}, {
+		desc:   "multi-byte runes",
+		runs:   []Run{{Face: face, Text: "aé"}},
+		carets: []int{0, 7, 7, 14},
+		selections: map[[2]int][]fixed.Rectangle26_6{
+			{2, 3}: {span(7, 14)},
+		},
+	}}
+
+	for _, tc := range testCases {
+		for i, want := range tc.carets {
+			got := Caret(tc.runs, i)
+			if got != span(want, want) {
+				t.Errorf("%s: Caret(%d): got %v, want x=%d", tc.desc, i, got, want)
+			}
+		}
+		for r, want := range tc.selections {
+			got := Selection(tc.runs, r[0], r[1])
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Selection(%d, %d): got %v, want %v", tc.desc, r[0], r[1], got, want)
+			}
+		}
+	}
+}
+
+func TestMeasureLineBidi(t *testing.T) {
+	face := basicfont.Face7x13
+	l := MeasureLine([]Run{
+		{Face: face, Text: "AB", Level: 1},
+		{Face: face, Text: "cde", Level: 2},
+		{Face: face, Text: "F", Level: 1},
+	})
+	wantX := []fixed.Int26_6{fixed.I(28), fixed.I(7), 0}
+	for i, r := range l.Runs {
+		if r.X != wantX[i] {
+			t.Errorf("Runs[%d].X: got %v, want %v", i, r.X, wantX[i])
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// JustifyOptions are optional parameters to Justify.
+//
+// A nil *JustifyOptions means to use the default (zero) values of each field.
+type JustifyOptions struct {
+	// MaxSpaceStretch is the most that each space may be widened by, as a
+	// multiple of its advance, such as 2 for a space to be at most three
+	// times its natural width. Zero means no limit.
+	MaxSpaceStretch float64
+
+	// LetterSpacing is whether to distribute the extra width that the spaces
+	// do not take, such as for a line without spaces or beyond
+	// MaxSpaceStretch, between every pair of visually adjacent glyphs.
+	//
+	// Justifying Arabic text by kashida insertion, elongating the joins
+	// between letters instead of spacing them apart, needs a shaping layout
+	// engine and is not supported. Callers should not set LetterSpacing for
+	// lines of cursive scripts.
+	LetterSpacing bool
+}
+
+// Glyph is the position of a rune of a justified line.
+type Glyph struct {
+	// Run is the index of the rune's run.
+	Run int
+
+	// Offset is the rune's byte offset into the line's text, the
+	// concatenation of the runs' Text in logical order.
+	Offset int
+
+	// Rune is the rune.
+	Rune rune
+
+	// X is the rune's dot, where to draw its glyph, relative to the start of
+	// the line's baseline.
+	X fixed.Int26_6
+
+	// Advance is the rune's advance width, including any extra width that it
+	// was given. For right-to-left runs, the extra width is to the left of
+	// the glyph. Runes that the face has no glyph for have a zero Advance.
+	Advance fixed.Int26_6
+}
+
+// Justify positions the glyphs of the line made up of runs, as laid out by
+// MeasureLine, so that the line is width wide. It returns the glyphs, in
+// logical order, and the line's metrics, adjusted for the extra width.
+//
+// The extra width is distributed equally over the line's spaces, other than
+// any trailing spaces, which are not visible at a line break, and then, if
+// opts.LetterSpacing is set, between the glyphs. A line that is already
+// width wide or wider, or that has no room to stretch, is returned at its
+// natural width. Callers typically do not justify the last line of a
+// paragraph.
+//
+// Like MeasureLine, kerning is applied within each run but not between
+// runs.
+func Justify(runs []Run, width fixed.Int26_6, opts *JustifyOptions) ([]Glyph, Line) {
+	var o JustifyOptions
+	if opts != nil {
+		o = *opts
+	}
+	l := MeasureLine(runs)
+
+	// Collect the runes, with their natural advances, and the kerning before
+	// each rune.
+	var (
+		glyphs []Glyph
+		kerns  []fixed.Int26_6
+	)
+	offset := 0
+	for i := range runs {
+		r := &runs[i]
+		prevC := rune(-1)
+		for j, c := range r.Text {
+			k := fixed.Int26_6(0)
+			if prevC >= 0 {
+				k = r.Face.Kern(prevC, c)
+			}
+			a, ok := r.Face.GlyphAdvance(c)
+			if ok {
+				prevC = c
+			}
+			glyphs = append(glyphs, Glyph{Run: i, Offset: offset + j, Rune: c, Advance: a})
+			kerns = append(kerns, k)
+		}
+		offset += len(r.Text)
+	}
+
+	extra := width - l.Advance
+	if extra > 0 {
+		extra -= stretchSpaces(glyphs, extra, o.MaxSpaceStretch)
+	}
+	if extra > 0 && o.LetterSpacing {
+		stretchLetters(runs, glyphs, extra)
+	}
+
+	// Lay the runs out again, in visual order, with the stretched advances.
+	x, first := fixed.Int26_6(0), 0
+	firsts := make([]int, len(runs))
+	for i := range runs {
+		firsts[i] = first
+		first += utf8.RuneCountInString(runs[i].Text)
+	}
+	for _, i := range visualOrder(runs) {
+		gs, ks := glyphs[firsts[i]:firsts[i]+utf8.RuneCountInString(runs[i].Text)], kerns[firsts[i]:]
+		pen := fixed.Int26_6(0)
+		for j := range gs {
+			pen += ks[j]
+			gs[j].X = pen
+			pen += gs[j].Advance
+		}
+		if runs[i].rightToLeft() {
+			for j := range gs {
+				gs[j].X = x + pen - gs[j].X - gs[j].Advance
+			}
+		} else {
+			for j := range gs {
+				gs[j].X += x
+			}
+		}
+		l.Runs[i].X, l.Runs[i].Advance = x, pen
+		x += pen
+	}
+	l.Advance = x
+	return glyphs, l
+}
+
+// stretchSpaces widens the spaces of glyphs, other than the trailing spaces,
+// by up to extra in total, and by at most maxStretch times their advance if
+// maxStretch is positive. It returns the total width added.
+func stretchSpaces(glyphs []Glyph, extra fixed.Int26_6, maxStretch float64) fixed.Int26_6 {
+	end := len(glyphs)
+	for end > 0 && isSpace(glyphs[end-1].Rune) {
+		end--
+	}
+	var spaces []int
+	for i := range glyphs[:end] {
+		if isSpace(glyphs[i].Rune) {
+			spaces = append(spaces, i)
+		}
+	}
+	if len(spaces) == 0 {
+		return 0
+	}
+	total := fixed.Int26_6(0)
+	for n, i := range spaces {
+		s := share(extra, len(spaces), n)
+		if maxStretch > 0 {
+			if max := fixed.Int26_6(float64(glyphs[i].Advance) * maxStretch); s > max {
+				s = max
+			}
+		}
+		glyphs[i].Advance += s
+		total += s
+	}
+	return total
+}
+
+// stretchLetters widens every glyph of glyphs that has an advance, other than
+// the visually last one, so that the gaps between the glyphs grow by extra in
+// total.
+func stretchLetters(runs []Run, glyphs []Glyph, extra fixed.Int26_6) {
+	// Find the rightmost glyph, which is the last glyph with an advance of
+	// the rightmost run that has one, or its first such glyph if the run is
+	// right-to-left.
+	last, order := -1, visualOrder(runs)
+	for v := len(order) - 1; v >= 0 && last < 0; v-- {
+		i := order[v]
+		for j := range glyphs {
+			if glyphs[j].Run != i || glyphs[j].Advance == 0 {
+				continue
+			}
+			if last < 0 || !runs[i].rightToLeft() {
+				last = j
+			}
+		}
+	}
+	var gaps []int
+	for j := range glyphs {
+		if j != last && glyphs[j].Advance != 0 {
+			gaps = append(gaps, j)
+		}
+	}
+	for n, j := range gaps {
+		glyphs[j].Advance += share(extra, len(gaps), n)
+	}
+}
+
+// share returns the n'th of count shares of total, which differ by at most
+// one unit and add up to total.
+func share(total fixed.Int26_6, count, n int) fixed.Int26_6 {
+	s := total / fixed.Int26_6(count)
+	if n < int(total%fixed.Int26_6(count)) {
+		s++
+	}
+	return s
+}
+
+// isSpace returns whether c is a space that Justify may widen.
+func isSpace(c rune) bool {
+	return unicode.Is(unicode.Zs, c)
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestJustify(t *testing.T) {
+	// Every glyph of Face7x13 is 7 pixels wide.
+	face := basicfont.Face7x13
+	testCases := []struct {
+		desc  string
+		runs  []Run
+		width int
+		opts  *JustifyOptions
+		// xs and advances are the glyphs' X and Advance, in pixels.
+		xs, advances []int
+	}{{
+		desc:     "spaces",
+		runs:     []Run{{Face: face, Text: "ab cd ef"}},
+		width:    66,
+		xs:       []int{0, 7, 14, 26, 33, 40, 52, 59},
+		advances: []int{7, 7, 12, 7, 7, 12, 7, 7},
+	}, {
+		desc:     "trailing space",
+		runs:     []Run{{Face: face, Text: "ab c "}},
+		width:    43,
+		xs:       []int{0, 7, 14, 29, 36},
+		advances: []int{7, 7, 15, 7, 7},
+	}, {
+		desc:     "too wide",
+		runs:     []Run{{Face: face, Text: "a b"}},
+		width:    10,
+		xs:       []int{0, 7, 14},
+		advances: []int{7, 7, 7},
+	}, {
+		desc:     "no spaces",
+		runs:     []Run{{Face: face, Text: "abc"}},
+		width:    30,
+		xs:       []int{0, 7, 14},
+		advances: []int{7, 7, 7},
+	}, {
+		desc:     "letter spacing",
+		runs:     []Run{{Face: face, Text: "abc"}},
+		width:    29,
+		opts:     &JustifyOptions{LetterSpacing: true},
+		xs:       []int{0, 11, 22},
+		advances: []int{11, 11, 7},
+	}, {
+		desc:     "max space stretch",
+		runs:     []Run{{Face: face, Text: "a b"}},
+		width:    40,
+		opts:     &JustifyOptions{MaxSpaceStretch: 1},
+		xs:       []int{0, 7, 21},
+		advances: []int{7, 14, 7},
+	}, {
+		desc:     "max space stretch and letter spacing",
+		runs:     []Run{{Face: face, Text: "a b"}},
+		width:    40,
+		opts:     &JustifyOptions{MaxSpaceStretch: 1, LetterSpacing: true},
+		xs:       []int{0, 13, 33},
+		advances: []int{13, 20, 7},
+	}, {
+		desc:     "right to left",
+		runs:     []Run{{Face: face, Text: "ab c", Level: 1}},
+		width:    35,
+		xs:       []int{28, 21, 7, 0},
+		advances: []int{7, 7, 14, 7},
+	}, {
+		desc: "mixed directions",
+		runs: []Run{
+			{Face: face, Text: "a "},
+			{Face: face, Text: "bc", Level: 1},
+		},
+		width:    32,
+		opts:     &JustifyOptions{LetterSpacing: true},
+		xs:       []int{0, 7, 25, 18},
+		advances: []int{7, 11, 7, 7},
+	}}
+
+	for _, tc := range testCases {
+		glyphs, l := Justify(tc.runs, fixed.I(tc.width), tc.opts)
+		if len(glyphs) != len(tc.xs) {
+			t.Errorf("%s: got %d glyphs, want %d", tc.desc, len(glyphs), len(tc.xs))
+			continue
+		}
+		sum := fixed.Int26_6(0)
+		for i, g := range glyphs {
+			if g.X != fixed.I(tc.xs[i]) || g.Advance != fixed.I(tc.advances[i]) {
+				t.Errorf("%s: glyph %d (%q): got X=%v, Advance=%v, want X=%d, Advance=%d",
+					tc.desc, i, g.Rune, g.X, g.Advance, tc.xs[i], tc.advances[i])
+			}
+			sum += g.Advance
+		}
+		if l.Advance != sum {
+			t.Errorf("%s: line Advance: got %v, want %v", tc.desc, l.Advance, sum)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package layout provides text layout on top of font.Face values, such as
+// measuring lines of text that mix several faces and directions, positioning
+// the lines of paragraphs, and mapping between text offsets and caret and
+// selection geometry.
+package layout // import "golang.org/x/image/font/layout"
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Run is a sequence of text that is drawn in a single face, such as the
+// output of a font fallback chain for one script.
+type Run struct {
+	Face font.Face
+	Text string
+
+	// Level is the run's bidirectional embedding level, as computed by the
+	// Unicode Bidirectional Algorithm, such as by the
+	// golang.org/x/text/unicode/bidi package. Even levels, such as the
+	// default zero, are left-to-right and odd levels are right-to-left: the
+	// first rune of a right-to-left run is its rightmost.
+	Level uint8
+}
+
+// rightToLeft returns whether r is drawn right to left.
+func (r *Run) rightToLeft() bool { return r.Level&1 != 0 }
+
+// RunMetrics holds the metrics of a single Run within a Line.
+type RunMetrics struct {
+	// Metrics are the run's face's metrics.
+	Metrics font.Metrics
+
+	// X is the position of the run's left edge along the line, relative to
+	// the line's left edge. For right-to-left runs, this is where the run's
+	// last rune ends.
+	X fixed.Int26_6
+
+	// Advance is the run's advance width, including kerning between the
+	// run's glyphs.
+	Advance fixed.Int26_6
+}
+
+// Line holds the metrics of a line of text made up of one or more runs, all
+// sharing the same baseline.
+type Line struct {
+	// Runs holds the metrics of each run, in order.
+	Runs []RunMetrics
+
+	// Ascent is the greatest of the runs' ascents, the distance from the
+	// baseline to the top of the line box.
+	Ascent fixed.Int26_6
+
+	// Descent is the greatest of the runs' descents, the distance from the
+	// baseline to the bottom of the line box.
+	Descent fixed.Int26_6
+
+	// Height is the recommended distance between the baselines of this line
+	// and the next. It is the greatest of the runs' heights, and no less than
+	// Ascent plus Descent.
+	Height fixed.Int26_6
+
+	// Advance is the line's total advance width, the sum of the runs'
+	// advances.
+	Advance fixed.Int26_6
+}
+
+// MeasureLine returns the metrics of the line made up of the given runs.
+//
+// The runs are in logical order, the order in which their text is read. They
+// are laid out in visual order, from left to right, which is the same unless
+// their Levels differ. The visual order follows rule L2 of the Unicode
+// Bidirectional Algorithm: from the highest level down to the lowest odd
+// level, every maximal sequence of runs at that level or higher is
+// reversed.
+//
+// The line box is the union of the runs' boxes: drawing every run with the
+// same dot.Y, that baseline is Ascent below the top of the line box, so that
+// glyphs from taller faces are not clipped by, or overlap, adjacent lines.
+//
+// No kerning is applied between the last glyph of one run and the first
+// glyph of the next, as they are from different faces.
+func MeasureLine(runs []Run) Line {
+	l := Line{
+		Runs: make([]RunMetrics, len(runs)),
+	}
+	for _, i := range visualOrder(runs) {
+		r := &runs[i]
+		m := r.Face.Metrics()
+		adv := font.MeasureString(r.Face, r.Text)
+		l.Runs[i] = RunMetrics{
+			Metrics: m,
+			X:       l.Advance,
+			Advance: adv,
+		}
+		l.Advance += adv
+		if l.Ascent < m.Ascent {
+			l.Ascent = m.Ascent
+		}
+		if l.Descent < m.Descent {
+			l.Descent = m.Descent
+		}
+		if l.Height < m.Height {
+			l.Height = m.Height
+		}
+	}
+	if h := l.Ascent + l.Descent; l.Height < h {
+		l.Height = h
+	}
+	return l
+}
+
+// visualOrder returns the indexes of runs in visual order, from left to
+// right.
+func visualOrder(runs []Run) []int {
+	order := make([]int, len(runs))
+	highest, lowestOdd := uint8(0), uint8(0xff)
+	for i := range runs {
+		order[i] = i
+		lv := runs[i].Level
+		if highest < lv {
+			highest = lv
+		}
+		if lv&1 != 0 && lowestOdd > lv {
+			lowestOdd = lv
+		}
+	}
+	for lv := highest; lv >= lowestOdd && lv > 0; lv-- {
+		for i := 0; i < len(order); {
+			if runs[order[i]].Level < lv {
+				i++
+				continue
+			}
+			j := i + 1
+			for j < len(order) && runs[order[j]].Level >= lv {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
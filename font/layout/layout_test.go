@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestMeasureLine(t *testing.T) {
+	big := basicfont.NewBitmapFace(map[rune]basicfont.Glyph{
+		'x': {
+			Mask:    image.NewAlpha(image.Rect(0, 0, 10, 20)),
+			Offset:  image.Point{0, -16},
+			Advance: 11,
+		},
+	}, nil)
+	small := basicfont.Face7x13
+
+	l := MeasureLine([]Run{
+		{Face: small, Text: "ab"},
+		{Face: big, Text: "xx"},
+		{Face: small, Text: "c"},
+	})
+
+	if got, want := len(l.Runs), 3; got != want {
+		t.Fatalf("len(Runs): got %d, want %d", got, want)
+	}
+	wantX := []fixed.Int26_6{0, fixed.I(14), fixed.I(36)}
+	wantAdvance := []fixed.Int26_6{fixed.I(14), fixed.I(22), fixed.I(7)}
+	for i, r := range l.Runs {
+		if r.X != wantX[i] {
+			t.Errorf("Runs[%d].X: got %v, want %v", i, r.X, wantX[i])
+		}
+		if r.Advance != wantAdvance[i] {
+			t.Errorf("Runs[%d].Advance: got %v, want %v", i, r.Advance, wantAdvance[i])
+		}
+	}
+	if got, want := l.Runs[0].Metrics, small.Metrics(); got != want {
+		t.Errorf("Runs[0].Metrics: got %v, want %v", got, want)
+	}
+
+	// The ascent comes from the big face, the descent from the small face.
+	if got, want := l.Ascent, fixed.I(16); got != want {
+		t.Errorf("Ascent: got %v, want %v", got, want)
+	}
+	if got, want := l.Descent, fixed.I(4); got != want {
+		t.Errorf("Descent: got %v, want %v", got, want)
+	}
+	if got, want := l.Height, fixed.I(20); got != want {
+		t.Errorf("Height: got %v, want %v", got, want)
+	}
+	if got, want := l.Advance, fixed.I(43); got != want {
+		t.Errorf("Advance: got %v, want %v", got, want)
+	}
+}
+
+func TestVisualOrder(t *testing.T) {
+	testCases := []struct {
+		levels []uint8
+		want   []int
+	}{
+		{[]uint8{}, []int{}},
+		{[]uint8{0, 0, 0}, []int{0, 1, 2}},
+		{[]uint8{0, 1, 0}, []int{0, 1, 2}},
+		{[]uint8{0, 1, 1, 0}, []int{0, 2, 1, 3}},
+		{[]uint8{1, 2, 1}, []int{2, 1, 0}},
+		{[]uint8{1, 2, 2, 1}, []int{3, 1, 2, 0}},
+		{[]uint8{0, 1, 2, 1, 0}, []int{0, 3, 2, 1, 4}},
+	}
+	for _, tc := range testCases {
+		runs := make([]Run, len(tc.levels))
+		for i, lv := range tc.levels {
+			runs[i].Level = lv
+		}
+		got := visualOrder(runs)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("levels %v: got %v, want %v", tc.levels, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Hyphenator finds the points at which words may be hyphenated, such as by
+// Liang's TeX hyphenation patterns or by a dictionary.
+type Hyphenator interface {
+	// Hyphenate returns the byte offsets into word at which it may be
+	// broken, with a hyphen after the first part, in increasing order. The
+	// word has no spaces and may contain punctuation, such as a trailing
+	// comma, which the Hyphenator may ignore.
+	Hyphenate(word string) []int
+}
+
+// HyphenatorFunc is a Hyphenator that calls a function.
+type HyphenatorFunc func(word string) []int
+
+// Hyphenate implements the Hyphenator interface.
+func (f HyphenatorFunc) Hyphenate(word string) []int {
+	return f(word)
+}
+
+// BreakOptions are optional parameters to BreakLines.
+//
+// A nil *BreakOptions means to use the default (zero) values of each field.
+type BreakOptions struct {
+	// Hyphenator, if non-nil, finds the points at which words that do not
+	// fit at the end of a line may be hyphenated.
+	Hyphenator Hyphenator
+
+	// Hyphen is the text that ends a line broken within a word, drawn in
+	// the face of the word's last rune on that line. Empty means "-".
+	Hyphen string
+}
+
+// BreakLines breaks the text of the runs, a paragraph, into lines that are
+// at most width wide, not counting their trailing spaces, and returns them as
+// a Paragraph, which can be passed to LayoutParagraphs or, line by line, to
+// Justify.
+//
+// Lines are broken after spaces, which are kept at the end of their line
+// but not counted in its width, and are filled greedily: each line has as
+// many words as fit. A word that does not fit at the end of a line is
+// hyphenated, if opts.Hyphenator allows a break that fits, and otherwise
+// starts the next line. A word that is wider than width on a line of its own
+// and cannot be hyphenated is not broken, and the line overflows.
+//
+// Each line's runs are parts of the given runs, with the same Face and
+// Level, in logical order.
+func BreakLines(runs []Run, width fixed.Int26_6, opts *BreakOptions) Paragraph {
+	var o BreakOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Hyphen == "" {
+		o.Hyphen = "-"
+	}
+
+	b := newBreaker(runs)
+	if len(b.units) == 0 {
+		return Paragraph{runs}
+	}
+	var p Paragraph
+	start := 0
+	for w := 0; w < len(b.words); {
+		wd := &b.words[w]
+		if b.width(start, wd.end) <= width {
+			w++
+			continue
+		}
+		if o.Hyphenator != nil {
+			if cut, ok := b.hyphenate(start, wd, width, &o); ok {
+				p = append(p, b.line(start, cut, o.Hyphen))
+				start, wd.start = cut, cut
+				continue
+			}
+		}
+		if start == wd.start {
+			// The word is wider than a line and cannot be hyphenated, so
+			// it overflows its line.
+			w++
+			continue
+		}
+		p = append(p, b.line(start, wd.start, ""))
+		start = wd.start
+	}
+	return append(p, b.line(start, len(b.units), ""))
+}
+
+// unit is a rune of the text being broken into lines.
+type unit struct {
+	// run is the index of the rune's run, and offset and size are the
+	// rune's byte offset into that run's Text and its length in bytes.
+	run, offset, size int
+	// kern is the kerning between the rune and the previous rune of the
+	// same run.
+	kern fixed.Int26_6
+}
+
+// word is a sequence of non-space runes, units[start:end]. orig is the
+// word's first rune, which start is moved past as the word is hyphenated.
+type word struct {
+	orig, start, end int
+}
+
+type breaker struct {
+	runs  []Run
+	units []unit
+	// pre[i] is the sum of the advances and kerning of units[:i].
+	pre   []fixed.Int26_6
+	words []word
+}
+
+func newBreaker(runs []Run) *breaker {
+	b := &breaker{runs: runs, pre: []fixed.Int26_6{0}}
+	inWord := false
+	for i := range runs {
+		r := &runs[i]
+		prevC := rune(-1)
+		for j, c := range r.Text {
+			u := unit{run: i, offset: j, size: utf8.RuneLen(c)}
+			adv := fixed.Int26_6(0)
+			if prevC >= 0 {
+				u.kern = r.Face.Kern(prevC, c)
+				adv += u.kern
+			}
+			if a, ok := r.Face.GlyphAdvance(c); ok {
+				adv += a
+				prevC = c
+			}
+			if space := isSpace(c); !space && !inWord {
+				b.words = append(b.words, word{orig: len(b.units), start: len(b.units)})
+				inWord = true
+			} else if space && inWord {
+				b.words[len(b.words)-1].end = len(b.units)
+				inWord = false
+			}
+			b.units = append(b.units, u)
+			b.pre = append(b.pre, b.pre[len(b.pre)-1]+adv)
+		}
+	}
+	if inWord {
+		b.words[len(b.words)-1].end = len(b.units)
+	}
+	return b
+}
+
+// width returns the width of units[i:j] at the start of a line, where the
+// kerning before units[i] does not apply.
+func (b *breaker) width(i, j int) fixed.Int26_6 {
+	if i >= j {
+		return 0
+	}
+	return b.pre[j] - b.pre[i] - b.units[i].kern
+}
+
+// hyphenate returns the last point at which the word wd may be hyphenated
+// so that the line from units[start] to that point, followed by a hyphen,
+// fits in width.
+func (b *breaker) hyphenate(start int, wd *word, width fixed.Int26_6, o *BreakOptions) (cut int, ok bool) {
+	// Find the word's text, and the byte offset into it of each of its
+	// runes.
+	var text []byte
+	offsets := make([]int, 0, wd.end-wd.orig)
+	for i := wd.orig; i < wd.end; i++ {
+		u := &b.units[i]
+		offsets = append(offsets, len(text))
+		text = append(text, b.runs[u.run].Text[u.offset:u.offset+u.size]...)
+	}
+	points := o.Hyphenator.Hyphenate(string(text))
+	for k := len(points) - 1; k >= 0; k-- {
+		// Find the rune that starts at the break point, if any.
+		n := 0
+		for n < len(offsets) && offsets[n] < points[k] {
+			n++
+		}
+		if n == len(offsets) || offsets[n] != points[k] {
+			continue
+		}
+		cut = wd.orig + n
+		if cut <= wd.start || cut >= wd.end {
+			continue
+		}
+		face := b.runs[b.units[cut-1].run].Face
+		if b.width(start, cut)+font.MeasureString(face, o.Hyphen) <= width {
+			return cut, true
+		}
+	}
+	return 0, false
+}
+
+// line returns the runs of units[i:j], followed by hyphen.
+func (b *breaker) line(i, j int, hyphen string) []Run {
+	var line []Run
+	for i < j {
+		u := &b.units[i]
+		k := i + 1
+		for k < j && b.units[k].run == u.run {
+			k++
+		}
+		last := &b.units[k-1]
+		r := b.runs[u.run]
+		r.Text = r.Text[u.offset : last.offset+last.size]
+		line = append(line, r)
+		i = k
+	}
+	if hyphen != "" && len(line) > 0 {
+		line[len(line)-1].Text += hyphen
+	}
+	return line
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// lineTexts returns the text of each line of p, with the runs of a line
+// separated by "|".
+func lineTexts(p Paragraph) []string {
+	var texts []string
+	for _, line := range p {
+		var parts []string
+		for _, r := range line {
+			parts = append(parts, r.Text)
+		}
+		texts = append(texts, strings.Join(parts, "|"))
+	}
+	return texts
+}
+
+func TestBreakLines(t *testing.T) {
+	// Every glyph of Face7x13 is 7 pixels wide.
+	face := basicfont.Face7x13
+	syllables := HyphenatorFunc(func(word string) []int {
+		switch strings.TrimRight(word, ",") {
+		case "hyphenation":
+			return []int{2, 6, 8}
+		case "extraordinary":
+			return []int{2, 5, 7, 9}
+		}
+		return nil
+	})
+	testCases := []struct {
+		desc  string
+		runs  []Run
+		chars int // The width, in characters.
+		opts  *BreakOptions
+		want  []string
+	}{{
+		desc:  "fits",
+		runs:  []Run{{Face: face, Text: "one two"}},
+		chars: 7,
+		want:  []string{"one two"},
+	}, {
+		desc:  "trailing spaces",
+		runs:  []Run{{Face: face, Text: "one two  three"}},
+		chars: 7,
+		want:  []string{"one two  ", "three"},
+	}, {
+		desc:  "overflow",
+		runs:  []Run{{Face: face, Text: "a hyphenation b"}},
+		chars: 6,
+		want:  []string{"a ", "hyphenation ", "b"},
+	}, {
+		desc:  "hyphenate at the end of a line",
+		runs:  []Run{{Face: face, Text: "a hyphenation b"}},
+		chars: 9,
+		opts:  &BreakOptions{Hyphenator: syllables},
+		want:  []string{"a hyphen-", "ation b"},
+	}, {
+		desc:  "hyphenate more than once",
+		runs:  []Run{{Face: face, Text: "extraordinary"}},
+		chars: 5,
+		opts:  &BreakOptions{Hyphenator: syllables, Hyphen: "="},
+		want:  []string{"ex=", "tra=", "ordi=", "nary"},
+	}, {
+		desc:  "no break fits",
+		runs:  []Run{{Face: face, Text: "ab hyphenation"}},
+		chars: 4,
+		opts:  &BreakOptions{Hyphenator: syllables},
+		want:  []string{"ab ", "hy-", "phenation"},
+	}, {
+		desc: "runs",
+		runs: []Run{
+			{Face: face, Text: "one t"},
+			{Face: face, Text: "wo three", Level: 1},
+		},
+		chars: 6,
+		want:  []string{"one ", "t|wo ", "three"},
+	}}
+	for _, tc := range testCases {
+		got := lineTexts(BreakLines(tc.runs, fixed.I(7*tc.chars), tc.opts))
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %q, want %q", tc.desc, got, tc.want)
+		}
+	}
+
+	// The lines keep their runs' faces and levels.
+	p := BreakLines([]Run{{Face: face, Text: "ab cd", Level: 1}}, fixed.I(14), nil)
+	for _, line := range p {
+		if len(line) != 1 || line[0].Face != face || line[0].Level != 1 {
+			t.Errorf("line %v: the run's face or level changed", line)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"golang.org/x/image/math/fixed"
+)
+
+// LineHeight is how LayoutParagraphs chooses the distance between
+// consecutive baselines.
+type LineHeight int
+
+const (
+	// LineHeightMetrics means to use each line's Height, the greatest of its
+	// faces' recommended line spacings. Any extra space beyond the line's
+	// Ascent and Descent is below the line, as with a font.Drawer that
+	// advances its dot by the face's Height after every line.
+	LineHeightMetrics LineHeight = iota
+	// LineHeightFixed means that every line is Options.Height high,
+	// regardless of its faces.
+	LineHeightFixed
+	// LineHeightMultiple means that every line is Options.Multiple times its
+	// Height high, such as 1.5 for one and a half line spacing.
+	LineHeightMultiple
+)
+
+// Options are optional parameters to LayoutParagraphs.
+//
+// A nil *Options means to use the default (zero) values of each field.
+type Options struct {
+	// LineHeight is the line height strategy.
+	LineHeight LineHeight
+
+	// Height is the height of every line, for LineHeightFixed.
+	Height fixed.Int26_6
+
+	// Multiple is the multiple of each line's Height, for
+	// LineHeightMultiple.
+	Multiple float64
+
+	// ParagraphSpacing is the extra space between the last line of one
+	// paragraph and the first line of the next.
+	ParagraphSpacing fixed.Int26_6
+
+	// FirstLineIndent is the indent of the first line of each paragraph. It
+	// may be negative, for a hanging indent, in which case callers typically
+	// indent the whole text block by its magnitude.
+	FirstLineIndent fixed.Int26_6
+}
+
+// Paragraph is a paragraph's lines, in order. Each line is the runs of that
+// line, as passed to MeasureLine. Breaking a paragraph's text into lines is
+// the caller's responsibility.
+type Paragraph [][]Run
+
+// PlacedLine is a line of a paragraph, positioned by LayoutParagraphs.
+type PlacedLine struct {
+	// Line holds the line's metrics, as returned by MeasureLine.
+	Line
+
+	// Dot is the start of the line's baseline, relative to the top-left
+	// corner of the text block. It is where to draw the line's first run
+	// in visual order.
+	Dot fixed.Point26_6
+
+	// Top and Bottom are the vertical extent of the space allotted to the
+	// line, relative to the top of the text block. Lines of the same
+	// paragraph touch, so that they can be hit-tested or highlighted
+	// without gaps.
+	Top, Bottom fixed.Int26_6
+}
+
+// LayoutParagraphs positions the lines of the given paragraphs, one below
+// the other. It returns the lines, in order, and the total height of the text
+// block.
+//
+// For LineHeightFixed and LineHeightMultiple, any difference between the
+// line height and the line's Ascent plus Descent, its leading, is split
+// equally above and below the line, as CSS does. The leading may be
+// negative, in which case the glyphs of adjacent lines may overlap.
+func LayoutParagraphs(paragraphs []Paragraph, opts *Options) (lines []PlacedLine, height fixed.Int26_6) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	for i, p := range paragraphs {
+		if i > 0 {
+			height += o.ParagraphSpacing
+		}
+		for j, runs := range p {
+			l := MeasureLine(runs)
+			h, above := l.Height, fixed.Int26_6(0)
+			switch o.LineHeight {
+			case LineHeightFixed:
+				h = o.Height
+			case LineHeightMultiple:
+				h = fixed.Int26_6(float64(l.Height)*o.Multiple + 0.5)
+			}
+			if o.LineHeight != LineHeightMetrics {
+				above = (h - l.Ascent - l.Descent) / 2
+			}
+			x := fixed.Int26_6(0)
+			if j == 0 {
+				x = o.FirstLineIndent
+			}
+			lines = append(lines, PlacedLine{
+				Line:   l,
+				Dot:    fixed.Point26_6{X: x, Y: height + above + l.Ascent},
+				Top:    height,
+				Bottom: height + h,
+			})
+			height += h
+		}
+	}
+	return lines, height
+}
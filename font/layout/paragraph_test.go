@@ -0,0 +1,92 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestLayoutParagraphs(t *testing.T) {
+	// Face7x13's Ascent, Descent and Height are 11, 2 and 13.
+	face := basicfont.Face7x13
+	paragraphs := []Paragraph{
+		{{{Face: face, Text: "one"}}, {{Face: face, Text: "two"}}},
+		{{{Face: face, Text: "three"}}},
+	}
+	half := func(i int) fixed.Int26_6 { return fixed.I(i) / 2 }
+	pt := func(x, y fixed.Int26_6) fixed.Point26_6 { return fixed.Point26_6{X: x, Y: y} }
+
+	testCases := []struct {
+		desc   string
+		opts   *Options
+		dots   []fixed.Point26_6
+		tops   []fixed.Int26_6
+		height fixed.Int26_6
+	}{{
+		desc:   "nil",
+		opts:   nil,
+		dots:   []fixed.Point26_6{pt(0, fixed.I(11)), pt(0, fixed.I(24)), pt(0, fixed.I(37))},
+		tops:   []fixed.Int26_6{0, fixed.I(13), fixed.I(26)},
+		height: fixed.I(39),
+	}, {
+		desc: "metrics",
+		opts: &Options{
+			ParagraphSpacing: fixed.I(5),
+			FirstLineIndent:  fixed.I(10),
+		},
+		dots:   []fixed.Point26_6{pt(fixed.I(10), fixed.I(11)), pt(0, fixed.I(24)), pt(fixed.I(10), fixed.I(42))},
+		tops:   []fixed.Int26_6{0, fixed.I(13), fixed.I(31)},
+		height: fixed.I(44),
+	}, {
+		// The leading of 20 - 13 = 7 is split equally above and below.
+		desc: "fixed",
+		opts: &Options{
+			LineHeight:       LineHeightFixed,
+			Height:           fixed.I(20),
+			ParagraphSpacing: fixed.I(5),
+		},
+		dots:   []fixed.Point26_6{pt(0, half(29)), pt(0, half(69)), pt(0, half(119))},
+		tops:   []fixed.Int26_6{0, fixed.I(20), fixed.I(45)},
+		height: fixed.I(65),
+	}, {
+		desc: "multiple",
+		opts: &Options{
+			LineHeight: LineHeightMultiple,
+			Multiple:   2,
+		},
+		dots:   []fixed.Point26_6{pt(0, half(35)), pt(0, half(87)), pt(0, half(139))},
+		tops:   []fixed.Int26_6{0, fixed.I(26), fixed.I(52)},
+		height: fixed.I(78),
+	}}
+
+	for _, tc := range testCases {
+		lines, height := LayoutParagraphs(paragraphs, tc.opts)
+		if height != tc.height {
+			t.Errorf("%s: height: got %v, want %v", tc.desc, height, tc.height)
+		}
+		if len(lines) != len(tc.dots) {
+			t.Errorf("%s: len(lines): got %d, want %d", tc.desc, len(lines), len(tc.dots))
+			continue
+		}
+		for i, l := range lines {
+			if l.Dot != tc.dots[i] {
+				t.Errorf("%s: lines[%d].Dot: got %v, want %v", tc.desc, i, l.Dot, tc.dots[i])
+			}
+			if l.Top != tc.tops[i] {
+				t.Errorf("%s: lines[%d].Top: got %v, want %v", tc.desc, i, l.Top, tc.tops[i])
+			}
+		}
+		// Lines of the same paragraph touch.
+		if lines[0].Bottom != lines[1].Top {
+			t.Errorf("%s: lines[0].Bottom: got %v, want %v", tc.desc, lines[0].Bottom, lines[1].Top)
+		}
+		if got := lines[2].Line.Advance; got != fixed.I(35) {
+			t.Errorf("%s: lines[2].Advance: got %v, want %v", tc.desc, got, fixed.I(35))
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Align is the horizontal alignment of the lines of a text block.
+type Align int
+
+const (
+	// AlignLeft aligns each line's start with the left edge of the block.
+	AlignLeft Align = iota
+	// AlignCenter centers each line within the block.
+	AlignCenter
+	// AlignRight aligns each line's end with the right edge of the block.
+	AlignRight
+)
+
+// TextOptions are optional parameters to LayoutText and DrawText.
+//
+// A nil *TextOptions means to use the default (zero) values of each field.
+type TextOptions struct {
+	// Options are the line height, paragraph spacing and indent options, as
+	// for LayoutParagraphs. Each line of the text, as separated by '\n', is
+	// a paragraph.
+	Options
+
+	// Width, if positive, is the width of the text block. Lines are wrapped
+	// at spaces so that they are at most Width wide, and aligned within
+	// Width. Zero means that lines are only broken at '\n', and aligned
+	// within the widest line.
+	Width fixed.Int26_6
+
+	// Align is the horizontal alignment of the lines.
+	Align Align
+
+	// TabWidth is the distance between tab stops, measured from the start
+	// of each line. Zero means 8 times the advance of the face's space, or
+	// its Height if it has no space.
+	TabWidth fixed.Int26_6
+}
+
+// TextLine is a line of text laid out by LayoutText.
+type TextLine struct {
+	// PlacedLine holds the line's position and metrics. Line.Runs[i] holds
+	// the position of Spans[i] along the line.
+	PlacedLine
+
+	// Spans are the line's runs of text, split at its tabs, which are not
+	// part of any span. Spaces at which the line was wrapped are not part of
+	// any span either. A line of no text has one empty span.
+	Spans []Run
+}
+
+// LayoutText lays out the text s, drawn with the face f, as a block of lines.
+// It returns the lines, in order, and the bounding box of the lines' advances
+// and line heights, relative to the top-left corner of the block, which is
+// where the first line's top meets the left edge of the block.
+//
+// The text is broken into lines at each '\n' and, if opts.Width is positive,
+// wrapped at spaces, filling each line greedily. A word that is wider than
+// the block on a line of its own overflows it. Tabs advance to the next tab
+// stop, and may be followed by a wrap like spaces.
+//
+// LayoutText is for left-to-right text in a single face, which may be a
+// font.MultiFace for text whose runes no single face covers. For text in
+// several faces or directions, use BreakLines and LayoutParagraphs.
+func LayoutText(f font.Face, s string, opts *TextOptions) (lines []TextLine, bounds fixed.Rectangle26_6) {
+	var o TextOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.TabWidth <= 0 {
+		adv, _ := f.GlyphAdvance(' ')
+		o.TabWidth = 8 * adv
+		if o.TabWidth <= 0 {
+			o.TabWidth = f.Metrics().Height
+		}
+	}
+
+	var (
+		paragraphs []Paragraph
+		xs         [][]fixed.Int26_6
+	)
+	for _, text := range strings.Split(s, "\n") {
+		var p Paragraph
+		w := text
+		for first := true; ; first = false {
+			width := o.Width
+			if width > 0 && first {
+				width -= o.FirstLineIndent
+			}
+			spans, x, rest := wrapLine(f, w, width, o.TabWidth)
+			line := make([]Run, len(spans))
+			for i, span := range spans {
+				line[i] = Run{Face: f, Text: span}
+			}
+			p = append(p, line)
+			xs = append(xs, x)
+			if rest == "" {
+				break
+			}
+			w = rest
+		}
+		paragraphs = append(paragraphs, p)
+	}
+
+	placed, height := LayoutParagraphs(paragraphs, &o.Options)
+	lines = make([]TextLine, len(placed))
+	var blockWidth fixed.Int26_6
+	k := 0
+	for _, p := range paragraphs {
+		for _, spans := range p {
+			l := &lines[k]
+			l.PlacedLine, l.Spans = placed[k], spans
+			// MeasureLine placed the spans one after the other. Move them to
+			// their tab stops.
+			l.Line.Runs = append([]RunMetrics(nil), l.Line.Runs...)
+			for i := range l.Line.Runs {
+				l.Line.Runs[i].X = xs[k][i]
+			}
+			last := l.Line.Runs[len(l.Line.Runs)-1]
+			l.Line.Advance = last.X + last.Advance
+			if w := l.Dot.X + l.Line.Advance; blockWidth < w {
+				blockWidth = w
+			}
+			k++
+		}
+	}
+	if o.Width > 0 {
+		blockWidth = o.Width
+	}
+
+	bounds.Min.X, bounds.Max.Y = blockWidth, height
+	for i := range lines {
+		l := &lines[i]
+		switch free := blockWidth - l.Dot.X - l.Line.Advance; o.Align {
+		case AlignCenter:
+			l.Dot.X += free / 2
+		case AlignRight:
+			l.Dot.X += free
+		}
+		if bounds.Min.X > l.Dot.X {
+			bounds.Min.X = l.Dot.X
+		}
+		if w := l.Dot.X + l.Line.Advance; bounds.Max.X < w {
+			bounds.Max.X = w
+		}
+	}
+	return lines, bounds
+}
+
+// wrapLine returns the first line of s, which has no '\n', drawn with f and
+// wrapped to width if width is positive. It returns the line's spans, split
+// at tabs, their positions along the line and the rest of s, after the
+// spaces at which the line was wrapped.
+func wrapLine(f font.Face, s string, width, tabWidth fixed.Int26_6) (spans []string, xs []fixed.Int26_6, rest string) {
+	var (
+		// The current span is s[start:end], from x to x+adv, and prevC is
+		// its last rune, for kerning, or -1.
+		start, end int
+		x, adv     fixed.Int26_6
+		prevC      = rune(-1)
+		// hasWord is whether the line has any non-space runes.
+		hasWord bool
+	)
+	for i := 0; i < len(s); {
+		// Find the spaces and tabs at s[i:j], and the word after them at
+		// s[j:k].
+		j := i
+		for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+			j++
+		}
+		k := j
+		for k < len(s) && s[k] != ' ' && s[k] != '\t' {
+			k++
+		}
+
+		// Measure where the word would end, without changing the line.
+		wSpans, wXs := spans, xs
+		wStart, wX, wAdv, wPrevC := start, x, adv, prevC
+		for m := i; m < j; m++ {
+			if s[m] == ' ' {
+				wAdv += advance(f, wPrevC, ' ')
+				wPrevC = ' '
+				continue
+			}
+			// A tab ends the span, and starts the next at the next tab stop.
+			wSpans, wXs = append(wSpans, s[wStart:m]), append(wXs, wX)
+			wX = ((wX+wAdv)/tabWidth + 1) * tabWidth
+			wStart, wAdv, wPrevC = m+1, 0, -1
+		}
+		for _, c := range s[j:k] {
+			wAdv += advance(f, wPrevC, c)
+			wPrevC = c
+		}
+
+		if width > 0 && hasWord && j < k && wX+wAdv > width {
+			// Wrap before the word, dropping the spaces and tabs before it.
+			return append(spans, s[start:end]), append(xs, x), s[j:]
+		}
+		spans, xs = wSpans, wXs
+		start, end, x, adv, prevC = wStart, k, wX, wAdv, wPrevC
+		hasWord = hasWord || j < k
+		i = k
+	}
+	return append(spans, s[start:]), append(xs, x), ""
+}
+
+// advance returns the advance of c drawn with f after prevC, or after nothing
+// if prevC is negative, including the kerning between them.
+func advance(f font.Face, prevC, c rune) fixed.Int26_6 {
+	a, _ := f.GlyphAdvance(c)
+	if prevC >= 0 {
+		a += f.Kern(prevC, c)
+	}
+	return a
+}
+
+// DrawText draws the text s with d, laid out by LayoutText with d's face,
+// with the top-left corner of the block at d.Dot. It returns the block's
+// bounding box, as LayoutText does but relative to d's destination image, and
+// moves d.Dot down by the block's height, to the top-left corner of any text
+// that follows the block.
+func DrawText(d *font.Drawer, s string, opts *TextOptions) fixed.Rectangle26_6 {
+	lines, bounds := LayoutText(d.Face, s, opts)
+	origin := d.Dot
+	for _, l := range lines {
+		for i, span := range l.Spans {
+			if span.Text == "" {
+				continue
+			}
+			d.Dot = origin.Add(l.Dot)
+			d.Dot.X += l.Line.Runs[i].X
+			d.DrawString(span.Text)
+		}
+	}
+	d.Dot = fixed.Point26_6{X: origin.X, Y: origin.Y + bounds.Max.Y}
+	return bounds.Add(origin)
+}
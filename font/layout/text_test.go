@@ -0,0 +1,158 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package layout
+
+import (
+	"fmt"
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// textLines returns each line's dot and spans, with the spans' positions, in
+// pixels.
+func textLines(lines []TextLine) []string {
+	var s []string
+	for _, l := range lines {
+		parts := []string{fmt.Sprintf("(%d,%d)", l.Dot.X.Round(), l.Dot.Y.Round())}
+		for i, span := range l.Spans {
+			parts = append(parts, fmt.Sprintf("%q@%d", span.Text, l.Line.Runs[i].X.Round()))
+		}
+		s = append(s, strings.Join(parts, " "))
+	}
+	return s
+}
+
+func TestLayoutText(t *testing.T) {
+	// Every glyph of Face7x13 is 7 pixels wide, and its Ascent, Descent and
+	// Height are 11, 2 and 13.
+	face := basicfont.Face7x13
+	testCases := []struct {
+		desc   string
+		text   string
+		opts   *TextOptions
+		want   []string
+		bounds image.Rectangle
+	}{{
+		desc:   "empty",
+		text:   "",
+		want:   []string{`(0,11) ""@0`},
+		bounds: image.Rect(0, 0, 0, 13),
+	}, {
+		desc:   "newlines",
+		text:   "ab\n\ncde",
+		want:   []string{`(0,11) "ab"@0`, `(0,24) ""@0`, `(0,37) "cde"@0`},
+		bounds: image.Rect(0, 0, 21, 39),
+	}, {
+		desc: "wrap",
+		text: "one two  three four",
+		opts: &TextOptions{Width: fixed.I(7 * 9)},
+		want: []string{
+			`(0,11) "one two"@0`,
+			`(0,24) "three"@0`,
+			`(0,37) "four"@0`,
+		},
+		bounds: image.Rect(0, 0, 49, 39),
+	}, {
+		desc:   "overflow",
+		text:   "a abcdefgh",
+		opts:   &TextOptions{Width: fixed.I(7 * 4)},
+		want:   []string{`(0,11) "a"@0`, `(0,24) "abcdefgh"@0`},
+		bounds: image.Rect(0, 0, 56, 26),
+	}, {
+		desc:   "tabs",
+		text:   "a\tbc\t\td",
+		opts:   &TextOptions{TabWidth: fixed.I(7 * 4)},
+		want:   []string{`(0,11) "a"@0 "bc"@28 ""@56 "d"@84`},
+		bounds: image.Rect(0, 0, 91, 13),
+	}, {
+		desc:   "default tabs",
+		text:   "\tab",
+		want:   []string{`(0,11) ""@0 "ab"@56`},
+		bounds: image.Rect(0, 0, 70, 13),
+	}, {
+		desc: "wrap at a tab",
+		text: "abc\tdef",
+		opts: &TextOptions{Width: fixed.I(7 * 6), TabWidth: fixed.I(7 * 4)},
+		want: []string{`(0,11) "abc"@0`, `(0,24) "def"@0`},
+		// The block is Width wide, but the bounds are those of the lines.
+		bounds: image.Rect(0, 0, 21, 26),
+	}, {
+		desc:   "center",
+		text:   "a\nbcd",
+		opts:   &TextOptions{Align: AlignCenter},
+		want:   []string{`(7,11) "a"@0`, `(0,24) "bcd"@0`},
+		bounds: image.Rect(0, 0, 21, 26),
+	}, {
+		desc:   "right",
+		text:   "ab cd ef",
+		opts:   &TextOptions{Width: fixed.I(7 * 6), Align: AlignRight},
+		want:   []string{`(7,11) "ab cd"@0`, `(28,24) "ef"@0`},
+		bounds: image.Rect(7, 0, 42, 26),
+	}, {
+		desc: "indent and line height",
+		text: "ab cd ef",
+		opts: &TextOptions{
+			Options: Options{
+				LineHeight:      LineHeightFixed,
+				Height:          fixed.I(17),
+				FirstLineIndent: fixed.I(7),
+			},
+			Width: fixed.I(7 * 5),
+		},
+		want:   []string{`(7,13) "ab"@0`, `(0,30) "cd ef"@0`},
+		bounds: image.Rect(0, 0, 35, 34),
+	}}
+	for _, tc := range testCases {
+		lines, bounds := LayoutText(face, tc.text, tc.opts)
+		if got := textLines(lines); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: lines:\ngot  %q\nwant %q", tc.desc, got, tc.want)
+		}
+		got := image.Rect(bounds.Min.X.Round(), bounds.Min.Y.Round(), bounds.Max.X.Round(), bounds.Max.Y.Round())
+		if got != tc.bounds {
+			t.Errorf("%s: bounds: got %v, want %v", tc.desc, got, tc.bounds)
+		}
+	}
+}
+
+func TestDrawText(t *testing.T) {
+	dst := image.NewGray(image.Rect(0, 0, 100, 100))
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.White,
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(10, 20),
+	}
+	bounds := DrawText(d, "a\n\tb", &TextOptions{TabWidth: fixed.I(14)})
+	if want := fixed.R(10, 20, 31, 46); bounds != want {
+		t.Errorf("bounds: got %v, want %v", bounds, want)
+	}
+	if want := fixed.P(10, 46); d.Dot != want {
+		t.Errorf("Dot: got %v, want %v", d.Dot, want)
+	}
+	// The "b" is drawn at the tab stop, 14 pixels right of the block's left
+	// edge, and nothing is drawn before it.
+	inked := func(x0, y0, x1, y1 int) bool {
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				if dst.GrayAt(x, y).Y != 0 {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !inked(24, 33, 31, 46) {
+		t.Error("no ink for the second line's b")
+	}
+	if inked(10, 33, 24, 46) {
+		t.Error("ink before the second line's tab stop")
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package font
+
+import (
+	"image"
+	"image/color"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/rangetable"
+)
+
+// CoverageFace is a Face that can report which runes it has glyphs for.
+//
+// A Face's Glyph, GlyphBounds and GlyphAdvance methods often return ok for
+// runes that the face has no glyph for, drawing a substitute instead, such as
+// a font file's .notdef glyph or the U+FFFD replacement character. HasGlyph
+// and Coverage do not count such substitutes.
+type CoverageFace interface {
+	Face
+
+	// HasGlyph returns whether the face has a glyph of its own for r.
+	HasGlyph(r rune) bool
+
+	// Coverage returns the runes that the face has glyphs of their own for.
+	// The returned table may be shared, and must not be modified.
+	Coverage() *unicode.RangeTable
+}
+
+// HasGlyph returns whether f has a glyph of its own for r. If f is not a
+// CoverageFace, it returns whether f's GlyphAdvance method returns ok for r,
+// which may be true even for runes that f draws as a substitute glyph.
+func HasGlyph(f Face, r rune) bool {
+	if cf, ok := f.(CoverageFace); ok {
+		return cf.HasGlyph(r)
+	}
+	_, ok := f.GlyphAdvance(r)
+	return ok
+}
+
+// Coverage returns the runes that f has glyphs of its own for, as reported by
+// HasGlyph. If f is not a CoverageFace, this calls HasGlyph for every valid
+// rune, which is slow.
+func Coverage(f Face) *unicode.RangeTable {
+	if cf, ok := f.(CoverageFace); ok {
+		return cf.Coverage()
+	}
+	var runes []rune
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if 0xd800 <= r && r < 0xe000 {
+			// Skip the surrogates, which are not valid runes.
+			continue
+		}
+		if HasGlyph(f, r) {
+			runes = append(runes, r)
+		}
+	}
+	return rangetable.New(runes...)
+}
+
+// MultiFace is a Face that draws each rune with the first of a list of faces
+// that has a glyph for it, as reported by HasGlyph, such as for text that
+// mixes scripts that no single font covers. Runes that none of the faces
+// have a glyph for are drawn by the first face, which typically draws a
+// substitute glyph.
+//
+// A MultiFace is a ColorFace and a CoverageFace. Its ColorGlyph method
+// returns !ok for runes whose face is not a ColorFace.
+//
+// Like other faces, a MultiFace is not safe for concurrent use by multiple
+// goroutines. It caches which face to use for each rune.
+type MultiFace struct {
+	faces []Face
+	// index maps runes to the index in faces of the face to draw them with.
+	index map[rune]int
+}
+
+// NewMultiFace returns a MultiFace that draws each rune with the first of
+// faces that has a glyph for it. The faces are typically of the same size,
+// and the first face, whose metrics the MultiFace reports, is the primary
+// face for the text's main script.
+func NewMultiFace(faces ...Face) *MultiFace {
+	return &MultiFace{
+		faces: faces,
+		index: make(map[rune]int),
+	}
+}
+
+// faceIndex returns the index in m.faces of the face to draw r with, or -1 if
+// there are no faces.
+func (m *MultiFace) faceIndex(r rune) int {
+	if len(m.faces) == 0 {
+		return -1
+	}
+	i, ok := m.index[r]
+	if !ok {
+		for j, f := range m.faces {
+			if HasGlyph(f, r) {
+				i = j
+				break
+			}
+		}
+		m.index[r] = i
+	}
+	return i
+}
+
+// face returns the face to draw r with, or nil if there are no faces.
+func (m *MultiFace) face(r rune) Face {
+	if i := m.faceIndex(r); i >= 0 {
+		return m.faces[i]
+	}
+	return nil
+}
+
+// Close closes all of the faces, and returns the first error, if any.
+func (m *MultiFace) Close() (retErr error) {
+	for _, f := range m.faces {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}
+	return retErr
+}
+
+// Glyph satisfies the Face interface.
+func (m *MultiFace) Glyph(dot fixed.Point26_6, r rune) (
+	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+
+	f := m.face(r)
+	if f == nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	return f.Glyph(dot, r)
+}
+
+// ColorGlyph satisfies the ColorFace interface.
+func (m *MultiFace) ColorGlyph(dot fixed.Point26_6, r rune, fg color.Color) (
+	dr image.Rectangle, src image.Image, sp image.Point, advance fixed.Int26_6, ok bool) {
+
+	cf, isColor := m.face(r).(ColorFace)
+	if !isColor {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	return cf.ColorGlyph(dot, r, fg)
+}
+
+// GlyphBounds satisfies the Face interface.
+func (m *MultiFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	f := m.face(r)
+	if f == nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	return f.GlyphBounds(r)
+}
+
+// GlyphAdvance satisfies the Face interface.
+func (m *MultiFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	f := m.face(r)
+	if f == nil {
+		return 0, false
+	}
+	return f.GlyphAdvance(r)
+}
+
+// Kern satisfies the Face interface. Runes drawn by different faces are not
+// kerned.
+func (m *MultiFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	i := m.faceIndex(r0)
+	if i < 0 || i != m.faceIndex(r1) {
+		return 0
+	}
+	return m.faces[i].Kern(r0, r1)
+}
+
+// Metrics satisfies the Face interface. It returns the first face's metrics.
+func (m *MultiFace) Metrics() Metrics {
+	if len(m.faces) == 0 {
+		return Metrics{}
+	}
+	return m.faces[0].Metrics()
+}
+
+// HasGlyph satisfies the CoverageFace interface. It returns whether any of
+// the faces has a glyph for r.
+func (m *MultiFace) HasGlyph(r rune) bool {
+	f := m.face(r)
+	return f != nil && HasGlyph(f, r)
+}
+
+// Coverage satisfies the CoverageFace interface. It returns the union of the
+// faces' coverage.
+func (m *MultiFace) Coverage() *unicode.RangeTable {
+	tables := make([]*unicode.RangeTable, len(m.faces))
+	for i, f := range m.faces {
+		tables[i] = Coverage(f)
+	}
+	return rangetable.Merge(tables...)
+}
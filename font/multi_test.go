@@ -0,0 +1,93 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package font
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/rangetable"
+)
+
+// coverageToyFace is a CoverageFace that has glyphs for the runes of a
+// string, all with the same advance, and that kerns every pair of runes by
+// the same amount.
+type coverageToyFace struct {
+	toyFace
+	runes   string
+	advance fixed.Int26_6
+	kern    fixed.Int26_6
+}
+
+func (f coverageToyFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	return f.advance, true
+}
+
+func (f coverageToyFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	return f.kern
+}
+
+func (f coverageToyFace) HasGlyph(r rune) bool {
+	return strings.ContainsRune(f.runes, r)
+}
+
+func (f coverageToyFace) Coverage() *unicode.RangeTable {
+	return rangetable.New([]rune(f.runes)...)
+}
+
+func TestMultiFace(t *testing.T) {
+	latin := coverageToyFace{runes: "ab", advance: fixed.I(10), kern: -fixed.I(1)}
+	greek := coverageToyFace{runes: "αβ", advance: fixed.I(20), kern: fixed.I(5)}
+	m := NewMultiFace(latin, greek)
+
+	testCases := []struct {
+		r       rune
+		advance fixed.Int26_6
+		has     bool
+	}{
+		{'a', fixed.I(10), true},
+		{'β', fixed.I(20), true},
+		// No face has 'z', so the first face draws it.
+		{'z', fixed.I(10), false},
+	}
+	cov := m.Coverage()
+	for _, tc := range testCases {
+		if got, ok := m.GlyphAdvance(tc.r); !ok || got != tc.advance {
+			t.Errorf("GlyphAdvance(%q): got %v, %t, want %v, true", tc.r, got, ok, tc.advance)
+		}
+		if got := m.HasGlyph(tc.r); got != tc.has {
+			t.Errorf("HasGlyph(%q): got %t, want %t", tc.r, got, tc.has)
+		}
+		if got := unicode.Is(cov, tc.r); got != tc.has {
+			t.Errorf("Coverage contains %q: got %t, want %t", tc.r, got, tc.has)
+		}
+	}
+
+	if got, want := m.Kern('a', 'b'), -fixed.I(1); got != want {
+		t.Errorf("Kern('a', 'b'): got %v, want %v", got, want)
+	}
+	if got, want := m.Kern('a', 'α'), fixed.Int26_6(0); got != want {
+		t.Errorf("Kern('a', 'α'): got %v, want %v", got, want)
+	}
+	if got, want := MeasureString(m, "abα"), fixed.I(10+10-1+20); got != want {
+		t.Errorf("MeasureString: got %v, want %v", got, want)
+	}
+
+	// A face that is not a CoverageFace has a glyph for every rune that its
+	// GlyphAdvance method returns ok for.
+	m = NewMultiFace(latin, toyFace{})
+	if got, ok := m.GlyphAdvance('z'); !ok || got != toyAdvance {
+		t.Errorf("toyFace: GlyphAdvance('z'): got %v, %t, want %v, true", got, ok, toyAdvance)
+	}
+	if !HasGlyph(m, 'z') {
+		t.Error("toyFace: HasGlyph('z'): got false, want true")
+	}
+
+	if _, ok := NewMultiFace().GlyphAdvance('a'); ok {
+		t.Error("no faces: GlyphAdvance: got ok")
+	}
+}
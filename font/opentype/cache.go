@@ -0,0 +1,200 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package opentype
+
+import (
+	"container/list"
+	"image"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultGlyphCacheSize is the memory budget, in bytes, of the glyph cache
+// that a Face has of its own when its FaceOptions.GlyphCache is nil.
+const DefaultGlyphCacheSize = 1 << 20
+
+// glyphEntryOverhead is roughly how many bytes a GlyphCache entry uses, other
+// than its mask's pixels.
+const glyphEntryOverhead = 256
+
+// GlyphCache is a cache of rasterized glyph masks, which a Face looks up
+// before rasterizing a glyph. When the masks exceed the cache's memory budget,
+// the least recently used ones are evicted.
+//
+// A GlyphCache is safe for concurrent use, so that it can be shared by Faces
+// of different fonts and sizes, including Faces that are used on different
+// goroutines. Faces share a glyph's mask if they have the same GlyphSource,
+// size, hinting, transform and subpixel antialiasing.
+type GlyphCache struct {
+	mu      sync.Mutex
+	budget  int
+	size    int
+	entries map[glyphKey]*list.Element
+	// lru holds the *glyphEntry values, most recently used first.
+	lru list.List
+}
+
+// NewGlyphCache returns a GlyphCache whose masks use at most about budget
+// bytes of memory. A budget of zero or less caches nothing.
+func NewGlyphCache(budget int) *GlyphCache {
+	return &GlyphCache{
+		budget:  budget,
+		entries: map[glyphKey]*list.Element{},
+	}
+}
+
+// Size returns about how many bytes of memory the cached masks use.
+func (c *GlyphCache) Size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Len returns the number of cached masks.
+func (c *GlyphCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear removes every cached mask.
+func (c *GlyphCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[glyphKey]*list.Element{}
+	c.lru.Init()
+	c.size = 0
+}
+
+// glyphKey identifies a glyph's mask. The mask depends on where the dot is
+// within a pixel, but not on which pixel it is in.
+type glyphKey struct {
+	src           GlyphSource
+	scale, xScale fixed.Int26_6
+	xform         f32.Aff3
+	hinting       font.Hinting
+	subpixel      font.SubpixelLayout
+	filter        font.LCDFilter
+	index         sfnt.GlyphIndex
+	subX, subY    fixed.Int26_6
+}
+
+type glyphEntry struct {
+	key glyphKey
+	// dr is the mask's dst-space rectangle for a dot with the key's subX and
+	// subY and a zero integer part.
+	dr image.Rectangle
+	// mask is an *image.Alpha, or a *font.SubpixelMask for faces with
+	// subpixel antialiasing. pixLen is the length of its Pix.
+	mask    image.Image
+	pixLen  int
+	advance fixed.Int26_6
+}
+
+func (e *glyphEntry) size() int {
+	return e.pixLen + glyphEntryOverhead
+}
+
+// get returns the entry for k, marking it as the most recently used, or nil.
+func (c *GlyphCache) get(k glyphKey) *glyphEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem := c.entries[k]
+	if elem == nil {
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*glyphEntry)
+}
+
+// put adds e to the cache, evicting the least recently used entries to stay
+// within the budget.
+func (c *GlyphCache) put(e *glyphEntry) {
+	n := e.size()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > c.budget {
+		return
+	}
+	if elem := c.entries[e.key]; elem != nil {
+		// Another Face added the same glyph since our get.
+		c.lru.MoveToFront(elem)
+		return
+	}
+	for c.size+n > c.budget {
+		elem := c.lru.Back()
+		old := c.lru.Remove(elem).(*glyphEntry)
+		delete(c.entries, old.key)
+		c.size -= old.size()
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += n
+}
+
+// glyphKey returns the key of the x'th glyph drawn at dot by f.
+func (f *Face) glyphKey(x sfnt.GlyphIndex, dot fixed.Point26_6) glyphKey {
+	k := glyphKey{
+		src:      f.f,
+		scale:    f.scale,
+		xScale:   f.xScale,
+		xform:    f.xform,
+		hinting:  f.hinting,
+		subpixel: f.subpixel.Layout,
+		index:    x,
+		subX:     dot.X & 63,
+		subY:     dot.Y & 63,
+	}
+	if f.subpixel.Filter != nil {
+		k.filter = *f.subpixel.Filter
+	}
+	return k
+}
+
+// cachedGlyph returns the cached mask of the x'th glyph drawn at dot, as
+// Glyph does, if f's cache has it.
+func (f *Face) cachedGlyph(x sfnt.GlyphIndex, dot fixed.Point26_6) (dr image.Rectangle, mask image.Image, advance fixed.Int26_6, ok bool) {
+	if f.cache == nil {
+		return image.Rectangle{}, nil, 0, false
+	}
+	e := f.cache.get(f.glyphKey(x, dot))
+	if e == nil {
+		return image.Rectangle{}, nil, 0, false
+	}
+	return e.dr.Add(image.Point{dot.X.Floor(), dot.Y.Floor()}), e.mask, e.advance, true
+}
+
+// cacheGlyph adds a copy of f.mask, or of f.lcd for faces with subpixel
+// antialiasing, the mask of the x'th glyph drawn at dot with the dst-space
+// rectangle dr, to f's cache.
+func (f *Face) cacheGlyph(x sfnt.GlyphIndex, dot fixed.Point26_6, dr image.Rectangle, advance fixed.Int26_6) {
+	if f.cache == nil || f.cache.budget <= 0 {
+		return
+	}
+	e := &glyphEntry{
+		key:     f.glyphKey(x, dot),
+		dr:      dr.Sub(image.Point{dot.X.Floor(), dot.Y.Floor()}),
+		advance: advance,
+	}
+	if f.subpixel.Layout != font.SubpixelNone {
+		e.mask = &font.SubpixelMask{
+			Pix:    append([]uint8(nil), f.lcd.Pix...),
+			Stride: f.lcd.Stride,
+			Rect:   f.lcd.Rect,
+		}
+		e.pixLen = len(f.lcd.Pix)
+	} else {
+		e.mask = &image.Alpha{
+			Pix:    append([]uint8(nil), f.mask.Pix...),
+			Stride: f.mask.Stride,
+			Rect:   f.mask.Rect,
+		}
+		e.pixLen = len(f.mask.Pix)
+	}
+	f.cache.put(e)
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package opentype
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestGlyphCache(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	dots := []fixed.Point26_6{
+		fixed.P(200, 500),
+		{X: fixed.I(200) + 17, Y: fixed.I(500) + 40},
+		// The same sub-pixel offsets as the previous dot, in another pixel.
+		{X: fixed.I(-3) + 17, Y: fixed.I(7) + 40},
+	}
+	for _, xform := range []*f64.Aff3{nil, {1, 0.25, 0, 0, 1, 0}} {
+		cache := NewGlyphCache(DefaultGlyphCacheSize)
+		cached, err := NewFace(f, &FaceOptions{Size: 12, DPI: 72, Transform: xform, GlyphCache: cache})
+		if err != nil {
+			t.Fatalf("NewFace: %v", err)
+		}
+		uncached, err := NewFace(f, &FaceOptions{Size: 12, DPI: 72, Transform: xform, GlyphCache: NewGlyphCache(0)})
+		if err != nil {
+			t.Fatalf("NewFace: %v", err)
+		}
+		for pass := 0; pass < 2; pass++ {
+			for _, dot := range dots {
+				for _, r := range "AÁÆix" {
+					dr0, mask0, maskp0, adv0, ok0 := uncached.Glyph(dot, r)
+					dr1, mask1, maskp1, adv1, ok1 := cached.Glyph(dot, r)
+					if !ok0 || !ok1 {
+						t.Fatalf("xform=%v, dot=%v, %q: Glyph: got ok %t, %t", xform, dot, r, ok0, ok1)
+					}
+					if dr0 != dr1 || adv0 != adv1 {
+						t.Errorf("xform=%v, dot=%v, %q: got %v, %v, want %v, %v", xform, dot, r, dr1, adv1, dr0, adv0)
+						continue
+					}
+					if !sameMask(mask0, maskp0, mask1, maskp1, dr0.Size()) {
+						t.Errorf("xform=%v, dot=%v, %q: masks differ", xform, dot, r)
+					}
+				}
+			}
+		}
+		// The last two dots share their glyphs.
+		if got, want := cache.Len(), 2*5; got != want {
+			t.Errorf("xform=%v: Len: got %d, want %d", xform, got, want)
+		}
+	}
+}
+
+// sameMask returns whether the size pixels of the masks a and b, at ap and bp,
+// are equal.
+func sameMask(a image.Image, ap image.Point, b image.Image, bp image.Point, size image.Point) bool {
+	aa, bb := a.(*image.Alpha), b.(*image.Alpha)
+	for y := 0; y < size.Y; y++ {
+		i, j := aa.PixOffset(ap.X, ap.Y+y), bb.PixOffset(bp.X, bp.Y+y)
+		if !bytes.Equal(aa.Pix[i:i+size.X], bb.Pix[j:j+size.X]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGlyphCacheShared(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	cache := NewGlyphCache(DefaultGlyphCacheSize)
+	var faces [3]font.Face
+	for i, size := range []float64{12, 12, 13} {
+		faces[i], err = NewFace(f, &FaceOptions{Size: size, DPI: 72, GlyphCache: cache})
+		if err != nil {
+			t.Fatalf("NewFace: %v", err)
+		}
+	}
+	dot := fixed.P(10, 20)
+	faces[0].Glyph(dot, 'A')
+	if _, mask, _, _, _ := faces[1].Glyph(dot, 'A'); mask == &faces[1].(*Face).mask {
+		t.Error("face of the same size: got its scratch mask, want the mask cached by the other face")
+	}
+	faces[2].Glyph(dot, 'A')
+	if got, want := cache.Len(), 2; got != want {
+		t.Errorf("Len: got %d, want %d", got, want)
+	}
+}
+
+func TestGlyphCacheBudget(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	const budget = 4 * (glyphEntryOverhead + 8*9)
+	cache := NewGlyphCache(budget)
+	face, err := NewFace(f, &FaceOptions{Size: 12, DPI: 72, GlyphCache: cache})
+	if err != nil {
+		t.Fatalf("NewFace: %v", err)
+	}
+	for _, r := range "The quick brown fox jumps over the lazy dog" {
+		face.Glyph(fixed.P(0, 0), r)
+		if got := cache.Size(); got > budget {
+			t.Fatalf("%q: Size: got %d, want at most %d", r, got, budget)
+		}
+	}
+	if cache.Len() == 0 {
+		t.Error("Len: got 0, want non-zero")
+	}
+	// The most recently drawn glyph is still cached, so its mask is not the
+	// Face's scratch mask.
+	if _, mask, _, _, _ := face.Glyph(fixed.P(0, 0), 'g'); mask == &face.(*Face).mask {
+		t.Error("most recent glyph: got the scratch mask, want a cached mask")
+	}
+}
+
+func BenchmarkFaceGlyphUncached(b *testing.B) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		b.Fatalf("Parse: %v", err)
+	}
+	face, err := NewFace(f, &FaceOptions{Size: 12, DPI: 72, GlyphCache: NewGlyphCache(0)})
+	if err != nil {
+		b.Fatalf("NewFace: %v", err)
+	}
+	fixedDot := fixed.P(200, 500)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, ok := face.Glyph(fixedDot, 'A'); !ok {
+			b.Fatal("could not get glyph")
+		}
+	}
+}
+
+func TestFaceMemStats(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	shared := NewGlyphCache(DefaultGlyphCacheSize)
+	for _, cache := range []*GlyphCache{nil, shared} {
+		face, err := NewFace(f, &FaceOptions{Size: 48, DPI: 72, GlyphCache: cache})
+		if err != nil {
+			t.Fatalf("NewFace: %v", err)
+		}
+		ff := face.(*Face)
+		_, mask, maskp0, _, _ := face.Glyph(fixed.P(0, 0), 'W')
+		// The mask may be the face's scratch mask, which TrimCaches releases.
+		a := mask.(*image.Alpha)
+		mask0 := &image.Alpha{Pix: append([]uint8(nil), a.Pix...), Stride: a.Stride, Rect: a.Rect}
+		s := ff.MemStats()
+		if s.Buffers < 48*48 {
+			t.Errorf("cache=%p: Buffers: got %d, want at least %d", cache, s.Buffers, 48*48)
+		}
+		if cache == nil && s.Caches == 0 {
+			t.Errorf("own cache: Caches: got 0, want non-zero")
+		} else if cache != nil && s.Caches != 0 {
+			t.Errorf("shared cache: Caches: got %d, want 0", s.Caches)
+		}
+
+		ff.TrimCaches()
+		if got := ff.MemStats(); got.Total() >= s.Total() || got.Caches != 0 {
+			t.Errorf("cache=%p: after TrimCaches: got %+v, before %+v", cache, got, s)
+		}
+		if cache != nil && cache.Len() == 0 {
+			t.Error("TrimCaches emptied a shared cache")
+		}
+		dr, mask1, maskp1, _, ok := face.Glyph(fixed.P(0, 0), 'W')
+		if !ok || !sameMask(mask0, maskp0, mask1, maskp1, dr.Size()) {
+			t.Errorf("cache=%p: after TrimCaches: Glyph differs", cache)
+		}
+	}
+	shared.Clear()
+	if shared.Len() != 0 || shared.Size() != 0 {
+		t.Errorf("after Clear: Len, Size: got %d, %d, want 0, 0", shared.Len(), shared.Size())
+	}
+}
@@ -12,14 +12,25 @@
 package opentype // import "golang.org/x/image/font/opentype"
 
 import (
+	"bytes"
 	"image"
+	"image/color"
 	"image/draw"
+	"image/png"
 	"io"
+	"math"
+	"reflect"
+	"unicode"
+	"unsafe"
 
+	xdraw "golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/math/f64"
 	"golang.org/x/image/math/fixed"
 	"golang.org/x/image/vector"
+	"golang.org/x/text/unicode/rangetable"
 )
 
 // ParseCollection parses an OpenType font collection, such as TTC or OTC data,
@@ -66,12 +77,99 @@ func ParseReaderAt(src io.ReaderAt) (*Font, error) {
 // call concurrently.
 type Font = sfnt.Font
 
+// GlyphSource is a source of glyph outlines and metrics, which a Face
+// rasterizes and caches. *Font implements GlyphSource, but so can other
+// sources, such as the instances of a variable font, fonts whose outlines are
+// modified by stroking or emboldening, or procedurally generated glyphs.
+//
+// The methods have the same semantics as the *Font methods of the same name.
+// In particular, the Segments returned by LoadGlyph only need to remain valid
+// until the *sfnt.Buffer is next used.
+type GlyphSource interface {
+	GlyphIndex(b *sfnt.Buffer, r rune) (sfnt.GlyphIndex, error)
+	GlyphAdvance(b *sfnt.Buffer, x sfnt.GlyphIndex, ppem fixed.Int26_6, h font.Hinting) (fixed.Int26_6, error)
+	LoadGlyph(b *sfnt.Buffer, x sfnt.GlyphIndex, ppem fixed.Int26_6, opts *sfnt.LoadGlyphOptions) (sfnt.Segments, error)
+	Kern(b *sfnt.Buffer, x0, x1 sfnt.GlyphIndex, ppem fixed.Int26_6, h font.Hinting) (fixed.Int26_6, error)
+	Metrics(b *sfnt.Buffer, ppem fixed.Int26_6, h font.Hinting) (font.Metrics, error)
+}
+
+// ColorGlyphSource is a GlyphSource that also has color glyphs, made of
+// layers of other glyphs, each filled with a color. *Font implements
+// ColorGlyphSource. A Face's ColorGlyph method only returns color glyphs if
+// its source implements ColorGlyphSource.
+type ColorGlyphSource interface {
+	GlyphSource
+	ColorLayers(b *sfnt.Buffer, dst []sfnt.ColorLayer, x sfnt.GlyphIndex) ([]sfnt.ColorLayer, error)
+	PaletteColor(b *sfnt.Buffer, palette int, index uint16) (color.NRGBA, error)
+}
+
+// BitmapGlyphSource is a GlyphSource that also has glyphs that are embedded
+// bitmap images, such as those of the sbix and CBDT tables. *Font implements
+// BitmapGlyphSource. A Face's ColorGlyph method only returns bitmap glyphs if
+// its source implements BitmapGlyphSource.
+type BitmapGlyphSource interface {
+	GlyphSource
+	GlyphBitmap(b *sfnt.Buffer, x sfnt.GlyphIndex, ppem int) (sfnt.GlyphBitmap, error)
+}
+
+var (
+	_ ColorGlyphSource  = (*Font)(nil)
+	_ BitmapGlyphSource = (*Font)(nil)
+)
+
 // FaceOptions describes the possible options given to NewFace when
 // creating a new font.Face from a Font.
 type FaceOptions struct {
 	Size    float64      // Size is the font size in points
 	DPI     float64      // DPI is the dots per inch resolution
 	Hinting font.Hinting // Hinting selects how to quantize a vector font's glyph nodes
+
+	// XDPI and YDPI, if non-zero, override DPI for the horizontal and
+	// vertical resolution respectively, for devices with non-square pixels.
+	// Horizontal quantities, such as advances, kerning and the glyphs' widths,
+	// are scaled by the horizontal resolution. Vertical quantities, such as
+	// the Metrics and the glyphs' heights, are scaled by the vertical
+	// resolution.
+	XDPI float64
+	YDPI float64
+
+	// Transform, if non-nil, is applied to the glyph outlines, in pixel space
+	// (with the Y axis increasing down and the origin at the dot), before they
+	// are rasterized. Only the 2x2 linear part is used: the translation
+	// elements Transform[2] and Transform[5] are ignored.
+	//
+	// Rasterizing pre-transformed outlines, e.g. for rotated or sheared text,
+	// gives better quality than transforming the rasterized masks. The
+	// transform affects the Glyph and GlyphBounds methods, but not advances,
+	// kerning or Metrics: positioning the dot along a rotated baseline is the
+	// caller's responsibility.
+	Transform *f64.Aff3
+
+	// Palette selects the CPAL palette that color glyphs are drawn with. The
+	// default, zero, is the font's default palette.
+	Palette int
+
+	// Variations, if non-empty, select the instance of a variable font, such
+	// as {{"wght", 650}}, that NewFace uses. Axes that are not given take
+	// their default values. See the sfnt.Font Instance method for details.
+	// NewFace returns an error if Variations is non-empty and the font is not
+	// a variable font. NewSourceFace ignores Variations.
+	Variations []sfnt.Variation
+
+	// GlyphCache, if non-nil, is the cache of rasterized glyph masks that the
+	// Face uses, which may be shared with other Faces, such as the other
+	// sizes and styles of a document's text. If nil, the Face has a cache of
+	// its own, of DefaultGlyphCacheSize bytes. NewGlyphCache(0) disables
+	// caching. Color glyphs are not cached, and nor are the glyphs of a
+	// GlyphSource whose dynamic type is not comparable.
+	GlyphCache *GlyphCache
+
+	// Subpixel, if its Layout is not font.SubpixelNone, selects subpixel
+	// antialiasing: the Glyph method returns a *font.SubpixelMask, one pixel
+	// wider (or higher, for a vertical layout) on either side of the glyph
+	// for the filter, which a font.Drawer whose Subpixel field is true draws
+	// with each color masked separately.
+	Subpixel font.Subpixel
 }
 
 func defaultFaceOptions() *FaceOptions {
@@ -82,33 +180,134 @@ func defaultFaceOptions() *FaceOptions {
 	}
 }
 
-// Face implements the font.Face interface for Font values.
+// Face implements the font.Face interface for Font values, or any other
+// GlyphSource. It also implements the font.ColorFace interface, for fonts
+// with color glyphs, such as many emoji fonts. Color glyphs are either layers
+// of outlines, defined by the COLR and CPAL tables, or PNG images, defined by
+// the sbix or CBDT table, which are scaled to the face's size. The Transform
+// option does not apply to bitmap glyphs.
+//
+// Face also implements the font.CoverageFace interface, so that it can be
+// one of the faces of a font.MultiFace. Runes that the source maps to the
+// .notdef glyph, glyph 0, are not covered.
 //
-// A Face is not safe to use concurrently.
+// A Face is not safe to use concurrently. See font.NewSafeFace for sharing
+// faces of the same Font between goroutines.
 type Face struct {
-	f       *Font
+	f GlyphSource
+	// cf is f as a ColorGlyphSource, or nil if f has no color glyphs.
+	cf ColorGlyphSource
+	// bf is f as a BitmapGlyphSource, or nil if f has no bitmap glyphs.
+	bf      BitmapGlyphSource
 	hinting font.Hinting
-	scale   fixed.Int26_6
+	scale   fixed.Int26_6 // The vertical ppem.
+	xScale  fixed.Int26_6 // The horizontal ppem.
+
+	// xform is the transform applied to glyph outlines. It is only used if
+	// hasXform is true, i.e. if xScale != scale or a Transform was given.
+	xform    f32.Aff3
+	hasXform bool
+
+	// loadOpts are the options for loading glyph outlines. They ask for
+	// hinted outlines unless hasXform, as hinting instructions assume an
+	// untransformed pixel grid.
+	loadOpts sfnt.LoadGlyphOptions
 
 	metrics    font.Metrics
 	metricsSet bool
 
+	palette  int
+	subpixel font.Subpixel
+
+	// cache is the cache of glyph masks, or nil if f.f is not comparable.
+	// ownCache is whether the face created it, rather than its FaceOptions.
+	cache    *GlyphCache
+	ownCache bool
+
 	buf  sfnt.Buffer
+	path vector.Path
 	rast vector.Rasterizer
 	mask image.Alpha
+	// lcd is the mask returned by Glyph for subpixel antialiasing.
+	lcd font.SubpixelMask
+
+	// layers, layerEnds, color and uniform are used by ColorGlyph.
+	layers    []sfnt.ColorLayer
+	layerEnds []int
+	color     image.RGBA
+	uniform   image.Uniform
 }
 
 // NewFace returns a new font.Face for the given Font.
 //
 // If opts is nil, sensible defaults will be used.
 func NewFace(f *Font, opts *FaceOptions) (font.Face, error) {
+	if opts != nil && len(opts.Variations) != 0 {
+		g, err := f.Instance(opts.Variations)
+		if err != nil {
+			return nil, err
+		}
+		f = g
+	}
+	return NewSourceFace(f, opts)
+}
+
+// NewSourceFace returns a new font.Face for the given GlyphSource, which
+// rasterizes the source's glyph outlines as NewFace does those of a Font.
+//
+// If opts is nil, sensible defaults will be used.
+func NewSourceFace(f GlyphSource, opts *FaceOptions) (font.Face, error) {
 	if opts == nil {
 		opts = defaultFaceOptions()
 	}
+	xDPI, yDPI := opts.XDPI, opts.YDPI
+	if xDPI == 0 {
+		xDPI = opts.DPI
+	}
+	if yDPI == 0 {
+		yDPI = opts.DPI
+	}
 	face := &Face{
 		f:       f,
 		hinting: opts.Hinting,
-		scale:   fixed.Int26_6(0.5 + (opts.Size * opts.DPI * 64 / 72)),
+		scale:   fixed.Int26_6(0.5 + (opts.Size * yDPI * 64 / 72)),
+		xScale:  fixed.Int26_6(0.5 + (opts.Size * xDPI * 64 / 72)),
+		palette: opts.Palette,
+	}
+	if opts.Subpixel.Layout != font.SubpixelNone {
+		face.subpixel = opts.Subpixel
+		if face.subpixel.Filter == nil {
+			face.subpixel.Filter = &font.LCDFilterDefault
+		}
+	}
+	if reflect.TypeOf(f).Comparable() {
+		face.cache = opts.GlyphCache
+		if face.cache == nil {
+			face.cache = NewGlyphCache(DefaultGlyphCacheSize)
+			face.ownCache = true
+		}
+	}
+	face.cf, _ = f.(ColorGlyphSource)
+	face.bf, _ = f.(BitmapGlyphSource)
+
+	// Glyph outlines are loaded at the vertical scale. Stretch them
+	// horizontally if the horizontal scale differs, then apply the device
+	// transform, if any.
+	sx := 1.0
+	if xDPI != yDPI && yDPI != 0 {
+		sx = xDPI / yDPI
+	}
+	m := f64.Aff3{sx, 0, 0, 0, 1, 0}
+	if t := opts.Transform; t != nil {
+		m = f64.Aff3{t[0] * sx, t[1], 0, t[3] * sx, t[4], 0}
+	}
+	if m != (f64.Aff3{1, 0, 0, 0, 1, 0}) {
+		face.hasXform = true
+		for i, v := range m {
+			face.xform[i] = float32(v)
+		}
+	} else {
+		face.loadOpts.Hinting = opts.Hinting
 	}
 	return face, nil
 }
@@ -118,6 +317,42 @@ func (f *Face) Close() error {
 	return nil
 }
 
+// MemStats returns an estimate of the memory that f retains: its scratch
+// buffers and, if it has a glyph cache of its own, that cache. The memory of
+// its Font, which may be shared by many Faces, is not included, and nor is
+// that of a GlyphCache given in its FaceOptions.
+func (f *Face) MemStats() sfnt.MemStats {
+	s := f.buf.MemStats()
+	s.Buffers += cap(f.path) * 4
+	s.Buffers += cap(f.mask.Pix) + cap(f.lcd.Pix) + cap(f.color.Pix)
+	// The rasterizer has an area buffer and an accumulated mask buffer, each
+	// of 4 bytes per pixel.
+	size := f.rast.Size()
+	s.Buffers += 8 * size.X * size.Y
+	s.Buffers += cap(f.layers)*int(unsafe.Sizeof(sfnt.ColorLayer{})) + cap(f.layerEnds)*int(unsafe.Sizeof(0))
+	if f.ownCache {
+		s.Caches = f.cache.Size()
+	}
+	return s
+}
+
+// TrimCaches releases f's scratch buffers, which grow to hold the largest
+// glyph drawn so far, and empties its glyph cache if it has one of its own. A
+// GlyphCache given in its FaceOptions is left as is: call its Clear method to
+// empty it. f remains usable.
+func (f *Face) TrimCaches() {
+	f.buf.Trim()
+	f.path = nil
+	f.rast = vector.Rasterizer{}
+	f.mask = image.Alpha{}
+	f.lcd = font.SubpixelMask{}
+	f.layers, f.layerEnds = nil, nil
+	f.color = image.RGBA{}
+	if f.ownCache {
+		f.cache.Clear()
+	}
+}
+
 // Metrics satisfies the font.Face interface.
 func (f *Face) Metrics() font.Metrics {
 	if !f.metricsSet {
@@ -135,7 +370,7 @@ func (f *Face) Metrics() font.Metrics {
 func (f *Face) Kern(r0, r1 rune) fixed.Int26_6 {
 	x0 := f.index(r0)
 	x1 := f.index(r1)
-	k, err := f.f.Kern(&f.buf, x0, x1, fixed.Int26_6(f.f.UnitsPerEm()), f.hinting)
+	k, err := f.f.Kern(&f.buf, x0, x1, f.xScale, f.hinting)
 	if err != nil {
 		return 0
 	}
@@ -148,20 +383,39 @@ func (f *Face) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask imag
 	if err != nil {
 		return image.Rectangle{}, nil, image.Point{}, 0, false
 	}
+	if dr, mask, advance, ok := f.cachedGlyph(x, dot); ok {
+		return dr, mask, mask.Bounds().Min, advance, true
+	}
 
 	// Call f.f.GlyphAdvance before f.f.LoadGlyph because the LoadGlyph docs
 	// say this about the &f.buf argument: the segments become invalid to use
 	// once [the buffer] is re-used.
 
-	advance, err = f.f.GlyphAdvance(&f.buf, x, f.scale, f.hinting)
+	advance, err = f.f.GlyphAdvance(&f.buf, x, f.xScale, f.hinting)
 	if err != nil {
 		return image.Rectangle{}, nil, image.Point{}, 0, false
 	}
 
-	segments, err := f.f.LoadGlyph(&f.buf, x, f.scale, nil)
+	segments, err := f.f.LoadGlyph(&f.buf, x, f.scale, &f.loadOpts)
 	if err != nil {
 		return image.Rectangle{}, nil, image.Point{}, 0, false
 	}
+	if f.subpixel.Layout != font.SubpixelNone {
+		dr, ok = f.subpixelGlyph(dot, segments)
+		if !ok {
+			return image.Rectangle{}, nil, image.Point{}, 0, false
+		}
+		f.cacheGlyph(x, dot, dr, advance)
+		return dr, &f.lcd, f.lcd.Rect.Min, advance, true
+	}
+	if f.hasXform {
+		dr, ok = f.transformedGlyph(dot, segments)
+		if !ok {
+			return image.Rectangle{}, nil, image.Point{}, 0, false
+		}
+		f.cacheGlyph(x, dot, dr, advance)
+		return dr, &f.mask, f.mask.Rect.Min, advance, true
+	}
 
 	// Numerical notation used below:
 	//  - 2    is an integer, "two"
@@ -177,10 +431,7 @@ func (f *Face) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask imag
 	dBounds := segments.Bounds().Add(dot)
 
 	// Quantize the sub-pixel bounds (dBounds) to integer-pixel bounds (dr).
-	dr.Min.X = dBounds.Min.X.Floor()
-	dr.Min.Y = dBounds.Min.Y.Floor()
-	dr.Max.X = dBounds.Max.X.Ceil()
-	dr.Max.Y = dBounds.Max.Y.Ceil()
+	dr = dBounds.Cover()
 	width := dr.Dx()
 	height := dr.Dy()
 	if width < 0 || height < 0 {
@@ -204,6 +455,211 @@ func (f *Face) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask imag
 	biasX := dot.X - fixed.Int26_6(dr.Min.X<<6)
 	biasY := dot.Y - fixed.Int26_6(dr.Min.Y<<6)
 
+	// Rasterize the biased segments, converting from fixed.Int26_6 to float32.
+	f.path = AppendPath(f.path[:0], segments, fixed.Point26_6{X: biasX, Y: biasY})
+	f.rasterize(f.path, width, height)
+	f.cacheGlyph(x, dot, dr, advance)
+
+	return dr, &f.mask, f.mask.Rect.Min, advance, true
+}
+
+// transformedGlyph is like Glyph, for faces with a non-identity xform. It
+// rasterizes the transformed segments into f.mask and returns the dst-space
+// rectangle that the mask covers.
+func (f *Face) transformedGlyph(dot fixed.Point26_6, segments sfnt.Segments) (dr image.Rectangle, ok bool) {
+	// Transform the glyph-space path, about the glyph origin, and quantize
+	// its dst-space bounds to integer pixels.
+	f.path = AppendPath(f.path[:0], segments, fixed.Point26_6{})
+	f.path.Transform(f.xform)
+	minX, minY, maxX, maxY := f.path.Bounds()
+	dotX, dotY := float32(dot.X)/64, float32(dot.Y)/64
+	dr.Min.X = int(math.Floor(float64(minX + dotX)))
+	dr.Min.Y = int(math.Floor(float64(minY + dotY)))
+	dr.Max.X = int(math.Ceil(float64(maxX + dotX)))
+	dr.Max.Y = int(math.Ceil(float64(maxY + dotY)))
+	width := dr.Dx()
+	height := dr.Dy()
+	if width < 0 || height < 0 {
+		return image.Rectangle{}, false
+	}
+
+	// Translate from glyph space to rasterizer space, as for the
+	// untransformed case in Glyph.
+	f.path.Transform(f32.Aff3{
+		1, 0, dotX - float32(dr.Min.X),
+		0, 1, dotY - float32(dr.Min.Y),
+	})
+	f.rasterize(f.path, width, height)
+	return dr, true
+}
+
+// subpixelGlyph is like transformedGlyph, for faces with subpixel
+// antialiasing. It rasterizes the segments at three times the resolution along
+// the subpixel axis into f.mask, and filters that into f.lcd.
+func (f *Face) subpixelGlyph(dot fixed.Point26_6, segments sfnt.Segments) (dr image.Rectangle, ok bool) {
+	f.path = AppendPath(f.path[:0], segments, fixed.Point26_6{})
+	if f.hasXform {
+		f.path.Transform(f.xform)
+	}
+	minX, minY, maxX, maxY := f.path.Bounds()
+	dotX, dotY := float32(dot.X)/64, float32(dot.Y)/64
+	dr.Min.X = int(math.Floor(float64(minX + dotX)))
+	dr.Min.Y = int(math.Floor(float64(minY + dotY)))
+	dr.Max.X = int(math.Ceil(float64(maxX + dotX)))
+	dr.Max.Y = int(math.Ceil(float64(maxY + dotY)))
+	if dr.Dx() < 0 || dr.Dy() < 0 {
+		return image.Rectangle{}, false
+	}
+
+	// The filter spreads the coverage of the glyph's outermost subpixels
+	// into the neighboring pixels.
+	sx, sy := float32(3), float32(1)
+	if f.subpixel.Layout.Vertical() {
+		sx, sy = 1, 3
+		dr.Min.Y--
+		dr.Max.Y++
+	} else {
+		dr.Min.X--
+		dr.Max.X++
+	}
+	f.path.Transform(f32.Aff3{
+		sx, 0, sx * (dotX - float32(dr.Min.X)),
+		0, sy, sy * (dotY - float32(dr.Min.Y)),
+	})
+	f.rasterize(f.path, dr.Dx()*int(sx), dr.Dy()*int(sy))
+	f.lcd.SetFromAlpha(&f.mask, f.subpixel)
+	return dr, true
+}
+
+// ColorGlyph satisfies the font.ColorFace interface.
+func (f *Face) ColorGlyph(dot fixed.Point26_6, r rune, fg color.Color) (dr image.Rectangle, src image.Image, sp image.Point, advance fixed.Int26_6, ok bool) {
+	if f.cf == nil && f.bf == nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	x, err := f.f.GlyphIndex(&f.buf, r)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	err = sfnt.ErrNotFound
+	if f.cf != nil {
+		f.layers, err = f.cf.ColorLayers(&f.buf, f.layers[:0], x)
+	}
+	if err == sfnt.ErrNotFound && f.bf != nil {
+		return f.bitmapGlyph(dot, x)
+	}
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	advance, err = f.f.GlyphAdvance(&f.buf, x, f.xScale, f.hinting)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+
+	// Append every layer's outline to f.path, recording where each one ends,
+	// so that the union of their bounds gives dr.
+	f.path = f.path[:0]
+	f.layerEnds = f.layerEnds[:0]
+	for _, l := range f.layers {
+		segments, err := f.f.LoadGlyph(&f.buf, l.Glyph, f.scale, &f.loadOpts)
+		if err != nil {
+			return image.Rectangle{}, nil, image.Point{}, 0, false
+		}
+		f.path = AppendPath(f.path, segments, fixed.Point26_6{})
+		f.layerEnds = append(f.layerEnds, len(f.path))
+	}
+	if f.hasXform {
+		f.path.Transform(f.xform)
+	}
+	minX, minY, maxX, maxY := f.path.Bounds()
+	dotX, dotY := float32(dot.X)/64, float32(dot.Y)/64
+	dr.Min.X = int(math.Floor(float64(minX + dotX)))
+	dr.Min.Y = int(math.Floor(float64(minY + dotY)))
+	dr.Max.X = int(math.Ceil(float64(maxX + dotX)))
+	dr.Max.Y = int(math.Ceil(float64(maxY + dotY)))
+	width := dr.Dx()
+	height := dr.Dy()
+	if width < 0 || height < 0 {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	f.path.Transform(f32.Aff3{
+		1, 0, dotX - float32(dr.Min.X),
+		0, 1, dotY - float32(dr.Min.Y),
+	})
+
+	f.clearColor(width, height)
+
+	// Composite the layers, from bottom to top, each filled with its color.
+	start := 0
+	for i, l := range f.layers {
+		end := f.layerEnds[i]
+		f.uniform.C = fg
+		if l.PaletteIndex != sfnt.ForegroundPaletteIndex {
+			f.uniform.C, err = f.cf.PaletteColor(&f.buf, f.palette, l.PaletteIndex)
+			if err != nil {
+				return image.Rectangle{}, nil, image.Point{}, 0, false
+			}
+		}
+		f.rasterize(f.path[start:end], width, height)
+		draw.DrawMask(&f.color, f.color.Rect, &f.uniform, image.Point{}, &f.mask, image.Point{}, draw.Over)
+		start = end
+	}
+	return dr, &f.color, image.Point{}, advance, true
+}
+
+// bitmapGlyph is like ColorGlyph, for the x'th glyph's embedded bitmap image,
+// which it scales from the image's size to the face's size.
+func (f *Face) bitmapGlyph(dot fixed.Point26_6, x sfnt.GlyphIndex) (dr image.Rectangle, src image.Image, sp image.Point, advance fixed.Int26_6, ok bool) {
+	advance, err := f.f.GlyphAdvance(&f.buf, x, f.xScale, f.hinting)
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	g, err := f.bf.GlyphBitmap(&f.buf, x, f.scale.Round())
+	if err != nil || g.Format != "png " || g.PPEM <= 0 {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	m, err := png.Decode(bytes.NewReader(g.Data))
+	if err != nil {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+
+	// The image's bottom left corner is at (Left, Bottom) relative to the
+	// glyph origin, in pixels at the image's ppem, with Y increasing up.
+	sx := float64(f.xScale) / 64 / float64(g.PPEM)
+	sy := float64(f.scale) / 64 / float64(g.PPEM)
+	mb := m.Bounds()
+	dotX, dotY := float64(dot.X)/64, float64(dot.Y)/64
+	dr.Min.X = int(math.Round(dotX + float64(g.Left)*sx))
+	dr.Max.X = int(math.Round(dotX + float64(g.Left+mb.Dx())*sx))
+	dr.Min.Y = int(math.Round(dotY - float64(g.Bottom+mb.Dy())*sy))
+	dr.Max.Y = int(math.Round(dotY - float64(g.Bottom)*sy))
+	width := dr.Dx()
+	height := dr.Dy()
+	if width <= 0 || height <= 0 {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	f.clearColor(width, height)
+	xdraw.BiLinear.Scale(&f.color, f.color.Rect, m, mb, xdraw.Src, nil)
+	return dr, &f.color, image.Point{}, advance, true
+}
+
+// clearColor re-configures f.color to be width by height transparent pixels,
+// re-allocating its buffer if necessary.
+func (f *Face) clearColor(width, height int) {
+	nBytes := 4 * width * height
+	if cap(f.color.Pix) < nBytes {
+		f.color.Pix = make([]uint8, 2*nBytes)
+	}
+	f.color.Pix = f.color.Pix[:nBytes]
+	for i := range f.color.Pix {
+		f.color.Pix[i] = 0
+	}
+	f.color.Stride = 4 * width
+	f.color.Rect = image.Rectangle{Max: image.Point{width, height}}
+}
+
+// rasterize rasterizes p into f.mask, which is re-configured to be width by
+// height pixels.
+func (f *Face) rasterize(p vector.Path, width, height int) {
 	// Configure the mask image, re-allocating its buffer if necessary.
 	nPixels := width * height
 	if cap(f.mask.Pix) < nPixels {
@@ -216,57 +672,116 @@ func (f *Face) Glyph(dot fixed.Point26_6, r rune) (dr image.Rectangle, mask imag
 	f.mask.Rect.Max.X = width
 	f.mask.Rect.Max.Y = height
 
-	// Rasterize the biased segments, converting from fixed.Int26_6 to float32.
 	f.rast.Reset(width, height)
 	f.rast.DrawOp = draw.Src
-	for _, seg := range segments {
-		switch seg.Op {
-		case sfnt.SegmentOpMoveTo:
-			f.rast.MoveTo(
-				float32(seg.Args[0].X+biasX)/64,
-				float32(seg.Args[0].Y+biasY)/64,
-			)
-		case sfnt.SegmentOpLineTo:
-			f.rast.LineTo(
-				float32(seg.Args[0].X+biasX)/64,
-				float32(seg.Args[0].Y+biasY)/64,
-			)
-		case sfnt.SegmentOpQuadTo:
-			f.rast.QuadTo(
-				float32(seg.Args[0].X+biasX)/64,
-				float32(seg.Args[0].Y+biasY)/64,
-				float32(seg.Args[1].X+biasX)/64,
-				float32(seg.Args[1].Y+biasY)/64,
-			)
-		case sfnt.SegmentOpCubeTo:
-			f.rast.CubeTo(
-				float32(seg.Args[0].X+biasX)/64,
-				float32(seg.Args[0].Y+biasY)/64,
-				float32(seg.Args[1].X+biasX)/64,
-				float32(seg.Args[1].Y+biasY)/64,
-				float32(seg.Args[2].X+biasX)/64,
-				float32(seg.Args[2].Y+biasY)/64,
-			)
-		}
-	}
+	p.AddTo(&f.rast)
 	f.rast.Draw(&f.mask, f.mask.Bounds(), image.Opaque, image.Point{})
-
-	return dr, &f.mask, f.mask.Rect.Min, advance, true
 }
 
 // GlyphBounds satisfies the font.Face interface.
 func (f *Face) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
-	bounds, advance, err := f.f.GlyphBounds(&f.buf, f.index(r), f.scale, f.hinting)
-	return bounds, advance, err == nil
+	x := f.index(r)
+	advance, err := f.f.GlyphAdvance(&f.buf, x, f.xScale, f.hinting)
+	if err != nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	segments, err := f.f.LoadGlyph(&f.buf, x, f.scale, &f.loadOpts)
+	if err != nil {
+		return fixed.Rectangle26_6{}, 0, false
+	}
+	if !f.hasXform {
+		return segments.Bounds(), advance, true
+	}
+	f.path = AppendPath(f.path[:0], segments, fixed.Point26_6{})
+	f.path.Transform(f.xform)
+	minX, minY, maxX, maxY := f.path.Bounds()
+	bounds.Min.X = fixed.Int26_6(math.Floor(float64(minX) * 64))
+	bounds.Min.Y = fixed.Int26_6(math.Floor(float64(minY) * 64))
+	bounds.Max.X = fixed.Int26_6(math.Ceil(float64(maxX) * 64))
+	bounds.Max.Y = fixed.Int26_6(math.Ceil(float64(maxY) * 64))
+	return bounds, advance, true
 }
 
 // GlyphAdvance satisfies the font.Face interface.
 func (f *Face) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
-	advance, err := f.f.GlyphAdvance(&f.buf, f.index(r), f.scale, f.hinting)
+	advance, err := f.f.GlyphAdvance(&f.buf, f.index(r), f.xScale, f.hinting)
 	return advance, err == nil
 }
 
+// HasGlyph satisfies the font.CoverageFace interface.
+func (f *Face) HasGlyph(r rune) bool {
+	return f.index(r) != 0
+}
+
+// Coverage satisfies the font.CoverageFace interface. If the GlyphSource has
+// a CmapEntries method, like *Font does, the runes are those of its cmap
+// entries. Otherwise, Coverage calls GlyphIndex for every valid rune, which is
+// slow.
+func (f *Face) Coverage() *unicode.RangeTable {
+	type cmapSource interface {
+		CmapEntries(b *sfnt.Buffer, dst []sfnt.CmapEntry) ([]sfnt.CmapEntry, error)
+	}
+	var runes []rune
+	if cs, ok := f.f.(cmapSource); ok {
+		entries, err := cs.CmapEntries(&f.buf, nil)
+		if err != nil {
+			return &unicode.RangeTable{}
+		}
+		runes = make([]rune, len(entries))
+		for i, e := range entries {
+			runes[i] = e.Rune
+		}
+	} else {
+		for r := rune(0); r <= unicode.MaxRune; r++ {
+			if (r < 0xd800 || 0xe000 <= r) && f.HasGlyph(r) {
+				runes = append(runes, r)
+			}
+		}
+	}
+	return rangetable.New(runes...)
+}
+
 func (f *Face) index(r rune) sfnt.GlyphIndex {
 	x, _ := f.f.GlyphIndex(&f.buf, r)
 	return x
 }
+
+// AppendPath appends the vector path equivalent of s, translated by dot, to p
+// and returns the extended path. The fixed.Int26_6 coordinates are converted
+// to float32 pixel coordinates, so that the result can be added to a
+// vector.Rasterizer, transformed or otherwise inspected.
+//
+// The Segments for a glyph can be obtained by Font.LoadGlyph.
+func AppendPath(p vector.Path, s sfnt.Segments, dot fixed.Point26_6) vector.Path {
+	for _, seg := range s {
+		switch seg.Op {
+		case sfnt.SegmentOpMoveTo:
+			p.MoveTo(
+				float32(seg.Args[0].X+dot.X)/64,
+				float32(seg.Args[0].Y+dot.Y)/64,
+			)
+		case sfnt.SegmentOpLineTo:
+			p.LineTo(
+				float32(seg.Args[0].X+dot.X)/64,
+				float32(seg.Args[0].Y+dot.Y)/64,
+			)
+		case sfnt.SegmentOpQuadTo:
+			p.QuadTo(
+				float32(seg.Args[0].X+dot.X)/64,
+				float32(seg.Args[0].Y+dot.Y)/64,
+				float32(seg.Args[1].X+dot.X)/64,
+				float32(seg.Args[1].Y+dot.Y)/64,
+			)
+		case sfnt.SegmentOpCubeTo:
+			p.CubeTo(
+				float32(seg.Args[0].X+dot.X)/64,
+				float32(seg.Args[0].Y+dot.Y)/64,
+				float32(seg.Args[1].X+dot.X)/64,
+				float32(seg.Args[1].Y+dot.Y)/64,
+				float32(seg.Args[2].X+dot.X)/64,
+				float32(seg.Args[2].Y+dot.Y)/64,
+			)
+		}
+	}
+	return p
+}
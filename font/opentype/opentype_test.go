@@ -5,12 +5,20 @@
 package opentype
 
 import (
+	"bytes"
+	"encoding/binary"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"sort"
 	"testing"
+	"unicode"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f64"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -109,6 +117,68 @@ func TestFaceGlyph(t *testing.T) {
 	}
 }
 
+func TestFaceTransform(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	testCases := []struct {
+		desc    string
+		opts    FaceOptions
+		advance fixed.Int26_6
+		dr      image.Rectangle
+	}{{
+		desc:    "identity",
+		opts:    FaceOptions{Size: 12, DPI: 72, Transform: &f64.Aff3{1, 0, 5, 0, 1, 5}},
+		advance: 512,
+		dr:      image.Rect(0, -9, 8, 0),
+	}, {
+		desc:    "double XDPI",
+		opts:    FaceOptions{Size: 12, DPI: 72, XDPI: 144},
+		advance: 1025,
+		dr:      image.Rect(0, -9, 16, 0),
+	}, {
+		desc:    "rotate 90",
+		opts:    FaceOptions{Size: 12, DPI: 72, Transform: &f64.Aff3{0, -1, 0, 1, 0, 0}},
+		advance: 512,
+		dr:      image.Rect(0, 0, 9, 8),
+	}}
+
+	dot := image.Pt(200, 500)
+	for _, tc := range testCases {
+		face, err := NewFace(f, &tc.opts)
+		if err != nil {
+			t.Errorf("%s: NewFace: %v", tc.desc, err)
+			continue
+		}
+		dr, mask, maskp, advance, ok := face.Glyph(fixed.P(dot.X, dot.Y), 'A')
+		if !ok {
+			t.Errorf("%s: could not get glyph", tc.desc)
+			continue
+		}
+		if got, want := dr, tc.dr.Add(dot); got != want {
+			t.Errorf("%s: glyph draw rectangle=%d. want=%d", tc.desc, got, want)
+		}
+		if got, want := mask.Bounds().Sub(maskp).Size(), dr.Size(); got != want {
+			t.Errorf("%s: glyph mask size=%d. want=%d", tc.desc, got, want)
+		}
+		if advance != tc.advance {
+			t.Errorf("%s: glyph advance width=%d. want=%d", tc.desc, advance, tc.advance)
+		}
+		bounds, _, ok := face.GlyphBounds('A')
+		if !ok {
+			t.Errorf("%s: could not get glyph bounds", tc.desc)
+			continue
+		}
+		if got, want := bounds.Min.X.Floor(), tc.dr.Min.X; got != want {
+			t.Errorf("%s: glyph bounds min x=%d. want=%d", tc.desc, got, want)
+		}
+		if got, want := bounds.Max.Y.Ceil(), tc.dr.Max.Y; got != want {
+			t.Errorf("%s: glyph bounds max y=%d. want=%d", tc.desc, got, want)
+		}
+	}
+}
+
 func BenchmarkFaceGlyph(b *testing.B) {
 	fixedDot := fixed.P(200, 500)
 	r := 'A'
@@ -150,6 +220,41 @@ func TestFaceKern(t *testing.T) {
 	}
 }
 
+// kernSource is a boxSource that kerns every pair of glyphs by a quarter of
+// an em. Like a *Font, it also has units per em, which are not the scale to
+// kern at.
+type kernSource struct{ boxSource }
+
+func (kernSource) UnitsPerEm() sfnt.Units { return 2048 }
+
+func (kernSource) Kern(b *sfnt.Buffer, x0, x1 sfnt.GlyphIndex, ppem fixed.Int26_6, h font.Hinting) (fixed.Int26_6, error) {
+	return -ppem / 4, nil
+}
+
+func TestFaceKernScale(t *testing.T) {
+	for _, opts := range []FaceOptions{
+		{Size: 12, DPI: 72},
+		{Size: 24, DPI: 72},
+		{Size: 12, DPI: 144},
+		{Size: 12, DPI: 72, XDPI: 216},
+		{Size: 12, DPI: 72, YDPI: 216},
+	} {
+		face, err := NewSourceFace(kernSource{}, &opts)
+		if err != nil {
+			t.Fatalf("NewSourceFace: %v", err)
+		}
+		xDPI := opts.DPI
+		if opts.XDPI != 0 {
+			xDPI = opts.XDPI
+		}
+		want := -fixed.Int26_6(0.5+opts.Size*xDPI*64/72) / 4
+		if got := face.Kern('A', 'V'); got != want {
+			t.Errorf("Size %v, DPI %v, XDPI %v, YDPI %v: got %v, want %v",
+				opts.Size, opts.DPI, opts.XDPI, opts.YDPI, got, want)
+		}
+	}
+}
+
 func TestFaceMetrics(t *testing.T) {
 	want := font.Metrics{Height: 888, Ascent: 726, Descent: 162, XHeight: 407, CapHeight: 555,
 		CaretSlope: image.Point{X: 0, Y: 1}}
@@ -158,3 +263,514 @@ func TestFaceMetrics(t *testing.T) {
 		t.Fatalf("metrics failed. got=%#v. want=%#v", got, want)
 	}
 }
+
+func TestAppendPath(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b sfnt.Buffer
+	x, err := f.GlyphIndex(&b, 'o')
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments, err := f.LoadGlyph(&b, x, fixed.I(12), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dot := fixed.P(100, 50)
+	p := AppendPath(nil, segments, dot)
+	if len(p) != len(segments) {
+		t.Fatalf("len(path): got %d, want %d", len(p), len(segments))
+	}
+	bounds := segments.Bounds().Add(dot)
+	x0, y0, x1, y1 := p.Bounds()
+	if got, want := [4]float32{x0, y0, x1, y1}, [4]float32{
+		float32(bounds.Min.X) / 64,
+		float32(bounds.Min.Y) / 64,
+		float32(bounds.Max.X) / 64,
+		float32(bounds.Max.Y) / 64,
+	}; got != want {
+		t.Fatalf("path bounds: got %v, want %v", got, want)
+	}
+}
+
+// addTables returns a copy of the single font data src with the given tables,
+// keyed by their 4-byte tags, added. Checksums are not updated.
+func addTables(src []byte, tables map[string][]byte) []byte {
+	type record struct {
+		tag  string
+		data []byte
+	}
+	var records []record
+	numTables := int(binary.BigEndian.Uint16(src[4:]))
+	for i := 0; i < numTables; i++ {
+		b := src[12+16*i:]
+		o, n := binary.BigEndian.Uint32(b[8:]), binary.BigEndian.Uint32(b[12:])
+		records = append(records, record{string(b[:4]), src[o : o+n]})
+	}
+	for tag, data := range tables {
+		records = append(records, record{tag, data})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].tag < records[j].tag })
+
+	dst := make([]byte, 12+16*len(records))
+	copy(dst, src[:4])
+	binary.BigEndian.PutUint16(dst[4:], uint16(len(records)))
+	for i, r := range records {
+		b := dst[12+16*i:]
+		copy(b, r.tag)
+		binary.BigEndian.PutUint32(b[8:], uint32(len(dst)))
+		binary.BigEndian.PutUint32(b[12:], uint32(len(r.data)))
+		dst = append(dst, r.data...)
+		for len(dst)&3 != 0 {
+			dst = append(dst, 0)
+		}
+	}
+	return dst
+}
+
+func TestFaceColorGlyph(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	xA, _ := f.GlyphIndex(nil, 'A')
+	xO, _ := f.GlyphIndex(nil, 'O')
+
+	// Make 'A' a color glyph: a red 'O' with a foreground colored 'A' on top.
+	colr := []byte{
+		0x00, 0x00, // version
+		0x00, 0x01, // numBaseGlyphRecords
+		0x00, 0x00, 0x00, 0x0e, // baseGlyphRecordsOffset
+		0x00, 0x00, 0x00, 0x14, // layerRecordsOffset
+		0x00, 0x02, // numLayerRecords
+		byte(xA >> 8), byte(xA), 0x00, 0x00, 0x00, 0x02,
+		byte(xO >> 8), byte(xO), 0x00, 0x00,
+		byte(xA >> 8), byte(xA), 0xff, 0xff,
+	}
+	cpal := []byte{
+		0x00, 0x00, // version
+		0x00, 0x01, // numPaletteEntries
+		0x00, 0x01, // numPalettes
+		0x00, 0x01, // numColorRecords
+		0x00, 0x00, 0x00, 0x0e, // colorRecordsArrayOffset
+		0x00, 0x00, // colorRecordIndices
+		0x00, 0x00, 0xff, 0xff, // red, as BGRA
+	}
+	cf, err := Parse(addTables(goregular.TTF, map[string][]byte{"COLR": colr, "CPAL": cpal}))
+	if err != nil {
+		t.Fatalf("Parse with color tables: %v", err)
+	}
+	face, err := NewFace(cf, &FaceOptions{Size: 48, DPI: 72})
+	if err != nil {
+		t.Fatalf("NewFace: %v", err)
+	}
+	colorFace, ok := face.(font.ColorFace)
+	if !ok {
+		t.Fatalf("Face is not a font.ColorFace")
+	}
+
+	dot := fixed.P(200, 500)
+	blue := color.RGBA{0x00, 0x00, 0xff, 0xff}
+	if _, _, _, _, ok := colorFace.ColorGlyph(dot, 'x', blue); ok {
+		t.Errorf("'x': got a color glyph, want !ok")
+	}
+	dr, src, sp, advance, ok := colorFace.ColorGlyph(dot, 'A', blue)
+	if !ok {
+		t.Fatalf("'A': could not get color glyph")
+	}
+	if want, _ := face.GlyphAdvance('A'); advance != want {
+		t.Errorf("advance: got %d, want %d", advance, want)
+	}
+
+	// dr is the union of the 'O' and 'A' glyphs' rectangles, and every pixel
+	// is blue where the 'A' is opaque, red where only the 'O' is opaque, and
+	// transparent where neither is.
+	drA, maskA, maskpA, _, _ := face.Glyph(dot, 'A')
+	maskA = cloneAlpha(maskA, maskpA, drA)
+	drO, maskO, maskpO, _, _ := face.Glyph(dot, 'O')
+	maskO = cloneAlpha(maskO, maskpO, drO)
+	if want := drA.Union(drO); dr != want {
+		t.Fatalf("dr: got %v, want %v", dr, want)
+	}
+	nBlue, nRed := 0, 0
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			got := color.RGBAModel.Convert(src.At(x-dr.Min.X+sp.X, y-dr.Min.Y+sp.Y)).(color.RGBA)
+			aA := maskA.At(x, y).(color.Alpha).A
+			aO := maskO.At(x, y).(color.Alpha).A
+			switch {
+			case aA == 0xff:
+				nBlue++
+				if got != blue {
+					t.Errorf("(%d, %d): got %v, want %v", x, y, got, blue)
+				}
+			case aA == 0 && aO == 0xff:
+				nRed++
+				if want := (color.RGBA{0xff, 0x00, 0x00, 0xff}); got != want {
+					t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+				}
+			case aA == 0 && aO == 0:
+				if got != (color.RGBA{}) {
+					t.Errorf("(%d, %d): got %v, want transparent", x, y, got)
+				}
+			}
+		}
+	}
+	if nBlue == 0 || nRed == 0 {
+		t.Errorf("got %d blue and %d red pixels, want some of each", nBlue, nRed)
+	}
+}
+
+func TestFaceBitmapGlyph(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	xA, _ := f.GlyphIndex(nil, 'A')
+
+	// Make 'A' a 10 by 10 pixel green square, drawn at 10 ppem, whose bottom
+	// left corner is at (1, -2) relative to the glyph origin.
+	green := color.RGBA{0x00, 0xff, 0x00, 0xff}
+	m := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	draw.Draw(m, m.Bounds(), image.NewUniform(green), image.Point{}, draw.Src)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, m); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	n := f.NumGlyphs()
+	strike := make([]byte, 4+4*(n+1))
+	binary.BigEndian.PutUint16(strike, 10)
+	for i := 0; i <= n; i++ {
+		o := len(strike)
+		if i > int(xA) {
+			o += 8 + pngData.Len()
+		}
+		binary.BigEndian.PutUint32(strike[4+4*i:], uint32(o))
+	}
+	strike = append(strike, 0x00, 0x01, 0xff, 0xfe, 'p', 'n', 'g', ' ')
+	strike = append(strike, pngData.Bytes()...)
+	sbix := append([]byte{0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0c}, strike...)
+
+	bf, err := Parse(addTables(goregular.TTF, map[string][]byte{"sbix": sbix}))
+	if err != nil {
+		t.Fatalf("Parse with sbix table: %v", err)
+	}
+	face, err := NewFace(bf, &FaceOptions{Size: 20, DPI: 72})
+	if err != nil {
+		t.Fatalf("NewFace: %v", err)
+	}
+	colorFace := face.(font.ColorFace)
+	dot := fixed.P(200, 500)
+	if _, _, _, _, ok := colorFace.ColorGlyph(dot, 'x', color.Black); ok {
+		t.Errorf("'x': got a color glyph, want !ok")
+	}
+	dr, src, sp, advance, ok := colorFace.ColorGlyph(dot, 'A', color.Black)
+	if !ok {
+		t.Fatalf("'A': could not get color glyph")
+	}
+	if want, _ := face.GlyphAdvance('A'); advance != want {
+		t.Errorf("advance: got %d, want %d", advance, want)
+	}
+	// The image is scaled by 2, from 10 to 20 ppem.
+	if want := image.Rect(202, 484, 222, 504); dr != want {
+		t.Fatalf("dr: got %v, want %v", dr, want)
+	}
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			if got := color.RGBAModel.Convert(src.At(sp.X+x, sp.Y+y)); got != green {
+				t.Fatalf("(%d, %d): got %v, want %v", x, y, got, green)
+			}
+		}
+	}
+}
+
+// cloneAlpha returns a copy of the glyph mask m, whose point maskp is drawn at
+// dr.Min, in dst space.
+func cloneAlpha(m image.Image, maskp image.Point, dr image.Rectangle) *image.Alpha {
+	dst := image.NewAlpha(dr)
+	draw.Draw(dst, dr, m, maskp, draw.Src)
+	return dst
+}
+
+// boxSource is a GlyphSource whose every glyph is a square, 1 em wide, sitting
+// on the baseline.
+type boxSource struct{}
+
+func (boxSource) GlyphIndex(b *sfnt.Buffer, r rune) (sfnt.GlyphIndex, error) {
+	return sfnt.GlyphIndex(r), nil
+}
+
+func (boxSource) GlyphAdvance(b *sfnt.Buffer, x sfnt.GlyphIndex, ppem fixed.Int26_6, h font.Hinting) (fixed.Int26_6, error) {
+	return ppem, nil
+}
+
+func (boxSource) LoadGlyph(b *sfnt.Buffer, x sfnt.GlyphIndex, ppem fixed.Int26_6, opts *sfnt.LoadGlyphOptions) (sfnt.Segments, error) {
+	op := func(op sfnt.SegmentOp, x, y fixed.Int26_6) sfnt.Segment {
+		return sfnt.Segment{Op: op, Args: [3]fixed.Point26_6{{X: x, Y: y}}}
+	}
+	return sfnt.Segments{
+		op(sfnt.SegmentOpMoveTo, 0, -ppem),
+		op(sfnt.SegmentOpLineTo, ppem, -ppem),
+		op(sfnt.SegmentOpLineTo, ppem, 0),
+		op(sfnt.SegmentOpLineTo, 0, 0),
+		op(sfnt.SegmentOpLineTo, 0, -ppem),
+	}, nil
+}
+
+func (boxSource) Kern(b *sfnt.Buffer, x0, x1 sfnt.GlyphIndex, ppem fixed.Int26_6, h font.Hinting) (fixed.Int26_6, error) {
+	return 0, nil
+}
+
+func (boxSource) Metrics(b *sfnt.Buffer, ppem fixed.Int26_6, h font.Hinting) (font.Metrics, error) {
+	return font.Metrics{Height: ppem, Ascent: ppem}, nil
+}
+
+func TestFaceVariations(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := NewFace(f, &FaceOptions{
+		Size:       12,
+		DPI:        72,
+		Variations: []sfnt.Variation{{Tag: "wght", Value: 700}},
+	}); err != sfnt.ErrNotFound {
+		t.Errorf("NewFace with Variations of a static font: got %v, want %v", err, sfnt.ErrNotFound)
+	}
+
+	// Make goregular a variable font, with a weight axis that widens every
+	// glyph's advance by 100 font units at its maximum.
+	fvar := []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x10, // axesArrayOffset
+		0x00, 0x02, // reserved
+		0x00, 0x01, // axisCount
+		0x00, 0x14, // axisSize
+		0x00, 0x00, // instanceCount
+		0x00, 0x08, // instanceSize
+		'w', 'g', 'h', 't',
+		0x00, 0x64, 0x00, 0x00, // minValue: 100
+		0x01, 0x90, 0x00, 0x00, // defaultValue: 400
+		0x03, 0x84, 0x00, 0x00, // maxValue: 900
+		0x00, 0x00, // flags
+		0x01, 0x00, // axisNameID
+	}
+	hvar := []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x00, 0x00, 0x14, // itemVariationStoreOffset
+		0x00, 0x00, 0x00, 0x34, // advanceWidthMappingOffset
+		0x00, 0x00, 0x00, 0x00, // lsbMappingOffset
+		0x00, 0x00, 0x00, 0x00, // rsbMappingOffset
+		// Item variation store.
+		0x00, 0x01, // format
+		0x00, 0x00, 0x00, 0x0c, // variationRegionListOffset
+		0x00, 0x01, // itemVariationDataCount
+		0x00, 0x00, 0x00, 0x16, // itemVariationDataOffsets
+		// Variation region list: one region, wght 0 to 1, peaking at 1.
+		0x00, 0x01, 0x00, 0x01,
+		0x00, 0x00, 0x40, 0x00, 0x40, 0x00,
+		// Item variation data: one item, whose delta is 100.
+		0x00, 0x01, 0x00, 0x01, 0x00, 0x01,
+		0x00, 0x00,
+		0x00, 0x64,
+		// Advance width mapping: all glyphs map to the first item.
+		0x00, 0x00, 0x00, 0x01, 0x00,
+	}
+	vf, err := Parse(addTables(goregular.TTF, map[string][]byte{"fvar": fvar, "HVAR": hvar}))
+	if err != nil {
+		t.Fatalf("Parse with variation tables: %v", err)
+	}
+	// At 2048 pixels per em, a pixel is a font unit.
+	opts := &FaceOptions{Size: 2048, DPI: 72}
+	face, err := NewFace(vf, opts)
+	if err != nil {
+		t.Fatalf("NewFace: %v", err)
+	}
+	want, _ := face.GlyphAdvance('A')
+	for _, tc := range []struct {
+		wght  float64
+		delta int
+	}{
+		{400, 0},
+		{650, 50},
+		{900, 100},
+		{100, 0},
+	} {
+		opts.Variations = []sfnt.Variation{{Tag: "wght", Value: tc.wght}}
+		face, err := NewFace(vf, opts)
+		if err != nil {
+			t.Errorf("wght=%v: NewFace: %v", tc.wght, err)
+			continue
+		}
+		if got, _ := face.GlyphAdvance('A'); got != want+fixed.I(tc.delta) {
+			t.Errorf("wght=%v: GlyphAdvance: got %v, want %v", tc.wght, got, want+fixed.I(tc.delta))
+		}
+	}
+}
+
+func TestSourceFace(t *testing.T) {
+	face, err := NewSourceFace(boxSource{}, &FaceOptions{Size: 10, DPI: 72})
+	if err != nil {
+		t.Fatalf("NewSourceFace: %v", err)
+	}
+	if got, want := face.Metrics().Ascent, fixed.I(10); got != want {
+		t.Errorf("ascent: got %v, want %v", got, want)
+	}
+	dr, mask, maskp, advance, ok := face.Glyph(fixed.P(3, 20), 'x')
+	if !ok {
+		t.Fatal("Glyph: got !ok")
+	}
+	if want := image.Rect(3, 10, 13, 20); dr != want {
+		t.Errorf("dr: got %v, want %v", dr, want)
+	}
+	if advance != fixed.I(10) {
+		t.Errorf("advance: got %v, want %v", advance, fixed.I(10))
+	}
+	for y := 0; y < dr.Dy(); y++ {
+		for x := 0; x < dr.Dx(); x++ {
+			if a := mask.At(maskp.X+x, maskp.Y+y).(color.Alpha).A; a != 0xff {
+				t.Fatalf("mask at (%d, %d): got %#02x, want 0xff", x, y, a)
+			}
+		}
+	}
+	bounds, _, ok := face.GlyphBounds('x')
+	if want := (fixed.Rectangle26_6{Min: fixed.P(0, -10), Max: fixed.P(10, 0)}); !ok || bounds != want {
+		t.Errorf("GlyphBounds: got %v, %t, want %v, true", bounds, ok, want)
+	}
+	if _, _, _, _, ok := face.(font.ColorFace).ColorGlyph(fixed.P(3, 20), 'x', color.Black); ok {
+		t.Error("ColorGlyph: got ok for a source without color glyphs")
+	}
+}
+
+func TestSourceFaceMatchesFont(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// Wrapping the Font hides all but the GlyphSource methods.
+	wrapped := struct{ GlyphSource }{f}
+	face, err := NewSourceFace(wrapped, defaultFaceOptions())
+	if err != nil {
+		t.Fatalf("NewSourceFace: %v", err)
+	}
+	for _, rt := range runeTests {
+		dr0, mask0, maskp0, adv0, ok0 := regular.Glyph(fixed.Point26_6{}, rt.r)
+		m0 := cloneAlpha(mask0, maskp0, dr0)
+		dr1, mask1, maskp1, adv1, ok1 := face.Glyph(fixed.Point26_6{}, rt.r)
+		m1 := cloneAlpha(mask1, maskp1, dr1)
+		if dr0 != dr1 || adv0 != adv1 || ok0 != ok1 {
+			t.Errorf("%q: got %v, %v, %t, want %v, %v, %t", rt.r, dr1, adv1, ok1, dr0, adv0, ok0)
+			continue
+		}
+		for i := range m0.Pix {
+			if m0.Pix[i] != m1.Pix[i] {
+				t.Errorf("%q: masks differ", rt.r)
+				break
+			}
+		}
+	}
+}
+
+func TestFaceCoverage(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// Wrapping the Font hides its CmapEntries method, so that the second
+	// face's Coverage calls GlyphIndex for every rune instead.
+	wrapped, err := NewSourceFace(struct{ GlyphSource }{f}, defaultFaceOptions())
+	if err != nil {
+		t.Fatalf("NewSourceFace: %v", err)
+	}
+	for _, tf := range []struct {
+		name string
+		face font.Face
+	}{
+		{"Font", regular},
+		{"GlyphSource", wrapped},
+	} {
+		cf, ok := tf.face.(font.CoverageFace)
+		if !ok {
+			t.Fatalf("%s: not a font.CoverageFace", tf.name)
+		}
+		cov := cf.Coverage()
+		for _, tc := range []struct {
+			r    rune
+			want bool
+		}{
+			{'A', true},
+			{'Æ', true},
+			{'一', false},
+			{'\U0001f600', false},
+		} {
+			if got := cf.HasGlyph(tc.r); got != tc.want {
+				t.Errorf("%s: HasGlyph(%q): got %t, want %t", tf.name, tc.r, got, tc.want)
+			}
+			if got := unicode.Is(cov, tc.r); got != tc.want {
+				t.Errorf("%s: Coverage contains %q: got %t, want %t", tf.name, tc.r, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestFaceSubpixel(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	gray, err := NewFace(f, &FaceOptions{Size: 24, DPI: 72})
+	if err != nil {
+		t.Fatalf("NewFace: %v", err)
+	}
+	dot := fixed.Point26_6{X: fixed.I(30) + 20, Y: fixed.I(40)}
+	wantDr, wantMask, wantMaskp, wantAdvance, _ := gray.Glyph(dot, 'W')
+	wantSum := 0
+	for y := 0; y < wantDr.Dy(); y++ {
+		for x := 0; x < wantDr.Dx(); x++ {
+			wantSum += int(wantMask.(*image.Alpha).AlphaAt(wantMaskp.X+x, wantMaskp.Y+y).A)
+		}
+	}
+
+	for _, layout := range []font.SubpixelLayout{font.SubpixelRGB, font.SubpixelBGR, font.SubpixelVRGB} {
+		face, err := NewFace(f, &FaceOptions{Size: 24, DPI: 72, Subpixel: font.Subpixel{Layout: layout}})
+		if err != nil {
+			t.Fatalf("NewFace: %v", err)
+		}
+		// The second call is a glyph cache hit.
+		for i := 0; i < 2; i++ {
+			dr, mask, maskp, advance, ok := face.Glyph(dot, 'W')
+			if !ok {
+				t.Fatalf("layout=%d: Glyph: got !ok", layout)
+			}
+			pad := image.Pt(1, 0)
+			if layout.Vertical() {
+				pad = image.Pt(0, 1)
+			}
+			if want := (image.Rectangle{wantDr.Min.Sub(pad), wantDr.Max.Add(pad)}); dr != want {
+				t.Errorf("layout=%d: dr: got %v, want %v", layout, dr, want)
+			}
+			if advance != wantAdvance {
+				t.Errorf("layout=%d: advance: got %v, want %v", layout, advance, wantAdvance)
+			}
+			m, ok := mask.(*font.SubpixelMask)
+			if !ok {
+				t.Fatalf("layout=%d: mask: got %T, want *font.SubpixelMask", layout, mask)
+			}
+			// The subpixels' total coverage is about three times that of the
+			// grayscale glyph's pixels, as the filter's weights sum to 256.
+			sum := 0
+			for y := 0; y < dr.Dy(); y++ {
+				p := m.Pix[m.PixOffset(maskp.X, maskp.Y+y):][:3*dr.Dx()]
+				for _, c := range p {
+					sum += int(c)
+				}
+			}
+			if sum < 3*wantSum*98/100 || 3*wantSum*102/100 < sum {
+				t.Errorf("layout=%d: total coverage: got %d, want about %d", layout, sum, 3*wantSum)
+			}
+		}
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pdf provides the font data that PDF writers need to embed SFNT
+// (TrueType and OpenType) fonts: subsetted font programs, glyph widths,
+// CIDToGIDMap streams and font descriptor metrics.
+//
+// It does not write PDF objects itself. The values it returns are in PDF
+// glyph space, 1000 units per em, with the Y axis increasing up, ready to be
+// written as the entries of a PDF writer's own font dictionaries.
+//
+// See section 9.6 "Simple Fonts", 9.7 "Composite Fonts" and 9.8 "Font
+// Descriptors" of the PDF 1.7 specification (ISO 32000-1).
+package pdf // import "golang.org/x/image/font/pdf"
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+var errUnsupportedCFF = errors.New("pdf: subsetting PostScript (CFF) outlines is not supported")
+
+// Font is an SFNT font prepared for embedding in a PDF document.
+//
+// Its methods are not safe for concurrent use.
+type Font struct {
+	// SFNT is the parsed font.
+	SFNT *sfnt.Font
+
+	upem sfnt.Units
+	buf  sfnt.Buffer
+}
+
+// Parse parses an SFNT font, such as TTF or OTF data. The src is retained
+// by the returned Font, and must not be modified while it is in use.
+func Parse(src []byte) (*Font, error) {
+	f, err := sfnt.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Font{
+		SFNT: f,
+		upem: f.UnitsPerEm(),
+	}, nil
+}
+
+// toGlyphSpace converts from font units to PDF glyph space units.
+func (f *Font) toGlyphSpace(x fixed.Int26_6) int {
+	// With a ppem of UnitsPerEm, the sfnt methods' fixed.Int26_6 values are
+	// in font units, without any further scaling.
+	return int(math.Floor(0.5 + float64(x)*1000/float64(f.upem)))
+}
+
+func (f *Font) ppem() fixed.Int26_6 { return fixed.Int26_6(f.upem) }
+
+// Width returns the advance width of the x'th glyph, in glyph space units.
+func (f *Font) Width(x sfnt.GlyphIndex) (int, error) {
+	a, err := f.SFNT.GlyphAdvance(&f.buf, x, f.ppem(), font.HintingNone)
+	if err != nil {
+		return 0, err
+	}
+	return f.toGlyphSpace(a), nil
+}
+
+// WidthRun is an element of a CIDFont's W array: the widths of consecutive
+// CIDs, starting at First.
+type WidthRun struct {
+	First  uint16
+	Widths []int
+}
+
+// Widths returns the W array for the given glyphs, assuming the Identity
+// CIDToGIDMap, where each glyph's CID equals its glyph index. The glyphs need
+// not be sorted, and duplicates are ignored.
+func (f *Font) Widths(glyphs []sfnt.GlyphIndex) ([]WidthRun, error) {
+	gs := sortedGlyphs(glyphs)
+	var runs []WidthRun
+	for i, x := range gs {
+		w, err := f.Width(x)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 || gs[i-1]+1 != x {
+			runs = append(runs, WidthRun{First: uint16(x)})
+		}
+		r := &runs[len(runs)-1]
+		r.Widths = append(r.Widths, w)
+	}
+	return runs, nil
+}
+
+// AppendWidths appends the PDF syntax for the W array runs, such as
+// "[1 [500 600] 7 [250]]", to dst and returns the extended buffer.
+func AppendWidths(dst []byte, runs []WidthRun) []byte {
+	dst = append(dst, '[')
+	for i, r := range runs {
+		if i != 0 {
+			dst = append(dst, ' ')
+		}
+		dst = strconv.AppendInt(dst, int64(r.First), 10)
+		dst = append(dst, " ["...)
+		for j, w := range r.Widths {
+			if j != 0 {
+				dst = append(dst, ' ')
+			}
+			dst = strconv.AppendInt(dst, int64(w), 10)
+		}
+		dst = append(dst, ']')
+	}
+	return append(dst, ']')
+}
+
+// CIDToGIDMap returns the contents of a CIDToGIDMap stream, mapping each CID
+// in m to its glyph index. CIDs not in m map to glyph 0, the missing glyph.
+func CIDToGIDMap(m map[uint16]sfnt.GlyphIndex) []byte {
+	n := 0
+	for cid := range m {
+		if n < int(cid)+1 {
+			n = int(cid) + 1
+		}
+	}
+	b := make([]byte, 2*n)
+	for cid, x := range m {
+		b[2*int(cid)+0] = uint8(x >> 8)
+		b[2*int(cid)+1] = uint8(x)
+	}
+	return b
+}
+
+// Flags are a font descriptor's Flags entry.
+type Flags uint32
+
+const (
+	FlagFixedPitch  Flags = 1 << 0
+	FlagSerif       Flags = 1 << 1
+	FlagSymbolic    Flags = 1 << 2
+	FlagScript      Flags = 1 << 3
+	FlagNonsymbolic Flags = 1 << 5
+	FlagItalic      Flags = 1 << 6
+	FlagAllCap      Flags = 1 << 16
+	FlagSmallCap    Flags = 1 << 17
+	FlagForceBold   Flags = 1 << 18
+)
+
+// Descriptor holds the entries of a font descriptor dictionary. Lengths are
+// in glyph space units.
+type Descriptor struct {
+	// FontName is the font's PostScript name. For a subsetted font program,
+	// prefix it with the SubsetTag.
+	FontName string
+	Flags    Flags
+	// FontBBox is the union of all of the glyphs' bounding boxes, as
+	// (llx, lly, urx, ury).
+	FontBBox    [4]int
+	ItalicAngle float64
+	Ascent      int
+	// Descent is typically negative, as the Y axis increases up.
+	Descent   int
+	CapHeight int
+	XHeight   int
+	// StemV is the thickness of the dominant vertical stems. Fonts do not
+	// record this, so it is estimated from the font's weight class.
+	StemV int
+}
+
+// Descriptor returns the font descriptor metrics.
+func (f *Font) Descriptor() (*Descriptor, error) {
+	name, err := f.SFNT.Name(&f.buf, sfnt.NameIDPostScript)
+	if err != nil && err != sfnt.ErrNotFound {
+		return nil, err
+	}
+	m, err := f.SFNT.Metrics(&f.buf, f.ppem(), font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	b, err := f.SFNT.Bounds(&f.buf, f.ppem(), font.HintingNone)
+	if err != nil {
+		return nil, err
+	}
+	d := &Descriptor{
+		FontName: name,
+		// Convert from sfnt's Y-down to PDF's Y-up.
+		FontBBox: [4]int{
+			f.toGlyphSpace(b.Min.X),
+			f.toGlyphSpace(-b.Max.Y),
+			f.toGlyphSpace(b.Max.X),
+			f.toGlyphSpace(-b.Min.Y),
+		},
+		Ascent:    f.toGlyphSpace(m.Ascent),
+		Descent:   -f.toGlyphSpace(m.Descent),
+		CapHeight: f.toGlyphSpace(m.CapHeight),
+		XHeight:   f.toGlyphSpace(m.XHeight),
+		StemV:     80,
+	}
+
+	if p := f.SFNT.PostTable(); p != nil {
+		d.ItalicAngle = p.ItalicAngle
+		if p.IsFixedPitch {
+			d.Flags |= FlagFixedPitch
+		}
+	}
+	if d.ItalicAngle != 0 {
+		d.Flags |= FlagItalic
+	}
+
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/os2
+	if os2, err := f.SFNT.TableData(&f.buf, "OS/2"); err == nil && len(os2) >= 32 {
+		// The usWeightClass to StemV estimate is the one used by several
+		// PDF producers: 10 + 220 * ((weight - 50) / 900)².
+		if w := float64(u16(os2[4:])); w > 50 {
+			r := (w - 50) / 900
+			d.StemV = int(0.5 + 10 + 220*r*r)
+		}
+		// The sFamilyClass high byte classifies the font's design.
+		switch os2[30] {
+		case 1, 2, 3, 4, 5, 7:
+			d.Flags |= FlagSerif
+		case 10:
+			d.Flags |= FlagScript
+		}
+	}
+
+	// A nonsymbolic font's glyphs are a subset of the Standard Latin
+	// character set. Approximate that by the presence of the basic letters.
+	d.Flags |= FlagNonsymbolic
+	for _, r := range "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz" {
+		if x, err := f.SFNT.GlyphIndex(&f.buf, r); err != nil || x == 0 {
+			d.Flags ^= FlagNonsymbolic | FlagSymbolic
+			break
+		}
+	}
+	return d, nil
+}
+
+// SubsetTag returns the six upper-case letter tag that prefixes the
+// FontName of a subsetted font program, followed by a '+', such as
+// "EOODIA+". The tag is derived from the glyphs, so that different subsets
+// of the same font within a document have different names.
+func SubsetTag(glyphs []sfnt.GlyphIndex) string {
+	h := fnv.New32a()
+	for _, x := range sortedGlyphs(glyphs) {
+		h.Write([]byte{uint8(x >> 8), uint8(x)})
+	}
+	s := h.Sum32()
+	var b [7]byte
+	for i := 0; i < 6; i++ {
+		b[i] = 'A' + uint8(s%26)
+		s /= 26
+	}
+	b[6] = '+'
+	return string(b[:])
+}
+
+func sortedGlyphs(glyphs []sfnt.GlyphIndex) []sfnt.GlyphIndex {
+	gs := append([]sfnt.GlyphIndex(nil), glyphs...)
+	sort.Slice(gs, func(i, j int) bool { return gs[i] < gs[j] })
+	n := 0
+	for i, x := range gs {
+		if i == 0 || gs[n-1] != x {
+			gs[n] = x
+			n++
+		}
+	}
+	return gs[:n]
+}
+
+func u16(b []byte) uint16 {
+	_ = b[1] // Bounds check hint to compiler.
+	return uint16(b[0])<<8 | uint16(b[1])<<0
+}
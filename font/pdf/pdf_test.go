@@ -0,0 +1,146 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdf
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// checksum returns the sum of b, whose length is a multiple of 4, as
+// big-endian uint32 values.
+func checksum(b []byte) (sum uint32) {
+	for ; len(b) >= 4; b = b[4:] {
+		sum += uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+	}
+	return sum
+}
+
+func glyphIndexes(t *testing.T, f *sfnt.Font, s string) []sfnt.GlyphIndex {
+	var b sfnt.Buffer
+	var ret []sfnt.GlyphIndex
+	for _, r := range s {
+		x, err := f.GlyphIndex(&b, r)
+		if err != nil || x == 0 {
+			t.Fatalf("GlyphIndex(%q): %v, %v", r, x, err)
+		}
+		ret = append(ret, x)
+	}
+	return ret
+}
+
+func TestWidths(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	gs := glyphIndexes(t, f.SFNT, "ABCAxB")
+	runs, err := f.Widths(gs)
+	if err != nil {
+		t.Fatalf("Widths: %v", err)
+	}
+	// A, B and C are consecutive glyphs, x is not.
+	if len(runs) != 2 || len(runs[0].Widths) != 3 || len(runs[1].Widths) != 1 {
+		t.Fatalf("runs: got %v", runs)
+	}
+	if got, want := runs[0].First, uint16(gs[0]); got != want {
+		t.Errorf("runs[0].First: got %d, want %d", got, want)
+	}
+	for i, r := range []rune("ABC") {
+		adv, _ := f.SFNT.GlyphAdvance(nil, gs[i], fixed.Int26_6(f.SFNT.UnitsPerEm()), font.HintingNone)
+		if got, want := runs[0].Widths[i], int(0.5+float64(adv)*1000/float64(f.SFNT.UnitsPerEm())); got != want {
+			t.Errorf("width of %q: got %d, want %d", r, got, want)
+		}
+	}
+
+	got := string(AppendWidths(nil, []WidthRun{{1, []int{500, 600}}, {7, []int{250}}}))
+	if want := "[1 [500 600] 7 [250]]"; got != want {
+		t.Errorf("AppendWidths: got %q, want %q", got, want)
+	}
+}
+
+func TestCIDToGIDMap(t *testing.T) {
+	got := CIDToGIDMap(map[uint16]sfnt.GlyphIndex{1: 0x1234, 3: 5})
+	want := []byte{0, 0, 0x12, 0x34, 0, 0, 0, 5}
+	if string(got) != string(want) {
+		t.Errorf("got % x, want % x", got, want)
+	}
+}
+
+func TestDescriptor(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	d, err := f.Descriptor()
+	if err != nil {
+		t.Fatalf("Descriptor: %v", err)
+	}
+	if d.FontName != "GoRegular" {
+		t.Errorf("FontName: got %q", d.FontName)
+	}
+	if d.Flags != FlagNonsymbolic {
+		t.Errorf("Flags: got %#x, want %#x", d.Flags, FlagNonsymbolic)
+	}
+	if d.Ascent <= d.CapHeight || d.CapHeight <= d.XHeight || d.XHeight <= 0 || d.Descent >= 0 {
+		t.Errorf("inconsistent vertical metrics: %+v", d)
+	}
+	if b := d.FontBBox; b[0] >= b[2] || b[1] >= 0 || b[3] <= d.CapHeight {
+		t.Errorf("FontBBox: got %v", b)
+	}
+	if d.StemV < 30 || d.StemV > 100 {
+		t.Errorf("StemV: got %d", d.StemV)
+	}
+}
+
+func TestSubset(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// "Á" is a composite glyph, whose components must be kept.
+	gs := glyphIndexes(t, f.SFNT, "HiÁ")
+	src, err := f.Subset(gs)
+	if err != nil {
+		t.Fatalf("Subset: %v", err)
+	}
+	if len(src) >= len(goregular.TTF)/2 {
+		t.Errorf("subset is too large: %d bytes, full font is %d bytes", len(src), len(goregular.TTF))
+	}
+	if got, want := checksum(src), uint32(0xb1b0afba); got != want {
+		t.Errorf("checksum: got %#08x, want %#08x", got, want)
+	}
+	sub, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse(subset): %v", err)
+	}
+	if got, want := sub.SFNT.NumGlyphs(), f.SFNT.NumGlyphs(); got != want {
+		t.Fatalf("NumGlyphs: got %d, want %d", got, want)
+	}
+
+	ppem := fixed.I(100)
+	var b0, b1 sfnt.Buffer
+	for _, x := range gs {
+		want, err := f.SFNT.LoadGlyph(&b0, x, ppem, nil)
+		if err != nil {
+			t.Fatalf("LoadGlyph(%d): %v", x, err)
+		}
+		got, err := sub.SFNT.LoadGlyph(&b1, x, ppem, nil)
+		if err != nil {
+			t.Fatalf("subset LoadGlyph(%d): %v", x, err)
+		}
+		if len(got) == 0 || len(got) != len(want) {
+			t.Errorf("glyph %d: got %d segments, want %d", x, len(got), len(want))
+		}
+	}
+	x := glyphIndexes(t, f.SFNT, "Z")[0]
+	if segs, err := sub.SFNT.LoadGlyph(&b1, x, ppem, nil); err != nil || len(segs) != 0 {
+		t.Errorf("unused glyph: got %d segments, %v, want empty", len(segs), err)
+	}
+}
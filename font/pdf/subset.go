@@ -0,0 +1,27 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pdf
+
+import (
+	"golang.org/x/image/font/sfnt"
+)
+
+// Subset returns a TrueType font program, for a FontFile2 stream, that
+// contains only the given glyphs and the glyphs that they are composed of.
+//
+// Glyph indexes are unchanged, so that the subset can be used with the
+// Identity CIDToGIDMap and the W array returned by Widths. The other glyphs
+// are present but empty. Glyph 0, the missing glyph, is always kept. See
+// sfnt.SubsetGlyphs for the tables that the subset keeps.
+//
+// Subsetting fonts with PostScript (CFF) outlines is not supported. Such
+// fonts can be embedded whole, as a FontFile3 stream with the OpenType
+// subtype.
+func (f *Font) Subset(glyphs []sfnt.GlyphIndex) ([]byte, error) {
+	if _, err := f.SFNT.TableData(&f.buf, "CFF "); err == nil {
+		return nil, errUnsupportedCFF
+	}
+	return sfnt.SubsetGlyphs(f.SFNT, glyphs)
+}
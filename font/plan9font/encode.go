@@ -0,0 +1,307 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package plan9font
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// EncodeOptions are optional parameters to EncodeFont and EncodeSubfont.
+//
+// A nil *EncodeOptions means to use the default (zero) values of each field.
+type EncodeOptions struct {
+	// Depth is the number of bits per pixel of the glyph images: 1, for
+	// glyphs that are either opaque or transparent, or 2, for four levels of
+	// antialiasing. Zero means 1.
+	Depth int
+
+	// Name is the prefix of the names of the subfont files that EncodeFont
+	// writes, which are followed by a dot and the subfont's first rune in
+	// hexadecimal, as in "name.0100". Empty means "font".
+	Name string
+}
+
+// maxBandSize is the largest compressed image band that Plan 9 reads, other
+// than a band of a single scan line, which is never split.
+const maxBandSize = 6000
+
+// EncodeFont writes the glyphs of f for the runes in runes as a Plan 9 font
+// file to w, and as subfont files to writeFile, which is called with each
+// subfont file's name, relative to the font file, and contents. It is the
+// counterpart of the readFile function passed to ParseFont.
+//
+// The runes that f has glyphs for are grouped into subfonts of consecutive
+// runes, within each block of 256 runes from U+0000, U+0100 and so on. Runes
+// that f has no glyphs for are left out.
+func EncodeFont(w io.Writer, f font.Face, runes *unicode.RangeTable, opts *EncodeOptions, writeFile func(relFilename string, data []byte) error) error {
+	name := "font"
+	if opts != nil && opts.Name != "" {
+		name = opts.Name
+	}
+	m := f.Metrics()
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%d %d\n", m.Height.Ceil(), m.Ascent.Ceil())
+
+	// lo and hi are the first and last runes of the current subfont, or lo
+	// is negative if there is none.
+	lo, hi := rune(-1), rune(-1)
+	flush := func() error {
+		if lo < 0 {
+			return nil
+		}
+		sub := &bytes.Buffer{}
+		if err := EncodeSubfont(sub, f, lo, hi, opts); err != nil {
+			return err
+		}
+		relFilename := fmt.Sprintf("%s.%04X", name, lo)
+		if err := writeFile(relFilename, sub.Bytes()); err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "0x%04X 0x%04X %s\n", lo, hi, relFilename)
+		lo = -1
+		return nil
+	}
+	var err error
+	eachRune(runes, func(r rune) bool {
+		if _, ok := f.GlyphAdvance(r); !ok {
+			return true
+		}
+		if lo >= 0 && (r != hi+1 || r&0xff == 0) {
+			if err = flush(); err != nil {
+				return false
+			}
+		}
+		if lo < 0 {
+			lo = r
+		}
+		hi = r
+		return true
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// eachRune calls f for each rune of t, in increasing order, until f returns
+// false.
+func eachRune(t *unicode.RangeTable, f func(r rune) bool) {
+	for _, r := range t.R16 {
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			if !f(c) {
+				return
+			}
+		}
+	}
+	for _, r := range t.R32 {
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			if !f(c) {
+				return
+			}
+		}
+	}
+}
+
+// subfontGlyph is a glyph to be written to a subfont file.
+type subfontGlyph struct {
+	mask    *image.Alpha // The glyph image, with the dot at (0, ascent).
+	advance int
+}
+
+// EncodeSubfont writes the glyphs of f for the runes from lo to hi, inclusive,
+// as a Plan 9 subfont file to w. Runes that f has no glyphs for have empty
+// glyphs with zero advances. The file does not record lo, which is passed to
+// ParseSubfont as its firstRune.
+//
+// The glyphs are drawn at integer pixel positions, and their advances rounded
+// to integers. The file format limits each glyph's advance to 255 pixels, and
+// the offset of its left edge from the dot to between -128 and 127 pixels.
+func EncodeSubfont(w io.Writer, f font.Face, lo, hi rune, opts *EncodeOptions) error {
+	depth := 1
+	if opts != nil && opts.Depth != 0 {
+		depth = opts.Depth
+	}
+	if depth != 1 && depth != 2 {
+		return fmt.Errorf("plan9font: unsupported depth %d", depth)
+	}
+	if lo < 0 || hi < lo || hi-lo >= 0xffff {
+		return errors.New("plan9font: invalid rune range")
+	}
+	m := f.Metrics()
+	height, ascent := m.Height.Ceil(), m.Ascent.Ceil()
+	if height < 0 || 0xff < height || ascent < 0 || height < ascent {
+		return errors.New("plan9font: unsupported metrics")
+	}
+
+	// Copy each glyph's mask, clipped to the lines of the subfont image, as
+	// the face may re-use its masks.
+	glyphs := make([]subfontGlyph, hi-lo+1)
+	width := 0
+	for i := range glyphs {
+		r := lo + rune(i)
+		dr, mask, maskp, advance, ok := f.Glyph(fixed.P(0, ascent), r)
+		if !ok {
+			glyphs[i].mask = image.NewAlpha(image.Rectangle{})
+			continue
+		}
+		if dr.Min.X < -0x80 || 0x7f < dr.Min.X || advance.Round() < 0 || 0xff < advance.Round() {
+			return fmt.Errorf("plan9font: unsupported glyph size for %U", r)
+		}
+		y0, y1 := clamp(dr.Min.Y, 0, height), clamp(dr.Max.Y, 0, height)
+		maskp.Y += y0 - dr.Min.Y
+		dr.Min.Y, dr.Max.Y = y0, y1
+		g := image.NewAlpha(dr)
+		draw.Draw(g, dr, mask, maskp, draw.Src)
+		glyphs[i] = subfontGlyph{g, advance.Round()}
+		width += dr.Dx()
+	}
+	if width > 0xffff {
+		return errors.New("plan9font: unsupported subfont width")
+	}
+
+	// Lay out the glyphs from left to right, as per the fontchar structures
+	// that ParseSubfont reads.
+	img := image.NewAlpha(image.Rect(0, 0, width, height))
+	fontchars := make([]byte, 0, 6*(len(glyphs)+1))
+	x := 0
+	for _, g := range glyphs {
+		r := g.mask.Rect
+		draw.Draw(img, image.Rect(x, r.Min.Y, x+r.Dx(), r.Max.Y), g.mask, r.Min, draw.Src)
+		top, bottom := r.Min.Y, r.Max.Y
+		if r.Empty() {
+			top, bottom = 0, 0
+		}
+		fontchars = append(fontchars, uint8(x), uint8(x>>8), uint8(top), uint8(bottom), uint8(int8(r.Min.X)), uint8(g.advance))
+		x += r.Dx()
+	}
+	fontchars = append(fontchars, uint8(x), uint8(x>>8), 0, 0, 0, 0)
+
+	buf := &bytes.Buffer{}
+	writeImage(buf, img, depth)
+	fmt.Fprintf(buf, "%11d %11d %11d ", len(glyphs), height, ascent)
+	buf.Write(fontchars)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// writeImage writes m, whose bounds' Min is the origin, as a compressed Plan
+// 9 image with the given depth, as per
+// https://9p.io/magic/man2html/6/image
+func writeImage(buf *bytes.Buffer, m *image.Alpha, depth int) {
+	buf.Write(compressed)
+	fmt.Fprintf(buf, "%11s %11d %11d %11d %11d ", fmt.Sprintf("k%d", depth),
+		m.Rect.Min.X, m.Rect.Min.Y, m.Rect.Max.X, m.Rect.Max.Y)
+
+	// Pack each line's pixels, most significant bits first.
+	bpl := bytesPerLine(m.Rect, depth)
+	lines := make([]byte, bpl*m.Rect.Dy())
+	for y := 0; y < m.Rect.Dy(); y++ {
+		line := lines[y*bpl:]
+		for x := 0; x < m.Rect.Dx(); x++ {
+			a := m.Pix[y*m.Stride+x]
+			if depth == 1 {
+				line[x/8] |= (a >> 7) << uint(7-x%8)
+			} else {
+				line[x/4] |= (a >> 6) << uint(6-2*(x%4))
+			}
+		}
+	}
+
+	// Compress bands of lines, each at most maxBandSize bytes unless it is a
+	// single line.
+	var band, next []byte
+	miny := 0
+	for y := 0; y < m.Rect.Dy(); y++ {
+		next = compressLine(next[:0], lines[miny*bpl:(y+1)*bpl], (y-miny)*bpl)
+		if y > miny && len(band)+len(next) > maxBandSize {
+			fmt.Fprintf(buf, "%11d %11d ", y, len(band))
+			buf.Write(band)
+			band, miny = band[:0], y
+			next = compressLine(next[:0], lines[y*bpl:(y+1)*bpl], 0)
+		}
+		band = append(band, next...)
+	}
+	if m.Rect.Dy() > 0 {
+		fmt.Fprintf(buf, "%11d %11d ", m.Rect.Dy(), len(band))
+		buf.Write(band)
+	}
+}
+
+// compressLine appends the compressed codes of data[start:] to dst, where the
+// preceding data[:start] are the band's previous lines, which the codes can
+// refer back to.
+//
+// The codes are as decompress reads them: literal runs of up to 128 bytes,
+// and matches of compShortestMatch to compShortestMatch+31 bytes that start at
+// most compWindowSize bytes back. A match may overlap the bytes it produces,
+// but neither kind of code may span lines.
+func compressLine(dst, data []byte, start int) []byte {
+	const maxMatch = compShortestMatch + 31
+	lit := start // The start of the pending literal run.
+	flushLiterals := func(end int) {
+		for lit < end {
+			n := end - lit
+			if n > 128 {
+				n = 128
+			}
+			dst = append(dst, 0x80|uint8(n-1))
+			dst = append(dst, data[lit:lit+n]...)
+			lit += n
+		}
+	}
+	for i := start; i < len(data); {
+		bestLen, bestOff := 0, 0
+		limit := len(data) - i
+		if limit > maxMatch {
+			limit = maxMatch
+		}
+		for j := i - 1; j >= 0 && i-j <= compWindowSize; j-- {
+			n := 0
+			for n < limit && data[j+n] == data[i+n] {
+				n++
+			}
+			if n > bestLen {
+				bestLen, bestOff = n, i-j
+				if n == limit {
+					break
+				}
+			}
+		}
+		if bestLen < compShortestMatch {
+			i++
+			continue
+		}
+		flushLiterals(i)
+		offs := bestOff - 1
+		dst = append(dst, uint8(bestLen-compShortestMatch)<<2|uint8(offs>>8), uint8(offs))
+		i += bestLen
+		lit = i
+	}
+	flushLiterals(len(data))
+	return dst
+}
@@ -25,12 +25,15 @@ import (
 //
 // For more detail, look for "struct Fontchar" in
 // https://9p.io/magic/man2html/2/cachechars
+//
+// The file format's fields are 8 or 16 bits, but those of a scaled subfont
+// can be larger.
 type fontchar struct {
 	x      uint32 // X position in the image holding the glyphs.
-	top    uint8  // First non-zero scan line.
-	bottom uint8  // Last non-zero scan line.
-	left   int8   // Offset of baseline.
-	width  uint8  // Width of baseline.
+	top    int    // First non-zero scan line.
+	bottom int    // Last non-zero scan line.
+	left   int    // Offset of baseline.
+	width  int    // Width of baseline.
 }
 
 func parseFontchars(p []byte) []fontchar {
@@ -38,10 +41,10 @@ func parseFontchars(p []byte) []fontchar {
 	for i := range fc {
 		fc[i] = fontchar{
 			x:      uint32(p[0]) | uint32(p[1])<<8,
-			top:    uint8(p[2]),
-			bottom: uint8(p[3]),
-			left:   int8(p[4]),
-			width:  uint8(p[5]),
+			top:    int(p[2]),
+			bottom: int(p[3]),
+			left:   int(int8(p[4])),
+			width:  int(p[5]),
 		}
 		p = p[6:]
 	}
@@ -126,6 +129,68 @@ func (f *subface) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
 	return fixed.Int26_6(f.fontchars[r].width) << 6, true
 }
 
+// scaled returns f scaled up by the factor n, with each pixel of its glyphs
+// replicated n times in each direction.
+func (f *subface) scaled(n int) *subface {
+	r := f.img.Rect
+	img := image.NewAlpha(image.Rectangle{r.Min.Mul(n), r.Max.Mul(n)})
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		i := img.PixOffset(img.Rect.Min.X, y)
+		j := f.img.PixOffset(r.Min.X, r.Min.Y+(y-img.Rect.Min.Y)/n)
+		for x := 0; x < img.Rect.Dx(); x++ {
+			img.Pix[i+x] = f.img.Pix[j+x/n]
+		}
+	}
+	fontchars := make([]fontchar, len(f.fontchars))
+	for i, c := range f.fontchars {
+		fontchars[i] = fontchar{
+			x:      c.x * uint32(n),
+			top:    c.top * n,
+			bottom: c.bottom * n,
+			left:   c.left * n,
+			width:  c.width * n,
+		}
+	}
+	return &subface{
+		firstRune: f.firstRune,
+		n:         f.n,
+		height:    f.height * n,
+		ascent:    f.ascent * n,
+		fontchars: fontchars,
+		img:       img,
+	}
+}
+
+// Scale returns a face whose glyphs and metrics are those of f scaled up by
+// the integer factor n, such as 2 or 3 for high resolution displays, with each
+// pixel of the glyphs replicated n times in each direction. f must be a face
+// returned by ParseFont, ParseSubfont or Scale. The subfonts of a face
+// returned by ParseFont are scaled as they are loaded.
+func Scale(f font.Face, n int) (font.Face, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("plan9font: invalid scale %d", n)
+	}
+	switch f := f.(type) {
+	case *subface:
+		return f.scaled(n), nil
+	case *face:
+		g := &face{
+			height:     f.height * n,
+			ascent:     f.ascent * n,
+			scale:      f.scale * n,
+			readFile:   f.readFile,
+			runeRanges: append([]runeRange(nil), f.runeRanges...),
+		}
+		for i := range g.runeRanges {
+			if x := &g.runeRanges[i]; x.subface != nil {
+				x.subface = x.subface.scaled(n)
+			}
+		}
+		return g, nil
+	}
+	return nil, errors.New("plan9font: cannot scale a face that is not a Plan 9 font")
+}
+
 // runeRange maps a single rune range [lo, hi] to a lazily loaded subface. Both
 // ends of the range are inclusive.
 type runeRange struct {
@@ -143,6 +208,7 @@ type runeRange struct {
 type face struct {
 	height     int
 	ascent     int
+	scale      int // Scale factor applied to subfaces as they are loaded.
 	readFile   func(relFilename string) ([]byte, error)
 	runeRanges []runeRange
 }
@@ -238,6 +304,9 @@ func (f *face) subface(r rune) (*subface, rune) {
 					continue
 				}
 				x.subface = sub.(*subface)
+				if f.scale > 1 {
+					x.subface = x.subface.scaled(f.scale)
+				}
 			}
 			return x.subface, rr
 		}
@@ -252,6 +321,7 @@ func (f *face) subface(r rune) (*subface, rune) {
 // one.
 func ParseFont(data []byte, readFile func(relFilename string) ([]byte, error)) (font.Face, error) {
 	f := &face{
+		scale:    1,
 		readFile: readFile,
 	}
 	// TODO: don't use strconv, to avoid the conversions from []byte to string?
@@ -5,13 +5,19 @@
 package plan9font
 
 import (
+	"bytes"
 	"image"
 	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
+	"unicode"
 
 	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 )
 
 func TestMetrics(t *testing.T) {
@@ -56,3 +62,149 @@ func BenchmarkParseSubfont(b *testing.B) {
 		}
 	}
 }
+
+// glyphPixels returns the alpha values of the pixels of r's glyph, drawn by f
+// at the dot (0, ascent), within the rectangle b.
+func glyphPixels(f font.Face, r rune, b image.Rectangle) []uint8 {
+	dst := image.NewAlpha(b)
+	d := &font.Drawer{Dst: dst, Src: image.Opaque, Face: f, Dot: fixed.P(0, f.Metrics().Ascent.Ceil())}
+	d.DrawString(string(r))
+	return dst.Pix
+}
+
+func TestEncodeSubfont(t *testing.T) {
+	face := basicfont.Face7x13
+	for _, depth := range []int{1, 2} {
+		var buf bytes.Buffer
+		if err := EncodeSubfont(&buf, face, ' ', '~', &EncodeOptions{Depth: depth}); err != nil {
+			t.Fatalf("depth %d: EncodeSubfont: %v", depth, err)
+		}
+		sub, err := ParseSubfont(buf.Bytes(), ' ')
+		if err != nil {
+			t.Fatalf("depth %d: ParseSubfont: %v", depth, err)
+		}
+		if got, want := sub.Metrics(), face.Metrics(); got != want {
+			t.Errorf("depth %d: Metrics: got %v, want %v", depth, got, want)
+		}
+		for r := ' '; r <= '~'; r++ {
+			b := image.Rect(-2, 0, 10, 13)
+			if got, want := glyphPixels(sub, r, b), glyphPixels(face, r, b); !bytes.Equal(got, want) {
+				t.Errorf("depth %d: %q: pixels differ", depth, r)
+			}
+			if got, ok := sub.GlyphAdvance(r); !ok || got != fixed.I(7) {
+				t.Errorf("depth %d: %q: GlyphAdvance: got %v, %t, want 7", depth, r, got, ok)
+			}
+		}
+	}
+}
+
+func TestEncodeSubfontRoundTrip(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.FromSlash("../testdata/fixed/7x13.0100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig, err := ParseSubfont(data, 0x100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := EncodeSubfont(&buf, orig, 0x100, 0x1ff, nil); err != nil {
+		t.Fatalf("EncodeSubfont: %v", err)
+	}
+	sub, err := ParseSubfont(buf.Bytes(), 0x100)
+	if err != nil {
+		t.Fatalf("ParseSubfont: %v", err)
+	}
+	for r := rune(0x100); r <= 0x1ff; r++ {
+		b := image.Rect(-2, 0, 10, 13)
+		if got, want := glyphPixels(sub, r, b), glyphPixels(orig, r, b); !bytes.Equal(got, want) {
+			t.Errorf("%U: pixels differ", r)
+		}
+	}
+	if len(buf.Bytes()) > len(data)*3/2 {
+		t.Errorf("encoded size: got %d bytes, want at most about the original %d", len(buf.Bytes()), len(data))
+	}
+}
+
+func TestEncodeFont(t *testing.T) {
+	face := basicfont.Face7x13
+	files := map[string][]byte{}
+	writeFile := func(name string, data []byte) error {
+		files[name] = data
+		return nil
+	}
+	runes := &unicode.RangeTable{R16: []unicode.Range16{{Lo: 'A', Hi: 'Z', Stride: 1}, {Lo: 0xa0, Hi: 0x110, Stride: 4}}}
+	var buf bytes.Buffer
+	if err := EncodeFont(&buf, face, runes, &EncodeOptions{Name: "7x13"}, writeFile); err != nil {
+		t.Fatalf("EncodeFont: %v", err)
+	}
+	// Face7x13 has no glyphs beyond U+00FF.
+	wantFont := "13 11\n" +
+		"0x0041 0x005A 7x13.0041\n" +
+		"0x00A0 0x00A0 7x13.00A0\n"
+	if got := buf.String(); !strings.HasPrefix(got, wantFont) || len(files) != strings.Count(got, "\n")-1 {
+		t.Errorf("font file:\ngot  %q\nwant %q...", got, wantFont)
+	}
+	readFile := func(name string) ([]byte, error) {
+		if data, ok := files[name]; ok {
+			return data, nil
+		}
+		return nil, os.ErrNotExist
+	}
+	f, err := ParseFont(buf.Bytes(), readFile)
+	if err != nil {
+		t.Fatalf("ParseFont: %v", err)
+	}
+	for _, r := range "AQZ¤ü" {
+		b := image.Rect(-2, 0, 10, 13)
+		if got, want := glyphPixels(f, r, b), glyphPixels(face, r, b); !bytes.Equal(got, want) {
+			t.Errorf("%q: pixels differ", r)
+		}
+	}
+	if _, ok := f.GlyphAdvance('a'); ok {
+		t.Error("'a': GlyphAdvance: got ok, want !ok")
+	}
+}
+
+func TestScale(t *testing.T) {
+	readFile := func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.FromSlash(path.Join("../testdata/fixed", name)))
+	}
+	data, err := readFile("unicode.7x13.font")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := ParseFont(data, readFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Load a subfont before scaling, and another after.
+	f.GlyphAdvance('A')
+	for _, n := range []int{2, 3} {
+		g, err := Scale(f, n)
+		if err != nil {
+			t.Fatalf("Scale(%d): %v", n, err)
+		}
+		if got, want := g.Metrics().Height, fixed.I(13*n); got != want {
+			t.Errorf("Scale(%d): Height: got %v, want %v", n, got, want)
+		}
+		for _, r := range "Aā" {
+			adv, ok := g.GlyphAdvance(r)
+			if !ok || adv != fixed.I(7*n) {
+				t.Errorf("Scale(%d): %q: GlyphAdvance: got %v, %t, want %v", n, r, adv, ok, fixed.I(7*n))
+			}
+			small := glyphPixels(f, r, image.Rect(0, 0, 7, 13))
+			big := glyphPixels(g, r, image.Rect(0, 0, 7*n, 13*n))
+			for y := 0; y < 13*n; y++ {
+				for x := 0; x < 7*n; x++ {
+					if big[y*7*n+x] != small[(y/n)*7+x/n] {
+						t.Fatalf("Scale(%d): %q: pixel (%d, %d) differs", n, r, x, y)
+					}
+				}
+			}
+		}
+	}
+	if _, err := Scale(basicfont.Face7x13, 2); err == nil {
+		t.Error("Scale(basicfont.Face7x13): got nil error")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package font
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"runtime"
+	"unicode"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// NewSafeFace returns a Face that is safe for concurrent use by multiple
+// goroutines, such as the request handlers of a server that all render text
+// in the same font.
+//
+// Most Face implementations are not safe for concurrent use, as they re-use
+// caches and mask image buffers between calls. Instead of serializing every
+// call on a single such Face, the returned Face holds n underlying faces,
+// each created by calling newFace, and lends each call one of them for the
+// duration of that call. Calls only block if all n faces are in use. If n is
+// not positive, runtime.GOMAXPROCS(0) faces are created.
+//
+// The mask image returned by the Glyph method is a copy that is owned by the
+// caller, and is not changed by subsequent Glyph calls. This copy makes Glyph
+// slower than calling the underlying faces directly.
+//
+// The returned Face is a ColorFace. Its ColorGlyph method returns !ok unless
+// the underlying faces are ColorFaces, and like Glyph, it returns a copy of
+// the color glyph image. It is also a CoverageFace, whose methods report the
+// coverage of the underlying faces.
+//
+// Closing the returned Face closes all of the underlying faces. It must not
+// be called concurrently with other method calls.
+func NewSafeFace(newFace func() (Face, error), n int) (Face, error) {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	s := &safeFace{
+		faces: make(chan Face, n),
+	}
+	for i := 0; i < n; i++ {
+		f, err := newFace()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		if i == 0 {
+			s.metrics = f.Metrics()
+		}
+		s.faces <- f
+	}
+	return s, nil
+}
+
+type safeFace struct {
+	faces   chan Face
+	metrics Metrics
+}
+
+func (s *safeFace) Close() (retErr error) {
+	for {
+		select {
+		case f := <-s.faces:
+			if err := f.Close(); err != nil && retErr == nil {
+				retErr = err
+			}
+		default:
+			return retErr
+		}
+	}
+}
+
+func (s *safeFace) Glyph(dot fixed.Point26_6, r rune) (
+	dr image.Rectangle, mask image.Image, maskp image.Point, advance fixed.Int26_6, ok bool) {
+
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+
+	dr, mask, maskp, advance, ok = f.Glyph(dot, r)
+	if !ok || mask == nil {
+		return dr, mask, maskp, advance, ok
+	}
+	// Copy the mask before returning f to the pool, as the next user of f
+	// may overwrite it. A SubpixelMask stays one, so that a Drawer can still
+	// draw its subpixels.
+	if sm, isSubpixel := mask.(*SubpixelMask); isSubpixel {
+		m := &SubpixelMask{
+			Pix:    append([]uint8(nil), sm.Pix...),
+			Stride: sm.Stride,
+			Rect:   sm.Rect,
+		}
+		return dr, m, maskp, advance, true
+	}
+	m := image.NewAlpha(image.Rectangle{Max: dr.Size()})
+	draw.Draw(m, m.Rect, mask, maskp, draw.Src)
+	return dr, m, image.Point{}, advance, true
+}
+
+func (s *safeFace) ColorGlyph(dot fixed.Point26_6, r rune, fg color.Color) (
+	dr image.Rectangle, src image.Image, sp image.Point, advance fixed.Int26_6, ok bool) {
+
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+
+	cf, isColor := f.(ColorFace)
+	if !isColor {
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	dr, src, sp, advance, ok = cf.ColorGlyph(dot, r, fg)
+	if !ok || src == nil {
+		return dr, src, sp, advance, ok
+	}
+	m := image.NewRGBA(image.Rectangle{Max: dr.Size()})
+	draw.Draw(m, m.Rect, src, sp, draw.Src)
+	return dr, m, image.Point{}, advance, true
+}
+
+func (s *safeFace) GlyphBounds(r rune) (bounds fixed.Rectangle26_6, advance fixed.Int26_6, ok bool) {
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+	return f.GlyphBounds(r)
+}
+
+func (s *safeFace) GlyphAdvance(r rune) (advance fixed.Int26_6, ok bool) {
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+	return f.GlyphAdvance(r)
+}
+
+func (s *safeFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+	return f.Kern(r0, r1)
+}
+
+func (s *safeFace) HasGlyph(r rune) bool {
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+	return HasGlyph(f, r)
+}
+
+func (s *safeFace) Coverage() *unicode.RangeTable {
+	f := <-s.faces
+	defer func() { s.faces <- f }()
+	return Coverage(f)
+}
+
+func (s *safeFace) Metrics() Metrics { return s.metrics }
@@ -0,0 +1,125 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package font
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// bufFace is a Face that re-uses its mask buffer, and that records whether
+// it was used by more than one goroutine at a time.
+type bufFace struct {
+	toyFace
+	mask   *image.Alpha
+	inUse  int32
+	misuse *int32
+}
+
+func (f *bufFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	if !atomic.CompareAndSwapInt32(&f.inUse, 0, 1) {
+		atomic.StoreInt32(f.misuse, 1)
+		return image.Rectangle{}, nil, image.Point{}, 0, false
+	}
+	defer atomic.StoreInt32(&f.inUse, 0)
+	for i := range f.mask.Pix {
+		f.mask.Pix[i] = uint8(r)
+	}
+	x, y := dot.X.Round(), dot.Y.Round()
+	return image.Rect(x, y, x+2, y+2), f.mask, image.Point{}, toyAdvance, true
+}
+
+func TestSafeFace(t *testing.T) {
+	misuse := int32(0)
+	nFaces := 0
+	face, err := NewSafeFace(func() (Face, error) {
+		nFaces++
+		return &bufFace{
+			mask:   image.NewAlpha(image.Rect(0, 0, 2, 2)),
+			misuse: &misuse,
+		}, nil
+	}, 3)
+	if err != nil {
+		t.Fatalf("NewSafeFace: %v", err)
+	}
+	defer face.Close()
+	if nFaces != 3 {
+		t.Fatalf("nFaces: got %d, want 3", nFaces)
+	}
+
+	var wg sync.WaitGroup
+	errc := make(chan string, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(r rune) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				dr, mask, maskp, _, ok := face.Glyph(fixed.P(j, 0), r)
+				if !ok {
+					errc <- "Glyph failed"
+					return
+				}
+				if got, want := dr, image.Rect(j, 0, j+2, 2); got != want {
+					errc <- "wrong dr"
+					return
+				}
+				if got := mask.At(maskp.X+1, maskp.Y+1).(color.Alpha).A; got != uint8(r) {
+					errc <- "mask overwritten"
+					return
+				}
+			}
+		}('A' + rune(i))
+	}
+	wg.Wait()
+	close(errc)
+	for msg := range errc {
+		t.Error(msg)
+	}
+	if misuse != 0 {
+		t.Error("an underlying face was used concurrently")
+	}
+}
+
+// lcdFace is a Face that re-uses its subpixel mask buffer.
+type lcdFace struct {
+	toyFace
+	mask SubpixelMask
+}
+
+func (f *lcdFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	f.mask.Pix = append(f.mask.Pix[:0], uint8(r), uint8(r+1), uint8(r+2))
+	f.mask.Stride = 3
+	f.mask.Rect = image.Rect(0, 0, 1, 1)
+	x, y := dot.X.Round(), dot.Y.Round()
+	return image.Rect(x, y, x+1, y+1), &f.mask, image.Point{}, toyAdvance, true
+}
+
+func TestSafeFaceSubpixel(t *testing.T) {
+	face, err := NewSafeFace(func() (Face, error) { return &lcdFace{}, nil }, 1)
+	if err != nil {
+		t.Fatalf("NewSafeFace: %v", err)
+	}
+	defer face.Close()
+	_, mask, _, _, ok := face.Glyph(fixed.Point26_6{}, 'A')
+	if !ok {
+		t.Fatal("Glyph failed")
+	}
+	face.Glyph(fixed.Point26_6{}, 'X')
+	m, isSubpixel := mask.(*SubpixelMask)
+	if !isSubpixel {
+		t.Fatalf("got a %T mask, want a *SubpixelMask", mask)
+	}
+	if got, want := string(m.Pix), "ABC"; got != want {
+		t.Errorf("Pix: got %q, want %q", got, want)
+	}
+	if got, want := m.Rect, image.Rect(0, 0, 1, 1); got != want {
+		t.Errorf("Rect: got %v, want %v", got, want)
+	}
+}
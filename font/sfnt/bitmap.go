@@ -0,0 +1,367 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+// GlyphBitmap is an embedded bitmap image of a glyph, as defined by the sbix
+// or the CBLC and CBDT tables. Such images are typically the glyphs of color
+// emoji fonts, which have no outlines.
+type GlyphBitmap struct {
+	// PPEM is the size, in pixels per em, that the image was drawn for,
+	// which may differ from the size that was asked for.
+	PPEM int
+
+	// Format is the encoding of Data, as an sbix graphic type tag, such as
+	// "png ", "jpg " or "tiff". CBDT images are always "png ".
+	Format string
+
+	// Data is the encoded image.
+	Data []byte
+
+	// Left and Bottom are the position of the image's left and bottom edges
+	// relative to the glyph's origin, in pixels at PPEM. Bottom increases
+	// upwards, unlike image coordinates.
+	Left, Bottom int
+}
+
+// bitmapStrike is the set of embedded bitmap glyphs for one size.
+type bitmapStrike struct {
+	ppem int
+	// For an sbix strike, offset is the offset of the strike in the font
+	// data. For a CBLC strike, offset is the offset of its IndexSubTableArray
+	// in the font data, and n is the number of elements in the array.
+	offset uint32
+	n      uint32
+	sbix   bool
+}
+
+func (f *Font) parseBitmapStrikes(buf []byte, numGlyphs int32) (buf1 []byte, ret []bitmapStrike, err error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/sbix
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/cblc
+
+	if f.sbix.length != 0 {
+		const headerSize = 8
+		if f.sbix.length < headerSize {
+			return nil, nil, errInvalidSbixTable
+		}
+		buf, err = f.src.view(buf, int(f.sbix.offset), headerSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		numStrikes := u32(buf[4:])
+		if headerSize+4*uint64(numStrikes) > uint64(f.sbix.length) {
+			return nil, nil, errInvalidSbixTable
+		}
+		// Each strike starts with its ppem and ppi, followed by the offsets
+		// of its numGlyphs+1 glyphs' data.
+		strikeHeaderSize := 4 + 4*(uint64(numGlyphs)+1)
+		for i := 0; i < int(numStrikes); i++ {
+			buf, err = f.src.view(buf, int(f.sbix.offset)+headerSize+4*i, 4)
+			if err != nil {
+				return nil, nil, err
+			}
+			o := u32(buf)
+			if uint64(o)+strikeHeaderSize > uint64(f.sbix.length) {
+				return nil, nil, errInvalidSbixTable
+			}
+			buf, err = f.src.view(buf, int(f.sbix.offset+o), 2)
+			if err != nil {
+				return nil, nil, err
+			}
+			ret = append(ret, bitmapStrike{
+				ppem:   int(u16(buf)),
+				offset: f.sbix.offset + o,
+				sbix:   true,
+			})
+		}
+		return buf, ret, nil
+	}
+
+	// The CBLC table locates the glyphs' images, which are in the CBDT
+	// table, so both are needed.
+	if f.cblc.length == 0 || f.cbdt.length == 0 {
+		return buf, nil, nil
+	}
+	const headerSize, bitmapSizeSize = 8, 48
+	if f.cblc.length < headerSize {
+		return nil, nil, errInvalidCBLCTable
+	}
+	buf, err = f.src.view(buf, int(f.cblc.offset), headerSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	numSizes := u32(buf[4:])
+	if headerSize+bitmapSizeSize*uint64(numSizes) > uint64(f.cblc.length) {
+		return nil, nil, errInvalidCBLCTable
+	}
+	buf, err = f.src.view(buf, int(f.cblc.offset)+headerSize, bitmapSizeSize*int(numSizes))
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := 0; i < int(numSizes); i++ {
+		rec := buf[bitmapSizeSize*i:]
+		o, n := u32(rec), u32(rec[8:])
+		if uint64(o)+8*uint64(n) > uint64(f.cblc.length) {
+			return nil, nil, errInvalidCBLCTable
+		}
+		ret = append(ret, bitmapStrike{
+			ppem:   int(rec[45]), // ppemY.
+			offset: f.cblc.offset + o,
+			n:      n,
+		})
+	}
+	return buf, ret, nil
+}
+
+// GlyphBitmap returns the embedded bitmap image of the x'th glyph for the
+// size, in pixels per em, that is closest to ppem: the smallest size that is
+// at least ppem, or else the largest size. Callers are expected to scale the
+// image from its PPEM to the size that they draw at.
+//
+// The returned Data is only valid until the next method call that is passed
+// b, and the caller should not modify its contents.
+//
+// It returns ErrNotFound if f has no sbix or CBDT table, or if it has no
+// image for x at that size.
+func (f *Font) GlyphBitmap(b *Buffer, x GlyphIndex, ppem int) (GlyphBitmap, error) {
+	s := f.bitmapStrike(ppem)
+	if s == nil || int(x) >= f.NumGlyphs() {
+		return GlyphBitmap{}, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+	if s.sbix {
+		return f.sbixGlyphBitmap(b, s, x, true)
+	}
+	return f.cbdtGlyphBitmap(b, s, x)
+}
+
+func (f *Font) bitmapStrike(ppem int) *bitmapStrike {
+	var ret *bitmapStrike
+	for i := range f.cached.bitmapStrikes {
+		s := &f.cached.bitmapStrikes[i]
+		switch {
+		case ret == nil:
+			ret = s
+		case ret.ppem < ppem:
+			if s.ppem > ret.ppem {
+				ret = s
+			}
+		default:
+			if ppem <= s.ppem && s.ppem < ret.ppem {
+				ret = s
+			}
+		}
+	}
+	return ret
+}
+
+// sbixGlyphBitmap returns the image of the x'th glyph in the sbix strike s.
+// If followDupe is true, an image that is a duplicate of another glyph's
+// image is replaced by that image.
+func (f *Font) sbixGlyphBitmap(b *Buffer, s *bitmapStrike, x GlyphIndex, followDupe bool) (GlyphBitmap, error) {
+	buf, err := b.view(&f.src, int(s.offset)+4+4*int(x), 8)
+	if err != nil {
+		return GlyphBitmap{}, err
+	}
+	lo, hi := u32(buf), u32(buf[4:])
+	if lo == hi {
+		return GlyphBitmap{}, ErrNotFound
+	}
+	// The glyph data starts with the image's origin and graphic type.
+	const headerSize = 8
+	if hi < lo+headerSize || uint64(s.offset-f.sbix.offset)+uint64(hi) > uint64(f.sbix.length) {
+		return GlyphBitmap{}, errInvalidSbixTable
+	}
+	buf, err = b.view(&f.src, int(s.offset+lo), int(hi-lo))
+	if err != nil {
+		return GlyphBitmap{}, err
+	}
+	g := GlyphBitmap{
+		PPEM:   s.ppem,
+		Format: string(buf[4:8]),
+		Data:   buf[headerSize:],
+		Left:   int(int16(u16(buf))),
+		Bottom: int(int16(u16(buf[2:]))),
+	}
+	if g.Format == "dupe" {
+		if !followDupe || len(g.Data) < 2 {
+			return GlyphBitmap{}, errInvalidSbixTable
+		}
+		y := GlyphIndex(u16(g.Data))
+		if int(y) >= f.NumGlyphs() {
+			return GlyphBitmap{}, errInvalidSbixTable
+		}
+		return f.sbixGlyphBitmap(b, s, y, false)
+	}
+	return g, nil
+}
+
+// cbdtGlyphBitmap returns the image of the x'th glyph in the CBLC strike s.
+func (f *Font) cbdtGlyphBitmap(b *Buffer, s *bitmapStrike, x GlyphIndex) (GlyphBitmap, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/cblc
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/cbdt
+
+	// Find the IndexSubTable whose range of glyphs contains x.
+	buf, err := b.view(&f.src, int(s.offset), 8*int(s.n))
+	if err != nil {
+		return GlyphBitmap{}, err
+	}
+	first, sub, found := GlyphIndex(0), uint32(0), false
+	for i := 0; i < int(s.n); i++ {
+		lo, hi := GlyphIndex(u16(buf[8*i:])), GlyphIndex(u16(buf[8*i+2:]))
+		if lo <= x && x <= hi {
+			first, sub, found = lo, u32(buf[8*i+4:]), true
+			break
+		}
+	}
+	if !found {
+		return GlyphBitmap{}, ErrNotFound
+	}
+	subOffset := uint64(s.offset) + uint64(sub)
+	cblcEnd := uint64(f.cblc.offset) + uint64(f.cblc.length)
+	view := func(o uint64, n int) ([]byte, error) {
+		if o+uint64(n) > cblcEnd {
+			return nil, errInvalidCBLCTable
+		}
+		return b.view(&f.src, int(o), n)
+	}
+
+	const subHeaderSize = 8
+	buf, err = view(subOffset, subHeaderSize)
+	if err != nil {
+		return GlyphBitmap{}, err
+	}
+	indexFormat, imageFormat, imageDataOffset := u16(buf), u16(buf[2:]), u32(buf[4:])
+	i := uint64(x - first)
+
+	// Find the glyph's data, as offsets relative to imageDataOffset, and, for
+	// the index formats that have them, the metrics that all of the
+	// subtable's glyphs share.
+	var (
+		lo, hi     uint64
+		bigMetrics []byte
+	)
+	switch indexFormat {
+	case 1, 3:
+		size := 4
+		if indexFormat == 3 {
+			size = 2
+		}
+		buf, err = view(subOffset+subHeaderSize+uint64(size)*i, 2*size)
+		if err != nil {
+			return GlyphBitmap{}, err
+		}
+		if size == 4 {
+			lo, hi = uint64(u32(buf)), uint64(u32(buf[4:]))
+		} else {
+			lo, hi = uint64(u16(buf)), uint64(u16(buf[2:]))
+		}
+	case 2:
+		buf, err = view(subOffset+subHeaderSize, 12)
+		if err != nil {
+			return GlyphBitmap{}, err
+		}
+		imageSize := uint64(u32(buf))
+		lo, hi = i*imageSize, (i+1)*imageSize
+		bigMetrics = append(bigMetrics, buf[4:12]...)
+	case 4:
+		buf, err = view(subOffset+subHeaderSize, 4)
+		if err != nil {
+			return GlyphBitmap{}, err
+		}
+		numGlyphs := int(u32(buf))
+		if numGlyphs > f.NumGlyphs() {
+			return GlyphBitmap{}, errInvalidCBLCTable
+		}
+		// The glyphs' {glyphID, offset} pairs are followed by one more pair
+		// that gives the end of the last glyph's data.
+		buf, err = view(subOffset+subHeaderSize+4, 4*(numGlyphs+1))
+		if err != nil {
+			return GlyphBitmap{}, err
+		}
+		found = false
+		for j := 0; j < numGlyphs; j++ {
+			if GlyphIndex(u16(buf[4*j:])) == x {
+				lo, hi, found = uint64(u16(buf[4*j+2:])), uint64(u16(buf[4*j+6:])), true
+				break
+			}
+		}
+		if !found {
+			return GlyphBitmap{}, ErrNotFound
+		}
+	case 5:
+		buf, err = view(subOffset+subHeaderSize, 16)
+		if err != nil {
+			return GlyphBitmap{}, err
+		}
+		imageSize, numGlyphs := uint64(u32(buf)), int(u32(buf[12:]))
+		if numGlyphs > f.NumGlyphs() {
+			return GlyphBitmap{}, errInvalidCBLCTable
+		}
+		bigMetrics = append(bigMetrics, buf[4:12]...)
+		buf, err = view(subOffset+subHeaderSize+16, 2*numGlyphs)
+		if err != nil {
+			return GlyphBitmap{}, err
+		}
+		found = false
+		for j := 0; j < numGlyphs; j++ {
+			if GlyphIndex(u16(buf[2*j:])) == x {
+				lo, hi, found = uint64(j)*imageSize, uint64(j+1)*imageSize, true
+				break
+			}
+		}
+		if !found {
+			return GlyphBitmap{}, ErrNotFound
+		}
+	default:
+		return GlyphBitmap{}, errUnsupportedBitmapFormat
+	}
+	if lo == hi {
+		return GlyphBitmap{}, ErrNotFound
+	}
+	if hi < lo || uint64(imageDataOffset)+hi > uint64(f.cbdt.length) {
+		return GlyphBitmap{}, errInvalidCBLCTable
+	}
+	buf, err = b.view(&f.src, int(uint64(f.cbdt.offset)+uint64(imageDataOffset)+lo), int(hi-lo))
+	if err != nil {
+		return GlyphBitmap{}, err
+	}
+
+	// The glyph's data is its metrics, unless they are in the index
+	// subtable, and then the length of the PNG image that follows.
+	g := GlyphBitmap{PPEM: s.ppem, Format: "png "}
+	var height, bearingX, bearingY int
+	switch imageFormat {
+	case 17:
+		// smallGlyphMetrics.
+		if len(buf) < 9 {
+			return GlyphBitmap{}, errInvalidCBLCTable
+		}
+		height, bearingX, bearingY = int(buf[0]), int(int8(buf[2])), int(int8(buf[3]))
+		buf = buf[5:]
+	case 18:
+		// bigGlyphMetrics.
+		if len(buf) < 12 {
+			return GlyphBitmap{}, errInvalidCBLCTable
+		}
+		height, bearingX, bearingY = int(buf[0]), int(int8(buf[2])), int(int8(buf[3]))
+		buf = buf[8:]
+	case 19:
+		if len(buf) < 4 || bigMetrics == nil {
+			return GlyphBitmap{}, errInvalidCBLCTable
+		}
+		height, bearingX, bearingY = int(bigMetrics[0]), int(int8(bigMetrics[2])), int(int8(bigMetrics[3]))
+	default:
+		return GlyphBitmap{}, errUnsupportedBitmapFormat
+	}
+	n := u32(buf)
+	if uint64(n) > uint64(len(buf)-4) {
+		return GlyphBitmap{}, errInvalidCBLCTable
+	}
+	g.Data = buf[4 : 4+n]
+	g.Left, g.Bottom = bearingX, bearingY-height
+	return g, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// sbixStrike returns an sbix strike for a font with numGlyphs glyphs, whose
+// glyphs' data are given by images.
+func sbixStrike(ppem, numGlyphs int, images map[int][]byte) []byte {
+	b := make([]byte, 4+4*(numGlyphs+1))
+	binary.BigEndian.PutUint16(b, uint16(ppem))
+	binary.BigEndian.PutUint16(b[2:], 72)
+	for i := 0; i < numGlyphs; i++ {
+		binary.BigEndian.PutUint32(b[4+4*i:], uint32(len(b)))
+		b = append(b, images[i]...)
+	}
+	binary.BigEndian.PutUint32(b[4+4*numGlyphs:], uint32(len(b)))
+	return b
+}
+
+func TestGlyphBitmapSbix(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := f.GlyphBitmap(nil, 5, 20); err != ErrNotFound {
+		t.Fatalf("GlyphBitmap without bitmap tables: got %v, want ErrNotFound", err)
+	}
+
+	n := f.NumGlyphs()
+	strikes := [][]byte{
+		sbixStrike(20, n, map[int][]byte{
+			5: {0x00, 0x01, 0xff, 0xfe, 'p', 'n', 'g', ' ', 0x20},
+		}),
+		sbixStrike(40, n, map[int][]byte{
+			5: {0x00, 0x02, 0xff, 0xfc, 'p', 'n', 'g', ' ', 0x40, 0x41},
+			6: {0x00, 0x00, 0x00, 0x00, 'd', 'u', 'p', 'e', 0x00, 0x05},
+		}),
+	}
+	sbix := []byte{0x00, 0x01, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02}
+	sbix = append(sbix, make([]byte, 8)...)
+	for i, s := range strikes {
+		binary.BigEndian.PutUint32(sbix[8+4*i:], uint32(len(sbix)))
+		sbix = append(sbix, s...)
+	}
+	f, err = Parse(addTables(goregular.TTF, map[string][]byte{"sbix": sbix}))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	small := GlyphBitmap{PPEM: 20, Format: "png ", Data: []byte{0x20}, Left: 1, Bottom: -2}
+	large := GlyphBitmap{PPEM: 40, Format: "png ", Data: []byte{0x40, 0x41}, Left: 2, Bottom: -4}
+	for _, tc := range []struct {
+		x    GlyphIndex
+		ppem int
+		want GlyphBitmap
+	}{
+		{5, 10, small},
+		{5, 20, small},
+		{5, 21, large},
+		{5, 100, large},
+		{6, 40, large},
+	} {
+		got, err := f.GlyphBitmap(nil, tc.x, tc.ppem)
+		if err != nil {
+			t.Errorf("x=%d, ppem=%d: %v", tc.x, tc.ppem, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("x=%d, ppem=%d:\ngot  %+v\nwant %+v", tc.x, tc.ppem, got, tc.want)
+		}
+	}
+	for _, ppem := range []int{20, 40} {
+		if _, err := f.GlyphBitmap(nil, 7, ppem); err != ErrNotFound {
+			t.Errorf("x=7, ppem=%d: got %v, want ErrNotFound", ppem, err)
+		}
+	}
+}
+
+func TestGlyphBitmapCBDT(t *testing.T) {
+	cbdt := []byte{
+		0x00, 0x03, 0x00, 0x00, // version
+		// Glyph 5: image format 17, smallGlyphMetrics then the PNG data.
+		0x04, 0x03, 0x01, 0x03, 0x04,
+		0x00, 0x00, 0x00, 0x02, 0x50, 0x51,
+		// Glyph 9: image format 19, the PNG data.
+		0x00, 0x00, 0x00, 0x01, 0x90,
+	}
+	cblc := []byte{
+		0x00, 0x03, 0x00, 0x00, // version
+		0x00, 0x00, 0x00, 0x01, // numSizes
+		// BitmapSize record.
+		0x00, 0x00, 0x00, 0x38, // indexSubTableArrayOffset
+		0x00, 0x00, 0x00, 0x00, // indexTablesSize
+		0x00, 0x00, 0x00, 0x02, // numberOfIndexSubTables
+		0x00, 0x00, 0x00, 0x00, // colorRef
+	}
+	cblc = append(cblc, make([]byte, 24)...) // hori, vert
+	cblc = append(cblc,
+		0x00, 0x05, 0x00, 0x09, // startGlyphIndex, endGlyphIndex
+		0x20, 0x20, 0x20, 0x01, // ppemX, ppemY, bitDepth, flags
+		// IndexSubTableArray.
+		0x00, 0x05, 0x00, 0x06, 0x00, 0x00, 0x00, 0x10,
+		0x00, 0x09, 0x00, 0x09, 0x00, 0x00, 0x00, 0x24,
+		// IndexSubTable format 1, for glyphs 5 and 6, of which only glyph 5
+		// has an image.
+		0x00, 0x01, 0x00, 0x11, 0x00, 0x00, 0x00, 0x04,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x0b,
+		0x00, 0x00, 0x00, 0x0b,
+		// IndexSubTable format 5, for glyph 9.
+		0x00, 0x05, 0x00, 0x13, 0x00, 0x00, 0x00, 0x0f,
+		0x00, 0x00, 0x00, 0x05, // imageSize
+		0x06, 0x05, 0x00, 0x02, 0x07, 0x00, 0x00, 0x00, // bigGlyphMetrics
+		0x00, 0x00, 0x00, 0x01, // numGlyphs
+		0x00, 0x09, // glyphIdArray
+	)
+	f, err := Parse(addTables(goregular.TTF, map[string][]byte{"CBDT": cbdt, "CBLC": cblc}))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for _, tc := range []struct {
+		x    GlyphIndex
+		want GlyphBitmap
+	}{
+		{5, GlyphBitmap{PPEM: 32, Format: "png ", Data: []byte{0x50, 0x51}, Left: 1, Bottom: -1}},
+		{9, GlyphBitmap{PPEM: 32, Format: "png ", Data: []byte{0x90}, Left: 0, Bottom: -4}},
+	} {
+		got, err := f.GlyphBitmap(nil, tc.x, 16)
+		if err != nil {
+			t.Errorf("x=%d: %v", tc.x, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("x=%d:\ngot  %+v\nwant %+v", tc.x, got, tc.want)
+		}
+	}
+	for _, x := range []GlyphIndex{4, 6, 7} {
+		if _, err := f.GlyphBitmap(nil, x, 16); err != ErrNotFound {
+			t.Errorf("x=%d: got %v, want ErrNotFound", x, err)
+		}
+	}
+}
@@ -5,6 +5,9 @@
 package sfnt
 
 import (
+	"sort"
+	"unicode"
+
 	"golang.org/x/text/encoding/charmap"
 )
 
@@ -88,7 +91,7 @@ var supportedCmapFormat = func(format, pid, psid uint16) bool {
 	return false
 }
 
-func (f *Font) makeCachedGlyphIndex(buf []byte, offset, length uint32, format uint16) ([]byte, glyphIndexFunc, error) {
+func (f *Font) makeCachedGlyphIndex(buf []byte, offset, length uint32, format uint16) ([]byte, glyphIndexFunc, []cmapRange, error) {
 	switch format {
 	case 0:
 		return f.makeCachedGlyphIndexFormat0(buf, offset, length)
@@ -102,17 +105,25 @@ func (f *Font) makeCachedGlyphIndex(buf []byte, offset, length uint32, format ui
 	panic("unreachable")
 }
 
-func (f *Font) makeCachedGlyphIndexFormat0(buf []byte, offset, length uint32) ([]byte, glyphIndexFunc, error) {
+func (f *Font) makeCachedGlyphIndexFormat0(buf []byte, offset, length uint32) ([]byte, glyphIndexFunc, []cmapRange, error) {
 	if length != 6+256 || offset+length > f.cmap.length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	var err error
 	buf, err = f.src.view(buf, int(f.cmap.offset+offset), int(length))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	var table [256]byte
 	copy(table[:], buf[6:])
+	var ranges []cmapRange
+	for x, g := range table {
+		if g != 0 {
+			r := charmap.Macintosh.DecodeByte(byte(x))
+			ranges = append(ranges, cmapRange{r, r})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].lo < ranges[j].lo })
 	return buf, func(f *Font, b *Buffer, r rune) (GlyphIndex, error) {
 		x, ok := charmap.Macintosh.EncodeRune(r)
 		if !ok {
@@ -120,37 +131,37 @@ func (f *Font) makeCachedGlyphIndexFormat0(buf []byte, offset, length uint32) ([
 			return 0, nil
 		}
 		return GlyphIndex(table[x]), nil
-	}, nil
+	}, ranges, nil
 }
 
-func (f *Font) makeCachedGlyphIndexFormat4(buf []byte, offset, length uint32) ([]byte, glyphIndexFunc, error) {
+func (f *Font) makeCachedGlyphIndexFormat4(buf []byte, offset, length uint32) ([]byte, glyphIndexFunc, []cmapRange, error) {
 	const headerSize = 14
 	if offset+headerSize > f.cmap.length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	var err error
 	buf, err = f.src.view(buf, int(f.cmap.offset+offset), headerSize)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	offset += headerSize
 
 	segCount := u16(buf[6:])
 	if segCount&1 != 0 {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	segCount /= 2
 	if segCount > maxCmapSegments {
-		return nil, nil, errUnsupportedNumberOfCmapSegments
+		return nil, nil, nil, errUnsupportedNumberOfCmapSegments
 	}
 
 	eLength := 8*uint32(segCount) + 2
 	if offset+eLength > f.cmap.length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	buf, err = f.src.view(buf, int(f.cmap.offset+offset), int(eLength))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	offset += eLength
 
@@ -165,6 +176,10 @@ func (f *Font) makeCachedGlyphIndexFormat4(buf []byte, offset, length uint32) ([
 	}
 	indexesBase := f.cmap.offset + offset
 	indexesLength := f.cmap.length - offset
+	ranges := make([]cmapRange, len(entries))
+	for i, e := range entries {
+		ranges[i] = cmapRange{rune(e.start), rune(e.end)}
+	}
 
 	return buf, func(f *Font, b *Buffer, r rune) (GlyphIndex, error) {
 		if uint32(r) > 0xffff {
@@ -194,18 +209,18 @@ func (f *Font) makeCachedGlyphIndexFormat4(buf []byte, offset, length uint32) ([
 			}
 		}
 		return 0, nil
-	}, nil
+	}, ranges, nil
 }
 
-func (f *Font) makeCachedGlyphIndexFormat6(buf []byte, offset, length uint32) ([]byte, glyphIndexFunc, error) {
+func (f *Font) makeCachedGlyphIndexFormat6(buf []byte, offset, length uint32) ([]byte, glyphIndexFunc, []cmapRange, error) {
 	const headerSize = 10
 	if offset+headerSize > f.cmap.length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	var err error
 	buf, err = f.src.view(buf, int(f.cmap.offset+offset), headerSize)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	offset += headerSize
 
@@ -214,13 +229,13 @@ func (f *Font) makeCachedGlyphIndexFormat6(buf []byte, offset, length uint32) ([
 
 	eLength := 2 * uint32(entryCount)
 	if offset+eLength > f.cmap.length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 
 	if entryCount != 0 {
 		buf, err = f.src.view(buf, int(f.cmap.offset+offset), int(eLength))
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		offset += eLength
 	}
@@ -229,6 +244,10 @@ func (f *Font) makeCachedGlyphIndexFormat6(buf []byte, offset, length uint32) ([
 	for i := range entries {
 		entries[i] = u16(buf[2*i:])
 	}
+	var ranges []cmapRange
+	if entryCount != 0 {
+		ranges = []cmapRange{{rune(firstCode), rune(firstCode) + rune(entryCount) - 1}}
+	}
 
 	return buf, func(f *Font, b *Buffer, r rune) (GlyphIndex, error) {
 		if uint16(r) < firstCode {
@@ -240,47 +259,55 @@ func (f *Font) makeCachedGlyphIndexFormat6(buf []byte, offset, length uint32) ([
 			return 0, nil
 		}
 		return GlyphIndex(entries[c]), nil
-	}, nil
+	}, ranges, nil
 }
 
-func (f *Font) makeCachedGlyphIndexFormat12(buf []byte, offset, _ uint32) ([]byte, glyphIndexFunc, error) {
+func (f *Font) makeCachedGlyphIndexFormat12(buf []byte, offset, _ uint32) ([]byte, glyphIndexFunc, []cmapRange, error) {
 	const headerSize = 16
 	if offset+headerSize > f.cmap.length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	var err error
 	buf, err = f.src.view(buf, int(f.cmap.offset+offset), headerSize)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	length := u32(buf[4:])
 	if f.cmap.length < offset || length > f.cmap.length-offset {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	offset += headerSize
 
 	numGroups := u32(buf[12:])
 	if numGroups > maxCmapSegments {
-		return nil, nil, errUnsupportedNumberOfCmapSegments
+		return nil, nil, nil, errUnsupportedNumberOfCmapSegments
 	}
 
 	eLength := 12 * numGroups
 	if headerSize+eLength != length {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	buf, err = f.src.view(buf, int(f.cmap.offset+offset), int(eLength))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	offset += eLength
 
 	entries := make([]cmapEntry32, numGroups)
+	ranges := make([]cmapRange, 0, numGroups)
 	for i := range entries {
-		entries[i] = cmapEntry32{
+		e := cmapEntry32{
 			start: u32(buf[0+12*i:]),
 			end:   u32(buf[4+12*i:]),
 			delta: u32(buf[8+12*i:]),
 		}
+		entries[i] = e
+		if e.end > unicode.MaxRune {
+			e.end = unicode.MaxRune
+		}
+		if e.start <= e.end {
+			ranges = append(ranges, cmapRange{rune(e.start), rune(e.end)})
+		}
 	}
 
 	return buf, func(f *Font, b *Buffer, r rune) (GlyphIndex, error) {
@@ -297,7 +324,7 @@ func (f *Font) makeCachedGlyphIndexFormat12(buf []byte, offset, _ uint32) ([]byt
 			}
 		}
 		return 0, nil
-	}, nil
+	}, ranges, nil
 }
 
 type cmapEntry16 struct {
@@ -307,3 +334,41 @@ type cmapEntry16 struct {
 type cmapEntry32 struct {
 	start, end, delta uint32
 }
+
+// cmapRange is an inclusive range of runes that a cmap subtable may map to
+// glyphs other than the .notdef glyph.
+type cmapRange struct {
+	lo, hi rune
+}
+
+// CmapEntry maps a rune to a glyph, as listed in a font's cmap table.
+type CmapEntry struct {
+	Rune  rune
+	Glyph GlyphIndex
+}
+
+// CmapEntries appends the entries of f's cmap table to dst, in increasing
+// rune order, and returns the extended slice. Runes that map to the .notdef
+// glyph, glyph 0, are omitted. The runes are those of the cmap subtable that
+// GlyphIndex uses, so that GlyphIndex(b, e.Rune) is e.Glyph for every entry
+// e.
+//
+// It lets a shaper or font fallback chain find every rune that f supports
+// without calling GlyphIndex for every possible rune.
+func (f *Font) CmapEntries(b *Buffer, dst []CmapEntry) ([]CmapEntry, error) {
+	if b == nil {
+		b = &Buffer{}
+	}
+	for _, rr := range f.cached.cmapRanges {
+		for r := rr.lo; r <= rr.hi; r++ {
+			x, err := f.cached.glyphIndex(f, b, r)
+			if err != nil {
+				return dst, err
+			}
+			if x != 0 {
+				dst = append(dst, CmapEntry{r, x})
+			}
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"image/color"
+)
+
+// ForegroundPaletteIndex is the ColorLayer.PaletteIndex value that means to
+// paint the layer in the foreground (text) color, instead of a palette color.
+const ForegroundPaletteIndex = 0xffff
+
+// ColorLayer is one layer of a color glyph, as defined by the COLR table. A
+// color glyph is drawn by filling each layer's glyph outline, in order from
+// bottom to top, with that layer's color.
+type ColorLayer struct {
+	// Glyph is the glyph whose outline gives the layer's shape.
+	Glyph GlyphIndex
+	// PaletteIndex is the index of the layer's color within a CPAL palette,
+	// or ForegroundPaletteIndex.
+	PaletteIndex uint16
+}
+
+// ColorLayers appends the layers of the color glyph x to dst and returns the
+// extended slice. It returns ErrNotFound if f has no COLR table or if x is not
+// a color glyph, in which case x should be drawn as a regular, monochrome
+// glyph.
+//
+// Only the version 0 layers are supported: the paint graphs that were added
+// in version 1 of the COLR table are ignored. Color glyphs that are bitmap
+// images, in the sbix or CBDT table, are returned by GlyphBitmap instead.
+func (f *Font) ColorLayers(b *Buffer, dst []ColorLayer, x GlyphIndex) ([]ColorLayer, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/colr
+
+	if f.colr.length == 0 {
+		return dst, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+
+	const headerSize, baseRecordSize, layerRecordSize = 14, 6, 4
+	if f.colr.length < headerSize {
+		return dst, errInvalidCOLRTable
+	}
+	buf, err := b.view(&f.src, int(f.colr.offset), headerSize)
+	if err != nil {
+		return dst, err
+	}
+	if u16(buf) > 1 {
+		return dst, errUnsupportedCOLRTable
+	}
+	numBaseRecords := int(u16(buf[2:]))
+	baseRecordsOffset := u32(buf[4:])
+	layerRecordsOffset := u32(buf[8:])
+	numLayerRecords := int(u16(buf[12:]))
+	if uint64(baseRecordsOffset)+baseRecordSize*uint64(numBaseRecords) > uint64(f.colr.length) ||
+		uint64(layerRecordsOffset)+layerRecordSize*uint64(numLayerRecords) > uint64(f.colr.length) {
+		return dst, errInvalidCOLRTable
+	}
+
+	// Binary search the base glyph records, which are sorted by glyph ID.
+	first, n := 0, 0
+	for lo, hi := 0, numBaseRecords; lo < hi; {
+		i := lo + (hi-lo)/2
+		buf, err = b.view(&f.src, int(f.colr.offset)+int(baseRecordsOffset)+baseRecordSize*i, baseRecordSize)
+		if err != nil {
+			return dst, err
+		}
+		if g := GlyphIndex(u16(buf)); g < x {
+			lo = i + 1
+		} else if g > x {
+			hi = i
+		} else {
+			first, n = int(u16(buf[2:])), int(u16(buf[4:]))
+			break
+		}
+	}
+	if n == 0 {
+		return dst, ErrNotFound
+	}
+	if first+n > numLayerRecords {
+		return dst, errInvalidCOLRTable
+	}
+
+	buf, err = b.view(&f.src, int(f.colr.offset)+int(layerRecordsOffset)+layerRecordSize*first, layerRecordSize*n)
+	if err != nil {
+		return dst, err
+	}
+	for ; len(buf) > 0; buf = buf[layerRecordSize:] {
+		dst = append(dst, ColorLayer{
+			Glyph:        GlyphIndex(u16(buf)),
+			PaletteIndex: u16(buf[2:]),
+		})
+	}
+	return dst, nil
+}
+
+// NumPalettes returns the number of color palettes in f's CPAL table, which
+// is zero if f has no such table.
+func (f *Font) NumPalettes(b *Buffer) (int, error) {
+	if f.cpal.length == 0 {
+		return 0, nil
+	}
+	buf, err := f.viewCPALHeader(b)
+	if err != nil {
+		return 0, err
+	}
+	return int(u16(buf[4:])), nil
+}
+
+// PaletteColor returns the color with the given index in the given CPAL
+// palette. Palette 0 is the default palette. It returns ErrNotFound if f has
+// no CPAL table or if either index is out of range.
+//
+// The returned color is not alpha-premultiplied. The foreground color,
+// ForegroundPaletteIndex, is not in any palette and is chosen by the caller.
+func (f *Font) PaletteColor(b *Buffer, palette int, index uint16) (color.NRGBA, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/cpal
+
+	if f.cpal.length == 0 {
+		return color.NRGBA{}, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+	buf, err := f.viewCPALHeader(b)
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+	numEntries := u16(buf[2:])
+	numPalettes := int(u16(buf[4:]))
+	numColorRecords := int(u16(buf[6:]))
+	colorRecordsOffset := u32(buf[8:])
+	if palette < 0 || palette >= numPalettes || index >= numEntries {
+		return color.NRGBA{}, ErrNotFound
+	}
+
+	const colorRecordSize = 4
+	buf, err = b.view(&f.src, int(f.cpal.offset)+cpalHeaderSize+2*palette, 2)
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+	i := int(u16(buf)) + int(index)
+	o := uint64(colorRecordsOffset) + colorRecordSize*uint64(i)
+	if i >= numColorRecords || o+colorRecordSize > uint64(f.cpal.length) {
+		return color.NRGBA{}, errInvalidCPALTable
+	}
+	buf, err = b.view(&f.src, int(f.cpal.offset)+int(o), colorRecordSize)
+	if err != nil {
+		return color.NRGBA{}, err
+	}
+	// Color records are stored as blue, green, red, alpha.
+	return color.NRGBA{R: buf[2], G: buf[1], B: buf[0], A: buf[3]}, nil
+}
+
+// cpalHeaderSize is the size of the CPAL table's header, excluding the color
+// record indices that follow it. Later versions append fields after those
+// indices, so the header is the same for all versions.
+const cpalHeaderSize = 12
+
+// viewCPALHeader returns the CPAL table's header, after checking that the
+// table is large enough to hold the color record indices.
+func (f *Font) viewCPALHeader(b *Buffer) ([]byte, error) {
+	if b == nil {
+		b = &Buffer{}
+	}
+	if f.cpal.length < cpalHeaderSize {
+		return nil, errInvalidCPALTable
+	}
+	buf, err := b.view(&f.src, int(f.cpal.offset), cpalHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	if f.cpal.length < cpalHeaderSize+2*uint32(u16(buf[4:])) {
+		return nil, errInvalidCPALTable
+	}
+	return buf, nil
+}
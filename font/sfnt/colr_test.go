@@ -0,0 +1,145 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"encoding/binary"
+	"image/color"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// addTables returns a copy of the single font data src with the given tables,
+// keyed by their 4-byte tags, added. Checksums are not updated.
+func addTables(src []byte, tables map[string][]byte) []byte {
+	type record struct {
+		tag  string
+		data []byte
+	}
+	var records []record
+	numTables := int(binary.BigEndian.Uint16(src[4:]))
+	for i := 0; i < numTables; i++ {
+		b := src[12+16*i:]
+		o, n := binary.BigEndian.Uint32(b[8:]), binary.BigEndian.Uint32(b[12:])
+		records = append(records, record{string(b[:4]), src[o : o+n]})
+	}
+	for tag, data := range tables {
+		records = append(records, record{tag, data})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].tag < records[j].tag })
+
+	dst := make([]byte, 12+16*len(records))
+	copy(dst, src[:4])
+	binary.BigEndian.PutUint16(dst[4:], uint16(len(records)))
+	for i, r := range records {
+		b := dst[12+16*i:]
+		copy(b, r.tag)
+		binary.BigEndian.PutUint32(b[8:], uint32(len(dst)))
+		binary.BigEndian.PutUint32(b[12:], uint32(len(r.data)))
+		dst = append(dst, r.data...)
+		for len(dst)&3 != 0 {
+			dst = append(dst, 0)
+		}
+	}
+	return dst
+}
+
+func TestColorLayers(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if _, err := f.ColorLayers(nil, nil, 1); err != ErrNotFound {
+		t.Fatalf("ColorLayers without a COLR table: got %v, want ErrNotFound", err)
+	}
+
+	colr := []byte{
+		0x00, 0x00, // version
+		0x00, 0x02, // numBaseGlyphRecords
+		0x00, 0x00, 0x00, 0x0e, // baseGlyphRecordsOffset
+		0x00, 0x00, 0x00, 0x1a, // layerRecordsOffset
+		0x00, 0x03, // numLayerRecords
+		// Base glyph records: glyph 5 has 2 layers, glyph 9 has 1 layer.
+		0x00, 0x05, 0x00, 0x00, 0x00, 0x02,
+		0x00, 0x09, 0x00, 0x02, 0x00, 0x01,
+		// Layer records.
+		0x00, 0x07, 0x00, 0x01,
+		0x00, 0x05, 0xff, 0xff,
+		0x00, 0x08, 0x00, 0x00,
+	}
+	cpal := []byte{
+		0x00, 0x00, // version
+		0x00, 0x02, // numPaletteEntries
+		0x00, 0x02, // numPalettes
+		0x00, 0x04, // numColorRecords
+		0x00, 0x00, 0x00, 0x10, // colorRecordsArrayOffset
+		0x00, 0x00, 0x00, 0x02, // colorRecordIndices
+		// Color records, as BGRA.
+		0x00, 0x00, 0xff, 0xff,
+		0xff, 0x00, 0x00, 0x80,
+		0x00, 0xff, 0x00, 0xff,
+		0x10, 0x20, 0x30, 0x40,
+	}
+	f, err = Parse(addTables(goregular.TTF, map[string][]byte{"COLR": colr, "CPAL": cpal}))
+	if err != nil {
+		t.Fatalf("Parse with color tables: %v", err)
+	}
+
+	layerTestCases := []struct {
+		x    GlyphIndex
+		want []ColorLayer
+	}{
+		{4, nil},
+		{5, []ColorLayer{{7, 1}, {5, ForegroundPaletteIndex}}},
+		{6, nil},
+		{9, []ColorLayer{{8, 0}}},
+		{10, nil},
+	}
+	b := &Buffer{}
+	for _, tc := range layerTestCases {
+		got, err := f.ColorLayers(b, nil, tc.x)
+		if tc.want == nil {
+			if err != ErrNotFound {
+				t.Errorf("x=%d: got %v, %v, want ErrNotFound", tc.x, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("x=%d: %v", tc.x, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("x=%d: got %v, want %v", tc.x, got, tc.want)
+		}
+	}
+
+	if n, err := f.NumPalettes(b); n != 2 || err != nil {
+		t.Fatalf("NumPalettes: got %d, %v, want 2, nil", n, err)
+	}
+	colorTestCases := []struct {
+		palette int
+		index   uint16
+		want    color.NRGBA
+		wantErr error
+	}{
+		{0, 0, color.NRGBA{0xff, 0x00, 0x00, 0xff}, nil},
+		{0, 1, color.NRGBA{0x00, 0x00, 0xff, 0x80}, nil},
+		{1, 0, color.NRGBA{0x00, 0xff, 0x00, 0xff}, nil},
+		{1, 1, color.NRGBA{0x30, 0x20, 0x10, 0x40}, nil},
+		{0, 2, color.NRGBA{}, ErrNotFound},
+		{2, 0, color.NRGBA{}, ErrNotFound},
+		{0, ForegroundPaletteIndex, color.NRGBA{}, ErrNotFound},
+	}
+	for _, tc := range colorTestCases {
+		got, err := f.PaletteColor(b, tc.palette, tc.index)
+		if got != tc.want || err != tc.wantErr {
+			t.Errorf("palette=%d, index=%d: got %v, %v, want %v, %v",
+				tc.palette, tc.index, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"time"
+)
+
+// FontID identifies a font, such as for the keys of a font cache, or to find
+// duplicate copies of a font. It is derived from the font's "name" and "head"
+// tables.
+//
+// Two fonts with the same Family and Subfamily may be different versions of
+// the same design. The CheckSumAdjustment depends on the entire font file, so
+// that files that differ in any way almost always have different values.
+type FontID struct {
+	// Family, Subfamily, Version and UniqueID are the name table values
+	// keyed by NameIDFamily, NameIDSubfamily, NameIDVersion and
+	// NameIDUniqueIdentifier, such as "Go", "Regular", "Version 2.008" and
+	// "Bigelow&HolmesInc.: Go Regular: 2016". Each is empty if the font has
+	// no such value, or only has it in unsupported encodings.
+	Family    string
+	Subfamily string
+	Version   string
+	UniqueID  string
+
+	// Revision is the font revision number set by the font manufacturer,
+	// such as 2.008.
+	Revision float64
+	// CheckSumAdjustment is the head table's checksum adjustment, which makes
+	// the checksum of the whole font file equal to 0xb1b0afba.
+	CheckSumAdjustment uint32
+	// Created and Modified are when the font was created and last modified,
+	// in UTC.
+	Created  time.Time
+	Modified time.Time
+}
+
+// mac1904 is the number of seconds from the 12:00 midnight, January 1, 1904
+// epoch of the head table's dates to the Unix epoch.
+const mac1904 = 2082844800
+
+// FontID returns the font's identity.
+func (f *Font) FontID(b *Buffer) (FontID, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/head
+
+	if b == nil {
+		b = &Buffer{}
+	}
+	id := FontID{}
+	names := [...]struct {
+		dst *string
+		id  NameID
+	}{
+		{&id.Family, NameIDFamily},
+		{&id.Subfamily, NameIDSubfamily},
+		{&id.Version, NameIDVersion},
+		{&id.UniqueID, NameIDUniqueIdentifier},
+	}
+	for _, n := range names {
+		s, err := f.Name(b, n.id)
+		if err != nil && err != ErrNotFound && err != errUnsupportedPlatformEncoding {
+			return FontID{}, err
+		}
+		*n.dst = s
+	}
+
+	// parseHead has already checked that the head table is 54 bytes long.
+	buf, err := b.view(&f.src, int(f.head.offset), 36)
+	if err != nil {
+		return FontID{}, err
+	}
+	id.Revision = float64(int32(u32(buf[4:]))) / 0x10000
+	id.CheckSumAdjustment = u32(buf[8:])
+	created := int64(u32(buf[20:]))<<32 | int64(u32(buf[24:]))
+	modified := int64(u32(buf[28:]))<<32 | int64(u32(buf[32:]))
+	id.Created = time.Unix(created-mac1904, 0).UTC()
+	id.Modified = time.Unix(modified-mac1904, 0).UTC()
+	return id, nil
+}
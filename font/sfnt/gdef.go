@@ -0,0 +1,277 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// GlyphClass is a glyph's class, as defined by the GDEF table. Shaping
+// engines use it, for example, to skip over marks when matching GSUB and GPOS
+// lookups.
+type GlyphClass uint16
+
+const (
+	// GlyphClassUnknown is the class of glyphs that the GDEF table does not
+	// assign a class to, including every glyph of a font without one.
+	GlyphClassUnknown GlyphClass = 0
+	// GlyphClassBase is the class of single characters, spacing glyphs.
+	GlyphClassBase GlyphClass = 1
+	// GlyphClassLigature is the class of multiple characters, spacing glyphs.
+	GlyphClassLigature GlyphClass = 2
+	// GlyphClassMark is the class of non-spacing combining glyphs.
+	GlyphClassMark GlyphClass = 3
+	// GlyphClassComponent is the class of parts of single characters.
+	GlyphClassComponent GlyphClass = 4
+)
+
+// gdef holds the parts of the GDEF table that are parsed when a Font is
+// initialized. The attachment point and ligature caret tables are only read
+// on demand: their offsets are relative to the start of the GDEF table, and
+// are zero if the table is absent.
+type gdef struct {
+	glyphClass       classLookupFunc
+	markAttachClass  classLookupFunc
+	attachList       uint32
+	attachCoverage   indexLookupFunc
+	ligCaretList     uint32
+	ligCaretCoverage indexLookupFunc
+}
+
+func (f *Font) parseGDEF(buf []byte) ([]byte, gdef, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/gdef
+
+	g := gdef{}
+	if f.gdef.length == 0 {
+		return buf, g, nil
+	}
+	// The GDEF header v1.2 and v1.3 are 14 and 18 bytes, but we don't support
+	// MarkGlyphSetsDef or ItemVariationStore.
+	const headerSize = 12
+	if f.gdef.length < headerSize {
+		return buf, g, errInvalidGDEFTable
+	}
+	buf, err := f.src.view(buf, int(f.gdef.offset), headerSize)
+	if err != nil {
+		return buf, g, err
+	}
+	if u16(buf) != 1 {
+		return buf, g, errUnsupportedGDEFTable
+	}
+	glyphClassDefOffset := uint32(u16(buf[4:]))
+	attachListOffset := uint32(u16(buf[6:]))
+	ligCaretListOffset := uint32(u16(buf[8:]))
+	markAttachClassDefOffset := uint32(u16(buf[10:]))
+
+	if glyphClassDefOffset != 0 {
+		buf, g.glyphClass, err = f.makeCachedClassLookup(buf, int(f.gdef.offset+glyphClassDefOffset))
+		if err != nil {
+			return buf, g, err
+		}
+	}
+	if markAttachClassDefOffset != 0 {
+		buf, g.markAttachClass, err = f.makeCachedClassLookup(buf, int(f.gdef.offset+markAttachClassDefOffset))
+		if err != nil {
+			return buf, g, err
+		}
+	}
+
+	// AttachList and LigCaretList tables both start with coverageOffset, the
+	// offset of a Coverage table relative to their own start.
+	if attachListOffset != 0 {
+		buf, g.attachCoverage, err = f.parseGDEFCoverage(buf, attachListOffset)
+		if err != nil {
+			return buf, g, err
+		}
+		g.attachList = attachListOffset
+	}
+	if ligCaretListOffset != 0 {
+		buf, g.ligCaretCoverage, err = f.parseGDEFCoverage(buf, ligCaretListOffset)
+		if err != nil {
+			return buf, g, err
+		}
+		g.ligCaretList = ligCaretListOffset
+	}
+	return buf, g, nil
+}
+
+func (f *Font) parseGDEFCoverage(buf []byte, listOffset uint32) ([]byte, indexLookupFunc, error) {
+	if f.gdef.length < listOffset+4 {
+		return buf, nil, errInvalidGDEFTable
+	}
+	buf, err := f.src.view(buf, int(f.gdef.offset+listOffset), 2)
+	if err != nil {
+		return buf, nil, err
+	}
+	return f.makeCachedCoverageLookup(buf, int(f.gdef.offset+listOffset)+int(u16(buf)))
+}
+
+// GlyphClass returns the class of the x'th glyph, as defined by f's GDEF
+// table. It returns GlyphClassUnknown if f has no GDEF table or if the table
+// does not classify x.
+//
+// It returns ErrNotFound if the glyph index is out of range.
+func (f *Font) GlyphClass(x GlyphIndex) (GlyphClass, error) {
+	if int(x) >= f.NumGlyphs() {
+		return 0, ErrNotFound
+	}
+	if f.cached.gdef.glyphClass == nil {
+		return GlyphClassUnknown, nil
+	}
+	return GlyphClass(f.cached.gdef.glyphClass(x)), nil
+}
+
+// MarkAttachClass returns the mark attachment class of the x'th glyph, as
+// defined by f's GDEF table. Lookups can use the class to only process some
+// of the marks. It returns zero if f has no GDEF table, if the table does not
+// classify x, or if x is not a mark.
+//
+// It returns ErrNotFound if the glyph index is out of range.
+func (f *Font) MarkAttachClass(x GlyphIndex) (int, error) {
+	if int(x) >= f.NumGlyphs() {
+		return 0, ErrNotFound
+	}
+	if f.cached.gdef.markAttachClass == nil {
+		return 0, nil
+	}
+	return f.cached.gdef.markAttachClass(x), nil
+}
+
+// AttachPoints appends the attachment points of the x'th glyph to dst and
+// returns the extended slice. Each attachment point is the index of a point
+// in the glyph's TrueType outline.
+//
+// It returns ErrNotFound if the glyph index is out of range, or if f's GDEF
+// table has no attachment points for x.
+func (f *Font) AttachPoints(b *Buffer, dst []uint16, x GlyphIndex) ([]uint16, error) {
+	if int(x) >= f.NumGlyphs() || f.cached.gdef.attachCoverage == nil {
+		return dst, ErrNotFound
+	}
+	i, ok := f.cached.gdef.attachCoverage(x)
+	if !ok {
+		return dst, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+
+	// AttachList: coverageOffset, glyphCount, []attachPointOffsets.
+	o, err := f.viewGDEFSubtable(b, f.cached.gdef.attachList, i)
+	if err != nil {
+		return dst, err
+	}
+	// AttachPoint: pointCount, []pointIndices.
+	buf, err := f.viewGDEF(b, o, 2)
+	if err != nil {
+		return dst, err
+	}
+	n := int(u16(buf))
+	buf, err = f.viewGDEF(b, o+2, 2*n)
+	if err != nil {
+		return dst, err
+	}
+	for ; len(buf) > 0; buf = buf[2:] {
+		dst = append(dst, u16(buf))
+	}
+	return dst, nil
+}
+
+// LigatureCarets appends the caret positions within the x'th glyph, a
+// ligature, to dst and returns the extended slice. ppem is the number of
+// pixels in 1 em. The positions are horizontal offsets from the glyph's
+// origin, in the order that they are listed in f's GDEF table, which is
+// logical order. A ligature of n components has n-1 carets.
+//
+// Caret positions that are given by a TrueType outline point are supported
+// for simple glyphs, but not for compound glyphs. Positions are unhinted, and
+// any device table adjustments are ignored.
+//
+// It returns ErrNotFound if the glyph index is out of range, or if f's GDEF
+// table has no carets for x.
+func (f *Font) LigatureCarets(b *Buffer, dst []fixed.Int26_6, x GlyphIndex, ppem fixed.Int26_6, h font.Hinting) ([]fixed.Int26_6, error) {
+	if int(x) >= f.NumGlyphs() || f.cached.gdef.ligCaretCoverage == nil {
+		return dst, ErrNotFound
+	}
+	i, ok := f.cached.gdef.ligCaretCoverage(x)
+	if !ok {
+		return dst, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+
+	// LigCaretList: coverageOffset, ligGlyphCount, []ligGlyphOffsets.
+	o, err := f.viewGDEFSubtable(b, f.cached.gdef.ligCaretList, i)
+	if err != nil {
+		return dst, err
+	}
+	// LigGlyph: caretCount, []caretValueOffsets.
+	buf, err := f.viewGDEF(b, o, 2)
+	if err != nil {
+		return dst, err
+	}
+	n := int(u16(buf))
+	for j := 0; j < n; j++ {
+		buf, err = f.viewGDEF(b, o+2+2*uint32(j), 2)
+		if err != nil {
+			return dst, err
+		}
+		// CaretValue: caretValueFormat, then a coordinate (formats 1 and 3)
+		// or an outline point index (format 2).
+		buf, err = f.viewGDEF(b, o+uint32(u16(buf)), 4)
+		if err != nil {
+			return dst, err
+		}
+		var c int16
+		switch u16(buf) {
+		case 1, 3:
+			c = int16(u16(buf[2:]))
+		case 2:
+			if f.cached.isPostScript {
+				return dst, errUnsupportedCaretValueFormat
+			}
+			if c, _, err = loadGlyfPoint(f, b, x, int(u16(buf[2:]))); err != nil {
+				return dst, err
+			}
+		default:
+			return dst, errUnsupportedCaretValueFormat
+		}
+		v := scale(fixed.Int26_6(c)*ppem, f.cached.unitsPerEm)
+		if h == font.HintingFull {
+			// Quantize the fixed.Int26_6 value to the nearest pixel.
+			v = (v + 32) &^ 63
+		}
+		dst = append(dst, v)
+	}
+	return dst, nil
+}
+
+// viewGDEFSubtable returns the offset, relative to the start of the GDEF
+// table, of the i'th subtable of the AttachList or LigCaretList table at
+// listOffset.
+func (f *Font) viewGDEFSubtable(b *Buffer, listOffset uint32, i int) (uint32, error) {
+	buf, err := f.viewGDEF(b, listOffset+2, 2)
+	if err != nil {
+		return 0, err
+	}
+	if i >= int(u16(buf)) {
+		return 0, errInvalidGDEFTable
+	}
+	buf, err = f.viewGDEF(b, listOffset+4+2*uint32(i), 2)
+	if err != nil {
+		return 0, err
+	}
+	return listOffset + uint32(u16(buf)), nil
+}
+
+// viewGDEF returns length bytes of the GDEF table, starting at offset,
+// relative to the start of the table.
+func (f *Font) viewGDEF(b *Buffer, offset uint32, length int) ([]byte, error) {
+	if uint64(offset)+uint64(length) > uint64(f.gdef.length) {
+		return nil, errInvalidGDEFTable
+	}
+	return b.view(&f.src, int(f.gdef.offset+offset), length)
+}
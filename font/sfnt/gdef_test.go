@@ -0,0 +1,135 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestGlyphClassTestdata(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.FromSlash("../testdata/glyfTest.ttf"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// glyfTest.ttf's GDEF table classifies all but the .notdef glyph as base
+	// glyphs, and has an empty ligature caret list.
+	for x := GlyphIndex(0); int(x) < f.NumGlyphs(); x++ {
+		want := GlyphClassBase
+		if x == 0 {
+			want = GlyphClassUnknown
+		}
+		if got, err := f.GlyphClass(x); err != nil || got != want {
+			t.Errorf("GlyphClass(%d): got %d, %v, want %d, nil", x, got, err, want)
+		}
+		if _, err := f.LigatureCarets(nil, nil, x, fixed.I(12), font.HintingNone); err != ErrNotFound {
+			t.Errorf("LigatureCarets(%d): got %v, want ErrNotFound", x, err)
+		}
+	}
+	if _, err := f.GlyphClass(GlyphIndex(f.NumGlyphs())); err != ErrNotFound {
+		t.Errorf("GlyphClass out of range: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestGDEF(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, err := f.GlyphClass(5); err != nil || got != GlyphClassUnknown {
+		t.Fatalf("GlyphClass without a GDEF table: got %d, %v, want 0, nil", got, err)
+	}
+	if _, err := f.AttachPoints(nil, nil, 5); err != ErrNotFound {
+		t.Fatalf("AttachPoints without a GDEF table: got %v, want ErrNotFound", err)
+	}
+
+	gdef := []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x0c, // glyphClassDefOffset
+		0x00, 0x22, // attachListOffset
+		0x00, 0x34, // ligCaretListOffset
+		0x00, 0x18, // markAttachClassDefOffset
+		// GlyphClassDef, format 1: glyphs 4, 5 and 6 are base, ligature and
+		// mark glyphs.
+		0x00, 0x01, 0x00, 0x04, 0x00, 0x03,
+		0x00, 0x01, 0x00, 0x02, 0x00, 0x03,
+		// MarkAttachClassDef, format 2: glyph 6 is in class 2.
+		0x00, 0x02, 0x00, 0x01,
+		0x00, 0x06, 0x00, 0x06, 0x00, 0x02,
+		// AttachList, with one AttachPoint table and a format 1 Coverage
+		// table for glyph 5.
+		0x00, 0x0c, 0x00, 0x01, 0x00, 0x06,
+		0x00, 0x02, 0x00, 0x00, 0x00, 0x03,
+		0x00, 0x01, 0x00, 0x01, 0x00, 0x05,
+		// LigCaretList, with one LigGlyph table.
+		0x00, 0x1c, 0x00, 0x01, 0x00, 0x06,
+		// LigGlyph, with three carets of formats 1, 2 and 3.
+		0x00, 0x03, 0x00, 0x08, 0x00, 0x0c, 0x00, 0x10,
+		0x00, 0x01, 0x01, 0x2c,
+		0x00, 0x02, 0x00, 0x00,
+		0x00, 0x03, 0xff, 0xec, 0x00, 0x00,
+		// LigCaretList's format 2 Coverage table for glyph 5.
+		0x00, 0x02, 0x00, 0x01,
+		0x00, 0x05, 0x00, 0x05, 0x00, 0x00,
+	}
+	f, err = Parse(addTables(goregular.TTF, map[string][]byte{"GDEF": gdef}))
+	if err != nil {
+		t.Fatalf("Parse with a GDEF table: %v", err)
+	}
+
+	classTestCases := []struct {
+		x          GlyphIndex
+		class      GlyphClass
+		markAttach int
+	}{
+		{3, GlyphClassUnknown, 0},
+		{4, GlyphClassBase, 0},
+		{5, GlyphClassLigature, 0},
+		{6, GlyphClassMark, 2},
+		{7, GlyphClassUnknown, 0},
+	}
+	for _, tc := range classTestCases {
+		if got, err := f.GlyphClass(tc.x); err != nil || got != tc.class {
+			t.Errorf("GlyphClass(%d): got %d, %v, want %d, nil", tc.x, got, err, tc.class)
+		}
+		if got, err := f.MarkAttachClass(tc.x); err != nil || got != tc.markAttach {
+			t.Errorf("MarkAttachClass(%d): got %d, %v, want %d, nil", tc.x, got, err, tc.markAttach)
+		}
+	}
+
+	b := &Buffer{}
+	if got, err := f.AttachPoints(b, nil, 5); err != nil || !reflect.DeepEqual(got, []uint16{0, 3}) {
+		t.Errorf("AttachPoints(5): got %v, %v, want [0 3], nil", got, err)
+	}
+	if _, err := f.AttachPoints(b, nil, 4); err != ErrNotFound {
+		t.Errorf("AttachPoints(4): got %v, want ErrNotFound", err)
+	}
+
+	// With a ppem of unitsPerEm/64 pixels, carets are in font units. The
+	// format 2 caret is at the glyph's first outline point, which starts
+	// the glyph's first contour.
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	segments, err := f.LoadGlyph(b, 5, ppem, nil)
+	if err != nil {
+		t.Fatalf("LoadGlyph: %v", err)
+	}
+	want := []fixed.Int26_6{300, segments[0].Args[0].X, -20}
+	if got, err := f.LigatureCarets(b, nil, 5, ppem, font.HintingNone); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("LigatureCarets(5): got %v, %v, want %v, nil", got, err, want)
+	}
+	if _, err := f.LigatureCarets(b, nil, 4, ppem, font.HintingNone); err != ErrNotFound {
+		t.Errorf("LigatureCarets(4): got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+const (
+	hexFeatureLiga = uint32(0x6c696761) // liga
+)
+
+// Ligature is a ligature substitution: a sequence of glyphs that is replaced
+// by a single glyph, such as "f" and "i" by "fi".
+type Ligature struct {
+	// Components are the glyphs that are replaced, in logical order.
+	Components []GlyphIndex
+	// Glyph is the ligature glyph that replaces them.
+	Glyph GlyphIndex
+}
+
+// ligatureFunc appends the ligatures of a LigatureSubst subtable whose first
+// component is x to dst.
+type ligatureFunc func(b *Buffer, dst []Ligature, x GlyphIndex) ([]Ligature, error)
+
+func (f *Font) parseGSUBLigatures(buf []byte) ([]byte, []ligatureFunc, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/gsub
+	//
+	// The GSUB header, and its script, feature and lookup lists, have the
+	// same layout as the GPOS table's.
+
+	if f.gsub.length == 0 {
+		return buf, nil, nil
+	}
+	const headerSize = 10 // GSUB header v1.1 is 14 bytes, but we don't support FeatureVariations
+	if f.gsub.length < headerSize {
+		return buf, nil, errInvalidGSUBTable
+	}
+
+	buf, err := f.src.view(buf, int(f.gsub.offset), headerSize)
+	if err != nil {
+		return buf, nil, err
+	}
+	if u16(buf) != 1 || u16(buf[2:]) > 1 {
+		return buf, nil, errUnsupportedGSUBTable
+	}
+	scriptListOffset := u16(buf[4:])
+	featureListOffset := u16(buf[6:])
+	lookupListOffset := u16(buf[8:])
+
+	buf, featureIdxs, err := f.parseGPOSScriptFeatures(buf, int(f.gsub.offset)+int(scriptListOffset), hexScriptLatn)
+	if err != nil {
+		return buf, nil, err
+	}
+	if len(featureIdxs) == 0 {
+		buf, featureIdxs, err = f.parseGPOSScriptFeatures(buf, int(f.gsub.offset)+int(scriptListOffset), hexScriptDFLT)
+		if err != nil {
+			return buf, nil, err
+		}
+		if len(featureIdxs) == 0 {
+			return buf, nil, nil
+		}
+	}
+	buf, lookupIdx, err := f.parseGPOSFeaturesLookup(buf, int(f.gsub.offset)+int(featureListOffset), featureIdxs, hexFeatureLiga)
+	if err != nil {
+		return buf, nil, err
+	}
+
+	// LookupList: lookupCount, []lookupOffsets
+	buf, numLookupTables, err := f.src.varLenView(buf, int(f.gsub.offset)+int(lookupListOffset), 2, 0, 2)
+	if err != nil {
+		return buf, nil, err
+	}
+	lookupOffsets := make([]int, numLookupTables)
+	for i := range lookupOffsets {
+		lookupOffsets[i] = int(f.gsub.offset) + int(lookupListOffset) + int(u16(buf[2+2*i:]))
+	}
+
+	var ligatureFuncs []ligatureFunc
+
+lookupTables:
+	for _, n := range lookupIdx {
+		if n >= numLookupTables {
+			return buf, nil, errInvalidGSUBTable
+		}
+		tableOffset := lookupOffsets[n]
+
+		// Lookup: lookupType, lookupFlag, subTableCount, []subtableOffsets
+		buf, numSubTables, err := f.src.varLenView(buf, tableOffset, 6, 4, 2)
+		if err != nil {
+			return buf, nil, err
+		}
+		subTableOffsets := make([]int, numSubTables)
+		for i := range subTableOffsets {
+			subTableOffsets[i] = tableOffset + int(u16(buf[6+i*2:]))
+		}
+
+		switch lookupType := u16(buf); lookupType {
+		case 4: // Ligature Substitution
+		case 7:
+			// Extension Substitution subtables add a u32 offset to
+			// subtables of another type.
+			for i := range subTableOffsets {
+				buf, err = f.src.view(buf, subTableOffsets[i], 8)
+				if err != nil {
+					return buf, nil, err
+				}
+				if format := u16(buf); format != 1 {
+					return buf, nil, errUnsupportedGSUBTable
+				}
+				if lookupType := u16(buf[2:]); lookupType != 4 {
+					continue lookupTables
+				}
+				subTableOffsets[i] += int(u32(buf[4:]))
+			}
+		default:
+			continue
+		}
+
+		for _, subTableOffset := range subTableOffsets {
+			// LigatureSubst Format 1: substFormat, coverageOffset,
+			// ligatureSetCount, []ligatureSetOffsets
+			buf, numSets, err := f.src.varLenView(buf, subTableOffset, 6, 4, 2)
+			if err != nil {
+				return buf, nil, err
+			}
+			if format := u16(buf); format != 1 {
+				return buf, nil, errUnsupportedGSUBTable
+			}
+			setOffsets := make([]uint16, numSets)
+			for i := range setOffsets {
+				setOffsets[i] = u16(buf[6+2*i:])
+			}
+			var coverage indexLookupFunc
+			buf, coverage, err = f.makeCachedCoverageLookup(buf, subTableOffset+int(u16(buf[2:])))
+			if err != nil {
+				return buf, nil, err
+			}
+			ligatureFuncs = append(ligatureFuncs, f.makeLigatureFunc(subTableOffset, coverage, setOffsets))
+		}
+	}
+	return buf, ligatureFuncs, nil
+}
+
+// makeLigatureFunc returns a ligatureFunc for the LigatureSubst subtable at
+// the given offset. The ligature sets are read on demand.
+func (f *Font) makeLigatureFunc(offset int, coverage indexLookupFunc, setOffsets []uint16) ligatureFunc {
+	return func(b *Buffer, dst []Ligature, x GlyphIndex) ([]Ligature, error) {
+		i, ok := coverage(x)
+		if !ok {
+			return dst, nil
+		}
+		if i >= len(setOffsets) {
+			return dst, errInvalidGSUBTable
+		}
+		// LigatureSet: ligatureCount, []ligatureOffsets
+		setOffset := offset + int(setOffsets[i])
+		buf, err := b.view(&f.src, setOffset, 2)
+		if err != nil {
+			return dst, err
+		}
+		n := int(u16(buf))
+		buf, err = b.view(&f.src, setOffset+2, 2*n)
+		if err != nil {
+			return dst, err
+		}
+		ligOffsets := make([]uint16, n)
+		for j := range ligOffsets {
+			ligOffsets[j] = u16(buf[2*j:])
+		}
+		for _, o := range ligOffsets {
+			// Ligature: ligatureGlyph, componentCount, []componentGlyphIDs,
+			// where the first component is x and is not listed.
+			buf, err := b.view(&f.src, setOffset+int(o), 4)
+			if err != nil {
+				return dst, err
+			}
+			lig := Ligature{Glyph: GlyphIndex(u16(buf))}
+			count := int(u16(buf[2:]))
+			if count == 0 {
+				return dst, errInvalidGSUBTable
+			}
+			buf, err = b.view(&f.src, setOffset+int(o)+4, 2*(count-1))
+			if err != nil {
+				return dst, err
+			}
+			lig.Components = make([]GlyphIndex, count)
+			lig.Components[0] = x
+			for k := 1; k < count; k++ {
+				lig.Components[k] = GlyphIndex(u16(buf[2*(k-1):]))
+			}
+			dst = append(dst, lig)
+		}
+		return dst, nil
+	}
+}
+
+// Ligatures appends the ligatures of f's GSUB table whose first component is
+// the x'th glyph to dst and returns the extended slice. They are the
+// ligatures of the standard ligatures ("liga") feature of the Latin script,
+// or of the default script if f has no Latin features, in the order that f
+// lists them, which is the order of preference: a shaper should substitute
+// the first ligature whose components match.
+//
+// Lookup flags, such as to skip over marks while matching the components,
+// are not applied. A text shaper that needs them, or other GSUB lookups,
+// can parse the table returned by TableData.
+//
+// It returns ErrNotFound if the glyph index is out of range.
+func (f *Font) Ligatures(b *Buffer, dst []Ligature, x GlyphIndex) ([]Ligature, error) {
+	if int(x) >= f.NumGlyphs() {
+		return dst, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+	for _, fn := range f.cached.ligatureFuncs {
+		var err error
+		if dst, err = fn(b, dst, x); err != nil {
+			return dst, err
+		}
+	}
+	return dst, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestLigatures(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got, err := f.Ligatures(nil, nil, 5); err != nil || len(got) != 0 {
+		t.Fatalf("Ligatures without a GSUB table: got %v, %v, want none, nil", got, err)
+	}
+
+	gsub := []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x0a, // scriptListOffset
+		0x00, 0x1e, // featureListOffset
+		0x00, 0x2c, // lookupListOffset
+		// ScriptList, with a DFLT Script table whose default LangSys has
+		// feature 0.
+		0x00, 0x01, 'D', 'F', 'L', 'T', 0x00, 0x08,
+		0x00, 0x04, 0x00, 0x00,
+		0x00, 0x00, 0xff, 0xff, 0x00, 0x01, 0x00, 0x00,
+		// FeatureList, with a liga Feature table that uses lookup 0.
+		0x00, 0x01, 'l', 'i', 'g', 'a', 0x00, 0x08,
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00,
+		// LookupList, with one Ligature Substitution Lookup table.
+		0x00, 0x01, 0x00, 0x04,
+		0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x08,
+		// LigatureSubst, with a format 1 Coverage table for glyph 5 and
+		// one LigatureSet.
+		0x00, 0x01, 0x00, 0x08, 0x00, 0x01, 0x00, 0x0e,
+		0x00, 0x01, 0x00, 0x01, 0x00, 0x05,
+		// LigatureSet, with two Ligature tables: glyphs 5, 6 and 7 to glyph
+		// 100, and glyphs 5 and 6 to glyph 101.
+		0x00, 0x02, 0x00, 0x06, 0x00, 0x0e,
+		0x00, 0x64, 0x00, 0x03, 0x00, 0x06, 0x00, 0x07,
+		0x00, 0x65, 0x00, 0x02, 0x00, 0x06,
+	}
+	f, err = Parse(addTables(goregular.TTF, map[string][]byte{"GSUB": gsub}))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []Ligature{
+		{Components: []GlyphIndex{5, 6, 7}, Glyph: 100},
+		{Components: []GlyphIndex{5, 6}, Glyph: 101},
+	}
+	if got, err := f.Ligatures(nil, nil, 5); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Ligatures(5): got %v, %v, want %v, nil", got, err, want)
+	}
+	if got, err := f.Ligatures(nil, nil, 6); err != nil || len(got) != 0 {
+		t.Errorf("Ligatures(6): got %v, %v, want none, nil", got, err)
+	}
+	if _, err := f.Ligatures(nil, nil, GlyphIndex(f.NumGlyphs())); err != ErrNotFound {
+		t.Errorf("Ligatures out of range: got %v, want ErrNotFound", err)
+	}
+
+	data, err := f.TableData(nil, "GSUB")
+	if err != nil || !bytes.Equal(data, gsub) {
+		t.Errorf("TableData(GSUB): got %d bytes, %v, want %d bytes, nil", len(data), err, len(gsub))
+	}
+	if _, err := f.TableData(nil, "GPOS"); err != ErrNotFound {
+		t.Errorf("TableData(GPOS): got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,1839 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+// This file implements the TrueType bytecode interpreter, which hints glyph
+// outlines by running the font's fpgm, prep and glyph programs.
+//
+// The TrueType specification is at
+// https://docs.microsoft.com/en-us/typography/opentype/spec/tt_instructions
+// and
+// https://developer.apple.com/fonts/TrueType-Reference-Manual/RM05/Chap5.html
+//
+// Where the specification is ambiguous, this implementation follows the
+// behavior of FreeType's v35 interpreter.
+
+import "golang.org/x/image/math/fixed"
+
+const (
+	twilightZone = 0
+	glyphZone    = 1
+
+	// maxHintingSteps bounds the number of instructions that running one
+	// program may execute, so that programs with infinite loops terminate.
+	maxHintingSteps = 1 << 20
+	// maxHintingCallDepth bounds the depth of nested CALL and LOOPCALL
+	// instructions.
+	maxHintingCallDepth = 32
+	// maxHintingIndex bounds the function indexes, which fonts do not
+	// always declare in the maxp table.
+	maxHintingIndex = 1 << 16
+)
+
+// Flags of a hintPoint.
+const (
+	pointOnCurve  = 1 << 0
+	pointTouchedX = 1 << 1
+	pointTouchedY = 1 << 2
+)
+
+// hintPoint is a point of a glyph outline, or of the twilight zone.
+type hintPoint struct {
+	// x and y are the current, hinted position, and ox and oy are the
+	// original position, in 26.6 pixels.
+	x, y, ox, oy fixed.Int26_6
+	// ux and uy are the original position in font units.
+	ux, uy int32
+	flags  uint8
+}
+
+// hintVector is a unit vector, with 2.14 fixed point elements.
+type hintVector [2]int32
+
+// dot returns the dot product of (x, y) and v.
+func (v hintVector) dot(x, y fixed.Int26_6) fixed.Int26_6 {
+	m := int64(x)*int64(v[0]) + int64(y)*int64(v[1])
+	if m < 0 {
+		// Round halves away from zero.
+		m--
+	}
+	return fixed.Int26_6((m + 0x2000) >> 14)
+}
+
+// normalize returns the unit vector in the direction of (x, y), which must
+// not be zero. It approximates the length by Newton's method, in integer
+// arithmetic, rounding as other rasterizers do.
+func normalize(x, y int32) hintVector {
+	sx, sy := int32(1), int32(1)
+	if x < 0 {
+		x, sx = -x, -1
+	}
+	if y < 0 {
+		y, sy = -y, -1
+	}
+	if x == 0 {
+		return hintVector{0, sy * 0x4000}
+	}
+	if y == 0 {
+		return hintVector{sx * 0x4000, 0}
+	}
+
+	// Scale (x, y) so that its approximate length is between 2/3 and 4/3
+	// in 16.16 fixed point.
+	ux, uy := uint32(x), uint32(y)
+	l := approxLength(ux, uy)
+	shift := 31 - msb(l)
+	shift -= 15
+	if l >= 0xaaaaaaaa>>uint(shift+15) {
+		shift--
+	}
+	if shift > 0 {
+		ux, uy = ux<<uint(shift), uy<<uint(shift)
+		l = approxLength(ux, uy)
+	} else {
+		ux, uy, l = ux>>uint(-shift), uy>>uint(-shift), l>>uint(-shift)
+	}
+
+	// Refine the reciprocal length, minus one, by Newton's iterations.
+	b := 0x10000 - int32(l)
+	x, y = int32(ux), int32(uy)
+	var u, v uint32
+	for {
+		u = uint32(x + (x * b >> 16))
+		v = uint32(y + (y * b >> 16))
+		z := -int32(u*u+v*v) / 0x200
+		z = z * ((0x10000 + b) >> 8) / 0x10000
+		b += z
+		if z <= 0 {
+			break
+		}
+	}
+	return hintVector{sx * int32(u) / 4, sy * int32(v) / 4}
+}
+
+// approxLength approximates the length of the vector (x, y).
+func approxLength(x, y uint32) uint32 {
+	if x > y {
+		return x + y>>1
+	}
+	return y + x>>1
+}
+
+// msb returns the index of the most significant set bit of x, which must not
+// be zero.
+func msb(x uint32) int {
+	i := 0
+	for ; x > 1; x >>= 1 {
+		i++
+	}
+	return i
+}
+
+type graphicsState struct {
+	// pv, fv and dv are the projection, freedom and dual projection vectors.
+	pv, fv, dv hintVector
+	// rp are the reference points, and zp the zone pointers.
+	rp [3]int32
+	zp [3]int32
+
+	controlValueCutIn fixed.Int26_6
+	singleWidthCutIn  fixed.Int26_6
+	singleWidth       fixed.Int26_6
+	minDist           fixed.Int26_6
+	deltaBase         int32
+	deltaShift        int32
+	loop              int32
+
+	// roundOff is whether rounding is turned off, and otherwise values are
+	// rounded to the nearest multiple of roundPeriod plus roundPhase, with
+	// the threshold giving the direction of the nearest multiple.
+	roundOff       bool
+	roundPeriod    fixed.Int26_6
+	roundPhase     fixed.Int26_6
+	roundThreshold fixed.Int26_6
+
+	autoFlip       bool
+	instructionsOn bool
+	// instructControl is set by the INSTCTRL instruction. Bit 0 turns
+	// off glyph instructions, and bit 1 makes them start from the default
+	// graphics state.
+	instructControl int32
+}
+
+var defaultGraphicsState = graphicsState{
+	pv:                hintVector{0x4000, 0},
+	fv:                hintVector{0x4000, 0},
+	dv:                hintVector{0x4000, 0},
+	zp:                [3]int32{glyphZone, glyphZone, glyphZone},
+	controlValueCutIn: 68, // 17/16 pixels.
+	minDist:           64,
+	deltaBase:         9,
+	deltaShift:        3,
+	loop:              1,
+	roundPeriod:       64,
+	roundThreshold:    32,
+	autoFlip:          true,
+}
+
+// resetForProgram resets the parts of the graphics state that every glyph
+// program, and the prep program, start with regardless of the state that
+// the previous program left.
+func (g *graphicsState) resetForProgram() {
+	g.pv = hintVector{0x4000, 0}
+	g.fv = hintVector{0x4000, 0}
+	g.dv = hintVector{0x4000, 0}
+	g.zp = [3]int32{glyphZone, glyphZone, glyphZone}
+	g.roundOff = false
+	g.roundPeriod, g.roundPhase, g.roundThreshold = 64, 0, 32
+	g.loop = 1
+}
+
+// funcDef is a function or instruction definition: the bytecode prog[start:]
+// up to the matching ENDF.
+type funcDef struct {
+	prog  []byte
+	start int
+}
+
+// callFrame is the state to return to at the end of a function.
+type callFrame struct {
+	prog  []byte
+	pc    int
+	start int
+	// count is the number of times that the function is still to be run.
+	count int32
+}
+
+// hinter is a TrueType bytecode interpreter. It is initialized for a font,
+// by running the font's fpgm program, and for a size, by running the prep
+// program, and then hints glyphs at that size.
+type hinter struct {
+	// font is the font that the fpgm program was last run for, and ppem is
+	// the size that the prep program was last run for, if prepDone.
+	font     *Font
+	ppem     fixed.Int26_6
+	prepDone bool
+
+	// intPPEM is ppem, rounded to an integer, as used by the MPPEM and DELTA
+	// instructions.
+	intPPEM   int32
+	upem      int64
+	scale16   int64
+	orusScale int64
+	maxStack  int
+	stack     []int32
+	frames    []callFrame
+	fpgm      []byte
+	prep      []byte
+	glyf      []byte
+	fdefs     []funcDef
+	idefs     [256]funcDef
+	rawCVT    []int16
+	cvt       []fixed.Int26_6
+	storage   []int32
+	twilight  []hintPoint
+	points    []hintPoint
+	ends      []int
+	gs        graphicsState
+	fdotp     int64
+	steps     int
+	glyphProg bool
+
+	// savedGS, savedCVT, savedStorage and savedTwilight are the state that
+	// the prep program left, which every glyph program starts from.
+	savedGS       graphicsState
+	savedCVT      []fixed.Int26_6
+	savedStorage  []int32
+	savedTwilight []hintPoint
+
+	// zone is the glyph zone, and zoneEnds its contours' inclusive end
+	// point indexes, while a glyph program runs.
+	zone     []hintPoint
+	zoneEnds []int
+
+	// ascent and descent place the top and bottom phantom points of glyphs
+	// in fonts without vertical metrics, in font units.
+	ascent, descent int32
+}
+
+// init prepares h to hint f's glyphs at ppem.
+func (h *hinter) init(f *Font, b *Buffer, ppem fixed.Int26_6) error {
+	if h.font != f {
+		h.font, h.prepDone = nil, false
+		if err := h.initFont(f, b); err != nil {
+			return err
+		}
+		h.font = f
+	}
+	if !h.prepDone || h.ppem != ppem {
+		h.prepDone = false
+		if err := h.initSize(ppem); err != nil {
+			return err
+		}
+		h.prepDone = true
+	}
+	return nil
+}
+
+func (h *hinter) initFont(f *Font, b *Buffer) error {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/maxp
+	if f.maxp.length < 32 {
+		return errInvalidMaxpTable
+	}
+	buf, err := b.view(&f.src, int(f.maxp.offset), 32)
+	if err != nil {
+		return err
+	}
+	maxTwilightPoints := int(u16(buf[16:]))
+	maxStorage := int(u16(buf[18:]))
+	maxFunctionDefs := int(u16(buf[20:]))
+	maxStackElements := int(u16(buf[24:]))
+
+	h.upem = int64(f.cached.unitsPerEm)
+	h.ascent, h.descent = f.cached.ascent, f.cached.descent
+	if f.os2.length >= 72 {
+		// https://docs.microsoft.com/en-us/typography/opentype/spec/os2#sta
+		if buf, err = b.view(&f.src, int(f.os2.offset)+68, 4); err != nil {
+			return err
+		}
+		h.ascent, h.descent = int32(int16(u16(buf))), int32(int16(u16(buf[2:])))
+	}
+	h.maxStack = maxStackElements + 32
+	h.stack = h.stack[:0]
+	h.fdefs = append(h.fdefs[:0], make([]funcDef, maxFunctionDefs)...)
+	h.idefs = [256]funcDef{}
+	h.storage = append(h.storage[:0], make([]int32, maxStorage)...)
+	h.twilight = append(h.twilight[:0], make([]hintPoint, maxTwilightPoints)...)
+
+	if h.fpgm, err = h.copyTable(h.fpgm, f, b, f.fpgm); err != nil {
+		return err
+	}
+	if h.prep, err = h.copyTable(h.prep, f, b, f.prep); err != nil {
+		return err
+	}
+	h.rawCVT = h.rawCVT[:0]
+	if f.cvt.length != 0 {
+		buf, err := b.view(&f.src, int(f.cvt.offset), int(f.cvt.length&^1))
+		if err != nil {
+			return err
+		}
+		for i := 0; i+1 < len(buf); i += 2 {
+			h.rawCVT = append(h.rawCVT, int16(u16(buf[i:])))
+		}
+	}
+	if f.coords != nil && len(h.rawCVT) != 0 {
+		deltas, err := f.cvtDeltas(b, len(h.rawCVT))
+		if err != nil {
+			return err
+		}
+		for i, d := range deltas {
+			h.rawCVT[i] += int16(roundDelta(d))
+		}
+	}
+	h.cvt = append(h.cvt[:0], make([]fixed.Int26_6, len(h.rawCVT))...)
+
+	// The fpgm program typically only defines functions, but runs as if
+	// hinting a glyph without any points.
+	h.gs = defaultGraphicsState
+	h.zone, h.zoneEnds = nil, nil
+	h.intPPEM = 0
+	return h.run(h.fpgm, false)
+}
+
+// copyTable returns the data of the table t, copied into dst, as the font
+// data may be in a Buffer that is re-used while hinting.
+func (h *hinter) copyTable(dst []byte, f *Font, b *Buffer, t table) ([]byte, error) {
+	if t.length == 0 {
+		return dst[:0], nil
+	}
+	buf, err := b.view(&f.src, int(t.offset), int(t.length))
+	if err != nil {
+		return nil, err
+	}
+	return append(dst[:0], buf...), nil
+}
+
+func (h *hinter) initSize(ppem fixed.Int26_6) error {
+	h.ppem = ppem
+	h.intPPEM = int32((ppem + 32) >> 6)
+	h.scale16 = mulDiv(int64(ppem), 1<<16, h.upem)
+	for i, v := range h.rawCVT {
+		h.cvt[i] = h.scale(int32(v))
+	}
+	for i := range h.storage {
+		h.storage[i] = 0
+	}
+	for i := range h.twilight {
+		h.twilight[i] = hintPoint{}
+	}
+	h.gs = defaultGraphicsState
+	h.zone, h.zoneEnds = nil, nil
+	if err := h.run(h.prep, false); err != nil {
+		return err
+	}
+	h.gs.resetForProgram()
+	h.savedGS = h.gs
+	h.savedCVT = append(h.savedCVT[:0], h.cvt...)
+	h.savedStorage = append(h.savedStorage[:0], h.storage...)
+	h.savedTwilight = append(h.savedTwilight[:0], h.twilight...)
+	return nil
+}
+
+// scale converts v from font units to 26.6 pixels. Like other rasterizers,
+// it multiplies by a 16.16 fixed point scale factor, so that hinted outlines
+// match theirs.
+func (h *hinter) scale(v int32) fixed.Int26_6 {
+	return fixed.Int26_6(mulDiv(int64(v), h.scale16, 1<<16))
+}
+
+// hint runs the glyph program prog on the glyph zone points, whose contours
+// end at the inclusive indexes ends, and whose last 4 points are the phantom
+// points. Errors in glyph programs are ignored, as by other rasterizers,
+// which leaves the points as the program left them.
+func (h *hinter) hint(prog []byte, points []hintPoint, ends []int, compound bool) {
+	if len(prog) == 0 || h.savedGS.instructControl&1 != 0 {
+		return
+	}
+	h.gs = h.savedGS
+	if h.gs.instructControl&2 != 0 {
+		h.gs = defaultGraphicsState
+	}
+	copy(h.cvt, h.savedCVT)
+	copy(h.storage, h.savedStorage)
+	copy(h.twilight, h.savedTwilight)
+	h.zone, h.zoneEnds = points, ends
+	// A compound glyph's points' unscaled coordinates are its components'
+	// hinted coordinates, already in 26.6 pixels.
+	h.orusScale = h.scale16
+	if compound {
+		h.orusScale = 1 << 16
+	}
+	h.run(prog, true)
+	h.zone, h.zoneEnds = nil, nil
+}
+
+// popCount is the number of stack elements that each opcode pops, not
+// counting those given by the loop variable or by a count that is itself
+// popped. -1 means an unused opcode, which may be defined by IDEF.
+var popCount = [256]int8{
+	// 1, 2, 3, 4, 5, 6, 7, 8, 9, a, b, c, d, e, f
+	0, 0, 0, 0, 0, 0, 2, 2, 2, 2, 2, 2, 0, 0, 0, 5, // 0x00 - 0x0f
+	1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 1, 0, 1, 1, 1, 1, // 0x10 - 0x1f
+	1, 1, 0, 2, 0, 1, 1, 2, -1, 1, 2, 1, 1, 0, 1, 1, // 0x20 - 0x2f
+	0, 0, 0, 0, 1, 1, 1, 1, 1, 0, 2, 2, 0, 0, 2, 2, // 0x30 - 0x3f
+	0, 0, 2, 1, 2, 1, 1, 1, 2, 2, 2, 0, 0, 0, 0, 1, // 0x40 - 0x4f
+	2, 2, 2, 2, 2, 2, 1, 1, 1, 0, 2, 2, 1, 1, 1, 1, // 0x50 - 0x5f
+	2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, // 0x60 - 0x6f
+	2, 1, 1, 1, 1, 1, 1, 1, 2, 2, 0, -1, 0, 0, 1, 1, // 0x70 - 0x7f
+	0, 2, 2, -1, -1, 1, 2, 2, 1, 1, 3, 2, 2, 1, 2, -1, // 0x80 - 0x8f
+	-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, // 0x90 - 0x9f
+	-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, // 0xa0 - 0xaf
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 0xb0 - 0xbf
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, // 0xc0 - 0xcf
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, // 0xd0 - 0xdf
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, // 0xe0 - 0xef
+	2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, // 0xf0 - 0xff
+}
+
+// instructionLength returns the length of the instruction at prog[pc],
+// including any inline data, or 0 if prog is truncated.
+func instructionLength(prog []byte, pc int) int {
+	n := 1
+	switch op := prog[pc]; {
+	case op == 0x40: // NPUSHB.
+		if pc+1 >= len(prog) {
+			return 0
+		}
+		n = 2 + int(prog[pc+1])
+	case op == 0x41: // NPUSHW.
+		if pc+1 >= len(prog) {
+			return 0
+		}
+		n = 2 + 2*int(prog[pc+1])
+	case op >= 0xb0 && op < 0xb8: // PUSHB.
+		n = 2 + int(op-0xb0)
+	case op >= 0xb8 && op < 0xc0: // PUSHW.
+		n = 3 + 2*int(op-0xb8)
+	}
+	if pc+n > len(prog) {
+		return 0
+	}
+	return n
+}
+
+// skip returns the pc after the instruction at prog[pc] that ends the
+// enclosing block: the matching ENDF if fdef, and otherwise the matching EIF
+// or, if stopAtElse, the matching ELSE.
+func skip(prog []byte, pc int, fdef, stopAtElse bool) (int, error) {
+	depth := 0
+	for pc += instructionLength(prog, pc); pc < len(prog); {
+		op := prog[pc]
+		switch {
+		case fdef && op == 0x2d: // ENDF.
+			return pc + 1, nil
+		case fdef && (op == 0x2c || op == 0x89): // FDEF, IDEF.
+			return 0, errInvalidBytecode
+		case !fdef && op == 0x58: // IF.
+			depth++
+		case !fdef && op == 0x1b: // ELSE.
+			if depth == 0 && stopAtElse {
+				return pc + 1, nil
+			}
+		case !fdef && op == 0x59: // EIF.
+			if depth == 0 {
+				return pc + 1, nil
+			}
+			depth--
+		}
+		n := instructionLength(prog, pc)
+		if n == 0 {
+			return 0, errInvalidBytecode
+		}
+		pc += n
+	}
+	return 0, errInvalidBytecode
+}
+
+// run runs the program prog. glyphProg is whether prog is a glyph program,
+// for which FDEF and IDEF are not allowed.
+func (h *hinter) run(prog []byte, glyphProg bool) error {
+	h.stack = h.stack[:0]
+	h.frames = h.frames[:0]
+	h.steps = 0
+	h.glyphProg = glyphProg
+	h.updateFDotP()
+	for pc := 0; ; {
+		if pc >= len(prog) {
+			if len(h.frames) != 0 {
+				// A function ran off the end of its program.
+				return errInvalidBytecode
+			}
+			return nil
+		}
+		if h.steps++; h.steps > maxHintingSteps {
+			return errUnsupportedBytecode
+		}
+		op := prog[pc]
+		n := popCount[op]
+		if n < 0 {
+			if d := h.idefs[op]; d.prog != nil {
+				if err := h.call(&prog, &pc, d, 1); err != nil {
+					return err
+				}
+				continue
+			}
+			return errInvalidBytecode
+		}
+		if len(h.stack) < int(n) {
+			// Like FreeType, replace the missing arguments with zeroes.
+			h.stack = append(h.stack[:0], make([]int32, n)...)
+		}
+		var err error
+		if prog, pc, err = h.step(prog, pc); err != nil {
+			return err
+		}
+	}
+}
+
+// call calls the function d, count times, returning to the instruction after
+// the one at (*prog)[*pc].
+func (h *hinter) call(prog *[]byte, pc *int, d funcDef, count int32) error {
+	if len(h.frames) >= maxHintingCallDepth {
+		return errUnsupportedBytecode
+	}
+	if count <= 0 {
+		*pc++
+		return nil
+	}
+	h.frames = append(h.frames, callFrame{prog: *prog, pc: *pc + 1, start: d.start, count: count})
+	*prog, *pc = d.prog, d.start
+	return nil
+}
+
+func (h *hinter) push(v int32) error {
+	if len(h.stack) >= h.maxStack {
+		return errInvalidBytecode
+	}
+	h.stack = append(h.stack, v)
+	return nil
+}
+
+func (h *hinter) pop() int32 {
+	v := h.stack[len(h.stack)-1]
+	h.stack = h.stack[:len(h.stack)-1]
+	return v
+}
+
+// zone returns the points of the zone that the zone pointer zp[i] points to.
+func (h *hinter) zonePoints(i int) []hintPoint {
+	if h.gs.zp[i] == twilightZone {
+		return h.twilight
+	}
+	return h.zone
+}
+
+// point returns the j'th point of the zone that zp[i] points to.
+func (h *hinter) point(i int, j int32) *hintPoint {
+	z := h.zonePoints(i)
+	if j < 0 || int(j) >= len(z) {
+		return nil
+	}
+	return &z[j]
+}
+
+func (h *hinter) updateFDotP() {
+	h.fdotp = (int64(h.gs.fv[0])*int64(h.gs.pv[0]) + int64(h.gs.fv[1])*int64(h.gs.pv[1])) >> 14
+	if -0x400 < h.fdotp && h.fdotp < 0x400 {
+		h.fdotp = 0x4000
+	}
+}
+
+// project returns the projection of (x, y) onto the projection vector.
+func (h *hinter) project(x, y fixed.Int26_6) fixed.Int26_6 {
+	return h.gs.pv.dot(x, y)
+}
+
+// dualProject returns the projection of (x, y) onto the dual projection
+// vector.
+func (h *hinter) dualProject(x, y fixed.Int26_6) fixed.Int26_6 {
+	return h.gs.dv.dot(x, y)
+}
+
+// originalDistance returns the distance between p1 and p2 in the original
+// outline, along the dual projection vector, for the MD and MDRP
+// instructions, whose p1 and p2 are in zones zp1 and zp0. Outside of the
+// twilight zone, it is measured in font units and then scaled, for precision.
+func (h *hinter) originalDistance(p1, p2 *hintPoint) fixed.Int26_6 {
+	if h.gs.zp[0] == twilightZone || h.gs.zp[1] == twilightZone {
+		return h.dualProject(p1.ox-p2.ox, p1.oy-p2.oy)
+	}
+	d := h.dualProject(fixed.Int26_6(p1.ux-p2.ux), fixed.Int26_6(p1.uy-p2.uy))
+	return fixed.Int26_6(mulDiv(int64(d), h.orusScale, 1<<16))
+}
+
+// move moves p along the freedom vector so that its projection onto the
+// projection vector changes by d, and marks it as touched if touch is set.
+func (h *hinter) move(p *hintPoint, d fixed.Int26_6, touch bool) {
+	if v := h.gs.fv[0]; v != 0 {
+		p.x += fixed.Int26_6(mulDiv(int64(d), int64(v), h.fdotp))
+		if touch {
+			p.flags |= pointTouchedX
+		}
+	}
+	if v := h.gs.fv[1]; v != 0 {
+		p.y += fixed.Int26_6(mulDiv(int64(d), int64(v), h.fdotp))
+		if touch {
+			p.flags |= pointTouchedY
+		}
+	}
+}
+
+// mulDiv returns a*b/c, rounded to the nearest integer.
+func mulDiv(a, b, c int64) int64 {
+	if c == 0 {
+		return 0
+	}
+	x := a * b
+	if (x < 0) != (c < 0) {
+		return -((abs64(x) + abs64(c)/2) / abs64(c))
+	}
+	return (abs64(x) + abs64(c)/2) / abs64(c)
+}
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func absInt26_6(x fixed.Int26_6) fixed.Int26_6 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// floorMultiple returns the largest multiple of m that is at most x.
+func floorMultiple(x, m fixed.Int26_6) fixed.Int26_6 {
+	q := x / m
+	if x%m != 0 && x < 0 {
+		q--
+	}
+	return q * m
+}
+
+// round rounds d according to the round state.
+func (h *hinter) round(d fixed.Int26_6) fixed.Int26_6 {
+	g := &h.gs
+	if g.roundOff {
+		return d
+	}
+	if d >= 0 {
+		v := floorMultiple(d-g.roundPhase+g.roundThreshold, g.roundPeriod) + g.roundPhase
+		if v < 0 {
+			v = g.roundPhase
+		}
+		return v
+	}
+	v := -floorMultiple(g.roundThreshold-g.roundPhase-d, g.roundPeriod) - g.roundPhase
+	if v > 0 {
+		v = -g.roundPhase
+	}
+	return v
+}
+
+// setRound sets the round state to the given period, phase and threshold.
+func (h *hinter) setRound(period, phase, threshold fixed.Int26_6) {
+	h.gs.roundOff = false
+	h.gs.roundPeriod, h.gs.roundPhase, h.gs.roundThreshold = period, phase, threshold
+}
+
+// setSuperRound sets the round state for the SROUND and S45ROUND
+// instructions, with a grid period in 2.14 fixed point.
+func (h *hinter) setSuperRound(gridPeriod, selector int32) {
+	var period int32
+	switch selector & 0xc0 {
+	case 0x00:
+		period = gridPeriod / 2
+	case 0x80:
+		period = gridPeriod * 2
+	default:
+		period = gridPeriod
+	}
+	phase := period * (selector & 0x30 >> 4) / 4
+	threshold := period - 1
+	if s := selector & 0x0f; s != 0 {
+		threshold = (s - 4) * period / 8
+	}
+	h.setRound(fixed.Int26_6(period>>8), fixed.Int26_6(phase>>8), fixed.Int26_6(threshold>>8))
+}
+
+// cvtIndex returns whether i is a valid control value table index.
+func (h *hinter) cvtIndex(i int32) bool {
+	return 0 <= i && int(i) < len(h.cvt)
+}
+
+// step executes the instruction at prog[pc], whose pops are known to be
+// available, and returns the program and pc of the next instruction.
+//
+// Like FreeType, and as many fonts in the wild rely on, invalid point, contour,
+// zone, storage and control value references are not errors: instructions
+// ignore them, and reads from them return zero.
+func (h *hinter) step(prog []byte, pc int) ([]byte, int, error) {
+	g := &h.gs
+	st := h.stack
+	top := len(st)
+	op := prog[pc]
+	next := pc + 1
+
+	switch op {
+	case 0x00, 0x01: // SVTCA[a].
+		v := hintVector{0, 0x4000}
+		if op&1 != 0 {
+			v = hintVector{0x4000, 0}
+		}
+		g.pv, g.fv, g.dv = v, v, v
+		h.updateFDotP()
+
+	case 0x02, 0x03: // SPVTCA[a].
+		v := hintVector{0, 0x4000}
+		if op&1 != 0 {
+			v = hintVector{0x4000, 0}
+		}
+		g.pv, g.dv = v, v
+		h.updateFDotP()
+
+	case 0x04, 0x05: // SFVTCA[a].
+		v := hintVector{0, 0x4000}
+		if op&1 != 0 {
+			v = hintVector{0x4000, 0}
+		}
+		g.fv = v
+		h.updateFDotP()
+
+	case 0x06, 0x07, 0x08, 0x09, 0x86, 0x87: // SPVTL[a], SFVTL[a], SDPVTL[a].
+		p2, p1 := h.point(2, st[top-1]), h.point(1, st[top-2])
+		h.stack = st[:top-2]
+		if p1 == nil || p2 == nil {
+			break
+		}
+		v := lineVector(p1.x-p2.x, p1.y-p2.y, op&1 != 0)
+		switch op {
+		case 0x06, 0x07:
+			g.pv, g.dv = v, v
+		case 0x08, 0x09:
+			g.fv = v
+		default:
+			g.pv = v
+			g.dv = lineVector(p1.ox-p2.ox, p1.oy-p2.oy, op&1 != 0)
+		}
+		h.updateFDotP()
+
+	case 0x0a, 0x0b: // SPVFS, SFVFS.
+		x, y := int32(int16(st[top-2])), int32(int16(st[top-1]))
+		h.stack = st[:top-2]
+		if x == 0 && y == 0 {
+			// Leave the vector unchanged.
+			break
+		}
+		v := normalize(x, y)
+		if op == 0x0a {
+			g.pv, g.dv = v, v
+		} else {
+			g.fv = v
+		}
+		h.updateFDotP()
+
+	case 0x0c, 0x0d: // GPV, GFV.
+		v := g.pv
+		if op == 0x0d {
+			v = g.fv
+		}
+		if err := h.push(v[0]); err != nil {
+			return nil, 0, err
+		}
+		if err := h.push(v[1]); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x0e: // SFVTPV.
+		g.fv = g.pv
+		h.updateFDotP()
+
+	case 0x0f: // ISECT.
+		b1, b0 := h.point(0, st[top-1]), h.point(0, st[top-2])
+		a1, a0 := h.point(1, st[top-3]), h.point(1, st[top-4])
+		p := h.point(2, st[top-5])
+		h.stack = st[:top-5]
+		if b1 == nil || b0 == nil || a1 == nil || a0 == nil || p == nil {
+			break
+		}
+		isect(p, a0, a1, b0, b1)
+
+	case 0x10, 0x11, 0x12: // SRP0, SRP1, SRP2.
+		g.rp[op-0x10] = st[top-1]
+		h.stack = st[:top-1]
+
+	case 0x13, 0x14, 0x15, 0x16: // SZP0, SZP1, SZP2, SZPS.
+		z := st[top-1]
+		h.stack = st[:top-1]
+		if z != twilightZone && z != glyphZone {
+			break
+		}
+		if op == 0x16 {
+			g.zp = [3]int32{z, z, z}
+		} else {
+			g.zp[op-0x13] = z
+		}
+
+	case 0x17: // SLOOP.
+		if st[top-1] < 0 {
+			return nil, 0, errInvalidBytecode
+		}
+		g.loop = st[top-1]
+		h.stack = st[:top-1]
+
+	case 0x18: // RTG.
+		h.setRound(64, 0, 32)
+
+	case 0x19: // RTHG.
+		h.setRound(64, 32, 32)
+
+	case 0x1a: // SMD.
+		g.minDist = fixed.Int26_6(st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x1b: // ELSE.
+		// Only reached at the end of an IF's true branch.
+		var err error
+		if next, err = skip(prog, pc, false, false); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x1c: // JMPR.
+		next = pc + int(st[top-1])
+		h.stack = st[:top-1]
+		if next < 0 || next > len(prog) || next == pc {
+			return nil, 0, errInvalidBytecode
+		}
+
+	case 0x1d: // SCVTCI.
+		g.controlValueCutIn = fixed.Int26_6(st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x1e: // SSWCI.
+		g.singleWidthCutIn = fixed.Int26_6(st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x1f: // SSW.
+		g.singleWidth = h.scale(st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x20: // DUP.
+		if err := h.push(st[top-1]); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x21: // POP.
+		h.stack = st[:top-1]
+
+	case 0x22: // CLEAR.
+		h.stack = st[:0]
+
+	case 0x23: // SWAP.
+		st[top-1], st[top-2] = st[top-2], st[top-1]
+
+	case 0x24: // DEPTH.
+		if err := h.push(int32(top)); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x25, 0x26: // CINDEX, MINDEX.
+		k := st[top-1]
+		if k <= 0 || int(k) >= top {
+			if op == 0x25 {
+				st[top-1] = 0
+			} else {
+				h.stack = st[:top-1]
+			}
+			break
+		}
+		i := top - 1 - int(k)
+		v := st[i]
+		if op == 0x25 {
+			st[top-1] = v
+		} else {
+			copy(st[i:], st[i+1:top-1])
+			st[top-2] = v
+			h.stack = st[:top-1]
+		}
+
+	case 0x27: // ALIGNPTS.
+		p2, p1 := h.point(0, st[top-1]), h.point(1, st[top-2])
+		h.stack = st[:top-2]
+		if p1 == nil || p2 == nil {
+			break
+		}
+		d := (h.project(p2.x-p1.x, p2.y-p1.y)) / 2
+		h.move(p1, d, true)
+		h.move(p2, -d, true)
+
+	case 0x29: // UTP.
+		p := h.point(0, st[top-1])
+		h.stack = st[:top-1]
+		if p == nil {
+			break
+		}
+		if g.fv[0] != 0 {
+			p.flags &^= pointTouchedX
+		}
+		if g.fv[1] != 0 {
+			p.flags &^= pointTouchedY
+		}
+
+	case 0x2a, 0x2b: // LOOPCALL, CALL.
+		i, count := st[top-1], int32(1)
+		if op == 0x2a {
+			count = st[top-2]
+			h.stack = st[:top-2]
+		} else {
+			h.stack = st[:top-1]
+		}
+		if i < 0 || int(i) >= len(h.fdefs) || h.fdefs[i].prog == nil {
+			return nil, 0, errInvalidBytecode
+		}
+		if err := h.call(&prog, &pc, h.fdefs[i], count); err != nil {
+			return nil, 0, err
+		}
+		return prog, pc, nil
+
+	case 0x2c, 0x89: // FDEF, IDEF.
+		i := st[top-1]
+		h.stack = st[:top-1]
+		if h.glyphProg {
+			return nil, 0, errInvalidBytecode
+		}
+		d := funcDef{prog: prog, start: pc + 1}
+		if op == 0x89 {
+			if i < 0 || i > 0xff {
+				return nil, 0, errInvalidBytecode
+			}
+			h.idefs[i] = d
+		} else {
+			if i < 0 || i >= maxHintingIndex {
+				return nil, 0, errInvalidBytecode
+			}
+			for int(i) >= len(h.fdefs) {
+				h.fdefs = append(h.fdefs, funcDef{})
+			}
+			h.fdefs[i] = d
+		}
+		var err error
+		if next, err = skip(prog, pc, true, false); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x2d: // ENDF.
+		if len(h.frames) == 0 {
+			return nil, 0, errInvalidBytecode
+		}
+		f := &h.frames[len(h.frames)-1]
+		if f.count--; f.count > 0 {
+			return prog, f.start, nil
+		}
+		prog, next = f.prog, f.pc
+		h.frames = h.frames[:len(h.frames)-1]
+
+	case 0x2e, 0x2f: // MDAP[a].
+		i := st[top-1]
+		p := h.point(0, i)
+		h.stack = st[:top-1]
+		if p == nil {
+			break
+		}
+		d := fixed.Int26_6(0)
+		if op == 0x2f {
+			c := h.project(p.x, p.y)
+			d = h.round(c) - c
+		}
+		h.move(p, d, true)
+		g.rp[0], g.rp[1] = i, i
+
+	case 0x30, 0x31: // IUP[a].
+		// Like FreeType, interpolate the glyph zone, whatever zp2 is.
+		h.iup(op == 0x31)
+
+	case 0x32, 0x33: // SHP[a].
+		if top < int(g.loop) {
+			g.loop = 1
+			break
+		}
+		dx, dy, ok := h.displacement(op&1 != 0)
+		if !ok {
+			break
+		}
+		for ; g.loop > 0; g.loop-- {
+			if p := h.point(2, h.pop()); p != nil {
+				h.shift(p, dx, dy, true)
+			}
+		}
+		g.loop = 1
+
+	case 0x34, 0x35: // SHC[a].
+		c := st[top-1]
+		h.stack = st[:top-1]
+		dx, dy, ok := h.displacement(op&1 != 0)
+		if !ok || g.zp[2] != glyphZone || c < 0 || int(c) >= len(h.zoneEnds) {
+			break
+		}
+		start := 0
+		if c > 0 {
+			start = h.zoneEnds[c-1] + 1
+		}
+		ref := h.refPoint(op&1 != 0)
+		for i := start; i <= h.zoneEnds[c] && i < len(h.zone); i++ {
+			if p := &h.zone[i]; p != ref {
+				h.shift(p, dx, dy, true)
+			}
+		}
+
+	case 0x36, 0x37: // SHZ[a].
+		z := st[top-1]
+		h.stack = st[:top-1]
+		dx, dy, ok := h.displacement(op&1 != 0)
+		if !ok || (z != twilightZone && z != glyphZone) {
+			break
+		}
+		// Like FreeType, shift the zone that zp2 points to, not the popped
+		// zone, and in the glyph zone, shift only the contours' points, not
+		// the phantom points.
+		points := h.zonePoints(2)
+		if h.gs.zp[2] == glyphZone {
+			n := 0
+			if len(h.zoneEnds) > 0 {
+				n = h.zoneEnds[len(h.zoneEnds)-1] + 1
+			}
+			points = points[:n]
+		}
+		ref := h.refPoint(op&1 != 0)
+		for i := range points {
+			if p := &points[i]; p != ref {
+				h.shift(p, dx, dy, false)
+			}
+		}
+
+	case 0x38: // SHPIX.
+		d := int64(st[top-1])
+		h.stack = st[:top-1]
+		if top-1 < int(g.loop) {
+			g.loop = 1
+			break
+		}
+		dx := fixed.Int26_6((d*int64(g.fv[0]) + 0x2000) >> 14)
+		dy := fixed.Int26_6((d*int64(g.fv[1]) + 0x2000) >> 14)
+		for ; g.loop > 0; g.loop-- {
+			if p := h.point(2, h.pop()); p != nil {
+				h.shift(p, dx, dy, true)
+			}
+		}
+		g.loop = 1
+
+	case 0x39: // IP.
+		rp1, rp2 := h.point(0, g.rp[1]), h.point(1, g.rp[2])
+		if rp1 == nil || top < int(g.loop) {
+			g.loop = 1
+			break
+		}
+		// Outside of the twilight zone, the original distances are measured
+		// in font units, for precision. An invalid rp2 is an empty range.
+		twilight := g.zp[0] == twilightZone || g.zp[1] == twilightZone || g.zp[2] == twilightZone
+		orgRange, curRange := fixed.Int26_6(0), fixed.Int26_6(0)
+		if rp2 != nil {
+			orgRange = h.dualProject(rp2.ox-rp1.ox, rp2.oy-rp1.oy)
+			if !twilight {
+				orgRange = h.dualProject(fixed.Int26_6(rp2.ux-rp1.ux), fixed.Int26_6(rp2.uy-rp1.uy))
+			}
+			curRange = h.project(rp2.x-rp1.x, rp2.y-rp1.y)
+		}
+		for ; g.loop > 0; g.loop-- {
+			p := h.point(2, h.pop())
+			if p == nil {
+				continue
+			}
+			orgDist := h.dualProject(p.ox-rp1.ox, p.oy-rp1.oy)
+			if !twilight {
+				orgDist = h.dualProject(fixed.Int26_6(p.ux-rp1.ux), fixed.Int26_6(p.uy-rp1.uy))
+			}
+			curDist := h.project(p.x-rp1.x, p.y-rp1.y)
+			newDist := orgDist
+			if orgRange != 0 {
+				newDist = fixed.Int26_6(mulDiv(int64(orgDist), int64(curRange), int64(orgRange)))
+			}
+			h.move(p, newDist-curDist, true)
+		}
+		g.loop = 1
+
+	case 0x3a, 0x3b: // MSIRP[a].
+		d := fixed.Int26_6(st[top-1])
+		i := st[top-2]
+		h.stack = st[:top-2]
+		p, rp0 := h.point(1, i), h.point(0, g.rp[0])
+		if p == nil || rp0 == nil {
+			break
+		}
+		if g.zp[1] == twilightZone {
+			p.ox, p.oy = rp0.ox, rp0.oy
+			h.move(p, d, false)
+			p.ox, p.oy = p.x, p.y
+			p.x, p.y = rp0.x, rp0.y
+			h.move(p, d, false)
+		}
+		h.move(p, d-h.project(p.x-rp0.x, p.y-rp0.y), true)
+		g.rp[1], g.rp[2] = g.rp[0], i
+		if op == 0x3b {
+			g.rp[0] = i
+		}
+
+	case 0x3c: // ALIGNRP.
+		rp0 := h.point(0, g.rp[0])
+		if rp0 == nil || top < int(g.loop) {
+			g.loop = 1
+			break
+		}
+		for ; g.loop > 0; g.loop-- {
+			if p := h.point(1, h.pop()); p != nil {
+				h.move(p, -h.project(p.x-rp0.x, p.y-rp0.y), true)
+			}
+		}
+		g.loop = 1
+
+	case 0x3d: // RTDG.
+		h.setRound(32, 0, 16)
+
+	case 0x3e, 0x3f: // MIAP[a].
+		c, i := st[top-1], st[top-2]
+		h.stack = st[:top-2]
+		p := h.point(0, i)
+		if p == nil || !h.cvtIndex(c) {
+			break
+		}
+		d := h.cvt[c]
+		if g.zp[0] == twilightZone {
+			p.ox = fixed.Int26_6((int64(d)*int64(g.fv[0]) + 0x2000) >> 14)
+			p.oy = fixed.Int26_6((int64(d)*int64(g.fv[1]) + 0x2000) >> 14)
+			p.x, p.y = p.ox, p.oy
+		}
+		orgDist := h.project(p.x, p.y)
+		if op == 0x3f {
+			if absInt26_6(d-orgDist) > g.controlValueCutIn {
+				d = orgDist
+			}
+			d = h.round(d)
+		}
+		h.move(p, d-orgDist, true)
+		g.rp[0], g.rp[1] = i, i
+
+	case 0x40, 0x41: // NPUSHB, NPUSHW.
+		n := instructionLength(prog, pc)
+		if n == 0 {
+			return nil, 0, errInvalidBytecode
+		}
+		if err := h.pushData(prog[pc+2:pc+n], op == 0x41); err != nil {
+			return nil, 0, err
+		}
+		next = pc + n
+
+	case 0x42: // WS.
+		v, i := st[top-1], st[top-2]
+		h.stack = st[:top-2]
+		if 0 <= i && int(i) < len(h.storage) {
+			h.storage[i] = v
+		}
+
+	case 0x43: // RS.
+		i := st[top-1]
+		st[top-1] = 0
+		if 0 <= i && int(i) < len(h.storage) {
+			st[top-1] = h.storage[i]
+		}
+
+	case 0x44, 0x70: // WCVTP, WCVTF.
+		v, i := st[top-1], st[top-2]
+		h.stack = st[:top-2]
+		if !h.cvtIndex(i) {
+			break
+		}
+		if op == 0x44 {
+			h.cvt[i] = fixed.Int26_6(v)
+		} else {
+			h.cvt[i] = h.scale(v)
+		}
+
+	case 0x45: // RCVT.
+		i := st[top-1]
+		st[top-1] = 0
+		if h.cvtIndex(i) {
+			st[top-1] = int32(h.cvt[i])
+		}
+
+	case 0x46, 0x47: // GC[a].
+		p := h.point(2, st[top-1])
+		if p == nil {
+			st[top-1] = 0
+			break
+		}
+		if op == 0x46 {
+			st[top-1] = int32(h.project(p.x, p.y))
+		} else {
+			st[top-1] = int32(h.dualProject(p.ox, p.oy))
+		}
+
+	case 0x48: // SCFS.
+		v, i := fixed.Int26_6(st[top-1]), st[top-2]
+		h.stack = st[:top-2]
+		p := h.point(2, i)
+		if p == nil {
+			break
+		}
+		h.move(p, v-h.project(p.x, p.y), true)
+		if g.zp[2] == twilightZone {
+			p.ox, p.oy = p.x, p.y
+		}
+
+	case 0x49, 0x4a: // MD[a].
+		p2, p1 := h.point(1, st[top-1]), h.point(0, st[top-2])
+		h.stack = st[:top-1]
+		switch {
+		case p1 == nil || p2 == nil:
+			st[top-2] = 0
+		case op == 0x49:
+			st[top-2] = int32(h.project(p1.x-p2.x, p1.y-p2.y))
+		default:
+			st[top-2] = int32(h.originalDistance(p1, p2))
+		}
+
+	case 0x4b, 0x4c: // MPPEM, MPS.
+		if err := h.push(h.intPPEM); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x4d, 0x4e: // FLIPON, FLIPOFF.
+		g.autoFlip = op == 0x4d
+
+	case 0x4f: // DEBUG.
+		h.stack = st[:top-1]
+
+	case 0x50: // LT.
+		st[top-2] = bool32(st[top-2] < st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x51: // LTEQ.
+		st[top-2] = bool32(st[top-2] <= st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x52: // GT.
+		st[top-2] = bool32(st[top-2] > st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x53: // GTEQ.
+		st[top-2] = bool32(st[top-2] >= st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x54: // EQ.
+		st[top-2] = bool32(st[top-2] == st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x55: // NEQ.
+		st[top-2] = bool32(st[top-2] != st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x56: // ODD.
+		st[top-1] = bool32(h.round(fixed.Int26_6(st[top-1]))&127 == 64)
+
+	case 0x57: // EVEN.
+		st[top-1] = bool32(h.round(fixed.Int26_6(st[top-1]))&127 == 0)
+
+	case 0x58: // IF.
+		c := st[top-1]
+		h.stack = st[:top-1]
+		if c == 0 {
+			var err error
+			if next, err = skip(prog, pc, false, true); err != nil {
+				return nil, 0, err
+			}
+		}
+
+	case 0x59: // EIF.
+		// No-op.
+
+	case 0x5a: // AND.
+		st[top-2] = bool32(st[top-2] != 0 && st[top-1] != 0)
+		h.stack = st[:top-1]
+
+	case 0x5b: // OR.
+		st[top-2] = bool32(st[top-2] != 0 || st[top-1] != 0)
+		h.stack = st[:top-1]
+
+	case 0x5c: // NOT.
+		st[top-1] = bool32(st[top-1] == 0)
+
+	case 0x5d, 0x71, 0x72, 0x73, 0x74, 0x75: // DELTAP1-3, DELTAC1-3.
+		if err := h.delta(op); err != nil {
+			return nil, 0, err
+		}
+
+	case 0x5e: // SDB.
+		g.deltaBase = st[top-1]
+		h.stack = st[:top-1]
+
+	case 0x5f: // SDS.
+		if st[top-1] < 0 || st[top-1] > 6 {
+			return nil, 0, errInvalidBytecode
+		}
+		g.deltaShift = st[top-1]
+		h.stack = st[:top-1]
+
+	case 0x60: // ADD.
+		st[top-2] += st[top-1]
+		h.stack = st[:top-1]
+
+	case 0x61: // SUB.
+		st[top-2] -= st[top-1]
+		h.stack = st[:top-1]
+
+	case 0x62: // DIV.
+		if st[top-1] == 0 {
+			return nil, 0, errInvalidBytecode
+		}
+		st[top-2] = int32(int64(st[top-2]) * 64 / int64(st[top-1]))
+		h.stack = st[:top-1]
+
+	case 0x63: // MUL.
+		st[top-2] = int32(mulDiv(int64(st[top-2]), int64(st[top-1]), 64))
+		h.stack = st[:top-1]
+
+	case 0x64: // ABS.
+		if st[top-1] < 0 {
+			st[top-1] = -st[top-1]
+		}
+
+	case 0x65: // NEG.
+		st[top-1] = -st[top-1]
+
+	case 0x66: // FLOOR.
+		st[top-1] &^= 63
+
+	case 0x67: // CEILING.
+		st[top-1] = (st[top-1] + 63) &^ 63
+
+	case 0x68, 0x69, 0x6a, 0x6b: // ROUND[ab].
+		st[top-1] = int32(h.round(fixed.Int26_6(st[top-1])))
+
+	case 0x6c, 0x6d, 0x6e, 0x6f: // NROUND[ab].
+		// No-op, as there is no engine compensation.
+
+	case 0x76, 0x77: // SROUND, S45ROUND.
+		gridPeriod := int32(0x4000)
+		if op == 0x77 {
+			gridPeriod = 0x2d41 // 1/√2 in 2.14 fixed point.
+		}
+		h.setSuperRound(gridPeriod, st[top-1])
+		h.stack = st[:top-1]
+
+	case 0x78, 0x79: // JROT, JROF.
+		c, offset := st[top-1], st[top-2]
+		h.stack = st[:top-2]
+		if (c != 0) == (op == 0x78) {
+			next = pc + int(offset)
+			if next < 0 || next > len(prog) || next == pc {
+				return nil, 0, errInvalidBytecode
+			}
+		}
+
+	case 0x7a: // ROFF.
+		g.roundOff = true
+
+	case 0x7c: // RUTG.
+		h.setRound(64, 0, 63)
+
+	case 0x7d: // RDTG.
+		h.setRound(64, 0, 0)
+
+	case 0x7e, 0x7f, 0x85, 0x8d: // SANGW, AA, SCANCTRL, SCANTYPE.
+		h.stack = st[:top-1]
+
+	case 0x80: // FLIPPT.
+		// Like FreeType, flip the glyph zone's points, whatever zp0 is.
+		if top < int(g.loop) {
+			g.loop = 1
+			break
+		}
+		for ; g.loop > 0; g.loop-- {
+			if i := h.pop(); 0 <= i && int(i) < len(h.zone) {
+				h.zone[i].flags ^= pointOnCurve
+			}
+		}
+		g.loop = 1
+
+	case 0x81, 0x82: // FLIPRGON, FLIPRGOFF.
+		hi, lo := st[top-1], st[top-2]
+		h.stack = st[:top-2]
+		z := h.zone
+		if lo < 0 || int(hi) >= len(z) {
+			break
+		}
+		for i := lo; i <= hi; i++ {
+			if op == 0x81 {
+				z[i].flags |= pointOnCurve
+			} else {
+				z[i].flags &^= pointOnCurve
+			}
+		}
+
+	case 0x88: // GETINFO.
+		v := int32(0)
+		if st[top-1]&1 != 0 {
+			// The rasterizer version, 35, is that of FreeType's
+			// interpreter that this one follows.
+			v = 35
+		}
+		st[top-1] = v
+
+	case 0x8a: // ROLL.
+		st[top-1], st[top-2], st[top-3] = st[top-3], st[top-1], st[top-2]
+
+	case 0x8b: // MAX.
+		if st[top-1] > st[top-2] {
+			st[top-2] = st[top-1]
+		}
+		h.stack = st[:top-1]
+
+	case 0x8c: // MIN.
+		if st[top-1] < st[top-2] {
+			st[top-2] = st[top-1]
+		}
+		h.stack = st[:top-1]
+
+	case 0x8e: // INSTCTRL.
+		selector, v := st[top-1], st[top-2]
+		h.stack = st[:top-2]
+		if !h.glyphProg && (selector == 1 || selector == 2) {
+			bit := int32(1) << uint(selector-1)
+			if v != 0 {
+				g.instructControl |= bit
+			} else {
+				g.instructControl &^= bit
+			}
+		}
+
+	default:
+		switch {
+		case op >= 0xb0 && op < 0xc0: // PUSHB, PUSHW.
+			n := instructionLength(prog, pc)
+			if n == 0 {
+				return nil, 0, errInvalidBytecode
+			}
+			if err := h.pushData(prog[pc+1:pc+n], op >= 0xb8); err != nil {
+				return nil, 0, err
+			}
+			next = pc + n
+
+		case op >= 0xc0 && op < 0xe0: // MDRP[abcde].
+			h.mdrp(op, st[top-1])
+			h.stack = st[:top-1]
+
+		case op >= 0xe0: // MIRP[abcde].
+			h.mirp(op, st[top-2], st[top-1])
+			h.stack = st[:top-2]
+
+		default:
+			return nil, 0, errInvalidBytecode
+		}
+	}
+	return prog, next, nil
+}
+
+func bool32(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// lineVector returns the unit vector in the direction of (dx, dy), rotated
+// 90 degrees counter-clockwise if perpendicular is set. A zero (dx, dy) means
+// the x axis, whether or not perpendicular is set.
+func lineVector(dx, dy fixed.Int26_6, perpendicular bool) hintVector {
+	if dx == 0 && dy == 0 {
+		return hintVector{0x4000, 0}
+	}
+	if perpendicular {
+		dx, dy = -dy, dx
+	}
+	return normalize(int32(dx), int32(dy))
+}
+
+// pushData pushes the inline data of a push instruction, as bytes or words.
+func (h *hinter) pushData(data []byte, words bool) error {
+	if words {
+		for i := 0; i+1 < len(data); i += 2 {
+			if err := h.push(int32(int16(u16(data[i:])))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, v := range data {
+		if err := h.push(int32(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refPoint returns the reference point of the SHP, SHC and SHZ instructions:
+// rp1 in zp0 if useRP1, and otherwise rp2 in zp1.
+func (h *hinter) refPoint(useRP1 bool) *hintPoint {
+	if useRP1 {
+		return h.point(0, h.gs.rp[1])
+	}
+	return h.point(1, h.gs.rp[2])
+}
+
+// displacement returns how far the reference point of the SHP, SHC and SHZ
+// instructions has moved, along the freedom vector.
+func (h *hinter) displacement(useRP1 bool) (dx, dy fixed.Int26_6, ok bool) {
+	p := h.refPoint(useRP1)
+	if p == nil {
+		return 0, 0, false
+	}
+	d := int64(h.project(p.x-p.ox, p.y-p.oy))
+	dx = fixed.Int26_6(mulDiv(d, int64(h.gs.fv[0]), h.fdotp))
+	dy = fixed.Int26_6(mulDiv(d, int64(h.gs.fv[1]), h.fdotp))
+	return dx, dy, true
+}
+
+// shift moves p by (dx, dy), marking it as touched along the freedom vector
+// if touch is set.
+func (h *hinter) shift(p *hintPoint, dx, dy fixed.Int26_6, touch bool) {
+	if h.gs.fv[0] != 0 {
+		p.x += dx
+		if touch {
+			p.flags |= pointTouchedX
+		}
+	}
+	if h.gs.fv[1] != 0 {
+		p.y += dy
+		if touch {
+			p.flags |= pointTouchedY
+		}
+	}
+}
+
+// isect moves p to the intersection of the lines a0-a1 and b0-b1.
+func isect(p, a0, a1, b0, b1 *hintPoint) {
+	dbx, dby := int64(b1.x-b0.x), int64(b1.y-b0.y)
+	dax, day := int64(a1.x-a0.x), int64(a1.y-a0.y)
+	dx, dy := int64(b0.x-a0.x), int64(b0.y-a0.y)
+	discriminant := mulDiv(dax, -dby, 64) + mulDiv(day, dbx, 64)
+	dotProduct := mulDiv(dax, dbx, 64) + mulDiv(day, dby, 64)
+	// Only intersect lines that are not nearly parallel, at an angle of
+	// more than about 3 degrees.
+	if 19*abs64(discriminant) > abs64(dotProduct) {
+		v := mulDiv(dx, -dby, 64) + mulDiv(dy, dbx, 64)
+		p.x = a0.x + fixed.Int26_6(mulDiv(v, dax, discriminant))
+		p.y = a0.y + fixed.Int26_6(mulDiv(v, day, discriminant))
+	} else {
+		p.x = (a0.x + a1.x + b0.x + b1.x) / 4
+		p.y = (a0.y + a1.y + b0.y + b1.y) / 4
+	}
+	p.flags |= pointTouchedX | pointTouchedY
+}
+
+// delta executes the DELTAP1-3 and DELTAC1-3 instructions.
+func (h *hinter) delta(op byte) error {
+	g := &h.gs
+	n := h.pop()
+	if n < 0 {
+		return errInvalidBytecode
+	}
+	// Like FreeType, tolerate the invalid DELTA instructions that some
+	// popular fonts contain: missing arguments empty the stack, and out of
+	// range points and cvt entries are ignored.
+	short := len(h.stack) < 2*int(n)
+	if short {
+		n = int32(len(h.stack) / 2)
+	}
+	base := g.deltaBase
+	switch op {
+	case 0x71, 0x74:
+		base += 16
+	case 0x72, 0x75:
+		base += 32
+	}
+	for ; n > 0; n-- {
+		i, arg := h.pop(), h.pop()
+		if base+(arg&0xf0>>4) != h.intPPEM {
+			continue
+		}
+		step := arg&0x0f - 8
+		if step >= 0 {
+			step++
+		}
+		d := fixed.Int26_6(step * 64 / (1 << uint(g.deltaShift)))
+		if op == 0x5d || op == 0x71 || op == 0x72 {
+			if p := h.point(0, i); p != nil {
+				h.move(p, d, true)
+			}
+		} else if h.cvtIndex(i) {
+			h.cvt[i] += d
+		}
+	}
+	if short {
+		h.stack = h.stack[:0]
+	}
+	return nil
+}
+
+// mdrp executes the MDRP instruction op for the i'th point.
+func (h *hinter) mdrp(op byte, i int32) {
+	g := &h.gs
+	p, rp0 := h.point(1, i), h.point(0, g.rp[0])
+	if p != nil && rp0 != nil {
+		h.move(p, h.mdrpDistance(op, p, rp0)-h.project(p.x-rp0.x, p.y-rp0.y), true)
+	}
+	g.rp[1], g.rp[2] = g.rp[0], i
+	if op&0x10 != 0 {
+		g.rp[0] = i
+	}
+}
+
+// mdrpDistance returns the distance that the MDRP instruction op moves p to,
+// from rp0.
+func (h *hinter) mdrpDistance(op byte, p, rp0 *hintPoint) fixed.Int26_6 {
+	g := &h.gs
+	orgDist := h.originalDistance(p, rp0)
+	if absInt26_6(orgDist-g.singleWidth) < g.singleWidthCutIn {
+		if orgDist >= 0 {
+			orgDist = g.singleWidth
+		} else {
+			orgDist = -g.singleWidth
+		}
+	}
+	d := orgDist
+	if op&0x04 != 0 {
+		d = h.round(orgDist)
+	}
+	if op&0x08 != 0 {
+		d = h.minDist(orgDist, d)
+	}
+	return d
+}
+
+// mirp executes the MIRP instruction op for the i'th point and the c'th
+// control value.
+func (h *hinter) mirp(op byte, i, c int32) {
+	g := &h.gs
+	p, rp0 := h.point(1, i), h.point(0, g.rp[0])
+	if p != nil && rp0 != nil && (c == -1 || h.cvtIndex(c)) {
+		h.mirpMove(op, p, rp0, c)
+	}
+	g.rp[1], g.rp[2] = g.rp[0], i
+	if op&0x10 != 0 {
+		g.rp[0] = i
+	}
+}
+
+// mirpMove moves p as the MIRP instruction op does, relative to rp0 and the
+// c'th control value, where -1 means a zero distance.
+func (h *hinter) mirpMove(op byte, p, rp0 *hintPoint, c int32) {
+	g := &h.gs
+	cvtDist := fixed.Int26_6(0)
+	if c != -1 {
+		cvtDist = h.cvt[c]
+	}
+	if absInt26_6(cvtDist-g.singleWidth) < g.singleWidthCutIn {
+		if cvtDist >= 0 {
+			cvtDist = g.singleWidth
+		} else {
+			cvtDist = -g.singleWidth
+		}
+	}
+	if g.zp[1] == twilightZone {
+		p.ox = rp0.ox + fixed.Int26_6((int64(cvtDist)*int64(g.fv[0])+0x2000)>>14)
+		p.oy = rp0.oy + fixed.Int26_6((int64(cvtDist)*int64(g.fv[1])+0x2000)>>14)
+		p.x, p.y = p.ox, p.oy
+	}
+	orgDist := h.dualProject(p.ox-rp0.ox, p.oy-rp0.oy)
+	curDist := h.project(p.x-rp0.x, p.y-rp0.y)
+	if g.autoFlip && (orgDist < 0) != (cvtDist < 0) && orgDist != 0 && cvtDist != 0 {
+		cvtDist = -cvtDist
+	}
+	d := cvtDist
+	if op&0x04 != 0 {
+		if g.zp[0] == g.zp[1] && absInt26_6(cvtDist-orgDist) > g.controlValueCutIn {
+			d = orgDist
+		}
+		d = h.round(d)
+	}
+	if op&0x08 != 0 {
+		d = h.minDist(orgDist, d)
+	}
+	h.move(p, d-curDist, true)
+}
+
+// minDist returns d, made at least the minimum distance in magnitude, in the
+// direction of orgDist.
+func (h *hinter) minDist(orgDist, d fixed.Int26_6) fixed.Int26_6 {
+	m := h.gs.minDist
+	if orgDist >= 0 {
+		if d < m {
+			d = m
+		}
+	} else if d > -m {
+		d = -m
+	}
+	return d
+}
+
+// iup interpolates the untouched points of the glyph zone's contours, in the
+// x direction if x is set and otherwise in the y direction, from the touched
+// points on either side of them.
+func (h *hinter) iup(x bool) {
+	touched := uint8(pointTouchedY)
+	if x {
+		touched = pointTouchedX
+	}
+	start := 0
+	for _, end := range h.zoneEnds {
+		if end >= len(h.zone) {
+			return
+		}
+		first := -1
+		for i := start; i <= end; i++ {
+			if h.zone[i].flags&touched != 0 {
+				first = i
+				break
+			}
+		}
+		if first >= 0 {
+			prev := first
+			for i := first + 1; i <= end; i++ {
+				if h.zone[i].flags&touched != 0 {
+					h.iupInterpolate(x, prev+1, i-1, prev, i)
+					prev = i
+				}
+			}
+			if prev == first {
+				h.iupShift(x, start, end, first)
+			} else {
+				// Interpolate the points between the last and first touched
+				// points, which wrap around the end of the contour.
+				h.iupInterpolate(x, prev+1, end, prev, first)
+				if first > start {
+					h.iupInterpolate(x, start, first-1, prev, first)
+				}
+			}
+		}
+		start = end + 1
+	}
+}
+
+// coords returns pointers to p's current, original and unscaled coordinates
+// in the x or y direction.
+func coords(p *hintPoint, x bool) (cur, org *fixed.Int26_6, orus int32) {
+	if x {
+		return &p.x, &p.ox, p.ux
+	}
+	return &p.y, &p.oy, p.uy
+}
+
+// iupShift shifts the untouched points of zone[start:end+1] by as much as the
+// touched point zone[ref] has moved.
+func (h *hinter) iupShift(x bool, start, end, ref int) {
+	cur, org, _ := coords(&h.zone[ref], x)
+	d := *cur - *org
+	for i := start; i <= end; i++ {
+		if i != ref {
+			c, _, _ := coords(&h.zone[i], x)
+			*c += d
+		}
+	}
+}
+
+// iupInterpolate interpolates the points of zone[p1:p2+1] from the touched
+// points zone[ref1] and zone[ref2].
+func (h *hinter) iupInterpolate(x bool, p1, p2, ref1, ref2 int) {
+	if p1 > p2 {
+		return
+	}
+	cur1p, org1p, orus1 := coords(&h.zone[ref1], x)
+	cur2p, org2p, orus2 := coords(&h.zone[ref2], x)
+	cur1, org1, cur2, org2 := *cur1p, *org1p, *cur2p, *org2p
+	if orus1 > orus2 {
+		cur1, org1, orus1, cur2, org2, orus2 = cur2, org2, orus2, cur1, org1, orus1
+	}
+	delta1, delta2 := cur1-org1, cur2-org2
+	// The interpolation uses a 16.16 fixed point scale, rounded as by other
+	// rasterizers, instead of the exact ratio.
+	scale := int64(0)
+	if orus1 != orus2 {
+		scale = mulDiv(int64(cur2-cur1), 1<<16, int64(orus2-orus1))
+	}
+	for i := p1; i <= p2; i++ {
+		c, o, u := coords(&h.zone[i], x)
+		switch {
+		case *o <= org1:
+			*c = *o + delta1
+		case *o >= org2:
+			*c = *o + delta2
+		case cur1 == cur2 || orus1 == orus2:
+			*c = cur1
+		default:
+			*c = cur1 + fixed.Int26_6(mulDiv(int64(u-orus1), scale, 1<<16))
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestHintedSegments(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var b Buffer
+	x, err := f.GlyphIndex(&b, 'H')
+	if err != nil {
+		t.Fatalf("GlyphIndex: %v", err)
+	}
+
+	// These are the points that FreeType's v35 interpreter gives. The Go
+	// fonts' instructions fit the horizontal edges to the pixel grid.
+	want := []Segment{
+		moveTo(62, 0),
+		lineTo(62, 576),
+		lineTo(141, 576),
+		lineTo(141, 318),
+		lineTo(414, 318),
+		lineTo(414, 576),
+		lineTo(492, 576),
+		lineTo(492, 0),
+		lineTo(414, 0),
+		lineTo(414, 258),
+		lineTo(141, 258),
+		lineTo(141, 0),
+		lineTo(62, 0),
+	}
+	got, err := f.LoadGlyph(&b, x, fixed.I(12), &LoadGlyphOptions{Hinting: font.HintingFull})
+	if err != nil {
+		t.Fatalf("LoadGlyph: %v", err)
+	}
+	if err := checkSegmentsEqual(got, want); err != nil {
+		t.Fatalf("HintingFull: %v", err)
+	}
+
+	// HintingVertical keeps the hinted y coordinates, but not the x ones.
+	unhinted, err := f.LoadGlyph(&b, x, fixed.I(12), nil)
+	if err != nil {
+		t.Fatalf("LoadGlyph: %v", err)
+	}
+	unhinted = append([]Segment(nil), unhinted...)
+	got, err = f.LoadGlyph(&b, x, fixed.I(12), &LoadGlyphOptions{Hinting: font.HintingVertical})
+	if err != nil {
+		t.Fatalf("LoadGlyph: %v", err)
+	}
+	if len(got) != len(want) || len(unhinted) != len(want) {
+		t.Fatalf("HintingVertical: got %d and %d segments, want %d", len(got), len(unhinted), len(want))
+	}
+	for i, s := range got {
+		if y := -want[i].Args[0].Y; s.Args[0].Y != y {
+			t.Errorf("HintingVertical: segment %d: got y %v, want %v", i, s.Args[0].Y, y)
+		}
+		if dx := s.Args[0].X - unhinted[i].Args[0].X; dx < -1 || dx > +1 {
+			t.Errorf("HintingVertical: segment %d: got x %v, want %v", i, s.Args[0].X, unhinted[i].Args[0].X)
+		}
+	}
+}
+
+func TestHintingAllGlyphs(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"goregular", goregular.TTF},
+		{"gobold", gobold.TTF},
+		{"goitalic", goitalic.TTF},
+		{"gomono", gomono.TTF},
+	} {
+		f, err := Parse(tc.data)
+		if err != nil {
+			t.Errorf("%s: Parse: %v", tc.name, err)
+			continue
+		}
+		var b Buffer
+		for _, ppem := range []int{7, 12, 16, 33} {
+			opts := &LoadGlyphOptions{Hinting: font.HintingFull}
+			for i, n := 0, f.NumGlyphs(); i < n; i++ {
+				segments, err := f.LoadGlyph(&b, GlyphIndex(i), fixed.I(ppem), opts)
+				if err != nil {
+					t.Errorf("%s: ppem=%d, glyph %d: LoadGlyph: %v", tc.name, ppem, i, err)
+					continue
+				}
+				// Segments must start each contour with a move.
+				if len(segments) != 0 && segments[0].Op != SegmentOpMoveTo {
+					t.Errorf("%s: ppem=%d, glyph %d: first segment is not a move", tc.name, ppem, i)
+				}
+			}
+		}
+	}
+}
+
+func TestHinterRun(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		prog    []byte
+		want    []int32
+		wantErr error
+	}{{
+		desc: "arithmetic",
+		prog: []byte{
+			0xb2, 0x02, 0x03, 0x80, // PUSHB[2] 2, 3, 128
+			0x60,       // ADD
+			0xb0, 0x80, // PUSHB[0] 128
+			0x62, // DIV
+		},
+		want: []int32{2, 65},
+	}, {
+		desc: "if-else",
+		prog: []byte{
+			0xb0, 0x00, // PUSHB[0] 0
+			0x58,       // IF
+			0xb0, 0x01, // PUSHB[0] 1
+			0x1b,       // ELSE
+			0xb0, 0x02, // PUSHB[0] 2
+			0x59, // EIF
+		},
+		want: []int32{2},
+	}, {
+		desc: "functions",
+		prog: []byte{
+			0xb0, 0x00, // PUSHB[0] 0
+			0x2c,       // FDEF
+			0xb0, 0x07, // PUSHB[0] 7
+			0x2d,             // ENDF
+			0xb1, 0x03, 0x00, // PUSHB[1] 3, 0
+			0x2a,       // LOOPCALL
+			0xb0, 0x00, // PUSHB[0] 0
+			0x2b, // CALL
+		},
+		want: []int32{7, 7, 7, 7},
+	}, {
+		desc: "rounding",
+		prog: []byte{
+			0xb1, 0x60, 0x60, // PUSHB[1] 96, 96
+			0x68, // ROUND[0]
+			0x23, // SWAP
+			0x19, // RTHG
+			0x68, // ROUND[0]
+		},
+		want: []int32{128, 96},
+	}, {
+		desc: "missing arguments are zero",
+		prog: []byte{
+			0xb0, 0x05, // PUSHB[0] 5
+			0x8b,       // MAX, of 0 and 0, as one argument is missing.
+			0xb0, 0x09, // PUSHB[0] 9
+			0x25, // CINDEX, of an element that is not on the stack.
+		},
+		want: []int32{0, 0},
+	}, {
+		desc:    "division by zero",
+		prog:    []byte{0xb1, 0x01, 0x00, 0x62},
+		wantErr: errInvalidBytecode,
+	}, {
+		desc:    "undefined opcode",
+		prog:    []byte{0x28},
+		wantErr: errInvalidBytecode,
+	}, {
+		desc:    "undefined function",
+		prog:    []byte{0xb0, 0x01, 0x2b},
+		wantErr: errInvalidBytecode,
+	}, {
+		desc: "infinite loop",
+		prog: []byte{
+			0xb8, 0xff, 0xfd, // PUSHW[0] -3
+			0x1c, // JMPR
+		},
+		wantErr: errUnsupportedBytecode,
+	}} {
+		h := &hinter{
+			maxStack: 16,
+			fdefs:    make([]funcDef, 1),
+			gs:       defaultGraphicsState,
+		}
+		err := h.run(tc.prog, false)
+		if err != tc.wantErr {
+			t.Errorf("%s: got error %v, want %v", tc.desc, err, tc.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(h.stack) != len(tc.want) {
+			t.Errorf("%s: got stack %v, want %v", tc.desc, h.stack, tc.want)
+			continue
+		}
+		for i := range h.stack {
+			if h.stack[i] != tc.want[i] {
+				t.Errorf("%s: got stack %v, want %v", tc.desc, h.stack, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHinterMovePoints(t *testing.T) {
+	h := &hinter{
+		maxStack: 16,
+		gs:       defaultGraphicsState,
+		zone: []hintPoint{
+			{x: 40, y: 100, ox: 40, oy: 100, ux: 40, uy: 100},
+			{x: 200, y: 100, ox: 200, oy: 100, ux: 200, uy: 100},
+			{x: 120, y: 100, ox: 120, oy: 100, ux: 120, uy: 100},
+		},
+		zoneEnds:  []int{2},
+		orusScale: 1 << 16,
+	}
+	prog := []byte{
+		0x01,                         // SVTCA[1], the x axis.
+		0xb3, 0x02, 0x01, 0x01, 0x00, // PUSHB[3] 2, 1, 1, 0
+		0x2f,       // MDAP[1] point 0, rounding it to 64.
+		0x2f,       // MDAP[1] point 1, rounding it to 192.
+		0x12,       // SRP2 point 1.
+		0xb0, 0x00, // PUSHB[0] 0
+		0x11, // SRP1 point 0.
+		0x39, // IP point 2, halfway between them.
+	}
+	if err := h.run(prog, false); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for i, want := range []fixed.Int26_6{64, 192, 128} {
+		p := h.zone[i]
+		if p.x != want || p.y != 100 {
+			t.Errorf("point %d: got (%v, %v), want (%v, %v)", i, p.x, p.y, want, fixed.Int26_6(100))
+		}
+		if p.flags&pointTouchedX == 0 || p.flags&pointTouchedY != 0 {
+			t.Errorf("point %d: got flags %#x, want only touched in x", i, p.flags)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"unsafe"
+)
+
+// MemStats is an estimate of the memory, in bytes, that a Font, Buffer or
+// font face retains, for programs that hold many fonts to decide which to
+// evict. The estimates count the memory of the values' fields and of the
+// slices that they refer to, but not the Go runtime's overheads.
+type MemStats struct {
+	// Source is the font data. It is the length of the []byte that a Font
+	// was parsed from, which is shared by the Fonts of a Collection and by
+	// the instances of a variable Font, and zero for a Font parsed from an
+	// io.ReaderAt.
+	Source int
+	// Tables is the data that is decoded from the font's tables when it is
+	// parsed, such as its glyph locations, cmap ranges and table directory.
+	Tables int
+	// Buffers is the scratch memory for loading glyphs, which grows to
+	// hold the largest glyph loaded so far.
+	Buffers int
+	// Caches is the memory of cached results, such as rasterized glyphs.
+	Caches int
+}
+
+// Total returns the sum of s's fields.
+func (s MemStats) Total() int {
+	return s.Source + s.Tables + s.Buffers + s.Caches
+}
+
+// MemStats returns an estimate of the memory that f retains. Its Buffers and
+// Caches are zero: a Font holds no scratch memory or caches, which are held
+// by the Buffers passed to its methods instead.
+func (f *Font) MemStats() MemStats {
+	c := &f.cached
+	n := int(unsafe.Sizeof(*f))
+	n += 4 * (cap(c.glyphData.locations) + cap(c.glyphData.gsubrs) + cap(c.glyphData.singleSubrs))
+	for _, s := range c.glyphData.multiSubrs {
+		n += int(unsafe.Sizeof(s)) + 4*cap(s)
+	}
+	n += cap(c.bitmapStrikes) * int(unsafe.Sizeof(bitmapStrike{}))
+	n += cap(c.cmapRanges) * int(unsafe.Sizeof(cmapRange{}))
+	// The lookup functions' closures are counted as one pointer each. What
+	// they capture is small, as they read the font's tables on demand.
+	n += (cap(c.kernFuncs) + cap(c.ligatureFuncs)) * int(unsafe.Sizeof(uintptr(0)))
+	if c.post != nil {
+		n += int(unsafe.Sizeof(*c.post))
+	}
+	for _, a := range c.variationAxes {
+		n += len(a.Tag)
+	}
+	n += cap(c.variationAxes) * int(unsafe.Sizeof(VariationAxis{}))
+	n += 2 * cap(f.coords)
+	n += cap(f.directory) * int(unsafe.Sizeof(tableRecord{}))
+	return MemStats{
+		Source: len(f.src.b),
+		Tables: n,
+	}
+}
+
+// MemStats returns an estimate of the memory that b retains, as its Buffers.
+func (b *Buffer) MemStats() MemStats {
+	n := int(unsafe.Sizeof(*b))
+	n += cap(b.buf)
+	n += cap(b.segments) * int(unsafe.Sizeof(Segment{}))
+	if h := b.hinter; h != nil {
+		n += h.memSize()
+	}
+	n += b.variation.memSize()
+	return MemStats{Buffers: n}
+}
+
+// Trim releases the scratch memory that b has grown to hold, including that
+// of its TrueType hinting interpreter, such as for a long-lived Buffer after
+// loading unusually large glyphs. b remains usable, and grows again as
+// needed.
+func (b *Buffer) Trim() {
+	*b = Buffer{}
+}
+
+// memSize returns the size of h and of its slices, other than those of the
+// font's programs, which are views of the font data.
+func (h *hinter) memSize() int {
+	const point = int(unsafe.Sizeof(hintPoint{}))
+	n := int(unsafe.Sizeof(*h))
+	n += 4 * (cap(h.stack) + cap(h.storage) + cap(h.savedStorage))
+	n += 4 * (cap(h.cvt) + cap(h.savedCVT))
+	n += 2 * cap(h.rawCVT)
+	n += cap(h.frames) * int(unsafe.Sizeof(callFrame{}))
+	n += cap(h.fdefs) * int(unsafe.Sizeof(funcDef{}))
+	n += point * (cap(h.twilight) + cap(h.savedTwilight) + cap(h.points) + cap(h.zone))
+	n += int(unsafe.Sizeof(0)) * (cap(h.ends) + cap(h.zoneEnds))
+	return n
+}
+
+// memSize returns the size of v's slices.
+func (v *varBuffer) memSize() int {
+	n := 8 * (cap(v.deltas) + cap(v.tuple))
+	n += cap(v.touched) + cap(v.sharedTuples)
+	n += 2 * (cap(v.sharedPoints) + cap(v.points) + cap(v.regions))
+	n += 4 * cap(v.packed)
+	n += cap(v.glyf) * int(unsafe.Sizeof(hintPoint{}))
+	n += int(unsafe.Sizeof(0)) * (cap(v.ends) + cap(v.glyfEnds))
+	return n
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestMemStats(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	s := f.MemStats()
+	if s.Source != len(goregular.TTF) {
+		t.Errorf("Source: got %d, want %d", s.Source, len(goregular.TTF))
+	}
+	// The glyph locations alone are 4 bytes per glyph.
+	if min := 4 * f.NumGlyphs(); s.Tables < min {
+		t.Errorf("Tables: got %d, want at least %d", s.Tables, min)
+	}
+	if s.Buffers != 0 || s.Caches != 0 {
+		t.Errorf("Buffers, Caches: got %d, %d, want 0, 0", s.Buffers, s.Caches)
+	}
+
+	g, err := ParseReaderAt(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatalf("ParseReaderAt: %v", err)
+	}
+	if got := g.MemStats().Source; got != 0 {
+		t.Errorf("ParseReaderAt: Source: got %d, want 0", got)
+	}
+
+	var b Buffer
+	empty := b.MemStats().Buffers
+	x, err := f.GlyphIndex(&b, 'G')
+	if err != nil {
+		t.Fatalf("GlyphIndex: %v", err)
+	}
+	if _, err := f.LoadGlyph(&b, x, fixed.I(16), &LoadGlyphOptions{Hinting: font.HintingFull}); err != nil {
+		t.Fatalf("LoadGlyph: %v", err)
+	}
+	if got := b.MemStats().Buffers; got <= empty {
+		t.Errorf("after hinting: Buffers: got %d, want more than %d", got, empty)
+	}
+	b.Trim()
+	if got := b.MemStats().Buffers; got != empty {
+		t.Errorf("after Trim: Buffers: got %d, want %d", got, empty)
+	}
+	if _, err := f.LoadGlyph(&b, x, fixed.I(16), &LoadGlyphOptions{Hinting: font.HintingFull}); err != nil {
+		t.Fatalf("LoadGlyph after Trim: %v", err)
+	}
+}
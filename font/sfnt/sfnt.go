@@ -29,6 +29,7 @@ import (
 	"errors"
 	"image"
 	"io"
+	"sort"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
@@ -63,6 +64,7 @@ const (
 	maxNumFontDicts           = 256
 	maxNumFonts               = 256
 	maxNumTables              = 256
+	maxNumVariationAxes       = 64
 	maxRealNumberStrLen       = 64 // Maximum length in bytes of the "-123.456E-7" representation.
 
 	// (maxTableOffset + maxTableLength) will not overflow an int32.
@@ -77,15 +79,26 @@ var (
 	// ErrNotFound indicates that the requested value was not found.
 	ErrNotFound = errors.New("sfnt: not found")
 
+	errInvalidAvarTable       = errors.New("sfnt: invalid avar table")
 	errInvalidBounds          = errors.New("sfnt: invalid bounds")
+	errInvalidBytecode        = errors.New("sfnt: invalid hinting bytecode")
 	errInvalidCFFTable        = errors.New("sfnt: invalid CFF table")
+	errInvalidCBLCTable       = errors.New("sfnt: invalid CBLC table")
+	errInvalidCOLRTable       = errors.New("sfnt: invalid COLR table")
+	errInvalidCPALTable       = errors.New("sfnt: invalid CPAL table")
 	errInvalidCmapTable       = errors.New("sfnt: invalid cmap table")
+	errInvalidCvarTable       = errors.New("sfnt: invalid cvar table")
 	errInvalidDfont           = errors.New("sfnt: invalid dfont")
 	errInvalidFont            = errors.New("sfnt: invalid font")
 	errInvalidFontCollection  = errors.New("sfnt: invalid font collection")
+	errInvalidFvarTable       = errors.New("sfnt: invalid fvar table")
+	errInvalidGDEFTable       = errors.New("sfnt: invalid GDEF table")
 	errInvalidGPOSTable       = errors.New("sfnt: invalid GPOS table")
+	errInvalidGSUBTable       = errors.New("sfnt: invalid GSUB table")
 	errInvalidGlyphData       = errors.New("sfnt: invalid glyph data")
 	errInvalidGlyphDataLength = errors.New("sfnt: invalid glyph data length")
+	errInvalidGvarTable       = errors.New("sfnt: invalid gvar table")
+	errInvalidHVARTable       = errors.New("sfnt: invalid HVAR table")
 	errInvalidHeadTable       = errors.New("sfnt: invalid head table")
 	errInvalidHheaTable       = errors.New("sfnt: invalid hhea table")
 	errInvalidHmtxTable       = errors.New("sfnt: invalid hmtx table")
@@ -96,22 +109,38 @@ var (
 	errInvalidNameTable       = errors.New("sfnt: invalid name table")
 	errInvalidOS2Table        = errors.New("sfnt: invalid OS/2 table")
 	errInvalidPostTable       = errors.New("sfnt: invalid post table")
+	errInvalidSbixTable       = errors.New("sfnt: invalid sbix table")
 	errInvalidSingleFont      = errors.New("sfnt: invalid single font (data is a font collection)")
 	errInvalidSourceData      = errors.New("sfnt: invalid source data")
 	errInvalidTableOffset     = errors.New("sfnt: invalid table offset")
 	errInvalidTableTagOrder   = errors.New("sfnt: invalid table tag order")
 	errInvalidUCS2String      = errors.New("sfnt: invalid UCS-2 string")
+	errInvalidVheaTable       = errors.New("sfnt: invalid vhea table")
+	errInvalidVmtxTable       = errors.New("sfnt: invalid vmtx table")
+	errInvalidWebFont         = errors.New("sfnt: invalid WOFF or WOFF2 data")
 
+	errNoBrotliDecompressor = errors.New("sfnt: no Brotli decompressor for WOFF2 data (see RegisterBrotli)")
+
+	errUnsupportedCFFCharset           = errors.New("sfnt: unsupported CFF charset")
 	errUnsupportedCFFFDSelectTable     = errors.New("sfnt: unsupported CFF FDSelect table")
 	errUnsupportedCFFVersion           = errors.New("sfnt: unsupported CFF version")
+	errUnsupportedBitmapFormat         = errors.New("sfnt: unsupported bitmap format")
+	errUnsupportedBytecode             = errors.New("sfnt: unsupported hinting bytecode")
+	errUnsupportedCOLRTable            = errors.New("sfnt: unsupported COLR table")
+	errUnsupportedCaretValueFormat     = errors.New("sfnt: unsupported caret value format")
 	errUnsupportedClassDefFormat       = errors.New("sfnt: unsupported class definition format")
 	errUnsupportedCmapEncodings        = errors.New("sfnt: unsupported cmap encodings")
 	errUnsupportedCompoundGlyph        = errors.New("sfnt: unsupported compound glyph")
 	errUnsupportedCoverageFormat       = errors.New("sfnt: unsupported coverage format")
 	errUnsupportedExtensionPosFormat   = errors.New("sfnt: unsupported extension positioning format")
+	errUnsupportedFvarTable            = errors.New("sfnt: unsupported fvar table")
+	errUnsupportedGDEFTable            = errors.New("sfnt: unsupported GDEF table")
 	errUnsupportedGPOSTable            = errors.New("sfnt: unsupported GPOS table")
+	errUnsupportedGSUBTable            = errors.New("sfnt: unsupported GSUB table")
 	errUnsupportedGlyphDataLength      = errors.New("sfnt: unsupported glyph data length")
+	errUnsupportedHVARTable            = errors.New("sfnt: unsupported HVAR table")
 	errUnsupportedKernTable            = errors.New("sfnt: unsupported kern table")
+	errUnsupportedNumberOfAxes         = errors.New("sfnt: unsupported number of variation axes")
 	errUnsupportedNumberOfCmapSegments = errors.New("sfnt: unsupported number of cmap segments")
 	errUnsupportedNumberOfFontDicts    = errors.New("sfnt: unsupported number of font dicts")
 	errUnsupportedNumberOfFonts        = errors.New("sfnt: unsupported number of fonts")
@@ -123,6 +152,9 @@ var (
 	errUnsupportedRealNumberEncoding   = errors.New("sfnt: unsupported real number encoding")
 	errUnsupportedTableOffsetLength    = errors.New("sfnt: unsupported table offset or length")
 	errUnsupportedType2Charstring      = errors.New("sfnt: unsupported Type 2 Charstring")
+	errUnsupportedWOFF2Collection      = errors.New("sfnt: unsupported WOFF2 font collection")
+	errUnsupportedWOFF2Transform       = errors.New("sfnt: unsupported WOFF2 table transform")
+	errUnsupportedWebFontLength        = errors.New("sfnt: unsupported WOFF or WOFF2 length")
 )
 
 // GlyphIndex is a glyph index in a Font.
@@ -319,8 +351,13 @@ type table struct {
 // from a []byte data source.
 //
 // If passed data for a single font, a TTF or OTF instead of a TTC or OTC, it
-// will return a collection containing 1 font.
+// will return a collection containing 1 font. So will WOFF and WOFF2 web
+// fonts, as for Parse.
 func ParseCollection(src []byte) (*Collection, error) {
+	src, err := decodeWebFont(src)
+	if err != nil {
+		return nil, err
+	}
 	c := &Collection{src: source{b: src}}
 	if err := c.initialize(); err != nil {
 		return nil, err
@@ -332,9 +369,14 @@ func ParseCollection(src []byte) (*Collection, error) {
 // from an io.ReaderAt data source.
 //
 // If passed data for a single font, a TTF or OTF instead of a TTC or OTC, it
-// will return a collection containing 1 font.
+// will return a collection containing 1 font. So will WOFF and WOFF2 web
+// fonts, as for ParseReaderAt.
 func ParseCollectionReaderAt(src io.ReaderAt) (*Collection, error) {
-	c := &Collection{src: source{r: src}}
+	s, err := readerAtSource(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &Collection{src: s}
 	if err := c.initialize(); err != nil {
 		return nil, err
 	}
@@ -512,7 +554,15 @@ func (c *Collection) Font(i int) (*Font, error) {
 
 // Parse parses an SFNT font, such as TTF or OTF data, from a []byte data
 // source.
+//
+// It also parses WOFF and WOFF2 web fonts, whose tables it decompresses into a
+// new SFNT font file. WOFF2 fonts need a Brotli decompressor: see
+// RegisterBrotli.
 func Parse(src []byte) (*Font, error) {
+	src, err := decodeWebFont(src)
+	if err != nil {
+		return nil, err
+	}
 	f := &Font{src: source{b: src}}
 	if err := f.initialize(0, false); err != nil {
 		return nil, err
@@ -522,14 +572,37 @@ func Parse(src []byte) (*Font, error) {
 
 // ParseReaderAt parses an SFNT font, such as TTF or OTF data, from an
 // io.ReaderAt data source.
+//
+// It also parses WOFF and WOFF2 web fonts, as for Parse, but reads them into
+// memory to decompress them.
 func ParseReaderAt(src io.ReaderAt) (*Font, error) {
-	f := &Font{src: source{r: src}}
+	s, err := readerAtSource(src)
+	if err != nil {
+		return nil, err
+	}
+	f := &Font{src: s}
 	if err := f.initialize(0, false); err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
+// readerAtSource returns the source for src: src itself, or the decompressed
+// SFNT font file if src is a web font.
+func readerAtSource(src io.ReaderAt) (source, error) {
+	if src == nil {
+		return source{}, nil
+	}
+	b, err := readWebFont(src)
+	if err != nil {
+		return source{}, err
+	}
+	if b != nil {
+		return source{b: b}, nil
+	}
+	return source{r: src}, nil
+}
+
 // Font is an SFNT font.
 //
 // Many of its methods take a *Buffer argument, as re-using buffers can reduce
@@ -575,10 +648,12 @@ type Font struct {
 	// https://www.microsoft.com/typography/otspec/otff.htm#otttables
 	// "Tables Related to TrueType Outlines".
 	//
-	// This implementation does not support hinting, so it does not read the
-	// cvt, fpgm gasp or prep tables.
+	// The gasp table is not read.
+	cvt  table
+	fpgm table
 	glyf table
 	loca table
+	prep table
 
 	// https://www.microsoft.com/typography/otspec/otff.htm#otttables
 	// "Tables Related to PostScript Outlines".
@@ -590,42 +665,89 @@ type Font struct {
 	// "Tables Related to Bitmap Glyphs".
 	//
 	// TODO: Others?
+	cbdt table
 	cblc table
+	sbix table
+
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/otff#tables-related-to-color-fonts
+	// "Tables Related to Color Fonts".
+	//
+	// TODO: cbdt, sbix, svg?
+	colr table
+	cpal table
 
 	// https://www.microsoft.com/typography/otspec/otff.htm#otttables
 	// "Advanced Typographic Tables".
 	//
-	// TODO: base, gdef, gsub, jstf, math?
+	// TODO: base, jstf, math?
+	gdef table
 	gpos table
+	gsub table
 
 	// https://www.microsoft.com/typography/otspec/otff.htm#otttables
 	// "Other OpenType Tables".
 	//
-	// TODO: hdmx, vmtx? Others?
+	// TODO: hdmx? Others?
 	kern table
+	vhea table
+	vmtx table
+
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/otff#tables-used-for-opentype-font-variations
+	// "Tables Used for OpenType Font Variations".
+	//
+	// TODO: cff2, mvar, vvar?
+	avar table
+	cvar table
+	fvar table
+	gvar table
+	hvar table
+
+	// coords are the normalized coordinates, in 2.14 fixed point, of the
+	// instance of a variable font, one per axis of the fvar table. It is nil
+	// for the default instance.
+	coords []int16
+
+	// directory holds every table of the font, including those listed
+	// above, sorted by tag.
+	directory []tableRecord
 
 	cached struct {
 		ascent           int32
+		bitmapStrikes    []bitmapStrike
 		capHeight        int32
 		glyphData        glyphData
 		glyphIndex       glyphIndexFunc
+		cmapRanges       []cmapRange
 		bounds           [4]int16
 		descent          int32
+		gdef             gdef
 		indexToLocFormat bool // false means short, true means long.
 		isColorBitmap    bool
 		isPostScript     bool
 		kernNumPairs     int32
 		kernOffset       int32
 		kernFuncs        []kernFunc
+		ligatureFuncs    []ligatureFunc
 		lineGap          int32
 		numHMetrics      int32
+		numVMetrics      int32
 		post             *PostTable
 		slope            [2]int32
 		unitsPerEm       Units
+		variationAxes    []VariationAxis
+		vertAscent       int32
+		vertDescent      int32
+		vertLineGap      int32
 		xHeight          int32
 	}
 }
 
+// tableRecord is an entry of a font's table directory.
+type tableRecord struct {
+	tag uint32
+	table
+}
+
 // NumGlyphs returns the number of glyphs in f.
 func (f *Font) NumGlyphs() int { return len(f.cached.glyphData.locations) - 1 }
 
@@ -661,7 +783,7 @@ func (f *Font) initialize(offset int, isDfont bool) error {
 	if err != nil {
 		return err
 	}
-	buf, glyphIndex, err := f.parseCmap(buf)
+	buf, glyphIndex, cmapRanges, err := f.parseCmap(buf)
 	if err != nil {
 		return err
 	}
@@ -673,6 +795,18 @@ func (f *Font) initialize(offset int, isDfont bool) error {
 	if err != nil {
 		return err
 	}
+	buf, ligatureFuncs, err := f.parseGSUBLigatures(buf)
+	if err != nil {
+		return err
+	}
+	buf, gdef, err := f.parseGDEF(buf)
+	if err != nil {
+		return err
+	}
+	buf, bitmapStrikes, err := f.parseBitmapStrikes(buf, numGlyphs)
+	if err != nil {
+		return err
+	}
 	buf, ascent, descent, lineGap, run, rise, numHMetrics, err := f.parseHhea(buf, numGlyphs)
 	if err != nil {
 		return err
@@ -681,6 +815,10 @@ func (f *Font) initialize(offset int, isDfont bool) error {
 	if err != nil {
 		return err
 	}
+	buf, vertAscent, vertDescent, vertLineGap, numVMetrics, err := f.parseVheaVmtx(buf, numGlyphs)
+	if err != nil {
+		return err
+	}
 	buf, hasXHeightCapHeight, xHeight, capHeight, err := f.parseOS2(buf)
 	if err != nil {
 		return err
@@ -689,24 +827,37 @@ func (f *Font) initialize(offset int, isDfont bool) error {
 	if err != nil {
 		return err
 	}
+	buf, variationAxes, err := f.parseFvar(buf)
+	if err != nil {
+		return err
+	}
 
 	f.cached.ascent = ascent
+	f.cached.bitmapStrikes = bitmapStrikes
 	f.cached.capHeight = capHeight
 	f.cached.glyphData = glyphData
 	f.cached.glyphIndex = glyphIndex
+	f.cached.cmapRanges = cmapRanges
 	f.cached.bounds = bounds
 	f.cached.descent = descent
+	f.cached.gdef = gdef
 	f.cached.indexToLocFormat = indexToLocFormat
 	f.cached.isColorBitmap = isColorBitmap
 	f.cached.isPostScript = isPostScript
 	f.cached.kernNumPairs = kernNumPairs
 	f.cached.kernOffset = kernOffset
 	f.cached.kernFuncs = kernFuncs
+	f.cached.ligatureFuncs = ligatureFuncs
 	f.cached.lineGap = lineGap
 	f.cached.numHMetrics = numHMetrics
+	f.cached.numVMetrics = numVMetrics
 	f.cached.post = post
 	f.cached.slope = [2]int32{run, rise}
 	f.cached.unitsPerEm = unitsPerEm
+	f.cached.variationAxes = variationAxes
+	f.cached.vertAscent = vertAscent
+	f.cached.vertDescent = vertDescent
+	f.cached.vertLineGap = vertLineGap
 	f.cached.xHeight = xHeight
 
 	if !hasXHeightCapHeight {
@@ -784,20 +935,46 @@ func (f *Font) initializeTables(offset int, isDfont bool) (buf1 []byte, isPostSc
 			return nil, false, errInvalidTableOffset
 		}
 
+		f.directory = append(f.directory, tableRecord{tag, table{o, n}})
+
 		// Match the 4-byte tag as a uint32. For example, "OS/2" is 0x4f532f32.
 		switch tag {
+		case 0x43424454:
+			f.cbdt = table{o, n}
 		case 0x43424c43:
 			f.cblc = table{o, n}
 		case 0x43464620:
 			f.cff = table{o, n}
+		case 0x434f4c52:
+			f.colr = table{o, n}
+		case 0x4350414c:
+			f.cpal = table{o, n}
 		case 0x4f532f32:
 			f.os2 = table{o, n}
+		case 0x61766172:
+			f.avar = table{o, n}
 		case 0x636d6170:
 			f.cmap = table{o, n}
+		case 0x63766172:
+			f.cvar = table{o, n}
+		case 0x63767420:
+			f.cvt = table{o, n}
+		case 0x6670676d:
+			f.fpgm = table{o, n}
+		case 0x66766172:
+			f.fvar = table{o, n}
 		case 0x676c7966:
 			f.glyf = table{o, n}
+		case 0x67766172:
+			f.gvar = table{o, n}
+		case 0x47444546:
+			f.gdef = table{o, n}
 		case 0x47504f53:
 			f.gpos = table{o, n}
+		case 0x47535542:
+			f.gsub = table{o, n}
+		case 0x48564152:
+			f.hvar = table{o, n}
 		case 0x68656164:
 			f.head = table{o, n}
 		case 0x68686561:
@@ -814,25 +991,33 @@ func (f *Font) initializeTables(offset int, isDfont bool) (buf1 []byte, isPostSc
 			f.name = table{o, n}
 		case 0x706f7374:
 			f.post = table{o, n}
+		case 0x70726570:
+			f.prep = table{o, n}
+		case 0x73626978:
+			f.sbix = table{o, n}
+		case 0x76686561:
+			f.vhea = table{o, n}
+		case 0x766d7478:
+			f.vmtx = table{o, n}
 		}
 	}
 	return buf, isPostScript, nil
 }
 
-func (f *Font) parseCmap(buf []byte) (buf1 []byte, glyphIndex glyphIndexFunc, err error) {
+func (f *Font) parseCmap(buf []byte) (buf1 []byte, glyphIndex glyphIndexFunc, ranges []cmapRange, err error) {
 	// https://www.microsoft.com/typography/OTSPEC/cmap.htm
 
 	const headerSize, entrySize = 4, 8
 	if f.cmap.length < headerSize {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 	u, err := f.src.u16(buf, f.cmap, 2)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 	numSubtables := int(u)
 	if f.cmap.length < headerSize+entrySize*uint32(numSubtables) {
-		return nil, nil, errInvalidCmapTable
+		return nil, nil, nil, errInvalidCmapTable
 	}
 
 	var (
@@ -847,7 +1032,7 @@ func (f *Font) parseCmap(buf []byte) (buf1 []byte, glyphIndex glyphIndexFunc, er
 	for i := 0; i < numSubtables; i++ {
 		buf, err = f.src.view(buf, int(f.cmap.offset)+headerSize+entrySize*i, entrySize)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		pid := u16(buf)
 		psid := u16(buf[2:])
@@ -858,11 +1043,11 @@ func (f *Font) parseCmap(buf []byte) (buf1 []byte, glyphIndex glyphIndexFunc, er
 		offset := u32(buf[4:])
 
 		if offset > f.cmap.length-4 {
-			return nil, nil, errInvalidCmapTable
+			return nil, nil, nil, errInvalidCmapTable
 		}
 		buf, err = f.src.view(buf, int(f.cmap.offset+offset), 4)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		format := u16(buf)
 		if !supportedCmapFormat(format, pid, psid) {
@@ -877,7 +1062,7 @@ func (f *Font) parseCmap(buf []byte) (buf1 []byte, glyphIndex glyphIndexFunc, er
 	}
 
 	if bestWidth == 0 {
-		return nil, nil, errUnsupportedCmapEncodings
+		return nil, nil, nil, errUnsupportedCmapEncodings
 	}
 	return f.makeCachedGlyphIndex(buf, bestOffset, bestLength, bestFormat)
 }
@@ -962,6 +1147,35 @@ func (f *Font) parseHmtx(buf []byte, numGlyphs, numHMetrics int32) (buf1 []byte,
 	return buf, nil
 }
 
+func (f *Font) parseVheaVmtx(buf []byte, numGlyphs int32) (buf1 []byte, ascent, descent, lineGap, numVMetrics int32, err error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/vhea
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/vmtx
+
+	// The vertical metrics are optional, and are only used if both tables
+	// are present.
+	if f.vhea.length == 0 || f.vmtx.length == 0 {
+		return buf, 0, 0, 0, 0, nil
+	}
+	if f.vhea.length != 36 {
+		return nil, 0, 0, 0, 0, errInvalidVheaTable
+	}
+	buf, err = f.src.view(buf, int(f.vhea.offset), 36)
+	if err != nil {
+		return nil, 0, 0, 0, 0, err
+	}
+	a, d, l, u := u16(buf[4:]), u16(buf[6:]), u16(buf[8:]), u16(buf[34:])
+	if int32(u) > numGlyphs || u == 0 {
+		return nil, 0, 0, 0, 0, errInvalidVheaTable
+	}
+	// As for the hmtx table, allow the topSideBearings of the glyphs after
+	// the last longVerMetric to be omitted.
+	n := int32(u)
+	if f.vmtx.length != uint32(4*n) && f.vmtx.length != uint32(4*n+2*(numGlyphs-n)) {
+		return nil, 0, 0, 0, 0, errInvalidVmtxTable
+	}
+	return buf, int32(int16(a)), int32(int16(d)), int32(int16(l)), n, nil
+}
+
 func (f *Font) parseKern(buf []byte) (buf1 []byte, kernNumPairs, kernOffset int32, err error) {
 	// https://www.microsoft.com/typography/otspec/kern.htm
 
@@ -1120,8 +1334,8 @@ func (f *Font) parseGlyphData(buf []byte, numGlyphs int32, indexToLocFormat, isP
 		}
 	} else if f.cblc.length != 0 {
 		isColorBitmap = true
-		// TODO: parse the CBLC (and CBDT) tables. For now, we return a font
-		// with empty glyphs.
+		// The glyphs have no outlines, only the images that GlyphBitmap
+		// returns.
 		ret.locations = make([]uint32, numGlyphs+1)
 	}
 
@@ -1351,7 +1565,13 @@ func (f *Font) viewGlyphData(b *Buffer, x GlyphIndex) (buf []byte, offset, lengt
 
 // LoadGlyphOptions are the options to the Font.LoadGlyph method.
 type LoadGlyphOptions struct {
-	// TODO: transform / hinting.
+	// Hinting selects whether TrueType glyphs are hinted, by running the
+	// font's bytecode instructions to fit the scaled outline to the pixel
+	// grid. HintingFull moves points in both directions, and HintingVertical
+	// only moves them vertically. PostScript (CFF) glyphs are never hinted.
+	Hinting font.Hinting
+
+	// TODO: transform.
 }
 
 // LoadGlyph returns the vector segments for the x'th glyph. ppem is the number
@@ -1368,6 +1588,10 @@ func (f *Font) LoadGlyph(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, opts *Load
 	if b == nil {
 		b = &Buffer{}
 	}
+	var o LoadGlyphOptions
+	if opts != nil {
+		o = *opts
+	}
 
 	b.segments = b.segments[:0]
 	if f.cached.isColorBitmap {
@@ -1385,15 +1609,27 @@ func (f *Font) LoadGlyph(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, opts *Load
 		if !b.psi.type2Charstrings.ended {
 			return nil, errInvalidCFFTable
 		}
+	} else if o.Hinting != font.HintingNone {
+		// TrueType hinting bytecode works on the scaled glyph points, so
+		// loadHintedGlyf scales (and flips) them itself.
+		if err := loadHintedGlyf(f, b, x, ppem, o.Hinting); err != nil {
+			return nil, err
+		}
+		return b.segments, nil
+	} else if f.coords != nil {
+		// The variation deltas are fractions of font units, so
+		// loadVariedGlyf scales (and flips) the glyph points itself.
+		if err := loadVariedGlyf(f, b, x, ppem); err != nil {
+			return nil, err
+		}
+		return b.segments, nil
 	} else if err := loadGlyf(f, b, x, 0, 0); err != nil {
 		return nil, err
 	}
 
-	// Scale the segments. If we want to support hinting, we'll have to push
-	// the scaling computation into the PostScript / TrueType specific glyph
-	// loading code, such as the appendGlyfSegments body, since TrueType
-	// hinting bytecode works on the scaled glyph vectors. For now, though,
-	// it's simpler to scale as a post-processing step.
+	// Scale the segments. Unhinted, it's simpler to scale as a
+	// post-processing step than in the PostScript / TrueType specific glyph
+	// loading code.
 	//
 	// We also flip the Y coordinates. OpenType's Y axis increases up. Go's
 	// standard graphics libraries' Y axis increases down.
@@ -1405,7 +1641,7 @@ func (f *Font) LoadGlyph(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, opts *Load
 		}
 	}
 
-	// TODO: look at opts to transform / hint the Buffer.segments.
+	// TODO: look at opts to transform the Buffer.segments.
 
 	return b.segments, nil
 }
@@ -1529,12 +1765,17 @@ func (f *Font) GlyphBounds(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, h font.H
 		metricIndex = n
 	}
 
+	delta := fixed.Int26_6(0)
+	if f.coords != nil {
+		if delta, err = f.advanceDelta(b, x); err != nil {
+			return fixed.Rectangle26_6{}, 0, err
+		}
+	}
 	buf, err := b.view(&f.src, int(f.hmtx.offset)+4*int(metricIndex), 2)
 	if err != nil {
 		return fixed.Rectangle26_6{}, 0, err
 	}
-	advance = fixed.Int26_6(u16(buf))
-	advance = scale(advance*ppem, f.cached.unitsPerEm)
+	advance = scaleAdvance(u16(buf), delta, ppem, f.cached.unitsPerEm)
 	if h == font.HintingFull {
 		// Quantize the fixed.Int26_6 value to the nearest pixel.
 		advance = (advance + 32) &^ 63
@@ -1547,7 +1788,7 @@ func (f *Font) GlyphBounds(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, h font.H
 	// obtained from the segments.
 
 	segments, err := f.LoadGlyph(b, x, ppem, &LoadGlyphOptions{
-		// TODO: pass h, the font.Hinting.
+		Hinting: h,
 	})
 	if err != nil {
 		return fixed.Rectangle26_6{}, 0, err
@@ -1567,6 +1808,15 @@ func (f *Font) GlyphAdvance(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, h font.
 		b = &Buffer{}
 	}
 
+	// The advance widths of a variable font's instances vary.
+	delta := fixed.Int26_6(0)
+	if f.coords != nil {
+		var err error
+		if delta, err = f.advanceDelta(b, x); err != nil {
+			return 0, err
+		}
+	}
+
 	// https://www.microsoft.com/typography/OTSPEC/hmtx.htm says that "As an
 	// optimization, the number of records can be less than the number of
 	// glyphs, in which case the advance width value of the last record applies
@@ -1579,8 +1829,7 @@ func (f *Font) GlyphAdvance(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, h font.
 	if err != nil {
 		return 0, err
 	}
-	adv := fixed.Int26_6(u16(buf))
-	adv = scale(adv*ppem, f.cached.unitsPerEm)
+	adv := scaleAdvance(u16(buf), delta, ppem, f.cached.unitsPerEm)
 	if h == font.HintingFull {
 		// Quantize the fixed.Int26_6 value to the nearest pixel.
 		adv = (adv + 32) &^ 63
@@ -1588,6 +1837,146 @@ func (f *Font) GlyphAdvance(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, h font.
 	return adv, nil
 }
 
+// hMetrics returns the x'th glyph's left side bearing and advance width, in
+// font units. Glyphs past the end of the hmtx table's left side bearings use
+// xMin, their bounding box's left edge.
+func (f *Font) hMetrics(b *Buffer, x GlyphIndex, xMin int16) (lsb int16, advance uint16, err error) {
+	n := GlyphIndex(f.cached.numHMetrics - 1)
+	i := x
+	if i > n {
+		i = n
+	}
+	buf, err := b.view(&f.src, int(f.hmtx.offset)+4*int(i), 4)
+	if err != nil {
+		return 0, 0, err
+	}
+	lsb, advance = int16(u16(buf[2:])), u16(buf)
+	if x > n {
+		lsb = xMin
+		if o := 4*int(n+1) + 2*int(x-n-1); o+2 <= int(f.hmtx.length) {
+			if buf, err = b.view(&f.src, int(f.hmtx.offset)+o, 2); err != nil {
+				return 0, 0, err
+			}
+			lsb = int16(u16(buf))
+		}
+	}
+	return lsb, advance, nil
+}
+
+// GlyphVerticalMetrics returns the vertical advance of the x'th glyph, and
+// its top side bearing: the distance from the top of the vertical line box,
+// the line's vertical ascent above the glyph's vertical origin, to the top of
+// the glyph's bounds. ppem is the number of pixels in 1 em.
+//
+// Vertical text, such as for CJK scripts, advances down by the vertical
+// advance after each glyph.
+//
+// It returns ErrNotFound if the glyph index is out of range, or if f has no
+// vertical metrics, in its vhea and vmtx tables.
+func (f *Font) GlyphVerticalMetrics(b *Buffer, x GlyphIndex, ppem fixed.Int26_6, h font.Hinting) (advance, topSideBearing fixed.Int26_6, err error) {
+	if int(x) >= f.NumGlyphs() || f.cached.numVMetrics == 0 {
+		return 0, 0, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+
+	tsb16, adv16, err := f.vMetrics(b, x)
+	if err != nil {
+		return 0, 0, err
+	}
+	adv := scale(fixed.Int26_6(adv16)*ppem, f.cached.unitsPerEm)
+	tsb := scale(fixed.Int26_6(tsb16)*ppem, f.cached.unitsPerEm)
+	if h == font.HintingFull {
+		// Quantize the fixed.Int26_6 values to the nearest pixel.
+		adv = (adv + 32) &^ 63
+		tsb = (tsb + 32) &^ 63
+	}
+	return adv, tsb, nil
+}
+
+// vMetrics returns the x'th glyph's unscaled top side bearing and vertical
+// advance. f must have vertical metrics.
+func (f *Font) vMetrics(b *Buffer, x GlyphIndex) (tsb int16, advance uint16, err error) {
+	// As for the hmtx table, the advance of the last longVerMetric applies
+	// to all remaining glyph IDs, which may each have their own
+	// topSideBearing.
+	n := GlyphIndex(f.cached.numVMetrics - 1)
+	buf, err := b.view(&f.src, int(f.vmtx.offset)+4*int(minGlyph(x, n)), 4)
+	if err != nil {
+		return 0, 0, err
+	}
+	tsb, advance = int16(u16(buf[2:])), u16(buf)
+	if x > n {
+		tsb = 0
+		if o := 4*uint32(n+1) + 2*uint32(x-n-1); o+2 <= f.vmtx.length {
+			if buf, err = b.view(&f.src, int(f.vmtx.offset+o), 2); err != nil {
+				return 0, 0, err
+			}
+			tsb = int16(u16(buf))
+		}
+	}
+	return tsb, advance, nil
+}
+
+func minGlyph(x, y GlyphIndex) GlyphIndex {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+// BulkGlyphAdvances appends the advance widths of the glyphs with the given
+// indices to dst, and returns the extended slice. ppem is the number of
+// pixels in 1 em.
+//
+// It is equivalent to calling GlyphAdvance for each glyph, but reads the
+// horizontal metrics only once, which is faster when laying out long runs of
+// text. Passing dst[:0] for a dst that is re-used across calls avoids
+// allocating.
+//
+// It returns ErrNotFound if any glyph index is out of range.
+func (f *Font) BulkGlyphAdvances(b *Buffer, dst []fixed.Int26_6, indices []GlyphIndex, ppem fixed.Int26_6, h font.Hinting) ([]fixed.Int26_6, error) {
+	if b == nil {
+		b = &Buffer{}
+	}
+	if f.coords != nil {
+		// Each glyph's advance width varies, by its own delta.
+		for _, x := range indices {
+			adv, err := f.GlyphAdvance(b, x, ppem, h)
+			if err != nil {
+				return dst, err
+			}
+			dst = append(dst, adv)
+		}
+		return dst, nil
+	}
+	n := GlyphIndex(f.cached.numHMetrics - 1)
+	buf, err := b.view(&f.src, int(f.hmtx.offset), 4*int(f.cached.numHMetrics))
+	if err != nil {
+		return dst, err
+	}
+	numGlyphs := f.NumGlyphs()
+	for _, x := range indices {
+		if int(x) >= numGlyphs {
+			return dst, ErrNotFound
+		}
+		// As for GlyphAdvance, the advance width of the last record applies
+		// to all remaining glyph IDs.
+		if x > n {
+			x = n
+		}
+		adv := fixed.Int26_6(u16(buf[4*int(x):]))
+		adv = scale(adv*ppem, f.cached.unitsPerEm)
+		if h == font.HintingFull {
+			// Quantize the fixed.Int26_6 value to the nearest pixel.
+			adv = (adv + 32) &^ 63
+		}
+		dst = append(dst, adv)
+	}
+	return dst, nil
+}
+
 // Kern returns the horizontal adjustment for the kerning pair (x0, x1). A
 // positive kern means to move the glyphs further apart. ppem is the number of
 // pixels in 1 em.
@@ -1689,6 +2078,55 @@ func (f *Font) Metrics(b *Buffer, ppem fixed.Int26_6, h font.Hinting) (font.Metr
 	return m, nil
 }
 
+// VerticalMetrics returns the metrics of f for vertical text, from its vhea
+// table. Ascent and Descent are the distances from the vertical origin, the
+// center line of a column of text, to the column's right and left edges.
+// Height is the recommended distance between the center lines of adjacent
+// columns. The XHeight, CapHeight and CaretSlope fields are zero.
+//
+// It returns ErrNotFound if f has no vertical metrics, in its vhea and vmtx
+// tables.
+func (f *Font) VerticalMetrics(b *Buffer, ppem fixed.Int26_6, h font.Hinting) (font.Metrics, error) {
+	if f.cached.numVMetrics == 0 {
+		return font.Metrics{}, ErrNotFound
+	}
+	m := font.Metrics{
+		Height:  scale(fixed.Int26_6(f.cached.vertAscent-f.cached.vertDescent+f.cached.vertLineGap)*ppem, f.cached.unitsPerEm),
+		Ascent:  +scale(fixed.Int26_6(f.cached.vertAscent)*ppem, f.cached.unitsPerEm),
+		Descent: -scale(fixed.Int26_6(f.cached.vertDescent)*ppem, f.cached.unitsPerEm),
+	}
+	if h == font.HintingFull {
+		// Quantize up to a whole pixel.
+		m.Height = (m.Height + 63) &^ 63
+		m.Ascent = (m.Ascent + 63) &^ 63
+		m.Descent = (m.Descent + 63) &^ 63
+	}
+	return m, nil
+}
+
+// TableData returns the data of f's table with the given 4-byte tag, such as
+// "GSUB" or "GPOS", for parsing parts of a font that f has no methods for,
+// such as the contextual lookups of a text shaper.
+//
+// The returned slice is only valid until the next method call that is passed
+// b, and the caller should not modify its contents.
+//
+// It returns ErrNotFound if f has no such table.
+func (f *Font) TableData(b *Buffer, tag string) ([]byte, error) {
+	if len(tag) != 4 {
+		return nil, ErrNotFound
+	}
+	t := uint32(tag[0])<<24 | uint32(tag[1])<<16 | uint32(tag[2])<<8 | uint32(tag[3])
+	i := sort.Search(len(f.directory), func(i int) bool { return f.directory[i].tag >= t })
+	if i == len(f.directory) || f.directory[i].tag != t {
+		return nil, ErrNotFound
+	}
+	if b == nil {
+		b = &Buffer{}
+	}
+	return b.view(&f.src, int(f.directory[i].offset), int(f.directory[i].length))
+}
+
 // Name returns the name value keyed by the given NameID.
 //
 // It returns ErrNotFound if there is no value for that key.
@@ -1782,17 +2220,24 @@ type Buffer struct {
 	segments Segments
 	// compoundStack holds the components of a TrueType compound glyph.
 	compoundStack [maxCompoundStackSize]struct {
-		glyphIndex   GlyphIndex
-		dx, dy       int16
-		hasTransform bool
-		transformXX  int16
-		transformXY  int16
-		transformYX  int16
-		transformYY  int16
-	}
+		glyphIndex    GlyphIndex
+		dx, dy        int16
+		roundXYToGrid bool
+		useMyMetrics  bool
+		hasTransform  bool
+		transformXX   int16
+		transformXY   int16
+		transformYX   int16
+		transformYY   int16
+	}
+	// hinter is a TrueType bytecode interpreter, allocated when a glyph is
+	// first loaded with hinting.
+	hinter *hinter
 	// psi is a PostScript interpreter for when the Font is an OpenType/CFF
 	// font.
 	psi psInterpreter
+	// variation holds the scratch space for the instances of variable fonts.
+	variation varBuffer
 }
 
 func (b *Buffer) view(src *source, offset, length int) ([]byte, error) {
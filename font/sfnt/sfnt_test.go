@@ -10,7 +10,10 @@ import (
 	"image"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/gobold"
@@ -393,6 +396,71 @@ func TestGlyphAdvance(t *testing.T) {
 	}
 }
 
+func TestBulkGlyphAdvances(t *testing.T) {
+	var b Buffer
+	for _, name := range []string{"gobold", "gomono", "goregular"} {
+		f, err := Parse(fontData(name))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", name, err)
+			continue
+		}
+		// The indices include every glyph, in reverse order, and repeat
+		// some.
+		var indices []GlyphIndex
+		for x := f.NumGlyphs() - 1; x >= 0; x-- {
+			indices = append(indices, GlyphIndex(x))
+		}
+		indices = append(indices, 0, 3, 3)
+
+		for _, h := range []font.Hinting{font.HintingNone, font.HintingFull} {
+			ppem := fixed.I(13)
+			got, err := f.BulkGlyphAdvances(&b, nil, indices, ppem, h)
+			if err != nil {
+				t.Errorf("name=%q, h=%v: BulkGlyphAdvances: %v", name, h, err)
+				continue
+			}
+			if len(got) != len(indices) {
+				t.Errorf("name=%q, h=%v: got %d advances, want %d", name, h, len(got), len(indices))
+				continue
+			}
+			for i, x := range indices {
+				want, err := f.GlyphAdvance(&b, x, ppem, h)
+				if err != nil {
+					t.Errorf("name=%q, x=%d: GlyphAdvance: %v", name, x, err)
+					break
+				}
+				if got[i] != want {
+					t.Errorf("name=%q, h=%v, x=%d: got %d, want %d", name, h, x, got[i], want)
+					break
+				}
+			}
+		}
+
+		if _, err := f.BulkGlyphAdvances(&b, nil, []GlyphIndex{0, GlyphIndex(f.NumGlyphs())}, fixed.I(13), font.HintingNone); err != ErrNotFound {
+			t.Errorf("name=%q: out of range: got %v, want %v", name, err, ErrNotFound)
+		}
+	}
+}
+
+func BenchmarkBulkGlyphAdvances(b *testing.B) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		b.Fatal(err)
+	}
+	indices := make([]GlyphIndex, 1000)
+	for i := range indices {
+		indices[i] = GlyphIndex(i % f.NumGlyphs())
+	}
+	var buf Buffer
+	dst := make([]fixed.Int26_6, 0, len(indices))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if dst, err = f.BulkGlyphAdvances(&buf, dst[:0], indices, fixed.I(13), font.HintingNone); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestGoRegularGlyphIndex(t *testing.T) {
 	f, err := Parse(goregular.TTF)
 	if err != nil {
@@ -527,7 +595,10 @@ func testGlyphIndex(t *testing.T, data []byte, cmapFormat int) {
 		{'\U0001f0b3', 0},
 	}
 
-	var b Buffer
+	var (
+		b           Buffer
+		wantEntries []CmapEntry
+	)
 	for _, tc := range testCases {
 		want := tc.want
 		switch {
@@ -540,6 +611,10 @@ func testGlyphIndex(t *testing.T, data []byte, cmapFormat int) {
 			want = 0
 		}
 
+		if want != 0 {
+			wantEntries = append(wantEntries, CmapEntry{tc.r, want})
+		}
+
 		got, err := f.GlyphIndex(&b, tc.r)
 		if err != nil {
 			t.Errorf("cmapFormat=%d, r=%q: %v", cmapFormat, tc.r, err)
@@ -550,6 +625,24 @@ func testGlyphIndex(t *testing.T, data []byte, cmapFormat int) {
 			continue
 		}
 	}
+	// CmapEntries lists every rune that maps to a glyph other than .notdef,
+	// which are the runes of the test cases with a non-zero want. The format
+	// 0 subtable also maps some control characters to ".null" and
+	// "nonmarkingreturn".
+	entries, err := f.CmapEntries(&b, nil)
+	if err != nil {
+		t.Errorf("cmapFormat=%d: CmapEntries: %v", cmapFormat, err)
+		return
+	}
+	gotEntries := entries[:0]
+	for _, e := range entries {
+		if e.Rune >= 0x20 {
+			gotEntries = append(gotEntries, e)
+		}
+	}
+	if !reflect.DeepEqual(gotEntries, wantEntries) {
+		t.Errorf("cmapFormat=%d: CmapEntries:\ngot  %v\nwant %v", cmapFormat, gotEntries, wantEntries)
+	}
 }
 
 func TestPostScriptSegments(t *testing.T) {
@@ -947,3 +1040,93 @@ func TestBuiltInPostNames(t *testing.T) {
 		}
 	}
 }
+
+func TestFontID(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	got, err := f.FontID(nil)
+	if err != nil {
+		t.Fatalf("FontID: %v", err)
+	}
+	want := FontID{
+		Family:             "Go",
+		Subfamily:          "Regular",
+		Version:            got.Version,
+		UniqueID:           "Bigelow&HolmesInc.: Go Regular: 2016",
+		Revision:           float64(0x2020c) / 0x10000,
+		CheckSumAdjustment: 0xa6c1c752,
+		Created:            time.Date(2016, 11, 10, 0, 0, 0, 0, time.UTC),
+		Modified:           time.Date(2017, 3, 23, 22, 46, 6, 0, time.UTC),
+	}
+	if got != want {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if !strings.HasPrefix(got.Version, "Version 2.008") {
+		t.Errorf("Version: got %q, want prefix %q", got.Version, "Version 2.008")
+	}
+}
+
+func TestVerticalMetrics(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	if _, err := f.VerticalMetrics(nil, ppem, font.HintingNone); err != ErrNotFound {
+		t.Fatalf("VerticalMetrics without a vhea table: got %v, want ErrNotFound", err)
+	}
+
+	vhea := []byte{
+		0x00, 0x01, 0x10, 0x00, // version
+		0x03, 0xe8, // vertTypoAscender: 1000
+		0xfc, 0x18, // vertTypoDescender: -1000
+		0x00, 0x64, // vertTypoLineGap: 100
+		0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x02, // numOfLongVerMetrics
+	}
+	vmtx := make([]byte, 4*2+2*(f.NumGlyphs()-2))
+	copy(vmtx, []byte{
+		// Two longVerMetrics.
+		0x08, 0x00, 0x00, 0x64,
+		0x07, 0x00, 0xff, 0xce,
+		// The topSideBearings of glyphs 2 and 3.
+		0x00, 0x32,
+		0xff, 0xf6,
+	})
+	f, err = Parse(addTables(goregular.TTF, map[string][]byte{"vhea": vhea, "vmtx": vmtx}))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	m, err := f.VerticalMetrics(nil, ppem, font.HintingNone)
+	if err != nil {
+		t.Fatalf("VerticalMetrics: %v", err)
+	}
+	if want := (font.Metrics{Height: 2100, Ascent: 1000, Descent: 1000}); m != want {
+		t.Errorf("VerticalMetrics: got %+v, want %+v", m, want)
+	}
+
+	testCases := []struct {
+		x        GlyphIndex
+		adv, tsb fixed.Int26_6
+	}{
+		{0, 2048, 100},
+		{1, 1792, -50},
+		{2, 1792, 50},
+		{3, 1792, -10},
+		{4, 1792, 0},
+	}
+	for _, tc := range testCases {
+		adv, tsb, err := f.GlyphVerticalMetrics(nil, tc.x, ppem, font.HintingNone)
+		if err != nil || adv != tc.adv || tsb != tc.tsb {
+			t.Errorf("GlyphVerticalMetrics(%d): got %d, %d, %v, want %d, %d, nil", tc.x, adv, tsb, err, tc.adv, tc.tsb)
+		}
+	}
+	if _, _, err := f.GlyphVerticalMetrics(nil, GlyphIndex(f.NumGlyphs()), ppem, font.HintingNone); err != ErrNotFound {
+		t.Errorf("GlyphVerticalMetrics out of range: got %v, want ErrNotFound", err)
+	}
+}
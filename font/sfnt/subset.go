@@ -0,0 +1,836 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"sort"
+)
+
+// Subset returns a new font file, with the same TrueType or CFF outlines as
+// f, that holds only the glyphs that f maps the given runes to, the glyphs
+// that those glyphs are composed of, and the .notdef glyph, glyph 0. It is
+// for embedding just the part of a font that a document uses, such as in a
+// PDF or SVG file.
+//
+// The subset's glyphs are renumbered: glyph 0 is followed by the runes'
+// glyphs, in increasing rune order, and then by the glyphs that those are
+// composed of. The subset's cmap table maps each rune to its new glyph. Runes
+// that f has no glyph for, or that f's cmap table maps to a glyph index past
+// its number of glyphs, are ignored.
+//
+// The subset keeps the tables that hold its glyphs and their horizontal
+// metrics, the name and OS/2 tables, and for TrueType outlines the hinting
+// tables. Other tables that refer to glyphs by their index, such as GSUB,
+// GPOS and kern, are dropped, as are the glyph names of the post table and
+// the tables of variable fonts: the subset holds the default instance's
+// glyphs, even if f is an instance returned by Instance. CFF subroutines are
+// kept whole, even if the subset's glyphs do not call them.
+func Subset(f *Font, runes []rune) ([]byte, error) {
+	s := &subsetter{
+		f:        f,
+		glyphs:   []GlyphIndex{0},
+		newIndex: map[GlyphIndex]GlyphIndex{0: 0},
+	}
+	sorted := append([]rune(nil), runes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	for i, r := range sorted {
+		if i > 0 && r == sorted[i-1] {
+			continue
+		}
+		x, err := f.GlyphIndex(&s.b, r)
+		if err != nil {
+			return nil, err
+		}
+		if x != 0 && int(x) < f.NumGlyphs() {
+			s.cmap = append(s.cmap, CmapEntry{r, s.add(x)})
+		}
+	}
+	return s.write()
+}
+
+// SubsetGlyphs is like Subset, but for the given glyphs instead of runes,
+// and the subset's glyphs keep their indexes in f: the glyphs of f that are
+// not in the subset are present but empty. It is for formats that refer to
+// glyphs by their index, such as PDF's CIDFontType2 fonts with the Identity
+// CIDToGIDMap.
+//
+// The subset keeps f's cmap table and horizontal metrics whole. Glyph indexes
+// past f's number of glyphs are ignored.
+func SubsetGlyphs(f *Font, glyphs []GlyphIndex) ([]byte, error) {
+	s := &subsetter{
+		f:           f,
+		glyphs:      []GlyphIndex{0},
+		newIndex:    map[GlyphIndex]GlyphIndex{0: 0},
+		keepIndexes: true,
+	}
+	for _, x := range glyphs {
+		if int(x) < f.NumGlyphs() {
+			s.add(x)
+		}
+	}
+	return s.write()
+}
+
+// write returns the subset's font file.
+func (s *subsetter) write() ([]byte, error) {
+	f := s.f
+	tables := map[uint32][]byte{}
+	version := uint32(0x00010000)
+	longLoca := false
+	if f.cached.isPostScript {
+		version = 0x4f54544f // "OTTO".
+		cff, err := s.subsetCFF()
+		if err != nil {
+			return nil, err
+		}
+		tables[tagCFF] = cff
+	} else {
+		glyf, loca, long, err := s.subsetGlyf()
+		if err != nil {
+			return nil, err
+		}
+		tables[tagGlyf], tables[tagLoca], longLoca = glyf, loca, long
+		for _, tag := range []uint32{tagCvt, tagFpgm, tagGasp, tagPrep} {
+			t, err := f.copyTable(tag)
+			if err != nil {
+				return nil, err
+			}
+			if t != nil {
+				tables[tag] = t
+			}
+		}
+	}
+	// From here on, s.glyphs holds every glyph of the subset.
+	numGlyphs := len(s.layout())
+
+	head, err := f.copyTable(tagHead)
+	if err != nil {
+		return nil, err
+	}
+	if len(head) < 54 {
+		return nil, errInvalidHeadTable
+	}
+	if !f.cached.isPostScript {
+		head[50], head[51] = 0, 0 // indexToLocFormat.
+		if longLoca {
+			head[51] = 1
+		}
+	}
+	tables[tagHead] = head
+
+	hmtx, numHMetrics, err := s.subsetHmtx()
+	if err != nil {
+		return nil, err
+	}
+	tables[tagHmtx] = hmtx
+	hhea, err := f.copyTable(tagHhea)
+	if err != nil {
+		return nil, err
+	}
+	if len(hhea) < 36 {
+		return nil, errInvalidHheaTable
+	}
+	hhea[34], hhea[35] = uint8(numHMetrics>>8), uint8(numHMetrics)
+	tables[tagHhea] = hhea
+
+	maxp, err := f.copyTable(tagMaxp)
+	if err != nil {
+		return nil, err
+	}
+	if len(maxp) < 6 {
+		return nil, errInvalidMaxpTable
+	}
+	maxp[4], maxp[5] = uint8(numGlyphs>>8), uint8(numGlyphs)
+	tables[tagMaxp] = maxp
+
+	if s.keepIndexes {
+		tables[tagCmap], err = f.copyTable(tagCmap)
+	} else {
+		tables[tagCmap], err = subsetCmap(s.cmap)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := f.copyTable(tagName)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		tables[tagName] = name
+	}
+	os2, err := f.copyTable(tagOS2)
+	if err != nil {
+		return nil, err
+	}
+	if os2 != nil {
+		// Update usFirstCharIndex and usLastCharIndex.
+		if n := len(s.cmap); n != 0 && len(os2) >= 68 {
+			first, last := s.cmap[0].Rune, s.cmap[n-1].Rune
+			if last > 0xffff {
+				last = 0xffff
+			}
+			os2[64], os2[65] = uint8(first>>8), uint8(first)
+			os2[66], os2[67] = uint8(last>>8), uint8(last)
+		}
+		tables[tagOS2] = os2
+	}
+	post, err := f.copyTable(tagPost)
+	if err != nil {
+		return nil, err
+	}
+	if len(post) >= 32 {
+		// Drop the glyph names by converting the post table to version
+		// 3.0, which is just the 32 byte header.
+		post = post[:32]
+		post[0], post[1], post[2], post[3] = 0x00, 0x03, 0x00, 0x00
+		tables[tagPost] = post
+	}
+
+	return writeFont(version, tables), nil
+}
+
+// subsetter holds the state of a Subset call.
+type subsetter struct {
+	f *Font
+	b Buffer
+
+	// glyphs holds the subset's glyphs' indexes in f, in the subset's order.
+	glyphs []GlyphIndex
+	// newIndex maps glyphs' indexes in f to their indexes in the subset.
+	newIndex map[GlyphIndex]GlyphIndex
+	// cmap holds the subset's cmap entries, in increasing rune order.
+	cmap []CmapEntry
+	// keepIndexes is whether the subset's glyphs keep their indexes in f,
+	// as for SubsetGlyphs.
+	keepIndexes bool
+}
+
+// add adds the x'th glyph of f to the subset, if it is not already there,
+// and returns its index in the subset.
+func (s *subsetter) add(x GlyphIndex) GlyphIndex {
+	if y, ok := s.newIndex[x]; ok {
+		return y
+	}
+	y := GlyphIndex(len(s.glyphs))
+	if s.keepIndexes {
+		y = x
+	}
+	s.glyphs = append(s.glyphs, x)
+	s.newIndex[x] = y
+	return y
+}
+
+// layout returns the index in f of each glyph of the subset, in the subset's
+// order. With keepIndexes, that is every glyph of f, including those that are
+// not in s.glyphs, which are left empty.
+func (s *subsetter) layout() []GlyphIndex {
+	if !s.keepIndexes {
+		return s.glyphs
+	}
+	ret := make([]GlyphIndex, s.f.NumGlyphs())
+	for i := range ret {
+		ret[i] = GlyphIndex(i)
+	}
+	return ret
+}
+
+// copyTable returns a copy of the table with the given tag, or nil if f has
+// no such table.
+func (f *Font) copyTable(tag uint32) ([]byte, error) {
+	i := sort.Search(len(f.directory), func(i int) bool { return f.directory[i].tag >= tag })
+	if i == len(f.directory) || f.directory[i].tag != tag {
+		return nil, nil
+	}
+	t := f.directory[i].table
+	buf, err := f.src.view(nil, int(t.offset), int(t.length))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, buf...), nil
+}
+
+// subsetGlyf returns the subset's glyf and loca tables, and whether the loca
+// table is in the long format. It adds the glyphs that composite glyphs
+// refer to to the subset.
+func (s *subsetter) subsetGlyf() (glyf, loca []byte, longLoca bool, err error) {
+	locations := s.f.cached.glyphData.locations
+	// outlines maps glyphs' indexes in f to their data in the subset. The
+	// loop adds to s.glyphs as it goes.
+	outlines := make(map[GlyphIndex][]byte, len(s.glyphs))
+	for i := 0; i < len(s.glyphs); i++ {
+		x := s.glyphs[i]
+		lo, hi := locations[x], locations[x+1]
+		if hi < lo {
+			return nil, nil, false, errInvalidLocationData
+		}
+		buf, err := s.b.view(&s.f.src, int(lo), int(hi-lo))
+		if err != nil {
+			return nil, nil, false, err
+		}
+		g := append([]byte(nil), buf...)
+		if err := s.remapComponents(g); err != nil {
+			return nil, nil, false, err
+		}
+		outlines[x] = g
+	}
+
+	glyphs := s.layout()
+	offsets := make([]int, 0, len(glyphs)+1)
+	for _, x := range glyphs {
+		offsets = append(offsets, len(glyf))
+		glyf = append(glyf, outlines[x]...)
+		for len(glyf)&3 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	offsets = append(offsets, len(glyf))
+
+	// Short loca offsets are divided by 2, and held in 16 bits.
+	longLoca = len(glyf) > 2*0xffff
+	for _, o := range offsets {
+		if longLoca {
+			loca = appendU32(loca, uint32(o))
+		} else {
+			loca = appendU16(loca, uint16(o/2))
+		}
+	}
+	return glyf, loca, longLoca, nil
+}
+
+// remapComponents replaces the glyph indexes of the components of g, if it
+// is a composite glyph, by their indexes in the subset, adding them to the
+// subset.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/glyf
+func (s *subsetter) remapComponents(g []byte) error {
+	if len(g) < 10 || int16(u16(g)) >= 0 {
+		return nil
+	}
+	const (
+		flagArg1And2AreWords   = 0x0001
+		flagWeHaveAScale       = 0x0008
+		flagMoreComponents     = 0x0020
+		flagWeHaveAnXAndYScale = 0x0040
+		flagWeHaveATwoByTwo    = 0x0080
+	)
+	numGlyphs := s.f.NumGlyphs()
+	for b := g[10:]; ; {
+		if len(b) < 4 {
+			return errInvalidGlyphData
+		}
+		flags, x := u16(b), u16(b[2:])
+		if int(x) >= numGlyphs {
+			return errInvalidGlyphData
+		}
+		y := s.add(GlyphIndex(x))
+		b[2], b[3] = uint8(y>>8), uint8(y)
+
+		n := 4 + 2
+		if flags&flagArg1And2AreWords != 0 {
+			n = 4 + 4
+		}
+		switch {
+		case flags&flagWeHaveAScale != 0:
+			n += 2
+		case flags&flagWeHaveAnXAndYScale != 0:
+			n += 4
+		case flags&flagWeHaveATwoByTwo != 0:
+			n += 8
+		}
+		if len(b) < n {
+			return errInvalidGlyphData
+		}
+		b = b[n:]
+		if flags&flagMoreComponents == 0 {
+			return nil
+		}
+	}
+}
+
+// subsetHmtx returns the subset's hmtx table and its number of long
+// horizontal metrics. The trailing glyphs of the same advance width share a
+// long metric, as the hmtx table allows.
+func (s *subsetter) subsetHmtx() (hmtx []byte, numHMetrics int, err error) {
+	n, numGlyphs := int(s.f.cached.numHMetrics), s.f.NumGlyphs()
+	if n == 0 || int(s.f.hmtx.length) < 4*n+2*(numGlyphs-n) {
+		return nil, 0, errInvalidHmtxTable
+	}
+	buf, err := s.b.view(&s.f.src, int(s.f.hmtx.offset), int(s.f.hmtx.length))
+	if err != nil {
+		return nil, 0, err
+	}
+	glyphs := s.layout()
+	advances := make([]uint16, len(glyphs))
+	lsbs := make([]uint16, len(glyphs))
+	for i, x := range glyphs {
+		if int(x) < n {
+			advances[i], lsbs[i] = u16(buf[4*x:]), u16(buf[4*x+2:])
+		} else {
+			advances[i], lsbs[i] = u16(buf[4*(n-1):]), u16(buf[4*n+2*(int(x)-n):])
+		}
+	}
+	numHMetrics = len(advances)
+	for numHMetrics > 1 && advances[numHMetrics-2] == advances[numHMetrics-1] {
+		numHMetrics--
+	}
+	hmtx = make([]byte, 0, 4*numHMetrics+2*(len(advances)-numHMetrics))
+	for i := range advances {
+		if i < numHMetrics {
+			hmtx = appendU16(hmtx, advances[i])
+		}
+		hmtx = appendU16(hmtx, lsbs[i])
+	}
+	return hmtx, numHMetrics, nil
+}
+
+// subsetCmap returns a cmap table that maps the runes of entries, which are
+// in increasing rune order, to their glyphs. It has a format 4 subtable for
+// the Basic Multilingual Plane and, if any rune is outside of it, a format 12
+// subtable for all of the runes.
+//
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/cmap
+func subsetCmap(entries []CmapEntry) ([]byte, error) {
+	// Each format 4 segment maps a range of runes to glyphs by adding the
+	// same delta.
+	type segment struct {
+		start, end rune
+		delta      uint16
+	}
+	var segments []segment
+	for _, e := range entries {
+		// The 0xffff rune is reserved for the final segment.
+		if e.Rune >= 0xffff {
+			break
+		}
+		delta := uint16(e.Glyph) - uint16(e.Rune)
+		if n := len(segments); n != 0 && segments[n-1].end+1 == e.Rune && segments[n-1].delta == delta {
+			segments[n-1].end = e.Rune
+			continue
+		}
+		segments = append(segments, segment{e.Rune, e.Rune, delta})
+	}
+	segments = append(segments, segment{0xffff, 0xffff, 1})
+
+	segCount := len(segments)
+	length := 16 + 8*segCount
+	if length > 0xffff {
+		return nil, errUnsupportedNumberOfCmapSegments
+	}
+	entrySelector := 0
+	for 2<<uint(entrySelector) <= segCount {
+		entrySelector++
+	}
+	searchRange := 2 << uint(entrySelector)
+	format4 := make([]byte, 0, length)
+	format4 = appendU16(format4, 4)
+	format4 = appendU16(format4, uint16(length))
+	format4 = appendU16(format4, 0) // language.
+	format4 = appendU16(format4, uint16(2*segCount))
+	format4 = appendU16(format4, uint16(searchRange))
+	format4 = appendU16(format4, uint16(entrySelector))
+	format4 = appendU16(format4, uint16(2*segCount-searchRange))
+	for _, seg := range segments {
+		format4 = appendU16(format4, uint16(seg.end))
+	}
+	format4 = appendU16(format4, 0) // reservedPad.
+	for _, seg := range segments {
+		format4 = appendU16(format4, uint16(seg.start))
+	}
+	for _, seg := range segments {
+		format4 = appendU16(format4, seg.delta)
+	}
+	for range segments {
+		format4 = appendU16(format4, 0) // idRangeOffset.
+	}
+
+	var format12 []byte
+	if n := len(entries); n != 0 && entries[n-1].Rune > 0xffff {
+		// Each format 12 group maps a range of runes to consecutive glyphs.
+		var groups []byte
+		numGroups := 0
+		for i := 0; i < n; {
+			j := i + 1
+			for j < n && entries[j].Rune == entries[j-1].Rune+1 && entries[j].Glyph == entries[j-1].Glyph+1 {
+				j++
+			}
+			groups = appendU32(groups, uint32(entries[i].Rune))
+			groups = appendU32(groups, uint32(entries[j-1].Rune))
+			groups = appendU32(groups, uint32(entries[i].Glyph))
+			numGroups++
+			i = j
+		}
+		format12 = appendU16(format12, 12)
+		format12 = appendU16(format12, 0) // reserved.
+		format12 = appendU32(format12, uint32(16+len(groups)))
+		format12 = appendU32(format12, 0) // language.
+		format12 = appendU32(format12, uint32(numGroups))
+		format12 = append(format12, groups...)
+	}
+
+	// The encoding records are for the Windows platform's Unicode BMP and
+	// Unicode full repertoire encodings.
+	numTables := 1
+	if format12 != nil {
+		numTables = 2
+	}
+	offset := 4 + 8*numTables
+	cmap := appendU16(nil, 0) // version.
+	cmap = appendU16(cmap, uint16(numTables))
+	cmap = appendU16(cmap, pidWindows)
+	cmap = appendU16(cmap, psidWindowsUCS2)
+	cmap = appendU32(cmap, uint32(offset))
+	if format12 != nil {
+		cmap = appendU16(cmap, pidWindows)
+		cmap = appendU16(cmap, psidWindowsUCS4)
+		cmap = appendU32(cmap, uint32(offset+len(format4)))
+	}
+	cmap = append(cmap, format4...)
+	cmap = append(cmap, format12...)
+	return cmap, nil
+}
+
+// subsetCFF returns the subset's CFF table.
+//
+// The subset's CFF table has the Name, String and Global Subrs INDEXes of
+// f's, and for CID-keyed fonts, all of its Font DICTs. Its Top DICT,
+// charset, FDSelect and CharStrings INDEX are rewritten for the subset's
+// glyphs, and its Private DICTs are rewritten so that their local Subrs
+// INDEX immediately follows them. Offsets in the rewritten DICTs are encoded
+// as five byte integers, so that each DICT's length is known before the
+// offsets are.
+//
+// See 5176.CFF.pdf.
+func (s *subsetter) subsetCFF() ([]byte, error) {
+	src, err := s.f.copyTable(tagCFF)
+	if err != nil {
+		return nil, err
+	}
+	if len(src) < 4 {
+		return nil, errInvalidCFFTable
+	}
+	_, nameEnd, err := parseCFFIndex(src, int(src[2]))
+	if err != nil {
+		return nil, err
+	}
+	topDicts, topEnd, err := parseCFFIndex(src, nameEnd)
+	if err != nil {
+		return nil, err
+	}
+	if len(topDicts) != 1 {
+		return nil, errInvalidCFFTable
+	}
+	_, stringsEnd, err := parseCFFIndex(src, topEnd)
+	if err != nil {
+		return nil, err
+	}
+	_, gsubrsEnd, err := parseCFFIndex(src, stringsEnd)
+	if err != nil {
+		return nil, err
+	}
+	top, err := parseCFFDict(topDicts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		charsetOffset, charStringsOffset, fdArrayOffset int
+		private                                         []int
+		isCID                                           bool
+		newTop                                          []cffDictEntry
+	)
+	for _, e := range top {
+		switch e.op {
+		case cffOpCharset, cffOpCharStrings, cffOpFDArray:
+			v, ok := cffDictInts(e, 1)
+			if !ok {
+				return nil, errInvalidCFFTable
+			}
+			switch e.op {
+			case cffOpCharset:
+				charsetOffset = v[0]
+			case cffOpCharStrings:
+				charStringsOffset = v[0]
+			case cffOpFDArray:
+				fdArrayOffset = v[0]
+			}
+			continue
+		case cffOpPrivate:
+			v, ok := cffDictInts(e, 2)
+			if !ok {
+				return nil, errInvalidCFFTable
+			}
+			private = v
+			continue
+		case cffOpEncoding, cffOpFDSelect:
+			// The subset has the standard encoding, which OpenType fonts
+			// ignore in favor of the cmap table, and a new FDSelect.
+			continue
+		case cffOpROS:
+			isCID = true
+		}
+		newTop = append(newTop, e)
+	}
+	charStrings, _, err := parseCFFIndex(src, charStringsOffset)
+	if err != nil {
+		return nil, err
+	}
+	if len(charStrings) != s.f.NumGlyphs() {
+		return nil, errInvalidCFFTable
+	}
+	ids, err := cffCharset(src, charsetOffset, len(charStrings))
+	if err != nil {
+		return nil, err
+	}
+
+	// Rewrite the Private DICTs, and for CID-keyed fonts the Font DICTs that
+	// point to them.
+	type privateDict struct {
+		dict, subrs []byte
+	}
+	var (
+		privates []privateDict
+		fdDicts  [][]cffDictEntry
+	)
+	if !isCID {
+		if private == nil {
+			return nil, errInvalidCFFTable
+		}
+		dict, subrs, err := cffPrivate(src, private[0], private[1])
+		if err != nil {
+			return nil, err
+		}
+		privates = append(privates, privateDict{dict, subrs})
+	} else {
+		fds, _, err := parseCFFIndex(src, fdArrayOffset)
+		if err != nil {
+			return nil, err
+		}
+		for _, fd := range fds {
+			entries, err := parseCFFDict(fd)
+			if err != nil {
+				return nil, err
+			}
+			var p privateDict
+			for _, e := range entries {
+				if e.op != cffOpPrivate {
+					continue
+				}
+				v, ok := cffDictInts(e, 2)
+				if !ok {
+					return nil, errInvalidCFFTable
+				}
+				if p.dict, p.subrs, err = cffPrivate(src, v[0], v[1]); err != nil {
+					return nil, err
+				}
+			}
+			fdDicts = append(fdDicts, entries)
+			privates = append(privates, p)
+		}
+	}
+
+	// Build the charset, in format 0, the FDSelect, in format 0, and the
+	// CharStrings INDEX.
+	glyphs := s.layout()
+	charset := []byte{0}
+	for _, x := range glyphs[1:] {
+		charset = appendU16(charset, ids[x])
+	}
+	var fdSelect []byte
+	if isCID {
+		fdSelect = []byte{0}
+		for _, x := range glyphs {
+			fd, err := s.f.cached.glyphData.fdSelect.lookup(s.f, &s.b, x)
+			if err != nil {
+				return nil, err
+			}
+			if fd >= len(fdDicts) {
+				return nil, errInvalidCFFTable
+			}
+			fdSelect = append(fdSelect, uint8(fd))
+		}
+	}
+	newCharStrings := make([][]byte, len(glyphs))
+	for i, x := range glyphs {
+		if _, ok := s.newIndex[x]; !ok {
+			// An empty glyph, with keepIndexes, is just an endchar.
+			newCharStrings[i] = []byte{14}
+			continue
+		}
+		newCharStrings[i] = charStrings[x]
+	}
+	charStringsIndex := appendCFFIndex(nil, newCharStrings)
+
+	// Lay out the subset's CFF table, filling in the DICTs' offsets, whose
+	// encoded lengths do not depend on their values.
+	topOffsets := func(charset, charStrings, fdSelect, fdArray, privateLen, private int) []cffDictEntry {
+		entries := append([]cffDictEntry(nil), newTop...)
+		entries = append(entries,
+			cffDictEntry{cffOpCharset, [][]byte{cffDictInt5(charset)}},
+			cffDictEntry{cffOpCharStrings, [][]byte{cffDictInt5(charStrings)}},
+		)
+		if isCID {
+			return append(entries,
+				cffDictEntry{cffOpFDSelect, [][]byte{cffDictInt5(fdSelect)}},
+				cffDictEntry{cffOpFDArray, [][]byte{cffDictInt5(fdArray)}},
+			)
+		}
+		return append(entries, cffDictEntry{cffOpPrivate, [][]byte{cffDictInt5(privateLen), cffDictInt5(private)}})
+	}
+	fdArray := func(privateOffsets []int) []byte {
+		objects := make([][]byte, len(fdDicts))
+		for i, entries := range fdDicts {
+			var dict []byte
+			for _, e := range entries {
+				if e.op == cffOpPrivate {
+					e.operands = [][]byte{cffDictInt5(len(privates[i].dict)), cffDictInt5(privateOffsets[i])}
+				}
+				dict = appendCFFDict(dict, []cffDictEntry{e})
+			}
+			objects[i] = dict
+		}
+		return appendCFFIndex(nil, objects)
+	}
+	topIndex := func(entries []cffDictEntry) []byte {
+		return appendCFFIndex(nil, [][]byte{appendCFFDict(nil, entries)})
+	}
+
+	privateOffsets := make([]int, len(privates))
+	offset := nameEnd + len(topIndex(topOffsets(0, 0, 0, 0, 0, 0))) + gsubrsEnd - topEnd
+	charsetOffset = offset
+	offset += len(charset)
+	fdSelectOffset := offset
+	offset += len(fdSelect)
+	charStringsOffset = offset
+	offset += len(charStringsIndex)
+	fdArrayOffset = offset
+	if isCID {
+		offset += len(fdArray(privateOffsets))
+	}
+	for i, p := range privates {
+		privateOffsets[i] = offset
+		offset += len(p.dict) + len(p.subrs)
+	}
+
+	dst := make([]byte, 0, offset)
+	dst = append(dst, src[:nameEnd]...)
+	dst = append(dst, topIndex(topOffsets(charsetOffset, charStringsOffset, fdSelectOffset,
+		fdArrayOffset, len(privates[0].dict), privateOffsets[0]))...)
+	dst = append(dst, src[topEnd:gsubrsEnd]...)
+	dst = append(dst, charset...)
+	dst = append(dst, fdSelect...)
+	dst = append(dst, charStringsIndex...)
+	if isCID {
+		dst = append(dst, fdArray(privateOffsets)...)
+	}
+	for _, p := range privates {
+		dst = append(dst, p.dict...)
+		dst = append(dst, p.subrs...)
+	}
+	if len(dst) != offset {
+		panic("unreachable")
+	}
+	return dst, nil
+}
+
+// cffDictInts returns the n integer operands of e.
+func cffDictInts(e cffDictEntry, n int) ([]int, bool) {
+	if len(e.operands) != n {
+		return nil, false
+	}
+	v := make([]int, n)
+	for i, o := range e.operands {
+		x, ok := cffDictInt(o)
+		if !ok || x < 0 {
+			return nil, false
+		}
+		v[i] = int(x)
+	}
+	return v, true
+}
+
+// cffPrivate returns the Private DICT of the given length at the given
+// offset of src, rewritten so that its local Subrs INDEX, if any,
+// immediately follows it, and that INDEX.
+func cffPrivate(src []byte, length, offset int) (dict, subrs []byte, err error) {
+	if offset > len(src) || length > len(src)-offset {
+		return nil, nil, errInvalidCFFTable
+	}
+	entries, err := parseCFFDict(src[offset : offset+length])
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, e := range entries {
+		if e.op != cffOpSubrs {
+			continue
+		}
+		v, ok := cffDictInts(e, 1)
+		if !ok {
+			return nil, nil, errInvalidCFFTable
+		}
+		_, end, err := parseCFFIndex(src, offset+v[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		subrs = src[offset+v[0] : end]
+		// The Subrs offset is relative to the Private DICT, whose length
+		// does not depend on the offset's five byte encoding.
+		entries[i].operands = [][]byte{cffDictInt5(0)}
+		entries[i].operands = [][]byte{cffDictInt5(len(appendCFFDict(nil, entries)))}
+	}
+	return appendCFFDict(nil, entries), subrs, nil
+}
+
+// cffCharset returns the SID, or for CID-keyed fonts the CID, of each glyph,
+// as listed by the charset at the given offset of src.
+func cffCharset(src []byte, offset, numGlyphs int) ([]uint16, error) {
+	ids := make([]uint16, numGlyphs)
+	switch offset {
+	case 0:
+		// The ISOAdobe charset's SIDs equal the glyph indexes.
+		for i := range ids {
+			ids[i] = uint16(i)
+		}
+		return ids, nil
+	case 1, 2:
+		return nil, errUnsupportedCFFCharset
+	}
+	if offset >= len(src) {
+		return nil, errInvalidCFFTable
+	}
+	format, b := src[offset], src[offset+1:]
+	switch format {
+	case 0:
+		if len(b) < 2*(numGlyphs-1) {
+			return nil, errInvalidCFFTable
+		}
+		for i := 1; i < numGlyphs; i++ {
+			ids[i] = u16(b[2*(i-1):])
+		}
+	case 1, 2:
+		// Ranges of a first ID and the number of IDs that follow it.
+		rangeLen := 3
+		if format == 2 {
+			rangeLen = 4
+		}
+		for i := 1; i < numGlyphs; {
+			if len(b) < rangeLen {
+				return nil, errInvalidCFFTable
+			}
+			first, nLeft := u16(b), int(b[2])
+			if format == 2 {
+				nLeft = int(u16(b[2:]))
+			}
+			b = b[rangeLen:]
+			for j := 0; j <= nLeft && i < numGlyphs; j++ {
+				ids[i] = first + uint16(j)
+				i++
+			}
+		}
+	default:
+		return nil, errInvalidCFFTable
+	}
+	return ids, nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func testSubset(t *testing.T, src []byte, runes []rune, otherRunes []rune) {
+	f, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	data, err := Subset(f, runes)
+	if err != nil {
+		t.Fatalf("Subset: %v", err)
+	}
+	g, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse(Subset): %v", err)
+	}
+	if got, want := checksum(data), uint32(0xb1b0afba); got != want {
+		t.Errorf("checksum: got %#08x, want %#08x", got, want)
+	}
+	if g.NumGlyphs() >= f.NumGlyphs() {
+		t.Errorf("NumGlyphs: got %d, want fewer than %d", g.NumGlyphs(), f.NumGlyphs())
+	}
+
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	var fb, gb Buffer
+	for _, r := range runes {
+		fx, err := f.GlyphIndex(&fb, r)
+		if err != nil {
+			t.Fatalf("GlyphIndex(%q): %v", r, err)
+		}
+		if fx == 0 {
+			continue
+		}
+		gx, err := g.GlyphIndex(&gb, r)
+		if err != nil {
+			t.Fatalf("subset GlyphIndex(%q): %v", r, err)
+		}
+		if gx == 0 {
+			t.Errorf("subset GlyphIndex(%q): got 0, want non-zero", r)
+			continue
+		}
+
+		fa, err := f.GlyphAdvance(&fb, fx, ppem, 0)
+		if err != nil {
+			t.Fatalf("GlyphAdvance(%q): %v", r, err)
+		}
+		ga, err := g.GlyphAdvance(&gb, gx, ppem, 0)
+		if err != nil {
+			t.Fatalf("subset GlyphAdvance(%q): %v", r, err)
+		}
+		if fa != ga {
+			t.Errorf("GlyphAdvance(%q): got %v, want %v", r, ga, fa)
+		}
+
+		want, err := f.LoadGlyph(&fb, fx, ppem, nil)
+		if err != nil {
+			t.Fatalf("LoadGlyph(%q): %v", r, err)
+		}
+		want = append(Segments(nil), want...)
+		got, err := g.LoadGlyph(&gb, gx, ppem, nil)
+		if err != nil {
+			t.Fatalf("subset LoadGlyph(%q): %v", r, err)
+		}
+		if len(got) != len(want) || (len(got) != 0 && !reflect.DeepEqual(got, want)) {
+			t.Errorf("LoadGlyph(%q):\ngot  %v\nwant %v", r, got, want)
+		}
+	}
+	for _, r := range otherRunes {
+		gx, err := g.GlyphIndex(&gb, r)
+		if err != nil {
+			t.Fatalf("subset GlyphIndex(%q): %v", r, err)
+		}
+		if gx != 0 {
+			t.Errorf("subset GlyphIndex(%q): got %d, want 0", r, gx)
+		}
+	}
+}
+
+func TestSubsetTrueType(t *testing.T) {
+	// U+00C1 LATIN CAPITAL LETTER A WITH ACUTE is a composite glyph.
+	testSubset(t, goregular.TTF, []rune("bÁA b"), []rune("aBcÀ一"))
+}
+
+func TestSubsetCFF(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.FromSlash("../testdata/CFFTest.otf"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	entries, err := f.CmapEntries(nil, nil)
+	if err != nil {
+		t.Fatalf("CmapEntries: %v", err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("CmapEntries: got %d entries, want at least 2", len(entries))
+	}
+	var runes []rune
+	for _, e := range entries[1:] {
+		runes = append(runes, e.Rune)
+	}
+	testSubset(t, data, runes, []rune{entries[0].Rune})
+}
+
+func testSubsetGlyphs(t *testing.T, src []byte, glyphs []GlyphIndex, other GlyphIndex) {
+	f, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	data, err := SubsetGlyphs(f, append(glyphs, GlyphIndex(f.NumGlyphs())))
+	if err != nil {
+		t.Fatalf("SubsetGlyphs: %v", err)
+	}
+	if len(data) >= len(src) {
+		t.Errorf("subset is %d bytes, want fewer than %d", len(data), len(src))
+	}
+	g, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse(SubsetGlyphs): %v", err)
+	}
+	if got, want := checksum(data), uint32(0xb1b0afba); got != want {
+		t.Errorf("checksum: got %#08x, want %#08x", got, want)
+	}
+	if got, want := g.NumGlyphs(), f.NumGlyphs(); got != want {
+		t.Fatalf("NumGlyphs: got %d, want %d", got, want)
+	}
+
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	var fb, gb Buffer
+	for _, x := range glyphs {
+		want, err := f.LoadGlyph(&fb, x, ppem, nil)
+		if err != nil {
+			t.Fatalf("LoadGlyph(%d): %v", x, err)
+		}
+		want = append(Segments(nil), want...)
+		got, err := g.LoadGlyph(&gb, x, ppem, nil)
+		if err != nil {
+			t.Fatalf("subset LoadGlyph(%d): %v", x, err)
+		}
+		if len(got) == 0 || !reflect.DeepEqual(got, want) {
+			t.Errorf("LoadGlyph(%d):\ngot  %v\nwant %v", x, got, want)
+		}
+	}
+	if segs, err := g.LoadGlyph(&gb, other, ppem, nil); err != nil || len(segs) != 0 {
+		t.Errorf("other glyph %d: got %d segments, %v, want empty", other, len(segs), err)
+	}
+}
+
+func TestSubsetGlyphsTrueType(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var glyphs [4]GlyphIndex
+	// U+00C1 LATIN CAPITAL LETTER A WITH ACUTE is a composite glyph.
+	for i, r := range []rune("bÁAZ") {
+		if glyphs[i], err = f.GlyphIndex(nil, r); err != nil {
+			t.Fatalf("GlyphIndex(%q): %v", r, err)
+		}
+	}
+	testSubsetGlyphs(t, goregular.TTF, glyphs[:3], glyphs[3])
+}
+
+func TestSubsetGlyphsCFF(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.FromSlash("../testdata/CFFTest.otf"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	testSubsetGlyphs(t, data, []GlyphIndex{2}, 1)
+}
+
+func TestSubsetInvalidGlyphIndex(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// Replace f's cmap table by one that maps 'B' past the last glyph.
+	tables := map[uint32][]byte{}
+	for _, r := range f.directory {
+		if tables[r.tag], err = f.copyTable(r.tag); err != nil {
+			t.Fatalf("copyTable: %v", err)
+		}
+	}
+	x, err := f.GlyphIndex(nil, 'A')
+	if err != nil {
+		t.Fatalf("GlyphIndex: %v", err)
+	}
+	if tables[tagCmap], err = subsetCmap([]CmapEntry{
+		{'A', x},
+		{'B', GlyphIndex(f.NumGlyphs())},
+	}); err != nil {
+		t.Fatalf("subsetCmap: %v", err)
+	}
+	f, err = Parse(writeFont(0x00010000, tables))
+	if err != nil {
+		t.Fatalf("Parse(malformed): %v", err)
+	}
+
+	data, err := Subset(f, []rune("AB"))
+	if err != nil {
+		t.Fatalf("Subset: %v", err)
+	}
+	g, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse(Subset): %v", err)
+	}
+	if n := g.NumGlyphs(); n != 2 {
+		t.Errorf("NumGlyphs: got %d, want 2", n)
+	}
+	if gx, err := g.GlyphIndex(nil, 'B'); err != nil || gx != 0 {
+		t.Errorf("subset GlyphIndex('B'): got %d, %v, want 0, nil", gx, err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"strconv"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// SVGPath returns the SVG path data (the "d" attribute of an SVG path
+// element) for s. It is equivalent to string(s.AppendSVGPath(nil, dot)) with
+// a zero dot.
+func (s Segments) SVGPath() string {
+	return string(s.AppendSVGPath(nil, fixed.Point26_6{}))
+}
+
+// AppendSVGPath appends the SVG path data for s, translated by dot, to dst and
+// returns the extended buffer.
+//
+// Like the Segments themselves, and like SVG's default user coordinate
+// system, the Y axis increases down, so a glyph drawn with its dot on the
+// baseline extends above (to smaller Y values than) that baseline. The scale
+// is that passed to Font.LoadGlyph: a ppem of fixed.Int26_6(f.UnitsPerEm())
+// gives coordinates in font units, and a ppem of fixed.I(n) gives coordinates
+// in pixels for an n pixel per em font size.
+//
+// Each contour is explicitly closed with a "Z" command, so that stroking the
+// path (e.g. by a plotter or a CNC tool) joins the first and last points.
+func (s Segments) AppendSVGPath(dst []byte, dot fixed.Point26_6) []byte {
+	return s.appendSVGPath(dst, dot, false)
+}
+
+// AppendSVGPathYUp is like AppendSVGPath, but with the Y axis increasing up,
+// as in the font's own coordinates, SVG font glyph elements and many
+// plotters. Each segment point (x, y) becomes (dot.X+x, dot.Y-y), so that a
+// glyph drawn with its dot on the baseline extends above (to larger Y values
+// than) that baseline.
+func (s Segments) AppendSVGPathYUp(dst []byte, dot fixed.Point26_6) []byte {
+	return s.appendSVGPath(dst, dot, true)
+}
+
+func (s Segments) appendSVGPath(dst []byte, dot fixed.Point26_6, yUp bool) []byte {
+	open := false
+	for _, seg := range s {
+		var (
+			cmd byte
+			n   int
+		)
+		switch seg.Op {
+		case SegmentOpMoveTo:
+			if open {
+				dst = append(dst, 'Z')
+			}
+			open = true
+			cmd, n = 'M', 1
+		case SegmentOpLineTo:
+			cmd, n = 'L', 1
+		case SegmentOpQuadTo:
+			cmd, n = 'Q', 2
+		case SegmentOpCubeTo:
+			cmd, n = 'C', 3
+		default:
+			continue
+		}
+		dst = append(dst, cmd)
+		for i := 0; i < n; i++ {
+			if i != 0 {
+				dst = append(dst, ' ')
+			}
+			dst = appendSVGCoord(dst, seg.Args[i].X+dot.X)
+			dst = append(dst, ' ')
+			if yUp {
+				dst = appendSVGCoord(dst, dot.Y-seg.Args[i].Y)
+			} else {
+				dst = appendSVGCoord(dst, seg.Args[i].Y+dot.Y)
+			}
+		}
+	}
+	if open {
+		dst = append(dst, 'Z')
+	}
+	return dst
+}
+
+// appendSVGCoord appends the decimal representation of x. Every 26.6 fixed
+// point value is exactly representable as a float64, so no precision is lost.
+func appendSVGCoord(dst []byte, x fixed.Int26_6) []byte {
+	return strconv.AppendFloat(dst, float64(x)/64, 'f', -1, 64)
+}
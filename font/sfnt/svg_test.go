@@ -0,0 +1,40 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func TestSVGPath(t *testing.T) {
+	segments := Segments{
+		moveTo(0, 0),
+		lineTo(64, 0),
+		quadTo(128, 32, 64, 64),
+		lineTo(0, 0),
+		moveTo(16, 16),
+		cubeTo(17, 18, 19, 20, 16, 16),
+	}
+	if got, want := segments.SVGPath(), ""+
+		"M0 0L1 0Q2 0.5 1 1L0 0Z"+
+		"M0.25 0.25C0.265625 0.28125 0.296875 0.3125 0.25 0.25Z"; got != want {
+		t.Errorf("SVGPath:\ngot  %q\nwant %q", got, want)
+	}
+
+	dot := fixed.Point26_6{X: fixed.I(10), Y: -fixed.I(2)}
+	if got, want := string(segments[:2].AppendSVGPath([]byte("d="), dot)), "d=M10 -2L11 -2Z"; got != want {
+		t.Errorf("AppendSVGPath:\ngot  %q\nwant %q", got, want)
+	}
+
+	if got, want := string(segments[:3].AppendSVGPathYUp(nil, dot)), "M10 -2L11 -2Q12 -2.5 11 -3Z"; got != want {
+		t.Errorf("AppendSVGPathYUp:\ngot  %q\nwant %q", got, want)
+	}
+
+	if got, want := Segments(nil).SVGPath(), ""; got != want {
+		t.Errorf("empty SVGPath: got %q, want %q", got, want)
+	}
+}
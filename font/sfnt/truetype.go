@@ -5,6 +5,7 @@
 package sfnt
 
 import (
+	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -44,13 +45,6 @@ const (
 	flagUnscaledComponentOffset = 1 << 12 // 0x1000
 )
 
-func midPoint(p, q fixed.Point26_6) fixed.Point26_6 {
-	return fixed.Point26_6{
-		X: (p.X + q.X) / 2,
-		Y: (p.Y + q.Y) / 2,
-	}
-}
-
 func parseLoca(src *source, loca table, glyfOffset uint32, indexToLocFormat bool, numGlyphs int32) (locations []uint32, err error) {
 	if indexToLocFormat {
 		if loca.length != 4*uint32(numGlyphs+1) {
@@ -165,6 +159,52 @@ func loadGlyf(f *Font, b *Buffer, x GlyphIndex, stackBottom, recursionDepth uint
 	return g.err
 }
 
+// loadGlyfPoint returns the coordinates, in font units with the Y axis
+// increasing up, of the i'th point of the x'th glyph's outline. Compound
+// glyphs are not supported.
+func loadGlyfPoint(f *Font, b *Buffer, x GlyphIndex, i int) (px, py int16, err error) {
+	data, _, _, err := f.viewGlyphData(b, x)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data) < glyfHeaderLen {
+		return 0, 0, errInvalidGlyphData
+	}
+	numContours := int16(u16(data))
+	if numContours < 0 {
+		return 0, 0, errUnsupportedCompoundGlyph
+	}
+	index := glyfHeaderLen + 2*int(numContours)
+	if numContours == 0 || index+2 > len(data) {
+		return 0, 0, errInvalidGlyphData
+	}
+	numPoints := 1 + int(u16(data[index-2:]))
+	if i >= numPoints {
+		return 0, 0, errInvalidGlyphData
+	}
+	index += 2 + int(u16(data[index:]))
+	if index > len(data) {
+		return 0, 0, errInvalidGlyphData
+	}
+
+	flagIndex := int32(index)
+	xIndex, yIndex, ok := findXYIndexes(data, index, numPoints)
+	if !ok {
+		return 0, 0, errInvalidGlyphData
+	}
+	g := glyfIter{
+		data:      data,
+		flagIndex: flagIndex,
+		xIndex:    xIndex,
+		yIndex:    yIndex,
+		nPoints:   int32(numPoints),
+	}
+	for j := 0; j <= i; j++ {
+		g.nextPoint()
+	}
+	return g.x, g.y, nil
+}
+
 func findXYIndexes(data []byte, index, numPoints int) (xIndex, yIndex int32, ok bool) {
 	xDataLen := 0
 	yDataLen := 0
@@ -223,44 +263,83 @@ func loadCompoundGlyf(f *Font, b *Buffer, data []byte, stackBottom, recursionDep
 	// for loops, since reading parses the elements of the data slice, and
 	// processing can overwrite the backing array.
 
-	stackTop := stackBottom
+	stackTop, _, err := parseCompoundGlyf(b, data, stackBottom)
+	if err != nil {
+		return err
+	}
+
+	for i := stackBottom; i < stackTop; i++ {
+		elem := &b.compoundStack[i]
+		base := len(b.segments)
+		if err := loadGlyf(f, b, elem.glyphIndex, stackTop, recursionDepth); err != nil {
+			return err
+		}
+		dx, dy := fixed.Int26_6(elem.dx), fixed.Int26_6(elem.dy)
+		segments := b.segments[base:]
+		if elem.hasTransform {
+			txx := elem.transformXX
+			txy := elem.transformXY
+			tyx := elem.transformYX
+			tyy := elem.transformYY
+			for j := range segments {
+				transformArgs(&segments[j].Args, txx, txy, tyx, tyy, dx, dy)
+			}
+		} else {
+			for j := range segments {
+				translateArgs(&segments[j].Args, dx, dy)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseCompoundGlyf reads a compound glyph's components into
+// b.compoundStack[stackBottom:stackTop]. It also returns the compound glyph's
+// hinting instructions, which are a sub-slice of data.
+func parseCompoundGlyf(b *Buffer, data []byte, stackBottom uint32) (stackTop uint32, instructions []byte, err error) {
+	haveInstructions := false
+	stackTop = stackBottom
 	for {
 		if stackTop >= maxCompoundStackSize {
-			return errUnsupportedCompoundGlyph
+			return 0, nil, errUnsupportedCompoundGlyph
 		}
 		elem := &b.compoundStack[stackTop]
 		stackTop++
 
 		if len(data) < 4 {
-			return errInvalidGlyphData
+			return 0, nil, errInvalidGlyphData
 		}
 		flags := u16(data)
 		elem.glyphIndex = GlyphIndex(u16(data[2:]))
 		if flags&flagArg1And2AreWords == 0 {
 			if len(data) < 6 {
-				return errInvalidGlyphData
+				return 0, nil, errInvalidGlyphData
 			}
 			elem.dx = int16(int8(data[4]))
 			elem.dy = int16(int8(data[5]))
 			data = data[6:]
 		} else {
 			if len(data) < 8 {
-				return errInvalidGlyphData
+				return 0, nil, errInvalidGlyphData
 			}
 			elem.dx = int16(u16(data[4:]))
 			elem.dy = int16(u16(data[6:]))
 			data = data[8:]
 		}
 
+		elem.roundXYToGrid = flags&flagRoundXYToGrid != 0
+		elem.useMyMetrics = flags&flagUseMyMetrics != 0
+		haveInstructions = haveInstructions || flags&flagWeHaveInstructions != 0
 		if flags&flagArgsAreXYValues == 0 {
-			return errUnsupportedCompoundGlyph
+			return 0, nil, errUnsupportedCompoundGlyph
 		}
 		elem.hasTransform = flags&(flagWeHaveAScale|flagWeHaveAnXAndYScale|flagWeHaveATwoByTwo) != 0
 		if elem.hasTransform {
 			switch {
 			case flags&flagWeHaveAScale != 0:
 				if len(data) < 2 {
-					return errInvalidGlyphData
+					return 0, nil, errInvalidGlyphData
 				}
 				elem.transformXX = int16(u16(data))
 				elem.transformXY = 0
@@ -269,7 +348,7 @@ func loadCompoundGlyf(f *Font, b *Buffer, data []byte, stackBottom, recursionDep
 				data = data[2:]
 			case flags&flagWeHaveAnXAndYScale != 0:
 				if len(data) < 4 {
-					return errInvalidGlyphData
+					return 0, nil, errInvalidGlyphData
 				}
 				elem.transformXX = int16(u16(data[0:]))
 				elem.transformXY = 0
@@ -278,7 +357,7 @@ func loadCompoundGlyf(f *Font, b *Buffer, data []byte, stackBottom, recursionDep
 				data = data[4:]
 			case flags&flagWeHaveATwoByTwo != 0:
 				if len(data) < 8 {
-					return errInvalidGlyphData
+					return 0, nil, errInvalidGlyphData
 				}
 				elem.transformXX = int16(u16(data[0:]))
 				elem.transformXY = int16(u16(data[2:]))
@@ -293,34 +372,350 @@ func loadCompoundGlyf(f *Font, b *Buffer, data []byte, stackBottom, recursionDep
 		}
 	}
 
-	// To support hinting, we'd have to save the remaining bytes in data here
-	// and interpret them after the for loop below, since that for loop's
-	// loadGlyf calls can overwrite the backing array.
+	if haveInstructions && len(data) >= 2 {
+		if n := 2 + int(u16(data)); n <= len(data) {
+			instructions = data[2:n]
+		}
+	}
+	return stackTop, instructions, nil
+}
+
+// loadHintedGlyf loads the x'th glyph's outline, scaled to ppem and hinted,
+// into b.segments, with the Y axis increasing down.
+func loadHintedGlyf(f *Font, b *Buffer, x GlyphIndex, ppem fixed.Int26_6, hinting font.Hinting) error {
+	if b.hinter == nil {
+		b.hinter = &hinter{}
+	}
+	h := b.hinter
+	if err := h.init(f, b, ppem); err != nil {
+		return err
+	}
+	h.points, h.ends = h.points[:0], h.ends[:0]
+	pp, err := h.loadGlyph(f, b, x, hinting, 0, 0)
+	if err != nil {
+		return err
+	}
+	origin := pp[0].X
 
-	for i := stackBottom; i < stackTop; i++ {
-		elem := &b.compoundStack[i]
-		base := len(b.segments)
-		if err := loadGlyf(f, b, elem.glyphIndex, stackTop, recursionDepth); err != nil {
-			return err
+	start := 0
+	for _, end := range h.ends {
+		b.segments = appendContourSegments(b.segments, h.points[start:end+1], origin)
+		start = end + 1
+	}
+	return nil
+}
+
+// loadGlyph appends the x'th glyph's points, scaled and hinted, to h.points,
+// and its contours' inclusive end point indexes to h.ends. It returns the
+// glyph's phantom points: its origin, advance, top and bottom, which hinting
+// may have moved.
+func (h *hinter) loadGlyph(f *Font, b *Buffer, x GlyphIndex, hinting font.Hinting, stackBottom, recursionDepth uint32) (pp [4]fixed.Point26_6, err error) {
+	data, _, _, err := f.viewGlyphData(b, x)
+	if err != nil {
+		return pp, err
+	}
+	numContours, xMin, yMax := int16(0), int16(0), int16(0)
+	if len(data) != 0 {
+		if len(data) < glyfHeaderLen {
+			return pp, errInvalidGlyphData
 		}
-		dx, dy := fixed.Int26_6(elem.dx), fixed.Int26_6(elem.dy)
-		segments := b.segments[base:]
-		if elem.hasTransform {
-			txx := elem.transformXX
-			txy := elem.transformXY
-			tyx := elem.transformYX
-			tyy := elem.transformYY
-			for j := range segments {
-				transformArgs(&segments[j].Args, txx, txy, tyx, tyy, dx, dy)
+		numContours, xMin, yMax = int16(u16(data)), int16(u16(data[2:])), int16(u16(data[8:]))
+	}
+	lsb, advance, err := f.hMetrics(b, x, xMin)
+	if err != nil {
+		return pp, err
+	}
+	pp1x := int32(xMin) - int32(lsb)
+	pp3y, pp4y := h.ascent, h.descent
+	if f.cached.numVMetrics != 0 {
+		tsb, vAdvance, err := f.vMetrics(b, x)
+		if err != nil {
+			return pp, err
+		}
+		pp3y = int32(tsb) + int32(yMax)
+		pp4y = pp3y - int32(vAdvance)
+	} else if pp4y > pp3y {
+		pp4y = 2*pp3y - pp4y
+	}
+	units := [4][2]int32{
+		{pp1x, 0},
+		{pp1x + int32(advance), 0},
+		{0, pp3y},
+		{0, pp4y},
+	}
+	for i, u := range units {
+		pp[i] = fixed.Point26_6{X: h.scale(u[0]), Y: h.scale(u[1])}
+	}
+	// Viewing the metrics can overwrite the backing array of data.
+	if data, _, _, err = f.viewGlyphData(b, x); err != nil {
+		return pp, err
+	}
+
+	base, endsBase := len(h.points), len(h.ends)
+	var prog []byte
+	switch {
+	case numContours < 0:
+		if recursionDepth++; recursionDepth == maxCompoundRecursionDepth {
+			return pp, errUnsupportedCompoundGlyph
+		}
+		stackTop, instructions, err := parseCompoundGlyf(b, data[glyfHeaderLen:], stackBottom)
+		if err != nil {
+			return pp, err
+		}
+		// Loading the components can overwrite the backing array of data.
+		prog = append(prog, instructions...)
+		if f.coords != nil {
+			// A compound glyph's deltas move its components' offsets and
+			// its phantom points, by whole font units.
+			n := int(stackTop - stackBottom)
+			deltas, err := f.glyphDeltas(b, x, n+4, nil, nil)
+			if err != nil {
+				return pp, err
 			}
-		} else {
-			for j := range segments {
-				translateArgs(&segments[j].Args, dx, dy)
+			for i := 0; i < n; i++ {
+				elem := &b.compoundStack[stackBottom+uint32(i)]
+				elem.dx += int16(roundDelta(deltas[2*i+0]))
+				elem.dy += int16(roundDelta(deltas[2*i+1]))
+			}
+			for i, u := range units {
+				u[0] += roundDelta(deltas[2*(n+i)+0])
+				u[1] += roundDelta(deltas[2*(n+i)+1])
+				pp[i] = fixed.Point26_6{X: h.scale(u[0]), Y: h.scale(u[1])}
+			}
+		}
+		for i := stackBottom; i < stackTop; i++ {
+			elem := &b.compoundStack[i]
+			cBase := len(h.points)
+			cpp, err := h.loadGlyph(f, b, elem.glyphIndex, hinting, stackTop, recursionDepth)
+			if err != nil {
+				return pp, err
+			}
+			if elem.useMyMetrics {
+				pp = cpp
+			}
+			dx, dy := h.scale(int32(elem.dx)), h.scale(int32(elem.dy))
+			if elem.roundXYToGrid {
+				dx, dy = (dx+32)&^63, (dy+32)&^63
+			}
+			points := h.points[cBase:]
+			for j := range points {
+				p := &points[j]
+				if elem.hasTransform {
+					txx := elem.transformXX
+					txy := elem.transformXY
+					tyx := elem.transformYX
+					tyy := elem.transformYY
+					c := tform(txx, txy, tyx, tyy, dx, dy, fixed.Point26_6{X: p.x, Y: p.y})
+					o := tform(txx, txy, tyx, tyy, dx, dy, fixed.Point26_6{X: p.ox, Y: p.oy})
+					p.x, p.y, p.ox, p.oy = c.X, c.Y, o.X, o.Y
+				} else {
+					p.x, p.y, p.ox, p.oy = p.x+dx, p.y+dy, p.ox+dx, p.oy+dy
+				}
 			}
 		}
+		if len(prog) == 0 {
+			return pp, nil
+		}
+		// A compound glyph's instructions work on its components' hinted
+		// points, as if they were the original outline.
+		for i := base; i < len(h.points); i++ {
+			p := &h.points[i]
+			p.ox, p.oy = p.x, p.y
+			p.ux, p.uy = int32(p.x), int32(p.y)
+			p.flags &^= pointTouchedX | pointTouchedY
+		}
+		for _, q := range pp {
+			h.points = append(h.points, hintPoint{
+				x: q.X, y: q.Y, ox: q.X, oy: q.Y,
+				ux: int32(q.X), uy: int32(q.Y),
+			})
+		}
+
+	case numContours > 0:
+		index := glyfHeaderLen + 2*int(numContours)
+		if index+2 > len(data) {
+			return pp, errInvalidGlyphData
+		}
+		prevEnd := -1
+		for i := glyfHeaderLen; i < index; i += 2 {
+			end := int(u16(data[i:]))
+			if end <= prevEnd {
+				return pp, errInvalidGlyphData
+			}
+			h.ends = append(h.ends, base+end)
+			prevEnd = end
+		}
+		numPoints := prevEnd + 1
+		n := int(u16(data[index:]))
+		index += 2
+		if index+n > len(data) {
+			return pp, errInvalidGlyphData
+		}
+		h.glyf = append(h.glyf[:0], data[index:index+n]...)
+		prog = h.glyf
+		index += n
+
+		flagIndex := int32(index)
+		xIndex, yIndex, ok := findXYIndexes(data, index, numPoints)
+		if !ok {
+			return pp, errInvalidGlyphData
+		}
+		g := glyfIter{
+			data:      data,
+			flagIndex: flagIndex,
+			xIndex:    xIndex,
+			yIndex:    yIndex,
+			nPoints:   int32(numPoints),
+		}
+		for g.nextPoint() {
+			p := hintPoint{ux: int32(g.x), uy: int32(g.y)}
+			if g.on {
+				p.flags = pointOnCurve
+			}
+			h.points = append(h.points, p)
+		}
+		fallthrough
+
+	default:
+		for _, u := range units {
+			h.points = append(h.points, hintPoint{ux: u[0], uy: u[1]})
+		}
+		points := h.points[base:]
+		for i := range points {
+			p := &points[i]
+			p.ox, p.oy = h.scale(p.ux), h.scale(p.uy)
+		}
+		if f.coords != nil {
+			ends := h.zoneEnds[:0]
+			for _, end := range h.ends[endsBase:] {
+				ends = append(ends, end-base)
+			}
+			h.zoneEnds = ends
+			deltas, err := f.glyphDeltas(b, x, len(points), points, ends)
+			if err != nil {
+				return pp, err
+			}
+			// The points are scaled from their varied positions, in 1/64ths
+			// of a font unit, but the unscaled positions that the hinting
+			// instructions see are in whole font units. As for FreeType, the
+			// scaled positions are rounded to 1/64ths of 1/64ths of a pixel
+			// before being rounded to 1/64ths of a pixel.
+			for i := range points {
+				p := &points[i]
+				dx, dy := roundDelta(64*deltas[2*i+0]), roundDelta(64*deltas[2*i+1])
+				p.ox = fixed.Int26_6((mulDiv(int64(p.ux)<<6+int64(dx), h.scale16, 1<<16) + 32) >> 6)
+				p.oy = fixed.Int26_6((mulDiv(int64(p.uy)<<6+int64(dy), h.scale16, 1<<16) + 32) >> 6)
+				p.ux += roundDelta(deltas[2*i+0])
+				p.uy += roundDelta(deltas[2*i+1])
+			}
+		}
+		for i := range points {
+			points[i].x, points[i].y = points[i].ox, points[i].oy
+		}
 	}
 
-	return nil
+	// The phantom points' current positions start rounded to the pixel grid.
+	phantoms := h.points[len(h.points)-4:]
+	for i := range phantoms {
+		p := &phantoms[i]
+		if i < 2 {
+			p.x = (p.x + 32) &^ 63
+		} else {
+			p.y = (p.y + 32) &^ 63
+		}
+	}
+
+	ends := h.zoneEnds[:0]
+	for _, end := range h.ends[endsBase:] {
+		ends = append(ends, end-base)
+	}
+	h.zoneEnds = ends
+	h.hint(prog, h.points[base:], ends, numContours < 0)
+
+	if hinting == font.HintingVertical {
+		for i := base; i < len(h.points); i++ {
+			h.points[i].x = h.points[i].ox
+		}
+	}
+	for i, p := range phantoms {
+		pp[i] = fixed.Point26_6{X: p.x, Y: p.y}
+	}
+	h.points = h.points[:len(h.points)-4]
+	return pp, nil
+}
+
+// appendContourSegments appends the segments of the closed contour whose
+// on- and off-curve points are given, translated by -originX and with the Y
+// axis flipped to increase down.
+func appendContourSegments(segments []Segment, points []hintPoint, originX fixed.Int26_6) []Segment {
+	if len(points) == 0 {
+		return segments
+	}
+	pt := func(p *hintPoint) fixed.Point26_6 {
+		return fixed.Point26_6{X: p.x - originX, Y: -p.y}
+	}
+	mid := func(a, b fixed.Point26_6) fixed.Point26_6 {
+		return fixed.Point26_6{X: (a.X + b.X) / 2, Y: (a.Y + b.Y) / 2}
+	}
+
+	// Start at the first on-curve point or, if there are none, at the
+	// implicit on-curve point between the last and first points.
+	start, n := -1, len(points)
+	for i := range points {
+		if points[i].flags&pointOnCurve != 0 {
+			start = i
+			break
+		}
+	}
+	var first fixed.Point26_6
+	if start >= 0 {
+		first = pt(&points[start])
+	} else {
+		first = mid(pt(&points[n-1]), pt(&points[0]))
+		start, n = n-1, n+1
+	}
+	segments = append(segments, Segment{
+		Op:   SegmentOpMoveTo,
+		Args: [3]fixed.Point26_6{first},
+	})
+
+	var ctrl fixed.Point26_6
+	haveCtrl := false
+	for i := 1; i < n; i++ {
+		p := &points[(start+i)%len(points)]
+		q := pt(p)
+		switch {
+		case p.flags&pointOnCurve != 0 && haveCtrl:
+			segments = append(segments, Segment{
+				Op:   SegmentOpQuadTo,
+				Args: [3]fixed.Point26_6{ctrl, q},
+			})
+			haveCtrl = false
+		case p.flags&pointOnCurve != 0:
+			segments = append(segments, Segment{
+				Op:   SegmentOpLineTo,
+				Args: [3]fixed.Point26_6{q},
+			})
+		default:
+			if haveCtrl {
+				segments = append(segments, Segment{
+					Op:   SegmentOpQuadTo,
+					Args: [3]fixed.Point26_6{ctrl, mid(ctrl, q)},
+				})
+			}
+			ctrl, haveCtrl = q, true
+		}
+	}
+	if haveCtrl {
+		return append(segments, Segment{
+			Op:   SegmentOpQuadTo,
+			Args: [3]fixed.Point26_6{ctrl, first},
+		})
+	}
+	return append(segments, Segment{
+		Op:   SegmentOpLineTo,
+		Args: [3]fixed.Point26_6{first},
+	})
 }
 
 type glyfIter struct {
@@ -435,7 +830,7 @@ func (g *glyfIter) close() {
 			Op: SegmentOpQuadTo,
 			Args: [3]fixed.Point26_6{
 				g.lastOffCurve,
-				midPoint(g.lastOffCurve, g.firstOffCurve),
+				g.lastOffCurve.Mid(g.firstOffCurve),
 			},
 		}
 	}
@@ -477,7 +872,7 @@ func (g *glyfIter) nextSegment() (ok bool) {
 				g.firstOffCurveValid = true
 				continue
 			} else {
-				g.firstOnCurve = midPoint(g.firstOffCurve, p)
+				g.firstOnCurve = g.firstOffCurve.Mid(p)
 				g.firstOnCurveValid = true
 				g.lastOffCurve = p
 				g.lastOffCurveValid = true
@@ -507,7 +902,7 @@ func (g *glyfIter) nextSegment() (ok bool) {
 					Op: SegmentOpQuadTo,
 					Args: [3]fixed.Point26_6{
 						g.lastOffCurve,
-						midPoint(g.lastOffCurve, p),
+						g.lastOffCurve.Mid(p),
 					},
 				}
 				g.lastOffCurve = p
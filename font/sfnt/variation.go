@@ -0,0 +1,1044 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+// This file implements the instances of variable fonts, whose glyphs vary
+// along the axes of a design space, such as weight or width. The fvar table
+// lists the axes, and the avar table remaps their normalized coordinates.
+// The gvar, cvar and HVAR tables give the deltas, at the corners of regions
+// of the design space, of the TrueType glyph outlines, the hinting control
+// values and the advance widths.
+//
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otvaroverview
+
+import (
+	"math"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// VariationAxis is an axis of the design space of a variable font.
+type VariationAxis struct {
+	// Tag identifies the axis, such as "wght" for weight or "wdth" for width.
+	Tag string
+	// Min and Max are the range of the axis's values, and Default is its
+	// value at the font's default instance.
+	Min, Default, Max float64
+	// Name is the ID of the axis's name in the name table.
+	Name NameID
+	// Hidden is whether the font asks for the axis not to be shown in user
+	// interfaces.
+	Hidden bool
+}
+
+// Variation is a value for an axis of a variable font's design space, such
+// as {"wght", 650} for a weight of 650.
+type Variation struct {
+	Tag   string
+	Value float64
+}
+
+// VariationAxes returns the axes of f's design space. It returns nil if f is
+// not a variable font.
+func (f *Font) VariationAxes() []VariationAxis {
+	return append([]VariationAxis(nil), f.cached.variationAxes...)
+}
+
+// Instance returns the instance of the variable font f at the given axis
+// values. Axes that are not given take their default values, values outside
+// of an axis's range are clamped to it, and the values of axes that f does
+// not have are ignored.
+//
+// The instance's TrueType glyph outlines, hinting control values and advance
+// widths vary, as given by f's gvar, cvar and HVAR tables. Its other metrics
+// and its kerning do not.
+//
+// It returns ErrNotFound if f is not a variable font.
+func (f *Font) Instance(variations []Variation) (*Font, error) {
+	axes := f.cached.variationAxes
+	if len(axes) == 0 {
+		return nil, ErrNotFound
+	}
+	coords := make([]int16, len(axes))
+	for _, v := range variations {
+		for i, a := range axes {
+			if a.Tag == v.Tag {
+				coords[i] = normalizeAxisValue(a, v.Value)
+			}
+		}
+	}
+	b := &Buffer{}
+	if err := f.mapAxisCoords(b, coords); err != nil {
+		return nil, err
+	}
+
+	g := *f
+	g.coords = nil
+	for _, c := range coords {
+		if c != 0 {
+			g.coords = coords
+			break
+		}
+	}
+	if g.coords != nil && g.gvar.length != 0 {
+		// Check that the gvar table is for f's axes and glyphs.
+		if f.gvar.length < gvarHeaderSize {
+			return nil, errInvalidGvarTable
+		}
+		buf, err := b.view(&f.src, int(f.gvar.offset), gvarHeaderSize)
+		if err != nil {
+			return nil, err
+		}
+		if int(u16(buf[4:])) != len(axes) || int(u16(buf[12:])) != f.NumGlyphs() {
+			return nil, errInvalidGvarTable
+		}
+	}
+	return &g, nil
+}
+
+// normalizeAxisValue returns the normalized coordinate, in 2.14 fixed point,
+// of the value v on the axis a: -1 at its minimum, 0 at its default and +1 at
+// its maximum.
+func normalizeAxisValue(a VariationAxis, v float64) int16 {
+	n := 0.0
+	switch {
+	case v < a.Default && a.Min < a.Default:
+		n = (math.Max(v, a.Min) - a.Default) / (a.Default - a.Min)
+	case v > a.Default && a.Max > a.Default:
+		n = (math.Min(v, a.Max) - a.Default) / (a.Max - a.Default)
+	}
+	return int16(math.Floor(n*0x4000 + 0.5))
+}
+
+func (f *Font) parseFvar(buf []byte) (buf1 []byte, axes []VariationAxis, err error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/fvar
+
+	if f.fvar.length == 0 {
+		return buf, nil, nil
+	}
+	const headerSize, axisSize = 16, 20
+	if f.fvar.length < headerSize {
+		return nil, nil, errInvalidFvarTable
+	}
+	buf, err = f.src.view(buf, int(f.fvar.offset), headerSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	axesOffset := uint32(u16(buf[4:]))
+	numAxes := int(u16(buf[8:]))
+	if u16(buf) != 1 || u16(buf[10:]) != axisSize {
+		return nil, nil, errUnsupportedFvarTable
+	}
+	if numAxes > maxNumVariationAxes {
+		return nil, nil, errUnsupportedNumberOfAxes
+	}
+	if f.fvar.length < axesOffset || f.fvar.length-axesOffset < axisSize*uint32(numAxes) {
+		return nil, nil, errInvalidFvarTable
+	}
+	buf, err = f.src.view(buf, int(f.fvar.offset+axesOffset), axisSize*numAxes)
+	if err != nil {
+		return nil, nil, err
+	}
+	axes = make([]VariationAxis, numAxes)
+	for i := range axes {
+		b := buf[axisSize*i:]
+		a := &axes[i]
+		a.Tag = string(b[:4])
+		a.Min = float64(int32(u32(b[4:]))) / 0x10000
+		a.Default = float64(int32(u32(b[8:]))) / 0x10000
+		a.Max = float64(int32(u32(b[12:]))) / 0x10000
+		a.Hidden = u16(b[16:])&1 != 0
+		a.Name = NameID(u16(b[18:]))
+		if a.Min > a.Default || a.Default > a.Max {
+			return nil, nil, errInvalidFvarTable
+		}
+	}
+	return buf, axes, nil
+}
+
+// mapAxisCoords maps the normalized coordinates coords by the avar table's
+// piecewise linear segment maps, if f has an avar table.
+func (f *Font) mapAxisCoords(b *Buffer, coords []int16) error {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/avar
+
+	if f.avar.length == 0 {
+		return nil
+	}
+	buf, err := b.view(&f.src, int(f.avar.offset), int(f.avar.length))
+	if err != nil {
+		return err
+	}
+	// Version 2 tables start as version 1 tables do. Their additional
+	// mappings are ignored.
+	if len(buf) < 8 || (u16(buf) != 1 && u16(buf) != 2) || int(u16(buf[6:])) != len(coords) {
+		return errInvalidAvarTable
+	}
+	buf = buf[8:]
+	for i, c := range coords {
+		if len(buf) < 2 {
+			return errInvalidAvarTable
+		}
+		n := 4 * int(u16(buf))
+		if len(buf) < 2+n {
+			return errInvalidAvarTable
+		}
+		maps := buf[2 : 2+n]
+		buf = buf[2+n:]
+
+		// The maps are sorted by their from coordinates.
+		for j := 0; j < len(maps); j += 4 {
+			from, to := int16(u16(maps[j:])), int16(u16(maps[j+2:]))
+			if c > from {
+				continue
+			}
+			if c == from {
+				coords[i] = to
+			} else if j > 0 {
+				from0, to0 := int64(int16(u16(maps[j-4:]))), int64(int16(u16(maps[j-2:])))
+				coords[i] = int16(to0 + mulDiv(int64(c)-from0, int64(to)-to0, int64(from)-from0))
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// varBuffer holds the scratch space for applying the deltas of an instance
+// of a variable font.
+type varBuffer struct {
+	// deltas are the deltas that glyphDeltas and cvtDeltas return, and tuple
+	// and touched are those of a single tuple variation.
+	deltas  []float64
+	tuple   []float64
+	touched []bool
+
+	sharedTuples []byte
+	sharedPoints []uint16
+	points       []uint16
+	packed       []int32
+	regions      []uint16
+
+	// glyf and ends are the points, and their contours' inclusive end point
+	// indexes, of the glyph that loadVariedGlyf loads, and glyfEnds are
+	// the end point indexes of a single simple glyph.
+	glyf     []hintPoint
+	ends     []int
+	glyfEnds []int
+}
+
+// zeroDeltas sets v.deltas to n zeros.
+func (v *varBuffer) zeroDeltas(n int) []float64 {
+	if cap(v.deltas) < n {
+		v.deltas = make([]float64, n)
+	}
+	v.deltas = v.deltas[:n]
+	for i := range v.deltas {
+		v.deltas[i] = 0
+	}
+	return v.deltas
+}
+
+// roundDelta rounds the delta d to the nearest integer, with halves rounded
+// up.
+func roundDelta(d float64) int32 {
+	return int32(math.Floor(d + 0.5))
+}
+
+// axisScalar returns the scalar of a variation on a single axis, whose region
+// on that axis spans start to end and peaks at peak, at the normalized
+// coordinate c. An axis whose peak is zero, or whose region is invalid, does
+// not affect the scalar.
+func axisScalar(c, start, peak, end int16) float64 {
+	switch {
+	case peak == 0 || c == peak || start > peak || peak > end || (start < 0 && end > 0):
+		return 1
+	case c <= start || c >= end:
+		return 0
+	case c < peak:
+		return float64(int32(c)-int32(start)) / float64(int32(peak)-int32(start))
+	}
+	return float64(int32(end)-int32(c)) / float64(int32(end)-int32(peak))
+}
+
+// unpackPoints unpacks the packed point numbers at the start of data,
+// appending them to dst. It also returns the rest of data, and whether the
+// point numbers are all of the points, which they are instead of being
+// given.
+func unpackPoints(dst []uint16, data []byte) (points []uint16, all bool, rest []byte, err error) {
+	if len(data) < 1 {
+		return nil, false, nil, errInvalidGvarTable
+	}
+	n := int(data[0])
+	data = data[1:]
+	if n == 0 {
+		return dst, true, data, nil
+	}
+	if n&0x80 != 0 {
+		if len(data) < 1 {
+			return nil, false, nil, errInvalidGvarTable
+		}
+		n = (n&0x7f)<<8 | int(data[0])
+		data = data[1:]
+	}
+	// Each point number is the difference from the previous one.
+	p := 0
+	for i := 0; i < n; {
+		if len(data) < 1 {
+			return nil, false, nil, errInvalidGvarTable
+		}
+		words, run := data[0]&0x80 != 0, int(data[0]&0x7f)+1
+		data = data[1:]
+		size := 1
+		if words {
+			size = 2
+		}
+		if len(data) < size*run {
+			return nil, false, nil, errInvalidGvarTable
+		}
+		for ; run > 0 && i < n; run, i = run-1, i+1 {
+			if words {
+				p += int(u16(data))
+			} else {
+				p += int(data[0])
+			}
+			data = data[size:]
+			dst = append(dst, uint16(p))
+		}
+	}
+	return dst, false, data, nil
+}
+
+// unpackDeltas unpacks n packed deltas from the start of data, appending
+// them to dst. It also returns the rest of data.
+func unpackDeltas(dst []int32, data []byte, n int) (deltas []int32, rest []byte, err error) {
+	for len(dst) < n {
+		if len(data) < 1 {
+			return nil, nil, errInvalidGvarTable
+		}
+		control, run := data[0]&0xc0, int(data[0]&0x3f)+1
+		data = data[1:]
+		size := 1
+		switch control {
+		case 0x80:
+			size = 0
+		case 0x40:
+			size = 2
+		case 0xc0:
+			size = 4
+		}
+		if len(data) < size*run {
+			return nil, nil, errInvalidGvarTable
+		}
+		for ; run > 0 && len(dst) < n; run-- {
+			switch size {
+			case 0:
+				dst = append(dst, 0)
+			case 1:
+				dst = append(dst, int32(int8(data[0])))
+			case 2:
+				dst = append(dst, int32(int16(u16(data))))
+			case 4:
+				dst = append(dst, int32(u32(data)))
+			}
+			data = data[size:]
+		}
+	}
+	return dst, data, nil
+}
+
+// applyTuples adds the deltas of the tuple variation store in data, each
+// tuple's scaled by its scalar at coords, to v.deltas, which hold dims values
+// for each of n points. The store's tupleVariationCount field is at
+// data[base:], and its offsets are relative to the start of data.
+//
+// For the gvar table, dims is 2, sharedTuples are the table's shared peak
+// tuples, and ends are the inclusive end point indexes of the glyph's
+// contours, whose default positions are points, to infer the deltas of the
+// points that a tuple does not give. For the cvar table, dims is 1, and
+// sharedTuples, points and ends are nil.
+func (v *varBuffer) applyTuples(data []byte, base int, coords []int16, sharedTuples []byte, n, dims int, points []hintPoint, ends []int) error {
+	if len(data) < base+4 {
+		return errInvalidGvarTable
+	}
+	count, dataOffset := u16(data[base:]), int(u16(data[base+2:]))
+	if dataOffset > len(data) {
+		return errInvalidGvarTable
+	}
+	headers, serialized := data[base+4:], data[dataOffset:]
+	sharedPoints, sharedAll := []uint16(nil), false
+	if count&0x8000 != 0 {
+		var err error
+		sharedPoints, sharedAll, serialized, err = unpackPoints(v.sharedPoints[:0], serialized)
+		if err != nil {
+			return err
+		}
+		v.sharedPoints = sharedPoints
+	}
+
+	tupleSize := 2 * len(coords)
+	if cap(v.tuple) < dims*n {
+		v.tuple = make([]float64, dims*n)
+	}
+	if cap(v.touched) < n {
+		v.touched = make([]bool, n)
+	}
+	tuple, touched := v.tuple[:dims*n], v.touched[:n]
+	for i := 0; i < int(count&0x0fff); i++ {
+		// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#tuplevariationheader
+		if len(headers) < 4 {
+			return errInvalidGvarTable
+		}
+		size, index := int(u16(headers)), u16(headers[2:])
+		headers = headers[4:]
+		var peak, start, end []byte
+		if index&0x8000 != 0 {
+			if len(headers) < tupleSize {
+				return errInvalidGvarTable
+			}
+			peak, headers = headers[:tupleSize], headers[tupleSize:]
+		} else {
+			j := int(index & 0x0fff)
+			if len(sharedTuples) < tupleSize*(j+1) {
+				return errInvalidGvarTable
+			}
+			peak = sharedTuples[tupleSize*j:]
+		}
+		if index&0x4000 != 0 {
+			if len(headers) < 2*tupleSize {
+				return errInvalidGvarTable
+			}
+			start, end, headers = headers[:tupleSize], headers[tupleSize:2*tupleSize], headers[2*tupleSize:]
+		}
+		if len(serialized) < size {
+			return errInvalidGvarTable
+		}
+		tupleData := serialized[:size]
+		serialized = serialized[size:]
+
+		scalar := 1.0
+		for j, c := range coords {
+			p := int16(u16(peak[2*j:]))
+			lo, hi := int16(0), p
+			if p < 0 {
+				lo, hi = p, 0
+			}
+			if start != nil {
+				lo, hi = int16(u16(start[2*j:])), int16(u16(end[2*j:]))
+			}
+			scalar *= axisScalar(c, lo, p, hi)
+		}
+		if scalar == 0 {
+			continue
+		}
+
+		pts, all := sharedPoints, sharedAll
+		if index&0x2000 != 0 {
+			var err error
+			pts, all, tupleData, err = unpackPoints(v.points[:0], tupleData)
+			if err != nil {
+				return err
+			}
+			v.points = pts
+		}
+		m := len(pts)
+		if all {
+			m = n
+		}
+		packed, _, err := unpackDeltas(v.packed[:0], tupleData, dims*m)
+		if err != nil {
+			return err
+		}
+		v.packed = packed
+
+		if all {
+			for j := 0; j < n; j++ {
+				for d := 0; d < dims; d++ {
+					v.deltas[dims*j+d] += scalar * float64(packed[d*m+j])
+				}
+			}
+			continue
+		}
+		for j := range tuple {
+			tuple[j] = 0
+		}
+		for j := range touched {
+			touched[j] = false
+		}
+		for k, p := range pts {
+			if int(p) >= n {
+				continue
+			}
+			touched[p] = true
+			for d := 0; d < dims; d++ {
+				tuple[dims*int(p)+d] = float64(packed[d*m+k])
+			}
+		}
+		if ends != nil {
+			inferDeltas(tuple, touched, points, ends)
+		}
+		for j, d := range tuple {
+			v.deltas[j] += scalar * d
+		}
+	}
+	return nil
+}
+
+// inferDeltas sets the x and y deltas of the points of each contour that are
+// not touched, interpolating, on each axis, between the deltas of the
+// previous and next touched points in the contour, by the points' default
+// positions.
+func inferDeltas(deltas []float64, touched []bool, points []hintPoint, ends []int) {
+	start := 0
+	for _, end := range ends {
+		if end >= len(touched) {
+			break
+		}
+		first := -1
+		for i := start; i <= end; i++ {
+			if touched[i] {
+				first = i
+				break
+			}
+		}
+		next := func(i int) int {
+			if i == end {
+				return start
+			}
+			return i + 1
+		}
+		for i := first; i >= 0; {
+			j := next(i)
+			for !touched[j] {
+				j = next(j)
+			}
+			p, q := &points[i], &points[j]
+			for k := next(i); k != j; k = next(k) {
+				r := &points[k]
+				deltas[2*k+0] = inferDelta(r.ux, p.ux, q.ux, deltas[2*i+0], deltas[2*j+0])
+				deltas[2*k+1] = inferDelta(r.uy, p.uy, q.uy, deltas[2*i+1], deltas[2*j+1])
+			}
+			if i = j; i == first {
+				break
+			}
+		}
+		start = end + 1
+	}
+}
+
+// inferDelta returns the delta of a point at the coordinate c, between two
+// touched points at c1 and c2 whose deltas are d1 and d2.
+func inferDelta(c, c1, c2 int32, d1, d2 float64) float64 {
+	if c1 == c2 {
+		if d1 == d2 {
+			return d1
+		}
+		return 0
+	}
+	if c1 > c2 {
+		c1, c2, d1, d2 = c2, c1, d2, d1
+	}
+	switch {
+	case c <= c1:
+		return d1
+	case c >= c2:
+		return d2
+	}
+	return d1 + (d2-d1)*float64(c-c1)/float64(c2-c1)
+}
+
+// gvarHeaderSize is the size of the gvar table's header.
+const gvarHeaderSize = 20
+
+// glyphDeltas returns the x and y deltas, in font units, at f's instance, of
+// the x'th glyph's n points, which include its four phantom points: its
+// origin, advance, top and bottom. For a simple glyph, points holds the
+// points' default positions and ends the inclusive end point indexes of its
+// contours, to infer the deltas of the points that a variation does not
+// give. For a compound glyph, whose points are its components' offsets,
+// points and ends are nil.
+//
+// The returned slice is valid until b is next used.
+func (f *Font) glyphDeltas(b *Buffer, x GlyphIndex, n int, points []hintPoint, ends []int) ([]float64, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/gvar
+
+	v := &b.variation
+	deltas := v.zeroDeltas(2 * n)
+	if f.gvar.length == 0 {
+		return deltas, nil
+	}
+	buf, err := b.view(&f.src, int(f.gvar.offset), gvarHeaderSize)
+	if err != nil {
+		return nil, err
+	}
+	numSharedTuples, sharedTuplesOffset := int(u16(buf[6:])), u32(buf[8:])
+	longOffsets, dataOffset := u16(buf[14:])&1 != 0, u32(buf[16:])
+
+	offsetSize := 2
+	if longOffsets {
+		offsetSize = 4
+	}
+	o := gvarHeaderSize + uint32(offsetSize)*uint32(x)
+	if o > f.gvar.length || f.gvar.length-o < 2*uint32(offsetSize) {
+		return nil, errInvalidGvarTable
+	}
+	if buf, err = b.view(&f.src, int(f.gvar.offset+o), 2*offsetSize); err != nil {
+		return nil, err
+	}
+	var start, end uint32
+	if longOffsets {
+		start, end = u32(buf), u32(buf[4:])
+	} else {
+		start, end = 2*uint32(u16(buf)), 2*uint32(u16(buf[2:]))
+	}
+	if start == end {
+		return deltas, nil
+	}
+
+	tuplesLength := uint32(2 * len(f.coords) * numSharedTuples)
+	if start > end || dataOffset > f.gvar.length || f.gvar.length-dataOffset < end ||
+		sharedTuplesOffset > f.gvar.length || f.gvar.length-sharedTuplesOffset < tuplesLength {
+		return nil, errInvalidGvarTable
+	}
+	if buf, err = b.view(&f.src, int(f.gvar.offset+sharedTuplesOffset), int(tuplesLength)); err != nil {
+		return nil, err
+	}
+	v.sharedTuples = append(v.sharedTuples[:0], buf...)
+	if buf, err = b.view(&f.src, int(f.gvar.offset+dataOffset+start), int(end-start)); err != nil {
+		return nil, err
+	}
+	if err := v.applyTuples(buf, 0, f.coords, v.sharedTuples, n, 2, points, ends); err != nil {
+		return nil, err
+	}
+	return deltas, nil
+}
+
+// cvtDeltas returns the deltas, in font units, at f's instance, of the n
+// values of the cvt table.
+//
+// The returned slice is valid until b is next used.
+func (f *Font) cvtDeltas(b *Buffer, n int) ([]float64, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/cvar
+
+	deltas := b.variation.zeroDeltas(n)
+	if f.cvar.length == 0 {
+		return deltas, nil
+	}
+	buf, err := b.view(&f.src, int(f.cvar.offset), int(f.cvar.length))
+	if err != nil {
+		return nil, err
+	}
+	if err := b.variation.applyTuples(buf, 4, f.coords, nil, n, 1, nil, nil); err != nil {
+		if err == errInvalidGvarTable {
+			err = errInvalidCvarTable
+		}
+		return nil, err
+	}
+	return deltas, nil
+}
+
+// advanceDelta returns the delta, in 1/64ths of a font unit, at f's instance,
+// of the x'th glyph's advance width. The HVAR table gives it, rounded to
+// whole font units, as the hmtx table's advance widths are, if f has one, and
+// otherwise the gvar deltas of the glyph's phantom points do.
+func (f *Font) advanceDelta(b *Buffer, x GlyphIndex) (fixed.Int26_6, error) {
+	if f.hvar.length != 0 {
+		d, err := f.hvarDelta(b, x)
+		return fixed.Int26_6(roundDelta(d)) << 6, err
+	}
+	if f.gvar.length == 0 || f.cached.isPostScript {
+		return 0, nil
+	}
+
+	// The phantom points follow the glyph's points, or, for a compound
+	// glyph, its components.
+	data, _, _, err := f.viewGlyphData(b, x)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	if len(data) != 0 {
+		if len(data) < glyfHeaderLen {
+			return 0, errInvalidGlyphData
+		}
+		switch numContours := int16(u16(data)); {
+		case numContours < 0:
+			stackTop, _, err := parseCompoundGlyf(b, data[glyfHeaderLen:], 0)
+			if err != nil {
+				return 0, err
+			}
+			n = int(stackTop)
+		case numContours > 0:
+			i := glyfHeaderLen + 2*int(numContours)
+			if i > len(data) {
+				return 0, errInvalidGlyphData
+			}
+			n = 1 + int(u16(data[i-2:]))
+		}
+	}
+	deltas, err := f.glyphDeltas(b, x, n+4, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	return fixed.Int26_6(roundDelta(64 * (deltas[2*n+2] - deltas[2*n]))), nil
+}
+
+// scaleAdvance returns the advance width, in font units, plus the delta, in
+// 1/64ths of a font unit, scaled to ppem.
+func scaleAdvance(advance uint16, delta, ppem fixed.Int26_6, unitsPerEm Units) fixed.Int26_6 {
+	if delta == 0 {
+		return scale(fixed.Int26_6(advance)*ppem, unitsPerEm)
+	}
+	return fixed.Int26_6(mulDiv(int64(advance)<<6+int64(delta), int64(ppem), 64*int64(unitsPerEm)))
+}
+
+// hvarDelta returns the delta, in font units, at f's instance, of the x'th
+// glyph's advance width, from the HVAR table.
+func (f *Font) hvarDelta(b *Buffer, x GlyphIndex) (float64, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/hvar
+
+	const headerSize = 20
+	if f.hvar.length < headerSize {
+		return 0, errInvalidHVARTable
+	}
+	buf, err := b.view(&f.src, int(f.hvar.offset), headerSize)
+	if err != nil {
+		return 0, err
+	}
+	storeOffset, mapOffset := u32(buf[4:]), u32(buf[8:])
+
+	// Without a mapping, the glyph index is the delta set's inner index.
+	outer, inner := uint32(0), uint32(x)
+	if mapOffset != 0 {
+		// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#associating-target-items-to-variation-data
+		if mapOffset > f.hvar.length || f.hvar.length-mapOffset < 4 {
+			return 0, errInvalidHVARTable
+		}
+		if buf, err = b.view(&f.src, int(f.hvar.offset+mapOffset), 4); err != nil {
+			return 0, err
+		}
+		format, entryFormat := buf[0], buf[1]
+		count, o := uint32(u16(buf[2:])), mapOffset+4
+		if format == 1 {
+			// Format 1 maps have a 32-bit count.
+			if f.hvar.length-mapOffset < 6 {
+				return 0, errInvalidHVARTable
+			}
+			if buf, err = b.view(&f.src, int(f.hvar.offset+mapOffset+2), 4); err != nil {
+				return 0, err
+			}
+			count, o = u32(buf), mapOffset+6
+		} else if format != 0 {
+			return 0, errUnsupportedHVARTable
+		}
+		if count == 0 {
+			return 0, errInvalidHVARTable
+		}
+		// Glyphs past the end of the map use its last entry.
+		i := uint32(x)
+		if i >= count {
+			i = count - 1
+		}
+		size := uint32(entryFormat>>4&3) + 1
+		if o += size * i; f.hvar.length < size || o > f.hvar.length-size {
+			return 0, errInvalidHVARTable
+		}
+		if buf, err = b.view(&f.src, int(f.hvar.offset+o), int(size)); err != nil {
+			return 0, err
+		}
+		entry := uint32(0)
+		for _, c := range buf {
+			entry = entry<<8 | uint32(c)
+		}
+		innerBits := uint32(entryFormat&0x0f) + 1
+		outer, inner = entry>>innerBits, entry&(1<<innerBits-1)
+	}
+	return f.itemDelta(b, f.hvar, storeOffset, outer, inner)
+}
+
+// itemDelta returns the delta, at f's instance, of the item with the given
+// outer and inner indexes of the item variation store at the offset
+// storeOffset in the table t.
+func (f *Font) itemDelta(b *Buffer, t table, storeOffset, outer, inner uint32) (float64, error) {
+	// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#item-variation-store
+
+	if storeOffset > t.length || t.length-storeOffset < 8 {
+		return 0, errInvalidHVARTable
+	}
+	buf, err := b.view(&f.src, int(t.offset+storeOffset), 8)
+	if err != nil {
+		return 0, err
+	}
+	if u16(buf) != 1 {
+		return 0, errUnsupportedHVARTable
+	}
+	regionsOffset, numData := storeOffset+u32(buf[2:]), uint32(u16(buf[6:]))
+	if outer >= numData {
+		// The items of 0xffff, 0xffff, or of other missing data, do not
+		// vary.
+		return 0, nil
+	}
+	o := storeOffset + 8 + 4*outer
+	if o > t.length-4 {
+		return 0, errInvalidHVARTable
+	}
+	if buf, err = b.view(&f.src, int(t.offset+o), 4); err != nil {
+		return 0, err
+	}
+	dataOffset := storeOffset + u32(buf)
+	if dataOffset < storeOffset || dataOffset > t.length || t.length-dataOffset < 6 {
+		return 0, errInvalidHVARTable
+	}
+	if buf, err = b.view(&f.src, int(t.offset+dataOffset), 6); err != nil {
+		return 0, err
+	}
+	numItems, numWords, numRegions := uint32(u16(buf)), uint32(u16(buf[2:])), uint32(u16(buf[4:]))
+	if inner >= numItems {
+		return 0, nil
+	}
+	longWords := numWords&0x8000 != 0
+	numWords &= 0x7fff
+	if numWords > numRegions {
+		return 0, errInvalidHVARTable
+	}
+	wordSize, byteSize := uint32(2), uint32(1)
+	if longWords {
+		wordSize, byteSize = 4, 2
+	}
+	rowSize := wordSize*numWords + byteSize*(numRegions-numWords)
+	o = dataOffset + 6 + 2*numRegions
+	if uint64(o)+uint64(rowSize)*uint64(inner+1) > uint64(t.length) {
+		return 0, errInvalidHVARTable
+	}
+
+	// Copy the row's region indexes and deltas, before viewing the regions.
+	v := &b.variation
+	if buf, err = b.view(&f.src, int(t.offset+dataOffset+6), int(2*numRegions)); err != nil {
+		return 0, err
+	}
+	v.regions = v.regions[:0]
+	for i := 0; i < len(buf); i += 2 {
+		v.regions = append(v.regions, u16(buf[i:]))
+	}
+	if buf, err = b.view(&f.src, int(t.offset+o+rowSize*inner), int(rowSize)); err != nil {
+		return 0, err
+	}
+	v.packed = v.packed[:0]
+	for i := uint32(0); i < numRegions; i++ {
+		var d int32
+		switch size := byteSize; {
+		case i < numWords && longWords:
+			d, buf = int32(u32(buf)), buf[4:]
+		case i < numWords || size == 2:
+			d, buf = int32(int16(u16(buf))), buf[2:]
+		default:
+			d, buf = int32(int8(buf[0])), buf[1:]
+		}
+		v.packed = append(v.packed, d)
+	}
+
+	if regionsOffset < storeOffset || regionsOffset > t.length || t.length-regionsOffset < 4 {
+		return 0, errInvalidHVARTable
+	}
+	if buf, err = b.view(&f.src, int(t.offset+regionsOffset), 4); err != nil {
+		return 0, err
+	}
+	numAxes, numAllRegions := int(u16(buf)), int(u16(buf[2:]))
+	regionSize := 6 * numAxes
+	if numAxes != len(f.coords) || uint64(regionsOffset)+4+uint64(regionSize*numAllRegions) > uint64(t.length) {
+		return 0, errInvalidHVARTable
+	}
+	if buf, err = b.view(&f.src, int(t.offset+regionsOffset+4), regionSize*numAllRegions); err != nil {
+		return 0, err
+	}
+	delta := 0.0
+	for i, r := range v.regions {
+		if int(r) >= numAllRegions {
+			return 0, errInvalidHVARTable
+		}
+		scalar := 1.0
+		region := buf[regionSize*int(r):]
+		for j, c := range f.coords {
+			a := region[6*j:]
+			scalar *= axisScalar(c, int16(u16(a)), int16(u16(a[2:])), int16(u16(a[4:])))
+		}
+		delta += scalar * float64(v.packed[i])
+	}
+	return delta, nil
+}
+
+// loadVariedGlyf loads the x'th glyph's outline, at f's instance, scaled to
+// ppem, into b.segments, with the Y axis increasing down. As the variation
+// deltas are fractions of font units, it scales (and flips) the points
+// itself.
+func loadVariedGlyf(f *Font, b *Buffer, x GlyphIndex, ppem fixed.Int26_6) error {
+	v := &b.variation
+	v.glyf, v.ends = v.glyf[:0], v.ends[:0]
+	origin, err := loadVariedPoints(f, b, x, 0, 0)
+	if err != nil {
+		return err
+	}
+	// As for FreeType, the scaled points are rounded to 1/64ths of 1/64ths
+	// of a pixel before being rounded to 1/64ths of a pixel.
+	scale16 := mulDiv(int64(ppem), 1<<16, int64(f.cached.unitsPerEm))
+	for i := range v.glyf {
+		p := &v.glyf[i]
+		p.x = fixed.Int26_6((mulDiv(int64(p.x-origin), scale16, 1<<16) + 32) >> 6)
+		p.y = fixed.Int26_6((mulDiv(int64(p.y), scale16, 1<<16) + 32) >> 6)
+	}
+	start := 0
+	for _, end := range v.ends {
+		b.segments = appendContourSegments(b.segments, v.glyf[start:end+1], 0)
+		start = end + 1
+	}
+	return nil
+}
+
+// loadVariedPoints appends the x'th glyph's points, at f's instance and in
+// 1/64ths of a font unit, to b.variation.glyf, and its contours' inclusive
+// end point indexes to b.variation.ends. It returns the x delta of the
+// glyph's origin, its first phantom point, as, unlike the hinted glyph, the
+// unhinted glyph is not otherwise moved to its origin.
+func loadVariedPoints(f *Font, b *Buffer, x GlyphIndex, stackBottom, recursionDepth uint32) (origin fixed.Int26_6, err error) {
+	data, _, _, err := f.viewGlyphData(b, x)
+	if err != nil {
+		return 0, err
+	}
+	numContours, xMin := int16(0), int16(0)
+	if len(data) != 0 {
+		if len(data) < glyfHeaderLen {
+			return 0, errInvalidGlyphData
+		}
+		numContours, xMin = int16(u16(data)), int16(u16(data[2:]))
+	}
+	lsb, advance, err := f.hMetrics(b, x, xMin)
+	if err != nil {
+		return 0, err
+	}
+	pp1x := int32(xMin) - int32(lsb)
+	// Viewing the metrics can overwrite the backing array of data.
+	if data, _, _, err = f.viewGlyphData(b, x); err != nil {
+		return 0, err
+	}
+
+	v := &b.variation
+	base := len(v.glyf)
+	switch {
+	case numContours < 0:
+		if recursionDepth++; recursionDepth == maxCompoundRecursionDepth {
+			return 0, errUnsupportedCompoundGlyph
+		}
+		stackTop, _, err := parseCompoundGlyf(b, data[glyfHeaderLen:], stackBottom)
+		if err != nil {
+			return 0, err
+		}
+		// A compound glyph's deltas move its components' offsets, by whole
+		// font units, and its phantom points.
+		n := int(stackTop - stackBottom)
+		deltas, err := f.glyphDeltas(b, x, n+4, nil, nil)
+		if err != nil {
+			return 0, err
+		}
+		for i := 0; i < n; i++ {
+			elem := &b.compoundStack[stackBottom+uint32(i)]
+			elem.dx += int16(roundDelta(deltas[2*i+0]))
+			elem.dy += int16(roundDelta(deltas[2*i+1]))
+		}
+		origin = fixed.Int26_6(roundDelta(64 * deltas[2*n]))
+
+		for i := stackBottom; i < stackTop; i++ {
+			elem := &b.compoundStack[i]
+			cBase := len(v.glyf)
+			cOrigin, err := loadVariedPoints(f, b, elem.glyphIndex, stackTop, recursionDepth)
+			if err != nil {
+				return 0, err
+			}
+			if elem.useMyMetrics {
+				origin = cOrigin
+			}
+			dx, dy := fixed.Int26_6(elem.dx)<<6, fixed.Int26_6(elem.dy)<<6
+			points := v.glyf[cBase:]
+			for j := range points {
+				p := &points[j]
+				if elem.hasTransform {
+					q := tform(elem.transformXX, elem.transformXY, elem.transformYX, elem.transformYY,
+						dx, dy, fixed.Point26_6{X: p.x, Y: p.y})
+					p.x, p.y = q.X, q.Y
+				} else {
+					p.x, p.y = p.x+dx, p.y+dy
+				}
+			}
+		}
+		return origin, nil
+
+	case numContours > 0:
+		index := glyfHeaderLen + 2*int(numContours)
+		if index+2 > len(data) {
+			return 0, errInvalidGlyphData
+		}
+		v.glyfEnds = v.glyfEnds[:0]
+		prevEnd := -1
+		for i := glyfHeaderLen; i < index; i += 2 {
+			end := int(u16(data[i:]))
+			if end <= prevEnd {
+				return 0, errInvalidGlyphData
+			}
+			v.glyfEnds = append(v.glyfEnds, end)
+			v.ends = append(v.ends, base+end)
+			prevEnd = end
+		}
+		numPoints := prevEnd + 1
+		index += 2 + int(u16(data[index:]))
+		if index > len(data) {
+			return 0, errInvalidGlyphData
+		}
+		flagIndex := int32(index)
+		xIndex, yIndex, ok := findXYIndexes(data, index, numPoints)
+		if !ok {
+			return 0, errInvalidGlyphData
+		}
+		g := glyfIter{
+			data:      data,
+			flagIndex: flagIndex,
+			xIndex:    xIndex,
+			yIndex:    yIndex,
+			nPoints:   int32(numPoints),
+		}
+		for g.nextPoint() {
+			p := hintPoint{ux: int32(g.x), uy: int32(g.y)}
+			if g.on {
+				p.flags = pointOnCurve
+			}
+			v.glyf = append(v.glyf, p)
+		}
+		fallthrough
+
+	default:
+		// Only the first two phantom points, the origin and advance, are
+		// horizontal, and only the first one matters here.
+		v.glyf = append(v.glyf,
+			hintPoint{ux: pp1x},
+			hintPoint{ux: pp1x + int32(advance)},
+			hintPoint{},
+			hintPoint{},
+		)
+		points := v.glyf[base:]
+		ends := v.glyfEnds
+		if numContours == 0 {
+			ends = nil
+		}
+		deltas, err := f.glyphDeltas(b, x, len(points), points, ends)
+		if err != nil {
+			return 0, err
+		}
+		for i := range points {
+			p := &points[i]
+			p.x = fixed.Int26_6(p.ux<<6) + fixed.Int26_6(roundDelta(64*deltas[2*i+0]))
+			p.y = fixed.Int26_6(p.uy<<6) + fixed.Int26_6(roundDelta(64*deltas[2*i+1]))
+		}
+		origin = points[len(points)-4].x - fixed.Int26_6(pp1x<<6)
+		v.glyf = v.glyf[:len(v.glyf)-4]
+		return origin, nil
+	}
+}
@@ -0,0 +1,408 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// Variation tables for glyfTest.ttf, with a weight axis from 100 to 900,
+// whose default is 400.
+var (
+	testFvar = []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x10, // axesArrayOffset
+		0x00, 0x02, // reserved
+		0x00, 0x01, // axisCount
+		0x00, 0x14, // axisSize
+		0x00, 0x00, // instanceCount
+		0x00, 0x08, // instanceSize
+		// Axis record: wght, 100, 400, 900, no flags, nameID 256.
+		'w', 'g', 'h', 't',
+		0x00, 0x64, 0x00, 0x00,
+		0x01, 0x90, 0x00, 0x00,
+		0x03, 0x84, 0x00, 0x00,
+		0x00, 0x00,
+		0x01, 0x00,
+	}
+	testAvar = []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x00, // reserved
+		0x00, 0x01, // axisCount
+		// Segment map: -1 to -1, 0 to 0, 0.5 to 0.75, 1 to 1.
+		0x00, 0x04,
+		0xc0, 0x00, 0xc0, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x20, 0x00, 0x30, 0x00,
+		0x40, 0x00, 0x40, 0x00,
+	}
+	testGvar = []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x01, // axisCount
+		0x00, 0x01, // sharedTupleCount
+		0x00, 0x00, 0x00, 0x2a, // sharedTuplesOffset
+		0x00, 0x0a, // glyphCount
+		0x00, 0x00, // flags
+		0x00, 0x00, 0x00, 0x2c, // glyphVariationDataArrayOffset
+		// Offsets, in 2-byte units: glyph 4 has 24 bytes of data and glyph 6
+		// has 30.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x0c, 0x00, 0x0c, 0x00, 0x1b,
+		0x00, 0x1b, 0x00, 0x1b, 0x00, 0x1b,
+		// Shared tuples: wght 1.
+		0x40, 0x00,
+
+		// Glyph 4, "one", whose 4 points are (205, 0), (205, 1638),
+		// (614, 1638) and (614, 0).
+		0x00, 0x01, // tupleVariationCount
+		0x00, 0x08, // dataOffset
+		// Tuple variation header: shared tuple 0, private points.
+		0x00, 0x0f, 0x20, 0x00,
+		// Points 1, 2 and 5, the second phantom point.
+		0x03, 0x02, 0x01, 0x01, 0x03,
+		// X deltas: 100, 200, 200.
+		0x42, 0x00, 0x64, 0x00, 0xc8, 0x00, 0xc8,
+		// Y deltas: 40, 0, 0.
+		0x00, 0x28, 0x81,
+		0x00, // padding
+
+		// Glyph 6, "six", whose components are "five" and "one", offset by
+		// (111, 234).
+		0x00, 0x01, // tupleVariationCount
+		0x00, 0x0e, // dataOffset
+		// Tuple variation header: embedded peak tuple 0.5 and intermediate
+		// region 0 to 1, private points.
+		0x00, 0x0f, 0xe0, 0x00,
+		0x20, 0x00,
+		0x00, 0x00, 0x40, 0x00,
+		// All points.
+		0x00,
+		// X deltas: 0, 20, 0, 0, 0, 0.
+		0x05, 0x00, 0x14, 0x00, 0x00, 0x00, 0x00,
+		// Y deltas: 0, 40, 0, 0, 0, 0.
+		0x05, 0x00, 0x28, 0x00, 0x00, 0x00, 0x00,
+		0x00, // padding
+	}
+	testHVAR = []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x00, 0x00, 0x14, // itemVariationStoreOffset
+		0x00, 0x00, 0x00, 0x00, // advanceWidthMappingOffset
+		0x00, 0x00, 0x00, 0x00, // lsbMappingOffset
+		0x00, 0x00, 0x00, 0x00, // rsbMappingOffset
+		// Item variation store.
+		0x00, 0x01, // format
+		0x00, 0x00, 0x00, 0x0c, // variationRegionListOffset
+		0x00, 0x01, // itemVariationDataCount
+		0x00, 0x00, 0x00, 0x16, // itemVariationDataOffsets
+		// Variation region list: one region, wght 0 to 1, peaking at 1.
+		0x00, 0x01, 0x00, 0x01,
+		0x00, 0x00, 0x40, 0x00, 0x40, 0x00,
+		// Item variation data, of 10 items of 1 word delta.
+		0x00, 0x0a, 0x00, 0x01, 0x00, 0x01,
+		0x00, 0x00, // regionIndexes
+		// Deltas: 300 for glyph 4 and -100 for glyph 6.
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x2c, 0x00, 0x00, 0xff, 0x9c, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+)
+
+func parseVariableTestFont(t *testing.T, tables map[string][]byte) *Font {
+	data, err := ioutil.ReadFile(filepath.FromSlash("../testdata/glyfTest.ttf"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	f, err := Parse(addTables(data, tables))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return f
+}
+
+func TestInstance(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.FromSlash("../testdata/glyfTest.ttf"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if axes := f.VariationAxes(); axes != nil {
+		t.Errorf("VariationAxes of a static font: got %v, want nil", axes)
+	}
+	if _, err := f.Instance([]Variation{{"wght", 700}}); err != ErrNotFound {
+		t.Errorf("Instance of a static font: got %v, want ErrNotFound", err)
+	}
+
+	f = parseVariableTestFont(t, map[string][]byte{"fvar": testFvar, "avar": testAvar})
+	wantAxes := []VariationAxis{{Tag: "wght", Min: 100, Default: 400, Max: 900, Name: 256}}
+	if axes := f.VariationAxes(); !reflect.DeepEqual(axes, wantAxes) {
+		t.Errorf("VariationAxes: got %v, want %v", axes, wantAxes)
+	}
+
+	testCases := []struct {
+		variations []Variation
+		want       []int16
+	}{
+		{nil, nil},
+		{[]Variation{{"wght", 400}}, nil},
+		{[]Variation{{"wdth", 50}}, nil},
+		{[]Variation{{"wght", 900}}, []int16{0x4000}},
+		{[]Variation{{"wght", 2000}}, []int16{0x4000}},
+		{[]Variation{{"wght", 650}}, []int16{0x3000}},
+		{[]Variation{{"wght", 525}}, []int16{0x1800}},
+		{[]Variation{{"wght", 775}}, []int16{0x3800}},
+		{[]Variation{{"wght", 250}}, []int16{-0x2000}},
+		{[]Variation{{"wght", 0}}, []int16{-0x4000}},
+		{[]Variation{{"wdth", 50}, {"wght", 900}}, []int16{0x4000}},
+	}
+	for _, tc := range testCases {
+		g, err := f.Instance(tc.variations)
+		if err != nil {
+			t.Errorf("%v: %v", tc.variations, err)
+			continue
+		}
+		if !reflect.DeepEqual(g.coords, tc.want) {
+			t.Errorf("%v: got coords %#x, want %#x", tc.variations, g.coords, tc.want)
+		}
+	}
+}
+
+func TestAxisScalar(t *testing.T) {
+	testCases := []struct {
+		c, start, peak, end int16
+		want                float64
+	}{
+		{0x2000, 0, 0, 0x4000, 1},
+		{0x2000, 0, 0x4000, 0x4000, 0.5},
+		{0x4000, 0, 0x4000, 0x4000, 1},
+		{0, 0, 0x4000, 0x4000, 0},
+		{-0x2000, 0, 0x4000, 0x4000, 0},
+		{-0x1000, -0x4000, -0x2000, 0, 0.5},
+		{0x3000, 0, 0x2000, 0x4000, 0.5},
+		{0x4000, 0, 0x2000, 0x4000, 0},
+		// Invalid regions do not affect the scalar.
+		{0x1000, 0x3000, 0x2000, 0x4000, 1},
+		{0x1000, -0x4000, 0x2000, 0x4000, 1},
+	}
+	for _, tc := range testCases {
+		if got := axisScalar(tc.c, tc.start, tc.peak, tc.end); got != tc.want {
+			t.Errorf("axisScalar(%#x, %#x, %#x, %#x): got %v, want %v",
+				tc.c, tc.start, tc.peak, tc.end, got, tc.want)
+		}
+	}
+}
+
+func TestInferDelta(t *testing.T) {
+	testCases := []struct {
+		c, c1, c2 int32
+		d1, d2    float64
+		want      float64
+	}{
+		{300, 200, 400, 10, 30, 20},
+		{300, 400, 200, 30, 10, 20},
+		{100, 200, 400, 10, 30, 10},
+		{500, 200, 400, 10, 30, 30},
+		{300, 200, 200, 10, 10, 10},
+		{300, 200, 200, 10, 30, 0},
+	}
+	for _, tc := range testCases {
+		if got := inferDelta(tc.c, tc.c1, tc.c2, tc.d1, tc.d2); got != tc.want {
+			t.Errorf("inferDelta(%d, %d, %d, %v, %v): got %v, want %v",
+				tc.c, tc.c1, tc.c2, tc.d1, tc.d2, got, tc.want)
+		}
+	}
+}
+
+func TestVariedGlyphs(t *testing.T) {
+	f := parseVariableTestFont(t, map[string][]byte{"fvar": testFvar, "avar": testAvar, "gvar": testGvar})
+	one := []Segment{
+		moveTo(205, 0),
+		lineTo(205, 1638),
+		lineTo(614, 1638),
+		lineTo(614, 0),
+		lineTo(205, 0),
+	}
+	five := []Segment{
+		moveTo(0, 0),
+		lineTo(0, 100),
+		lineTo(400, 100),
+		lineTo(400, 0),
+		lineTo(0, 0),
+	}
+
+	testCases := []struct {
+		wght        float64
+		x           GlyphIndex
+		want        []Segment
+		wantAdvance fixed.Int26_6
+	}{
+		{400, 4, one, 819},
+		// At 900, the weight's normalized coordinate is 1. Points 0 and 3
+		// take the X deltas of points 1 and 2, and, as the Y deltas of points
+		// 1 and 2, which have the same Y coordinate, differ, no Y delta.
+		{900, 4, []Segment{
+			moveTo(305, 0),
+			lineTo(305, 1678),
+			lineTo(814, 1638),
+			lineTo(814, 0),
+			lineTo(305, 0),
+		}, 1019},
+		// At 650, the weight's normalized coordinate, as mapped by the avar
+		// table, is 0.75, which is half way along the region of "six"'s
+		// deltas.
+		{650, 4, []Segment{
+			moveTo(280, 0),
+			lineTo(280, 1668),
+			lineTo(764, 1638),
+			lineTo(764, 0),
+			lineTo(280, 0),
+		}, 969},
+		{650, 6, append(five[:5:5], translateSegments(121, 254, []Segment{
+			moveTo(280, 0),
+			lineTo(280, 1668),
+			lineTo(764, 1638),
+			lineTo(764, 0),
+			lineTo(280, 0),
+		})...), 400},
+		{900, 6, append(five[:5:5], translateSegments(111, 234, []Segment{
+			moveTo(305, 0),
+			lineTo(305, 1678),
+			lineTo(814, 1638),
+			lineTo(814, 0),
+			lineTo(305, 0),
+		})...), 400},
+	}
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	var b Buffer
+	for _, tc := range testCases {
+		g, err := f.Instance([]Variation{{"wght", tc.wght}})
+		if err != nil {
+			t.Fatalf("Instance: %v", err)
+		}
+		got, err := g.LoadGlyph(&b, tc.x, ppem, nil)
+		if err != nil {
+			t.Errorf("wght=%v, x=%d: LoadGlyph: %v", tc.wght, tc.x, err)
+			continue
+		}
+		if err := checkSegmentsEqual(got, tc.want); err != nil {
+			t.Errorf("wght=%v, x=%d: %v", tc.wght, tc.x, err)
+		}
+		advance, err := g.GlyphAdvance(&b, tc.x, ppem, font.HintingNone)
+		if err != nil {
+			t.Errorf("wght=%v, x=%d: GlyphAdvance: %v", tc.wght, tc.x, err)
+		} else if advance != tc.wantAdvance {
+			t.Errorf("wght=%v, x=%d: GlyphAdvance: got %v, want %v", tc.wght, tc.x, advance, tc.wantAdvance)
+		}
+		advances, err := g.BulkGlyphAdvances(&b, nil, []GlyphIndex{tc.x}, ppem, font.HintingNone)
+		if err != nil || len(advances) != 1 || advances[0] != tc.wantAdvance {
+			t.Errorf("wght=%v, x=%d: BulkGlyphAdvances: got %v, %v, want [%v]", tc.wght, tc.x, advances, err, tc.wantAdvance)
+		}
+
+		// Hinting, at a size where the font's units are 1/64ths of a
+		// pixel, only rounds the points to the pixel grid.
+		got, err = g.LoadGlyph(&b, tc.x, ppem, &LoadGlyphOptions{Hinting: font.HintingFull})
+		if err != nil {
+			t.Errorf("wght=%v, x=%d: LoadGlyph (hinted): %v", tc.wght, tc.x, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("wght=%v, x=%d: LoadGlyph (hinted): got %d segments, want %d", tc.wght, tc.x, len(got), len(tc.want))
+			continue
+		}
+		for i := range got {
+			p, q := got[i].Args[0], tc.want[i].Args[0]
+			if dx, dy := p.X-q.X, p.Y+q.Y; dx < -32 || dx > 32 || dy < -32 || dy > 32 {
+				t.Errorf("wght=%v, x=%d: LoadGlyph (hinted): segment %d: got %v, want about %v", tc.wght, tc.x, i, p, q)
+				break
+			}
+		}
+	}
+}
+
+func TestHVAR(t *testing.T) {
+	f := parseVariableTestFont(t, map[string][]byte{
+		"fvar": testFvar, "avar": testAvar, "gvar": testGvar, "HVAR": testHVAR,
+	})
+	testCases := []struct {
+		wght        float64
+		x           GlyphIndex
+		wantAdvance fixed.Int26_6
+	}{
+		{400, 4, 819},
+		{900, 3, 1228},
+		{900, 4, 1119},
+		{900, 6, 300},
+		{650, 4, 1044},
+		{650, 6, 325},
+		{100, 4, 819},
+	}
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	var b Buffer
+	for _, tc := range testCases {
+		g, err := f.Instance([]Variation{{"wght", tc.wght}})
+		if err != nil {
+			t.Fatalf("Instance: %v", err)
+		}
+		advance, err := g.GlyphAdvance(&b, tc.x, ppem, font.HintingNone)
+		if err != nil {
+			t.Errorf("wght=%v, x=%d: GlyphAdvance: %v", tc.wght, tc.x, err)
+		} else if advance != tc.wantAdvance {
+			t.Errorf("wght=%v, x=%d: GlyphAdvance: got %v, want %v", tc.wght, tc.x, advance, tc.wantAdvance)
+		}
+		_, advance, err = g.GlyphBounds(&b, tc.x, ppem, font.HintingNone)
+		if err != nil {
+			t.Errorf("wght=%v, x=%d: GlyphBounds: %v", tc.wght, tc.x, err)
+		} else if advance != tc.wantAdvance {
+			t.Errorf("wght=%v, x=%d: GlyphBounds: got advance %v, want %v", tc.wght, tc.x, advance, tc.wantAdvance)
+		}
+	}
+}
+
+// translateSegments returns a copy of the line segments, translated by dx
+// and dy.
+func translateSegments(dx, dy fixed.Int26_6, segments []Segment) []Segment {
+	dst := make([]Segment, len(segments))
+	for i, s := range segments {
+		s.Args[0].X += dx
+		s.Args[0].Y += dy
+		dst[i] = s
+	}
+	return dst
+}
+
+func TestCvtDeltas(t *testing.T) {
+	cvar := []byte{
+		0x00, 0x01, 0x00, 0x00, // version
+		0x00, 0x01, // tupleVariationCount
+		0x00, 0x0e, // dataOffset
+		// Tuple variation header: embedded peak tuple 1, private points.
+		0x00, 0x07, 0xa0, 0x00,
+		0x40, 0x00,
+		// Points 1 and 3.
+		0x02, 0x01, 0x01, 0x02,
+		// Deltas: 10, -10.
+		0x01, 0x0a, 0xf6,
+	}
+	f := parseVariableTestFont(t, map[string][]byte{"fvar": testFvar, "avar": testAvar, "cvar": cvar})
+	g, err := f.Instance([]Variation{{"wght", 650}})
+	if err != nil {
+		t.Fatalf("Instance: %v", err)
+	}
+	got, err := g.cvtDeltas(&Buffer{}, 4)
+	if err != nil {
+		t.Fatalf("cvtDeltas: %v", err)
+	}
+	if want := []float64{0, 7.5, 0, -7.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cvtDeltas: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,728 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"sync/atomic"
+)
+
+// This file implements decoding WOFF and WOFF2 web fonts, which wrap an SFNT
+// font's tables in a compressed container, into SFNT font files.
+//
+// https://www.w3.org/TR/WOFF/
+// https://www.w3.org/TR/WOFF2/
+
+const (
+	woffMagic  = 0x774f4646 // "wOFF".
+	woff2Magic = 0x774f4632 // "wOF2".
+)
+
+var brotliDecompressor atomic.Value // Of type func(io.Reader) io.Reader.
+
+// RegisterBrotli registers the Brotli decompressor that is used to parse
+// WOFF2 fonts, whose tables are Brotli compressed. The Go standard library has
+// no Brotli decompressor, so parsing WOFF2 fonts fails until one is
+// registered, such as one from a third-party package:
+//
+//	sfnt.RegisterBrotli(func(r io.Reader) io.Reader { return brotli.NewReader(r) })
+//
+// WOFF fonts, whose tables are zlib compressed, need no registration.
+func RegisterBrotli(dcomp func(r io.Reader) io.Reader) {
+	brotliDecompressor.Store(dcomp)
+}
+
+// isWebFont returns whether b starts with a WOFF or WOFF2 signature.
+func isWebFont(b []byte) bool {
+	if len(b) < 4 {
+		return false
+	}
+	magic := u32(b)
+	return magic == woffMagic || magic == woff2Magic
+}
+
+// decodeWebFont returns the SFNT font file of src if it is a WOFF or WOFF2
+// font, or src itself otherwise.
+func decodeWebFont(src []byte) ([]byte, error) {
+	if !isWebFont(src) {
+		return src, nil
+	}
+	if u32(src) == woffMagic {
+		return decodeWOFF(src)
+	}
+	return decodeWOFF2(src)
+}
+
+// readWebFont returns the SFNT font file of src if it is a WOFF or WOFF2
+// font, or nil otherwise. Web fonts are read into memory, as their tables must
+// be decompressed.
+func readWebFont(src io.ReaderAt) ([]byte, error) {
+	// Both headers have the file's length at offset 8.
+	var header [12]byte
+	if n, err := src.ReadAt(header[:], 0); n != len(header) {
+		if err == io.EOF || err == nil {
+			// Too short to be a web font.
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !isWebFont(header[:]) {
+		return nil, nil
+	}
+	length := u32(header[8:])
+	if length > maxTableOffset {
+		return nil, errUnsupportedWebFontLength
+	}
+	b := make([]byte, length)
+	if n, err := src.ReadAt(b, 0); n != len(b) {
+		if err == io.EOF || err == nil {
+			err = errInvalidWebFont
+		}
+		return nil, err
+	}
+	return decodeWebFont(b)
+}
+
+// decodeWOFF decodes a WOFF font, as per https://www.w3.org/TR/WOFF/
+// sections 3 "WOFF Header", 4 "Table Directory" and 5 "Font Data Tables".
+func decodeWOFF(src []byte) ([]byte, error) {
+	// The header is 44 bytes, and is followed by 20 bytes for each table.
+	if len(src) < 44 {
+		return nil, errInvalidWebFont
+	}
+	flavor := u32(src[4:])
+	numTables := int(u16(src[12:]))
+	if numTables > maxNumTables {
+		return nil, errUnsupportedNumberOfTables
+	}
+	if len(src) < 44+20*numTables {
+		return nil, errInvalidWebFont
+	}
+	tables := make(map[uint32][]byte, numTables)
+	total := 0
+	for i := 0; i < numTables; i++ {
+		d := src[44+20*i:]
+		tag, offset, compLength, origLength := u32(d), u32(d[4:]), u32(d[8:]), u32(d[12:])
+		if origLength > maxTableLength || compLength > origLength {
+			return nil, errInvalidWebFont
+		}
+		if total += int(origLength); total > maxTableOffset {
+			return nil, errUnsupportedWebFontLength
+		}
+		if offset > uint32(len(src)) || compLength > uint32(len(src))-offset {
+			return nil, errInvalidWebFont
+		}
+		if _, ok := tables[tag]; ok {
+			return nil, errInvalidWebFont
+		}
+		data := src[offset : offset+compLength]
+		if compLength == origLength {
+			// The table is stored uncompressed.
+			tables[tag] = append([]byte(nil), data...)
+			continue
+		}
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errInvalidWebFont
+		}
+		t := make([]byte, origLength)
+		if _, err := io.ReadFull(r, t); err != nil {
+			return nil, errInvalidWebFont
+		}
+		tables[tag] = t
+	}
+	return writeFont(flavor, tables), nil
+}
+
+// woff2KnownTags are the tags that a WOFF2 table directory entry can refer to
+// by their index, as per https://www.w3.org/TR/WOFF2/ section 5.1 "Table
+// Directory Format".
+var woff2KnownTags = [63]uint32{
+	0x636d6170, 0x68656164, 0x68686561, 0x686d7478, // cmap, head, hhea, hmtx.
+	0x6d617870, 0x6e616d65, 0x4f532f32, 0x706f7374, // maxp, name, OS/2, post.
+	0x63767420, 0x6670676d, 0x676c7966, 0x6c6f6361, // cvt , fpgm, glyf, loca.
+	0x70726570, 0x43464620, 0x564f5247, 0x45424454, // prep, CFF , VORG, EBDT.
+	0x45424c43, 0x67617370, 0x68646d78, 0x6b65726e, // EBLC, gasp, hdmx, kern.
+	0x4c545348, 0x50434c54, 0x56444d58, 0x76686561, // LTSH, PCLT, VDMX, vhea.
+	0x766d7478, 0x42415345, 0x47444546, 0x47504f53, // vmtx, BASE, GDEF, GPOS.
+	0x47535542, 0x45425343, 0x4a535446, 0x4d415448, // GSUB, EBSC, JSTF, MATH.
+	0x43424454, 0x43424c43, 0x434f4c52, 0x4350414c, // CBDT, CBLC, COLR, CPAL.
+	0x53564720, 0x73626978, 0x61636e74, 0x61766172, // SVG , sbix, acnt, avar.
+	0x62646174, 0x626c6f63, 0x62736c6e, 0x63766172, // bdat, bloc, bsln, cvar.
+	0x66647363, 0x66656174, 0x666d7478, 0x66766172, // fdsc, feat, fmtx, fvar.
+	0x67766172, 0x68737479, 0x6a757374, 0x6c636172, // gvar, hsty, just, lcar.
+	0x6d6f7274, 0x6d6f7278, 0x6f706264, 0x70726f70, // mort, morx, opbd, prop.
+	0x7472616b, 0x5a617066, 0x53696c66, 0x476c6174, // trak, Zapf, Silf, Glat.
+	0x476c6f63, 0x46656174, 0x53696c6c, // Gloc, Feat, Sill.
+}
+
+// woff2Table is a WOFF2 table directory entry.
+type woff2Table struct {
+	tag         uint32
+	transformed bool
+	origLength  uint32
+	// length is the length of the table's data in the decompressed stream,
+	// which is its transformLength if it is transformed.
+	length uint32
+}
+
+// decodeWOFF2 decodes a WOFF2 font, as per https://www.w3.org/TR/WOFF2/
+// sections 4 "WOFF2 Header", 5 "Table Directory" and 6 "Compressed Data
+// Format". It reconstructs the transformed glyf, loca and hmtx tables.
+func decodeWOFF2(src []byte) ([]byte, error) {
+	// The header is 48 bytes.
+	if len(src) < 48 {
+		return nil, errInvalidWebFont
+	}
+	flavor := u32(src[4:])
+	if flavor == 0x74746366 { // "ttcf".
+		return nil, errUnsupportedWOFF2Collection
+	}
+	numTables := int(u16(src[12:]))
+	if numTables > maxNumTables {
+		return nil, errUnsupportedNumberOfTables
+	}
+	totalCompressedSize := u32(src[20:])
+
+	r := woff2Reader{b: src[48:]}
+	dir := make([]woff2Table, numTables)
+	total := uint32(0)
+	for i := range dir {
+		t := &dir[i]
+		flags := r.u8()
+		if i := flags & 0x3f; i < 0x3f {
+			t.tag = woff2KnownTags[i]
+		} else {
+			t.tag = r.u32()
+		}
+		t.origLength = r.base128()
+		t.length = t.origLength
+		// The glyf and loca tables are transformed unless their transform
+		// version is 3. The others are transformed unless it is 0.
+		version := flags >> 6
+		if t.tag == tagGlyf || t.tag == tagLoca {
+			t.transformed = version != 3
+		} else {
+			t.transformed = version != 0
+		}
+		if t.transformed {
+			t.length = r.base128()
+		}
+		if r.err || t.origLength > maxTableLength || t.length > maxTableLength {
+			return nil, errInvalidWebFont
+		}
+		if total += t.length; total > maxTableOffset {
+			return nil, errUnsupportedWebFontLength
+		}
+	}
+	if r.err || uint32(len(r.b)) < totalCompressedSize {
+		return nil, errInvalidWebFont
+	}
+
+	dcomp, _ := brotliDecompressor.Load().(func(io.Reader) io.Reader)
+	if dcomp == nil {
+		return nil, errNoBrotliDecompressor
+	}
+	data := make([]byte, total)
+	if _, err := io.ReadFull(dcomp(bytes.NewReader(r.b[:totalCompressedSize])), data); err != nil {
+		return nil, errInvalidWebFont
+	}
+
+	tables := make(map[uint32][]byte, numTables)
+	var glyf, loca, hmtx *woff2Table
+	for i := range dir {
+		t := &dir[i]
+		if _, ok := tables[t.tag]; ok {
+			return nil, errInvalidWebFont
+		}
+		// The tables alias data, which writeFont may modify.
+		tables[t.tag], data = data[:t.length], data[t.length:]
+		if !t.transformed {
+			continue
+		}
+		switch t.tag {
+		case tagGlyf:
+			glyf = t
+		case tagLoca:
+			loca = t
+		case tagHmtx:
+			hmtx = t
+		default:
+			return nil, errUnsupportedWOFF2Transform
+		}
+	}
+
+	// The glyf and loca tables are transformed together, and the loca
+	// table's transformed data is empty.
+	var xMins []int16
+	if (glyf == nil) != (loca == nil) || (loca != nil && loca.length != 0) {
+		return nil, errInvalidWebFont
+	}
+	if glyf != nil {
+		g, l, x, err := reconstructGlyf(tables[tagGlyf], loca.origLength)
+		if err != nil {
+			return nil, err
+		}
+		tables[tagGlyf], tables[tagLoca], xMins = g, l, x
+	}
+	if hmtx != nil {
+		if xMins == nil {
+			return nil, errInvalidWebFont
+		}
+		h, err := reconstructHmtx(tables[tagHmtx], tables[tagHhea], xMins, hmtx.origLength)
+		if err != nil {
+			return nil, err
+		}
+		tables[tagHmtx] = h
+	}
+	return writeFont(flavor, tables), nil
+}
+
+// woff2Reader reads the big-endian values of a WOFF2 file or stream, setting
+// err instead of returning an error if there is too little data.
+type woff2Reader struct {
+	b   []byte
+	err bool
+}
+
+func (r *woff2Reader) bytes(n int) []byte {
+	if n < 0 || n > len(r.b) {
+		r.err = true
+		r.b = nil
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *woff2Reader) u8() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *woff2Reader) u16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return u16(b)
+	}
+	return 0
+}
+
+func (r *woff2Reader) u32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return u32(b)
+	}
+	return 0
+}
+
+// base128 reads a UIntBase128 value, as per https://www.w3.org/TR/WOFF2/
+// section 3.1 "Data Types".
+func (r *woff2Reader) base128() uint32 {
+	v := uint32(0)
+	for i := 0; i < 5; i++ {
+		b := r.u8()
+		// Leading zeroes and values that overflow 32 bits are invalid.
+		if (i == 0 && b == 0x80) || v&0xfe000000 != 0 {
+			r.err = true
+		}
+		if r.err {
+			return 0
+		}
+		v = v<<7 | uint32(b&0x7f)
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+	r.err = true
+	return 0
+}
+
+// u255 reads a 255UInt16 value, as per https://www.w3.org/TR/WOFF2/ section
+// 3.1 "Data Types".
+func (r *woff2Reader) u255() uint16 {
+	switch code := r.u8(); code {
+	case 253:
+		return r.u16()
+	case 254:
+		return 2*253 + uint16(r.u8())
+	case 255:
+		return 253 + uint16(r.u8())
+	default:
+		return uint16(code)
+	}
+}
+
+// The flags of TrueType simple and compound glyphs.
+const (
+	glyfOnCurve       = 0x01
+	glyfXShort        = 0x02
+	glyfYShort        = 0x04
+	glyfXSame         = 0x10
+	glyfYSame         = 0x20
+	glyfOverlapSimple = 0x40
+
+	compoundArgsAreWords   = 0x0001
+	compoundHaveScale      = 0x0008
+	compoundMoreComponents = 0x0020
+	compoundHaveXYScale    = 0x0040
+	compoundHaveTwoByTwo   = 0x0080
+	compoundHaveInstrs     = 0x0100
+)
+
+// reconstructGlyf returns the glyf and loca tables, and the glyphs' minimum
+// x coordinates, of a transformed glyf table, as per
+// https://www.w3.org/TR/WOFF2/ section 5.1 "Transformed glyf table format".
+func reconstructGlyf(t []byte, locaLength uint32) (glyf, loca []byte, xMins []int16, err error) {
+	// The header is 36 bytes: 4 16-bit values and 7 stream sizes.
+	r := woff2Reader{b: t}
+	r.u16() // Reserved.
+	optionFlags := r.u16()
+	numGlyphs := int(r.u16())
+	longLoca := r.u16() != 0
+	var sizes [7]uint32
+	for i := range sizes {
+		sizes[i] = r.u32()
+	}
+	var streams [7]woff2Reader
+	for i, size := range sizes {
+		if size > maxTableLength {
+			return nil, nil, nil, errInvalidWebFont
+		}
+		streams[i].b = r.bytes(int(size))
+	}
+	var overlap []byte
+	if optionFlags&1 != 0 {
+		overlap = r.bytes((numGlyphs + 7) / 8)
+	}
+	if r.err {
+		return nil, nil, nil, errInvalidWebFont
+	}
+	nContours, nPoints, flags, glyphs, composites, bboxes, instrs :=
+		&streams[0], &streams[1], &streams[2], &streams[3], &streams[4], &streams[5], &streams[6]
+	bboxBitmap := bboxes.bytes(4 * ((numGlyphs + 31) / 32))
+
+	if n := uint32(numGlyphs+1) * 2; (longLoca && locaLength != 2*n) || (!longLoca && locaLength != n) {
+		return nil, nil, nil, errInvalidWebFont
+	}
+	loca = make([]byte, 0, locaLength)
+	xMins = make([]int16, numGlyphs)
+	var (
+		endPts []uint16
+		xs, ys []int
+		ons    []bool
+	)
+	for i := 0; i < numGlyphs; i++ {
+		if longLoca {
+			loca = appendU32(loca, uint32(len(glyf)))
+		} else {
+			loca = appendU16(loca, uint16(len(glyf)/2))
+		}
+		n := int16(nContours.u16())
+		hasBBox := bboxBitmap != nil && bboxBitmap[i/8]&(0x80>>uint(i%8)) != 0
+		var bbox [4]int16
+		if hasBBox {
+			for j := range bbox {
+				bbox[j] = int16(bboxes.u16())
+			}
+		}
+		start := len(glyf)
+
+		switch {
+		case n == 0:
+			// An empty glyph has no data.
+			if hasBBox {
+				return nil, nil, nil, errInvalidWebFont
+			}
+
+		case n > 0:
+			// A simple glyph.
+			endPts, xs, ys, ons = endPts[:0], xs[:0], ys[:0], ons[:0]
+			total := 0
+			for j := int16(0); j < n; j++ {
+				total += int(nPoints.u255())
+				if total == 0 || total > 0xffff {
+					return nil, nil, nil, errInvalidWebFont
+				}
+				endPts = append(endPts, uint16(total-1))
+			}
+			x, y := 0, 0
+			for j := 0; j < total; j++ {
+				dx, dy, on, ok := readTriplet(flags.u8(), glyphs)
+				if !ok {
+					return nil, nil, nil, errInvalidWebFont
+				}
+				x, y = x+dx, y+dy
+				if x < -0x8000 || 0x7fff < x || y < -0x8000 || 0x7fff < y {
+					return nil, nil, nil, errInvalidWebFont
+				}
+				xs, ys, ons = append(xs, x), append(ys, y), append(ons, on)
+			}
+			if !hasBBox {
+				bbox = [4]int16{int16(xs[0]), int16(ys[0]), int16(xs[0]), int16(ys[0])}
+				for j := range xs {
+					bbox[0] = minInt16(bbox[0], int16(xs[j]))
+					bbox[1] = minInt16(bbox[1], int16(ys[j]))
+					bbox[2] = maxInt16(bbox[2], int16(xs[j]))
+					bbox[3] = maxInt16(bbox[3], int16(ys[j]))
+				}
+			}
+			glyf = appendU16(glyf, uint16(n))
+			for _, v := range bbox {
+				glyf = appendU16(glyf, uint16(v))
+			}
+			for _, e := range endPts {
+				glyf = appendU16(glyf, e)
+			}
+			nInstrs := glyphs.u255()
+			glyf = appendU16(glyf, nInstrs)
+			glyf = append(glyf, instrs.bytes(int(nInstrs))...)
+			glyf = appendSimpleGlyfPoints(glyf, xs, ys, ons, overlap != nil && overlap[i/8]&(0x80>>uint(i%8)) != 0)
+
+		case n == -1:
+			// A compound glyph, which must have an explicit bounding box.
+			if !hasBBox {
+				return nil, nil, nil, errInvalidWebFont
+			}
+			glyf = appendU16(glyf, uint16(n))
+			for _, v := range bbox {
+				glyf = appendU16(glyf, uint16(v))
+			}
+			haveInstrs := false
+			for more := true; more; {
+				flags := composites.u16()
+				size := 2 + 2 + 2
+				if flags&compoundArgsAreWords != 0 {
+					size += 2
+				}
+				switch {
+				case flags&compoundHaveScale != 0:
+					size += 2
+				case flags&compoundHaveXYScale != 0:
+					size += 4
+				case flags&compoundHaveTwoByTwo != 0:
+					size += 8
+				}
+				glyf = appendU16(glyf, flags)
+				glyf = append(glyf, composites.bytes(size-2)...)
+				if composites.err {
+					return nil, nil, nil, errInvalidWebFont
+				}
+				haveInstrs = haveInstrs || flags&compoundHaveInstrs != 0
+				more = flags&compoundMoreComponents != 0
+			}
+			if haveInstrs {
+				nInstrs := glyphs.u255()
+				glyf = appendU16(glyf, nInstrs)
+				glyf = append(glyf, instrs.bytes(int(nInstrs))...)
+			}
+
+		default:
+			return nil, nil, nil, errInvalidWebFont
+		}
+
+		if len(glyf) > start {
+			xMins[i] = bbox[0]
+		}
+		// Pad each glyph to 4 bytes, which also keeps the offsets of a short
+		// loca table, which are halved, exact.
+		for len(glyf)&3 != 0 {
+			glyf = append(glyf, 0)
+		}
+		if len(glyf) > maxTableLength || (!longLoca && len(glyf) > 2*0xffff) {
+			return nil, nil, nil, errInvalidWebFont
+		}
+	}
+	if longLoca {
+		loca = appendU32(loca, uint32(len(glyf)))
+	} else {
+		loca = appendU16(loca, uint16(len(glyf)/2))
+	}
+	for i := range streams {
+		if streams[i].err {
+			return nil, nil, nil, errInvalidWebFont
+		}
+	}
+	return glyf, loca, xMins, nil
+}
+
+// readTriplet reads a point's coordinates, relative to the previous point, as
+// per https://www.w3.org/TR/WOFF2/ section 5.2 "Decoding of variable-length
+// X and Y coordinates", given its flag and the glyph stream that holds the
+// rest of its encoding.
+func readTriplet(flag uint8, glyphs *woff2Reader) (dx, dy int, on, ok bool) {
+	on = flag&0x80 == 0
+	flag &= 0x7f
+	// withSign returns v, negated unless bit 0 of f is set.
+	withSign := func(f uint8, v int) int {
+		if f&1 != 0 {
+			return v
+		}
+		return -v
+	}
+	switch {
+	case flag < 10:
+		b := glyphs.bytes(1)
+		if b == nil {
+			return 0, 0, false, false
+		}
+		dy = withSign(flag, int(flag&14)<<7+int(b[0]))
+	case flag < 20:
+		b := glyphs.bytes(1)
+		if b == nil {
+			return 0, 0, false, false
+		}
+		dx = withSign(flag, int((flag-10)&14)<<7+int(b[0]))
+	case flag < 84:
+		b := glyphs.bytes(1)
+		if b == nil {
+			return 0, 0, false, false
+		}
+		b0 := int(flag - 20)
+		dx = withSign(flag, 1+b0&0x30+int(b[0]>>4))
+		dy = withSign(flag>>1, 1+(b0&0x0c)<<2+int(b[0]&0x0f))
+	case flag < 120:
+		b := glyphs.bytes(2)
+		if b == nil {
+			return 0, 0, false, false
+		}
+		b0 := int(flag - 84)
+		dx = withSign(flag, 1+(b0/12)<<8+int(b[0]))
+		dy = withSign(flag>>1, 1+((b0%12)>>2)<<8+int(b[1]))
+	case flag < 124:
+		b := glyphs.bytes(3)
+		if b == nil {
+			return 0, 0, false, false
+		}
+		dx = withSign(flag, int(b[0])<<4+int(b[1]>>4))
+		dy = withSign(flag>>1, int(b[1]&0x0f)<<8+int(b[2]))
+	default:
+		b := glyphs.bytes(4)
+		if b == nil {
+			return 0, 0, false, false
+		}
+		dx = withSign(flag, int(u16(b)))
+		dy = withSign(flag>>1, int(u16(b[2:])))
+	}
+	return dx, dy, on, true
+}
+
+// appendSimpleGlyfPoints appends the flags and coordinates of a TrueType
+// simple glyph's points, given by their absolute coordinates, to dst.
+func appendSimpleGlyfPoints(dst []byte, xs, ys []int, ons []bool, overlap bool) []byte {
+	flagsStart := len(dst)
+	prevX, prevY := 0, 0
+	for i := range xs {
+		f := uint8(0)
+		if ons[i] {
+			f |= glyfOnCurve
+		}
+		if i == 0 && overlap {
+			f |= glyfOverlapSimple
+		}
+		f |= coordFlags(xs[i]-prevX, glyfXShort, glyfXSame)
+		f |= coordFlags(ys[i]-prevY, glyfYShort, glyfYSame)
+		dst = append(dst, f)
+		prevX, prevY = xs[i], ys[i]
+	}
+	prevX, prevY = 0, 0
+	for i, x := range xs {
+		dst = appendCoord(dst, x-prevX, dst[flagsStart+i], glyfXShort, glyfXSame)
+		prevX = x
+	}
+	for i, y := range ys {
+		dst = appendCoord(dst, y-prevY, dst[flagsStart+i], glyfYShort, glyfYSame)
+		prevY = y
+	}
+	return dst
+}
+
+// coordFlags returns the flags for a coordinate's delta d, given the flags
+// for a short delta and for a same (zero) or positive short delta.
+func coordFlags(d int, short, same uint8) uint8 {
+	switch {
+	case d == 0:
+		return same
+	case -0xff <= d && d < 0:
+		return short
+	case 0 < d && d <= 0xff:
+		return short | same
+	}
+	return 0
+}
+
+func appendCoord(dst []byte, d int, flags, short, same uint8) []byte {
+	switch {
+	case flags&short != 0:
+		if d < 0 {
+			d = -d
+		}
+		return append(dst, uint8(d))
+	case flags&same != 0:
+		return dst
+	}
+	return appendU16(dst, uint16(int16(d)))
+}
+
+// reconstructHmtx returns the hmtx table of a transformed hmtx table, as per
+// https://www.w3.org/TR/WOFF2/ section 5.4 "Transformed hmtx table format",
+// given the hhea table and the glyphs' minimum x coordinates.
+func reconstructHmtx(t, hhea []byte, xMins []int16, origLength uint32) ([]byte, error) {
+	if len(hhea) < 36 {
+		return nil, errInvalidWebFont
+	}
+	numHMetrics := int(u16(hhea[34:]))
+	numGlyphs := len(xMins)
+	if numHMetrics == 0 || numHMetrics > numGlyphs || origLength != uint32(2*numHMetrics+2*numGlyphs) {
+		return nil, errInvalidWebFont
+	}
+	r := woff2Reader{b: t}
+	flags := r.u8()
+	// At least one of the side bearing arrays is omitted, and the other
+	// flags are reserved.
+	if flags&3 == 0 || flags&^3 != 0 {
+		return nil, errInvalidWebFont
+	}
+	advances := r.bytes(2 * numHMetrics)
+	lsbs := append([]int16(nil), xMins...)
+	if flags&1 == 0 {
+		for i := 0; i < numHMetrics; i++ {
+			lsbs[i] = int16(r.u16())
+		}
+	}
+	if flags&2 == 0 {
+		for i := numHMetrics; i < numGlyphs; i++ {
+			lsbs[i] = int16(r.u16())
+		}
+	}
+	if r.err {
+		return nil, errInvalidWebFont
+	}
+	dst := make([]byte, 0, origLength)
+	for i, lsb := range lsbs {
+		if i < numHMetrics {
+			dst = append(dst, advances[2*i:2*i+2]...)
+		}
+		dst = appendU16(dst, uint16(lsb))
+	}
+	return dst, nil
+}
+
+func minInt16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,458 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+// sfntTables returns the tags, in order, and the data of the tables of the
+// SFNT font file src.
+func sfntTables(t *testing.T, src []byte) (tags []uint32, tables map[uint32][]byte) {
+	f, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tables = map[uint32][]byte{}
+	for _, r := range f.directory {
+		tags = append(tags, r.tag)
+		tables[r.tag] = src[r.offset : r.offset+r.length]
+	}
+	return tags, tables
+}
+
+// encodeWOFF returns the WOFF font of the SFNT font file src.
+func encodeWOFF(t *testing.T, src []byte) []byte {
+	tags, tables := sfntTables(t, src)
+	dir := appendU32(nil, woffMagic)
+	dir = appendU32(dir, u32(src))
+	dir = appendU32(dir, 0) // The length, set below.
+	dir = appendU16(dir, uint16(len(tags)))
+	dir = append(dir, make([]byte, 44-len(dir))...)
+	var data []byte
+	offset := 44 + 20*len(tags)
+	for _, tag := range tags {
+		orig := tables[tag]
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write(orig)
+		w.Close()
+		comp := buf.Bytes()
+		if len(comp) >= len(orig) {
+			comp = orig
+		}
+		dir = appendU32(dir, tag)
+		dir = appendU32(dir, uint32(offset+len(data)))
+		dir = appendU32(dir, uint32(len(comp)))
+		dir = appendU32(dir, uint32(len(orig)))
+		dir = appendU32(dir, checksum(orig))
+		data = append(data, comp...)
+		for len(data)&3 != 0 {
+			data = append(data, 0)
+		}
+	}
+	b := append(dir, data...)
+	copy(b[8:], appendU32(nil, uint32(len(b))))
+	return b
+}
+
+// identityBrotli is a stand-in for a Brotli decompressor, for WOFF2 fonts
+// whose data is not compressed.
+func identityBrotli(r io.Reader) io.Reader { return r }
+
+// encodeWOFF2 returns the WOFF2 font of the TrueType font file src, with
+// transformed glyf and loca tables, and, if transformHmtx, a transformed hmtx
+// table. Its data is not compressed, for identityBrotli.
+func encodeWOFF2(t *testing.T, src []byte, transformHmtx bool) []byte {
+	tags, tables := sfntTables(t, src)
+	glyf, xMins := transformGlyf(tables[tagGlyf], tables[tagLoca], u16(tables[tagHead][50:]) != 0)
+	var dir, data []byte
+	for _, tag := range tags {
+		flags := uint8(0x3f)
+		for i, known := range woff2KnownTags {
+			if known == tag {
+				flags = uint8(i)
+			}
+		}
+		orig, transformed := tables[tag], []byte(nil)
+		switch tag {
+		case tagGlyf:
+			transformed = glyf
+		case tagLoca:
+			transformed = []byte{}
+		case tagHmtx:
+			if transformHmtx {
+				flags |= 1 << 6
+				transformed = transformHmtxTable(t, orig, tables[tagHhea], xMins)
+			}
+		}
+		if (tag == tagGlyf || tag == tagLoca) && transformed == nil {
+			flags |= 3 << 6
+		}
+		dir = append(dir, flags)
+		if flags&0x3f == 0x3f {
+			dir = appendU32(dir, tag)
+		}
+		dir = appendBase128(dir, uint32(len(orig)))
+		if transformed != nil {
+			dir = appendBase128(dir, uint32(len(transformed)))
+			data = append(data, transformed...)
+		} else {
+			data = append(data, orig...)
+		}
+	}
+	b := appendU32(nil, woff2Magic)
+	b = appendU32(b, u32(src))
+	b = appendU32(b, 0) // The length, set below.
+	b = appendU16(b, uint16(len(tags)))
+	b = appendU16(b, 0)
+	b = appendU32(b, uint32(len(src)))
+	b = appendU32(b, uint32(len(data)))
+	b = append(b, make([]byte, 48-len(b))...)
+	b = append(b, dir...)
+	b = append(b, data...)
+	copy(b[8:], appendU32(nil, uint32(len(b))))
+	return b
+}
+
+func appendBase128(b []byte, v uint32) []byte {
+	n := 1
+	for v>>(7*uint(n)) != 0 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := uint8(v>>(7*uint(i))) & 0x7f
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+func append255(b []byte, v uint16) []byte {
+	switch {
+	case v < 253:
+		return append(b, uint8(v))
+	case v < 2*253:
+		return append(b, 255, uint8(v-253))
+	case v < 3*253:
+		return append(b, 254, uint8(v-2*253))
+	}
+	return appendU16(append(b, 253), v)
+}
+
+// transformGlyf returns the transformed glyf table, as per WOFF2 section 5.1,
+// and the glyphs' minimum x coordinates. Every other glyph has an explicit
+// bounding box, even if it could be computed from its points.
+func transformGlyf(glyf, loca []byte, longLoca bool) ([]byte, []int16) {
+	numGlyphs := len(loca)/2 - 1
+	if longLoca {
+		numGlyphs = len(loca)/4 - 1
+	}
+	var nContours, nPoints, flags, glyphs, composites, bboxes, instrs []byte
+	bboxBitmap := make([]byte, 4*((numGlyphs+31)/32))
+	xMins := make([]int16, numGlyphs)
+	for i := 0; i < numGlyphs; i++ {
+		var g []byte
+		if longLoca {
+			g = glyf[u32(loca[4*i:]):u32(loca[4*i+4:])]
+		} else {
+			g = glyf[2*int(u16(loca[2*i:])) : 2*int(u16(loca[2*i+2:]))]
+		}
+		if len(g) == 0 {
+			nContours = appendU16(nContours, 0)
+			continue
+		}
+		n := int16(u16(g))
+		nContours = appendU16(nContours, uint16(n))
+		xMins[i] = int16(u16(g[2:]))
+		if n < 0 || i%2 == 1 {
+			bboxBitmap[i/8] |= 0x80 >> uint(i%8)
+			bboxes = append(bboxes, g[2:10]...)
+		}
+		g = g[10:]
+		if n < 0 {
+			haveInstrs := false
+			for more := true; more; {
+				f := u16(g)
+				size := 6
+				if f&compoundArgsAreWords != 0 {
+					size += 2
+				}
+				switch {
+				case f&compoundHaveScale != 0:
+					size += 2
+				case f&compoundHaveXYScale != 0:
+					size += 4
+				case f&compoundHaveTwoByTwo != 0:
+					size += 8
+				}
+				composites = append(composites, g[:size]...)
+				g = g[size:]
+				haveInstrs = haveInstrs || f&compoundHaveInstrs != 0
+				more = f&compoundMoreComponents != 0
+			}
+			if haveInstrs {
+				nInstrs := u16(g)
+				glyphs = append255(glyphs, nInstrs)
+				instrs = append(instrs, g[2:2+nInstrs]...)
+			}
+			continue
+		}
+
+		prevEnd := -1
+		for j := 0; j < int(n); j++ {
+			end := int(u16(g[2*j:]))
+			nPoints = append255(nPoints, uint16(end-prevEnd))
+			prevEnd = end
+		}
+		total := prevEnd + 1
+		g = g[2*n:]
+		nInstrs := u16(g)
+		instrs = append(instrs, g[2:2+nInstrs]...)
+		g = g[2+nInstrs:]
+		// Decode the flags and then the coordinates.
+		pointFlags := make([]uint8, 0, total)
+		for len(pointFlags) < total {
+			f := g[0]
+			g = g[1:]
+			repeat := 0
+			if f&0x08 != 0 {
+				repeat, g = int(g[0]), g[1:]
+			}
+			for k := 0; k <= repeat; k++ {
+				pointFlags = append(pointFlags, f)
+			}
+		}
+		readCoords := func(short, same uint8) []int {
+			ds := make([]int, total)
+			for j, f := range pointFlags {
+				switch {
+				case f&short != 0:
+					ds[j] = int(g[0])
+					if f&same == 0 {
+						ds[j] = -ds[j]
+					}
+					g = g[1:]
+				case f&same == 0:
+					ds[j] = int(int16(u16(g)))
+					g = g[2:]
+				}
+			}
+			return ds
+		}
+		dxs := readCoords(glyfXShort, glyfXSame)
+		dys := readCoords(glyfYShort, glyfYSame)
+		for j := range pointFlags {
+			flag, triplet := encodeTriplet(dxs[j], dys[j], pointFlags[j]&glyfOnCurve != 0)
+			flags = append(flags, flag)
+			glyphs = append(glyphs, triplet...)
+		}
+		glyphs = append255(glyphs, nInstrs)
+	}
+	bboxes = append(bboxBitmap, bboxes...)
+
+	dst := appendU16(nil, 0)
+	dst = appendU16(dst, 0)
+	dst = appendU16(dst, uint16(numGlyphs))
+	if longLoca {
+		dst = appendU16(dst, 1)
+	} else {
+		dst = appendU16(dst, 0)
+	}
+	streams := [][]byte{nContours, nPoints, flags, glyphs, composites, bboxes, instrs}
+	for _, s := range streams {
+		dst = appendU32(dst, uint32(len(s)))
+	}
+	for _, s := range streams {
+		dst = append(dst, s...)
+	}
+	return dst, xMins
+}
+
+// encodeTriplet returns the flag and the glyph stream bytes of a point's
+// coordinates, as per WOFF2 section 5.2.
+func encodeTriplet(dx, dy int, on bool) (uint8, []byte) {
+	flag := 0
+	if !on {
+		flag = 0x80
+	}
+	ax, ay := dx, dy
+	xSign, ySign := 1, 2
+	if ax < 0 {
+		ax, xSign = -ax, 0
+	}
+	if ay < 0 {
+		ay, ySign = -ay, 0
+	}
+	switch {
+	case dx == 0 && ay < 1280:
+		return uint8(flag + (ay&0xf00)>>7 + ySign>>1), []byte{uint8(ay)}
+	case dy == 0 && ax < 1280:
+		return uint8(flag + 10 + (ax&0xf00)>>7 + xSign), []byte{uint8(ax)}
+	case ax < 65 && ay < 65:
+		return uint8(flag + 20 + (ax-1)&0x30 + ((ay-1)&0x30)>>2 + xSign + ySign),
+			[]byte{uint8((ax-1)&0xf<<4 | (ay-1)&0xf)}
+	case ax < 769 && ay < 769:
+		return uint8(flag + 84 + 12*(((ax-1)&0x300)>>8) + ((ay-1)&0x300)>>6 + xSign + ySign),
+			[]byte{uint8(ax - 1), uint8(ay - 1)}
+	case ax < 4096 && ay < 4096:
+		return uint8(flag + 120 + xSign + ySign), []byte{uint8(ax >> 4), uint8(ax&0xf<<4 | ay>>8), uint8(ay)}
+	}
+	return uint8(flag + 124 + xSign + ySign), []byte{uint8(ax >> 8), uint8(ax), uint8(ay >> 8), uint8(ay)}
+}
+
+// transformHmtxTable returns the transformed hmtx table, as per WOFF2 section
+// 5.4, omitting both side bearing arrays.
+func transformHmtxTable(t *testing.T, hmtx, hhea []byte, xMins []int16) []byte {
+	numHMetrics := int(u16(hhea[34:]))
+	dst := []byte{3}
+	for i := range xMins {
+		var lsb int16
+		if i < numHMetrics {
+			dst = append(dst, hmtx[4*i:4*i+2]...)
+			lsb = int16(u16(hmtx[4*i+2:]))
+		} else {
+			lsb = int16(u16(hmtx[4*numHMetrics+2*(i-numHMetrics):]))
+		}
+		if lsb != xMins[i] {
+			t.Fatalf("glyph %d: lsb %d differs from xMin %d", i, lsb, xMins[i])
+		}
+	}
+	return dst
+}
+
+// checkSameFont checks that g's glyphs, metrics and cmap are those of f.
+func checkSameFont(t *testing.T, desc string, f, g *Font) {
+	t.Helper()
+	if f.NumGlyphs() != g.NumGlyphs() {
+		t.Fatalf("%s: NumGlyphs: got %d, want %d", desc, g.NumGlyphs(), f.NumGlyphs())
+	}
+	var fb, gb Buffer
+	ppem := fixed.Int26_6(f.UnitsPerEm())
+	for i := 0; i < f.NumGlyphs(); i++ {
+		x := GlyphIndex(i)
+		for _, opts := range []*LoadGlyphOptions{nil, {Hinting: font.HintingFull}} {
+			p := ppem
+			if opts != nil {
+				p = fixed.I(16)
+			}
+			want, err := f.LoadGlyph(&fb, x, p, opts)
+			if err != nil {
+				t.Fatalf("%s: glyph %d: LoadGlyph: %v", desc, i, err)
+			}
+			got, err := g.LoadGlyph(&gb, x, p, opts)
+			if err != nil {
+				t.Fatalf("%s: glyph %d: LoadGlyph: %v", desc, i, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("%s: glyph %d, opts %v: segments differ:\ngot  %v\nwant %v", desc, i, opts, got, want)
+			}
+		}
+		wantAdv, _ := f.GlyphAdvance(&fb, x, ppem, font.HintingNone)
+		gotAdv, err := g.GlyphAdvance(&gb, x, ppem, font.HintingNone)
+		if err != nil || gotAdv != wantAdv {
+			t.Fatalf("%s: glyph %d: GlyphAdvance: got %v, %v, want %v", desc, i, gotAdv, err, wantAdv)
+		}
+	}
+	for _, r := range "Hello, Wörld!" {
+		want, _ := f.GlyphIndex(&fb, r)
+		if got, err := g.GlyphIndex(&gb, r); err != nil || got != want {
+			t.Errorf("%s: GlyphIndex(%q): got %d, %v, want %d", desc, r, got, err, want)
+		}
+	}
+}
+
+func TestParseWOFF(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	woff := encodeWOFF(t, goregular.TTF)
+	if len(woff) >= len(goregular.TTF) {
+		t.Errorf("WOFF is %d bytes, want fewer than the TTF's %d", len(woff), len(goregular.TTF))
+	}
+	g, err := Parse(woff)
+	if err != nil {
+		t.Fatalf("Parse(WOFF): %v", err)
+	}
+	checkSameFont(t, "WOFF", f, g)
+
+	g, err = ParseReaderAt(bytes.NewReader(woff))
+	if err != nil {
+		t.Fatalf("ParseReaderAt(WOFF): %v", err)
+	}
+	checkSameFont(t, "ParseReaderAt(WOFF)", f, g)
+
+	c, err := ParseCollection(woff)
+	if err != nil {
+		t.Fatalf("ParseCollection(WOFF): %v", err)
+	}
+	if n := c.NumFonts(); n != 1 {
+		t.Errorf("ParseCollection(WOFF): NumFonts: got %d, want 1", n)
+	}
+
+	if _, err := Parse(woff[:len(woff)-100]); err == nil {
+		t.Error("Parse(truncated WOFF): got nil error")
+	}
+}
+
+func TestParseWOFF2(t *testing.T) {
+	f, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	woff2 := encodeWOFF2(t, goregular.TTF, true)
+	if _, err := Parse(woff2); err != errNoBrotliDecompressor {
+		t.Errorf("Parse without RegisterBrotli: got %v, want %v", err, errNoBrotliDecompressor)
+	}
+
+	RegisterBrotli(identityBrotli)
+	defer brotliDecompressor.Store((func(io.Reader) io.Reader)(nil))
+	for _, transformHmtx := range []bool{false, true} {
+		woff2 := encodeWOFF2(t, goregular.TTF, transformHmtx)
+		g, err := Parse(woff2)
+		if err != nil {
+			t.Fatalf("transformHmtx=%t: Parse(WOFF2): %v", transformHmtx, err)
+		}
+		checkSameFont(t, "WOFF2", f, g)
+		g, err = ParseReaderAt(bytes.NewReader(woff2))
+		if err != nil {
+			t.Fatalf("transformHmtx=%t: ParseReaderAt(WOFF2): %v", transformHmtx, err)
+		}
+		checkSameFont(t, "ParseReaderAt(WOFF2)", f, g)
+	}
+
+	for _, n := range []int{10, 47, 100, len(woff2) / 2, len(woff2) - 1} {
+		if _, err := Parse(woff2[:n]); err == nil {
+			t.Errorf("Parse(WOFF2[:%d]): got nil error", n)
+		}
+	}
+}
+
+func TestTriplets(t *testing.T) {
+	for _, dx := range []int{0, 1, -1, 64, -65, 300, 768, -769, 1279, 1280, 4095, -4096, 30000} {
+		for _, dy := range []int{0, 2, -2, 64, 65, -500, 769, 1279, -1280, 4095, 4096, -30000} {
+			for _, on := range []bool{false, true} {
+				flag, b := encodeTriplet(dx, dy, on)
+				r := woff2Reader{b: b}
+				gotX, gotY, gotOn, ok := readTriplet(flag, &r)
+				if !ok || gotX != dx || gotY != dy || gotOn != on || len(r.b) != 0 {
+					t.Errorf("(%d, %d, %t): got (%d, %d, %t), ok=%t, %d bytes left",
+						dx, dy, on, gotX, gotY, gotOn, ok, len(r.b))
+				}
+			}
+		}
+	}
+}
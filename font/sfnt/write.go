@@ -0,0 +1,293 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sfnt
+
+import (
+	"sort"
+)
+
+// This file implements writing SFNT font files and the CFF table.
+
+const (
+	tagCFF  = 0x43464620 // "CFF ".
+	tagOS2  = 0x4f532f32 // "OS/2".
+	tagCmap = 0x636d6170 // "cmap".
+	tagCvt  = 0x63767420 // "cvt ".
+	tagFpgm = 0x6670676d // "fpgm".
+	tagGasp = 0x67617370 // "gasp".
+	tagGlyf = 0x676c7966 // "glyf".
+	tagHead = 0x68656164 // "head".
+	tagHhea = 0x68686561 // "hhea".
+	tagHmtx = 0x686d7478 // "hmtx".
+	tagLoca = 0x6c6f6361 // "loca".
+	tagMaxp = 0x6d617870 // "maxp".
+	tagName = 0x6e616d65 // "name".
+	tagPost = 0x706f7374 // "post".
+	tagPrep = 0x70726570 // "prep".
+)
+
+// writeFont returns an SFNT font file with the given version, 0x00010000 for
+// TrueType outlines or "OTTO" for CFF outlines, and tables, keyed by their
+// 4-byte tags. It sets the head table's checkSumAdjustment, if there is a
+// head table, so that the whole file's checksum is 0xb1b0afba.
+func writeFont(version uint32, tables map[uint32][]byte) []byte {
+	tags := make([]uint32, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	n := len(tags)
+	entrySelector := 0
+	for 2<<uint(entrySelector) <= n {
+		entrySelector++
+	}
+	searchRange := 16 << uint(entrySelector)
+
+	dst := appendU32(nil, version)
+	dst = appendU16(dst, uint16(n))
+	dst = appendU16(dst, uint16(searchRange))
+	dst = appendU16(dst, uint16(entrySelector))
+	dst = appendU16(dst, uint16(16*n-searchRange))
+
+	headOffset := -1
+	offset := 12 + 16*n
+	for _, tag := range tags {
+		t := tables[tag]
+		if tag == tagHead && len(t) >= 12 {
+			headOffset = offset
+			// The checksum of the head table is computed with a zero
+			// checkSumAdjustment.
+			t[8], t[9], t[10], t[11] = 0, 0, 0, 0
+		}
+		dst = appendU32(dst, tag)
+		dst = appendU32(dst, checksum(t))
+		dst = appendU32(dst, uint32(offset))
+		dst = appendU32(dst, uint32(len(t)))
+		offset += (len(t) + 3) &^ 3
+	}
+	for _, tag := range tags {
+		dst = append(dst, tables[tag]...)
+		for len(dst)&3 != 0 {
+			dst = append(dst, 0)
+		}
+	}
+
+	if headOffset >= 0 {
+		adj := 0xb1b0afba - checksum(dst)
+		dst[headOffset+8] = uint8(adj >> 24)
+		dst[headOffset+9] = uint8(adj >> 16)
+		dst[headOffset+10] = uint8(adj >> 8)
+		dst[headOffset+11] = uint8(adj)
+	}
+	return dst
+}
+
+// checksum returns the sum of b as big-endian uint32 values, padding b with
+// zeroes to a multiple of 4 bytes.
+func checksum(b []byte) (sum uint32) {
+	for ; len(b) >= 4; b = b[4:] {
+		sum += u32(b)
+	}
+	if len(b) > 0 {
+		var pad [4]byte
+		copy(pad[:], b)
+		sum += u32(pad[:])
+	}
+	return sum
+}
+
+func appendU16(b []byte, v uint16) []byte {
+	return append(b, uint8(v>>8), uint8(v))
+}
+
+func appendU32(b []byte, v uint32) []byte {
+	return append(b, uint8(v>>24), uint8(v>>16), uint8(v>>8), uint8(v))
+}
+
+// parseCFFIndex parses the CFF INDEX at src[offset:], as per 5176.CFF.pdf
+// section 5 "INDEX Data". It returns the INDEX's objects, which alias src,
+// and the offset of the end of the INDEX.
+func parseCFFIndex(src []byte, offset int) (objects [][]byte, end int, err error) {
+	if offset < 0 || len(src)-offset < 2 {
+		return nil, 0, errInvalidCFFTable
+	}
+	count := int(u16(src[offset:]))
+	if count == 0 {
+		return nil, offset + 2, nil
+	}
+	if len(src)-offset < 3 {
+		return nil, 0, errInvalidCFFTable
+	}
+	offSize := int(src[offset+2])
+	if offSize < 1 || 4 < offSize {
+		return nil, 0, errInvalidCFFTable
+	}
+	locs := offset + 3
+	if (len(src)-locs)/offSize < count+1 {
+		return nil, 0, errInvalidCFFTable
+	}
+	// Locations are relative to the byte that precedes the object data.
+	base := locs + (count+1)*offSize - 1
+	objects = make([][]byte, count)
+	prev := 0
+	for i := 0; i <= count; i++ {
+		loc := int(bigEndian(src[locs+i*offSize : locs+(i+1)*offSize]))
+		if (i == 0 && loc != 1) || loc < prev || len(src)-base < loc {
+			return nil, 0, errInvalidCFFTable
+		}
+		if i > 0 {
+			objects[i-1] = src[base+prev : base+loc]
+		}
+		prev = loc
+	}
+	return objects, base + prev, nil
+}
+
+// appendCFFIndex appends a CFF INDEX of the given objects to dst.
+func appendCFFIndex(dst []byte, objects [][]byte) []byte {
+	dst = appendU16(dst, uint16(len(objects)))
+	if len(objects) == 0 {
+		return dst
+	}
+	total := 1
+	for _, o := range objects {
+		total += len(o)
+	}
+	offSize := 1
+	for total >= 1<<uint(8*offSize) {
+		offSize++
+	}
+	dst = append(dst, uint8(offSize))
+	appendLoc := func(loc int) {
+		for i := offSize - 1; i >= 0; i-- {
+			dst = append(dst, uint8(loc>>uint(8*i)))
+		}
+	}
+	loc := 1
+	appendLoc(loc)
+	for _, o := range objects {
+		loc += len(o)
+		appendLoc(loc)
+	}
+	for _, o := range objects {
+		dst = append(dst, o...)
+	}
+	return dst
+}
+
+// CFF DICT operators, with two-byte operators' escape byte, 12, in the high
+// byte.
+const (
+	cffOpCharset     = 15
+	cffOpEncoding    = 16
+	cffOpCharStrings = 17
+	cffOpPrivate     = 18
+	cffOpSubrs       = 19
+	cffOpROS         = 12<<8 | 30
+	cffOpFDArray     = 12<<8 | 36
+	cffOpFDSelect    = 12<<8 | 37
+)
+
+// cffDictEntry is an operator of a CFF DICT, and its operands, each in its
+// original encoding.
+type cffDictEntry struct {
+	op       uint16
+	operands [][]byte
+}
+
+// parseCFFDict parses a CFF DICT, as per 5176.CFF.pdf section 4 "DICT Data".
+func parseCFFDict(src []byte) ([]cffDictEntry, error) {
+	var (
+		entries  []cffDictEntry
+		operands [][]byte
+	)
+	for i := 0; i < len(src); {
+		b0, n := src[i], 0
+		switch {
+		case b0 <= 21:
+			op := uint16(b0)
+			n = 1
+			if b0 == 12 {
+				if len(src)-i < 2 {
+					return nil, errInvalidCFFTable
+				}
+				op, n = 12<<8|uint16(src[i+1]), 2
+			}
+			entries = append(entries, cffDictEntry{op, operands})
+			operands = nil
+			i += n
+			continue
+		case b0 == 28:
+			n = 3
+		case b0 == 29:
+			n = 5
+		case b0 == 30:
+			// A real number, whose nibbles end with 0xf.
+			for n = 1; ; n++ {
+				if i+n >= len(src) {
+					return nil, errInvalidCFFTable
+				}
+				if c := src[i+n]; c&0x0f == 0x0f || c&0xf0 == 0xf0 {
+					n++
+					break
+				}
+			}
+		case 32 <= b0 && b0 <= 246:
+			n = 1
+		case 247 <= b0 && b0 <= 254:
+			n = 2
+		default:
+			return nil, errInvalidCFFTable
+		}
+		if len(src)-i < n {
+			return nil, errInvalidCFFTable
+		}
+		operands = append(operands, src[i:i+n])
+		i += n
+	}
+	if len(operands) != 0 {
+		return nil, errInvalidCFFTable
+	}
+	return entries, nil
+}
+
+// cffDictInt decodes a CFF DICT integer operand.
+func cffDictInt(b []byte) (int32, bool) {
+	switch b0 := b[0]; {
+	case b0 == 28:
+		return int32(int16(u16(b[1:]))), true
+	case b0 == 29:
+		return int32(u32(b[1:])), true
+	case 32 <= b0 && b0 <= 246:
+		return int32(b0) - 139, true
+	case 247 <= b0 && b0 <= 250:
+		return (int32(b0)-247)*256 + int32(b[1]) + 108, true
+	case 251 <= b0 && b0 <= 254:
+		return -(int32(b0)-251)*256 - int32(b[1]) - 108, true
+	}
+	return 0, false
+}
+
+// cffDictInt5 encodes v as a five byte CFF DICT integer operand. Offsets are
+// encoded this way, whatever their value, so that a DICT's length does not
+// depend on the offsets that it holds.
+func cffDictInt5(v int) []byte {
+	return []byte{29, uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}
+}
+
+// appendCFFDict appends a CFF DICT of the given entries to dst.
+func appendCFFDict(dst []byte, entries []cffDictEntry) []byte {
+	for _, e := range entries {
+		for _, o := range e.operands {
+			dst = append(dst, o...)
+		}
+		if e.op > 0xff {
+			dst = append(dst, uint8(e.op>>8))
+		}
+		dst = append(dst, uint8(e.op))
+	}
+	return dst
+}
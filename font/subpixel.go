@@ -0,0 +1,196 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package font
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// SubpixelLayout is the arrangement of the red, green and blue subpixels of
+// each pixel of an LCD panel, for subpixel antialiasing. Most panels are
+// SubpixelRGB.
+type SubpixelLayout int
+
+const (
+	// SubpixelNone means grayscale antialiasing.
+	SubpixelNone SubpixelLayout = iota
+	// SubpixelRGB means red, green and blue subpixels from left to right.
+	SubpixelRGB
+	// SubpixelBGR means blue, green and red subpixels from left to right.
+	SubpixelBGR
+	// SubpixelVRGB means red, green and blue subpixels from top to bottom.
+	SubpixelVRGB
+	// SubpixelVBGR means blue, green and red subpixels from top to bottom.
+	SubpixelVBGR
+)
+
+// Vertical returns whether l's subpixels are stacked vertically.
+func (l SubpixelLayout) Vertical() bool {
+	return l == SubpixelVRGB || l == SubpixelVBGR
+}
+
+// LCDFilter is the weights, out of 256, of a filter that spreads each
+// subpixel's coverage over its two neighbors on either side, to reduce the
+// color fringes of subpixel antialiasing at the cost of some sharpness. The
+// weights should sum to 256.
+type LCDFilter [5]int
+
+var (
+	// LCDFilterDefault is a filter that removes most color fringes. It is
+	// the same as FreeType's default LCD filter.
+	LCDFilterDefault = LCDFilter{8, 77, 86, 77, 8}
+	// LCDFilterLight is a sharper filter than LCDFilterDefault, with more
+	// color fringes.
+	LCDFilterLight = LCDFilter{0, 85, 86, 85, 0}
+	// LCDFilterNone leaves each subpixel's coverage unfiltered.
+	LCDFilterNone = LCDFilter{0, 0, 256, 0, 0}
+)
+
+// Subpixel is a face's subpixel antialiasing mode.
+type Subpixel struct {
+	// Layout is the arrangement of the panel's subpixels. The zero value,
+	// SubpixelNone, means grayscale antialiasing.
+	Layout SubpixelLayout
+	// Filter is the filter applied to the subpixels' coverage. Nil means
+	// LCDFilterDefault.
+	Filter *LCDFilter
+}
+
+// SubpixelMask is a glyph mask with separate coverages for the red, green and
+// blue subpixels of each pixel, for subpixel antialiasing. Each pixel is three
+// bytes, in red, green and blue order whatever the panel's SubpixelLayout.
+//
+// A Drawer whose Subpixel field is true draws the colors of a SubpixelMask
+// separately. Otherwise, it is an ordinary alpha mask: its At method returns
+// the average of the three coverages, for grayscale antialiasing.
+type SubpixelMask struct {
+	// Pix holds the mask's pixels, as red, green and blue coverages. The
+	// pixel at (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*3].
+	Pix []uint8
+	// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
+	Stride int
+	// Rect is the mask's bounds.
+	Rect image.Rectangle
+}
+
+func (m *SubpixelMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m *SubpixelMask) Bounds() image.Rectangle { return m.Rect }
+
+func (m *SubpixelMask) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(m.Rect)) {
+		return color.Alpha{}
+	}
+	p := m.Pix[m.PixOffset(x, y):]
+	return color.Alpha{uint8((int(p[0]) + int(p[1]) + int(p[2]) + 1) / 3)}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (m *SubpixelMask) PixOffset(x, y int) int {
+	return (y-m.Rect.Min.Y)*m.Stride + (x-m.Rect.Min.X)*3
+}
+
+// SetFromAlpha sets m to the subpixel mask of src, an alpha mask rendered at
+// three times the resolution along the layout's axis: three times as wide for
+// a horizontal layout, or as high for a vertical one. The filter is applied
+// to the subpixels along that axis, and m's bounds become those of src with
+// the axis divided by three, with its Min at the origin. It re-uses m's Pix
+// buffer if it is large enough.
+func (m *SubpixelMask) SetFromAlpha(src *image.Alpha, s Subpixel) {
+	filter := &LCDFilterDefault
+	if s.Filter != nil {
+		filter = s.Filter
+	}
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	// Walk each line of subpixels along the axis, with step between
+	// subpixels, and lineStep between lines.
+	n, lines, step, lineStep := w, h, 1, src.Stride
+	if s.Layout.Vertical() {
+		h /= 3
+		n, lines, step, lineStep = h*3, w, src.Stride, 1
+	} else {
+		w /= 3
+	}
+	if cap(m.Pix) < 3*w*h {
+		m.Pix = make([]uint8, 3*w*h)
+	}
+	m.Pix = m.Pix[:3*w*h]
+	m.Stride = 3 * w
+	m.Rect = image.Rect(0, 0, w, h)
+
+	bgr := s.Layout == SubpixelBGR || s.Layout == SubpixelVBGR
+	for line := 0; line < lines; line++ {
+		base := line * lineStep
+		for i := 0; i < n/3*3; i++ {
+			v := 0
+			for k, weight := range filter {
+				if j := i + k - 2; 0 <= j && j < n && weight != 0 {
+					v += weight * int(src.Pix[base+j*step])
+				}
+			}
+			v = (v + 128) >> 8
+			if v > 0xff {
+				v = 0xff
+			}
+			c := i % 3
+			if bgr {
+				c = 2 - c
+			}
+			x, y := i/3, line
+			if s.Layout.Vertical() {
+				x, y = line, i/3
+			}
+			m.Pix[y*m.Stride+3*x+c] = uint8(v)
+		}
+	}
+}
+
+// drawSubpixel draws src through the subpixel mask over dst, like
+// draw.DrawMask with the Over operator but for each color separately.
+func drawSubpixel(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point, mask *SubpixelMask, mp image.Point) {
+	// As with draw.DrawMask, r.Min in dst space corresponds to sp in src
+	// space and mp in mask space.
+	o := r.Min
+	r = r.Intersect(dst.Bounds()).Intersect(mask.Rect.Sub(mp).Add(o))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		sy := sp.Y + y - o.Y
+		my := mp.Y + y - o.Y
+		for x := r.Min.X; x < r.Max.X; x++ {
+			sx := sp.X + x - o.X
+			mx := mp.X + x - o.X
+			p := mask.Pix[mask.PixOffset(mx, my):]
+			if p[0]|p[1]|p[2] == 0 {
+				continue
+			}
+			sr, sg, sb, sa := src.At(sx, sy).RGBA()
+			dr, dg, db, da := dst.At(x, y).RGBA()
+			ca := (uint32(p[0]) + uint32(p[1]) + uint32(p[2])) * 0x101 / 3
+			a := over(sa, da, sa, ca)
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(min32(over(sr, dr, sa, uint32(p[0])*0x101), a)),
+				G: uint16(min32(over(sg, dg, sa, uint32(p[1])*0x101), a)),
+				B: uint16(min32(over(sb, db, sa, uint32(p[2])*0x101), a)),
+				A: uint16(a),
+			})
+		}
+	}
+}
+
+// over returns the Porter-Duff over composition of the premultiplied
+// component s, with alpha sa, through the mask coverage m, onto d. The
+// values are 16 bit.
+func over(s, d, sa, m uint32) uint32 {
+	return s*m/0xffff + d*(0xffff-sa*m/0xffff)/0xffff
+}
+
+func min32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package font
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func TestSubpixelMaskSetFromAlpha(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		layout SubpixelLayout
+		filter *LCDFilter
+		// cov is the coverage of each subpixel of the line.
+		cov  []uint8
+		want []uint8
+	}{{
+		desc:   "RGB, no filter",
+		layout: SubpixelRGB,
+		filter: &LCDFilterNone,
+		cov:    []uint8{0, 0, 0xff, 0xff, 0xff, 0xff, 0x80, 0, 0},
+		want:   []uint8{0, 0, 0xff, 0xff, 0xff, 0xff, 0x80, 0, 0},
+	}, {
+		desc:   "BGR, no filter",
+		layout: SubpixelBGR,
+		filter: &LCDFilterNone,
+		cov:    []uint8{0, 0, 0xff, 0xff, 0xff, 0xff, 0x80, 0, 0},
+		want:   []uint8{0xff, 0, 0, 0xff, 0xff, 0xff, 0, 0, 0x80},
+	}, {
+		desc:   "RGB, default filter",
+		layout: SubpixelRGB,
+		cov:    []uint8{0, 0, 0, 0xff, 0, 0, 0, 0, 0},
+		want:   []uint8{0, 8, 77, 86, 77, 8, 0, 0, 0},
+	}, {
+		desc:   "VRGB, light filter",
+		layout: SubpixelVRGB,
+		filter: &LCDFilterLight,
+		cov:    []uint8{0, 0, 0, 0xff, 0, 0, 0, 0, 0},
+		want:   []uint8{0, 0, 85, 86, 85, 0, 0, 0, 0},
+	}}
+	for _, tc := range testCases {
+		// The alpha image is three pixels by one line of subpixels, along the
+		// layout's axis.
+		r := image.Rect(0, 0, len(tc.cov), 1)
+		if tc.layout.Vertical() {
+			r = image.Rect(0, 0, 1, len(tc.cov))
+		}
+		src := image.NewAlpha(r)
+		copy(src.Pix, tc.cov)
+		var m SubpixelMask
+		m.SetFromAlpha(src, Subpixel{Layout: tc.layout, Filter: tc.filter})
+		wantRect := image.Rect(0, 0, 3, 1)
+		if tc.layout.Vertical() {
+			wantRect = image.Rect(0, 0, 1, 3)
+		}
+		if m.Rect != wantRect {
+			t.Errorf("%s: Rect: got %v, want %v", tc.desc, m.Rect, wantRect)
+			continue
+		}
+		if !reflect.DeepEqual(m.Pix, tc.want) {
+			t.Errorf("%s: Pix:\ngot  %v\nwant %v", tc.desc, m.Pix, tc.want)
+		}
+	}
+}
+
+// subpixelToyFace is a toyFace whose glyphs are one pixel, whose red subpixel
+// is fully covered and whose green subpixel is half covered.
+type subpixelToyFace struct {
+	toyFace
+}
+
+func (subpixelToyFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	x, y := dot.X.Floor(), dot.Y.Floor()
+	m := &SubpixelMask{
+		Pix:    []uint8{0xff, 0x80, 0x00},
+		Stride: 3,
+		Rect:   image.Rect(0, 0, 1, 1),
+	}
+	return image.Rect(x, y, x+1, y+1), m, image.Point{}, toyAdvance, true
+}
+
+func TestDrawSubpixel(t *testing.T) {
+	for _, subpixel := range []bool{false, true} {
+		dst := image.NewRGBA(image.Rect(0, 0, 2, 1))
+		for i := range dst.Pix {
+			dst.Pix[i] = 0xff
+		}
+		d := &Drawer{
+			Dst:      dst,
+			Src:      image.Black,
+			Face:     subpixelToyFace{},
+			Dot:      fixed.P(1, 0),
+			Subpixel: subpixel,
+		}
+		d.DrawString("x")
+		want := color.RGBA{0x7f, 0x7f, 0x7f, 0xff}
+		if subpixel {
+			want = color.RGBA{0x00, 0x7f, 0xff, 0xff}
+		}
+		if got := dst.RGBAAt(1, 0); got != want {
+			t.Errorf("subpixel=%t: got %v, want %v", subpixel, got, want)
+		}
+		if got, want := dst.RGBAAt(0, 0), (color.RGBA{0xff, 0xff, 0xff, 0xff}); got != want {
+			t.Errorf("subpixel=%t: left pixel: got %v, want %v", subpixel, got, want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package framediff encodes successive frames, such as screen captures, as
+// the changes from one frame to the next.
+//
+// A Differ compares each frame with the one before it, tile by tile, and
+// returns a Delta: the rectangles that changed, merged into as few as it can,
+// and their pixels, packed together. A Delta can be marshaled to send it to a
+// remote desktop client or to store it in a screen recording, and applied to
+// the previous frame to reconstruct the next one.
+package framediff // import "golang.org/x/image/framediff"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+)
+
+// DefaultTileSize is the tile width and height used when Options.TileSize is
+// zero.
+const DefaultTileSize = 16
+
+// Options are the differencing parameters. A nil *Options means the default
+// for every field.
+type Options struct {
+	// TileSize is the width and height of the tiles that frames are
+	// compared by. A tile is dirty if any of its pixels changed, so smaller
+	// tiles give tighter rectangles, at the cost of more of them. Zero means
+	// DefaultTileSize.
+	TileSize int
+}
+
+// Delta is the change from one frame to the next.
+type Delta struct {
+	// Bounds is the bounds of the frame.
+	Bounds image.Rectangle
+	// Rects is the dirty rectangles, which do not overlap.
+	Rects []image.Rectangle
+	// Pix holds the pixels of each rectangle, in turn, in the same layout as
+	// the Pix of an image.RGBA of the rectangle's size.
+	Pix []byte
+}
+
+// Differ compares successive frames. The zero Differ is not usable; use
+// NewDiffer.
+type Differ struct {
+	tileSize int
+	prev     *image.RGBA
+}
+
+// NewDiffer returns a Differ with no previous frame.
+func NewDiffer(opts *Options) *Differ {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.TileSize <= 0 {
+		o.TileSize = DefaultTileSize
+	}
+	return &Differ{tileSize: o.TileSize}
+}
+
+// Reset forgets the previous frame, so that the next frame is wholly dirty,
+// such as when a client reconnects.
+func (d *Differ) Reset() {
+	d.prev = nil
+}
+
+// Next returns the change from the previous frame to m, and makes m the
+// previous frame. The first frame, and a frame whose bounds differ from
+// those of the previous frame, is wholly dirty.
+func (d *Differ) Next(m image.Image) *Delta {
+	b := m.Bounds()
+	if d.prev == nil || d.prev.Rect != b {
+		d.prev = image.NewRGBA(b)
+		draw.Draw(d.prev, b, m, b.Min, draw.Src)
+		delta := &Delta{Bounds: b}
+		if !b.Empty() {
+			delta.Rects = []image.Rectangle{b}
+			delta.Pix = append([]byte(nil), d.prev.Pix...)
+		}
+		return delta
+	}
+
+	cur, ok := m.(*image.RGBA)
+	if !ok {
+		cur = image.NewRGBA(b)
+		draw.Draw(cur, b, m, b.Min, draw.Src)
+	}
+	delta := &Delta{
+		Bounds: b,
+		Rects:  d.dirtyRects(cur),
+	}
+	for _, r := range delta.Rects {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			i, j := cur.PixOffset(r.Min.X, y), cur.PixOffset(r.Max.X, y)
+			delta.Pix = append(delta.Pix, cur.Pix[i:j]...)
+			copy(d.prev.Pix[d.prev.PixOffset(r.Min.X, y):], cur.Pix[i:j])
+		}
+	}
+	return delta
+}
+
+// dirtyRects returns the rectangles of the tiles that differ between the
+// previous frame and cur. Dirty tiles that are next to each other in a row
+// are merged, and so are merged rows of tiles that are above each other and
+// span the same columns.
+func (d *Differ) dirtyRects(cur *image.RGBA) []image.Rectangle {
+	b, size := cur.Rect, d.tileSize
+	var rects []image.Rectangle
+	// open holds the indexes in rects of the rectangles that end at the
+	// previous row of tiles, and so can be extended downwards.
+	var open, next []int
+	for y0 := b.Min.Y; y0 < b.Max.Y; y0 += size {
+		y1 := min(y0+size, b.Max.Y)
+		next = next[:0]
+		run := -1
+		for x0 := b.Min.X; ; x0 += size {
+			end := x0 >= b.Max.X
+			dirty := !end && !d.sameTile(cur, image.Rect(x0, y0, min(x0+size, b.Max.X), y1))
+			if dirty && run < 0 {
+				run = x0
+			}
+			if dirty || run < 0 {
+				if end {
+					break
+				}
+				continue
+			}
+			// The run of dirty tiles from run to x0 has ended.
+			r := image.Rect(run, y0, min(x0, b.Max.X), y1)
+			merged := false
+			for _, i := range open {
+				if p := &rects[i]; p.Min.X == r.Min.X && p.Max.X == r.Max.X {
+					p.Max.Y = r.Max.Y
+					next = append(next, i)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				next = append(next, len(rects))
+				rects = append(rects, r)
+			}
+			run = -1
+			if end {
+				break
+			}
+		}
+		open, next = next, open
+	}
+	return rects
+}
+
+// sameTile returns whether the r part of cur is the same as that of the
+// previous frame.
+func (d *Differ) sameTile(cur *image.RGBA, r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i, j := cur.PixOffset(r.Min.X, y), cur.PixOffset(r.Max.X, y)
+		k := d.prev.PixOffset(r.Min.X, y)
+		if !bytes.Equal(cur.Pix[i:j], d.prev.Pix[k:k+j-i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Apply applies the delta to dst, which holds the previous frame, so that it
+// holds the next frame. The rectangles must be within dst's bounds.
+func (d *Delta) Apply(dst *image.RGBA) error {
+	pix := d.Pix
+	for _, r := range d.Rects {
+		if !r.In(dst.Rect) {
+			return errors.New("framediff: rectangle outside of the image")
+		}
+		n := 4 * r.Dx()
+		if len(pix) < n*r.Dy() {
+			return errors.New("framediff: too few pixels")
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			copy(dst.Pix[dst.PixOffset(r.Min.X, y):], pix[:n])
+			pix = pix[n:]
+		}
+	}
+	if len(pix) != 0 {
+		return errors.New("framediff: too many pixels")
+	}
+	return nil
+}
+
+// magic starts a marshaled Delta.
+const magic = "FDIF"
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. The
+// bounds and rectangles are varint encoded, and followed by the pixels.
+func (d *Delta) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, len(magic)+binary.MaxVarintLen64*(4+4*len(d.Rects)+1)+len(d.Pix))
+	buf = append(buf, magic...)
+	var tmp [binary.MaxVarintLen64]byte
+	put := func(v int) {
+		buf = append(buf, tmp[:binary.PutVarint(tmp[:], int64(v))]...)
+	}
+	put(d.Bounds.Min.X)
+	put(d.Bounds.Min.Y)
+	put(d.Bounds.Max.X)
+	put(d.Bounds.Max.Y)
+	put(len(d.Rects))
+	for _, r := range d.Rects {
+		// The rectangles are relative to the bounds, and so small.
+		r = r.Sub(d.Bounds.Min)
+		put(r.Min.X)
+		put(r.Min.Y)
+		put(r.Dx())
+		put(r.Dy())
+	}
+	return append(buf, d.Pix...), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (d *Delta) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return errors.New("framediff: invalid delta")
+	}
+	data = data[len(magic):]
+	var err error
+	get := func() int {
+		v, n := binary.Varint(data)
+		if n <= 0 || v < -1<<30 || v > 1<<30 {
+			err = errors.New("framediff: invalid delta")
+			return 0
+		}
+		data = data[n:]
+		return int(v)
+	}
+	var nd Delta
+	nd.Bounds = image.Rect(get(), get(), get(), get())
+	n := get()
+	if err == nil && (n < 0 || n > len(data)) {
+		err = errors.New("framediff: invalid delta")
+	}
+	size := 0
+	for i := 0; i < n && err == nil; i++ {
+		min := image.Pt(get(), get()).Add(nd.Bounds.Min)
+		r := image.Rectangle{Min: min, Max: min.Add(image.Pt(get(), get()))}
+		if !r.In(nd.Bounds) {
+			err = errors.New("framediff: invalid delta")
+		}
+		size += 4 * r.Dx() * r.Dy()
+		nd.Rects = append(nd.Rects, r)
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) != size {
+		return errors.New("framediff: invalid delta")
+	}
+	nd.Pix = append([]byte(nil), data...)
+	*d = nd
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package framediff
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestNext(t *testing.T) {
+	b := image.Rect(5, 7, 105, 67)
+	rng := rand.New(rand.NewSource(1))
+	frame := image.NewRGBA(b)
+	rng.Read(frame.Pix)
+
+	d := NewDiffer(&Options{TileSize: 8})
+	client := image.NewRGBA(b)
+	for i := 0; i < 10; i++ {
+		if i > 0 {
+			// Change a few rectangles of the frame.
+			for j := 0; j < i%4; j++ {
+				p := image.Pt(b.Min.X+rng.Intn(b.Dx()), b.Min.Y+rng.Intn(b.Dy()))
+				r := image.Rectangle{Min: p, Max: p.Add(image.Pt(1+rng.Intn(30), 1+rng.Intn(30)))}
+				c := color.RGBA{uint8(rng.Intn(256)), uint8(i), uint8(j), 0xff}
+				draw.Draw(frame, r, image.NewUniform(c), image.Point{}, draw.Src)
+			}
+		}
+		delta := d.Next(frame)
+
+		// The rectangles do not overlap.
+		for j, r := range delta.Rects {
+			for _, s := range delta.Rects[j+1:] {
+				if r.Overlaps(s) {
+					t.Fatalf("frame %d: %v overlaps %v", i, r, s)
+				}
+			}
+		}
+
+		data, err := delta.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Delta
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("frame %d: UnmarshalBinary: %v", i, err)
+		}
+		if !reflect.DeepEqual(&got, delta) {
+			t.Fatalf("frame %d: UnmarshalBinary: got %v, want %v", i, got.Rects, delta.Rects)
+		}
+		if err := got.Apply(client); err != nil {
+			t.Fatalf("frame %d: Apply: %v", i, err)
+		}
+		if !bytes.Equal(client.Pix, frame.Pix) {
+			t.Fatalf("frame %d: reconstructed frame differs", i)
+		}
+	}
+}
+
+func TestNextRects(t *testing.T) {
+	b := image.Rect(0, 0, 50, 40)
+	frame := image.NewRGBA(b)
+	d := NewDiffer(&Options{TileSize: 10})
+	if delta := d.Next(frame); !reflect.DeepEqual(delta.Rects, []image.Rectangle{b}) {
+		t.Fatalf("first frame: got %v, want %v", delta.Rects, b)
+	}
+	if delta := d.Next(frame); len(delta.Rects) != 0 || len(delta.Pix) != 0 {
+		t.Fatalf("unchanged frame: got %v", delta.Rects)
+	}
+
+	// A change spanning two tile columns and three tile rows is one
+	// rectangle, and a change at the partial right edge is another.
+	draw.Draw(frame, image.Rect(12, 5, 25, 28), image.White, image.Point{}, draw.Src)
+	frame.Set(45, 35, color.White)
+	want := []image.Rectangle{
+		image.Rect(10, 0, 30, 30),
+		image.Rect(40, 30, 50, 40),
+	}
+	delta := d.Next(frame)
+	if !reflect.DeepEqual(delta.Rects, want) {
+		t.Errorf("got %v, want %v", delta.Rects, want)
+	}
+	if got, want := len(delta.Pix), 4*(20*30+10*10); got != want {
+		t.Errorf("pixels: got %d bytes, want %d", got, want)
+	}
+
+	// A frame of a new size is wholly dirty.
+	b = image.Rect(0, 0, 30, 30)
+	if delta := d.Next(image.NewRGBA(b)); !reflect.DeepEqual(delta.Rects, []image.Rectangle{b}) {
+		t.Errorf("resized frame: got %v, want %v", delta.Rects, b)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	delta := &Delta{
+		Bounds: image.Rect(0, 0, 4, 4),
+		Rects:  []image.Rectangle{image.Rect(1, 1, 3, 2)},
+		Pix:    make([]byte, 8),
+	}
+	data, _ := delta.MarshalBinary()
+	for n := 0; n < len(data); n++ {
+		var d Delta
+		if err := d.UnmarshalBinary(data[:n]); err == nil {
+			t.Errorf("truncated to %d bytes: got nil error", n)
+		}
+	}
+	if err := new(Delta).UnmarshalBinary(append(data, 0)); err == nil {
+		t.Error("extra byte: got nil error")
+	}
+	if err := delta.Apply(image.NewRGBA(image.Rect(0, 0, 2, 2))); err == nil {
+		t.Error("Apply to a smaller image: got nil error")
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hdr implements a decoder for Radiance HDR (RGBE) images, the
+// high dynamic range format of the Radiance lighting simulation system,
+// which is widely used for environment maps.
+//
+// Decoded images are *floatimage.RGBAF32 images, whose samples are the
+// radiance values stored in the file. They are not tone mapped, so values
+// above 1 are common.
+//
+// The format is described in "Real Pixels", by Greg Ward, in Graphics Gems
+// II, and at https://radsite.lbl.gov/radiance/refer/filefmts.pdf
+package hdr // import "golang.org/x/image/hdr"
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/floatimage"
+)
+
+// A FormatError reports that the input is not a valid Radiance HDR image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "hdr: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "hdr: unsupported feature: " + string(e)
+}
+
+const (
+	// maxHeaderLine is the longest header line that Decode accepts.
+	maxHeaderLine = 4096
+	// maxPixels is the largest number of pixels of an image that Decode
+	// accepts.
+	maxPixels = 1 << 28
+)
+
+// header is the decoded header and resolution string of an image.
+type header struct {
+	width, height int
+	// yMajor is whether scanlines are rows, rather than columns.
+	yMajor bool
+	// flipX and flipY are whether x decreases, and y increases, along the
+	// scanlines and from scanline to scanline. The standard orientation,
+	// "-Y height +X width", has neither.
+	flipX, flipY bool
+}
+
+// scanlines returns the number and the length of the scanlines.
+func (h *header) scanlines() (n, length int) {
+	if h.yMajor {
+		return h.height, h.width
+	}
+	return h.width, h.height
+}
+
+// point returns the pixel at position j of scanline i.
+func (h *header) point(i, j int) (x, y int) {
+	if h.yMajor {
+		x, y = j, i
+	} else {
+		x, y = i, j
+	}
+	// In the file, y increases upwards, but in the image it increases
+	// downwards, so a "-Y" axis is not flipped.
+	if h.flipX {
+		x = h.width - 1 - x
+	}
+	if h.flipY {
+		y = h.height - 1 - y
+	}
+	return x, y
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull || len(line) > maxHeaderLine {
+		return "", FormatError("header line too long")
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+// readHeader reads the header and the resolution string of an image.
+func readHeader(r *bufio.Reader) (*header, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "#?") {
+		return nil, FormatError("bad magic number")
+	}
+	// The header's variables end at an empty line.
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "FORMAT=") {
+			if f := strings.TrimSpace(line[len("FORMAT="):]); f != "32-bit_rle_rgbe" {
+				return nil, UnsupportedError("format " + strconv.Quote(f))
+			}
+		}
+	}
+
+	line, err = readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	f := strings.Fields(line)
+	if len(f) != 4 || len(f[0]) != 2 || len(f[2]) != 2 || f[0][1] == f[2][1] {
+		return nil, FormatError("bad resolution string")
+	}
+	h := &header{}
+	for i := 0; i < 4; i += 2 {
+		sign, axis := f[i][0], f[i][1]
+		n, err := strconv.Atoi(f[i+1])
+		if err != nil || n < 0 || (sign != '-' && sign != '+') {
+			return nil, FormatError("bad resolution string")
+		}
+		switch axis {
+		case 'X':
+			h.width, h.flipX = n, sign == '-'
+		case 'Y':
+			h.height, h.flipY = n, sign == '+'
+			h.yMajor = i == 0
+		default:
+			return nil, FormatError("bad resolution string")
+		}
+	}
+	if int64(h.width)*int64(h.height) > maxPixels {
+		return nil, UnsupportedError("image too large")
+	}
+	return h, nil
+}
+
+// readScanline reads a scanline of RGBE pixels into scan, which is four
+// bytes per pixel.
+func readScanline(r *bufio.Reader, scan []byte) error {
+	n := len(scan) / 4
+	if n < 8 || n > 0x7fff {
+		return readFlatScanline(r, scan)
+	}
+	b, err := r.Peek(4)
+	if err != nil {
+		return err
+	}
+	if b[0] != 2 || b[1] != 2 || b[2]&0x80 != 0 {
+		return readFlatScanline(r, scan)
+	}
+	if int(b[2])<<8|int(b[3]) != n {
+		return FormatError("scanline length mismatch")
+	}
+	r.Discard(4)
+
+	// Each of the four components is run-length encoded in turn.
+	for c := 0; c < 4; c++ {
+		for i := 0; i < n; {
+			count, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if count > 128 {
+				run := int(count) - 128
+				if i+run > n {
+					return FormatError("run overflows the scanline")
+				}
+				v, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				for ; run > 0; run-- {
+					scan[4*i+c] = v
+					i++
+				}
+				continue
+			}
+			if count == 0 || i+int(count) > n {
+				return FormatError("bad literal run")
+			}
+			for ; count > 0; count-- {
+				v, err := r.ReadByte()
+				if err != nil {
+					return err
+				}
+				scan[4*i+c] = v
+				i++
+			}
+		}
+	}
+	return nil
+}
+
+// readFlatScanline reads a scanline that is either uncompressed or in the
+// original run-length encoding, in which a pixel of 1, 1, 1, n repeats the
+// previous pixel n times, shifted left by 8 bits for each such pixel before
+// it.
+func readFlatScanline(r *bufio.Reader, scan []byte) error {
+	shift := uint(0)
+	for i := 0; i < len(scan); {
+		if _, err := io.ReadFull(r, scan[i:i+4]); err != nil {
+			return err
+		}
+		if scan[i] != 1 || scan[i+1] != 1 || scan[i+2] != 1 {
+			i += 4
+			shift = 0
+			continue
+		}
+		if i == 0 || shift > 16 {
+			return FormatError("bad run")
+		}
+		run := int(scan[i+3]) << shift
+		if i+4*run > len(scan) {
+			return FormatError("run overflows the scanline")
+		}
+		for ; run > 0; run-- {
+			copy(scan[i:i+4], scan[i-4:i])
+			i += 4
+		}
+		shift += 8
+	}
+	return nil
+}
+
+// rgbe returns the color of an RGBE pixel, whose shared exponent is biased
+// by 128. As in Radiance, each mantissa is taken to be at the center of its
+// interval.
+func rgbe(p []byte) (r, g, b float32) {
+	if p[3] == 0 {
+		return 0, 0, 0
+	}
+	f := float32(math.Ldexp(1, int(p[3])-(128+8)))
+	return (float32(p[0]) + 0.5) * f, (float32(p[1]) + 0.5) * f, (float32(p[2]) + 0.5) * f
+}
+
+// Decode reads a Radiance HDR image from r and returns it as a
+// *floatimage.RGBAF32 image, with an alpha of 1.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	m := floatimage.NewRGBAF32(image.Rect(0, 0, h.width, h.height))
+	n, length := h.scanlines()
+	scan := make([]byte, 4*length)
+	for i := 0; i < n; i++ {
+		if err := readScanline(br, scan); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		for j := 0; j < length; j++ {
+			x, y := h.point(i, j)
+			s := m.Pix[m.PixOffset(x, y):]
+			s[0], s[1], s[2] = rgbe(scan[4*j:])
+			s[3] = 1
+		}
+	}
+	return m, nil
+}
+
+// DecodeConfig returns the color model and dimensions of a Radiance HDR
+// image without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: floatimage.RGBAF32Model,
+		Width:      h.width,
+		Height:     h.height,
+	}, nil
+}
+
+func init() {
+	image.RegisterFormat("hdr", "#?RADIANCE", Decode, DecodeConfig)
+	image.RegisterFormat("hdr", "#?RGBE", Decode, DecodeConfig)
+}
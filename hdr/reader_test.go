@@ -0,0 +1,142 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdr
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/floatimage"
+)
+
+const testHeader = "#?RADIANCE\n# a comment\nFORMAT=32-bit_rle_rgbe\nEXPOSURE=1.0\n\n"
+
+// pixel returns the RGBE pixel for the values (r, g, b) * 2**e, whose
+// mantissas are at most 255.
+func pixel(r, g, b byte, e int) []byte {
+	return []byte{r, g, b, byte(e + 128 + 8)}
+}
+
+// rleScanline returns the scanline of pixels in the run-length encoding of
+// components: the first component as a single run, and the others as
+// literals.
+func rleScanline(pixels [][]byte) []byte {
+	n := len(pixels)
+	b := []byte{2, 2, byte(n >> 8), byte(n)}
+	b = append(b, byte(128+n), pixels[0][0])
+	for c := 1; c < 4; c++ {
+		b = append(b, byte(n))
+		for _, p := range pixels {
+			b = append(b, p[c])
+		}
+	}
+	return b
+}
+
+func TestDecode(t *testing.T) {
+	// A 10x2 image: the first scanline run-length encoded, and the second
+	// flat, with a run in the original encoding.
+	var row0 [][]byte
+	for x := 0; x < 10; x++ {
+		row0 = append(row0, pixel(100, byte(10*x), 0, x-8))
+	}
+	data := []byte(testHeader + "-Y 2 +X 10\n")
+	data = append(data, rleScanline(row0)...)
+	data = append(data, pixel(1, 2, 3, 0)...)
+	data = append(data, 1, 1, 1, 8)
+	data = append(data, pixel(4, 5, 6, 1)...)
+
+	m, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := m.(*floatimage.RGBAF32)
+	if f.Rect != image.Rect(0, 0, 10, 2) {
+		t.Fatalf("bounds: got %v", f.Rect)
+	}
+	for x := 0; x < 10; x++ {
+		scale := float32(1) / (1 << 8) * float32(int(1)<<uint(x))
+		want := floatimage.RGBAF32Color{R: 100.5 * scale, G: (float32(10*x) + 0.5) * scale, B: 0.5 * scale, A: 1}
+		if got := f.RGBAF32At(x, 0); got != want {
+			t.Errorf("(%d, 0): got %v, want %v", x, got, want)
+		}
+		want = floatimage.RGBAF32Color{R: 1.5, G: 2.5, B: 3.5, A: 1}
+		if x == 9 {
+			want = floatimage.RGBAF32Color{R: 9, G: 11, B: 13, A: 1}
+		}
+		if got := f.RGBAF32At(x, 1); got != want {
+			t.Errorf("(%d, 1): got %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestDecodeOrientation(t *testing.T) {
+	// The pixels of a 3x2 image, in file order, have red values of 0 to 5.
+	var pixels []byte
+	for i := 0; i < 6; i++ {
+		pixels = append(pixels, pixel(byte(i), 0, 0, 0)...)
+	}
+	testCases := []struct {
+		resolution string
+		want       [2][3]float32
+	}{
+		{"-Y 2 +X 3", [2][3]float32{{0, 1, 2}, {3, 4, 5}}},
+		{"+Y 2 +X 3", [2][3]float32{{3, 4, 5}, {0, 1, 2}}},
+		{"-Y 2 -X 3", [2][3]float32{{2, 1, 0}, {5, 4, 3}}},
+		{"+X 3 -Y 2", [2][3]float32{{0, 2, 4}, {1, 3, 5}}},
+		{"-X 3 +Y 2", [2][3]float32{{5, 3, 1}, {4, 2, 0}}},
+	}
+	for _, tc := range testCases {
+		data := append([]byte(testHeader+tc.resolution+"\n"), pixels...)
+		m, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.resolution, err)
+			continue
+		}
+		f := m.(*floatimage.RGBAF32)
+		for y, row := range tc.want {
+			for x, want := range row {
+				if got := f.RGBAF32At(x, y).R; got != want+0.5 {
+					t.Errorf("%s: (%d, %d): got %v, want %v", tc.resolution, x, y, got, want+0.5)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"bad magic", "P6\n", FormatError("bad magic number")},
+		{"truncated header", "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\n", io.ErrUnexpectedEOF},
+		{"XYZE", "#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n", UnsupportedError(`format "32-bit_rle_xyze"`)},
+		{"bad resolution", testHeader + "-Y 1 +Y 1\n", FormatError("bad resolution string")},
+		{"truncated data", testHeader + "-Y 1 +X 2\n\x80\x80\x80\x80", io.ErrUnexpectedEOF},
+		{"run overflow", testHeader + "-Y 1 +X 8\n\x02\x02\x00\x08\x89\x00", FormatError("run overflows the scanline")},
+		{"length mismatch", testHeader + "-Y 1 +X 8\n\x02\x02\x00\x09", FormatError("scanline length mismatch")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(strings.NewReader(tc.data)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	data := testHeader + "-Y 20 +X 30\n"
+	c, format, err := image.DecodeConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "hdr" || c.Width != 30 || c.Height != 20 || c.ColorModel != floatimage.RGBAF32Model {
+		t.Errorf("got %q, %v", format, c)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package heif
+
+import (
+	"bytes"
+)
+
+// box is an ISO base media file format box.
+type box struct {
+	typ string
+	// data is the box's payload, after its size and type.
+	data []byte
+}
+
+// readBoxes returns the boxes in b, which is a sequence of boxes, such as a
+// file or the payload of a container box.
+func readBoxes(b []byte) ([]box, error) {
+	var boxes []box
+	for len(b) > 0 {
+		r := &reader{b: b}
+		size := uint64(r.u32())
+		typ := r.fourCC()
+		hdr := uint64(8)
+		switch size {
+		case 0:
+			// The box extends to the end of its container.
+			size = uint64(len(b))
+		case 1:
+			size = r.u64()
+			hdr = 16
+		}
+		if r.err != nil || size < hdr || size > uint64(len(b)) {
+			return nil, FormatError("bad box size")
+		}
+		boxes = append(boxes, box{typ, b[hdr:size]})
+		b = b[size:]
+	}
+	return boxes, nil
+}
+
+// find returns the first box of the given type, or nil.
+func find(boxes []box, typ string) *box {
+	for i := range boxes {
+		if boxes[i].typ == typ {
+			return &boxes[i]
+		}
+	}
+	return nil
+}
+
+// fullBox returns the version, flags and payload of a full box.
+func fullBox(b *box) (version uint8, flags uint32, r *reader) {
+	r = &reader{b: b.data}
+	v := r.u32()
+	return uint8(v >> 24), v & 0xffffff, r
+}
+
+// reader reads big-endian values from a box's payload. The first read past
+// the end of the payload sets err, and subsequent reads return zero.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = FormatError("truncated box")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+// uint reads an n-byte unsigned integer, where n is 0, 1, 2, 4 or 8.
+func (r *reader) uint(n int) uint64 {
+	b := r.next(n)
+	v := uint64(0)
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func (r *reader) u8() uint8   { return uint8(r.uint(1)) }
+func (r *reader) u16() uint16 { return uint16(r.uint(2)) }
+func (r *reader) u32() uint32 { return uint32(r.uint(4)) }
+func (r *reader) u64() uint64 { return r.uint(8) }
+
+func (r *reader) fourCC() string {
+	return string(r.next(4))
+}
+
+// cstring reads a null-terminated string.
+func (r *reader) cstring() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.b, 0)
+	if i < 0 {
+		r.err = FormatError("unterminated string")
+		return ""
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s
+}
@@ -0,0 +1,541 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package heif parses the HEIF container of AVIF and HEIC images, and
+// decodes their primary image with a registered decoder.
+//
+// A HEIF file is a set of items, such as coded images, thumbnails and
+// metadata, with properties, such as their size and color profile. This
+// package extracts the items and their properties, but does not decode the
+// coded images itself: decoding AV1 or HEVC is left to decoders that are
+// registered with RegisterDecoder, such as wrappers around external codec
+// libraries. Image grids and derived images are not supported.
+//
+// The format is specified by ISO/IEC 23008-12, and AVIF by
+// https://aomediacodec.github.io/av1-avif/
+package heif // import "golang.org/x/image/heif"
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// A FormatError reports that the input is not a valid HEIF file.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "heif: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "heif: unsupported feature: " + string(e)
+}
+
+// File is a parsed HEIF file.
+type File struct {
+	// MajorBrand and CompatibleBrands are the brands of the file type box,
+	// such as "avif" and "heic".
+	MajorBrand       string
+	CompatibleBrands []string
+	// Items holds the file's items, in the order of the item information
+	// box.
+	Items []*Item
+	// Primary is the primary item, the image to display.
+	Primary *Item
+
+	data []byte
+	idat []byte
+}
+
+// Item is an item of a HEIF file.
+type Item struct {
+	ID uint32
+	// Type is the item type, such as "av01" for AV1 images, "hvc1" for HEVC
+	// images, "grid" for image grids and "Exif" for Exif metadata.
+	Type string
+	Name string
+	// Width and Height are the image size declared by the item's image
+	// spatial extents property, or zero if it has none.
+	Width, Height int
+	// ICC is the ICC profile of the item's color property, if any.
+	ICC []byte
+	// CodecConfig is the payload of the item's codec configuration
+	// property, the av1C box for AV1 images and the hvcC box for HEVC
+	// images, which decoders need.
+	CodecConfig []byte
+	// Rotation is the number of anti-clockwise quarter turns, and Mirror
+	// the mirroring axis (0 for vertical, 1 for horizontal, or -1 for
+	// none), with which the image is to be displayed. They are not applied
+	// by Decode.
+	Rotation int
+	Mirror   int
+	// Refs holds the IDs of the items that this item refers to, by
+	// reference type, such as "cdsc" for the items that metadata items
+	// describe, and "thmb" for the images that thumbnails are of.
+	Refs map[string][]uint32
+
+	extents      []extent
+	construction int
+}
+
+// extent is a part of an item's data.
+type extent struct {
+	offset, length uint64
+}
+
+// Construction methods of the item location box.
+const (
+	constructFile = 0
+	constructIdat = 1
+)
+
+// Parse reads and parses a HEIF file.
+func Parse(r io.Reader) (*File, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := readBoxes(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(boxes) == 0 || boxes[0].typ != "ftyp" {
+		return nil, FormatError("missing file type box")
+	}
+	f := &File{data: data}
+	fr := &reader{b: boxes[0].data}
+	f.MajorBrand = fr.fourCC()
+	fr.u32() // The minor version.
+	for len(fr.b) >= 4 {
+		f.CompatibleBrands = append(f.CompatibleBrands, fr.fourCC())
+	}
+	if fr.err != nil {
+		return nil, fr.err
+	}
+
+	meta := find(boxes, "meta")
+	if meta == nil {
+		return nil, FormatError("missing meta box")
+	}
+	if err := f.parseMeta(meta); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) parseMeta(meta *box) error {
+	_, _, r := fullBox(meta)
+	if r.err != nil {
+		return r.err
+	}
+	boxes, err := readBoxes(r.b)
+	if err != nil {
+		return err
+	}
+	if b := find(boxes, "idat"); b != nil {
+		f.idat = b.data
+	}
+	if err := f.parseItemInfo(find(boxes, "iinf")); err != nil {
+		return err
+	}
+	if err := f.parseItemLocations(find(boxes, "iloc")); err != nil {
+		return err
+	}
+	if err := f.parseProperties(find(boxes, "iprp")); err != nil {
+		return err
+	}
+	if err := f.parseReferences(find(boxes, "iref")); err != nil {
+		return err
+	}
+
+	pitm := find(boxes, "pitm")
+	if pitm == nil {
+		return FormatError("missing primary item box")
+	}
+	v, _, r := fullBox(pitm)
+	id := uint32(r.u16())
+	if v > 0 {
+		id = id<<16 | uint32(r.u16())
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if f.Primary = f.Item(id); f.Primary == nil {
+		return FormatError("missing primary item")
+	}
+	return nil
+}
+
+// Item returns the item with the given ID, or nil.
+func (f *File) Item(id uint32) *Item {
+	for _, it := range f.Items {
+		if it.ID == id {
+			return it
+		}
+	}
+	return nil
+}
+
+func (f *File) parseItemInfo(iinf *box) error {
+	if iinf == nil {
+		return FormatError("missing item information box")
+	}
+	v, _, r := fullBox(iinf)
+	if v == 0 {
+		r.u16() // The entry count.
+	} else {
+		r.u32()
+	}
+	if r.err != nil {
+		return r.err
+	}
+	boxes, err := readBoxes(r.b)
+	if err != nil {
+		return err
+	}
+	for i := range boxes {
+		if boxes[i].typ != "infe" {
+			continue
+		}
+		v, _, r := fullBox(&boxes[i])
+		if v < 2 {
+			// Versions 0 and 1 have no item type, and are not used by
+			// image files.
+			continue
+		}
+		it := &Item{Mirror: -1}
+		if v == 2 {
+			it.ID = uint32(r.u16())
+		} else {
+			it.ID = r.u32()
+		}
+		r.u16() // The item protection index.
+		it.Type = r.fourCC()
+		// Some writers omit the name's terminating null.
+		if bytes.IndexByte(r.b, 0) >= 0 {
+			it.Name = r.cstring()
+		} else {
+			it.Name = string(r.b)
+		}
+		if r.err != nil {
+			return r.err
+		}
+		f.Items = append(f.Items, it)
+	}
+	return nil
+}
+
+func (f *File) parseItemLocations(iloc *box) error {
+	if iloc == nil {
+		return FormatError("missing item location box")
+	}
+	v, _, r := fullBox(iloc)
+	if v > 2 {
+		return UnsupportedError("item location box version")
+	}
+	sizes := r.u16()
+	offsetSize, lengthSize := int(sizes>>12), int(sizes>>8&0xf)
+	baseOffsetSize, indexSize := int(sizes>>4&0xf), 0
+	if v > 0 {
+		indexSize = int(sizes & 0xf)
+	}
+	for _, n := range []int{offsetSize, lengthSize, baseOffsetSize, indexSize} {
+		if n != 0 && n != 4 && n != 8 {
+			return FormatError("bad item location field size")
+		}
+	}
+	n := uint32(0)
+	if v < 2 {
+		n = uint32(r.u16())
+	} else {
+		n = r.u32()
+	}
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		id := uint32(0)
+		if v < 2 {
+			id = uint32(r.u16())
+		} else {
+			id = r.u32()
+		}
+		construction := 0
+		if v > 0 {
+			construction = int(r.u16() & 0xf)
+		}
+		r.u16() // The data reference index.
+		base := r.uint(baseOffsetSize)
+		var extents []extent
+		for j, m := 0, int(r.u16()); j < m && r.err == nil; j++ {
+			r.uint(indexSize)
+			e := extent{offset: base + r.uint(offsetSize), length: r.uint(lengthSize)}
+			extents = append(extents, e)
+		}
+		if it := f.Item(id); it != nil {
+			it.extents, it.construction = extents, construction
+		}
+	}
+	return r.err
+}
+
+func (f *File) parseProperties(iprp *box) error {
+	if iprp == nil {
+		return nil
+	}
+	boxes, err := readBoxes(iprp.data)
+	if err != nil {
+		return err
+	}
+	ipco := find(boxes, "ipco")
+	if ipco == nil {
+		return FormatError("missing item property container")
+	}
+	props, err := readBoxes(ipco.data)
+	if err != nil {
+		return err
+	}
+	for i := range boxes {
+		if boxes[i].typ != "ipma" {
+			continue
+		}
+		v, flags, r := fullBox(&boxes[i])
+		for j, n := uint32(0), r.u32(); j < n && r.err == nil; j++ {
+			id := uint32(0)
+			if v < 1 {
+				id = uint32(r.u16())
+			} else {
+				id = r.u32()
+			}
+			it := f.Item(id)
+			for k, m := 0, int(r.u8()); k < m && r.err == nil; k++ {
+				index := 0
+				if flags&1 != 0 {
+					index = int(r.u16() & 0x7fff)
+				} else {
+					index = int(r.u8() & 0x7f)
+				}
+				// Index 0 means no property.
+				if it == nil || index == 0 {
+					continue
+				}
+				if index > len(props) {
+					return FormatError("bad property index")
+				}
+				if err := it.setProperty(&props[index-1]); err != nil {
+					return err
+				}
+			}
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// setProperty sets the fields of it that the property p describes.
+func (it *Item) setProperty(p *box) error {
+	switch p.typ {
+	case "ispe":
+		_, _, r := fullBox(p)
+		w, h := r.u32(), r.u32()
+		if r.err != nil {
+			return r.err
+		}
+		if w > 1<<30 || h > 1<<30 {
+			return UnsupportedError("image too large")
+		}
+		it.Width, it.Height = int(w), int(h)
+	case "colr":
+		r := &reader{b: p.data}
+		if t := r.fourCC(); t == "prof" || t == "rICC" {
+			it.ICC = r.b
+		}
+		return r.err
+	case "av1C", "hvcC":
+		it.CodecConfig = p.data
+	case "irot":
+		r := &reader{b: p.data}
+		it.Rotation = int(r.u8() & 3)
+		return r.err
+	case "imir":
+		r := &reader{b: p.data}
+		it.Mirror = int(r.u8() & 1)
+		return r.err
+	}
+	return nil
+}
+
+func (f *File) parseReferences(iref *box) error {
+	if iref == nil {
+		return nil
+	}
+	v, _, r := fullBox(iref)
+	if r.err != nil {
+		return r.err
+	}
+	boxes, err := readBoxes(r.b)
+	if err != nil {
+		return err
+	}
+	id := func(r *reader) uint32 {
+		if v == 0 {
+			return uint32(r.u16())
+		}
+		return r.u32()
+	}
+	for i := range boxes {
+		r := &reader{b: boxes[i].data}
+		from := f.Item(id(r))
+		for j, n := 0, int(r.u16()); j < n && r.err == nil; j++ {
+			to := id(r)
+			if from != nil {
+				if from.Refs == nil {
+					from.Refs = map[string][]uint32{}
+				}
+				from.Refs[boxes[i].typ] = append(from.Refs[boxes[i].typ], to)
+			}
+		}
+		if r.err != nil {
+			return r.err
+		}
+	}
+	return nil
+}
+
+// Data returns the data of the item, such as the coded image of an image
+// item, or the metadata of a metadata item.
+func (f *File) Data(it *Item) ([]byte, error) {
+	var src []byte
+	switch it.construction {
+	case constructFile:
+		src = f.data
+	case constructIdat:
+		src = f.idat
+	default:
+		return nil, UnsupportedError("item construction method")
+	}
+	if len(it.extents) == 1 {
+		return extentData(src, it.extents[0])
+	}
+	var data []byte
+	for _, e := range it.extents {
+		b, err := extentData(src, e)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, b...)
+	}
+	return data, nil
+}
+
+func extentData(src []byte, e extent) ([]byte, error) {
+	end := e.offset + e.length
+	if e.length == 0 {
+		// A length of zero means the rest of the source.
+		end = uint64(len(src))
+	}
+	if e.offset > end || end > uint64(len(src)) {
+		return nil, FormatError("item extent out of range")
+	}
+	return src[e.offset:end], nil
+}
+
+// EXIF returns the Exif metadata of the primary item, as a TIFF structure
+// starting with its byte order mark, or nil if it has none.
+func (f *File) EXIF() ([]byte, error) {
+	for _, it := range f.Items {
+		if it.Type != "Exif" || !it.describes(f.Primary.ID) {
+			continue
+		}
+		data, err := f.Data(it)
+		if err != nil {
+			return nil, err
+		}
+		// The data starts with the offset of the TIFF header, after the
+		// offset itself.
+		r := &reader{b: data}
+		off := r.u32()
+		if r.err != nil || uint64(off) > uint64(len(r.b)) {
+			return nil, FormatError("bad Exif item")
+		}
+		return r.b[off:], nil
+	}
+	return nil, nil
+}
+
+// describes returns whether it is a metadata item that describes the item
+// with the given ID.
+func (it *Item) describes(id uint32) bool {
+	for _, to := range it.Refs["cdsc"] {
+		if to == id {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]func(it *Item, data []byte) (image.Image, error){}
+)
+
+// RegisterDecoder registers a decoder for the coded images of items of the
+// given type, such as "av01" or "hvc1". The decoder is passed the item,
+// whose CodecConfig holds the codec configuration, and the item's data.
+func RegisterDecoder(itemType string, decode func(it *Item, data []byte) (image.Image, error)) {
+	decodersMu.Lock()
+	decoders[itemType] = decode
+	decodersMu.Unlock()
+}
+
+// Decode reads a HEIF file from r and decodes its primary image with the
+// decoder registered for its item type.
+func Decode(r io.Reader) (image.Image, error) {
+	f, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	decodersMu.RLock()
+	decode := decoders[f.Primary.Type]
+	decodersMu.RUnlock()
+	if decode == nil {
+		return nil, UnsupportedError("no decoder for item type " + f.Primary.Type)
+	}
+	data, err := f.Data(f.Primary)
+	if err != nil {
+		return nil, err
+	}
+	return decode(f.Primary, data)
+}
+
+// DecodeConfig returns the dimensions of the primary image of a HEIF file,
+// as declared by its image spatial extents property, without decoding the
+// image. The color model is color.YCbCrModel, as AV1 and HEVC code images
+// as YCbCr.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	f, err := Parse(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: color.YCbCrModel,
+		Width:      f.Primary.Width,
+		Height:     f.Primary.Height,
+	}, nil
+}
+
+func init() {
+	image.RegisterFormat("avif", "????ftypavif", Decode, DecodeConfig)
+	image.RegisterFormat("avif", "????ftypavis", Decode, DecodeConfig)
+	image.RegisterFormat("heif", "????ftypheic", Decode, DecodeConfig)
+	image.RegisterFormat("heif", "????ftypheix", Decode, DecodeConfig)
+	image.RegisterFormat("heif", "????ftypmif1", Decode, DecodeConfig)
+}
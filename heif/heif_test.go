@@ -0,0 +1,159 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package heif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// mkbox returns a box of the given type whose payload is the concatenation
+// of the parts.
+func mkbox(typ string, parts ...[]byte) []byte {
+	payload := bytes.Join(parts, nil)
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(8+len(payload)))
+	copy(b[4:], typ)
+	return append(b, payload...)
+}
+
+// be returns the big-endian encoding of the values, each of which is a
+// uint8, uint16 or uint32.
+func be(vs ...interface{}) []byte {
+	var buf bytes.Buffer
+	for _, v := range vs {
+		binary.Write(&buf, binary.BigEndian, v)
+	}
+	return buf.Bytes()
+}
+
+var (
+	testCodec = []byte{0x81, 0x00, 0x0c, 0x00}
+	testICC   = []byte("fake ICC profile")
+	testImage = []byte("coded AV1 image")
+	testExif  = []byte("MM\x00\x2a fake TIFF")
+)
+
+// testFile returns an AVIF file with an AV1 image item, with the ID 1, and
+// an Exif item describing it, with the ID 2, whose data is stored in idat.
+func testFile() []byte {
+	ftyp := mkbox("ftyp", []byte("avif"), be(uint32(0)), []byte("avifmif1miaf"))
+	exif := append(be(uint32(2), uint16(0)), testExif...)
+	hdlr := mkbox("hdlr", be(uint32(0), uint32(0)), []byte("pict"), make([]byte, 13))
+	pitm := mkbox("pitm", be(uint32(0), uint16(1)))
+	iinf := mkbox("iinf", be(uint32(0), uint16(2)),
+		mkbox("infe", be(uint32(2<<24), uint16(1), uint16(0)), []byte("av01Color\x00")),
+		mkbox("infe", be(uint32(2<<24), uint16(2), uint16(0)), []byte("Exif\x00")),
+	)
+	iref := mkbox("iref", be(uint32(0)),
+		mkbox("cdsc", be(uint16(2), uint16(1), uint16(1))),
+	)
+	iprp := mkbox("iprp",
+		mkbox("ipco",
+			mkbox("ispe", be(uint32(0), uint32(640), uint32(480))),
+			mkbox("colr", []byte("prof"), testICC),
+			mkbox("av1C", testCodec),
+			mkbox("irot", be(uint8(1))),
+		),
+		mkbox("ipma", be(uint32(0), uint32(1), uint16(1), uint8(4), uint8(0x81), uint8(2), uint8(0x83), uint8(4))),
+	)
+	idat := mkbox("idat", exif)
+	// The iloc box is version 1, with 4-byte offsets and lengths and no base
+	// offsets. Its size does not depend on the offsets that it holds.
+	iloc := func(mdatOffset int) []byte {
+		return mkbox("iloc", be(uint32(1<<24), uint8(0x44), uint8(0x00), uint16(2),
+			uint16(1), uint16(0), uint16(0), uint16(1), uint32(mdatOffset), uint32(len(testImage)),
+			uint16(2), uint16(1), uint16(0), uint16(1), uint32(0), uint32(len(exif)),
+		))
+	}
+	meta := func(mdatOffset int) []byte {
+		return mkbox("meta", be(uint32(0)), hdlr, pitm, iinf, iref, iprp, idat, iloc(mdatOffset))
+	}
+	off := len(ftyp) + len(meta(0)) + 8
+	return bytes.Join([][]byte{ftyp, meta(off), mkbox("mdat", testImage)}, nil)
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.MajorBrand != "avif" || len(f.CompatibleBrands) != 3 || f.CompatibleBrands[1] != "mif1" {
+		t.Errorf("brands: got %q, %q", f.MajorBrand, f.CompatibleBrands)
+	}
+	if len(f.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(f.Items))
+	}
+	p := f.Primary
+	if p == nil || p.ID != 1 || p.Type != "av01" || p.Name != "Color" {
+		t.Fatalf("primary item: got %+v", p)
+	}
+	if p.Width != 640 || p.Height != 480 {
+		t.Errorf("size: got %dx%d, want 640x480", p.Width, p.Height)
+	}
+	if !bytes.Equal(p.ICC, testICC) {
+		t.Errorf("ICC: got %q, want %q", p.ICC, testICC)
+	}
+	if !bytes.Equal(p.CodecConfig, testCodec) {
+		t.Errorf("codec config: got %x, want %x", p.CodecConfig, testCodec)
+	}
+	if p.Rotation != 1 || p.Mirror != -1 {
+		t.Errorf("rotation, mirror: got %d, %d, want 1, -1", p.Rotation, p.Mirror)
+	}
+	data, err := f.Data(p)
+	if err != nil || !bytes.Equal(data, testImage) {
+		t.Errorf("data: got %q, %v, want %q", data, err, testImage)
+	}
+	exif, err := f.EXIF()
+	if err != nil || !bytes.Equal(exif, testExif) {
+		t.Errorf("Exif: got %q, %v, want %q", exif, err, testExif)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	want := image.NewGray(image.Rect(0, 0, 2, 2))
+	RegisterDecoder("av01", func(it *Item, data []byte) (image.Image, error) {
+		if !bytes.Equal(data, testImage) || !bytes.Equal(it.CodecConfig, testCodec) {
+			t.Errorf("decoder called with %q, %x", data, it.CodecConfig)
+		}
+		return want, nil
+	})
+	defer RegisterDecoder("av01", nil)
+
+	m, name, err := image.Decode(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "avif" || m != want {
+		t.Errorf("got %q, %p, want avif, %p", name, m, want)
+	}
+
+	c, _, err := image.DecodeConfig(bytes.NewReader(testFile()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Width != 640 || c.Height != 480 || c.ColorModel != color.YCbCrModel {
+		t.Errorf("config: got %+v", c)
+	}
+}
+
+func TestNoDecoder(t *testing.T) {
+	_, err := Decode(bytes.NewReader(testFile()))
+	if _, ok := err.(UnsupportedError); !ok {
+		t.Errorf("got %v, want an UnsupportedError", err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	b := testFile()
+	for n := 0; n < len(b)-len(testImage)-8; n++ {
+		if _, err := Parse(bytes.NewReader(b[:n])); err == nil {
+			t.Errorf("%d bytes: got nil error", n)
+		}
+	}
+}
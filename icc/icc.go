@@ -0,0 +1,207 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package icc implements a parser for ICC color profiles, and conversion of
+// colors between the color spaces that such profiles describe, such as from
+// an image's embedded profile to sRGB.
+//
+// Matrix/TRC and LUT based RGB and gray profiles, of ICC versions 2 and 4,
+// are supported as the source of a conversion. Only matrix/TRC based RGB
+// profiles and TRC based gray profiles, the kinds commonly embedded in camera
+// and web images, are currently supported as its destination.
+//
+// The ICC specification is at http://www.color.org/specification/ICC1v43_2010-12.pdf
+package icc // import "golang.org/x/image/icc"
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	errInvalidProfile = errors.New("icc: invalid profile")
+
+	// ErrUnsupported means that a profile is valid, but describes a color
+	// space or uses a tag type that this package cannot convert from.
+	ErrUnsupported = errors.New("icc: unsupported profile")
+)
+
+// Profile is a parsed ICC profile.
+type Profile struct {
+	// Version is the profile's version, such as 0x02100000 for version 2.1
+	// or 0x04300000 for version 4.3.
+	Version uint32
+	// Class is the profile's device class, such as "mntr" for a display
+	// device profile.
+	Class string
+	// ColorSpace is the profile's data color space, such as "RGB " or
+	// "GRAY".
+	ColorSpace string
+	// PCS is the profile's connection space, "XYZ " or "Lab ".
+	PCS string
+
+	// tags maps tag signatures to tag data.
+	tags map[string][]byte
+	// srgb is whether this is the built-in profile returned by SRGB.
+	srgb bool
+}
+
+// Parse parses an ICC profile. The profile keeps references to b, which must
+// not be modified while the profile is in use.
+func Parse(b []byte) (*Profile, error) {
+	const headerSize = 128
+	if len(b) < headerSize+4 || string(b[36:40]) != "acsp" {
+		return nil, errInvalidProfile
+	}
+	size := u32(b[0:])
+	if size < headerSize+4 || uint64(size) > uint64(len(b)) {
+		return nil, errInvalidProfile
+	}
+	b = b[:size]
+	p := &Profile{
+		Version:    u32(b[8:]),
+		Class:      string(b[12:16]),
+		ColorSpace: string(b[16:20]),
+		PCS:        string(b[20:24]),
+	}
+
+	n := u32(b[headerSize:])
+	if uint64(n) > uint64(len(b)-headerSize-4)/12 {
+		return nil, errInvalidProfile
+	}
+	p.tags = make(map[string][]byte, n)
+	for i := uint32(0); i < n; i++ {
+		e := b[headerSize+4+12*i:]
+		offset, size := u32(e[4:]), u32(e[8:])
+		if uint64(offset)+uint64(size) > uint64(len(b)) {
+			return nil, errInvalidProfile
+		}
+		p.tags[string(e[0:4])] = b[offset : offset+size]
+	}
+	return p, nil
+}
+
+// xyz parses an 'XYZ ' type tag holding a single XYZ value.
+func (p *Profile) xyz(sig string) (x, y, z float64, err error) {
+	b := p.tags[sig]
+	if b == nil {
+		return 0, 0, 0, ErrUnsupported
+	}
+	if len(b) < 20 || string(b[0:4]) != "XYZ " {
+		return 0, 0, 0, errInvalidProfile
+	}
+	return s15Fixed16(b[8:]), s15Fixed16(b[12:]), s15Fixed16(b[16:]), nil
+}
+
+// curve is a tone reproduction curve, mapping encoded device values in the
+// range [0, 1] to linear values.
+type curve func(x float64) float64
+
+// trc parses a 'curv' or 'para' type tag.
+func (p *Profile) trc(sig string) (curve, error) {
+	b := p.tags[sig]
+	if b == nil {
+		return nil, ErrUnsupported
+	}
+	c, _, err := parseCurve(b)
+	return c, err
+}
+
+// parseCurve parses a 'curv' or 'para' type element at the start of b, and
+// returns its length in bytes, excluding any padding.
+func parseCurve(b []byte) (c curve, n int, err error) {
+	if len(b) < 12 {
+		return nil, 0, errInvalidProfile
+	}
+	switch string(b[0:4]) {
+	case "curv":
+		n := u32(b[8:])
+		if uint64(n) > uint64(len(b)-12)/2 {
+			return nil, 0, errInvalidProfile
+		}
+		switch n {
+		case 0:
+			return func(x float64) float64 { return x }, 12, nil
+		case 1:
+			g := float64(u16(b[12:])) / 256
+			return func(x float64) float64 { return math.Pow(x, g) }, 14, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(u16(b[12+2*i:])) / 0xffff
+		}
+		return tableCurve(table), 12 + 2*int(n), nil
+
+	case "para":
+		funcType := u16(b[8:])
+		nParams := [...]int{1, 3, 4, 5, 7}
+		if int(funcType) >= len(nParams) {
+			return nil, 0, ErrUnsupported
+		}
+		n := 12 + 4*nParams[funcType]
+		if len(b) < n {
+			return nil, 0, errInvalidProfile
+		}
+		// Unused parameters are zero, which makes every function type a
+		// special case of type 4:
+		// Y = (aX+b)^g + e for X >= d, and Y = cX + f otherwise.
+		var v [7]float64
+		for i := 0; i < nParams[funcType]; i++ {
+			v[i] = s15Fixed16(b[12+4*i:])
+		}
+		g, a, bb, c, d, e, f := v[0], v[1], v[2], v[3], v[4], v[5], v[6]
+		if a == 0 && (funcType == 1 || funcType == 2) {
+			return nil, 0, errInvalidProfile
+		}
+		switch funcType {
+		case 0:
+			a, d = 1, math.Inf(-1)
+		case 1:
+			d = -bb / a
+		case 2:
+			d, e, f = -bb/a, c, c
+			c = 0
+		}
+		return func(x float64) float64 {
+			if x >= d {
+				if t := a*x + bb; t > 0 {
+					return math.Pow(t, g) + e
+				}
+				return e
+			}
+			return c*x + f
+		}, n, nil
+	}
+	return nil, 0, ErrUnsupported
+}
+
+// tableCurve returns the curve that linearly interpolates between the
+// evenly spaced samples of table, which has at least two elements.
+func tableCurve(table []float64) curve {
+	return func(x float64) float64 {
+		f := x * float64(len(table)-1)
+		i := int(f)
+		if i >= len(table)-1 {
+			return table[len(table)-1]
+		}
+		if i < 0 {
+			return table[0]
+		}
+		return table[i] + (f-float64(i))*(table[i+1]-table[i])
+	}
+}
+
+func u16(b []byte) uint16 {
+	_ = b[1] // Bounds check hint to compiler.
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func u32(b []byte) uint32 {
+	_ = b[3] // Bounds check hint to compiler.
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(u32(b))) / 0x10000
+}
@@ -0,0 +1,296 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"image"
+	"io/ioutil"
+	"math"
+	"sort"
+	"testing"
+)
+
+// linear-rgb.icc is a v2 matrix/TRC profile with the sRGB primaries but a
+// linear (gamma 1.0) tone response.
+const linearRGBProfile = "../testdata/linear-rgb.icc"
+
+func TestParse(t *testing.T) {
+	b, err := ioutil.ReadFile(linearRGBProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if p.Version != 0x02100000 || p.Class != "mntr" || p.ColorSpace != "RGB " || p.PCS != "XYZ " {
+		t.Errorf("header: got %#x %q %q %q", p.Version, p.Class, p.ColorSpace, p.PCS)
+	}
+
+	for _, n := range []int{0, 100, 131, len(b) - 1} {
+		if _, err := Parse(b[:n]); err == nil {
+			t.Errorf("truncated to %d bytes: got nil error", n)
+		}
+	}
+}
+
+func TestTransformToSRGB(t *testing.T) {
+	b, err := ioutil.ReadFile(linearRGBProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	tr, err := p.TransformToSRGB()
+	if err != nil {
+		t.Fatalf("TransformToSRGB: %v", err)
+	}
+
+	// With sRGB primaries, only the tone response differs from sRGB, so
+	// gray and primary colors stay gray and primary.
+	testCases := []struct {
+		in, want [3]uint8
+	}{
+		{[3]uint8{0x00, 0x00, 0x00}, [3]uint8{0x00, 0x00, 0x00}},
+		{[3]uint8{0xff, 0xff, 0xff}, [3]uint8{0xff, 0xff, 0xff}},
+		{[3]uint8{0x80, 0x80, 0x80}, [3]uint8{0xbc, 0xbc, 0xbc}},
+		{[3]uint8{0x20, 0x00, 0x00}, [3]uint8{0x63, 0x00, 0x00}},
+	}
+	for _, tc := range testCases {
+		r, g, b := tr.Convert(tc.in[0], tc.in[1], tc.in[2])
+		if got := [3]uint8{r, g, b}; got != tc.want {
+			t.Errorf("Convert(%v): got %v, want %v", tc.in, got, tc.want)
+		}
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	copy(m.Pix, []uint8{0x80, 0x80, 0x80, 0x40, 0x20, 0x00, 0x00, 0xff})
+	tr.ConvertNRGBA(m)
+	want := []uint8{0xbc, 0xbc, 0xbc, 0x40, 0x63, 0x00, 0x00, 0xff}
+	if string(m.Pix) != string(want) {
+		t.Errorf("ConvertNRGBA: got % x, want % x", m.Pix, want)
+	}
+}
+
+func TestParametricCurve(t *testing.T) {
+	// The sRGB tone response as a type 3 parametric curve.
+	para := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		x := uint32(int32(math.Round(v * 0x10000)))
+		para = append(para, uint8(x>>24), uint8(x>>16), uint8(x>>8), uint8(x))
+	}
+	p := &Profile{tags: map[string][]byte{"rTRC": para}}
+	c, err := p.trc("rTRC")
+	if err != nil {
+		t.Fatalf("trc: %v", err)
+	}
+	for _, tc := range []struct{ x, want float64 }{
+		{0, 0},
+		{0.02, 0.02 / 12.92},
+		{0.5, 0.2140},
+		{1, 1},
+	} {
+		if got := c(tc.x); math.Abs(got-tc.want) > 1e-3 {
+			t.Errorf("x=%v: got %v, want %v", tc.x, got, tc.want)
+		}
+	}
+}
+
+func TestNewTransformIdentity(t *testing.T) {
+	b, err := ioutil.ReadFile(linearRGBProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	for _, tc := range []struct {
+		name string
+		p    *Profile
+	}{
+		{"linear", p},
+		{"sRGB", SRGB()},
+	} {
+		tr, err := NewTransform(tc.p, tc.p)
+		if err != nil {
+			t.Fatalf("%s: NewTransform: %v", tc.name, err)
+		}
+		for i := 0; i < 256; i += 5 {
+			in := [3]uint8{uint8(i), uint8(255 - i), uint8(i / 2)}
+			r, g, b := tr.Convert(in[0], in[1], in[2])
+			if got := [3]uint8{r, g, b}; !within1(got, in) {
+				t.Errorf("%s: Convert(%v): got %v", tc.name, in, got)
+			}
+		}
+	}
+
+	// Converting to sRGB and back is also the identity.
+	to, err := NewTransform(p, SRGB())
+	if err != nil {
+		t.Fatalf("NewTransform: %v", err)
+	}
+	from, err := NewTransform(SRGB(), p)
+	if err != nil {
+		t.Fatalf("NewTransform: %v", err)
+	}
+	for _, in := range [][3]uint8{{0x80, 0x80, 0x80}, {0xc0, 0x40, 0x20}, {0xff, 0xff, 0xff}} {
+		r, g, b := to.Convert(in[0], in[1], in[2])
+		r, g, b = from.Convert(r, g, b)
+		if got := [3]uint8{r, g, b}; !within1(got, in) {
+			t.Errorf("round trip of %v: got %v", in, got)
+		}
+	}
+}
+
+func within1(a, b [3]uint8) bool {
+	for i := range a {
+		if d := int(a[i]) - int(b[i]); d < -1 || d > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// buildProfile returns an ICC profile with the given header fields and tags.
+func buildProfile(colorSpace, pcs string, tags map[string][]byte) []byte {
+	var sigs []string
+	for sig := range tags {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+
+	b := make([]byte, 128+4+12*len(sigs))
+	copy(b[8:], "\x04\x30\x00\x00mntr")
+	copy(b[16:], colorSpace)
+	copy(b[20:], pcs)
+	copy(b[36:], "acsp")
+	putU32(b[128:], uint32(len(sigs)))
+	for i, sig := range sigs {
+		e := b[128+4+12*i:]
+		copy(e, sig)
+		putU32(e[4:], uint32(len(b)))
+		putU32(e[8:], uint32(len(tags[sig])))
+		b = append(b, tags[sig]...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	putU32(b[0:], uint32(len(b)))
+	return b
+}
+
+func putU32(b []byte, u uint32) {
+	b[0], b[1], b[2], b[3] = uint8(u>>24), uint8(u>>16), uint8(u>>8), uint8(u)
+}
+
+func appendU16(b []byte, u uint16) []byte {
+	return append(b, uint8(u>>8), uint8(u))
+}
+
+func TestLUTProfiles(t *testing.T) {
+	b, err := ioutil.ReadFile(linearRGBProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	linear, err := Parse(b)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want, err := linear.TransformToSRGB()
+	if err != nil {
+		t.Fatalf("TransformToSRGB: %v", err)
+	}
+
+	// A 2×2×2 CLUT holding the XYZ colors of the linear profile's primaries
+	// and their sums describes the same color space, as interpolating
+	// linearly between them is exact.
+	var cols [3][3]float64
+	for i, sig := range [3]string{"rXYZ", "gXYZ", "bXYZ"} {
+		cols[i][0], cols[i][1], cols[i][2], _ = linear.xyz(sig)
+	}
+	var clut []byte
+	for r := 0; r < 2; r++ {
+		for g := 0; g < 2; g++ {
+			for b := 0; b < 2; b++ {
+				for k := 0; k < 3; k++ {
+					v := float64(r)*cols[0][k] + float64(g)*cols[1][k] + float64(b)*cols[2][k]
+					clut = appendU16(clut, uint16(math.Round(v*0x8000)))
+				}
+			}
+		}
+	}
+	identity := []byte("curv\x00\x00\x00\x00\x00\x00\x00\x00")
+
+	// The 'mft2' type has 2-entry identity tables.
+	mft2 := []byte("mft2\x00\x00\x00\x00\x03\x03\x02\x00")
+	for i := 0; i < 9; i++ {
+		mft2 = append(mft2, 0, 0, 0, 0)
+	}
+	mft2 = append(mft2, 0, 2, 0, 2)
+	for i := 0; i < 3; i++ {
+		mft2 = append(mft2, 0x00, 0x00, 0xff, 0xff)
+	}
+	mft2 = append(mft2, clut...)
+	for i := 0; i < 3; i++ {
+		mft2 = append(mft2, 0x00, 0x00, 0xff, 0xff)
+	}
+
+	// The 'mAB ' type has A curves, a CLUT and B curves, but no M curves or
+	// matrix.
+	mAB := []byte("mAB \x00\x00\x00\x00\x03\x03\x00\x00")
+	for _, off := range []uint32{32, 0, 0, 68, 136} {
+		mAB = append(mAB, uint8(off>>24), uint8(off>>16), uint8(off>>8), uint8(off))
+	}
+	for len(mAB) < 68 {
+		mAB = append(mAB, identity...)
+	}
+	mAB = append(mAB, 2, 2, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0)
+	mAB = append(mAB, clut...)
+	for i := 0; i < 3; i++ {
+		mAB = append(mAB, identity...)
+	}
+
+	for name, tag := range map[string][]byte{"mft2": mft2, "mAB ": mAB} {
+		p, err := Parse(buildProfile("RGB ", "XYZ ", map[string][]byte{"A2B0": tag}))
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", name, err)
+		}
+		tr, err := p.TransformToSRGB()
+		if err != nil {
+			t.Fatalf("%s: TransformToSRGB: %v", name, err)
+		}
+		for i := 0; i < 256; i += 3 {
+			in := [3]uint8{uint8(i), uint8(255 - i), uint8(i / 3)}
+			r0, g0, b0 := want.Convert(in[0], in[1], in[2])
+			r1, g1, b1 := tr.Convert(in[0], in[1], in[2])
+			if got, want := [3]uint8{r1, g1, b1}, [3]uint8{r0, g0, b0}; !within1(got, want) {
+				t.Errorf("%s: Convert(%v): got %v, want %v", name, in, got, want)
+			}
+		}
+
+		// Truncated tags are invalid.
+		for _, n := range []int{0, 20, len(tag) - 1} {
+			p, err := Parse(buildProfile("RGB ", "XYZ ", map[string][]byte{"A2B0": tag[:n]}))
+			if err != nil {
+				t.Fatalf("%s: Parse: %v", name, err)
+			}
+			if _, err := p.TransformToSRGB(); err == nil {
+				t.Errorf("%s: truncated to %d bytes: got nil error", name, n)
+			}
+		}
+	}
+}
+
+func TestLabToXYZ(t *testing.T) {
+	if got := labToXYZ(100, 0, 0); math.Abs(got[0]-d50[0]) > 1e-9 || math.Abs(got[1]-d50[1]) > 1e-9 || math.Abs(got[2]-d50[2]) > 1e-9 {
+		t.Errorf("white: got %v, want %v", got, d50)
+	}
+	if got := labToXYZ(0, 0, 0); got != [3]float64{} {
+		t.Errorf("black: got %v, want zero", got)
+	}
+}
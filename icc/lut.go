@@ -0,0 +1,344 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+// d50 is the profile connection space's white point, in CIE XYZ.
+var d50 = [3]float64{0.9642, 1.0000, 0.8249}
+
+// lut is a parsed 'mft1', 'mft2' or 'mAB ' type tag, which maps device values
+// to PCS values, both in the range [0, 1], through a pipeline of optional
+// stages: input curves, a multi-dimensional color lookup table, middle
+// curves, a matrix and output curves.
+type lut struct {
+	nIn     int
+	aCurves []curve
+	clut    *clut
+	mCurves []curve
+	matrix  *[12]float64
+	bCurves []curve
+	// legacyLab is whether Lab PCS values use the 16-bit encoding of ICC
+	// version 2, where 0xff00 means an L* of 100, instead of 0xffff.
+	legacyLab bool
+}
+
+// clut is a color lookup table: a grid of output values, with grid[i]
+// points along the i'th input dimension. The first input varies the least
+// rapidly.
+type clut struct {
+	grid []int
+	nOut int
+	data []float64
+}
+
+// lut parses the lut type tag with the given signature, for a device with
+// nIn channels.
+func (p *Profile) lut(sig string, nIn int) (*lut, error) {
+	b := p.tags[sig]
+	if b == nil {
+		return nil, ErrUnsupported
+	}
+	if len(b) < 12 {
+		return nil, errInvalidProfile
+	}
+	switch string(b[0:4]) {
+	case "mft1":
+		return parseLutN(b, nIn, 1)
+	case "mft2":
+		return parseLutN(b, nIn, 2)
+	case "mAB ":
+		return parseLutAToB(b, nIn)
+	}
+	return nil, ErrUnsupported
+}
+
+// parseLutN parses a 'mft1' or 'mft2' type tag, whose samples are n bytes
+// long. Their matrix only applies to XYZ input, and so is ignored.
+func parseLutN(b []byte, nIn, n int) (*lut, error) {
+	if len(b) < 48 {
+		return nil, errInvalidProfile
+	}
+	if int(b[8]) != nIn || b[9] != 3 {
+		return nil, ErrUnsupported
+	}
+	g := int(b[10])
+	if g < 2 {
+		return nil, errInvalidProfile
+	}
+	inEntries, outEntries, b := 256, 256, b[48:]
+	if n == 2 {
+		if len(b) < 4 {
+			return nil, errInvalidProfile
+		}
+		inEntries, outEntries, b = int(u16(b[0:])), int(u16(b[2:])), b[4:]
+		if inEntries < 2 || outEntries < 2 {
+			return nil, errInvalidProfile
+		}
+	}
+
+	l := &lut{nIn: nIn, legacyLab: n == 2}
+	var err error
+	if l.aCurves, b, err = parseTables(b, nIn, inEntries, n); err != nil {
+		return nil, err
+	}
+	grid := make([]int, nIn)
+	for i := range grid {
+		grid[i] = g
+	}
+	if l.clut, b, err = parseCLUT(b, grid, 3, n); err != nil {
+		return nil, err
+	}
+	if l.bCurves, _, err = parseTables(b, 3, outEntries, n); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// parseTables parses the channels' tables of a 'mft1' or 'mft2' type tag, and
+// returns them as curves and the rest of b.
+func parseTables(b []byte, channels, entries, n int) ([]curve, []byte, error) {
+	size := channels * entries * n
+	if len(b) < size {
+		return nil, nil, errInvalidProfile
+	}
+	curves := make([]curve, channels)
+	for i := range curves {
+		table := make([]float64, entries)
+		for j := range table {
+			table[j] = sample(b[(i*entries+j)*n:], n)
+		}
+		curves[i] = tableCurve(table)
+	}
+	return curves, b[size:], nil
+}
+
+// parseLutAToB parses a 'mAB ' type tag. Its elements are at offsets, from
+// the start of the tag, in its header.
+func parseLutAToB(b []byte, nIn int) (*lut, error) {
+	if len(b) < 32 {
+		return nil, errInvalidProfile
+	}
+	if int(b[8]) != nIn || b[9] != 3 {
+		return nil, ErrUnsupported
+	}
+	offB, offMatrix, offM, offCLUT, offA := u32(b[12:]), u32(b[16:]), u32(b[20:]), u32(b[24:]), u32(b[28:])
+	element := func(off uint32) []byte {
+		if off == 0 || uint64(off) >= uint64(len(b)) {
+			return nil
+		}
+		return b[off:]
+	}
+
+	l := &lut{nIn: nIn}
+	var err error
+	// The B curves are required, and the other elements are optional. The M
+	// curves and matrix come as a pair, as do the A curves and CLUT.
+	if offB == 0 {
+		return nil, errInvalidProfile
+	}
+	if l.bCurves, err = parseCurves(element(offB), 3); err != nil {
+		return nil, err
+	}
+	if offMatrix != 0 {
+		e := element(offMatrix)
+		if len(e) < 48 {
+			return nil, errInvalidProfile
+		}
+		l.matrix = &[12]float64{}
+		for i := range l.matrix {
+			l.matrix[i] = s15Fixed16(e[4*i:])
+		}
+		if l.mCurves, err = parseCurves(element(offM), 3); err != nil {
+			return nil, err
+		}
+	}
+	if offCLUT != 0 {
+		e := element(offCLUT)
+		if len(e) < 20 {
+			return nil, errInvalidProfile
+		}
+		grid := make([]int, nIn)
+		for i := range grid {
+			grid[i] = int(e[i])
+		}
+		n := int(e[16])
+		if n != 1 && n != 2 {
+			return nil, errInvalidProfile
+		}
+		if l.clut, _, err = parseCLUT(e[20:], grid, 3, n); err != nil {
+			return nil, err
+		}
+		if l.aCurves, err = parseCurves(element(offA), nIn); err != nil {
+			return nil, err
+		}
+	} else if nIn != 3 {
+		// Without a CLUT, the number of input and output channels must match.
+		return nil, errInvalidProfile
+	}
+	return l, nil
+}
+
+// parseCurves parses n 'curv' or 'para' type elements, each padded to a
+// multiple of 4 bytes.
+func parseCurves(b []byte, n int) ([]curve, error) {
+	curves := make([]curve, n)
+	for i := range curves {
+		c, m, err := parseCurve(b)
+		if err != nil {
+			return nil, err
+		}
+		curves[i] = c
+		if m = (m + 3) &^ 3; m < len(b) {
+			b = b[m:]
+		} else {
+			b = nil
+		}
+	}
+	return curves, nil
+}
+
+// parseCLUT parses a color lookup table, whose samples are n bytes long, and
+// returns it and the rest of b.
+func parseCLUT(b []byte, grid []int, nOut, n int) (*clut, []byte, error) {
+	points := 1
+	for _, g := range grid {
+		if g < 2 {
+			return nil, nil, errInvalidProfile
+		}
+		// With at most 3 inputs of at most 255 grid points each, points
+		// cannot overflow.
+		points *= g
+	}
+	size := points * nOut * n
+	if len(b) < size {
+		return nil, nil, errInvalidProfile
+	}
+	c := &clut{
+		grid: grid,
+		nOut: nOut,
+		data: make([]float64, points*nOut),
+	}
+	for i := range c.data {
+		c.data[i] = sample(b[i*n:], n)
+	}
+	return c, b[size:], nil
+}
+
+// sample returns the n byte unsigned sample at the start of b, scaled to
+// [0, 1].
+func sample(b []byte, n int) float64 {
+	if n == 1 {
+		return float64(b[0]) / 0xff
+	}
+	return float64(u16(b)) / 0xffff
+}
+
+// lookup sets out to the color of the table at the point in, interpolating
+// linearly between the surrounding grid points.
+func (c *clut) lookup(out, in []float64) {
+	var (
+		base   int
+		frac   [3]float64
+		stride [3]int
+	)
+	s := c.nOut
+	for i := len(c.grid) - 1; i >= 0; i-- {
+		g := c.grid[i]
+		f := clamp01(in[i]) * float64(g-1)
+		j := int(f)
+		if j >= g-1 {
+			j = g - 2
+		}
+		frac[i] = f - float64(j)
+		stride[i] = s
+		base += j * s
+		s *= g
+	}
+	for k := range out[:c.nOut] {
+		out[k] = 0
+	}
+	// Sum the weighted colors of the 2^len(grid) surrounding grid points.
+	for corner := 0; corner < 1<<uint(len(c.grid)); corner++ {
+		w, offset := 1.0, base
+		for i := range c.grid {
+			if corner&(1<<uint(i)) != 0 {
+				w *= frac[i]
+				offset += stride[i]
+			} else {
+				w *= 1 - frac[i]
+			}
+		}
+		if w == 0 {
+			continue
+		}
+		for k := range out[:c.nOut] {
+			out[k] += w * c.data[offset+k]
+		}
+	}
+}
+
+// apply returns the CIE XYZ color, relative to the D50 white point, of the
+// device color in, given pcs, the profile's connection space.
+func (l *lut) apply(in []float64, pcs string) [3]float64 {
+	var v [3]float64
+	copy(v[:], in[:l.nIn])
+	for i, c := range l.aCurves {
+		v[i] = c(clamp01(v[i]))
+	}
+	if l.clut != nil {
+		var out [3]float64
+		l.clut.lookup(out[:], v[:l.nIn])
+		v = out
+	}
+	for i, c := range l.mCurves {
+		v[i] = c(clamp01(v[i]))
+	}
+	if m := l.matrix; m != nil {
+		v = [3]float64{
+			m[0]*v[0] + m[1]*v[1] + m[2]*v[2] + m[9],
+			m[3]*v[0] + m[4]*v[1] + m[5]*v[2] + m[10],
+			m[6]*v[0] + m[7]*v[1] + m[8]*v[2] + m[11],
+		}
+	}
+	for i, c := range l.bCurves {
+		v[i] = c(clamp01(v[i]))
+	}
+
+	if pcs == "XYZ " {
+		// 1.0 is encoded as 0x8000.
+		const scale = 0xffff / float64(0x8000)
+		return [3]float64{v[0] * scale, v[1] * scale, v[2] * scale}
+	}
+	if l.legacyLab {
+		const scale = 0xffff / float64(0xff00)
+		v[0], v[1], v[2] = v[0]*scale, v[1]*scale, v[2]*scale
+	}
+	return labToXYZ(100*v[0], 255*v[1]-128, 255*v[2]-128)
+}
+
+// labToXYZ converts from CIE L*a*b* to CIE XYZ, both relative to the D50
+// white point.
+func labToXYZ(l, a, b float64) [3]float64 {
+	fy := (l + 16) / 116
+	fx := fy + a/500
+	fz := fy - b/200
+	finv := func(t float64) float64 {
+		const delta = 6.0 / 29
+		if t > delta {
+			return t * t * t
+		}
+		return 3 * delta * delta * (t - 4.0/29)
+	}
+	return [3]float64{d50[0] * finv(fx), d50[1] * finv(fy), d50[2] * finv(fz)}
+}
+
+func clamp01(x float64) float64 {
+	if !(x > 0) { // Also catches NaN.
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
@@ -0,0 +1,408 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package icc
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/math/f64"
+)
+
+// xyzD50ToLinearSRGB converts from the profile connection space, CIE XYZ
+// relative to the D50 illuminant, to linear sRGB. It is the inverse of the
+// sRGB primaries' matrix, Bradford-adapted from D65 to D50.
+var xyzD50ToLinearSRGB = [9]float64{
+	+3.1338561, -1.6168667, -0.4906146,
+	-0.9787684, +1.9161415, +0.0334540,
+	+0.0719453, -0.2289914, +1.4052427,
+}
+
+// encodeTableSize is the number of entries in an encodeTable.
+const encodeTableSize = 4096
+
+// encodeTable maps linear values in [0, 1], scaled to [0, encodeTableSize -
+// 1], to 8-bit encoded values.
+type encodeTable [encodeTableSize]uint8
+
+// srgbEncode is the encodeTable of the sRGB tone response.
+var srgbEncode = func() (t encodeTable) {
+	for i := range t {
+		x := float64(i) / (encodeTableSize - 1)
+		if x <= 0.0031308 {
+			x *= 12.92
+		} else {
+			x = 1.055*math.Pow(x, 1/2.4) - 0.055
+		}
+		t[i] = uint8(0.5 + 255*x)
+	}
+	return t
+}()
+
+// srgbDecode is the sRGB tone response curve.
+func srgbDecode(x float64) float64 {
+	if x <= 0.04045 {
+		return x / 12.92
+	}
+	return math.Pow((x+0.055)/1.055, 2.4)
+}
+
+// inverseEncodeTable returns the encodeTable of the inverse of c, which must
+// be non-decreasing.
+func inverseEncodeTable(c curve) *encodeTable {
+	// The linear value c((k+0.5)/255) is the boundary between the 8-bit
+	// values k and k+1.
+	var bounds [0xff]float64
+	for k := range bounds {
+		bounds[k] = c((float64(k) + 0.5) / 0xff)
+	}
+	t := &encodeTable{}
+	k := 0
+	for i := range t {
+		x := float64(i) / (encodeTableSize - 1)
+		for k < len(bounds) && bounds[k] < x {
+			k++
+		}
+		t[i] = uint8(k)
+	}
+	return t
+}
+
+// encode converts a linear value to an 8-bit encoded value, clamping out of
+// gamut values.
+func (t *encodeTable) encode(x float32) uint8 {
+	if !(x > 0) { // Also catches NaN.
+		return 0
+	}
+	if x >= 1 {
+		return 0xff
+	}
+	return t[int(x*(encodeTableSize-1)+0.5)]
+}
+
+// SRGB returns a profile of the sRGB color space, for use as either end of a
+// Transform.
+func SRGB() *Profile {
+	return &Profile{
+		Version:    0x04300000,
+		Class:      "mntr",
+		ColorSpace: "RGB ",
+		PCS:        "XYZ ",
+		srgb:       true,
+	}
+}
+
+// source describes how a profile's device colors map to CIE XYZ.
+type source struct {
+	nIn int
+	// curves linearize the device channels, and toXYZ then maps them to
+	// CIE XYZ, for a matrix/TRC or TRC gray profile.
+	curves [3]curve
+	toXYZ  [9]float64
+	// lut, if non-nil, replaces curves and toXYZ.
+	lut *lut
+}
+
+// source returns how p's device colors map to CIE XYZ. It prefers the
+// colorimetric A2B1 tag, then the A2B0 tag, to the matrix/TRC tags, as the
+// specification does.
+func (p *Profile) source() (*source, error) {
+	s := &source{}
+	if p.srgb {
+		s.nIn = 3
+		s.curves = [3]curve{srgbDecode, srgbDecode, srgbDecode}
+		inv, _ := f64.Mat3(xyzD50ToLinearSRGB).Invert()
+		s.toXYZ = inv
+		return s, nil
+	}
+	switch p.ColorSpace {
+	case "RGB ":
+		s.nIn = 3
+	case "GRAY":
+		s.nIn = 1
+	default:
+		return nil, ErrUnsupported
+	}
+	if p.PCS != "XYZ " && p.PCS != "Lab " {
+		return nil, ErrUnsupported
+	}
+	for _, sig := range [2]string{"A2B1", "A2B0"} {
+		if p.tags[sig] != nil {
+			l, err := p.lut(sig, s.nIn)
+			if err != nil {
+				return nil, err
+			}
+			s.lut = l
+			return s, nil
+		}
+	}
+	if p.PCS != "XYZ " {
+		return nil, ErrUnsupported
+	}
+
+	if s.nIn == 1 {
+		// Gray values are luminance along the D50 white point's axis.
+		c, err := p.trc("kTRC")
+		if err != nil {
+			return nil, err
+		}
+		s.curves[0] = c
+		s.toXYZ = [9]float64{d50[0], 0, 0, d50[1], 0, 0, d50[2], 0, 0}
+		return s, nil
+	}
+	// The rXYZ, gXYZ and bXYZ tags are the columns of the matrix from linear
+	// device RGB to XYZ.
+	for i, sig := range [3]string{"rXYZ", "gXYZ", "bXYZ"} {
+		x, y, z, err := p.xyz(sig)
+		if err != nil {
+			return nil, err
+		}
+		s.toXYZ[0+i], s.toXYZ[3+i], s.toXYZ[6+i] = x, y, z
+	}
+	for i, sig := range [3]string{"rTRC", "gTRC", "bTRC"} {
+		c, err := p.trc(sig)
+		if err != nil {
+			return nil, err
+		}
+		s.curves[i] = c
+	}
+	return s, nil
+}
+
+// destination describes how CIE XYZ colors map to a profile's device colors.
+type destination struct {
+	// fromXYZ maps CIE XYZ to linear device values, and enc then encodes
+	// them.
+	fromXYZ [9]float64
+	enc     [3]*encodeTable
+}
+
+// destination returns how CIE XYZ colors map to p's device colors. Only
+// matrix/TRC RGB profiles and TRC gray profiles, whose curves can be
+// inverted, are supported.
+func (p *Profile) destination() (*destination, error) {
+	d := &destination{}
+	if p.srgb {
+		d.fromXYZ = xyzD50ToLinearSRGB
+		d.enc = [3]*encodeTable{&srgbEncode, &srgbEncode, &srgbEncode}
+		return d, nil
+	}
+	if p.PCS != "XYZ " {
+		return nil, ErrUnsupported
+	}
+	switch p.ColorSpace {
+	case "RGB ":
+		var toXYZ [9]float64
+		for i, sig := range [3]string{"rXYZ", "gXYZ", "bXYZ"} {
+			x, y, z, err := p.xyz(sig)
+			if err != nil {
+				return nil, err
+			}
+			toXYZ[0+i], toXYZ[3+i], toXYZ[6+i] = x, y, z
+		}
+		inv, ok := f64.Mat3(toXYZ).Invert()
+		if !ok {
+			return nil, errInvalidProfile
+		}
+		d.fromXYZ = inv
+		for i, sig := range [3]string{"rTRC", "gTRC", "bTRC"} {
+			c, err := p.trc(sig)
+			if err != nil {
+				return nil, err
+			}
+			d.enc[i] = inverseEncodeTable(c)
+		}
+
+	case "GRAY":
+		// The gray value is the luminance, Y, in all three channels.
+		d.fromXYZ = [9]float64{0, 1, 0, 0, 1, 0, 0, 1, 0}
+		c, err := p.trc("kTRC")
+		if err != nil {
+			return nil, err
+		}
+		e := inverseEncodeTable(c)
+		d.enc = [3]*encodeTable{e, e, e}
+
+	default:
+		return nil, ErrUnsupported
+	}
+	return d, nil
+}
+
+// gridSize is the number of grid points along each axis of the table that
+// a Transform from a LUT-based RGB profile interpolates between.
+const gridSize = 33
+
+// Transform converts 8-bit colors from one profile's color space to
+// another's.
+//
+// A Transform is safe to use concurrently.
+type Transform struct {
+	// lin holds each channel's linearized values, for each 8-bit input.
+	lin [3][256]float32
+	// m converts from linear src device values to linear dst device values.
+	m [9]float32
+	// grid, if non-nil, replaces lin and m for a LUT-based src profile. It
+	// holds the linear dst device values for each 8-bit input of a gray
+	// src, or for each point of a gridSize³ grid of an RGB src.
+	grid   []float32
+	grayIn bool
+	// enc encodes each channel's linear dst device values.
+	enc [3]*encodeTable
+}
+
+// NewTransform returns a Transform from src's color space to dst's, using
+// the colorimetric (not perceptual) rendering intent.
+//
+// The src profile may be a matrix/TRC or LUT-based RGB profile, or a TRC or
+// LUT-based gray profile. The dst profile may be a matrix/TRC RGB profile or
+// a TRC gray profile, whose output gray value is in all three channels of
+// the converted colors. Otherwise, NewTransform returns ErrUnsupported.
+func NewTransform(src, dst *Profile) (*Transform, error) {
+	s, err := src.source()
+	if err != nil {
+		return nil, err
+	}
+	d, err := dst.destination()
+	if err != nil {
+		return nil, err
+	}
+	t := &Transform{enc: d.enc}
+
+	if s.lut == nil {
+		m := mul3(&d.fromXYZ, &s.toXYZ)
+		for i, v := range m {
+			t.m[i] = float32(v)
+		}
+		for i, c := range s.curves[:s.nIn] {
+			for j := range t.lin[i] {
+				t.lin[i][j] = float32(c(float64(j) / 255))
+			}
+		}
+		return t, nil
+	}
+
+	// Evaluating a LUT's pipeline is too slow to do for every pixel, so
+	// tabulate it instead.
+	pcs := src.PCS
+	add := func(in []float64) {
+		xyz := s.lut.apply(in, pcs)
+		m := &d.fromXYZ
+		t.grid = append(t.grid,
+			float32(m[0]*xyz[0]+m[1]*xyz[1]+m[2]*xyz[2]),
+			float32(m[3]*xyz[0]+m[4]*xyz[1]+m[5]*xyz[2]),
+			float32(m[6]*xyz[0]+m[7]*xyz[1]+m[8]*xyz[2]),
+		)
+	}
+	if s.nIn == 1 {
+		t.grayIn = true
+		t.grid = make([]float32, 0, 3*256)
+		for i := 0; i < 256; i++ {
+			add([]float64{float64(i) / 255})
+		}
+		return t, nil
+	}
+	t.grid = make([]float32, 0, 3*gridSize*gridSize*gridSize)
+	for r := 0; r < gridSize; r++ {
+		for g := 0; g < gridSize; g++ {
+			for b := 0; b < gridSize; b++ {
+				add([]float64{
+					float64(r) / (gridSize - 1),
+					float64(g) / (gridSize - 1),
+					float64(b) / (gridSize - 1),
+				})
+			}
+		}
+	}
+	return t, nil
+}
+
+// TransformToSRGB returns a Transform from p's color space to sRGB. It is
+// equivalent to NewTransform(p, SRGB()).
+func (p *Profile) TransformToSRGB() (*Transform, error) {
+	return NewTransform(p, SRGB())
+}
+
+// Convert converts a single color. For a gray src profile, the input gray
+// value is r, and g and b are ignored.
+func (t *Transform) Convert(r, g, b uint8) (uint8, uint8, uint8) {
+	var lr, lg, lb float32
+	switch {
+	case t.grid == nil:
+		ir, ig, ib := t.lin[0][r], t.lin[1][g], t.lin[2][b]
+		m := &t.m
+		lr = m[0]*ir + m[1]*ig + m[2]*ib
+		lg = m[3]*ir + m[4]*ig + m[5]*ib
+		lb = m[6]*ir + m[7]*ig + m[8]*ib
+	case t.grayIn:
+		i := 3 * int(r)
+		lr, lg, lb = t.grid[i+0], t.grid[i+1], t.grid[i+2]
+	default:
+		lr, lg, lb = t.interpolate(r, g, b)
+	}
+	return t.enc[0].encode(lr), t.enc[1].encode(lg), t.enc[2].encode(lb)
+}
+
+// interpolate returns the trilinear interpolation of t.grid at the 8-bit
+// input color.
+func (t *Transform) interpolate(r, g, b uint8) (float32, float32, float32) {
+	const n = gridSize - 1
+	var (
+		index int
+		frac  [3]float32
+	)
+	for k, v := range [3]uint8{r, g, b} {
+		// x is the grid coordinate, in units of 1/255th of a grid step.
+		x := int(v) * n
+		i := x / 255
+		frac[k] = float32(x-255*i) / 255
+		if i == n {
+			i, frac[k] = n-1, 1
+		}
+		index = index*gridSize + i
+	}
+	var out [3]float32
+	for corner := 0; corner < 8; corner++ {
+		w, i := float32(1), index
+		for k, stride := range [3]int{gridSize * gridSize, gridSize, 1} {
+			if corner&(4>>uint(k)) != 0 {
+				w *= frac[k]
+				i += stride
+			} else {
+				w *= 1 - frac[k]
+			}
+		}
+		if w == 0 {
+			continue
+		}
+		out[0] += w * t.grid[3*i+0]
+		out[1] += w * t.grid[3*i+1]
+		out[2] += w * t.grid[3*i+2]
+	}
+	return out[0], out[1], out[2]
+}
+
+// ConvertNRGBA converts the colors of m's pixels within m.Rect, in place.
+// Alpha values are unchanged.
+func (t *Transform) ConvertNRGBA(m *image.NRGBA) {
+	w := 4 * m.Rect.Dx()
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		i := m.PixOffset(m.Rect.Min.X, y)
+		pix := m.Pix[i : i+w]
+		for j := 0; j < len(pix); j += 4 {
+			pix[j+0], pix[j+1], pix[j+2] = t.Convert(pix[j+0], pix[j+1], pix[j+2])
+		}
+	}
+}
+
+// mul3 returns the product of two 3x3 row major matrices.
+func mul3(a, b *[9]float64) (c [9]float64) {
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			c[3*i+j] = a[3*i+0]*b[0+j] + a[3*i+1]*b[3+j] + a[3*i+2]*b[6+j]
+		}
+	}
+	return c
+}
@@ -0,0 +1,300 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ico implements a decoder and encoder for Windows icon (ICO) and
+// cursor (CUR) files.
+//
+// An ICO or CUR file holds one or more images, usually of the same icon at
+// different sizes, each stored as a BMP image without its file header, or as
+// a PNG image. The BMP images are decoded and encoded by the
+// golang.org/x/image/bmp package.
+//
+// The format is described at
+// https://docs.microsoft.com/en-us/previous-versions/ms997538(v=msdn.10)
+package ico // import "golang.org/x/image/ico"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+
+	"golang.org/x/image/bmp"
+)
+
+// A FormatError reports that the input is not a valid ICO or CUR file.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "ico: invalid format: " + string(e)
+}
+
+const (
+	typeICO = 1
+	typeCUR = 2
+
+	dirLen   = 6
+	entryLen = 16
+	// maxSize is the largest width and height of an image.
+	maxSize = 256
+)
+
+// ICO is the images of an ICO or CUR file.
+type ICO struct {
+	Image []image.Image
+	// Hotspot holds the position of each image's hotspot, the pixel that
+	// points, for CUR files. It is nil for ICO files.
+	Hotspot []image.Point
+}
+
+// entry is a directory entry, describing one image.
+type entry struct {
+	// width and height are those declared by the directory, which may
+	// differ from those of the image data.
+	width, height int
+	bitCount      int
+	hotspot       image.Point
+	data          []byte
+}
+
+// readEntries reads the directory of an ICO or CUR file, whose contents are
+// data.
+func readEntries(data []byte) (entries []entry, cursor bool, err error) {
+	if len(data) < dirLen {
+		return nil, false, FormatError("short header")
+	}
+	typ, n := binary.LittleEndian.Uint16(data[2:]), int(binary.LittleEndian.Uint16(data[4:]))
+	if binary.LittleEndian.Uint16(data[0:]) != 0 || (typ != typeICO && typ != typeCUR) {
+		return nil, false, FormatError("bad header")
+	}
+	if n == 0 {
+		return nil, false, FormatError("no images")
+	}
+	if len(data) < dirLen+n*entryLen {
+		return nil, false, FormatError("short directory")
+	}
+	cursor = typ == typeCUR
+	entries = make([]entry, n)
+	for i := range entries {
+		b := data[dirLen+i*entryLen:]
+		e := &entries[i]
+		e.width, e.height = int(b[0]), int(b[1])
+		if e.width == 0 {
+			e.width = maxSize
+		}
+		if e.height == 0 {
+			e.height = maxSize
+		}
+		planes, bitCount := binary.LittleEndian.Uint16(b[4:]), binary.LittleEndian.Uint16(b[6:])
+		if cursor {
+			e.hotspot = image.Point{int(planes), int(bitCount)}
+		} else {
+			e.bitCount = int(bitCount)
+		}
+		size, offset := binary.LittleEndian.Uint32(b[8:]), binary.LittleEndian.Uint32(b[12:])
+		if uint64(offset)+uint64(size) > uint64(len(data)) {
+			return nil, false, FormatError("image data out of bounds")
+		}
+		e.data = data[offset : offset+size]
+	}
+	return entries, cursor, nil
+}
+
+// largest returns the index of the largest entry, preferring the deepest of
+// those of the same size.
+func largest(entries []entry) int {
+	best := 0
+	for i, e := range entries {
+		b := &entries[best]
+		if a, ba := e.width*e.height, b.width*b.height; a > ba || (a == ba && e.bitCount > b.bitCount) {
+			best = i
+		}
+	}
+	return best
+}
+
+func isPNG(data []byte) bool {
+	return len(data) >= 8 && string(data[:8]) == "\x89PNG\r\n\x1a\n"
+}
+
+// decodeEntry decodes the image of e.
+func decodeEntry(e *entry) (image.Image, error) {
+	if isPNG(e.data) {
+		return png.Decode(bytes.NewReader(e.data))
+	}
+	return decodeDIB(e.data)
+}
+
+// dibLayout returns the DIB's width and height, excluding its AND mask, and
+// the offsets of its XOR (color) and AND (transparency) bitmaps. andOffset is
+// zero if the DIB has no AND mask.
+func dibLayout(dib []byte) (width, height int, xorOffset, andOffset int, err error) {
+	const (
+		infoHeaderLen = 40
+		biBitFields   = 3
+		biAlpha       = 6
+	)
+	if len(dib) < infoHeaderLen {
+		return 0, 0, 0, 0, FormatError("short BMP header")
+	}
+	infoLen := int(binary.LittleEndian.Uint32(dib[0:]))
+	width = int(int32(binary.LittleEndian.Uint32(dib[4:])))
+	height = int(int32(binary.LittleEndian.Uint32(dib[8:])))
+	bpp := int(binary.LittleEndian.Uint16(dib[14:]))
+	compression := binary.LittleEndian.Uint32(dib[16:])
+	colors := int(binary.LittleEndian.Uint32(dib[32:]))
+	// The height counts the rows of both the XOR and AND bitmaps.
+	if infoLen < infoHeaderLen || width <= 0 || width > maxSize || height <= 0 || height > 2*maxSize || height%2 != 0 {
+		return 0, 0, 0, 0, FormatError("bad BMP header")
+	}
+	height /= 2
+	xorOffset = infoLen
+	if bpp <= 8 {
+		if colors == 0 {
+			colors = 1 << uint(bpp)
+		}
+		xorOffset += 4 * colors
+	}
+	if infoLen == infoHeaderLen && compression == biBitFields {
+		xorOffset += 12
+	} else if infoLen == infoHeaderLen && compression == biAlpha {
+		xorOffset += 16
+	}
+	if compression == 0 || compression == biBitFields || compression == biAlpha {
+		xorLen := (width*bpp + 31) / 32 * 4 * height
+		andOffset = xorOffset + xorLen
+		if andOffset+(width+31)/32*4*height > len(dib) {
+			andOffset = 0
+		}
+	}
+	return width, height, xorOffset, andOffset, nil
+}
+
+// decodeDIB decodes a BMP image without its file header, whose height is
+// doubled to count the rows of the AND mask that follows its pixels. It
+// returns an *image.NRGBA.
+func decodeDIB(dib []byte) (image.Image, error) {
+	width, height, xorOffset, andOffset, err := dibLayout(dib)
+	if err != nil {
+		return nil, err
+	}
+	// Decode the DIB with the bmp package, after prepending a BMP file header
+	// and halving its height.
+	const fileHeaderLen = 14
+	b := make([]byte, fileHeaderLen+len(dib))
+	copy(b, "BM")
+	binary.LittleEndian.PutUint32(b[2:], uint32(len(b)))
+	binary.LittleEndian.PutUint32(b[10:], uint32(fileHeaderLen+xorOffset))
+	copy(b[fileHeaderLen:], dib)
+	binary.LittleEndian.PutUint32(b[fileHeaderLen+8:], uint32(height))
+	m, err := bmp.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	// 32 bit images have an alpha channel, unless all of its values are
+	// zero. Other images are transparent where their AND mask is set.
+	dst, ok := m.(*image.NRGBA)
+	if ok {
+		for i := 3; i < len(dst.Pix); i += 4 {
+			if dst.Pix[i] != 0 {
+				return dst, nil
+			}
+		}
+		for i := 3; i < len(dst.Pix); i += 4 {
+			dst.Pix[i] = 0xff
+		}
+	} else {
+		dst = image.NewNRGBA(m.Bounds())
+		draw.Draw(dst, dst.Rect, m, image.Point{}, draw.Src)
+	}
+	if andOffset == 0 {
+		return dst, nil
+	}
+	stride := (width + 31) / 32 * 4
+	for y := 0; y < height; y++ {
+		// The AND mask's rows are bottom-up.
+		row := dib[andOffset+(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			if row[x/8]&(0x80>>uint(x%8)) != 0 {
+				i := dst.PixOffset(x, y)
+				copy(dst.Pix[i:i+4], []byte{0, 0, 0, 0})
+			}
+		}
+	}
+	return dst, nil
+}
+
+// readAll reads an ICO or CUR file from r.
+func readAll(r io.Reader) ([]entry, bool, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, false, err
+	}
+	return readEntries(data)
+}
+
+// Decode reads an ICO or CUR file from r and returns its largest image, or
+// the deepest of its largest images. Images stored as BMP are returned as an
+// *image.NRGBA.
+func Decode(r io.Reader) (image.Image, error) {
+	entries, _, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEntry(&entries[largest(entries)])
+}
+
+// DecodeConfig returns the color model and dimensions of the image that
+// Decode returns, without decoding it.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	entries, _, err := readAll(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	e := &entries[largest(entries)]
+	if isPNG(e.data) {
+		return png.DecodeConfig(bytes.NewReader(e.data))
+	}
+	width, height, _, _, err := dibLayout(e.data)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: width, Height: height}, nil
+}
+
+// DecodeAll reads an ICO or CUR file from r and returns all of its images,
+// in the order that the file lists them.
+func DecodeAll(r io.Reader) (*ICO, error) {
+	entries, cursor, err := readAll(r)
+	if err != nil {
+		return nil, err
+	}
+	x := &ICO{
+		Image: make([]image.Image, len(entries)),
+	}
+	if cursor {
+		x.Hotspot = make([]image.Point, len(entries))
+	}
+	for i := range entries {
+		m, err := decodeEntry(&entries[i])
+		if err != nil {
+			return nil, err
+		}
+		x.Image[i] = m
+		if cursor {
+			x.Hotspot[i] = entries[i].hotspot
+		}
+	}
+	return x, nil
+}
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", Decode, DecodeConfig)
+	image.RegisterFormat("cur", "\x00\x00\x02\x00", Decode, DecodeConfig)
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ico
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// icoFile returns an ICO or CUR file holding the given image data.
+func icoFile(typ uint16, hotspot image.Point, payloads ...[]byte) []byte {
+	b := make([]byte, dirLen+entryLen*len(payloads))
+	binary.LittleEndian.PutUint16(b[2:], typ)
+	binary.LittleEndian.PutUint16(b[4:], uint16(len(payloads)))
+	for i, p := range payloads {
+		e := b[dirLen+i*entryLen:]
+		w, h := binary.LittleEndian.Uint32(p[4:]), binary.LittleEndian.Uint32(p[8:])/2
+		e[0], e[1] = uint8(w), uint8(h)
+		if typ == typeCUR {
+			binary.LittleEndian.PutUint16(e[4:], uint16(hotspot.X))
+			binary.LittleEndian.PutUint16(e[6:], uint16(hotspot.Y))
+		} else {
+			binary.LittleEndian.PutUint16(e[4:], 1)
+			binary.LittleEndian.PutUint16(e[6:], binary.LittleEndian.Uint16(p[14:]))
+		}
+		binary.LittleEndian.PutUint32(e[8:], uint32(len(p)))
+		binary.LittleEndian.PutUint32(e[12:], uint32(len(b)))
+		b = append(b, p...)
+	}
+	return b
+}
+
+// monoDIB returns a 1 bit-per-pixel 3×2 DIB, of black and white pixels, with
+// an AND mask that makes its top-left pixel transparent.
+func monoDIB() []byte {
+	b := make([]byte, 40)
+	binary.LittleEndian.PutUint32(b[0:], 40)
+	binary.LittleEndian.PutUint32(b[4:], 3)
+	binary.LittleEndian.PutUint32(b[8:], 2*2)
+	binary.LittleEndian.PutUint16(b[12:], 1)
+	binary.LittleEndian.PutUint16(b[14:], 1)
+	// The palette: black and white.
+	b = append(b, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0x00)
+	// The XOR bitmap, bottom-up: white, black, white then black, white,
+	// black.
+	b = append(b, 0xa0, 0, 0, 0, 0x40, 0, 0, 0)
+	// The AND bitmap, bottom-up.
+	b = append(b, 0x00, 0, 0, 0, 0x80, 0, 0, 0)
+	return b
+}
+
+func TestDecodeMono(t *testing.T) {
+	data := icoFile(typeICO, image.Point{}, monoDIB())
+	m, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transparent := color.NRGBA{}
+	black := color.NRGBA{0x00, 0x00, 0x00, 0xff}
+	white := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	want := []color.NRGBA{
+		transparent, white, black,
+		white, black, white,
+	}
+	if b := m.Bounds(); b != image.Rect(0, 0, 3, 2) {
+		t.Fatalf("got bounds %v, want 3×2", b)
+	}
+	for i, w := range want {
+		if got := m.At(i%3, i/3); got != w {
+			t.Errorf("pixel (%d, %d): got %v, want %v", i%3, i/3, got, w)
+		}
+	}
+
+	c, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Width != 3 || c.Height != 2 || c.ColorModel != color.NRGBAModel {
+		t.Errorf("DecodeConfig: got %+v", c)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	data := icoFile(typeCUR, image.Pt(1, 2), monoDIB(), monoDIB())
+	x, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(x.Image) != 2 || len(x.Hotspot) != 2 {
+		t.Fatalf("got %d images and %d hotspots, want 2 and 2", len(x.Image), len(x.Hotspot))
+	}
+	for i, h := range x.Hotspot {
+		if h != image.Pt(1, 2) {
+			t.Errorf("hotspot #%d: got %v, want (1,2)", i, h)
+		}
+	}
+
+	// Registered formats are detected by their magic number.
+	_, name, err := image.Decode(bytes.NewReader(data))
+	if err != nil || name != "cur" {
+		t.Errorf("image.Decode: got %q, %v, want \"cur\", nil", name, err)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	valid := icoFile(typeICO, image.Point{}, monoDIB())
+	badOffset := append([]byte(nil), valid...)
+	binary.LittleEndian.PutUint32(badOffset[dirLen+12:], uint32(len(valid)))
+	noImages := append([]byte(nil), valid[:dirLen]...)
+	noImages[4] = 0
+	badType := append([]byte(nil), valid...)
+	badType[2] = 3
+
+	testCases := map[string][]byte{
+		"empty":      nil,
+		"truncated":  valid[:dirLen+entryLen-1],
+		"bad offset": badOffset,
+		"no images":  noImages,
+		"bad type":   badType,
+	}
+	for name, data := range testCases {
+		if _, err := Decode(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: got nil error, want non-nil", name)
+		}
+	}
+}
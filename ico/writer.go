@@ -0,0 +1,140 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ico
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/bmp"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// PNG is whether to store the images as PNG instead of BMP images. PNG
+	// images are smaller, but are not supported before Windows Vista, and
+	// some programs only support them for 256×256 images.
+	PNG bool
+}
+
+// Encode writes the image m to w as an ICO file. A nil opts is equivalent to
+// a zero Options.
+func Encode(w io.Writer, m image.Image, opts *Options) error {
+	return EncodeAll(w, &ICO{Image: []image.Image{m}}, opts)
+}
+
+// EncodeAll writes the images of x to w as an ICO file, or as a CUR file if
+// x.Hotspot is non-nil. A nil opts is equivalent to a zero Options.
+//
+// The images must be at most 256×256 pixels.
+func EncodeAll(w io.Writer, x *ICO, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if len(x.Image) == 0 {
+		return errors.New("ico: no images")
+	}
+	if len(x.Image) > 0xffff {
+		return errors.New("ico: too many images")
+	}
+	typ := uint16(typeICO)
+	if x.Hotspot != nil {
+		if len(x.Hotspot) != len(x.Image) {
+			return errors.New("ico: mismatched image and hotspot lengths")
+		}
+		typ = typeCUR
+	}
+
+	payloads := make([][]byte, len(x.Image))
+	for i, m := range x.Image {
+		var err error
+		if payloads[i], err = encodeImage(m, o.PNG); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, dirLen+entryLen*len(x.Image))
+	binary.LittleEndian.PutUint16(buf[2:], typ)
+	binary.LittleEndian.PutUint16(buf[4:], uint16(len(x.Image)))
+	offset := len(buf)
+	for i, m := range x.Image {
+		b := buf[dirLen+i*entryLen:]
+		size := m.Bounds().Size()
+		// A width or height of 256 is stored as 0.
+		b[0], b[1] = uint8(size.X), uint8(size.Y)
+		if x.Hotspot != nil {
+			binary.LittleEndian.PutUint16(b[4:], uint16(x.Hotspot[i].X))
+			binary.LittleEndian.PutUint16(b[6:], uint16(x.Hotspot[i].Y))
+		} else {
+			bitCount := uint16(32)
+			if !isPNG(payloads[i]) {
+				bitCount = binary.LittleEndian.Uint16(payloads[i][14:])
+			}
+			binary.LittleEndian.PutUint16(b[4:], 1)
+			binary.LittleEndian.PutUint16(b[6:], bitCount)
+		}
+		binary.LittleEndian.PutUint32(b[8:], uint32(len(payloads[i])))
+		binary.LittleEndian.PutUint32(b[12:], uint32(offset))
+		offset += len(payloads[i])
+	}
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	for _, p := range payloads {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeImage returns the image data of m, as a PNG image or as a BMP image
+// without its file header but with an AND mask.
+func encodeImage(m image.Image, asPNG bool) ([]byte, error) {
+	b := m.Bounds()
+	if b.Empty() {
+		return nil, errors.New("ico: empty image")
+	}
+	if b.Dx() > maxSize || b.Dy() > maxSize {
+		return nil, errors.New("ico: image is larger than 256×256")
+	}
+	buf := &bytes.Buffer{}
+	if asPNG {
+		if err := png.Encode(buf, m); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	// The bmp package writes a 32 bit image with alpha if m has any
+	// transparency, and a 24 bit image otherwise.
+	nrgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(nrgba, nrgba.Rect, m, b.Min, draw.Src)
+	if err := bmp.Encode(buf, nrgba); err != nil {
+		return nil, err
+	}
+	const fileHeaderLen = 14
+	dib := buf.Bytes()[fileHeaderLen:]
+	// The height counts the rows of both the XOR and the AND bitmaps. The
+	// AND bitmap is set where the image is fully transparent.
+	binary.LittleEndian.PutUint32(dib[8:], uint32(2*b.Dy()))
+	stride := (b.Dx() + 31) / 32 * 4
+	and := make([]byte, stride*b.Dy())
+	for y := 0; y < b.Dy(); y++ {
+		row := and[(b.Dy()-1-y)*stride:]
+		for x := 0; x < b.Dx(); x++ {
+			if nrgba.Pix[nrgba.PixOffset(x, y)+3] == 0 {
+				row[x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return append(dib, and...), nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ico
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testImage returns a size×size image with a transparent border, if border
+// is true.
+func testImage(size int, border bool) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := color.NRGBA{uint8(x), uint8(y), uint8(x + y), 0xff}
+			if border && (x == 0 || y == 0 || x == size-1 || y == size-1) {
+				c = color.NRGBA{}
+			} else if border && x == 1 {
+				c.A = 0x80
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestEncodeAll(t *testing.T) {
+	images := []image.Image{
+		testImage(16, true),
+		testImage(32, false),
+		testImage(256, true),
+	}
+	for _, o := range []Options{{PNG: false}, {PNG: true}} {
+		for _, hotspot := range [][]image.Point{nil, {{1, 2}, {3, 4}, {255, 255}}} {
+			var buf bytes.Buffer
+			if err := EncodeAll(&buf, &ICO{Image: images, Hotspot: hotspot}, &o); err != nil {
+				t.Fatalf("%+v: %v", o, err)
+			}
+			x, err := DecodeAll(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%+v: %v", o, err)
+			}
+			if len(x.Image) != len(images) {
+				t.Fatalf("%+v: got %d images, want %d", o, len(x.Image), len(images))
+			}
+			for i, m := range images {
+				if err := compare(m, x.Image[i]); err != nil {
+					t.Errorf("%+v: image #%d: %v", o, i, err)
+				}
+			}
+			if (hotspot == nil) != (x.Hotspot == nil) {
+				t.Fatalf("%+v: got hotspots %v, want %v", o, x.Hotspot, hotspot)
+			}
+			for i := range hotspot {
+				if x.Hotspot[i] != hotspot[i] {
+					t.Errorf("%+v: hotspot #%d: got %v, want %v", o, i, x.Hotspot[i], hotspot[i])
+				}
+			}
+
+			// Decode returns the largest image.
+			m, err := Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("%+v: %v", o, err)
+			}
+			if got := m.Bounds().Size(); got != image.Pt(256, 256) {
+				t.Errorf("%+v: Decode: got size %v, want 256×256", o, got)
+			}
+		}
+	}
+}
+
+// compare returns an error if the images differ, treating all fully
+// transparent colors as equal.
+func compare(want, got image.Image) error {
+	b := want.Bounds()
+	if got.Bounds() != b {
+		return fmt.Errorf("got bounds %v, want %v", got.Bounds(), b)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			w := color.NRGBAModel.Convert(want.At(x, y)).(color.NRGBA)
+			g := color.NRGBAModel.Convert(got.At(x, y)).(color.NRGBA)
+			if w.A == 0 && g.A == 0 {
+				continue
+			}
+			if w != g {
+				return fmt.Errorf("pixel (%d, %d): got %v, want %v", x, y, g, w)
+			}
+		}
+	}
+	return nil
+}
+
+func TestEncodeErrors(t *testing.T) {
+	testCases := map[string]*ICO{
+		"no images":   {},
+		"too large":   {Image: []image.Image{image.NewNRGBA(image.Rect(0, 0, 257, 16))}},
+		"empty image": {Image: []image.Image{image.NewNRGBA(image.Rect(0, 0, 0, 16))}},
+		"hotspots": {
+			Image:   []image.Image{testImage(16, false)},
+			Hotspot: []image.Point{{}, {}},
+		},
+	}
+	for name, x := range testCases {
+		if err := EncodeAll(&bytes.Buffer{}, x, nil); err == nil {
+			t.Errorf("%s: got nil error, want non-nil", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imageio
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"io"
+)
+
+var errBadGIF = errors.New("imageio: bad GIF encoding")
+
+// encodeGIF87a writes m to w as a GIF87a image, for decoders that reject
+// the extension blocks of GIF89a.
+//
+// It rewrites image/gif's GIF89a output: it drops the extension blocks,
+// including the graphic control extension that holds the transparent color
+// index, and clears the header fields that GIF87a reserves.
+func encodeGIF87a(w io.Writer, m image.Image, o *gif.Options) error {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, m, o); err != nil {
+		return err
+	}
+	out, err := toGIF87a(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// toGIF87a converts a GIF89a encoding to GIF87a.
+func toGIF87a(src []byte) ([]byte, error) {
+	// The header and logical screen descriptor.
+	if len(src) < 13 || string(src[:6]) != "GIF89a" {
+		return nil, errBadGIF
+	}
+	dst := make([]byte, 0, len(src))
+	dst = append(dst, "GIF87a"...)
+	dst = append(dst, src[6:13]...)
+	// Clear the sort flag and the pixel aspect ratio.
+	dst[10] &^= 0x08
+	dst[12] = 0
+	flags, src := src[10], src[13:]
+	if flags&0x80 != 0 {
+		n := 3 << (flags&0x07 + 1)
+		if len(src) < n {
+			return nil, errBadGIF
+		}
+		dst, src = append(dst, src[:n]...), src[n:]
+	}
+
+	for len(src) > 0 {
+		switch src[0] {
+		case 0x21: // Extension introducer.
+			if len(src) < 2 {
+				return nil, errBadGIF
+			}
+			rest, _, err := subBlocks(src[2:])
+			if err != nil {
+				return nil, err
+			}
+			src = rest
+
+		case 0x2c: // Image separator.
+			if len(src) < 10 {
+				return nil, errBadGIF
+			}
+			i := len(dst)
+			dst, src = append(dst, src[:10]...), src[10:]
+			// Clear the sort flag and the reserved bits.
+			dst[i+9] &^= 0x38
+			flags := dst[i+9]
+			if flags&0x80 != 0 {
+				n := 3 << (flags&0x07 + 1)
+				if len(src) < n {
+					return nil, errBadGIF
+				}
+				dst, src = append(dst, src[:n]...), src[n:]
+			}
+			// The LZW minimum code size, followed by the image data.
+			if len(src) < 1 {
+				return nil, errBadGIF
+			}
+			rest, n, err := subBlocks(src[1:])
+			if err != nil {
+				return nil, err
+			}
+			dst, src = append(dst, src[:1+n]...), rest
+
+		case 0x3b: // Trailer.
+			return append(dst, 0x3b), nil
+
+		default:
+			return nil, errBadGIF
+		}
+	}
+	return nil, errBadGIF
+}
+
+// subBlocks returns the data after the sub-blocks at the start of src,
+// including their zero-length terminator, and the length of those
+// sub-blocks.
+func subBlocks(src []byte) (rest []byte, n int, err error) {
+	for {
+		if n >= len(src) {
+			return nil, 0, errBadGIF
+		}
+		size := int(src[n])
+		n += 1 + size
+		if size == 0 {
+			return src[n:], n, nil
+		}
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package imageio loads and saves images in any of the formats supported by
+// the standard library and this repository, selecting the codec by sniffing
+// the data when loading and by the file extension when saving.
+//
+// Loading applies the EXIF orientation, if any, so that the returned image is
+// upright, and rejects images whose dimensions exceed a limit before
+// allocating memory for their pixels.
+package imageio // import "golang.org/x/image/imageio"
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/wbmp"
+	_ "golang.org/x/image/webp" // Register the WEBP decoder.
+)
+
+// DefaultMaxPixels is the maximum number of pixels, width times height, of
+// an image that Load and Decode accept when LoadOptions.MaxPixels is zero.
+const DefaultMaxPixels = 1 << 28
+
+var (
+	// ErrTooLarge means that an image's dimensions exceed the
+	// LoadOptions.MaxPixels limit.
+	ErrTooLarge = errors.New("imageio: image is too large")
+
+	errUnknownFormat = errors.New("imageio: unknown format")
+)
+
+// LoadOptions are optional parameters to Load and Decode.
+type LoadOptions struct {
+	// MaxPixels is the maximum number of pixels, width times height, of an
+	// image to decode. Zero means DefaultMaxPixels and a negative value means
+	// no limit.
+	MaxPixels int64
+	// IgnoreOrientation is whether to return the image as stored, instead of
+	// applying its EXIF orientation.
+	IgnoreOrientation bool
+}
+
+// Load decodes the image in the named file. It returns the image and the
+// format name, such as "png" or "webp", used during format registration.
+func Load(path string, opts *LoadOptions) (image.Image, string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return decode(data, opts)
+}
+
+// Decode decodes the image read from r. It returns the image and the format
+// name, such as "png" or "webp", used during format registration.
+//
+// The encoded image is read into memory before decoding, so that its header
+// and metadata can be examined first.
+func Decode(r io.Reader, opts *LoadOptions) (image.Image, string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return decode(data, opts)
+}
+
+func decode(data []byte, opts *LoadOptions) (image.Image, string, error) {
+	maxPixels, ignoreOrientation := int64(DefaultMaxPixels), false
+	if opts != nil {
+		if opts.MaxPixels != 0 {
+			maxPixels = opts.MaxPixels
+		}
+		ignoreOrientation = opts.IgnoreOrientation
+	}
+
+	c, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if maxPixels > 0 && int64(c.Width)*int64(c.Height) > maxPixels {
+		return nil, format, ErrTooLarge
+	}
+	m, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, err
+	}
+	if !ignoreOrientation {
+		m = orient(m, orientation(format, data))
+	}
+	return m, format, nil
+}
+
+// SaveOptions are optional parameters to Save and Encode. Each format's
+// options are only used when encoding in that format, and nil means that
+// format's default options.
+type SaveOptions struct {
+	GIF  *gif.Options
+	JPEG *jpeg.Options
+	PNG  *png.Encoder
+	TIFF *tiff.Options
+
+	// GIF87a is whether to write GIF images in the older GIF87a format,
+	// which some embedded decoders require, instead of GIF89a. GIF87a has
+	// no extension blocks, so transparency is lost.
+	GIF87a bool
+}
+
+// Formats that Save and Encode support.
+const (
+	BMP  = "bmp"
+	GIF  = "gif"
+	JPEG = "jpeg"
+	PNG  = "png"
+	TIFF = "tiff"
+	WBMP = "wbmp" // Not recognized by Load and Decode: WBMP has no signature.
+)
+
+// FormatFromExtension returns the format, such as PNG, for a file name's
+// extension, or "" if the extension is not recognized. The match is case
+// insensitive.
+func FormatFromExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".bmp":
+		return BMP
+	case ".gif":
+		return GIF
+	case ".jpg", ".jpeg":
+		return JPEG
+	case ".png":
+		return PNG
+	case ".tif", ".tiff":
+		return TIFF
+	case ".wbmp":
+		return WBMP
+	}
+	return ""
+}
+
+// Save encodes m to the named file, in the format given by the file name's
+// extension. The file is created or truncated.
+func Save(path string, m image.Image, opts *SaveOptions) (err error) {
+	format := FormatFromExtension(path)
+	if format == "" {
+		return fmt.Errorf("imageio: unknown file extension %q", filepath.Ext(path))
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return Encode(f, m, format, opts)
+}
+
+// Encode writes m to w in the given format, such as PNG.
+func Encode(w io.Writer, m image.Image, format string, opts *SaveOptions) error {
+	if opts == nil {
+		opts = &SaveOptions{}
+	}
+	switch format {
+	case BMP:
+		return bmp.Encode(w, m)
+	case GIF:
+		if opts.GIF87a {
+			return encodeGIF87a(w, m, opts.GIF)
+		}
+		return gif.Encode(w, m, opts.GIF)
+	case JPEG:
+		return jpeg.Encode(w, m, opts.JPEG)
+	case PNG:
+		if opts.PNG != nil {
+			return opts.PNG.Encode(w, m)
+		}
+		return png.Encode(w, m)
+	case TIFF:
+		return tiff.Encode(w, m, opts.TIFF)
+	case WBMP:
+		return wbmp.Encode(w, m)
+	}
+	return errUnknownFormat
+}
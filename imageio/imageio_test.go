@@ -0,0 +1,231 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imageio
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFormats(t *testing.T) {
+	testCases := []struct {
+		filename, format string
+	}{
+		{"video-001.bmp", "bmp"},
+		{"video-001.tiff", "tiff"},
+		{"blue-purple-pink.lossless.webp", "webp"},
+		{"go-turns-two-14x18.png", "png"},
+	}
+	for _, tc := range testCases {
+		m, format, err := Load("../testdata/"+tc.filename, nil)
+		if err != nil {
+			t.Errorf("%s: Load: %v", tc.filename, err)
+			continue
+		}
+		if format != tc.format {
+			t.Errorf("%s: format: got %q, want %q", tc.filename, format, tc.format)
+		}
+		if m.Bounds().Empty() {
+			t.Errorf("%s: empty bounds", tc.filename)
+		}
+	}
+}
+
+func TestMaxPixels(t *testing.T) {
+	const filename = "../testdata/video-001.bmp"
+	m, _, err := Load(filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := int64(m.Bounds().Dx() * m.Bounds().Dy())
+
+	if _, _, err := Load(filename, &LoadOptions{MaxPixels: n}); err != nil {
+		t.Errorf("MaxPixels = n: got %v, want nil", err)
+	}
+	if _, _, err := Load(filename, &LoadOptions{MaxPixels: n - 1}); err != ErrTooLarge {
+		t.Errorf("MaxPixels = n-1: got %v, want %v", err, ErrTooLarge)
+	}
+	if _, _, err := Load(filename, &LoadOptions{MaxPixels: -1}); err != nil {
+		t.Errorf("MaxPixels = -1: got %v, want nil", err)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "imageio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(40 * i)
+	}
+	for i := 3; i < len(src.Pix); i += 4 {
+		src.Pix[i] = 0xff
+	}
+	for _, ext := range []string{".bmp", ".png", ".TIF"} {
+		path := filepath.Join(dir, "img"+ext)
+		if err := Save(path, src, nil); err != nil {
+			t.Errorf("%s: Save: %v", ext, err)
+			continue
+		}
+		m, _, err := Load(path, nil)
+		if err != nil {
+			t.Errorf("%s: Load: %v", ext, err)
+			continue
+		}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 3; x++ {
+				r0, g0, b0, a0 := src.At(x, y).RGBA()
+				r1, g1, b1, a1 := m.At(x, y).RGBA()
+				if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+					t.Errorf("%s: (%d, %d): got %v, want %v", ext, x, y, m.At(x, y), src.At(x, y))
+				}
+			}
+		}
+	}
+	if err := Save(filepath.Join(dir, "img.xyz"), src, nil); err == nil {
+		t.Error("unknown extension: got nil error")
+	}
+}
+
+// withOrientation returns a JPEG encoding of m with an APP1 EXIF segment
+// holding the given orientation.
+func withOrientation(t *testing.T, m image.Image, orientation uint8) []byte {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, m, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	exif := []byte(exifPrefix +
+		"MM\x00*\x00\x00\x00\x08" + // TIFF header.
+		"\x00\x01" + // One IFD entry.
+		"\x01\x12\x00\x03\x00\x00\x00\x01\x00" + string(orientation) + "\x00\x00" +
+		"\x00\x00\x00\x00") // No next IFD.
+	app1 := []byte{0xff, 0xe1, 0, uint8(2 + len(exif))}
+	data := buf.Bytes()
+	return append(append(append([]byte{0xff, 0xd8}, app1...), exif...), data[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	// A 32x16 image whose left half is black and right half is white.
+	src := image.NewGray(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 16; x < 32; x++ {
+			src.SetGray(x, y, color.Gray{0xff})
+		}
+	}
+	testCases := []struct {
+		orientation uint8
+		size        image.Point
+		// white is a point that is in the upright image's white part.
+		white image.Point
+	}{
+		{1, image.Point{32, 16}, image.Point{24, 8}},
+		{2, image.Point{32, 16}, image.Point{8, 8}},
+		{3, image.Point{32, 16}, image.Point{8, 8}},
+		{6, image.Point{16, 32}, image.Point{8, 24}},
+		{8, image.Point{16, 32}, image.Point{8, 8}},
+	}
+	for _, tc := range testCases {
+		data := withOrientation(t, src, tc.orientation)
+		if got := orientation(JPEG, data); got != int(tc.orientation) {
+			t.Errorf("orientation %d: parsed %d", tc.orientation, got)
+			continue
+		}
+		m, _, err := Decode(bytes.NewReader(data), nil)
+		if err != nil {
+			t.Errorf("orientation %d: Decode: %v", tc.orientation, err)
+			continue
+		}
+		if got := m.Bounds().Size(); got != tc.size {
+			t.Errorf("orientation %d: size: got %v, want %v", tc.orientation, got, tc.size)
+		}
+		if y := color.GrayModel.Convert(m.At(tc.white.X, tc.white.Y)).(color.Gray).Y; y < 0x80 {
+			t.Errorf("orientation %d: at %v: got %#02x, want white", tc.orientation, tc.white, y)
+		}
+
+		m, _, err = Decode(bytes.NewReader(data), &LoadOptions{IgnoreOrientation: true})
+		if err != nil {
+			t.Errorf("orientation %d: Decode: %v", tc.orientation, err)
+			continue
+		}
+		if got, want := m.Bounds().Size(), src.Bounds().Size(); got != want {
+			t.Errorf("orientation %d: IgnoreOrientation: size: got %v, want %v", tc.orientation, got, want)
+		}
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3x2 image: 0 1 2 / 3 4 5.
+	src := image.NewGray(image.Rect(0, 0, 3, 2))
+	copy(src.Pix, []uint8{0, 1, 2, 3, 4, 5})
+	want := map[int][]uint8{
+		2: {2, 1, 0, 5, 4, 3},
+		3: {5, 4, 3, 2, 1, 0},
+		4: {3, 4, 5, 0, 1, 2},
+		5: {0, 3, 1, 4, 2, 5},
+		6: {3, 0, 4, 1, 5, 2},
+		7: {5, 2, 4, 1, 3, 0},
+		8: {2, 5, 1, 4, 0, 3},
+	}
+	for o := 2; o <= 8; o++ {
+		m, ok := orient(src, o).(*image.Gray)
+		if !ok {
+			t.Errorf("orientation %d: not an *image.Gray", o)
+			continue
+		}
+		if !bytes.Equal(m.Pix, want[o]) {
+			t.Errorf("orientation %d: got %v, want %v", o, m.Pix, want[o])
+		}
+	}
+	if orient(src, 1) != image.Image(src) {
+		t.Error("orientation 1: got a copy, want the image itself")
+	}
+}
+
+func TestGIF87a(t *testing.T) {
+	p := color.Palette{color.Transparent, color.Black, color.White}
+	src := image.NewPaletted(image.Rect(0, 0, 5, 3), p)
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i % 3)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, src, GIF, &SaveOptions{GIF87a: true}); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("GIF87a")) {
+		t.Fatalf("header: got %q, want %q", data[:6], "GIF87a")
+	}
+	// The image descriptor follows the 13 byte header and logical screen
+	// descriptor and the 4 entry global color table, with no extension
+	// blocks in between.
+	if i := 13 + 3*4; data[i] != 0x2c {
+		t.Fatalf("byte %d: got %#02x, want an image separator", i, data[i])
+	}
+	m, err := gif.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, ok := m.(*image.Paletted)
+	if !ok {
+		t.Fatalf("got %T, want *image.Paletted", m)
+	}
+	if !bytes.Equal(pm.Pix, src.Pix) {
+		t.Errorf("pixels: got %v, want %v", pm.Pix, src.Pix)
+	}
+
+	if _, err := toGIF87a(data[:len(data)-1]); err != errBadGIF {
+		t.Errorf("truncated: got %v, want %v", err, errBadGIF)
+	}
+}
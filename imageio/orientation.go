@@ -0,0 +1,156 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imageio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/riff"
+)
+
+// orientation returns the EXIF orientation, from 1 to 8, of the encoded
+// image in data. It returns 1, meaning upright, if there is none.
+func orientation(format string, data []byte) int {
+	var exif []byte
+	switch format {
+	case JPEG:
+		exif = jpegEXIF(data)
+	case TIFF:
+		// A TIFF file's first IFD holds the orientation tag itself.
+		exif = data
+	case "webp":
+		exif = webpEXIF(data)
+	}
+	return exifOrientation(exif)
+}
+
+// exifPrefix prefixes the EXIF data in a JPEG APP1 segment, and sometimes in
+// a WEBP EXIF chunk.
+const exifPrefix = "Exif\x00\x00"
+
+// jpegEXIF returns the TIFF structured EXIF data of a JPEG image, or nil.
+func jpegEXIF(data []byte) []byte {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+	for b := data[2:]; len(b) >= 4 && b[0] == 0xff; {
+		marker := b[1]
+		if marker == 0xd9 || marker == 0xda { // EOI or SOS.
+			break
+		}
+		n := int(binary.BigEndian.Uint16(b[2:4]))
+		if n < 2 || len(b) < 2+n {
+			break
+		}
+		if seg := b[4 : 2+n]; marker == 0xe1 && bytes.HasPrefix(seg, []byte(exifPrefix)) {
+			return seg[len(exifPrefix):]
+		}
+		b = b[2+n:]
+	}
+	return nil
+}
+
+// webpEXIF returns the TIFF structured EXIF data of a WEBP image, or nil.
+func webpEXIF(data []byte) []byte {
+	_, r, err := riff.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	for {
+		id, _, chunkData, err := r.Next()
+		if err != nil {
+			return nil
+		}
+		if id == (riff.FourCC{'E', 'X', 'I', 'F'}) {
+			exif, err := ioutil.ReadAll(chunkData)
+			if err != nil {
+				return nil
+			}
+			return bytes.TrimPrefix(exif, []byte(exifPrefix))
+		}
+	}
+}
+
+// exifOrientation returns the Orientation tag of the first IFD of TIFF
+// structured data, or 1 if there is none.
+func exifOrientation(b []byte) int {
+	const tOrientation = 0x0112
+	if len(b) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(b[0:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := uint64(order.Uint32(b[4:8]))
+	if offset+2 > uint64(len(b)) {
+		return 1
+	}
+	n := uint64(order.Uint16(b[offset:]))
+	entries := b[offset+2:]
+	if n*12 > uint64(len(entries)) {
+		return 1
+	}
+	for i := uint64(0); i < n; i++ {
+		e := entries[12*i:]
+		// The value must be a single Short (data type 3).
+		if order.Uint16(e[0:2]) == tOrientation && order.Uint16(e[2:4]) == 3 && order.Uint32(e[4:8]) == 1 {
+			if o := int(order.Uint16(e[8:10])); 1 <= o && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// orient returns m transformed from the given EXIF orientation to upright.
+// It returns m itself if the orientation is 1.
+func orient(m image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return m
+	}
+	b := m.Bounds()
+	_, dr := draw.OrientationTransform(orientation, b)
+	dst := newLike(m, dr)
+	draw.Orient(dst, image.Point{}, m, b, orientation, draw.Src, nil)
+	return dst
+}
+
+// newLike returns a new image with bounds r that can hold m's colors without
+// loss. Image types that cannot be set pixel by pixel, such as *image.YCbCr,
+// are replaced by a suitable RGBA type.
+func newLike(m image.Image, r image.Rectangle) draw.Image {
+	switch m := m.(type) {
+	case *image.Alpha:
+		return image.NewAlpha(r)
+	case *image.Alpha16:
+		return image.NewAlpha16(r)
+	case *image.CMYK:
+		return image.NewCMYK(r)
+	case *image.Gray:
+		return image.NewGray(r)
+	case *image.Gray16:
+		return image.NewGray16(r)
+	case *image.NRGBA, *image.NYCbCrA:
+		return image.NewNRGBA(r)
+	case *image.NRGBA64:
+		return image.NewNRGBA64(r)
+	case *image.Paletted:
+		return image.NewPaletted(r, m.Palette)
+	case *image.RGBA64:
+		return image.NewRGBA64(r)
+	}
+	return image.NewRGBA(r)
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package imagelimit limits the size of images to decode, so that decoding
+// untrusted input, whose header can declare dimensions far larger than its
+// data, fails with an error instead of allocating huge pixel buffers.
+//
+// The decoders that support limits, such as those of the tiff, webp, bmp and
+// ccitt packages, take a *Limits option, and check the dimensions in an
+// image's header against it before allocating its pixels.
+package imagelimit // import "golang.org/x/image/imagelimit"
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+)
+
+// Limits are the maximum dimensions and memory of an image to decode. A zero
+// field means no limit, and a nil *Limits means no limits at all.
+type Limits struct {
+	// MaxWidth and MaxHeight are the maximum width and height, in pixels.
+	MaxWidth  int
+	MaxHeight int
+	// MaxPixels is the maximum number of pixels, width times height.
+	MaxPixels int64
+	// MaxMemory is the maximum size, in bytes, of the decoded image's pixel
+	// buffers. Other memory used while decoding, such as for compressed
+	// data, is not counted, but decoders may reject a piece of compressed
+	// data, such as a TIFF strip, that alone is larger than MaxMemory.
+	MaxMemory int64
+}
+
+// Error is the error returned when an image exceeds a limit.
+type Error struct {
+	// Limit is the exceeded limit: "width", "height", "pixels" or "memory".
+	Limit string
+	// Value is the image's value, such as its width, and Max is the limit.
+	Value, Max int64
+}
+
+func (e *Error) Error() string {
+	return "imagelimit: image " + e.Limit + " " + strconv.FormatInt(e.Value, 10) +
+		" exceeds the maximum of " + strconv.FormatInt(e.Max, 10)
+}
+
+// Check returns an *Error if an image of the given dimensions, with the
+// given number of bits per pixel in its decoded pixel buffers, exceeds l. It
+// returns nil if l is nil.
+//
+// Decoders call Check before allocating an image's pixels.
+func (l *Limits) Check(width, height, bitsPerPixel int) error {
+	if l == nil || width < 0 || height < 0 {
+		return nil
+	}
+	if l.MaxWidth > 0 && width > l.MaxWidth {
+		return &Error{"width", int64(width), int64(l.MaxWidth)}
+	}
+	if l.MaxHeight > 0 && height > l.MaxHeight {
+		return &Error{"height", int64(height), int64(l.MaxHeight)}
+	}
+	if n := mul(int64(width), int64(height)); l.MaxPixels > 0 && n > l.MaxPixels {
+		return &Error{"pixels", n, l.MaxPixels}
+	}
+	if l.MaxMemory > 0 {
+		rowBits := mul(int64(width), int64(bitsPerPixel))
+		if rowBits < math.MaxInt64 {
+			// Each row is rounded up to a whole number of bytes.
+			rowBits += 7
+		}
+		if n := mul(rowBits/8, int64(height)); n > l.MaxMemory {
+			return &Error{"memory", n, l.MaxMemory}
+		}
+	}
+	return nil
+}
+
+// CheckConfig is like Check, for an image with the dimensions and color
+// model of c, such as returned by a DecodeConfig function.
+//
+// The memory of an image is estimated from its color model, as for the
+// image type that holds that model's colors, such as 4 bytes per pixel for
+// color.RGBAModel. Y'CbCr images are assumed to have no chroma subsampling,
+// and other color models 8 bytes per pixel, so that the estimate is at
+// least the memory that decoding allocates.
+func (l *Limits) CheckConfig(c image.Config) error {
+	return l.Check(c.Width, c.Height, BitsPerPixel(c.ColorModel))
+}
+
+// BitsPerPixel returns an estimate of the number of bits per pixel of an
+// image with the color model m, as described for CheckConfig.
+func BitsPerPixel(m color.Model) int {
+	switch m {
+	case color.AlphaModel, color.GrayModel:
+		return 8
+	case color.Alpha16Model, color.Gray16Model:
+		return 16
+	case color.YCbCrModel:
+		return 24
+	case color.RGBAModel, color.NRGBAModel, color.CMYKModel, color.NYCbCrAModel:
+		return 32
+	}
+	if _, ok := m.(color.Palette); ok {
+		return 8
+	}
+	return 64
+}
+
+// mul returns a*b, for non-negative a and b, or math.MaxInt64 if that
+// overflows.
+func mul(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imagelimit
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	l := &Limits{
+		MaxWidth:  1000,
+		MaxHeight: 2000,
+		MaxPixels: 1000000,
+		MaxMemory: 1 << 20,
+	}
+	testCases := []struct {
+		width, height, bits int
+		want                *Error
+	}{
+		{100, 100, 32, nil},
+		{1000, 1000, 8, nil},
+		{1001, 1, 8, &Error{"width", 1001, 1000}},
+		{1, 2001, 8, &Error{"height", 2001, 2000}},
+		{1000, 1001, 8, &Error{"pixels", 1001000, 1000000}},
+		{1000, 1000, 32, &Error{"memory", 4000000, 1 << 20}},
+		// Rows are rounded up to whole bytes.
+		{1, 2000, 1, nil},
+		{9, 1000, 1, nil},
+		{1000, 1000, 9, &Error{"memory", 1125000, 1 << 20}},
+		{1000, 1000, math.MaxInt32, &Error{"memory", 268435455875000, 1 << 20}},
+		// Negative dimensions are left for decoders to reject.
+		{-1, -1, 8, nil},
+	}
+	for _, tc := range testCases {
+		err := l.Check(tc.width, tc.height, tc.bits)
+		if tc.want == nil {
+			if err != nil {
+				t.Errorf("%dx%d, %d bits: got %v, want nil", tc.width, tc.height, tc.bits, err)
+			}
+			continue
+		}
+		if e, ok := err.(*Error); !ok || *e != *tc.want {
+			t.Errorf("%dx%d, %d bits: got %v, want %v", tc.width, tc.height, tc.bits, err, tc.want)
+		}
+	}
+
+	// A nil or zero Limits has no limits.
+	for _, l := range []*Limits{nil, {}} {
+		if err := l.Check(math.MaxInt32, math.MaxInt32, 64); err != nil {
+			t.Errorf("%v: got %v, want nil", l, err)
+		}
+	}
+
+	// The products saturate instead of overflowing, whatever the size of int.
+	maxInt := int(^uint(0) >> 1)
+	if err := (&Limits{MaxMemory: math.MaxInt64 - 1}).Check(maxInt, maxInt, 64); err == nil {
+		t.Error("memory overflow: got nil error")
+	}
+}
+
+func TestCheckConfig(t *testing.T) {
+	l := &Limits{MaxMemory: 4 * 100 * 100}
+	for _, tc := range []struct {
+		m    color.Model
+		want bool
+	}{
+		{color.GrayModel, true},
+		{color.Palette{color.Black}, true},
+		{color.RGBAModel, true},
+		{color.NRGBAModel, true},
+		{color.RGBA64Model, false},
+		{color.ModelFunc(func(c color.Color) color.Color { return c }), false},
+	} {
+		err := l.CheckConfig(image.Config{ColorModel: tc.m, Width: 100, Height: 100})
+		if got := err == nil; got != tc.want {
+			t.Errorf("%T: got %v, want ok=%t", tc.m, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imgdiff
+
+import (
+	"image"
+	"image/color"
+)
+
+// Diff returns an image, of the same size as a and b, that shows where they
+// differ, and the number of pixels that differ.
+//
+// Two pixels differ if any of their alpha-premultiplied red, green, blue or
+// alpha values, scaled to the range [0, 1], differ by more than threshold.
+// Differing pixels are red, brighter for larger differences, and the other
+// pixels are a faded gray copy of a, for context.
+//
+// The returned image's bounds are a's bounds.
+func Diff(a, b image.Image, threshold float64) (*image.RGBA, int, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Size() != bb.Size() {
+		return nil, 0, ErrSizeMismatch
+	}
+	t := uint32(threshold * 0xffff)
+	if threshold < 0 {
+		t = 0
+	} else if threshold >= 1 {
+		t = 0xffff
+	}
+
+	ret := image.NewRGBA(ab)
+	n := 0
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			c0 := a.At(ab.Min.X+x, ab.Min.Y+y)
+			r0, g0, b0, a0 := c0.RGBA()
+			r1, g1, b1, a1 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			d := absDiff(r0, r1)
+			if v := absDiff(g0, g1); d < v {
+				d = v
+			}
+			if v := absDiff(b0, b1); d < v {
+				d = v
+			}
+			if v := absDiff(a0, a1); d < v {
+				d = v
+			}
+			var c color.RGBA
+			if d > t {
+				n++
+				// Scale the difference from (t, 0xffff] to [0x80, 0xff].
+				c = color.RGBA{uint8(0x80 + 0x7f*(d-t)/(0xffff-t)), 0, 0, 0xff}
+			} else {
+				// Fade the luma to the upper quarter of its range.
+				g := color.Gray16Model.Convert(c0).(color.Gray16)
+				v := uint8(0xc0 + uint32(g.Y)>>10)
+				c = color.RGBA{v, v, v, 0xff}
+			}
+			ret.SetRGBA(ab.Min.X+x, ab.Min.Y+y, c)
+		}
+	}
+	return ret, n, nil
+}
+
+func absDiff(a, b uint32) uint32 {
+	if a < b {
+		return b - a
+	}
+	return a - b
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package imgdiff compares images, measuring how similar they are and
+// showing where they differ.
+//
+// MSE and PSNR measure the per-pixel error, such as introduced by a lossy
+// codec. SSIM and MSSSIM track perceived quality more closely. Diff draws
+// the differing pixels, for looking at a failed comparison.
+//
+// Each function compares two images of the same size pixel by pixel from
+// their Bounds().Min, which need not be equal.
+package imgdiff // import "golang.org/x/image/imgdiff"
+
+import (
+	"errors"
+	"image"
+	"math"
+)
+
+// ErrSizeMismatch is returned when comparing images of different sizes.
+var ErrSizeMismatch = errors.New("imgdiff: images have different sizes")
+
+// MSE returns the mean squared error between two images of the same size,
+// over their alpha-premultiplied red, green, blue and alpha channels, scaled
+// to the range [0, 1]. Identical images have an MSE of 0.
+func MSE(a, b image.Image) (float64, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Size() != bb.Size() {
+		return 0, ErrSizeMismatch
+	}
+	if ab.Empty() {
+		return 0, nil
+	}
+	w, h := ab.Dx(), ab.Dy()
+	switch a := a.(type) {
+	case *image.RGBA:
+		if b, ok := b.(*image.RGBA); ok {
+			return float64(sumSq8(a.Pix, b.Pix, a.Stride, b.Stride,
+				a.PixOffset(ab.Min.X, ab.Min.Y), b.PixOffset(bb.Min.X, bb.Min.Y), 4*w, h)) /
+				float64(4*w*h) / (0xff * 0xff), nil
+		}
+	case *image.Gray:
+		if b, ok := b.(*image.Gray); ok {
+			// The red, green and blue channels are equal, and the alpha
+			// channels are both opaque.
+			return float64(3*sumSq8(a.Pix, b.Pix, a.Stride, b.Stride,
+				a.PixOffset(ab.Min.X, ab.Min.Y), b.PixOffset(bb.Min.X, bb.Min.Y), w, h)) /
+				float64(4*w*h) / (0xff * 0xff), nil
+		}
+	}
+
+	sum := 0.0
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r0, g0, b0, a0 := a.At(ab.Min.X+x, ab.Min.Y+y).RGBA()
+			r1, g1, b1, a1 := b.At(bb.Min.X+x, bb.Min.Y+y).RGBA()
+			for _, d := range [4]float64{
+				float64(r0) - float64(r1),
+				float64(g0) - float64(g1),
+				float64(b0) - float64(b1),
+				float64(a0) - float64(a1),
+			} {
+				sum += d * d
+			}
+		}
+	}
+	return sum / float64(4*w*h) / (0xffff * 0xffff), nil
+}
+
+// sumSq8 returns the sum of the squared differences of n bytes per row, for
+// h rows, of two pixel buffers, starting at the offsets i and j.
+func sumSq8(p, q []uint8, pStride, qStride, i, j, n, h int) uint64 {
+	sum := uint64(0)
+	for y := 0; y < h; y, i, j = y+1, i+pStride, j+qStride {
+		pp, qq := p[i:i+n], q[j:j+n]
+		for k, v := range pp {
+			d := int64(v) - int64(qq[k])
+			sum += uint64(d * d)
+		}
+	}
+	return sum
+}
+
+// PSNR returns the peak signal-to-noise ratio, in decibels, between two
+// images of the same size, derived from their MSE. Higher is more similar,
+// and identical images have an infinite PSNR. Values above 40 are usually
+// indistinguishable by eye.
+func PSNR(a, b image.Image) (float64, error) {
+	mse, err := MSE(a, b)
+	if err != nil {
+		return 0, err
+	}
+	if mse == 0 {
+		return math.Inf(+1), nil
+	}
+	return -10 * math.Log10(mse), nil
+}
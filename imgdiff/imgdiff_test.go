@@ -0,0 +1,200 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imgdiff
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// pattern returns a gray image with structure at a range of scales.
+func pattern(r image.Rectangle) *image.Gray {
+	m := image.NewGray(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := math.Sin(float64(x*x+y*y) / 64)
+			m.SetGray(x, y, color.Gray{uint8(0x80 + 0x7f*v)})
+		}
+	}
+	return m
+}
+
+// noisy returns a copy of m with every n'th pixel changed by delta, up or
+// down, whichever does not overflow.
+func noisy(m *image.Gray, n int, delta uint8) *image.Gray {
+	ret := image.NewGray(m.Bounds())
+	copy(ret.Pix, m.Pix)
+	for i := 0; i < len(ret.Pix); i += n {
+		if ret.Pix[i] <= 0xff-delta {
+			ret.Pix[i] += delta
+		} else {
+			ret.Pix[i] -= delta
+		}
+	}
+	return ret
+}
+
+// toRGBA returns a copy of m as an *image.RGBA.
+func toRGBA(m image.Image) *image.RGBA {
+	b := m.Bounds()
+	ret := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			ret.Set(x, y, m.At(x, y))
+		}
+	}
+	return ret
+}
+
+// opaque hides the type of an image, so that the general code paths are
+// used instead of the fast paths.
+type opaque struct {
+	image.Image
+}
+
+func TestFastPaths(t *testing.T) {
+	m0 := pattern(image.Rect(0, 0, 40, 30))
+	m1 := noisy(m0, 3, 20)
+	translucent := toRGBA(m1)
+	for i := 3; i < len(translucent.Pix); i += 20 {
+		translucent.Pix[i-3] /= 2
+		translucent.Pix[i-2] /= 2
+		translucent.Pix[i-1] /= 2
+		translucent.Pix[i] /= 2
+	}
+	testCases := []struct {
+		name string
+		a, b image.Image
+	}{
+		{"Gray", m0, m1},
+		{"RGBA", toRGBA(m0), translucent},
+		{"SubImage", m0.SubImage(image.Rect(5, 5, 25, 25)), m1.SubImage(image.Rect(10, 3, 30, 23))},
+	}
+	for _, tc := range testCases {
+		a, b := opaque{tc.a}, opaque{tc.b}
+		for _, f := range []struct {
+			name string
+			f    func(a, b image.Image) (float64, error)
+		}{
+			{"MSE", MSE},
+			{"SSIM", SSIM},
+			{"MSSSIM", MSSSIM},
+		} {
+			got, err := f.f(tc.a, tc.b)
+			if err != nil {
+				t.Errorf("%s: %s: %v", tc.name, f.name, err)
+				continue
+			}
+			want, _ := f.f(a, b)
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: %s: got %v, want %v", tc.name, f.name, got, want)
+			}
+		}
+	}
+}
+
+func TestIdentical(t *testing.T) {
+	m := pattern(image.Rect(0, 0, 64, 64))
+
+	// Translating the bounds does not change the comparison.
+	moved := image.NewGray(image.Rect(5, 7, 69, 71))
+	copy(moved.Pix, m.Pix)
+	if mse, err := MSE(m, moved); err != nil || mse != 0 {
+		t.Errorf("MSE: got %v, %v, want 0, nil", mse, err)
+	}
+	if psnr, err := PSNR(m, moved); err != nil || !math.IsInf(psnr, +1) {
+		t.Errorf("PSNR: got %v, %v, want +Inf, nil", psnr, err)
+	}
+	if ssim, err := SSIM(m, moved); err != nil || math.Abs(ssim-1) > 1e-9 {
+		t.Errorf("SSIM: got %v, %v, want 1, nil", ssim, err)
+	}
+	if msssim, err := MSSSIM(m, moved); err != nil || math.Abs(msssim-1) > 1e-9 {
+		t.Errorf("MSSSIM: got %v, %v, want 1, nil", msssim, err)
+	}
+}
+
+func TestNoise(t *testing.T) {
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 300, 200),
+		// MSSSIM uses fewer scales for smaller images.
+		image.Rect(0, 0, 20, 50),
+		image.Rect(0, 0, 5, 5),
+	} {
+		m := pattern(r)
+		small, large := noisy(m, 7, 3), noisy(m, 7, 30)
+		for _, f := range []struct {
+			name string
+			f    func(a, b image.Image) (float64, error)
+			min  float64
+			max  float64
+		}{
+			{"PSNR", PSNR, 0, math.Inf(+1)},
+			{"SSIM", SSIM, -1, 1},
+			{"MSSSIM", MSSSIM, 0, 1},
+		} {
+			v0, err0 := f.f(m, small)
+			v1, err1 := f.f(m, large)
+			if err0 != nil || err1 != nil {
+				t.Errorf("%v: %s: %v, %v", r, f.name, err0, err1)
+				continue
+			}
+			if !(v0 > v1) || v0 > f.max || v1 < f.min {
+				t.Errorf("%v: %s: got %v for small noise and %v for large noise", r, f.name, v0, v1)
+			}
+		}
+	}
+	m := pattern(image.Rect(0, 0, 64, 64))
+	if psnr, _ := PSNR(m, noisy(m, 7, 3)); psnr < 40 {
+		t.Errorf("PSNR: got %v for small noise, want >= 40", psnr)
+	}
+}
+
+func TestSizeMismatch(t *testing.T) {
+	a := image.NewGray(image.Rect(0, 0, 64, 64))
+	b := image.NewGray(image.Rect(0, 0, 64, 63))
+	for _, f := range []func(a, b image.Image) (float64, error){MSE, PSNR, SSIM, MSSSIM} {
+		if _, err := f(a, b); err != ErrSizeMismatch {
+			t.Errorf("got %v, want %v", err, ErrSizeMismatch)
+		}
+	}
+	if _, _, err := Diff(a, b, 0); err != ErrSizeMismatch {
+		t.Errorf("Diff: got %v, want %v", err, ErrSizeMismatch)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := image.NewRGBA(image.Rect(10, 10, 14, 12))
+	b := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for i := range a.Pix {
+		a.Pix[i] = 0xff
+		b.Pix[i] = 0xff
+	}
+	b.SetNRGBA(1, 0, color.NRGBA{0xff, 0xff, 0xf0, 0xff})
+	b.SetNRGBA(2, 1, color.NRGBA{0x00, 0x00, 0x00, 0xff})
+
+	m, n, err := Diff(a, b, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Rect != a.Rect {
+		t.Errorf("bounds: got %v, want %v", m.Rect, a.Rect)
+	}
+	if n != 2 {
+		t.Errorf("zero threshold: got %d differing pixels, want 2", n)
+	}
+	dim, bright := m.RGBAAt(11, 10), m.RGBAAt(12, 11)
+	if dim.G != 0 || bright.G != 0 || !(dim.R < bright.R) || bright.R != 0xff {
+		t.Errorf("differing pixels: got %v and %v, want dim and bright red", dim, bright)
+	}
+	if c := m.RGBAAt(10, 10); c.R != c.G || c.G != c.B || c.R < 0xc0 {
+		t.Errorf("same pixel: got %v, want light gray", c)
+	}
+
+	if _, n, _ := Diff(a, b, 0.1); n != 1 {
+		t.Errorf("threshold 0.1: got %d differing pixels, want 1", n)
+	}
+}
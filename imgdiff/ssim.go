@@ -0,0 +1,193 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package imgdiff
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+const (
+	ssimWindow = 8
+	ssimStride = 4
+	ssimC1     = 0.01 * 0.01
+	ssimC2     = 0.03 * 0.03
+)
+
+// msssimWeights are the exponents of each scale's terms, from the finest
+// scale to the coarsest, from Wang et al.'s MS-SSIM paper.
+var msssimWeights = [5]float64{0.0448, 0.2856, 0.3001, 0.2363, 0.1333}
+
+// SSIM returns the mean structural similarity index between the luma of two
+// images of the same size, computed over 8x8 pixel windows at a stride of 4
+// pixels. It ranges from -1 to 1, where 1 means identical. Unlike PSNR, it
+// tracks perceived quality, being more sensitive to lost structure, such as
+// blurred edges, than to uniform changes in brightness.
+//
+// Transparent pixels are compared as black. Images smaller than 8x8 pixels
+// are compared as a single window.
+//
+// See Wang et al., "Image Quality Assessment: From Error Visibility to
+// Structural Similarity", IEEE Transactions on Image Processing, 2004.
+func SSIM(a, b image.Image) (float64, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Size() != bb.Size() {
+		return 0, ErrSizeMismatch
+	}
+	if ab.Empty() {
+		return 1, nil
+	}
+	ssim, _, _ := ssimStats(luma(a), luma(b))
+	return ssim, nil
+}
+
+// MSSSIM returns the multi-scale structural similarity index between the
+// luma of two images of the same size. It compares the images at up to five
+// scales, each half the size of the one before, like SSIM, and combines the
+// results, so that it weighs structure according to its size, instead of
+// only at the scale of SSIM's 8x8 pixel windows. It ranges from 0 to 1,
+// where 1 means identical.
+//
+// Images are compared at fewer scales if halving them again would leave
+// them smaller than 8x8 pixels, with the remaining scales' weights scaled to
+// sum to 1.
+//
+// See Wang et al., "Multi-Scale Structural Similarity for Image Quality
+// Assessment", Asilomar Conference on Signals, Systems and Computers, 2003.
+func MSSSIM(a, b image.Image) (float64, error) {
+	ab, bb := a.Bounds(), b.Bounds()
+	if ab.Size() != bb.Size() {
+		return 0, ErrSizeMismatch
+	}
+	if ab.Empty() {
+		return 1, nil
+	}
+	la, lb := luma(a), luma(b)
+	n := 1
+	for w, h := la.w, la.h; n < len(msssimWeights) && w >= 2*ssimWindow && h >= 2*ssimWindow; n++ {
+		w, h = w/2, h/2
+	}
+	total := 0.0
+	for _, w := range msssimWeights[:n] {
+		total += w
+	}
+
+	ret := 1.0
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			la, lb = la.half(), lb.half()
+		}
+		_, l, cs := ssimStats(la, lb)
+		w := msssimWeights[i] / total
+		ret *= math.Pow(math.Max(cs, 0), w)
+		if i == n-1 {
+			ret *= math.Pow(math.Max(l, 0), w)
+		}
+	}
+	return ret, nil
+}
+
+// plane is a single channel image, with values in the range [0, 1].
+type plane struct {
+	pix  []float64
+	w, h int
+}
+
+// luma returns m's pixels' luma.
+func luma(m image.Image) plane {
+	b := m.Bounds()
+	p := plane{
+		pix: make([]float64, 0, b.Dx()*b.Dy()),
+		w:   b.Dx(),
+		h:   b.Dy(),
+	}
+	switch m := m.(type) {
+	case *image.Gray:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := m.PixOffset(b.Min.X, y)
+			for _, v := range m.Pix[i : i+p.w] {
+				p.pix = append(p.pix, float64(uint32(v)*0x101)/0xffff)
+			}
+		}
+	case *image.RGBA:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			i := m.PixOffset(b.Min.X, y)
+			for s := m.Pix[i : i+4*p.w]; len(s) >= 4; s = s[4:] {
+				// This is color.Gray16Model's conversion, as for the general
+				// case below.
+				r, g, b := uint32(s[0])*0x101, uint32(s[1])*0x101, uint32(s[2])*0x101
+				y := (19595*r + 38470*g + 7471*b + 1<<15) >> 16
+				p.pix = append(p.pix, float64(y)/0xffff)
+			}
+		}
+	default:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				g := color.Gray16Model.Convert(m.At(x, y)).(color.Gray16)
+				p.pix = append(p.pix, float64(g.Y)/0xffff)
+			}
+		}
+	}
+	return p
+}
+
+// half returns p scaled down to half its size, rounded down, by averaging
+// each 2x2 block of pixels.
+func (p plane) half() plane {
+	q := plane{
+		pix: make([]float64, (p.w/2)*(p.h/2)),
+		w:   p.w / 2,
+		h:   p.h / 2,
+	}
+	for y := 0; y < q.h; y++ {
+		r0, r1 := p.pix[2*y*p.w:], p.pix[(2*y+1)*p.w:]
+		for x := 0; x < q.w; x++ {
+			q.pix[y*q.w+x] = (r0[2*x] + r0[2*x+1] + r1[2*x] + r1[2*x+1]) / 4
+		}
+	}
+	return q
+}
+
+// ssimStats returns the mean, over the windows of two planes of the same
+// size, of the SSIM index and of its luminance and contrast-structure terms,
+// whose product is the index.
+func ssimStats(a, b plane) (ssim, l, cs float64) {
+	w, h := a.w, a.h
+	ww, wh := ssimWindow, ssimWindow
+	if ww > w {
+		ww = w
+	}
+	if wh > h {
+		wh = h
+	}
+	n := 0
+	for y0 := 0; y0+wh <= h; y0 += ssimStride {
+		for x0 := 0; x0+ww <= w; x0 += ssimStride {
+			var sa, sb, saa, sbb, sab float64
+			for y := y0; y < y0+wh; y++ {
+				for x := x0; x < x0+ww; x++ {
+					va, vb := a.pix[y*w+x], b.pix[y*w+x]
+					sa += va
+					sb += vb
+					saa += va * va
+					sbb += vb * vb
+					sab += va * vb
+				}
+			}
+			k := float64(ww * wh)
+			ma, mb := sa/k, sb/k
+			varA, varB := saa/k-ma*ma, sbb/k-mb*mb
+			cov := sab/k - ma*mb
+			wl := (2*ma*mb + ssimC1) / (ma*ma + mb*mb + ssimC1)
+			wcs := (2*cov + ssimC2) / (varA + varB + ssimC2)
+			ssim += wl * wcs
+			l += wl
+			cs += wcs
+			n++
+		}
+	}
+	return ssim / float64(n), l / float64(n), cs / float64(n)
+}
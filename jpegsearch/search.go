@@ -0,0 +1,176 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package jpegsearch searches for the JPEG encoding quality that meets a
+// target file size or visual similarity, such as for an image CDN that
+// serves images within a byte budget, or as small as possible without
+// visible loss.
+//
+// The search is a binary search over the quality, which assumes that the
+// encoded size and similarity both increase with the quality. That is true
+// of the image/jpeg encoder, give or take a few bytes, and of most other
+// encoders.
+package jpegsearch // import "golang.org/x/image/jpegsearch"
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+
+	"golang.org/x/image/imgdiff"
+)
+
+// ErrUnreachable is returned when no quality in the searched range meets the
+// target.
+var ErrUnreachable = errors.New("jpegsearch: no quality meets the target")
+
+// Options are the search parameters.
+type Options struct {
+	// MaxSize, if positive, is the largest encoded size in bytes. The search
+	// finds the highest quality whose encoding fits.
+	MaxSize int
+	// MinSSIM, if positive, is the smallest structural similarity, as
+	// computed by golang.org/x/image/imgdiff's SSIM function, between
+	// the image and its decoded encoding. The search finds the lowest
+	// quality that is similar enough, which is then also subject to any
+	// MaxSize.
+	MinSSIM float64
+	// MinQuality and MaxQuality bound the searched qualities, from 1 to 100
+	// inclusive. Zero means 1 and 100 respectively.
+	MinQuality, MaxQuality int
+	// Encode, if non-nil, encodes m at the given quality, such as with
+	// another encoder or with non-default settings. When MinSSIM is
+	// positive, its output must be decodable by image.Decode. Nil means
+	// image/jpeg's Encode.
+	Encode func(w io.Writer, m image.Image, quality int) error
+}
+
+// Result is an encoding found by a search.
+type Result struct {
+	Quality int
+	// Data is the encoded image.
+	Data []byte
+	// SSIM is the structural similarity between the image and Data
+	// decoded, if Options.MinSSIM is positive, or zero otherwise.
+	SSIM float64
+}
+
+// searcher encodes an image, caching the result for each quality.
+type searcher struct {
+	m       image.Image
+	o       Options
+	results map[int]*Result
+}
+
+func (s *searcher) encode(quality int) (*Result, error) {
+	if r := s.results[quality]; r != nil {
+		return r, nil
+	}
+	buf := &bytes.Buffer{}
+	if err := s.o.Encode(buf, s.m, quality); err != nil {
+		return nil, err
+	}
+	r := &Result{
+		Quality: quality,
+		Data:    buf.Bytes(),
+	}
+	if s.o.MinSSIM > 0 {
+		decoded, _, err := image.Decode(bytes.NewReader(r.Data))
+		if err != nil {
+			return nil, err
+		}
+		if r.SSIM, err = imgdiff.SSIM(s.m, decoded); err != nil {
+			return nil, err
+		}
+	}
+	s.results[quality] = r
+	return r, nil
+}
+
+// search returns the lowest quality in [lo, hi] for which ok is true,
+// assuming that ok is monotonic, or hi+1 if there is none.
+func (s *searcher) search(lo, hi int, ok func(*Result) bool) (int, error) {
+	for lo <= hi {
+		mid := int(uint(lo+hi) >> 1)
+		r, err := s.encode(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok(r) {
+			hi = mid - 1
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
+
+// Search encodes m at the quality that best meets the targets of opts. With
+// only a MaxSize, that is the highest quality that fits. With a MinSSIM, it
+// is the lowest quality that is similar enough. It returns ErrUnreachable if
+// no quality meets the targets, and the encoding at MaxQuality if there are
+// no targets.
+func Search(m image.Image, opts *Options) (*Result, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.MinQuality <= 0 {
+		o.MinQuality = 1
+	}
+	if o.MaxQuality <= 0 || o.MaxQuality > 100 {
+		o.MaxQuality = 100
+	}
+	if o.MinQuality > o.MaxQuality {
+		return nil, errors.New("jpegsearch: MinQuality is greater than MaxQuality")
+	}
+	if o.Encode == nil {
+		o.Encode = func(w io.Writer, m image.Image, quality int) error {
+			return jpeg.Encode(w, m, &jpeg.Options{Quality: quality})
+		}
+	}
+	s := &searcher{
+		m:       m,
+		o:       o,
+		results: map[int]*Result{},
+	}
+
+	quality := o.MaxQuality
+	if o.MinSSIM > 0 {
+		q, err := s.search(o.MinQuality, o.MaxQuality, func(r *Result) bool {
+			return r.SSIM >= o.MinSSIM
+		})
+		if err != nil {
+			return nil, err
+		}
+		if q > o.MaxQuality {
+			return nil, ErrUnreachable
+		}
+		quality = q
+	} else if o.MaxSize > 0 {
+		// Search for the lowest quality that does not fit. The quality below
+		// it is the highest that does.
+		q, err := s.search(o.MinQuality, o.MaxQuality, func(r *Result) bool {
+			return len(r.Data) > o.MaxSize
+		})
+		if err != nil {
+			return nil, err
+		}
+		if q == o.MinQuality {
+			return nil, ErrUnreachable
+		}
+		quality = q - 1
+	}
+
+	r, err := s.encode(quality)
+	if err != nil {
+		return nil, err
+	}
+	if o.MaxSize > 0 && len(r.Data) > o.MaxSize {
+		return nil, ErrUnreachable
+	}
+	return r, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jpegsearch
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"golang.org/x/image/testsupport"
+)
+
+func encodedSize(t *testing.T, m image.Image, quality int) int {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, m, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Len()
+}
+
+func TestSearchMaxSize(t *testing.T) {
+	m := testsupport.ZonePlate(image.Rect(0, 0, 96, 64))
+	for _, quality := range []int{10, 50, 90} {
+		maxSize := encodedSize(t, m, quality)
+		r, err := Search(m, &Options{MaxSize: maxSize})
+		if err != nil {
+			t.Errorf("quality %d: %v", quality, err)
+			continue
+		}
+		if len(r.Data) > maxSize {
+			t.Errorf("quality %d: got %d bytes, want at most %d", quality, len(r.Data), maxSize)
+		}
+		if r.Quality < quality {
+			t.Errorf("quality %d: got quality %d, want at least %d", quality, r.Quality, quality)
+		}
+		if r.Quality < 100 && encodedSize(t, m, r.Quality+1) <= maxSize {
+			t.Errorf("quality %d: got quality %d, but the next quality also fits", quality, r.Quality)
+		}
+	}
+
+	if _, err := Search(m, &Options{MaxSize: 100}); err != ErrUnreachable {
+		t.Errorf("tiny MaxSize: got %v, want ErrUnreachable", err)
+	}
+}
+
+func TestSearchMinSSIM(t *testing.T) {
+	m := testsupport.SiemensStar(image.Rect(0, 0, 96, 96), 24)
+	for _, minSSIM := range []float64{0.8, 0.95} {
+		r, err := Search(m, &Options{MinSSIM: minSSIM})
+		if err != nil {
+			t.Errorf("MinSSIM %v: %v", minSSIM, err)
+			continue
+		}
+		if r.SSIM < minSSIM {
+			t.Errorf("MinSSIM %v: got SSIM %v", minSSIM, r.SSIM)
+		}
+		if r.Quality > 1 {
+			lower, err := Search(m, &Options{MaxQuality: r.Quality - 1, MinSSIM: minSSIM})
+			if err != ErrUnreachable {
+				t.Errorf("MinSSIM %v: quality %d is also similar enough: %v, %v",
+					minSSIM, r.Quality-1, lower, err)
+			}
+		}
+	}
+
+	if _, err := Search(m, &Options{MinSSIM: 0.9, MaxSize: 100}); err != ErrUnreachable {
+		t.Errorf("MinSSIM with tiny MaxSize: got %v, want ErrUnreachable", err)
+	}
+}
+
+func TestSearchEncode(t *testing.T) {
+	m := testsupport.ZonePlate(image.Rect(0, 0, 32, 32))
+	var qualities []int
+	r, err := Search(m, &Options{
+		MinSSIM:    0.5,
+		MinQuality: 20,
+		MaxQuality: 40,
+		Encode: func(w io.Writer, m image.Image, quality int) error {
+			qualities = append(qualities, quality)
+			// The quality is ignored, so that the lowest quality is always
+			// similar enough.
+			return png.Encode(w, m)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Quality != 20 || r.SSIM != 1 {
+		t.Errorf("got quality %d and SSIM %v, want 20 and 1", r.Quality, r.SSIM)
+	}
+	// Each quality is encoded once.
+	seen := map[int]bool{}
+	for _, q := range qualities {
+		if seen[q] {
+			t.Errorf("quality %d was encoded more than once", q)
+		}
+		seen[q] = true
+	}
+}
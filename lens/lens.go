@@ -0,0 +1,139 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package lens corrects, and applies, the geometric distortion of camera
+// lenses, such as the barrel distortion of wide-angle lenses and the
+// pincushion distortion of telephoto lenses.
+//
+// The distortion follows the Brown–Conrady model, as used by OpenCV: radial
+// distortion with the coefficients K1 and K2, and tangential distortion,
+// which is caused by a lens that is not parallel to the sensor, with the
+// coefficients P1 and P2. Coefficients estimated by OpenCV's camera
+// calibration can be used as is, with Focal set to the focal length in
+// pixels, fx, and Center set to the principal point, (cx, cy).
+package lens // import "golang.org/x/image/lens"
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// undistortIterations is the number of fixed-point iterations of Undistort.
+// It is the number that OpenCV's undistortPoints uses by default.
+const undistortIterations = 20
+
+// Distortion is the distortion of a lens.
+//
+// A point p is distorted by normalizing it to (x, y) = (p - Center) / Focal
+// and, with r² = x² + y², moving it to
+//
+//	x' = x(1 + K1 r² + K2 r⁴) + 2 P1 x y + P2 (r² + 2 x²)
+//	y' = y(1 + K1 r² + K2 r⁴) + P1 (r² + 2 y²) + 2 P2 x y
+//
+// before undoing the normalization. Negative K1 gives barrel distortion and
+// positive K1 gives pincushion distortion.
+type Distortion struct {
+	// K1 and K2 are the radial distortion coefficients.
+	K1, K2 float64
+	// P1 and P2 are the tangential distortion coefficients.
+	P1, P2 float64
+	// Center is the center of distortion, usually the center of the image.
+	Center f64.Vec2
+	// Focal is the distance, in pixels, that normalizes distances from
+	// Center. It is the focal length in pixels for calibrated coefficients.
+	// Otherwise, half of the image's diagonal is a common choice. A Focal
+	// that is not positive means no distortion.
+	Focal float64
+}
+
+func (d *Distortion) distort(x, y float64) (float64, float64) {
+	r2 := x*x + y*y
+	radial := 1 + d.K1*r2 + d.K2*r2*r2
+	return x*radial + 2*d.P1*x*y + d.P2*(r2+2*x*x),
+		y*radial + d.P1*(r2+2*y*y) + 2*d.P2*x*y
+}
+
+// Distort returns where the lens moves the point p.
+func (d *Distortion) Distort(p f64.Vec2) f64.Vec2 {
+	if d.Focal <= 0 {
+		return p
+	}
+	x, y := d.distort((p[0]-d.Center[0])/d.Focal, (p[1]-d.Center[1])/d.Focal)
+	return f64.Vec2{d.Center[0] + x*d.Focal, d.Center[1] + y*d.Focal}
+}
+
+// Undistort returns the point that the lens moves to p, the inverse of
+// Distort. There is no closed form, so it is solved iteratively, as by
+// OpenCV's undistortPoints. It returns !ok if the iteration does not
+// converge, such as for points beyond the radius at which strong barrel
+// distortion folds the image back on itself.
+func (d *Distortion) Undistort(p f64.Vec2) (q f64.Vec2, ok bool) {
+	if d.Focal <= 0 {
+		return p, true
+	}
+	xd, yd := (p[0]-d.Center[0])/d.Focal, (p[1]-d.Center[1])/d.Focal
+	x, y := xd, yd
+	for i := 0; i < undistortIterations; i++ {
+		r2 := x*x + y*y
+		radial := 1 + d.K1*r2 + d.K2*r2*r2
+		if radial <= 0 {
+			return f64.Vec2{}, false
+		}
+		dx := 2*d.P1*x*y + d.P2*(r2+2*x*x)
+		dy := d.P1*(r2+2*y*y) + 2*d.P2*x*y
+		x, y = (xd-dx)/radial, (yd-dy)/radial
+	}
+	// Check that the solution maps back to p, to within a thousandth of a
+	// pixel.
+	ex, ey := d.distort(x, y)
+	if math.Hypot(ex-xd, ey-yd)*d.Focal > 1e-3 {
+		return f64.Vec2{}, false
+	}
+	return f64.Vec2{d.Center[0] + x*d.Focal, d.Center[1] + y*d.Focal}, true
+}
+
+// CorrectionMapper returns a draw.Mapper from the points of a corrected
+// image to those of the distorted image, as taken through the lens.
+func (d *Distortion) CorrectionMapper() draw.Mapper {
+	return draw.MapperFunc(func(dx, dy float64) (sx, sy float64, ok bool) {
+		p := d.Distort(f64.Vec2{dx, dy})
+		return p[0], p[1], true
+	})
+}
+
+// DistortionMapper returns a draw.Mapper from the points of a distorted
+// image, as taken through the lens, to those of the undistorted image.
+func (d *Distortion) DistortionMapper() draw.Mapper {
+	return draw.MapperFunc(func(dx, dy float64) (sx, sy float64, ok bool) {
+		p, ok := d.Undistort(f64.Vec2{dx, dy})
+		return p[0], p[1], ok
+	})
+}
+
+// Correct draws the corrected src, an image taken through the lens, to dst.
+// dst and src share a coordinate space, in which the Distortion is given.
+// Parts of dst that map to outside of src's bounds are left unchanged.
+//
+// t resamples src. Nil means draw.CatmullRom.
+func Correct(dst draw.Image, src image.Image, d *Distortion, t draw.MapTransformer) {
+	if t == nil {
+		t = draw.CatmullRom
+	}
+	t.TransformMap(dst, d.CorrectionMapper(), src, src.Bounds(), draw.Src, nil)
+}
+
+// Apply draws src, distorted as if taken through the lens, to dst. dst and
+// src share a coordinate space, in which the Distortion is given. Parts of
+// dst that map to outside of src's bounds are left unchanged.
+//
+// t resamples src. Nil means draw.CatmullRom.
+func Apply(dst draw.Image, src image.Image, d *Distortion, t draw.MapTransformer) {
+	if t == nil {
+		t = draw.CatmullRom
+	}
+	t.TransformMap(dst, d.DistortionMapper(), src, src.Bounds(), draw.Src, nil)
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lens
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+func TestUndistort(t *testing.T) {
+	testCases := []Distortion{
+		{K1: -0.2, K2: 0.05, Center: f64.Vec2{50, 40}, Focal: 64},
+		{K1: 0.15, Center: f64.Vec2{50, 40}, Focal: 64},
+		{K1: -0.1, P1: 0.01, P2: -0.02, Center: f64.Vec2{48, 41}, Focal: 80},
+		{},
+	}
+	for i, d := range testCases {
+		for y := 0.0; y <= 80; y += 10 {
+			for x := 0.0; x <= 100; x += 10 {
+				p := f64.Vec2{x, y}
+				q := d.Distort(p)
+				got, ok := d.Undistort(q)
+				if !ok {
+					t.Errorf("case %d, %v: Undistort: got !ok", i, p)
+					continue
+				}
+				if math.Hypot(got[0]-p[0], got[1]-p[1]) > 1e-3 {
+					t.Errorf("case %d: Undistort(Distort(%v)): got %v", i, p, got)
+				}
+			}
+		}
+	}
+
+	// Beyond a radius of 1/sqrt(3) focal lengths, this barrel distortion
+	// folds the image back on itself, so that the far corners have no
+	// undistorted point.
+	d := Distortion{K1: -1, Focal: 10}
+	if _, ok := d.Undistort(f64.Vec2{100, 100}); ok {
+		t.Error("folded point: got ok, want !ok")
+	}
+}
+
+func TestDistortCenter(t *testing.T) {
+	d := Distortion{K1: -0.3, K2: 0.1, Center: f64.Vec2{32, 24}, Focal: 40}
+	if got := d.Distort(d.Center); got != d.Center {
+		t.Errorf("center: got %v, want %v", got, d.Center)
+	}
+	// Barrel distortion moves points towards the center.
+	if got := d.Distort(f64.Vec2{72, 24}); got[0] >= 72 || got[1] != 24 {
+		t.Errorf("barrel: got %v", got)
+	}
+}
+
+// TestCorrectApply checks that correcting an image that has had the
+// distortion applied gives back the image, away from its edges.
+func TestCorrectApply(t *testing.T) {
+	const w, h = 64, 48
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// A smooth pattern, which resampling changes little.
+			src.SetRGBA(x, y, color.RGBA{
+				uint8(x * 4),
+				uint8(y * 5),
+				uint8(128 + 100*math.Sin(float64(x+y)/8)),
+				0xff,
+			})
+		}
+	}
+	d := &Distortion{K1: -0.2, K2: 0.02, Center: f64.Vec2{w / 2, h / 2}, Focal: 40}
+
+	for _, q := range []draw.MapTransformer{nil, draw.ApproxBiLinear.(draw.MapTransformer)} {
+		distorted := image.NewRGBA(src.Bounds())
+		Apply(distorted, src, d, q)
+		got := image.NewRGBA(src.Bounds())
+		Correct(got, distorted, d, q)
+
+		for y := 10; y < h-10; y++ {
+			for x := 10; x < w-10; x++ {
+				g, s := got.RGBAAt(x, y), src.RGBAAt(x, y)
+				if !near(g.R, s.R) || !near(g.G, s.G) || !near(g.B, s.B) || g.A != 0xff {
+					t.Fatalf("%T: (%d, %d): got %v, want %v", q, x, y, g, s)
+				}
+			}
+		}
+	}
+}
+
+func near(a, b uint8) bool {
+	return math.Abs(float64(a)-float64(b)) <= 8
+}
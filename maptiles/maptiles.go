@@ -0,0 +1,194 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package maptiles slices a large image into a pyramid of square tiles, as
+// served by map servers and consumed by slippy map viewers such as Leaflet
+// and OpenLayers.
+//
+// The tiles of zoom level z are numbered by their column x and row y. At the
+// highest zoom level, the image is at full resolution. Each lower level is
+// half the width and height of the one above, down to level 0, which fits in
+// a single tile.
+package maptiles // import "golang.org/x/image/maptiles"
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/image/draw"
+)
+
+// DefaultTileSize is the tile width and height used when Options.TileSize is
+// zero.
+const DefaultTileSize = 256
+
+// Scheme is the way that the rows of tiles are numbered.
+type Scheme int
+
+const (
+	// XYZ numbers the rows from the top, row 0 being the top row, as for
+	// OpenStreetMap and Google Maps tiles.
+	XYZ Scheme = iota
+	// TMS numbers the rows from the bottom, row 0 being the bottom row, as
+	// specified by the OSGeo Tile Map Service specification.
+	TMS
+)
+
+// Edge is the way that the tiles along the right and bottom edges of a level
+// are cut when the level's width or height is not a multiple of the tile
+// size.
+type Edge int
+
+const (
+	// EdgePad pads the edge tiles with transparent pixels, so that every
+	// tile is TileSize pixels wide and high.
+	EdgePad Edge = iota
+	// EdgeCrop crops the edge tiles to the level, so that they can be
+	// narrower or shorter than TileSize.
+	EdgeCrop
+)
+
+// Options are the slicing parameters. A nil *Options means the default for
+// every field.
+type Options struct {
+	// TileSize is the width and height of the tiles. Zero means
+	// DefaultTileSize.
+	TileSize int
+	// Scheme is the way that the rows of tiles are numbered.
+	Scheme Scheme
+	// Edge is the way that the edge tiles are cut.
+	Edge Edge
+	// Scaler scales each level to half the width and height of the level
+	// above. Nil means draw.Box, which averages every source pixel and so
+	// does not alias.
+	Scaler draw.Scaler
+}
+
+// A Sink receives the tiles of a pyramid.
+type Sink interface {
+	// WriteTile is called with the tile at column x and row y of zoom
+	// level z. m is only valid until WriteTile returns, as its pixels may
+	// be reused for the next tile.
+	WriteTile(z, x, y int, m image.Image) error
+}
+
+// SinkFunc is a Sink that calls a function, such as one that uploads each
+// tile to an object store.
+type SinkFunc func(z, x, y int, m image.Image) error
+
+// WriteTile implements the Sink interface.
+func (f SinkFunc) WriteTile(z, x, y int, m image.Image) error {
+	return f(z, x, y, m)
+}
+
+// DirSink returns a Sink that encodes each tile with encode, such as
+// png.Encode, and writes it to the file dir/z/x/y followed by ext, such as
+// ".png", creating the directories as needed.
+func DirSink(dir, ext string, encode func(w io.Writer, m image.Image) error) Sink {
+	return SinkFunc(func(z, x, y int, m image.Image) error {
+		d := filepath.Join(dir, fmt.Sprint(z), fmt.Sprint(x))
+		if err := os.MkdirAll(d, 0777); err != nil {
+			return err
+		}
+		f, err := os.Create(filepath.Join(d, fmt.Sprint(y)+ext))
+		if err != nil {
+			return err
+		}
+		if err := encode(f, m); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// MaxZoom returns the highest zoom level of the pyramid of an image of the
+// given size: the number of times that it can be halved, rounding up, before
+// it fits in a single tile.
+func MaxZoom(width, height, tileSize int) int {
+	z := 0
+	for width > tileSize || height > tileSize {
+		width, height = (width+1)/2, (height+1)/2
+		z++
+	}
+	return z
+}
+
+// Slice cuts m into a pyramid of tiles and passes each tile to sink. The
+// levels are sliced from the highest zoom level down, and each level's tiles
+// are passed row by row, from left to right. Slicing stops at the first
+// error returned by sink.
+//
+// m may be any image.Image, including one that decodes or generates its
+// pixels on demand, a tile at a time. Only the lower levels are held in
+// memory, which needs about a third of the memory of an image.RGBA the size
+// of m.
+func Slice(m image.Image, sink Sink, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.TileSize == 0 {
+		o.TileSize = DefaultTileSize
+	}
+	if o.TileSize < 0 {
+		return errors.New("maptiles: negative tile size")
+	}
+	if o.Scaler == nil {
+		o.Scaler = draw.Box
+	}
+
+	b := m.Bounds()
+	if b.Empty() {
+		return errors.New("maptiles: empty image")
+	}
+	buf := image.NewRGBA(image.Rect(0, 0, o.TileSize, o.TileSize))
+	for z := MaxZoom(b.Dx(), b.Dy(), o.TileSize); ; z-- {
+		if err := sliceLevel(m, z, buf, sink, &o); err != nil {
+			return err
+		}
+		if z == 0 {
+			return nil
+		}
+		sb := m.Bounds()
+		dr := image.Rect(0, 0, (sb.Dx()+1)/2, (sb.Dy()+1)/2)
+		dst := image.NewRGBA(dr)
+		o.Scaler.Scale(dst, dr, m, sb, draw.Src, nil)
+		m = dst
+	}
+}
+
+// sliceLevel passes the tiles of the level m, of zoom level z, to sink. The
+// tiles are copied to buf, which is a tile in size.
+func sliceLevel(m image.Image, z int, buf *image.RGBA, sink Sink, o *Options) error {
+	b := m.Bounds()
+	size := o.TileSize
+	cols, rows := (b.Dx()+size-1)/size, (b.Dy()+size-1)/size
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			sr := image.Rect(col*size, row*size, (col+1)*size, (row+1)*size).Add(b.Min).Intersect(b)
+			dr := sr.Sub(sr.Min)
+			var t image.Image = buf.SubImage(dr)
+			if o.Edge == EdgePad {
+				if dr != buf.Rect {
+					draw.Draw(buf, buf.Rect, image.Transparent, image.Point{}, draw.Src)
+				}
+				t = buf
+			}
+			draw.Draw(buf, dr, m, sr.Min, draw.Src)
+			y := row
+			if o.Scheme == TMS {
+				y = rows - 1 - row
+			}
+			if err := sink.WriteTile(z, col, y, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package maptiles
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImage returns an opaque image with distinct pixels, whose bounds do not
+// start at the origin.
+func testImage(w, h int) *image.RGBA {
+	m := image.NewRGBA(image.Rect(-7, 3, w-7, h+3))
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			m.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 0xff})
+		}
+	}
+	return m
+}
+
+func TestMaxZoom(t *testing.T) {
+	testCases := []struct {
+		w, h, size, want int
+	}{
+		{1, 1, 256, 0},
+		{256, 256, 256, 0},
+		{257, 1, 256, 1},
+		{512, 512, 256, 1},
+		{600, 300, 256, 2},
+		{1 << 20, 1, 256, 12},
+	}
+	for _, tc := range testCases {
+		if got := MaxZoom(tc.w, tc.h, tc.size); got != tc.want {
+			t.Errorf("MaxZoom(%d, %d, %d): got %d, want %d", tc.w, tc.h, tc.size, got, tc.want)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	src := testImage(600, 300)
+	for _, o := range []Options{
+		{Edge: EdgePad},
+		{Edge: EdgeCrop},
+		{Edge: EdgeCrop, Scheme: TMS},
+	} {
+		tiles := map[string]image.Rectangle{}
+		var order []string
+		err := Slice(src, SinkFunc(func(z, x, y int, m image.Image) error {
+			key := fmt.Sprintf("%d/%d/%d", z, x, y)
+			tiles[key] = m.Bounds()
+			order = append(order, key)
+			if z != 2 {
+				return nil
+			}
+			// The tiles of the highest zoom level are the source's pixels.
+			row := y
+			if o.Scheme == TMS {
+				row = 1 - y
+			}
+			min := src.Rect.Min.Add(image.Pt(256*x, 256*row))
+			b := m.Bounds()
+			for dy := 0; dy < b.Dy(); dy++ {
+				for dx := 0; dx < b.Dx(); dx++ {
+					got := m.At(b.Min.X+dx, b.Min.Y+dy)
+					want := color.RGBA{}
+					if p := min.Add(image.Pt(dx, dy)); p.In(src.Rect) {
+						want = src.RGBAAt(p.X, p.Y)
+					}
+					if got != want {
+						return fmt.Errorf("tile %s: pixel (%d, %d): got %v, want %v", key, dx, dy, got, want)
+					}
+				}
+			}
+			return nil
+		}), &o)
+		if err != nil {
+			t.Fatalf("%+v: %v", o, err)
+		}
+
+		// The 600x300 image is 3x2 tiles at zoom level 2, 2x1 at level 1
+		// and 1x1 at level 0.
+		want := map[string]image.Point{
+			"2/0/0": {256, 256}, "2/1/0": {256, 256}, "2/2/0": {88, 256},
+			"2/0/1": {256, 44}, "2/1/1": {256, 44}, "2/2/1": {88, 44},
+			"1/0/0": {256, 150}, "1/1/0": {44, 150},
+			"0/0/0": {150, 75},
+		}
+		if o.Scheme == TMS {
+			for _, x := range []string{"0", "1", "2"} {
+				want["2/"+x+"/0"], want["2/"+x+"/1"] = want["2/"+x+"/1"], want["2/"+x+"/0"]
+			}
+		}
+		if len(tiles) != len(want) {
+			t.Errorf("%+v: got %d tiles, want %d", o, len(tiles), len(want))
+		}
+		for key, size := range want {
+			if o.Edge == EdgePad {
+				size = image.Point{256, 256}
+			}
+			if got, ok := tiles[key]; !ok {
+				t.Errorf("%+v: missing tile %s", o, key)
+			} else if got.Size() != size {
+				t.Errorf("%+v: tile %s: got size %v, want %v", o, key, got.Size(), size)
+			}
+		}
+		if order[0][0] != '2' || order[len(order)-1] != "0/0/0" {
+			t.Errorf("%+v: got order %q, want from the highest zoom level down", o, order)
+		}
+	}
+}
+
+func TestSliceSinkError(t *testing.T) {
+	errStop := errors.New("stop")
+	n := 0
+	err := Slice(testImage(600, 300), SinkFunc(func(z, x, y int, m image.Image) error {
+		n++
+		if n == 3 {
+			return errStop
+		}
+		return nil
+	}), nil)
+	if err != errStop || n != 3 {
+		t.Errorf("got %v after %d tiles, want %v after 3", err, n, errStop)
+	}
+}
+
+func TestDirSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "maptiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := Slice(testImage(300, 100), DirSink(dir, ".png", png.Encode), &Options{Edge: EdgeCrop}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"1/0/0.png", "1/1/0.png", "0/0/0.png"} {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		_, err = png.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package f64
+
+import (
+	"math"
+)
+
+// The matrix methods below do not modify their receivers. They return new
+// values instead.
+
+// Identity returns the identity transformation.
+func Identity() Aff3 {
+	return Aff3{
+		1, 0, 0,
+		0, 1, 0,
+	}
+}
+
+// Translate returns the transformation that translates by (tx, ty).
+func Translate(tx, ty float64) Aff3 {
+	return Aff3{
+		1, 0, tx,
+		0, 1, ty,
+	}
+}
+
+// Scale returns the transformation that scales by sx horizontally and by sy
+// vertically, about the origin.
+func Scale(sx, sy float64) Aff3 {
+	return Aff3{
+		sx, 0, 0,
+		0, sy, 0,
+	}
+}
+
+// Rotate returns the transformation that rotates by angle radians about the
+// origin. A positive angle rotates from the positive X axis towards the
+// positive Y axis, which is clockwise when the Y axis points down.
+func Rotate(angle float64) Aff3 {
+	sin, cos := math.Sincos(angle)
+	return Aff3{
+		cos, -sin, 0,
+		sin, +cos, 0,
+	}
+}
+
+// RotateAbout is like Rotate, except that it rotates about the point (cx,
+// cy) instead of the origin.
+func RotateAbout(angle, cx, cy float64) Aff3 {
+	sin, cos := math.Sincos(angle)
+	return Aff3{
+		cos, -sin, cx - cos*cx + sin*cy,
+		sin, +cos, cy - sin*cx - cos*cy,
+	}
+}
+
+// Shear returns the transformation that maps (x, y) to (x + kx*y, ky*x + y).
+func Shear(kx, ky float64) Aff3 {
+	return Aff3{
+		1, kx, 0,
+		ky, 1, 0,
+	}
+}
+
+// Mul returns the matrix product m×n, the transformation that applies n and
+// then m.
+func (m Aff3) Mul(n Aff3) Aff3 {
+	return Aff3{
+		m[3*0+0]*n[3*0+0] + m[3*0+1]*n[3*1+0],
+		m[3*0+0]*n[3*0+1] + m[3*0+1]*n[3*1+1],
+		m[3*0+0]*n[3*0+2] + m[3*0+1]*n[3*1+2] + m[3*0+2],
+		m[3*1+0]*n[3*0+0] + m[3*1+1]*n[3*1+0],
+		m[3*1+0]*n[3*0+1] + m[3*1+1]*n[3*1+1],
+		m[3*1+0]*n[3*0+2] + m[3*1+1]*n[3*1+2] + m[3*1+2],
+	}
+}
+
+// Det returns the determinant of m.
+func (m Aff3) Det() float64 {
+	return m[3*0+0]*m[3*1+1] - m[3*0+1]*m[3*1+0]
+}
+
+// Invert returns the inverse of m. It returns the zero matrix and !ok if m is
+// singular.
+func (m Aff3) Invert() (inv Aff3, ok bool) {
+	det := m.Det()
+	if det == 0 {
+		return Aff3{}, false
+	}
+	return Aff3{
+		+m[3*1+1] / det,
+		-m[3*0+1] / det,
+		(m[3*1+2]*m[3*0+1] - m[3*1+1]*m[3*0+2]) / det,
+		-m[3*1+0] / det,
+		+m[3*0+0] / det,
+		(m[3*1+0]*m[3*0+2] - m[3*1+2]*m[3*0+0]) / det,
+	}, true
+}
+
+// Transform returns the point p transformed by m.
+func (m Aff3) Transform(p Vec2) Vec2 {
+	return Vec2{
+		m[3*0+0]*p[0] + m[3*0+1]*p[1] + m[3*0+2],
+		m[3*1+0]*p[0] + m[3*1+1]*p[1] + m[3*1+2],
+	}
+}
+
+// TransformVector returns the vector v transformed by m, ignoring m's
+// translation.
+func (m Aff3) TransformVector(v Vec2) Vec2 {
+	return Vec2{
+		m[3*0+0]*v[0] + m[3*0+1]*v[1],
+		m[3*1+0]*v[0] + m[3*1+1]*v[1],
+	}
+}
+
+// Mat3 returns m as a Mat3, with an explicit bottom row of [0 0 1].
+func (m Aff3) Mat3() Mat3 {
+	return Mat3{
+		m[0], m[1], m[2],
+		m[3], m[4], m[5],
+		0, 0, 1,
+	}
+}
+
+// Mul returns the matrix product m×n.
+func (m Mat3) Mul(n Mat3) (ret Mat3) {
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			ret[3*r+c] = m[3*r+0]*n[3*0+c] + m[3*r+1]*n[3*1+c] + m[3*r+2]*n[3*2+c]
+		}
+	}
+	return ret
+}
+
+// Det returns the determinant of m.
+func (m Mat3) Det() float64 {
+	return m[0]*(m[4]*m[8]-m[5]*m[7]) +
+		m[1]*(m[5]*m[6]-m[3]*m[8]) +
+		m[2]*(m[3]*m[7]-m[4]*m[6])
+}
+
+// Invert returns the inverse of m. It returns the zero matrix and !ok if m is
+// singular.
+func (m Mat3) Invert() (inv Mat3, ok bool) {
+	c00 := m[4]*m[8] - m[5]*m[7]
+	c01 := m[5]*m[6] - m[3]*m[8]
+	c02 := m[3]*m[7] - m[4]*m[6]
+	det := m[0]*c00 + m[1]*c01 + m[2]*c02
+	if det == 0 {
+		return Mat3{}, false
+	}
+	return Mat3{
+		c00 / det,
+		(m[2]*m[7] - m[1]*m[8]) / det,
+		(m[1]*m[5] - m[2]*m[4]) / det,
+		c01 / det,
+		(m[0]*m[8] - m[2]*m[6]) / det,
+		(m[2]*m[3] - m[0]*m[5]) / det,
+		c02 / det,
+		(m[1]*m[6] - m[0]*m[7]) / det,
+		(m[0]*m[4] - m[1]*m[3]) / det,
+	}, true
+}
+
+// Transform returns the column vector v multiplied by m.
+func (m Mat3) Transform(v Vec3) Vec3 {
+	return Vec3{
+		m[0]*v[0] + m[1]*v[1] + m[2]*v[2],
+		m[3]*v[0] + m[4]*v[1] + m[5]*v[2],
+		m[6]*v[0] + m[7]*v[1] + m[8]*v[2],
+	}
+}
+
+// Project returns the point p transformed by the projective transformation
+// m, in homogeneous coordinates, and then divided by its third coordinate. It
+// returns !ok if p is on m's horizon, where that coordinate is zero.
+func (m Mat3) Project(p Vec2) (q Vec2, ok bool) {
+	v := m.Transform(Vec3{p[0], p[1], 1})
+	if v[2] == 0 {
+		return Vec2{}, false
+	}
+	return Vec2{v[0] / v[2], v[1] / v[2]}, true
+}
+
+// Mul returns the matrix product m×n.
+func (m Mat4) Mul(n Mat4) (ret Mat4) {
+	for r := 0; r < 4; r++ {
+		for c := 0; c < 4; c++ {
+			ret[4*r+c] = m[4*r+0]*n[4*0+c] + m[4*r+1]*n[4*1+c] + m[4*r+2]*n[4*2+c] + m[4*r+3]*n[4*3+c]
+		}
+	}
+	return ret
+}
+
+// minors4 returns the 2x2 minors of the top two rows of m, s, and of its
+// bottom two rows, c, from which Mat4's determinant and inverse are computed
+// by Laplace expansion.
+func (m *Mat4) minors4() (s, c [6]float64) {
+	s[0] = m[0]*m[5] - m[4]*m[1]
+	s[1] = m[0]*m[6] - m[4]*m[2]
+	s[2] = m[0]*m[7] - m[4]*m[3]
+	s[3] = m[1]*m[6] - m[5]*m[2]
+	s[4] = m[1]*m[7] - m[5]*m[3]
+	s[5] = m[2]*m[7] - m[6]*m[3]
+	c[0] = m[8]*m[13] - m[12]*m[9]
+	c[1] = m[8]*m[14] - m[12]*m[10]
+	c[2] = m[8]*m[15] - m[12]*m[11]
+	c[3] = m[9]*m[14] - m[13]*m[10]
+	c[4] = m[9]*m[15] - m[13]*m[11]
+	c[5] = m[10]*m[15] - m[14]*m[11]
+	return s, c
+}
+
+// Det returns the determinant of m.
+func (m Mat4) Det() float64 {
+	s, c := m.minors4()
+	return s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0]
+}
+
+// Invert returns the inverse of m. It returns the zero matrix and !ok if m is
+// singular.
+func (m Mat4) Invert() (inv Mat4, ok bool) {
+	s, c := m.minors4()
+	det := s[0]*c[5] - s[1]*c[4] + s[2]*c[3] + s[3]*c[2] - s[4]*c[1] + s[5]*c[0]
+	if det == 0 {
+		return Mat4{}, false
+	}
+	inv = Mat4{
+		+m[5]*c[5] - m[6]*c[4] + m[7]*c[3],
+		-m[1]*c[5] + m[2]*c[4] - m[3]*c[3],
+		+m[13]*s[5] - m[14]*s[4] + m[15]*s[3],
+		-m[9]*s[5] + m[10]*s[4] - m[11]*s[3],
+
+		-m[4]*c[5] + m[6]*c[2] - m[7]*c[1],
+		+m[0]*c[5] - m[2]*c[2] + m[3]*c[1],
+		-m[12]*s[5] + m[14]*s[2] - m[15]*s[1],
+		+m[8]*s[5] - m[10]*s[2] + m[11]*s[1],
+
+		+m[4]*c[4] - m[5]*c[2] + m[7]*c[0],
+		-m[0]*c[4] + m[1]*c[2] - m[3]*c[0],
+		+m[12]*s[4] - m[13]*s[2] + m[15]*s[0],
+		-m[8]*s[4] + m[9]*s[2] - m[11]*s[0],
+
+		-m[4]*c[3] + m[5]*c[1] - m[6]*c[0],
+		+m[0]*c[3] - m[1]*c[1] + m[2]*c[0],
+		-m[12]*s[3] + m[13]*s[1] - m[14]*s[0],
+		+m[8]*s[3] - m[9]*s[1] + m[10]*s[0],
+	}
+	for i := range inv {
+		inv[i] /= det
+	}
+	return inv, true
+}
+
+// Transform returns the column vector v multiplied by m.
+func (m Mat4) Transform(v Vec4) Vec4 {
+	return Vec4{
+		m[0]*v[0] + m[1]*v[1] + m[2]*v[2] + m[3]*v[3],
+		m[4]*v[0] + m[5]*v[1] + m[6]*v[2] + m[7]*v[3],
+		m[8]*v[0] + m[9]*v[1] + m[10]*v[2] + m[11]*v[3],
+		m[12]*v[0] + m[13]*v[1] + m[14]*v[2] + m[15]*v[3],
+	}
+}
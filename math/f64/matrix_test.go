@@ -0,0 +1,123 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package f64
+
+import (
+	"math"
+	"testing"
+)
+
+func near(got, want []float64) bool {
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAff3(t *testing.T) {
+	testCases := []struct {
+		desc string
+		m    Aff3
+		p, q Vec2
+	}{
+		{"Identity", Identity(), Vec2{3, 4}, Vec2{3, 4}},
+		{"Translate", Translate(1, -2), Vec2{3, 4}, Vec2{4, 2}},
+		{"Scale", Scale(2, -3), Vec2{3, 4}, Vec2{6, -12}},
+		{"Rotate", Rotate(math.Pi / 2), Vec2{3, 4}, Vec2{-4, 3}},
+		{"RotateAbout", RotateAbout(math.Pi/2, 1, 1), Vec2{3, 4}, Vec2{-2, 3}},
+		{"Shear", Shear(2, 0.25), Vec2{3, 4}, Vec2{11, 4.75}},
+		{"Mul", Translate(1, 2).Mul(Scale(2, 2)), Vec2{3, 4}, Vec2{7, 10}},
+	}
+	for _, tc := range testCases {
+		if got := tc.m.Transform(tc.p); !near(got[:], tc.q[:]) {
+			t.Errorf("%s: Transform: got %v, want %v", tc.desc, got, tc.q)
+		}
+		inv, ok := tc.m.Invert()
+		if !ok {
+			t.Errorf("%s: Invert: got !ok", tc.desc)
+			continue
+		}
+		if got := inv.Transform(tc.q); !near(got[:], tc.p[:]) {
+			t.Errorf("%s: inverse Transform: got %v, want %v", tc.desc, got, tc.p)
+		}
+		if got, want := tc.m.Mul(inv), Identity(); !near(got[:], want[:]) {
+			t.Errorf("%s: m×inv: got %v, want %v", tc.desc, got, want)
+		}
+		m3 := tc.m.Mat3()
+		if got, want := m3.Det(), tc.m.Det(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s: Mat3 Det: got %v, want %v", tc.desc, got, want)
+		}
+		if got, ok := m3.Project(tc.p); !ok || !near(got[:], tc.q[:]) {
+			t.Errorf("%s: Mat3 Project: got %v, %t, want %v, true", tc.desc, got, ok, tc.q)
+		}
+	}
+
+	if got, want := Scale(2, 3).TransformVector(Vec2{1, 1}), (Vec2{2, 3}); got != want {
+		t.Errorf("TransformVector: got %v, want %v", got, want)
+	}
+	if _, ok := Scale(0, 1).Invert(); ok {
+		t.Errorf("Invert of a singular matrix: got ok, want !ok")
+	}
+}
+
+func TestMat3(t *testing.T) {
+	m := Mat3{
+		2, 0, 1,
+		1, 3, 2,
+		1, 1, 2,
+	}
+	if got, want := m.Det(), 6.0; got != want {
+		t.Errorf("Det: got %v, want %v", got, want)
+	}
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatalf("Invert: got !ok")
+	}
+	if got, want := m.Mul(inv), (Mat3{1, 0, 0, 0, 1, 0, 0, 0, 1}); !near(got[:], want[:]) {
+		t.Errorf("m×inv: got %v, want %v", got, want)
+	}
+	if got, want := m.Transform(Vec3{1, 2, 3}), (Vec3{5, 13, 9}); got != want {
+		t.Errorf("Transform: got %v, want %v", got, want)
+	}
+	if _, ok := (Mat3{1, 2, 3, 2, 4, 6, 0, 0, 1}).Invert(); ok {
+		t.Errorf("Invert of a singular matrix: got ok, want !ok")
+	}
+	if _, ok := (Mat3{1, 0, 0, 0, 1, 0, 1, 0, 0}).Project(Vec2{0, 5}); ok {
+		t.Errorf("Project onto the horizon: got ok, want !ok")
+	}
+}
+
+func TestMat4(t *testing.T) {
+	m := Mat4{
+		2, 0, 0, 1,
+		0, 1, 3, 0,
+		1, 0, 1, 2,
+		0, 2, 0, 1,
+	}
+	// Expanding along the first column gives
+	// 2*det([1 3 0; 0 1 2; 2 0 1]) + 1*det([0 0 1; 1 3 0; 2 0 1]) = 2*13 + -6.
+	if got, want := m.Det(), 20.0; got != want {
+		t.Errorf("Det: got %v, want %v", got, want)
+	}
+	inv, ok := m.Invert()
+	if !ok {
+		t.Fatalf("Invert: got !ok")
+	}
+	identity := Mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+	if got := m.Mul(inv); !near(got[:], identity[:]) {
+		t.Errorf("m×inv: got %v, want %v", got, identity)
+	}
+	if got := inv.Mul(m); !near(got[:], identity[:]) {
+		t.Errorf("inv×m: got %v, want %v", got, identity)
+	}
+	if got, want := m.Transform(Vec4{1, 2, 3, 4}), (Vec4{6, 11, 12, 8}); got != want {
+		t.Errorf("Transform: got %v, want %v", got, want)
+	}
+	if _, ok := (Mat4{}).Invert(); ok {
+		t.Errorf("Invert of a singular matrix: got ok, want !ok")
+	}
+}
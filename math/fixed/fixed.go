@@ -7,6 +7,11 @@ package fixed // import "golang.org/x/image/math/fixed"
 
 import (
 	"fmt"
+	"image"
+	"math"
+	"math/bits"
+
+	"golang.org/x/image/math/f64"
 )
 
 // TODO: implement fmt.Formatter for %f and %g.
@@ -48,19 +53,73 @@ func (x Int26_6) Floor() int { return int((x + 0x00) >> 6) }
 
 // Round returns the nearest integer value to x. Ties are rounded up.
 //
-// Its return type is int, not Int26_6.
-func (x Int26_6) Round() int { return int((x + 0x20) >> 6) }
+// Its return type is int, not Int26_6. It does not overflow, even for the
+// maximum Int26_6.
+func (x Int26_6) Round() int { return int(x>>6) + int((x&0x3f+0x20)>>6) }
 
 // Ceil returns the least integer value greater than or equal to x.
 //
-// Its return type is int, not Int26_6.
-func (x Int26_6) Ceil() int { return int((x + 0x3f) >> 6) }
+// Its return type is int, not Int26_6. It does not overflow, even for the
+// maximum Int26_6.
+func (x Int26_6) Ceil() int { return int(x>>6) + int((x&0x3f+0x3f)>>6) }
 
 // Mul returns x*y in 26.6 fixed-point arithmetic.
 func (x Int26_6) Mul(y Int26_6) Int26_6 {
 	return Int26_6((int64(x)*int64(y) + 1<<5) >> 6)
 }
 
+// MulSat is like Mul, except that a result outside of the range of an Int26_6
+// saturates to its minimum or maximum value, instead of wrapping around.
+func (x Int26_6) MulSat(y Int26_6) Int26_6 {
+	return sat26_6((int64(x)*int64(y) + 1<<5) >> 6)
+}
+
+// AddSat returns x+y, except that a result outside of the range of an
+// Int26_6 saturates to its minimum or maximum value, instead of wrapping
+// around.
+func (x Int26_6) AddSat(y Int26_6) Int26_6 {
+	return sat26_6(int64(x) + int64(y))
+}
+
+// sat26_6 returns v clamped to the range of an Int26_6.
+func sat26_6(v int64) Int26_6 {
+	if v < math.MinInt32 {
+		return math.MinInt32
+	}
+	if v > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return Int26_6(v)
+}
+
+// Div returns x/y in 26.6 fixed-point arithmetic, rounded to the nearest
+// value. Ties are rounded up. Like Mul, a result outside of the range of an
+// Int26_6 wraps around.
+//
+// It panics if y is zero.
+func (x Int26_6) Div(y Int26_6) Int26_6 {
+	q := divRound(0, uint64(abs64(int64(x)))<<6, uint64(abs64(int64(y))), (x < 0) != (y < 0))
+	return Int26_6(q)
+}
+
+// Sqrt returns the square root of x in 26.6 fixed-point arithmetic, rounded
+// to the nearest value. It returns zero if x is negative.
+func (x Int26_6) Sqrt() Int26_6 {
+	if x <= 0 {
+		return 0
+	}
+	return Int26_6(sqrtRound(0, uint64(x)<<6))
+}
+
+// Abs returns the absolute value of x. Like Go's negation operator, it wraps
+// around for the minimum Int26_6, which it returns unchanged.
+func (x Int26_6) Abs() Int26_6 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
 // Int52_12 is a signed 52.12 fixed-point number.
 //
 // The integer part ranges from -2251799813685248 to 2251799813685247,
@@ -87,18 +146,23 @@ func (x Int52_12) String() string {
 
 // Floor returns the greatest integer value less than or equal to x.
 //
-// Its return type is int, not Int52_12.
+// Its return type is int, not Int52_12. On platforms where an int has 32 bits,
+// a result outside of the range of an int wraps around.
 func (x Int52_12) Floor() int { return int((x + 0x000) >> 12) }
 
 // Round returns the nearest integer value to x. Ties are rounded up.
 //
-// Its return type is int, not Int52_12.
-func (x Int52_12) Round() int { return int((x + 0x800) >> 12) }
+// Its return type is int, not Int52_12. It does not overflow, even for the
+// maximum Int52_12, but on platforms where an int has 32 bits, a result
+// outside of the range of an int wraps around.
+func (x Int52_12) Round() int { return int(x>>12) + int((x&0xfff+0x800)>>12) }
 
 // Ceil returns the least integer value greater than or equal to x.
 //
-// Its return type is int, not Int52_12.
-func (x Int52_12) Ceil() int { return int((x + 0xfff) >> 12) }
+// Its return type is int, not Int52_12. It does not overflow, even for the
+// maximum Int52_12, but on platforms where an int has 32 bits, a result
+// outside of the range of an int wraps around.
+func (x Int52_12) Ceil() int { return int(x>>12) + int((x&0xfff+0xfff)>>12) }
 
 // Mul returns x*y in 52.12 fixed-point arithmetic.
 func (x Int52_12) Mul(y Int52_12) Int52_12 {
@@ -109,6 +173,135 @@ func (x Int52_12) Mul(y Int52_12) Int52_12 {
 	return ret
 }
 
+// MulSat is like Mul, except that a result outside of the range of an
+// Int52_12 saturates to its minimum or maximum value, instead of wrapping
+// around.
+func (x Int52_12) MulSat(y Int52_12) Int52_12 {
+	const M, N = 52, 12
+	lo, hi := muli64(int64(x), int64(y))
+	// The 128-bit product, shifted right by N, fits in an int64 if its high
+	// 64 bits are the sign extension of its low 64 bits.
+	ret := int64(hi<<M | lo>>N)
+	if int64(hi)>>N != ret>>63 {
+		if int64(hi) < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	if (lo>>(N-1))&1 != 0 {
+		if ret == math.MaxInt64 {
+			return math.MaxInt64
+		}
+		ret++
+	}
+	return Int52_12(ret)
+}
+
+// AddSat returns x+y, except that a result outside of the range of an
+// Int52_12 saturates to its minimum or maximum value, instead of wrapping
+// around.
+func (x Int52_12) AddSat(y Int52_12) Int52_12 {
+	ret := x + y
+	// The addition overflowed if x and y have the same sign, and ret has a
+	// different one.
+	if (x < 0) == (y < 0) && (ret < 0) != (x < 0) {
+		if x < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return ret
+}
+
+// Div returns x/y in 52.12 fixed-point arithmetic, rounded to the nearest
+// value. Ties are rounded up. Like Mul, a result outside of the range of an
+// Int52_12 wraps around.
+//
+// It panics if y is zero.
+func (x Int52_12) Div(y Int52_12) Int52_12 {
+	const M, N = 52, 12
+	ux := uint64(abs64(int64(x)))
+	q := divRound(ux>>M, ux<<N, uint64(abs64(int64(y))), (x < 0) != (y < 0))
+	return Int52_12(q)
+}
+
+// Sqrt returns the square root of x in 52.12 fixed-point arithmetic, rounded
+// to the nearest value. It returns zero if x is negative.
+func (x Int52_12) Sqrt() Int52_12 {
+	const M, N = 52, 12
+	if x <= 0 {
+		return 0
+	}
+	return Int52_12(sqrtRound(uint64(x)>>M, uint64(x)<<N))
+}
+
+// Abs returns the absolute value of x. Like Go's negation operator, it wraps
+// around for the minimum Int52_12, which it returns unchanged.
+func (x Int52_12) Abs() Int52_12 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// abs64 returns the absolute value of v. For the minimum int64, it returns
+// that value, which, converted to a uint64, is the correct absolute value.
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// divRound returns the quotient n/d, negated if neg is true, where n is the
+// 128-bit unsigned integer hi<<64 | lo. The quotient is rounded to the nearest
+// integer, with ties rounded up after negation, and only its low 64 bits are
+// returned.
+func divRound(hi, lo, d uint64, neg bool) uint64 {
+	if d == 0 {
+		panic("fixed: division by zero")
+	}
+	// Rounding the magnitude down after adding (d-c)/2, itself rounded down,
+	// where c is 1 if neg is true and 0 otherwise, rounds ties away from zero
+	// for positive quotients and towards zero for negative ones.
+	k := d >> 1
+	if neg && d&1 == 0 {
+		k = (d - 1) >> 1
+	}
+	lo, carry := bits.Add64(lo, k, 0)
+	hi += carry
+	q, _ := bits.Div64(hi%d, lo, d)
+	if neg {
+		return -q
+	}
+	return q
+}
+
+// sqrtRound returns the square root of n, the 128-bit unsigned integer
+// hi<<64 | lo, rounded to the nearest integer. Ties cannot occur.
+func sqrtRound(hi, lo uint64) uint64 {
+	// lessEq returns whether the 128-bit integer rhi<<64 | rlo is less than
+	// or equal to n.
+	lessEq := func(rhi, rlo uint64) bool {
+		return rhi < hi || rhi == hi && rlo <= lo
+	}
+	// Start with a floating point approximation, and then correct it to the
+	// floor of the square root.
+	r := uint64(math.Sqrt(float64(hi)*(1<<64) + float64(lo)))
+	for r > 0 && !lessEq(bits.Mul64(r, r)) {
+		r--
+	}
+	for lessEq(bits.Mul64(r+1, r+1)) {
+		r++
+	}
+	// n is an integer, so it is nearer to r+1 than to r if it is greater than
+	// (r + 1/2)², or equivalently, greater than r² + r.
+	if rhi, rlo := bits.Mul64(r, r+1); rhi < hi || rhi == hi && rlo < lo {
+		r++
+	}
+	return r
+}
+
 // muli64 multiplies two int64 values, returning the 128-bit signed integer
 // result as two uint64 values.
 //
@@ -172,6 +365,95 @@ func (p Point26_6) In(r Rectangle26_6) bool {
 	return r.Min.X <= p.X && p.X < r.Max.X && r.Min.Y <= p.Y && p.Y < r.Max.Y
 }
 
+// Dot returns the dot product p·q. Its return type is Int52_12, which holds
+// the product of two Int26_6 values without losing precision.
+func (p Point26_6) Dot(q Point26_6) Int52_12 {
+	return Int52_12(int64(p.X)*int64(q.X) + int64(p.Y)*int64(q.Y))
+}
+
+// Cross returns the Z component of the cross product p×q, which is positive
+// if q is clockwise from p when the Y axis points down. Its return type is
+// Int52_12, which holds the product of two Int26_6 values without losing
+// precision.
+func (p Point26_6) Cross(q Point26_6) Int52_12 {
+	return Int52_12(int64(p.X)*int64(q.Y) - int64(p.Y)*int64(q.X))
+}
+
+// Len returns the length of the vector p, rounded to the nearest value.
+func (p Point26_6) Len() Int26_6 {
+	x := uint64(abs64(int64(p.X)))
+	y := uint64(abs64(int64(p.Y)))
+	return Int26_6(sqrtRound(0, x*x+y*y))
+}
+
+// Normalize returns the vector with the same direction as p and length k,
+// rounded to the nearest value. It returns the zero vector if p is the zero
+// vector.
+func (p Point26_6) Normalize(k Int26_6) Point26_6 {
+	n := uint64(p.Len())
+	if n == 0 {
+		return Point26_6{}
+	}
+	scale := func(v Int26_6) Int26_6 {
+		u := uint64(abs64(int64(v))) * uint64(abs64(int64(k)))
+		return Int26_6(divRound(0, u, n, (v < 0) != (k < 0)))
+	}
+	return Point26_6{scale(p.X), scale(p.Y)}
+}
+
+// Mid returns the midpoint of p and q, rounded towards zero. Unlike
+// (p.X+q.X)/2, it does not overflow.
+func (p Point26_6) Mid(q Point26_6) Point26_6 {
+	return Point26_6{
+		Int26_6((int64(p.X) + int64(q.X)) / 2),
+		Int26_6((int64(p.Y) + int64(q.Y)) / 2),
+	}
+}
+
+// Lerp returns the linear interpolation between p and q, where t is 0 for p
+// and 1 (Int26_6(1<<6)) for q, rounded to the nearest value. Ties are rounded
+// up.
+func (p Point26_6) Lerp(q Point26_6, t Int26_6) Point26_6 {
+	lerp := func(a, b Int26_6) Int26_6 {
+		return a + Int26_6(((int64(b)-int64(a))*int64(t)+1<<5)>>6)
+	}
+	return Point26_6{lerp(p.X, q.X), lerp(p.Y, q.Y)}
+}
+
+// Floor returns the greatest integer point less than or equal to p, in both
+// X and Y.
+func (p Point26_6) Floor() image.Point {
+	return image.Point{p.X.Floor(), p.Y.Floor()}
+}
+
+// Round returns the nearest integer point to p. Ties are rounded up.
+func (p Point26_6) Round() image.Point {
+	return image.Point{p.X.Round(), p.Y.Round()}
+}
+
+// Ceil returns the least integer point greater than or equal to p, in both X
+// and Y.
+func (p Point26_6) Ceil() image.Point {
+	return image.Point{p.X.Ceil(), p.Y.Ceil()}
+}
+
+// Vec2 returns p as an f64.Vec2. The conversion is exact.
+func (p Point26_6) Vec2() f64.Vec2 {
+	return f64.Vec2{float64(p.X) / (1 << 6), float64(p.Y) / (1 << 6)}
+}
+
+// PointFromVec2 returns v as a Point26_6, rounded to the nearest value. Ties
+// are rounded away from zero. The result is unspecified if v is outside of
+// the range of a Point26_6.
+//
+// For example, passing f64.Vec2{2.5, -0.25} yields Point26_6{160, -16}.
+func PointFromVec2(v f64.Vec2) Point26_6 {
+	return Point26_6{
+		Int26_6(math.Round(v[0] * (1 << 6))),
+		Int26_6(math.Round(v[1] * (1 << 6))),
+	}
+}
+
 // Point52_12 is a 52.12 fixed-point coordinate pair.
 //
 // It is analogous to the image.Point type in the standard library.
@@ -320,6 +602,17 @@ func (r Rectangle26_6) In(s Rectangle26_6) bool {
 		s.Min.Y <= r.Min.Y && r.Max.Y <= s.Max.Y
 }
 
+// Round returns r with its bounds rounded to the nearest integer points.
+func (r Rectangle26_6) Round() image.Rectangle {
+	return image.Rectangle{r.Min.Round(), r.Max.Round()}
+}
+
+// Cover returns the smallest integer rectangle that contains r, with its Min
+// bound rounded down and its Max bound rounded up.
+func (r Rectangle26_6) Cover() image.Rectangle {
+	return image.Rectangle{r.Min.Floor(), r.Max.Ceil()}
+}
+
 // Rectangle52_12 is a 52.12 fixed-point coordinate rectangle. The Min bound is
 // inclusive and the Max bound is exclusive. It is well-formed if Min.X <=
 // Max.X and likewise for Y.
@@ -5,9 +5,13 @@
 package fixed
 
 import (
+	"image"
 	"math"
+	"math/big"
 	"math/rand"
 	"testing"
+
+	"golang.org/x/image/math/f64"
 )
 
 var testCases = []struct {
@@ -374,6 +378,228 @@ func TestMulu32(t *testing.T) {
 	}
 }
 
+// refOp is a reference implementation of a fixed-point operation, using
+// arbitrary precision arithmetic, for numbers with fracBits fractional bits.
+// It returns the exact result, before any wrapping around or saturation.
+type refOp func(x, y *big.Int, fracBits uint) *big.Int
+
+// floorDiv returns floor(n/d), for d > 0.
+func floorDiv(n, d *big.Int) *big.Int {
+	q, m := new(big.Int), new(big.Int)
+	q.DivMod(n, d, m)
+	return q
+}
+
+// refDiv returns x/y, rounded to the nearest value, with ties rounded up.
+func refDiv(x, y *big.Int, fracBits uint) *big.Int {
+	// floor((x<<fracBits)/y + 1/2) == floor(((x<<fracBits)*2 + y) / 2y).
+	n := new(big.Int).Lsh(x, fracBits+1)
+	n.Add(n, y)
+	d := new(big.Int).Lsh(y, 1)
+	if d.Sign() < 0 {
+		n.Neg(n)
+		d.Neg(d)
+	}
+	return floorDiv(n, d)
+}
+
+// refMul returns x*y, rounded to the nearest value, with ties rounded up.
+func refMul(x, y *big.Int, fracBits uint) *big.Int {
+	n := new(big.Int).Mul(x, y)
+	n.Add(n, new(big.Int).Lsh(big.NewInt(1), fracBits-1))
+	return floorDiv(n, new(big.Int).Lsh(big.NewInt(1), fracBits))
+}
+
+func refAdd(x, y *big.Int, fracBits uint) *big.Int {
+	return new(big.Int).Add(x, y)
+}
+
+// refSqrt returns the square root of x, rounded to the nearest value.
+func refSqrt(x, y *big.Int, fracBits uint) *big.Int {
+	if x.Sign() <= 0 {
+		return new(big.Int)
+	}
+	n := new(big.Int).Lsh(x, fracBits)
+	r := new(big.Int).Sqrt(n)
+	if rr := new(big.Int).Mul(r, r); rr.Add(rr, r).Cmp(n) < 0 {
+		r.Add(r, big.NewInt(1))
+	}
+	return r
+}
+
+// testRefOp compares f, an operation on fixed-point numbers with totalBits
+// bits, fracBits of which are fractional, against ref. If sat is true, f is
+// expected to saturate its results. Otherwise, results outside of the range
+// of the fixed-point type are not checked.
+func testRefOp(t *testing.T, name string, totalBits, fracBits uint, f func(x, y int64) int64, ref refOp, sat bool) {
+	t.Helper()
+	min := new(big.Int).Lsh(big.NewInt(-1), totalBits-1)
+	max := new(big.Int).Sub(new(big.Int).Neg(min), big.NewInt(1))
+	rng := rand.New(rand.NewSource(4))
+	for i := 0; i < 10000; i++ {
+		// Use both small and large values.
+		shift := uint(rng.Intn(int(totalBits)))
+		x := int64(rng.Uint64()) >> (64 - totalBits) >> shift
+		y := int64(rng.Uint64()) >> (64 - totalBits) >> uint(rng.Intn(int(totalBits)))
+		if y == 0 {
+			continue
+		}
+		want := ref(big.NewInt(x), big.NewInt(y), fracBits)
+		switch {
+		case want.Cmp(min) < 0 && sat:
+			want = min
+		case want.Cmp(max) > 0 && sat:
+			want = max
+		case want.Cmp(min) < 0 || want.Cmp(max) > 0:
+			continue
+		}
+		if got := f(x, y); got != want.Int64() {
+			t.Errorf("%s(%#x, %#x): got %#x, want %#x", name, x, y, got, want.Int64())
+		}
+	}
+}
+
+func TestInt26_6Arithmetic(t *testing.T) {
+	const totalBits, fracBits = 32, 6
+	testRefOp(t, "Div", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int26_6(x).Div(Int26_6(y)))
+	}, refDiv, false)
+	testRefOp(t, "Sqrt", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int26_6(x).Sqrt())
+	}, refSqrt, false)
+	testRefOp(t, "MulSat", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int26_6(x).MulSat(Int26_6(y)))
+	}, refMul, true)
+	testRefOp(t, "AddSat", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int26_6(x).AddSat(Int26_6(y)))
+	}, refAdd, true)
+}
+
+func TestInt52_12Arithmetic(t *testing.T) {
+	const totalBits, fracBits = 64, 12
+	testRefOp(t, "Div", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int52_12(x).Div(Int52_12(y)))
+	}, refDiv, false)
+	testRefOp(t, "Sqrt", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int52_12(x).Sqrt())
+	}, refSqrt, false)
+	testRefOp(t, "MulSat", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int52_12(x).MulSat(Int52_12(y)))
+	}, refMul, true)
+	testRefOp(t, "AddSat", totalBits, fracBits, func(x, y int64) int64 {
+		return int64(Int52_12(x).AddSat(Int52_12(y)))
+	}, refAdd, true)
+}
+
+func TestArithmeticEdgeCases(t *testing.T) {
+	if got, want := Int26_6(math.MaxInt32).Round(), 1<<25; got != want {
+		t.Errorf("Int26_6 max Round: got %d, want %d", got, want)
+	}
+	if got, want := Int26_6(math.MaxInt32).Ceil(), 1<<25; got != want {
+		t.Errorf("Int26_6 max Ceil: got %d, want %d", got, want)
+	}
+	if got, want := Int26_6(-5<<6).Div(2<<6), Int26_6(-5<<5); got != want {
+		t.Errorf("Int26_6 Div: got %v, want %v", got, want)
+	}
+	// -1/128 is a tie between -1/64 and 0, which is rounded up.
+	if got, want := Int26_6(-1).Div(2<<6), Int26_6(0); got != want {
+		t.Errorf("Int26_6 Div tie: got %v, want %v", got, want)
+	}
+	if got, want := Int26_6(2<<6).Sqrt(), Int26_6(91); got != want {
+		t.Errorf("Int26_6 Sqrt(2): got %v, want %v", got, want)
+	}
+	if got, want := Int26_6(-1).Sqrt(), Int26_6(0); got != want {
+		t.Errorf("Int26_6 Sqrt(-1): got %v, want %v", got, want)
+	}
+	if got, want := Int26_6(-3).Abs(), Int26_6(3); got != want {
+		t.Errorf("Int26_6 Abs: got %v, want %v", got, want)
+	}
+	if got, want := Int52_12(math.MinInt64).Abs(), Int52_12(math.MinInt64); got != want {
+		t.Errorf("Int52_12 min Abs: got %v, want %v", got, want)
+	}
+	if got, want := Int52_12(math.MaxInt64).AddSat(1), Int52_12(math.MaxInt64); got != want {
+		t.Errorf("Int52_12 AddSat: got %v, want %v", got, want)
+	}
+	if got, want := Int52_12(math.MinInt64).MulSat(-1<<12), Int52_12(math.MaxInt64); got != want {
+		t.Errorf("Int52_12 MulSat: got %v, want %v", got, want)
+	}
+	if got, want := Int52_12(math.MinInt64).Div(1<<12), Int52_12(math.MinInt64); got != want {
+		t.Errorf("Int52_12 Div: got %v, want %v", got, want)
+	}
+}
+
+func TestPoint26_6Geometry(t *testing.T) {
+	p := Point26_6{3 << 6, 4 << 6}
+	q := Point26_6{-1 << 6, 2 << 6}
+	if got, want := p.Dot(q), Int52_12(5<<12); got != want {
+		t.Errorf("Dot: got %v, want %v", got, want)
+	}
+	if got, want := p.Cross(q), Int52_12(10<<12); got != want {
+		t.Errorf("Cross: got %v, want %v", got, want)
+	}
+	if got, want := p.Len(), Int26_6(5<<6); got != want {
+		t.Errorf("Len: got %v, want %v", got, want)
+	}
+	if got, want := (Point26_6{1 << 6, 1 << 6}).Len(), Int26_6(91); got != want {
+		t.Errorf("Len of (1, 1): got %v, want %v", got, want)
+	}
+	if got, want := p.Normalize(10<<6), (Point26_6{6 << 6, 8 << 6}); got != want {
+		t.Errorf("Normalize: got %v, want %v", got, want)
+	}
+	if got, want := p.Normalize(-1<<6), (Point26_6{-38, -51}); got != want {
+		t.Errorf("Normalize to -1: got %v, want %v", got, want)
+	}
+	if got, want := (Point26_6{}).Normalize(1<<6), (Point26_6{}); got != want {
+		t.Errorf("Normalize of zero: got %v, want %v", got, want)
+	}
+	if got, want := p.Mid(q), (Point26_6{1 << 6, 3 << 6}); got != want {
+		t.Errorf("Mid: got %v, want %v", got, want)
+	}
+	max := Point26_6{math.MaxInt32, math.MaxInt32}
+	if got, want := max.Mid(max), max; got != want {
+		t.Errorf("Mid of max: got %v, want %v", got, want)
+	}
+	if got, want := p.Lerp(q, 1<<4), (Point26_6{2 << 6, 7 << 5}); got != want {
+		t.Errorf("Lerp: got %v, want %v", got, want)
+	}
+	if got, want := p.Lerp(q, 1<<6), q; got != want {
+		t.Errorf("Lerp to q: got %v, want %v", got, want)
+	}
+
+	r := Point26_6{-(3<<6 + 32), 1<<6 + 16}
+	if got, want := r.Floor(), (image.Point{-4, 1}); got != want {
+		t.Errorf("Floor: got %v, want %v", got, want)
+	}
+	if got, want := r.Round(), (image.Point{-3, 1}); got != want {
+		t.Errorf("Round: got %v, want %v", got, want)
+	}
+	if got, want := r.Ceil(), (image.Point{-3, 2}); got != want {
+		t.Errorf("Ceil: got %v, want %v", got, want)
+	}
+	if got, want := r.Vec2(), (f64.Vec2{-3.5, 1.25}); got != want {
+		t.Errorf("Vec2: got %v, want %v", got, want)
+	}
+	if got, want := PointFromVec2(f64.Vec2{-3.5, 1.2}), (Point26_6{-224, 77}); got != want {
+		t.Errorf("PointFromVec2: got %v, want %v", got, want)
+	}
+	if got := PointFromVec2(r.Vec2()); got != r {
+		t.Errorf("PointFromVec2 round trip: got %v, want %v", got, r)
+	}
+}
+
+func TestRectangle26_6Conversions(t *testing.T) {
+	r := Rectangle26_6{
+		Min: Point26_6{-(1<<6 + 16), 2<<6 + 40},
+		Max: Point26_6{3<<6 + 16, 5<<6 + 32},
+	}
+	if got, want := r.Round(), image.Rect(-1, 3, 3, 6); got != want {
+		t.Errorf("Round: got %v, want %v", got, want)
+	}
+	if got, want := r.Cover(), image.Rect(-2, 2, 4, 6); got != want {
+		t.Errorf("Cover: got %v, want %v", got, want)
+	}
+}
+
 // mul (with a lower case 'm') is an alternative implementation of Int26_6.Mul
 // (with an upper case 'M'). It has the same structure as the Int52_12.Mul
 // implementation, but Int26_6.mul is easier to test since Go has built-in
@@ -0,0 +1,375 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package matte refines the masks of cut-out images into soft alpha mattes.
+//
+// A mask that is made by selecting pixels, such as by color or by a
+// segmentation model, is binary, or nearly so, which makes cut-outs look
+// jagged, and hair and fur look cut out with scissors. The functions of this
+// package soften a mask's edges, make them follow the edges of the image,
+// and remove the background's color from the semi-transparent edge pixels,
+// so that the cut-out can be composited over a new background.
+//
+// Masks and mattes are read from the alpha channel of an image.Image, so
+// that an *image.Alpha, an *image.Gray or the cut-out image itself can be
+// passed.
+package matte // import "golang.org/x/image/matte"
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Trimap values.
+const (
+	Background = 0x00
+	Unknown    = 0x80
+	Foreground = 0xff
+)
+
+// plane is a single-channel image with float32 samples in [0, 1].
+type plane struct {
+	w, h int
+	pix  []float32
+}
+
+func newPlane(w, h int) *plane {
+	return &plane{w, h, make([]float32, w*h)}
+}
+
+// alphaPlane returns the alpha channel of m.
+func alphaPlane(m image.Image) *plane {
+	b := m.Bounds()
+	p := newPlane(b.Dx(), b.Dy())
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, _, _, a := m.At(x, y).RGBA()
+			p.pix[i] = float32(a) / 0xffff
+			i++
+		}
+	}
+	return p
+}
+
+// toAlpha returns p as an *image.Alpha with the bounds r.
+func (p *plane) toAlpha(r image.Rectangle) *image.Alpha {
+	dst := image.NewAlpha(r)
+	for y := 0; y < p.h; y++ {
+		row := dst.Pix[y*dst.Stride : y*dst.Stride+p.w]
+		for x := range row {
+			row[x] = unit8(p.pix[y*p.w+x])
+		}
+	}
+	return dst
+}
+
+// unit8 returns v, in [0, 1], as an 8-bit value.
+func unit8(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 0xff
+	}
+	return uint8(v*0xff + 0.5)
+}
+
+// Feather returns the alpha channel of mask blurred by a Gaussian with the
+// standard deviation sigma, in pixels, so that its edges fade out over about
+// 2*sigma pixels on either side. The result has the same bounds as mask.
+func Feather(mask image.Image, sigma float64) *image.Alpha {
+	p := alphaPlane(mask)
+	gaussian(p, sigma)
+	return p.toAlpha(mask.Bounds())
+}
+
+// gaussian blurs p in place by a Gaussian with the standard deviation sigma.
+// Pixels beyond p's edges are those at the edges.
+func gaussian(p *plane, sigma float64) {
+	if sigma <= 0 || p.w == 0 || p.h == 0 {
+		return
+	}
+	r := int(math.Ceil(3 * sigma))
+	k := make([]float32, 2*r+1)
+	sum := float32(0)
+	for i := range k {
+		d := float64(i - r)
+		k[i] = float32(math.Exp(-d * d / (2 * sigma * sigma)))
+		sum += k[i]
+	}
+	for i := range k {
+		k[i] /= sum
+	}
+	convolve(p, k, true)
+	convolve(p, k, false)
+}
+
+// convolve convolves the rows, or columns, of p with the kernel k, whose
+// length is odd.
+func convolve(p *plane, k []float32, rows bool) {
+	n, m, step, stride := p.w, p.h, 1, p.w
+	if !rows {
+		n, m, step, stride = p.h, p.w, p.w, 1
+	}
+	r := len(k) / 2
+	line := make([]float32, n)
+	for j := 0; j < m; j++ {
+		base := j * stride
+		for i := range line {
+			line[i] = p.pix[base+i*step]
+		}
+		for i := 0; i < n; i++ {
+			v := float32(0)
+			for t, kt := range k {
+				v += kt * line[clamp(i+t-r, 0, n-1)]
+			}
+			p.pix[base+i*step] = v
+		}
+	}
+}
+
+// boxMean returns the mean of p over the (2r+1)×(2r+1) box around each
+// pixel, clipped to p's bounds.
+func boxMean(p *plane, r int) *plane {
+	// s is the summed-area table of p, with a row and column of zeroes.
+	w, h := p.w, p.h
+	s := make([]float64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		row := 0.0
+		for x := 0; x < w; x++ {
+			row += float64(p.pix[y*w+x])
+			s[(y+1)*(w+1)+x+1] = s[y*(w+1)+x+1] + row
+		}
+	}
+	dst := newPlane(w, h)
+	for y := 0; y < h; y++ {
+		y0, y1 := clamp(y-r, 0, h), clamp(y+r+1, 0, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := clamp(x-r, 0, w), clamp(x+r+1, 0, w)
+			sum := s[y1*(w+1)+x1] - s[y0*(w+1)+x1] - s[y1*(w+1)+x0] + s[y0*(w+1)+x0]
+			dst.pix[y*w+x] = float32(sum / float64((y1-y0)*(x1-x0)))
+		}
+	}
+	return dst
+}
+
+// morph returns the minimum, or maximum, of p over the (2r+1)×(2r+1) box
+// around each pixel, clipped to p's bounds.
+func morph(p *plane, r int, max bool) *plane {
+	dst := &plane{p.w, p.h, append([]float32(nil), p.pix...)}
+	for _, rows := range []bool{true, false} {
+		n, m, step, stride := dst.w, dst.h, 1, dst.w
+		if !rows {
+			n, m, step, stride = dst.h, dst.w, dst.w, 1
+		}
+		line := make([]float32, n)
+		for j := 0; j < m; j++ {
+			base := j * stride
+			for i := range line {
+				line[i] = dst.pix[base+i*step]
+			}
+			for i := 0; i < n; i++ {
+				v := line[i]
+				for t := clamp(i-r, 0, n-1); t <= clamp(i+r, 0, n-1); t++ {
+					if max && line[t] > v || !max && line[t] < v {
+						v = line[t]
+					}
+				}
+				dst.pix[base+i*step] = v
+			}
+		}
+	}
+	return dst
+}
+
+func clamp(i, lo, hi int) int {
+	if i < lo {
+		return lo
+	}
+	if i > hi {
+		return hi
+	}
+	return i
+}
+
+// Trimap returns the trimap of the alpha channel of mask: the pixels that
+// are within band pixels, horizontally and vertically, of both a pixel that
+// is at least half opaque and one that is not are Unknown, and the others
+// are Foreground if they are at least half opaque and Background if not.
+// The result has the same bounds as mask.
+//
+// A trimap marks the band around the edges of a rough mask within which a
+// matting algorithm, such as FeatherTrimap or GuidedFilter, is to find the
+// exact edges.
+func Trimap(mask image.Image, band int) *image.Gray {
+	b := mask.Bounds()
+	p := alphaPlane(mask)
+	for i, v := range p.pix {
+		if v >= 0.5 {
+			p.pix[i] = 1
+		} else {
+			p.pix[i] = 0
+		}
+	}
+	lo, hi := morph(p, band, false), morph(p, band, true)
+	dst := image.NewGray(b)
+	for y := 0; y < p.h; y++ {
+		for x := 0; x < p.w; x++ {
+			i := y*p.w + x
+			v := uint8(Unknown)
+			switch {
+			case lo.pix[i] == 1:
+				v = Foreground
+			case hi.pix[i] == 0:
+				v = Background
+			}
+			dst.Pix[y*dst.Stride+x] = v
+		}
+	}
+	return dst
+}
+
+// FeatherTrimap returns a matte from the trimap, an image such as that
+// returned by Trimap whose gray values are Background, Unknown or
+// Foreground. Background and Foreground pixels are transparent and opaque,
+// and Unknown pixels are the trimap blurred by a Gaussian with the standard
+// deviation sigma, in pixels, so that the matte fades across the unknown
+// band. The result has the same bounds as trimap.
+func FeatherTrimap(trimap image.Image, sigma float64) *image.Alpha {
+	b := trimap.Bounds()
+	t := grayPlane(trimap)
+	p := &plane{t.w, t.h, append([]float32(nil), t.pix...)}
+	gaussian(p, sigma)
+	// Pixels are classified by the trimap value that they are nearest to.
+	for i, v := range t.pix {
+		switch {
+		case v < 0.25:
+			p.pix[i] = 0
+		case v > 0.75:
+			p.pix[i] = 1
+		}
+	}
+	return p.toAlpha(b)
+}
+
+// grayPlane returns the gray values of m.
+func grayPlane(m image.Image) *plane {
+	b := m.Bounds()
+	p := newPlane(b.Dx(), b.Dy())
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g := color.Gray16Model.Convert(m.At(x, y)).(color.Gray16)
+			p.pix[i] = float32(g.Y) / 0xffff
+			i++
+		}
+	}
+	return p
+}
+
+// GuidedFilter returns the alpha channel of matte filtered by the guided
+// filter of He, Sun and Tang, with the luminance of guide as the guidance
+// image. The filter smooths the matte while making its edges follow those of
+// guide, the image being cut out, which recovers soft edges such as hair
+// from a rough or feathered matte.
+//
+// The filter works on (2r+1)×(2r+1) windows. eps, typically from 1e-4 to
+// 1e-2, is the regularization: the larger it is, the more the matte is
+// blurred where guide is flat. guide and matte must have the same size, and
+// the result has the same bounds as matte.
+func GuidedFilter(matte, guide image.Image, r int, eps float64) *image.Alpha {
+	p, g := alphaPlane(matte), grayPlane(guide)
+	if g.w != p.w || g.h != p.h {
+		panic("matte: GuidedFilter: guide and matte sizes differ")
+	}
+	n := len(p.pix)
+	gg, gp := newPlane(p.w, p.h), newPlane(p.w, p.h)
+	for i := 0; i < n; i++ {
+		gg.pix[i] = g.pix[i] * g.pix[i]
+		gp.pix[i] = g.pix[i] * p.pix[i]
+	}
+	meanG, meanP := boxMean(g, r), boxMean(p, r)
+	meanGG, meanGP := boxMean(gg, r), boxMean(gp, r)
+
+	// Within each window, the matte is modeled as a*guide + b.
+	a, bb := newPlane(p.w, p.h), newPlane(p.w, p.h)
+	for i := 0; i < n; i++ {
+		varG := meanGG.pix[i] - meanG.pix[i]*meanG.pix[i]
+		covGP := meanGP.pix[i] - meanG.pix[i]*meanP.pix[i]
+		a.pix[i] = covGP / (varG + float32(eps))
+		bb.pix[i] = meanP.pix[i] - a.pix[i]*meanG.pix[i]
+	}
+	meanA, meanB := boxMean(a, r), boxMean(bb, r)
+	for i := 0; i < n; i++ {
+		p.pix[i] = meanA.pix[i]*g.pix[i] + meanB.pix[i]
+	}
+	return p.toAlpha(matte.Bounds())
+}
+
+// Decontaminate returns src, as cut out by the alpha channel of matte, with
+// the background's color removed from its semi-transparent pixels.
+//
+// A semi-transparent edge pixel of a photograph mixes the colors of the
+// foreground and the background, I = αF + (1-α)B. Without decontamination, a
+// cut-out composited over a new background keeps a fringe of the old
+// background's color. Decontaminate estimates B as the mean color of the
+// transparent pixels within r pixels, and solves for F. Opaque pixels, and
+// those with no transparent pixel within r pixels, keep their color. src and
+// matte must have the same size, and the result has the same bounds as src.
+func Decontaminate(src, matte image.Image, r int) *image.NRGBA {
+	sb := src.Bounds()
+	alpha := alphaPlane(matte)
+	w, h := alpha.w, alpha.h
+	if sb.Dx() != w || sb.Dy() != h {
+		panic("matte: Decontaminate: src and matte sizes differ")
+	}
+
+	// The color channels of src, and those of the transparent pixels.
+	var ch, bg [3]*plane
+	for c := range ch {
+		ch[c], bg[c] = newPlane(w, h), newPlane(w, h)
+	}
+	transparent := newPlane(w, h)
+	i := 0
+	for y := sb.Min.Y; y < sb.Max.Y; y++ {
+		for x := sb.Min.X; x < sb.Max.X; x++ {
+			c := color.NRGBA64Model.Convert(src.At(x, y)).(color.NRGBA64)
+			ch[0].pix[i] = float32(c.R) / 0xffff
+			ch[1].pix[i] = float32(c.G) / 0xffff
+			ch[2].pix[i] = float32(c.B) / 0xffff
+			if alpha.pix[i] == 0 {
+				transparent.pix[i] = 1
+				for c := range bg {
+					bg[c].pix[i] = ch[c].pix[i]
+				}
+			}
+			i++
+		}
+	}
+	meanT := boxMean(transparent, r)
+	for c := range bg {
+		bg[c] = boxMean(bg[c], r)
+	}
+
+	dst := image.NewNRGBA(sb)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			a := alpha.pix[i]
+			d := dst.Pix[y*dst.Stride+4*x:]
+			for c := 0; c < 3; c++ {
+				f := ch[c].pix[i]
+				if a > 0 && a < 1 && meanT.pix[i] > 0 {
+					b := bg[c].pix[i] / meanT.pix[i]
+					f = (f - (1-a)*b) / a
+				}
+				d[c] = unit8(f)
+			}
+			d[3] = unit8(a)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,150 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package matte
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// halfMask returns a w×h mask whose left half, x < w/2, is opaque.
+func halfMask(w, h int) *image.Alpha {
+	m := image.NewAlpha(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w/2; x++ {
+			m.SetAlpha(x, y, color.Alpha{0xff})
+		}
+	}
+	return m
+}
+
+func TestFeather(t *testing.T) {
+	m := Feather(halfMask(40, 4), 2)
+	if m.Bounds() != image.Rect(0, 0, 40, 4) {
+		t.Fatalf("bounds: got %v", m.Bounds())
+	}
+	row := m.Pix[m.Stride : m.Stride+40]
+	if row[0] != 0xff || row[39] != 0 {
+		t.Errorf("far from the edge: got %d and %d, want 255 and 0", row[0], row[39])
+	}
+	for x := 1; x < 40; x++ {
+		if row[x] > row[x-1] {
+			t.Fatalf("not monotonic at x=%d: %v", x, row)
+		}
+	}
+	if row[19] < 0x80 || row[19] > 0xc0 || row[20] < 0x40 || row[20] > 0x80 {
+		t.Errorf("at the edge: got %d, %d", row[19], row[20])
+	}
+}
+
+func TestTrimap(t *testing.T) {
+	tri := Trimap(halfMask(20, 3), 2)
+	want := []uint8{
+		Foreground, Foreground, Foreground, Foreground, Foreground,
+		Foreground, Foreground, Foreground, Unknown, Unknown,
+		Unknown, Unknown, Background, Background, Background,
+		Background, Background, Background, Background, Background,
+	}
+	for y := 0; y < 3; y++ {
+		for x, w := range want {
+			if got := tri.GrayAt(x, y).Y; got != w {
+				t.Fatalf("(%d, %d): got %#x, want %#x", x, y, got, w)
+			}
+		}
+	}
+
+	m := FeatherTrimap(tri, 1)
+	for x, w := range want {
+		got := m.AlphaAt(x, 1).A
+		switch w {
+		case Foreground:
+			if got != 0xff {
+				t.Errorf("FeatherTrimap: x=%d: got %d, want 255", x, got)
+			}
+		case Background:
+			if got != 0 {
+				t.Errorf("FeatherTrimap: x=%d: got %d, want 0", x, got)
+			}
+		default:
+			if got == 0 || got == 0xff || got > m.AlphaAt(x-1, 1).A {
+				t.Errorf("FeatherTrimap: x=%d: got %d", x, got)
+			}
+		}
+	}
+}
+
+func TestGuidedFilter(t *testing.T) {
+	// The guide has a sharp edge, and the matte is a blurred version of it.
+	const w, h = 40, 10
+	guide := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w/2; x++ {
+			guide.SetGray(x, y, color.Gray{0xe0})
+		}
+		for x := w / 2; x < w; x++ {
+			guide.SetGray(x, y, color.Gray{0x20})
+		}
+	}
+	blurred := Feather(halfMask(w, h), 3)
+	m := GuidedFilter(blurred, guide, 4, 1e-4)
+
+	// The filtered matte follows the guide's edge more closely.
+	for _, x := range []int{18, 19, 20, 21} {
+		want := 0
+		if x < w/2 {
+			want = 0xff
+		}
+		d0 := abs(int(blurred.AlphaAt(x, 5).A) - want)
+		d1 := abs(int(m.AlphaAt(x, 5).A) - want)
+		if d1 >= d0 {
+			t.Errorf("x=%d: error got %d, blurred error %d", x, d1, d0)
+		}
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestDecontaminate(t *testing.T) {
+	// A red foreground over a blue background, with a ramp of alpha between
+	// them.
+	const w, h = 20, 5
+	fg, bg := [3]float64{0xff, 0x20, 0x00}, [3]float64{0x00, 0x00, 0xff}
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	matte := image.NewAlpha(src.Bounds())
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := 1.0
+			if x >= 8 {
+				a = 1 - float64(x-7)/5
+				if a < 0 {
+					a = 0
+				}
+			}
+			var c [3]uint8
+			for i := range c {
+				c[i] = uint8(a*fg[i] + (1-a)*bg[i] + 0.5)
+			}
+			src.SetRGBA(x, y, color.RGBA{c[0], c[1], c[2], 0xff})
+			matte.SetAlpha(x, y, color.Alpha{uint8(a*0xff + 0.5)})
+		}
+	}
+
+	m := Decontaminate(src, matte, 6)
+	for x := 0; x < 12; x++ {
+		got := m.NRGBAAt(x, 2)
+		if got.A != matte.AlphaAt(x, 2).A {
+			t.Errorf("x=%d: alpha: got %d, want %d", x, got.A, matte.AlphaAt(x, 2).A)
+		}
+		if abs(int(got.R)-0xff) > 8 || abs(int(got.G)-0x20) > 8 || got.B > 8 {
+			t.Errorf("x=%d: got %v, want about {255 32 0}", x, got)
+		}
+	}
+}
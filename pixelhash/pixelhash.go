@@ -0,0 +1,165 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pixelhash fingerprints the decoded pixels of images, so that caches
+// and de-duplication systems can key on an image's content instead of on its
+// encoded bytes, which change with the file format and encoder options.
+//
+// The fingerprint is the SHA-256 hash of a canonical form of the image: its
+// width and height, followed by each pixel's alpha-premultiplied 16-bit red,
+// green, blue and alpha values, as returned by its color's RGBA method, in
+// row-major order. Two images therefore have the same fingerprint if and only
+// if (barring hash collisions) they have the same size and every pair of
+// corresponding pixels has the same RGBA values, whatever their Go types,
+// color models or bounds' origins. For example, an *image.Gray and an
+// *image.Gray16 with the same gray levels scaled to 16 bits have the same
+// fingerprint, as do two *image.NRGBA images whose only difference is the
+// color of their fully transparent pixels.
+package pixelhash // import "golang.org/x/image/pixelhash"
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"image"
+)
+
+// Size is the size, in bytes, of a fingerprint.
+const Size = sha256.Size
+
+// magic starts the canonical form, so that a change to the canonical form
+// changes every fingerprint.
+const magic = "pixelhash1\x00"
+
+var (
+	errSize     = errors.New("pixelhash: rows have the wrong width")
+	errTooLong  = errors.New("pixelhash: wrote more than the image height")
+	errTooShort = errors.New("pixelhash: sum before the image height was written")
+)
+
+// Sum returns the fingerprint of m.
+func Sum(m image.Image) [Size]byte {
+	b := m.Bounds()
+	h := NewHasher(b.Dx(), b.Dy())
+	h.WriteRows(m)
+	sum, _ := h.Sum()
+	return sum
+}
+
+// Hasher computes the fingerprint of an image that is supplied a band of rows
+// at a time, such as one that is decoded incrementally or that is too big to
+// hold in memory at once.
+type Hasher struct {
+	h             hash.Hash
+	width, height int
+	// y is the number of rows written.
+	y   int
+	buf []byte
+	err error
+}
+
+// NewHasher returns a Hasher for an image of the given width and height.
+func NewHasher(width, height int) *Hasher {
+	if width < 0 || height < 0 {
+		width, height = 0, 0
+	}
+	h := &Hasher{
+		h:      sha256.New(),
+		width:  width,
+		height: height,
+		buf:    make([]byte, 8*width),
+	}
+	var hdr [len(magic) + 16]byte
+	copy(hdr[:], magic)
+	binary.BigEndian.PutUint64(hdr[len(magic):], uint64(width))
+	binary.BigEndian.PutUint64(hdr[len(magic)+8:], uint64(height))
+	h.h.Write(hdr[:])
+	return h
+}
+
+// WriteRows writes the next m.Bounds().Dy() rows of the image, which must be
+// m.Bounds().Dx() == width pixels wide. The m image's bounds need not be at
+// the rows' coordinates: rows are taken in order from the top of m.
+func (h *Hasher) WriteRows(m image.Image) error {
+	if h.err != nil {
+		return h.err
+	}
+	b := m.Bounds()
+	if b.Empty() {
+		return nil
+	}
+	if b.Dx() != h.width {
+		h.err = errSize
+		return h.err
+	}
+	if b.Dy() > h.height-h.y {
+		h.err = errTooLong
+		return h.err
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		h.readRow(m, b.Min.X, y)
+		h.h.Write(h.buf)
+		h.y++
+	}
+	return nil
+}
+
+// Sum returns the fingerprint of the image. It returns an error if fewer rows
+// than the image height have been written, or if a previous WriteRows call
+// failed.
+func (h *Hasher) Sum() (sum [Size]byte, err error) {
+	if h.err != nil {
+		return sum, h.err
+	}
+	if h.y != h.height {
+		return sum, errTooShort
+	}
+	copy(sum[:], h.h.Sum(nil))
+	return sum, nil
+}
+
+// readRow reads m's row y, starting at column x0, into h.buf in the canonical
+// form.
+func (h *Hasher) readRow(m image.Image, x0, y int) {
+	buf := h.buf
+	switch m := m.(type) {
+	case *image.Gray:
+		pix := m.Pix[m.PixOffset(x0, y):]
+		for x := 0; x < h.width; x++ {
+			v := uint16(pix[x]) * 0x101
+			put(buf[8*x:], v, v, v, 0xffff)
+		}
+	case *image.RGBA:
+		pix := m.Pix[m.PixOffset(x0, y):]
+		for x := 0; x < h.width; x++ {
+			p := pix[4*x : 4*x+4]
+			put(buf[8*x:], uint16(p[0])*0x101, uint16(p[1])*0x101, uint16(p[2])*0x101, uint16(p[3])*0x101)
+		}
+	case *image.NRGBA:
+		pix := m.Pix[m.PixOffset(x0, y):]
+		for x := 0; x < h.width; x++ {
+			p := pix[4*x : 4*x+4]
+			// This is the same premultiplication as color.NRGBA's RGBA
+			// method.
+			a := uint32(p[3]) * 0x101
+			r := uint32(p[0]) * 0x101 * a / 0xffff
+			g := uint32(p[1]) * 0x101 * a / 0xffff
+			b := uint32(p[2]) * 0x101 * a / 0xffff
+			put(buf[8*x:], uint16(r), uint16(g), uint16(b), uint16(a))
+		}
+	default:
+		for x := 0; x < h.width; x++ {
+			r, g, b, a := m.At(x0+x, y).RGBA()
+			put(buf[8*x:], uint16(r), uint16(g), uint16(b), uint16(a))
+		}
+	}
+}
+
+func put(b []byte, r, g, bl, a uint16) {
+	binary.BigEndian.PutUint16(b[0:], r)
+	binary.BigEndian.PutUint16(b[2:], g)
+	binary.BigEndian.PutUint16(b[4:], bl)
+	binary.BigEndian.PutUint16(b[6:], a)
+}
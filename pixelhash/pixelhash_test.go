@@ -0,0 +1,150 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pixelhash
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// opaqueImage hides the type of an image, so that Hasher reads it with At.
+type opaqueImage struct {
+	image.Image
+}
+
+func testNRGBA() *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(3, 4, 8, 7))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(i * 37)
+	}
+	return m
+}
+
+func TestSumSameContent(t *testing.T) {
+	m := testNRGBA()
+	want := Sum(m)
+
+	rgba64 := image.NewRGBA64(image.Rect(-10, -10, -5, -7))
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			rgba64.Set(x-b.Min.X-10, y-b.Min.Y-10, m.At(x, y))
+		}
+	}
+	if Sum(opaqueImage{m}) != want {
+		t.Error("NRGBA and At: got different sums")
+	}
+	if Sum(rgba64) != want {
+		t.Error("NRGBA and RGBA64: got different sums")
+	}
+
+	// An opaque NRGBA image's pixels are also exactly those of an RGBA image.
+	for i := 3; i < len(m.Pix); i += 4 {
+		m.Pix[i] = 0xff
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 5, 3))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = m.Pix[i]
+	}
+	if Sum(rgba) != Sum(m) {
+		t.Error("opaque RGBA and NRGBA: got different sums")
+	}
+	if Sum(rgba) != Sum(opaqueImage{rgba}) {
+		t.Error("RGBA and At: got different sums")
+	}
+}
+
+func TestSumGray(t *testing.T) {
+	g := image.NewGray(image.Rect(0, 0, 4, 2))
+	g16 := image.NewGray16(image.Rect(1, 1, 5, 3))
+	for i := range g.Pix {
+		g.Pix[i] = uint8(i * 31)
+		g16.Pix[2*i], g16.Pix[2*i+1] = g.Pix[i], g.Pix[i]
+	}
+	if Sum(g) != Sum(g16) {
+		t.Error("Gray and Gray16: got different sums")
+	}
+	if Sum(g) != Sum(opaqueImage{g}) {
+		t.Error("Gray and At: got different sums")
+	}
+	g16.Pix[1]++
+	if Sum(g) == Sum(g16) {
+		t.Error("different Gray16: got the same sum")
+	}
+}
+
+func TestSumTransparent(t *testing.T) {
+	a := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	b := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := range b.Pix {
+		if i%4 != 3 {
+			b.Pix[i] = 0xff
+		}
+	}
+	if Sum(a) != Sum(b) {
+		t.Error("transparent pixels of different colors: got different sums")
+	}
+	b.Pix[3] = 1
+	if Sum(a) == Sum(b) {
+		t.Error("different alpha: got the same sum")
+	}
+}
+
+func TestSumSize(t *testing.T) {
+	// The images have the same pixels, in different shapes.
+	a := image.NewGray(image.Rect(0, 0, 4, 1))
+	b := image.NewGray(image.Rect(0, 0, 1, 4))
+	c := image.NewGray(image.Rect(0, 0, 2, 2))
+	if Sum(a) == Sum(b) || Sum(a) == Sum(c) || Sum(b) == Sum(c) {
+		t.Error("different sizes: got the same sum")
+	}
+	if Sum(image.NewGray(image.Rect(0, 0, 0, 3))) == Sum(image.NewGray(image.Rect(0, 0, 3, 0))) {
+		t.Error("different empty sizes: got the same sum")
+	}
+}
+
+func TestHasher(t *testing.T) {
+	m := testNRGBA()
+	want := Sum(m)
+	b := m.Bounds()
+
+	h := NewHasher(b.Dx(), b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		band := image.Rect(b.Min.X, y, b.Max.X, y+2).Intersect(b)
+		if err := h.WriteRows(m.SubImage(band)); err != nil {
+			t.Fatalf("WriteRows: %v", err)
+		}
+	}
+	if got, err := h.Sum(); err != nil || got != want {
+		t.Errorf("Sum: got %x, %v, want %x, nil", got, err, want)
+	}
+
+	// Errors.
+	h = NewHasher(b.Dx(), b.Dy())
+	if _, err := h.Sum(); err == nil {
+		t.Error("Sum before every row: got nil error")
+	}
+	if err := h.WriteRows(image.NewGray(image.Rect(0, 0, b.Dx()+1, 1))); err == nil {
+		t.Error("WriteRows of the wrong width: got nil error")
+	}
+	if err := h.WriteRows(m); err == nil {
+		t.Error("WriteRows after an error: got nil error")
+	}
+	h = NewHasher(b.Dx(), 1)
+	if err := h.WriteRows(image.NewGray(image.Rect(0, 0, b.Dx(), 2))); err == nil {
+		t.Error("WriteRows of too many rows: got nil error")
+	}
+}
+
+func TestSumPaletted(t *testing.T) {
+	p := image.NewPaletted(image.Rect(0, 0, 2, 1), color.Palette{color.Black, color.White})
+	p.Pix[1] = 1
+	g := image.NewGray(image.Rect(0, 0, 2, 1))
+	g.Pix[1] = 0xff
+	if Sum(p) != Sum(g) {
+		t.Error("Paletted and Gray: got different sums")
+	}
+}
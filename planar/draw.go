@@ -0,0 +1,147 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package planar
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Draw replaces the r part of dst with the part of src starting at sp, like
+// draw.Draw with the draw.Src operator. It has fast paths for converting
+// between the planar types and image.RGBA, and for copying between planar
+// images of the same type, and falls back to draw.Draw otherwise.
+func Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
+	// Clip r to dst's and src's bounds, as draw.Draw does.
+	orig := r.Min
+	r = r.Intersect(dst.Bounds())
+	r = r.Intersect(src.Bounds().Add(orig.Sub(sp)))
+	if r.Empty() {
+		return
+	}
+	sp = sp.Add(r.Min.Sub(orig))
+
+	switch d := dst.(type) {
+	case *RGB:
+		switch s := src.(type) {
+		case *image.RGBA:
+			deinterleave(r, sp, s, [4][]uint8{d.R, d.G, d.B}, d.PixOffset)
+			return
+		case *RGB:
+			if s != d {
+				copyPlanes(r, sp, [][]uint8{d.R, d.G, d.B}, d.Stride, d.PixOffset,
+					[][]uint8{s.R, s.G, s.B}, s.Stride, s.PixOffset)
+				return
+			}
+		}
+	case *RGBA:
+		switch s := src.(type) {
+		case *image.RGBA:
+			deinterleave(r, sp, s, [4][]uint8{d.R, d.G, d.B, d.A}, d.PixOffset)
+			return
+		case *RGBA:
+			if s != d {
+				copyPlanes(r, sp, [][]uint8{d.R, d.G, d.B, d.A}, d.Stride, d.PixOffset,
+					[][]uint8{s.R, s.G, s.B, s.A}, s.Stride, s.PixOffset)
+				return
+			}
+		}
+	case *image.RGBA:
+		switch s := src.(type) {
+		case *RGB:
+			interleave(r, sp, d, [4][]uint8{s.R, s.G, s.B}, s.PixOffset)
+			return
+		case *RGBA:
+			interleave(r, sp, d, [4][]uint8{s.R, s.G, s.B, s.A}, s.PixOffset)
+			return
+		}
+	}
+	draw.Draw(dst, r, src, sp, draw.Src)
+}
+
+// deinterleave copies the r part of dst, whose planes are given, from the
+// part of src starting at sp. A nil alpha plane is not copied.
+func deinterleave(r image.Rectangle, sp image.Point, src *image.RGBA, planes [4][]uint8, offset func(x, y int) int) {
+	w := r.Dx()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		d := offset(r.Min.X, y)
+		s := src.Pix[src.PixOffset(sp.X, sp.Y+y-r.Min.Y):]
+		for c, p := range planes {
+			if p == nil {
+				continue
+			}
+			row := p[d : d+w]
+			for x := range row {
+				row[x] = s[4*x+c]
+			}
+		}
+	}
+}
+
+// interleave copies the r part of dst from the part of src, whose planes are
+// given, starting at sp. A nil alpha plane means that src is opaque.
+func interleave(r image.Rectangle, sp image.Point, dst *image.RGBA, planes [4][]uint8, offset func(x, y int) int) {
+	w := r.Dx()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		d := dst.Pix[dst.PixOffset(r.Min.X, y):]
+		s := offset(sp.X, sp.Y+y-r.Min.Y)
+		for c, p := range planes {
+			if p == nil {
+				for x := 0; x < w; x++ {
+					d[4*x+c] = 0xff
+				}
+				continue
+			}
+			for x, v := range p[s : s+w] {
+				d[4*x+c] = v
+			}
+		}
+	}
+}
+
+// copyPlanes copies the r part of the dst planes from the part of the src
+// planes starting at sp.
+func copyPlanes(r image.Rectangle, sp image.Point, dst [][]uint8, dstStride int, dstOffset func(x, y int) int, src [][]uint8, srcStride int, srcOffset func(x, y int) int) {
+	w := r.Dx()
+	d, s := dstOffset(r.Min.X, r.Min.Y), srcOffset(sp.X, sp.Y)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for c := range dst {
+			copy(dst[c][d:d+w], src[c][s:s+w])
+		}
+		d += dstStride
+		s += srcStride
+	}
+}
+
+// NewRGBFrom returns a new RGB image with the bounds and the pixels of m.
+// Pixels that are not opaque are composited over black.
+func NewRGBFrom(m image.Image) *RGB {
+	b := m.Bounds()
+	p := NewRGB(b)
+	Draw(p, b, m, b.Min)
+	return p
+}
+
+// NewRGBAFrom returns a new RGBA image with the bounds and the pixels of m.
+func NewRGBAFrom(m image.Image) *RGBA {
+	b := m.Bounds()
+	p := NewRGBA(b)
+	Draw(p, b, m, b.Min)
+	return p
+}
+
+// Interleaved returns a new image.RGBA with the bounds and the pixels of p.
+func (p *RGB) Interleaved() *image.RGBA {
+	m := image.NewRGBA(p.Rect)
+	Draw(m, p.Rect, p, p.Rect.Min)
+	return m
+}
+
+// Interleaved returns a new image.RGBA with the bounds and the pixels of p.
+func (p *RGBA) Interleaved() *image.RGBA {
+	m := image.NewRGBA(p.Rect)
+	Draw(m, p.Rect, p, p.Rect.Min)
+	return m
+}
@@ -0,0 +1,209 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package planar implements images whose channels are stored in separate
+// planes, rather than interleaved, for scientific processing and GPU upload
+// of one channel at a time.
+//
+// Each plane is contiguous, and an image's planes share a single backing
+// array, one after another, when the image is allocated by NewRGB or
+// NewRGBA.
+package planar // import "golang.org/x/image/planar"
+
+import (
+	"image"
+	"image/color"
+)
+
+// RGB is an in-memory image with separate red, green and blue planes. It is
+// opaque, and its At method returns color.RGBA values.
+type RGB struct {
+	// R, G and B hold the image's planes. The pixel at (x, y) has its
+	// samples at index (y-Rect.Min.Y)*Stride + (x-Rect.Min.X) of each plane.
+	R, G, B []uint8
+	// Stride is the plane stride (in bytes) between vertically adjacent
+	// pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+func (p *RGB) ColorModel() color.Model { return color.RGBAModel }
+
+func (p *RGB) Bounds() image.Rectangle { return p.Rect }
+
+func (p *RGB) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+func (p *RGB) RGBAAt(x, y int) color.RGBA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return color.RGBA{}
+	}
+	i := p.PixOffset(x, y)
+	return color.RGBA{p.R[i], p.G[i], p.B[i], 0xff}
+}
+
+// PixOffset returns the index of the samples of the pixel at (x, y) within
+// each plane.
+func (p *RGB) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x - p.Rect.Min.X)
+}
+
+// Set sets the pixel at (x, y) to c. As an RGB image is opaque, c's alpha is
+// dropped, which is the same as compositing c over black.
+func (p *RGB) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+func (p *RGB) SetRGBA(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.R[i], p.G[i], p.B[i] = c.R, c.G, c.B
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *RGB) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be
+	// inside either r1 or r2 if the intersection is empty. Without explicitly
+	// checking for this, the Pix[i:] expressions below can panic.
+	if r.Empty() {
+		return &RGB{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGB{
+		R:      p.R[i:],
+		G:      p.G[i:],
+		B:      p.B[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *RGB) Opaque() bool {
+	return true
+}
+
+// NewRGB returns a new RGB image with the given bounds.
+func NewRGB(r image.Rectangle) *RGB {
+	w, h := r.Dx(), r.Dy()
+	pix := make([]uint8, 3*w*h)
+	return &RGB{
+		R:      pix[0*w*h : 1*w*h : 1*w*h],
+		G:      pix[1*w*h : 2*w*h : 2*w*h],
+		B:      pix[2*w*h : 3*w*h : 3*w*h],
+		Stride: w,
+		Rect:   r,
+	}
+}
+
+// RGBA is an in-memory image with separate red, green, blue and alpha
+// planes. Its At method returns color.RGBA values, and so its color
+// samples are alpha-premultiplied, as for image.RGBA.
+type RGBA struct {
+	// R, G, B and A hold the image's planes. The pixel at (x, y) has its
+	// samples at index (y-Rect.Min.Y)*Stride + (x-Rect.Min.X) of each plane.
+	R, G, B, A []uint8
+	// Stride is the plane stride (in bytes) between vertically adjacent
+	// pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+func (p *RGBA) ColorModel() color.Model { return color.RGBAModel }
+
+func (p *RGBA) Bounds() image.Rectangle { return p.Rect }
+
+func (p *RGBA) At(x, y int) color.Color {
+	return p.RGBAAt(x, y)
+}
+
+func (p *RGBA) RGBAAt(x, y int) color.RGBA {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return color.RGBA{}
+	}
+	i := p.PixOffset(x, y)
+	return color.RGBA{p.R[i], p.G[i], p.B[i], p.A[i]}
+}
+
+// PixOffset returns the index of the samples of the pixel at (x, y) within
+// each plane.
+func (p *RGBA) PixOffset(x, y int) int {
+	return (y-p.Rect.Min.Y)*p.Stride + (x - p.Rect.Min.X)
+}
+
+func (p *RGBA) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	p.SetRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+func (p *RGBA) SetRGBA(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(p.Rect)) {
+		return
+	}
+	i := p.PixOffset(x, y)
+	p.R[i], p.G[i], p.B[i], p.A[i] = c.R, c.G, c.B, c.A
+}
+
+// SubImage returns an image representing the portion of the image p visible
+// through r. The returned value shares pixels with the original image.
+func (p *RGBA) SubImage(r image.Rectangle) image.Image {
+	r = r.Intersect(p.Rect)
+	// If r1 and r2 are Rectangles, r1.Intersect(r2) is not guaranteed to be
+	// inside either r1 or r2 if the intersection is empty. Without explicitly
+	// checking for this, the Pix[i:] expressions below can panic.
+	if r.Empty() {
+		return &RGBA{}
+	}
+	i := p.PixOffset(r.Min.X, r.Min.Y)
+	return &RGBA{
+		R:      p.R[i:],
+		G:      p.G[i:],
+		B:      p.B[i:],
+		A:      p.A[i:],
+		Stride: p.Stride,
+		Rect:   r,
+	}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (p *RGBA) Opaque() bool {
+	if p.Rect.Empty() {
+		return true
+	}
+	w := p.Rect.Dx()
+	for y, i := 0, 0; y < p.Rect.Dy(); y, i = y+1, i+p.Stride {
+		for _, a := range p.A[i : i+w] {
+			if a != 0xff {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// NewRGBA returns a new RGBA image with the given bounds.
+func NewRGBA(r image.Rectangle) *RGBA {
+	w, h := r.Dx(), r.Dy()
+	pix := make([]uint8, 4*w*h)
+	return &RGBA{
+		R:      pix[0*w*h : 1*w*h : 1*w*h],
+		G:      pix[1*w*h : 2*w*h : 2*w*h],
+		B:      pix[2*w*h : 3*w*h : 3*w*h],
+		A:      pix[3*w*h : 4*w*h : 4*w*h],
+		Stride: w,
+		Rect:   r,
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package planar
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestRGBA(t *testing.T) {
+	r := image.Rect(2, 3, 12, 9)
+	p := NewRGBA(r)
+	if len(p.R) != 60 || len(p.A) != 60 || p.Stride != 10 {
+		t.Fatalf("got planes of %d and %d samples, stride %d", len(p.R), len(p.A), p.Stride)
+	}
+	if p.Opaque() {
+		t.Error("new image: got opaque")
+	}
+	c := color.RGBA{0x10, 0x20, 0x30, 0x40}
+	p.Set(5, 6, c)
+	if got := p.At(5, 6); got != c {
+		t.Errorf("At: got %v, want %v", got, c)
+	}
+	if got := p.At(1, 1); got != (color.RGBA{}) {
+		t.Errorf("At outside the bounds: got %v", got)
+	}
+	sub := p.SubImage(image.Rect(4, 5, 7, 8)).(*RGBA)
+	if got := sub.At(5, 6); got != c {
+		t.Errorf("SubImage At: got %v, want %v", got, c)
+	}
+	sub.Set(6, 7, color.White)
+	if got := p.At(6, 7); got != (color.RGBA{0xff, 0xff, 0xff, 0xff}) {
+		t.Errorf("SubImage shares pixels: got %v", got)
+	}
+	if !NewRGBFrom(p).Opaque() {
+		t.Error("RGB: got not opaque")
+	}
+	if s := p.SubImage(image.Rect(50, 50, 60, 60)); !s.Bounds().Empty() {
+		t.Errorf("empty SubImage: got %v", s.Bounds())
+	}
+}
+
+func TestRGBSet(t *testing.T) {
+	p := NewRGB(image.Rect(0, 0, 2, 2))
+	p.Set(1, 1, color.NRGBA{0xff, 0x80, 0x00, 0x80})
+	want := color.RGBA{0x80, 0x40, 0x00, 0xff}
+	if got := p.At(1, 1); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// testImage returns an image.RGBA with distinct, non-opaque pixels.
+func testImage(r image.Rectangle) *image.RGBA {
+	m := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			a := uint8(0x80 + (x*y)&0x7f)
+			m.SetRGBA(x, y, color.RGBA{uint8(x*7) % a, uint8(y*13) % a, uint8(x+y) % a, a})
+		}
+	}
+	return m
+}
+
+// TestDraw checks that the fast paths of Draw match draw.Draw.
+func TestDraw(t *testing.T) {
+	src := testImage(image.Rect(-3, 4, 30, 25))
+	dr := image.Rect(5, 2, 40, 18)
+	sp := image.Pt(-1, 6)
+	newImages := func() []draw.Image {
+		return []draw.Image{
+			image.NewRGBA(image.Rect(0, 0, 32, 20)),
+			NewRGB(image.Rect(0, 0, 32, 20)),
+			NewRGBA(image.Rect(0, 0, 32, 20)),
+			// A sub-image, with a stride wider than its width.
+			NewRGBA(image.Rect(-10, -10, 40, 40)).SubImage(image.Rect(0, 0, 32, 20)).(draw.Image),
+		}
+	}
+	srcs := []image.Image{src, NewRGBFrom(src), NewRGBAFrom(src)}
+	for _, s := range srcs {
+		for _, dst := range newImages() {
+			want := newImages()[0]
+			// Fill both with a pattern, to check that only dr is replaced.
+			draw.Draw(dst, dst.Bounds(), testImage(dst.Bounds()), dst.Bounds().Min, draw.Src)
+			draw.Draw(want, want.Bounds(), dst, dst.Bounds().Min, draw.Src)
+			draw.Draw(want, dr, s, sp, draw.Src)
+			Draw(dst, dr, s, sp)
+
+			if _, ok := dst.(*RGB); ok {
+				// An RGB image drops the alpha.
+				drop := image.NewRGBA(want.Bounds())
+				draw.Draw(drop, drop.Bounds(), NewRGBFrom(want), image.Point{}, draw.Src)
+				want = drop
+			}
+			b := dst.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					if got, w := dst.At(x, y), want.At(x, y); got != w {
+						t.Fatalf("src %T, dst %T: (%d, %d): got %v, want %v", s, dst, x, y, got, w)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestInterleaved(t *testing.T) {
+	src := testImage(image.Rect(1, 2, 9, 7))
+	got := NewRGBAFrom(src).Interleaved()
+	if got.Rect != src.Rect || string(got.Pix) != string(src.Pix) {
+		t.Error("RGBA round trip differs")
+	}
+	got = NewRGBFrom(src).Interleaved()
+	for i := 3; i < len(got.Pix); i += 4 {
+		if got.Pix[i] != 0xff {
+			t.Fatal("RGB: got non-opaque pixel")
+		}
+	}
+}
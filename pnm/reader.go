@@ -0,0 +1,425 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pnm implements a decoder and encoder for the Netpbm image formats:
+// PBM (bitmaps), PGM (grayscale), PPM (color) and PAM (arbitrary tuples,
+// such as color with alpha).
+//
+// Both the plain (ASCII) and raw (binary) variants of PBM, PGM and PPM are
+// supported, with maximum sample values of up to 65535.
+//
+// The formats are described at http://netpbm.sourceforge.net/doc/
+package pnm // import "golang.org/x/image/pnm"
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// A FormatError reports that the input is not a valid Netpbm image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "pnm: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "pnm: unsupported feature: " + string(e)
+}
+
+// maxSamples is the largest number of samples, width × height × depth, of an
+// image that Decode accepts.
+const maxSamples = 1 << 32
+
+// header is the decoded header of a Netpbm image.
+type header struct {
+	// magic is the second byte of the magic number, '1' to '7'.
+	magic         byte
+	width, height int
+	// depth is the number of samples per pixel: 1 for grayscale, 2 for
+	// grayscale with alpha, 3 for color and 4 for color with alpha.
+	depth  int
+	maxval int
+}
+
+func (h *header) plain() bool {
+	return h.magic <= '3'
+}
+
+func (h *header) bitmap() bool {
+	return h.magic == '1' || h.magic == '4'
+}
+
+func (h *header) colorModel() color.Model {
+	wide := h.maxval > 0xff
+	switch h.depth {
+	case 1:
+		if wide {
+			return color.Gray16Model
+		}
+		return color.GrayModel
+	case 3:
+		if wide {
+			return color.RGBA64Model
+		}
+		return color.RGBAModel
+	}
+	if wide {
+		return color.NRGBA64Model
+	}
+	return color.NRGBAModel
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\v' || c == '\f' || c == '\r'
+}
+
+// skipSpace skips whitespace and comments, which run from a '#' to the end
+// of the line.
+func skipSpace(r *bufio.Reader) error {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if c == '#' {
+			if _, err := r.ReadSlice('\n'); err != nil && err != bufio.ErrBufferFull {
+				return err
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return r.UnreadByte()
+		}
+	}
+}
+
+// readInt reads a decimal integer, preceded by whitespace and comments, and
+// the single whitespace character that follows it, if any.
+func readInt(r *bufio.Reader) (int, error) {
+	if err := skipSpace(r); err != nil {
+		return 0, err
+	}
+	n, digits := 0, 0
+	for {
+		c, err := r.ReadByte()
+		if err == io.EOF && digits > 0 {
+			return n, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if c < '0' || c > '9' {
+			if digits == 0 || !isSpace(c) {
+				return 0, FormatError("bad number")
+			}
+			return n, nil
+		}
+		if n > 1<<24 {
+			return 0, FormatError("number too large")
+		}
+		n = 10*n + int(c-'0')
+		digits++
+	}
+}
+
+// readHeader reads the header of a Netpbm image, up to and including the
+// single whitespace character that precedes the image data.
+func readHeader(r *bufio.Reader) (*header, error) {
+	var magic [2]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+	if magic[0] != 'P' || magic[1] < '1' || magic[1] > '7' {
+		return nil, FormatError("bad magic number")
+	}
+	h := &header{magic: magic[1], depth: 1, maxval: 1}
+	if h.magic == '7' {
+		if err := readPAMHeader(r, h); err != nil {
+			return nil, err
+		}
+	} else {
+		if h.magic == '3' || h.magic == '6' {
+			h.depth = 3
+		}
+		var err error
+		if h.width, err = readInt(r); err == nil {
+			h.height, err = readInt(r)
+		}
+		if err == nil && !h.bitmap() {
+			h.maxval, err = readInt(r)
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	if h.maxval < 1 || h.maxval > 0xffff {
+		return nil, FormatError("bad maximum value")
+	}
+	if int64(h.width)*int64(h.height)*int64(h.depth) > maxSamples {
+		return nil, UnsupportedError("image too large")
+	}
+	return h, nil
+}
+
+// readPAMHeader reads the header lines of a PAM image, which follow the
+// magic number, up to and including the ENDHDR line.
+func readPAMHeader(r *bufio.Reader, h *header) error {
+	seen := map[string]bool{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		var fields []string
+		for i := 0; i < len(line); {
+			for i < len(line) && isSpace(line[i]) {
+				i++
+			}
+			j := i
+			for j < len(line) && !isSpace(line[j]) {
+				j++
+			}
+			if i < j {
+				fields = append(fields, line[i:j])
+			}
+			i = j
+		}
+		if len(fields) == 0 || fields[0][0] == '#' {
+			continue
+		}
+		key := fields[0]
+		if key == "ENDHDR" {
+			break
+		}
+		if key == "TUPLTYPE" {
+			// The tuple type only names the interpretation of the samples,
+			// which follows from the depth for the supported types.
+			continue
+		}
+		var v *int
+		switch key {
+		case "WIDTH":
+			v = &h.width
+		case "HEIGHT":
+			v = &h.height
+		case "DEPTH":
+			v = &h.depth
+		case "MAXVAL":
+			v = &h.maxval
+		default:
+			return FormatError("unknown PAM header field " + strconv.Quote(key))
+		}
+		if len(fields) != 2 {
+			return FormatError("bad PAM header line")
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 0 || n > 1<<24 {
+			return FormatError("bad PAM header value")
+		}
+		*v = n
+		seen[key] = true
+	}
+	for _, key := range []string{"WIDTH", "HEIGHT", "DEPTH", "MAXVAL"} {
+		if !seen[key] {
+			return FormatError("missing PAM header field " + key)
+		}
+	}
+	if h.depth < 1 || h.depth > 4 {
+		return UnsupportedError("PAM depth")
+	}
+	return nil
+}
+
+// readSamples reads the w×h×depth samples of the image data into samples,
+// scaled from [0, maxval] to [0, 0xffff]. Bitmap samples are inverted, as a
+// PBM 1 is black.
+func readSamples(r *bufio.Reader, h *header, samples []uint16) error {
+	switch {
+	case h.magic == '1':
+		for i := range samples {
+			if err := skipSpace(r); err != nil {
+				return err
+			}
+			// The digits of plain PBM images need not be separated.
+			c, _ := r.ReadByte()
+			switch c {
+			case '0':
+				samples[i] = 0xffff
+			case '1':
+				samples[i] = 0
+			default:
+				return FormatError("bad bitmap digit")
+			}
+		}
+	case h.magic == '4':
+		row := make([]byte, (h.width+7)/8)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(r, row); err != nil {
+				return err
+			}
+			for x := 0; x < h.width; x++ {
+				if row[x/8]&(0x80>>uint(x%8)) == 0 {
+					samples[y*h.width+x] = 0xffff
+				} else {
+					samples[y*h.width+x] = 0
+				}
+			}
+		}
+	case h.plain():
+		for i := range samples {
+			v, err := readInt(r)
+			if err != nil {
+				return err
+			}
+			if v > h.maxval {
+				return FormatError("sample exceeds the maximum value")
+			}
+			samples[i] = scale(v, h.maxval)
+		}
+	default:
+		n := 1
+		if h.maxval > 0xff {
+			n = 2
+		}
+		buf := make([]byte, h.width*h.depth*n)
+		for y := 0; y < h.height; y++ {
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return err
+			}
+			s := samples[y*len(buf)/n:]
+			for i := range buf[:len(buf)/n] {
+				v := int(buf[i])
+				if n == 2 {
+					v = int(buf[2*i])<<8 | int(buf[2*i+1])
+				}
+				if v > h.maxval {
+					return FormatError("sample exceeds the maximum value")
+				}
+				s[i] = scale(v, h.maxval)
+			}
+		}
+	}
+	return nil
+}
+
+// scale scales v from [0, maxval] to [0, 0xffff].
+func scale(v, maxval int) uint16 {
+	return uint16((v*0xffff + maxval/2) / maxval)
+}
+
+// Decode reads a Netpbm image from r and returns it as an image.Image. The
+// type of Image returned depends on the depth and the maximum sample value:
+// an *image.Gray, *image.RGBA or *image.NRGBA for grayscale, color and
+// color with alpha images whose maximum value is at most 255, and an
+// *image.Gray16, *image.RGBA64 or *image.NRGBA64 otherwise. PAM images with
+// a depth of 2 are grayscale with alpha, and are returned as color with
+// alpha.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]uint16, h.width*h.height*h.depth)
+	if err := readSamples(br, h, samples); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	rect := image.Rect(0, 0, h.width, h.height)
+	wide := h.maxval > 0xff
+	switch h.depth {
+	case 1:
+		if wide {
+			m := image.NewGray16(rect)
+			for i, v := range samples {
+				m.Pix[2*i], m.Pix[2*i+1] = uint8(v>>8), uint8(v)
+			}
+			return m, nil
+		}
+		m := image.NewGray(rect)
+		for i, v := range samples {
+			m.Pix[i] = uint8(v >> 8)
+		}
+		return m, nil
+	}
+
+	// Expand the samples to RGBA.
+	var m image.Image
+	var pix []byte
+	switch {
+	case h.depth == 3 && wide:
+		c := image.NewRGBA64(rect)
+		m, pix = c, c.Pix
+	case h.depth == 3:
+		c := image.NewRGBA(rect)
+		m, pix = c, c.Pix
+	case wide:
+		c := image.NewNRGBA64(rect)
+		m, pix = c, c.Pix
+	default:
+		c := image.NewNRGBA(rect)
+		m, pix = c, c.Pix
+	}
+	for i := 0; i < h.width*h.height; i++ {
+		s := samples[i*h.depth : (i+1)*h.depth]
+		var rgba [4]uint16
+		switch h.depth {
+		case 2:
+			rgba = [4]uint16{s[0], s[0], s[0], s[1]}
+		case 3:
+			rgba = [4]uint16{s[0], s[1], s[2], 0xffff}
+		case 4:
+			rgba = [4]uint16{s[0], s[1], s[2], s[3]}
+		}
+		for j, v := range rgba {
+			if wide {
+				pix[8*i+2*j], pix[8*i+2*j+1] = uint8(v>>8), uint8(v)
+			} else {
+				pix[4*i+j] = uint8(v >> 8)
+			}
+		}
+	}
+	return m, nil
+}
+
+// DecodeConfig returns the color model and dimensions of a Netpbm image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: h.colorModel(),
+		Width:      h.width,
+		Height:     h.height,
+	}, nil
+}
+
+func init() {
+	image.RegisterFormat("pbm", "P1", Decode, DecodeConfig)
+	image.RegisterFormat("pgm", "P2", Decode, DecodeConfig)
+	image.RegisterFormat("ppm", "P3", Decode, DecodeConfig)
+	image.RegisterFormat("pbm", "P4", Decode, DecodeConfig)
+	image.RegisterFormat("pgm", "P5", Decode, DecodeConfig)
+	image.RegisterFormat("ppm", "P6", Decode, DecodeConfig)
+	image.RegisterFormat("pam", "P7", Decode, DecodeConfig)
+}
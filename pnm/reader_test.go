@@ -0,0 +1,134 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pnm
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name  string
+		data  string
+		model color.Model
+		want  []color.Color
+	}{{
+		"plain PBM",
+		"P1\n# a comment\n3 1\n0 10",
+		color.GrayModel,
+		[]color.Color{color.Gray{0xff}, color.Gray{0x00}, color.Gray{0xff}},
+	}, {
+		"raw PBM",
+		"P4 3 1\n\xa0",
+		color.GrayModel,
+		[]color.Color{color.Gray{0x00}, color.Gray{0xff}, color.Gray{0x00}},
+	}, {
+		"plain PGM",
+		"P2 3 1 15\n0 # a comment\n 5 15\n",
+		color.GrayModel,
+		[]color.Color{color.Gray{0x00}, color.Gray{0x55}, color.Gray{0xff}},
+	}, {
+		"raw PGM",
+		"P5\n3 1\n255\n\x00\x80\xff",
+		color.GrayModel,
+		[]color.Color{color.Gray{0x00}, color.Gray{0x80}, color.Gray{0xff}},
+	}, {
+		"raw 16-bit PGM",
+		"P5 2 1 65535\n\x12\x34\xff\xff",
+		color.Gray16Model,
+		[]color.Color{color.Gray16{0x1234}, color.Gray16{0xffff}},
+	}, {
+		"plain PPM",
+		"P3 1 1 255 1 2 3",
+		color.RGBAModel,
+		[]color.Color{color.RGBA{1, 2, 3, 0xff}},
+	}, {
+		"raw PPM",
+		"P6 2 1 255\n\x01\x02\x03\x04\x05\x06",
+		color.RGBAModel,
+		[]color.Color{color.RGBA{1, 2, 3, 0xff}, color.RGBA{4, 5, 6, 0xff}},
+	}, {
+		"raw 16-bit PPM",
+		"P6 1 1 1000\n\x03\xe8\x01\xf4\x00\x00",
+		color.RGBA64Model,
+		[]color.Color{color.RGBA64{0xffff, 0x8000, 0x0000, 0xffff}},
+	}, {
+		"PAM with alpha",
+		"P7\nWIDTH 1\nHEIGHT 1\nDEPTH 4\nMAXVAL 255\nTUPLTYPE RGB_ALPHA\nENDHDR\n\x10\x20\x30\x40",
+		color.NRGBAModel,
+		[]color.Color{color.NRGBA{0x10, 0x20, 0x30, 0x40}},
+	}, {
+		"PAM grayscale with alpha",
+		"P7\n# a comment\nWIDTH 1\nHEIGHT 1\nDEPTH 2\nMAXVAL 255\nENDHDR\n\x10\x80",
+		color.NRGBAModel,
+		[]color.Color{color.NRGBA{0x10, 0x10, 0x10, 0x80}},
+	}}
+	for _, tc := range testCases {
+		m, err := Decode(strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if m.ColorModel() != tc.model {
+			t.Errorf("%s: color model: got %v, want %v", tc.name, m.ColorModel(), tc.model)
+		}
+		if got, want := m.Bounds().Dx(), len(tc.want); got != want {
+			t.Errorf("%s: width: got %d, want %d", tc.name, got, want)
+			continue
+		}
+		for x, want := range tc.want {
+			if got := m.At(x, 0); got != want {
+				t.Errorf("%s: pixel %d: got %v, want %v", tc.name, x, got, want)
+			}
+		}
+		c, err := DecodeConfig(strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: DecodeConfig: %v", tc.name, err)
+		} else if c.ColorModel != tc.model || c.Width != len(tc.want) || c.Height != 1 {
+			t.Errorf("%s: DecodeConfig: got %v", tc.name, c)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"bad magic", "P8 1 1 255\n\x00", FormatError("bad magic number")},
+		{"truncated header", "P5 1", io.ErrUnexpectedEOF},
+		{"truncated data", "P5 2 2 255\n\x00\x00", io.ErrUnexpectedEOF},
+		{"zero maxval", "P5 1 1 0\n\x00", FormatError("bad maximum value")},
+		{"sample above maxval", "P2 1 1 10\n11\n", FormatError("sample exceeds the maximum value")},
+		{"bad bitmap digit", "P1 1 1\n2", FormatError("bad bitmap digit")},
+		{"missing PAM field", "P7\nWIDTH 1\nHEIGHT 1\nMAXVAL 255\nENDHDR\n\x00", FormatError("missing PAM header field DEPTH")},
+		{"PAM depth", "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 5\nMAXVAL 255\nENDHDR\n", UnsupportedError("PAM depth")},
+		{"too large", "P5 16777216 16777216 255\n", UnsupportedError("image too large")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(strings.NewReader(tc.data)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestDecodeRegistered(t *testing.T) {
+	for _, tc := range []struct{ data, format string }{
+		{"P1 1 1 0", "pbm"},
+		{"P5 1 1 255\n\x00", "pgm"},
+		{"P6 1 1 255\n\x00\x00\x00", "ppm"},
+		{"P7\nWIDTH 1\nHEIGHT 1\nDEPTH 1\nMAXVAL 255\nENDHDR\n\x00", "pam"},
+	} {
+		_, format, err := image.Decode(strings.NewReader(tc.data))
+		if err != nil || format != tc.format {
+			t.Errorf("%q: got %q, %v, want %q", tc.data, format, err, tc.format)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pnm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Plain is whether to write the plain (ASCII) variant of PGM or PPM,
+	// instead of the raw (binary) variant. PAM has no plain variant, so
+	// Plain is an error for images that are written as PAM.
+	Plain bool
+	// PAM is whether to write every image as PAM. Otherwise, only images
+	// with alpha are written as PAM.
+	PAM bool
+}
+
+// Encode writes the image m to w in a Netpbm format. A nil opts is
+// equivalent to a zero Options.
+//
+// Grayscale images, *image.Gray and *image.Gray16, are written as PGM.
+// Other opaque images are written as PPM, and images with alpha as PAM, with
+// non-premultiplied alpha. The maximum sample value is 65535 for images with
+// 16-bit samples, such as *image.Gray16 and *image.RGBA64, and 255 for other
+// images.
+func Encode(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	b := m.Bounds()
+
+	depth, maxval := 3, 0xff
+	switch m.(type) {
+	case *image.Gray:
+		depth = 1
+	case *image.Gray16:
+		depth, maxval = 1, 0xffff
+	case *image.RGBA64, *image.NRGBA64:
+		maxval = 0xffff
+	}
+	if op, ok := m.(interface{ Opaque() bool }); depth == 3 && (!ok || !op.Opaque()) {
+		depth = 4
+	}
+	pam := o.PAM || depth == 4
+	if pam && o.Plain {
+		return errors.New("pnm: PAM has no plain variant")
+	}
+
+	bw := bufio.NewWriter(w)
+	if pam {
+		tupleType := "RGB_ALPHA"
+		switch depth {
+		case 1:
+			tupleType = "GRAYSCALE"
+		case 3:
+			tupleType = "RGB"
+		}
+		fmt.Fprintf(bw, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH %d\nMAXVAL %d\nTUPLTYPE %s\nENDHDR\n",
+			b.Dx(), b.Dy(), depth, maxval, tupleType)
+	} else {
+		magic := 6
+		if depth == 1 {
+			magic = 5
+		}
+		if o.Plain {
+			magic -= 3
+		}
+		fmt.Fprintf(bw, "P%d\n%d %d\n%d\n", magic, b.Dx(), b.Dy(), maxval)
+	}
+
+	// Samples are written row by row. Plain rows are split into lines of at
+	// most 70 characters, as the specification recommends.
+	samples := make([]uint16, 0, b.Dx()*depth)
+	var line []byte
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		samples = samples[:0]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := nrgba64At(m, x, y)
+			switch depth {
+			case 1:
+				samples = append(samples, c.R)
+			case 3:
+				samples = append(samples, c.R, c.G, c.B)
+			case 4:
+				samples = append(samples, c.R, c.G, c.B, c.A)
+			}
+		}
+		for _, v := range samples {
+			if maxval == 0xff {
+				v >>= 8
+			}
+			switch {
+			case o.Plain:
+				s := strconv.Itoa(int(v))
+				if len(line) > 0 && len(line)+1+len(s) > 70 {
+					bw.Write(append(line, '\n'))
+					line = line[:0]
+				}
+				if len(line) > 0 {
+					line = append(line, ' ')
+				}
+				line = append(line, s...)
+			case maxval == 0xffff:
+				bw.WriteByte(uint8(v >> 8))
+				bw.WriteByte(uint8(v))
+			default:
+				bw.WriteByte(uint8(v))
+			}
+		}
+		if o.Plain && len(line) > 0 {
+			bw.Write(append(line, '\n'))
+			line = line[:0]
+		}
+	}
+	return bw.Flush()
+}
+
+// nrgba64At returns the non-premultiplied color of m at (x, y), without the
+// loss of precision of converting non-premultiplied colors to premultiplied
+// ones and back.
+func nrgba64At(m image.Image, x, y int) color.NRGBA64 {
+	switch m := m.(type) {
+	case *image.NRGBA:
+		c := m.NRGBAAt(x, y)
+		return color.NRGBA64{
+			uint16(c.R) * 0x101, uint16(c.G) * 0x101, uint16(c.B) * 0x101, uint16(c.A) * 0x101,
+		}
+	case *image.NRGBA64:
+		return m.NRGBA64At(x, y)
+	}
+	return color.NRGBA64Model.Convert(m.At(x, y)).(color.NRGBA64)
+}
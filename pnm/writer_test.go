@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pnm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	r := image.Rect(2, 3, 40, 20)
+	gray := image.NewGray(r)
+	gray16 := image.NewGray16(r)
+	rgba := image.NewRGBA(r)
+	rgba64 := image.NewRGBA64(r)
+	nrgba := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := uint8(x*7 + y*3)
+			gray.SetGray(x, y, color.Gray{v})
+			gray16.SetGray16(x, y, color.Gray16{uint16(x*1234 + y)})
+			rgba.SetRGBA(x, y, color.RGBA{v, 0xff - v, v / 2, 0xff})
+			rgba64.SetRGBA64(x, y, color.RGBA64{uint16(x * 999), uint16(y * 777), 0x1234, 0xffff})
+			nrgba.SetNRGBA(x, y, color.NRGBA{v, 0xff - v, v / 2, uint8(y * 10)})
+		}
+	}
+
+	testCases := []struct {
+		name  string
+		m     image.Image
+		opts  *Options
+		magic string
+	}{
+		{"gray", gray, nil, "P5"},
+		{"gray plain", gray, &Options{Plain: true}, "P2"},
+		{"gray PAM", gray, &Options{PAM: true}, "P7"},
+		{"gray16", gray16, nil, "P5"},
+		{"gray16 plain", gray16, &Options{Plain: true}, "P2"},
+		{"rgba", rgba, nil, "P6"},
+		{"rgba plain", rgba, &Options{Plain: true}, "P3"},
+		{"rgba64", rgba64, nil, "P6"},
+		{"nrgba", nrgba, nil, "P7"},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := Encode(&buf, tc.m, tc.opts); err != nil {
+			t.Errorf("%s: Encode: %v", tc.name, err)
+			continue
+		}
+		if !strings.HasPrefix(buf.String(), tc.magic) {
+			t.Errorf("%s: got magic %q, want %q", tc.name, buf.String()[:2], tc.magic)
+		}
+		if tc.opts != nil && tc.opts.Plain {
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if len(line) > 70 {
+					t.Errorf("%s: line of %d characters", tc.name, len(line))
+					break
+				}
+			}
+		}
+		m, err := Decode(&buf)
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tc.name, err)
+			continue
+		}
+		if m.ColorModel() != tc.m.ColorModel() {
+			t.Errorf("%s: color model: got %v, want %v", tc.name, m.ColorModel(), tc.m.ColorModel())
+		}
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				got, want := m.At(x, y), tc.m.At(r.Min.X+x, r.Min.Y+y)
+				if got != want {
+					t.Fatalf("%s: (%d, %d): got %v, want %v", tc.name, x, y, got, want)
+				}
+			}
+		}
+	}
+
+	if err := Encode(new(bytes.Buffer), nrgba, &Options{Plain: true}); err == nil {
+		t.Error("plain PAM: got nil error")
+	}
+}
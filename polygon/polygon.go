@@ -0,0 +1,239 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package polygon scan-converts polygons to whole pixels, without
+// antialiasing.
+//
+// Each pixel is either inside or outside of a polygon, depending only on
+// whether the pixel's center is inside. A center that lies exactly on an
+// edge is inside the polygon to the right of that edge or, for horizontal
+// edges, below it, so that polygons that share edges, such as the cells of a
+// tiling, cover every pixel exactly once. This exact pixel ownership suits
+// masks, hit maps and GIS rasterization, where hard edges matter more than
+// smoothness. For antialiased rendering, use the vector package instead.
+package polygon // import "golang.org/x/image/polygon"
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"golang.org/x/image/math/f64"
+)
+
+// Rule is a fill rule, which decides whether a point is inside a polygon from
+// the windings of the polygon's rings around that point.
+type Rule int
+
+const (
+	// NonZero fills points around which the rings wind a non-zero number of
+	// times, counting clockwise and counter-clockwise windings as +1 and -1.
+	NonZero Rule = iota
+	// EvenOdd fills points that are inside an odd number of rings.
+	EvenOdd
+)
+
+func (r Rule) inside(winding int) bool {
+	if r == EvenOdd {
+		return winding&1 != 0
+	}
+	return winding != 0
+}
+
+// Polygon is a set of rings, each a sequence of (x, y) vertices. Every ring
+// is implicitly closed: its last vertex is joined to its first. Holes are
+// rings that wind in the opposite direction to their enclosing ring (for the
+// NonZero rule) or simply rings that lie inside another ring (for the EvenOdd
+// rule).
+//
+// The coordinates are in pixel space: the pixel (x, y) has its center at
+// (x+0.5, y+0.5).
+type Polygon [][]f64.Vec2
+
+// Bounds returns the smallest rectangle that contains every pixel that p
+// could fill. It is empty if p has no vertices.
+func (p Polygon) Bounds() image.Rectangle {
+	minX, minY := math.Inf(+1), math.Inf(+1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, ring := range p {
+		for _, v := range ring {
+			minX = math.Min(minX, v[0])
+			minY = math.Min(minY, v[1])
+			maxX = math.Max(maxX, v[0])
+			maxY = math.Max(maxY, v[1])
+		}
+	}
+	if minX > maxX {
+		return image.Rectangle{}
+	}
+	return image.Rect(
+		int(math.Floor(minX)), int(math.Floor(minY)),
+		int(math.Ceil(maxX)), int(math.Ceil(maxY)),
+	)
+}
+
+// Contains returns whether the point (x, y) is inside p, according to the
+// rule.
+func (p Polygon) Contains(x, y float64, rule Rule) bool {
+	winding := 0
+	for _, ring := range p {
+		for i := range ring {
+			e, ok := makeEdge(ring[i], ring[(i+1)%len(ring)])
+			if !ok || y < e.y0 || e.y1 <= y {
+				continue
+			}
+			if x >= e.xAt(y) {
+				winding += e.dir
+			}
+		}
+	}
+	return rule.inside(winding)
+}
+
+// Spans calls f for every horizontal run of pixels inside p that is also
+// inside clip. Each call covers the pixels from (x0, y) inclusive to (x1, y)
+// exclusive, and x0 < x1. Spans are produced in increasing y order and, for
+// each y, in increasing x order. Adjacent runs are merged, so that no two
+// spans on the same row touch.
+func (p Polygon) Spans(clip image.Rectangle, rule Rule, f func(y, x0, x1 int)) {
+	clip = clip.Intersect(p.Bounds())
+	if clip.Empty() {
+		return
+	}
+
+	var edges []edge
+	for _, ring := range p {
+		for i := range ring {
+			if e, ok := makeEdge(ring[i], ring[(i+1)%len(ring)]); ok {
+				edges = append(edges, e)
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].y0 < edges[j].y0
+	})
+
+	var (
+		active    []*edge
+		crossings []crossing
+		next      int
+	)
+	for y := clip.Min.Y; y < clip.Max.Y; y++ {
+		cy := float64(y) + 0.5
+
+		// Add the edges that start at or above this row's centers, and
+		// remove those that end at or above them.
+		for ; next < len(edges) && edges[next].y0 <= cy; next++ {
+			active = append(active, &edges[next])
+		}
+		j := 0
+		for _, e := range active {
+			if cy < e.y1 {
+				active[j] = e
+				j++
+			}
+		}
+		active = active[:j]
+
+		crossings = crossings[:0]
+		for _, e := range active {
+			crossings = append(crossings, crossing{e.xAt(cy), e.dir})
+		}
+		sort.Slice(crossings, func(i, j int) bool {
+			return crossings[i].x < crossings[j].x
+		})
+
+		// Walk the crossings from left to right. A pixel is inside if its
+		// center is at or to the right of the crossing that enters the
+		// filled region, and to the left of the one that leaves it. Runs
+		// that touch are merged before calling f.
+		winding, x0, x1 := 0, 0, 0
+		pending := false
+		for _, c := range crossings {
+			wasInside := rule.inside(winding)
+			winding += c.dir
+			isInside := rule.inside(winding)
+			if wasInside == isInside {
+				continue
+			}
+			x := int(math.Ceil(c.x - 0.5))
+			if !isInside {
+				x1, pending = x, true
+				continue
+			}
+			if pending && x > x1 {
+				emitSpan(clip, y, x0, x1, f)
+				pending = false
+			}
+			if !pending {
+				x0 = x
+			}
+		}
+		if pending {
+			emitSpan(clip, y, x0, x1, f)
+		}
+	}
+}
+
+// emitSpan calls f with the span from (x0, y) to (x1, y), clipped to clip,
+// unless that is empty.
+func emitSpan(clip image.Rectangle, y, x0, x1 int, f func(y, x0, x1 int)) {
+	if x0 < clip.Min.X {
+		x0 = clip.Min.X
+	}
+	if x1 > clip.Max.X {
+		x1 = clip.Max.X
+	}
+	if x0 < x1 {
+		f(y, x0, x1)
+	}
+}
+
+// Fill sets every pixel of dst that is inside p to c.
+func (p Polygon) Fill(dst *image.Alpha, rule Rule, c color.Alpha) {
+	p.Spans(dst.Bounds(), rule, func(y, x0, x1 int) {
+		pix := dst.Pix[dst.PixOffset(x0, y):dst.PixOffset(x1, y)]
+		for i := range pix {
+			pix[i] = c.A
+		}
+	})
+}
+
+// Mask returns an *image.Alpha with bounds r that is opaque where p is
+// filled, according to the rule, and transparent elsewhere.
+func (p Polygon) Mask(r image.Rectangle, rule Rule) *image.Alpha {
+	m := image.NewAlpha(r)
+	p.Fill(m, rule, color.Alpha{0xff})
+	return m
+}
+
+// edge is a non-horizontal polygon edge, with y0 < y1. dir is +1 if the
+// original edge went down (increasing y) and -1 if it went up.
+type edge struct {
+	x0, y0 float64
+	x1, y1 float64
+	dir    int
+}
+
+func makeEdge(a, b f64.Vec2) (e edge, ok bool) {
+	if a[1] == b[1] {
+		return edge{}, false
+	}
+	if a[1] < b[1] {
+		return edge{a[0], a[1], b[0], b[1], +1}, true
+	}
+	return edge{b[0], b[1], a[0], a[1], -1}, true
+}
+
+// xAt returns the x coordinate of the edge at y, which should be in the range
+// [e.y0, e.y1].
+func (e *edge) xAt(y float64) float64 {
+	return e.x0 + (y-e.y0)*(e.x1-e.x0)/(e.y1-e.y0)
+}
+
+type crossing struct {
+	x   float64
+	dir int
+}
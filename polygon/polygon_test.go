@@ -0,0 +1,171 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package polygon
+
+import (
+	"image"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+)
+
+// maskString returns m as a string of '#' (opaque) and '.' (transparent)
+// pixels, one line per row.
+func maskString(m *image.Alpha) string {
+	b := m.Bounds()
+	buf := new(strings.Builder)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if m.AlphaAt(x, y).A != 0 {
+				buf.WriteByte('#')
+			} else {
+				buf.WriteByte('.')
+			}
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+func TestRules(t *testing.T) {
+	// Two overlapping squares, both clockwise, and a clockwise hole.
+	p := Polygon{
+		{{1, 1}, {5, 1}, {5, 5}, {1, 5}},
+		{{3, 3}, {7, 3}, {7, 7}, {3, 7}},
+	}
+	testCases := []struct {
+		rule Rule
+		want string
+	}{{
+		NonZero, "" +
+			"........\n" +
+			".####...\n" +
+			".####...\n" +
+			".######.\n" +
+			".######.\n" +
+			"...####.\n" +
+			"...####.\n" +
+			"........\n",
+	}, {
+		EvenOdd, "" +
+			"........\n" +
+			".####...\n" +
+			".####...\n" +
+			".##..##.\n" +
+			".##..##.\n" +
+			"...####.\n" +
+			"...####.\n" +
+			"........\n",
+	}}
+	for _, tc := range testCases {
+		got := maskString(p.Mask(image.Rect(0, 0, 8, 8), tc.rule))
+		if got != tc.want {
+			t.Errorf("rule %d:\ngot\n%swant\n%s", tc.rule, got, tc.want)
+		}
+	}
+
+	// A counter-clockwise inner ring is a hole under both rules.
+	p = Polygon{
+		{{1, 1}, {7, 1}, {7, 7}, {1, 7}},
+		{{3, 3}, {3, 5}, {5, 5}, {5, 3}},
+	}
+	for _, rule := range []Rule{NonZero, EvenOdd} {
+		got := maskString(p.Mask(image.Rect(0, 0, 8, 8), rule))
+		want := "" +
+			"........\n" +
+			".######.\n" +
+			".######.\n" +
+			".##..##.\n" +
+			".##..##.\n" +
+			".######.\n" +
+			".######.\n" +
+			"........\n"
+		if got != want {
+			t.Errorf("hole, rule %d:\ngot\n%swant\n%s", rule, got, want)
+		}
+	}
+}
+
+// TestTiling tests that polygons sharing edges cover every pixel exactly
+// once, and that Spans agrees with Contains at pixel centers.
+func TestTiling(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	const n = 5
+	// A grid of jittered vertices, split into quadrilaterals and then
+	// triangles.
+	var grid [n + 1][n + 1]f64.Vec2
+	for j := range grid {
+		for i := range grid[j] {
+			x, y := float64(8*i), float64(8*j)
+			if 0 < i && i < n {
+				x += 6*r.Float64() - 3
+			}
+			if 0 < j && j < n {
+				y += 6*r.Float64() - 3
+			}
+			grid[j][i] = f64.Vec2{x, y}
+		}
+	}
+	var ps []Polygon
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			a, b, c, d := grid[j][i], grid[j][i+1], grid[j+1][i+1], grid[j+1][i]
+			ps = append(ps, Polygon{{a, b, c}}, Polygon{{a, c, d}})
+		}
+	}
+
+	bounds := image.Rect(0, 0, 8*n, 8*n)
+	counts := make([]int, bounds.Dx()*bounds.Dy())
+	for _, p := range ps {
+		prevY, prevX1 := -1, 0
+		p.Spans(bounds, NonZero, func(y, x0, x1 int) {
+			if x0 >= x1 {
+				t.Errorf("empty span: y=%d, x0=%d, x1=%d", y, x0, x1)
+			}
+			if y < prevY || (y == prevY && x0 <= prevX1) {
+				t.Errorf("spans out of order or touching: y=%d, x0=%d", y, x0)
+			}
+			prevY, prevX1 = y, x1
+			for x := x0; x < x1; x++ {
+				counts[y*bounds.Dx()+x]++
+			}
+		})
+		m := p.Mask(bounds, NonZero)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				in := p.Contains(float64(x)+0.5, float64(y)+0.5, NonZero)
+				if got := m.AlphaAt(x, y).A != 0; got != in {
+					t.Fatalf("(%d, %d): Mask gave %t, Contains gave %t", x, y, got, in)
+				}
+			}
+		}
+	}
+	for i, c := range counts {
+		if c != 1 {
+			t.Fatalf("pixel (%d, %d) covered %d times, want 1", i%bounds.Dx(), i/bounds.Dx(), c)
+		}
+	}
+}
+
+func TestClip(t *testing.T) {
+	p := Polygon{{{-10, -10}, {20, -10}, {20, 20}, {-10, 20}}}
+	clip := image.Rect(2, 3, 6, 5)
+	n := 0
+	p.Spans(clip, EvenOdd, func(y, x0, x1 int) {
+		n++
+		if x0 != 2 || x1 != 6 || y < 3 || 5 <= y {
+			t.Errorf("got span y=%d, x0=%d, x1=%d, want within %v", y, x0, x1, clip)
+		}
+	})
+	if n != 2 {
+		t.Errorf("got %d spans, want 2", n)
+	}
+
+	if got := (Polygon{}).Bounds(); !got.Empty() {
+		t.Errorf("empty polygon Bounds: got %v, want empty", got)
+	}
+}
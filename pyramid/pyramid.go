@@ -0,0 +1,111 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pyramid writes multi-resolution images: an image together with a
+// sequence of overviews, each half the width and height of the one before.
+//
+// The overviews let a viewer show any part of a large image at any zoom level
+// while reading only the pixels that it displays. EncodeTIFF writes the
+// levels as a pyramidal, tiled TIFF, the format consumed by deep-zoom viewers,
+// GIS tools and whole slide imaging (WSI) software.
+package pyramid // import "golang.org/x/image/pyramid"
+
+import (
+	"errors"
+	"image"
+	"io"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/tiff"
+)
+
+// DefaultTileSize is the tile width and height used when Options.TileSize is
+// zero.
+const DefaultTileSize = 256
+
+// Options are the parameters for EncodeTIFF. A nil *Options means the default
+// for every field.
+type Options struct {
+	// TileSize is the width and height of the tiles that each level is split
+	// into. It must be a positive multiple of 16, or zero to mean
+	// DefaultTileSize.
+	TileSize int
+	// MinSize is the size at which the pyramid stops: overviews are added
+	// until the last level's width and height are both at most MinSize. Zero
+	// means the tile size, so that the smallest level is a single tile.
+	MinSize int
+	// Compression and Predictor are as for tiff.Options.
+	Compression tiff.CompressionType
+	Predictor   bool
+}
+
+// Levels returns m followed by its overviews, each half the width and height
+// of the one before, rounding up, until the width and height of the last are
+// both at most minSize. minSize must be positive.
+//
+// The overviews are scaled with draw.Box, which averages every source pixel
+// and so does not alias. An overview of an *image.Gray or *image.Gray16 has the
+// same type as m; other overviews are *image.RGBA64 if m has 16 bit samples,
+// and *image.RGBA otherwise.
+func Levels(m image.Image, minSize int) []image.Image {
+	levels := []image.Image{m}
+	for {
+		b := m.Bounds()
+		w, h := b.Dx(), b.Dy()
+		if (w <= minSize && h <= minSize) || (w <= 1 && h <= 1) {
+			return levels
+		}
+		dr := image.Rect(0, 0, (w+1)/2, (h+1)/2)
+		dst := newLevel(m, dr)
+		draw.Box.Scale(dst, dr, m, b, draw.Src, nil)
+		levels = append(levels, dst)
+		m = dst
+	}
+}
+
+// newLevel returns an image with bounds r for an overview of m.
+func newLevel(m image.Image, r image.Rectangle) draw.Image {
+	switch m.(type) {
+	case *image.Gray:
+		return image.NewGray(r)
+	case *image.Gray16:
+		return image.NewGray16(r)
+	case *image.RGBA64, *image.NRGBA64:
+		return image.NewRGBA64(r)
+	}
+	return image.NewRGBA(r)
+}
+
+// EncodeTIFF writes m and its overviews, as returned by Levels, to w as a
+// tiled TIFF. The full-resolution image is the first page, and each overview
+// is a following page that is marked as a reduced-resolution image.
+//
+// m may be any image.Image, including one that decodes or generates its
+// pixels on demand, a tile at a time. The overviews are held in memory, which
+// needs about a third of the memory of an image.RGBA the size of m.
+func EncodeTIFF(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.TileSize == 0 {
+		o.TileSize = DefaultTileSize
+	}
+	if o.TileSize < 0 || o.TileSize%16 != 0 {
+		return errors.New("pyramid: tile size must be a positive multiple of 16")
+	}
+	if o.MinSize < 0 {
+		return errors.New("pyramid: negative minimum size")
+	}
+	if o.MinSize == 0 {
+		o.MinSize = o.TileSize
+	}
+	return tiff.EncodeAll(w, Levels(m, o.MinSize), &tiff.Options{
+		Compression:       o.Compression,
+		Predictor:         o.Predictor,
+		TileWidth:         o.TileSize,
+		TileHeight:        o.TileSize,
+		ReducedResolution: true,
+	})
+}
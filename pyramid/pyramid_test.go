@@ -0,0 +1,101 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pyramid
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/testsupport"
+	"golang.org/x/image/tiff"
+)
+
+func TestLevels(t *testing.T) {
+	m := testsupport.ZonePlate(image.Rect(10, 20, 310, 220))
+	levels := Levels(m, 64)
+	want := []image.Point{{300, 200}, {150, 100}, {75, 50}, {38, 25}}
+	if len(levels) != len(want) {
+		t.Fatalf("got %d levels, want %d", len(levels), len(want))
+	}
+	for i, l := range levels {
+		if got := l.Bounds().Size(); got != want[i] {
+			t.Errorf("level %d: got size %v, want %v", i, got, want[i])
+		}
+		if _, ok := l.(*image.Gray); !ok {
+			t.Errorf("level %d: got %T, want *image.Gray", i, l)
+		}
+	}
+
+	// A uniform image's overviews are the same color.
+	c := color.RGBA{0x40, 0x80, 0xc0, 0xff}
+	u := image.NewRGBA(image.Rect(0, 0, 33, 17))
+	for i := 0; i < len(u.Pix); i += 4 {
+		u.Pix[i+0], u.Pix[i+1], u.Pix[i+2], u.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	levels = Levels(u, 1)
+	if got, want := len(levels), 7; got != want {
+		t.Fatalf("uniform: got %d levels, want %d", got, want)
+	}
+	last := levels[len(levels)-1]
+	if got := last.Bounds().Size(); got != (image.Point{1, 1}) {
+		t.Fatalf("uniform: got last size %v, want (1,1)", got)
+	}
+	if got := last.At(0, 0); got != c {
+		t.Errorf("uniform: got last color %v, want %v", got, c)
+	}
+}
+
+func TestEncodeTIFF(t *testing.T) {
+	m := testsupport.Checkerboard(image.Rect(0, 0, 200, 150), 8, color.White, color.NRGBA{0, 0, 0xff, 0x80})
+	buf := new(bytes.Buffer)
+	if err := EncodeTIFF(buf, m, &Options{TileSize: 32, Compression: tiff.Deflate}); err != nil {
+		t.Fatal(err)
+	}
+	pages, err := tiff.DecodeAll(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := Levels(m, 32)
+	if len(pages) != len(levels) {
+		t.Fatalf("got %d pages, want %d", len(pages), len(levels))
+	}
+	for i, p := range pages {
+		if err := testsupport.Compare(p, levels[i], testsupport.Tolerance{}); err != nil {
+			t.Errorf("page %d: %v", i, err)
+		}
+	}
+
+	// Every page but the first is marked as a reduced-resolution image.
+	dirs, err := tiff.Validate(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, dir := range dirs {
+		reduced := false
+		for _, e := range dir.Entries {
+			if e.Tag == 254 {
+				reduced = true
+			}
+		}
+		if want := i > 0; reduced != want {
+			t.Errorf("page %d: got reduced %t, want %t", i, reduced, want)
+		}
+	}
+}
+
+func TestEncodeTIFFErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 10, 10))
+	for _, o := range []*Options{
+		{TileSize: 20},
+		{TileSize: -16},
+		{MinSize: -1},
+	} {
+		if err := EncodeTIFF(new(bytes.Buffer), m, o); err == nil {
+			t.Errorf("%+v: got nil error", *o)
+		}
+	}
+}
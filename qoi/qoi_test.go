@@ -0,0 +1,136 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qoi
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	r := image.Rect(3, 4, 200, 90)
+	rng := rand.New(rand.NewSource(1))
+	nrgba := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			var c color.NRGBA
+			switch (x / 20) % 5 {
+			case 0:
+				// A run, longer than a single chunk can hold.
+				c = color.NRGBA{0x10, 0x20, 0x30, 0xff}
+			case 1:
+				// Small differences.
+				c = color.NRGBA{uint8(x), uint8(x + 1), uint8(x - 1), 0xff}
+			case 2:
+				// Larger differences, with wrap-around.
+				c = color.NRGBA{uint8(x * 9), uint8(x * 13), uint8(x * 11), 0xff}
+			case 3:
+				// A few repeated colors.
+				c = color.NRGBA{uint8(rng.Intn(3) * 100), 0, 0, 0x80}
+			case 4:
+				c = color.NRGBA{uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256)), uint8(rng.Intn(256))}
+			}
+			nrgba.SetNRGBA(x, y, c)
+		}
+	}
+	rgba := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := nrgba.NRGBAAt(x, y)
+			c.A = 0xff
+			rgba.Set(x, y, c)
+		}
+	}
+
+	for _, m := range []image.Image{nrgba, rgba, image.NewGray(r)} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m); err != nil {
+			t.Fatalf("%T: Encode: %v", m, err)
+		}
+		if raw := 4 * r.Dx() * r.Dy(); buf.Len() >= raw {
+			t.Errorf("%T: encoded %d bytes, raw pixels are %d bytes", m, buf.Len(), raw)
+		}
+		got, format, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatalf("%T: Decode: %v", m, err)
+		}
+		if format != "qoi" {
+			t.Errorf("%T: format: got %q", m, format)
+		}
+		if got.Bounds().Size() != r.Size() {
+			t.Fatalf("%T: size: got %v, want %v", m, got.Bounds().Size(), r.Size())
+		}
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				g := color.NRGBAModel.Convert(got.At(x, y))
+				w := nrgbaAt(m, r.Min.X+x, r.Min.Y+y)
+				if g != w {
+					t.Fatalf("%T: (%d, %d): got %v, want %v", m, x, y, g, w)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeChunks(t *testing.T) {
+	m := image.NewNRGBA(image.Rect(0, 0, 7, 1))
+	for x, c := range []color.NRGBA{
+		{0x00, 0x00, 0x00, 0xff}, // a run of the initial pixel
+		{0x01, 0xff, 0x00, 0xff}, // a diff
+		{0x0b, 0x07, 0x08, 0xff}, // a luma
+		{0x80, 0x07, 0x08, 0xff}, // an RGB
+		{0x80, 0x07, 0x08, 0x40}, // an RGBA
+		{0x01, 0xff, 0x00, 0xff}, // an index
+		{0x01, 0xff, 0x00, 0xff}, // a run
+	} {
+		m.SetNRGBA(x, 0, c)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		opRun | 0,
+		opDiff | 3<<4 | 1<<2 | 2,
+		opLuma | 40, 10<<4 | 8,
+		opRGB, 0x80, 0x07, 0x08,
+		opRGBA, 0x80, 0x07, 0x08, 0x40,
+		opIndex | byte(hash(color.NRGBA{0x01, 0xff, 0x00, 0xff})),
+		opRun | 0,
+	}
+	want = append(want, padding[:]...)
+	if got := buf.Bytes()[headerLen:]; !bytes.Equal(got, want) {
+		t.Errorf("got  % x\nwant % x", got, want)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	header := func(w, h, channels, colorspace byte) string {
+		return magic + string([]byte{0, 0, 0, w, 0, 0, 0, h, channels, colorspace})
+	}
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"bad magic", "qoiF" + header(1, 1, 4, 0)[4:], FormatError("bad magic number")},
+		{"bad channels", header(1, 1, 2, 0), FormatError("bad channel count")},
+		{"bad color space", header(1, 1, 3, 2), FormatError("bad color space")},
+		{"too large", magic + "\xff\xff\xff\xff\xff\xff\xff\xff\x04\x00", FormatError("image too large")},
+		{"truncated header", magic + "\x00", io.ErrUnexpectedEOF},
+		{"truncated data", header(2, 1, 4, 0) + "\xfe\x01", io.ErrUnexpectedEOF},
+		{"no end marker", header(1, 1, 4, 0) + "\xc0", io.ErrUnexpectedEOF},
+		{"bad end marker", header(1, 1, 4, 0) + "\xc0" + "\x00\x00\x00\x00\x00\x00\x00\x00", FormatError("bad end marker")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(bytes.NewReader([]byte(tc.data))); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
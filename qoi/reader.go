@@ -0,0 +1,205 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package qoi implements a decoder and encoder for QOI (Quite OK Image)
+// images.
+//
+// QOI is a simple, fast, lossless format for 8-bit RGB and RGBA images.
+//
+// The format is specified at https://qoiformat.org/qoi-specification.pdf
+package qoi // import "golang.org/x/image/qoi"
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+)
+
+// A FormatError reports that the input is not a valid QOI image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "qoi: invalid format: " + string(e)
+}
+
+const (
+	magic     = "qoif"
+	headerLen = 14
+	// maxPixels is the largest number of pixels of an image, as for the
+	// reference implementation.
+	maxPixels = 400000000
+
+	opIndex = 0x00 // 00xxxxxx
+	opDiff  = 0x40 // 01xxxxxx
+	opLuma  = 0x80 // 10xxxxxx
+	opRun   = 0xc0 // 11xxxxxx
+	opRGB   = 0xfe // 11111110
+	opRGBA  = 0xff // 11111111
+	opMask  = 0xc0
+)
+
+// padding ends the image data.
+var padding = [8]byte{0, 0, 0, 0, 0, 0, 0, 1}
+
+// hash returns the position of c in the array of previously seen pixels.
+func hash(c color.NRGBA) int {
+	return (int(c.R)*3 + int(c.G)*5 + int(c.B)*7 + int(c.A)*11) % 64
+}
+
+// header is the decoded header of a QOI image.
+type header struct {
+	width, height int
+	channels      int
+}
+
+func readHeader(r io.Reader) (header, error) {
+	var b [headerLen]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return header{}, err
+	}
+	if string(b[:4]) != magic {
+		return header{}, FormatError("bad magic number")
+	}
+	w := uint32(b[4])<<24 | uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7])
+	h := uint32(b[8])<<24 | uint32(b[9])<<16 | uint32(b[10])<<8 | uint32(b[11])
+	if b[12] != 3 && b[12] != 4 {
+		return header{}, FormatError("bad channel count")
+	}
+	if b[13] > 1 {
+		return header{}, FormatError("bad color space")
+	}
+	if w != 0 && uint64(h) > maxPixels/uint64(w) {
+		return header{}, FormatError("image too large")
+	}
+	return header{int(w), int(h), int(b[12])}, nil
+}
+
+// Decode reads a QOI image from r and returns it as an image.Image: an
+// *image.NRGBA for images with 4 channels, and an *image.RGBA, which is
+// opaque, for images with 3 channels. The color space byte, which declares
+// whether the color channels are sRGB or linear, is only informative, and
+// does not change the decoding.
+func Decode(r io.Reader) (image.Image, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		m   image.Image
+		pix []byte
+	)
+	rect := image.Rect(0, 0, h.width, h.height)
+	if h.channels == 4 {
+		n := image.NewNRGBA(rect)
+		m, pix = n, n.Pix
+	} else {
+		n := image.NewRGBA(rect)
+		m, pix = n, n.Pix
+	}
+
+	br := bufio.NewReader(r)
+	var index [64]color.NRGBA
+	px := color.NRGBA{0, 0, 0, 0xff}
+	run := 0
+	for i := 0; i < len(pix); i += 4 {
+		if run > 0 {
+			run--
+		} else {
+			if px, run, err = readChunk(br, px, &index); err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			index[hash(px)] = px
+		}
+		pix[i+0] = px.R
+		pix[i+1] = px.G
+		pix[i+2] = px.B
+		pix[i+3] = px.A
+		if h.channels == 3 {
+			pix[i+3] = 0xff
+		}
+	}
+
+	var end [len(padding)]byte
+	if _, err := io.ReadFull(br, end[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if end != padding {
+		return nil, FormatError("bad end marker")
+	}
+	return m, nil
+}
+
+// readChunk reads a chunk, given the previous pixel and the array of
+// previously seen pixels, and returns the next pixel and, for a run, the
+// number of times that it repeats after the first.
+func readChunk(r *bufio.Reader, px color.NRGBA, index *[64]color.NRGBA) (color.NRGBA, int, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return px, 0, err
+	}
+	switch {
+	case b == opRGB:
+		var c [3]byte
+		if _, err := io.ReadFull(r, c[:]); err != nil {
+			return px, 0, err
+		}
+		px.R, px.G, px.B = c[0], c[1], c[2]
+	case b == opRGBA:
+		var c [4]byte
+		if _, err := io.ReadFull(r, c[:]); err != nil {
+			return px, 0, err
+		}
+		px = color.NRGBA{c[0], c[1], c[2], c[3]}
+	case b&opMask == opIndex:
+		px = index[b]
+	case b&opMask == opDiff:
+		px.R += (b>>4)&3 - 2
+		px.G += (b>>2)&3 - 2
+		px.B += b&3 - 2
+	case b&opMask == opLuma:
+		b1, err := r.ReadByte()
+		if err != nil {
+			return px, 0, err
+		}
+		dg := b&0x3f - 32
+		px.R += dg + b1>>4 - 8
+		px.G += dg
+		px.B += dg + b1&0x0f - 8
+	default:
+		return px, int(b & 0x3f), nil
+	}
+	return px, 0, nil
+}
+
+// DecodeConfig returns the color model and dimensions of a QOI image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	cm := color.RGBAModel
+	if h.channels == 4 {
+		cm = color.NRGBAModel
+	}
+	return image.Config{
+		ColorModel: cm,
+		Width:      h.width,
+		Height:     h.height,
+	}, nil
+}
+
+func init() {
+	image.RegisterFormat("qoi", magic, Decode, DecodeConfig)
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package qoi
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Encode writes the image m to w in QOI format. Opaque images are written
+// with 3 channels, and other images with 4. The color space is declared as
+// sRGB.
+func Encode(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	if b.Dx() != 0 && int64(b.Dy()) > maxPixels/int64(b.Dx()) {
+		return errors.New("qoi: image is too large to encode")
+	}
+	channels := byte(4)
+	if op, ok := m.(interface{ Opaque() bool }); ok && op.Opaque() {
+		channels = 3
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(magic)
+	for _, v := range []int{b.Dx(), b.Dy()} {
+		bw.Write([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)})
+	}
+	bw.Write([]byte{channels, 0})
+
+	var index [64]color.NRGBA
+	prev := color.NRGBA{0, 0, 0, 0xff}
+	run := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			px := nrgbaAt(m, x, y)
+			if px == prev {
+				run++
+				if run == 62 {
+					bw.WriteByte(opRun | byte(run-1))
+					run = 0
+				}
+				continue
+			}
+			if run > 0 {
+				bw.WriteByte(opRun | byte(run-1))
+				run = 0
+			}
+			writeChunk(bw, px, prev, &index)
+			prev = px
+		}
+	}
+	if run > 0 {
+		bw.WriteByte(opRun | byte(run-1))
+	}
+	bw.Write(padding[:])
+	return bw.Flush()
+}
+
+// writeChunk writes the shortest chunk for px, given the previous pixel and
+// the array of previously seen pixels, which it updates.
+func writeChunk(w *bufio.Writer, px, prev color.NRGBA, index *[64]color.NRGBA) {
+	h := hash(px)
+	if index[h] == px {
+		w.WriteByte(opIndex | byte(h))
+		return
+	}
+	index[h] = px
+	if px.A != prev.A {
+		w.Write([]byte{opRGBA, px.R, px.G, px.B, px.A})
+		return
+	}
+	// The differences wrap around, as the decoder's additions do.
+	dr, dg, db := int8(px.R-prev.R), int8(px.G-prev.G), int8(px.B-prev.B)
+	drg, dbg := dr-dg, db-dg
+	switch {
+	case -2 <= dr && dr <= 1 && -2 <= dg && dg <= 1 && -2 <= db && db <= 1:
+		w.WriteByte(opDiff | byte(dr+2)<<4 | byte(dg+2)<<2 | byte(db+2))
+	case -32 <= dg && dg <= 31 && -8 <= drg && drg <= 7 && -8 <= dbg && dbg <= 7:
+		w.Write([]byte{opLuma | byte(dg+32), byte(drg+8)<<4 | byte(dbg+8)})
+	default:
+		w.Write([]byte{opRGB, px.R, px.G, px.B})
+	}
+}
+
+// nrgbaAt returns the non-premultiplied color of m at (x, y), without the
+// loss of precision of converting an image.NRGBA's colors to premultiplied
+// ones and back.
+func nrgbaAt(m image.Image, x, y int) color.NRGBA {
+	if n, ok := m.(*image.NRGBA); ok {
+		return n.NRGBAAt(x, y)
+	}
+	return color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+}
@@ -0,0 +1,120 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rectify straightens the photograph of a flat rectangle, such as a
+// document, a whiteboard or a screen, given the four corners of the rectangle
+// in the photograph.
+//
+// The corners are mapped to those of an upright rectangular image by the
+// projective transform, or homography, returned by draw.Homography.
+package rectify // import "golang.org/x/image/rectify"
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+// ErrDegenerate is returned when three of the corners are collinear, so that
+// they do not outline a rectangle.
+var ErrDegenerate = errors.New("rectify: three of the corners are collinear")
+
+// Options are the rectification parameters. A nil *Options means the default
+// for every field.
+type Options struct {
+	// Width and Height are the size of the rectified image. If both are
+	// zero, the size is estimated by Size. If one is zero, it follows from
+	// the other and the estimated aspect ratio.
+	Width, Height int
+	// Interpolator resamples the photograph. Nil means draw.CatmullRom.
+	Interpolator draw.ProjectiveTransformer
+}
+
+// OrderCorners returns the corners in clockwise order, starting from the
+// top-left, as Rectify and Size expect. The top-left corner is the one with
+// the smallest sum of coordinates.
+//
+// It lets the corners be picked in any order, such as by a user tapping on
+// a screen, as long as they outline a convex quadrilateral.
+func OrderCorners(corners [4]f64.Vec2) [4]f64.Vec2 {
+	var cx, cy float64
+	for _, c := range corners {
+		cx += c[0] / 4
+		cy += c[1] / 4
+	}
+	// With the y axis pointing down, increasing angles are clockwise.
+	sort.Slice(corners[:], func(i, j int) bool {
+		ai := math.Atan2(corners[i][1]-cy, corners[i][0]-cx)
+		aj := math.Atan2(corners[j][1]-cy, corners[j][0]-cx)
+		return ai < aj
+	})
+	first := 0
+	for i, c := range corners {
+		if c[0]+c[1] < corners[first][0]+corners[first][1] {
+			first = i
+		}
+	}
+	var ordered [4]f64.Vec2
+	for i := range ordered {
+		ordered[i] = corners[(first+i)%4]
+	}
+	return ordered
+}
+
+// Size estimates the size of the rectangle whose corners, clockwise from the
+// top-left, are given: the width is the length of the longer of the top and
+// bottom edges, and the height that of the longer of the left and right
+// edges. That is exact for a photograph taken head on, and keeps as much
+// detail as the photograph has otherwise.
+func Size(corners [4]f64.Vec2) (width, height int) {
+	w := math.Max(dist(corners[0], corners[1]), dist(corners[3], corners[2]))
+	h := math.Max(dist(corners[0], corners[3]), dist(corners[1], corners[2]))
+	return int(math.Round(w)), int(math.Round(h))
+}
+
+func dist(p, q f64.Vec2) float64 {
+	return math.Hypot(q[0]-p[0], q[1]-p[1])
+}
+
+// Rectify returns the part of src within the corners, clockwise from the
+// top-left, as an upright rectangular image. The corners are in src's
+// coordinate space, where the pixel at (x, y) covers the square from (x, y)
+// to (x+1, y+1). Parts of the result that map to outside of src's bounds are
+// transparent.
+func Rectify(src image.Image, corners [4]f64.Vec2, opts *Options) (*image.RGBA, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Width < 0 || o.Height < 0 {
+		return nil, errors.New("rectify: negative size")
+	}
+	if o.Width == 0 || o.Height == 0 {
+		w, h := Size(corners)
+		switch {
+		case o.Width != 0 && h != 0 && w != 0:
+			o.Height = int(math.Round(float64(o.Width) * float64(h) / float64(w)))
+		case o.Height != 0 && h != 0 && w != 0:
+			o.Width = int(math.Round(float64(o.Height) * float64(w) / float64(h)))
+		default:
+			o.Width, o.Height = w, h
+		}
+	}
+	if o.Interpolator == nil {
+		o.Interpolator = draw.CatmullRom
+	}
+
+	w, h := float64(o.Width), float64(o.Height)
+	s2d, ok := draw.Homography(corners, [4]f64.Vec2{{0, 0}, {w, 0}, {w, h}, {0, h}})
+	if !ok || o.Width == 0 || o.Height == 0 {
+		return nil, ErrDegenerate
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, o.Width, o.Height))
+	o.Interpolator.TransformProjective(dst, s2d, src, src.Bounds(), draw.Src, nil)
+	return dst, nil
+}
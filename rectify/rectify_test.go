@@ -0,0 +1,107 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rectify
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/f64"
+)
+
+func TestOrderCorners(t *testing.T) {
+	want := [4]f64.Vec2{{10, 5}, {70, 15}, {60, 75}, {5, 50}}
+	for _, perm := range [][4]int{
+		{0, 1, 2, 3},
+		{3, 2, 1, 0},
+		{2, 0, 3, 1},
+		{1, 3, 0, 2},
+	} {
+		var corners [4]f64.Vec2
+		for i, j := range perm {
+			corners[i] = want[j]
+		}
+		if got := OrderCorners(corners); got != want {
+			t.Errorf("%v: got %v, want %v", corners, got, want)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	w, h := Size([4]f64.Vec2{{10, 10}, {50, 10}, {60, 40}, {0, 40}})
+	if w != 60 || h != 32 {
+		t.Errorf("got %d×%d, want 60×32", w, h)
+	}
+}
+
+// pattern returns a smooth w×h test image, whose pixels survive resampling.
+func pattern(w, h int) *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.SetRGBA(x, y, color.RGBA{uint8(4 * x), uint8(4 * y), 0x80, 0xff})
+		}
+	}
+	return m
+}
+
+func TestRectify(t *testing.T) {
+	want := pattern(60, 40)
+	corners := [4]f64.Vec2{{12.5, 8}, {80, 20}, {70.5, 90}, {6, 60}}
+	s2d, ok := draw.Homography([4]f64.Vec2{{0, 0}, {60, 0}, {60, 40}, {0, 40}}, corners)
+	if !ok {
+		t.Fatal("Homography: got !ok")
+	}
+	photo := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	draw.CatmullRom.TransformProjective(photo, s2d, want, want.Bounds(), draw.Src, nil)
+
+	shuffled := [4]f64.Vec2{corners[2], corners[0], corners[3], corners[1]}
+	got, err := Rectify(photo, OrderCorners(shuffled), &Options{Width: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := got.Bounds(); b.Dx() != 60 || b.Dy() < 30 || b.Dy() > 90 {
+		t.Fatalf("got bounds %v, want a width of 60 and a plausible height", b)
+	}
+
+	got, err = Rectify(photo, OrderCorners(shuffled), &Options{Width: 60, Height: 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Away from the edges, where the photograph's transparent background
+	// bleeds in, the rectified image matches the original.
+	sum, n := 0, 0
+	for y := 4; y < 36; y++ {
+		for x := 4; x < 56; x++ {
+			g, w := got.RGBAAt(x, y), want.RGBAAt(x, y)
+			sum += absDiff(g.R, w.R) + absDiff(g.G, w.G) + absDiff(g.B, w.B)
+			n += 3
+		}
+	}
+	if mean := float64(sum) / float64(n); mean > 2 {
+		t.Errorf("mean absolute difference: got %.2f, want at most 2", mean)
+	}
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+func TestRectifyDegenerate(t *testing.T) {
+	src := pattern(10, 10)
+	collinear := [4]f64.Vec2{{0, 0}, {5, 5}, {10, 10}, {0, 10}}
+	if _, err := Rectify(src, collinear, nil); err != ErrDegenerate {
+		t.Errorf("collinear: got %v, want %v", err, ErrDegenerate)
+	}
+	point := [4]f64.Vec2{{3, 3}, {3, 3}, {3, 3}, {3, 3}}
+	if _, err := Rectify(src, point, nil); err != ErrDegenerate {
+		t.Errorf("point: got %v, want %v", err, ErrDegenerate)
+	}
+}
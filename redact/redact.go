@@ -0,0 +1,164 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package redact hides regions of images, such as faces, license plates or
+// personal details in screenshots, by blanking, pixelating or blurring them in
+// place.
+//
+// Blanking replaces a region's pixels with a solid color, so that nothing of
+// them remains. Pixelating and blurring keep the region's rough appearance,
+// which is less jarring, but they do leave some information: small blocks or
+// blurs of text from a known font can sometimes be matched against candidate
+// text. Use Blank, or large block sizes and blurs, for text.
+//
+// Redaction only changes the decoded pixels. Re-encode the image to a new
+// file, without the original's metadata, such as EXIF thumbnails, which may
+// hold an unredacted copy of the image.
+package redact // import "golang.org/x/image/redact"
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/polygon"
+)
+
+// Method is how a region is hidden.
+type Method int
+
+const (
+	// Blank fills the region with Options.Color.
+	Blank Method = iota
+	// Pixelate replaces the region with square cells of Options.BlockSize
+	// pixels, each the average color of the pixels that it covers.
+	Pixelate
+	// Blur replaces the region with a Gaussian blur of it, with the standard
+	// deviation Options.Sigma.
+	Blur
+)
+
+const (
+	// DefaultBlockSize is the cell size used when Options.BlockSize is zero.
+	DefaultBlockSize = 16
+	// DefaultSigma is the blur's standard deviation used when Options.Sigma
+	// is zero.
+	DefaultSigma = 8
+)
+
+// Options are the redaction parameters. A nil *Options means the default for
+// every field.
+type Options struct {
+	// Method is how the regions are hidden. The default is Blank.
+	Method Method
+	// Color is the color that Blank fills with. Nil means opaque black.
+	Color color.Color
+	// BlockSize is the width and height, in pixels, of Pixelate's cells.
+	// The cells are aligned with each region's bounding box, and those at its
+	// right and bottom edges may be smaller. Zero means DefaultBlockSize.
+	BlockSize int
+	// Sigma is Blur's standard deviation, in pixels. Zero means DefaultSigma.
+	Sigma float64
+	// Rule is the fill rule for polygons.
+	Rule polygon.Rule
+}
+
+// Rects hides the rectangles rs of dst, in order.
+func Rects(dst draw.Image, rs []image.Rectangle, opts *Options) {
+	o := defaults(opts)
+	for _, r := range rs {
+		redact(dst, r.Intersect(dst.Bounds()), nil, &o)
+	}
+}
+
+// Polygons hides the pixels of dst inside the polygons ps, in order. A pixel
+// is inside a polygon if its center is, as for the polygon package.
+func Polygons(dst draw.Image, ps []polygon.Polygon, opts *Options) {
+	o := defaults(opts)
+	for _, p := range ps {
+		r := p.Bounds().Intersect(dst.Bounds())
+		if r.Empty() {
+			continue
+		}
+		redact(dst, r, func(f func(y, x0, x1 int)) { p.Spans(r, o.Rule, f) }, &o)
+	}
+}
+
+func defaults(opts *Options) Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Color == nil {
+		o.Color = color.Black
+	}
+	if o.BlockSize <= 0 {
+		o.BlockSize = DefaultBlockSize
+	}
+	if o.Sigma <= 0 {
+		o.Sigma = DefaultSigma
+	}
+	return o
+}
+
+// redact hides the pixels of dst within r or, if spans is non-nil, only those
+// of the horizontal runs of pixels, within r, that spans calls its argument
+// with.
+func redact(dst draw.Image, r image.Rectangle, spans func(func(y, x0, x1 int)), o *Options) {
+	if r.Empty() {
+		return
+	}
+	var src image.Image
+	switch o.Method {
+	case Pixelate:
+		src = pixelate(dst, r, o.BlockSize)
+	case Blur:
+		src = blur(dst, r, o.Sigma)
+	default:
+		src = image.NewUniform(o.Color)
+	}
+	if spans == nil {
+		draw.Draw(dst, r, src, r.Min, draw.Src)
+		return
+	}
+	spans(func(y, x0, x1 int) {
+		sr := image.Rect(x0, y, x1, y+1)
+		draw.Draw(dst, sr, src, sr.Min, draw.Src)
+	})
+}
+
+// pixelate returns an image, with bounds r, of the pixels of m within r
+// averaged over cells of size by size pixels.
+func pixelate(m image.Image, r image.Rectangle, size int) *image.RGBA64 {
+	// Average each whole cell with the Box kernel, and the partial cells at
+	// the right and bottom edges with the same kernel over fewer pixels.
+	cw, ch := (r.Dx()+size-1)/size, (r.Dy()+size-1)/size
+	cells := image.NewRGBA64(image.Rect(0, 0, cw, ch))
+	for cy := 0; cy < ch; cy++ {
+		for cx := 0; cx < cw; cx++ {
+			cr := image.Rect(cx*size, cy*size, (cx+1)*size, (cy+1)*size).Add(r.Min).Intersect(r)
+			xdraw.Box.Scale(cells, image.Rect(cx, cy, cx+1, cy+1), m, cr, xdraw.Src, nil)
+		}
+	}
+	out := image.NewRGBA64(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			out.SetRGBA64(x, y, cells.RGBA64At((x-r.Min.X)/size, (y-r.Min.Y)/size))
+		}
+	}
+	return out
+}
+
+// blur returns an image, with bounds r, of the pixels of m within r blurred
+// by a Gaussian with the standard deviation sigma. Pixels beyond r, up to
+// three standard deviations away and within m's bounds, contribute to the
+// blur, so that the region blends into its surroundings.
+func blur(m image.Image, r image.Rectangle, sigma float64) *image.RGBA64 {
+	w := r.Inset(-int(math.Ceil(3 * sigma))).Intersect(m.Bounds())
+	out := image.NewRGBA64(w)
+	xdraw.GaussianBlur(out, w.Min, m, w, sigma, xdraw.Src, nil)
+	return out.SubImage(r).(*image.RGBA64)
+}
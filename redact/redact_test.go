@@ -0,0 +1,167 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package redact
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/polygon"
+)
+
+// gradient returns a 64x64 image whose pixels are all different.
+func gradient() *image.RGBA {
+	m := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			m.SetRGBA(x, y, color.RGBA{uint8(4 * x), uint8(4 * y), uint8(2*x + 2*y), 0xff})
+		}
+	}
+	return m
+}
+
+// unchanged returns whether the pixels of a and b outside r are equal.
+func unchanged(a, b *image.RGBA, r image.Rectangle) bool {
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			if !(image.Point{x, y}.In(r)) && a.RGBAAt(x, y) != b.RGBAAt(x, y) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestBlank(t *testing.T) {
+	orig := gradient()
+	m := gradient()
+	r := image.Rect(10, 20, 30, 25)
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	Rects(m, []image.Rectangle{r, image.Rect(60, 60, 80, 80)}, &Options{Color: red})
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if got := m.RGBAAt(x, y); got != red {
+				t.Fatalf("(%d, %d): got %v, want %v", x, y, got, red)
+			}
+		}
+	}
+	if got := m.RGBAAt(63, 63); got != red {
+		t.Errorf("(63, 63): got %v, want %v", got, red)
+	}
+	if !unchanged(orig, m, r.Union(image.Rect(60, 60, 64, 64))) {
+		t.Error("pixels outside the rectangles changed")
+	}
+}
+
+func TestPixelate(t *testing.T) {
+	orig := gradient()
+	m := gradient()
+	r := image.Rect(8, 8, 28, 24)
+	Rects(m, []image.Rectangle{r}, &Options{Method: Pixelate, BlockSize: 8})
+	// The cells are 8x8, except for the 4x8 cells at the right edge.
+	cells := []image.Rectangle{
+		image.Rect(8, 8, 16, 16), image.Rect(16, 8, 24, 16), image.Rect(24, 8, 28, 16),
+		image.Rect(8, 16, 16, 24), image.Rect(16, 16, 24, 24), image.Rect(24, 16, 28, 24),
+	}
+	for _, c := range cells {
+		// Each channel of the gradient is linear, so a cell's average is the
+		// color at its center.
+		want := color.RGBA{
+			uint8(2 * (c.Min.X + c.Max.X - 1)),
+			uint8(2 * (c.Min.Y + c.Max.Y - 1)),
+			uint8(c.Min.X + c.Max.X + c.Min.Y + c.Max.Y - 2),
+			0xff,
+		}
+		for y := c.Min.Y; y < c.Max.Y; y++ {
+			for x := c.Min.X; x < c.Max.X; x++ {
+				if got := m.RGBAAt(x, y); !near(got, want, 1) {
+					t.Fatalf("cell %v: (%d, %d): got %v, want %v", c, x, y, got, want)
+				}
+			}
+		}
+	}
+	if !unchanged(orig, m, r) {
+		t.Error("pixels outside the rectangle changed")
+	}
+}
+
+func TestBlur(t *testing.T) {
+	// A white square on black, half of which is blurred.
+	m := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 16; y < 48; y++ {
+		for x := 16; x < 48; x++ {
+			m.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+		}
+	}
+	for i := range m.Pix {
+		if i%4 == 3 {
+			m.Pix[i] = 0xff
+		}
+	}
+	orig := image.NewRGBA(m.Rect)
+	copy(orig.Pix, m.Pix)
+	r := image.Rect(0, 0, 32, 64)
+	Rects(m, []image.Rectangle{r}, &Options{Method: Blur, Sigma: 2})
+
+	// Far from the square's edges, the blur changes nothing. At its edge,
+	// it is a ramp from black to white.
+	if got, want := m.RGBAAt(24, 32), orig.RGBAAt(24, 32); got != want {
+		t.Errorf("inside: got %v, want %v", got, want)
+	}
+	if got, want := m.RGBAAt(4, 32), orig.RGBAAt(4, 32); got != want {
+		t.Errorf("outside: got %v, want %v", got, want)
+	}
+	prev := uint8(0)
+	for x := 10; x < 23; x++ {
+		c := m.RGBAAt(x, 32)
+		if c.R < prev || c.R != c.G || c.R != c.B || c.A != 0xff {
+			t.Fatalf("edge: (%d, 32): got %v after red %#02x", x, c, prev)
+		}
+		prev = c.R
+	}
+	if c := m.RGBAAt(16, 32); c.R < 0x60 || c.R > 0xa0 {
+		t.Errorf("edge: got %v, want about half white", c)
+	}
+	if !unchanged(orig, m, r) {
+		t.Error("pixels outside the rectangle changed")
+	}
+}
+
+func TestPolygons(t *testing.T) {
+	orig := gradient()
+	m := gradient()
+	// No pixel center is on the triangle's edges.
+	tri := polygon.Polygon{{{10, 10}, {50.25, 10}, {10, 50.75}}}
+	for _, method := range []Method{Blank, Pixelate, Blur} {
+		copy(m.Pix, orig.Pix)
+		Polygons(m, []polygon.Polygon{tri}, &Options{Method: method, BlockSize: 4})
+		for y := 0; y < 64; y++ {
+			for x := 0; x < 64; x++ {
+				in := tri.Contains(float64(x)+0.5, float64(y)+0.5, polygon.NonZero)
+				changed := m.RGBAAt(x, y) != orig.RGBAAt(x, y)
+				if !in && changed {
+					t.Fatalf("method %d: (%d, %d) is outside the polygon but changed", method, x, y)
+				}
+				if in && method == Blank && !changed {
+					t.Fatalf("method %d: (%d, %d) is inside the polygon but unchanged", method, x, y)
+				}
+			}
+		}
+	}
+
+	// The triangle's corner is outside the image.
+	copy(m.Pix, orig.Pix)
+	Polygons(m, []polygon.Polygon{{{f64.Vec2{-10, -10}, f64.Vec2{20, 0}, f64.Vec2{0, 20}}}}, nil)
+	if got, want := m.RGBAAt(0, 0), (color.RGBA{0, 0, 0, 0xff}); got != want {
+		t.Errorf("clipped: got %v, want %v", got, want)
+	}
+}
+
+func near(a, b color.RGBA, tol int) bool {
+	d := func(x, y uint8) bool { return int(x)-int(y) <= tol && int(y)-int(x) <= tol }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
@@ -5,6 +5,7 @@
 package riff_test
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -111,3 +112,33 @@ var (
 	l01  = encodeMulti("LIST", "META", l0, d0, l1)
 	data = encodeMulti("RIFF", "ROOT", d0, d1, l01, d2, l2)
 )
+
+func ExampleWriter() {
+	buf := &bytes.Buffer{}
+	w := riff.NewWriter(buf, riff.FourCC{'R', 'O', 'O', 'T'})
+	w.WriteChunk(riff.FourCC{'O', 'N', 'E', ' '}, []byte("a"))
+	w.BeginList(riff.FourCC{'M', 'E', 'T', 'A'})
+	w.WriteChunk(riff.FourCC{'T', 'W', 'O', ' '}, []byte("bc"))
+	w.BeginChunk(riff.FourCC{'T', 'H', 'R', 'E'})
+	fmt.Fprintf(w, "%s", "def")
+	w.End()
+	w.End()
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	formType, r, err := riff.NewReader(buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("RIFF(%s)\n", formType)
+	if err := dump(r, ".\t"); err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// RIFF(ROOT)
+	// .	ONE  "a"
+	// .	LIST(META)
+	// .	.	TWO  "bc"
+	// .	.	THRE "def"
+}
@@ -9,6 +9,9 @@
 // header (containing a 4-byte chunk type and a 4-byte chunk length), the chunk
 // data (presented as an io.Reader), and some padding bytes.
 //
+// A Reader reads the chunks of a RIFF stream in order, a SeekReader reads them
+// in any order, and a Writer writes them.
+//
 // A detailed description of the format is at
 // http://www.tactilemedia.com/info/MCI_Control_Info.html
 package riff // import "golang.org/x/image/riff"
@@ -44,6 +47,9 @@ var LIST = FourCC{'L', 'I', 'S', 'T'}
 
 // NewReader returns the RIFF stream's form type, such as "AVI " or "WAVE", and
 // its chunks as a *Reader.
+//
+// If r also implements io.Seeker, such as an *io.SectionReader does, then the
+// unread data of each chunk is skipped by seeking instead of reading.
 func NewReader(r io.Reader) (formType FourCC, data *Reader, err error) {
 	var buf [chunkHeaderSize]byte
 	if _, err := io.ReadFull(r, buf[:]); err != nil {
@@ -99,13 +105,18 @@ func (z *Reader) Next() (chunkID FourCC, chunkLen uint32, chunkData io.Reader, e
 		return FourCC{}, 0, nil, z.err
 	}
 
-	// Drain the rest of the previous chunk.
+	// Drain the rest of the previous chunk, seeking past it if the underlying
+	// reader allows, so that large unread chunks are not read at all.
 	if z.chunkLen != 0 {
-		want := z.chunkLen
-		var got int64
-		got, z.err = io.Copy(ioutil.Discard, z.chunkReader)
-		if z.err == nil && uint32(got) != want {
-			z.err = errShortChunkData
+		if s, ok := z.r.(io.Seeker); ok {
+			z.err = z.skip(s)
+		} else {
+			want := z.chunkLen
+			var got int64
+			got, z.err = io.Copy(ioutil.Discard, z.chunkReader)
+			if z.err == nil && uint32(got) != want {
+				z.err = errShortChunkData
+			}
 		}
 		if z.err != nil {
 			return FourCC{}, 0, nil, z.err
@@ -156,6 +167,23 @@ func (z *Reader) Next() (chunkID FourCC, chunkLen uint32, chunkData io.Reader, e
 	return chunkID, z.chunkLen, z.chunkReader, nil
 }
 
+// skip seeks past the rest of the current chunk. It reads the chunk's last
+// byte, instead of seeking past it, to detect truncated chunk data.
+func (z *Reader) skip(s io.Seeker) error {
+	if _, err := s.Seek(int64(z.chunkLen)-1, io.SeekCurrent); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(z.r, z.buf[:1]); err != nil {
+		if err == io.EOF {
+			err = errShortChunkData
+		}
+		return err
+	}
+	z.totalLen -= z.chunkLen
+	z.chunkLen = 0
+	return nil
+}
+
 type chunkReader struct {
 	z *Reader
 }
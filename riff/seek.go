@@ -0,0 +1,155 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package riff
+
+import (
+	"io"
+)
+
+// Chunk is the location of a chunk in a SeekReader's underlying
+// io.ReadSeeker.
+type Chunk struct {
+	ID  FourCC
+	Len uint32
+	// Offset is the offset of the chunk's data, after its header, in the
+	// underlying io.ReadSeeker.
+	Offset int64
+}
+
+// SeekReader reads chunks from an underlying io.ReadSeeker. Unlike a Reader,
+// it reads only the chunk headers, and seeks past the chunk data, so that the
+// cost of skipping a chunk does not depend on its length. It also remembers
+// the chunks that it has read, so that their data can be read later, in any
+// order.
+type SeekReader struct {
+	r   io.ReadSeeker
+	err error
+
+	// pos is the offset of the next chunk header, and end is the offset of
+	// the end of the list's data. pos is greater than end if the previous
+	// chunk's padding byte is missing.
+	pos, end int64
+
+	chunks []Chunk
+	buf    [chunkHeaderSize]byte
+}
+
+// NewSeekReader returns the RIFF stream's form type, such as "AVI " or "WAVE",
+// and its chunks as a *SeekReader. The stream starts at r's current offset.
+func NewSeekReader(r io.ReadSeeker) (formType FourCC, data *SeekReader, err error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return FourCC{}, nil, err
+	}
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return FourCC{}, nil, err
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return FourCC{}, nil, err
+	}
+	var buf [chunkHeaderSize + 4]byte
+	if _, err := io.ReadFull(r, buf[:chunkHeaderSize]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errMissingRIFFChunkHeader
+		}
+		return FourCC{}, nil, err
+	}
+	if buf[0] != 'R' || buf[1] != 'I' || buf[2] != 'F' || buf[3] != 'F' {
+		return FourCC{}, nil, errMissingRIFFChunkHeader
+	}
+	// Checking the RIFF chunk's length against the stream's size up front
+	// means that no chunk inside it can be truncated.
+	chunkLen := u32(buf[4:])
+	if start+chunkHeaderSize+int64(chunkLen) > size {
+		return FourCC{}, nil, errShortChunkData
+	}
+	return newSeekListReader(r, Chunk{Len: chunkLen, Offset: start + chunkHeaderSize})
+}
+
+// newSeekListReader returns the list type and chunks of the LIST chunk c.
+func newSeekListReader(r io.ReadSeeker, c Chunk) (listType FourCC, data *SeekReader, err error) {
+	if c.Len < 4 {
+		return FourCC{}, nil, errShortChunkData
+	}
+	z := &SeekReader{
+		r:   r,
+		pos: c.Offset + 4,
+		end: c.Offset + int64(c.Len),
+	}
+	if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+		return FourCC{}, nil, err
+	}
+	if _, err := io.ReadFull(r, z.buf[:4]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errShortChunkData
+		}
+		return FourCC{}, nil, err
+	}
+	return FourCC{z.buf[0], z.buf[1], z.buf[2], z.buf[3]}, z, nil
+}
+
+// Next returns the next chunk. It returns io.EOF if there are no more chunks.
+// It reads the chunk's header but not its data, which can be read by Data.
+func (z *SeekReader) Next() (Chunk, error) {
+	if z.err != nil {
+		return Chunk{}, z.err
+	}
+	if z.pos > z.end {
+		z.err = errListSubchunkTooLong
+		return Chunk{}, z.err
+	}
+	if z.pos == z.end {
+		z.err = io.EOF
+		return Chunk{}, z.err
+	}
+	if z.end-z.pos < chunkHeaderSize {
+		z.err = errShortChunkHeader
+		return Chunk{}, z.err
+	}
+	if _, z.err = z.r.Seek(z.pos, io.SeekStart); z.err != nil {
+		return Chunk{}, z.err
+	}
+	if _, z.err = io.ReadFull(z.r, z.buf[:chunkHeaderSize]); z.err != nil {
+		if z.err == io.EOF || z.err == io.ErrUnexpectedEOF {
+			z.err = errShortChunkHeader
+		}
+		return Chunk{}, z.err
+	}
+	c := Chunk{
+		ID:     FourCC{z.buf[0], z.buf[1], z.buf[2], z.buf[3]},
+		Len:    u32(z.buf[4:]),
+		Offset: z.pos + chunkHeaderSize,
+	}
+	if int64(c.Len) > z.end-c.Offset {
+		z.err = errListSubchunkTooLong
+		return Chunk{}, z.err
+	}
+	z.pos = c.Offset + int64(c.Len) + int64(c.Len&1)
+	z.chunks = append(z.chunks, c)
+	return c, nil
+}
+
+// Chunks returns the chunks that Next has returned so far, in order.
+func (z *SeekReader) Chunks() []Chunk {
+	return z.chunks
+}
+
+// Data returns the data of the chunk c, which need not be the chunk most
+// recently returned by Next. The io.Reader returned shares the underlying
+// io.ReadSeeker, and becomes stale after the next call to a SeekReader method,
+// including those of other SeekReaders for the same RIFF stream.
+func (z *SeekReader) Data(c Chunk) (io.Reader, error) {
+	if _, err := z.r.Seek(c.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.LimitReader(z.r, int64(c.Len)), nil
+}
+
+// List returns the list type, such as "movi" or "wavl", and the chunks of the
+// LIST chunk c, as a *SeekReader.
+func (z *SeekReader) List(c Chunk) (listType FourCC, data *SeekReader, err error) {
+	return newSeekListReader(z.r, c)
+}
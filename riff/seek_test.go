@@ -0,0 +1,128 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package riff
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// countingReader counts the bytes read from an io.ReadSeeker.
+type countingReader struct {
+	io.ReadSeeker
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestSeekReader(t *testing.T) {
+	big := make([]byte, 1<<20)
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, FourCC{'R', 'O', 'O', 'T'})
+	w.WriteChunk(FourCC{'O', 'N', 'E', ' '}, []byte("a"))
+	w.WriteChunk(FourCC{'B', 'I', 'G', ' '}, big)
+	w.BeginList(FourCC{'M', 'E', 'T', 'A'})
+	w.WriteChunk(FourCC{'T', 'W', 'O', ' '}, []byte("bc"))
+	w.End()
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The stream need not start at the io.ReadSeeker's origin.
+	r := &countingReader{ReadSeeker: bytes.NewReader(append([]byte("prefix"), buf.Bytes()...))}
+	r.Seek(6, io.SeekStart)
+	formType, z, err := NewSeekReader(r)
+	if err != nil {
+		t.Fatalf("NewSeekReader: %v", err)
+	}
+	if formType != (FourCC{'R', 'O', 'O', 'T'}) {
+		t.Fatalf("formType: got %q, want %q", formType[:], "ROOT")
+	}
+	want := []Chunk{
+		{FourCC{'O', 'N', 'E', ' '}, 1, 26},
+		{FourCC{'B', 'I', 'G', ' '}, 1 << 20, 36},
+		{LIST, 14, 36 + 8 + 1<<20},
+	}
+	for i, w := range want {
+		c, err := z.Next()
+		if err != nil {
+			t.Fatalf("Next #%d: %v", i, err)
+		}
+		if c != w {
+			t.Errorf("Next #%d: got %v, want %v", i, c, w)
+		}
+	}
+	if _, err := z.Next(); err != io.EOF {
+		t.Fatalf("last Next: got %v, want %v", err, io.EOF)
+	}
+	// Skipping the big chunk's data reads only the chunk headers.
+	if r.n > 100 {
+		t.Errorf("read %d bytes, want at most 100", r.n)
+	}
+
+	chunks := z.Chunks()
+	if len(chunks) != len(want) {
+		t.Fatalf("Chunks: got %d chunks, want %d", len(chunks), len(want))
+	}
+	listType, list, err := z.List(chunks[2])
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if listType != (FourCC{'M', 'E', 'T', 'A'}) {
+		t.Errorf("listType: got %q, want %q", listType[:], "META")
+	}
+	c, err := list.Next()
+	if err != nil {
+		t.Fatalf("list Next: %v", err)
+	}
+	for _, tc := range []struct {
+		c    Chunk
+		want string
+	}{
+		{c, "bc"},
+		{chunks[0], "a"},
+	} {
+		data, err := z.Data(tc.c)
+		if err != nil {
+			t.Fatalf("Data: %v", err)
+		}
+		got, err := ioutil.ReadAll(data)
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s data: got %q, want %q", tc.c.ID[:], got, tc.want)
+		}
+	}
+}
+
+func TestSeekReaderErrors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		s    string
+		want error
+	}{
+		{"truncated RIFF chunk", "RIFF\x10\x00\x00\x00ABCD", errShortChunkData},
+		{"short RIFF chunk", "RIFF\x02\x00\x00\x00AB", errShortChunkData},
+		{"short chunk header", "RIFF\x08\x00\x00\x00ABCDabcd", errShortChunkHeader},
+		{"subchunk too long", "RIFF\x0d\x00\x00\x00ABCDabcd\x02\x00\x00\x00a", errListSubchunkTooLong},
+		{"missing padding byte", "RIFF\x0d\x00\x00\x00ABCDabcd\x01\x00\x00\x00a", errListSubchunkTooLong},
+	}
+	for _, tc := range testCases {
+		_, z, err := NewSeekReader(bytes.NewReader([]byte(tc.s)))
+		for err == nil {
+			_, err = z.Next()
+		}
+		if err != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.desc, err, tc.want)
+		}
+	}
+}
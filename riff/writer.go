@@ -0,0 +1,228 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package riff
+
+import (
+	"errors"
+	"io"
+	"math"
+)
+
+var (
+	errChunkTooLong      = errors.New("riff: chunk too long")
+	errClosedWriter      = errors.New("riff: closed writer")
+	errNestedDataChunk   = errors.New("riff: chunk inside a data chunk")
+	errNoOpenChunk       = errors.New("riff: no open chunk to end")
+	errWriteOutsideChunk = errors.New("riff: write outside a data chunk")
+)
+
+// putU32 encodes u as a little-endian integer in the first four bytes of b.
+func putU32(b []byte, u uint32) {
+	b[0] = byte(u >> 0)
+	b[1] = byte(u >> 8)
+	b[2] = byte(u >> 16)
+	b[3] = byte(u >> 24)
+}
+
+// Writer writes a RIFF stream's chunks.
+//
+// A chunk's length is in its header, before its data, so the header of a
+// chunk whose length is not known in advance, such as a LIST chunk or the
+// RIFF stream itself, is written with a placeholder length that is updated
+// when the chunk ends. If the underlying io.Writer also implements
+// io.WriteSeeker, such as an *os.File does, then the update seeks back to the
+// header and the stream is written as it goes. Otherwise, the whole stream is
+// buffered in memory until Close is called.
+type Writer struct {
+	w  io.Writer
+	ws io.WriteSeeker
+	// buf holds the stream when ws is nil.
+	buf []byte
+	// base is the offset in ws of the start of the stream, and n is the
+	// number of bytes written since then.
+	base int64
+	n    int64
+	// open are the offsets of the headers of the chunks that have begun but
+	// not yet ended, from the RIFF chunk outwards.
+	open []int64
+	// inData is whether the innermost open chunk is a data chunk, begun by
+	// BeginChunk, instead of a LIST chunk or the RIFF chunk.
+	inData bool
+	closed bool
+	err    error
+}
+
+// NewWriter returns a *Writer for a RIFF stream with the given form type, such
+// as "AVI " or "WAVE". The caller must call Close to finish the stream.
+func NewWriter(w io.Writer, formType FourCC) *Writer {
+	z := &Writer{w: w}
+	if ws, ok := w.(io.WriteSeeker); ok {
+		z.ws = ws
+		z.base, z.err = ws.Seek(0, io.SeekCurrent)
+	}
+	z.begin(FourCC{'R', 'I', 'F', 'F'})
+	z.write(formType[:])
+	return z
+}
+
+// write writes p to the stream, unless there was a previous error.
+func (z *Writer) write(p []byte) {
+	if z.err != nil {
+		return
+	}
+	if z.ws == nil {
+		z.buf = append(z.buf, p...)
+	} else if _, z.err = z.ws.Write(p); z.err != nil {
+		return
+	}
+	z.n += int64(len(p))
+}
+
+// writeHeader writes a chunk header.
+func (z *Writer) writeHeader(chunkID FourCC, chunkLen uint32) {
+	var buf [chunkHeaderSize]byte
+	copy(buf[:4], chunkID[:])
+	putU32(buf[4:], chunkLen)
+	z.write(buf[:])
+}
+
+// begin writes a chunk header with a placeholder length, and pushes the chunk
+// onto the stack of open chunks.
+func (z *Writer) begin(chunkID FourCC) {
+	z.open = append(z.open, z.n)
+	z.writeHeader(chunkID, 0)
+}
+
+// end pops the innermost open chunk, writes its padding byte, if any, and
+// updates its header's length.
+func (z *Writer) end() {
+	offset := z.open[len(z.open)-1]
+	z.open = z.open[:len(z.open)-1]
+	z.inData = false
+	if z.err != nil {
+		return
+	}
+	n := z.n - offset - chunkHeaderSize
+	if n > math.MaxUint32 {
+		z.err = errChunkTooLong
+		return
+	}
+	if n&1 == 1 {
+		z.write([]byte{0})
+	}
+
+	var buf [4]byte
+	putU32(buf[:], uint32(n))
+	if z.ws == nil {
+		copy(z.buf[offset+4:], buf[:])
+		return
+	}
+	if _, z.err = z.ws.Seek(z.base+offset+4, io.SeekStart); z.err != nil {
+		return
+	}
+	if _, z.err = z.ws.Write(buf[:]); z.err != nil {
+		return
+	}
+	_, z.err = z.ws.Seek(z.base+z.n, io.SeekStart)
+}
+
+// check returns the error, if any, of adding a chunk to the innermost open
+// chunk.
+func (z *Writer) check() error {
+	if z.closed {
+		return errClosedWriter
+	}
+	if z.inData {
+		return errNestedDataChunk
+	}
+	return z.err
+}
+
+// WriteChunk writes a chunk with the given ID and data, and its padding byte,
+// if any.
+func (z *Writer) WriteChunk(chunkID FourCC, chunkData []byte) error {
+	if err := z.check(); err != nil {
+		return err
+	}
+	if uint64(len(chunkData)) > math.MaxUint32 {
+		return errChunkTooLong
+	}
+	z.writeHeader(chunkID, uint32(len(chunkData)))
+	z.write(chunkData)
+	if len(chunkData)&1 == 1 {
+		z.write([]byte{0})
+	}
+	return z.err
+}
+
+// BeginChunk begins a chunk with the given ID, whose data is written by
+// subsequent calls to Write until End is called.
+func (z *Writer) BeginChunk(chunkID FourCC) error {
+	if err := z.check(); err != nil {
+		return err
+	}
+	z.begin(chunkID)
+	z.inData = true
+	return z.err
+}
+
+// BeginList begins a LIST chunk with the given list type, such as "movi" or
+// "wavl". Its chunks are the ones written until the matching call to End.
+func (z *Writer) BeginList(listType FourCC) error {
+	if err := z.check(); err != nil {
+		return err
+	}
+	z.begin(LIST)
+	z.write(listType[:])
+	return z.err
+}
+
+// Write writes p as part of the data of the chunk begun by BeginChunk.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errClosedWriter
+	}
+	if !z.inData {
+		return 0, errWriteOutsideChunk
+	}
+	if z.err != nil {
+		return 0, z.err
+	}
+	z.write(p)
+	if z.err != nil {
+		return 0, z.err
+	}
+	return len(p), nil
+}
+
+// End ends the innermost chunk begun by BeginChunk or BeginList.
+func (z *Writer) End() error {
+	if z.closed {
+		return errClosedWriter
+	}
+	if len(z.open) <= 1 {
+		return errNoOpenChunk
+	}
+	z.end()
+	return z.err
+}
+
+// Close ends any open chunks and the RIFF stream, and, if the stream was
+// buffered, writes it to the underlying io.Writer. It does not close the
+// underlying io.Writer.
+func (z *Writer) Close() error {
+	if z.closed {
+		return errClosedWriter
+	}
+	for len(z.open) > 0 {
+		z.end()
+	}
+	z.closed = true
+	if z.err == nil && z.ws == nil {
+		_, z.err = z.w.Write(z.buf)
+		z.buf = nil
+	}
+	return z.err
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package riff
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// writeSeeker is an in-memory io.WriteSeeker.
+type writeSeeker struct {
+	buf []byte
+	off int64
+}
+
+func (w *writeSeeker) Write(p []byte) (int, error) {
+	if n := w.off + int64(len(p)); n > int64(len(w.buf)) {
+		w.buf = append(w.buf, make([]byte, n-int64(len(w.buf)))...)
+	}
+	copy(w.buf[w.off:], p)
+	w.off += int64(len(p))
+	return len(p), nil
+}
+
+func (w *writeSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		w.off = offset
+	case io.SeekCurrent:
+		w.off += offset
+	case io.SeekEnd:
+		w.off = int64(len(w.buf)) + offset
+	}
+	return w.off, nil
+}
+
+func TestWriter(t *testing.T) {
+	want := []byte("RIFF\x38\x00\x00\x00ROOT" +
+		"ONE \x01\x00\x00\x00a\x00" +
+		"LIST\x1a\x00\x00\x00META" +
+		"TWO \x02\x00\x00\x00bc" +
+		"THRE\x03\x00\x00\x00def\x00" +
+		"ZERO\x00\x00\x00\x00")
+	build := func(w io.Writer) error {
+		z := NewWriter(w, FourCC{'R', 'O', 'O', 'T'})
+		if err := z.WriteChunk(FourCC{'O', 'N', 'E', ' '}, []byte("a")); err != nil {
+			return err
+		}
+		if err := z.BeginList(FourCC{'M', 'E', 'T', 'A'}); err != nil {
+			return err
+		}
+		if err := z.WriteChunk(FourCC{'T', 'W', 'O', ' '}, []byte("bc")); err != nil {
+			return err
+		}
+		if err := z.BeginChunk(FourCC{'T', 'H', 'R', 'E'}); err != nil {
+			return err
+		}
+		for _, s := range []string{"d", "ef"} {
+			if _, err := z.Write([]byte(s)); err != nil {
+				return err
+			}
+		}
+		if err := z.End(); err != nil {
+			return err
+		}
+		if err := z.End(); err != nil {
+			return err
+		}
+		// Close ends the ZERO chunk.
+		if err := z.BeginChunk(FourCC{'Z', 'E', 'R', 'O'}); err != nil {
+			return err
+		}
+		return z.Close()
+	}
+
+	buf := &bytes.Buffer{}
+	if err := build(buf); err != nil {
+		t.Fatalf("io.Writer: %v", err)
+	}
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("io.Writer:\ngot  %q\nwant %q", got, want)
+	}
+
+	// The stream need not start at the io.WriteSeeker's origin.
+	ws := &writeSeeker{buf: []byte("prefix")}
+	ws.off = int64(len(ws.buf))
+	if err := build(ws); err != nil {
+		t.Fatalf("io.WriteSeeker: %v", err)
+	}
+	if got := ws.buf; !bytes.Equal(got, append([]byte("prefix"), want...)) {
+		t.Errorf("io.WriteSeeker:\ngot  %q\nwant %q", got, want)
+	}
+
+	formType, r, err := NewReader(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	if formType != (FourCC{'R', 'O', 'O', 'T'}) {
+		t.Errorf("formType: got %q, want %q", formType[:], "ROOT")
+	}
+	var ids []FourCC
+	for {
+		chunkID, _, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		ids = append(ids, chunkID)
+	}
+	if len(ids) != 3 || ids[0] != (FourCC{'O', 'N', 'E', ' '}) || ids[1] != LIST || ids[2] != (FourCC{'Z', 'E', 'R', 'O'}) {
+		t.Errorf("chunk IDs: got %q", ids)
+	}
+}
+
+func TestWriterMisuse(t *testing.T) {
+	z := NewWriter(&bytes.Buffer{}, FourCC{'R', 'O', 'O', 'T'})
+	if _, err := z.Write([]byte("a")); err != errWriteOutsideChunk {
+		t.Errorf("Write outside a chunk: got %v, want %v", err, errWriteOutsideChunk)
+	}
+	if err := z.End(); err != errNoOpenChunk {
+		t.Errorf("End with no open chunk: got %v, want %v", err, errNoOpenChunk)
+	}
+	if err := z.BeginChunk(FourCC{'a', 'b', 'c', 'd'}); err != nil {
+		t.Fatalf("BeginChunk: %v", err)
+	}
+	if err := z.BeginList(FourCC{'a', 'b', 'c', 'd'}); err != errNestedDataChunk {
+		t.Errorf("BeginList in a data chunk: got %v, want %v", err, errNestedDataChunk)
+	}
+	if err := z.WriteChunk(FourCC{'a', 'b', 'c', 'd'}, nil); err != errNestedDataChunk {
+		t.Errorf("WriteChunk in a data chunk: got %v, want %v", err, errNestedDataChunk)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := z.WriteChunk(FourCC{'a', 'b', 'c', 'd'}, nil); err != errClosedWriter {
+		t.Errorf("WriteChunk after Close: got %v, want %v", err, errClosedWriter)
+	}
+}
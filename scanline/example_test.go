@@ -0,0 +1,56 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package scanline_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"log"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/tiff"
+)
+
+// This example scales an image and encodes the result as a TIFF, holding only
+// 16 rows of the scaled image in memory at a time.
+func Example() {
+	src := image.NewGray(image.Rect(0, 0, 400, 300))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	const w, h, bandHeight = 200, 150, 16
+
+	buf := new(bytes.Buffer)
+	z, err := tiff.NewRowWriter(buf, w, h, color.GrayModel, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	band := image.NewGray(image.Rect(0, 0, w, bandHeight))
+	draw.CatmullRom.Scale(band, image.Rect(0, 0, w, h), src, src.Bounds(), draw.Src, &draw.Options{
+		RowsDone: func(y0, y1 int) {
+			if err == nil {
+				err = z.WriteRows(band.SubImage(image.Rect(0, y0, w, y1)))
+			}
+			// Move the band on to the next rows.
+			band.Rect = image.Rect(0, y1, w, y1+bandHeight)
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	m, err := tiff.Decode(buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(m.Bounds())
+	// Output:
+	// (0,0)-(200,150)
+}
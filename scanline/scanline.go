@@ -0,0 +1,30 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package scanline defines an interface for image encoders that write an
+// image's rows as they become available, from top to bottom, so that the
+// whole image never needs to be in memory at once.
+//
+// The bmp and tiff packages' NewRowWriter functions return such encoders.
+// Together with the golang.org/x/image/draw package's Options.RowsDone field,
+// they allow resizing and re-encoding an image in constant memory.
+package scanline // import "golang.org/x/image/scanline"
+
+import (
+	"image"
+)
+
+// Writer encodes the rows of an image of a fixed width and height, in order
+// from top to bottom.
+type Writer interface {
+	// WriteRows encodes all of m's rows as the next rows of the image. m's
+	// width must be the image's width, but its bounds need not match the
+	// position of its rows in the image.
+	WriteRows(m image.Image) error
+
+	// Close finishes encoding the image, and returns an error if fewer rows
+	// were written than the image's height. It does not close the
+	// underlying io.Writer.
+	Close() error
+}
@@ -0,0 +1,340 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/polygon"
+)
+
+// Channel masks for multi-channel edge colors.
+const (
+	red   = 1 << 0
+	green = 1 << 1
+	blue  = 1 << 2
+	white = red | green | blue
+)
+
+// edge is a line segment of a flattened contour. color is the set of
+// channels that the edge contributes to. joint is whether a is the start of
+// an original (unflattened) segment, and so could be a corner. extendA and
+// extendB are whether the edge's pseudo-distance extends beyond a and b,
+// which are the ends of a run of same-colored edges.
+type edge struct {
+	a, b             f64.Vec2
+	color            uint8
+	joint            bool
+	extendA, extendB bool
+}
+
+// outline is a flattened glyph outline.
+type outline struct {
+	contours [][]edge
+	// insideSign is +1 if the filled side of each edge is to the right of
+	// its direction (in the Y-down coordinate space) and -1 otherwise.
+	insideSign float64
+}
+
+// FromSegments returns the signed distance field, with bounds r, of the
+// glyph outline segs, as returned by sfnt's Font.LoadGlyph. The outline's
+// coordinates are in pixels, and so r is typically segs.Bounds(), rounded
+// out to whole pixels and padded by the spread. The glyph is filled with the
+// non-zero winding rule.
+//
+// Unlike FromMask, the distances are computed analytically, from the
+// outline's (flattened) segments, so they have sub-pixel accuracy.
+func FromSegments(segs sfnt.Segments, r image.Rectangle, spread float64) *image.Gray {
+	o := flatten(segs)
+	inside := o.polygon().Mask(r, polygon.NonZero)
+	dst := image.NewGray(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			p := f64.Vec2{float64(x) + 0.5, float64(y) + 0.5}
+			d := math.Inf(+1)
+			for _, c := range o.contours {
+				for i := range c {
+					if di := c[i].distance(p); d > di {
+						d = di
+					}
+				}
+			}
+			if inside.AlphaAt(x, y).A == 0 {
+				d = -d
+			}
+			dst.SetGray(x, y, color.Gray{encode(d, spread)})
+		}
+	}
+	return dst
+}
+
+// MultiFromSegments is like FromSegments but returns a multi-channel signed
+// distance field, in the red, green and blue channels of an opaque RGBA
+// image. The median of the three channels reconstructs the outline, and
+// unlike a single-channel field, it preserves sharp corners when magnified.
+//
+// This is a simple implementation of the technique from "Shape
+// Decomposition for Multi-channel Distance Fields" by Viktor Chlumský. It
+// colors each contour's edges by splitting the contour at its corners, and
+// does no error correction, so some glyphs, such as those with contours
+// that have a single corner, or with very close contours, may show
+// artifacts. FromSegments is more robust.
+func MultiFromSegments(segs sfnt.Segments, r image.Rectangle, spread float64) *image.RGBA {
+	o := flatten(segs)
+	o.colorEdges()
+	dst := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			p := f64.Vec2{float64(x) + 0.5, float64(y) + 0.5}
+			var (
+				minD  = [3]float64{math.Inf(+1), math.Inf(+1), math.Inf(+1)}
+				minO  [3]float64
+				nearE [3]*edge
+			)
+			for _, c := range o.contours {
+				for i := range c {
+					e := &c[i]
+					d, orth := e.distance(p), -1.0
+					for ch := uint(0); ch < 3; ch++ {
+						if e.color&(1<<ch) == 0 || minD[ch] < d-distanceEpsilon {
+							continue
+						}
+						if minD[ch] <= d+distanceEpsilon {
+							// Break ties, such as at a shared end point, in
+							// favor of the edge that p is more orthogonal to.
+							if orth < 0 {
+								orth = e.orthogonality(p)
+							}
+							if minO[ch] >= orth {
+								continue
+							}
+						}
+						if orth < 0 {
+							orth = e.orthogonality(p)
+						}
+						minD[ch], minO[ch], nearE[ch] = d, orth, e
+					}
+				}
+			}
+			var v [3]uint8
+			for ch := range v {
+				if e := nearE[ch]; e != nil {
+					v[ch] = encode(o.insideSign*e.signedPseudoDistance(p), spread)
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{v[0], v[1], v[2], 0xff})
+		}
+	}
+	return dst
+}
+
+// flatten converts segs to contours of line segments.
+func flatten(segs sfnt.Segments) *outline {
+	o := &outline{}
+	var (
+		contour []edge
+		first   f64.Vec2
+		pen     f64.Vec2
+	)
+	joint := false
+	lineTo := func(q f64.Vec2) {
+		if q != pen {
+			contour = append(contour, edge{a: pen, b: q, color: white, joint: joint})
+			joint = false
+		}
+		pen = q
+	}
+	closeContour := func() {
+		joint = true
+		lineTo(first)
+		if len(contour) > 0 {
+			o.contours = append(o.contours, contour)
+		}
+		contour = nil
+	}
+	for _, s := range segs {
+		var a [3]f64.Vec2
+		for i := range a {
+			a[i] = f64.Vec2{float64(s.Args[i].X) / 64, float64(s.Args[i].Y) / 64}
+		}
+		joint = true
+		switch s.Op {
+		case sfnt.SegmentOpMoveTo:
+			closeContour()
+			first, pen = a[0], a[0]
+		case sfnt.SegmentOpLineTo:
+			lineTo(a[0])
+		case sfnt.SegmentOpQuadTo:
+			p0 := pen
+			n := numPieces(devSquared(p0, a[0], a[1]))
+			for i := 1; i < n; i++ {
+				t := float64(i) / float64(n)
+				lineTo(lerp(t, lerp(t, p0, a[0]), lerp(t, a[0], a[1])))
+			}
+			lineTo(a[1])
+		case sfnt.SegmentOpCubeTo:
+			p0 := pen
+			n := numPieces(math.Max(devSquared(p0, a[0], a[2]), devSquared(p0, a[1], a[2])))
+			for i := 1; i < n; i++ {
+				t := float64(i) / float64(n)
+				ab, bc, cd := lerp(t, p0, a[0]), lerp(t, a[0], a[1]), lerp(t, a[1], a[2])
+				lineTo(lerp(t, lerp(t, ab, bc), lerp(t, bc, cd)))
+			}
+			lineTo(a[2])
+		}
+	}
+	closeContour()
+
+	// The sum of the contours' signed areas is dominated by the outer
+	// contours, whose orientation determines which side of every edge is
+	// filled. In the Y-down coordinate space, a positive area means that
+	// the contours go clockwise, with the filled side to their right.
+	area := 0.0
+	for _, c := range o.contours {
+		for _, e := range c {
+			area += e.a[0]*e.b[1] - e.b[0]*e.a[1]
+		}
+	}
+	o.insideSign = +1
+	if area < 0 {
+		o.insideSign = -1
+	}
+	return o
+}
+
+// polygon returns o as a polygon.Polygon.
+func (o *outline) polygon() polygon.Polygon {
+	p := make(polygon.Polygon, len(o.contours))
+	for i, c := range o.contours {
+		ring := make([]f64.Vec2, len(c))
+		for j, e := range c {
+			ring[j] = e.a
+		}
+		p[i] = ring
+	}
+	return p
+}
+
+// cornerThreshold is the sine of the smallest angle, between consecutive
+// segments' directions, that counts as a corner: about 8 degrees.
+const cornerThreshold = 0.14
+
+// colorEdges splits each contour into runs of edges between corners, and
+// gives consecutive runs different colors that share exactly one channel. A
+// contour with fewer than two corners is left white.
+func (o *outline) colorEdges() {
+	colors := [3]uint8{red | blue, green | blue, red | green}
+	for _, c := range o.contours {
+		n := len(c)
+		var corners []int
+		for i := range c {
+			if c[i].joint && isCorner(&c[(i+n-1)%n], &c[i]) {
+				corners = append(corners, i)
+			}
+		}
+		if len(corners) < 2 {
+			continue
+		}
+		for k, start := range corners {
+			end := corners[(k+1)%len(corners)]
+			col := colors[k%3]
+			if k == len(corners)-1 && k%3 == 0 {
+				// Don't give the last run the same color as the first.
+				col = colors[1]
+			}
+			for i := start; ; i = (i + 1) % n {
+				c[i].color = col
+				c[i].extendA = i == start
+				c[i].extendB = (i+1)%n == end
+				if (i+1)%n == end {
+					break
+				}
+			}
+		}
+	}
+}
+
+// isCorner returns whether the direction changes sharply between e0 and e1,
+// where e1 follows e0.
+func isCorner(e0, e1 *edge) bool {
+	d0, d1 := normalize(sub(e0.b, e0.a)), normalize(sub(e1.b, e1.a))
+	return dot(d0, d1) <= 0 || math.Abs(cross(d0, d1)) > cornerThreshold
+}
+
+// distanceEpsilon is the tolerance within which two edges are equally distant
+// from a point.
+const distanceEpsilon = 1e-9
+
+// orthogonality returns the absolute sine of the angle between e and the line
+// from p to e's nearest point to p.
+func (e *edge) orthogonality(p f64.Vec2) float64 {
+	ab, ap := sub(e.b, e.a), sub(p, e.a)
+	t := dot(ap, ab) / dot(ab, ab)
+	t = math.Max(0, math.Min(1, t))
+	q := sub(p, lerp(t, e.a, e.b))
+	return math.Abs(cross(normalize(ab), normalize(q)))
+}
+
+// distance returns the unsigned distance from p to e.
+func (e *edge) distance(p f64.Vec2) float64 {
+	ab, ap := sub(e.b, e.a), sub(p, e.a)
+	t := dot(ap, ab) / dot(ab, ab)
+	t = math.Max(0, math.Min(1, t))
+	return length(sub(p, lerp(t, e.a, e.b)))
+}
+
+// signedPseudoDistance returns the distance from p to e, positive to the
+// right of e's direction. Beyond the ends of a run of same-colored edges, it
+// is the distance to e's extended line instead of to e's end point.
+func (e *edge) signedPseudoDistance(p f64.Vec2) float64 {
+	ab, ap := sub(e.b, e.a), sub(p, e.a)
+	t := dot(ap, ab) / dot(ab, ab)
+	if (t < 0 && e.extendA) || (t > 1 && e.extendB) {
+		return cross(ab, ap) / length(ab)
+	}
+	t = math.Max(0, math.Min(1, t))
+	d := length(sub(p, lerp(t, e.a, e.b)))
+	if cross(ab, ap) < 0 {
+		return -d
+	}
+	return d
+}
+
+// numPieces returns how many line segments approximate a Bézier curve, given
+// its devSquared measure of curviness.
+func numPieces(devsq float64) int {
+	const tol = 12
+	return 1 + int(math.Sqrt(math.Sqrt(tol*devsq)))
+}
+
+// devSquared is like the vector package's function of the same name.
+func devSquared(a, b, c f64.Vec2) float64 {
+	devx := a[0] - 2*b[0] + c[0]
+	devy := a[1] - 2*b[1] + c[1]
+	return devx*devx + devy*devy
+}
+
+func lerp(t float64, p, q f64.Vec2) f64.Vec2 {
+	return f64.Vec2{p[0] + t*(q[0]-p[0]), p[1] + t*(q[1]-p[1])}
+}
+
+func sub(p, q f64.Vec2) f64.Vec2 { return f64.Vec2{p[0] - q[0], p[1] - q[1]} }
+
+func dot(p, q f64.Vec2) float64 { return p[0]*q[0] + p[1]*q[1] }
+
+func cross(p, q f64.Vec2) float64 { return p[0]*q[1] - p[1]*q[0] }
+
+func length(p f64.Vec2) float64 { return math.Sqrt(dot(p, p)) }
+
+func normalize(p f64.Vec2) f64.Vec2 {
+	if l := length(p); l != 0 {
+		return f64.Vec2{p[0] / l, p[1] / l}
+	}
+	return p
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sdf generates signed distance fields from alpha masks and from
+// glyph outlines.
+//
+// A signed distance field stores, for each pixel, the distance from that
+// pixel's center to the nearest edge of a shape, positive inside the shape
+// and negative outside. Sampled with bilinear filtering and thresholded, for
+// example by a GPU fragment shader, it reproduces the shape's edges crisply
+// at any scale, which suits text and icon rendering.
+//
+// Distances are in pixels and are encoded in 8 bits: a distance d maps to
+// 0x80 + d*0x80/spread, clamped to [0x00, 0xff]. Thus 0x80 is on the edge,
+// values above 0x80 are inside and the spread is the largest distance that
+// can be represented.
+package sdf // import "golang.org/x/image/sdf"
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// encode returns the 8-bit encoding of the signed distance d.
+func encode(d, spread float64) uint8 {
+	v := math.Floor(0x80 + d*0x80/spread + 0.5)
+	if v < 0 {
+		return 0
+	}
+	if v > 0xff {
+		return 0xff
+	}
+	return uint8(v)
+}
+
+// Decode returns the signed distance, in pixels, encoded by the 8-bit value v
+// for the given spread.
+func Decode(v uint8, spread float64) float64 {
+	return (float64(v) - 0x80) * spread / 0x80
+}
+
+// FromMask returns the signed distance field of the shape defined by m's
+// alpha channel. A pixel is inside the shape if its alpha is at least half
+// opaque. The result has the same bounds as m.
+//
+// The distances are exact Euclidean distances between pixel centers, less
+// half a pixel, so they are only accurate to about half a pixel. For
+// sub-pixel accuracy, generate the field from a mask rendered at a higher
+// resolution and then scale it down, or use FromSegments.
+func FromMask(m image.Image, spread float64) *image.Gray {
+	b := m.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewGray(b)
+	if w <= 0 || h <= 0 {
+		return dst
+	}
+
+	inside := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			_, _, _, a := m.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			inside[y*w+x] = a >= 0x8000
+		}
+	}
+
+	// toInside[i] is the squared distance from pixel i to the nearest inside
+	// pixel, and toOutside[i] likewise to the nearest outside pixel.
+	toInside := squaredDistances(inside, w, h, true)
+	toOutside := squaredDistances(inside, w, h, false)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			d := 0.0
+			if inside[i] {
+				d = math.Sqrt(toOutside[i]) - 0.5
+			} else {
+				d = 0.5 - math.Sqrt(toInside[i])
+			}
+			dst.SetGray(b.Min.X+x, b.Min.Y+y, color.Gray{encode(d, spread)})
+		}
+	}
+	return dst
+}
+
+// squaredDistances returns the squared Euclidean distance from every pixel of
+// a w×h grid to the nearest pixel whose inside value equals target. Pixels
+// with no such pixel in the grid get +Inf.
+//
+// It implements the linear time algorithm from "Distance Transforms of
+// Sampled Functions" by Felzenszwalb and Huttenlocher, applied first to
+// columns and then to rows.
+func squaredDistances(inside []bool, w, h int, target bool) []float64 {
+	n := w
+	if n < h {
+		n = h
+	}
+	var (
+		f   = make([]float64, n)
+		out = make([]float64, n)
+		v   = make([]int, n)
+		z   = make([]float64, n+1)
+		d   = make([]float64, w*h)
+	)
+	for i := range d {
+		if inside[i] == target {
+			d[i] = 0
+		} else {
+			d[i] = math.Inf(+1)
+		}
+	}
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			f[y] = d[y*w+x]
+		}
+		transform1D(out[:h], f[:h], v, z)
+		for y := 0; y < h; y++ {
+			d[y*w+x] = out[y]
+		}
+	}
+	for y := 0; y < h; y++ {
+		row := d[y*w : (y+1)*w]
+		copy(f, row)
+		transform1D(out[:w], f[:w], v, z)
+		copy(row, out[:w])
+	}
+	return d
+}
+
+// transform1D sets out[q] to the minimum, over all p, of (q-p)² + f[p]. v and
+// z are scratch buffers of at least len(f) and len(f)+1 elements.
+func transform1D(out, f []float64, v []int, z []float64) {
+	n := len(f)
+	k := -1
+	for q := 0; q < n; q++ {
+		if math.IsInf(f[q], +1) {
+			continue
+		}
+		for k >= 0 {
+			p := v[k]
+			s := ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+			if s > z[k] {
+				break
+			}
+			k--
+		}
+		k++
+		v[k] = q
+		if k == 0 {
+			z[k] = math.Inf(-1)
+		} else {
+			p := v[k-1]
+			z[k] = ((f[q] + float64(q*q)) - (f[p] + float64(p*p))) / float64(2*q-2*p)
+		}
+		z[k+1] = math.Inf(+1)
+	}
+	if k < 0 {
+		for q := range out {
+			out[q] = math.Inf(+1)
+		}
+		return
+	}
+	j := 0
+	for q := 0; q < n; q++ {
+		for z[j+1] < float64(q) {
+			j++
+		}
+		dq := float64(q - v[j])
+		out[q] = dq*dq + f[v[j]]
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sdf
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// square returns the segments of a clockwise square from (x0, y0) to (x1,
+// y1), in pixels.
+func square(x0, y0, x1, y1 float64) sfnt.Segments {
+	p := func(x, y float64) [3]fixed.Point26_6 {
+		return [3]fixed.Point26_6{{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)}}
+	}
+	return sfnt.Segments{
+		{Op: sfnt.SegmentOpMoveTo, Args: p(x0, y0)},
+		{Op: sfnt.SegmentOpLineTo, Args: p(x1, y0)},
+		{Op: sfnt.SegmentOpLineTo, Args: p(x1, y1)},
+		{Op: sfnt.SegmentOpLineTo, Args: p(x0, y1)},
+	}
+}
+
+func median(a, b, c uint8) uint8 {
+	if a > b {
+		a, b = b, a
+	}
+	if b > c {
+		b = c
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func TestEncodeDecode(t *testing.T) {
+	const spread = 4
+	for _, d := range []float64{-4, -1.5, 0, 0.5, 3.96875} {
+		if got := Decode(encode(d, spread), spread); math.Abs(got-d) > spread/256.0 {
+			t.Errorf("d=%v: round trip gave %v", d, got)
+		}
+	}
+	if got := encode(100, spread); got != 0xff {
+		t.Errorf("large d: got %#02x, want 0xff", got)
+	}
+	if got := encode(-100, spread); got != 0x00 {
+		t.Errorf("small d: got %#02x, want 0x00", got)
+	}
+}
+
+func TestFromMask(t *testing.T) {
+	m := image.NewAlpha(image.Rect(0, 0, 20, 20))
+	for y := 5; y < 15; y++ {
+		for x := 5; x < 15; x++ {
+			m.Pix[m.PixOffset(x, y)] = 0xff
+		}
+	}
+	const spread = 8
+	f := FromMask(m, spread)
+	testCases := []struct {
+		x, y int
+		want float64
+	}{
+		{5, 10, 0.5},
+		{4, 10, -0.5},
+		{9, 10, 4.5},
+		{1, 10, -3.5},
+		{2, 2, 0.5 - 3*math.Sqrt2},
+	}
+	for _, tc := range testCases {
+		if got := Decode(f.GrayAt(tc.x, tc.y).Y, spread); math.Abs(got-tc.want) > 0.05 {
+			t.Errorf("(%d, %d): got %.3f, want %.3f", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestFromSegments(t *testing.T) {
+	segs := square(4, 4, 16, 16)
+	const spread = 8
+	f := FromSegments(segs, image.Rect(0, 0, 20, 20), spread)
+	testCases := []struct {
+		x, y int
+		want float64
+	}{
+		{4, 10, 0.5},
+		{3, 10, -0.5},
+		{10, 10, 5.5},
+		{0, 10, -3.5},
+		{1, 1, -2.5 * math.Sqrt2},
+	}
+	for _, tc := range testCases {
+		if got := Decode(f.GrayAt(tc.x, tc.y).Y, spread); math.Abs(got-tc.want) > 0.05 {
+			t.Errorf("(%d, %d): got %.3f, want %.3f", tc.x, tc.y, got, tc.want)
+		}
+	}
+
+	// Reversing the contour's direction should not change the field, or
+	// which pixels the multi-channel field considers inside.
+	rev := sfnt.Segments{segs[0], segs[3], segs[2], segs[1]}
+	rev[1].Args, rev[3].Args = segs[3].Args, segs[1].Args
+	g := FromSegments(rev, image.Rect(0, 0, 20, 20), spread)
+	mf := MultiFromSegments(segs, image.Rect(0, 0, 20, 20), spread)
+	mg := MultiFromSegments(rev, image.Rect(0, 0, 20, 20), spread)
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			if f.GrayAt(x, y) != g.GrayAt(x, y) {
+				t.Fatalf("(%d, %d): reversed contour gave %v, want %v", x, y, g.GrayAt(x, y), f.GrayAt(x, y))
+			}
+			c, d := mf.RGBAAt(x, y), mg.RGBAAt(x, y)
+			if (median(c.R, c.G, c.B) >= 0x80) != (median(d.R, d.G, d.B) >= 0x80) {
+				t.Fatalf("(%d, %d): reversed contour gave multi-channel %v, original gave %v", x, y, d, c)
+			}
+		}
+	}
+}
+
+// TestGlyph tests that the signed distance fields of a real glyph, including
+// the multi-channel one's median, agree on which pixels are inside.
+func TestGlyph(t *testing.T) {
+	f, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b sfnt.Buffer
+	x, err := f.GlyphIndex(&b, 'g')
+	if err != nil {
+		t.Fatal(err)
+	}
+	segs, err := f.LoadGlyph(&b, x, fixed.I(48), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := segs.Bounds()
+	r := image.Rect(
+		bounds.Min.X.Floor()-4, bounds.Min.Y.Floor()-4,
+		bounds.Max.X.Ceil()+4, bounds.Max.Y.Ceil()+4,
+	)
+	const spread = 4
+	single := FromSegments(segs, r, spread)
+	multi := MultiFromSegments(segs, r, spread)
+	nInside, nDisagree := 0, 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			s := single.GrayAt(x, y).Y
+			c := multi.RGBAAt(x, y)
+			m := median(c.R, c.G, c.B)
+			if s >= 0x80 {
+				nInside++
+			}
+			// Ignore pixels very close to the edge.
+			if s < 0x78 || 0x88 < s {
+				if (s >= 0x80) != (m >= 0x80) {
+					nDisagree++
+				}
+			}
+		}
+	}
+	if nInside == 0 {
+		t.Fatal("no pixels inside the glyph")
+	}
+	if nDisagree != 0 {
+		t.Errorf("%d pixels disagree on being inside", nDisagree)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package spritesheet packs many small images into one or more larger
+// images, called atlases, such as for the textures of a game or the icons of
+// a web page.
+//
+// The images are placed by the golang.org/x/image/binpack package, which a
+// glyph atlas can also use to place glyphs as they are rendered.
+package spritesheet // import "golang.org/x/image/spritesheet"
+
+import (
+	"errors"
+	"image"
+	"sort"
+
+	"golang.org/x/image/binpack"
+	"golang.org/x/image/draw"
+)
+
+// DefaultSize is the default width and height of an atlas.
+const DefaultSize = 2048
+
+// Options are the packing parameters.
+type Options struct {
+	// Width and Height are the size of each atlas. Zero means DefaultSize.
+	Width, Height int
+	// Heuristic is how the images are placed within an atlas.
+	Heuristic binpack.Heuristic
+	// Padding is the number of transparent pixels between the images, and
+	// between the images and the atlas edges.
+	Padding int
+	// Extrude is the number of pixels by which the edge pixels of each image
+	// are repeated outwards, so that sampling an image with a filter does
+	// not bleed in its neighbors' or the padding's pixels. The extruded
+	// pixels are in addition to the Padding.
+	Extrude int
+	// Crop is whether to crop each atlas to the images it holds, plus their
+	// padding. Otherwise, each atlas is Width by Height pixels.
+	Crop bool
+}
+
+// Sprite is the placement of an image within the atlases.
+type Sprite struct {
+	// Atlas is the index of the atlas that holds the image.
+	Atlas int
+	// Rect is the image's bounds within the atlas, excluding the extruded
+	// pixels.
+	Rect image.Rectangle
+}
+
+// Sheet is a set of packed images.
+type Sheet struct {
+	Atlases []*image.RGBA
+	// Sprites holds the placement of each image, in the order that the
+	// images were passed to Pack.
+	Sprites []Sprite
+}
+
+// Pack packs the images into as few atlases as it can. A nil opts is
+// equivalent to a zero Options.
+//
+// The images are placed from the tallest to the shortest, which packs more
+// tightly than placing them in the given order. Each image is placed in the
+// first atlas with room for it, and a new atlas is added when none has room.
+func Pack(images []image.Image, opts *Options) (*Sheet, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Width == 0 {
+		o.Width = DefaultSize
+	}
+	if o.Height == 0 {
+		o.Height = DefaultSize
+	}
+	if o.Width < 0 || o.Height < 0 || o.Padding < 0 || o.Extrude < 0 {
+		return nil, errors.New("spritesheet: negative size, padding or extrusion")
+	}
+
+	// The bins are smaller than the atlases by the padding along their right
+	// and bottom edges. Each image takes a cell of the bin, made of the
+	// image, its extruded pixels and the padding along its left and top
+	// edges.
+	binW, binH := o.Width-o.Padding, o.Height-o.Padding
+	margin := 2*o.Extrude + o.Padding
+	order := make([]int, len(images))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		bi, bj := images[order[i]].Bounds(), images[order[j]].Bounds()
+		if bi.Dy() != bj.Dy() {
+			return bi.Dy() > bj.Dy()
+		}
+		return bi.Dx() > bj.Dx()
+	})
+
+	var packers []*binpack.Packer
+	s := &Sheet{
+		Sprites: make([]Sprite, len(images)),
+	}
+	for _, i := range order {
+		size := images[i].Bounds().Size()
+		cellW, cellH := size.X+margin, size.Y+margin
+		atlas, cell, ok := 0, image.Rectangle{}, false
+		for ; atlas < len(packers); atlas++ {
+			if cell, ok = packers[atlas].Insert(cellW, cellH); ok {
+				break
+			}
+		}
+		if !ok {
+			p := binpack.NewPacker(binW, binH, o.Heuristic)
+			if cell, ok = p.Insert(cellW, cellH); !ok {
+				return nil, errors.New("spritesheet: image is larger than an atlas")
+			}
+			packers = append(packers, p)
+		}
+		min := cell.Min.Add(image.Point{o.Padding + o.Extrude, o.Padding + o.Extrude})
+		s.Sprites[i] = Sprite{
+			Atlas: atlas,
+			Rect:  image.Rectangle{Min: min, Max: min.Add(size)},
+		}
+	}
+
+	// Size and draw the atlases.
+	s.Atlases = make([]*image.RGBA, len(packers))
+	if o.Crop {
+		sizes := make([]image.Point, len(packers))
+		for _, sp := range s.Sprites {
+			max := sp.Rect.Max.Add(image.Point{o.Extrude + o.Padding, o.Extrude + o.Padding})
+			sz := &sizes[sp.Atlas]
+			if sz.X < max.X {
+				sz.X = max.X
+			}
+			if sz.Y < max.Y {
+				sz.Y = max.Y
+			}
+		}
+		for i, sz := range sizes {
+			s.Atlases[i] = image.NewRGBA(image.Rectangle{Max: sz})
+		}
+	} else {
+		for i := range s.Atlases {
+			s.Atlases[i] = image.NewRGBA(image.Rect(0, 0, o.Width, o.Height))
+		}
+	}
+	for i, sp := range s.Sprites {
+		dst := s.Atlases[sp.Atlas]
+		draw.Draw(dst, sp.Rect, images[i], images[i].Bounds().Min, draw.Src)
+		extrude(dst, sp.Rect, o.Extrude)
+	}
+	return s, nil
+}
+
+// extrude repeats the edge pixels of the r part of m outwards by n pixels.
+func extrude(m *image.RGBA, r image.Rectangle, n int) {
+	if n == 0 || r.Empty() {
+		return
+	}
+	e := r.Inset(-n)
+	for y := e.Min.Y; y < e.Max.Y; y++ {
+		sy := clamp(y, r.Min.Y, r.Max.Y-1)
+		for x := e.Min.X; x < e.Max.X; x++ {
+			if y >= r.Min.Y && y < r.Max.Y && x == r.Min.X {
+				// Skip the pixels within r.
+				x = r.Max.X - 1
+				continue
+			}
+			sx := clamp(x, r.Min.X, r.Max.X-1)
+			d, s := m.PixOffset(x, y), m.PixOffset(sx, sy)
+			copy(m.Pix[d:d+4], m.Pix[s:s+4])
+		}
+	}
+}
+
+func clamp(x, lo, hi int) int {
+	if x < lo {
+		return lo
+	}
+	if x > hi {
+		return hi
+	}
+	return x
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package spritesheet
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/binpack"
+)
+
+// randomImages returns n opaque images of random sizes and colors, with
+// bounds that do not start at the origin.
+func randomImages(n, maxSize int) []image.Image {
+	rng := rand.New(rand.NewSource(1))
+	images := make([]image.Image, n)
+	for i := range images {
+		w, h := 1+rng.Intn(maxSize), 1+rng.Intn(maxSize)
+		m := image.NewNRGBA(image.Rect(10, 20, 10+w, 20+h))
+		for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				m.SetNRGBA(x, y, color.NRGBA{uint8(i), uint8(x), uint8(y), 0xff})
+			}
+		}
+		images[i] = m
+	}
+	return images
+}
+
+func TestPack(t *testing.T) {
+	images := randomImages(200, 40)
+	for _, h := range []binpack.Heuristic{binpack.MaxRects, binpack.Skyline} {
+		for _, o := range []Options{
+			{Width: 256, Height: 256, Heuristic: h},
+			{Width: 256, Height: 128, Heuristic: h, Padding: 2},
+			{Width: 200, Height: 200, Heuristic: h, Padding: 1, Extrude: 2, Crop: true},
+		} {
+			s, err := Pack(images, &o)
+			if err != nil {
+				t.Fatalf("%+v: %v", o, err)
+			}
+			if len(s.Atlases) < 2 {
+				t.Errorf("%+v: got %d atlases, want at least 2", o, len(s.Atlases))
+			}
+			margin := o.Padding + o.Extrude
+			for i, sp := range s.Sprites {
+				src := images[i]
+				if sp.Rect.Size() != src.Bounds().Size() {
+					t.Fatalf("%+v: sprite #%d: got size %v, want %v", o, i, sp.Rect.Size(), src.Bounds().Size())
+				}
+				atlas := s.Atlases[sp.Atlas]
+				if !sp.Rect.Inset(-margin).In(atlas.Bounds()) {
+					t.Fatalf("%+v: sprite #%d: %v and its margin are not within %v", o, i, sp.Rect, atlas.Bounds())
+				}
+				// The sprite, its extruded pixels and the padding between
+				// them must not overlap any other sprite's.
+				for j, sq := range s.Sprites[:i] {
+					if sq.Atlas == sp.Atlas && sp.Rect.Inset(-margin).Overlaps(sq.Rect.Inset(-o.Extrude)) {
+						t.Fatalf("%+v: sprites #%d and #%d are too close: %v, %v", o, i, j, sp.Rect, sq.Rect)
+					}
+				}
+				// The atlas holds the image's pixels, and the extruded
+				// pixels repeat its edges.
+				b := src.Bounds()
+				for y := -o.Extrude; y < b.Dy()+o.Extrude; y++ {
+					for x := -o.Extrude; x < b.Dx()+o.Extrude; x++ {
+						sx, sy := clamp(x, 0, b.Dx()-1), clamp(y, 0, b.Dy()-1)
+						got := atlas.At(sp.Rect.Min.X+x, sp.Rect.Min.Y+y)
+						want := src.At(b.Min.X+sx, b.Min.Y+sy)
+						if color.RGBAModel.Convert(want) != got {
+							t.Fatalf("%+v: sprite #%d: pixel (%d, %d): got %v, want %v", o, i, x, y, got, want)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPackTooLarge(t *testing.T) {
+	images := []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 10, 10)),
+		image.NewRGBA(image.Rect(0, 0, 30, 10)),
+	}
+	if _, err := Pack(images, &Options{Width: 32, Height: 32}); err != nil {
+		t.Errorf("without padding: %v", err)
+	}
+	if _, err := Pack(images, &Options{Width: 32, Height: 32, Padding: 2}); err == nil {
+		t.Error("with padding: got nil error, want non-nil")
+	}
+}
@@ -0,0 +1,332 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svg
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+	"golang.org/x/image/math/f32"
+)
+
+// scanner reads the numbers, flags and names of attribute values, such as
+// path data, lists of points and transforms.
+type scanner struct {
+	s string
+	i int
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func (c *scanner) done() bool {
+	return c.i >= len(c.s)
+}
+
+func (c *scanner) peek() byte {
+	if c.done() {
+		return 0
+	}
+	return c.s[c.i]
+}
+
+func (c *scanner) skipSpace() {
+	for !c.done() && isSpace(c.s[c.i]) {
+		c.i++
+	}
+}
+
+// skipSep skips white space, with at most one comma.
+func (c *scanner) skipSep() {
+	c.skipSpace()
+	if c.peek() == ',' {
+		c.i++
+		c.skipSpace()
+	}
+}
+
+// number reads a number, and the separator after it. Numbers need not be
+// separated where the next one starts with a sign or a second decimal point,
+// as in "1-2.5.5", which is 1, -2.5 and 0.5.
+func (c *scanner) number() (float32, bool) {
+	start := c.i
+	if p := c.peek(); p == '+' || p == '-' {
+		c.i++
+	}
+	digits := 0
+	for isDigit(c.peek()) {
+		c.i++
+		digits++
+	}
+	if c.peek() == '.' {
+		c.i++
+		for isDigit(c.peek()) {
+			c.i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		c.i = start
+		return 0, false
+	}
+	if p := c.peek(); p == 'e' || p == 'E' {
+		j := c.i + 1
+		if j < len(c.s) && (c.s[j] == '+' || c.s[j] == '-') {
+			j++
+		}
+		if j < len(c.s) && isDigit(c.s[j]) {
+			for j < len(c.s) && isDigit(c.s[j]) {
+				j++
+			}
+			c.i = j
+		}
+	}
+	f, err := strconv.ParseFloat(c.s[start:c.i], 32)
+	if err != nil {
+		c.i = start
+		return 0, false
+	}
+	c.skipSep()
+	return float32(f), true
+}
+
+// flag reads an elliptical arc's flag, a single 0 or 1 that need not be
+// separated from what follows it, and the separator after it.
+func (c *scanner) flag() (bool, bool) {
+	switch c.peek() {
+	case '0':
+		c.i++
+		c.skipSep()
+		return false, true
+	case '1':
+		c.i++
+		c.skipSep()
+		return true, true
+	}
+	return false, false
+}
+
+// parseNumbers parses a list of numbers separated by white space or commas.
+func parseNumbers(s string) ([]float32, bool) {
+	c := scanner{s: s}
+	c.skipSpace()
+	var ret []float32
+	for !c.done() {
+		f, ok := c.number()
+		if !ok {
+			return ret, false
+		}
+		ret = append(ret, f)
+	}
+	return ret, true
+}
+
+// parseNumber parses a single number, with no units.
+func parseNumber(s string) (float32, bool) {
+	c := scanner{s: s}
+	f, ok := c.number()
+	return f, ok && c.done()
+}
+
+// units are the sizes, in pixels, of the units of lengths, other than
+// percentages.
+var units = map[string]float32{
+	"":   1,
+	"px": 1,
+	"pt": 4.0 / 3,
+	"pc": 16,
+	"in": 96,
+	"cm": 96 / 2.54,
+	"mm": 96 / 25.4,
+	"em": 16,
+	"ex": 8,
+}
+
+// parseLength parses a length, such as "12", "12px" or "50%", where ref is
+// the length of 100%.
+func parseLength(s string, ref float32) (float32, bool) {
+	c := scanner{s: s}
+	f, ok := c.number()
+	if !ok {
+		return 0, false
+	}
+	// The number may have skipped a separator, which a unit cannot follow.
+	unit := strings.TrimSpace(s[c.i:])
+	if c.i < len(s) && unit != "" && isSpace(s[c.i-1]) {
+		return 0, false
+	}
+	if unit == "%" {
+		return f * ref / 100, true
+	}
+	u, ok := units[unit]
+	return f * u, ok
+}
+
+// parseColor parses a color, as a hexadecimal "#rgb" or "#rrggbb", a
+// functional "rgb(r, g, b)" or "rgba(r, g, b, a)", or a color keyword.
+func parseColor(s string) (color.NRGBA, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		h := s[1:]
+		v, err := strconv.ParseUint(h, 16, 32)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		switch len(h) {
+		case 3:
+			return color.NRGBA{
+				uint8(v>>8&0xf) * 0x11,
+				uint8(v>>4&0xf) * 0x11,
+				uint8(v&0xf) * 0x11,
+				0xff,
+			}, true
+		case 6:
+			return color.NRGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+		}
+		return color.NRGBA{}, false
+	}
+
+	lower := strings.ToLower(s)
+	if lower == "transparent" {
+		return color.NRGBA{}, true
+	}
+	if c, ok := colornames.Map[lower]; ok {
+		return color.NRGBA{c.R, c.G, c.B, c.A}, true
+	}
+	var args string
+	switch {
+	case strings.HasPrefix(lower, "rgb(") && strings.HasSuffix(lower, ")"):
+		args = lower[4 : len(lower)-1]
+	case strings.HasPrefix(lower, "rgba(") && strings.HasSuffix(lower, ")"):
+		args = lower[5 : len(lower)-1]
+	default:
+		return color.NRGBA{}, false
+	}
+	parts := strings.Split(args, ",")
+	if len(parts) != 3 && len(parts) != 4 {
+		return color.NRGBA{}, false
+	}
+	var v [4]float32
+	v[3] = 1
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		ref := float32(255)
+		if i == 3 {
+			ref = 1
+		}
+		if strings.HasSuffix(p, "%") {
+			f, ok := parseNumber(p[:len(p)-1])
+			if !ok {
+				return color.NRGBA{}, false
+			}
+			v[i] = f * ref / 100
+		} else {
+			f, ok := parseNumber(p)
+			if !ok {
+				return color.NRGBA{}, false
+			}
+			v[i] = f
+		}
+	}
+	return color.NRGBA{
+		clamp8(v[0] / 255),
+		clamp8(v[1] / 255),
+		clamp8(v[2] / 255),
+		clamp8(v[3]),
+	}, true
+}
+
+// clamp8 converts f, from 0 to 1, to a uint8 from 0 to 0xff.
+func clamp8(f float32) uint8 {
+	if !(f > 0) {
+		return 0
+	}
+	if f >= 1 {
+		return 0xff
+	}
+	return uint8(f*0xff + 0.5)
+}
+
+// mul returns the affine transformation that applies b and then a.
+func mul(a, b f32.Aff3) f32.Aff3 {
+	return f32.Aff3{
+		a[0]*b[0] + a[1]*b[3],
+		a[0]*b[1] + a[1]*b[4],
+		a[0]*b[2] + a[1]*b[5] + a[2],
+		a[3]*b[0] + a[4]*b[3],
+		a[3]*b[1] + a[4]*b[4],
+		a[3]*b[2] + a[4]*b[5] + a[5],
+	}
+}
+
+var identity = f32.Aff3{1, 0, 0, 0, 1, 0}
+
+// parseTransform parses a transform attribute's list of transformations. It
+// returns false if s is invalid.
+func parseTransform(s string) (f32.Aff3, bool) {
+	m := identity
+	c := scanner{s: s}
+	c.skipSep()
+	for !c.done() {
+		start := c.i
+		for !c.done() && ('a' <= c.s[c.i] && c.s[c.i] <= 'z' || 'A' <= c.s[c.i] && c.s[c.i] <= 'Z') {
+			c.i++
+		}
+		name := c.s[start:c.i]
+		c.skipSpace()
+		if c.peek() != '(' {
+			return identity, false
+		}
+		c.i++
+		c.skipSpace()
+		var args []float32
+		for c.peek() != ')' {
+			f, ok := c.number()
+			if !ok {
+				return identity, false
+			}
+			args = append(args, f)
+		}
+		c.i++
+		c.skipSep()
+
+		var t f32.Aff3
+		switch n := len(args); {
+		case name == "matrix" && n == 6:
+			t = f32.Aff3{args[0], args[2], args[4], args[1], args[3], args[5]}
+		case name == "translate" && n == 1:
+			t = f32.Aff3{1, 0, args[0], 0, 1, 0}
+		case name == "translate" && n == 2:
+			t = f32.Aff3{1, 0, args[0], 0, 1, args[1]}
+		case name == "scale" && n == 1:
+			t = f32.Aff3{args[0], 0, 0, 0, args[0], 0}
+		case name == "scale" && n == 2:
+			t = f32.Aff3{args[0], 0, 0, 0, args[1], 0}
+		case name == "rotate" && (n == 1 || n == 3):
+			sin, cos := math.Sincos(float64(args[0]) * math.Pi / 180)
+			t = f32.Aff3{float32(cos), float32(-sin), 0, float32(sin), float32(cos), 0}
+			if n == 3 {
+				cx, cy := args[1], args[2]
+				t = mul(f32.Aff3{1, 0, cx, 0, 1, cy}, mul(t, f32.Aff3{1, 0, -cx, 0, 1, -cy}))
+			}
+		case name == "skewX" && n == 1:
+			t = f32.Aff3{1, float32(math.Tan(float64(args[0]) * math.Pi / 180)), 0, 0, 1, 0}
+		case name == "skewY" && n == 1:
+			t = f32.Aff3{1, 0, 0, float32(math.Tan(float64(args[0]) * math.Pi / 180)), 1, 0}
+		default:
+			return identity, false
+		}
+		m = mul(m, t)
+	}
+	return m, true
+}
@@ -0,0 +1,284 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svg
+
+import (
+	"math"
+
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/vector"
+)
+
+// parsePathData parses a path element's d attribute. As per the SVG
+// specification's error handling, an invalid d attribute's path is the path
+// up to the first error.
+func parsePathData(d string) vector.Path {
+	var (
+		p vector.Path
+		c = scanner{s: d}
+		// cur is the current point, start is the start of the current
+		// sub-path, and ctrl is the last control point of the previous
+		// segment, if it is a Bézier curve of the same order.
+		cur, start, ctrl f32.Vec2
+		cmd, prev        byte
+		args             [7]float32
+	)
+	c.skipSpace()
+	for !c.done() {
+		if b := c.peek(); 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' {
+			cmd = b
+			c.i++
+			c.skipSpace()
+		} else if cmd == 0 || cmd == 'Z' || cmd == 'z' {
+			break
+		}
+		if cmd != 'M' && cmd != 'm' && len(p) == 0 {
+			// A path must start with a moveto.
+			break
+		}
+
+		n := 0
+		switch cmd {
+		case 'M', 'm', 'L', 'l', 'T', 't':
+			n = 2
+		case 'H', 'h', 'V', 'v':
+			n = 1
+		case 'C', 'c':
+			n = 6
+		case 'S', 's', 'Q', 'q':
+			n = 4
+		case 'A', 'a':
+			n = 7
+		case 'Z', 'z':
+		default:
+			return p
+		}
+		for i := 0; i < n; i++ {
+			ok := false
+			if (cmd == 'A' || cmd == 'a') && (i == 3 || i == 4) {
+				var f bool
+				f, ok = c.flag()
+				if f {
+					args[i] = 1
+				} else {
+					args[i] = 0
+				}
+			} else {
+				args[i], ok = c.number()
+			}
+			if !ok {
+				return p
+			}
+		}
+
+		// rel is the offset of relative coordinates, and pt returns the
+		// absolute point of the i'th and i+1'th arguments.
+		var rel f32.Vec2
+		if 'a' <= cmd && cmd <= 'z' {
+			rel = cur
+		}
+		pt := func(i int) f32.Vec2 {
+			return f32.Vec2{rel[0] + args[i], rel[1] + args[i+1]}
+		}
+
+		switch cmd {
+		case 'M', 'm':
+			cur = pt(0)
+			start = cur
+			p.MoveTo(cur[0], cur[1])
+			// Subsequent pairs of coordinates are implicit linetos.
+			cmd -= 'M' - 'L'
+		case 'L', 'l':
+			cur = pt(0)
+			p.LineTo(cur[0], cur[1])
+		case 'H', 'h':
+			cur[0] = rel[0] + args[0]
+			p.LineTo(cur[0], cur[1])
+		case 'V', 'v':
+			cur[1] = rel[1] + args[0]
+			p.LineTo(cur[0], cur[1])
+		case 'C', 'c', 'S', 's':
+			b := cur
+			i := 0
+			if cmd == 'C' || cmd == 'c' {
+				b = pt(0)
+				i = 2
+			} else if prev == 'C' || prev == 'c' || prev == 'S' || prev == 's' {
+				b = f32.Vec2{2*cur[0] - ctrl[0], 2*cur[1] - ctrl[1]}
+			}
+			ctrl, cur = pt(i), pt(i+2)
+			p.CubeTo(b[0], b[1], ctrl[0], ctrl[1], cur[0], cur[1])
+		case 'Q', 'q', 'T', 't':
+			i := 0
+			if cmd == 'Q' || cmd == 'q' {
+				ctrl = pt(0)
+				i = 2
+			} else if prev == 'Q' || prev == 'q' || prev == 'T' || prev == 't' {
+				ctrl = f32.Vec2{2*cur[0] - ctrl[0], 2*cur[1] - ctrl[1]}
+			} else {
+				ctrl = cur
+			}
+			cur = pt(i)
+			p.QuadTo(ctrl[0], ctrl[1], cur[0], cur[1])
+		case 'A', 'a':
+			end := pt(5)
+			arcTo(&p, cur, args[0], args[1], args[2], args[3] != 0, args[4] != 0, end)
+			cur = end
+		case 'Z', 'z':
+			p.ClosePath()
+			cur = start
+		}
+		prev = cmd
+	}
+	return p
+}
+
+// arcTo adds an elliptical arc from a to b to p, converting the endpoint
+// parameterization of SVG's path data to a center parameterization, as per
+// https://www.w3.org/TR/SVG11/implnote.html#ArcImplementationNotes
+func arcTo(p *vector.Path, a f32.Vec2, rx, ry, rotation float32, large, sweep bool, b f32.Vec2) {
+	if a == b {
+		return
+	}
+	if rx == 0 || ry == 0 {
+		p.LineTo(b[0], b[1])
+		return
+	}
+	r1 := math.Abs(float64(rx))
+	r2 := math.Abs(float64(ry))
+	sin, cos := math.Sincos(float64(rotation) * math.Pi / 180)
+
+	// (x1, y1) is the midpoint between a and b, relative to the center, in
+	// the ellipse's unrotated coordinate space.
+	dx := float64(a[0]-b[0]) / 2
+	dy := float64(a[1]-b[1]) / 2
+	x1 := cos*dx + sin*dy
+	y1 := -sin*dx + cos*dy
+	if l := x1*x1/(r1*r1) + y1*y1/(r2*r2); l > 1 {
+		l = math.Sqrt(l)
+		r1, r2 = r1*l, r2*l
+	}
+
+	num := r1*r1*r2*r2 - r1*r1*y1*y1 - r2*r2*x1*x1
+	den := r1*r1*y1*y1 + r2*r2*x1*x1
+	coef := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		coef = -coef
+	}
+	cx1 := coef * r1 * y1 / r2
+	cy1 := -coef * r2 * x1 / r1
+	cx := cos*cx1 - sin*cy1 + float64(a[0]+b[0])/2
+	cy := sin*cx1 + cos*cy1 + float64(a[1]+b[1])/2
+
+	theta := math.Atan2((y1-cy1)/r2, (x1-cx1)/r1)
+	delta := math.Atan2((-y1-cy1)/r2, (-x1-cx1)/r1) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	// Add the arc of the unrotated ellipse centered on the origin, and then
+	// rotate and translate it. Its first two segments, a MoveTo and a LineTo
+	// to the start of the arc, are dropped.
+	var q vector.Path
+	q.MoveTo(float32(r1*math.Cos(theta)), float32(r2*math.Sin(theta)))
+	q.ArcTo(0, 0, float32(r1), float32(r2), float32(theta), float32(delta))
+	q.Transform(f32.Aff3{
+		float32(cos), float32(-sin), float32(cx),
+		float32(sin), float32(cos), float32(cy),
+	})
+	q = q[2:]
+	if len(q) > 0 {
+		// Avoid rounding errors at the end point.
+		q[len(q)-1].Args[2] = b
+	}
+	*p = append(*p, q...)
+}
+
+// shapePath returns the path of a shape element, or false if n is not a
+// shape element. Percentages are relative to vb.
+func shapePath(n *node, vb ViewBox) (vector.Path, bool) {
+	w, h, d := vb.Width, vb.Height, vb.diagonal()
+	length := func(name string, ref float32) float32 {
+		f, _ := parseLength(n.attrs[name], ref)
+		return f
+	}
+
+	var p vector.Path
+	switch n.name {
+	case "path":
+		return parsePathData(n.attrs["d"]), true
+
+	case "rect":
+		x, y := length("x", w), length("y", h)
+		width, height := length("width", w), length("height", h)
+		if width <= 0 || height <= 0 {
+			return nil, true
+		}
+		rx, hasRX := parseLength(n.attrs["rx"], w)
+		ry, hasRY := parseLength(n.attrs["ry"], h)
+		if !hasRX || rx < 0 {
+			rx, hasRX = ry, hasRY
+		}
+		if !hasRY || ry < 0 {
+			ry = rx
+		}
+		rx = float32(math.Max(0, math.Min(float64(rx), float64(width/2))))
+		ry = float32(math.Max(0, math.Min(float64(ry), float64(height/2))))
+		x1, y1 := x+width, y+height
+		if rx == 0 || ry == 0 {
+			p.MoveTo(x, y)
+			p.LineTo(x1, y)
+			p.LineTo(x1, y1)
+			p.LineTo(x, y1)
+			p.ClosePath()
+			return p, true
+		}
+		p.MoveTo(x+rx, y)
+		p.LineTo(x1-rx, y)
+		p.ArcTo(x1-rx, y+ry, rx, ry, -math.Pi/2, math.Pi/2)
+		p.LineTo(x1, y1-ry)
+		p.ArcTo(x1-rx, y1-ry, rx, ry, 0, math.Pi/2)
+		p.LineTo(x+rx, y1)
+		p.ArcTo(x+rx, y1-ry, rx, ry, math.Pi/2, math.Pi/2)
+		p.LineTo(x, y+ry)
+		p.ArcTo(x+rx, y+ry, rx, ry, math.Pi, math.Pi/2)
+		p.ClosePath()
+
+	case "circle":
+		if r := length("r", d); r > 0 {
+			p.Ellipse(length("cx", w), length("cy", h), r, r)
+		}
+
+	case "ellipse":
+		if rx, ry := length("rx", w), length("ry", h); rx > 0 && ry > 0 {
+			p.Ellipse(length("cx", w), length("cy", h), rx, ry)
+		}
+
+	case "line":
+		p.MoveTo(length("x1", w), length("y1", h))
+		p.LineTo(length("x2", w), length("y2", h))
+
+	case "polyline", "polygon":
+		// As per the SVG specification's error handling, an odd number of
+		// coordinates is rendered up to the last pair.
+		v, _ := parseNumbers(n.attrs["points"])
+		for i := 0; i+1 < len(v); i += 2 {
+			if i == 0 {
+				p.MoveTo(v[0], v[1])
+			} else {
+				p.LineTo(v[i], v[i+1])
+			}
+		}
+		if n.name == "polygon" && len(p) > 0 {
+			p.ClosePath()
+		}
+
+	default:
+		return nil, false
+	}
+	return p, true
+}
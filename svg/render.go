@@ -0,0 +1,339 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svg
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/vector"
+)
+
+// paint is a fill or stroke property's value.
+type paint struct {
+	none bool
+	// current is whether the paint is the color property's value, instead
+	// of c.
+	current bool
+	c       color.NRGBA
+}
+
+// parsePaint parses a fill or stroke property. A paint server's URL is
+// replaced by its fallback color or, if there is none, by none.
+func parsePaint(s string) (paint, bool) {
+	if strings.HasPrefix(s, "url(") {
+		i := strings.IndexByte(s, ')')
+		if i < 0 {
+			return paint{}, false
+		}
+		s = strings.TrimSpace(s[i+1:])
+		if s == "" {
+			return paint{none: true}, true
+		}
+	}
+	switch s {
+	case "none":
+		return paint{none: true}, true
+	case "currentColor":
+		return paint{current: true}, true
+	}
+	c, ok := parseColor(s)
+	return paint{c: c}, ok
+}
+
+// style is the values of the properties that affect how shapes are painted.
+type style struct {
+	fill, stroke               paint
+	color                      color.NRGBA
+	fillOpacity, strokeOpacity float32
+	// opacity is the product of the opacity properties of the element and
+	// its ancestors.
+	opacity float32
+	visible bool
+	// strokeStyle is the stroke's width, caps, joins and dashes.
+	strokeStyle vector.Stroke
+}
+
+var initialStyle = style{
+	fill:          paint{c: color.NRGBA{0, 0, 0, 0xff}},
+	stroke:        paint{none: true},
+	color:         color.NRGBA{0, 0, 0, 0xff},
+	fillOpacity:   1,
+	strokeOpacity: 1,
+	opacity:       1,
+	visible:       true,
+	strokeStyle: vector.Stroke{
+		Width:      1,
+		MiterLimit: 4,
+	},
+}
+
+// inherit returns the style of an element whose parent's style is s. Invalid
+// property values are ignored, as are values of "inherit", which is the
+// default for all but the opacity property.
+func (s style) inherit(attrs map[string]string, vb ViewBox) style {
+	if v, ok := parseColor(attrs["color"]); ok {
+		s.color = v
+	}
+	if v, ok := parsePaint(attrs["fill"]); ok {
+		s.fill = v
+	}
+	if v, ok := parsePaint(attrs["stroke"]); ok {
+		s.stroke = v
+	}
+	opacity := func(name string, dst *float32, scale float32) {
+		if v, ok := parseNumber(attrs[name]); ok {
+			*dst = scale * float32(math.Max(0, math.Min(1, float64(v))))
+		}
+	}
+	opacity("fill-opacity", &s.fillOpacity, 1)
+	opacity("stroke-opacity", &s.strokeOpacity, 1)
+	opacity("opacity", &s.opacity, s.opacity)
+	switch attrs["visibility"] {
+	case "visible":
+		s.visible = true
+	case "hidden", "collapse":
+		s.visible = false
+	}
+
+	k := &s.strokeStyle
+	if v, ok := parseLength(attrs["stroke-width"], vb.diagonal()); ok && v >= 0 {
+		k.Width = v
+	}
+	switch attrs["stroke-linecap"] {
+	case "butt":
+		k.Cap = vector.ButtCap
+	case "round":
+		k.Cap = vector.RoundCap
+	case "square":
+		k.Cap = vector.SquareCap
+	}
+	switch attrs["stroke-linejoin"] {
+	case "miter":
+		k.Join = vector.MiterJoin
+	case "round":
+		k.Join = vector.RoundJoin
+	case "bevel":
+		k.Join = vector.BevelJoin
+	}
+	if v, ok := parseNumber(attrs["stroke-miterlimit"]); ok && v >= 1 {
+		k.MiterLimit = v
+	}
+	if a, ok := attrs["stroke-dasharray"]; ok {
+		if a == "none" {
+			k.Dashes = nil
+		} else if v, ok := parseDashes(a, vb.diagonal()); ok {
+			k.Dashes = v
+		}
+	}
+	if v, ok := parseLength(attrs["stroke-dashoffset"], vb.diagonal()); ok {
+		k.DashOffset = v
+	}
+	return s
+}
+
+// parseDashes parses a stroke-dasharray property's list of lengths.
+func parseDashes(s string, ref float32) ([]float32, bool) {
+	var ret []float32
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r < 0x80 && isSpace(byte(r))
+	}) {
+		v, ok := parseLength(f, ref)
+		if !ok || v < 0 {
+			return nil, false
+		}
+		ret = append(ret, v)
+	}
+	return ret, len(ret) > 0
+}
+
+// renderer draws an Image's elements.
+type renderer struct {
+	dst draw.Image
+	r   image.Rectangle
+	vb  ViewBox
+	z   *vector.Rasterizer
+}
+
+// Rasterize returns the image drawn at the given size, in pixels. If width or
+// height is zero, it is calculated from the other and the image's aspect
+// ratio, and if both are zero, the image's intrinsic size is used.
+func (m *Image) Rasterize(width, height int) *image.RGBA {
+	switch {
+	case width <= 0 && height <= 0:
+		width = int(math.Ceil(float64(m.Width)))
+		height = int(math.Ceil(float64(m.Height)))
+	case width <= 0:
+		width = int(math.Ceil(float64(height) * float64(m.Width) / float64(m.Height)))
+	case height <= 0:
+		height = int(math.Ceil(float64(width) * float64(m.Height) / float64(m.Width)))
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	m.Draw(dst, dst.Bounds())
+	return dst
+}
+
+// Draw draws the image onto dst, compositing it over dst's pixels, so that
+// the image's viewBox is mapped to r as per its preserveAspectRatio
+// attribute.
+func (m *Image) Draw(dst draw.Image, r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+	d := &renderer{
+		dst: dst,
+		r:   r,
+		vb:  m.ViewBox,
+		z:   vector.NewRasterizer(r.Dx(), r.Dy()),
+	}
+	t := viewBoxTransform(m.ViewBox, 0, 0, float32(r.Dx()), float32(r.Dy()), m.root.attrs["preserveAspectRatio"])
+	d.drawChildren(m.root, t, initialStyle.inherit(m.root.attrs, m.ViewBox))
+}
+
+// viewBoxTransform returns the transformation that maps vb to the viewport
+// from (x, y) with the given width and height, as per the
+// preserveAspectRatio attribute value par.
+func viewBoxTransform(vb ViewBox, x, y, width, height float32, par string) f32.Aff3 {
+	sx, sy := width/vb.Width, height/vb.Height
+	fields := strings.Fields(par)
+	align := "xMidYMid"
+	if len(fields) > 0 {
+		align = fields[0]
+	}
+	if align != "none" {
+		if len(fields) > 1 && fields[1] == "slice" {
+			sx = float32(math.Max(float64(sx), float64(sy)))
+		} else {
+			sx = float32(math.Min(float64(sx), float64(sy)))
+		}
+		sy = sx
+	}
+	tx, ty := x-vb.MinX*sx, y-vb.MinY*sy
+	if len(align) == 8 {
+		extraX, extraY := width-vb.Width*sx, height-vb.Height*sy
+		switch align[1:4] {
+		case "Mid":
+			tx += extraX / 2
+		case "Max":
+			tx += extraX
+		}
+		switch align[5:8] {
+		case "Mid":
+			ty += extraY / 2
+		case "Max":
+			ty += extraY
+		}
+	}
+	return f32.Aff3{sx, 0, tx, 0, sy, ty}
+}
+
+func (d *renderer) drawChildren(n *node, t f32.Aff3, s style) {
+	for _, c := range n.children {
+		d.drawNode(c, t, s)
+	}
+}
+
+func (d *renderer) drawNode(n *node, t f32.Aff3, s style) {
+	if n.attrs["display"] == "none" {
+		return
+	}
+	if v, ok := n.attrs["transform"]; ok {
+		// An invalid transform is ignored, as by web browsers.
+		if m, ok := parseTransform(v); ok {
+			t = mul(t, m)
+		}
+	}
+	s = s.inherit(n.attrs, d.vb)
+
+	switch n.name {
+	case "g", "a":
+		d.drawChildren(n, t, s)
+		return
+	case "svg":
+		// A nested svg element establishes a new viewport, which is not
+		// clipped.
+		w, h := d.vb.Width, d.vb.Height
+		x, _ := parseLength(n.attrs["x"], w)
+		y, _ := parseLength(n.attrs["y"], h)
+		if v, ok := parseLength(n.attrs["width"], w); ok {
+			w = v
+		}
+		if v, ok := parseLength(n.attrs["height"], h); ok {
+			h = v
+		}
+		if w <= 0 || h <= 0 {
+			return
+		}
+		if vb, ok := parseViewBox(n.attrs["viewBox"]); ok {
+			t = mul(t, viewBoxTransform(vb, x, y, w, h, n.attrs["preserveAspectRatio"]))
+		} else {
+			t = mul(t, f32.Aff3{1, 0, x, 0, 1, y})
+		}
+		d.drawChildren(n, t, s)
+		return
+	}
+
+	p, ok := shapePath(n, d.vb)
+	if !ok || len(p) == 0 || !s.visible {
+		return
+	}
+	if f := s.fill; !f.none {
+		q := closeSubpaths(p)
+		q.Transform(t)
+		d.drawPath(q, s.color, f, s.fillOpacity*s.opacity)
+	}
+	if k := s.stroke; !k.none && s.strokeStyle.Width > 0 {
+		q := append(vector.Path(nil), p...)
+		q.Transform(t)
+		// The stroke is scaled by the square root of the transformation's
+		// determinant, which is exact for uniform scales and rotations.
+		scale := float32(math.Sqrt(math.Abs(float64(t[0]*t[4] - t[1]*t[3]))))
+		ks := s.strokeStyle
+		ks.Width *= scale
+		ks.DashOffset *= scale
+		if ks.Dashes != nil {
+			ks.Dashes = make([]float32, len(ks.Dashes))
+			for i, v := range s.strokeStyle.Dashes {
+				ks.Dashes[i] = v * scale
+			}
+		}
+		d.drawPath(q.Stroke(&ks), s.color, k, s.strokeOpacity*s.opacity)
+	}
+}
+
+// drawPath fills p, in pixel coordinates, with the color of f.
+func (d *renderer) drawPath(p vector.Path, current color.NRGBA, f paint, opacity float32) {
+	c := f.c
+	if f.current {
+		c = current
+	}
+	c.A = clamp8(float32(c.A) / 0xff * opacity)
+	if c.A == 0 {
+		return
+	}
+	d.z.Reset(d.r.Dx(), d.r.Dy())
+	p.AddTo(d.z)
+	d.z.Draw(d.dst, d.r, image.NewUniform(c), image.Point{})
+}
+
+// closeSubpaths returns a copy of p with each of its sub-paths closed, as
+// they are when they are filled.
+func closeSubpaths(p vector.Path) vector.Path {
+	q := make(vector.Path, 0, len(p)+1)
+	for i, s := range p {
+		if s.Op == vector.PathOpMoveTo && i > 0 && p[i-1].Op != vector.PathOpClose {
+			q.ClosePath()
+		}
+		q = append(q, s)
+	}
+	if n := len(q); n > 0 && q[n-1].Op != vector.PathOpClose {
+		q.ClosePath()
+	}
+	return q
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package svg implements a decoder and rasterizer for a static subset of
+// Scalable Vector Graphics (SVG) 1.1 images, such as icons.
+//
+// The subset is the path, rect, circle, ellipse, line, polyline and polygon
+// shapes, inside svg, g and a elements, with their transform, viewBox and
+// preserveAspectRatio attributes, and their fill and stroke properties, given
+// as presentation attributes or in style attributes. Fills use the non-zero
+// winding rule, whatever their fill-rule. An element's opacity applies to
+// each of its shapes separately, instead of to the element as a group. Paint
+// servers, such as gradients, are replaced by their fallback colors.
+//
+// Text, images, the use element, clipping, masking, filters, CSS style sheets,
+// animation and scripting are not supported, and the elements that need them
+// are ignored.
+//
+// The format is described at https://www.w3.org/TR/SVG11/
+package svg // import "golang.org/x/image/svg"
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"strings"
+)
+
+// A FormatError reports that the input is not a valid SVG image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "svg: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "svg: unsupported feature: " + string(e)
+}
+
+// maxDepth is the deepest nesting of elements that Decode accepts.
+const maxDepth = 1024
+
+// ViewBox is the rectangle of an image's user coordinate space that is
+// mapped to its viewport.
+type ViewBox struct {
+	MinX, MinY, Width, Height float32
+}
+
+// Image is a decoded SVG image.
+type Image struct {
+	// Width and Height are the image's intrinsic size, in pixels, from the
+	// width and height attributes of its root svg element or, failing that,
+	// from its ViewBox.
+	Width, Height float32
+
+	// ViewBox is the root svg element's viewBox or, failing that, the
+	// rectangle from the origin to (Width, Height).
+	ViewBox ViewBox
+
+	root *node
+}
+
+// node is an element of an SVG image.
+type node struct {
+	name string
+	// attrs are the element's attributes, by their local names, where the
+	// properties of its style attribute, if any, override its presentation
+	// attributes.
+	attrs    map[string]string
+	children []*node
+}
+
+// Decode reads an SVG image from r.
+func Decode(r io.Reader) (*Image, error) {
+	root, err := decodeTree(r)
+	if err != nil {
+		return nil, err
+	}
+	if root.name != "svg" {
+		return nil, FormatError("root element is not svg")
+	}
+
+	m := &Image{root: root}
+	vb, hasViewBox := parseViewBox(root.attrs["viewBox"])
+	m.Width, _ = parseLength(root.attrs["width"], 0)
+	m.Height, _ = parseLength(root.attrs["height"], 0)
+	switch {
+	case hasViewBox && m.Width <= 0 && m.Height <= 0:
+		m.Width, m.Height = vb.Width, vb.Height
+	case hasViewBox && m.Width <= 0:
+		m.Width = m.Height * vb.Width / vb.Height
+	case hasViewBox && m.Height <= 0:
+		m.Height = m.Width * vb.Height / vb.Width
+	case !hasViewBox:
+		if m.Width <= 0 || m.Height <= 0 {
+			return nil, FormatError("no viewBox or size")
+		}
+		vb = ViewBox{0, 0, m.Width, m.Height}
+	}
+	m.ViewBox = vb
+	return m, nil
+}
+
+// decodeTree returns the root element of the XML document read from r.
+func decodeTree(r io.Reader) (*node, error) {
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity
+	var root *node
+	var stack []*node
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*xml.SyntaxError); ok {
+				return nil, FormatError(err.Error())
+			}
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if root != nil && len(stack) == 0 {
+				return nil, FormatError("more than one root element")
+			}
+			if len(stack) == maxDepth {
+				return nil, UnsupportedError("deeply nested elements")
+			}
+			n := &node{
+				name:  tok.Name.Local,
+				attrs: make(map[string]string, len(tok.Attr)),
+			}
+			for _, a := range tok.Attr {
+				n.attrs[a.Name.Local] = strings.TrimSpace(a.Value)
+			}
+			parseStyle(n.attrs, n.attrs["style"])
+			if root == nil {
+				root = n
+			} else {
+				p := stack[len(stack)-1]
+				p.children = append(p.children, n)
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil {
+		return nil, FormatError("no root element")
+	}
+	return root, nil
+}
+
+// parseStyle adds the properties of the CSS declarations in style to attrs.
+func parseStyle(attrs map[string]string, style string) {
+	for _, decl := range strings.Split(style, ";") {
+		i := strings.IndexByte(decl, ':')
+		if i < 0 {
+			continue
+		}
+		k := strings.TrimSpace(decl[:i])
+		v := strings.TrimSpace(decl[i+1:])
+		v = strings.TrimSpace(strings.TrimSuffix(v, "!important"))
+		if k != "" {
+			attrs[k] = v
+		}
+	}
+}
+
+// parseViewBox parses a viewBox attribute. It returns false if s is not a
+// valid viewBox with a positive width and height.
+func parseViewBox(s string) (ViewBox, bool) {
+	v, ok := parseNumbers(s)
+	if !ok || len(v) != 4 || v[2] <= 0 || v[3] <= 0 {
+		return ViewBox{}, false
+	}
+	return ViewBox{v[0], v[1], v[2], v[3]}, true
+}
+
+// diagonal returns the reference length for percentages that are neither
+// horizontal nor vertical, such as a circle's radius.
+func (vb ViewBox) diagonal() float32 {
+	w, h := float64(vb.Width), float64(vb.Height)
+	return float32(math.Sqrt((w*w + h*h) / 2))
+}
@@ -0,0 +1,354 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package svg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/math/f32"
+	"golang.org/x/image/vector"
+)
+
+func decodeString(t *testing.T, s string) *Image {
+	t.Helper()
+	m, err := Decode(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return m
+}
+
+func TestDecodeSize(t *testing.T) {
+	testCases := []struct {
+		attrs         string
+		width, height float32
+		vb            ViewBox
+	}{
+		{`width="20" height="10"`, 20, 10, ViewBox{0, 0, 20, 10}},
+		{`viewBox="1 2 30 40"`, 30, 40, ViewBox{1, 2, 30, 40}},
+		{`width="1in" viewBox="0,0,30,40"`, 96, 128, ViewBox{0, 0, 30, 40}},
+		{`height="8" viewBox="0 0 30 40"`, 6, 8, ViewBox{0, 0, 30, 40}},
+		{`width="24px" height="12pt" viewBox="0 0 10 10"`, 24, 16, ViewBox{0, 0, 10, 10}},
+	}
+	for _, tc := range testCases {
+		m := decodeString(t, `<svg xmlns="http://www.w3.org/2000/svg" `+tc.attrs+`/>`)
+		if m.Width != tc.width || m.Height != tc.height || m.ViewBox != tc.vb {
+			t.Errorf("%s: got %v×%v %v, want %v×%v %v",
+				tc.attrs, m.Width, m.Height, m.ViewBox, tc.width, tc.height, tc.vb)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	testCases := []string{
+		``,
+		`<html/>`,
+		`<svg/>`,
+		`<svg viewBox="0 0 0 10"/>`,
+		`<svg width="10" height="10"><g></svg>`,
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(strings.NewReader(tc)); err == nil {
+			t.Errorf("%q: got nil error, want non-nil", tc)
+		}
+	}
+}
+
+// checkPixels checks the colors of m at the given points.
+func checkPixels(t *testing.T, desc string, m image.Image, want map[image.Point]color.RGBA) {
+	t.Helper()
+	for p, w := range want {
+		r, g, b, a := m.At(p.X, p.Y).RGBA()
+		got := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if got != w {
+			t.Errorf("%s: %v: got %v, want %v", desc, p, got, w)
+		}
+	}
+}
+
+var (
+	transparent = color.RGBA{}
+	black       = color.RGBA{0, 0, 0, 0xff}
+	red         = color.RGBA{0xff, 0, 0, 0xff}
+	green       = color.RGBA{0, 0x80, 0, 0xff}
+	blue        = color.RGBA{0, 0, 0xff, 0xff}
+)
+
+func TestRasterize(t *testing.T) {
+	testCases := []struct {
+		desc string
+		svg  string
+		want map[image.Point]color.RGBA
+	}{{
+		desc: "fills",
+		svg: `<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32">
+			<rect x="2" y="2" width="8" height="8"/>
+			<circle cx="20" cy="6" r="4" fill="red"/>
+			<ellipse cx="6" cy="20" rx="4" ry="2" style="fill: #00f"/>
+			<polygon points="16 16 28 16 28 28" fill="rgb(0, 128, 0)"/>
+			<path d="M16 28 L2 28 L2 24" fill="none"/>
+		</svg>`,
+		want: map[image.Point]color.RGBA{
+			{5, 5}:   black,
+			{11, 5}:  transparent,
+			{20, 6}:  red,
+			{6, 20}:  blue,
+			{6, 23}:  transparent,
+			{26, 18}: green,
+			{18, 26}: transparent,
+			{3, 27}:  transparent,
+		},
+	}, {
+		desc: "strokes",
+		svg: `<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32">
+			<line x1="2" y1="4" x2="30" y2="4" stroke="red" stroke-width="4"/>
+			<rect x="8" y="12" width="16" height="16" fill="none" stroke="blue" stroke-width="2"/>
+			<polyline points="2 20 6 20" stroke="black" stroke-width="2" stroke-dasharray="2"/>
+		</svg>`,
+		want: map[image.Point]color.RGBA{
+			{15, 2}:  red,
+			{15, 5}:  red,
+			{15, 6}:  transparent,
+			{7, 20}:  blue,
+			{8, 20}:  blue,
+			{9, 20}:  transparent,
+			{15, 20}: transparent,
+			{2, 20}:  black,
+			{3, 20}:  black,
+			{4, 20}:  transparent,
+			{5, 20}:  transparent,
+		},
+	}, {
+		desc: "groups",
+		svg: `<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32">
+			<g fill="red" transform="translate(16 0)">
+				<rect width="8" height="8"/>
+				<rect y="8" width="8" height="8" fill="blue"/>
+				<g transform="scale(2)" style="display: none"><rect width="8" height="8"/></g>
+				<rect y="16" width="8" height="8" visibility="hidden"/>
+			</g>
+			<g color="green" fill="currentColor"><rect y="24" width="8" height="8"/></g>
+		</svg>`,
+		want: map[image.Point]color.RGBA{
+			{4, 4}:   transparent,
+			{20, 4}:  red,
+			{20, 12}: blue,
+			{28, 4}:  transparent,
+			{20, 20}: transparent,
+			{4, 28}:  green,
+		},
+	}, {
+		desc: "opacity",
+		svg: `<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32">
+			<g opacity="0.5"><rect width="8" height="8" fill="red" opacity="0.5"/></g>
+			<rect x="8" width="8" height="8" fill="red" fill-opacity="0.5"/>
+		</svg>`,
+		want: map[image.Point]color.RGBA{
+			{4, 4}:  {0x40, 0, 0, 0x40},
+			{12, 4}: {0x80, 0, 0, 0x80},
+		},
+	}, {
+		desc: "viewBox",
+		svg: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="10 10 8 4">
+			<rect x="10" y="10" width="2" height="2"/>
+		</svg>`,
+		// The 8×4 viewBox is scaled by 4 and centered vertically.
+		want: map[image.Point]color.RGBA{
+			{0, 7}:  transparent,
+			{0, 8}:  black,
+			{7, 15}: black,
+			{8, 15}: transparent,
+			{7, 16}: transparent,
+		},
+	}, {
+		desc: "preserveAspectRatio",
+		svg: `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 8 4" preserveAspectRatio="none">
+			<rect width="2" height="2"/>
+		</svg>`,
+		want: map[image.Point]color.RGBA{
+			{7, 15}: black,
+			{8, 15}: transparent,
+			{7, 16}: transparent,
+		},
+	}}
+	for _, tc := range testCases {
+		m := decodeString(t, tc.svg)
+		checkPixels(t, tc.desc, m.Rasterize(32, 32), tc.want)
+	}
+}
+
+func TestRasterizeSize(t *testing.T) {
+	m := decodeString(t, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 30 20"/>`)
+	testCases := []struct {
+		width, height int
+		want          image.Point
+	}{
+		{0, 0, image.Point{30, 20}},
+		{60, 0, image.Point{60, 40}},
+		{0, 10, image.Point{15, 10}},
+		{7, 7, image.Point{7, 7}},
+	}
+	for _, tc := range testCases {
+		if got := m.Rasterize(tc.width, tc.height).Bounds().Size(); got != tc.want {
+			t.Errorf("%d×%d: got %v, want %v", tc.width, tc.height, got, tc.want)
+		}
+	}
+}
+
+// segments returns a compact representation of p, with its coordinates
+// rounded to 2 decimal places.
+func segments(p vector.Path) string {
+	nArgs := [...]int{1, 1, 2, 3, 0}
+	var b strings.Builder
+	for _, s := range p {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteByte("MLQCZ"[s.Op])
+		for i := 0; i < nArgs[s.Op]; i++ {
+			for _, v := range s.Args[i] {
+				fmt.Fprintf(&b, " %g", math.Round(float64(v)*100)/100)
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestParsePathData(t *testing.T) {
+	testCases := []struct {
+		d, want string
+	}{
+		{"M10 10h5v5H10z", "M 10 10 L 15 10 L 15 15 L 10 15 Z"},
+		// Implicit linetos, and relative coordinates after a closepath.
+		{"m1,2 3,4 5-6 z l1 1", "M 1 2 L 4 6 L 9 0 Z L 2 3"},
+		// Numbers need not be separated by white space.
+		{"M1-2.5.5.5L+1e1,2E-1", "M 1 -2.5 L 0.5 0.5 L 10 0.2"},
+		{"M0 0C1 0 2 1 2 2S3 4 4 4", "M 0 0 C 1 0 2 1 2 2 C 2 3 3 4 4 4"},
+		{"M0 0S1 1 2 0", "M 0 0 C 0 0 1 1 2 0"},
+		{"M0 0Q1 1 2 0T4 0t2 0", "M 0 0 Q 1 1 2 0 Q 3 -1 4 0 Q 5 1 6 0"},
+		// Arcs with zero radii are lines, and arcs to the current point are
+		// dropped.
+		{"M0 0A0 5 0 0 1 4 4A5 5 0 0 1 4 4", "M 0 0 L 4 4"},
+		// The path is rendered up to the first error.
+		{"M0 0L1 1L2", "M 0 0 L 1 1"},
+		{"L1 1", ""},
+		{"M0 0 1", "M 0 0"},
+		{"M0 0Z1 1", "M 0 0 Z"},
+	}
+	for _, tc := range testCases {
+		if got := segments(parsePathData(tc.d)); got != tc.want {
+			t.Errorf("%q:\ngot  %q\nwant %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestParsePathDataArc(t *testing.T) {
+	testCases := []struct {
+		d string
+		// mid is the point halfway along the arc.
+		mid f32.Vec2
+	}{
+		// Semicircles from (0, 0) to (20, 0), through (10, 10)
+		// counter-clockwise, as the Y axis increases down, or through
+		// (10, -10) clockwise.
+		{"M0 0A10 10 0 0 0 20 0", f32.Vec2{10, 10}},
+		{"M0 0A10 10 0 0 1 20 0", f32.Vec2{10, -10}},
+		// Radii that are too small are scaled up.
+		{"M0 0a1 1 0 1 1 20 0", f32.Vec2{10, -10}},
+		// A quarter of an ellipse, rotated by 90 degrees, so that its radii
+		// along the X and Y axes are 10 and 20, centered on (0, 20).
+		{"M0 0A20 10 90 0 1 10 20", f32.Vec2{10 / math.Sqrt2, 20 - 20/math.Sqrt2}},
+	}
+	for _, tc := range testCases {
+		p := parsePathData(tc.d)
+		if len(p) < 2 || p[0].Op != vector.PathOpMoveTo {
+			t.Errorf("%q: got %s, want cubic Bézier segments", tc.d, segments(p))
+			continue
+		}
+		cubes := p[1:]
+		for _, s := range cubes {
+			if s.Op != vector.PathOpCubeTo {
+				t.Fatalf("%q: got %s, want cubic Bézier segments", tc.d, segments(p))
+			}
+		}
+		// The arcs are symmetrical, so that they are halfway along at the
+		// end of the middle segment or, for an odd number of segments, at the
+		// middle of the middle segment.
+		var mid f32.Vec2
+		if n := len(cubes); n%2 == 0 {
+			mid = cubes[n/2-1].Args[2]
+		} else {
+			prev := p[n/2].Args[0]
+			if n/2 > 0 {
+				prev = p[n/2].Args[2]
+			}
+			a := &cubes[n/2].Args
+			for i := range mid {
+				mid[i] = (prev[i] + 3*a[0][i] + 3*a[1][i] + a[2][i]) / 8
+			}
+		}
+		if math.Abs(float64(mid[0]-tc.mid[0])) > 1e-3 || math.Abs(float64(mid[1]-tc.mid[1])) > 1e-3 {
+			t.Errorf("%q: got midpoint %v, want %v", tc.d, mid, tc.mid)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want color.NRGBA
+		ok   bool
+	}{
+		{"#123", color.NRGBA{0x11, 0x22, 0x33, 0xff}, true},
+		{"#A0b0C0", color.NRGBA{0xa0, 0xb0, 0xc0, 0xff}, true},
+		{"#12345", color.NRGBA{}, false},
+		{"rgb(10, 20, 30)", color.NRGBA{10, 20, 30, 0xff}, true},
+		{"rgb(100%,0%,50%)", color.NRGBA{0xff, 0, 0x80, 0xff}, true},
+		{"rgba(10, 20, 30, 0.5)", color.NRGBA{10, 20, 30, 0x80}, true},
+		{"rgb(10, 20)", color.NRGBA{}, false},
+		{"SteelBlue", color.NRGBA{70, 130, 180, 0xff}, true},
+		{"transparent", color.NRGBA{}, true},
+		{"nosuchcolor", color.NRGBA{}, false},
+	}
+	for _, tc := range testCases {
+		got, ok := parseColor(tc.s)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%q: got %v, %t, want %v, %t", tc.s, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseTransform(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want f32.Aff3
+		ok   bool
+	}{
+		{"", identity, true},
+		{"matrix(1 2 3 4 5 6)", f32.Aff3{1, 3, 5, 2, 4, 6}, true},
+		{"translate(10) scale(2, 3)", f32.Aff3{2, 0, 10, 0, 3, 0}, true},
+		{"scale(2),translate(10,20)", f32.Aff3{2, 0, 20, 0, 2, 40}, true},
+		{"rotate(90 10 10)", f32.Aff3{0, -1, 20, 1, 0, 0}, true},
+		{"skewX(45)", f32.Aff3{1, 1, 0, 0, 1, 0}, true},
+		{"translate(1 2 3)", identity, false},
+		{"spin(90)", identity, false},
+		{"scale(2", identity, false},
+	}
+	for _, tc := range testCases {
+		got, ok := parseTransform(tc.s)
+		for i := range got {
+			got[i] = float32(math.Round(float64(got[i])*1e4) / 1e4)
+		}
+		if !reflect.DeepEqual(got, tc.want) || ok != tc.ok {
+			t.Errorf("%q: got %v, %t, want %v, %t", tc.s, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testsupport
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"testing"
+
+	"golang.org/x/image/imgdiff"
+)
+
+// PSNR returns the peak signal-to-noise ratio, in decibels, between two
+// images of the same size. It is imgdiff.PSNR.
+func PSNR(a, b image.Image) (float64, error) {
+	return imgdiff.PSNR(a, b)
+}
+
+// SSIM returns the mean structural similarity index between the luma of two
+// images of the same size. It is imgdiff.SSIM.
+func SSIM(a, b image.Image) (float64, error) {
+	return imgdiff.SSIM(a, b)
+}
+
+// Tolerance is the minimum similarity for two images to be considered equal.
+// The zero value requires identical pixels.
+type Tolerance struct {
+	// MinPSNR is the minimum PSNR, in decibels. Zero means +Inf.
+	MinPSNR float64
+	// MinSSIM is the minimum SSIM. Zero means no minimum.
+	MinSSIM float64
+}
+
+// Compare returns a non-nil error, describing the difference, if got and
+// want have different sizes or are less similar than tol allows.
+func Compare(got, want image.Image, tol Tolerance) error {
+	psnr, err := PSNR(got, want)
+	if err != nil {
+		return fmt.Errorf("testsupport: got size %v, want %v", got.Bounds().Size(), want.Bounds().Size())
+	}
+	minPSNR := tol.MinPSNR
+	if minPSNR == 0 {
+		minPSNR = math.Inf(+1)
+	}
+	if psnr < minPSNR {
+		return fmt.Errorf("testsupport: PSNR %.2f dB is below the minimum %.2f dB", psnr, minPSNR)
+	}
+	if tol.MinSSIM != 0 {
+		ssim, err := SSIM(got, want)
+		if err != nil {
+			return err
+		}
+		if ssim < tol.MinSSIM {
+			return fmt.Errorf("testsupport: SSIM %.4f is below the minimum %.4f", ssim, tol.MinSSIM)
+		}
+	}
+	return nil
+}
+
+// CheckGolden compares got to the golden PNG image in the named file, and
+// reports an error to tb if they differ by more than tol allows.
+//
+// If update is true, it writes got to the file instead, creating or
+// replacing it. Tests typically set update from a command line flag, like
+// the draw package's -gen_golden_files flag.
+func CheckGolden(tb testing.TB, filename string, got image.Image, tol Tolerance, update bool) {
+	tb.Helper()
+	if update {
+		if err := writePNG(filename, got); err != nil {
+			tb.Fatalf("%s: %v", filename, err)
+		}
+		return
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		tb.Fatalf("%s: %v", filename, err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		tb.Fatalf("%s: %v", filename, err)
+	}
+	if err := Compare(got, want, tol); err != nil {
+		tb.Errorf("%s: %v", filename, err)
+	}
+}
+
+func writePNG(filename string, m image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package testsupport provides standard test patterns and golden image
+// comparison, for regression tests of image processing code such as scalers
+// and codecs.
+//
+// The patterns are deterministic and exercise different weaknesses: gradients
+// show banding, zone plates and Siemens stars show aliasing and ringing, and
+// translucent checkerboards show mistakes in alpha handling.
+//
+// Golden image comparisons are tolerant, measuring similarity by PSNR and
+// SSIM instead of requiring identical pixels, so that tests survive harmless
+// changes such as different rounding.
+package testsupport // import "golang.org/x/image/testsupport"
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Gradient returns an image with bounds r that fades linearly, from left to
+// right, from the color from to the color to. The interpolation is of
+// non-alpha-premultiplied 16-bit color values.
+func Gradient(r image.Rectangle, from, to color.Color) *image.NRGBA64 {
+	c0 := color.NRGBA64Model.Convert(from).(color.NRGBA64)
+	c1 := color.NRGBA64Model.Convert(to).(color.NRGBA64)
+	lerp := func(a, b uint16, t float64) uint16 {
+		return uint16(math.Floor(0.5 + float64(a) + t*(float64(b)-float64(a))))
+	}
+	m := image.NewNRGBA64(r)
+	w := r.Dx()
+	for x := r.Min.X; x < r.Max.X; x++ {
+		t := 0.0
+		if w > 1 {
+			t = float64(x-r.Min.X) / float64(w-1)
+		}
+		c := color.NRGBA64{
+			R: lerp(c0.R, c1.R, t),
+			G: lerp(c0.G, c1.G, t),
+			B: lerp(c0.B, c1.B, t),
+			A: lerp(c0.A, c1.A, t),
+		}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			m.SetNRGBA64(x, y, c)
+		}
+	}
+	return m
+}
+
+// ZonePlate returns a gray image with bounds r of concentric rings around
+// its center, whose spatial frequency increases linearly with the distance
+// from the center, reaching the Nyquist frequency, 0.5 cycles per pixel, at
+// the middle of the nearest edge. Scaling it down shows aliasing as spurious
+// rings, called moiré patterns.
+func ZonePlate(r image.Rectangle) *image.Gray {
+	m := image.NewGray(r)
+	cx := float64(r.Min.X+r.Max.X) / 2
+	cy := float64(r.Min.Y+r.Max.Y) / 2
+	radius := float64(r.Dx())
+	if h := float64(r.Dy()); radius > h {
+		radius = h
+	}
+	radius /= 2
+	if radius == 0 {
+		return m
+	}
+	// The phase is k*d², so the frequency is k*d/π cycles per pixel.
+	k := math.Pi / (2 * radius)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		dy := float64(y) + 0.5 - cy
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dx := float64(x) + 0.5 - cx
+			v := 0.5 + 0.5*math.Cos(k*(dx*dx+dy*dy))
+			m.SetGray(x, y, color.Gray{uint8(math.Floor(0.5 + 255*v))})
+		}
+	}
+	return m
+}
+
+// SiemensStar returns a gray image with bounds r of the given number of
+// black and the same number of white sectors, alternating around its center.
+// The sectors narrow towards the center, so it shows the spatial frequency at
+// which a scaler or codec loses detail. The sector edges are anti-aliased.
+func SiemensStar(r image.Rectangle, spokes int) *image.Gray {
+	const samples = 4
+	m := image.NewGray(r)
+	cx := float64(r.Min.X+r.Max.X) / 2
+	cy := float64(r.Min.Y+r.Max.Y) / 2
+	n := 2 * float64(spokes)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			white := 0
+			for j := 0; j < samples; j++ {
+				dy := float64(y) + (float64(j)+0.5)/samples - cy
+				for i := 0; i < samples; i++ {
+					dx := float64(x) + (float64(i)+0.5)/samples - cx
+					a := math.Atan2(dy, dx) + math.Pi
+					if int(a*n/(2*math.Pi))&1 != 0 {
+						white++
+					}
+				}
+			}
+			m.SetGray(x, y, color.Gray{uint8(255 * white / (samples * samples))})
+		}
+	}
+	return m
+}
+
+// Checkerboard returns an image with bounds r of size by size pixel squares
+// alternating between the colors c0 and c1, with c0 at r.Min. With translucent
+// colors, it tests that code composites, blends and encodes alpha correctly.
+func Checkerboard(r image.Rectangle, size int, c0, c1 color.Color) *image.NRGBA {
+	n0 := color.NRGBAModel.Convert(c0).(color.NRGBA)
+	n1 := color.NRGBAModel.Convert(c1).(color.NRGBA)
+	if size < 1 {
+		size = 1
+	}
+	m := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := n0
+			if ((x-r.Min.X)/size+(y-r.Min.Y)/size)&1 != 0 {
+				c = n1
+			}
+			m.SetNRGBA(x, y, c)
+		}
+	}
+	return m
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package testsupport
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	r := image.Rect(10, 20, 74, 68)
+
+	g := Gradient(r, color.Black, color.White)
+	if got := g.NRGBA64At(r.Min.X, r.Min.Y); got != (color.NRGBA64{0, 0, 0, 0xffff}) {
+		t.Errorf("Gradient: left: got %v", got)
+	}
+	if got := g.NRGBA64At(r.Max.X-1, r.Max.Y-1); got != (color.NRGBA64{0xffff, 0xffff, 0xffff, 0xffff}) {
+		t.Errorf("Gradient: right: got %v", got)
+	}
+
+	z := ZonePlate(r)
+	if got := z.GrayAt(42, 44).Y; got != 0xff {
+		t.Errorf("ZonePlate: center: got %#02x, want 0xff", got)
+	}
+
+	s := SiemensStar(r, 8)
+	black, white := 0, 0
+	for _, v := range s.Pix {
+		switch v {
+		case 0x00:
+			black++
+		case 0xff:
+			white++
+		}
+	}
+	if diff := black - white; diff < -len(s.Pix)/20 || diff > len(s.Pix)/20 {
+		t.Errorf("SiemensStar: got %d black and %d white pixels, want roughly equal", black, white)
+	}
+
+	c0, c1 := color.NRGBA{0xff, 0, 0, 0xff}, color.NRGBA{0, 0, 0xff, 0x40}
+	c := Checkerboard(r, 4, c0, c1)
+	if got := c.NRGBAAt(r.Min.X+3, r.Min.Y+3); got != c0 {
+		t.Errorf("Checkerboard: got %v, want %v", got, c0)
+	}
+	if got := c.NRGBAAt(r.Min.X+4, r.Min.Y+3); got != c1 {
+		t.Errorf("Checkerboard: got %v, want %v", got, c1)
+	}
+	if got := c.NRGBAAt(r.Min.X+4, r.Min.Y+4); got != c0 {
+		t.Errorf("Checkerboard: got %v, want %v", got, c0)
+	}
+}
+
+// noisy returns a copy of m with every n'th pixel changed by delta, up or
+// down, whichever does not overflow.
+func noisy(m *image.Gray, n int, delta uint8) *image.Gray {
+	ret := image.NewGray(m.Bounds())
+	copy(ret.Pix, m.Pix)
+	for i := 0; i < len(ret.Pix); i += n {
+		if ret.Pix[i] <= 0xff-delta {
+			ret.Pix[i] += delta
+		} else {
+			ret.Pix[i] -= delta
+		}
+	}
+	return ret
+}
+
+func TestPSNRAndSSIM(t *testing.T) {
+	m := ZonePlate(image.Rect(0, 0, 64, 64))
+
+	// Translating the bounds does not change the comparison.
+	moved := image.NewGray(image.Rect(5, 7, 69, 71))
+	copy(moved.Pix, m.Pix)
+	if psnr, err := PSNR(m, moved); err != nil || !math.IsInf(psnr, +1) {
+		t.Errorf("PSNR(identical): got %v, %v, want +Inf, nil", psnr, err)
+	}
+	if ssim, err := SSIM(m, moved); err != nil || math.Abs(ssim-1) > 1e-9 {
+		t.Errorf("SSIM(identical): got %v, %v, want 1, nil", ssim, err)
+	}
+
+	psnr1, _ := PSNR(m, noisy(m, 7, 3))
+	psnr2, _ := PSNR(m, noisy(m, 7, 30))
+	if !(psnr1 > psnr2) || psnr1 < 40 {
+		t.Errorf("PSNR: got %v for small noise and %v for large noise", psnr1, psnr2)
+	}
+	ssim1, _ := SSIM(m, noisy(m, 7, 3))
+	ssim2, _ := SSIM(m, noisy(m, 7, 30))
+	if !(ssim1 > ssim2) || ssim1 > 1 || ssim2 < -1 {
+		t.Errorf("SSIM: got %v for small noise and %v for large noise", ssim1, ssim2)
+	}
+
+	if _, err := PSNR(m, image.NewGray(image.Rect(0, 0, 64, 63))); err == nil {
+		t.Error("PSNR: different sizes: got nil error")
+	}
+	if _, err := SSIM(m, image.NewGray(image.Rect(0, 0, 63, 64))); err == nil {
+		t.Error("SSIM: different sizes: got nil error")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	m := SiemensStar(image.Rect(0, 0, 48, 48), 6)
+	n := noisy(m, 5, 2)
+	if err := Compare(m, m, Tolerance{}); err != nil {
+		t.Errorf("identical: %v", err)
+	}
+	if err := Compare(n, m, Tolerance{}); err == nil {
+		t.Error("different, zero tolerance: got nil error")
+	}
+	if err := Compare(n, m, Tolerance{MinPSNR: 40, MinSSIM: 0.9}); err != nil {
+		t.Errorf("different, within tolerance: %v", err)
+	}
+	if err := Compare(n, m, Tolerance{MinPSNR: 40, MinSSIM: 0.99999}); err == nil {
+		t.Error("different, SSIM out of tolerance: got nil error")
+	}
+}
+
+func TestCheckGolden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testsupport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "golden.png")
+
+	m := Checkerboard(image.Rect(0, 0, 16, 16), 2, color.White, color.Transparent)
+	CheckGolden(t, filename, m, Tolerance{}, true)
+	CheckGolden(t, filename, m, Tolerance{}, false)
+}
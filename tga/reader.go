@@ -0,0 +1,343 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tga implements a decoder and encoder for Truevision TGA (Targa)
+// images.
+//
+// Color-mapped images with 8-bit indices, true-color images with 15, 16, 24
+// or 32 bits per pixel and grayscale images with 8 bits per pixel are
+// supported, both uncompressed and run-length encoded.
+//
+// The format is described at
+// http://www.dca.fee.unicamp.br/~martino/disciplinas/ea978/tgaffs.pdf
+package tga // import "golang.org/x/image/tga"
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+)
+
+// A FormatError reports that the input is not a valid TGA image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "tga: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// TGA feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "tga: unsupported feature: " + string(e)
+}
+
+// Image types. The rle flag is set for run-length encoded images.
+const (
+	typeColorMapped = 1
+	typeTrueColor   = 2
+	typeGray        = 3
+	typeRLE         = 8
+)
+
+const (
+	headerLen = 18
+
+	// The image descriptor's flags. The low 4 bits are the number of
+	// attribute, or alpha, bits per pixel.
+	flagRightToLeft = 0x10
+	flagTopToBottom = 0x20
+	alphaBitsMask   = 0x0f
+)
+
+// header is the decoded header of a TGA image, together with its color map.
+type header struct {
+	imageType   int
+	width       int
+	height      int
+	depth       int
+	descriptor  byte
+	palette     color.Palette
+	colorModel  color.Model
+	rle         bool
+	bytesPerPix int
+}
+
+func readUint16(b []byte) int {
+	return int(b[0]) | int(b[1])<<8
+}
+
+// readHeader reads the header, image ID and color map of a TGA image.
+func readHeader(r io.Reader) (*header, error) {
+	var b [headerLen]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	h := &header{
+		imageType:  int(b[2] &^ typeRLE),
+		rle:        b[2]&typeRLE != 0,
+		width:      readUint16(b[12:]),
+		height:     readUint16(b[14:]),
+		depth:      int(b[16]),
+		descriptor: b[17],
+	}
+	idLen, cmType := int(b[0]), b[1]
+	cmFirst, cmLen, cmEntrySize := readUint16(b[3:]), readUint16(b[5:]), int(b[7])
+
+	if b[2]&^(typeRLE|3) != 0 || h.imageType == 0 {
+		return nil, UnsupportedError("image type")
+	}
+	if cmType > 1 {
+		return nil, FormatError("color map type")
+	}
+	if cmType == 0 && h.imageType == typeColorMapped {
+		return nil, FormatError("color-mapped image without a color map")
+	}
+	switch h.imageType {
+	case typeColorMapped, typeGray:
+		if h.depth != 8 {
+			return nil, UnsupportedError("pixel depth")
+		}
+	case typeTrueColor:
+		if h.depth != 15 && h.depth != 16 && h.depth != 24 && h.depth != 32 {
+			return nil, UnsupportedError("pixel depth")
+		}
+	}
+	h.bytesPerPix = (h.depth + 7) / 8
+
+	if _, err := io.CopyN(ioutil.Discard, r, int64(idLen)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	if cmType == 1 {
+		if cmEntrySize != 15 && cmEntrySize != 16 && cmEntrySize != 24 && cmEntrySize != 32 {
+			return nil, UnsupportedError("color map entry size")
+		}
+		n := (cmEntrySize + 7) / 8
+		cm := make([]byte, cmLen*n)
+		if _, err := io.ReadFull(r, cm); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if h.imageType == typeColorMapped {
+			if cmFirst+cmLen > 256 {
+				return nil, UnsupportedError("color map length")
+			}
+			// Pixel values index the color map from cmFirst. The entries
+			// before cmFirst, and those after the color map, are opaque
+			// black, so that every 8-bit pixel value is valid.
+			h.palette = make(color.Palette, 256)
+			for i := range h.palette {
+				h.palette[i] = color.RGBA{0x00, 0x00, 0x00, 0xff}
+			}
+			for i := 0; i < cmLen; i++ {
+				h.palette[cmFirst+i] = pixelColor(cm[i*n:], cmEntrySize, cmEntrySize == 16 || cmEntrySize == 32)
+			}
+		}
+	}
+
+	alpha := h.descriptor&alphaBitsMask != 0
+	switch h.imageType {
+	case typeColorMapped:
+		h.colorModel = h.palette
+	case typeGray:
+		h.colorModel = color.GrayModel
+	case typeTrueColor:
+		if alpha && (h.depth == 16 || h.depth == 32) {
+			h.colorModel = color.NRGBAModel
+		} else {
+			h.colorModel = color.RGBAModel
+		}
+	}
+	return h, nil
+}
+
+// pixelColor returns the color of a true-color pixel, or color map entry, of
+// the given depth. alpha is whether the pixel's attribute bits are alpha.
+// Otherwise, the pixel is opaque.
+func pixelColor(b []byte, depth int, alpha bool) color.Color {
+	switch depth {
+	case 15, 16:
+		v := readUint16(b)
+		c := color.NRGBA{
+			R: expand5(v >> 10),
+			G: expand5(v >> 5),
+			B: expand5(v),
+			A: 0xff,
+		}
+		if alpha && depth == 16 && v&0x8000 == 0 {
+			c.A = 0
+		}
+		return c
+	case 24:
+		return color.RGBA{b[2], b[1], b[0], 0xff}
+	}
+	if !alpha {
+		return color.RGBA{b[2], b[1], b[0], 0xff}
+	}
+	return color.NRGBA{b[2], b[1], b[0], b[3]}
+}
+
+// expand5 expands the low 5 bits of v to 8 bits.
+func expand5(v int) uint8 {
+	v &= 0x1f
+	return uint8(v<<3 | v>>2)
+}
+
+// readRLE reads run-length encoded pixels from r into pix, which is a whole
+// number of pixels of n bytes each. Packets may cross rows.
+func readRLE(r *bufio.Reader, pix []byte, n int) error {
+	for i := 0; i < len(pix); {
+		c, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		count := int(c&0x7f) + 1
+		if count*n > len(pix)-i {
+			return FormatError("run-length packet overflows the image")
+		}
+		if c&0x80 == 0 {
+			// A raw packet of count pixels.
+			if _, err := io.ReadFull(r, pix[i:i+count*n]); err != nil {
+				return err
+			}
+			i += count * n
+			continue
+		}
+		// A run-length packet of one pixel, repeated count times.
+		if _, err := io.ReadFull(r, pix[i:i+n]); err != nil {
+			return err
+		}
+		for j := 1; j < count; j++ {
+			copy(pix[i+j*n:i+j*n+n], pix[i:i+n])
+		}
+		i += count * n
+	}
+	return nil
+}
+
+// Decode reads a TGA image from r and returns it as an image.Image. The
+// type of Image returned depends on the contents of the TGA: an
+// *image.Paletted for color-mapped images, an *image.Gray for grayscale
+// images, and an *image.NRGBA or *image.RGBA for true-color images with or
+// without alpha. The attribute bits of 16 and 32 bit pixels are alpha only if
+// the image descriptor declares them.
+func Decode(r io.Reader) (image.Image, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	pix := make([]byte, h.width*h.height*h.bytesPerPix)
+	if h.rle {
+		err = readRLE(bufio.NewReader(r), pix, h.bytesPerPix)
+	} else {
+		_, err = io.ReadFull(r, pix)
+	}
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	rect := image.Rect(0, 0, h.width, h.height)
+	switch h.imageType {
+	case typeColorMapped, typeGray:
+		var dst []byte
+		var stride int
+		var m image.Image
+		if h.imageType == typeGray {
+			g := image.NewGray(rect)
+			dst, stride, m = g.Pix, g.Stride, g
+		} else {
+			p := image.NewPaletted(rect, h.palette)
+			dst, stride, m = p.Pix, p.Stride, p
+		}
+		for y := 0; y < h.height; y++ {
+			for x := 0; x < h.width; x++ {
+				dst[y*stride+x] = pix[h.offset(x, y)]
+			}
+		}
+		return m, nil
+	}
+
+	var dst []byte
+	var stride int
+	var m image.Image
+	alpha := h.colorModel == color.NRGBAModel
+	if alpha {
+		n := image.NewNRGBA(rect)
+		dst, stride, m = n.Pix, n.Stride, n
+	} else {
+		c := image.NewRGBA(rect)
+		dst, stride, m = c.Pix, c.Stride, c
+	}
+	for y := 0; y < h.height; y++ {
+		for x := 0; x < h.width; x++ {
+			s, d := pix[h.offset(x, y):], dst[y*stride+4*x:]
+			switch h.depth {
+			case 15, 16:
+				c := pixelColor(s, h.depth, alpha).(color.NRGBA)
+				d[0], d[1], d[2], d[3] = c.R, c.G, c.B, c.A
+			case 24:
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 0xff
+			case 32:
+				d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 0xff
+				if alpha {
+					d[3] = s[3]
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// offset returns the offset in the image data of the pixel at (x, y), where
+// y increases downwards, allowing for the image's origin.
+func (h *header) offset(x, y int) int {
+	if h.descriptor&flagRightToLeft != 0 {
+		x = h.width - 1 - x
+	}
+	if h.descriptor&flagTopToBottom == 0 {
+		y = h.height - 1 - y
+	}
+	return (y*h.width + x) * h.bytesPerPix
+}
+
+// DecodeConfig returns the color model and dimensions of a TGA image without
+// decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: h.colorModel,
+		Width:      h.width,
+		Height:     h.height,
+	}, nil
+}
+
+func init() {
+	// TGA files have no signature, so they are recognized by the color map
+	// type and image type that follow the image ID length, for the image
+	// types that Decode supports.
+	for _, magic := range []string{
+		"?\x00\x02", "?\x00\x03", "?\x00\x0a", "?\x00\x0b",
+		"?\x01\x01", "?\x01\x02", "?\x01\x09", "?\x01\x0a",
+	} {
+		image.RegisterFormat("tga", magic, Decode, DecodeConfig)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tga
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+// tgaFile returns a TGA file with the given header fields, image ID, color
+// map and image data.
+func tgaFile(imageType, depth, descriptor byte, w, h int, cm []byte, cmEntrySize byte, data []byte) []byte {
+	id := []byte("id")
+	b := []byte{
+		byte(len(id)), 0, imageType,
+		0, 0, 0, 0, cmEntrySize,
+		0, 0, 0, 0,
+		byte(w), byte(w >> 8), byte(h), byte(h >> 8),
+		depth, descriptor,
+	}
+	if cm != nil {
+		b[1] = 1
+		n := len(cm) / int((cmEntrySize+7)/8)
+		b[5], b[6] = byte(n), byte(n>>8)
+	}
+	b = append(b, id...)
+	b = append(b, cm...)
+	return append(b, data...)
+}
+
+func TestDecodeOrigin(t *testing.T) {
+	// A 2x2 grayscale image whose stored pixels are 1, 2, 3, 4, in the
+	// order given by the descriptor.
+	data := []byte{1, 2, 3, 4}
+	testCases := []struct {
+		descriptor byte
+		want       []uint8
+	}{
+		{0, []uint8{3, 4, 1, 2}},
+		{flagTopToBottom, []uint8{1, 2, 3, 4}},
+		{flagRightToLeft, []uint8{4, 3, 2, 1}},
+		{flagTopToBottom | flagRightToLeft, []uint8{2, 1, 4, 3}},
+	}
+	for _, tc := range testCases {
+		m, err := Decode(bytes.NewReader(tgaFile(typeGray, 8, tc.descriptor, 2, 2, nil, 0, data)))
+		if err != nil {
+			t.Fatalf("descriptor %#x: %v", tc.descriptor, err)
+		}
+		if got := m.(*image.Gray).Pix; !bytes.Equal(got, tc.want) {
+			t.Errorf("descriptor %#x: got %v, want %v", tc.descriptor, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeRLE(t *testing.T) {
+	// A 3x2 24-bit image, top-down, whose run-length packet crosses from
+	// the first row to the second.
+	data := []byte{
+		0x81, 0x10, 0x20, 0x30, // 2 x (0x30, 0x20, 0x10)
+		0x82, 0x01, 0x02, 0x03, // 3 x (0x03, 0x02, 0x01)
+		0x00, 0xff, 0xfe, 0xfd, // 1 raw pixel
+	}
+	m, err := Decode(bytes.NewReader(tgaFile(typeTrueColor|typeRLE, 24, flagTopToBottom, 3, 2, nil, 0, data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba := m.(*image.RGBA)
+	want := []color.RGBA{
+		{0x30, 0x20, 0x10, 0xff}, {0x30, 0x20, 0x10, 0xff}, {0x03, 0x02, 0x01, 0xff},
+		{0x03, 0x02, 0x01, 0xff}, {0x03, 0x02, 0x01, 0xff}, {0xfd, 0xfe, 0xff, 0xff},
+	}
+	for i, w := range want {
+		if got := rgba.RGBAAt(i%3, i/3); got != w {
+			t.Errorf("pixel %d: got %v, want %v", i, got, w)
+		}
+	}
+
+	// A packet that overflows the image is an error.
+	data = []byte{0x86, 0x01, 0x02, 0x03}
+	if _, err := Decode(bytes.NewReader(tgaFile(typeTrueColor|typeRLE, 24, 0, 3, 2, nil, 0, data))); err == nil {
+		t.Error("overflowing packet: got nil error")
+	}
+}
+
+func TestDecodeDepths(t *testing.T) {
+	testCases := []struct {
+		name string
+		file []byte
+		want color.Color
+	}{{
+		"16-bit with alpha",
+		tgaFile(typeTrueColor, 16, flagTopToBottom|1, 1, 1, nil, 0, []byte{0x1f, 0x7c}),
+		color.NRGBA{0xff, 0x00, 0xff, 0x00},
+	}, {
+		"15-bit",
+		tgaFile(typeTrueColor, 15, flagTopToBottom, 1, 1, nil, 0, []byte{0xe0, 0x03}),
+		color.RGBA{0x00, 0xff, 0x00, 0xff},
+	}, {
+		"32-bit with alpha",
+		tgaFile(typeTrueColor, 32, flagTopToBottom|8, 1, 1, nil, 0, []byte{1, 2, 3, 4}),
+		color.NRGBA{3, 2, 1, 4},
+	}, {
+		"32-bit without alpha",
+		tgaFile(typeTrueColor, 32, flagTopToBottom, 1, 1, nil, 0, []byte{1, 2, 3, 0}),
+		color.RGBA{3, 2, 1, 0xff},
+	}, {
+		"color-mapped",
+		tgaFile(typeColorMapped, 8, flagTopToBottom, 1, 1, []byte{1, 2, 3, 4, 5, 6}, 24, []byte{1}),
+		color.RGBA{6, 5, 4, 0xff},
+	}}
+	for _, tc := range testCases {
+		m, err := Decode(bytes.NewReader(tc.file))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		got, want := m.ColorModel().Convert(m.At(0, 0)), m.ColorModel().Convert(tc.want)
+		if got != want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, want)
+		}
+		c, err := DecodeConfig(bytes.NewReader(tc.file))
+		if err != nil {
+			t.Errorf("%s: DecodeConfig: %v", tc.name, err)
+		} else if c.Width != 1 || c.Height != 1 {
+			t.Errorf("%s: DecodeConfig: got %dx%d, want 1x1", tc.name, c.Width, c.Height)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		file []byte
+		err  error
+	}{
+		{"truncated header", []byte{0, 0, 2}, io.ErrUnexpectedEOF},
+		{"truncated data", tgaFile(typeGray, 8, 0, 2, 2, nil, 0, []byte{1, 2}), io.ErrUnexpectedEOF},
+		{"no color map", tgaFile(typeColorMapped, 8, 0, 1, 1, nil, 0, []byte{0}), FormatError("color-mapped image without a color map")},
+		{"gray depth", tgaFile(typeGray, 16, 0, 1, 1, nil, 0, []byte{0, 0}), UnsupportedError("pixel depth")},
+		{"image type", tgaFile(32, 8, 0, 1, 1, nil, 0, []byte{0}), UnsupportedError("image type")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(bytes.NewReader(tc.file)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tga
+
+import (
+	"bufio"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+// footer marks a file as a TGA 2.0 file, with neither an extension area nor
+// a developer directory.
+const footer = "\x00\x00\x00\x00\x00\x00\x00\x00TRUEVISION-XFILE.\x00"
+
+// Options are the encoding parameters.
+type Options struct {
+	// RLE is whether to run-length encode the image.
+	RLE bool
+}
+
+// Encode writes the image m to w in TGA format. A nil opts is equivalent to
+// a zero Options.
+//
+// An *image.Gray is written as a grayscale image, and an *image.Paletted as
+// a color-mapped image. Other images are written as 24-bit true-color images
+// if they are opaque, and 32-bit true-color images with alpha otherwise. The
+// rows are written from the bottom up, the traditional TGA order.
+func Encode(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	b := m.Bounds()
+	if b.Dx() > 0xffff || b.Dy() > 0xffff {
+		return errors.New("tga: image is too large to encode")
+	}
+
+	var h [headerLen]byte
+	var cm []byte
+	var row func(y int, dst []byte)
+	switch m := m.(type) {
+	case *image.Gray:
+		h[2], h[16] = typeGray, 8
+		row = func(y int, dst []byte) {
+			i := m.PixOffset(b.Min.X, y)
+			copy(dst, m.Pix[i:i+b.Dx()])
+		}
+	case *image.Paletted:
+		if len(m.Palette) == 0 || len(m.Palette) > 256 {
+			return errors.New("tga: bad palette length")
+		}
+		h[1], h[2], h[16] = 1, typeColorMapped, 8
+		cm, h[7] = encodePalette(m.Palette)
+		h[5], h[6] = uint8(len(m.Palette)), uint8(len(m.Palette)>>8)
+		row = func(y int, dst []byte) {
+			i := m.PixOffset(b.Min.X, y)
+			copy(dst, m.Pix[i:i+b.Dx()])
+		}
+	default:
+		n := 3
+		h[2], h[16] = typeTrueColor, 24
+		if op, ok := m.(interface{ Opaque() bool }); !ok || !op.Opaque() {
+			n = 4
+			h[16], h[17] = 32, 8
+		}
+		row = func(y int, dst []byte) {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+				dst[0], dst[1], dst[2] = c.B, c.G, c.R
+				if n == 4 {
+					dst[3] = c.A
+				}
+				dst = dst[n:]
+			}
+		}
+	}
+	if o.RLE {
+		h[2] |= typeRLE
+	}
+	h[12], h[13] = uint8(b.Dx()), uint8(b.Dx()>>8)
+	h[14], h[15] = uint8(b.Dy()), uint8(b.Dy()>>8)
+
+	bw := bufio.NewWriter(w)
+	bw.Write(h[:])
+	bw.Write(cm)
+	n := int(h[16]) / 8
+	buf := make([]byte, b.Dx()*n)
+	var packets []byte
+	for y := b.Max.Y - 1; y >= b.Min.Y; y-- {
+		row(y, buf)
+		if o.RLE {
+			packets = appendRLE(packets[:0], buf, n)
+			bw.Write(packets)
+		} else {
+			bw.Write(buf)
+		}
+	}
+	bw.WriteString(footer)
+	return bw.Flush()
+}
+
+// encodePalette returns the color map entries for p, and their size in bits:
+// 24 if every color is opaque, or 32 otherwise.
+func encodePalette(p color.Palette) ([]byte, uint8) {
+	n := 3
+	for _, c := range p {
+		if _, _, _, a := c.RGBA(); a != 0xffff {
+			n = 4
+			break
+		}
+	}
+	cm := make([]byte, 0, len(p)*n)
+	for _, c := range p {
+		nc := color.NRGBAModel.Convert(c).(color.NRGBA)
+		cm = append(cm, nc.B, nc.G, nc.R)
+		if n == 4 {
+			cm = append(cm, nc.A)
+		}
+	}
+	return cm, uint8(8 * n)
+}
+
+// appendRLE appends the run-length encoded packets of a row of pixels of n
+// bytes each to dst. Packets do not cross rows, as TGA 2.0 recommends.
+func appendRLE(dst, row []byte, n int) []byte {
+	npix := len(row) / n
+	same := func(i, j int) bool {
+		for k := 0; k < n; k++ {
+			if row[i*n+k] != row[j*n+k] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < npix; {
+		// Count the run of pixels equal to the i'th.
+		run := 1
+		for i+run < npix && run < 128 && same(i, i+run) {
+			run++
+		}
+		if run > 1 {
+			dst = append(dst, uint8(0x80|(run-1)))
+			dst = append(dst, row[i*n:i*n+n]...)
+			i += run
+			continue
+		}
+		// Collect raw pixels up to the start of the next run.
+		j := i + 1
+		for j < npix && j-i < 128 && !(j+1 < npix && same(j, j+1)) {
+			j++
+		}
+		dst = append(dst, uint8(j-i-1))
+		dst = append(dst, row[i*n:j*n]...)
+		i = j
+	}
+	return dst
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tga
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func compare(m0, m1 image.Image) error {
+	b0, b1 := m0.Bounds(), m1.Bounds()
+	if b0.Size() != b1.Size() {
+		return fmt.Errorf("dimensions differ: %v vs %v", b0, b1)
+	}
+	for y := 0; y < b0.Dy(); y++ {
+		for x := 0; x < b0.Dx(); x++ {
+			c0 := color.NRGBAModel.Convert(m0.At(b0.Min.X+x, b0.Min.Y+y))
+			c1 := color.NRGBAModel.Convert(m1.At(b1.Min.X+x, b1.Min.Y+y))
+			if c0 != c1 {
+				return fmt.Errorf("pixel (%d, %d): %v vs %v", x, y, c0, c1)
+			}
+		}
+	}
+	return nil
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := image.Rect(3, 5, 40, 21)
+	gray := image.NewGray(r)
+	rgba := image.NewRGBA(r)
+	nrgba := image.NewNRGBA(r)
+	paletted := image.NewPaletted(r, color.Palette{
+		color.RGBA{0xff, 0x00, 0x00, 0xff},
+		color.NRGBA{0x00, 0xff, 0x00, 0x80},
+		color.RGBA{0x00, 0x00, 0x00, 0x00},
+	})
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			// Runs of equal pixels, broken by unequal ones.
+			v := uint8(x/4*20 + y)
+			if x%7 == 0 {
+				v = uint8(x * y)
+			}
+			gray.SetGray(x, y, color.Gray{v})
+			rgba.SetRGBA(x, y, color.RGBA{v, v / 2, 0xff - v, 0xff})
+			nrgba.SetNRGBA(x, y, color.NRGBA{v, v / 2, 0xff - v, v / 3})
+			paletted.SetColorIndex(x, y, v%3)
+		}
+	}
+
+	testCases := []struct {
+		name  string
+		m     image.Image
+		model color.Model
+	}{
+		{"gray", gray, color.GrayModel},
+		{"rgba", rgba, color.RGBAModel},
+		{"nrgba", nrgba, color.NRGBAModel},
+		{"paletted", paletted, nil},
+	}
+	for _, tc := range testCases {
+		for _, rle := range []bool{false, true} {
+			var buf bytes.Buffer
+			if err := Encode(&buf, tc.m, &Options{RLE: rle}); err != nil {
+				t.Errorf("%s, rle=%t: Encode: %v", tc.name, rle, err)
+				continue
+			}
+			m, format, err := image.Decode(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Errorf("%s, rle=%t: Decode: %v", tc.name, rle, err)
+				continue
+			}
+			if format != "tga" {
+				t.Errorf("%s, rle=%t: format: got %q, want \"tga\"", tc.name, rle, format)
+			}
+			if tc.model != nil && m.ColorModel() != tc.model {
+				t.Errorf("%s, rle=%t: color model: got %v, want %v", tc.name, rle, m.ColorModel(), tc.model)
+			}
+			if err := compare(tc.m, m); err != nil {
+				t.Errorf("%s, rle=%t: %v", tc.name, rle, err)
+			}
+		}
+	}
+}
+
+func TestAppendRLE(t *testing.T) {
+	row := []byte{1, 1, 1, 2, 3, 4, 4}
+	got := appendRLE(nil, row, 1)
+	want := []byte{0x82, 1, 0x01, 2, 3, 0x81, 4}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+
+	// Runs and raw packets are at most 128 pixels long.
+	row = make([]byte, 300)
+	got = appendRLE(nil, row, 1)
+	want = []byte{0xff, 0, 0xff, 0, 0xab, 0}
+	if !bytes.Equal(got, want) {
+		t.Errorf("long run: got %x, want %x", got, want)
+	}
+	for i := range row {
+		row[i] = byte(i)
+	}
+	got = appendRLE(nil, row, 1)
+	if got[0] != 0x7f || got[129] != 0x7f || got[258] != 300-256-1 {
+		t.Errorf("long raw packet: got headers %#x, %#x, %#x", got[0], got[129], got[258])
+	}
+}
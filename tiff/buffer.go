@@ -13,23 +13,19 @@ type buffer struct {
 }
 
 // fill reads data from b.r until the buffer contains at least end bytes.
+// The buffer grows as the data arrives, rather than to end at once, as end
+// may come from a malformed file and be far beyond the end of the data.
 func (b *buffer) fill(end int) error {
-	m := len(b.buf)
-	if end > m {
-		if end > cap(b.buf) {
-			newcap := 1024
-			for newcap < end {
-				newcap *= 2
-			}
-			newbuf := make([]byte, end, newcap)
+	for len(b.buf) < end {
+		if len(b.buf) == cap(b.buf) {
+			newbuf := make([]byte, len(b.buf), 2*cap(b.buf)+1024)
 			copy(newbuf, b.buf)
 			b.buf = newbuf
-		} else {
-			b.buf = b.buf[:end]
 		}
-		if n, err := io.ReadFull(b.r, b.buf[m:end]); err != nil {
-			end = m + n
-			b.buf = b.buf[:end]
+		m := len(b.buf)
+		n, err := io.ReadFull(b.r, b.buf[m:minInt(end, cap(b.buf))])
+		b.buf = b.buf[:m+n]
+		if err != nil {
 			return err
 		}
 	}
@@ -44,7 +40,10 @@ func (b *buffer) ReadAt(p []byte, off int64) (int, error) {
 	}
 
 	err := b.fill(end)
-	return copy(p, b.buf[o:end]), err
+	if o >= len(b.buf) {
+		return 0, err
+	}
+	return copy(p, b.buf[o:minInt(end, len(b.buf))]), err
 }
 
 // Slice returns a slice of the underlying buffer. The slice contains
@@ -0,0 +1,148 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"errors"
+	"image"
+	"io"
+)
+
+// ErrNoChannel is returned by DecodeChannel for a channel that the image does
+// not have, such as Alpha for an image without an alpha channel.
+var ErrNoChannel = errors.New("tiff: no such channel")
+
+// Alpha is the channel argument to DecodeChannel that selects the image's
+// alpha channel: the first of its extra samples, as listed by the
+// ExtraSamples tag, that is associated or unassociated alpha.
+const Alpha = -1
+
+// DecodeChannel reads a TIFF image from r and returns one of its channels as
+// a grayscale image, such as the alpha channel or an extra sample plane of a
+// layered scan, for use as a mask. The channel is either Alpha or the index
+// of a sample of each pixel: for example 0, 1 and 2 for the red, green and
+// blue samples of an RGB image, and 3 and up for its extra samples.
+//
+// The channel's sample values are returned as they are stored, without the
+// color interpretation that Decode applies: samples of 16 bits are reduced to
+// their high 8 bits, and samples of 1 bit become 0x00 or 0xff. Only the one
+// channel is held in memory, and for images whose channels are stored in
+// separate planes, only that channel's plane is read. Images with floating
+// point samples are not supported.
+//
+// Of opts, only the Limits are used, and a nil opts means no limits.
+// DecodeChannel returns an *imagelimit.Error if the image exceeds them, and
+// ErrNoChannel if the image has no such channel.
+func DecodeChannel(r io.Reader, channel int, opts *DecodeOptions) (*image.Gray, error) {
+	ra := newReaderAt(r)
+	byteOrder, bigTIFF, ifdOffset, err := readHeader(ra)
+	if err != nil {
+		return nil, err
+	}
+	d, err := readIFD(ra, byteOrder, bigTIFF, ifdOffset)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		d.limits = opts.Limits
+	}
+	if d.float {
+		return nil, UnsupportedError("floating point channel")
+	}
+	spp := len(d.features[tBitsPerSample])
+	for _, b := range d.features[tBitsPerSample] {
+		if b != d.bpp {
+			return nil, UnsupportedError("channels of different sizes")
+		}
+	}
+
+	if channel == Alpha {
+		extra := d.features[tExtraSamples]
+		for i, e := range extra {
+			if e == esAssociatedAlpha || e == esUnassociatedAlpha {
+				channel = spp - len(extra) + i
+				break
+			}
+		}
+	}
+	if channel < 0 || spp <= channel {
+		return nil, ErrNoChannel
+	}
+
+	// The blocks of a planar image hold one sample per pixel.
+	plane := 0
+	if d.firstVal(tPlanarConfiguration) == pcPlanar {
+		plane, channel, spp = channel, 0, 1
+	}
+	blocks, err := d.blocks(plane)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.checkLimits(blocks, 8); err != nil {
+		return nil, err
+	}
+	img := image.NewGray(image.Rect(0, 0, d.config.Width, d.config.Height))
+	for _, b := range blocks {
+		if err := d.decompress(d.r, b.offset, b.n, b.rect.Dx(), b.rect.Dy()); err != nil {
+			return nil, err
+		}
+		if err := d.decodeChannel(img, channel, spp, b.rect.Min.X, b.rect.Min.Y, b.rect.Max.X, b.rect.Max.Y); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// decodeChannel decodes the channel'th sample of each pixel of the raw data
+// in d.buf, whose pixels have spp samples, into the strip or tile of dst from
+// (xmin, ymin) to (xmax, ymax).
+func (d *decoder) decodeChannel(dst *image.Gray, channel, spp, xmin, ymin, xmax, ymax int) error {
+	d.off = 0
+	if err := d.undoPredictor(spp, xmin, ymin, xmax, ymax); err != nil {
+		return err
+	}
+
+	rMaxX := minInt(xmax, dst.Rect.Max.X)
+	rMaxY := minInt(ymax, dst.Rect.Max.Y)
+	switch d.bpp {
+	case 8, 16:
+		n := int(d.bpp / 8) // Bytes per sample.
+		for y := ymin; y < rMaxY; y++ {
+			off := ((y-ymin)*(xmax-xmin)*spp + channel) * n
+			i := dst.PixOffset(xmin, y)
+			for x := xmin; x < rMaxX; x++ {
+				if off+n > len(d.buf) {
+					return errNoPixels
+				}
+				if n == 2 {
+					dst.Pix[i] = uint8(d.byteOrder.Uint16(d.buf[off:]) >> 8)
+				} else {
+					dst.Pix[i] = d.buf[off]
+				}
+				off += spp * n
+				i++
+			}
+		}
+	default:
+		for y := ymin; y < rMaxY; y++ {
+			i := dst.PixOffset(xmin, y)
+			for x := xmin; x < rMaxX; x++ {
+				for s := 0; s < spp; s++ {
+					v, ok := d.readBits(d.bpp)
+					if !ok {
+						return errNoPixels
+					}
+					if s == channel {
+						dst.Pix[i] = uint8(v * 0xff)
+					}
+				}
+				i++
+			}
+			d.skipBits(spp * (xmax - rMaxX))
+			d.flushBits()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDecodeChannel(t *testing.T) {
+	m := image.NewNRGBA64(image.Rect(0, 0, 5, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			m.SetNRGBA64(x, y, color.NRGBA64{
+				R: uint16(x * 0x1111),
+				G: uint16(y * 0x2222),
+				B: 0x1234,
+				A: uint16((x + 5*y) * 0x0f0f),
+			})
+		}
+	}
+	for _, opts := range []*Options{nil, {Compression: Deflate, Predictor: true}} {
+		for _, depth16 := range []bool{false, true} {
+			var src image.Image = m
+			if !depth16 {
+				m8 := image.NewNRGBA(m.Bounds())
+				for y := 0; y < 3; y++ {
+					for x := 0; x < 5; x++ {
+						m8.Set(x, y, m.At(x, y))
+					}
+				}
+				src = m8
+			}
+			var buf bytes.Buffer
+			if err := Encode(&buf, src, opts); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			for _, channel := range []int{0, 1, Alpha} {
+				i := channel
+				if channel == Alpha {
+					i = 2
+				}
+				got, err := DecodeChannel(bytes.NewReader(buf.Bytes()), channel, nil)
+				if err != nil {
+					t.Fatalf("opts=%v, 16 bit=%t, channel=%d: DecodeChannel: %v", opts, depth16, channel, err)
+				}
+				for y := 0; y < 3; y++ {
+					for x := 0; x < 5; x++ {
+						c := m.NRGBA64At(x, y)
+						want := [...]uint16{c.R, c.G, c.A}[i]
+						if g := got.GrayAt(x, y).Y; g != uint8(want>>8) {
+							t.Errorf("opts=%v, 16 bit=%t, channel=%d: (%d, %d): got %#02x, want %#02x",
+								opts, depth16, channel, x, y, g, want>>8)
+						}
+					}
+				}
+			}
+			if _, err := DecodeChannel(bytes.NewReader(buf.Bytes()), 4, nil); err != ErrNoChannel {
+				t.Errorf("opts=%v, 16 bit=%t: DecodeChannel(4): got %v, want ErrNoChannel", opts, depth16, err)
+			}
+		}
+	}
+}
+
+func TestDecodeChannelGray(t *testing.T) {
+	data, err := ioutil.ReadFile(testdataDir + "video-001-gray.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	got, err := DecodeChannel(bytes.NewReader(data), 0, nil)
+	if err != nil {
+		t.Fatalf("DecodeChannel: %v", err)
+	}
+	if !bytes.Equal(got.Pix, want.(*image.Gray).Pix) {
+		t.Error("DecodeChannel(0) differs from Decode")
+	}
+	if _, err := DecodeChannel(bytes.NewReader(data), Alpha, nil); err != ErrNoChannel {
+		t.Errorf("DecodeChannel(Alpha): got %v, want ErrNoChannel", err)
+	}
+}
+
+// planarTIFF returns an uncompressed, planar, 8 bit RGB TIFF file, one pixel
+// high, with a strip for each of the given planes.
+func planarTIFF(planes [3][]byte) []byte {
+	type entry struct {
+		tag, datatype uint16
+		values        []uint32
+	}
+	width := uint32(len(planes[0]))
+	const ifdOffset, dataOffset = 8, 8 + 2 + 8*12 + 4
+	entries := []entry{
+		{tImageWidth, dtLong, []uint32{width}},
+		{tImageLength, dtLong, []uint32{1}},
+		{tBitsPerSample, dtShort, []uint32{8, 8, 8}},
+		{tPhotometricInterpretation, dtShort, []uint32{pRGB}},
+		{tStripOffsets, dtLong, nil},
+		{tRowsPerStrip, dtLong, []uint32{1}},
+		{tStripByteCounts, dtLong, []uint32{width, width, width}},
+		{tPlanarConfiguration, dtShort, []uint32{pcPlanar}},
+	}
+	// The arrays of more than one value follow the IFD, and then the strips.
+	var arrays []byte
+	arraysLen := 2*3 + 4*3 + 4*3
+	for i := 0; i < 3; i++ {
+		entries[4].values = append(entries[4].values, uint32(dataOffset+arraysLen)+uint32(i)*width)
+	}
+
+	le := binary.LittleEndian
+	b := []byte(leHeader)
+	b = append(b, 0, 0, 0, 0)
+	le.PutUint32(b[4:], ifdOffset)
+	b = append(b, 0, 0)
+	le.PutUint16(b[ifdOffset:], uint16(len(entries)))
+	for _, e := range entries {
+		var p [12]byte
+		le.PutUint16(p[0:], e.tag)
+		le.PutUint16(p[2:], e.datatype)
+		le.PutUint32(p[4:], uint32(len(e.values)))
+		if len(e.values) == 1 {
+			le.PutUint32(p[8:], e.values[0])
+			if e.datatype == dtShort {
+				le.PutUint16(p[8:], uint16(e.values[0]))
+			}
+		} else {
+			le.PutUint32(p[8:], uint32(dataOffset+len(arrays)))
+			for _, v := range e.values {
+				if e.datatype == dtShort {
+					arrays = append(arrays, uint8(v), uint8(v>>8))
+				} else {
+					arrays = append(arrays, uint8(v), uint8(v>>8), uint8(v>>16), uint8(v>>24))
+				}
+			}
+		}
+		b = append(b, p[:]...)
+	}
+	b = append(b, 0, 0, 0, 0) // No next IFD.
+	b = append(b, arrays...)
+	for _, p := range planes {
+		b = append(b, p...)
+	}
+	return b
+}
+
+func TestDecodeChannelPlanar(t *testing.T) {
+	planes := [3][]byte{
+		{0x10, 0x11, 0x12},
+		{0x20, 0x21, 0x22},
+		{0x30, 0x31, 0x32},
+	}
+	data := planarTIFF(planes)
+	for channel, want := range planes {
+		got, err := DecodeChannel(bytes.NewReader(data), channel, nil)
+		if err != nil {
+			t.Fatalf("channel=%d: DecodeChannel: %v", channel, err)
+		}
+		if !bytes.Equal(got.Pix, want) {
+			t.Errorf("channel=%d: got % x, want % x", channel, got.Pix, want)
+		}
+	}
+	if _, err := Decode(bytes.NewReader(data)); err == nil {
+		t.Error("Decode: got nil error, want non-nil")
+	}
+}
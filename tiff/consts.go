@@ -14,28 +14,45 @@ package tiff
 //  - the data itself or a pointer to it if it is more than 4 bytes.
 //
 // The presence of a length means that each IFD is effectively an array.
+//
+// BigTIFF files, for images of more than 4 GB, have the same structure but
+// use 8 byte offsets, 8 byte entry counts and entries of 20 bytes, with the
+// data stored in the entry itself if it is at most 8 bytes. See
+// https://www.awaresystems.be/imaging/tiff/bigtiff.html
 
 const (
-	leHeader = "II\x2A\x00" // Header for little-endian files.
-	beHeader = "MM\x00\x2A" // Header for big-endian files.
+	leHeader    = "II\x2A\x00" // Header for little-endian files.
+	beHeader    = "MM\x00\x2A" // Header for big-endian files.
+	leBigHeader = "II\x2B\x00" // Header for little-endian BigTIFF files.
+	beBigHeader = "MM\x00\x2B" // Header for big-endian BigTIFF files.
 
-	ifdLen = 12 // Length of an IFD entry in bytes.
+	ifdLen    = 12 // Length of an IFD entry in bytes.
+	bigIFDLen = 20 // Length of a BigTIFF IFD entry in bytes.
 )
 
 // Data types (p. 14-16 of the spec).
 const (
-	dtByte     = 1
-	dtASCII    = 2
-	dtShort    = 3
-	dtLong     = 4
-	dtRational = 5
+	dtByte      = 1
+	dtASCII     = 2
+	dtShort     = 3
+	dtLong      = 4
+	dtRational  = 5
+	dtUndefined = 7
+	dtSRational = 10
+	dtDouble    = 12
+	dtLong8     = 16 // BigTIFF only.
+	dtIFD8      = 18 // BigTIFF only.
 )
 
-// The length of one instance of each data type in bytes.
-var lengths = [...]uint32{0, 1, 1, 2, 4, 8}
+// The length of one instance of each data type in bytes. Types 6 to 11 are
+// SByte, Undefined, SShort, SLong, SRational and Float, type 13 is IFD and
+// type 17 is SLong8. Types 14 and 15 are not defined.
+var lengths = [...]uint32{0, 1, 1, 2, 4, 8, 1, 1, 2, 4, 8, 4, 8, 4, 0, 0, 8, 8, 8}
 
 // Tags (see p. 28-41 of the spec).
 const (
+	tNewSubfileType = 254
+
 	tImageWidth                = 256
 	tImageLength               = 257
 	tBitsPerSample             = 258
@@ -45,6 +62,7 @@ const (
 	tFillOrder = 266
 
 	tStripOffsets    = 273
+	tOrientation     = 274
 	tSamplesPerPixel = 277
 	tRowsPerStrip    = 278
 	tStripByteCounts = 279
@@ -52,19 +70,35 @@ const (
 	tT4Options = 292 // CCITT Group 3 options, a set of 32 flag bits.
 	tT6Options = 293 // CCITT Group 4 options, a set of 32 flag bits.
 
+	tPageNumber = 297
+
 	tTileWidth      = 322
 	tTileLength     = 323
 	tTileOffsets    = 324
 	tTileByteCounts = 325
 
-	tXResolution    = 282
-	tYResolution    = 283
-	tResolutionUnit = 296
+	tXResolution         = 282
+	tYResolution         = 283
+	tPlanarConfiguration = 284
+	tResolutionUnit      = 296
 
 	tPredictor    = 317
 	tColorMap     = 320
 	tExtraSamples = 338
 	tSampleFormat = 339
+
+	// GeoTIFF tags (see section 2.6 of the GeoTIFF 1.0 specification).
+	tModelPixelScale     = 33550
+	tModelTiepoint       = 33922
+	tModelTransformation = 34264
+	tGeoKeyDirectory     = 34735
+	tGeoDoubleParams     = 34736
+	tGeoASCIIParams      = 34737
+
+	// Metadata tags from the EXIF 2.3 and ICC.1:2010 specifications.
+	tExifIFD    = 34665 // The offset of the EXIF sub-IFD.
+	tICCProfile = 34675
+	tInteropIFD = 40965 // The offset of the Interoperability IFD, in the EXIF IFD.
 )
 
 // Compression types (defined in various places in the spec and supplements).
@@ -93,10 +127,37 @@ const (
 	pCIELab      = 8
 )
 
-// Values for the tPredictor tag (page 64-65 of the spec).
+// Values for the tPredictor tag (page 64-65 of the spec, and Adobe
+// Photoshop TIFF Technical Note 3 for prFloatingPoint).
+const (
+	prNone          = 1
+	prHorizontal    = 2
+	prFloatingPoint = 3
+)
+
+// Values for the tPlanarConfiguration tag (page 38 of the spec).
+const (
+	pcChunky = 1 // The samples of each pixel are stored contiguously.
+	pcPlanar = 2 // Each sample is stored in its own plane of strips or tiles.
+)
+
+// Values for the tExtraSamples tag (page 31 of the spec).
+const (
+	esUnspecified       = 0
+	esAssociatedAlpha   = 1 // Premultiplied alpha.
+	esUnassociatedAlpha = 2
+)
+
+// Values for the tSampleFormat tag (page 80 of the spec).
+const (
+	sfUint  = 1 // Unsigned integer data.
+	sfFloat = 3 // IEEE floating point data.
+)
+
+// Bits of the tNewSubfileType tag (page 36).
 const (
-	prNone       = 1
-	prHorizontal = 2
+	subfileReducedImage = 1 // A reduced-resolution version of another image.
+	subfilePage         = 2 // A single page of a multi-page image.
 )
 
 // Values for the tResolutionUnit tag (page 18).
@@ -6,17 +6,16 @@
 
 package tiff
 
-import "bytes"
+import (
+	"bytes"
+
+	"golang.org/x/image/imagelimit"
+)
 
 func Fuzz(data []byte) int {
-	cfg, err := DecodeConfig(bytes.NewReader(data))
-	if err != nil {
-		return 0
-	}
-	if cfg.Width*cfg.Height > 1e6 {
-		return 0
-	}
-	img, err := Decode(bytes.NewReader(data))
+	img, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+		Limits: &imagelimit.Limits{MaxPixels: 1e6},
+	})
 	if err != nil {
 		return 0
 	}
@@ -0,0 +1,195 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"io"
+	"math"
+	"strings"
+)
+
+// GeoTIFF holds the georeferencing tags of a GeoTIFF image, as defined by
+// the GeoTIFF 1.0 specification. The values are passed through unchanged: no
+// projection or coordinate transformation is performed.
+//
+// Empty fields correspond to absent tags.
+type GeoTIFF struct {
+	// ModelPixelScale is the (ScaleX, ScaleY, ScaleZ) size of a pixel in
+	// model space.
+	ModelPixelScale []float64
+	// ModelTiepoint holds tie points, each of six values (I, J, K, X, Y, Z),
+	// mapping raster space (I, J, K) to model space (X, Y, Z).
+	ModelTiepoint []float64
+	// ModelTransformation is a 4x4 row major raster to model space
+	// transformation matrix.
+	ModelTransformation []float64
+	// GeoKeyDirectory is the raw GeoKeyDirectoryTag value. Use the Keys
+	// method to decode it.
+	GeoKeyDirectory []uint16
+	// GeoDoubleParams holds the double valued GeoKeys' values.
+	GeoDoubleParams []float64
+	// GeoASCIIParams holds the ASCII valued GeoKeys' values, each terminated
+	// by a '|'.
+	GeoASCIIParams string
+}
+
+// GeoKey is a single entry of a GeoKeyDirectory. Exactly one of Shorts,
+// Doubles and ASCII is set, depending on where the key's value is stored.
+type GeoKey struct {
+	ID      uint16
+	Shorts  []uint16
+	Doubles []float64
+	ASCII   string
+}
+
+// Keys decodes the GeoKeyDirectory. It returns nil if there is no directory.
+func (g *GeoTIFF) Keys() ([]GeoKey, error) {
+	dir := g.GeoKeyDirectory
+	if len(dir) == 0 {
+		return nil, nil
+	}
+	if len(dir) < 4 {
+		return nil, FormatError("bad GeoKeyDirectory length")
+	}
+	if dir[0] != 1 {
+		return nil, UnsupportedError("GeoKeyDirectory version")
+	}
+	n := int(dir[3])
+	if len(dir) < 4+4*n {
+		return nil, FormatError("bad GeoKeyDirectory length")
+	}
+	keys := make([]GeoKey, n)
+	for i := range keys {
+		e := dir[4+4*i:]
+		id, location, count, value := e[0], e[1], int(e[2]), int(e[3])
+		keys[i].ID = id
+		switch location {
+		case 0:
+			keys[i].Shorts = []uint16{uint16(value)}
+		case tGeoKeyDirectory:
+			if value+count > len(dir) {
+				return nil, FormatError("bad GeoKey offset")
+			}
+			keys[i].Shorts = dir[value : value+count]
+		case tGeoDoubleParams:
+			if value+count > len(g.GeoDoubleParams) {
+				return nil, FormatError("bad GeoKey offset")
+			}
+			keys[i].Doubles = g.GeoDoubleParams[value : value+count]
+		case tGeoASCIIParams:
+			if value+count > len(g.GeoASCIIParams) {
+				return nil, FormatError("bad GeoKey offset")
+			}
+			keys[i].ASCII = strings.TrimSuffix(g.GeoASCIIParams[value:value+count], "|")
+		default:
+			return nil, UnsupportedError("GeoKey location")
+		}
+	}
+	return keys, nil
+}
+
+// DecodeGeoTIFF reads the georeferencing tags of the TIFF image in r. It
+// returns a nil *GeoTIFF if the image has none of them.
+func DecodeGeoTIFF(r io.Reader) (*GeoTIFF, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.geo, nil
+}
+
+// parseGeoTIFF stows away the GeoTIFF IFD entry in p.
+func (d *decoder) parseGeoTIFF(p []byte) error {
+	if d.geo == nil {
+		d.geo = &GeoTIFF{}
+	}
+	tag := d.byteOrder.Uint16(p[0:2])
+	var err error
+	switch tag {
+	case tModelPixelScale:
+		d.geo.ModelPixelScale, err = d.ifdFloat(p)
+	case tModelTiepoint:
+		d.geo.ModelTiepoint, err = d.ifdFloat(p)
+	case tModelTransformation:
+		d.geo.ModelTransformation, err = d.ifdFloat(p)
+	case tGeoDoubleParams:
+		d.geo.GeoDoubleParams, err = d.ifdFloat(p)
+	case tGeoKeyDirectory:
+		var val []uint
+		val, err = d.ifdUint(p)
+		d.geo.GeoKeyDirectory = make([]uint16, len(val))
+		for i, v := range val {
+			d.geo.GeoKeyDirectory[i] = uint16(v)
+		}
+	case tGeoASCIIParams:
+		var raw []byte
+		raw, err = d.ifdRaw(p, dtASCII)
+		d.geo.GeoASCIIParams = strings.TrimRight(string(raw), "\x00")
+	}
+	return err
+}
+
+// ifdRaw returns the raw data of the IFD entry in p, which must be of the
+// given data type.
+func (d *decoder) ifdRaw(p []byte, datatype uint16) ([]byte, error) {
+	if len(p) < d.entryLen() {
+		return nil, FormatError("bad IFD entry")
+	}
+	if d.byteOrder.Uint16(p[2:4]) != datatype {
+		return nil, UnsupportedError("data type")
+	}
+	_, raw, err := d.entryData(p)
+	return raw, err
+}
+
+// ifdFloat decodes the IFD entry in p, which must be of the Double type, and
+// returns the decoded float64 values.
+func (d *decoder) ifdFloat(p []byte) ([]float64, error) {
+	raw, err := d.ifdRaw(p, dtDouble)
+	if err != nil {
+		return nil, err
+	}
+	f := make([]float64, len(raw)/8)
+	for i := range f {
+		f[i] = math.Float64frombits(d.byteOrder.Uint64(raw[8*i:]))
+	}
+	return f, nil
+}
+
+// ifdEntries returns the IFD entries for g's non-empty fields.
+func (g *GeoTIFF) ifdEntries() []ifdEntry {
+	var ifd []ifdEntry
+	doubles := func(tag int, f []float64) {
+		if len(f) == 0 {
+			return
+		}
+		data := make([]uint32, 0, 2*len(f))
+		for _, v := range f {
+			b := math.Float64bits(v)
+			data = append(data, uint32(b), uint32(b>>32))
+		}
+		ifd = append(ifd, ifdEntry{tag, dtDouble, data})
+	}
+	doubles(tModelPixelScale, g.ModelPixelScale)
+	doubles(tModelTiepoint, g.ModelTiepoint)
+	doubles(tModelTransformation, g.ModelTransformation)
+	doubles(tGeoDoubleParams, g.GeoDoubleParams)
+	if len(g.GeoKeyDirectory) != 0 {
+		data := make([]uint32, len(g.GeoKeyDirectory))
+		for i, v := range g.GeoKeyDirectory {
+			data[i] = uint32(v)
+		}
+		ifd = append(ifd, ifdEntry{tGeoKeyDirectory, dtShort, data})
+	}
+	if len(g.GeoASCIIParams) != 0 {
+		// ASCII values are NUL terminated.
+		data := make([]uint32, len(g.GeoASCIIParams)+1)
+		for i := 0; i < len(g.GeoASCIIParams); i++ {
+			data[i] = uint32(g.GeoASCIIParams[i])
+		}
+		ifd = append(ifd, ifdEntry{tGeoASCIIParams, dtASCII, data})
+	}
+	return ifd
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestGeoTIFFRoundtrip(t *testing.T) {
+	g := &GeoTIFF{
+		ModelPixelScale: []float64{0.5, 0.25, 0},
+		ModelTiepoint:   []float64{0, 0, 0, 440720, 3751320, 0},
+		GeoKeyDirectory: []uint16{
+			1, 1, 0, 4,
+			1024, 0, 1, 1, // GTModelTypeGeoKey: projected.
+			1026, tGeoASCIIParams, 8, 0, // GTCitationGeoKey.
+			2057, tGeoDoubleParams, 1, 0, // GeogSemiMajorAxisGeoKey.
+			3072, 0, 1, 26711, // ProjectedCSTypeGeoKey.
+		},
+		GeoDoubleParams: []float64{6378206.4},
+		GeoASCIIParams:  "UTM 11N|",
+	}
+	m := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(40 * i)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &Options{GeoTIFF: g}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	got, err := DecodeGeoTIFF(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeGeoTIFF: %v", err)
+	}
+	if !reflect.DeepEqual(got, g) {
+		t.Fatalf("DecodeGeoTIFF:\ngot  %+v\nwant %+v", got, g)
+	}
+	m1, err := Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	compare(t, m, m1)
+
+	keys, err := got.Keys()
+	if err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	wantKeys := []GeoKey{
+		{ID: 1024, Shorts: []uint16{1}},
+		{ID: 1026, ASCII: "UTM 11N"},
+		{ID: 2057, Doubles: []float64{6378206.4}},
+		{ID: 3072, Shorts: []uint16{26711}},
+	}
+	if !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("Keys:\ngot  %+v\nwant %+v", keys, wantKeys)
+	}
+
+	// An image without GeoTIFF tags has a nil *GeoTIFF.
+	buf.Reset()
+	if err := Encode(&buf, m, nil); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if got, err := DecodeGeoTIFF(&buf); got != nil || err != nil {
+		t.Errorf("DecodeGeoTIFF without tags: got %v, %v, want nil, nil", got, err)
+	}
+}
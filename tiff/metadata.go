@@ -0,0 +1,344 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+
+	"golang.org/x/image/icc"
+)
+
+// ResolutionUnit is the unit of a Metadata's XResolution and YResolution.
+type ResolutionUnit int
+
+// The values of the ResolutionUnit tag (page 18 of the spec). The zero value
+// means that the tag is absent.
+const (
+	ResolutionNone       ResolutionUnit = resNone
+	ResolutionInch       ResolutionUnit = resPerInch
+	ResolutionCentimeter ResolutionUnit = resPerCM
+)
+
+// Metadata holds the descriptive tags of a TIFF image that do not affect the
+// decoding of its pixels: its resolution, orientation, ICC color profile and
+// EXIF data. The values are passed through unchanged: in particular, the
+// orientation is not applied to the decoded image.
+//
+// Zero fields correspond to absent tags.
+type Metadata struct {
+	// XResolution and YResolution are the number of pixels per
+	// ResolutionUnit in each direction.
+	XResolution, YResolution float64
+	// ResolutionUnit is the unit of XResolution and YResolution. When
+	// encoding, zero means ResolutionInch, the default of the spec.
+	ResolutionUnit ResolutionUnit
+	// Orientation, from 1 to 8, is the EXIF orientation of the image: how
+	// its rows and columns map to the visual top and left. 1 means upright.
+	Orientation int
+	// ICCProfile is the embedded ICC color profile, such as one parsed by
+	// the golang.org/x/image/icc package.
+	ICCProfile []byte
+	// EXIF holds the fields of the EXIF sub-IFD, in tag order. Nested IFDs,
+	// such as the Interoperability IFD, are not included.
+	EXIF []EXIFField
+}
+
+// An EXIFField is an entry of an EXIF IFD.
+type EXIFField struct {
+	Tag uint16
+	// Type is the TIFF data type of the field's values, such as 2 for ASCII,
+	// 5 for Rational or 7 for Undefined.
+	Type uint16
+	// Value holds the field's values, in little-endian byte order whatever
+	// the byte order of the file.
+	Value []byte
+}
+
+// DPI returns m's resolution in dots per inch. It returns zeroes if the
+// resolution is absent or has no unit.
+func (m *Metadata) DPI() (x, y float64) {
+	switch m.ResolutionUnit {
+	case ResolutionNone:
+		return 0, 0
+	case ResolutionCentimeter:
+		return m.XResolution * 2.54, m.YResolution * 2.54
+	}
+	return m.XResolution, m.YResolution
+}
+
+// DecodeMetadata reads the metadata tags of the TIFF image in r. It returns
+// a nil *Metadata if the image has none of them.
+func DecodeMetadata(r io.Reader) (*Metadata, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.metadata()
+}
+
+// DecodeWithMetadata is like Decode but also returns the image's metadata
+// tags, as DecodeMetadata does, reading r once.
+func DecodeWithMetadata(r io.Reader) (image.Image, *Metadata, error) {
+	d, err := newDecoder(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, err := d.metadata()
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := d.decodeImage(1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, meta, nil
+}
+
+// convertToSRGB converts m from the color space described by the image's
+// ICC profile to sRGB, returning an *image.NRGBA. It returns m unchanged if
+// there is no profile.
+func (d *decoder) convertToSRGB(m image.Image) (image.Image, error) {
+	meta, err := d.metadata()
+	if err != nil || meta == nil || meta.ICCProfile == nil {
+		return m, err
+	}
+	p, err := icc.Parse(meta.ICCProfile)
+	if err != nil {
+		return nil, err
+	}
+	t, err := p.TransformToSRGB()
+	if err != nil {
+		return nil, err
+	}
+	b := m.Bounds()
+	dst := image.NewNRGBA(b)
+	draw.Draw(dst, b, m, b.Min, draw.Src)
+	t.ConvertNRGBA(dst)
+	return dst, nil
+}
+
+// metadata parses the metadata IFD entries stowed away by parseIFD. They are
+// only parsed on demand, as the ICC profile and EXIF data are not needed to
+// decode the image.
+func (d *decoder) metadata() (*Metadata, error) {
+	if len(d.meta) == 0 {
+		return nil, nil
+	}
+	m := &Metadata{}
+	for _, p := range d.meta {
+		var err error
+		switch d.byteOrder.Uint16(p[0:2]) {
+		case tXResolution:
+			m.XResolution, err = d.ifdRational(p)
+		case tYResolution:
+			m.YResolution, err = d.ifdRational(p)
+		case tResolutionUnit:
+			m.ResolutionUnit = ResolutionUnit(d.firstUint(p, &err))
+		case tOrientation:
+			m.Orientation = int(d.firstUint(p, &err))
+		case tICCProfile:
+			_, m.ICCProfile, err = d.entryData(p)
+		case tExifIFD:
+			if off := d.firstUint(p, &err); err == nil {
+				m.EXIF, err = d.readEXIF(int64(off))
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// firstUint returns the first value of the IFD entry in p, as ifdUint
+// decodes it. It sets *err if the entry is bad or empty.
+func (d *decoder) firstUint(p []byte, err *error) uint {
+	val, e := d.ifdUint(p)
+	if e == nil && len(val) == 0 {
+		e = FormatError("empty IFD entry")
+	}
+	if e != nil {
+		*err = e
+		return 0
+	}
+	return val[0]
+}
+
+// ifdRational decodes the IFD entry in p, which must hold a single Rational,
+// as a float64. A zero denominator gives zero, as for an absent tag.
+func (d *decoder) ifdRational(p []byte) (float64, error) {
+	raw, err := d.ifdRaw(p, dtRational)
+	if err != nil {
+		return 0, err
+	}
+	if len(raw) < 8 {
+		return 0, FormatError("empty IFD entry")
+	}
+	num, den := d.byteOrder.Uint32(raw[0:4]), d.byteOrder.Uint32(raw[4:8])
+	if den == 0 {
+		return 0, nil
+	}
+	return float64(num) / float64(den), nil
+}
+
+// readEXIF reads the entries of the EXIF IFD at the given offset. Entries
+// holding the offsets of nested IFDs are skipped, as those offsets would not
+// survive re-encoding.
+func (d *decoder) readEXIF(offset int64) ([]EXIFField, error) {
+	n, countLen, err := d.readEntryCount(offset)
+	if err != nil {
+		return nil, err
+	}
+	entryLen := d.entryLen()
+	p := make([]byte, entryLen*n)
+	if _, err := d.r.ReadAt(p, offset+int64(countLen)); err != nil {
+		return nil, err
+	}
+	var fields []EXIFField
+	for i := 0; i < len(p); i += entryLen {
+		tag := d.byteOrder.Uint16(p[i : i+2])
+		datatype := d.byteOrder.Uint16(p[i+2 : i+4])
+		if tag == tInteropIFD || datatype == 13 || datatype == dtIFD8 {
+			continue
+		}
+		_, raw, err := d.entryData(p[i : i+entryLen])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, EXIFField{
+			Tag:   tag,
+			Type:  datatype,
+			Value: d.littleEndian(datatype, raw),
+		})
+	}
+	return fields, nil
+}
+
+// littleEndian returns the values raw of the given data type, in d's byte
+// order, in little-endian byte order.
+func (d *decoder) littleEndian(datatype uint16, raw []byte) []byte {
+	n := int(lengths[datatype])
+	if datatype == dtRational || datatype == dtSRational {
+		// A rational is a pair of 32 bit values.
+		n = 4
+	}
+	if n == 1 || d.byteOrder == binary.LittleEndian {
+		return raw
+	}
+	le := make([]byte, len(raw))
+	for i := 0; i+n <= len(raw); i += n {
+		for j := 0; j < n; j++ {
+			le[i+j] = raw[i+n-1-j]
+		}
+	}
+	return le
+}
+
+// ifdEntries returns the IFD entries for m's resolution, orientation and ICC
+// profile. The EXIF IFD is written separately, by writeSubIFD.
+func (m *Metadata) ifdEntries() []ifdEntry {
+	var ifd []ifdEntry
+	if m.hasResolution() {
+		unit := m.ResolutionUnit
+		if unit == 0 {
+			unit = ResolutionInch
+		}
+		ifd = append(ifd,
+			ifdEntry{tXResolution, dtRational, rational(m.XResolution)},
+			ifdEntry{tYResolution, dtRational, rational(m.YResolution)},
+			ifdEntry{tResolutionUnit, dtShort, []uint32{uint32(unit)}},
+		)
+	}
+	if m.Orientation >= 1 && m.Orientation <= 8 {
+		ifd = append(ifd, ifdEntry{tOrientation, dtShort, []uint32{uint32(m.Orientation)}})
+	}
+	if len(m.ICCProfile) != 0 {
+		data := make([]uint32, len(m.ICCProfile))
+		for i, b := range m.ICCProfile {
+			data[i] = uint32(b)
+		}
+		ifd = append(ifd, ifdEntry{tICCProfile, dtUndefined, data})
+	}
+	return ifd
+}
+
+// hasResolution returns whether m holds a resolution to encode.
+func (m *Metadata) hasResolution() bool {
+	return m != nil && m.XResolution > 0 && m.YResolution > 0
+}
+
+// rational returns the numerator and denominator of a Rational close to v,
+// with a denominator that is a power of 10.
+func rational(v float64) []uint32 {
+	den := 1.0
+	for v*den != math.Trunc(v*den) && den < 1e6 && v*den*10 < math.MaxUint32 {
+		den *= 10
+	}
+	return []uint32{uint32(math.Min(math.Round(v*den), math.MaxUint32)), uint32(den)}
+}
+
+// exifIFD returns the EXIF IFD of m, or nil if m has no EXIF fields.
+func (m *Metadata) exifIFD() ([]ifdEntry, error) {
+	if m == nil || len(m.EXIF) == 0 {
+		return nil, nil
+	}
+	ifd := make([]ifdEntry, len(m.EXIF))
+	for i, f := range m.EXIF {
+		dt := int(f.Type)
+		if dt <= 0 || dt >= len(lengths) || lengths[dt] == 0 || dt == 13 || dt == dtIFD8 {
+			return nil, errors.New("tiff: bad EXIF field type")
+		}
+		if len(f.Value)%int(lengths[dt]) != 0 {
+			return nil, errors.New("tiff: bad EXIF field length")
+		}
+		e := ifdEntry{tag: int(f.Tag), datatype: dt}
+		n := e.valueLen()
+		e.data = make([]uint32, len(f.Value)/n)
+		for j := range e.data {
+			v := f.Value[n*j : n*(j+1)]
+			switch n {
+			case 1:
+				e.data[j] = uint32(v[0])
+			case 2:
+				e.data[j] = uint32(enc.Uint16(v))
+			case 4:
+				e.data[j] = enc.Uint32(v)
+			}
+		}
+		ifd[i] = e
+	}
+	return ifd, nil
+}
+
+// subIFDSize returns the length in bytes of the sub-IFD d, as written by
+// writeSubIFD, or zero if d is empty.
+func subIFDSize(d []ifdEntry, bigTIFF bool) int {
+	if len(d) == 0 {
+		return 0
+	}
+	return (ifdSize(d, bigTIFF) + 1) &^ 1
+}
+
+// writeSubIFD writes the sub-IFD d, if non-empty, at offset off in the file,
+// followed by a padding byte if needed for the next IFD to begin on a word
+// boundary.
+func writeSubIFD(w io.Writer, off int, d []ifdEntry, bigTIFF bool) error {
+	if len(d) == 0 {
+		return nil
+	}
+	if err := writeIFD(w, off, d, 0, bigTIFF); err != nil {
+		return err
+	}
+	if ifdSize(d, bigTIFF)&1 != 0 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/icc"
+)
+
+var testMetadata = &Metadata{
+	XResolution:    118.5,
+	YResolution:    120,
+	ResolutionUnit: ResolutionCentimeter,
+	Orientation:    6,
+	ICCProfile:     []byte("not really an ICC profile"),
+	EXIF: []EXIFField{
+		{Tag: 0x829a, Type: dtRational, Value: []byte{1, 0, 0, 0, 60, 0, 0, 0}}, // ExposureTime.
+		{Tag: 0x8827, Type: dtShort, Value: []byte{100, 0}},                     // ISOSpeedRatings.
+		{Tag: 0x9000, Type: dtUndefined, Value: []byte("0230")},                 // ExifVersion.
+		{Tag: 0x9003, Type: dtASCII, Value: []byte("2020:01:02 03:04:05\x00")},  // DateTimeOriginal.
+	},
+}
+
+func TestMetadataRoundtrip(t *testing.T) {
+	m := image.NewRGBA(image.Rect(0, 0, 5, 3))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(7 * i)
+	}
+	for _, opt := range []Options{
+		{},
+		{Compression: Deflate},
+		{TileWidth: 16, TileHeight: 16},
+		{BigTIFF: true},
+	} {
+		opt.Metadata = testMetadata
+		var buf bytes.Buffer
+		if err := Encode(&buf, m, &opt); err != nil {
+			t.Fatalf("%+v: Encode: %v", opt, err)
+		}
+		if _, err := Validate(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Errorf("%+v: Validate: %v", opt, err)
+		}
+		got, meta, err := DecodeWithMetadata(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("%+v: DecodeWithMetadata: %v", opt, err)
+		}
+		compare(t, m, got)
+		if !reflect.DeepEqual(meta, testMetadata) {
+			t.Errorf("%+v: got metadata\n%+v\nwant\n%+v", opt, meta, testMetadata)
+		}
+	}
+}
+
+func TestMetadataEncodeAll(t *testing.T) {
+	ms := []image.Image{
+		image.NewGray(image.Rect(0, 0, 3, 3)),
+		image.NewGray(image.Rect(0, 0, 1, 1)),
+	}
+	var buf bytes.Buffer
+	if err := EncodeAll(&buf, ms, &Options{Metadata: testMetadata}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Validate(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	pages, err := DecodeAll(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != len(ms) {
+		t.Fatalf("got %d pages, want %d", len(pages), len(ms))
+	}
+	meta, err := DecodeMetadata(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(meta, testMetadata) {
+		t.Errorf("got metadata\n%+v\nwant\n%+v", meta, testMetadata)
+	}
+}
+
+func TestMetadataRowWriter(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 3, 3))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(20 * i)
+	}
+	for _, opt := range []Options{{}, {Compression: LZW}} {
+		opt.Metadata = testMetadata
+		w := &seekBuffer{}
+		rw, err := NewRowWriter(w, 3, 3, m.ColorModel(), &opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.WriteRows(m); err != nil {
+			t.Fatal(err)
+		}
+		if err := rw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, meta, err := DecodeWithMetadata(bytes.NewReader(w.buf))
+		if err != nil {
+			t.Fatalf("%+v: %v", opt, err)
+		}
+		compare(t, m, got)
+		if !reflect.DeepEqual(meta, testMetadata) {
+			t.Errorf("%+v: got metadata\n%+v\nwant\n%+v", opt, meta, testMetadata)
+		}
+	}
+}
+
+func TestMetadataDefault(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1)), nil); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := DecodeMetadata(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{XResolution: 72, YResolution: 72, ResolutionUnit: ResolutionInch}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("got %+v, want %+v", meta, want)
+	}
+	if x, y := meta.DPI(); x != 72 || y != 72 {
+		t.Errorf("got %v x %v dpi, want 72 x 72", x, y)
+	}
+	if x, y := testMetadata.DPI(); x != 118.5*2.54 || y != 120*2.54 {
+		t.Errorf("got %v x %v dpi, want %v x %v", x, y, 118.5*2.54, 120*2.54)
+	}
+}
+
+func TestEncodeBadEXIF(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 1, 1))
+	for _, f := range []EXIFField{
+		{Tag: 1, Type: 0},
+		{Tag: 1, Type: 14},
+		{Tag: 1, Type: dtShort, Value: []byte{1}},
+	} {
+		opt := &Options{Metadata: &Metadata{EXIF: []EXIFField{f}}}
+		if err := Encode(new(bytes.Buffer), m, opt); err == nil {
+			t.Errorf("%+v: got nil error", f)
+		}
+	}
+}
+
+// TestDecodeBigEndianMetadata tests decoding the metadata of a hand-made
+// big-endian file, whose EXIF values are returned in little-endian order.
+func TestDecodeBigEndianMetadata(t *testing.T) {
+	be := binary.BigEndian
+	type entry struct {
+		tag, datatype uint16
+		count         uint32
+		value         []byte
+	}
+	// ifd appends the IFD of the given entries to b, with the values of
+	// more than four bytes after it.
+	ifd := func(b []byte, entries []entry) []byte {
+		start := len(b)
+		b = append(b, 0, byte(len(entries)))
+		values := start + 2 + ifdLen*len(entries) + 4
+		var extra []byte
+		for _, e := range entries {
+			p := make([]byte, ifdLen)
+			be.PutUint16(p[0:2], e.tag)
+			be.PutUint16(p[2:4], e.datatype)
+			be.PutUint32(p[4:8], e.count)
+			if len(e.value) <= 4 {
+				copy(p[8:], e.value)
+			} else {
+				be.PutUint32(p[8:], uint32(values+len(extra)))
+				extra = append(extra, e.value...)
+			}
+			b = append(b, p...)
+		}
+		b = append(b, 0, 0, 0, 0)
+		return append(b, extra...)
+	}
+
+	b := []byte("MM\x00\x2a\x00\x00\x00\x0a")
+	// The pixel data.
+	b = append(b, 0x80, 0)
+	exifOffset := 8 + 2 + 2 + 6*ifdLen + 4 + 8
+	b = ifd(b, []entry{
+		{tImageWidth, dtShort, 1, []byte{0, 1}},
+		{tImageLength, dtShort, 1, []byte{0, 1}},
+		{tStripOffsets, dtLong, 1, []byte{0, 0, 0, 8}},
+		{tStripByteCounts, dtLong, 1, []byte{0, 0, 0, 1}},
+		{tXResolution, dtRational, 1, []byte{0, 0, 1, 0x2c, 0, 0, 0, 1}},
+		{tExifIFD, dtLong, 1, []byte{0, 0, 0, byte(exifOffset)}},
+	})
+	if len(b) != exifOffset {
+		t.Fatalf("EXIF IFD at %d, want %d", len(b), exifOffset)
+	}
+	b = ifd(b, []entry{
+		{0x829a, dtRational, 1, []byte{0, 0, 0, 1, 0, 0, 0, 60}},
+		{0x8827, dtShort, 2, []byte{0, 100, 1, 0}},
+		{tInteropIFD, dtLong, 1, []byte{0, 0, 0, 0}},
+	})
+
+	meta, err := DecodeMetadata(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{
+		XResolution: 300,
+		EXIF: []EXIFField{
+			{Tag: 0x829a, Type: dtRational, Value: []byte{1, 0, 0, 0, 60, 0, 0, 0}},
+			{Tag: 0x8827, Type: dtShort, Value: []byte{100, 0, 0, 1}},
+		},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("got %+v, want %+v", meta, want)
+	}
+}
+
+func TestDecodeConvertToSRGB(t *testing.T) {
+	profile, err := ioutil.ReadFile("../testdata/linear-rgb.icc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := icc.Parse(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xform, err := p.TransformToSRGB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := image.NewNRGBA(image.Rect(0, 0, 5, 3))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(7 * i)
+	}
+	want := image.NewNRGBA(m.Rect)
+	copy(want.Pix, m.Pix)
+	xform.ConvertNRGBA(want)
+
+	for _, tc := range []struct {
+		profile []byte
+		want    *image.NRGBA
+	}{
+		{nil, m},
+		{profile, want},
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m, &Options{Metadata: &Metadata{ICCProfile: tc.profile}}); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		got, err := DecodeWithOptions(bytes.NewReader(buf.Bytes()), &DecodeOptions{ConvertToSRGB: true})
+		if err != nil {
+			t.Fatalf("DecodeWithOptions: %v", err)
+		}
+		compare(t, tc.want, got)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &Options{Metadata: testMetadata}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if _, err := DecodeWithOptions(bytes.NewReader(buf.Bytes()), &DecodeOptions{ConvertToSRGB: true}); err == nil {
+		t.Errorf("invalid profile: got nil error")
+	}
+}
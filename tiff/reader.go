@@ -5,9 +5,13 @@
 // Package tiff implements a TIFF image decoder and encoder.
 //
 // The TIFF specification is at http://partners.adobe.com/public/developer/en/tiff/TIFF6.pdf
+//
+// BigTIFF files, which use 64 bit offsets for images of more than 4 GB, are
+// also decoded, and are encoded when Options.BigTIFF is set.
 package tiff // import "golang.org/x/image/tiff"
 
 import (
+	"bytes"
 	"compress/zlib"
 	"encoding/binary"
 	"fmt"
@@ -16,8 +20,12 @@ import (
 	"io"
 	"io/ioutil"
 	"math"
+	"sync"
 
+	"golang.org/x/image/bitmap"
 	"golang.org/x/image/ccitt"
+	"golang.org/x/image/floatimage"
+	"golang.org/x/image/imagelimit"
 	"golang.org/x/image/tiff/lzw"
 )
 
@@ -41,11 +49,27 @@ var errNoPixels = FormatError("not enough pixel data")
 type decoder struct {
 	r         io.ReaderAt
 	byteOrder binary.ByteOrder
+	bigTIFF   bool
 	config    image.Config
 	mode      imageMode
 	bpp       uint
-	features  map[int][]uint
-	palette   []color.Color
+	// float is whether the samples are IEEE floating point numbers of bpp
+	// bits.
+	float    bool
+	features map[int][]uint
+	palette  []color.Color
+	geo      *GeoTIFF
+	// meta holds the IFD entries of the metadata tags, parsed on demand by
+	// the metadata method.
+	meta [][]byte
+	// nextIFD is the position of the offset of the next IFD, after this
+	// decoder's IFD.
+	nextIFD int64
+	// bilevel is whether to decode images with 1 BitsPerSample to
+	// *bitmap.Image.
+	bilevel bool
+	// limits are the limits on the image to decode, or nil.
+	limits *imagelimit.Limits
 
 	buf   []byte
 	off   int    // Current offset in buf.
@@ -63,48 +87,92 @@ func (d *decoder) firstVal(tag int) uint {
 	return f[0]
 }
 
-// ifdUint decodes the IFD entry in p, which must be of the Byte, Short
-// or Long type, and returns the decoded uint values.
-func (d *decoder) ifdUint(p []byte) (u []uint, err error) {
-	var raw []byte
-	if len(p) < ifdLen {
-		return nil, FormatError("bad IFD entry")
+// entryLen returns the length in bytes of an IFD entry: 12 for TIFF and 20
+// for BigTIFF.
+func (d *decoder) entryLen() int {
+	if d.bigTIFF {
+		return bigIFDLen
 	}
+	return ifdLen
+}
 
-	datatype := d.byteOrder.Uint16(p[2:4])
-	if dt := int(datatype); dt <= 0 || dt >= len(lengths) {
-		return nil, UnsupportedError("IFD entry datatype")
+// offsetLen returns the length in bytes of an offset, and of the value field
+// of an IFD entry: 4 for TIFF and 8 for BigTIFF.
+func (d *decoder) offsetLen() int {
+	if d.bigTIFF {
+		return 8
 	}
+	return 4
+}
 
-	count := d.byteOrder.Uint32(p[4:8])
-	if count > math.MaxInt32/lengths[datatype] {
-		return nil, FormatError("IFD data too large")
+// offset decodes the offset at the start of p.
+func (d *decoder) offset(p []byte) int64 {
+	if d.bigTIFF {
+		return int64(d.byteOrder.Uint64(p))
 	}
-	if datalen := lengths[datatype] * count; datalen > 4 {
-		// The IFD contains a pointer to the real value.
-		raw = make([]byte, datalen)
-		_, err = d.r.ReadAt(raw, int64(d.byteOrder.Uint32(p[8:12])))
-	} else {
-		raw = p[8 : 8+datalen]
+	return int64(d.byteOrder.Uint32(p))
+}
+
+// entry returns the data type and count of the IFD entry in p, and its value
+// field, which holds the entry's data if it fits and otherwise its offset.
+func (d *decoder) entry(p []byte) (datatype uint16, count uint64, field []byte) {
+	datatype = d.byteOrder.Uint16(p[2:4])
+	if d.bigTIFF {
+		return datatype, d.byteOrder.Uint64(p[4:12]), p[12:20]
+	}
+	return datatype, uint64(d.byteOrder.Uint32(p[4:8])), p[8:12]
+}
+
+// entryData returns the data type and the raw data of the IFD entry in p.
+func (d *decoder) entryData(p []byte) (uint16, []byte, error) {
+	if len(p) < d.entryLen() {
+		return 0, nil, FormatError("bad IFD entry")
+	}
+	datatype, count, field := d.entry(p)
+	if dt := int(datatype); dt <= 0 || dt >= len(lengths) || lengths[dt] == 0 {
+		return 0, nil, UnsupportedError("IFD entry datatype")
+	}
+	if count > uint64(math.MaxInt32/lengths[datatype]) {
+		return 0, nil, FormatError("IFD data too large")
+	}
+	datalen := int(lengths[datatype]) * int(count)
+	if datalen <= len(field) {
+		return datatype, field[:datalen], nil
+	}
+	// The IFD contains a pointer to the real value.
+	raw := make([]byte, datalen)
+	if _, err := d.r.ReadAt(raw, d.offset(field)); err != nil {
+		return 0, nil, err
 	}
+	return datatype, raw, nil
+}
+
+// ifdUint decodes the IFD entry in p, which must be of the Byte, Short,
+// Long, Long8 or IFD8 type, and returns the decoded uint values.
+func (d *decoder) ifdUint(p []byte) (u []uint, err error) {
+	datatype, raw, err := d.entryData(p)
 	if err != nil {
 		return nil, err
 	}
 
-	u = make([]uint, count)
+	u = make([]uint, len(raw)/int(lengths[datatype]))
 	switch datatype {
 	case dtByte:
-		for i := uint32(0); i < count; i++ {
+		for i := range u {
 			u[i] = uint(raw[i])
 		}
 	case dtShort:
-		for i := uint32(0); i < count; i++ {
+		for i := range u {
 			u[i] = uint(d.byteOrder.Uint16(raw[2*i : 2*(i+1)]))
 		}
 	case dtLong:
-		for i := uint32(0); i < count; i++ {
+		for i := range u {
 			u[i] = uint(d.byteOrder.Uint32(raw[4*i : 4*(i+1)]))
 		}
+	case dtLong8, dtIFD8:
+		for i := range u {
+			u[i] = uint(d.byteOrder.Uint64(raw[8*i : 8*(i+1)]))
+		}
 	default:
 		return nil, UnsupportedError("data type")
 	}
@@ -121,6 +189,7 @@ func (d *decoder) parseIFD(p []byte) (int, error) {
 		tExtraSamples,
 		tPhotometricInterpretation,
 		tCompression,
+		tPlanarConfiguration,
 		tPredictor,
 		tStripOffsets,
 		tStripByteCounts,
@@ -157,20 +226,38 @@ func (d *decoder) parseIFD(p []byte) (int, error) {
 				0xffff,
 			}
 		}
+	case tModelPixelScale,
+		tModelTiepoint,
+		tModelTransformation,
+		tGeoKeyDirectory,
+		tGeoDoubleParams,
+		tGeoASCIIParams:
+		if err := d.parseGeoTIFF(p); err != nil {
+			return 0, err
+		}
+	case tXResolution,
+		tYResolution,
+		tResolutionUnit,
+		tOrientation,
+		tICCProfile,
+		tExifIFD:
+		d.meta = append(d.meta, p)
 	case tSampleFormat:
 		// Page 27 of the spec: If the SampleFormat is present and
 		// the value is not 1 [= unsigned integer data], a Baseline
 		// TIFF reader that cannot handle the SampleFormat value
-		// must terminate the import process gracefully.
+		// must terminate the import process gracefully. This reader
+		// also handles IEEE floating point data, for every sample.
 		val, err := d.ifdUint(p)
 		if err != nil {
 			return 0, err
 		}
 		for _, v := range val {
-			if v != 1 {
+			if v != val[0] || (v != sfUint && v != sfFloat) {
 				return 0, UnsupportedError("sample format")
 			}
 		}
+		d.features[int(tag)] = val
 	}
 	return int(tag), nil
 }
@@ -192,6 +279,16 @@ func (d *decoder) readBits(n uint) (v uint32, ok bool) {
 	return rv, true
 }
 
+// skipBits skips the padding of n pixels at the end of a line of a tile that
+// extends past the right edge of the image.
+func (d *decoder) skipBits(n int) {
+	for ; n > 0; n-- {
+		if _, ok := d.readBits(d.bpp); !ok {
+			return
+		}
+	}
+}
+
 // flushBits discards the unread bits in the buffer used by readBits.
 // It is used at the end of a line.
 func (d *decoder) flushBits() {
@@ -207,19 +304,100 @@ func minInt(a, b int) int {
 	return b
 }
 
-// decode decodes the raw data of an image.
-// It reads from d.buf and writes the strip or tile into dst.
-func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
-	d.off = 0
+// undoFloatingPointPredictor reverses the floating point predictor, of Adobe
+// Photoshop TIFF Technical Note 3, of the width by height block in d.buf.
+// The bytes of each row's samples are split into planes, from the most
+// significant byte of every sample to the least, and then each byte is
+// differenced with the same byte of the previous pixel.
+func (d *decoder) undoFloatingPointPredictor(width, height int) error {
+	if d.bpp != 32 {
+		return UnsupportedError("floating point predictor with BitsPerSample other than 32")
+	}
+	spp := len(d.features[tBitsPerSample])
+	n := width * spp // The number of samples per row.
+	rowLen := 4 * n
+	if len(d.buf) < height*rowLen {
+		return errNoPixels
+	}
+	tmp := make([]byte, rowLen)
+	for y := 0; y < height; y++ {
+		row := d.buf[y*rowLen : (y+1)*rowLen]
+		for i := spp; i < rowLen; i++ {
+			row[i] += row[i-spp]
+		}
+		copy(tmp, row)
+		for i := 0; i < n; i++ {
+			v := uint32(tmp[i])<<24 | uint32(tmp[n+i])<<16 | uint32(tmp[2*n+i])<<8 | uint32(tmp[3*n+i])
+			d.byteOrder.PutUint32(row[4*i:], v)
+		}
+	}
+	return nil
+}
+
+// decodeFloat decodes the 32 bit floating point samples in d.buf of the block
+// from (xmin, ymin) to (xmax, ymax) into dst, up to (rMaxX, rMaxY).
+func (d *decoder) decodeFloat(dst image.Image, xmin, ymin, xmax, rMaxX, rMaxY int) error {
+	spp := len(d.features[tBitsPerSample])
+	sample := func(i int) float32 {
+		return math.Float32frombits(d.byteOrder.Uint32(d.buf[4*i:]))
+	}
+	for y := ymin; y < rMaxY; y++ {
+		// i is the index in d.buf of the sample at (x, y), in units of 4
+		// bytes.
+		i := (y - ymin) * (xmax - xmin) * spp
+		if 4*(i+(rMaxX-xmin)*spp) > len(d.buf) {
+			return errNoPixels
+		}
+		for x := xmin; x < rMaxX; x, i = x+1, i+spp {
+			switch d.mode {
+			case mGray:
+				img := dst.(*floatimage.GrayF32)
+				img.Pix[img.PixOffset(x, y)] = sample(i)
+			case mGrayInvert:
+				img := dst.(*floatimage.GrayF32)
+				img.Pix[img.PixOffset(x, y)] = 1 - sample(i)
+			case mRGB:
+				img := dst.(*floatimage.RGBAF32)
+				img.SetRGBAF32(x, y, floatimage.RGBAF32Color{R: sample(i), G: sample(i + 1), B: sample(i + 2), A: 1})
+			case mRGBA:
+				img := dst.(*floatimage.RGBAF32)
+				img.SetRGBAF32(x, y, floatimage.RGBAF32Color{R: sample(i), G: sample(i + 1), B: sample(i + 2), A: sample(i + 3)})
+			case mNRGBA:
+				img := dst.(*floatimage.RGBAF32)
+				a := sample(i + 3)
+				img.SetRGBAF32(x, y, floatimage.RGBAF32Color{R: sample(i) * a, G: sample(i+1) * a, B: sample(i+2) * a, A: a})
+			}
+		}
+	}
+	return nil
+}
 
-	// Apply horizontal predictor if necessary.
-	// In this case, p contains the color difference to the preceding pixel.
-	// See page 64-65 of the spec.
-	if d.firstVal(tPredictor) == prHorizontal {
+// undoPredictor applies the horizontal predictor, if any, to the raw data of
+// the block from (xmin, ymin) to (xmax, ymax) in d.buf, whose pixels have spp
+// samples. In this case, d.buf contains the color difference to the
+// preceding pixel. See page 64-65 of the spec.
+func (d *decoder) undoPredictor(spp, xmin, ymin, xmax, ymax int) error {
+	switch d.firstVal(tPredictor) {
+	case prHorizontal:
 		switch d.bpp {
+		case 32:
+			var off int
+			n := 4 * spp // bytes per sample times samples per pixel
+			for y := ymin; y < ymax; y++ {
+				off += n
+				for x := 0; x < (xmax-xmin-1)*n; x += 4 {
+					if off+4 > len(d.buf) {
+						return errNoPixels
+					}
+					v0 := d.byteOrder.Uint32(d.buf[off-n : off-n+4])
+					v1 := d.byteOrder.Uint32(d.buf[off : off+4])
+					d.byteOrder.PutUint32(d.buf[off:off+4], v1+v0)
+					off += 4
+				}
+			}
 		case 16:
 			var off int
-			n := 2 * len(d.features[tBitsPerSample]) // bytes per sample times samples per pixel
+			n := 2 * spp // bytes per sample times samples per pixel
 			for y := ymin; y < ymax; y++ {
 				off += n
 				for x := 0; x < (xmax-xmin-1)*n; x += 2 {
@@ -234,7 +412,7 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 			}
 		case 8:
 			var off int
-			n := 1 * len(d.features[tBitsPerSample]) // bytes per sample times samples per pixel
+			n := 1 * spp // bytes per sample times samples per pixel
 			for y := ymin; y < ymax; y++ {
 				off += n
 				for x := 0; x < (xmax-xmin-1)*n; x++ {
@@ -248,10 +426,31 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 		case 1:
 			return UnsupportedError("horizontal predictor with 1 BitsPerSample")
 		}
+	case prFloatingPoint:
+		if !d.float {
+			return UnsupportedError("floating point predictor with integer samples")
+		}
+		if err := d.undoFloatingPointPredictor(xmax-xmin, ymax-ymin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decode decodes the raw data of an image.
+// It reads from d.buf and writes the strip or tile into dst.
+func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
+	d.off = 0
+
+	if err := d.undoPredictor(len(d.features[tBitsPerSample]), xmin, ymin, xmax, ymax); err != nil {
+		return err
 	}
 
 	rMaxX := minInt(xmax, dst.Bounds().Max.X)
 	rMaxY := minInt(ymax, dst.Bounds().Max.Y)
+	if d.float {
+		return d.decodeFloat(dst, xmin, ymin, xmax, rMaxX, rMaxY)
+	}
 	switch d.mode {
 	case mGray, mGrayInvert:
 		if d.bpp == 16 {
@@ -272,6 +471,18 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 					d.off += 2 * (xmax - img.Bounds().Max.X)
 				}
 			}
+		} else if img, ok := dst.(*bitmap.Image); ok {
+			for y := ymin; y < rMaxY; y++ {
+				for x := xmin; x < rMaxX; x++ {
+					v, ok := d.readBits(1)
+					if !ok {
+						return errNoPixels
+					}
+					img.SetBit(x, y, (v != 0) != (d.mode == mGrayInvert))
+				}
+				d.skipBits(xmax - rMaxX)
+				d.flushBits()
+			}
 		} else {
 			img := dst.(*image.Gray)
 			max := uint32((1 << d.bpp) - 1)
@@ -287,6 +498,7 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 					}
 					img.SetGray(x, y, color.Gray{uint8(v)})
 				}
+				d.skipBits(xmax - rMaxX)
 				d.flushBits()
 			}
 		}
@@ -300,6 +512,7 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 				}
 				img.SetColorIndex(x, y, uint8(v))
 			}
+			d.skipBits(xmax - rMaxX)
 			d.flushBits()
 		}
 	case mRGB:
@@ -316,6 +529,9 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 					d.off += 6
 					img.SetRGBA64(x, y, color.RGBA64{r, g, b, 0xffff})
 				}
+				if rMaxX == img.Bounds().Max.X {
+					d.off += 6 * (xmax - img.Bounds().Max.X)
+				}
 			}
 		} else {
 			img := dst.(*image.RGBA)
@@ -350,6 +566,9 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 					d.off += 8
 					img.SetNRGBA64(x, y, color.NRGBA64{r, g, b, a})
 				}
+				if rMaxX == img.Bounds().Max.X {
+					d.off += 8 * (xmax - img.Bounds().Max.X)
+				}
 			}
 		} else {
 			img := dst.(*image.NRGBA)
@@ -378,6 +597,9 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 					d.off += 8
 					img.SetRGBA64(x, y, color.RGBA64{r, g, b, a})
 				}
+				if rMaxX == img.Bounds().Max.X {
+					d.off += 8 * (xmax - img.Bounds().Max.X)
+				}
 			}
 		} else {
 			img := dst.(*image.RGBA)
@@ -396,45 +618,121 @@ func (d *decoder) decode(dst image.Image, xmin, ymin, xmax, ymax int) error {
 	return nil
 }
 
+// newDecoder returns a decoder for the first image of r.
 func newDecoder(r io.Reader) (*decoder, error) {
-	d := &decoder{
-		r:        newReaderAt(r),
-		features: make(map[int][]uint),
+	ra := newReaderAt(r)
+	byteOrder, bigTIFF, ifdOffset, err := readHeader(ra)
+	if err != nil {
+		return nil, err
 	}
+	return newIFDDecoder(ra, byteOrder, bigTIFF, ifdOffset)
+}
 
+// readHeader returns the byte order of the TIFF file in r, whether it is a
+// BigTIFF file, and the offset of its first IFD.
+func readHeader(r io.ReaderAt) (byteOrder binary.ByteOrder, bigTIFF bool, ifdOffset int64, err error) {
 	p := make([]byte, 8)
-	if _, err := d.r.ReadAt(p, 0); err != nil {
+	if _, err := r.ReadAt(p, 0); err != nil {
 		if err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
-		return nil, err
+		return nil, false, 0, err
 	}
 	switch string(p[0:4]) {
 	case leHeader:
-		d.byteOrder = binary.LittleEndian
+		byteOrder = binary.LittleEndian
 	case beHeader:
-		d.byteOrder = binary.BigEndian
+		byteOrder = binary.BigEndian
+	case leBigHeader:
+		byteOrder, bigTIFF = binary.LittleEndian, true
+	case beBigHeader:
+		byteOrder, bigTIFF = binary.BigEndian, true
 	default:
-		return nil, FormatError("malformed header")
+		return nil, false, 0, FormatError("malformed header")
 	}
+	if !bigTIFF {
+		return byteOrder, false, int64(byteOrder.Uint32(p[4:8])), nil
+	}
+
+	// The BigTIFF header continues with the size of offsets, which is always
+	// 8, a reserved zero, and the 8 byte offset of the first IFD.
+	if byteOrder.Uint16(p[4:6]) != 8 || byteOrder.Uint16(p[6:8]) != 0 {
+		return nil, false, 0, FormatError("malformed BigTIFF header")
+	}
+	if _, err := r.ReadAt(p, 8); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, false, 0, err
+	}
+	return byteOrder, true, int64(byteOrder.Uint64(p)), nil
+}
+
+// maxIFDEntries is the maximum number of entries in an IFD, which bounds the
+// memory used to read a BigTIFF IFD.
+const maxIFDEntries = 1<<16 - 1
+
+// readEntryCount returns the number of entries of the IFD at ifdOffset, and
+// the length in bytes of that number.
+func (d *decoder) readEntryCount(ifdOffset int64) (n, countLen int, err error) {
+	// The count is 2 bytes long for TIFF and 8 for BigTIFF.
+	countLen = 2
+	if d.bigTIFF {
+		countLen = 8
+	}
+	p := make([]byte, countLen)
+	if _, err := d.r.ReadAt(p, ifdOffset); err != nil {
+		return 0, 0, err
+	}
+	if !d.bigTIFF {
+		return int(d.byteOrder.Uint16(p)), countLen, nil
+	}
+	c := d.byteOrder.Uint64(p)
+	if c > maxIFDEntries {
+		return 0, 0, UnsupportedError("number of IFD entries")
+	}
+	return int(c), countLen, nil
+}
+
+// newIFDDecoder returns a decoder for the image of the IFD at ifdOffset in r.
+func newIFDDecoder(r io.ReaderAt, byteOrder binary.ByteOrder, bigTIFF bool, ifdOffset int64) (*decoder, error) {
+	d, err := readIFD(r, byteOrder, bigTIFF, ifdOffset)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.setMode(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
 
-	ifdOffset := int64(d.byteOrder.Uint32(p[4:8]))
+// readIFD returns a decoder for the samples of the image of the IFD at
+// ifdOffset in r, without determining the image mode, which is set by
+// setMode.
+func readIFD(r io.ReaderAt, byteOrder binary.ByteOrder, bigTIFF bool, ifdOffset int64) (*decoder, error) {
+	d := &decoder{
+		r:         r,
+		byteOrder: byteOrder,
+		bigTIFF:   bigTIFF,
+		features:  make(map[int][]uint),
+	}
 
-	// The first two bytes contain the number of entries (12 bytes each).
-	if _, err := d.r.ReadAt(p[0:2], ifdOffset); err != nil {
+	numItems, countLen, err := d.readEntryCount(ifdOffset)
+	if err != nil {
 		return nil, err
 	}
-	numItems := int(d.byteOrder.Uint16(p[0:2]))
+	entryLen := d.entryLen()
+	d.nextIFD = ifdOffset + int64(countLen+entryLen*numItems)
 
 	// All IFD entries are read in one chunk.
-	p = make([]byte, ifdLen*numItems)
-	if _, err := d.r.ReadAt(p, ifdOffset+2); err != nil {
+	p := make([]byte, entryLen*numItems)
+	if _, err := d.r.ReadAt(p, ifdOffset+int64(countLen)); err != nil {
 		return nil, err
 	}
 
 	prevTag := -1
-	for i := 0; i < len(p); i += ifdLen {
-		tag, err := d.parseIFD(p[i : i+ifdLen])
+	for i := 0; i < len(p); i += entryLen {
+		tag, err := d.parseIFD(p[i : i+entryLen])
 		if err != nil {
 			return nil, err
 		}
@@ -452,28 +750,40 @@ func newDecoder(r io.Reader) (*decoder, error) {
 		d.features[tBitsPerSample] = []uint{1}
 	}
 	d.bpp = d.firstVal(tBitsPerSample)
-	switch d.bpp {
-	case 0:
+	d.float = d.firstVal(tSampleFormat) == sfFloat
+	switch {
+	case d.bpp == 0:
 		return nil, FormatError("BitsPerSample must not be 0")
-	case 1, 8, 16:
+	case d.float && d.bpp == 32:
+		// 32 bit floating point samples are decoded as a floatimage type.
+	case d.float:
+		return nil, UnsupportedError(fmt.Sprintf("floating point BitsPerSample of %v", d.bpp))
+	case d.bpp == 1, d.bpp == 8, d.bpp == 16:
 		// Nothing to do, these are accepted by this implementation.
 	default:
 		return nil, UnsupportedError(fmt.Sprintf("BitsPerSample of %v", d.bpp))
 	}
+	return d, nil
+}
+
+// setMode determines the image mode and color model of d's image.
+func (d *decoder) setMode() error {
+	if d.firstVal(tPlanarConfiguration) == pcPlanar && len(d.features[tBitsPerSample]) > 1 {
+		return UnsupportedError("planar configuration")
+	}
 
-	// Determine the image mode.
 	switch d.firstVal(tPhotometricInterpretation) {
 	case pRGB:
-		if d.bpp == 16 {
+		if d.bpp == 16 || d.bpp == 32 {
 			for _, b := range d.features[tBitsPerSample] {
-				if b != 16 {
-					return nil, FormatError("wrong number of samples for 16bit RGB")
+				if b != d.bpp {
+					return FormatError(fmt.Sprintf("wrong number of samples for %dbit RGB", d.bpp))
 				}
 			}
 		} else {
 			for _, b := range d.features[tBitsPerSample] {
 				if b != 8 {
-					return nil, FormatError("wrong number of samples for 8bit RGB")
+					return FormatError("wrong number of samples for 8bit RGB")
 				}
 			}
 		}
@@ -493,14 +803,14 @@ func newDecoder(r io.Reader) (*decoder, error) {
 			}
 		case 4:
 			switch d.firstVal(tExtraSamples) {
-			case 1:
+			case esAssociatedAlpha:
 				d.mode = mRGBA
 				if d.bpp == 16 {
 					d.config.ColorModel = color.RGBA64Model
 				} else {
 					d.config.ColorModel = color.RGBAModel
 				}
-			case 2:
+			case esUnassociatedAlpha:
 				d.mode = mNRGBA
 				if d.bpp == 16 {
 					d.config.ColorModel = color.NRGBA64Model
@@ -508,10 +818,10 @@ func newDecoder(r io.Reader) (*decoder, error) {
 					d.config.ColorModel = color.NRGBAModel
 				}
 			default:
-				return nil, FormatError("wrong number of samples for RGB")
+				return FormatError("wrong number of samples for RGB")
 			}
 		default:
-			return nil, FormatError("wrong number of samples for RGB")
+			return FormatError("wrong number of samples for RGB")
 		}
 	case pPaletted:
 		d.mode = mPaletted
@@ -531,10 +841,19 @@ func newDecoder(r io.Reader) (*decoder, error) {
 			d.config.ColorModel = color.GrayModel
 		}
 	default:
-		return nil, UnsupportedError("color model")
+		return UnsupportedError("color model")
 	}
-
-	return d, nil
+	if d.float {
+		switch d.mode {
+		case mGray, mGrayInvert:
+			d.config.ColorModel = floatimage.GrayF32Model
+		case mRGB, mRGBA, mNRGBA:
+			d.config.ColorModel = floatimage.RGBAF32Model
+		default:
+			return UnsupportedError("floating point color model")
+		}
+	}
+	return nil
 }
 
 // DecodeConfig returns the color model and dimensions of a TIFF image without
@@ -547,6 +866,14 @@ func DecodeConfig(r io.Reader) (image.Config, error) {
 	return d.config, nil
 }
 
+// DecodeConfigReaderAt is like DecodeConfig but reads the size bytes of a
+// TIFF image from r, such as an *os.File or a blob store supporting range
+// reads. Only the header and the first IFD are read, wherever they are in the
+// file, so the image data is never buffered in memory.
+func DecodeConfigReaderAt(r io.ReaderAt, size int64) (image.Config, error) {
+	return DecodeConfig(io.NewSectionReader(r, 0, size))
+}
+
 func ccittFillOrder(tiffFillOrder uint) ccitt.Order {
 	if tiffFillOrder == 2 {
 		return ccitt.LSB
@@ -557,11 +884,250 @@ func ccittFillOrder(tiffFillOrder uint) ccitt.Order {
 // Decode reads a TIFF image from r and returns it as an image.Image.
 // The type of Image returned depends on the contents of the TIFF.
 func Decode(r io.Reader) (img image.Image, err error) {
+	return decode(r, DecodeOptions{Concurrency: 1})
+}
+
+// DecodeOptions are optional parameters to DecodeWithOptions.
+type DecodeOptions struct {
+	// Concurrency is the maximum number of strips or tiles that are
+	// decompressed at once, by separate goroutines. Strips and tiles are
+	// compressed independently, so large compressed images decode up to
+	// Concurrency times faster on multicore machines, at the cost of holding
+	// up to Concurrency strips or tiles in memory at once. Zero or one means
+	// to decode one strip or tile at a time, like Decode.
+	Concurrency int
+	// Bilevel is whether to decode gray images with 1 BitsPerSample, such as
+	// CCITT compressed faxes, to a *bitmap.Image instead of an *image.Gray,
+	// which takes 8 times the memory.
+	Bilevel bool
+	// ConvertToSRGB is whether to convert the pixels of an image with an
+	// embedded ICC profile to sRGB, so that wide-gamut images display
+	// correctly in code that assumes sRGB. Such images are returned as an
+	// *image.NRGBA. Images without an ICC profile are unaffected.
+	//
+	// If the profile is not supported by the golang.org/x/image/icc package,
+	// DecodeWithOptions returns an error.
+	ConvertToSRGB bool
+	// Limits, if non-nil, are the limits on the image to decode, which are
+	// checked before its pixels are allocated. DecodeWithOptions returns an
+	// *imagelimit.Error if the image exceeds them, or if the compressed data
+	// of one of its strips or tiles is larger than their MaxMemory.
+	Limits *imagelimit.Limits
+}
+
+// DecodeWithOptions is like Decode but with optional parameters. A nil opts
+// is equivalent to a zero DecodeOptions.
+//
+// The io.ReaderAt methods of r, if it implements io.ReaderAt, are never
+// called concurrently.
+func DecodeWithOptions(r io.Reader, opts *DecodeOptions) (image.Image, error) {
+	return decode(r, decodeOptions(opts))
+}
+
+// decodeOptions returns *opts, or the defaults if opts is nil.
+func decodeOptions(opts *DecodeOptions) DecodeOptions {
+	var o DecodeOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency < 1 {
+		o.Concurrency = 1
+	}
+	return o
+}
+
+// DecodeReaderAt is like DecodeWithOptions but reads the size bytes of a TIFF
+// image from r, such as an *os.File or a blob store supporting range reads.
+// Only the IFD and the strips or tiles are read, as they are needed, instead
+// of buffering the file up to the furthest offset read.
+func DecodeReaderAt(r io.ReaderAt, size int64, opts *DecodeOptions) (image.Image, error) {
+	return DecodeWithOptions(io.NewSectionReader(r, 0, size), opts)
+}
+
+func decode(r io.Reader, o DecodeOptions) (image.Image, error) {
 	d, err := newDecoder(r)
 	if err != nil {
-		return
+		return nil, err
+	}
+	return d.decodeWithOptions(o)
+}
+
+// decodeWithOptions decodes the image of d's IFD with the options o.
+func (d *decoder) decodeWithOptions(o DecodeOptions) (image.Image, error) {
+	d.bilevel = o.Bilevel
+	d.limits = o.Limits
+	img, err := d.decodeImage(o.Concurrency)
+	if err != nil || !o.ConvertToSRGB {
+		return img, err
 	}
+	return d.convertToSRGB(img)
+}
 
+// decodeIFDs calls f with the decoder for each image of r, in order.
+func decodeIFDs(r io.Reader, f func(d *decoder) error) error {
+	ra := newReaderAt(r)
+	byteOrder, bigTIFF, offset, err := readHeader(ra)
+	if err != nil {
+		return err
+	}
+	seen := map[int64]bool{}
+	for offset != 0 {
+		if seen[offset] {
+			return FormatError("IFD cycle")
+		}
+		seen[offset] = true
+		d, err := newIFDDecoder(ra, byteOrder, bigTIFF, offset)
+		if err != nil {
+			return err
+		}
+		if err := f(d); err != nil {
+			return err
+		}
+		p := make([]byte, d.offsetLen())
+		if _, err := ra.ReadAt(p, d.nextIFD); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		offset = d.offset(p)
+	}
+	return nil
+}
+
+// DecodeAll reads all of the images, or pages, of a multi-page TIFF from r,
+// in the order of their IFDs. Reduced resolution images, such as thumbnails,
+// are returned as separate images. Each image is decoded with the options
+// opts, as for DecodeWithOptions, and a nil opts is equivalent to a zero
+// DecodeOptions.
+func DecodeAll(r io.Reader, opts *DecodeOptions) ([]image.Image, error) {
+	o := decodeOptions(opts)
+	var ms []image.Image
+	err := decodeIFDs(r, func(d *decoder) error {
+		m, err := d.decodeWithOptions(o)
+		if err != nil {
+			return err
+		}
+		ms = append(ms, m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// DecodeConfigAll returns the color model and dimensions of each of the
+// images of a multi-page TIFF, as returned by DecodeAll, without decoding
+// them. It returns an *imagelimit.Error if the dimensions of an image, decoded
+// with the options opts, exceed opts.Limits. A nil opts is equivalent to a
+// zero DecodeOptions.
+func DecodeConfigAll(r io.Reader, opts *DecodeOptions) ([]image.Config, error) {
+	o := decodeOptions(opts)
+	var cs []image.Config
+	err := decodeIFDs(r, func(d *decoder) error {
+		d.bilevel = o.Bilevel
+		if err := o.Limits.Check(d.config.Width, d.config.Height, d.pixelBits()); err != nil {
+			return err
+		}
+		cs = append(cs, d.config)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// decodeImage decodes the image of d's IFD, decompressing up to concurrency
+// strips or tiles at once.
+func (d *decoder) decodeImage(concurrency int) (img image.Image, err error) {
+	blocks, err := d.blocks(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.checkLimits(blocks, d.pixelBits()); err != nil {
+		return nil, err
+	}
+	imgRect := image.Rect(0, 0, d.config.Width, d.config.Height)
+	switch d.mode {
+	case mGray, mGrayInvert:
+		if d.float {
+			img = floatimage.NewGrayF32(imgRect)
+		} else if d.bpp == 16 {
+			img = image.NewGray16(imgRect)
+		} else if d.bpp == 1 && d.bilevel {
+			img = bitmap.New(imgRect)
+		} else {
+			img = image.NewGray(imgRect)
+		}
+	case mPaletted:
+		img = image.NewPaletted(imgRect, d.palette)
+	case mNRGBA:
+		if d.float {
+			img = floatimage.NewRGBAF32(imgRect)
+		} else if d.bpp == 16 {
+			img = image.NewNRGBA64(imgRect)
+		} else {
+			img = image.NewNRGBA(imgRect)
+		}
+	case mRGB, mRGBA:
+		if d.float {
+			img = floatimage.NewRGBAF32(imgRect)
+		} else if d.bpp == 16 {
+			img = image.NewRGBA64(imgRect)
+		} else {
+			img = image.NewRGBA(imgRect)
+		}
+	}
+
+	if concurrency > 1 && len(blocks) > 1 {
+		if err := d.decodeBlocksConcurrently(img, blocks, concurrency); err != nil {
+			return nil, err
+		}
+		return img, nil
+	}
+	for _, b := range blocks {
+		if err := d.decompress(d.r, b.offset, b.n, b.rect.Dx(), b.rect.Dy()); err != nil {
+			return nil, err
+		}
+		if err := d.decode(img, b.rect.Min.X, b.rect.Min.Y, b.rect.Max.X, b.rect.Max.Y); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+// pixelBits returns the number of bits per pixel of the image that
+// decodeImage returns.
+func (d *decoder) pixelBits() int {
+	switch d.mode {
+	case mGray, mGrayInvert:
+		if d.float {
+			return 32
+		} else if d.bpp == 16 {
+			return 16
+		} else if d.bpp == 1 && d.bilevel {
+			return 1
+		}
+		return 8
+	case mPaletted:
+		return 8
+	case mNRGBA, mRGB, mRGBA:
+		if d.float {
+			return 128
+		} else if d.bpp == 16 {
+			return 64
+		}
+		return 32
+	}
+	return 0
+}
+
+// blocks returns the strips or tiles of d's image, or for a planar image,
+// those of the given plane.
+func (d *decoder) blocks(plane int) ([]block, error) {
 	blockPadding := false
 	blockWidth := d.config.Width
 	blockHeight := d.config.Height
@@ -607,34 +1173,14 @@ func Decode(r io.Reader) (img image.Image, err error) {
 	}
 
 	// Check if we have the right number of strips/tiles, offsets and counts.
-	if n := blocksAcross * blocksDown; len(blockOffsets) < n || len(blockCounts) < n {
+	n := blocksAcross * blocksDown
+	if p := (plane + 1) * n; len(blockOffsets) < p || len(blockCounts) < p {
 		return nil, FormatError("inconsistent header")
 	}
+	blockOffsets = blockOffsets[plane*n:]
+	blockCounts = blockCounts[plane*n:]
 
-	imgRect := image.Rect(0, 0, d.config.Width, d.config.Height)
-	switch d.mode {
-	case mGray, mGrayInvert:
-		if d.bpp == 16 {
-			img = image.NewGray16(imgRect)
-		} else {
-			img = image.NewGray(imgRect)
-		}
-	case mPaletted:
-		img = image.NewPaletted(imgRect, d.palette)
-	case mNRGBA:
-		if d.bpp == 16 {
-			img = image.NewNRGBA64(imgRect)
-		} else {
-			img = image.NewNRGBA(imgRect)
-		}
-	case mRGB, mRGBA:
-		if d.bpp == 16 {
-			img = image.NewRGBA64(imgRect)
-		} else {
-			img = image.NewRGBA(imgRect)
-		}
-	}
-
+	var blocks []block
 	for i := 0; i < blocksAcross; i++ {
 		blkW := blockWidth
 		if !blockPadding && i == blocksAcross-1 && d.config.Width%blockWidth != 0 {
@@ -645,65 +1191,184 @@ func Decode(r io.Reader) (img image.Image, err error) {
 			if !blockPadding && j == blocksDown-1 && d.config.Height%blockHeight != 0 {
 				blkH = d.config.Height % blockHeight
 			}
-			offset := int64(blockOffsets[j*blocksAcross+i])
-			n := int64(blockCounts[j*blocksAcross+i])
-			switch d.firstVal(tCompression) {
-
-			// According to the spec, Compression does not have a default value,
-			// but some tools interpret a missing Compression value as none so we do
-			// the same.
-			case cNone, 0:
-				if b, ok := d.r.(*buffer); ok {
-					d.buf, err = b.Slice(int(offset), int(n))
-				} else {
-					d.buf = make([]byte, n)
-					_, err = d.r.ReadAt(d.buf, offset)
-				}
-			case cG3:
-				inv := d.firstVal(tPhotometricInterpretation) == pWhiteIsZero
-				order := ccittFillOrder(d.firstVal(tFillOrder))
-				r := ccitt.NewReader(io.NewSectionReader(d.r, offset, n), order, ccitt.Group3, blkW, blkH, &ccitt.Options{Invert: inv, Align: false})
-				d.buf, err = ioutil.ReadAll(r)
-			case cG4:
-				inv := d.firstVal(tPhotometricInterpretation) == pWhiteIsZero
-				order := ccittFillOrder(d.firstVal(tFillOrder))
-				r := ccitt.NewReader(io.NewSectionReader(d.r, offset, n), order, ccitt.Group4, blkW, blkH, &ccitt.Options{Invert: inv, Align: false})
-				d.buf, err = ioutil.ReadAll(r)
-			case cLZW:
-				r := lzw.NewReader(io.NewSectionReader(d.r, offset, n), lzw.MSB, 8)
-				d.buf, err = ioutil.ReadAll(r)
-				r.Close()
-			case cDeflate, cDeflateOld:
-				var r io.ReadCloser
-				r, err = zlib.NewReader(io.NewSectionReader(d.r, offset, n))
-				if err != nil {
-					return nil, err
-				}
-				d.buf, err = ioutil.ReadAll(r)
-				r.Close()
-			case cPackBits:
-				d.buf, err = unpackBits(io.NewSectionReader(d.r, offset, n))
-			default:
-				err = UnsupportedError(fmt.Sprintf("compression value %d", d.firstVal(tCompression)))
-			}
-			if err != nil {
-				return nil, err
-			}
-
 			xmin := i * blockWidth
 			ymin := j * blockHeight
-			xmax := xmin + blkW
-			ymax := ymin + blkH
-			err = d.decode(img, xmin, ymin, xmax, ymax)
-			if err != nil {
-				return nil, err
+			blocks = append(blocks, block{
+				offset: int64(blockOffsets[j*blocksAcross+i]),
+				n:      int64(blockCounts[j*blocksAcross+i]),
+				rect:   image.Rect(xmin, ymin, xmin+blkW, ymin+blkH),
+			})
+		}
+	}
+	return blocks, nil
+}
+
+// blockLen returns the length of the uncompressed data of a blkW by blkH strip
+// or tile, whose rows are padded to a whole number of bytes.
+func (d *decoder) blockLen(blkW, blkH int) int64 {
+	spp := len(d.features[tBitsPerSample])
+	if d.firstVal(tPlanarConfiguration) == pcPlanar {
+		spp = 1
+	}
+	rowBits := float64(blkW) * float64(spp) * float64(d.bpp)
+	if rowBits/8*float64(blkH) >= math.MaxInt64/2 {
+		return math.MaxInt64
+	}
+	return (int64(rowBits) + 7) / 8 * int64(blkH)
+}
+
+// checkLimits returns an *imagelimit.Error if d's image, which has
+// bitsPerPixel bits per pixel once decoded, exceeds d.limits, or if the
+// compressed data of one of its blocks, which is read into memory whole,
+// exceeds d.limits.MaxMemory.
+func (d *decoder) checkLimits(blocks []block, bitsPerPixel int) error {
+	if err := d.limits.Check(d.config.Width, d.config.Height, bitsPerPixel); err != nil {
+		return err
+	}
+	if d.limits == nil || d.limits.MaxMemory <= 0 {
+		return nil
+	}
+	for _, b := range blocks {
+		if b.n > d.limits.MaxMemory {
+			return &imagelimit.Error{Limit: "memory", Value: b.n, Max: d.limits.MaxMemory}
+		}
+	}
+	return nil
+}
+
+// block is a strip or tile of an image.
+type block struct {
+	// offset and n are the position and length of the compressed data.
+	offset, n int64
+	// rect is the block's position in the image. It may extend beyond the
+	// image bounds, for padded tiles.
+	rect image.Rectangle
+}
+
+// decompress reads the compressed strip or tile of n bytes at offset in r,
+// which is blkW by blkH pixels, and stores the uncompressed data in d.buf.
+func (d *decoder) decompress(ra io.ReaderAt, offset, n int64, blkW, blkH int) (err error) {
+	switch d.firstVal(tCompression) {
+
+	// According to the spec, Compression does not have a default value,
+	// but some tools interpret a missing Compression value as none so we do
+	// the same.
+	case cNone, 0:
+		// Read no more than the block's pixels, whatever byte count the
+		// file claims.
+		if m := d.blockLen(blkW, blkH); n > m {
+			n = m
+		}
+		if b, ok := ra.(*buffer); ok {
+			d.buf, err = b.Slice(int(offset), int(n))
+		} else {
+			d.buf, err = ioutil.ReadAll(io.NewSectionReader(ra, offset, n))
+			if err == nil && int64(len(d.buf)) < n {
+				err = io.ErrUnexpectedEOF
 			}
 		}
+	case cG3:
+		inv := d.firstVal(tPhotometricInterpretation) == pWhiteIsZero
+		order := ccittFillOrder(d.firstVal(tFillOrder))
+		// Bit 0 of T4Options means that rows may be coded in two
+		// dimensions.
+		opts := &ccitt.Options{Invert: inv, Align: false}
+		if d.firstVal(tT4Options)&1 != 0 {
+			opts.K = 1
+		}
+		r := ccitt.NewReader(io.NewSectionReader(ra, offset, n), order, ccitt.Group3, blkW, blkH, opts)
+		d.buf, err = ioutil.ReadAll(r)
+	case cG4:
+		inv := d.firstVal(tPhotometricInterpretation) == pWhiteIsZero
+		order := ccittFillOrder(d.firstVal(tFillOrder))
+		r := ccitt.NewReader(io.NewSectionReader(ra, offset, n), order, ccitt.Group4, blkW, blkH, &ccitt.Options{Invert: inv, Align: false})
+		d.buf, err = ioutil.ReadAll(r)
+	case cLZW:
+		r := lzw.NewReader(io.NewSectionReader(ra, offset, n), lzw.MSB, 8)
+		d.buf, err = ioutil.ReadAll(r)
+		r.Close()
+	case cDeflate, cDeflateOld:
+		var r io.ReadCloser
+		r, err = zlib.NewReader(io.NewSectionReader(ra, offset, n))
+		if err != nil {
+			return err
+		}
+		d.buf, err = ioutil.ReadAll(r)
+		r.Close()
+	case cPackBits:
+		d.buf, err = unpackBits(io.NewSectionReader(ra, offset, n))
+	default:
+		err = UnsupportedError(fmt.Sprintf("compression value %d", d.firstVal(tCompression)))
+	}
+	return err
+}
+
+// decodeBlocksConcurrently decompresses and decodes the blocks into img,
+// using up to concurrency goroutines. Reading the compressed data from d.r is
+// serialized, as d.r may not be safe for concurrent use, but decompression
+// and decoding, which dominate the cost, are not. At most concurrency blocks
+// are in memory at once.
+func (d *decoder) decodeBlocksConcurrently(img image.Image, blocks []block, concurrency int) error {
+	var (
+		mu       sync.Mutex // Guards d.r and firstErr.
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mu.Unlock()
+	}
+
+	work := make(chan block)
+	if concurrency > len(blocks) {
+		concurrency = len(blocks)
+	}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each goroutine has its own copy of the decoder's per-block
+			// state (buf, off, v and nbits). The rest is read-only.
+			dd := *d
+			for b := range work {
+				mu.Lock()
+				if firstErr != nil {
+					mu.Unlock()
+					continue
+				}
+				// b.n comes from the file, so grow the buffer as data
+				// arrives instead of allocating b.n bytes up front. Short
+				// data is left to decompress to handle, as it would be
+				// without concurrency.
+				var raw bytes.Buffer
+				_, err := raw.ReadFrom(io.NewSectionReader(d.r, b.offset, b.n))
+				mu.Unlock()
+				if err == nil {
+					err = dd.decompress(bytes.NewReader(raw.Bytes()), 0, int64(raw.Len()), b.rect.Dx(), b.rect.Dy())
+				}
+				if err == nil {
+					err = dd.decode(img, b.rect.Min.X, b.rect.Min.Y, b.rect.Max.X, b.rect.Max.Y)
+				}
+				if err != nil {
+					setErr(err)
+				}
+			}
+		}()
+	}
+	for _, b := range blocks {
+		work <- b
 	}
-	return
+	close(work)
+	wg.Wait()
+	return firstErr
 }
 
 func init() {
 	image.RegisterFormat("tiff", leHeader, Decode, DecodeConfig)
 	image.RegisterFormat("tiff", beHeader, Decode, DecodeConfig)
+	image.RegisterFormat("tiff", leBigHeader, Decode, DecodeConfig)
+	image.RegisterFormat("tiff", beBigHeader, Decode, DecodeConfig)
 }
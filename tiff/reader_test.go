@@ -13,10 +13,14 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 
 	_ "image/png"
+
+	"golang.org/x/image/bitmap"
+	"golang.org/x/image/imagelimit"
 )
 
 const testdataDir = "../testdata/"
@@ -194,6 +198,165 @@ func TestDecodeLZW(t *testing.T) {
 	compare(t, img0, img1)
 }
 
+// TestDecodeConcurrently tests that decoding strips and tiles concurrently
+// gives the same result as decoding them one at a time.
+func TestDecodeConcurrently(t *testing.T) {
+	filenames := []string{
+		"blue-purple-pink.lzwcompressed.tiff",
+		"bw-deflate.tiff",
+		"bw-gopher_ccittGroup3.tiff",
+		"bw-gopher_ccittGroup4.tiff",
+		"bw-packbits.tiff",
+		"video-001-16bit.tiff",
+		"video-001-paletted.tiff",
+		"video-001-strip-64.tiff",
+		"video-001-tile-64x64.tiff",
+	}
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(testdataDir + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img0, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: Decode: %v", filename, err)
+			continue
+		}
+		for _, r := range []io.Reader{bytes.NewReader(data), bytes.NewBuffer(data)} {
+			img1, err := DecodeWithOptions(r, &DecodeOptions{Concurrency: 4})
+			if err != nil {
+				t.Errorf("%s: DecodeWithOptions: %v", filename, err)
+				continue
+			}
+			compare(t, img0, img1)
+		}
+	}
+
+	// Errors in any block are reported.
+	data, err := ioutil.ReadFile(testdataDir + "blue-purple-pink.lzwcompressed.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 8; i < len(data)/2; i++ {
+		data[i] = 0xff
+	}
+	if _, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Concurrency: 4}); err == nil {
+		t.Error("corrupt image: got nil error")
+	}
+}
+
+// TestDecodeConcurrentlyHugeByteCounts tests that decoding strips
+// concurrently does not allocate what their byte counts claim, when the file
+// is much shorter.
+func TestDecodeConcurrentlyHugeByteCounts(t *testing.T) {
+	data, err := ioutil.ReadFile(testdataDir + "video-001-strip-64.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The image's two StripByteCounts are at offset 30830.
+	const off = 30830
+	binary.LittleEndian.PutUint32(data[off+0:], 0x7fffffff)
+	binary.LittleEndian.PutUint32(data[off+4:], 0x7fffffff)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	// The strips' data is followed by other data, so decoding may fail.
+	DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Concurrency: 2})
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<24 {
+		t.Errorf("allocated %d bytes, want at most %d", n, 1<<24)
+	}
+}
+
+// TestDecodeHugeByteCount tests that decoding an uncompressed single strip
+// image does not allocate what its byte count claims, when the file is much
+// shorter, and that the byte count is checked against the limits.
+func TestDecodeHugeByteCount(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 9, 7))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(i)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// Replace the strip's byte count, 63, in the StripByteCounts entry.
+	old := []byte{tStripByteCounts & 0xff, tStripByteCounts >> 8, dtLong, 0, 1, 0, 0, 0, 63, 0, 0, 0}
+	i := bytes.Index(data, old)
+	if i < 0 {
+		t.Fatal("could not find the StripByteCounts entry")
+	}
+	binary.LittleEndian.PutUint32(data[i+8:], 2147483900)
+
+	for _, r := range []io.Reader{bytes.NewReader(data), struct{ io.Reader }{bytes.NewReader(data)}} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		got, err := Decode(r)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			t.Errorf("%T: Decode: %v", r, err)
+			continue
+		}
+		if n := after.TotalAlloc - before.TotalAlloc; n > 1<<24 {
+			t.Errorf("%T: allocated %d bytes, want at most %d", r, n, 1<<24)
+		}
+		compare(t, m, got)
+	}
+
+	limits := &imagelimit.Limits{MaxMemory: 1 << 20}
+	_, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Limits: limits})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "memory" {
+		t.Errorf("DecodeWithOptions: got %v, want a memory *imagelimit.Error", err)
+	}
+	_, err = DecodeAll(bytes.NewReader(data), &DecodeOptions{Limits: limits})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "memory" {
+		t.Errorf("DecodeAll: got %v, want a memory *imagelimit.Error", err)
+	}
+	_, err = DecodeChannel(bytes.NewReader(data), 0, &DecodeOptions{Limits: limits})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "memory" {
+		t.Errorf("DecodeChannel: got %v, want a memory *imagelimit.Error", err)
+	}
+	_, err = DecodeConfigAll(bytes.NewReader(data), &DecodeOptions{
+		Limits: &imagelimit.Limits{MaxPixels: 9*7 - 1},
+	})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "pixels" {
+		t.Errorf("DecodeConfigAll: got %v, want a pixels *imagelimit.Error", err)
+	}
+}
+
+func TestDecodeReaderAt(t *testing.T) {
+	for _, filename := range []string{"video-001-strip-64.tiff", "video-001-tile-64x64.tiff"} {
+		f, err := os.Open(testdataDir + filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := DecodeConfigReaderAt(f, fi.Size())
+		if err != nil {
+			t.Errorf("%s: DecodeConfigReaderAt: %v", filename, err)
+			continue
+		}
+		img0, err := load(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img0.Bounds(); c.Width != b.Dx() || c.Height != b.Dy() {
+			t.Errorf("%s: DecodeConfigReaderAt: got %dx%d, want %dx%d", filename, c.Width, c.Height, b.Dx(), b.Dy())
+		}
+		img1, err := DecodeReaderAt(f, fi.Size(), nil)
+		if err != nil {
+			t.Errorf("%s: DecodeReaderAt: %v", filename, err)
+			continue
+		}
+		compare(t, img0, img1)
+	}
+}
+
 // TestEOF tests that decoding a TIFF image returns io.ErrUnexpectedEOF
 // when there are no headers or data is empty
 func TestEOF(t *testing.T) {
@@ -229,6 +392,91 @@ func TestDecodeCCITT(t *testing.T) {
 	}
 }
 
+func TestDecodeBilevel(t *testing.T) {
+	want, err := load("bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range []string{
+		"bw-gopher_ccittGroup3.tiff",
+		"bw-gopher_ccittGroup4.tiff",
+	} {
+		data, err := ioutil.ReadFile(testdataDir + fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, concurrency := range []int{1, 4} {
+			m, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+				Concurrency: concurrency,
+				Bilevel:     true,
+			})
+			if err != nil {
+				t.Fatalf("%s: %v", fn, err)
+			}
+			if _, ok := m.(*bitmap.Image); !ok {
+				t.Fatalf("%s: got %T, want *bitmap.Image", fn, m)
+			}
+			compare(t, want, m)
+		}
+	}
+
+	// Images with more than 1 BitsPerSample are not affected.
+	data, err := ioutil.ReadFile(testdataDir + "video-001.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Bilevel: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.(*bitmap.Image); ok {
+		t.Error("8 bit image was decoded to a *bitmap.Image")
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	data, err := ioutil.ReadFile(testdataDir + "video-001.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := int64(c.Width * c.Height)
+	if _, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+		Limits: &imagelimit.Limits{MaxPixels: n},
+	}); err != nil {
+		t.Errorf("within limits: %v", err)
+	}
+	_, err = DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+		Limits: &imagelimit.Limits{MaxPixels: n - 1},
+	})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "pixels" {
+		t.Errorf("beyond limits: got %v, want a pixels *imagelimit.Error", err)
+	}
+
+	// Bilevel images take 1 bit per pixel, instead of 8.
+	data, err = ioutil.ReadFile(testdataDir + "bw-gopher_ccittGroup4.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err = DecodeConfig(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	limits := &imagelimit.Limits{MaxMemory: int64((c.Width + 7) / 8 * c.Height)}
+	if _, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+		Bilevel: true,
+		Limits:  limits,
+	}); err != nil {
+		t.Errorf("bilevel: %v", err)
+	}
+	_, err = DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Limits: limits})
+	if e, ok := err.(*imagelimit.Error); !ok || e.Limit != "memory" {
+		t.Errorf("gray: got %v, want a memory *imagelimit.Error", err)
+	}
+}
+
 // TestDecodeTagOrder tests that a malformed image with unsorted IFD entries is
 // correctly rejected.
 func TestDecodeTagOrder(t *testing.T) {
@@ -0,0 +1,321 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+
+	"golang.org/x/image/bitmap"
+	"golang.org/x/image/floatimage"
+	"golang.org/x/image/scanline"
+	"golang.org/x/image/tiff/lzw"
+)
+
+// stripSize is the approximate size in bytes of the uncompressed strips
+// written by a RowWriter, as recommended by page 39 of the spec.
+const stripSize = 8 << 10
+
+// NewRowWriter returns a scanline.Writer that writes a width by height image
+// to w in TIFF format, a band of rows at a time. Rows are written in strips,
+// so that only one strip of pixels is held in memory.
+//
+// The pixel format is that used by Encode for an image whose color model is
+// model, such as 8 bit grayscale for color.GrayModel and 8 bit paletted for a
+// color.Palette, and 32 bit floating point for the models of the
+// golang.org/x/image/floatimage package. Models other than the standard
+// library's gray, RGBA and NRGBA models, color.Palette and the floatimage
+// models mean 8 bit RGBA. Rows are converted to that model as needed, except
+// that the indexes of an *image.Paletted are written as is.
+//
+// The location of a compressed image's IFD is not known until all of its
+// rows are written, so writing with a Compression other than Uncompressed
+// requires w to be an io.WriteSeeker. Tiled output is not supported.
+func NewRowWriter(w io.Writer, width, height int, model color.Model, opt *Options) (scanline.Writer, error) {
+	if width < 0 || height < 0 {
+		return nil, errors.New("tiff: negative image size")
+	}
+	z := &rowWriter{
+		w:           w,
+		d:           image.Point{width, height},
+		compression: cNone,
+		row:         newRow(model, width),
+		opt:         opt,
+	}
+	if opt != nil {
+		z.compression = opt.Compression.specValue()
+		z.predictor = opt.Predictor && (z.compression == cLZW || z.compression == cDeflate)
+		z.bigTIFF = opt.BigTIFF
+		if opt.TileWidth != 0 || opt.TileHeight != 0 {
+			return nil, UnsupportedError("tiled RowWriter")
+		}
+		var err error
+		if z.exif, err = opt.Metadata.exifIFD(); err != nil {
+			return nil, err
+		}
+	}
+	switch z.compression {
+	case cNone, cLZW, cDeflate:
+	default:
+		return nil, UnsupportedError("compression")
+	}
+	z.f = newFormat(z.row)
+	z.rowsPerStrip = 1
+	if n := width * z.f.bytesPerPixel; n > 0 && n < stripSize {
+		z.rowsPerStrip = stripSize / n
+	}
+
+	ifdOffset := 0
+	if z.compression == cNone {
+		ifdOffset = headerLen(z.bigTIFF) + width*height*z.f.bytesPerPixel
+		ifdOffset += ifdOffset & 1
+		ifdOffset += subIFDSize(z.exif, z.bigTIFF)
+		if !z.bigTIFF && uint64(ifdOffset) > math.MaxUint32 {
+			return nil, errTooLarge
+		}
+	} else {
+		ws, ok := w.(io.WriteSeeker)
+		if !ok {
+			return nil, errors.New("tiff: compressed RowWriter requires an io.WriteSeeker")
+		}
+		start, err := ws.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		z.ws, z.start = ws, start
+	}
+	if err := writeHeader(w, ifdOffset, z.bigTIFF); err != nil {
+		return nil, err
+	}
+	z.off = headerLen(z.bigTIFF)
+	return z, nil
+}
+
+// newRow returns a one row image of the type for model.
+func newRow(model color.Model, width int) image.Image {
+	r := image.Rect(0, 0, width, 1)
+	if p, ok := model.(color.Palette); ok {
+		return image.NewPaletted(r, p)
+	}
+	switch model {
+	case color.GrayModel, bitmap.Model:
+		return image.NewGray(r)
+	case color.Gray16Model:
+		return image.NewGray16(r)
+	case color.NRGBAModel:
+		return image.NewNRGBA(r)
+	case color.NRGBA64Model:
+		return image.NewNRGBA64(r)
+	case color.RGBA64Model:
+		return image.NewRGBA64(r)
+	case floatimage.GrayF32Model:
+		return floatimage.NewGrayF32(r)
+	case floatimage.RGBAF32Model:
+		return floatimage.NewRGBAF32(r)
+	}
+	return image.NewRGBA(r)
+}
+
+// isRGBA returns whether m is an *image.RGBA, the type returned by newRow for
+// models without a type of their own.
+func isRGBA(m image.Image) bool {
+	_, ok := m.(*image.RGBA)
+	return ok
+}
+
+// rowWriter is the scanline.Writer returned by NewRowWriter.
+type rowWriter struct {
+	w           io.Writer
+	d           image.Point
+	compression uint32
+	predictor   bool
+	bigTIFF     bool
+	opt         *Options
+	exif        []ifdEntry
+	f           *format
+	// row is a one row image of the type being encoded, for converting rows
+	// of other types.
+	row image.Image
+
+	// ws and start are the io.WriteSeeker for compressed images, and its
+	// offset at the start of the file.
+	ws    io.WriteSeeker
+	start int64
+
+	// y is the number of rows written so far, and pending holds the encoded,
+	// but not yet compressed, rows of the current strip.
+	y            int
+	pending      bytes.Buffer
+	rowsPerStrip int
+	// off is the offset of the next byte written to w.
+	off                 int
+	offsets, byteCounts []uint64
+	err                 error
+}
+
+func (z *rowWriter) WriteRows(m image.Image) error {
+	if z.err != nil {
+		return z.err
+	}
+	b := m.Bounds()
+	if b.Dx() != z.d.X {
+		return errors.New("tiff: row width does not match the image width")
+	}
+	if b.Dy() > z.d.Y-z.y {
+		return errors.New("tiff: too many rows")
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src, sy := convertRow(z.row, m, y)
+		r := image.Rect(src.Bounds().Min.X, sy, src.Bounds().Max.X, sy+1)
+		if z.err = encodeRect(&z.pending, src, r, z.predictor); z.err != nil {
+			return z.err
+		}
+		z.y++
+		if z.y%z.rowsPerStrip == 0 || z.y == z.d.Y {
+			if z.err = z.writeStrip(); z.err != nil {
+				return z.err
+			}
+		}
+	}
+	return nil
+}
+
+// convertRow returns an image whose row sy is m's row y. It is m itself if m
+// has the same type as row, and otherwise row, with m's row y converted to
+// row's color model.
+func convertRow(row, m image.Image, y int) (image.Image, int) {
+	same := false
+	switch row.(type) {
+	case *image.Paletted:
+		_, same = m.(*image.Paletted)
+	case *image.Gray:
+		_, same = m.(*image.Gray)
+	case *image.Gray16:
+		_, same = m.(*image.Gray16)
+	case *image.NRGBA:
+		_, same = m.(*image.NRGBA)
+	case *image.NRGBA64:
+		_, same = m.(*image.NRGBA64)
+	case *image.RGBA:
+		_, same = m.(*image.RGBA)
+	case *image.RGBA64:
+		_, same = m.(*image.RGBA64)
+	case *floatimage.GrayF32:
+		_, same = m.(*floatimage.GrayF32)
+	case *floatimage.RGBAF32:
+		_, same = m.(*floatimage.RGBAF32)
+	}
+	if same {
+		return m, y
+	}
+	dst := row.(interface {
+		Set(x, y int, c color.Color)
+	})
+	b := m.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		dst.Set(x-b.Min.X, 0, m.At(x, y))
+	}
+	return row, 0
+}
+
+// writeStrip writes the pending rows to w as a strip.
+func (z *rowWriter) writeStrip() error {
+	cw := &countWriter{w: z.w}
+	var dst io.WriteCloser
+	switch z.compression {
+	case cLZW:
+		dst = lzw.NewWriter(cw, lzw.MSB, 8)
+	case cDeflate:
+		dst = zlib.NewWriter(cw)
+	}
+	if dst == nil {
+		if _, err := z.pending.WriteTo(cw); err != nil {
+			return err
+		}
+	} else {
+		if _, err := z.pending.WriteTo(dst); err != nil {
+			return err
+		}
+		if err := dst.Close(); err != nil {
+			return err
+		}
+	}
+	z.offsets = append(z.offsets, uint64(z.off))
+	z.byteCounts = append(z.byteCounts, uint64(cw.n))
+	z.off += cw.n
+	return nil
+}
+
+func (z *rowWriter) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if z.y != z.d.Y {
+		return errors.New("tiff: not enough rows")
+	}
+	z.err = errors.New("tiff: RowWriter is closed")
+
+	// The IFD, and the EXIF IFD before it, have to begin on a word boundary
+	// (page 15).
+	if z.off&1 != 0 {
+		if _, err := z.w.Write([]byte{0}); err != nil {
+			return err
+		}
+		z.off++
+	}
+	ifd := z.f.ifdEntries(z.d, z.compression, z.predictor, z.opt)
+	if z.exif != nil {
+		if err := writeSubIFD(z.w, z.off, z.exif, z.bigTIFF); err != nil {
+			return err
+		}
+		ifd = append(ifd, offsetsEntry(tExifIFD, []uint64{uint64(z.off)}, z.bigTIFF))
+		z.off += subIFDSize(z.exif, z.bigTIFF)
+	}
+	ifd = append(ifd,
+		offsetsEntry(tStripOffsets, z.offsets, z.bigTIFF),
+		uintEntry(tRowsPerStrip, z.rowsPerStrip),
+		offsetsEntry(tStripByteCounts, z.byteCounts, z.bigTIFF),
+	)
+	if !z.bigTIFF && uint64(z.off+ifdSize(ifd, false)) > math.MaxUint32 {
+		return errTooLarge
+	}
+	if err := writeIFD(z.w, z.off, ifd, 0, z.bigTIFF); err != nil {
+		return err
+	}
+	if z.ws == nil {
+		return nil
+	}
+
+	// Fill in the IFD offset in the header, now that it is known.
+	end, err := z.ws.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := z.ws.Seek(z.start, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeHeader(z.ws, z.off, z.bigTIFF); err != nil {
+		return err
+	}
+	_, err = z.ws.Seek(end, io.SeekStart)
+	return err
+}
+
+// countWriter is an io.Writer that counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += n
+	return n, err
+}
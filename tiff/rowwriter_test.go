@@ -0,0 +1,161 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"testing"
+)
+
+// seekBuffer is an in-memory io.WriteSeeker.
+type seekBuffer struct {
+	buf []byte
+	off int
+}
+
+func (b *seekBuffer) Write(p []byte) (int, error) {
+	if n := b.off + len(p); n > len(b.buf) {
+		b.buf = append(b.buf, make([]byte, n-len(b.buf))...)
+	}
+	b.off += copy(b.buf[b.off:], p)
+	return len(p), nil
+}
+
+func (b *seekBuffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += int64(b.off)
+	case io.SeekEnd:
+		offset += int64(len(b.buf))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	b.off = int(offset)
+	return offset, nil
+}
+
+// writeBands writes m to z in bands of n rows.
+func writeBands(z interface{ WriteRows(image.Image) error }, m image.Image, n int) error {
+	type subImager interface {
+		SubImage(image.Rectangle) image.Image
+	}
+	b := m.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += n {
+		r := image.Rect(b.Min.X, y, b.Max.X, y+n).Intersect(b)
+		if err := z.WriteRows(m.(subImager).SubImage(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRowWriter(t *testing.T) {
+	testCases := []struct {
+		filename string
+		opts     *Options
+	}{
+		{"video-001.tiff", nil},
+		{"video-001-16bit.tiff", nil},
+		{"video-001-gray.tiff", nil},
+		{"video-001-gray-16bit.tiff", &Options{Compression: LZW, Predictor: true}},
+		{"video-001-paletted.tiff", &Options{Compression: Deflate}},
+		{"video-001.tiff", &Options{Compression: LZW, Predictor: true}},
+		{"video-001.tiff", &Options{Compression: Deflate}},
+		{"video-001-gray.tiff", &Options{BigTIFF: true}},
+		{"video-001.tiff", &Options{Compression: LZW, BigTIFF: true}},
+	}
+	for _, tc := range testCases {
+		m0, err := openImage(tc.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := m0.Bounds()
+		model := m0.ColorModel()
+		if p, ok := m0.(*image.Paletted); ok {
+			model = p.Palette
+		}
+
+		// Write to a non-zero offset of a seekBuffer, after a prefix.
+		buf := &seekBuffer{}
+		buf.Write([]byte("prefix"))
+		z, err := NewRowWriter(buf, b.Dx(), b.Dy(), model, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: NewRowWriter: %v", tc.filename, err)
+		}
+		if err := writeBands(z, m0, 7); err != nil {
+			t.Fatalf("%s: WriteRows: %v", tc.filename, err)
+		}
+		if err := z.Close(); err != nil {
+			t.Fatalf("%s: Close: %v", tc.filename, err)
+		}
+		m1, err := Decode(bytes.NewReader(buf.buf[len("prefix"):]))
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tc.filename, err)
+		}
+		compare(t, m0, m1)
+	}
+}
+
+func TestRowWriterConvert(t *testing.T) {
+	m0 := image.NewNRGBA(image.Rect(3, 4, 40, 30))
+	for i := range m0.Pix {
+		m0.Pix[i] = uint8(i * 3)
+	}
+	want := image.NewGray(image.Rect(0, 0, 37, 26))
+	for y := 0; y < 26; y++ {
+		for x := 0; x < 37; x++ {
+			want.Set(x, y, m0.At(x+3, y+4))
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	z, err := NewRowWriter(buf, 37, 26, color.GrayModel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBands(z, m0, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compare(t, want, got)
+}
+
+func TestRowWriterErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 10, 10))
+	if _, err := NewRowWriter(&bytes.Buffer{}, 10, 10, color.GrayModel, &Options{Compression: LZW}); err == nil {
+		t.Error("compression without an io.WriteSeeker: got nil error")
+	}
+	if _, err := NewRowWriter(&bytes.Buffer{}, 10, 10, color.GrayModel, &Options{TileWidth: 16, TileHeight: 16}); err == nil {
+		t.Error("tiles: got nil error")
+	}
+
+	z, err := NewRowWriter(&bytes.Buffer{}, 10, 10, color.GrayModel, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := z.WriteRows(m.SubImage(image.Rect(0, 0, 9, 5))); err == nil {
+		t.Error("wrong width: got nil error")
+	}
+	if err := z.WriteRows(m.SubImage(image.Rect(0, 0, 10, 5))); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.WriteRows(m); err == nil {
+		t.Error("too many rows: got nil error")
+	}
+	if err := z.Close(); err == nil {
+		t.Error("not enough rows: got nil error")
+	}
+}
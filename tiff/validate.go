@@ -0,0 +1,269 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// maxIFDs is the maximum number of IFDs that Validate walks, which bounds the
+// work done on malicious input.
+const maxIFDs = 1 << 12
+
+// Directory describes an Image File Directory (IFD), the metadata of one of
+// the images in a TIFF file.
+type Directory struct {
+	// Offset is the IFD's position in the file.
+	Offset int64
+	// Entries are the IFD's entries, in file order.
+	Entries []Entry
+}
+
+// Entry describes a single IFD entry.
+type Entry struct {
+	Tag   uint16
+	Type  uint16
+	Count uint32
+	// ValueOffset is the position of the entry's value in the file. Values
+	// of four bytes or less are stored within the IFD entry itself.
+	ValueOffset int64
+}
+
+// String returns a human readable, multi-line description of d.
+func (d Directory) String() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "IFD at offset %d, %d entries\n", d.Offset, len(d.Entries))
+	for _, e := range d.Entries {
+		name := tagNames[int(e.Tag)]
+		if name == "" {
+			name = "unknown"
+		}
+		typ := "unknown"
+		if int(e.Type) < len(typeNames) && typeNames[e.Type] != "" {
+			typ = typeNames[e.Type]
+		}
+		fmt.Fprintf(b, "  tag %5d %-26s type %-9s count %-8d value offset %d\n",
+			e.Tag, name, typ, e.Count, e.ValueOffset)
+	}
+	return b.String()
+}
+
+var typeNames = [...]string{
+	dtByte:      "Byte",
+	dtASCII:     "ASCII",
+	dtShort:     "Short",
+	dtLong:      "Long",
+	dtRational:  "Rational",
+	6:           "SByte",
+	dtUndefined: "Undefined",
+	8:           "SShort",
+	9:           "SLong",
+	dtSRational: "SRational",
+	11:          "Float",
+	dtDouble:    "Double",
+	13:          "IFD",
+	dtLong8:     "Long8",
+	17:          "SLong8",
+	dtIFD8:      "IFD8",
+}
+
+var tagNames = map[int]string{
+	tNewSubfileType:            "NewSubfileType",
+	tImageWidth:                "ImageWidth",
+	tImageLength:               "ImageLength",
+	tBitsPerSample:             "BitsPerSample",
+	tCompression:               "Compression",
+	tPhotometricInterpretation: "PhotometricInterpretation",
+	tFillOrder:                 "FillOrder",
+	tStripOffsets:              "StripOffsets",
+	tSamplesPerPixel:           "SamplesPerPixel",
+	tRowsPerStrip:              "RowsPerStrip",
+	tStripByteCounts:           "StripByteCounts",
+	tT4Options:                 "T4Options",
+	tT6Options:                 "T6Options",
+	tTileWidth:                 "TileWidth",
+	tTileLength:                "TileLength",
+	tTileOffsets:               "TileOffsets",
+	tTileByteCounts:            "TileByteCounts",
+	tXResolution:               "XResolution",
+	tYResolution:               "YResolution",
+	tResolutionUnit:            "ResolutionUnit",
+	tPredictor:                 "Predictor",
+	tColorMap:                  "ColorMap",
+	tExtraSamples:              "ExtraSamples",
+	tSampleFormat:              "SampleFormat",
+	tModelPixelScale:           "ModelPixelScale",
+	tModelTiepoint:             "ModelTiepoint",
+	tModelTransformation:       "ModelTransformation",
+	tGeoKeyDirectory:           "GeoKeyDirectory",
+	tGeoDoubleParams:           "GeoDoubleParams",
+	tGeoASCIIParams:            "GeoASCIIParams",
+	tOrientation:               "Orientation",
+	tPageNumber:                "PageNumber",
+	tExifIFD:                   "ExifIFD",
+	tICCProfile:                "ICCProfile",
+}
+
+// Validate walks all of the IFDs of the TIFF or BigTIFF file in r and checks
+// that the file is structurally sound: that the IFDs do not form a loop, that
+// their entries are sorted and of known types, and that every entry value and
+// every strip or tile lies within the file. It does not decompress any image
+// data.
+//
+// It returns a description of the IFDs. On error, the description covers the
+// IFDs that were walked before the error was found, and the error, a
+// FormatError or UnsupportedError, says which IFD and tag is at fault.
+func Validate(r io.ReaderAt) ([]Directory, error) {
+	byteOrder, bigTIFF, offset, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	d := &decoder{r: r, byteOrder: byteOrder, bigTIFF: bigTIFF}
+
+	var dirs []Directory
+	seen := map[int64]bool{}
+	for offset != 0 {
+		n := len(dirs)
+		if n == maxIFDs {
+			return dirs, UnsupportedError("number of IFDs")
+		}
+		if seen[offset] {
+			return dirs, FormatError(fmt.Sprintf("IFD #%d: offset %d loops back to an earlier IFD", n, offset))
+		}
+		seen[offset] = true
+		dir, next, err := d.validateIFD(n, offset)
+		if err != nil {
+			return dirs, err
+		}
+		dirs = append(dirs, dir)
+		offset = next
+	}
+	if len(dirs) == 0 {
+		return nil, FormatError("no IFDs")
+	}
+	return dirs, nil
+}
+
+// validateIFD validates the n'th IFD, at the given offset, and returns its
+// description and the offset of the next IFD.
+func (d *decoder) validateIFD(n int, offset int64) (dir Directory, next int64, err error) {
+	errorf := func(format string, args ...interface{}) error {
+		return FormatError(fmt.Sprintf("IFD #%d: ", n) + fmt.Sprintf(format, args...))
+	}
+	numItems, countLen, err := d.readEntryCount(offset)
+	if _, ok := err.(UnsupportedError); ok {
+		return Directory{}, 0, UnsupportedError(fmt.Sprintf("IFD #%d: number of entries", n))
+	} else if err != nil {
+		return Directory{}, 0, errorf("offset %d is out of bounds", offset)
+	}
+	if numItems == 0 {
+		return Directory{}, 0, errorf("no entries")
+	}
+	// The entries are followed by the offset of the next IFD.
+	entryLen, offsetLen := d.entryLen(), d.offsetLen()
+	p := make([]byte, entryLen*numItems+offsetLen)
+	if _, err := d.r.ReadAt(p, offset+int64(countLen)); err != nil {
+		return Directory{}, 0, errorf("%d entries extend beyond the end of the file", numItems)
+	}
+
+	dir = Directory{
+		Offset:  offset,
+		Entries: make([]Entry, numItems),
+	}
+	entries := map[int][]byte{}
+	prevTag := -1
+	for i := range dir.Entries {
+		q := p[entryLen*i : entryLen*(i+1)]
+		datatype, count, field := d.entry(q)
+		e := Entry{
+			Tag:         d.byteOrder.Uint16(q[0:2]),
+			Type:        datatype,
+			Count:       uint32(count),
+			ValueOffset: offset + int64(countLen+entryLen*(i+1)-offsetLen),
+		}
+		dir.Entries[i] = e
+		if int(e.Tag) <= prevTag {
+			return dir, 0, errorf("tag %d: tags are not sorted in ascending order", e.Tag)
+		}
+		prevTag = int(e.Tag)
+		if e.Type == 0 || int(e.Type) >= len(lengths) || lengths[e.Type] == 0 {
+			return dir, 0, errorf("tag %d: unknown data type %d", e.Tag, e.Type)
+		}
+		if count > math.MaxUint32 {
+			return dir, 0, errorf("tag %d: count %d is too large", e.Tag, count)
+		}
+		datalen := uint64(lengths[e.Type]) * count
+		if datalen > uint64(offsetLen) {
+			e.ValueOffset = d.offset(field)
+			dir.Entries[i] = e
+			if err := d.checkRange(e.ValueOffset, datalen); err != nil {
+				return dir, 0, errorf("tag %d: %d byte value at offset %d is out of bounds", e.Tag, datalen, e.ValueOffset)
+			}
+		}
+		entries[int(e.Tag)] = q
+	}
+	next = d.offset(p[entryLen*numItems:])
+
+	for _, tag := range [...]int{tImageWidth, tImageLength} {
+		if entries[tag] == nil {
+			return dir, 0, errorf("missing required tag %d (%s)", tag, tagNames[tag])
+		}
+	}
+	if err := d.validateBlocks(entries, tStripOffsets, tStripByteCounts, errorf); err != nil {
+		return dir, 0, err
+	}
+	if err := d.validateBlocks(entries, tTileOffsets, tTileByteCounts, errorf); err != nil {
+		return dir, 0, err
+	}
+	if entries[tStripOffsets] == nil && entries[tTileOffsets] == nil {
+		return dir, 0, errorf("missing StripOffsets or TileOffsets")
+	}
+	return dir, next, nil
+}
+
+// validateBlocks checks that the strips or tiles described by the offsets
+// and counts tags lie within the file.
+func (d *decoder) validateBlocks(entries map[int][]byte, offsetsTag, countsTag int, errorf func(string, ...interface{}) error) error {
+	if entries[offsetsTag] == nil && entries[countsTag] == nil {
+		return nil
+	}
+	if entries[offsetsTag] == nil || entries[countsTag] == nil {
+		return errorf("%s and %s must both be present", tagNames[offsetsTag], tagNames[countsTag])
+	}
+	offsets, err := d.ifdUint(entries[offsetsTag])
+	if err != nil {
+		return errorf("tag %d (%s): %v", offsetsTag, tagNames[offsetsTag], err)
+	}
+	counts, err := d.ifdUint(entries[countsTag])
+	if err != nil {
+		return errorf("tag %d (%s): %v", countsTag, tagNames[countsTag], err)
+	}
+	if len(offsets) != len(counts) {
+		return errorf("%d %s but %d %s", len(offsets), tagNames[offsetsTag], len(counts), tagNames[countsTag])
+	}
+	for i := range offsets {
+		if err := d.checkRange(int64(offsets[i]), uint64(counts[i])); err != nil {
+			return errorf("block %d: %d bytes at offset %d are out of bounds", i, counts[i], offsets[i])
+		}
+	}
+	return nil
+}
+
+// checkRange returns an error if the n bytes at offset do not lie within the
+// file.
+func (d *decoder) checkRange(offset int64, n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	if offset < 0 || n > math.MaxInt64-uint64(offset) {
+		return FormatError("invalid range")
+	}
+	var b [1]byte
+	_, err := d.r.ReadAt(b[:], offset+int64(n)-1)
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tiff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateTestdata(t *testing.T) {
+	filenames, err := filepath.Glob(testdataDir + "*.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filenames) == 0 {
+		t.Fatal("no testdata")
+	}
+	for _, filename := range filenames {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs, err := Validate(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", filename, err)
+			continue
+		}
+		if s := dirs[0].String(); !strings.Contains(s, "ImageWidth") {
+			t.Errorf("%s: String does not mention ImageWidth:\n%s", filename, s)
+		}
+	}
+}
+
+func TestValidateCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	valid := buf.Bytes()
+	ifdOffset := int(binary.LittleEndian.Uint32(valid[4:8]))
+	numItems := int(binary.LittleEndian.Uint16(valid[ifdOffset:]))
+	nextOffset := ifdOffset + 2 + ifdLen*numItems
+
+	// entry returns the offset of the IFD entry with the given tag.
+	entry := func(tag int) int {
+		for i := 0; i < numItems; i++ {
+			o := ifdOffset + 2 + ifdLen*i
+			if int(binary.LittleEndian.Uint16(valid[o:])) == tag {
+				return o
+			}
+		}
+		t.Fatalf("no tag %d", tag)
+		return 0
+	}
+
+	testCases := []struct {
+		desc    string
+		corrupt func(b []byte)
+		want    string
+	}{{
+		desc: "loop",
+		corrupt: func(b []byte) {
+			binary.LittleEndian.PutUint32(b[nextOffset:], uint32(ifdOffset))
+		},
+		want: "IFD #1: offset",
+	}, {
+		desc: "strip out of bounds",
+		corrupt: func(b []byte) {
+			binary.LittleEndian.PutUint32(b[entry(tStripByteCounts)+8:], 1<<20)
+		},
+		want: "IFD #0: block 0",
+	}, {
+		desc: "unsorted tags",
+		corrupt: func(b []byte) {
+			binary.LittleEndian.PutUint16(b[entry(tImageLength):], tImageWidth)
+		},
+		want: "not sorted",
+	}, {
+		desc: "unknown data type",
+		corrupt: func(b []byte) {
+			binary.LittleEndian.PutUint16(b[entry(tCompression)+2:], 99)
+		},
+		want: "unknown data type",
+	}, {
+		desc: "IFD out of bounds",
+		corrupt: func(b []byte) {
+			binary.LittleEndian.PutUint32(b[4:], 1<<20)
+		},
+		want: "out of bounds",
+	}}
+
+	if _, err := Validate(bytes.NewReader(valid)); err != nil {
+		t.Fatalf("valid: %v", err)
+	}
+	for _, tc := range testCases {
+		b := append([]byte(nil), valid...)
+		tc.corrupt(b)
+		_, err := Validate(bytes.NewReader(b))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tc.desc, err, tc.want)
+		}
+	}
+}
@@ -8,15 +8,22 @@ import (
 	"bytes"
 	"compress/zlib"
 	"encoding/binary"
+	"errors"
 	"image"
+	"image/draw"
 	"io"
+	"math"
 	"sort"
+
+	"golang.org/x/image/ccitt"
+	"golang.org/x/image/floatimage"
+	"golang.org/x/image/tiff/lzw"
 )
 
 // The TIFF format allows to choose the order of the different elements freely.
 // The basic structure of a TIFF file written by this package is:
 //
-//   1. Header (8 bytes).
+//   1. Header (8 bytes, or 16 for BigTIFF).
 //   2. Image data.
 //   3. Image File Directory (IFD).
 //   4. "Pointer area" for larger entries in the IFD.
@@ -27,26 +34,70 @@ var enc = binary.LittleEndian
 // An ifdEntry is a single entry in an Image File Directory.
 // A value of type dtRational is composed of two 32-bit values,
 // thus data contains two uints (numerator and denominator) for a single number.
+// Similarly, every other 8 byte value, such as one of type dtDouble or
+// dtLong8, is composed of its low and high 32 bits.
 type ifdEntry struct {
 	tag      int
 	datatype int
 	data     []uint32
 }
 
+// valueLen returns the length in bytes that each element of e.data encodes
+// to.
+func (e ifdEntry) valueLen() int {
+	if n := int(lengths[e.datatype]); n < 4 {
+		return n
+	}
+	return 4
+}
+
 func (e ifdEntry) putData(p []byte) {
+	n := e.valueLen()
 	for _, d := range e.data {
-		switch e.datatype {
-		case dtByte, dtASCII:
+		switch n {
+		case 1:
 			p[0] = byte(d)
-			p = p[1:]
-		case dtShort:
+		case 2:
 			enc.PutUint16(p, uint16(d))
-			p = p[2:]
-		case dtLong, dtRational:
-			enc.PutUint32(p, uint32(d))
-			p = p[4:]
+		case 4:
+			enc.PutUint32(p, d)
 		}
+		p = p[n:]
+	}
+}
+
+// count returns the number of values in e.
+func (e ifdEntry) count() uint32 {
+	if lengths[e.datatype] == 8 {
+		return uint32(len(e.data) / 2)
 	}
+	return uint32(len(e.data))
+}
+
+// uintEntry returns an IFD entry holding v, of type Short if v fits and
+// otherwise Long.
+func uintEntry(tag int, v int) ifdEntry {
+	if v > 0xffff {
+		return ifdEntry{tag, dtLong, []uint32{uint32(v)}}
+	}
+	return ifdEntry{tag, dtShort, []uint32{uint32(v)}}
+}
+
+// offsetsEntry returns an IFD entry holding the offsets or byte counts v, of
+// type Long, or Long8 for BigTIFF.
+func offsetsEntry(tag int, v []uint64, bigTIFF bool) ifdEntry {
+	if !bigTIFF {
+		data := make([]uint32, len(v))
+		for i, x := range v {
+			data[i] = uint32(x)
+		}
+		return ifdEntry{tag, dtLong, data}
+	}
+	data := make([]uint32, 0, 2*len(v))
+	for _, x := range v {
+		data = append(data, uint32(x), uint32(x>>32))
+	}
+	return ifdEntry{tag, dtLong8, data}
 }
 
 type byTag []ifdEntry
@@ -166,8 +217,41 @@ func encodeRGBA64(w io.Writer, pix []uint8, dx, dy, stride int, predictor bool)
 	return nil
 }
 
-func encode(w io.Writer, m image.Image, predictor bool) error {
-	bounds := m.Bounds()
+// encodeFloat writes rows of dx pixels of spp 32 bit floating point samples.
+// With a predictor, it is the floating point predictor of Adobe Photoshop
+// TIFF Technical Note 3: the bytes of each row's samples are split into
+// planes, from the most significant byte of every sample to the least, and
+// then each byte is differenced with the same byte of the previous pixel.
+func encodeFloat(w io.Writer, pix []float32, dx, dy, stride, spp int, predictor bool) error {
+	n := dx * spp // The number of samples per row.
+	buf := make([]byte, 4*n)
+	for y := 0; y < dy; y++ {
+		row := pix[y*stride : y*stride+n]
+		if predictor {
+			for i, v := range row {
+				b := math.Float32bits(v)
+				buf[i+0*n] = byte(b >> 24)
+				buf[i+1*n] = byte(b >> 16)
+				buf[i+2*n] = byte(b >> 8)
+				buf[i+3*n] = byte(b)
+			}
+			for i := len(buf) - 1; i >= spp; i-- {
+				buf[i] -= buf[i-spp]
+			}
+		} else {
+			for i, v := range row {
+				// We only write little-endian TIFF files.
+				enc.PutUint32(buf[4*i:], math.Float32bits(v))
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encode(w io.Writer, m image.Image, bounds image.Rectangle, predictor bool) error {
 	buf := make([]byte, 4*bounds.Dx())
 	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
 		off := 0
@@ -211,41 +295,98 @@ func writePix(w io.Writer, pix []byte, nrows, length, stride int) error {
 		_, err := w.Write(pix[:nrows*length])
 		return err
 	}
-	for ; nrows > 0; nrows-- {
-		if _, err := w.Write(pix[:length]); err != nil {
+	for y := 0; y < nrows; y++ {
+		if _, err := w.Write(pix[y*stride : y*stride+length]); err != nil {
 			return err
 		}
-		pix = pix[stride:]
 	}
 	return nil
 }
 
-func writeIFD(w io.Writer, ifdOffset int, d []ifdEntry) error {
-	var buf [ifdLen]byte
+// errTooLarge means that an image is too large for a TIFF file, whose
+// offsets are 32 bits.
+var errTooLarge = errors.New("tiff: file larger than 4 GB requires Options.BigTIFF")
+
+// headerLen returns the length in bytes of the file header.
+func headerLen(bigTIFF bool) int {
+	if bigTIFF {
+		return 16
+	}
+	return 8
+}
+
+// writeHeader writes a little-endian TIFF or BigTIFF header, with the offset
+// of the first IFD.
+func writeHeader(w io.Writer, ifdOffset int, bigTIFF bool) error {
+	if !bigTIFF {
+		if _, err := io.WriteString(w, leHeader); err != nil {
+			return err
+		}
+		return binary.Write(w, enc, uint32(ifdOffset))
+	}
+	// The BigTIFF header holds the size of offsets, and a reserved zero.
+	if _, err := io.WriteString(w, leBigHeader+"\x08\x00\x00\x00"); err != nil {
+		return err
+	}
+	return binary.Write(w, enc, uint64(ifdOffset))
+}
+
+// ifdLayout returns the length in bytes of the entry count, of each entry,
+// and of the next IFD offset and each entry's value field, of an IFD.
+func ifdLayout(bigTIFF bool) (countLen, entryLen, offsetLen int) {
+	if bigTIFF {
+		return 8, bigIFDLen, 8
+	}
+	return 2, ifdLen, 4
+}
+
+// ifdSize returns the length in bytes of the IFD d, including its "pointer
+// area", as written by writeIFD.
+func ifdSize(d []ifdEntry, bigTIFF bool) int {
+	countLen, entryLen, offsetLen := ifdLayout(bigTIFF)
+	n := countLen + entryLen*len(d) + offsetLen
+	for _, ent := range d {
+		if datalen := int(ent.count() * lengths[ent.datatype]); datalen > offsetLen {
+			n += datalen
+		}
+	}
+	return n
+}
+
+// writeIFD writes the IFD d, which is at ifdOffset in the file. nextOffset is
+// the offset of the next IFD, or zero if it is the last one.
+func writeIFD(w io.Writer, ifdOffset int, d []ifdEntry, nextOffset int, bigTIFF bool) error {
+	countLen, entryLen, offsetLen := ifdLayout(bigTIFF)
+	buf := make([]byte, entryLen)
 	// Make space for "pointer area" containing IFD entry data
-	// longer than 4 bytes.
+	// longer than the entries' value fields.
 	parea := make([]byte, 1024)
-	pstart := ifdOffset + ifdLen*len(d) + 6
+	pstart := ifdOffset + countLen + entryLen*len(d) + offsetLen
 	var o int // Current offset in parea.
 
 	// The IFD has to be written with the tags in ascending order.
 	sort.Sort(byTag(d))
 
 	// Write the number of entries in this IFD.
-	if err := binary.Write(w, enc, uint16(len(d))); err != nil {
+	if err := writeUint(w, len(d), countLen); err != nil {
 		return err
 	}
 	for _, ent := range d {
+		for i := range buf {
+			buf[i] = 0
+		}
 		enc.PutUint16(buf[0:2], uint16(ent.tag))
 		enc.PutUint16(buf[2:4], uint16(ent.datatype))
-		count := uint32(len(ent.data))
-		if ent.datatype == dtRational {
-			count /= 2
+		count := ent.count()
+		field := buf[entryLen-offsetLen:]
+		if bigTIFF {
+			enc.PutUint64(buf[4:12], uint64(count))
+		} else {
+			enc.PutUint32(buf[4:8], count)
 		}
-		enc.PutUint32(buf[4:8], count)
 		datalen := int(count * lengths[ent.datatype])
-		if datalen <= 4 {
-			ent.putData(buf[8:12])
+		if datalen <= offsetLen {
+			ent.putData(field)
 		} else {
 			if (o + datalen) > len(parea) {
 				newlen := len(parea) + 1024
@@ -257,182 +398,572 @@ func writeIFD(w io.Writer, ifdOffset int, d []ifdEntry) error {
 				parea = newarea
 			}
 			ent.putData(parea[o : o+datalen])
-			enc.PutUint32(buf[8:12], uint32(pstart+o))
+			if bigTIFF {
+				enc.PutUint64(field, uint64(pstart+o))
+			} else {
+				enc.PutUint32(field, uint32(pstart+o))
+			}
 			o += datalen
 		}
-		if _, err := w.Write(buf[:]); err != nil {
+		if _, err := w.Write(buf); err != nil {
 			return err
 		}
 	}
 	// The IFD ends with the offset of the next IFD in the file,
 	// or zero if it is the last one (page 14).
-	if err := binary.Write(w, enc, uint32(0)); err != nil {
+	if err := writeUint(w, nextOffset, offsetLen); err != nil {
 		return err
 	}
 	_, err := w.Write(parea[:o])
 	return err
 }
 
-// Options are the encoding parameters.
-type Options struct {
-	// Compression is the type of compression used.
-	Compression CompressionType
-	// Predictor determines whether a differencing predictor is used;
-	// if true, instead of each pixel's color, the color difference to the
-	// preceding one is saved.  This improves the compression for certain
-	// types of images and compressors. For example, it works well for
-	// photos with Deflate compression.
-	Predictor bool
+// writeUint writes v to w as an n byte unsigned integer, where n is 2, 4 or
+// 8.
+func writeUint(w io.Writer, v int, n int) error {
+	switch n {
+	case 2:
+		return binary.Write(w, enc, uint16(v))
+	case 4:
+		return binary.Write(w, enc, uint32(v))
+	}
+	return binary.Write(w, enc, uint64(v))
 }
 
-// Encode writes the image m to w. opt determines the options used for
-// encoding, such as the compression type. If opt is nil, an uncompressed
-// image is written.
-func Encode(w io.Writer, m image.Image, opt *Options) error {
-	d := m.Bounds().Size()
-
-	compression := uint32(cNone)
-	predictor := false
-	if opt != nil {
-		compression = opt.Compression.specValue()
-		// The predictor field is only used with LZW. See page 64 of the spec.
-		predictor = opt.Predictor && compression == cLZW
+// encodeRect writes the pixels of m within r, which must be inside m's
+// bounds.
+func encodeRect(w io.Writer, m image.Image, r image.Rectangle, predictor bool) error {
+	dx, dy := r.Dx(), r.Dy()
+	switch m := m.(type) {
+	case *image.Paletted:
+		return encodeGray(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *image.Gray:
+		return encodeGray(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *image.Gray16:
+		return encodeGray16(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *image.NRGBA:
+		return encodeRGBA(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *image.NRGBA64:
+		return encodeRGBA64(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *image.RGBA:
+		return encodeRGBA(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *image.RGBA64:
+		return encodeRGBA64(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, predictor)
+	case *floatimage.GrayF32:
+		return encodeFloat(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, 1, predictor)
+	case *floatimage.RGBAF32:
+		return encodeFloat(w, m.Pix[m.PixOffset(r.Min.X, r.Min.Y):], dx, dy, m.Stride, 4, predictor)
 	}
-
-	_, err := io.WriteString(w, leHeader)
-	if err != nil {
-		return err
+	row := newRow(m.ColorModel(), dx)
+	if isRGBA(row) {
+		return encode(w, m, r, predictor)
 	}
+	// Other color models, such as color.Gray16Model, are converted a row at a
+	// time to the image type for their model.
+	dst := row.(draw.Image)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.Set(x-r.Min.X, 0, m.At(x, y))
+		}
+		if err := encodeRect(w, row, row.Bounds(), predictor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// Compressed data is written into a buffer first, so that we
-	// know the compressed size.
+// encodeTile writes the pixels of m within r, padded with zeroes on the right
+// and bottom to a tile that is tw by th pixels.
+func encodeTile(w io.Writer, m image.Image, r image.Rectangle, tw, th, bytesPerPixel int, predictor bool) error {
+	if predictor {
+		// The floating point predictor works on the tile's whole rows, so
+		// they are padded before encoding.
+		switch m := m.(type) {
+		case *floatimage.GrayF32:
+			t := floatimage.NewGrayF32(image.Rect(0, 0, tw, th))
+			for y := 0; y < r.Dy(); y++ {
+				copy(t.Pix[y*t.Stride:], m.Pix[m.PixOffset(r.Min.X, r.Min.Y+y):][:r.Dx()])
+			}
+			return encodeRect(w, t, t.Rect, predictor)
+		case *floatimage.RGBAF32:
+			t := floatimage.NewRGBAF32(image.Rect(0, 0, tw, th))
+			for y := 0; y < r.Dy(); y++ {
+				copy(t.Pix[y*t.Stride:], m.Pix[m.PixOffset(r.Min.X, r.Min.Y+y):][:4*r.Dx()])
+			}
+			return encodeRect(w, t, t.Rect, predictor)
+		}
+	}
 	var buf bytes.Buffer
-	// dst holds the destination for the pixel data of the image --
-	// either w or a writer to buf.
-	var dst io.Writer
-	// imageLen is the length of the pixel data in bytes.
-	// The offset of the IFD is imageLen + 8 header bytes.
-	var imageLen int
-
-	switch compression {
-	case cNone:
-		dst = w
-		// Write IFD offset before outputting pixel data.
-		switch m.(type) {
-		case *image.Paletted:
-			imageLen = d.X * d.Y * 1
-		case *image.Gray:
-			imageLen = d.X * d.Y * 1
-		case *image.Gray16:
-			imageLen = d.X * d.Y * 2
-		case *image.RGBA64:
-			imageLen = d.X * d.Y * 8
-		case *image.NRGBA64:
-			imageLen = d.X * d.Y * 8
-		default:
-			imageLen = d.X * d.Y * 4
-		}
-		err = binary.Write(w, enc, uint32(imageLen+8))
-		if err != nil {
+	if err := encodeRect(&buf, m, r, predictor); err != nil {
+		return err
+	}
+	rowLen := r.Dx() * bytesPerPixel
+	zeroes := make([]byte, tw*bytesPerPixel)
+	for y := 0; y < th; y++ {
+		if y < r.Dy() {
+			if _, err := w.Write(buf.Next(rowLen)); err != nil {
+				return err
+			}
+			if _, err := w.Write(zeroes[rowLen:]); err != nil {
+				return err
+			}
+		} else if _, err := w.Write(zeroes); err != nil {
 			return err
 		}
-	case cDeflate:
-		dst = zlib.NewWriter(&buf)
 	}
+	return nil
+}
 
-	pr := uint32(prNone)
-	photometricInterpretation := uint32(pRGB)
-	samplesPerPixel := uint32(4)
-	bitsPerSample := []uint32{8, 8, 8, 8}
-	extraSamples := uint32(0)
-	colorMap := []uint32{}
+// format is how the pixels of an image are stored in a TIFF file.
+type format struct {
+	photometricInterpretation uint32
+	samplesPerPixel           uint32
+	bitsPerSample             []uint32
+	bytesPerPixel             int
+	extraSamples              uint32
+	colorMap                  []uint32
+	// float is whether the samples are 32 bit floating point numbers.
+	float bool
+}
 
-	if predictor {
-		pr = prHorizontal
+// newFormat returns the format that Encode uses for m, based on its type.
+func newFormat(m image.Image) *format {
+	f := &format{
+		photometricInterpretation: pRGB,
+		samplesPerPixel:           4,
+		bitsPerSample:             []uint32{8, 8, 8, 8},
+		bytesPerPixel:             4,
 	}
 	switch m := m.(type) {
 	case *image.Paletted:
-		photometricInterpretation = pPaletted
-		samplesPerPixel = 1
-		bitsPerSample = []uint32{8}
-		colorMap = make([]uint32, 256*3)
+		f.photometricInterpretation = pPaletted
+		f.samplesPerPixel = 1
+		f.bitsPerSample = []uint32{8}
+		f.bytesPerPixel = 1
+		f.colorMap = make([]uint32, 256*3)
 		for i := 0; i < 256 && i < len(m.Palette); i++ {
 			r, g, b, _ := m.Palette[i].RGBA()
-			colorMap[i+0*256] = uint32(r)
-			colorMap[i+1*256] = uint32(g)
-			colorMap[i+2*256] = uint32(b)
+			f.colorMap[i+0*256] = uint32(r)
+			f.colorMap[i+1*256] = uint32(g)
+			f.colorMap[i+2*256] = uint32(b)
 		}
-		err = encodeGray(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
 	case *image.Gray:
-		photometricInterpretation = pBlackIsZero
-		samplesPerPixel = 1
-		bitsPerSample = []uint32{8}
-		err = encodeGray(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
+		f.photometricInterpretation = pBlackIsZero
+		f.samplesPerPixel = 1
+		f.bitsPerSample = []uint32{8}
+		f.bytesPerPixel = 1
 	case *image.Gray16:
-		photometricInterpretation = pBlackIsZero
-		samplesPerPixel = 1
-		bitsPerSample = []uint32{16}
-		err = encodeGray16(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
+		f.photometricInterpretation = pBlackIsZero
+		f.samplesPerPixel = 1
+		f.bitsPerSample = []uint32{16}
+		f.bytesPerPixel = 2
 	case *image.NRGBA:
-		extraSamples = 2 // Unassociated alpha.
-		err = encodeRGBA(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
+		f.extraSamples = 2 // Unassociated alpha.
 	case *image.NRGBA64:
-		extraSamples = 2 // Unassociated alpha.
-		bitsPerSample = []uint32{16, 16, 16, 16}
-		err = encodeRGBA64(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
+		f.extraSamples = 2 // Unassociated alpha.
+		f.bitsPerSample = []uint32{16, 16, 16, 16}
+		f.bytesPerPixel = 8
 	case *image.RGBA:
-		extraSamples = 1 // Associated alpha.
-		err = encodeRGBA(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
+		f.extraSamples = 1 // Associated alpha.
 	case *image.RGBA64:
-		extraSamples = 1 // Associated alpha.
-		bitsPerSample = []uint32{16, 16, 16, 16}
-		err = encodeRGBA64(dst, m.Pix, d.X, d.Y, m.Stride, predictor)
+		f.extraSamples = 1 // Associated alpha.
+		f.bitsPerSample = []uint32{16, 16, 16, 16}
+		f.bytesPerPixel = 8
+	case *floatimage.GrayF32:
+		f.photometricInterpretation = pBlackIsZero
+		f.samplesPerPixel = 1
+		f.bitsPerSample = []uint32{32}
+		f.bytesPerPixel = 4
+		f.float = true
+	case *floatimage.RGBAF32:
+		f.extraSamples = 1 // Associated alpha.
+		f.bitsPerSample = []uint32{32, 32, 32, 32}
+		f.bytesPerPixel = 16
+		f.float = true
 	default:
-		extraSamples = 1 // Associated alpha.
-		err = encode(dst, m, predictor)
+		// Other images are written in the format of the image type for
+		// their color model, which is 8 bit RGBA for most models.
+		if row := newRow(m.ColorModel(), 0); !isRGBA(row) {
+			return newFormat(row)
+		}
+		f.extraSamples = 1 // Associated alpha.
 	}
-	if err != nil {
-		return err
+	return f
+}
+
+// ifdEntries returns the IFD entries for an image of size d in the format f,
+// other than those for the locations of its strips or tiles.
+func (f *format) ifdEntries(d image.Point, compression uint32, predictor bool, opt *Options) []ifdEntry {
+	ifd := []ifdEntry{
+		uintEntry(tImageWidth, d.X),
+		uintEntry(tImageLength, d.Y),
+		{tBitsPerSample, dtShort, f.bitsPerSample},
+		{tCompression, dtShort, []uint32{compression}},
+		{tPhotometricInterpretation, dtShort, []uint32{f.photometricInterpretation}},
+		{tSamplesPerPixel, dtShort, []uint32{f.samplesPerPixel}},
+	}
+	var meta *Metadata
+	if opt != nil {
+		meta = opt.Metadata
+	}
+	if !meta.hasResolution() {
+		// The resolution tags are required, so give a bogus value of
+		// 72x72 dpi.
+		ifd = append(ifd,
+			ifdEntry{tXResolution, dtRational, []uint32{72, 1}},
+			ifdEntry{tYResolution, dtRational, []uint32{72, 1}},
+			ifdEntry{tResolutionUnit, dtShort, []uint32{resPerInch}},
+		)
+	}
+	if meta != nil {
+		ifd = append(ifd, meta.ifdEntries()...)
+	}
+	if f.float {
+		ifd = append(ifd, ifdEntry{tSampleFormat, dtShort, []uint32{sfFloat}})
+	}
+	if predictor {
+		pr := uint32(prHorizontal)
+		if f.float {
+			pr = prFloatingPoint
+		}
+		ifd = append(ifd, ifdEntry{tPredictor, dtShort, []uint32{pr}})
+	}
+	switch compression {
+	case cG3:
+		// Every row is coded in one dimension, with unaligned EOL codes.
+		ifd = append(ifd, ifdEntry{tT4Options, dtLong, []uint32{0}})
+	case cG4:
+		// No uncompressed mode is used.
+		ifd = append(ifd, ifdEntry{tT6Options, dtLong, []uint32{0}})
 	}
+	if len(f.colorMap) != 0 {
+		ifd = append(ifd, ifdEntry{tColorMap, dtShort, f.colorMap})
+	}
+	if f.extraSamples > 0 {
+		ifd = append(ifd, ifdEntry{tExtraSamples, dtShort, []uint32{f.extraSamples}})
+	}
+	if opt != nil && opt.GeoTIFF != nil {
+		ifd = append(ifd, opt.GeoTIFF.ifdEntries()...)
+	}
+	return ifd
+}
 
-	if compression != cNone {
-		if err = dst.(io.Closer).Close(); err != nil {
-			return err
+// Options are the encoding parameters.
+type Options struct {
+	// Compression is the type of compression used. With CCITTGroup3 or
+	// CCITTGroup4, the image is written in black and white, with the gray
+	// levels less than half being black, as a single strip. CCITTGroup4 is
+	// the more compact.
+	Compression CompressionType
+	// Predictor determines whether a differencing predictor is used;
+	// if true, instead of each pixel's color, the color difference to the
+	// preceding one is saved.  This improves the compression for certain
+	// types of images and compressors. For example, it works well for
+	// photos with Deflate compression. Floating point samples are
+	// predicted byte by byte instead. It is ignored for uncompressed images.
+	Predictor bool
+	// GeoTIFF, if non-nil, holds georeferencing tags to write, such as those
+	// returned by DecodeGeoTIFF.
+	GeoTIFF *GeoTIFF
+	// Metadata, if non-nil, holds the resolution, orientation, ICC profile
+	// and EXIF tags to write, such as those returned by DecodeMetadata.
+	// Without a resolution, 72 dpi is written.
+	Metadata *Metadata
+	// TileWidth and TileHeight, if non-zero, are the size of the tiles that
+	// the image is split into, instead of a single strip. Both must be
+	// positive multiples of 16, such as 256. Tiles at the right and bottom
+	// edges are padded to the full tile size. Tiled images can be read
+	// piecewise by GIS and prepress tools, but some older readers only
+	// support strips.
+	TileWidth  int
+	TileHeight int
+	// ReducedResolution is whether EncodeAll marks every page after the
+	// first as a reduced-resolution version of the first, as for the
+	// overview levels of a pyramidal TIFF. It is ignored by Encode.
+	ReducedResolution bool
+	// MultiPage is whether every page, including the only page written by
+	// Encode, is marked as a page of a multi-page document, with its page
+	// number, as for the pages of a fax.
+	MultiPage bool
+	// BigTIFF is whether to write a BigTIFF file, whose 64 bit offsets allow
+	// files larger than 4 GB. Writing a larger file without BigTIFF is an
+	// error. BigTIFF files cannot be read by some older readers.
+	BigTIFF bool
+}
+
+// page is an image to be encoded, with its pixel data.
+type page struct {
+	m           image.Image
+	f           *format
+	compression uint32
+	predictor   bool
+	opt         *Options
+	tileWidth   int
+	tileHeight  int
+	bigTIFF     bool
+	// reduced is whether the page is a reduced-resolution version of
+	// another page.
+	reduced bool
+	// number is the page's index in a multi-page document of count pages,
+	// if count is non-zero.
+	number, count int
+	// exif is the page's EXIF IFD, if any, which follows its pixel data.
+	exif []ifdEntry
+
+	// data holds the encoded strips or tiles. It is nil for a single
+	// uncompressed strip, which is written directly from m.
+	data *bytes.Buffer
+	// dataLen is the length in bytes of the pixel data, and offsets and
+	// byteCounts are the location, relative to its start, and length of each
+	// strip or tile.
+	dataLen             int
+	offsets, byteCounts []uint64
+}
+
+// newPage returns the page for m, encoding and compressing its pixel data
+// unless it is a single uncompressed strip.
+func newPage(m image.Image, opt *Options) (*page, error) {
+	p := &page{
+		m:           m,
+		f:           newFormat(m),
+		compression: cNone,
+		opt:         opt,
+	}
+	if opt != nil {
+		p.compression = opt.Compression.specValue()
+		// The predictor field is only used with LZW and Deflate. See page 64
+		// of the spec, and the "Adobe Photoshop TIFF Technical Notes".
+		p.predictor = opt.Predictor && (p.compression == cLZW || p.compression == cDeflate)
+		p.tileWidth, p.tileHeight = opt.TileWidth, opt.TileHeight
+		p.bigTIFF = opt.BigTIFF
+		var err error
+		if p.exif, err = opt.Metadata.exifIFD(); err != nil {
+			return nil, err
 		}
-		imageLen = buf.Len()
-		if err = binary.Write(w, enc, uint32(imageLen+8)); err != nil {
-			return err
+	}
+	switch p.compression {
+	case cNone, cLZW, cDeflate, cG3, cG4:
+	default:
+		return nil, UnsupportedError("compression")
+	}
+	tiled := p.tiled()
+	if tiled && (p.tileWidth <= 0 || p.tileHeight <= 0 || p.tileWidth%16 != 0 || p.tileHeight%16 != 0) {
+		return nil, errors.New("tiff: tile width and height must be positive multiples of 16")
+	}
+
+	if p.compression == cG3 || p.compression == cG4 {
+		if tiled {
+			return nil, UnsupportedError("tiled CCITT compression")
 		}
-		if _, err = buf.WriteTo(w); err != nil {
+		// The pixels are coded in black and white, with rows of single bit
+		// samples in the default FillOrder, most significant bit first.
+		p.f = &format{
+			photometricInterpretation: pWhiteIsZero,
+			samplesPerPixel:           1,
+			bitsPerSample:             []uint32{1},
+		}
+		p.data = new(bytes.Buffer)
+		sf := ccitt.Group3
+		if p.compression == cG4 {
+			sf = ccitt.Group4
+		}
+		if err := ccitt.Encode(p.data, m, ccitt.MSB, sf, nil); err != nil {
+			return nil, err
+		}
+		p.dataLen = p.data.Len()
+		p.offsets, p.byteCounts = []uint64{0}, []uint64{uint64(p.dataLen)}
+		return p, nil
+	}
+
+	bounds := m.Bounds()
+	if p.compression == cNone && !tiled {
+		p.dataLen = bounds.Dx() * bounds.Dy() * p.f.bytesPerPixel
+		p.offsets, p.byteCounts = []uint64{0}, []uint64{uint64(p.dataLen)}
+		return p, nil
+	}
+
+	// Compressed data and tiles are written into a buffer first, so that we
+	// know their sizes.
+	p.data = new(bytes.Buffer)
+	blocks := []image.Rectangle{bounds}
+	if tiled {
+		blocks = blocks[:0]
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += p.tileHeight {
+			for x := bounds.Min.X; x < bounds.Max.X; x += p.tileWidth {
+				blocks = append(blocks, image.Rect(x, y, x+p.tileWidth, y+p.tileHeight).Intersect(bounds))
+			}
+		}
+	}
+	for _, b := range blocks {
+		n := p.data.Len()
+		// dst holds the destination for the pixel data of the block --
+		// either p.data or a compressing writer to it.
+		var dst io.Writer = p.data
+		switch p.compression {
+		case cLZW:
+			dst = lzw.NewWriter(p.data, lzw.MSB, 8)
+		case cDeflate:
+			dst = zlib.NewWriter(p.data)
+		}
+		var err error
+		if tiled {
+			err = encodeTile(dst, m, b, p.tileWidth, p.tileHeight, p.f.bytesPerPixel, p.predictor)
+		} else {
+			err = encodeRect(dst, m, b, p.predictor)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c, ok := dst.(io.Closer); ok {
+			if err = c.Close(); err != nil {
+				return nil, err
+			}
+		}
+		p.offsets = append(p.offsets, uint64(n))
+		p.byteCounts = append(p.byteCounts, uint64(p.data.Len()-n))
+	}
+	p.dataLen = p.data.Len()
+	return p, nil
+}
+
+func (p *page) tiled() bool {
+	return p.tileWidth != 0 || p.tileHeight != 0
+}
+
+// exifOffset returns the offset of p's EXIF IFD, for pixel data at
+// dataOffset in the file. IFDs have to begin on a word boundary (page 15).
+func (p *page) exifOffset(dataOffset int) int {
+	return (dataOffset + p.dataLen + 1) &^ 1
+}
+
+// ifdOffset returns the offset of p's IFD, for pixel data at dataOffset in
+// the file.
+func (p *page) ifdOffset(dataOffset int) int {
+	return p.exifOffset(dataOffset) + subIFDSize(p.exif, p.bigTIFF)
+}
+
+// writeData writes p's pixel data, which is at dataOffset in the file, to w,
+// followed by its EXIF IFD and enough padding for its IFD to follow.
+func (p *page) writeData(w io.Writer, dataOffset int) error {
+	var err error
+	if p.data == nil {
+		err = encodeRect(w, p.m, p.m.Bounds(), false)
+	} else {
+		_, err = p.data.WriteTo(w)
+	}
+	if err != nil {
+		return err
+	}
+	exifOffset := p.exifOffset(dataOffset)
+	if exifOffset != dataOffset+p.dataLen {
+		if _, err := w.Write([]byte{0}); err != nil {
 			return err
 		}
 	}
+	return writeSubIFD(w, exifOffset, p.exif, p.bigTIFF)
+}
 
-	ifd := []ifdEntry{
-		{tImageWidth, dtShort, []uint32{uint32(d.X)}},
-		{tImageLength, dtShort, []uint32{uint32(d.Y)}},
-		{tBitsPerSample, dtShort, bitsPerSample},
-		{tCompression, dtShort, []uint32{compression}},
-		{tPhotometricInterpretation, dtShort, []uint32{photometricInterpretation}},
-		{tStripOffsets, dtLong, []uint32{8}},
-		{tSamplesPerPixel, dtShort, []uint32{samplesPerPixel}},
-		{tRowsPerStrip, dtShort, []uint32{uint32(d.Y)}},
-		{tStripByteCounts, dtLong, []uint32{uint32(imageLen)}},
-		// There is currently no support for storing the image
-		// resolution, so give a bogus value of 72x72 dpi.
-		{tXResolution, dtRational, []uint32{72, 1}},
-		{tYResolution, dtRational, []uint32{72, 1}},
-		{tResolutionUnit, dtShort, []uint32{resPerInch}},
-	}
-	if pr != prNone {
-		ifd = append(ifd, ifdEntry{tPredictor, dtShort, []uint32{pr}})
+// ifd returns p's IFD, for pixel data at dataOffset in the file.
+func (p *page) ifd(dataOffset int) []ifdEntry {
+	offsets := make([]uint64, len(p.offsets))
+	for i, o := range p.offsets {
+		offsets[i] = uint64(dataOffset) + o
+	}
+	d := p.m.Bounds().Size()
+	ifd := p.f.ifdEntries(d, p.compression, p.predictor, p.opt)
+	var subfileType uint32
+	if p.reduced {
+		subfileType |= subfileReducedImage
+	}
+	if p.count != 0 {
+		subfileType |= subfilePage
+		ifd = append(ifd, ifdEntry{tPageNumber, dtShort, []uint32{uint32(p.number), uint32(p.count)}})
+	}
+	if subfileType != 0 {
+		ifd = append(ifd, ifdEntry{tNewSubfileType, dtLong, []uint32{subfileType}})
+	}
+	if p.exif != nil {
+		ifd = append(ifd, offsetsEntry(tExifIFD, []uint64{uint64(p.exifOffset(dataOffset))}, p.bigTIFF))
 	}
-	if len(colorMap) != 0 {
-		ifd = append(ifd, ifdEntry{tColorMap, dtShort, colorMap})
+	if p.tiled() {
+		return append(ifd,
+			ifdEntry{tTileWidth, dtShort, []uint32{uint32(p.tileWidth)}},
+			ifdEntry{tTileLength, dtShort, []uint32{uint32(p.tileHeight)}},
+			offsetsEntry(tTileOffsets, offsets, p.bigTIFF),
+			offsetsEntry(tTileByteCounts, p.byteCounts, p.bigTIFF),
+		)
 	}
-	if extraSamples > 0 {
-		ifd = append(ifd, ifdEntry{tExtraSamples, dtShort, []uint32{extraSamples}})
+	return append(ifd,
+		offsetsEntry(tStripOffsets, offsets, p.bigTIFF),
+		uintEntry(tRowsPerStrip, d.Y),
+		offsetsEntry(tStripByteCounts, p.byteCounts, p.bigTIFF),
+	)
+}
+
+// Encode writes the image m to w. opt determines the options used for
+// encoding, such as the compression type. If opt is nil, an uncompressed
+// image is written.
+//
+// The samples are 8 or 16 bit integers, as for the image types of the
+// standard library, or 32 bit floating point numbers, for the types of the
+// golang.org/x/image/floatimage package. Images of other types are written
+// as the type for their color model, such as 16 bit grayscale for
+// color.Gray16Model, or else as 8 bit RGBA.
+func Encode(w io.Writer, m image.Image, opt *Options) error {
+	return EncodeAll(w, []image.Image{m}, opt)
+}
+
+// EncodeAll writes the images ms to w as the pages of a multi-page TIFF, in
+// order. opt determines the options used for encoding every page, as for
+// Encode. Only one page's compressed pixel data is held in memory at a time.
+func EncodeAll(w io.Writer, ms []image.Image, opt *Options) error {
+	if len(ms) == 0 {
+		return errors.New("tiff: no images to encode")
+	}
+	p, err := newPage(ms[0], opt)
+	if err != nil {
+		return err
+	}
+	bigTIFF := opt != nil && opt.BigTIFF
+	multiPage := opt != nil && opt.MultiPage
+	if multiPage {
+		p.count = len(ms)
 	}
 
-	return writeIFD(w, imageLen+8, ifd)
+	// Each page's pixel data is followed by its IFD.
+	dataOffset := headerLen(bigTIFF)
+	ifdOffset := p.ifdOffset(dataOffset)
+	if err := writeHeader(w, ifdOffset, bigTIFF); err != nil {
+		return err
+	}
+	for i := range ms {
+		ifd := p.ifd(dataOffset)
+		if !bigTIFF && uint64(ifdOffset+ifdSize(ifd, false)) > math.MaxUint32 {
+			return errTooLarge
+		}
+		if err := p.writeData(w, dataOffset); err != nil {
+			return err
+		}
+
+		// The next page's pixel data is encoded before this page's IFD is
+		// written, as the IFD holds the offset of the next page's IFD.
+		var next *page
+		nextIFDOffset := 0
+		if i+1 < len(ms) {
+			if next, err = newPage(ms[i+1], opt); err != nil {
+				return err
+			}
+			next.reduced = opt != nil && opt.ReducedResolution
+			if multiPage {
+				next.number, next.count = i+1, len(ms)
+			}
+			dataOffset = ifdOffset + ifdSize(ifd, bigTIFF)
+			nextIFDOffset = next.ifdOffset(dataOffset)
+		}
+		if err := writeIFD(w, ifdOffset, ifd, nextIFDOffset, bigTIFF); err != nil {
+			return err
+		}
+		p, ifdOffset = next, nextIFDOffset
+	}
+	return nil
 }
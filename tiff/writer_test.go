@@ -6,10 +6,15 @@ package tiff
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"image"
 	"io/ioutil"
 	"os"
 	"testing"
+
+	"golang.org/x/image/bitmap"
+	"golang.org/x/image/floatimage"
 )
 
 var roundtripTests = []struct {
@@ -25,6 +30,21 @@ var roundtripTests = []struct {
 	{"video-001.tiff", &Options{Predictor: true}},
 	{"video-001.tiff", &Options{Compression: Deflate}},
 	{"video-001.tiff", &Options{Predictor: true, Compression: Deflate}},
+	{"video-001.tiff", &Options{Compression: LZW}},
+	{"video-001.tiff", &Options{Predictor: true, Compression: LZW}},
+	{"video-001-16bit.tiff", &Options{Predictor: true, Compression: LZW}},
+	{"video-001-gray.tiff", &Options{Compression: LZW}},
+	{"video-001-gray-16bit.tiff", &Options{Predictor: true, Compression: Deflate}},
+	{"video-001-paletted.tiff", &Options{Predictor: true, Compression: LZW}},
+	{"bw-packbits.tiff", &Options{Compression: LZW}},
+	{"video-001.tiff", &Options{TileWidth: 64, TileHeight: 32}},
+	{"video-001.tiff", &Options{Compression: LZW, TileWidth: 32, TileHeight: 48}},
+	{"video-001-16bit.tiff", &Options{Predictor: true, Compression: Deflate, TileWidth: 256, TileHeight: 256}},
+	{"video-001-paletted.tiff", &Options{Compression: Deflate, TileWidth: 16, TileHeight: 16}},
+	{"video-001.tiff", &Options{BigTIFF: true}},
+	{"video-001-gray.tiff", &Options{BigTIFF: true, Compression: LZW}},
+	{"video-001-16bit.tiff", &Options{BigTIFF: true, Compression: Deflate, TileWidth: 64, TileHeight: 64}},
+	{"bw-packbits.tiff", &Options{BigTIFF: true}},
 }
 
 func openImage(filename string) (image.Image, error) {
@@ -75,6 +95,391 @@ func TestRoundtrip2(t *testing.T) {
 	compare(t, m0, m1)
 }
 
+func TestEncodeOptionErrors(t *testing.T) {
+	m := image.NewGray(image.Rect(0, 0, 10, 10))
+	for _, opts := range []*Options{
+		{Compression: CCITTGroup3, TileWidth: 16, TileHeight: 16},
+		{Compression: CCITTGroup4, TileWidth: 16, TileHeight: 16},
+		{TileWidth: 16},
+		{TileWidth: 16, TileHeight: 24},
+		{TileWidth: -16, TileHeight: 16},
+	} {
+		if err := Encode(ioutil.Discard, m, opts); err == nil {
+			t.Errorf("%+v: got nil error, want non-nil", opts)
+		}
+	}
+}
+
+func TestEncodeCCITT(t *testing.T) {
+	for _, tc := range []struct {
+		compression CompressionType
+		optionsTag  uint16
+	}{
+		{CCITTGroup3, tT4Options},
+		{CCITTGroup4, tT6Options},
+	} {
+		testEncodeCCITT(t, tc.compression, tc.optionsTag)
+	}
+}
+
+// TestEncodeBitmap tests that a *bitmap.Image, which has no TIFF format of its
+// own, is written in 8 bit gray unless it is CCITT compressed.
+func TestEncodeBitmap(t *testing.T) {
+	m, err := load("bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm := bitmap.FromImage(m)
+	for _, compression := range []CompressionType{Uncompressed, CCITTGroup4} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, bm, &Options{Compression: compression}); err != nil {
+			t.Fatalf("compression %d: %v", compression, err)
+		}
+		got, err := DecodeWithOptions(bytes.NewReader(buf.Bytes()), &DecodeOptions{Bilevel: true})
+		if err != nil {
+			t.Fatalf("compression %d: %v", compression, err)
+		}
+		compare(t, m, got)
+	}
+}
+
+func testEncodeCCITT(t *testing.T, compression CompressionType, optionsTag uint16) {
+	t.Helper()
+	m, err := load("bw-gopher.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	opt := &Options{Compression: compression, MultiPage: true}
+	if err := EncodeAll(&buf, []image.Image{m, m}, opt); err != nil {
+		t.Fatal(err)
+	}
+	pages, err := DecodeAll(bytes.NewReader(buf.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(pages))
+	}
+	for _, p := range pages {
+		compare(t, m, p)
+	}
+
+	dirs, err := Validate(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, dir := range dirs {
+		found := map[uint16]bool{}
+		for _, e := range dir.Entries {
+			found[e.Tag] = true
+		}
+		for _, tag := range []uint16{tNewSubfileType, tPageNumber, optionsTag} {
+			if !found[tag] {
+				t.Errorf("page %d: no tag %d", i, tag)
+			}
+		}
+	}
+}
+
+// TestLZWRoundtrip tests encoding an image that is large and noisy enough for
+// the LZW encoder to run out of codes and send clear codes.
+func TestLZWRoundtrip(t *testing.T) {
+	m0 := image.NewGray(image.Rect(0, 0, 300, 200))
+	x := uint32(1)
+	for i := range m0.Pix {
+		x = x*1664525 + 1013904223
+		m0.Pix[i] = uint8(x>>24) & 0x3f
+	}
+	out := new(bytes.Buffer)
+	if err := Encode(out, m0, &Options{Compression: LZW}); err != nil {
+		t.Fatal(err)
+	}
+	m1, err := Decode(&buffer{buf: out.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	compare(t, m0, m1)
+}
+
+func TestEncodeAll(t *testing.T) {
+	filenames := []string{
+		"video-001.tiff",
+		"video-001-gray.tiff",
+		"video-001-paletted.tiff",
+		"bw-packbits.tiff",
+		"video-001-16bit.tiff",
+	}
+	var ms []image.Image
+	for _, filename := range filenames {
+		m, err := openImage(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ms = append(ms, m)
+	}
+	for _, opts := range []*Options{
+		nil,
+		{Compression: LZW, Predictor: true},
+		{Compression: Deflate, TileWidth: 32, TileHeight: 16},
+		{Compression: LZW, BigTIFF: true},
+	} {
+		out := new(bytes.Buffer)
+		if err := EncodeAll(out, ms, opts); err != nil {
+			t.Fatalf("%v: EncodeAll: %v", opts, err)
+		}
+		got, err := DecodeAll(bytes.NewReader(out.Bytes()), nil)
+		if err != nil {
+			t.Fatalf("%v: DecodeAll: %v", opts, err)
+		}
+		if len(got) != len(ms) {
+			t.Fatalf("%v: got %d pages, want %d", opts, len(got), len(ms))
+		}
+		for i := range ms {
+			compare(t, ms[i], got[i])
+		}
+
+		cfgs, err := DecodeConfigAll(bytes.NewReader(out.Bytes()), nil)
+		if err != nil {
+			t.Fatalf("%v: DecodeConfigAll: %v", opts, err)
+		}
+		if len(cfgs) != len(ms) {
+			t.Fatalf("%v: got %d configs, want %d", opts, len(cfgs), len(ms))
+		}
+		for i, cfg := range cfgs {
+			b := ms[i].Bounds()
+			if cfg.Width != b.Dx() || cfg.Height != b.Dy() || fmt.Sprintf("%T", cfg.ColorModel) != fmt.Sprintf("%T", got[i].ColorModel()) {
+				t.Errorf("%v: page %d: config does not match the decoded image", opts, i)
+			}
+		}
+
+		// Decode returns the first page.
+		m, err := Decode(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatalf("%v: Decode: %v", opts, err)
+		}
+		compare(t, ms[0], m)
+	}
+
+	if err := EncodeAll(new(bytes.Buffer), nil, nil); err == nil {
+		t.Error("no images: got nil error")
+	}
+}
+
+func TestEncodeBigTIFF(t *testing.T) {
+	m0, err := openImage("video-001.tiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := EncodeAll(out, []image.Image{m0, m0}, &Options{BigTIFF: true, TileWidth: 64, TileHeight: 64}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(out.Bytes()[:8]), "II\x2b\x00\x08\x00\x00\x00"; got != want {
+		t.Fatalf("header: got %q, want %q", got, want)
+	}
+	dirs, err := Validate(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(dirs) != 2 {
+		t.Fatalf("got %d IFDs, want 2", len(dirs))
+	}
+	for _, e := range dirs[0].Entries {
+		if e.Tag == tTileOffsets && e.Type != dtLong8 {
+			t.Errorf("TileOffsets: got type %d, want Long8", e.Type)
+		}
+	}
+	ms, err := DecodeAll(bytes.NewReader(out.Bytes()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m1 := range ms {
+		compare(t, m0, m1)
+	}
+}
+
+// TestDecodeBigEndianBigTIFF tests decoding a hand-made big-endian BigTIFF
+// file, with 8 byte entry values.
+func TestDecodeBigEndianBigTIFF(t *testing.T) {
+	be := binary.BigEndian
+	b := []byte("MM\x00\x2b\x00\x08\x00\x00")
+	b = append(b, make([]byte, 8)...)
+	be.PutUint64(b[8:], 18)
+	// The pixel data.
+	b = append(b, 0x10, 0x20)
+	entries := []struct {
+		tag, datatype uint16
+		value         uint64
+	}{
+		{tImageWidth, dtShort, 2},
+		{tImageLength, dtShort, 1},
+		{tBitsPerSample, dtShort, 8},
+		{tPhotometricInterpretation, dtShort, pBlackIsZero},
+		{tStripOffsets, dtLong8, 16},
+		{tStripByteCounts, dtLong8, 2},
+	}
+	b = append(b, make([]byte, 8)...)
+	be.PutUint64(b[len(b)-8:], uint64(len(entries)))
+	for _, e := range entries {
+		p := make([]byte, bigIFDLen)
+		be.PutUint16(p[0:2], e.tag)
+		be.PutUint16(p[2:4], e.datatype)
+		be.PutUint64(p[4:12], 1)
+		if e.datatype == dtShort {
+			be.PutUint16(p[12:14], uint16(e.value))
+		} else {
+			be.PutUint64(p[12:20], e.value)
+		}
+		b = append(b, p...)
+	}
+	// The offset of the next IFD.
+	b = append(b, make([]byte, 8)...)
+
+	m, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &image.Gray{Pix: []uint8{0x10, 0x20}, Stride: 2, Rect: image.Rect(0, 0, 2, 1)}
+	compare(t, want, m)
+	if _, err := Validate(bytes.NewReader(b)); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestEncodeFloat(t *testing.T) {
+	gray := floatimage.NewGrayF32(image.Rect(2, 3, 21, 20))
+	for i := range gray.Pix {
+		gray.Pix[i] = float32(i)/100 - 1
+	}
+	rgba := floatimage.NewRGBAF32(image.Rect(0, 0, 19, 17))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = float32(i%7) * 0.75
+	}
+	for _, m := range []image.Image{gray, rgba} {
+		for _, opt := range []*Options{
+			nil,
+			{Compression: Deflate},
+			{Compression: Deflate, Predictor: true},
+			{Compression: LZW, Predictor: true, TileWidth: 16, TileHeight: 16},
+		} {
+			out := new(bytes.Buffer)
+			if err := Encode(out, m, opt); err != nil {
+				t.Fatalf("%T %+v: Encode: %v", m, opt, err)
+			}
+			got, err := Decode(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatalf("%T %+v: Decode: %v", m, opt, err)
+			}
+			if !equalFloat(m, got) {
+				t.Errorf("%T %+v: decoded image differs", m, opt)
+			}
+		}
+	}
+}
+
+// equalFloat returns whether m0 and m1 are floatimage images of the same
+// type, size and samples.
+func equalFloat(m0, m1 image.Image) bool {
+	if m0.Bounds().Size() != m1.Bounds().Size() {
+		return false
+	}
+	b0, b1 := m0.Bounds(), m1.Bounds()
+	for y := 0; y < b0.Dy(); y++ {
+		for x := 0; x < b0.Dx(); x++ {
+			switch m0 := m0.(type) {
+			case *floatimage.GrayF32:
+				m1, ok := m1.(*floatimage.GrayF32)
+				if !ok || m0.GrayF32At(b0.Min.X+x, b0.Min.Y+y) != m1.GrayF32At(b1.Min.X+x, b1.Min.Y+y) {
+					return false
+				}
+			case *floatimage.RGBAF32:
+				m1, ok := m1.(*floatimage.RGBAF32)
+				if !ok || m0.RGBAF32At(b0.Min.X+x, b0.Min.Y+y) != m1.RGBAF32At(b1.Min.X+x, b1.Min.Y+y) {
+					return false
+				}
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// gray16Image is an image whose color model is color.Gray16Model, but which
+// is not an *image.Gray16.
+type gray16Image struct {
+	*image.Gray16
+}
+
+func TestEncodeColorModel(t *testing.T) {
+	m := image.NewGray16(image.Rect(0, 0, 7, 5))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(i * 37)
+	}
+	for _, opt := range []*Options{nil, {Compression: LZW, Predictor: true, TileWidth: 16, TileHeight: 16}} {
+		out := new(bytes.Buffer)
+		if err := Encode(out, gray16Image{m}, opt); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Decode(bytes.NewReader(out.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := got.(*image.Gray16); !ok {
+			t.Errorf("%+v: got %T, want *image.Gray16", opt, got)
+		}
+		compare(t, m, got)
+	}
+}
+
+// TestDecodeFloatingPointPredictor tests decoding a hand-made big-endian
+// file with the floating point predictor.
+func TestDecodeFloatingPointPredictor(t *testing.T) {
+	be := binary.BigEndian
+	b := []byte("MM\x00\x2a\x00\x00\x00\x10")
+	// The pixel data, of the samples 1 (0x3f800000) and 2 (0x40000000),
+	// split into byte planes and then differenced.
+	b = append(b, 0x3f, 0x01, 0x40, 0x80, 0, 0, 0, 0)
+	entries := []struct {
+		tag, datatype uint16
+		value         uint32
+	}{
+		{tImageWidth, dtShort, 2},
+		{tImageLength, dtShort, 1},
+		{tBitsPerSample, dtShort, 32},
+		{tPhotometricInterpretation, dtShort, pBlackIsZero},
+		{tStripOffsets, dtLong, 8},
+		{tStripByteCounts, dtLong, 8},
+		{tPredictor, dtShort, prFloatingPoint},
+		{tSampleFormat, dtShort, sfFloat},
+	}
+	b = append(b, 0, byte(len(entries)))
+	for _, e := range entries {
+		p := make([]byte, ifdLen)
+		be.PutUint16(p[0:2], e.tag)
+		be.PutUint16(p[2:4], e.datatype)
+		be.PutUint32(p[4:8], 1)
+		if e.datatype == dtShort {
+			be.PutUint16(p[8:10], uint16(e.value))
+		} else {
+			be.PutUint32(p[8:12], e.value)
+		}
+		b = append(b, p...)
+	}
+	// The offset of the next IFD.
+	b = append(b, 0, 0, 0, 0)
+
+	m, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &floatimage.GrayF32{Pix: []float32{1, 2}, Stride: 2, Rect: image.Rect(0, 0, 2, 1)}
+	if !equalFloat(want, m) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
+
 func benchmarkEncode(b *testing.B, name string, pixelSize int) {
 	b.Helper()
 	img, err := openImage(name)
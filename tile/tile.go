@@ -0,0 +1,291 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tile implements very large images that are split into tiles, which
+// are fetched on demand and cached, such as gigapixel images stored as
+// separate tiles on disk or on a tile server.
+//
+// An *Image is an image.Image, so that the golang.org/x/image/draw Scalers
+// and Transformers can draw from it while only the tiles under the src
+// pixels that they read are fetched. A Pyramid adds overviews, each half the
+// width and height of the one before, such as those written by the
+// golang.org/x/image/pyramid package, so that scaling down reads the level
+// nearest to the dst resolution instead of every full-resolution pixel.
+package tile // import "golang.org/x/image/tile"
+
+import (
+	"container/list"
+	"image"
+	"image/color"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// DefaultCacheSize is the number of tiles cached when Options.CacheSize is
+// zero.
+const DefaultCacheSize = 64
+
+// FetchFunc returns the tile at column tx and row ty of an Image, counting
+// from zero at the top-left tile. Its pixels are mapped to the Image's
+// starting at the tile's Bounds().Min, so it may be decoded at the origin.
+// A tile smaller than the tile size, such as at the right and bottom edges,
+// is transparent beyond its bounds.
+//
+// A FetchFunc may be called concurrently for different tiles, and is called
+// at most once for each tile while it is cached.
+type FetchFunc func(tx, ty int) (image.Image, error)
+
+// Options are optional parameters to New and NewPyramid. A nil *Options
+// means to use the default (zero) values of each field.
+type Options struct {
+	// CacheSize is the maximum number of tiles that are kept in memory. The
+	// least recently used tile is evicted to make room for another. Zero
+	// means DefaultCacheSize.
+	//
+	// It should be at least the number of tiles across sr that a Scaler or
+	// Transformer reads from at once, such as the number of tiles in one row
+	// of tiles, or else tiles may be fetched more than once.
+	CacheSize int
+
+	// ColorModel is the color model of the Image. Nil means
+	// color.RGBA64Model.
+	ColorModel color.Model
+}
+
+// Image is an image.Image whose pixels are fetched a tile at a time. It is
+// safe to use concurrently, such as by a draw Scaler with
+// draw.Options.Concurrency.
+//
+// The pixels of tiles that could not be fetched are transparent, and the
+// first error is returned by the Err method.
+type Image struct {
+	r     image.Rectangle
+	tw    int
+	th    int
+	model color.Model
+	fetch FetchFunc
+	cache *cache
+	level int
+}
+
+// New returns an Image with the bounds r, split into tiles of tileSize
+// pixels, whose top-left tile's top-left pixel is r.Min. The tiles are
+// fetched by calling fetch.
+func New(r image.Rectangle, tileSize image.Point, fetch FetchFunc, opts *Options) *Image {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	return newImage(r, tileSize, fetch, newCache(o.CacheSize), 0, o.ColorModel)
+}
+
+func newImage(r image.Rectangle, tileSize image.Point, fetch FetchFunc, c *cache, level int, model color.Model) *Image {
+	if model == nil {
+		model = color.RGBA64Model
+	}
+	if tileSize.X < 1 {
+		tileSize.X = 1
+	}
+	if tileSize.Y < 1 {
+		tileSize.Y = 1
+	}
+	return &Image{
+		r:     r,
+		tw:    tileSize.X,
+		th:    tileSize.Y,
+		model: model,
+		fetch: fetch,
+		cache: c,
+		level: level,
+	}
+}
+
+// ColorModel implements the image.Image interface.
+func (m *Image) ColorModel() color.Model { return m.model }
+
+// Bounds implements the image.Image interface.
+func (m *Image) Bounds() image.Rectangle { return m.r }
+
+// At implements the image.Image interface.
+func (m *Image) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(m.r)) {
+		return m.model.Convert(color.Transparent)
+	}
+	x -= m.r.Min.X
+	y -= m.r.Min.Y
+	t, _ := m.Tile(x/m.tw, y/m.th)
+	if t == nil {
+		return m.model.Convert(color.Transparent)
+	}
+	b := t.Bounds()
+	return t.At(b.Min.X+x%m.tw, b.Min.Y+y%m.th)
+}
+
+// TileSize returns the width and height of m's tiles.
+func (m *Image) TileSize() image.Point { return image.Point{m.tw, m.th} }
+
+// TileBounds returns the pixels of m in the tile at column tx and row ty.
+func (m *Image) TileBounds(tx, ty int) image.Rectangle {
+	min := m.r.Min.Add(image.Point{tx * m.tw, ty * m.th})
+	return image.Rectangle{min, min.Add(image.Point{m.tw, m.th})}.Intersect(m.r)
+}
+
+// Tile returns the tile at column tx and row ty, fetching it if it is not
+// cached.
+func (m *Image) Tile(tx, ty int) (image.Image, error) {
+	return m.cache.get(key{m.level, tx, ty}, func() (image.Image, error) {
+		return m.fetch(tx, ty)
+	})
+}
+
+// Err returns the first error returned by fetching one of m's tiles, or nil.
+func (m *Image) Err() error {
+	return m.cache.error()
+}
+
+// Pyramid is a tiled image and its overviews, each half the width and height
+// of the one before, rounding up. All of the levels share one tile cache.
+type Pyramid struct {
+	levels []*Image
+}
+
+// NewPyramid returns a Pyramid of n levels, the first of which is size
+// pixels, with its top-left pixel at the origin, like each of the others.
+// The tiles are fetched by calling fetch with the level, from zero for the
+// full-resolution image, and the tile's column and row in that level.
+func NewPyramid(size image.Point, n int, tileSize image.Point, fetch func(level, tx, ty int) (image.Image, error), opts *Options) *Pyramid {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	p := &Pyramid{}
+	c := newCache(o.CacheSize)
+	for i := 0; i < n; i++ {
+		level := i
+		p.levels = append(p.levels, newImage(image.Rectangle{Max: size}, tileSize, func(tx, ty int) (image.Image, error) {
+			return fetch(level, tx, ty)
+		}, c, level, o.ColorModel))
+		size = image.Point{(size.X + 1) / 2, (size.Y + 1) / 2}
+	}
+	return p
+}
+
+// Len returns the number of levels of p.
+func (p *Pyramid) Len() int { return len(p.levels) }
+
+// Level returns the level i of p, where level 0 is the full-resolution image.
+func (p *Pyramid) Level(i int) *Image { return p.levels[i] }
+
+// LevelFor returns the smallest level of p that has at least scale times the
+// width and height of the full-resolution image, such as the level to scale
+// down from to display the image at that scale. scale is at most 1.
+func (p *Pyramid) LevelFor(scale float64) int {
+	i := 0
+	for i+1 < len(p.levels) && scale*float64(int(1)<<uint(i+1)) <= 1 {
+		i++
+	}
+	return i
+}
+
+// Scale scales the part of the full-resolution image defined by sr, such as
+// a viewer's viewport, to the part of dst defined by dr with s, reading from
+// the level that LevelFor chooses for the larger of the horizontal and
+// vertical scales, so that neither is scaled up from an overview.
+func (p *Pyramid) Scale(s draw.Scaler, dst draw.Image, dr, sr image.Rectangle, op draw.Op, opts *draw.Options) {
+	if dr.Empty() || sr.Empty() || len(p.levels) == 0 {
+		return
+	}
+	scale := float64(dr.Dx()) / float64(sr.Dx())
+	if sy := float64(dr.Dy()) / float64(sr.Dy()); scale < sy {
+		scale = sy
+	}
+	i := p.LevelFor(scale)
+	f := 1 << uint(i)
+	// Map sr to the level, rounding outwards.
+	lr := image.Rect(
+		floorDiv(sr.Min.X, f), floorDiv(sr.Min.Y, f),
+		-floorDiv(-sr.Max.X, f), -floorDiv(-sr.Max.Y, f),
+	)
+	s.Scale(dst, dr, p.levels[i], lr, op, opts)
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// key identifies a tile in a cache.
+type key struct {
+	level, tx, ty int
+}
+
+// entry is a cached tile. done is closed when m and err are set.
+type entry struct {
+	k    key
+	m    image.Image
+	err  error
+	done chan struct{}
+	elem *list.Element
+}
+
+// cache is an LRU cache of tiles.
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[key]*entry
+	lru     list.List
+	err     error
+}
+
+func newCache(size int) *cache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &cache{
+		size:    size,
+		entries: map[key]*entry{},
+	}
+}
+
+// get returns the tile k, calling fetch if it is not cached. Concurrent calls
+// for the same tile wait for the one fetch.
+func (c *cache) get(k key, fetch func() (image.Image, error)) (image.Image, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[k]; ok {
+		c.lru.MoveToFront(e.elem)
+		c.mu.Unlock()
+		<-e.done
+		return e.m, e.err
+	}
+	e := &entry{k: k, done: make(chan struct{})}
+	e.elem = c.lru.PushFront(e)
+	c.entries[k] = e
+	for c.lru.Len() > c.size {
+		old := c.lru.Remove(c.lru.Back()).(*entry)
+		delete(c.entries, old.k)
+	}
+	c.mu.Unlock()
+
+	e.m, e.err = fetch()
+	if e.err != nil {
+		e.m = nil
+		c.mu.Lock()
+		if c.err == nil {
+			c.err = e.err
+		}
+		c.mu.Unlock()
+	}
+	close(e.done)
+	return e.m, e.err
+}
+
+func (c *cache) error() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
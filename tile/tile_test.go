@@ -0,0 +1,162 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tile
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/pyramid"
+	"golang.org/x/image/testsupport"
+)
+
+// tiler serves the tiles of m, decoded at the origin, and counts the fetches.
+type tiler struct {
+	m        image.Image
+	size     int
+	mu       sync.Mutex
+	fetches  map[image.Point]int
+	failures map[image.Point]bool
+}
+
+func (t *tiler) fetch(tx, ty int) (image.Image, error) {
+	t.mu.Lock()
+	if t.fetches == nil {
+		t.fetches = map[image.Point]int{}
+	}
+	t.fetches[image.Point{tx, ty}]++
+	fail := t.failures[image.Point{tx, ty}]
+	t.mu.Unlock()
+	if fail {
+		return nil, errors.New("no such tile")
+	}
+	b := t.m.Bounds()
+	r := image.Rect(tx*t.size, ty*t.size, (tx+1)*t.size, (ty+1)*t.size).Add(b.Min).Intersect(b)
+	dst := image.NewRGBA(image.Rectangle{Max: r.Size()})
+	draw.Copy(dst, image.Point{}, t.m, r, draw.Src, nil)
+	return dst, nil
+}
+
+func TestImage(t *testing.T) {
+	src := testsupport.ZonePlate(image.Rect(5, 10, 105, 90))
+	tl := &tiler{m: src, size: 16}
+	m := New(src.Bounds(), image.Point{16, 16}, tl.fetch, &Options{CacheSize: 4})
+	if m.Bounds() != src.Bounds() {
+		t.Fatalf("Bounds: got %v, want %v", m.Bounds(), src.Bounds())
+	}
+	// Read the pixels row by row, so that each tile in a row of tiles is
+	// fetched once, but there are 7 tiles in a row and only 4 are cached.
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+			r0, g0, b0, a0 := m.At(x, y).RGBA()
+			r1, g1, b1, a1 := src.At(x, y).RGBA()
+			if r0 != r1 || g0 != g1 || b0 != b1 || a0 != a1 {
+				t.Fatalf("(%d, %d): got %v, want %v", x, y, m.At(x, y), src.At(x, y))
+			}
+		}
+	}
+	if got, want := len(tl.fetches), 7*5; got != want {
+		t.Errorf("got %d distinct tiles fetched, want %d", got, want)
+	}
+	if got, want := tl.fetches[image.Point{0, 0}], 16; got != want {
+		t.Errorf("tile (0, 0): got %d fetches, want %d", got, want)
+	}
+	if got := m.TileBounds(6, 4); got != image.Rect(101, 74, 105, 90) {
+		t.Errorf("TileBounds(6, 4): got %v", got)
+	}
+	if err := m.Err(); err != nil {
+		t.Errorf("Err: %v", err)
+	}
+
+	// With a large enough cache, every tile is fetched once, even when
+	// scaling concurrently.
+	tl = &tiler{m: src, size: 16}
+	m = New(src.Bounds(), image.Point{16, 16}, tl.fetch, &Options{ColorModel: color.GrayModel})
+	dst := image.NewGray(image.Rect(0, 0, 50, 40))
+	draw.CatmullRom.Scale(dst, dst.Rect, m, m.Bounds(), draw.Src, &draw.Options{Concurrency: 4})
+	want := image.NewGray(dst.Rect)
+	draw.CatmullRom.Scale(want, want.Rect, src, src.Rect, draw.Src, nil)
+	for i := range dst.Pix {
+		if d := int(dst.Pix[i]) - int(want.Pix[i]); d < -1 || d > 1 {
+			t.Fatalf("Scale: Pix[%d]: got %d, want %d", i, dst.Pix[i], want.Pix[i])
+		}
+	}
+	for p, n := range tl.fetches {
+		if n != 1 {
+			t.Errorf("Scale: tile %v: got %d fetches, want 1", p, n)
+		}
+	}
+}
+
+func TestImageError(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range src.Pix {
+		src.Pix[i] = 0xff
+	}
+	tl := &tiler{m: src, size: 16, failures: map[image.Point]bool{{1, 0}: true}}
+	m := New(src.Bounds(), image.Point{16, 16}, tl.fetch, nil)
+	if _, _, _, a := m.At(0, 0).RGBA(); a != 0xffff {
+		t.Errorf("good tile: got alpha %#x, want 0xffff", a)
+	}
+	if _, _, _, a := m.At(20, 0).RGBA(); a != 0 {
+		t.Errorf("bad tile: got alpha %#x, want 0", a)
+	}
+	m.At(21, 0)
+	if err := m.Err(); err == nil {
+		t.Error("Err: got nil, want an error")
+	}
+	if n := tl.fetches[image.Point{1, 0}]; n != 1 {
+		t.Errorf("bad tile: got %d fetches, want 1", n)
+	}
+}
+
+func TestPyramid(t *testing.T) {
+	levels := pyramid.Levels(testsupport.ZonePlate(image.Rect(0, 0, 200, 120)), 16)
+	tilers := make([]*tiler, len(levels))
+	for i, l := range levels {
+		tilers[i] = &tiler{m: l, size: 8}
+	}
+	p := NewPyramid(image.Point{200, 120}, len(levels), image.Point{8, 8}, func(level, tx, ty int) (image.Image, error) {
+		return tilers[level].fetch(tx, ty)
+	}, nil)
+	if p.Len() != len(levels) {
+		t.Fatalf("Len: got %d, want %d", p.Len(), len(levels))
+	}
+	for i, l := range levels {
+		if got, want := p.Level(i).Bounds(), l.Bounds(); got != want {
+			t.Errorf("level %d: got bounds %v, want %v", i, got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		scale float64
+		want  int
+	}{
+		{2, 0}, {1, 0}, {0.6, 0}, {0.5, 1}, {0.3, 1}, {0.25, 2}, {0.01, len(levels) - 1},
+	} {
+		if got := p.LevelFor(tc.scale); got != tc.want {
+			t.Errorf("LevelFor(%v): got %d, want %d", tc.scale, got, tc.want)
+		}
+	}
+
+	// Scaling the right half down by 4 reads only the right half of level 2,
+	// which is 50 pixels wide.
+	dst := image.NewRGBA(image.Rect(0, 0, 25, 30))
+	p.Scale(draw.ApproxBiLinear, dst, dst.Rect, image.Rect(100, 0, 200, 120), draw.Src, nil)
+	for i, tl := range tilers {
+		if i != 2 && len(tl.fetches) != 0 {
+			t.Errorf("level %d: got %d tiles fetched, want 0", i, len(tl.fetches))
+		}
+	}
+	for tp := range tilers[2].fetches {
+		if tp.X < 3 {
+			t.Errorf("level 2: tile %v, left of the scaled pixels, was fetched", tp)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"image"
+)
+
+// ClipTo clips z's subsequent Draw and Mask calls to the coverage of the
+// vector paths previously added to other, which is itself clipped if other
+// has a clip. Pixels outside of other's bounds are clipped out. Clips
+// accumulate, so that calling ClipTo again, or ClipToMask, intersects the
+// clips, as for nested SVG clipPath or PDF clipping path elements. A nil
+// other removes z's clip.
+//
+// The coverage is copied when ClipTo is called, so that other can then be
+// Reset and re-used. The clip applies to all of z's paths, whether added
+// before or after ClipTo is called, but it is removed when z is Reset.
+func (z *Rasterizer) ClipTo(other *Rasterizer) {
+	if other == nil {
+		z.clip = nil
+		return
+	}
+	other.accumulateMask()
+	if other == z {
+		// z's coverage is already clipped by z's clip.
+		z.clip = append(z.clip[:0], z.bufMask...)
+		return
+	}
+	z.clipTo(func(x, y int) uint32 {
+		if x >= other.size.X || y >= other.size.Y {
+			return 0
+		}
+		return other.bufMask[y*other.size.X+x]
+	})
+}
+
+// ClipToMask clips z's subsequent Draw and Mask calls to mask, whose alpha at
+// mp is the coverage of z's pixel (0, 0), as for the standard library's
+// image/draw.DrawMask function. Pixels outside of mask's bounds are clipped
+// out. Clips accumulate, as per ClipTo.
+//
+// A typical mask is a Rasterizer's Mask, which can be cached.
+func (z *Rasterizer) ClipToMask(mask image.Image, mp image.Point) {
+	b := mask.Bounds()
+	m, _ := mask.(*image.Alpha)
+	z.clipTo(func(x, y int) uint32 {
+		p := image.Point{mp.X + x, mp.Y + y}
+		if !p.In(b) {
+			return 0
+		}
+		if m != nil {
+			return uint32(m.Pix[m.PixOffset(p.X, p.Y)]) * 0x101
+		}
+		_, _, _, a := mask.At(p.X, p.Y).RGBA()
+		return a
+	})
+}
+
+// clipTo intersects z's clip with the coverage, from 0 to 0xffff, returned by
+// f for each of z's pixels.
+func (z *Rasterizer) clipTo(f func(x, y int) uint32) {
+	n := z.size.X * z.size.Y
+	if z.clip == nil {
+		z.clip = make([]uint32, n)
+		for i := range z.clip {
+			z.clip[i] = 0xffff
+		}
+	}
+	for y, i := 0, 0; y < z.size.Y; y++ {
+		for x := 0; x < z.size.X; x, i = x+1, i+1 {
+			z.clip[i] = z.clip[i] * f(x, y) / 0xffff
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+)
+
+// addRect adds a closed rectangular sub-path to z.
+func addRect(z *Rasterizer, x0, y0, x1, y1 float32) {
+	z.MoveTo(x0, y0)
+	z.LineTo(x1, y0)
+	z.LineTo(x1, y1)
+	z.LineTo(x0, y1)
+	z.ClosePath()
+}
+
+// checkMask checks that m's pixels are all within 1 of want's.
+func checkMask(t *testing.T, desc string, m, want *image.Alpha) {
+	t.Helper()
+	for i := range want.Pix {
+		if d := int(m.Pix[i]) - int(want.Pix[i]); d < -1 || 1 < d {
+			x, y := i%want.Stride, i/want.Stride
+			t.Errorf("%s: (%d, %d): got %d, want %d", desc, x, y, m.Pix[i], want.Pix[i])
+			return
+		}
+	}
+}
+
+func TestClipTo(t *testing.T) {
+	clip := NewRasterizer(32, 32)
+	clip.Ellipse(16, 16, 12, 9)
+	want := clip.Mask()
+
+	z := NewRasterizer(32, 32)
+	z.ClipTo(clip)
+	clip.Reset(32, 32)
+	addRect(z, 0, 0, 32, 32)
+	checkMask(t, "Mask", z.Mask(), want)
+
+	// Draw's fast path for a full-sized *image.Alpha is clipped.
+	for _, op := range []draw.Op{draw.Over, draw.Src} {
+		dst := image.NewAlpha(z.Bounds())
+		z.DrawOp = op
+		z.Draw(dst, dst.Bounds(), image.Opaque, image.Point{})
+		checkMask(t, "Draw", dst, want)
+	}
+
+	// Removing the clip.
+	z.ClipTo(nil)
+	full := image.NewAlpha(z.Bounds())
+	draw.Draw(full, full.Bounds(), image.Opaque, image.Point{}, draw.Src)
+	checkMask(t, "unclipped Mask", z.Mask(), full)
+}
+
+func TestClipToIntersection(t *testing.T) {
+	left, top := NewRasterizer(32, 32), NewRasterizer(16, 16)
+	addRect(left, 0, 0, 16, 32)
+	addRect(top, 0, 0, 32, 32)
+
+	z := NewRasterizer(32, 32)
+	z.ClipTo(left)
+	// top is smaller than z, so that its coverage is zero beyond (16, 16).
+	z.ClipTo(top)
+	addRect(z, 8, 8, 24, 24)
+	m := z.Mask()
+
+	want := image.NewAlpha(z.Bounds())
+	draw.Draw(want, image.Rect(8, 8, 16, 16), image.Opaque, image.Point{}, draw.Src)
+	checkMask(t, "Mask", m, want)
+
+	z.Reset(32, 32)
+	if z.clip != nil {
+		t.Fatal("Reset did not remove the clip")
+	}
+}
+
+func TestClipToMask(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(100, 100, 120, 120))
+	draw.Draw(mask, image.Rect(100, 100, 110, 120), image.Opaque, image.Point{}, draw.Src)
+	mask.Pix[mask.PixOffset(110, 105)] = 0x80
+
+	z := NewRasterizer(16, 16)
+	z.ClipToMask(mask, image.Point{100, 100})
+	addRect(z, 0, 0, 16, 16)
+	m := z.Mask()
+	testCases := []struct {
+		x, y int
+		want uint8
+	}{
+		{0, 0, 0xff},
+		{9, 15, 0xff},
+		{10, 0, 0x00},
+		{10, 5, 0x80},
+	}
+	for _, tc := range testCases {
+		if got := m.AlphaAt(tc.x, tc.y).A; got != tc.want {
+			t.Errorf("(%d, %d): got 0x%02x, want 0x%02x", tc.x, tc.y, got, tc.want)
+		}
+	}
+
+	// Pixels beyond the mask are clipped out.
+	z = NewRasterizer(16, 16)
+	z.ClipToMask(mask, image.Point{96, 96})
+	addRect(z, 0, 0, 16, 16)
+	if got := z.Mask().AlphaAt(2, 6).A; got != 0 {
+		t.Errorf("beyond mask: got 0x%02x, want 0x00", got)
+	}
+}
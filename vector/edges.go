@@ -0,0 +1,175 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"sort"
+)
+
+// Edge is a line segment from (X0, Y0) to (X1, Y1), in the Rasterizer's
+// pixel coordinate space. The Y axis increases down.
+//
+// Edges are what the Rasterizer accumulates coverage from: Bézier curves are
+// flattened to edges, and hairline strokes are expanded to quadrilaterals of
+// four edges. An edge's direction matters, as edges that go down add to the
+// winding number of the points to their right, and edges that go up subtract
+// from it.
+type Edge struct {
+	X0, Y0, X1, Y1 float32
+}
+
+// Edges returns the edges recorded since z.RecordEdges was set. They can be
+// passed to a GPU, which then only has to compute the final coverage, while
+// this package handles the flattening of curves and the expansion of
+// hairlines.
+//
+// The returned slice is only valid until the next XxxTo or Reset call.
+func (z *Rasterizer) Edges() []Edge {
+	return z.edges
+}
+
+// Trapezoid is a horizontal band of a filled region, from Y0 down to Y1,
+// bounded on the left by the line from (Left0, Y0) to (Left1, Y1), and on the
+// right by the line from (Right0, Y0) to (Right1, Y1).
+type Trapezoid struct {
+	Y0, Y1         float32
+	Left0, Left1   float32
+	Right0, Right1 float32
+}
+
+// Trapezoids decomposes the region enclosed by the edges, under the non-zero
+// winding rule that the Rasterizer uses, into trapezoids that do not
+// overlap. The trapezoids are ordered from top to bottom, and then from left
+// to right.
+//
+// The region is cut into bands at the endpoints of the edges and at their
+// intersections, so the time taken grows with the square of the number of
+// edges that cross each band.
+func Trapezoids(edges []Edge) []Trapezoid {
+	var es []Edge
+	var ys []float32
+	for _, e := range edges {
+		if e.Y0 == e.Y1 {
+			// Horizontal edges bound no band.
+			continue
+		}
+		es = append(es, e)
+		ys = append(ys, e.Y0, e.Y1)
+	}
+	ys = sortUnique(ys)
+
+	var (
+		ts      []Trapezoid
+		active  []Edge
+		splits  []float32
+		crosses []crossing
+	)
+	for i := 0; i+1 < len(ys); i++ {
+		y0, y1 := ys[i], ys[i+1]
+		active = active[:0]
+		for _, e := range es {
+			if min(e.Y0, e.Y1) <= y0 && y1 <= max(e.Y0, e.Y1) {
+				active = append(active, e)
+			}
+		}
+		if len(active) < 2 {
+			continue
+		}
+
+		// Split the band where its edges intersect, so that the edges'
+		// left-to-right order is the same throughout each sub-band.
+		splits = append(splits[:0], y0, y1)
+		for j := range active {
+			ja, jb := xAt(&active[j], y0), xAt(&active[j], y1)
+			for k := j + 1; k < len(active); k++ {
+				ka, kb := xAt(&active[k], y0), xAt(&active[k], y1)
+				if da, db := ja-ka, jb-kb; (da < 0 && db > 0) || (da > 0 && db < 0) {
+					if y := y0 + (y1-y0)*da/(da-db); y0 < y && y < y1 {
+						splits = append(splits, y)
+					}
+				}
+			}
+		}
+		splits = sortUnique(splits)
+
+		for j := 0; j+1 < len(splits); j++ {
+			s0, s1 := splits[j], splits[j+1]
+			crosses = crosses[:0]
+			for k := range active {
+				e := &active[k]
+				c := crossing{x0: xAt(e, s0), x1: xAt(e, s1), winding: 1}
+				if e.Y0 > e.Y1 {
+					c.winding = -1
+				}
+				crosses = append(crosses, c)
+			}
+			sort.Slice(crosses, func(a, b int) bool {
+				return crosses[a].x0+crosses[a].x1 < crosses[b].x0+crosses[b].x1
+			})
+			winding := 0
+			var left crossing
+			for _, c := range crosses {
+				w := winding + c.winding
+				if winding == 0 && w != 0 {
+					left = c
+				} else if winding != 0 && w == 0 {
+					ts = append(ts, Trapezoid{
+						Y0: s0, Y1: s1,
+						Left0: left.x0, Left1: left.x1,
+						Right0: c.x0, Right1: c.x1,
+					})
+				}
+				winding = w
+			}
+		}
+	}
+	return ts
+}
+
+// crossing is where an edge crosses a band: at x0 along the top of the band,
+// and at x1 along the bottom.
+type crossing struct {
+	x0, x1  float32
+	winding int
+}
+
+// xAt returns the x coordinate of the point at the given y on the line
+// through e, which is not horizontal.
+func xAt(e *Edge, y float32) float32 {
+	if y == e.Y0 {
+		return e.X0
+	}
+	if y == e.Y1 {
+		return e.X1
+	}
+	return e.X0 + (e.X1-e.X0)*(y-e.Y0)/(e.Y1-e.Y0)
+}
+
+// sortUnique sorts s and removes its duplicate values, in place.
+func sortUnique(s []float32) []float32 {
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	n := 0
+	for i, y := range s {
+		if i == 0 || y != s[n-1] {
+			s[n] = y
+			n++
+		}
+	}
+	return s[:n]
+}
+
+func min(x, y float32) float32 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func max(x, y float32) float32 {
+	if x > y {
+		return x
+	}
+	return y
+}
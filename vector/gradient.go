@@ -0,0 +1,245 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/math/f32"
+)
+
+// Spread is how a gradient extends beyond its first and last stops.
+type Spread uint32
+
+const (
+	// SpreadPad extends the colors of the first and last stops.
+	SpreadPad Spread = iota
+	// SpreadRepeat repeats the gradient.
+	SpreadRepeat
+	// SpreadReflect repeats the gradient, alternately mirrored.
+	SpreadReflect
+)
+
+// Stop is a gradient's color at an offset along the gradient, from 0 at its
+// start to 1 at its end.
+type Stop struct {
+	Offset float32
+	Color  color.Color
+}
+
+// infiniteBounds is the bounds of the paint sources, which, like an
+// image.Uniform, extend in all directions.
+var infiniteBounds = image.Rectangle{image.Point{-1e9, -1e9}, image.Point{1e9, 1e9}}
+
+// LinearGradient is an image.Image whose colors vary along the line from
+// (X0, Y0), at offset 0, to (X1, Y1), at offset 1, and are constant along
+// lines perpendicular to it. It is a paint source for a Rasterizer's Draw
+// method, which paints it without an intermediate image.
+//
+// Between the Stops, whose Offsets should be in increasing order, colors are
+// linearly interpolated in premultiplied alpha space. An area whose colors
+// are all from one Stop, such as a gradient with no length, has the last
+// Stop's color, and an area without any Stops is transparent.
+//
+// Its pixels are sampled at their centers, so that pixel (x, y) has the
+// color at (x+0.5, y+0.5) in the gradient's space, which Transform, if
+// non-nil, maps to the image's space.
+type LinearGradient struct {
+	X0, Y0, X1, Y1 float32
+	Stops          []Stop
+	Spread         Spread
+	Transform      *f32.Aff3
+}
+
+// ColorModel implements the image.Image interface.
+func (g *LinearGradient) ColorModel() color.Model { return color.RGBA64Model }
+
+// Bounds implements the image.Image interface.
+func (g *LinearGradient) Bounds() image.Rectangle { return infiniteBounds }
+
+// At implements the image.Image interface.
+func (g *LinearGradient) At(x, y int) color.Color { return g.rgba64At(x, y) }
+
+func (g *LinearGradient) rgba64At(x, y int) color.RGBA64 {
+	px, py, ok := invert(g.Transform, x, y)
+	if !ok {
+		return color.RGBA64{}
+	}
+	dx, dy := float64(g.X1-g.X0), float64(g.Y1-g.Y0)
+	lenSq := dx*dx + dy*dy
+	if lenSq == 0 {
+		return lastStop(g.Stops)
+	}
+	t := ((px-float64(g.X0))*dx + (py-float64(g.Y0))*dy) / lenSq
+	return colorAt(g.Stops, g.Spread, t)
+}
+
+// RadialGradient is an image.Image whose colors vary from the focal point
+// (FX, FY), at offset 0, to the circle centered on (CX, CY) with radius R, at
+// offset 1. For a gradient of concentric circles, the focal point is the
+// center. A focal point outside the circle is moved to just inside it. It is
+// a paint source for a Rasterizer's Draw method, which paints it without an
+// intermediate image.
+//
+// Its Stops, Spread, Transform and pixel sampling are as per LinearGradient.
+type RadialGradient struct {
+	CX, CY, R float32
+	FX, FY    float32
+	Stops     []Stop
+	Spread    Spread
+	Transform *f32.Aff3
+}
+
+// ColorModel implements the image.Image interface.
+func (g *RadialGradient) ColorModel() color.Model { return color.RGBA64Model }
+
+// Bounds implements the image.Image interface.
+func (g *RadialGradient) Bounds() image.Rectangle { return infiniteBounds }
+
+// At implements the image.Image interface.
+func (g *RadialGradient) At(x, y int) color.Color { return g.rgba64At(x, y) }
+
+func (g *RadialGradient) rgba64At(x, y int) color.RGBA64 {
+	px, py, ok := invert(g.Transform, x, y)
+	if !ok {
+		return color.RGBA64{}
+	}
+	r := float64(g.R)
+	if r <= 0 {
+		return lastStop(g.Stops)
+	}
+	cx, cy := float64(g.CX), float64(g.CY)
+	fx, fy := float64(g.FX), float64(g.FY)
+	// e is the focal point relative to the center.
+	ex, ey := fx-cx, fy-cy
+	if eLen, max := math.Hypot(ex, ey), 0.999*r; eLen > max {
+		ex, ey = ex*max/eLen, ey*max/eLen
+		fx, fy = cx+ex, cy+ey
+	}
+	// The ray from the focal point f through p, f + s*d, meets the circle
+	// where |e + s*d| = r, and p is at offset 1/s.
+	dx, dy := px-fx, py-fy
+	dd := dx*dx + dy*dy
+	if dd == 0 {
+		return colorAt(g.Stops, g.Spread, 0)
+	}
+	ed := ex*dx + ey*dy
+	ee := ex*ex + ey*ey
+	s := (-ed + math.Sqrt(ed*ed-dd*(ee-r*r))) / dd
+	return colorAt(g.Stops, g.Spread, 1/s)
+}
+
+// Pattern is an image.Image that repeats Src, in both directions, from the
+// origin. It is a paint source for a Rasterizer's Draw method, which paints
+// it without an intermediate image.
+//
+// Its pixels are sampled at their centers, so that pixel (x, y) has the color
+// of the pixel of Src that contains (x+0.5, y+0.5) in the pattern's space,
+// which Transform, if non-nil, maps to the image's space.
+type Pattern struct {
+	Src       image.Image
+	Transform *f32.Aff3
+}
+
+// ColorModel implements the image.Image interface.
+func (p *Pattern) ColorModel() color.Model { return p.Src.ColorModel() }
+
+// Bounds implements the image.Image interface.
+func (p *Pattern) Bounds() image.Rectangle { return infiniteBounds }
+
+// At implements the image.Image interface.
+func (p *Pattern) At(x, y int) color.Color {
+	b := p.Src.Bounds()
+	if b.Empty() {
+		return color.Transparent
+	}
+	fx, fy, ok := invert(p.Transform, x, y)
+	if !ok {
+		return color.Transparent
+	}
+	sx := math.Floor(fx - float64(b.Min.X))
+	sy := math.Floor(fy - float64(b.Min.Y))
+	sx -= float64(b.Dx()) * math.Floor(sx/float64(b.Dx()))
+	sy -= float64(b.Dy()) * math.Floor(sy/float64(b.Dy()))
+	return p.Src.At(b.Min.X+int(sx), b.Min.Y+int(sy))
+}
+
+// rgba64Source is implemented by the gradients, so that Draw can read their
+// colors without an allocation per pixel.
+type rgba64Source interface {
+	rgba64At(x, y int) color.RGBA64
+}
+
+// invert returns the center of pixel (x, y) mapped by the inverse of m, or by
+// the identity if m is nil. It returns false if m is not invertible.
+func invert(m *f32.Aff3, x, y int) (fx, fy float64, ok bool) {
+	fx, fy = float64(x)+0.5, float64(y)+0.5
+	if m == nil {
+		return fx, fy, true
+	}
+	a, b, c := float64(m[0]), float64(m[1]), float64(m[2])
+	d, e, f := float64(m[3]), float64(m[4]), float64(m[5])
+	det := a*e - b*d
+	if det == 0 {
+		return 0, 0, false
+	}
+	fx, fy = fx-c, fy-f
+	return (e*fx - b*fy) / det, (a*fy - d*fx) / det, true
+}
+
+// colorAt returns the color at offset t of a gradient.
+func colorAt(stops []Stop, spread Spread, t float64) color.RGBA64 {
+	if len(stops) == 0 {
+		return color.RGBA64{}
+	}
+	switch spread {
+	case SpreadRepeat:
+		t -= math.Floor(t)
+	case SpreadReflect:
+		t -= 2 * math.Floor(t/2)
+		if t > 1 {
+			t = 2 - t
+		}
+	}
+	if math.IsNaN(t) || t <= float64(stops[0].Offset) {
+		return toRGBA64(stops[0].Color)
+	}
+	for i := 1; i < len(stops); i++ {
+		s0, s1 := &stops[i-1], &stops[i]
+		if t >= float64(s1.Offset) {
+			continue
+		}
+		c0, c1 := toRGBA64(s0.Color), toRGBA64(s1.Color)
+		u := (t - float64(s0.Offset)) / float64(s1.Offset-s0.Offset)
+		return color.RGBA64{
+			R: lerp16(c0.R, c1.R, u),
+			G: lerp16(c0.G, c1.G, u),
+			B: lerp16(c0.B, c1.B, u),
+			A: lerp16(c0.A, c1.A, u),
+		}
+	}
+	return lastStop(stops)
+}
+
+func lastStop(stops []Stop) color.RGBA64 {
+	if len(stops) == 0 {
+		return color.RGBA64{}
+	}
+	return toRGBA64(stops[len(stops)-1].Color)
+}
+
+func toRGBA64(c color.Color) color.RGBA64 {
+	if c == nil {
+		return color.RGBA64{}
+	}
+	r, g, b, a := c.RGBA()
+	return color.RGBA64{uint16(r), uint16(g), uint16(b), uint16(a)}
+}
+
+func lerp16(a, b uint16, t float64) uint16 {
+	return uint16(float64(a) + t*(float64(b)-float64(a)) + 0.5)
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"golang.org/x/image/math/f32"
+)
+
+var blackToWhite = []Stop{
+	{0, color.Black},
+	{1, color.White},
+}
+
+// gray returns the red channel, out of 0xff, of m at (x, y).
+func gray(m image.Image, x, y int) int {
+	r, _, _, _ := m.At(x, y).RGBA()
+	return int(r >> 8)
+}
+
+func TestLinearGradient(t *testing.T) {
+	testCases := []struct {
+		spread Spread
+		xs     []int
+		want   []int
+	}{
+		{SpreadPad, []int{-10, 0, 49, 99, 150}, []int{0x00, 0x01, 0x7e, 0xfe, 0xff}},
+		{SpreadRepeat, []int{-51, 149, 249}, []int{0x7e, 0x7e, 0x7e}},
+		{SpreadReflect, []int{-51, 149, 150}, []int{0x81, 0x81, 0x7e}},
+	}
+	for _, tc := range testCases {
+		g := &LinearGradient{X0: 0, Y0: 0, X1: 100, Y1: 0, Stops: blackToWhite, Spread: tc.spread}
+		for i, x := range tc.xs {
+			if got := gray(g, x, 7); got != tc.want[i] {
+				t.Errorf("spread %d, x=%d: got 0x%02x, want 0x%02x", tc.spread, x, got, tc.want[i])
+			}
+		}
+	}
+
+	// Scaling by 2 doubles the length of the gradient.
+	g := &LinearGradient{X1: 50, Stops: blackToWhite, Transform: &f32.Aff3{2, 0, 0, 0, 2, 0}}
+	if got := gray(g, 49, 0); got != 0x7e {
+		t.Errorf("transformed: got 0x%02x, want 0x7e", got)
+	}
+
+	// A gradient with no length has the last stop's color.
+	g = &LinearGradient{X0: 5, Y0: 5, X1: 5, Y1: 5, Stops: blackToWhite}
+	if got := gray(g, 0, 0); got != 0xff {
+		t.Errorf("no length: got 0x%02x, want 0xff", got)
+	}
+
+	// Colors are interpolated in premultiplied alpha space.
+	g = &LinearGradient{X1: 2, Stops: []Stop{
+		{0, color.RGBA{0xff, 0, 0, 0xff}},
+		{1, color.Transparent},
+	}}
+	if got, want := g.At(0, 0), (color.RGBA64{0xbfff, 0, 0, 0xbfff}); got != want {
+		t.Errorf("premultiplied: got %v, want %v", got, want)
+	}
+}
+
+func TestRadialGradient(t *testing.T) {
+	// The center is at the center of pixel (50, 50).
+	g := &RadialGradient{CX: 50.5, CY: 50.5, R: 40, FX: 50.5, FY: 50.5, Stops: blackToWhite}
+	testCases := []struct {
+		x, y, want int
+	}{
+		{50, 50, 0x00},
+		{70, 50, 0x80},
+		{50, 30, 0x80},
+		{0, 0, 0xff},
+	}
+	for _, tc := range testCases {
+		if got := gray(g, tc.x, tc.y); got != tc.want {
+			t.Errorf("(%d, %d): got 0x%02x, want 0x%02x", tc.x, tc.y, got, tc.want)
+		}
+	}
+
+	// With the focal point at the center of pixel (30, 50), a point halfway
+	// from it to the circle, in any direction, is at offset 0.5.
+	g.FX = 30.5
+	for _, p := range []image.Point{{60, 50}, {20, 50}} {
+		if got := gray(g, p.X, p.Y); got != 0x80 {
+			t.Errorf("focal, %v: got 0x%02x, want 0x80", p, got)
+		}
+	}
+	if got := gray(g, 30, 50); got != 0x00 {
+		t.Errorf("focal point: got 0x%02x, want 0x00", got)
+	}
+
+	// A focal point outside the circle is moved inside it.
+	g.FX = 200
+	if got := gray(g, 89, 50); got > 0x04 {
+		t.Errorf("outside focal point: got 0x%02x, want 0x00", got)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	src := image.NewGray(image.Rect(10, 10, 12, 12))
+	src.SetGray(10, 10, color.Gray{0xff})
+	src.SetGray(11, 11, color.Gray{0xff})
+	p := &Pattern{Src: src}
+	for _, pt := range []image.Point{{0, 0}, {1, 1}, {-2, 2}, {5, 7}} {
+		if got := gray(p, pt.X, pt.Y); got != 0xff {
+			t.Errorf("%v: got 0x%02x, want 0xff", pt, got)
+		}
+	}
+	for _, pt := range []image.Point{{1, 0}, {-1, 0}, {4, 7}} {
+		if got := gray(p, pt.X, pt.Y); got != 0x00 {
+			t.Errorf("%v: got 0x%02x, want 0x00", pt, got)
+		}
+	}
+
+	// Scaling by 2 doubles the size of the tiles.
+	p.Transform = &f32.Aff3{2, 0, 0, 0, 2, 0}
+	if got := gray(p, 1, 1); got != 0xff {
+		t.Errorf("transformed (1, 1): got 0x%02x, want 0xff", got)
+	}
+	if got := gray(p, 2, 1); got != 0x00 {
+		t.Errorf("transformed (2, 1): got 0x%02x, want 0x00", got)
+	}
+}
+
+func TestDrawGradient(t *testing.T) {
+	srcs := []image.Image{
+		&LinearGradient{X0: 4, Y0: 4, X1: 28, Y1: 20, Stops: blackToWhite},
+		&RadialGradient{CX: 16, CY: 16, R: 12, FX: 12, FY: 12, Stops: blackToWhite, Spread: SpreadReflect},
+	}
+	for i, src := range srcs {
+		for _, op := range []draw.Op{draw.Over, draw.Src} {
+			z := NewRasterizer(32, 32)
+			z.DrawOp = op
+			z.Ellipse(16, 16, 12, 8)
+			got := image.NewRGBA(z.Bounds())
+			z.Draw(got, got.Bounds(), src, image.Point{})
+
+			want := image.NewRGBA(z.Bounds())
+			draw.DrawMask(want, want.Bounds(), src, image.Point{}, z.Mask(), image.Point{}, op)
+			for j := range got.Pix {
+				if d := int(got.Pix[j]) - int(want.Pix[j]); d < -1 || 1 < d {
+					t.Errorf("src #%d, op %v: Draw differs from DrawMask at byte %d: got %d, want %d",
+						i, op, j, got.Pix[j], want.Pix[j])
+					break
+				}
+			}
+		}
+	}
+}
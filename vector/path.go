@@ -0,0 +1,153 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"math"
+
+	"golang.org/x/image/math/f32"
+)
+
+// PathOp is a vector path segment's operator.
+type PathOp uint32
+
+const (
+	PathOpMoveTo PathOp = iota
+	PathOpLineTo
+	PathOpQuadTo
+	PathOpCubeTo
+	PathOpClose
+)
+
+// nArgs returns the number of (x, y) coordinates used by the op.
+func (o PathOp) nArgs() int {
+	switch o {
+	case PathOpMoveTo, PathOpLineTo:
+		return 1
+	case PathOpQuadTo:
+		return 2
+	case PathOpCubeTo:
+		return 3
+	}
+	return 0
+}
+
+// PathSegment is a segment of a vector path.
+type PathSegment struct {
+	// Op is the operator.
+	Op PathOp
+	// Args is up to three (x, y) coordinates. The Y axis increases down. A
+	// PathOpClose segment has no arguments.
+	Args [3]f32.Vec2
+}
+
+// Path is a recorded sequence of vector path segments. Unlike a Rasterizer,
+// which consumes path segments as they are added, a Path can be inspected,
+// transformed and replayed onto any number of Rasterizers.
+//
+// The zero value is an empty path, ready to use.
+type Path []PathSegment
+
+// MoveTo starts a new sub-path at (ax, ay).
+func (p *Path) MoveTo(ax, ay float32) {
+	*p = append(*p, PathSegment{
+		Op:   PathOpMoveTo,
+		Args: [3]f32.Vec2{{ax, ay}},
+	})
+}
+
+// LineTo adds a line segment to (bx, by).
+func (p *Path) LineTo(bx, by float32) {
+	*p = append(*p, PathSegment{
+		Op:   PathOpLineTo,
+		Args: [3]f32.Vec2{{bx, by}},
+	})
+}
+
+// QuadTo adds a quadratic Bézier segment via (bx, by) to (cx, cy).
+func (p *Path) QuadTo(bx, by, cx, cy float32) {
+	*p = append(*p, PathSegment{
+		Op:   PathOpQuadTo,
+		Args: [3]f32.Vec2{{bx, by}, {cx, cy}},
+	})
+}
+
+// CubeTo adds a cubic Bézier segment via (bx, by) and (cx, cy) to (dx, dy).
+func (p *Path) CubeTo(bx, by, cx, cy, dx, dy float32) {
+	*p = append(*p, PathSegment{
+		Op:   PathOpCubeTo,
+		Args: [3]f32.Vec2{{bx, by}, {cx, cy}, {dx, dy}},
+	})
+}
+
+// ClosePath closes the current sub-path.
+func (p *Path) ClosePath() {
+	*p = append(*p, PathSegment{Op: PathOpClose})
+}
+
+// AddTo adds p's segments to z. It does not call z.Reset, so a Path can be
+// added on top of other paths previously added to z.
+func (p Path) AddTo(z *Rasterizer) {
+	for _, s := range p {
+		a := &s.Args
+		switch s.Op {
+		case PathOpMoveTo:
+			z.MoveTo(a[0][0], a[0][1])
+		case PathOpLineTo:
+			z.LineTo(a[0][0], a[0][1])
+		case PathOpQuadTo:
+			z.QuadTo(a[0][0], a[0][1], a[1][0], a[1][1])
+		case PathOpCubeTo:
+			z.CubeTo(a[0][0], a[0][1], a[1][0], a[1][1], a[2][0], a[2][1])
+		case PathOpClose:
+			z.ClosePath()
+		}
+	}
+}
+
+// Transform applies the affine transformation m to every point of p, in
+// place. The src-space point (x, y) maps to (m[0]*x + m[1]*y + m[2],
+// m[3]*x + m[4]*y + m[5]).
+func (p Path) Transform(m f32.Aff3) {
+	for i := range p {
+		a := &p[i].Args
+		for j, n := 0, p[i].Op.nArgs(); j < n; j++ {
+			x, y := a[j][0], a[j][1]
+			a[j][0] = m[0]*x + m[1]*y + m[2]
+			a[j][1] = m[3]*x + m[4]*y + m[5]
+		}
+	}
+}
+
+// Bounds returns the bounding box of p's points, including Bézier control
+// points, as (minX, minY, maxX, maxY). It returns all zeroes if p has no
+// points.
+func (p Path) Bounds() (minX, minY, maxX, maxY float32) {
+	minX, minY = +math.MaxFloat32, +math.MaxFloat32
+	maxX, maxY = -math.MaxFloat32, -math.MaxFloat32
+	seen := false
+	for _, s := range p {
+		for j, n := 0, s.Op.nArgs(); j < n; j++ {
+			seen = true
+			x, y := s.Args[j][0], s.Args[j][1]
+			if minX > x {
+				minX = x
+			}
+			if maxX < x {
+				maxX = x
+			}
+			if minY > y {
+				minY = y
+			}
+			if maxY < y {
+				maxY = y
+			}
+		}
+	}
+	if !seen {
+		return 0, 0, 0, 0
+	}
+	return minX, minY, maxX, maxY
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"golang.org/x/image/math/f32"
+)
+
+func TestPathAddTo(t *testing.T) {
+	var p Path
+	p.MoveTo(2, 2)
+	p.LineTo(14, 2)
+	p.QuadTo(14, 14, 8, 14)
+	p.CubeTo(4, 14, 2, 10, 2, 8)
+	p.ClosePath()
+
+	want := NewRasterizer(16, 16)
+	want.MoveTo(2, 2)
+	want.LineTo(14, 2)
+	want.QuadTo(14, 14, 8, 14)
+	want.CubeTo(4, 14, 2, 10, 2, 8)
+	want.ClosePath()
+	wantDst := image.NewAlpha(want.Bounds())
+	want.Draw(wantDst, wantDst.Bounds(), image.Opaque, image.Point{})
+
+	got := NewRasterizer(16, 16)
+	p.AddTo(got)
+	gotDst := image.NewAlpha(got.Bounds())
+	got.Draw(gotDst, gotDst.Bounds(), image.Opaque, image.Point{})
+
+	if !bytes.Equal(gotDst.Pix, wantDst.Pix) {
+		t.Fatal("Path.AddTo rasterization differs from direct rasterization")
+	}
+}
+
+func TestPathTransformAndBounds(t *testing.T) {
+	var p Path
+	if x0, y0, x1, y1 := p.Bounds(); x0 != 0 || y0 != 0 || x1 != 0 || y1 != 0 {
+		t.Fatalf("empty Bounds: got (%v, %v, %v, %v), want zeroes", x0, y0, x1, y1)
+	}
+	p.MoveTo(1, 2)
+	p.QuadTo(5, -3, 4, 4)
+	p.ClosePath()
+	if x0, y0, x1, y1 := p.Bounds(); x0 != 1 || y0 != -3 || x1 != 5 || y1 != 4 {
+		t.Fatalf("Bounds: got (%v, %v, %v, %v), want (1, -3, 5, 4)", x0, y0, x1, y1)
+	}
+
+	// Scale by 2 and translate by (10, 20).
+	p.Transform(f32.Aff3{2, 0, 10, 0, 2, 20})
+	if x0, y0, x1, y1 := p.Bounds(); x0 != 12 || y0 != 14 || x1 != 20 || y1 != 28 {
+		t.Fatalf("transformed Bounds: got (%v, %v, %v, %v), want (12, 14, 20, 28)", x0, y0, x1, y1)
+	}
+	if p[2].Args[0] != (f32.Vec2{}) {
+		t.Fatalf("ClosePath args were transformed: %v", p[2].Args)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"math"
+
+	"golang.org/x/image/math/f32"
+)
+
+// pather is the path-drawing methods shared by *Rasterizer and *Path.
+type pather interface {
+	MoveTo(ax, ay float32)
+	LineTo(bx, by float32)
+	CubeTo(bx, by, cx, cy, dx, dy float32)
+	ClosePath()
+}
+
+// ArcTo adds a line segment, from the pen to the start of an elliptical arc,
+// and then the arc, approximated by cubic Bézier segments, and moves the pen
+// to the end of the arc.
+//
+// The ellipse is centered on (cx, cy) with radii rx and ry along the X and Y
+// axes. The arc starts at startAngle and sweeps by sweepAngle, both in
+// radians, where angles increase from the +X axis towards the +Y axis, which
+// is clockwise as the Y axis increases down. A negative sweepAngle sweeps
+// counter-clockwise. A sweepAngle of ±2π or more traces a whole ellipse.
+//
+// The coordinates are allowed to be out of the Rasterizer's bounds.
+func (z *Rasterizer) ArcTo(cx, cy, rx, ry, startAngle, sweepAngle float32) {
+	arcTo(z, cx, cy, rx, ry, startAngle, sweepAngle)
+}
+
+// Ellipse adds a closed elliptical sub-path, centered on (cx, cy) with radii
+// rx and ry along the X and Y axes, and approximated by four cubic Bézier
+// segments. The sub-path starts and ends at (cx+rx, cy), where the pen is
+// left, and runs clockwise as the Y axis increases down.
+//
+// The coordinates are allowed to be out of the Rasterizer's bounds.
+func (z *Rasterizer) Ellipse(cx, cy, rx, ry float32) {
+	ellipse(z, cx, cy, rx, ry)
+}
+
+// RoundRect adds a closed rectangular sub-path, from (x0, y0) to (x1, y1),
+// whose corners are rounded by circular arcs of radius r. The radius is
+// reduced to at most half of the rectangle's width and height, and a
+// non-positive radius gives square corners. The sub-path runs clockwise as
+// the Y axis increases down, if x0 < x1 and y0 < y1.
+//
+// The coordinates are allowed to be out of the Rasterizer's bounds.
+func (z *Rasterizer) RoundRect(x0, y0, x1, y1, r float32) {
+	roundRect(z, x0, y0, x1, y1, r)
+}
+
+// ArcTo adds segments for an elliptical arc, as per Rasterizer.ArcTo.
+func (p *Path) ArcTo(cx, cy, rx, ry, startAngle, sweepAngle float32) {
+	arcTo(p, cx, cy, rx, ry, startAngle, sweepAngle)
+}
+
+// Ellipse adds segments for a closed elliptical sub-path, as per
+// Rasterizer.Ellipse.
+func (p *Path) Ellipse(cx, cy, rx, ry float32) {
+	ellipse(p, cx, cy, rx, ry)
+}
+
+// RoundRect adds segments for a closed rounded rectangle sub-path, as per
+// Rasterizer.RoundRect.
+func (p *Path) RoundRect(x0, y0, x1, y1, r float32) {
+	roundRect(p, x0, y0, x1, y1, r)
+}
+
+// QuadToCube returns the control points, (b1x, b1y) and (c1x, c1y), of the
+// cubic Bézier curve that is exactly equal to the quadratic Bézier curve from
+// (ax, ay) via (bx, by) to (cx, cy). The cubic curve has the same end points.
+func QuadToCube(ax, ay, bx, by, cx, cy float32) (b1x, b1y, c1x, c1y float32) {
+	b1x, b1y = lerp(2.0/3, ax, ay, bx, by)
+	c1x, c1y = lerp(2.0/3, cx, cy, bx, by)
+	return b1x, b1y, c1x, c1y
+}
+
+// QuadsToCubes replaces p's quadratic Bézier segments, in place, by equal
+// cubic Bézier segments, for consumers of paths that only support cubic
+// curves.
+func (p Path) QuadsToCubes() {
+	var pen, start f32.Vec2
+	for i := range p {
+		s := &p[i]
+		a := &s.Args
+		switch s.Op {
+		case PathOpMoveTo:
+			pen, start = a[0], a[0]
+		case PathOpLineTo:
+			pen = a[0]
+		case PathOpQuadTo:
+			b1x, b1y, c1x, c1y := QuadToCube(pen[0], pen[1], a[0][0], a[0][1], a[1][0], a[1][1])
+			s.Op = PathOpCubeTo
+			a[2] = a[1]
+			a[0][0], a[0][1], a[1][0], a[1][1] = b1x, b1y, c1x, c1y
+			pen = a[2]
+		case PathOpCubeTo:
+			pen = a[2]
+		case PathOpClose:
+			pen = start
+		}
+	}
+}
+
+func arcTo(p pather, cx, cy, rx, ry, startAngle, sweepAngle float32) {
+	sin, cos := math.Sincos(float64(startAngle))
+	p.LineTo(cx+rx*float32(cos), cy+ry*float32(sin))
+	arc(p, cx, cy, rx, ry, float64(startAngle), float64(sweepAngle))
+}
+
+// arc adds the cubic Bézier segments of an elliptical arc, from the pen,
+// which should be at the start of the arc.
+func arc(p pather, cx, cy, rx, ry float32, startAngle, sweepAngle float64) {
+	if sweepAngle == 0 {
+		return
+	} else if sweepAngle > 2*math.Pi {
+		sweepAngle = 2 * math.Pi
+	} else if sweepAngle < -2*math.Pi {
+		sweepAngle = -2 * math.Pi
+	}
+	// Each segment sweeps at most 90 degrees, and its control points are at
+	// a distance of 4/3 * tan(θ/4) along the tangents, for a unit circle.
+	// The tolerance avoids an extra segment for a sweepAngle, such as
+	// float32(math.Pi), that is a multiple of 90 degrees plus rounding
+	// errors.
+	n := int(math.Ceil(math.Abs(sweepAngle)/(math.Pi/2) - 1e-6))
+	if n == 0 {
+		n = 1
+	}
+	theta := sweepAngle / float64(n)
+	t := float32(4 * math.Tan(theta/4) / 3)
+	sin0, cos0 := math.Sincos(startAngle)
+	for i := 1; i <= n; i++ {
+		sin1, cos1 := math.Sincos(startAngle + float64(i)*theta)
+		x0, y0 := rx*float32(cos0), ry*float32(sin0)
+		x1, y1 := rx*float32(cos1), ry*float32(sin1)
+		p.CubeTo(
+			cx+x0-t*rx*float32(sin0), cy+y0+t*ry*float32(cos0),
+			cx+x1+t*rx*float32(sin1), cy+y1-t*ry*float32(cos1),
+			cx+x1, cy+y1,
+		)
+		sin0, cos0 = sin1, cos1
+	}
+}
+
+func ellipse(p pather, cx, cy, rx, ry float32) {
+	p.MoveTo(cx+rx, cy)
+	arc(p, cx, cy, rx, ry, 0, 2*math.Pi)
+	p.ClosePath()
+}
+
+func roundRect(p pather, x0, y0, x1, y1, r float32) {
+	if w := float32(math.Abs(float64(x1-x0))) / 2; r > w {
+		r = w
+	}
+	if h := float32(math.Abs(float64(y1-y0))) / 2; r > h {
+		r = h
+	}
+	if r <= 0 {
+		p.MoveTo(x0, y0)
+		p.LineTo(x1, y0)
+		p.LineTo(x1, y1)
+		p.LineTo(x0, y1)
+		p.ClosePath()
+		return
+	}
+	// rx and ry are r, with the signs that move inwards from (x0, y0).
+	rx, ry := r, r
+	if x0 > x1 {
+		rx = -r
+	}
+	if y0 > y1 {
+		ry = -r
+	}
+	p.MoveTo(x0+rx, y0)
+	p.LineTo(x1-rx, y0)
+	arc(p, x1-rx, y0+ry, rx, ry, -math.Pi/2, math.Pi/2)
+	p.LineTo(x1, y1-ry)
+	arc(p, x1-rx, y1-ry, rx, ry, 0, math.Pi/2)
+	p.LineTo(x0+rx, y1)
+	arc(p, x0+rx, y1-ry, rx, ry, math.Pi/2, math.Pi/2)
+	p.LineTo(x0, y0+ry)
+	arc(p, x0+rx, y0+ry, rx, ry, math.Pi, math.Pi/2)
+	p.ClosePath()
+}
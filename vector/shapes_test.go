@@ -0,0 +1,131 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// maskArea returns the area, in pixels, of z's mask.
+func maskArea(z *Rasterizer) float64 {
+	sum := 0
+	for _, a := range z.Mask().Pix {
+		sum += int(a)
+	}
+	return float64(sum) / 0xff
+}
+
+func TestEllipse(t *testing.T) {
+	z := NewRasterizer(64, 64)
+	z.Ellipse(32, 32, 24, 12)
+	if x, y := z.Pen(); x != 56 || y != 32 {
+		t.Errorf("pen: got (%v, %v), want (56, 32)", x, y)
+	}
+	want := math.Pi * 24 * 12
+	if got := maskArea(z); math.Abs(got-want)/want > 0.01 {
+		t.Errorf("area: got %.2f, want %.2f", got, want)
+	}
+
+	// A Path's Ellipse rasterizes the same as a Rasterizer's.
+	var p Path
+	p.Ellipse(32, 32, 24, 12)
+	zp := NewRasterizer(64, 64)
+	p.AddTo(zp)
+	if !bytes.Equal(zp.Mask().Pix, z.Mask().Pix) {
+		t.Error("Path.Ellipse rasterization differs from Rasterizer.Ellipse")
+	}
+}
+
+func TestArcTo(t *testing.T) {
+	// A pie slice: a quarter of a circle of radius 32, clockwise from the +X
+	// axis.
+	z := NewRasterizer(64, 64)
+	z.MoveTo(0, 0)
+	z.ArcTo(0, 0, 32, 32, 0, math.Pi/2)
+	z.ClosePath()
+	want := math.Pi * 32 * 32 / 4
+	if got := maskArea(z); math.Abs(got-want)/want > 0.01 {
+		t.Errorf("clockwise area: got %.2f, want %.2f", got, want)
+	}
+
+	// The same slice, counter-clockwise from the +Y axis.
+	z.Reset(64, 64)
+	z.MoveTo(0, 0)
+	z.ArcTo(0, 0, 32, 32, math.Pi/2, -math.Pi/2)
+	if x, y := z.Pen(); math.Abs(float64(x-32)) > 1e-4 || math.Abs(float64(y)) > 1e-4 {
+		t.Errorf("pen: got (%v, %v), want (32, 0)", x, y)
+	}
+	z.ClosePath()
+	if got := maskArea(z); math.Abs(got-want)/want > 0.01 {
+		t.Errorf("counter-clockwise area: got %.2f, want %.2f", got, want)
+	}
+
+	// A zero sweep adds only the line segment.
+	var p Path
+	p.MoveTo(0, 0)
+	p.ArcTo(0, 0, 8, 8, 0, 0)
+	if len(p) != 2 || p[1].Op != PathOpLineTo {
+		t.Errorf("zero sweep: got %v, want a MoveTo and a LineTo", p)
+	}
+}
+
+func TestRoundRect(t *testing.T) {
+	testCases := []struct {
+		x0, y0, x1, y1, r float32
+		want              float64
+	}{
+		{8, 8, 56, 40, 0, 48 * 32},
+		{8, 8, 56, 40, 8, 48*32 - (4-math.Pi)*8*8},
+		{56, 40, 8, 8, 8, 48*32 - (4-math.Pi)*8*8},
+		// The radius is reduced to half of the height.
+		{8, 8, 56, 40, 100, 32*16 + math.Pi*16*16},
+	}
+	for _, tc := range testCases {
+		z := NewRasterizer(64, 64)
+		z.RoundRect(tc.x0, tc.y0, tc.x1, tc.y1, tc.r)
+		if got := maskArea(z); math.Abs(got-tc.want)/tc.want > 0.01 {
+			t.Errorf("(%v, %v)-(%v, %v), r=%v: got area %.2f, want %.2f",
+				tc.x0, tc.y0, tc.x1, tc.y1, tc.r, got, tc.want)
+		}
+	}
+}
+
+func TestQuadsToCubes(t *testing.T) {
+	var p Path
+	p.MoveTo(2, 2)
+	p.QuadTo(30, 2, 30, 30)
+	p.QuadTo(2, 30, 2, 2)
+	p.ClosePath()
+	q := append(Path(nil), p...)
+	q.QuadsToCubes()
+
+	for i := range q {
+		if q[i].Op == PathOpQuadTo {
+			t.Fatalf("segment #%d is still a QuadTo", i)
+		}
+	}
+	if q[1].Args[2] != p[1].Args[1] || q[2].Args[2] != p[2].Args[1] {
+		t.Fatalf("end points changed: got %v, want %v", q, p)
+	}
+
+	// The curves are equal, so they rasterize almost the same, other than
+	// by how they are flattened.
+	zp, zq := NewRasterizer(32, 32), NewRasterizer(32, 32)
+	p.AddTo(zp)
+	q.AddTo(zq)
+	if gotP, gotQ := maskArea(zp), maskArea(zq); math.Abs(gotP-gotQ)/gotP > 0.01 {
+		t.Errorf("area: got %.2f after QuadsToCubes, %.2f before", gotQ, gotP)
+	}
+
+	// The midpoint of a cubic from QuadToCube is the midpoint of the quad.
+	b1x, b1y, c1x, c1y := QuadToCube(0, 0, 6, 12, 12, 0)
+	midX := (0 + 3*b1x + 3*c1x + 12) / 8
+	midY := (0 + 3*b1y + 3*c1y + 0) / 8
+	if midX != 6 || midY != 6 {
+		t.Errorf("QuadToCube midpoint: got (%v, %v), want (6, 6)", midX, midY)
+	}
+}
@@ -0,0 +1,542 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"math"
+
+	"golang.org/x/image/math/f32"
+)
+
+// Cap is the shape at the ends of a stroked open sub-path or dash.
+type Cap uint32
+
+const (
+	// ButtCap ends a stroke squarely at its end point.
+	ButtCap Cap = iota
+	// RoundCap ends a stroke with a semicircle, centered on its end point.
+	RoundCap
+	// SquareCap ends a stroke with a half square, centered on its end point,
+	// extending the stroke by half its width.
+	SquareCap
+)
+
+// Join is the shape at the corners where two segments of a stroked path meet.
+type Join uint32
+
+const (
+	// MiterJoin extends the outer edges of the two segments until they meet,
+	// unless that would exceed the miter limit, in which case it is a
+	// BevelJoin.
+	MiterJoin Join = iota
+	// RoundJoin rounds the corner with a circular arc, centered on the
+	// corner.
+	RoundJoin
+	// BevelJoin cuts the corner with a straight line.
+	BevelJoin
+)
+
+// Stroke is how to stroke a Path.
+//
+// A nil *Stroke means to use the default (zero) values of each field.
+type Stroke struct {
+	// Width is the width of the stroke. Zero means 1.
+	Width float32
+
+	// Cap is the shape at the ends of open sub-paths and of dashes.
+	Cap Cap
+
+	// Join is the shape at the corners of the path.
+	Join Join
+
+	// MiterLimit is the largest ratio of a MiterJoin's length, from its inner
+	// to its outer corner, to the stroke's width, beyond which the corner is
+	// beveled instead. Zero means 4.
+	MiterLimit float32
+
+	// Dashes is the lengths of the alternately drawn and skipped parts of the
+	// path, starting with a drawn part. The pattern repeats, and is repeated
+	// twice if it has an odd number of lengths. Empty, or a pattern with a
+	// negative length or whose lengths total less than 1/64 of a pixel,
+	// means an undashed stroke, as does a pattern that would split a
+	// sub-path into more than a million dashes.
+	Dashes []float32
+
+	// DashOffset is the distance into the dash pattern at which the path
+	// starts.
+	DashOffset float32
+}
+
+// Stroke returns the outline of p as stroked with s, as a Path to be filled.
+// The outline overlaps itself where p does, and at the inside of corners,
+// but it winds in the same direction wherever it overlaps itself, so it is
+// filled as expected by a Rasterizer, which uses the non-zero winding rule.
+//
+// Bézier curves are flattened to line segments, as by a Rasterizer, so p
+// should be in the Rasterizer's (pixel) coordinate space, instead of being
+// transformed after it is stroked. The outline's rounded caps and joins are
+// cubic Bézier curves.
+//
+// A sub-path with no length, such as a MoveTo immediately followed by a
+// ClosePath, is stroked as a dot for RoundCap and SquareCap, and not at all
+// for ButtCap. A sub-path of a single MoveTo is not stroked.
+func (p Path) Stroke(s *Stroke) Path {
+	k := stroker{
+		halfWidth:  0.5,
+		miterLimit: 4,
+	}
+	var dashes []float32
+	if s != nil {
+		if s.Width != 0 {
+			k.halfWidth = s.Width / 2
+		}
+		if s.MiterLimit != 0 {
+			k.miterLimit = s.MiterLimit
+		}
+		k.cap, k.join = s.Cap, s.Join
+		dashes = validDashes(s.Dashes)
+	}
+	for _, sp := range flatten(p) {
+		if dashes == nil {
+			k.stroke(sp)
+			continue
+		}
+		for _, d := range dash(sp, dashes, s.DashOffset) {
+			k.stroke(d)
+		}
+	}
+	return k.out
+}
+
+// AddStrokeTo adds the outline of p, as stroked with s, to z. It is
+// equivalent to p.Stroke(s).AddTo(z). z.Hairline should be false.
+func (p Path) AddStrokeTo(z *Rasterizer, s *Stroke) {
+	p.Stroke(s).AddTo(z)
+}
+
+// polyline is a flattened sub-path.
+type polyline struct {
+	// pts are the points of the polyline, with no two consecutive points
+	// equal. A closed polyline does not repeat its first point at its end.
+	pts []f32.Vec2
+	// closed is whether the polyline has a segment from its last point back
+	// to its first.
+	closed bool
+	// dot is whether a polyline with a single point is stroked as a dot, and
+	// dir is the dot's direction, for a SquareCap.
+	dot bool
+	dir f32.Vec2
+}
+
+func (l *polyline) add(q f32.Vec2) {
+	if n := len(l.pts); n == 0 || l.pts[n-1] != q {
+		l.pts = append(l.pts, q)
+	}
+}
+
+// flatten returns p's sub-paths as polylines.
+func flatten(p Path) []polyline {
+	var (
+		ret   []polyline
+		cur   polyline
+		start f32.Vec2
+		pen   f32.Vec2
+	)
+	finish := func() {
+		if cur.closed && len(cur.pts) > 1 && cur.pts[len(cur.pts)-1] == cur.pts[0] {
+			cur.pts = cur.pts[:len(cur.pts)-1]
+		}
+		if len(cur.pts) < 2 {
+			cur.closed = false
+		}
+		if len(cur.pts) > 1 || cur.dot {
+			ret = append(ret, cur)
+		}
+		cur = polyline{}
+	}
+	// draw starts a sub-path, if there isn't one, at the pen, as per the
+	// Rasterizer, which starts at (0, 0) and returns to the start of a
+	// closed sub-path.
+	draw := func() {
+		if len(cur.pts) == 0 {
+			cur.pts = append(cur.pts, pen)
+			start = pen
+		}
+		cur.dot, cur.dir = true, f32.Vec2{1, 0}
+	}
+	for _, s := range p {
+		a := &s.Args
+		switch s.Op {
+		case PathOpMoveTo:
+			finish()
+			pen, start = a[0], a[0]
+			cur.pts = append(cur.pts, pen)
+		case PathOpLineTo:
+			draw()
+			cur.add(a[0])
+			pen = a[0]
+		case PathOpQuadTo:
+			draw()
+			flattenQuad(&cur, pen, a[0], a[1])
+			pen = a[1]
+		case PathOpCubeTo:
+			draw()
+			flattenCube(&cur, pen, a[0], a[1], a[2])
+			pen = a[2]
+		case PathOpClose:
+			draw()
+			cur.closed = true
+			finish()
+			pen = start
+		}
+	}
+	finish()
+	return ret
+}
+
+// flattenQuad adds the quadratic Bézier curve from a via b to c to l, other
+// than a, as line segments, subdivided in the same way as by
+// Rasterizer.QuadTo.
+func flattenQuad(l *polyline, a, b, c f32.Vec2) {
+	devsq := devSquared(a[0], a[1], b[0], b[1], c[0], c[1])
+	if devsq >= 0.333 {
+		const tol = 3
+		n := 1 + int(math.Sqrt(math.Sqrt(tol*float64(devsq))))
+		t, nInv := float32(0), 1/float32(n)
+		for i := 0; i < n-1; i++ {
+			t += nInv
+			abx, aby := lerp(t, a[0], a[1], b[0], b[1])
+			bcx, bcy := lerp(t, b[0], b[1], c[0], c[1])
+			x, y := lerp(t, abx, aby, bcx, bcy)
+			l.add(f32.Vec2{x, y})
+		}
+	}
+	l.add(c)
+}
+
+// flattenCube adds the cubic Bézier curve from a via b and c to d to l, other
+// than a, as line segments, subdivided in the same way as by
+// Rasterizer.CubeTo.
+func flattenCube(l *polyline, a, b, c, d f32.Vec2) {
+	devsq := devSquared(a[0], a[1], b[0], b[1], d[0], d[1])
+	if devsqAlt := devSquared(a[0], a[1], c[0], c[1], d[0], d[1]); devsq < devsqAlt {
+		devsq = devsqAlt
+	}
+	if devsq >= 0.333 {
+		const tol = 3
+		n := 1 + int(math.Sqrt(math.Sqrt(tol*float64(devsq))))
+		t, nInv := float32(0), 1/float32(n)
+		for i := 0; i < n-1; i++ {
+			t += nInv
+			abx, aby := lerp(t, a[0], a[1], b[0], b[1])
+			bcx, bcy := lerp(t, b[0], b[1], c[0], c[1])
+			cdx, cdy := lerp(t, c[0], c[1], d[0], d[1])
+			abcx, abcy := lerp(t, abx, aby, bcx, bcy)
+			bcdx, bcdy := lerp(t, bcx, bcy, cdx, cdy)
+			x, y := lerp(t, abcx, abcy, bcdx, bcdy)
+			l.add(f32.Vec2{x, y})
+		}
+	}
+	l.add(d)
+}
+
+const (
+	// minDashPeriod is the smallest total length of a dash pattern. Finer
+	// patterns are indistinguishable from an undashed stroke.
+	minDashPeriod = 1.0 / 64
+	// maxDashes is the largest number of dashes, and gaps, that dash splits
+	// a polyline into.
+	maxDashes = 1 << 20
+)
+
+// validDashes returns the dash pattern to use for the given Stroke.Dashes, or
+// nil for an undashed stroke.
+func validDashes(dashes []float32) []float32 {
+	total := float32(0)
+	for _, d := range dashes {
+		if d < 0 {
+			return nil
+		}
+		total += d
+	}
+	if !(total >= minDashPeriod) || math.IsInf(float64(total), 0) {
+		return nil
+	}
+	if len(dashes)%2 != 0 {
+		return append(dashes[:len(dashes):len(dashes)], dashes...)
+	}
+	return dashes
+}
+
+// dash splits l into the open polylines of its drawn dashes. If l is closed,
+// and its first and last dashes meet at its first point, they are joined.
+//
+// l is returned whole if its length is not finite, or if it would be split
+// into more than maxDashes dashes and gaps.
+func dash(l polyline, dashes []float32, offset float32) []polyline {
+	if len(l.pts) < 2 {
+		// A dot is drawn, as if it was a dash of zero length, unless the
+		// pattern starts with a gap.
+		if offset == 0 {
+			return []polyline{l}
+		}
+		return nil
+	}
+	total := float32(0)
+	for _, d := range dashes {
+		total += d
+	}
+	n := len(l.pts)
+	if !l.closed {
+		n--
+	}
+	pathLength := 0.0
+	for j := 0; j < n; j++ {
+		a, b := l.pts[j], l.pts[(j+1)%len(l.pts)]
+		pathLength += math.Hypot(float64(b[0]-a[0]), float64(b[1]-a[1]))
+	}
+	if math.IsInf(pathLength, 0) || math.IsNaN(pathLength) ||
+		pathLength/float64(total)*float64(len(dashes)) > maxDashes {
+		return []polyline{l}
+	}
+	offset = float32(math.Mod(float64(offset), float64(total)))
+	if offset < 0 {
+		offset += total
+	}
+	i := 0
+	for offset > 0 && offset >= dashes[i] {
+		offset -= dashes[i]
+		i = (i + 1) % len(dashes)
+	}
+	rem, on, toggled := dashes[i]-offset, i%2 == 0, false
+	startsOn := on
+
+	var ret []polyline
+	var cur polyline
+	if on {
+		cur.pts = append(cur.pts, l.pts[0])
+		cur.dot, cur.dir = true, unit(l.pts[0], l.pts[1])
+	}
+	for j := 0; j < n; j++ {
+		a, b := l.pts[j], l.pts[(j+1)%len(l.pts)]
+		length := float32(math.Hypot(float64(b[0]-a[0]), float64(b[1]-a[1])))
+		pos := float32(0)
+		for length-pos > rem {
+			pos += rem
+			x, y := lerp(pos/length, a[0], a[1], b[0], b[1])
+			q := f32.Vec2{x, y}
+			if on {
+				cur.add(q)
+				ret = append(ret, cur)
+				cur = polyline{}
+			} else {
+				cur.pts = append(cur.pts, q)
+				cur.dot, cur.dir = true, unit(a, b)
+			}
+			i = (i + 1) % len(dashes)
+			rem, on, toggled = dashes[i], !on, true
+		}
+		rem -= length - pos
+		if on {
+			cur.add(b)
+		}
+	}
+	if !on {
+		return ret
+	}
+	if !toggled {
+		// The path is shorter than the first dash.
+		return []polyline{l}
+	}
+	if l.closed && startsOn && len(ret) > 0 {
+		ret[0].pts = append(cur.pts, ret[0].pts[1:]...)
+		return ret
+	}
+	return append(ret, cur)
+}
+
+// stroker accumulates the outlines of stroked polylines.
+type stroker struct {
+	out        Path
+	pen        f32.Vec2
+	started    bool
+	halfWidth  float32
+	miterLimit float32
+	cap        Cap
+	join       Join
+}
+
+func (k *stroker) lineTo(q f32.Vec2) {
+	if !k.started {
+		k.out.MoveTo(q[0], q[1])
+		k.pen, k.started = q, true
+	} else if k.pen != q {
+		k.out.LineTo(q[0], q[1])
+		k.pen = q
+	}
+}
+
+func (k *stroker) closePath() {
+	if k.started {
+		k.out.ClosePath()
+		k.started = false
+	}
+}
+
+// normal returns the vector of length k.halfWidth that is d, a unit vector,
+// rotated by 90 degrees, from the +X axis towards the +Y axis.
+func (k *stroker) normal(d f32.Vec2) f32.Vec2 {
+	return f32.Vec2{-d[1] * k.halfWidth, d[0] * k.halfWidth}
+}
+
+// stroke adds the outline of l. Each closed outline is the offset of l to the
+// side of its normals, followed by the offset to the other side of l
+// reversed, so that the outlines of all polylines wind in the same direction.
+func (k *stroker) stroke(l polyline) {
+	if len(l.pts) < 2 {
+		if l.dot && k.cap != ButtCap {
+			q, d := l.pts[0], l.dir
+			k.lineTo(add(q, k.normal(d)))
+			k.addCap(q, d)
+			k.addCap(q, f32.Vec2{-d[0], -d[1]})
+			k.closePath()
+		}
+		return
+	}
+	dirs := make([]f32.Vec2, len(l.pts))
+	for i, a := range l.pts {
+		dirs[i] = unit(a, l.pts[(i+1)%len(l.pts)])
+	}
+	if !l.closed {
+		dirs = dirs[:len(dirs)-1]
+	}
+
+	for side := 0; side < 2; side++ {
+		if side == 1 {
+			l.pts = reversed(l.pts)
+			if l.closed {
+				// The reversed segment from pts[i] is the old segment to
+				// pts[i].
+				d := reversed(dirs)
+				dirs = append(d[1:], d[0])
+			} else {
+				dirs = reversed(dirs)
+			}
+			for i, d := range dirs {
+				dirs[i] = f32.Vec2{-d[0], -d[1]}
+			}
+		}
+		pts := l.pts
+		if l.closed {
+			for i, q := range pts {
+				k.addJoin(q, dirs[(i+len(dirs)-1)%len(dirs)], dirs[i])
+			}
+			k.closePath()
+			continue
+		}
+		k.lineTo(add(pts[0], k.normal(dirs[0])))
+		for i := 1; i < len(pts)-1; i++ {
+			k.addJoin(pts[i], dirs[i-1], dirs[i])
+		}
+		last := len(pts) - 1
+		k.lineTo(add(pts[last], k.normal(dirs[last-1])))
+		k.addCap(pts[last], dirs[last-1])
+	}
+	if !l.closed {
+		k.closePath()
+	}
+}
+
+// addJoin adds the corner at q between the segments with unit directions d0
+// and d1, to the side of their normals.
+func (k *stroker) addJoin(q, d0, d1 f32.Vec2) {
+	n0, n1 := k.normal(d0), k.normal(d1)
+	k.lineTo(add(q, n0))
+	cross := d0[0]*d1[1] - d0[1]*d1[0]
+	dot := d0[0]*d1[0] + d0[1]*d1[1]
+	if cross > 0 {
+		// The corner turns towards this side. Going via q, instead of via
+		// the intersection of the two offsets, is simpler and fills the same
+		// region, as the two segments' outlines overlap there.
+		k.lineTo(q)
+		k.lineTo(add(q, n1))
+		return
+	}
+	if cross == 0 && dot > 0 {
+		return
+	}
+	switch k.join {
+	case MiterJoin:
+		// The miter is at q plus (n0 + n1) / (1 + cos θ), where θ is the
+		// angle between the two segments' directions, and its ratio to the
+		// stroke's width is 1 / cos(θ/2), which is sqrt(2 / (1 + cos θ)).
+		if den := 1 + dot; den > 0 && 2 <= k.miterLimit*k.miterLimit*den {
+			k.lineTo(f32.Vec2{q[0] + (n0[0]+n1[0])/den, q[1] + (n0[1]+n1[1])/den})
+		}
+	case RoundJoin:
+		angle := math.Atan2(math.Abs(float64(cross)), float64(dot))
+		k.arc(q, n0, -angle)
+	}
+	k.lineTo(add(q, n1))
+}
+
+// addCap adds the cap at the end point q of a segment with unit direction d,
+// from the side of its normal to the other side.
+func (k *stroker) addCap(q, d f32.Vec2) {
+	n := k.normal(d)
+	switch k.cap {
+	case RoundCap:
+		k.arc(q, n, -math.Pi)
+	case SquareCap:
+		e := f32.Vec2{d[0] * k.halfWidth, d[1] * k.halfWidth}
+		k.lineTo(add(add(q, n), e))
+		k.lineTo(add(sub(q, n), e))
+	}
+	k.lineTo(sub(q, n))
+}
+
+// arc adds a circular arc, centered on c, from the pen at c plus v, rotated
+// by the given angle in radians, as cubic Bézier curves.
+func (k *stroker) arc(c, v f32.Vec2, angle float64) {
+	n := int(math.Ceil(math.Abs(angle) / (math.Pi / 2)))
+	if n == 0 {
+		return
+	}
+	theta := angle / float64(n)
+	sin, cos := math.Sincos(theta)
+	// The control points are at a distance of 4/3 * tan(θ/4) times the
+	// radius along the tangents.
+	t := float32(4 * math.Tan(theta/4) / 3)
+	for i := 0; i < n; i++ {
+		w := f32.Vec2{
+			float32(float64(v[0])*cos - float64(v[1])*sin),
+			float32(float64(v[0])*sin + float64(v[1])*cos),
+		}
+		b := f32.Vec2{c[0] + v[0] - t*v[1], c[1] + v[1] + t*v[0]}
+		d := add(c, w)
+		k.out.CubeTo(b[0], b[1], d[0]+t*w[1], d[1]-t*w[0], d[0], d[1])
+		k.pen = d
+		v = w
+	}
+}
+
+func add(p, q f32.Vec2) f32.Vec2 { return f32.Vec2{p[0] + q[0], p[1] + q[1]} }
+func sub(p, q f32.Vec2) f32.Vec2 { return f32.Vec2{p[0] - q[0], p[1] - q[1]} }
+
+// unit returns the unit vector in the direction from a to b, which must not
+// be equal.
+func unit(a, b f32.Vec2) f32.Vec2 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	length := float32(math.Hypot(float64(dx), float64(dy)))
+	return f32.Vec2{dx / length, dy / length}
+}
+
+func reversed(s []f32.Vec2) []f32.Vec2 {
+	r := make([]f32.Vec2, len(s))
+	for i, v := range s {
+		r[len(s)-1-i] = v
+	}
+	return r
+}
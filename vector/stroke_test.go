@@ -0,0 +1,271 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+// strokeArea returns the area, in pixels, of p stroked with s and rasterized
+// onto a 64x64 mask.
+func strokeArea(p Path, s *Stroke) float64 {
+	z := NewRasterizer(64, 64)
+	p.AddStrokeTo(z, s)
+	sum := 0
+	for _, a := range z.Mask().Pix {
+		sum += int(a)
+	}
+	return float64(sum) / 0xff
+}
+
+// circleArea returns the area of a circle of radius r, as flattened and
+// rasterized by a Rasterizer, which is slightly less than π r².
+func circleArea(r float32) float64 {
+	k := 0.5522848 * r
+	var p Path
+	p.MoveTo(32+r, 32)
+	p.CubeTo(32+r, 32+k, 32+k, 32+r, 32, 32+r)
+	p.CubeTo(32-k, 32+r, 32-r, 32+k, 32-r, 32)
+	p.CubeTo(32-r, 32-k, 32-k, 32-r, 32, 32-r)
+	p.CubeTo(32+k, 32-r, 32+r, 32-k, 32+r, 32)
+	p.ClosePath()
+	z := NewRasterizer(64, 64)
+	p.AddTo(z)
+	sum := 0
+	for _, a := range z.Mask().Pix {
+		sum += int(a)
+	}
+	return float64(sum) / 0xff
+}
+
+func TestStrokeCaps(t *testing.T) {
+	var p Path
+	p.MoveTo(16, 32)
+	p.LineTo(48, 32)
+
+	const r = 4
+	testCases := []struct {
+		c    Cap
+		want float64
+	}{
+		{ButtCap, 32 * 2 * r},
+		{RoundCap, 32*2*r + circleArea(r)},
+		{SquareCap, (32 + 2*r) * 2 * r},
+	}
+	for _, tc := range testCases {
+		got := strokeArea(p, &Stroke{Width: 2 * r, Cap: tc.c})
+		if math.Abs(got-tc.want) > 0.1 {
+			t.Errorf("cap %d: got area %.2f, want %.2f", tc.c, got, tc.want)
+		}
+	}
+}
+
+func TestStrokeJoins(t *testing.T) {
+	// An L shape, stroked 4 pixels wide, is a 26x4 and a 4x22 rectangle,
+	// less part of the outer 2x2 corner for all but a MiterJoin.
+	var p Path
+	p.MoveTo(4, 4)
+	p.LineTo(28, 4)
+	p.LineTo(28, 28)
+
+	testCases := []struct {
+		j          Join
+		miterLimit float32
+		want       float64
+	}{
+		{MiterJoin, 0, 192},
+		{MiterJoin, 1.2, 190},
+		{RoundJoin, 0, 188 + circleArea(2)/4},
+		{BevelJoin, 0, 190},
+	}
+	for _, tc := range testCases {
+		got := strokeArea(p, &Stroke{Width: 4, Join: tc.j, MiterLimit: tc.miterLimit})
+		if math.Abs(got-tc.want) > 0.1 {
+			t.Errorf("join %d, miter limit %v: got area %.2f, want %.2f", tc.j, tc.miterLimit, got, tc.want)
+		}
+	}
+
+	// The same shape, traversed the other way, turns the other way.
+	var q Path
+	q.MoveTo(28, 28)
+	q.LineTo(28, 4)
+	q.LineTo(4, 4)
+	if got := strokeArea(q, &Stroke{Width: 4}); math.Abs(got-192) > 0.1 {
+		t.Errorf("reversed: got area %.2f, want 192", got)
+	}
+}
+
+func TestStrokeClosed(t *testing.T) {
+	// A closed square, stroked 4 pixels wide, is a 28x28 square less a 20x20
+	// square, for both directions around the path.
+	var cw, ccw Path
+	cw.MoveTo(8, 8)
+	cw.LineTo(32, 8)
+	cw.LineTo(32, 32)
+	cw.LineTo(8, 32)
+	cw.ClosePath()
+	ccw.MoveTo(8, 8)
+	ccw.LineTo(8, 32)
+	ccw.LineTo(32, 32)
+	ccw.LineTo(32, 8)
+	ccw.ClosePath()
+
+	const want = 28*28 - 20*20
+	for i, p := range []Path{cw, ccw} {
+		z := NewRasterizer(64, 64)
+		p.AddStrokeTo(z, &Stroke{Width: 4})
+		m := z.Mask()
+		if got := strokeArea(p, &Stroke{Width: 4}); math.Abs(got-want) > 0.1 {
+			t.Errorf("path #%d: got area %.2f, want %d", i, got, want)
+		}
+		if a := m.AlphaAt(20, 20).A; a != 0 {
+			t.Errorf("path #%d: inside alpha: got %d, want 0", i, a)
+		}
+		if a := m.AlphaAt(6, 6).A; a != 0xff {
+			t.Errorf("path #%d: corner alpha: got %d, want 255", i, a)
+		}
+	}
+}
+
+func TestStrokeDashes(t *testing.T) {
+	var p Path
+	p.MoveTo(8, 32)
+	p.LineTo(40, 32)
+
+	testCases := []struct {
+		dashes []float32
+		offset float32
+		want   []bool
+	}{
+		// Each want is whether there is a dash at x = 8.5, 9.5, etc.
+		{[]float32{4, 4}, 0, []bool{
+			true, true, true, true, false, false, false, false,
+			true, true, true, true, false, false, false, false,
+		}},
+		{[]float32{2, 4}, 3, []bool{
+			false, false, false, true, true, false, false, false,
+			false, true, true, false, false, false, false, true,
+		}},
+		// An odd number of lengths is repeated.
+		{[]float32{3}, 0, []bool{
+			true, true, true, false, false, false,
+			true, true, true, false, false, false,
+		}},
+		// A negative length means an undashed stroke.
+		{[]float32{4, -1}, 0, []bool{
+			true, true, true, true, true, true, true, true,
+		}},
+	}
+	for _, tc := range testCases {
+		z := NewRasterizer(64, 64)
+		p.AddStrokeTo(z, &Stroke{Width: 2, Dashes: tc.dashes, DashOffset: tc.offset})
+		m := z.Mask()
+		for i, want := range tc.want {
+			a := m.AlphaAt(8+i, 32).A
+			if got := a == 0xff; got != want || (a != 0 && a != 0xff) {
+				t.Errorf("dashes %v, offset %v: x=%d: got alpha %d, want dash %t", tc.dashes, tc.offset, 8+i, a, want)
+			}
+		}
+	}
+
+	// A closed path's first and last dashes are joined, so that a dash around
+	// each corner of a square makes 4 L shapes, each a 2x2 square with two
+	// 1x2 arms, with MiterJoins.
+	var sq Path
+	sq.MoveTo(8, 8)
+	sq.LineTo(24, 8)
+	sq.LineTo(24, 24)
+	sq.LineTo(8, 24)
+	sq.ClosePath()
+	s := &Stroke{Width: 2, Dashes: []float32{4, 12}, DashOffset: 2}
+	if got, want := strokeArea(sq, s), 4*8; math.Abs(got-float64(want)) > 0.1 {
+		t.Errorf("closed path: got area %.2f, want %d", got, want)
+	}
+}
+
+// TestStrokeDashLimits checks that dash patterns that are too fine, or that
+// would split a path into too many dashes, and paths with non-finite
+// coordinates, are stroked undashed instead of exhausting memory or time.
+func TestStrokeDashLimits(t *testing.T) {
+	line := func(x0, x1 float32) Path {
+		var p Path
+		p.MoveTo(x0, 32)
+		p.LineTo(x1, 32)
+		return p
+	}
+	inf := float32(math.Inf(+1))
+	testCases := []struct {
+		name   string
+		p      Path
+		dashes []float32
+	}{
+		{"tiny period", line(0, 100), []float32{0, 0, 0, 1e-30}},
+		{"fine period", line(0, 100), []float32{1e-4, 1e-4}},
+		{"too many dashes", line(0, 1e6), []float32{1.0 / 32, 1.0 / 32}},
+		{"infinite coordinate", line(0, inf), []float32{4, 4}},
+		{"NaN coordinate", line(0, float32(math.NaN())), []float32{4, 4}},
+	}
+	for _, tc := range testCases {
+		got := tc.p.Stroke(&Stroke{Width: 2, Dashes: tc.dashes})
+		want := tc.p.Stroke(&Stroke{Width: 2})
+		if len(got) != len(want) {
+			t.Errorf("%s: got %d segments, want %d, as for an undashed stroke", tc.name, len(got), len(want))
+		}
+	}
+
+	// The smallest allowed period still dashes the stroke.
+	p := line(0, 100)
+	got := p.Stroke(&Stroke{Width: 2, Dashes: []float32{1.0 / 128, 1.0 / 128}})
+	if want := p.Stroke(&Stroke{Width: 2}); len(got) <= len(want) {
+		t.Errorf("period 1/64: got %d segments, want more than %d", len(got), len(want))
+	}
+}
+
+func TestStrokeDots(t *testing.T) {
+	var p Path
+	p.MoveTo(32, 32)
+	p.ClosePath()
+
+	testCases := []struct {
+		c    Cap
+		want float64
+	}{
+		{ButtCap, 0},
+		{RoundCap, circleArea(4)},
+		{SquareCap, 8 * 8},
+	}
+	for _, tc := range testCases {
+		got := strokeArea(p, &Stroke{Width: 8, Cap: tc.c})
+		if math.Abs(got-tc.want) > 0.1 {
+			t.Errorf("cap %d: got area %.2f, want %.2f", tc.c, got, tc.want)
+		}
+	}
+
+	// A single MoveTo has no stroke.
+	var q Path
+	q.MoveTo(32, 32)
+	if n := len(q.Stroke(&Stroke{Cap: RoundCap})); n != 0 {
+		t.Errorf("MoveTo: got %d segments, want 0", n)
+	}
+}
+
+func TestStrokeCurve(t *testing.T) {
+	// A circle of radius 16, stroked 4 pixels wide, is approximately an
+	// annulus.
+	const k = 0.5522848 * 16
+	var p Path
+	p.MoveTo(48, 32)
+	p.CubeTo(48, 32+k, 32+k, 48, 32, 48)
+	p.CubeTo(32-k, 48, 16, 32+k, 16, 32)
+	p.CubeTo(16, 32-k, 32-k, 16, 32, 16)
+	p.CubeTo(32+k, 16, 48, 32-k, 48, 32)
+	p.ClosePath()
+
+	want := math.Pi * (18*18 - 14*14)
+	if got := strokeArea(p, &Stroke{Width: 4}); math.Abs(got-want)/want > 0.01 {
+		t.Errorf("got area %.2f, want %.2f", got, want)
+	}
+}
@@ -92,6 +92,11 @@ type Rasterizer struct {
 	bufF32 []float32
 	bufU32 []uint32
 
+	// bufMask holds the accumulated mask values, computed by accumulateMask
+	// from bufF32 or bufU32, leaving those unchanged so that Draw can be
+	// called more than once.
+	bufMask []uint32
+
 	useFloatingPointMath bool
 
 	size   image.Point
@@ -105,13 +110,43 @@ type Rasterizer struct {
 	// The zero value is draw.Over.
 	DrawOp draw.Op
 
+	// Hairline is whether subsequent LineTo, QuadTo, CubeTo and ClosePath
+	// calls stroke their segments as antialiased lines exactly 1 pixel wide,
+	// instead of adding them to a filled path. The width is in the
+	// Rasterizer's (pixel) coordinate space, so it does not depend on any
+	// transformation applied to the points beforehand. Strokes have butt
+	// ends, and overlapping strokes do not accumulate more than full
+	// coverage.
+	//
+	// Hairline and filled segments can be mixed, but a filled path that
+	// winds counter-clockwise (in the Y-down coordinate space) can cancel
+	// out overlapping strokes, so it is simplest to rasterize them
+	// separately.
+	//
+	// The zero value is false.
+	Hairline bool
+
+	// RecordEdges is whether subsequent XxxTo calls also record the line
+	// segments that they add, after Bézier curves are flattened and
+	// hairlines are expanded, so that they can be retrieved by Edges.
+	//
+	// The zero value is false.
+	RecordEdges bool
+
+	edges []Edge
+
+	// clip, if non-nil, is the coverage, from 0 to 0xffff, of each pixel of
+	// the clip set by ClipTo or ClipToMask.
+	clip []uint32
+
 	// TODO: an exported field equivalent to the mask point in the
 	// draw.DrawMask function in the stdlib image/draw package?
 }
 
 // Reset resets a Rasterizer as if it was just returned by NewRasterizer.
 //
-// This includes setting z.DrawOp to draw.Over.
+// This includes setting z.DrawOp to draw.Over, z.Hairline and z.RecordEdges
+// to false, and discarding any recorded edges and clip.
 func (z *Rasterizer) Reset(w, h int) {
 	z.size = image.Point{w, h}
 	z.firstX = 0
@@ -119,6 +154,10 @@ func (z *Rasterizer) Reset(w, h int) {
 	z.penX = 0
 	z.penY = 0
 	z.DrawOp = draw.Over
+	z.Hairline = false
+	z.RecordEdges = false
+	z.edges = z.edges[:0]
+	z.clip = nil
 
 	z.setUseFloatingPointMath(w > floatingPointMathThreshold || h > floatingPointMathThreshold)
 }
@@ -185,6 +224,20 @@ func (z *Rasterizer) MoveTo(ax, ay float32) {
 //
 // The coordinates are allowed to be out of the Rasterizer's bounds.
 func (z *Rasterizer) LineTo(bx, by float32) {
+	if z.Hairline {
+		z.hairlineTo(bx, by)
+	} else {
+		z.lineTo(bx, by)
+	}
+}
+
+// lineTo adds the line segment from the pen to (bx, by) to the accumulation
+// buffer, and to the recorded edges if z.RecordEdges, and moves the pen to
+// (bx, by).
+func (z *Rasterizer) lineTo(bx, by float32) {
+	if z.RecordEdges {
+		z.edges = append(z.edges, Edge{z.penX, z.penY, bx, by})
+	}
 	if z.useFloatingPointMath {
 		z.floatingLineTo(bx, by)
 	} else {
@@ -192,6 +245,34 @@ func (z *Rasterizer) LineTo(bx, by float32) {
 	}
 }
 
+// hairlineTo strokes the line segment from the pen to (bx, by), 1 pixel wide,
+// and moves the pen to (bx, by).
+//
+// The stroke is added as a closed quadrilateral, offset half a pixel either
+// side of the segment. The quadrilateral is always traversed in the same
+// rotational direction, regardless of the segment's direction, so that the
+// signed areas of overlapping strokes add instead of cancelling.
+func (z *Rasterizer) hairlineTo(bx, by float32) {
+	ax, ay := z.penX, z.penY
+	defer func() {
+		z.penX, z.penY = bx, by
+	}()
+
+	dx, dy := bx-ax, by-ay
+	d := float32(math.Sqrt(float64(dx*dx + dy*dy)))
+	if d == 0 {
+		return
+	}
+	// (nx, ny) is perpendicular to (dx, dy), with length 0.5.
+	nx, ny := -dy/(2*d), dx/(2*d)
+
+	z.penX, z.penY = ax+nx, ay+ny
+	z.lineTo(bx+nx, by+ny)
+	z.lineTo(bx-nx, by-ny)
+	z.lineTo(ax-nx, ay-ny)
+	z.lineTo(ax+nx, ay+ny)
+}
+
 // QuadTo adds a quadratic Bézier segment, from the pen via (bx, by) to (cx,
 // cy), and moves the pen to (cx, cy).
 //
@@ -264,7 +345,9 @@ func devSquared(ax, ay, bx, by, cx, cy float32) float32 {
 // package.
 //
 // The vector paths previously added via the XxxTo calls become the mask for
-// drawing src onto dst.
+// drawing src onto dst. Draw does not consume those paths, so it can be called
+// more than once, with different dst or src arguments, without adding the
+// paths again.
 func (z *Rasterizer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
 	// TODO: adjust r and sp (and mp?) if src.Bounds() doesn't contain
 	// r.Add(sp.Sub(r.Min)).
@@ -299,30 +382,69 @@ func (z *Rasterizer) Draw(dst draw.Image, r image.Rectangle, src image.Image, sp
 	}
 }
 
-func (z *Rasterizer) accumulateMask() {
-	if z.useFloatingPointMath {
-		if n := z.size.X * z.size.Y; n > cap(z.bufU32) {
-			z.bufU32 = make([]uint32, n)
+// Mask returns the coverage of the vector paths previously added via the
+// XxxTo calls, as an image whose bounds are z.Bounds(). It is what Draw would
+// paint onto a transparent *image.Alpha with an opaque src.
+//
+// The mask does not share memory with z, and calling Mask does not change z,
+// so that the mask remains valid after z is Reset. Callers can cache the mask,
+// for example for static shapes across the frames of a user interface, and
+// composite it onto any number of destinations with any src by calling the
+// standard library's image/draw.DrawMask function, instead of rasterizing the
+// paths again.
+func (z *Rasterizer) Mask() *image.Alpha {
+	m := image.NewAlpha(z.Bounds())
+	if z.clip != nil {
+		z.accumulateMask()
+		for i, ma := range z.bufMask {
+			m.Pix[i] = uint8(ma >> 8)
+		}
+	} else if z.useFloatingPointMath {
+		if haveAccumulateSIMD {
+			floatingAccumulateOpSrcSIMD(m.Pix, z.bufF32)
 		} else {
-			z.bufU32 = z.bufU32[:n]
+			floatingAccumulateOpSrc(m.Pix, z.bufF32)
 		}
+	} else {
+		if haveAccumulateSIMD {
+			fixedAccumulateOpSrcSIMD(m.Pix, z.bufU32)
+		} else {
+			fixedAccumulateOpSrc(m.Pix, z.bufU32)
+		}
+	}
+	return m
+}
+
+func (z *Rasterizer) accumulateMask() {
+	if n := z.size.X * z.size.Y; n > cap(z.bufMask) {
+		z.bufMask = make([]uint32, n)
+	} else {
+		z.bufMask = z.bufMask[:n]
+	}
+	if z.useFloatingPointMath {
 		if haveAccumulateSIMD {
-			floatingAccumulateMaskSIMD(z.bufU32, z.bufF32)
+			floatingAccumulateMaskSIMD(z.bufMask, z.bufF32)
 		} else {
-			floatingAccumulateMask(z.bufU32, z.bufF32)
+			floatingAccumulateMask(z.bufMask, z.bufF32)
 		}
 	} else {
+		copy(z.bufMask, z.bufU32)
 		if haveAccumulateSIMD {
-			fixedAccumulateMaskSIMD(z.bufU32)
+			fixedAccumulateMaskSIMD(z.bufMask)
 		} else {
-			fixedAccumulateMask(z.bufU32)
+			fixedAccumulateMask(z.bufMask)
+		}
+	}
+	if z.clip != nil {
+		for i, c := range z.clip {
+			z.bufMask[i] = z.bufMask[i] * c / 0xffff
 		}
 	}
 }
 
 func (z *Rasterizer) rasterizeDstAlphaSrcOpaqueOpOver(dst *image.Alpha, r image.Rectangle) {
 	// TODO: non-zero vs even-odd winding?
-	if r == dst.Bounds() && r == z.Bounds() {
+	if r == dst.Bounds() && r == z.Bounds() && z.clip == nil {
 		// We bypass the z.accumulateMask step and convert straight from
 		// z.bufF32 or z.bufU32 to dst.Pix.
 		if z.useFloatingPointMath {
@@ -345,7 +467,7 @@ func (z *Rasterizer) rasterizeDstAlphaSrcOpaqueOpOver(dst *image.Alpha, r image.
 	pix := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y):]
 	for y, y1 := 0, r.Max.Y-r.Min.Y; y < y1; y++ {
 		for x, x1 := 0, r.Max.X-r.Min.X; x < x1; x++ {
-			ma := z.bufU32[y*z.size.X+x]
+			ma := z.bufMask[y*z.size.X+x]
 			i := y*dst.Stride + x
 
 			// This formula is like rasterizeOpOver's, simplified for the
@@ -358,7 +480,7 @@ func (z *Rasterizer) rasterizeDstAlphaSrcOpaqueOpOver(dst *image.Alpha, r image.
 
 func (z *Rasterizer) rasterizeDstAlphaSrcOpaqueOpSrc(dst *image.Alpha, r image.Rectangle) {
 	// TODO: non-zero vs even-odd winding?
-	if r == dst.Bounds() && r == z.Bounds() {
+	if r == dst.Bounds() && r == z.Bounds() && z.clip == nil {
 		// We bypass the z.accumulateMask step and convert straight from
 		// z.bufF32 or z.bufU32 to dst.Pix.
 		if z.useFloatingPointMath {
@@ -381,7 +503,7 @@ func (z *Rasterizer) rasterizeDstAlphaSrcOpaqueOpSrc(dst *image.Alpha, r image.R
 	pix := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y):]
 	for y, y1 := 0, r.Max.Y-r.Min.Y; y < y1; y++ {
 		for x, x1 := 0, r.Max.X-r.Min.X; x < x1; x++ {
-			ma := z.bufU32[y*z.size.X+x]
+			ma := z.bufMask[y*z.size.X+x]
 
 			// This formula is like rasterizeOpSrc's, simplified for the
 			// concrete dst type and opaque src assumption.
@@ -395,7 +517,7 @@ func (z *Rasterizer) rasterizeDstRGBASrcUniformOpOver(dst *image.RGBA, r image.R
 	pix := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y):]
 	for y, y1 := 0, r.Max.Y-r.Min.Y; y < y1; y++ {
 		for x, x1 := 0, r.Max.X-r.Min.X; x < x1; x++ {
-			ma := z.bufU32[y*z.size.X+x]
+			ma := z.bufMask[y*z.size.X+x]
 
 			// This formula is like rasterizeOpOver's, simplified for the
 			// concrete dst type and uniform src assumption.
@@ -414,7 +536,7 @@ func (z *Rasterizer) rasterizeDstRGBASrcUniformOpSrc(dst *image.RGBA, r image.Re
 	pix := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y):]
 	for y, y1 := 0, r.Max.Y-r.Min.Y; y < y1; y++ {
 		for x, x1 := 0, r.Max.X-r.Min.X; x < x1; x++ {
-			ma := z.bufU32[y*z.size.X+x]
+			ma := z.bufMask[y*z.size.X+x]
 
 			// This formula is like rasterizeOpSrc's, simplified for the
 			// concrete dst type and uniform src assumption.
@@ -427,14 +549,25 @@ func (z *Rasterizer) rasterizeDstRGBASrcUniformOpSrc(dst *image.RGBA, r image.Re
 	}
 }
 
+// srcRGBA returns the color of src at (x, y), reading it via rs, if non-nil,
+// which is src as an rgba64Source.
+func srcRGBA(src image.Image, rs rgba64Source, x, y int) (r, g, b, a uint32) {
+	if rs != nil {
+		c := rs.rgba64At(x, y)
+		return uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+	}
+	return src.At(x, y).RGBA()
+}
+
 func (z *Rasterizer) rasterizeOpOver(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point) {
 	z.accumulateMask()
 	out := color.RGBA64{}
 	outc := color.Color(&out)
+	rs, _ := src.(rgba64Source)
 	for y, y1 := 0, r.Max.Y-r.Min.Y; y < y1; y++ {
 		for x, x1 := 0, r.Max.X-r.Min.X; x < x1; x++ {
-			sr, sg, sb, sa := src.At(sp.X+x, sp.Y+y).RGBA()
-			ma := z.bufU32[y*z.size.X+x]
+			sr, sg, sb, sa := srcRGBA(src, rs, sp.X+x, sp.Y+y)
+			ma := z.bufMask[y*z.size.X+x]
 
 			// This algorithm comes from the standard library's image/draw
 			// package.
@@ -454,10 +587,11 @@ func (z *Rasterizer) rasterizeOpSrc(dst draw.Image, r image.Rectangle, src image
 	z.accumulateMask()
 	out := color.RGBA64{}
 	outc := color.Color(&out)
+	rs, _ := src.(rgba64Source)
 	for y, y1 := 0, r.Max.Y-r.Min.Y; y < y1; y++ {
 		for x, x1 := 0, r.Max.X-r.Min.X; x < x1; x++ {
-			sr, sg, sb, sa := src.At(sp.X+x, sp.Y+y).RGBA()
-			ma := z.bufU32[y*z.size.X+x]
+			sr, sg, sb, sa := srcRGBA(src, rs, sp.X+x, sp.Y+y)
+			ma := z.bufMask[y*z.size.X+x]
 
 			// This algorithm comes from the standard library's image/draw
 			// package.
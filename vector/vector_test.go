@@ -7,6 +7,7 @@ package vector
 // TODO: add tests for NaN and Inf coordinates.
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
@@ -517,3 +518,205 @@ func BenchmarkGlyphNRGBA256Over(b *testing.B)  { benchGlyph(b, 'N', false, 256,
 func BenchmarkGlyphNRGBA256Src(b *testing.B)   { benchGlyph(b, 'N', false, 256, draw.Src) }
 func BenchmarkGlyphNRGBA1024Over(b *testing.B) { benchGlyph(b, 'N', false, 1024, draw.Over) }
 func BenchmarkGlyphNRGBA1024Src(b *testing.B)  { benchGlyph(b, 'N', false, 1024, draw.Src) }
+
+func TestMaskAndRepeatedDraw(t *testing.T) {
+	for _, useFloatingPointMath := range []bool{false, true} {
+		z := NewRasterizer(16, 16)
+		z.setUseFloatingPointMath(useFloatingPointMath)
+		z.MoveTo(2, 2)
+		z.LineTo(8, 2)
+		z.QuadTo(14, 2, 14, 14)
+		z.CubeTo(8, 2, 5, 20, 2, 8)
+		z.ClosePath()
+
+		want := image.NewAlpha(z.Bounds())
+		z.DrawOp = draw.Src
+		z.Draw(want, want.Bounds(), image.Opaque, image.Point{})
+
+		mask := z.Mask()
+		if !bytes.Equal(mask.Pix, want.Pix) {
+			t.Errorf("useFloatingPointMath=%t: Mask differs from Draw", useFloatingPointMath)
+			continue
+		}
+
+		// Drawing again, including via the slow path, should neither change
+		// the mask nor depend on the previous Draw calls.
+		src := image.NewUniform(color.RGBA{0x00, 0x40, 0x80, 0x80})
+		z.DrawOp = draw.Over
+		var dsts [2]*image.RGBA
+		for i := range dsts {
+			dsts[i] = image.NewRGBA(z.Bounds())
+			z.Draw(dsts[i], z.Bounds(), src, image.Point{})
+			z.Draw(image.NewNRGBA(z.Bounds()), z.Bounds(), src, image.Point{})
+		}
+		if !bytes.Equal(dsts[0].Pix, dsts[1].Pix) {
+			t.Errorf("useFloatingPointMath=%t: repeated Draw calls differ", useFloatingPointMath)
+		}
+		if got := z.Mask(); !bytes.Equal(got.Pix, mask.Pix) {
+			t.Errorf("useFloatingPointMath=%t: Mask changed after Draw", useFloatingPointMath)
+		}
+
+		// Compositing the mask with the standard library should match
+		// drawing the paths directly.
+		cached := image.NewRGBA(z.Bounds())
+		draw.DrawMask(cached, cached.Bounds(), src, image.Point{}, mask, image.Point{}, draw.Over)
+		for i := range cached.Pix {
+			if delta := int(cached.Pix[i]) - int(dsts[0].Pix[i]); delta < -2 || +2 < delta {
+				t.Errorf("useFloatingPointMath=%t: i=%d: DrawMask gave %#02x, Draw gave %#02x",
+					useFloatingPointMath, i, cached.Pix[i], dsts[0].Pix[i])
+				break
+			}
+		}
+	}
+}
+
+func TestHairline(t *testing.T) {
+	for _, useFloatingPointMath := range []bool{false, true} {
+		z := NewRasterizer(16, 16)
+		z.setUseFloatingPointMath(useFloatingPointMath)
+		z.Hairline = true
+
+		// A horizontal hairline along pixel centers fully covers one row.
+		z.MoveTo(2, 4.5)
+		z.LineTo(12, 4.5)
+		// Going back over the same line should not change the coverage.
+		z.LineTo(2, 4.5)
+		// A vertical hairline along pixel boundaries half covers two columns.
+		z.MoveTo(8, 8)
+		z.LineTo(8, 14)
+
+		mask := z.Mask()
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				want := 0x00
+				if y == 4 && 2 <= x && x < 12 {
+					want = 0xff
+				} else if 8 <= y && y < 14 && (x == 7 || x == 8) {
+					want = 0x80
+				}
+				if got := int(mask.AlphaAt(x, y).A); got < want-1 || want+1 < got {
+					t.Errorf("useFloatingPointMath=%t: (%d, %d): got %#02x, want %#02x",
+						useFloatingPointMath, x, y, got, want)
+				}
+			}
+		}
+
+		// The total coverage of a diagonal hairline is its length.
+		z.Reset(16, 16)
+		z.setUseFloatingPointMath(useFloatingPointMath)
+		z.Hairline = true
+		z.MoveTo(3, 2)
+		z.LineTo(11, 8)
+		sum := 0
+		for _, p := range z.Mask().Pix {
+			sum += int(p)
+		}
+		if got, want := float64(sum)/0xff, 10.0; math.Abs(got-want) > 0.1 {
+			t.Errorf("useFloatingPointMath=%t: diagonal coverage: got %.3f, want %.3f",
+				useFloatingPointMath, got, want)
+		}
+	}
+}
+
+func TestEdges(t *testing.T) {
+	z := NewRasterizer(16, 16)
+	z.MoveTo(1, 1)
+	z.LineTo(2, 2)
+	if got := len(z.Edges()); got != 0 {
+		t.Fatalf("RecordEdges=false: got %d edges, want 0", got)
+	}
+
+	z.RecordEdges = true
+	z.MoveTo(2, 2)
+	z.QuadTo(14, 2, 14, 14)
+	z.ClosePath()
+	edges := z.Edges()
+	if len(edges) < 3 {
+		t.Fatalf("got %d edges, want at least 3", len(edges))
+	}
+	// The edges form a closed chain, from the curve's start to its end and
+	// back.
+	for i, e := range edges {
+		next := edges[(i+1)%len(edges)]
+		if e.X1 != next.X0 || e.Y1 != next.Y0 {
+			t.Errorf("edge %d ends at (%v, %v) but edge %d starts at (%v, %v)",
+				i, e.X1, e.Y1, (i+1)%len(edges), next.X0, next.Y0)
+		}
+	}
+	if e := edges[len(edges)-2]; e.X1 != 14 || e.Y1 != 14 {
+		t.Errorf("curve ends at (%v, %v), want (14, 14)", e.X1, e.Y1)
+	}
+
+	// A hairline is expanded to a quadrilateral.
+	z.Reset(16, 16)
+	z.RecordEdges = true
+	z.Hairline = true
+	z.MoveTo(2, 4)
+	z.LineTo(12, 4)
+	want := []Edge{
+		{2, 4.5, 12, 4.5},
+		{12, 4.5, 12, 3.5},
+		{12, 3.5, 2, 3.5},
+		{2, 3.5, 2, 4.5},
+	}
+	if got := z.Edges(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("hairline: got %v, want %v", got, want)
+	}
+}
+
+func trapezoidArea(ts []Trapezoid) float64 {
+	area := 0.0
+	for _, t := range ts {
+		area += float64(t.Y1-t.Y0) * float64(t.Right0-t.Left0+t.Right1-t.Left1) / 2
+	}
+	return area
+}
+
+func TestTrapezoids(t *testing.T) {
+	square := []Edge{
+		{2, 2, 10, 2},
+		{10, 2, 10, 10},
+		{10, 10, 2, 10},
+		{2, 10, 2, 2},
+	}
+	got := Trapezoids(square)
+	want := []Trapezoid{{Y0: 2, Y1: 10, Left0: 2, Left1: 2, Right0: 10, Right1: 10}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("square: got %v, want %v", got, want)
+	}
+
+	// Under the non-zero winding rule, the pentagon at the center of a
+	// pentagram is filled, as is the region covered by two overlapping
+	// squares with the same winding. The total area of the trapezoids
+	// matches the coverage that the Rasterizer computes.
+	z := NewRasterizer(64, 64)
+	z.RecordEdges = true
+	z.MoveTo(pointOnCircle(32, 28, 0, 5))
+	for i := 1; i <= 5; i++ {
+		z.LineTo(pointOnCircle(32, 28, 2*i, 5))
+	}
+	z.MoveTo(4, 4)
+	z.LineTo(20, 4)
+	z.LineTo(20, 20)
+	z.LineTo(4, 20)
+	z.ClosePath()
+	z.MoveTo(10, 10)
+	z.LineTo(26, 10)
+	z.LineTo(26, 26)
+	z.LineTo(10, 26)
+	z.ClosePath()
+
+	ts := Trapezoids(z.Edges())
+	for i, tr := range ts {
+		if tr.Y0 >= tr.Y1 || tr.Left0 > tr.Right0 || tr.Left1 > tr.Right1 {
+			t.Errorf("trapezoid %d is degenerate: %v", i, tr)
+		}
+	}
+	sum := 0
+	for _, p := range z.Mask().Pix {
+		sum += int(p)
+	}
+	if got, want := trapezoidArea(ts), float64(sum)/0xff; math.Abs(got-want) > 1 {
+		t.Errorf("area: got %.3f, want %.3f", got, want)
+	}
+}
@@ -4,12 +4,18 @@
 
 // Package vp8 implements a decoder for the VP8 lossy image format.
 //
+// As well as the key frames of still images, such as those in WebP files, it
+// decodes the interframes of VP8 video, such as the frames of IVF and WebM
+// files, which are predicted from previously decoded reference frames. It
+// also encodes images as key frames.
+//
 // The VP8 specification is RFC 6386.
 package vp8 // import "golang.org/x/image/vp8"
 
 // This file implements the top-level decoding algorithm.
 
 import (
+	"bytes"
 	"errors"
 	"image"
 	"io"
@@ -90,13 +96,20 @@ type mb struct {
 }
 
 // Decoder decodes VP8 bitstreams into frames. Decoding one frame consists of
-// calling Init, DecodeFrameHeader and then DecodeFrame in that order.
-// A Decoder can be re-used to decode multiple frames.
+// calling Init, DecodeFrameHeader and then DecodeFrame in that order, or of
+// calling Decode.
+//
+// A Decoder can be re-used to decode multiple frames. The successive frames
+// of a video are decoded with the same Decoder, in order, starting with a key
+// frame, as the Decoder keeps the reference frames and probabilities that
+// interframes are decoded with.
 type Decoder struct {
 	// r is the input bitsream.
 	r limitReader
 	// scratch is a scratch buffer.
 	scratch [8]byte
+	// concurrent is whether to apply the loop filter on a second goroutine.
+	concurrent bool
 	// img is the YCbCr image to decode into.
 	img *image.YCbCr
 	// mbw and mbh are the number of 16x16 macroblocks wide and high the image is.
@@ -121,20 +134,59 @@ type Decoder struct {
 	useSkipProb bool
 	skipProb    uint8
 	// Loop filter parameters.
-	filterParams      [nSegment][2]filterParam
+	filterParams      [nSegment][nRefFrame][nModeLFDelta]filterParam
 	perMBFilterParams []filterParam
+	// segments holds the segment of each macroblock, which interframes
+	// inherit unless they update the segment map.
+	segments []uint8
+
+	// The fields below relate to interframes, which are predicted from the
+	// reference frames, as specified in section 9.7.
+	//
+	// ref holds the last, golden and alternate reference frames, indexed by
+	// refLast, refGolden and refAltRef. They are nil before the first key
+	// frame. frames holds the frame buffers that ref and img are taken from.
+	ref    [nRefFrame]*image.YCbCr
+	frames []*image.YCbCr
+	// signBias is the sign bias of each reference frame's motion vectors.
+	signBias [nRefFrame]bool
+	// refresh is whether each reference frame is replaced by this frame. If
+	// not, copyTo is, for the golden and alternate reference frames, whether
+	// it is replaced by another reference frame: 0 for none, 1 for the last
+	// frame and 2 for the alternate or golden reference frame, respectively.
+	refresh [nRefFrame]bool
+	copyTo  [nRefFrame]uint8
+	// refreshProb is whether the probabilities updated by this frame are
+	// kept for the next frame. If not, savedProb holds those to restore.
+	refreshProb bool
+	savedProb   probs
+	// Mode and motion vector decoding probabilities.
+	ymodeProb  [nYMode - 1]uint8
+	uvmodeProb [nUVMode - 1]uint8
+	mvProb     [2][nMVProb]uint8
+	// Reference frame probabilities.
+	intraProb, lastProb, goldenProb uint8
+	// mbInfo holds the reference frame and motion vectors of each macroblock
+	// of the frame.
+	mbInfo []mbInfo
 
-	// The eight fields below relate to the current macroblock being decoded.
+	// The fields below relate to the current macroblock being decoded.
 	//
 	// Segment-based adjustments.
 	segment int
+	// inter is whether the macroblock is predicted from a reference frame,
+	// and lfMode is its mode for the loop filter, as specified in section
+	// 9.6.
+	inter  bool
+	lfMode uint8
 	// Per-macroblock state for the macroblock immediately left of and those
 	// macroblocks immediately above the current macroblock.
 	leftMB mb
 	upMB   []mb
 	// Bitmasks for which 4x4 regions of coeff contain non-zero coefficients.
 	nzDCMask, nzACMask uint32
-	// Predictor modes.
+	// Predictor modes. usePredY16 is also true for inter-predicted
+	// macroblocks with a single motion vector.
 	usePredY16 bool // The libwebp C code calls this !is_i4x4_.
 	predY16    uint8
 	predC8     uint8
@@ -151,6 +203,15 @@ func NewDecoder() *Decoder {
 	return &Decoder{}
 }
 
+// SetConcurrent sets whether the Decoder decodes each frame with two
+// goroutines instead of one. Like libwebp's multi-threaded decoding, one
+// goroutine parses and reconstructs the rows of macroblocks while the other
+// applies the loop filter to the rows that are complete, one row behind. The
+// decoded frames are the same either way.
+func (d *Decoder) SetConcurrent(concurrent bool) {
+	d.concurrent = concurrent
+}
+
 // Init initializes the decoder to read at most n bytes from r.
 func (d *Decoder) Init(r io.Reader, n int) {
 	d.r = limitReader{r, n}
@@ -182,30 +243,115 @@ func (d *Decoder) DecodeFrameHeader() (fh FrameHeader, err error) {
 	}
 	d.frameHeader.Width = int(b[4]&0x3f)<<8 | int(b[3])
 	d.frameHeader.Height = int(b[6]&0x3f)<<8 | int(b[5])
+	if d.frameHeader.Width == 0 || d.frameHeader.Height == 0 {
+		// A frame has at least one macroblock, which the reconstruction and
+		// the loop filter assume.
+		err = errors.New("vp8: zero width or height")
+		return
+	}
 	d.frameHeader.XScale = b[4] >> 6
 	d.frameHeader.YScale = b[6] >> 6
 	d.mbw = (d.frameHeader.Width + 0x0f) >> 4
 	d.mbh = (d.frameHeader.Height + 0x0f) >> 4
 	d.segmentHeader = segmentHeader{
-		prob: [3]uint8{0xff, 0xff, 0xff},
+		relativeDelta: true,
+		prob:          [3]uint8{0xff, 0xff, 0xff},
 	}
+	d.filterHeader.refLFDelta = [nRefLFDelta]int8{}
+	d.filterHeader.modeLFDelta = [nModeLFDelta]int8{}
 	d.tokenProb = defaultTokenProb
+	d.ymodeProb = defaultYModeProb
+	d.uvmodeProb = defaultUVModeProb
+	d.mvProb = defaultMVProb
+	d.signBias = [nRefFrame]bool{}
 	d.segment = 0
 	return d.frameHeader, nil
 }
 
-// ensureImg ensures that d.img is large enough to hold the decoded frame.
-func (d *Decoder) ensureImg() {
-	if d.img != nil {
-		p0, p1 := d.img.Rect.Min, d.img.Rect.Max
-		if p0.X == 0 && p0.Y == 0 && p1.X >= 16*d.mbw && p1.Y >= 16*d.mbh {
-			return
+// Decode decodes a frame whose compressed data, including its frame header,
+// is p, such as a frame of an IVF file or a block of a WebM file. It is
+// equivalent to calling Init, DecodeFrameHeader and DecodeFrame.
+//
+// Frames whose header's ShowFrame is false, such as alternate reference
+// frames, are decoded for the frames that follow, but are not meant to be
+// displayed.
+func (d *Decoder) Decode(p []byte) (FrameHeader, *image.YCbCr, error) {
+	d.Init(bytes.NewReader(p), len(p))
+	fh, err := d.DecodeFrameHeader()
+	if err != nil {
+		return fh, nil, err
+	}
+	m, err := d.DecodeFrame()
+	return fh, m, err
+}
+
+// ensureImg ensures that d.img is large enough to hold the decoded frame, and
+// is not one of the reference frames. If dst is non-nil and can hold the
+// frame, d.img shares dst's pixels.
+func (d *Decoder) ensureImg(dst *image.YCbCr) {
+	d.img = d.fitImg(dst)
+	for i, m := range d.frames {
+		if d.img != nil {
+			break
+		}
+		if m == d.ref[refLast] || m == d.ref[refGolden] || m == d.ref[refAltRef] {
+			continue
+		}
+		// The frame buffers are whole macroblocks wide and high.
+		if m.YStride == 16*d.mbw && len(m.Y) == 16*d.mbw*16*d.mbh {
+			d.img = m.SubImage(image.Rect(0, 0, d.frameHeader.Width, d.frameHeader.Height)).(*image.YCbCr)
+			d.frames[i] = d.img
+			break
+		}
+	}
+	if d.img == nil {
+		// Frame buffers that are too small, such as those of a previous key
+		// frame of a smaller size, are no longer needed once they are not
+		// reference frames.
+		frames := d.frames[:0]
+		for _, m := range d.frames {
+			if m == d.ref[refLast] || m == d.ref[refGolden] || m == d.ref[refAltRef] {
+				frames = append(frames, m)
+			}
+		}
+		m := image.NewYCbCr(image.Rect(0, 0, 16*d.mbw, 16*d.mbh), image.YCbCrSubsampleRatio420)
+		d.img = m.SubImage(image.Rect(0, 0, d.frameHeader.Width, d.frameHeader.Height)).(*image.YCbCr)
+		d.frames = append(frames, d.img)
+	}
+	if n := d.mbw * d.mbh; len(d.perMBFilterParams) != n || len(d.upMB) != d.mbw {
+		d.perMBFilterParams = make([]filterParam, n)
+		d.segments = make([]uint8, n)
+		d.mbInfo = make([]mbInfo, n)
+		d.upMB = make([]mb, d.mbw)
+	}
+}
+
+// fitImg returns dst, extended to whole macroblocks and then cropped to the
+// frame, if it is a 4:2:0 image whose strides and capacities are those of a
+// frame buffer for the frame, and it is not one of the reference frames.
+// Otherwise, it returns nil.
+func (d *Decoder) fitImg(dst *image.YCbCr) *image.YCbCr {
+	w, h := 16*d.mbw, 16*d.mbh
+	if dst == nil || dst.SubsampleRatio != image.YCbCrSubsampleRatio420 || dst.Rect.Min != (image.Point{}) ||
+		dst.YStride != w || dst.CStride != w/2 ||
+		cap(dst.Y) < w*h || cap(dst.Cb) < w*h/4 || cap(dst.Cr) < w*h/4 {
+		return nil
+	}
+	for _, m := range d.ref {
+		if m != nil && cap(m.Y) > 0 && cap(dst.Y) > 0 && &m.Y[:1][0] == &dst.Y[:1][0] {
+			return nil
 		}
 	}
-	m := image.NewYCbCr(image.Rect(0, 0, 16*d.mbw, 16*d.mbh), image.YCbCrSubsampleRatio420)
-	d.img = m.SubImage(image.Rect(0, 0, d.frameHeader.Width, d.frameHeader.Height)).(*image.YCbCr)
-	d.perMBFilterParams = make([]filterParam, d.mbw*d.mbh)
-	d.upMB = make([]mb, d.mbw)
+	m := &image.YCbCr{
+		Y:              dst.Y[:w*h],
+		Cb:             dst.Cb[:w*h/4],
+		Cr:             dst.Cr[:w*h/4],
+		YStride:        w,
+		CStride:        w / 2,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+		Rect:           image.Rect(0, 0, w, h),
+	}
+	return m.SubImage(image.Rect(0, 0, d.frameHeader.Width, d.frameHeader.Height)).(*image.YCbCr)
 }
 
 // parseSegmentHeader parses the segment header, as specified in section 9.3.
@@ -340,19 +486,29 @@ func (d *Decoder) parseOtherHeaders() error {
 		return err
 	}
 	d.parseQuant()
+	if d.frameHeader.KeyFrame {
+		// A key frame replaces every reference frame.
+		d.refresh = [nRefFrame]bool{false, true, true, true}
+		d.copyTo = [nRefFrame]uint8{}
+	} else {
+		d.parseRefHeader()
+	}
+	// The refresh_entropy_probs bit is specified in section 9.8.
+	d.refreshProb = d.fp.readBit(uniformProb)
+	if !d.refreshProb {
+		d.saveProbs()
+	}
 	if !d.frameHeader.KeyFrame {
-		// Golden and AltRef frames are specified in section 9.7.
-		// TODO(nigeltao): implement. Note that they are only used for video, not still images.
-		return errors.New("vp8: Golden / AltRef frames are not implemented")
+		d.refresh[refLast] = d.fp.readBit(uniformProb)
 	}
-	// Read and ignore the refreshLastFrameBuffer bit, specified in section 9.8.
-	// It applies only to video, and not still images.
-	d.fp.readBit(uniformProb)
 	d.parseTokenProb()
 	d.useSkipProb = d.fp.readBit(uniformProb)
 	if d.useSkipProb {
 		d.skipProb = uint8(d.fp.readUint(uniformProb, 8))
 	}
+	if !d.frameHeader.KeyFrame {
+		d.parseInterProb()
+	}
 	if d.fp.unexpectedEOF {
 		return io.ErrUnexpectedEOF
 	}
@@ -360,12 +516,58 @@ func (d *Decoder) parseOtherHeaders() error {
 }
 
 // DecodeFrame decodes the frame and returns it as an YCbCr image.
-// The image's contents are valid up until the next call to Decoder.Init.
+// The image's contents are valid up until the next call to DecodeFrame. The
+// image may be a reference frame for the frames that follow, and must not be
+// modified.
 func (d *Decoder) DecodeFrame() (*image.YCbCr, error) {
-	d.ensureImg()
+	return d.decodeFrame(nil)
+}
+
+// DecodeFrameInto is like DecodeFrame but decodes into dst's pixels, instead
+// of the Decoder's own frame buffers, if dst can hold the frame, so that
+// decoding a sequence of frames of the same size into the same image does
+// not allocate. dst can hold the frame if it is a 4:2:0 image whose strides
+// are the frame's width rounded up to whole 16x16 macroblocks, such as one
+// returned by DecodeFrame or DecodeFrameInto for a frame of the same size,
+// and it is not one of the reference frames. Otherwise, or if dst is nil,
+// DecodeFrameInto is equivalent to DecodeFrame.
+//
+// The returned image shares dst's pixels, if they were used, but not its
+// Rect. As for DecodeFrame, it may be a reference frame for the frames that
+// follow, and must not be modified.
+func (d *Decoder) DecodeFrameInto(dst *image.YCbCr) (*image.YCbCr, error) {
+	return d.decodeFrame(dst)
+}
+
+func (d *Decoder) decodeFrame(dst *image.YCbCr) (*image.YCbCr, error) {
+	d.ensureImg(dst)
 	if err := d.parseOtherHeaders(); err != nil {
 		return nil, err
 	}
+	if !d.frameHeader.KeyFrame && d.ref[refLast] == nil {
+		return nil, errors.New("vp8: interframe without a preceding key frame")
+	}
+	// Even if we are using per-segment levels, section 15 says that "loop
+	// filtering must be skipped entirely if loop_filter_level at either the
+	// frame header level or macroblock override level is 0".
+	filter := d.filterHeader.level != 0
+	// When decoding concurrently, the loop filter is applied to each row of
+	// macroblocks once the row below it is reconstructed, as reconstructing
+	// that row predicts from the unfiltered bottom pixels of the row above,
+	// and filtering a row modifies the bottom pixels of the row above.
+	var (
+		filterRows chan int
+		filterDone chan struct{}
+	)
+	if filter && d.concurrent {
+		filterRows, filterDone = make(chan int, d.mbh), make(chan struct{})
+		go func() {
+			for mby := range filterRows {
+				d.filterRow(mby)
+			}
+			close(filterDone)
+		}()
+	}
 	// Reconstruct the rows.
 	for mbx := 0; mbx < d.mbw; mbx++ {
 		d.upMB[mbx] = mb{}
@@ -374,10 +576,18 @@ func (d *Decoder) DecodeFrame() (*image.YCbCr, error) {
 		d.leftMB = mb{}
 		for mbx := 0; mbx < d.mbw; mbx++ {
 			skip := d.reconstruct(mbx, mby)
-			fs := d.filterParams[d.segment][btou(!d.usePredY16)]
+			fs := d.filterParams[d.segment][d.mbInfo[d.mbw*mby+mbx].ref][d.lfMode]
 			fs.inner = fs.inner || !skip
 			d.perMBFilterParams[d.mbw*mby+mbx] = fs
 		}
+		if filterRows != nil && mby > 0 {
+			filterRows <- mby - 1
+		}
+	}
+	if filterRows != nil {
+		filterRows <- d.mbh - 1
+		close(filterRows)
+		<-filterDone
 	}
 	if d.fp.unexpectedEOF {
 		return nil, io.ErrUnexpectedEOF
@@ -387,17 +597,15 @@ func (d *Decoder) DecodeFrame() (*image.YCbCr, error) {
 			return nil, io.ErrUnexpectedEOF
 		}
 	}
-	// Apply the loop filter.
-	//
-	// Even if we are using per-segment levels, section 15 says that "loop
-	// filtering must be skipped entirely if loop_filter_level at either the
-	// frame header level or macroblock override level is 0".
-	if d.filterHeader.level != 0 {
-		if d.filterHeader.simple {
-			d.simpleFilter()
-		} else {
-			d.normalFilter()
+	// Apply the loop filter, unless it was applied concurrently.
+	if filter && filterRows == nil {
+		for mby := 0; mby < d.mbh; mby++ {
+			d.filterRow(mby)
 		}
 	}
+	d.updateRefs()
+	if !d.refreshProb {
+		d.restoreProbs()
+	}
 	return d.img, nil
 }
@@ -0,0 +1,67 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vp8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+	"testing"
+)
+
+// readVP8 returns the frame of the VP8 chunk of a simple format (lossy) WEBP
+// file.
+func readVP8(t *testing.T, filename string) []byte {
+	data, err := ioutil.ReadFile("../testdata/" + filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) < 20 || string(data[8:16]) != "WEBPVP8 " {
+		t.Fatalf("%s: not a simple format lossy WEBP file", filename)
+	}
+	n := int(binary.LittleEndian.Uint32(data[16:20]))
+	if n > len(data)-20 {
+		t.Fatalf("%s: truncated VP8 chunk", filename)
+	}
+	return data[20 : 20+n]
+}
+
+func TestDecodeZeroSize(t *testing.T) {
+	frame := readVP8(t, "video-001.lossy.webp")
+	for _, c := range []struct {
+		name string
+		i    int
+	}{
+		{"width", 6},
+		{"height", 8},
+	} {
+		p := append([]byte(nil), frame...)
+		// Clear the 14 bit dimension, keeping the 2 bit scale.
+		p[c.i] = 0
+		p[c.i+1] &= 0xc0
+		for _, concurrent := range []bool{false, true} {
+			d := NewDecoder()
+			d.SetConcurrent(concurrent)
+			if _, _, err := d.Decode(p); err == nil {
+				t.Errorf("zero %s, concurrent=%t: got nil error", c.name, concurrent)
+			}
+			// The frame header is rejected, before any frame is decoded.
+			d.Init(bytes.NewReader(p), len(p))
+			if _, err := d.DecodeFrameHeader(); err == nil {
+				t.Errorf("zero %s, concurrent=%t: DecodeFrameHeader: got nil error", c.name, concurrent)
+			}
+		}
+	}
+}
+
+func TestFitImgEmpty(t *testing.T) {
+	// Empty planes, such as those of a zero-sized image, share no pixels.
+	d := NewDecoder()
+	d.ref[refLast] = &image.YCbCr{SubsampleRatio: image.YCbCrSubsampleRatio420}
+	if m := d.fitImg(image.NewYCbCr(image.Rectangle{}, image.YCbCrSubsampleRatio420)); m == nil {
+		t.Error("got nil, want an empty image")
+	}
+}
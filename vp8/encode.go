@@ -0,0 +1,654 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vp8
+
+// This file implements encoding key frames. The encoder predicts and
+// reconstructs each macroblock with the same code as the decoder, so that
+// later macroblocks are predicted from exactly what the decoder will see.
+//
+// Every macroblock is predicted as one 16x16 luma region, with the DC, TM,
+// VE or HE mode whose prediction is closest to the source image. The
+// coefficients are coded with the default token probabilities.
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+// DefaultQuality is the default quality encoding parameter.
+const DefaultQuality = 75
+
+// EncodeOptions are the encoding parameters.
+type EncodeOptions struct {
+	// Quality ranges from 1 to 100 inclusive, higher is better. Zero means
+	// DefaultQuality.
+	Quality int
+}
+
+// maxDimension is the maximum width or height of a VP8 frame.
+const maxDimension = 1<<14 - 1
+
+// boolEncoder writes arithmetic-coded bits, the inverse of partition. It
+// follows the specification's reference C implementation in section 7.3.
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func (e *boolEncoder) init() {
+	e.buf = e.buf[:0]
+	e.rng = 255
+	e.bottom = 0
+	e.bitCount = 24
+}
+
+// addOne propagates a carry into the bytes already written.
+func (e *boolEncoder) addOne() {
+	i := len(e.buf) - 1
+	for ; e.buf[i] == 0xff; i-- {
+		e.buf[i] = 0
+	}
+	e.buf[i]++
+}
+
+// writeBit writes a bit whose probability of being 0 is prob/256.
+func (e *boolEncoder) writeBit(prob uint8, bit bool) {
+	split := 1 + (e.rng-1)*uint32(prob)>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			e.addOne()
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, uint8(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// writeUint writes the n-bit unsigned integer u.
+func (e *boolEncoder) writeUint(prob uint8, u uint32, n uint8) {
+	for n > 0 {
+		n--
+		e.writeBit(prob, u&(1<<n) != 0)
+	}
+}
+
+// flush writes the remaining bits and returns the encoded bytes.
+func (e *boolEncoder) flush() []byte {
+	c, v := e.bitCount, e.bottom
+	if v&(1<<uint(32-c)) != 0 {
+		e.addOne()
+	}
+	v <<= uint(c & 7)
+	for c >>= 3; c > 0; c-- {
+		v <<= 8
+	}
+	for i := 0; i < 4; i++ {
+		e.buf = append(e.buf, uint8(v>>24))
+		v <<= 8
+	}
+	return e.buf
+}
+
+// mbModes are the parameters of an encoded macroblock that are written to
+// the first partition.
+type mbModes struct {
+	predY16 uint8
+	predC8  uint8
+	skip    bool
+}
+
+// encoder holds the state for encoding a key frame.
+type encoder struct {
+	// d is the decoder whose prediction and reconstruction code, and
+	// workspace, the encoder shares. Its img is the reconstructed frame.
+	d Decoder
+	// src is the source frame, padded to whole macroblocks.
+	src *image.YCbCr
+	// qIndex is the quantizer index and filterLevel the loop filter level.
+	qIndex      uint8
+	filterLevel uint8
+	// fp and tp are the first partition and the token partition.
+	fp, tp boolEncoder
+	// modes holds each macroblock's modes.
+	modes []mbModes
+	// levels holds the current macroblock's quantized coefficients, in
+	// zigzag order: 16 luma regions, 4+4 chroma regions and the WHT.
+	levels [16 + 4 + 4 + 1][16]int16
+}
+
+// Encode writes the image m to w as a VP8 key frame.
+func Encode(w io.Writer, m image.Image, opts *EncodeOptions) error {
+	b := m.Bounds()
+	if b.Dx() < 1 || b.Dy() < 1 || b.Dx() > maxDimension || b.Dy() > maxDimension {
+		return errors.New("vp8: invalid image dimensions")
+	}
+	quality := DefaultQuality
+	if opts != nil && opts.Quality != 0 {
+		quality = opts.Quality
+	}
+	if quality < 1 {
+		quality = 1
+	} else if quality > 100 {
+		quality = 100
+	}
+
+	e := &encoder{}
+	e.qIndex = uint8((100 - quality) * 127 / 99)
+	e.filterLevel = uint8(int(e.qIndex) * 3 / 8)
+	e.d.mbw = (b.Dx() + 0x0f) >> 4
+	e.d.mbh = (b.Dy() + 0x0f) >> 4
+	e.d.img = image.NewYCbCr(image.Rect(0, 0, 16*e.d.mbw, 16*e.d.mbh), image.YCbCrSubsampleRatio420)
+	e.d.upMB = make([]mb, e.d.mbw)
+	e.d.tokenProb = defaultTokenProb
+	e.d.quant[0] = newQuant(int32(e.qIndex), 0, 0, 0, 0, 0)
+	e.src = padYCbCr(m, e.d.mbw, e.d.mbh)
+	e.modes = make([]mbModes, e.d.mbw*e.d.mbh)
+
+	e.tp.init()
+	for mby := 0; mby < e.d.mbh; mby++ {
+		e.d.leftMB = mb{}
+		for mbx := 0; mbx < e.d.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+	tokens := e.tp.flush()
+	first := e.encodeFirstPartition()
+	if len(first) >= 1<<19 {
+		return errors.New("vp8: first partition is too large")
+	}
+
+	fh := [10]byte{
+		uint8(len(first)<<5) | 1<<4,
+		uint8(len(first) >> 3),
+		uint8(len(first) >> 11),
+		0x9d, 0x01, 0x2a,
+		uint8(b.Dx()), uint8(b.Dx() >> 8),
+		uint8(b.Dy()), uint8(b.Dy() >> 8),
+	}
+	if _, err := w.Write(fh[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(first); err != nil {
+		return err
+	}
+	_, err := w.Write(tokens)
+	return err
+}
+
+// padYCbCr converts m to a 4:2:0 YCbCr image of mbw x mbh macroblocks,
+// replicating the right and bottom edges.
+func padYCbCr(m image.Image, mbw, mbh int) *image.YCbCr {
+	b := m.Bounds()
+	dst := image.NewYCbCr(image.Rect(0, 0, 16*mbw, 16*mbh), image.YCbCrSubsampleRatio444)
+	for y := 0; y < 16*mbh; y++ {
+		sy := b.Min.Y + y
+		if sy >= b.Max.Y {
+			sy = b.Max.Y - 1
+		}
+		for x := 0; x < 16*mbw; x++ {
+			sx := b.Min.X + x
+			if sx >= b.Max.X {
+				sx = b.Max.X - 1
+			}
+			i := y*dst.YStride + x
+			if src, ok := m.(*image.YCbCr); ok {
+				dst.Y[i] = src.Y[src.YOffset(sx, sy)]
+				dst.Cb[i] = src.Cb[src.COffset(sx, sy)]
+				dst.Cr[i] = src.Cr[src.COffset(sx, sy)]
+				continue
+			}
+			c := color.NRGBAModel.Convert(m.At(sx, sy)).(color.NRGBA)
+			dst.Y[i], dst.Cb[i], dst.Cr[i] = color.RGBToYCbCr(c.R, c.G, c.B)
+		}
+	}
+
+	// Subsample the chroma by averaging each 2x2 block.
+	cw, ch := 8*mbw, 8*mbh
+	cb, cr := make([]uint8, cw*ch), make([]uint8, cw*ch)
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			i := 2*y*dst.CStride + 2*x
+			j := i + dst.CStride
+			cb[y*cw+x] = uint8((int(dst.Cb[i]) + int(dst.Cb[i+1]) + int(dst.Cb[j]) + int(dst.Cb[j+1]) + 2) / 4)
+			cr[y*cw+x] = uint8((int(dst.Cr[i]) + int(dst.Cr[i+1]) + int(dst.Cr[j]) + int(dst.Cr[j+1]) + 2) / 4)
+		}
+	}
+	dst.Cb, dst.Cr = cb, cr
+	dst.CStride = cw
+	dst.SubsampleRatio = image.YCbCrSubsampleRatio420
+	return dst
+}
+
+// sse returns the sum of squared differences between the n x n region of
+// the workspace at (y, x) and the n x n region of the plane src at (sx, sy).
+func (e *encoder) sse(y, x int, src []uint8, stride, sx, sy, n int) int {
+	sum := 0
+	for j := 0; j < n; j++ {
+		s := src[(sy+j)*stride+sx:]
+		for i := 0; i < n; i++ {
+			diff := int(e.d.ybr[y+j][x+i]) - int(s[i])
+			sum += diff * diff
+		}
+	}
+	return sum
+}
+
+var predModes = [4]uint8{predDC, predTM, predVE, predHE}
+
+// forwardDCT4 returns the DCT of the difference between the 4x4 region of
+// the plane src at (sx, sy) and its prediction in the workspace at (y, x).
+// It is the inverse of inverseDCT4, following libvpx's vp8_short_fdct4x4_c.
+func (e *encoder) forwardDCT4(y, x int, src []uint8, stride, sx, sy int) (out [16]int32) {
+	var m [16]int32
+	for j := 0; j < 4; j++ {
+		var r [4]int32
+		s := src[(sy+j)*stride+sx:]
+		for i := range r {
+			r[i] = int32(s[i]) - int32(e.d.ybr[y+j][x+i])
+		}
+		a := (r[0] + r[3]) * 8
+		b := (r[1] + r[2]) * 8
+		c := (r[1] - r[2]) * 8
+		d := (r[0] - r[3]) * 8
+		m[4*j+0] = a + b
+		m[4*j+2] = a - b
+		m[4*j+1] = (c*2217 + d*5352 + 14500) >> 12
+		m[4*j+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := m[i+0] + m[i+12]
+		b := m[i+4] + m[i+8]
+		c := m[i+4] - m[i+8]
+		d := m[i+0] - m[i+12]
+		out[i+0] = (a + b + 7) >> 4
+		out[i+8] = (a - b + 7) >> 4
+		out[i+4] = (c*2217+d*5352+12000)>>16 + int32(btou(d != 0))
+		out[i+12] = (d*2217 - c*5352 + 51000) >> 16
+	}
+	return out
+}
+
+// forwardWHT16 returns the WHT of the luma regions' DC coefficients. It is
+// the inverse of inverseWHT16, following libvpx's vp8_short_walsh4x4_c.
+func forwardWHT16(in *[16]int32) (out [16]int32) {
+	var m [16]int32
+	for j := 0; j < 4; j++ {
+		r := in[4*j:]
+		a := (r[0] + r[2]) * 4
+		d := (r[1] + r[3]) * 4
+		c := (r[1] - r[3]) * 4
+		b := (r[0] - r[2]) * 4
+		m[4*j+0] = a + d + int32(btou(a != 0))
+		m[4*j+1] = b + c
+		m[4*j+2] = b - c
+		m[4*j+3] = a - d
+	}
+	for i := 0; i < 4; i++ {
+		a := m[i+0] + m[i+8]
+		d := m[i+4] + m[i+12]
+		c := m[i+4] - m[i+12]
+		b := m[i+0] - m[i+8]
+		for k, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			if v < 0 {
+				v++
+			}
+			out[i+4*k] = (v + 3) >> 3
+		}
+	}
+	return out
+}
+
+// quantize quantizes coeff into levels, in zigzag order, starting at the
+// first'th coefficient. It writes the dequantized coefficients to
+// d.coeff[coeffBase:], as parseResiduals4 would, and returns a 0/1 value
+// indicating whether there was at least one non-zero level.
+func (e *encoder) quantize(levels *[16]int16, coeff *[16]int32, q [2]uint16, first int, coeffBase int) uint8 {
+	nz := uint8(0)
+	for n := first; n < 16; n++ {
+		z := zigzag[n]
+		qz := int32(q[btou(z > 0)])
+		c := coeff[z]
+		v := c
+		if v < 0 {
+			v = -v
+		}
+		// Round towards zero slightly more often than to the nearest level,
+		// as small coefficients are the most expensive to encode.
+		v = (v + qz*3/8) / qz
+		if v > 2048 {
+			v = 2048
+		}
+		if c < 0 {
+			v = -v
+		}
+		levels[n] = int16(v)
+		if v != 0 {
+			nz = 1
+			e.d.coeff[coeffBase+int(z)] = int16(v * qz)
+		}
+	}
+	return nz
+}
+
+// encodeMacroblock chooses the predictor modes of a macroblock, encodes its
+// residuals to the token partition and reconstructs it.
+func (e *encoder) encodeMacroblock(mbx, mby int) {
+	d := &e.d
+	src := e.src
+	for i := range d.coeff {
+		d.coeff[i] = 0
+	}
+	d.prepareYBR(mbx, mby)
+
+	// Choose the predictor modes.
+	modes := &e.modes[d.mbw*mby+mbx]
+	bestY, bestC := -1, -1
+	for _, p := range predModes {
+		predFunc16[checkTopLeftPred(mbx, mby, p)](d, ybrYY, ybrYX)
+		if s := e.sse(ybrYY, ybrYX, src.Y, src.YStride, 16*mbx, 16*mby, 16); bestY < 0 || s < bestY {
+			bestY, modes.predY16 = s, p
+		}
+		predFunc8[checkTopLeftPred(mbx, mby, p)](d, ybrBY, ybrBX)
+		predFunc8[checkTopLeftPred(mbx, mby, p)](d, ybrRY, ybrRX)
+		s := e.sse(ybrBY, ybrBX, src.Cb, src.CStride, 8*mbx, 8*mby, 8) +
+			e.sse(ybrRY, ybrRX, src.Cr, src.CStride, 8*mbx, 8*mby, 8)
+		if bestC < 0 || s < bestC {
+			bestC, modes.predC8 = s, p
+		}
+	}
+	predFunc16[checkTopLeftPred(mbx, mby, modes.predY16)](d, ybrYY, ybrYX)
+	predFunc8[checkTopLeftPred(mbx, mby, modes.predC8)](d, ybrBY, ybrBX)
+	predFunc8[checkTopLeftPred(mbx, mby, modes.predC8)](d, ybrRY, ybrRX)
+
+	// Transform and quantize the residuals.
+	quant := &d.quant[0]
+	var dc [16]int32
+	nz := uint8(0)
+	for n := 0; n < 16; n++ {
+		y, x := 4*(n/4), 4*(n%4)
+		coeff := e.forwardDCT4(ybrYY+y, ybrYX+x, src.Y, src.YStride, 16*mbx+x, 16*mby+y)
+		dc[n] = coeff[0]
+		nz |= e.quantize(&e.levels[n], &coeff, quant.y1, 1, 16*n)
+	}
+	for n := 0; n < 8; n++ {
+		plane, stride, y, x := src.Cb, src.CStride, ybrBY, ybrBX
+		if n >= 4 {
+			plane, y, x = src.Cr, ybrRY, ybrRX
+		}
+		cy, cx := 4*((n/2)%2), 4*(n%2)
+		coeff := e.forwardDCT4(y+cy, x+cx, plane, stride, 8*mbx+cx, 8*mby+cy)
+		nz |= e.quantize(&e.levels[16+n], &coeff, quant.uv, 0, bCoeffBase+16*n)
+	}
+	wht := forwardWHT16(&dc)
+	nz |= e.quantize(&e.levels[24], &wht, quant.y2, 0, whtCoeffBase)
+	d.inverseWHT16()
+
+	// Encode the residuals and reconstruct the macroblock.
+	modes.skip = nz == 0
+	if modes.skip {
+		d.leftMB.nzY16 = 0
+		d.upMB[mbx].nzY16 = 0
+		d.leftMB.nzMask = 0
+		d.upMB[mbx].nzMask = 0
+		d.nzDCMask = 0
+		d.nzACMask = 0
+	} else {
+		e.encodeResiduals(mbx)
+	}
+	d.usePredY16 = true
+	d.predY16 = modes.predY16
+	d.predC8 = modes.predC8
+	d.reconstructMacroblock(mbx, mby)
+	for i, y := (mby*d.img.YStride+mbx)*16, 0; y < 16; i, y = i+d.img.YStride, y+1 {
+		copy(d.img.Y[i:i+16], d.ybr[ybrYY+y][ybrYX:ybrYX+16])
+	}
+	for i, y := (mby*d.img.CStride+mbx)*8, 0; y < 8; i, y = i+d.img.CStride, y+1 {
+		copy(d.img.Cb[i:i+8], d.ybr[ybrBY+y][ybrBX:ybrBX+8])
+		copy(d.img.Cr[i:i+8], d.ybr[ybrRY+y][ybrRX:ybrRX+8])
+	}
+}
+
+// encodeResiduals4 encodes a 4x4 region's quantized coefficients, the
+// inverse of parseResiduals4, and returns a 0/1 value indicating whether
+// there was at least one non-zero coefficient.
+func (e *encoder) encodeResiduals4(plane int, context uint8, levels *[16]int16, first int) uint8 {
+	last := -1
+	for n := 15; n >= first; n-- {
+		if levels[n] != 0 {
+			last = n
+			break
+		}
+	}
+	prob, w := &e.d.tokenProb[plane], &e.tp
+	p := prob[bands[first]][context]
+	w.writeBit(p[0], last >= 0)
+	if last < 0 {
+		return 0
+	}
+	for n := first; n <= last; n++ {
+		v := int32(levels[n])
+		if v < 0 {
+			v = -v
+		}
+		if v == 0 {
+			w.writeBit(p[1], false)
+			p = prob[bands[n+1]][0]
+			continue
+		}
+		w.writeBit(p[1], true)
+		if v == 1 {
+			w.writeBit(p[2], false)
+			p = prob[bands[n+1]][1]
+		} else {
+			w.writeBit(p[2], true)
+			switch {
+			case v <= 4:
+				w.writeBit(p[3], false)
+				w.writeBit(p[4], v != 2)
+				if v != 2 {
+					w.writeBit(p[5], v == 4)
+				}
+			case v <= 10:
+				w.writeBit(p[3], true)
+				w.writeBit(p[6], false)
+				w.writeBit(p[7], v >= 7)
+				if v < 7 {
+					// Category 1.
+					w.writeBit(159, v == 6)
+				} else {
+					// Category 2.
+					w.writeBit(165, (v-7)&2 != 0)
+					w.writeBit(145, (v-7)&1 != 0)
+				}
+			default:
+				// Categories 3, 4, 5 or 6.
+				w.writeBit(p[3], true)
+				w.writeBit(p[6], true)
+				cat := uint32(0)
+				for cat < 3 && v >= 3+(8<<(cat+1)) {
+					cat++
+				}
+				b1 := cat >> 1
+				w.writeBit(p[8], b1 != 0)
+				w.writeBit(p[9+b1], cat&1 != 0)
+				tab := &cat3456[cat]
+				nBits := 0
+				for tab[nBits] != 0 {
+					nBits++
+				}
+				extra := v - (3 + 8<<cat)
+				for i := 0; i < nBits; i++ {
+					w.writeBit(tab[i], extra&(1<<uint(nBits-1-i)) != 0)
+				}
+			}
+			p = prob[bands[n+1]][2]
+		}
+		w.writeBit(uniformProb, levels[n] < 0)
+		if n+1 < 16 {
+			w.writeBit(p[0], n != last)
+		}
+	}
+	return 1
+}
+
+// encodeResiduals encodes the current macroblock's residuals, the inverse
+// of parseResiduals.
+func (e *encoder) encodeResiduals(mbx int) {
+	d := &e.d
+
+	// Encode the DC coefficient of each 4x4 luma region.
+	nz := e.encodeResiduals4(planeY2, d.leftMB.nzY16+d.upMB[mbx].nzY16, &e.levels[24], 0)
+	d.leftMB.nzY16 = nz
+	d.upMB[mbx].nzY16 = nz
+
+	var (
+		nzDC, nzAC         [4]uint8
+		nzDCMask, nzACMask uint32
+		coeffBase          int
+	)
+
+	// Encode the luma coefficients.
+	lnz := unpack[d.leftMB.nzMask&0x0f]
+	unz := unpack[d.upMB[mbx].nzMask&0x0f]
+	for y := 0; y < 4; y++ {
+		nz := lnz[y]
+		for x := 0; x < 4; x++ {
+			nz = e.encodeResiduals4(planeY1WithY2, nz+unz[x], &e.levels[4*y+x], 1)
+			unz[x] = nz
+			nzAC[x] = nz
+			nzDC[x] = btou(d.coeff[coeffBase] != 0)
+			coeffBase += 16
+		}
+		lnz[y] = nz
+		nzDCMask |= pack(nzDC, y*4)
+		nzACMask |= pack(nzAC, y*4)
+	}
+	lnzMask := pack(lnz, 0)
+	unzMask := pack(unz, 0)
+
+	// Encode the chroma coefficients.
+	lnz = unpack[d.leftMB.nzMask>>4]
+	unz = unpack[d.upMB[mbx].nzMask>>4]
+	for c := 0; c < 4; c += 2 {
+		for y := 0; y < 2; y++ {
+			nz := lnz[y+c]
+			for x := 0; x < 2; x++ {
+				nz = e.encodeResiduals4(planeUV, nz+unz[x+c], &e.levels[16+2*c+2*y+x], 0)
+				unz[x+c] = nz
+				nzAC[y*2+x] = nz
+				nzDC[y*2+x] = btou(d.coeff[coeffBase] != 0)
+				coeffBase += 16
+			}
+			lnz[y+c] = nz
+		}
+		nzDCMask |= pack(nzDC, 16+c*2)
+		nzACMask |= pack(nzAC, 16+c*2)
+	}
+	lnzMask |= pack(lnz, 4)
+	unzMask |= pack(unz, 4)
+
+	// Save encoder state.
+	d.leftMB.nzMask = uint8(lnzMask)
+	d.upMB[mbx].nzMask = uint8(unzMask)
+	d.nzDCMask = nzDCMask
+	d.nzACMask = nzACMask
+}
+
+// encodeFirstPartition encodes the frame's headers and each macroblock's
+// modes, the inverse of parseOtherHeaders and the mode parsing in
+// reconstruct.
+func (e *encoder) encodeFirstPartition() []byte {
+	w := &e.fp
+	w.init()
+	// Color space and pixel clamping.
+	w.writeBit(uniformProb, false)
+	w.writeBit(uniformProb, false)
+	// No segmentation.
+	w.writeBit(uniformProb, false)
+	// The normal loop filter, with no mode or reference frame deltas.
+	w.writeBit(uniformProb, false)
+	w.writeUint(uniformProb, uint32(e.filterLevel), 6)
+	w.writeUint(uniformProb, 0, 3)
+	w.writeBit(uniformProb, false)
+	// One token partition.
+	w.writeUint(uniformProb, 0, 2)
+	// The quantizer index, with no deltas.
+	w.writeUint(uniformProb, uint32(e.qIndex), 7)
+	for i := 0; i < 5; i++ {
+		w.writeBit(uniformProb, false)
+	}
+	// Refresh the entropy probabilities.
+	w.writeBit(uniformProb, true)
+	// No token probability updates.
+	for i := range tokenProbUpdateProb {
+		for j := range tokenProbUpdateProb[i] {
+			for k := range tokenProbUpdateProb[i][j] {
+				for _, p := range tokenProbUpdateProb[i][j][k] {
+					w.writeBit(p, false)
+				}
+			}
+		}
+	}
+	// The skip probability.
+	nSkip := 0
+	for _, m := range e.modes {
+		if m.skip {
+			nSkip++
+		}
+	}
+	skipProb := clip(int32(255*(len(e.modes)-nSkip)/len(e.modes)), 1, 255)
+	w.writeBit(uniformProb, true)
+	w.writeUint(uniformProb, uint32(skipProb), 8)
+
+	for _, m := range e.modes {
+		w.writeBit(uint8(skipProb), m.skip)
+		w.writeBit(145, true)
+		switch m.predY16 {
+		case predDC:
+			w.writeBit(156, false)
+			w.writeBit(163, false)
+		case predVE:
+			w.writeBit(156, false)
+			w.writeBit(163, true)
+		case predHE:
+			w.writeBit(156, true)
+			w.writeBit(128, false)
+		case predTM:
+			w.writeBit(156, true)
+			w.writeBit(128, true)
+		}
+		switch m.predC8 {
+		case predDC:
+			w.writeBit(142, false)
+		case predVE:
+			w.writeBit(142, true)
+			w.writeBit(114, false)
+		case predHE:
+			w.writeBit(142, true)
+			w.writeBit(114, true)
+			w.writeBit(183, false)
+		case predTM:
+			w.writeBit(142, true)
+			w.writeBit(114, true)
+			w.writeBit(183, true)
+		}
+	}
+	return w.flush()
+}
@@ -77,71 +77,80 @@ func filter246(pix []byte, n, level, ilevel, hlevel, index, iStep, jStep int, fo
 	}
 }
 
-// simpleFilter implements the simple filter, as specified in section 15.2.
-func (d *Decoder) simpleFilter() {
-	for mby := 0; mby < d.mbh; mby++ {
-		for mbx := 0; mbx < d.mbw; mbx++ {
-			f := d.perMBFilterParams[d.mbw*mby+mbx]
-			if f.level == 0 {
-				continue
-			}
-			l := int(f.level)
-			yIndex := (mby*d.img.YStride + mbx) * 16
-			if mbx > 0 {
-				filter2(d.img.Y, l+4, yIndex, d.img.YStride, 1)
-			}
-			if f.inner {
-				filter2(d.img.Y, l, yIndex+0x4, d.img.YStride, 1)
-				filter2(d.img.Y, l, yIndex+0x8, d.img.YStride, 1)
-				filter2(d.img.Y, l, yIndex+0xc, d.img.YStride, 1)
-			}
-			if mby > 0 {
-				filter2(d.img.Y, l+4, yIndex, 1, d.img.YStride)
-			}
-			if f.inner {
-				filter2(d.img.Y, l, yIndex+d.img.YStride*0x4, 1, d.img.YStride)
-				filter2(d.img.Y, l, yIndex+d.img.YStride*0x8, 1, d.img.YStride)
-				filter2(d.img.Y, l, yIndex+d.img.YStride*0xc, 1, d.img.YStride)
-			}
+// filterRow applies the loop filter to the mby'th row of macroblocks. Besides
+// that row's pixels, it modifies the bottom three rows of pixels of the row of
+// macroblocks above, but it neither reads nor modifies the rows below.
+func (d *Decoder) filterRow(mby int) {
+	if d.filterHeader.simple {
+		d.simpleFilterRow(mby)
+	} else {
+		d.normalFilterRow(mby)
+	}
+}
+
+// simpleFilterRow implements the simple filter, as specified in section 15.2,
+// for the mby'th row of macroblocks.
+func (d *Decoder) simpleFilterRow(mby int) {
+	for mbx := 0; mbx < d.mbw; mbx++ {
+		f := d.perMBFilterParams[d.mbw*mby+mbx]
+		if f.level == 0 {
+			continue
+		}
+		l := int(f.level)
+		yIndex := (mby*d.img.YStride + mbx) * 16
+		if mbx > 0 {
+			filter2(d.img.Y, l+4, yIndex, d.img.YStride, 1)
+		}
+		if f.inner {
+			filter2(d.img.Y, l, yIndex+0x4, d.img.YStride, 1)
+			filter2(d.img.Y, l, yIndex+0x8, d.img.YStride, 1)
+			filter2(d.img.Y, l, yIndex+0xc, d.img.YStride, 1)
+		}
+		if mby > 0 {
+			filter2(d.img.Y, l+4, yIndex, 1, d.img.YStride)
+		}
+		if f.inner {
+			filter2(d.img.Y, l, yIndex+d.img.YStride*0x4, 1, d.img.YStride)
+			filter2(d.img.Y, l, yIndex+d.img.YStride*0x8, 1, d.img.YStride)
+			filter2(d.img.Y, l, yIndex+d.img.YStride*0xc, 1, d.img.YStride)
 		}
 	}
 }
 
-// normalFilter implements the normal filter, as specified in section 15.3.
-func (d *Decoder) normalFilter() {
-	for mby := 0; mby < d.mbh; mby++ {
-		for mbx := 0; mbx < d.mbw; mbx++ {
-			f := d.perMBFilterParams[d.mbw*mby+mbx]
-			if f.level == 0 {
-				continue
-			}
-			l, il, hl := int(f.level), int(f.ilevel), int(f.hlevel)
-			yIndex := (mby*d.img.YStride + mbx) * 16
-			cIndex := (mby*d.img.CStride + mbx) * 8
-			if mbx > 0 {
-				filter246(d.img.Y, 16, l+4, il, hl, yIndex, d.img.YStride, 1, false)
-				filter246(d.img.Cb, 8, l+4, il, hl, cIndex, d.img.CStride, 1, false)
-				filter246(d.img.Cr, 8, l+4, il, hl, cIndex, d.img.CStride, 1, false)
-			}
-			if f.inner {
-				filter246(d.img.Y, 16, l, il, hl, yIndex+0x4, d.img.YStride, 1, true)
-				filter246(d.img.Y, 16, l, il, hl, yIndex+0x8, d.img.YStride, 1, true)
-				filter246(d.img.Y, 16, l, il, hl, yIndex+0xc, d.img.YStride, 1, true)
-				filter246(d.img.Cb, 8, l, il, hl, cIndex+0x4, d.img.CStride, 1, true)
-				filter246(d.img.Cr, 8, l, il, hl, cIndex+0x4, d.img.CStride, 1, true)
-			}
-			if mby > 0 {
-				filter246(d.img.Y, 16, l+4, il, hl, yIndex, 1, d.img.YStride, false)
-				filter246(d.img.Cb, 8, l+4, il, hl, cIndex, 1, d.img.CStride, false)
-				filter246(d.img.Cr, 8, l+4, il, hl, cIndex, 1, d.img.CStride, false)
-			}
-			if f.inner {
-				filter246(d.img.Y, 16, l, il, hl, yIndex+d.img.YStride*0x4, 1, d.img.YStride, true)
-				filter246(d.img.Y, 16, l, il, hl, yIndex+d.img.YStride*0x8, 1, d.img.YStride, true)
-				filter246(d.img.Y, 16, l, il, hl, yIndex+d.img.YStride*0xc, 1, d.img.YStride, true)
-				filter246(d.img.Cb, 8, l, il, hl, cIndex+d.img.CStride*0x4, 1, d.img.CStride, true)
-				filter246(d.img.Cr, 8, l, il, hl, cIndex+d.img.CStride*0x4, 1, d.img.CStride, true)
-			}
+// normalFilterRow implements the normal filter, as specified in section 15.3,
+// for the mby'th row of macroblocks.
+func (d *Decoder) normalFilterRow(mby int) {
+	for mbx := 0; mbx < d.mbw; mbx++ {
+		f := d.perMBFilterParams[d.mbw*mby+mbx]
+		if f.level == 0 {
+			continue
+		}
+		l, il, hl := int(f.level), int(f.ilevel), int(f.hlevel)
+		yIndex := (mby*d.img.YStride + mbx) * 16
+		cIndex := (mby*d.img.CStride + mbx) * 8
+		if mbx > 0 {
+			filter246(d.img.Y, 16, l+4, il, hl, yIndex, d.img.YStride, 1, false)
+			filter246(d.img.Cb, 8, l+4, il, hl, cIndex, d.img.CStride, 1, false)
+			filter246(d.img.Cr, 8, l+4, il, hl, cIndex, d.img.CStride, 1, false)
+		}
+		if f.inner {
+			filter246(d.img.Y, 16, l, il, hl, yIndex+0x4, d.img.YStride, 1, true)
+			filter246(d.img.Y, 16, l, il, hl, yIndex+0x8, d.img.YStride, 1, true)
+			filter246(d.img.Y, 16, l, il, hl, yIndex+0xc, d.img.YStride, 1, true)
+			filter246(d.img.Cb, 8, l, il, hl, cIndex+0x4, d.img.CStride, 1, true)
+			filter246(d.img.Cr, 8, l, il, hl, cIndex+0x4, d.img.CStride, 1, true)
+		}
+		if mby > 0 {
+			filter246(d.img.Y, 16, l+4, il, hl, yIndex, 1, d.img.YStride, false)
+			filter246(d.img.Cb, 8, l+4, il, hl, cIndex, 1, d.img.CStride, false)
+			filter246(d.img.Cr, 8, l+4, il, hl, cIndex, 1, d.img.CStride, false)
+		}
+		if f.inner {
+			filter246(d.img.Y, 16, l, il, hl, yIndex+d.img.YStride*0x4, 1, d.img.YStride, true)
+			filter246(d.img.Y, 16, l, il, hl, yIndex+d.img.YStride*0x8, 1, d.img.YStride, true)
+			filter246(d.img.Y, 16, l, il, hl, yIndex+d.img.YStride*0xc, 1, d.img.YStride, true)
+			filter246(d.img.Cb, 8, l, il, hl, cIndex+d.img.CStride*0x4, 1, d.img.CStride, true)
+			filter246(d.img.Cr, 8, l, il, hl, cIndex+d.img.CStride*0x4, 1, d.img.CStride, true)
 		}
 	}
 }
@@ -162,71 +171,77 @@ type filterParam struct {
 // section 15.4.
 func (d *Decoder) computeFilterParams() {
 	for i := range d.filterParams {
-		baseLevel := d.filterHeader.level
+		baseLevel := int(d.filterHeader.level)
 		if d.segmentHeader.useSegment {
-			baseLevel = d.segmentHeader.filterStrength[i]
+			baseLevel = int(d.segmentHeader.filterStrength[i])
 			if d.segmentHeader.relativeDelta {
-				baseLevel += d.filterHeader.level
+				baseLevel += int(d.filterHeader.level)
 			}
 		}
+		baseLevel = clamp63(baseLevel)
 
-		for j := range d.filterParams[i] {
-			p := &d.filterParams[i][j]
-			p.inner = j != 0
-			level := baseLevel
-			if d.filterHeader.useLFDelta {
-				// The libwebp C code has a "TODO: only CURRENT is handled for now."
-				level += d.filterHeader.refLFDelta[0]
-				if j != 0 {
-					level += d.filterHeader.modeLFDelta[0]
-				}
-			}
-			if level <= 0 {
-				p.level = 0
-				continue
-			}
-			if level > 63 {
-				level = 63
-			}
-			ilevel := level
-			if d.filterHeader.sharpness > 0 {
-				if d.filterHeader.sharpness > 4 {
-					ilevel >>= 2
-				} else {
-					ilevel >>= 1
-				}
-				if x := int8(9 - d.filterHeader.sharpness); ilevel > x {
-					ilevel = x
-				}
-			}
-			if ilevel < 1 {
-				ilevel = 1
-			}
-			p.ilevel = uint8(ilevel)
-			p.level = uint8(2*level + ilevel)
-			if d.frameHeader.KeyFrame {
-				if level < 15 {
-					p.hlevel = 0
-				} else if level < 40 {
-					p.hlevel = 1
-				} else {
-					p.hlevel = 2
-				}
-			} else {
-				if level < 15 {
-					p.hlevel = 0
-				} else if level < 20 {
-					p.hlevel = 1
-				} else if level < 40 {
-					p.hlevel = 2
-				} else {
-					p.hlevel = 3
-				}
+		for ref := range d.filterParams[i] {
+			for mode := range d.filterParams[i][ref] {
+				d.filterParams[i][ref][mode] = d.computeFilterParam(baseLevel, ref, mode)
 			}
 		}
 	}
 }
 
+// computeFilterParam computes the loop filter parameters of macroblocks
+// with the given reference frame and loop filter mode, from the level of
+// their segment.
+func (d *Decoder) computeFilterParam(level, ref, mode int) (p filterParam) {
+	p.inner = mode == lfModeBPred || mode == lfModeSplit
+	if d.filterHeader.useLFDelta {
+		level += int(d.filterHeader.refLFDelta[ref])
+		// Of the intra macroblocks, only B_PRED ones have a mode delta.
+		if ref != refIntra || mode == lfModeBPred {
+			level += int(d.filterHeader.modeLFDelta[mode])
+		}
+		level = clamp63(level)
+	}
+	if level == 0 {
+		return p
+	}
+	ilevel := level
+	if d.filterHeader.sharpness > 0 {
+		if d.filterHeader.sharpness > 4 {
+			ilevel >>= 2
+		} else {
+			ilevel >>= 1
+		}
+		if x := 9 - int(d.filterHeader.sharpness); ilevel > x {
+			ilevel = x
+		}
+	}
+	if ilevel < 1 {
+		ilevel = 1
+	}
+	p.ilevel = uint8(ilevel)
+	p.level = uint8(2*level + ilevel)
+	if d.frameHeader.KeyFrame {
+		if level < 15 {
+			p.hlevel = 0
+		} else if level < 40 {
+			p.hlevel = 1
+		} else {
+			p.hlevel = 2
+		}
+	} else {
+		if level < 15 {
+			p.hlevel = 0
+		} else if level < 20 {
+			p.hlevel = 1
+		} else if level < 40 {
+			p.hlevel = 2
+		} else {
+			p.hlevel = 3
+		}
+	}
+	return p
+}
+
 // intSize is either 32 or 64.
 const intSize = 32 << (^uint(0) >> 63)
 
@@ -252,6 +267,16 @@ func clamp15(x int) int {
 	return x
 }
 
+func clamp63(x int) int {
+	if x < 0 {
+		return 0
+	}
+	if x > 63 {
+		return 63
+	}
+	return x
+}
+
 func clamp127(x int) int {
 	if x < -128 {
 		return -128
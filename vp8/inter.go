@@ -0,0 +1,584 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vp8
+
+// This file implements decoding interframes: parsing their headers, their
+// macroblocks' reference frames and motion vectors, and predicting their
+// macroblocks from the reference frames.
+
+// The reference frames are specified in section 9.7. An intra macroblock
+// refers to none.
+const (
+	refIntra = iota
+	refLast
+	refGolden
+	refAltRef
+	nRefFrame
+)
+
+// The macroblock modes for the loop filter, which index the mode deltas of
+// section 9.6.
+const (
+	lfModeBPred = iota // B_PRED.
+	lfModeZero         // ZEROMV and the other intra modes.
+	lfModeMV           // NEARESTMV, NEARMV and NEWMV.
+	lfModeSplit        // SPLITMV.
+)
+
+const (
+	nYMode  = 5
+	nUVMode = 4
+	nMVProb = 19
+)
+
+// mv is a motion vector, in quarter pixels.
+type mv struct {
+	y, x int32
+}
+
+// mbInfo is the reference frame and motion vectors of a macroblock, which
+// the macroblocks right of and below it are predicted from.
+type mbInfo struct {
+	ref uint8
+	// split is whether the macroblock has a motion vector per 4x4 region,
+	// in mvs, rather than the single mv. For split macroblocks, mv is that of
+	// the bottom right region.
+	split bool
+	mv    mv
+	mvs   [16]mv
+}
+
+// blockMV returns the motion vector of the macroblock's 4x4 region n.
+func (m *mbInfo) blockMV(n int) mv {
+	if m.split {
+		return m.mvs[n]
+	}
+	return m.mv
+}
+
+// probs are the probabilities that a frame may update, and whose updates
+// are kept for the frames that follow unless the frame says otherwise.
+type probs struct {
+	token  [nPlane][nBand][nContext][nProb]uint8
+	ymode  [nYMode - 1]uint8
+	uvmode [nUVMode - 1]uint8
+	mv     [2][nMVProb]uint8
+}
+
+func (d *Decoder) saveProbs() {
+	d.savedProb = probs{d.tokenProb, d.ymodeProb, d.uvmodeProb, d.mvProb}
+}
+
+func (d *Decoder) restoreProbs() {
+	d.tokenProb = d.savedProb.token
+	d.ymodeProb = d.savedProb.ymode
+	d.uvmodeProb = d.savedProb.uvmode
+	d.mvProb = d.savedProb.mv
+}
+
+// parseRefHeader parses which reference frames an interframe updates and
+// the sign biases of the golden and alternate reference frames, as
+// specified in section 9.7.
+func (d *Decoder) parseRefHeader() {
+	d.refresh[refGolden] = d.fp.readBit(uniformProb)
+	d.refresh[refAltRef] = d.fp.readBit(uniformProb)
+	d.copyTo = [nRefFrame]uint8{}
+	if !d.refresh[refGolden] {
+		d.copyTo[refGolden] = uint8(d.fp.readUint(uniformProb, 2))
+	}
+	if !d.refresh[refAltRef] {
+		d.copyTo[refAltRef] = uint8(d.fp.readUint(uniformProb, 2))
+	}
+	d.signBias[refGolden] = d.fp.readBit(uniformProb)
+	d.signBias[refAltRef] = d.fp.readBit(uniformProb)
+}
+
+// parseInterProb parses the reference frame probabilities and the updates
+// to the mode and motion vector probabilities of an interframe, as specified
+// in sections 9.10, 16.2 and 17.2.
+func (d *Decoder) parseInterProb() {
+	d.intraProb = uint8(d.fp.readUint(uniformProb, 8))
+	d.lastProb = uint8(d.fp.readUint(uniformProb, 8))
+	d.goldenProb = uint8(d.fp.readUint(uniformProb, 8))
+	if d.fp.readBit(uniformProb) {
+		for i := range d.ymodeProb {
+			d.ymodeProb[i] = uint8(d.fp.readUint(uniformProb, 8))
+		}
+	}
+	if d.fp.readBit(uniformProb) {
+		for i := range d.uvmodeProb {
+			d.uvmodeProb[i] = uint8(d.fp.readUint(uniformProb, 8))
+		}
+	}
+	for i := range d.mvProb {
+		for j := range d.mvProb[i] {
+			if d.fp.readBit(mvProbUpdateProb[i][j]) {
+				if x := uint8(d.fp.readUint(uniformProb, 7)); x != 0 {
+					d.mvProb[i][j] = x << 1
+				} else {
+					d.mvProb[i][j] = 1
+				}
+			}
+		}
+	}
+}
+
+// updateRefs updates the reference frames once a frame is decoded. The
+// golden and alternate reference frames' copies from other reference frames
+// are made before they are replaced by the decoded frame, the alternate
+// reference frame's first, in the same order as the libvpx decoder.
+func (d *Decoder) updateRefs() {
+	switch d.copyTo[refAltRef] {
+	case 1:
+		d.ref[refAltRef] = d.ref[refLast]
+	case 2:
+		d.ref[refAltRef] = d.ref[refGolden]
+	}
+	switch d.copyTo[refGolden] {
+	case 1:
+		d.ref[refGolden] = d.ref[refLast]
+	case 2:
+		d.ref[refGolden] = d.ref[refAltRef]
+	}
+	for i := refLast; i < nRefFrame; i++ {
+		if d.refresh[i] {
+			d.ref[i] = d.img
+		}
+	}
+}
+
+// parseInterModes parses the reference frame and motion vectors of an inter
+// macroblock, as specified in sections 16.3 and 17.
+func (d *Decoder) parseInterModes(mbx, mby int, m *mbInfo) {
+	m.ref = refLast
+	if d.fp.readBit(d.lastProb) {
+		m.ref = refGolden
+		if d.fp.readBit(d.goldenProb) {
+			m.ref = refAltRef
+		}
+	}
+	m.split = false
+	near, cnt := d.findNearMVs(mbx, mby, m.ref)
+	switch {
+	case !d.fp.readBit(modeContexts[cnt[0]][0]):
+		m.mv = mv{}
+		d.lfMode = lfModeZero
+	case !d.fp.readBit(modeContexts[cnt[1]][1]):
+		m.mv = near[1]
+		d.lfMode = lfModeMV
+	case !d.fp.readBit(modeContexts[cnt[2]][2]):
+		m.mv = near[2]
+		d.lfMode = lfModeMV
+	case !d.fp.readBit(modeContexts[cnt[3]][3]):
+		m.mv = d.readMV()
+		m.mv.y += near[0].y
+		m.mv.x += near[0].x
+		d.lfMode = lfModeMV
+	default:
+		d.parseSplitMV(mbx, mby, m, near[0])
+		d.lfMode = lfModeSplit
+	}
+	d.usePredY16 = !m.split
+}
+
+// findNearMVs returns the best, nearest and near motion vectors of the
+// macroblock, from those of the macroblocks above, left of and above-left of
+// it, and the counts that select the probabilities of its mode, as specified
+// in section 16.3.
+func (d *Decoder) findNearMVs(mbx, mby int, ref uint8) (near [3]mv, cnt [4]int) {
+	var border mbInfo
+	above, left, aboveLeft := &border, &border, &border
+	i := d.mbw*mby + mbx
+	if mby > 0 {
+		above = &d.mbInfo[i-d.mbw]
+		if mbx > 0 {
+			aboveLeft = &d.mbInfo[i-d.mbw-1]
+		}
+	}
+	if mbx > 0 {
+		left = &d.mbInfo[i-1]
+	}
+
+	var mvs [4]mv
+	n := 0
+	for j, m := range [3]*mbInfo{above, left, aboveLeft} {
+		if m.ref == refIntra {
+			continue
+		}
+		weight := 2
+		if j == 2 {
+			weight = 1
+		}
+		if m.mv == (mv{}) {
+			cnt[0] += weight
+			continue
+		}
+		v := m.mv
+		if d.signBias[m.ref] != d.signBias[ref] {
+			v = mv{-v.y, -v.x}
+		}
+		if v != mvs[n] {
+			n++
+			mvs[n] = v
+		}
+		cnt[n] += weight
+	}
+	// If there are three distinct motion vectors, the above-left one can
+	// still be the same as the above one.
+	if cnt[3] > 0 && mvs[3] == mvs[1] {
+		cnt[1]++
+	}
+	cnt[3] = 2*int(btou(above.split)+btou(left.split)) + int(btou(aboveLeft.split))
+	if cnt[2] > cnt[1] {
+		cnt[1], cnt[2] = cnt[2], cnt[1]
+		mvs[1], mvs[2] = mvs[2], mvs[1]
+	}
+	if cnt[1] >= cnt[0] {
+		mvs[0] = mvs[1]
+	}
+	for j := range near {
+		near[j] = d.clampMV(mbx, mby, mvs[j])
+	}
+	return near, cnt
+}
+
+// clampMV clamps v so that it points no further than 16 pixels beyond the
+// edges of the frame.
+func (d *Decoder) clampMV(mbx, mby int, v mv) mv {
+	minX, maxX := int32(-64*(mbx+1)), int32(64*(d.mbw-mbx))
+	minY, maxY := int32(-64*(mby+1)), int32(64*(d.mbh-mby))
+	if v.x < minX {
+		v.x = minX
+	} else if v.x > maxX {
+		v.x = maxX
+	}
+	if v.y < minY {
+		v.y = minY
+	} else if v.y > maxY {
+		v.y = maxY
+	}
+	return v
+}
+
+// parseSplitMV parses the motion vectors of a split macroblock, as specified
+// in section 17.3.
+func (d *Decoder) parseSplitMV(mbx, mby int, m *mbInfo, best mv) {
+	var s int
+	if !d.fp.readBit(110) {
+		s = 3
+	} else if !d.fp.readBit(111) {
+		s = 2
+	} else {
+		s = int(d.fp.readUint(150, 1))
+	}
+	var border mbInfo
+	above, left := &border, &border
+	i := d.mbw*mby + mbx
+	if mby > 0 {
+		above = &d.mbInfo[i-d.mbw]
+	}
+	if mbx > 0 {
+		left = &d.mbInfo[i-1]
+	}
+
+	split := &splitMaps[s]
+	for j, k := uint8(0), 0; j < splitCounts[s]; j++ {
+		// k is the first 4x4 region of the partition j.
+		for split[k] != j {
+			k++
+		}
+		var l, a mv
+		if k&3 == 0 {
+			l = left.blockMV(k + 3)
+		} else {
+			l = m.mvs[k-1]
+		}
+		if k < 4 {
+			a = above.blockMV(k + 12)
+		} else {
+			a = m.mvs[k-4]
+		}
+		var context int
+		switch {
+		case l == a && a == (mv{}):
+			context = 4
+		case l == a:
+			context = 3
+		case a == (mv{}):
+			context = 2
+		case l == (mv{}):
+			context = 1
+		}
+		prob := &subMVRefProb[context]
+		var v mv
+		switch {
+		case !d.fp.readBit(prob[0]):
+			v = l
+		case !d.fp.readBit(prob[1]):
+			v = a
+		case !d.fp.readBit(prob[2]):
+			v = mv{}
+		default:
+			v = d.readMV()
+			v.y += best.y
+			v.x += best.x
+		}
+		for n := k; n < 16; n++ {
+			if split[n] == j {
+				m.mvs[n] = v
+			}
+		}
+	}
+	m.split = true
+	m.mv = m.mvs[15]
+}
+
+// readMV reads a motion vector, as specified in section 17.2.
+func (d *Decoder) readMV() mv {
+	y := d.readMVComponent(&d.mvProb[0])
+	x := d.readMVComponent(&d.mvProb[1])
+	return mv{y, x}
+}
+
+func (d *Decoder) readMVComponent(p *[nMVProb]uint8) int32 {
+	const (
+		isShort = 0
+		sign    = 1
+		short   = 2
+		long    = 9
+	)
+	var x int32
+	if d.fp.readBit(p[isShort]) {
+		for i := 0; i < 3; i++ {
+			x += int32(d.fp.readUint(p[long+i], 1)) << uint(i)
+		}
+		for i := 9; i > 3; i-- {
+			x += int32(d.fp.readUint(p[long+i], 1)) << uint(i)
+		}
+		// Bit 3 is implicitly set when no higher bit is.
+		if x&^0xf == 0 || d.fp.readBit(p[long+3]) {
+			x += 8
+		}
+	} else if !d.fp.readBit(p[short+0]) {
+		if !d.fp.readBit(p[short+1]) {
+			x = int32(d.fp.readUint(p[short+2], 1))
+		} else {
+			x = 2 + int32(d.fp.readUint(p[short+3], 1))
+		}
+	} else if !d.fp.readBit(p[short+4]) {
+		x = 4 + int32(d.fp.readUint(p[short+5], 1))
+	} else {
+		x = 6 + int32(d.fp.readUint(p[short+6], 1))
+	}
+	if x != 0 && d.fp.readBit(p[sign]) {
+		x = -x
+	}
+	return x
+}
+
+// predictInter sets the workspace's luma and chroma values to those
+// predicted from the macroblock's reference frame, as specified in section
+// 18.
+func (d *Decoder) predictInter(mbx, mby int) {
+	m := &d.mbInfo[d.mbw*mby+mbx]
+	ref := d.ref[m.ref]
+	// Version 3 has motion vectors of whole chroma pixels.
+	var cmask int32 = -1
+	if d.frameHeader.VersionNumber == 3 {
+		cmask = ^7
+	}
+	yw, yh := 16*d.mbw, 16*d.mbh
+	cw, ch := 8*d.mbw, 8*d.mbh
+	if !m.split {
+		// Luma motion vectors are in quarter pixels and chroma motion vectors
+		// in eighth pixels, of half as many pixels.
+		d.predictBlock(ybrYY, ybrYX, ref.Y, ref.YStride, yw, yh, 16*mbx, 16*mby, 16, 2*m.mv.x, 2*m.mv.y)
+		x, y := m.mv.x&cmask, m.mv.y&cmask
+		d.predictBlock(ybrBY, ybrBX, ref.Cb, ref.CStride, cw, ch, 8*mbx, 8*mby, 8, x, y)
+		d.predictBlock(ybrRY, ybrRX, ref.Cr, ref.CStride, cw, ch, 8*mbx, 8*mby, 8, x, y)
+		return
+	}
+	for n, v := range m.mvs {
+		i, j := 4*(n&3), 4*(n>>2)
+		d.predictBlock(ybrYY+j, ybrYX+i, ref.Y, ref.YStride, yw, yh, 16*mbx+i, 16*mby+j, 4, 2*v.x, 2*v.y)
+	}
+	// Each 4x4 chroma region's motion vector is the average of those of the
+	// four luma regions that it covers.
+	for j := 0; j < 8; j += 4 {
+		for i := 0; i < 8; i += 4 {
+			n := 2*j + i/2
+			var x, y int32
+			for _, k := range [4]int{n, n + 1, n + 4, n + 5} {
+				x += 2 * m.mvs[k].x
+				y += 2 * m.mvs[k].y
+			}
+			x, y = average8(x)&cmask, average8(y)&cmask
+			d.predictBlock(ybrBY+j, ybrBX+i, ref.Cb, ref.CStride, cw, ch, 8*mbx+i, 8*mby+j, 4, x, y)
+			d.predictBlock(ybrRY+j, ybrRX+i, ref.Cr, ref.CStride, cw, ch, 8*mbx+i, 8*mby+j, 4, x, y)
+		}
+	}
+}
+
+// average8 returns x divided by 8, rounded to nearest with ties away from
+// zero.
+func average8(x int32) int32 {
+	if x >= 0 {
+		return (x + 4) / 8
+	}
+	return (x - 4) / 8
+}
+
+// predictBlock sets the n×n block of the workspace at (ybrX, ybrY) to the
+// block at (x, y) of the w×h reference plane src, displaced by the motion
+// vector (mx, my) in eighth pixels. Pixels outside of the plane are those
+// of its nearest edge.
+func (d *Decoder) predictBlock(ybrY, ybrX int, src []uint8, stride, w, h, x, y, n int, mx, my int32) {
+	x += int(mx >> 3)
+	y += int(my >> 3)
+	fx, fy := mx&7, my&7
+
+	// Gather the block, with the 2 pixels left and above and the 3 pixels
+	// right and below it that the six-tap filters read.
+	const ps = 16 + 5
+	var patch [ps * ps]uint8
+	for j := 0; j < n+5; j++ {
+		row := src[stride*clampInt(y+j-2, h):]
+		p := patch[ps*j : ps*j+n+5]
+		if x-2 >= 0 && x+n+3 <= w {
+			copy(p, row[x-2:])
+			continue
+		}
+		for i := range p {
+			p[i] = row[clampInt(x+i-2, w)]
+		}
+	}
+
+	if fx == 0 && fy == 0 {
+		for j := 0; j < n; j++ {
+			copy(d.ybr[ybrY+j][ybrX:ybrX+n], patch[ps*(j+2)+2:])
+		}
+		return
+	}
+
+	// The filters are applied horizontally and then vertically, as
+	// specified in section 18.3.
+	var tmp [ps * 16]uint8
+	if v := d.frameHeader.VersionNumber; v >= 1 && v <= 3 {
+		f := &bilinearFilters[fx]
+		for j := 0; j < n+1; j++ {
+			for i := 0; i < n; i++ {
+				p := patch[ps*(j+2)+i+2:]
+				tmp[16*j+i] = uint8((int(p[0])*f[0] + int(p[1])*f[1] + 64) >> 7)
+			}
+		}
+		f = &bilinearFilters[fy]
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				p := tmp[16*j+i:]
+				d.ybr[ybrY+j][ybrX+i] = uint8((int(p[0])*f[0] + int(p[16])*f[1] + 64) >> 7)
+			}
+		}
+		return
+	}
+	f := &sixtapFilters[fx]
+	for j := 0; j < n+5; j++ {
+		for i := 0; i < n; i++ {
+			p := patch[ps*j+i:]
+			tmp[16*j+i] = clamp255((int(p[0])*f[0] + int(p[1])*f[1] + int(p[2])*f[2] +
+				int(p[3])*f[3] + int(p[4])*f[4] + int(p[5])*f[5] + 64) >> 7)
+		}
+	}
+	f = &sixtapFilters[fy]
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			p := tmp[16*j+i:]
+			d.ybr[ybrY+j][ybrX+i] = clamp255((int(p[0])*f[0] + int(p[16])*f[1] + int(p[32])*f[2] +
+				int(p[48])*f[3] + int(p[64])*f[4] + int(p[80])*f[5] + 64) >> 7)
+		}
+	}
+}
+
+// clampInt clamps x to the range [0, n).
+func clampInt(x, n int) int {
+	if x < 0 {
+		return 0
+	}
+	if x >= n {
+		return n - 1
+	}
+	return x
+}
+
+var (
+	// The default mode probabilities are specified in sections 16.2 and
+	// 11.5.
+	defaultYModeProb  = [nYMode - 1]uint8{112, 86, 140, 37}
+	defaultUVModeProb = [nUVMode - 1]uint8{162, 101, 204}
+	interBModeProb    = [9]uint8{120, 90, 79, 133, 87, 85, 80, 111, 151}
+
+	// The motion vector probabilities and the probabilities of updating
+	// them, for the vertical and then horizontal components, are specified
+	// in section 17.2.
+	defaultMVProb = [2][nMVProb]uint8{
+		{162, 128, 225, 146, 172, 147, 214, 39, 156, 128, 129, 132, 75, 145, 178, 206, 239, 254, 254},
+		{164, 128, 204, 170, 119, 235, 140, 230, 228, 128, 130, 130, 74, 148, 180, 203, 236, 254, 254},
+	}
+	mvProbUpdateProb = [2][nMVProb]uint8{
+		{237, 246, 253, 253, 254, 254, 254, 254, 254, 254, 254, 254, 254, 254, 250, 250, 252, 254, 254},
+		{231, 243, 245, 253, 254, 254, 254, 254, 254, 254, 254, 254, 254, 254, 251, 251, 254, 254, 254},
+	}
+
+	// The mode probabilities, given the counts of the neighboring
+	// macroblocks' motion vectors, are specified in section 16.3.
+	modeContexts = [6][4]uint8{
+		{7, 1, 1, 143},
+		{14, 18, 14, 107},
+		{135, 64, 57, 68},
+		{60, 56, 128, 65},
+		{159, 134, 128, 34},
+		{234, 188, 128, 28},
+	}
+
+	// The partitions of split macroblocks into 16x8, 8x16, 8x8 and 4x4
+	// regions, and the probabilities of the regions' motion vectors given
+	// those left of and above them, are specified in section 17.3.
+	splitMaps = [4][16]uint8{
+		{0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1},
+		{0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 1, 1, 0, 0, 1, 1},
+		{0, 0, 1, 1, 0, 0, 1, 1, 2, 2, 3, 3, 2, 2, 3, 3},
+		{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	}
+	splitCounts  = [4]uint8{2, 2, 4, 16}
+	subMVRefProb = [5][3]uint8{
+		{147, 136, 18},
+		{106, 145, 1},
+		{179, 121, 1},
+		{223, 1, 34},
+		{208, 1, 1},
+	}
+
+	// The six-tap and bilinear filters, for each eighth pixel position, are
+	// specified in section 18.3.
+	sixtapFilters = [8][6]int{
+		{0, 0, 128, 0, 0, 0},
+		{0, -6, 123, 12, -1, 0},
+		{2, -11, 108, 36, -8, 1},
+		{0, -9, 93, 50, -6, 0},
+		{3, -16, 77, 77, -16, 3},
+		{0, -6, 50, 93, -9, 0},
+		{1, -8, 36, 108, -11, 2},
+		{0, -1, 12, 123, -6, 0},
+	}
+	bilinearFilters = [8][2]int{
+		{128, 0},
+		{112, 16},
+		{96, 32},
+		{80, 48},
+		{64, 64},
+		{48, 80},
+		{32, 96},
+		{16, 112},
+	}
+)
@@ -0,0 +1,345 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vp8
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// interMB is the reference frame and motion vector of a macroblock of an
+// interframe written by writeInterFrame.
+type interMB struct {
+	ref uint8
+	mv  mv
+}
+
+// writeInterFrame returns an interframe of mbw×mbh macroblocks, all of which
+// are predicted from the given reference frames and motion vectors, with no
+// residuals. It refreshes the last frame if refreshLast is true, and no
+// other reference frame.
+//
+// It chooses each macroblock's mode, ZEROMV, NEARESTMV, NEARMV or NEWMV, as
+// an encoder would, from the motion vectors of the macroblocks above and left
+// of it.
+func writeInterFrame(mbw, mbh int, filterLevel uint8, refreshLast bool, mbs []interMB) []byte {
+	var w boolEncoder
+	w.init()
+	// No segmentation.
+	w.writeBit(uniformProb, false)
+	// The normal loop filter, with no mode or reference frame deltas.
+	w.writeBit(uniformProb, false)
+	w.writeUint(uniformProb, uint32(filterLevel), 6)
+	w.writeUint(uniformProb, 0, 3)
+	w.writeBit(uniformProb, false)
+	// One token partition.
+	w.writeUint(uniformProb, 0, 2)
+	// The quantizer index, with no deltas.
+	w.writeUint(uniformProb, 60, 7)
+	for i := 0; i < 5; i++ {
+		w.writeBit(uniformProb, false)
+	}
+	// Keep the golden and alternate reference frames, with no sign bias.
+	w.writeBit(uniformProb, false)
+	w.writeBit(uniformProb, false)
+	w.writeUint(uniformProb, 0, 2)
+	w.writeUint(uniformProb, 0, 2)
+	w.writeBit(uniformProb, false)
+	w.writeBit(uniformProb, false)
+	// Refresh the entropy probabilities, and maybe the last frame.
+	w.writeBit(uniformProb, true)
+	w.writeBit(uniformProb, refreshLast)
+	// No token probability updates.
+	for i := range tokenProbUpdateProb {
+		for j := range tokenProbUpdateProb[i] {
+			for k := range tokenProbUpdateProb[i][j] {
+				for _, p := range tokenProbUpdateProb[i][j][k] {
+					w.writeBit(p, false)
+				}
+			}
+		}
+	}
+	// Every macroblock is skipped.
+	const skipProb = 1
+	w.writeBit(uniformProb, true)
+	w.writeUint(uniformProb, skipProb, 8)
+	// The reference frame probabilities, and no mode or motion vector
+	// probability updates.
+	const intraProb, lastProb, goldenProb = 128, 128, 128
+	w.writeUint(uniformProb, intraProb, 8)
+	w.writeUint(uniformProb, lastProb, 8)
+	w.writeUint(uniformProb, goldenProb, 8)
+	w.writeBit(uniformProb, false)
+	w.writeBit(uniformProb, false)
+	for i := range mvProbUpdateProb {
+		for _, p := range mvProbUpdateProb[i] {
+			w.writeBit(p, false)
+		}
+	}
+
+	// d tracks the macroblocks' motion vectors, which the modes of those
+	// that follow are predicted from.
+	d := &Decoder{mbw: mbw, mbh: mbh, mbInfo: make([]mbInfo, mbw*mbh)}
+	for mby := 0; mby < mbh; mby++ {
+		for mbx := 0; mbx < mbw; mbx++ {
+			m := mbs[mbw*mby+mbx]
+			w.writeBit(skipProb, true)
+			w.writeBit(intraProb, true)
+			w.writeBit(lastProb, m.ref != refLast)
+			if m.ref != refLast {
+				w.writeBit(goldenProb, m.ref == refAltRef)
+			}
+			near, cnt := d.findNearMVs(mbx, mby, m.ref)
+			switch {
+			case m.mv == (mv{}):
+				w.writeBit(modeContexts[cnt[0]][0], false)
+			case m.mv == near[1]:
+				w.writeBit(modeContexts[cnt[0]][0], true)
+				w.writeBit(modeContexts[cnt[1]][1], false)
+			case m.mv == near[2]:
+				w.writeBit(modeContexts[cnt[0]][0], true)
+				w.writeBit(modeContexts[cnt[1]][1], true)
+				w.writeBit(modeContexts[cnt[2]][2], false)
+			default:
+				w.writeBit(modeContexts[cnt[0]][0], true)
+				w.writeBit(modeContexts[cnt[1]][1], true)
+				w.writeBit(modeContexts[cnt[2]][2], true)
+				w.writeBit(modeContexts[cnt[3]][3], false)
+				writeMVComponent(&w, &defaultMVProb[0], m.mv.y-near[0].y)
+				writeMVComponent(&w, &defaultMVProb[1], m.mv.x-near[0].x)
+			}
+			d.mbInfo[mbw*mby+mbx] = mbInfo{ref: m.ref, mv: m.mv}
+		}
+	}
+	first := w.flush()
+
+	// The frame tag, of a shown version 0 interframe, and then the first
+	// partition. The token partition is empty.
+	return append([]byte{
+		uint8(len(first)<<5) | 1<<4 | 1,
+		uint8(len(first) >> 3),
+		uint8(len(first) >> 11),
+	}, first...)
+}
+
+// writeMVComponent writes x, the inverse of Decoder.readMVComponent.
+func writeMVComponent(w *boolEncoder, p *[nMVProb]uint8, x int32) {
+	const (
+		isShort = 0
+		sign    = 1
+		short   = 2
+		long    = 9
+	)
+	a := x
+	if a < 0 {
+		a = -a
+	}
+	if a >= 8 {
+		w.writeBit(p[isShort], true)
+		for i := uint(0); i < 3; i++ {
+			w.writeBit(p[long+i], a&(1<<i) != 0)
+		}
+		for i := uint(9); i > 3; i-- {
+			w.writeBit(p[long+i], a&(1<<i) != 0)
+		}
+		if a&^0xf != 0 {
+			w.writeBit(p[long+3], a&8 != 0)
+		}
+	} else {
+		w.writeBit(p[isShort], false)
+		w.writeBit(p[short+0], a >= 4)
+		if a < 4 {
+			w.writeBit(p[short+1], a >= 2)
+			w.writeBit(p[short+2+a/2], a&1 != 0)
+		} else {
+			w.writeBit(p[short+4], a >= 6)
+			w.writeBit(p[short+5+(a-4)/2], a&1 != 0)
+		}
+	}
+	if x != 0 {
+		w.writeBit(p[sign], x < 0)
+	}
+}
+
+// copyYCbCr returns a copy of m, including the pixels outside of its Rect.
+func copyYCbCr(m *image.YCbCr) *image.YCbCr {
+	c := *m
+	c.Y = append([]uint8(nil), m.Y...)
+	c.Cb = append([]uint8(nil), m.Cb...)
+	c.Cr = append([]uint8(nil), m.Cr...)
+	return &c
+}
+
+// shifted returns the frame predicted from ref by mbs, whose motion vectors
+// are of whole chroma pixels: each macroblock's pixels are those of ref
+// displaced by its motion vector, with the pixels outside of ref being those
+// of its nearest edge.
+func shifted(ref [nRefFrame]*image.YCbCr, mbw, mbh int, mbs []interMB) *image.YCbCr {
+	m := copyYCbCr(ref[refLast])
+	clamp := func(x, n int) int {
+		if x < 0 {
+			return 0
+		}
+		if x >= n {
+			return n - 1
+		}
+		return x
+	}
+	for mby := 0; mby < mbh; mby++ {
+		for mbx := 0; mbx < mbw; mbx++ {
+			mb := mbs[mbw*mby+mbx]
+			r := ref[mb.ref]
+			// Luma motion vectors are in quarter pixels.
+			dx, dy := int(mb.mv.x/4), int(mb.mv.y/4)
+			for y := 16 * mby; y < 16*mby+16; y++ {
+				for x := 16 * mbx; x < 16*mbx+16; x++ {
+					sx, sy := clamp(x+dx, 16*mbw), clamp(y+dy, 16*mbh)
+					m.Y[y*m.YStride+x] = r.Y[sy*r.YStride+sx]
+				}
+			}
+			dx, dy = dx/2, dy/2
+			for y := 8 * mby; y < 8*mby+8; y++ {
+				for x := 8 * mbx; x < 8*mbx+8; x++ {
+					sx, sy := clamp(x+dx, 8*mbw), clamp(y+dy, 8*mbh)
+					m.Cb[y*m.CStride+x] = r.Cb[sy*r.CStride+sx]
+					m.Cr[y*m.CStride+x] = r.Cr[sy*r.CStride+sx]
+				}
+			}
+		}
+	}
+	return m
+}
+
+func sameYCbCr(a, b *image.YCbCr) bool {
+	return a.Rect == b.Rect && bytes.Equal(a.Y, b.Y) && bytes.Equal(a.Cb, b.Cb) && bytes.Equal(a.Cr, b.Cr)
+}
+
+// keyFrame returns a key frame of a w×h gradient, with some detail.
+func keyFrame(t *testing.T, w, h int) []byte {
+	m := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio444)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			m.Y[y*m.YStride+x] = uint8(3*x + 2*y + 16*((x/5+y/3)&1))
+			m.Cb[y*m.CStride+x] = uint8(4 * x)
+			m.Cr[y*m.CStride+x] = uint8(5 * y)
+		}
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &EncodeOptions{Quality: 90}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeInterframes(t *testing.T) {
+	const mbw, mbh = 4, 3
+	key := keyFrame(t, 16*mbw, 16*mbh)
+
+	// The motion vectors are of whole chroma pixels, so that the predicted
+	// pixels are copies of those of the reference frame. Some are repeated,
+	// so that the NEARESTMV and NEARMV modes are used as well as ZEROMV and
+	// NEWMV.
+	moving := []interMB{
+		{refLast, mv{0, 0}}, {refLast, mv{8, -16}}, {refLast, mv{8, -16}}, {refLast, mv{-24, 16}},
+		{refLast, mv{16, 8}}, {refLast, mv{16, 8}}, {refLast, mv{0, 0}}, {refLast, mv{-8, 8}},
+		{refLast, mv{-8, 8}}, {refLast, mv{-8, 8}}, {refLast, mv{8, -16}}, {refGolden, mv{16, 8}},
+	}
+	still := func(ref uint8) []interMB {
+		mbs := make([]interMB, mbw*mbh)
+		for i := range mbs {
+			mbs[i].ref = ref
+		}
+		return mbs
+	}
+
+	d := NewDecoder()
+	_, m, err := d.Decode(key)
+	if err != nil {
+		t.Fatalf("key frame: %v", err)
+	}
+	k := copyYCbCr(m)
+	refs := [nRefFrame]*image.YCbCr{refLast: k, refGolden: k, refAltRef: k}
+
+	// The first interframe moves the key frame's macroblocks, and becomes
+	// the last frame.
+	_, m, err = d.Decode(writeInterFrame(mbw, mbh, 0, true, moving))
+	if err != nil {
+		t.Fatalf("frame 1: %v", err)
+	}
+	f1 := copyYCbCr(m)
+	if want := shifted(refs, mbw, mbh, moving); !sameYCbCr(f1, want) {
+		t.Fatal("frame 1: moved macroblocks differ")
+	}
+	if sameYCbCr(f1, k) {
+		t.Fatal("frame 1: got the key frame")
+	}
+
+	// The second is the golden frame, which is still the key frame, and is
+	// not kept as the last frame.
+	_, m, err = d.Decode(writeInterFrame(mbw, mbh, 0, false, still(refGolden)))
+	if err != nil {
+		t.Fatalf("frame 2: %v", err)
+	}
+	if !sameYCbCr(m, k) {
+		t.Fatal("frame 2: got a different frame than the golden frame")
+	}
+
+	// The third is the last frame, which is still the first interframe.
+	_, m, err = d.Decode(writeInterFrame(mbw, mbh, 0, true, still(refLast)))
+	if err != nil {
+		t.Fatalf("frame 3: %v", err)
+	}
+	if !sameYCbCr(m, f1) {
+		t.Fatal("frame 3: got a different frame than the last frame")
+	}
+
+	// An interframe needs a preceding key frame.
+	if _, _, err := NewDecoder().Decode(writeInterFrame(mbw, mbh, 0, true, moving)); err == nil {
+		t.Fatal("interframe without a key frame: got nil error")
+	}
+}
+
+// TestDecodeConcurrentInterframes checks that decoding a key frame and
+// interframes concurrently, with the loop filter applied on a second
+// goroutine, gives the same frames as decoding them serially.
+func TestDecodeConcurrentInterframes(t *testing.T) {
+	key := readVP8(t, "video-001.lossy.webp")
+	fh, _, err := NewDecoder().Decode(key)
+	if err != nil {
+		t.Fatalf("key frame: %v", err)
+	}
+	mbw, mbh := (fh.Width+15)/16, (fh.Height+15)/16
+
+	frames := [][]byte{key}
+	for i, filterLevel := range []uint8{0, 20, 63} {
+		mbs := make([]interMB, mbw*mbh)
+		for j := range mbs {
+			// Include motion vectors of fractional pixels, and
+			// macroblocks that refer to the golden frame.
+			mbs[j] = interMB{refLast, mv{int32((i+j)%7 - 3), int32((2*i+j)%11 - 5)}}
+			if j%5 == 0 {
+				mbs[j].ref = refGolden
+			}
+		}
+		frames = append(frames, writeInterFrame(mbw, mbh, filterLevel, true, mbs))
+	}
+
+	serial, concurrent := NewDecoder(), NewDecoder()
+	concurrent.SetConcurrent(true)
+	for i, p := range frames {
+		_, want, err := serial.Decode(p)
+		if err != nil {
+			t.Fatalf("frame %d: serial: %v", i, err)
+		}
+		_, got, err := concurrent.Decode(p)
+		if err != nil {
+			t.Fatalf("frame %d: concurrent: %v", i, err)
+		}
+		if !sameYCbCr(got, want) {
+			t.Fatalf("frame %d: concurrent and serial decoding differ", i)
+		}
+	}
+}
@@ -43,30 +43,7 @@ func (d *Decoder) parsePredModeY4(mbx int) {
 	for j := 0; j < 4; j++ {
 		p := d.leftMB.pred[j]
 		for i := 0; i < 4; i++ {
-			prob := &predProb[d.upMB[mbx].pred[i]][p]
-			if !d.fp.readBit(prob[0]) {
-				p = predDC
-			} else if !d.fp.readBit(prob[1]) {
-				p = predTM
-			} else if !d.fp.readBit(prob[2]) {
-				p = predVE
-			} else if !d.fp.readBit(prob[3]) {
-				if !d.fp.readBit(prob[4]) {
-					p = predHE
-				} else if !d.fp.readBit(prob[5]) {
-					p = predRD
-				} else {
-					p = predVR
-				}
-			} else if !d.fp.readBit(prob[6]) {
-				p = predLD
-			} else if !d.fp.readBit(prob[7]) {
-				p = predVL
-			} else if !d.fp.readBit(prob[8]) {
-				p = predHD
-			} else {
-				p = predHU
-			}
+			p = d.readPredModeY4(&predProb[d.upMB[mbx].pred[i]][p])
 			d.predY4[j][i] = p
 			d.upMB[mbx].pred[i] = p
 		}
@@ -74,6 +51,68 @@ func (d *Decoder) parsePredModeY4(mbx int) {
 	}
 }
 
+// readPredModeY4 reads a 4x4 region's predictor mode with the given
+// probabilities.
+func (d *Decoder) readPredModeY4(prob *[9]uint8) uint8 {
+	if !d.fp.readBit(prob[0]) {
+		return predDC
+	} else if !d.fp.readBit(prob[1]) {
+		return predTM
+	} else if !d.fp.readBit(prob[2]) {
+		return predVE
+	} else if !d.fp.readBit(prob[3]) {
+		if !d.fp.readBit(prob[4]) {
+			return predHE
+		} else if !d.fp.readBit(prob[5]) {
+			return predRD
+		}
+		return predVR
+	} else if !d.fp.readBit(prob[6]) {
+		return predLD
+	} else if !d.fp.readBit(prob[7]) {
+		return predVL
+	} else if !d.fp.readBit(prob[8]) {
+		return predHD
+	}
+	return predHU
+}
+
+// parsePredModes parses the predictor modes of an intra macroblock of an
+// interframe, as specified in section 16.1. Unlike in key frames, their
+// probabilities do not depend on the neighboring macroblocks' modes.
+func (d *Decoder) parsePredModes() {
+	y := &d.ymodeProb
+	d.usePredY16 = true
+	if !d.fp.readBit(y[0]) {
+		d.predY16 = predDC
+	} else if !d.fp.readBit(y[1]) {
+		if !d.fp.readBit(y[2]) {
+			d.predY16 = predVE
+		} else {
+			d.predY16 = predHE
+		}
+	} else if !d.fp.readBit(y[3]) {
+		d.predY16 = predTM
+	} else {
+		d.usePredY16 = false
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				d.predY4[j][i] = d.readPredModeY4(&interBModeProb)
+			}
+		}
+	}
+	uv := &d.uvmodeProb
+	if !d.fp.readBit(uv[0]) {
+		d.predC8 = predDC
+	} else if !d.fp.readBit(uv[1]) {
+		d.predC8 = predVE
+	} else if !d.fp.readBit(uv[2]) {
+		d.predC8 = predHE
+	} else {
+		d.predC8 = predTM
+	}
+}
+
 // predProb are the probabilities to decode a 4x4 region's predictor mode given
 // the predictor modes of the regions above and left of it.
 // These values are specified in section 11.5.
@@ -343,46 +343,38 @@ func (d *Decoder) parseResiduals(mbx, mby int) (skip bool) {
 // reconstructMacroblock applies the predictor functions and adds the inverse-
 // DCT transformed residuals to recover the YCbCr data.
 func (d *Decoder) reconstructMacroblock(mbx, mby int) {
-	if d.usePredY16 {
+	if d.inter {
+		d.predictInter(mbx, mby)
+	} else if d.usePredY16 {
 		p := checkTopLeftPred(mbx, mby, d.predY16)
 		predFunc16[p](d, 1, 8)
-		for j := 0; j < 4; j++ {
-			for i := 0; i < 4; i++ {
-				n := 4*j + i
-				y := 4*j + 1
-				x := 4*i + 8
-				mask := uint32(1) << uint(n)
-				if d.nzACMask&mask != 0 {
-					d.inverseDCT4(y, x, 16*n)
-				} else if d.nzDCMask&mask != 0 {
-					d.inverseDCT4DCOnly(y, x, 16*n)
-				}
-			}
-		}
-	} else {
-		for j := 0; j < 4; j++ {
-			for i := 0; i < 4; i++ {
-				n := 4*j + i
-				y := 4*j + 1
-				x := 4*i + 8
+	}
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			n := 4*j + i
+			y := 4*j + 1
+			x := 4*i + 8
+			if !d.inter && !d.usePredY16 {
 				predFunc4[d.predY4[j][i]](d, y, x)
-				mask := uint32(1) << uint(n)
-				if d.nzACMask&mask != 0 {
-					d.inverseDCT4(y, x, 16*n)
-				} else if d.nzDCMask&mask != 0 {
-					d.inverseDCT4DCOnly(y, x, 16*n)
-				}
+			}
+			mask := uint32(1) << uint(n)
+			if d.nzACMask&mask != 0 {
+				d.inverseDCT4(y, x, 16*n)
+			} else if d.nzDCMask&mask != 0 {
+				d.inverseDCT4DCOnly(y, x, 16*n)
 			}
 		}
 	}
-	p := checkTopLeftPred(mbx, mby, d.predC8)
-	predFunc8[p](d, ybrBY, ybrBX)
+	if !d.inter {
+		p := checkTopLeftPred(mbx, mby, d.predC8)
+		predFunc8[p](d, ybrBY, ybrBX)
+		predFunc8[p](d, ybrRY, ybrRX)
+	}
 	if d.nzACMask&0x0f0000 != 0 {
 		d.inverseDCT8(ybrBY, ybrBX, bCoeffBase)
 	} else if d.nzDCMask&0x0f0000 != 0 {
 		d.inverseDCT8DCOnly(ybrBY, ybrBX, bCoeffBase)
 	}
-	predFunc8[p](d, ybrRY, ybrRX)
 	if d.nzACMask&0xf00000 != 0 {
 		d.inverseDCT8(ybrRY, ybrRX, rCoeffBase)
 	} else if d.nzDCMask&0xf00000 != 0 {
@@ -393,13 +385,17 @@ func (d *Decoder) reconstructMacroblock(mbx, mby int) {
 // reconstruct reconstructs one macroblock and returns whether inner loop
 // filtering should be skipped for it.
 func (d *Decoder) reconstruct(mbx, mby int) (skip bool) {
+	n := d.mbw*mby + mbx
 	if d.segmentHeader.updateMap {
 		if !d.fp.readBit(d.segmentHeader.prob[0]) {
-			d.segment = int(d.fp.readUint(d.segmentHeader.prob[1], 1))
+			d.segments[n] = uint8(d.fp.readUint(d.segmentHeader.prob[1], 1))
 		} else {
-			d.segment = int(d.fp.readUint(d.segmentHeader.prob[2], 1)) + 2
+			d.segments[n] = uint8(d.fp.readUint(d.segmentHeader.prob[2], 1)) + 2
 		}
+	} else if d.frameHeader.KeyFrame {
+		d.segments[n] = 0
 	}
+	d.segment = int(d.segments[n])
 	if d.useSkipProb {
 		skip = d.fp.readBit(d.skipProb)
 	}
@@ -408,14 +404,28 @@ func (d *Decoder) reconstruct(mbx, mby int) (skip bool) {
 		d.coeff[i] = 0
 	}
 	d.prepareYBR(mbx, mby)
-	// Parse the predictor modes.
-	d.usePredY16 = d.fp.readBit(145)
-	if d.usePredY16 {
-		d.parsePredModeY16(mbx)
+	// Parse the predictor modes, or the reference frame and motion vectors.
+	d.inter = !d.frameHeader.KeyFrame && d.fp.readBit(d.intraProb)
+	if d.inter {
+		d.parseInterModes(mbx, mby, &d.mbInfo[n])
 	} else {
-		d.parsePredModeY4(mbx)
+		d.mbInfo[n] = mbInfo{}
+		if d.frameHeader.KeyFrame {
+			d.usePredY16 = d.fp.readBit(145)
+			if d.usePredY16 {
+				d.parsePredModeY16(mbx)
+			} else {
+				d.parsePredModeY4(mbx)
+			}
+			d.parsePredModeC8()
+		} else {
+			d.parsePredModes()
+		}
+		d.lfMode = lfModeBPred
+		if d.usePredY16 {
+			d.lfMode = lfModeZero
+		}
 	}
-	d.parsePredModeC8()
 	// Parse the residuals.
 	if !skip {
 		skip = d.parseResiduals(mbx, mby)
@@ -2,7 +2,8 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package vp8l implements a decoder for the VP8L lossless image format.
+// Package vp8l implements a decoder and an encoder for the VP8L lossless
+// image format.
 //
 // The VP8L specification is at:
 // https://developers.google.com/speed/webp/docs/riff_container
@@ -61,6 +62,9 @@ type decoder struct {
 	r     io.ByteReader
 	bits  uint32
 	nBits uint32
+	// buf, if large enough, holds the top-level pixels instead of a newly
+	// allocated slice.
+	buf []byte
 }
 
 // read reads the next n bits from the decoder's bit-stream.
@@ -382,7 +386,12 @@ func (d *decoder) decodePix(w int32, h int32, minCap int32, topLevel bool) ([]by
 	if minCap < 4*w*h {
 		minCap = 4 * w * h
 	}
-	pix := make([]byte, 4*w*h, minCap)
+	var pix []byte
+	if topLevel && int32(cap(d.buf)) >= minCap {
+		pix = d.buf[:4*w*h]
+	} else {
+		pix = make([]byte, 4*w*h, minCap)
+	}
 	p, cachedP := 0, 0
 	x, y := int32(0), int32(0)
 	hg, lookupHG := &hGroups[0], hMask != 0
@@ -554,6 +563,31 @@ func DecodeConfig(r io.Reader) (image.Config, error) {
 
 // Decode decodes a VP8L image from r.
 func Decode(r io.Reader) (image.Image, error) {
+	m, err := decode(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DecodeInto is like Decode but decodes into dst's Pix, instead of a newly
+// allocated slice, if it has the capacity to hold the image, such as an image
+// returned by DecodeInto for an image of the same size, so that decoding a
+// sequence of images of the same size does not allocate their pixels. The
+// returned image shares dst's Pix, if it was used, but is otherwise new. dst
+// may be nil.
+//
+// If decoding fails, dst's pixels may have been partially overwritten.
+func DecodeInto(dst *image.NRGBA, r io.Reader) (*image.NRGBA, error) {
+	var buf []byte
+	if dst != nil {
+		buf = dst.Pix
+	}
+	return decode(r, buf)
+}
+
+// decode decodes a VP8L image from r, into buf if it is large enough.
+func decode(r io.Reader, buf []byte) (*image.NRGBA, error) {
 	d, w, h, err := decodeHeader(r)
 	if err != nil {
 		return nil, err
@@ -585,6 +619,14 @@ func Decode(r io.Reader) (image.Image, error) {
 		transforms[nTransforms] = t
 		nTransforms++
 	}
+	// The final pixels are those of the top level, unless a color-indexing
+	// transform unpacks bundled pixels into a new slice.
+	for i := 0; i < nTransforms; i++ {
+		if t := &transforms[i]; t.transformType == transformTypeColorIndexing && t.bits != 0 {
+			t.dst, buf = buf, nil
+		}
+	}
+	d.buf = buf
 	// Decode the transformed pixels.
 	pix, err := d.decodePix(w, h, 0, true)
 	if err != nil {
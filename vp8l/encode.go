@@ -0,0 +1,835 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vp8l
+
+// This file implements the encoder. It mirrors decode.go: each encoding
+// step is the inverse of the decoding step of the same name.
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// maxDimension is the maximum width or height of a VP8L image.
+const maxDimension = 1 << 14
+
+const (
+	// predictorBits is the log-2 tile size of the predictor transform.
+	predictorBits = 4
+	// maxCacheBits is the largest color cache size that the encoder tries.
+	maxCacheBits = 10
+)
+
+// These constants configure the LZ77 backwards reference search.
+const (
+	hashBits       = 16
+	maxChainLength = 32
+	minMatchLength = 3
+	maxMatchLength = 4096
+	// maxDistance is the largest distance that fits in a distance code.
+	maxDistance = 1<<20 - len(distanceMapTable)
+)
+
+// planeCodes is the inverse of distanceMapTable. Its index is a distance map
+// entry and its value is one plus that entry's index in distanceMapTable, or
+// zero if there is no such entry.
+var planeCodes = func() (t [128]uint8) {
+	for i, c := range distanceMapTable {
+		t[c] = uint8(i + 1)
+	}
+	return t
+}()
+
+// distanceCode returns the LZ77 distance code for a backwards reference of
+// dist pixels in an image w pixels wide, the inverse of distanceMap.
+func distanceCode(w int32, dist int32) uint32 {
+	yOffset, xOffset := dist/w, dist%w
+	if xOffset <= 8 && yOffset < 8 {
+		if c := planeCodes[yOffset<<4|(8-xOffset)]; c != 0 {
+			return uint32(c)
+		}
+	}
+	if xOffset > w-8 && yOffset < 7 {
+		if c := planeCodes[(yOffset+1)<<4|(8+w-xOffset)]; c != 0 {
+			return uint32(c)
+		}
+	}
+	return uint32(dist) + uint32(len(distanceMapTable))
+}
+
+// prefixEncode returns the prefix symbol and the extra bits for an LZ77
+// parameter: a length or a distance code. It is the inverse of lz77Param.
+func prefixEncode(v uint32) (symbol uint32, nExtra uint32, extra uint32) {
+	v--
+	if v < 4 {
+		return v, 0, 0
+	}
+	hb := uint32(bits.Len32(v)) - 1
+	nExtra = hb - 1
+	return 2*hb + (v>>nExtra)&1, nExtra, v & (1<<nExtra - 1)
+}
+
+// encoder holds the bit-stream for a VP8L image.
+type encoder struct {
+	buf   []byte
+	bits  uint64
+	nBits uint32
+}
+
+// write writes the least significant n bits of u to the encoder's
+// bit-stream.
+func (e *encoder) write(u uint32, n uint32) {
+	e.bits |= uint64(u) << e.nBits
+	e.nBits += n
+	for e.nBits >= 8 {
+		e.buf = append(e.buf, uint8(e.bits))
+		e.bits >>= 8
+		e.nBits -= 8
+	}
+}
+
+// flush pads the bit-stream to a whole number of bytes.
+func (e *encoder) flush() {
+	if e.nBits > 0 {
+		e.write(0, 8-e.nBits)
+	}
+}
+
+// huffmanCode holds the bit-reversed canonical Huffman codes and their
+// lengths, indexed by symbol, for writing to the LSB-first bit-stream.
+type huffmanCode struct {
+	codes   []uint32
+	lengths []uint32
+}
+
+func (e *encoder) writeSymbol(c *huffmanCode, symbol uint32) {
+	e.write(c.codes[symbol], c.lengths[symbol])
+}
+
+// newHuffmanCode returns the huffmanCode for the given code lengths. A tree
+// with a single symbol encodes that symbol with zero bits.
+func newHuffmanCode(codeLengths []uint32) huffmanCode {
+	c := huffmanCode{
+		codes:   make([]uint32, len(codeLengths)),
+		lengths: make([]uint32, len(codeLengths)),
+	}
+	nSymbols := 0
+	for _, cl := range codeLengths {
+		if cl != 0 {
+			nSymbols++
+		}
+	}
+	if nSymbols < 2 {
+		return c
+	}
+	codes, _ := codeLengthsToCodes(codeLengths)
+	for symbol, cl := range codeLengths {
+		if cl == 0 {
+			continue
+		}
+		c.codes[symbol] = bits.Reverse32(codes[symbol]) >> (32 - cl)
+		c.lengths[symbol] = cl
+	}
+	return c
+}
+
+// buildCodeLengths returns Huffman code lengths, no longer than maxLength,
+// for the given symbol frequencies.
+func buildCodeLengths(histogram []uint32, maxLength uint32) []uint32 {
+	codeLengths := make([]uint32, len(histogram))
+	var symbols []int
+	for symbol, count := range histogram {
+		if count != 0 {
+			symbols = append(symbols, symbol)
+		}
+	}
+	switch len(symbols) {
+	case 0:
+		return codeLengths
+	case 1:
+		codeLengths[symbols[0]] = 1
+		return codeLengths
+	}
+
+	weights := make([]uint32, len(histogram))
+	copy(weights, histogram)
+	n := len(symbols)
+	// Nodes [0, n) are the leaves and nodes [n, 2*n-1) are the internal
+	// nodes, in order of construction. The last node is the root.
+	weight := make([]uint64, 2*n-1)
+	parent := make([]int, 2*n-1)
+	depth := make([]uint32, 2*n-1)
+	for {
+		sort.Slice(symbols, func(i, j int) bool {
+			wi, wj := weights[symbols[i]], weights[symbols[j]]
+			return wi < wj || (wi == wj && symbols[i] < symbols[j])
+		})
+		for i, symbol := range symbols {
+			weight[i] = uint64(weights[symbol])
+		}
+		leaf, internal := 0, n
+		for next := n; next < 2*n-1; next++ {
+			for k := 0; k < 2; k++ {
+				child := internal
+				if leaf < n && (internal == next || weight[leaf] <= weight[internal]) {
+					child, leaf = leaf, leaf+1
+				} else {
+					internal++
+				}
+				weight[next] += weight[child]
+				parent[child] = next
+			}
+		}
+		maxDepth := uint32(0)
+		depth[2*n-2] = 0
+		for i := 2*n - 3; i >= 0; i-- {
+			depth[i] = depth[parent[i]] + 1
+		}
+		for i, symbol := range symbols {
+			codeLengths[symbol] = depth[i]
+			if maxDepth < depth[i] {
+				maxDepth = depth[i]
+			}
+		}
+		if maxDepth <= maxLength {
+			return codeLengths
+		}
+		// Flatten the distribution and try again.
+		for _, symbol := range symbols {
+			weights[symbol] = weights[symbol]>>1 + 1
+		}
+		for i := n; i < 2*n-1; i++ {
+			weight[i] = 0
+		}
+	}
+}
+
+// encodeCodeLengths encodes a Huffman tree's code lengths, the inverse of
+// decodeCodeLengths. Runs are encoded with the repeat codes.
+func (e *encoder) encodeCodeLengths(codeLengths []uint32) {
+	type token struct{ code, extra uint32 }
+	var tokens []token
+	for i := 0; i < len(codeLengths); {
+		cl, run := codeLengths[i], 1
+		for i+run < len(codeLengths) && codeLengths[i+run] == cl {
+			run++
+		}
+		i += run
+		if cl != 0 {
+			tokens = append(tokens, token{cl, 0})
+			run--
+		}
+		for run >= 3 {
+			n := 0
+			switch {
+			case cl != 0:
+				n = min(run, 6)
+				tokens = append(tokens, token{16, uint32(n - 3)})
+			case run >= 11:
+				n = min(run, 138)
+				tokens = append(tokens, token{18, uint32(n - 11)})
+			default:
+				n = min(run, 10)
+				tokens = append(tokens, token{17, uint32(n - 3)})
+			}
+			run -= n
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, token{cl, 0})
+		}
+	}
+
+	var histogram [len(codeLengthCodeOrder)]uint32
+	for _, t := range tokens {
+		histogram[t.code]++
+	}
+	codeLengthCodeLengths := buildCodeLengths(histogram[:], 7)
+	nCodes := len(codeLengthCodeOrder)
+	for nCodes > 4 && codeLengthCodeLengths[codeLengthCodeOrder[nCodes-1]] == 0 {
+		nCodes--
+	}
+	e.write(uint32(nCodes-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:nCodes] {
+		e.write(codeLengthCodeLengths[symbol], 3)
+	}
+	// Don't use a max_symbol: every symbol's code length is encoded.
+	e.write(0, 1)
+
+	c := newHuffmanCode(codeLengthCodeLengths)
+	for _, t := range tokens {
+		e.writeSymbol(&c, t.code)
+		if t.code >= repeatsCodeLength {
+			e.write(t.extra, uint32(repeatBits[t.code-repeatsCodeLength]))
+		}
+	}
+}
+
+// encodeHuffmanTree encodes a Huffman tree with the given code lengths, the
+// inverse of decodeHuffmanTree, and returns its codes.
+func (e *encoder) encodeHuffmanTree(codeLengths []uint32) huffmanCode {
+	var (
+		nSymbols int
+		symbols  [2]uint32
+		simple   = true
+	)
+	for symbol, cl := range codeLengths {
+		if cl == 0 {
+			continue
+		}
+		if nSymbols == 2 || symbol >= nLiteralCodes {
+			simple = false
+			break
+		}
+		symbols[nSymbols] = uint32(symbol)
+		nSymbols++
+	}
+	if !simple {
+		e.write(0, 1)
+		e.encodeCodeLengths(codeLengths)
+		return newHuffmanCode(codeLengths)
+	}
+
+	// Use the simple code length code. An unused tree is encoded as a
+	// single zero symbol.
+	e.write(1, 1)
+	c := huffmanCode{
+		codes:   make([]uint32, len(codeLengths)),
+		lengths: make([]uint32, len(codeLengths)),
+	}
+	if nSymbols == 0 {
+		nSymbols = 1
+	}
+	e.write(uint32(nSymbols-1), 1)
+	if symbols[0] < 2 {
+		e.write(0, 1)
+		e.write(symbols[0], 1)
+	} else {
+		e.write(1, 1)
+		e.write(symbols[0], 8)
+	}
+	if nSymbols == 2 {
+		e.write(symbols[1], 8)
+		c.codes[symbols[1]] = 1
+		c.lengths[symbols[0]] = 1
+		c.lengths[symbols[1]] = 1
+	}
+	return c
+}
+
+const (
+	symbolLiteral = iota
+	symbolCache
+	symbolCopy
+)
+
+// symbol is an element of the encoded pixel data: a literal pixel, a color
+// cache index or a LZ77 backwards reference.
+type symbol struct {
+	kind uint8
+	// value is the ARGB color of a literal pixel, the color cache index,
+	// or the length of a backwards reference.
+	value uint32
+	// distCode is the distance code of a backwards reference.
+	distCode uint32
+}
+
+// matchLength returns the number of pixels, up to n, that are equal at
+// pix[i:] and pix[j:].
+func matchLength(pix []uint32, i int, j int, n int) int {
+	l := 0
+	for l < n && pix[i+l] == pix[j+l] {
+		l++
+	}
+	return l
+}
+
+// findBackwardRefs splits the pixels of an image w pixels wide into literal
+// pixels and LZ77 backwards references, using a hash chain.
+func findBackwardRefs(pix []uint32, w int32) []symbol {
+	const hashShift = 32 - hashBits
+	hash := func(i int) uint32 {
+		return ((pix[i] * colorCacheMultiplier) ^ (pix[i+1] * 0x9e3779b1)) >> hashShift
+	}
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, len(pix))
+	insert := func(i int) {
+		if i+1 < len(pix) {
+			h := hash(i)
+			prev[i], head[h] = head[h], int32(i)
+		}
+	}
+
+	// The left and upper neighbors are tried first, as their distance
+	// codes are the cheapest.
+	neighbors := [2]int{1, int(w)}
+	syms := make([]symbol, 0, len(pix))
+	for i := 0; i < len(pix); {
+		maxLength := min(len(pix)-i, maxMatchLength)
+		bestLength, bestDist := 0, 0
+		if maxLength >= minMatchLength {
+			for _, d := range neighbors {
+				if d <= i {
+					if l := matchLength(pix, i-d, i, maxLength); l > bestLength {
+						bestLength, bestDist = l, d
+					}
+				}
+			}
+			for j, n := head[hash(i)], 0; j >= 0 && n < maxChainLength && bestLength < maxLength; j, n = prev[j], n+1 {
+				d := i - int(j)
+				if d > maxDistance {
+					break
+				}
+				if l := matchLength(pix, int(j), i, maxLength); l > bestLength {
+					bestLength, bestDist = l, d
+				}
+			}
+		}
+		if bestLength < minMatchLength {
+			syms = append(syms, symbol{kind: symbolLiteral, value: pix[i]})
+			insert(i)
+			i++
+			continue
+		}
+		syms = append(syms, symbol{
+			kind:     symbolCopy,
+			value:    uint32(bestLength),
+			distCode: distanceCode(w, int32(bestDist)),
+		})
+		for end := i + bestLength; i < end; i++ {
+			insert(i)
+		}
+	}
+	return syms
+}
+
+// applyColorCache replaces the literal pixels that are in a color cache of
+// 1<<ccBits entries with color cache indexes.
+func applyColorCache(syms []symbol, pix []uint32, ccBits uint32) []symbol {
+	if ccBits == 0 {
+		return syms
+	}
+	ccShift, ccEntries := 32-ccBits, make([]uint32, 1<<ccBits)
+	dst, p := make([]symbol, len(syms)), 0
+	for i, s := range syms {
+		dst[i] = s
+		if s.kind == symbolCopy {
+			for end := p + int(s.value); p < end; p++ {
+				ccEntries[(pix[p]*colorCacheMultiplier)>>ccShift] = pix[p]
+			}
+			continue
+		}
+		k := (s.value * colorCacheMultiplier) >> ccShift
+		if ccEntries[k] == s.value {
+			dst[i] = symbol{kind: symbolCache, value: k}
+		}
+		ccEntries[k] = s.value
+		p++
+	}
+	return dst
+}
+
+// histograms returns the symbol frequencies of each of the five Huffman
+// trees, and the number of extra bits, for the encoded pixel data.
+func histograms(syms []symbol, ccBits uint32) (h [nHuff][]uint32, extraBits uint32) {
+	for i, alphabetSize := range alphabetSizes {
+		if i == huffGreen && ccBits > 0 {
+			alphabetSize += 1 << ccBits
+		}
+		h[i] = make([]uint32, alphabetSize)
+	}
+	for _, s := range syms {
+		switch s.kind {
+		case symbolLiteral:
+			h[huffAlpha][s.value>>24]++
+			h[huffRed][(s.value>>16)&0xff]++
+			h[huffGreen][(s.value>>8)&0xff]++
+			h[huffBlue][s.value&0xff]++
+		case symbolCache:
+			h[huffGreen][nLiteralCodes+nLengthCodes+s.value]++
+		case symbolCopy:
+			lengthSymbol, nExtra, _ := prefixEncode(s.value)
+			h[huffGreen][nLiteralCodes+lengthSymbol]++
+			extraBits += nExtra
+			distSymbol, nExtra, _ := prefixEncode(s.distCode)
+			h[huffDistance][distSymbol]++
+			extraBits += nExtra
+		}
+	}
+	return h, extraBits
+}
+
+// entropy returns the estimated number of bits needed to Huffman encode
+// symbols with the given frequencies.
+func entropy(histogram []uint32) float64 {
+	total, sum := uint32(0), 0.0
+	for _, count := range histogram {
+		if count != 0 {
+			total += count
+			sum += float64(count) * math.Log2(float64(count))
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(total)*math.Log2(float64(total)) - sum
+}
+
+// encodePix encodes pixel data, the inverse of decodePix.
+func (e *encoder) encodePix(pix []uint32, w int32, topLevel bool) {
+	// Choose the color cache size with the smallest estimated cost.
+	syms := findBackwardRefs(pix, w)
+	bestSyms, bestBits, bestCost := syms, uint32(0), math.Inf(1)
+	for ccBits := uint32(0); ccBits <= maxCacheBits; ccBits++ {
+		s := applyColorCache(syms, pix, ccBits)
+		h, extraBits := histograms(s, ccBits)
+		cost := float64(extraBits)
+		for i := range h {
+			cost += entropy(h[i])
+		}
+		if cost < bestCost {
+			bestSyms, bestBits, bestCost = s, ccBits, cost
+		}
+	}
+	syms, ccBits := bestSyms, bestBits
+
+	// Encode the color cache parameters.
+	if ccBits == 0 {
+		e.write(0, 1)
+	} else {
+		e.write(1, 1)
+		e.write(ccBits, 4)
+	}
+
+	// Encode the Huffman groups. There is only one group, so there is no
+	// meta Huffman image.
+	if topLevel {
+		e.write(0, 1)
+	}
+	h, _ := histograms(syms, ccBits)
+	var codes [nHuff]huffmanCode
+	for i := range codes {
+		codes[i] = e.encodeHuffmanTree(buildCodeLengths(h[i], 15))
+	}
+
+	// Encode the pixels.
+	for _, s := range syms {
+		switch s.kind {
+		case symbolLiteral:
+			e.writeSymbol(&codes[huffGreen], (s.value>>8)&0xff)
+			e.writeSymbol(&codes[huffRed], (s.value>>16)&0xff)
+			e.writeSymbol(&codes[huffBlue], s.value&0xff)
+			e.writeSymbol(&codes[huffAlpha], s.value>>24)
+		case symbolCache:
+			e.writeSymbol(&codes[huffGreen], nLiteralCodes+nLengthCodes+s.value)
+		case symbolCopy:
+			lengthSymbol, nExtra, extra := prefixEncode(s.value)
+			e.writeSymbol(&codes[huffGreen], nLiteralCodes+lengthSymbol)
+			e.write(extra, nExtra)
+			distSymbol, nExtra, extra := prefixEncode(s.distCode)
+			e.writeSymbol(&codes[huffDistance], distSymbol)
+			e.write(extra, nExtra)
+		}
+	}
+}
+
+// subPixels returns the per-channel difference a - b of two ARGB colors.
+func subPixels(a, b uint32) uint32 {
+	ag := 0x00ff00ff + a&0xff00ff00 - b&0xff00ff00
+	rb := 0xff00ff00 + a&0x00ff00ff - b&0x00ff00ff
+	return ag&0xff00ff00 | rb&0x00ff00ff
+}
+
+// channels returns the A, R, G and B channels of an ARGB color.
+func channels(c uint32) [4]uint8 {
+	return [4]uint8{uint8(c >> 24), uint8(c >> 16), uint8(c >> 8), uint8(c)}
+}
+
+// mapChannels returns the ARGB color whose channels are f applied to the
+// corresponding channels of a, b and c.
+func mapChannels(a, b, c uint32, f func(a, b, c uint8) uint8) uint32 {
+	x, y, z := channels(a), channels(b), channels(c)
+	return uint32(f(x[0], y[0], z[0]))<<24 |
+		uint32(f(x[1], y[1], z[1]))<<16 |
+		uint32(f(x[2], y[2], z[2]))<<8 |
+		uint32(f(x[3], y[3], z[3]))
+}
+
+// average2 returns the per-channel average of two ARGB colors.
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + a&b
+}
+
+// predict returns the predicted ARGB color of the pixel at pix[p], for a
+// pixel not in the first row or column of an image w pixels wide. It matches
+// the predictors in inversePredictor.
+func predict(mode uint8, pix []uint32, p int, w int) uint32 {
+	l, t := pix[p-1], pix[p-w]
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return pix[p-w+1]
+	case 4:
+		return pix[p-w-1]
+	case 5:
+		return average2(average2(l, pix[p-w+1]), t)
+	case 6:
+		return average2(l, pix[p-w-1])
+	case 7:
+		return average2(l, t)
+	case 8:
+		return average2(pix[p-w-1], t)
+	case 9:
+		return average2(t, pix[p-w+1])
+	case 10:
+		return average2(average2(l, pix[p-w-1]), average2(t, pix[p-w+1]))
+	case 11:
+		lc, tc, cc := channels(l), channels(t), channels(pix[p-w-1])
+		pl, pt := int32(0), int32(0)
+		for i := range cc {
+			pl += abs(int32(cc[i]) - int32(tc[i]))
+			pt += abs(int32(cc[i]) - int32(lc[i]))
+		}
+		if pl < pt {
+			return l
+		}
+		return t
+	case 12:
+		return mapChannels(l, t, pix[p-w-1], clampAddSubtractFull)
+	case 13:
+		return mapChannels(average2(l, t), pix[p-w-1], 0, func(a, b, _ uint8) uint8 {
+			return clampAddSubtractHalf(a, b)
+		})
+	}
+	return 0
+}
+
+// residualCost returns an estimate of the cost of encoding an ARGB residual.
+func residualCost(r uint32) int32 {
+	c := channels(r)
+	return abs(int32(int8(c[0]))) + abs(int32(int8(c[1]))) +
+		abs(int32(int8(c[2]))) + abs(int32(int8(c[3])))
+}
+
+// forwardPredictor applies the predictor transform to pix, the inverse of
+// inversePredictor. For each tile, it chooses the predictor mode with the
+// smallest residuals. It returns the residuals and the tile image.
+func forwardPredictor(pix []uint32, w int32, h int32) (residuals []uint32, tiles []uint32) {
+	residuals = make([]uint32, len(pix))
+	tilesPerRow, tilesPerColumn := nTiles(w, predictorBits), nTiles(h, predictorBits)
+	tiles = make([]uint32, tilesPerRow*tilesPerColumn)
+	iw := int(w)
+	for ty := int32(0); ty < tilesPerColumn; ty++ {
+		y0, y1 := ty<<predictorBits, min32((ty+1)<<predictorBits, h)
+		for tx := int32(0); tx < tilesPerRow; tx++ {
+			x0, x1 := tx<<predictorBits, min32((tx+1)<<predictorBits, w)
+			bestMode, bestCost := uint8(0), int32(math.MaxInt32)
+			for mode := uint8(0); mode < 14; mode++ {
+				cost := int32(0)
+				for y := max32(y0, 1); y < y1; y++ {
+					for x := max32(x0, 1); x < x1; x++ {
+						p := int(y)*iw + int(x)
+						cost += residualCost(subPixels(pix[p], predict(mode, pix, p, iw)))
+					}
+				}
+				if cost < bestCost {
+					bestMode, bestCost = mode, cost
+				}
+			}
+			tiles[ty*tilesPerRow+tx] = 0xff000000 | uint32(bestMode)<<8
+		}
+	}
+
+	for y, p := int32(0), 0; y < h; y++ {
+		for x := int32(0); x < w; x, p = x+1, p+1 {
+			var pred uint32
+			switch {
+			case x == 0 && y == 0:
+				pred = 0xff000000
+			case y == 0:
+				pred = pix[p-1]
+			case x == 0:
+				pred = pix[p-iw]
+			default:
+				mode := uint8(tiles[(y>>predictorBits)*tilesPerRow+(x>>predictorBits)] >> 8)
+				pred = predict(mode, pix, p, iw)
+			}
+			residuals[p] = subPixels(pix[p], pred)
+		}
+	}
+	return residuals, tiles
+}
+
+// forwardSubtractGreen applies the subtract-green transform to pix in place,
+// the inverse of inverseSubtractGreen.
+func forwardSubtractGreen(pix []uint32) {
+	for p, c := range pix {
+		g := (c >> 8) & 0xff
+		pix[p] = subPixels(c, g<<16|g)
+	}
+}
+
+// findPalette returns the colors of pix, sorted, if there are no more than
+// 256 of them.
+func findPalette(pix []uint32) ([]uint32, bool) {
+	seen := map[uint32]bool{}
+	for _, c := range pix {
+		if !seen[c] {
+			if len(seen) == 256 {
+				return nil, false
+			}
+			seen[c] = true
+		}
+	}
+	palette := make([]uint32, 0, len(seen))
+	for c := range seen {
+		palette = append(palette, c)
+	}
+	sort.Slice(palette, func(i, j int) bool { return palette[i] < palette[j] })
+	return palette, true
+}
+
+// forwardColorIndexing replaces the colors of pix with their palette index,
+// the inverse of inverseColorIndexing. Small palettes pack several indexes
+// into each pixel. It returns the indexes and the new image width.
+func forwardColorIndexing(pix []uint32, w int32, h int32, palette []uint32) ([]uint32, int32) {
+	xBits := uint32(0)
+	switch n := len(palette); {
+	case n <= 2:
+		xBits = 3
+	case n <= 4:
+		xBits = 2
+	case n <= 16:
+		xBits = 1
+	}
+	index := make(map[uint32]uint32, len(palette))
+	for i, c := range palette {
+		index[c] = uint32(i)
+	}
+	newW, bitsPerPixel, xMask := nTiles(w, xBits), uint32(8>>xBits), int32(1)<<xBits-1
+	dst := make([]uint32, newW*h)
+	for y, p := int32(0), 0; y < h; y++ {
+		row := dst[y*newW:]
+		for x := int32(0); x < w; x, p = x+1, p+1 {
+			row[x>>xBits] |= index[pix[p]] << (8 + bitsPerPixel*uint32(x&xMask))
+		}
+	}
+	return dst, newW
+}
+
+// encodeTransforms chooses and applies the transforms, encoding their
+// parameters. It returns the transformed pixels and the width of the image
+// after transformation.
+func (e *encoder) encodeTransforms(pix []uint32, w int32, h int32) ([]uint32, int32) {
+	if palette, ok := findPalette(pix); ok {
+		e.write(1, 1)
+		e.write(transformTypeColorIndexing, 2)
+		e.write(uint32(len(palette)-1), 8)
+		deltas := make([]uint32, len(palette))
+		deltas[0] = palette[0]
+		for i := 1; i < len(palette); i++ {
+			deltas[i] = subPixels(palette[i], palette[i-1])
+		}
+		e.encodePix(deltas, int32(len(deltas)), false)
+		pix, w = forwardColorIndexing(pix, w, h, palette)
+	} else {
+		e.write(1, 1)
+		e.write(transformTypeSubtractGreen, 2)
+		forwardSubtractGreen(pix)
+
+		e.write(1, 1)
+		e.write(transformTypePredictor, 2)
+		e.write(predictorBits-2, 3)
+		var tiles []uint32
+		pix, tiles = forwardPredictor(pix, w, h)
+		e.encodePix(tiles, nTiles(w, predictorBits), false)
+	}
+	e.write(0, 1)
+	return pix, w
+}
+
+// toARGB returns the non-alpha-premultiplied ARGB colors of m's pixels, and
+// whether any of them are not opaque.
+func toARGB(m image.Image) (pix []uint32, hasAlpha bool) {
+	b := m.Bounds()
+	pix = make([]uint32, 0, b.Dx()*b.Dy())
+	if m, ok := m.(*image.NRGBA); ok {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			s := m.Pix[m.PixOffset(b.Min.X, y):m.PixOffset(b.Max.X, y)]
+			for i := 0; i < len(s); i += 4 {
+				pix = append(pix, uint32(s[i+3])<<24|uint32(s[i+0])<<16|uint32(s[i+1])<<8|uint32(s[i+2]))
+				hasAlpha = hasAlpha || s[i+3] != 0xff
+			}
+		}
+		return pix, hasAlpha
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+			pix = append(pix, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+			hasAlpha = hasAlpha || c.A != 0xff
+		}
+	}
+	return pix, hasAlpha
+}
+
+// Encode writes the image m to w in the VP8L lossless format.
+func Encode(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	if b.Dx() < 1 || b.Dy() < 1 || b.Dx() > maxDimension || b.Dy() > maxDimension {
+		return errors.New("vp8l: invalid image dimensions")
+	}
+	width, height := int32(b.Dx()), int32(b.Dy())
+	pix, hasAlpha := toARGB(m)
+
+	e := &encoder{}
+	e.write(0x2f, 8)
+	e.write(uint32(width-1), 14)
+	e.write(uint32(height-1), 14)
+	if hasAlpha {
+		e.write(1, 1)
+	} else {
+		e.write(0, 1)
+	}
+	e.write(0, 3)
+	pix, width = e.encodeTransforms(pix, width, height)
+	e.encodePix(pix, width, true)
+	e.flush()
+	_, err := w.Write(e.buf)
+	return err
+}
+
+func min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func min32(x, y int32) int32 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func max32(x, y int32) int32 {
+	if x > y {
+		return x
+	}
+	return y
+}
@@ -37,6 +37,9 @@ type transform struct {
 	// pix is the tile values, for the predictor and cross-color
 	// transforms, and the color palette, for the color-index transform.
 	pix []byte
+	// dst, if large enough, holds the unbundled pixels of a color-index
+	// transform with non-zero bits, instead of a newly allocated slice.
+	dst []byte
 }
 
 var inverseTransforms = [nTransformTypes]func(*transform, []byte, int32) []byte{
@@ -244,7 +247,13 @@ func inverseColorIndexing(t *transform, pix []byte, h int32) []byte {
 		vMask, xMask = 0x01, 0x07
 	}
 
-	d, p, v, dst := 0, 0, uint32(0), make([]byte, 4*t.oldWidth*h)
+	dst := t.dst
+	if n := int(4 * t.oldWidth * h); cap(dst) >= n {
+		dst = dst[:n]
+	} else {
+		dst = make([]byte, n)
+	}
+	d, p, v := 0, 0, uint32(0)
 	for y := int32(0); y < h; y++ {
 		for x := int32(0); x < t.oldWidth; x++ {
 			if x&xMask == 0 {
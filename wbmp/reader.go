@@ -0,0 +1,141 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package wbmp implements a decoder and encoder for Wireless Bitmap (WBMP)
+// images, the bilevel image format of WAP phones and of other small devices.
+//
+// Only type 0 WBMP images, which are uncompressed and without extension
+// headers, are supported. They hold rows of bits, most significant bit
+// first, with 1 meaning white and 0 meaning black, and with each row padded
+// to a whole byte: the layout of golang.org/x/image/bitmap images.
+//
+// WBMP images have no signature to recognize them by, other than two zero
+// bytes, which are also how ICO and CUR files start, so this package does
+// not register itself with the image package. Decode WBMP images by calling
+// this package's Decode function directly.
+//
+// The format is described in the WAP Wireless Application Environment
+// Specification, WAP-190-WAESpec, appendix A.
+package wbmp // import "golang.org/x/image/wbmp"
+
+import (
+	"bufio"
+	"image"
+	"io"
+
+	"golang.org/x/image/bitmap"
+)
+
+// A FormatError reports that the input is not a valid WBMP image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "wbmp: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// WBMP feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "wbmp: unsupported feature: " + string(e)
+}
+
+// maxPixels is the largest number of pixels, width × height, of an image
+// that Decode accepts.
+const maxPixels = 1 << 32
+
+// readUint reads a multi-byte integer: 7 bits per byte, most significant
+// first, with the high bit of each byte but the last set.
+func readUint(r io.ByteReader) (int, error) {
+	v := 0
+	for i := 0; ; i++ {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		// Larger values than 1<<28 are not image dimensions.
+		if i == 4 {
+			return 0, FormatError("bad multi-byte integer")
+		}
+		v = v<<7 | int(c&0x7f)
+		if c&0x80 == 0 {
+			return v, nil
+		}
+	}
+}
+
+// readHeader reads a WBMP image's header: its type, fixed header field,
+// width and height.
+func readHeader(r io.ByteReader) (width, height int, err error) {
+	typ, err := readUint(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	if typ != 0 {
+		return 0, 0, UnsupportedError("type")
+	}
+	fixHeader, err := r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	// The high bit flags extension headers, which type 0 images do not
+	// have.
+	if fixHeader&0x80 != 0 {
+		return 0, 0, UnsupportedError("extension headers")
+	}
+	if width, err = readUint(r); err != nil {
+		return 0, 0, err
+	}
+	if height, err = readUint(r); err != nil {
+		return 0, 0, err
+	}
+	if int64(width)*int64(height) > maxPixels {
+		return 0, 0, UnsupportedError("image too large")
+	}
+	return width, height, nil
+}
+
+// Decode reads a WBMP image from r and returns it as a *bitmap.Image.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	width, height, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	m := bitmap.New(image.Rect(0, 0, width, height))
+	if _, err := io.ReadFull(br, m.Pix); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	// Clear the padding bits at the end of each row.
+	if rem := width % 8; rem != 0 {
+		for y := 0; y < height; y++ {
+			m.Pix[(y+1)*m.Stride-1] &= 0xff << uint(8-rem)
+		}
+	}
+	return m, nil
+}
+
+// DecodeConfig returns the color model and dimensions of a WBMP image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	width, height, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: bitmap.Model,
+		Width:      width,
+		Height:     height,
+	}, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wbmp
+
+import (
+	"image"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bitmap"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		want []string
+	}{{
+		"small",
+		"\x00\x00\x0a\x02\x7f\x80\x00\x3f",
+		[]string{
+			"X........X",
+			"XXXXXXXXXX",
+		},
+	}, {
+		"multi-byte width",
+		"\x00\x00\x81\x01\x01" + strings.Repeat("\xff", 16) + "\x7f",
+		[]string{
+			strings.Repeat(".", 128) + "X",
+		},
+	}}
+	for _, tc := range testCases {
+		m, err := Decode(strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if m.ColorModel() != bitmap.Model {
+			t.Errorf("%s: color model: got %v, want bitmap.Model", tc.name, m.ColorModel())
+		}
+		if got, want := m.Bounds(), image.Rect(0, 0, len(tc.want[0]), len(tc.want)); got != want {
+			t.Errorf("%s: bounds: got %v, want %v", tc.name, got, want)
+			continue
+		}
+		for y, row := range tc.want {
+			for x := range row {
+				want := bitmap.White
+				if row[x] == 'X' {
+					want = bitmap.Black
+				}
+				if got := m.At(x, y); got != want {
+					t.Errorf("%s: (%d, %d): got %v, want %v", tc.name, x, y, got, want)
+				}
+			}
+		}
+		c, err := DecodeConfig(strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: DecodeConfig: %v", tc.name, err)
+		} else if c.ColorModel != bitmap.Model || c.Width != len(tc.want[0]) || c.Height != len(tc.want) {
+			t.Errorf("%s: DecodeConfig: got %v", tc.name, c)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"empty", "", io.ErrUnexpectedEOF},
+		{"truncated header", "\x00\x00\x08", io.ErrUnexpectedEOF},
+		{"type", "\x01\x00\x08\x01\x00", UnsupportedError("type")},
+		{"extension headers", "\x00\x80\x08\x01\x00", UnsupportedError("extension headers")},
+		{"bad integer", "\x00\x00\x81\x81\x81\x81\x01", FormatError("bad multi-byte integer")},
+		{"truncated data", "\x00\x00\x09\x01\x00", io.ErrUnexpectedEOF},
+		{"too large", "\x00\x00\xff\xff\xff\x7f\xff\xff\xff\x7f", UnsupportedError("image too large")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(strings.NewReader(tc.data)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wbmp
+
+import (
+	"bufio"
+	"image"
+	"io"
+
+	"golang.org/x/image/bitmap"
+)
+
+// appendUint appends v as a multi-byte integer.
+func appendUint(dst []byte, v int) []byte {
+	n := 1
+	for u := v >> 7; u != 0; u >>= 7 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := byte(v>>uint(7*i)) & 0x7f
+		if i != 0 {
+			c |= 0x80
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// Encode writes the image m to w as a type 0 WBMP image.
+//
+// Images other than *bitmap.Image are converted by bitmap.Model, so that
+// pixels whose gray level is less than half are written as black.
+func Encode(w io.Writer, m image.Image) error {
+	b := m.Bounds()
+	bm, ok := m.(*bitmap.Image)
+	if !ok {
+		bm = bitmap.FromImage(m)
+	}
+
+	header := []byte{0x00, 0x00}
+	header = appendUint(header, b.Dx())
+	header = appendUint(header, b.Dy())
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+
+	row := make([]byte, (b.Dx()+7)/8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		if b.Min.X&7 == 0 {
+			// The row's bits are already aligned to its bytes.
+			copy(row, bm.Pix[bm.PixOffset(b.Min.X, y):])
+		} else {
+			for i := range row {
+				row[i] = 0
+			}
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if bm.BitAt(x, y) {
+					i := x - b.Min.X
+					row[i>>3] |= 0x80 >> uint(i&7)
+				}
+			}
+		}
+		if rem := b.Dx() % 8; rem != 0 {
+			row[len(row)-1] &= 0xff << uint(8-rem)
+		}
+		if _, err := bw.Write(row); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
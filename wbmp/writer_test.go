@@ -0,0 +1,71 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package wbmp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/bitmap"
+)
+
+func TestEncode(t *testing.T) {
+	m := bitmap.New(image.Rect(0, 0, 130, 2))
+	for x := 1; x < 130; x++ {
+		m.SetBit(x, 0, true)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x00\x00\x81\x02\x02" +
+		"\x7f" + string(bytes.Repeat([]byte{0xff}, 15)) + "\xc0" +
+		string(make([]byte, 17))
+	if got := buf.String(); got != want {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := image.Rect(3, 5, 40, 20)
+	gray := image.NewGray(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			gray.SetGray(x, y, color.Gray{uint8(x*37 + y*11)})
+		}
+	}
+	bm := bitmap.FromImage(gray)
+	for _, m := range []image.Image{
+		gray,
+		bm,
+		bm.SubImage(image.Rect(8, 6, 30, 19)),
+		bm.SubImage(image.Rect(9, 6, 30, 19)),
+	} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m); err != nil {
+			t.Errorf("%v: Encode: %v", m.Bounds(), err)
+			continue
+		}
+		got, err := Decode(&buf)
+		if err != nil {
+			t.Errorf("%v: Decode: %v", m.Bounds(), err)
+			continue
+		}
+		b := m.Bounds()
+		if got.Bounds() != b.Sub(b.Min) {
+			t.Errorf("%v: bounds: got %v, want %v", m.Bounds(), got.Bounds(), b.Sub(b.Min))
+			continue
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if g, w := got.At(x-b.Min.X, y-b.Min.Y), bitmap.Model.Convert(m.At(x, y)); g != w {
+					t.Fatalf("%v: (%d, %d): got %v, want %v", m.Bounds(), x, y, g, w)
+				}
+			}
+		}
+	}
+}
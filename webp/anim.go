@@ -0,0 +1,183 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webp
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"time"
+
+	"golang.org/x/image/imagelimit"
+	"golang.org/x/image/riff"
+)
+
+// Disposal methods.
+const (
+	// DisposalNone leaves the canvas as is after showing the frame.
+	DisposalNone = 0x00
+	// DisposalBackground clears the frame's rectangle to the background
+	// color after showing the frame.
+	DisposalBackground = 0x01
+)
+
+// Blending methods.
+const (
+	// BlendAlpha alpha-blends the frame over the canvas.
+	BlendAlpha = 0x00
+	// BlendNone replaces the canvas's pixels in the frame's rectangle.
+	BlendNone = 0x01
+)
+
+// WEBP represents the possibly multiple images stored in a WEBP file.
+type WEBP struct {
+	// Image holds the successive frames. Each frame's bounds are its
+	// rectangle within the canvas. Frames are *image.YCbCr, *image.NYCbCrA
+	// or *image.NRGBA images.
+	Image []image.Image
+	// Duration holds the successive display times, one per frame.
+	Duration []time.Duration
+	// Disposal holds the successive disposal methods, one per frame.
+	Disposal []byte
+	// Blend holds the successive blending methods, one per frame.
+	Blend []byte
+	// LoopCount is the number of times the animation is played. Zero means
+	// that it loops forever.
+	LoopCount int
+	// BackgroundColor is the canvas's suggested background color, which
+	// decoders may ignore.
+	BackgroundColor color.NRGBA
+	// Config is the canvas's color model and dimensions.
+	Config image.Config
+}
+
+// frameInfo is the display parameters of an ANMF chunk's frame.
+type frameInfo struct {
+	duration time.Duration
+	disposal byte
+	blend    byte
+}
+
+// decodeFrameChunk decodes an ANMF chunk, the frame of an animation whose
+// canvas has the given dimensions. The frame's dimensions are checked against
+// limits, which may be nil.
+func decodeFrameChunk(chunkLen uint32, chunkData io.Reader, canvasWidthMinusOne, canvasHeightMinusOne uint32,
+	limits *imagelimit.Limits) (
+	image.Image, frameInfo, error) {
+
+	// The ANMF chunk starts with a 16-byte header, followed by the frame's
+	// chunks. The last 4 bytes of the header, the frame's duration and
+	// flags, are read by riff.NewListReader as if they were a list type.
+	const anmfHeaderLen = 16
+	if chunkLen < anmfHeaderLen {
+		return nil, frameInfo{}, errInvalidFormat
+	}
+	var buf [12]byte
+	if _, err := io.ReadFull(chunkData, buf[:]); err != nil {
+		return nil, frameInfo{}, err
+	}
+	durationAndFlags, frameReader, err := riff.NewListReader(chunkLen-uint32(len(buf)), chunkData)
+	if err != nil {
+		return nil, frameInfo{}, err
+	}
+	var (
+		x              = 2 * (int(buf[0]) | int(buf[1])<<8 | int(buf[2])<<16)
+		y              = 2 * (int(buf[3]) | int(buf[4])<<8 | int(buf[5])<<16)
+		widthMinusOne  = uint32(buf[6]) | uint32(buf[7])<<8 | uint32(buf[8])<<16
+		heightMinusOne = uint32(buf[9]) | uint32(buf[10])<<8 | uint32(buf[11])<<16
+		d              = durationAndFlags
+		flags          = d[3]
+	)
+	if x+int(widthMinusOne) > int(canvasWidthMinusOne) || y+int(heightMinusOne) > int(canvasHeightMinusOne) {
+		return nil, frameInfo{}, errInvalidFormat
+	}
+	const (
+		disposalBit = 1 << 0
+		blendingBit = 1 << 1
+	)
+	f := frameInfo{
+		duration: time.Duration(int(d[0])|int(d[1])<<8|int(d[2])<<16) * time.Millisecond,
+		disposal: flags & disposalBit,
+		blend:    (flags & blendingBit) >> 1,
+	}
+
+	// The frame data is an optional ALPH chunk and a VP8 chunk, or a VP8L
+	// chunk. Unknown chunks are ignored.
+	var (
+		alpha       []byte
+		alphaStride int
+		m           image.Image
+	)
+	for m == nil {
+		chunkID, chunkLen, chunkData, err := frameReader.Next()
+		if err == io.EOF {
+			err = errInvalidFormat
+		}
+		if err != nil {
+			return nil, frameInfo{}, err
+		}
+		switch chunkID {
+		case fccALPH:
+			if alpha != nil {
+				return nil, frameInfo{}, errInvalidFormat
+			}
+			alpha, alphaStride, err = decodeAlphaChunk(chunkData, widthMinusOne, heightMinusOne, nil)
+		case fccVP8:
+			m, err = decodeVP8Chunk(chunkData, chunkLen, alpha, alphaStride, false, nil, limits)
+		case fccVP8L:
+			if alpha != nil {
+				return nil, frameInfo{}, errInvalidFormat
+			}
+			m, err = decodeVP8LChunk(chunkData, nil, limits)
+		}
+		if err != nil {
+			return nil, frameInfo{}, err
+		}
+	}
+	if b := m.Bounds(); b.Dx() != int(widthMinusOne)+1 || b.Dy() != int(heightMinusOne)+1 {
+		return nil, frameInfo{}, errInvalidFormat
+	}
+	translate(m, image.Pt(x, y))
+	return m, f, nil
+}
+
+// translate moves the bounds of m, an image returned by the VP8 or VP8L
+// decoders, by p.
+func translate(m image.Image, p image.Point) {
+	switch m := m.(type) {
+	case *image.YCbCr:
+		m.Rect = m.Rect.Add(p)
+	case *image.NYCbCrA:
+		m.Rect = m.Rect.Add(p)
+	case *image.NRGBA:
+		m.Rect = m.Rect.Add(p)
+	}
+}
+
+// DecodeAll reads a WEBP image from r and returns the sequential frames and
+// timing information. A WEBP image that is not animated is returned as a
+// single frame.
+func DecodeAll(r io.Reader) (*WEBP, error) {
+	w := &WEBP{}
+	m, _, err := decode(r, false, nil, w, nil)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		b := m.Bounds()
+		*w = WEBP{
+			Image:    []image.Image{m},
+			Duration: []time.Duration{0},
+			Disposal: []byte{DisposalNone},
+			Blend:    []byte{BlendAlpha},
+			Config: image.Config{
+				ColorModel: m.ColorModel(),
+				Width:      b.Dx(),
+				Height:     b.Dy(),
+			},
+		}
+	}
+	return w, nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// encodeChunks returns the chunks, after the RIFF header, of m encoded as a
+// WEBP image.
+func encodeChunks(t *testing.T, m image.Image, opts *Options) []byte {
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, m, opts); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()[12:]
+	if string(b[:4]) == "VP8X" {
+		b = b[18:]
+	}
+	return b
+}
+
+func appendChunk(b []byte, id string, data ...[]byte) []byte {
+	n := 0
+	for _, d := range data {
+		n += len(d)
+	}
+	b = append(b, id...)
+	b = append(b, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	for _, d := range data {
+		b = append(b, d...)
+	}
+	if n&1 != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func uint24(u int) []byte {
+	return []byte{byte(u), byte(u >> 8), byte(u >> 16)}
+}
+
+func TestDecodeAll(t *testing.T) {
+	const w, h = 40, 30
+	gradient := func(r image.Rectangle, a uint8) *image.NRGBA {
+		m := image.NewNRGBA(r.Sub(r.Min))
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				m.SetNRGBA(x, y, color.NRGBA{uint8(8 * x), uint8(8 * y), 0x40, a})
+			}
+		}
+		return m
+	}
+	frames := []struct {
+		rect     image.Rectangle
+		opts     *Options
+		alpha    uint8
+		duration time.Duration
+		flags    byte
+	}{
+		{image.Rect(0, 0, w, h), &Options{Lossless: true}, 0xff, 100 * time.Millisecond, 0x00},
+		{image.Rect(10, 4, 26, 20), nil, 0x80, 50 * time.Millisecond, 0x01},
+		{image.Rect(2, 6, 39, 29), nil, 0xff, 1200 * time.Millisecond, 0x02},
+	}
+	var chunks []byte
+	chunks = appendChunk(chunks, "VP8X", []byte{0x12, 0, 0, 0}, uint24(w-1), uint24(h-1))
+	chunks = appendChunk(chunks, "ANIM", []byte{0x10, 0x20, 0x30, 0xff, 3, 0})
+	for _, f := range frames {
+		chunks = appendChunk(chunks, "ANMF",
+			uint24(f.rect.Min.X/2), uint24(f.rect.Min.Y/2),
+			uint24(f.rect.Dx()-1), uint24(f.rect.Dy()-1),
+			uint24(int(f.duration/time.Millisecond)), []byte{f.flags},
+			encodeChunks(t, gradient(f.rect, f.alpha), f.opts),
+		)
+	}
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), chunks...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+
+	g, err := DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeAll: %v", err)
+	}
+	if len(g.Image) != len(frames) || len(g.Duration) != len(frames) ||
+		len(g.Disposal) != len(frames) || len(g.Blend) != len(frames) {
+		t.Fatalf("got %d images, %d durations, %d disposals and %d blends, want %d of each",
+			len(g.Image), len(g.Duration), len(g.Disposal), len(g.Blend), len(frames))
+	}
+	if g.Config.Width != w || g.Config.Height != h {
+		t.Errorf("Config: got %dx%d, want %dx%d", g.Config.Width, g.Config.Height, w, h)
+	}
+	if g.LoopCount != 3 {
+		t.Errorf("LoopCount: got %d, want 3", g.LoopCount)
+	}
+	if want := (color.NRGBA{0x30, 0x20, 0x10, 0xff}); g.BackgroundColor != want {
+		t.Errorf("BackgroundColor: got %v, want %v", g.BackgroundColor, want)
+	}
+	wantDisposal := []byte{DisposalNone, DisposalBackground, DisposalNone}
+	wantBlend := []byte{BlendAlpha, BlendAlpha, BlendNone}
+	for i, f := range frames {
+		m := g.Image[i]
+		if got := m.Bounds(); got != f.rect {
+			t.Errorf("frame #%d: bounds: got %v, want %v", i, got, f.rect)
+			continue
+		}
+		if g.Duration[i] != f.duration {
+			t.Errorf("frame #%d: Duration: got %v, want %v", i, g.Duration[i], f.duration)
+		}
+		if g.Disposal[i] != wantDisposal[i] {
+			t.Errorf("frame #%d: Disposal: got %d, want %d", i, g.Disposal[i], wantDisposal[i])
+		}
+		if g.Blend[i] != wantBlend[i] {
+			t.Errorf("frame #%d: Blend: got %d, want %d", i, g.Blend[i], wantBlend[i])
+		}
+		src := gradient(f.rect, f.alpha)
+		for y := f.rect.Min.Y; y < f.rect.Max.Y; y++ {
+			for x := f.rect.Min.X; x < f.rect.Max.X; x++ {
+				c := color.NRGBAModel.Convert(m.At(x, y)).(color.NRGBA)
+				s := src.NRGBAAt(x-f.rect.Min.X, y-f.rect.Min.Y)
+				if c.A != s.A || (f.opts != nil && f.opts.Lossless && c != s) {
+					t.Fatalf("frame #%d: pixel (%d, %d): got %v, want %v", i, x, y, c, s)
+				}
+			}
+		}
+	}
+
+	m, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got := m.Bounds(); got != frames[0].rect {
+		t.Errorf("Decode: bounds: got %v, want %v", got, frames[0].rect)
+	}
+	c, err := DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeConfig: %v", err)
+	}
+	if c.Width != w || c.Height != h {
+		t.Errorf("DecodeConfig: got %dx%d, want %dx%d", c.Width, c.Height, w, h)
+	}
+
+	// A frame that does not fit within the canvas is invalid.
+	bad := append([]byte(nil), data...)
+	bad[12+8+10+8+6+8] = 0xff
+	if _, err := DecodeAll(bytes.NewReader(bad)); err == nil {
+		t.Error("DecodeAll: frame outside the canvas: got nil error")
+	}
+}
+
+func TestDecodeAllStill(t *testing.T) {
+	src, err := ioutil.ReadFile("../testdata/yellow_rose.lossy-with-alpha.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := DecodeAll(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 1 || len(g.Duration) != 1 || len(g.Disposal) != 1 || len(g.Blend) != 1 {
+		t.Fatalf("got %d images, want 1", len(g.Image))
+	}
+	if _, ok := g.Image[0].(*image.NYCbCrA); !ok {
+		t.Errorf("got %T, want *image.NYCbCrA", g.Image[0])
+	}
+	if b := g.Image[0].Bounds(); g.Config.Width != b.Dx() || g.Config.Height != b.Dy() {
+		t.Errorf("Config: got %dx%d, want %dx%d", g.Config.Width, g.Config.Height, b.Dx(), b.Dy())
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"image"
 	"image/color"
 	"io"
+	"io/ioutil"
 
+	"golang.org/x/image/icc"
+	"golang.org/x/image/imagelimit"
 	"golang.org/x/image/riff"
 	"golang.org/x/image/vp8"
 	"golang.org/x/image/vp8l"
@@ -20,13 +23,26 @@ var errInvalidFormat = errors.New("webp: invalid format")
 
 var (
 	fccALPH = riff.FourCC{'A', 'L', 'P', 'H'}
+	fccANIM = riff.FourCC{'A', 'N', 'I', 'M'}
+	fccANMF = riff.FourCC{'A', 'N', 'M', 'F'}
+	fccEXIF = riff.FourCC{'E', 'X', 'I', 'F'}
+	fccICCP = riff.FourCC{'I', 'C', 'C', 'P'}
 	fccVP8  = riff.FourCC{'V', 'P', '8', ' '}
 	fccVP8L = riff.FourCC{'V', 'P', '8', 'L'}
 	fccVP8X = riff.FourCC{'V', 'P', '8', 'X'}
 	fccWEBP = riff.FourCC{'W', 'E', 'B', 'P'}
+	fccXMP  = riff.FourCC{'X', 'M', 'P', ' '}
 )
 
-func decode(r io.Reader, configOnly bool) (image.Image, image.Config, error) {
+// decode decodes a WEBP image. If all is non-nil, every frame of an animated
+// image is decoded into all, and the returned image is nil. Otherwise, only
+// the first frame is decoded.
+//
+// If meta is non-nil, the ICCP, EXIF and XMP chunks are read into it, which
+// means reading the chunks that follow the image data. If configOnly is also
+// true, only those chunks are read, and the returned image and config are
+// zero.
+func decode(r io.Reader, configOnly bool, opts *DecodeOptions, all *WEBP, meta *Metadata) (image.Image, image.Config, error) {
 	formType, riffReader, err := riff.NewReader(r)
 	if err != nil {
 		return nil, image.Config{}, err
@@ -38,14 +54,46 @@ func decode(r io.Reader, configOnly bool) (image.Image, image.Config, error) {
 	var (
 		alpha          []byte
 		alphaStride    int
-		wantAlpha      bool
+		extended       bool
+		animated       bool
+		iccProfile     []byte
 		widthMinusOne  uint32
 		heightMinusOne uint32
 		buf            [10]byte
+		// result is the decoded image, when meta is non-nil and the chunks
+		// after it are still to be read.
+		result   image.Image
+		haveData bool
 	)
+	metaOnly := configOnly && meta != nil
+	// intoYCbCr, intoA and intoNRGBA are the buffers of the image passed to
+	// DecodeInto, if any, for VP8, ALPH and VP8L chunks.
+	var (
+		intoYCbCr *image.YCbCr
+		intoA     []byte
+		intoNRGBA *image.NRGBA
+		limits    *imagelimit.Limits
+	)
+	if opts != nil {
+		limits = opts.Limits
+		switch m := opts.into.(type) {
+		case *image.YCbCr:
+			intoYCbCr = m
+		case *image.NYCbCrA:
+			intoYCbCr, intoA = &m.YCbCr, m.A
+		case *image.NRGBA:
+			intoNRGBA = m
+		}
+	}
 	for {
 		chunkID, chunkLen, chunkData, err := riffReader.Next()
 		if err == io.EOF {
+			if animated && all != nil && len(all.Image) > 0 {
+				return nil, image.Config{}, nil
+			}
+			if meta != nil && haveData {
+				return result, image.Config{}, nil
+			}
 			err = errInvalidFormat
 		}
 		if err != nil {
@@ -54,63 +102,138 @@ func decode(r io.Reader, configOnly bool) (image.Image, image.Config, error) {
 
 		switch chunkID {
 		case fccALPH:
-			if !wantAlpha {
+			// The VP8X chunk's alpha flag is only a hint: an ALPH chunk is
+			// used even if the flag is not set.
+			if !extended || animated || alpha != nil || haveData {
 				return nil, image.Config{}, errInvalidFormat
 			}
-			wantAlpha = false
-			// Read the Pre-processing | Filter | Compression byte.
-			if _, err := io.ReadFull(chunkData, buf[:1]); err != nil {
-				if err == io.EOF {
-					err = errInvalidFormat
+			if metaOnly {
+				break
+			}
+			alpha, alphaStride, err = decodeAlphaChunk(chunkData, widthMinusOne, heightMinusOne, intoA)
+			if err != nil {
+				return nil, image.Config{}, err
+			}
+
+		case fccANIM:
+			if !animated || chunkLen != 6 {
+				return nil, image.Config{}, errInvalidFormat
+			}
+			if _, err := io.ReadFull(chunkData, buf[:6]); err != nil {
+				return nil, image.Config{}, err
+			}
+			if all != nil {
+				all.BackgroundColor = color.NRGBA{buf[2], buf[1], buf[0], buf[3]}
+				all.LoopCount = int(buf[4]) | int(buf[5])<<8
+			}
+
+		case fccANMF:
+			if !animated {
+				return nil, image.Config{}, errInvalidFormat
+			}
+			if metaOnly || (all == nil && haveData) {
+				haveData = true
+				break
+			}
+			m, f, err := decodeFrameChunk(chunkLen, chunkData, widthMinusOne, heightMinusOne, limits)
+			if err != nil {
+				return nil, image.Config{}, err
+			}
+			haveData = true
+			if all == nil {
+				if meta == nil {
+					return m, image.Config{}, nil
 				}
+				result = m
+				break
+			}
+			all.Image = append(all.Image, m)
+			all.Duration = append(all.Duration, f.duration)
+			all.Disposal = append(all.Disposal, f.disposal)
+			all.Blend = append(all.Blend, f.blend)
+
+		case fccICCP:
+			convert := opts != nil && opts.ConvertToSRGB && !configOnly
+			if !convert && meta == nil {
+				break
+			}
+			profile, err := ioutil.ReadAll(chunkData)
+			if err != nil {
 				return nil, image.Config{}, err
 			}
-			alpha, alphaStride, err = readAlpha(chunkData, widthMinusOne, heightMinusOne, buf[0]&0x03)
+			if convert {
+				iccProfile = profile
+			}
+			if meta != nil {
+				meta.ICCProfile = profile
+			}
+
+		case fccEXIF, fccXMP:
+			if meta == nil {
+				break
+			}
+			data, err := ioutil.ReadAll(chunkData)
 			if err != nil {
 				return nil, image.Config{}, err
 			}
-			unfilterAlpha(alpha, alphaStride, (buf[0]>>2)&0x03)
+			if chunkID == fccEXIF {
+				meta.EXIF = data
+			} else {
+				meta.XMP = data
+			}
 
 		case fccVP8:
-			if wantAlpha || int32(chunkLen) < 0 {
+			if animated || haveData {
 				return nil, image.Config{}, errInvalidFormat
 			}
-			d := vp8.NewDecoder()
-			d.Init(chunkData, int(chunkLen))
-			fh, err := d.DecodeFrameHeader()
-			if err != nil {
-				return nil, image.Config{}, err
+			haveData = true
+			if metaOnly {
+				break
 			}
 			if configOnly {
+				fh, err := decodeVP8FrameHeader(chunkData, chunkLen)
+				if err != nil {
+					return nil, image.Config{}, err
+				}
 				return nil, image.Config{
 					ColorModel: color.YCbCrModel,
 					Width:      fh.Width,
 					Height:     fh.Height,
 				}, nil
 			}
-			m, err := d.DecodeFrame()
+			m, err := decodeVP8Chunk(chunkData, chunkLen, alpha, alphaStride, opts != nil && opts.Concurrent, intoYCbCr, limits)
 			if err != nil {
 				return nil, image.Config{}, err
 			}
-			if alpha != nil {
-				return &image.NYCbCrA{
-					YCbCr:   *m,
-					A:       alpha,
-					AStride: alphaStride,
-				}, image.Config{}, nil
+			if meta == nil {
+				return convertToSRGB(m, iccProfile)
+			}
+			if result, _, err = convertToSRGB(m, iccProfile); err != nil {
+				return nil, image.Config{}, err
 			}
-			return m, image.Config{}, nil
 
 		case fccVP8L:
-			if wantAlpha || alpha != nil {
+			if alpha != nil || animated || haveData {
 				return nil, image.Config{}, errInvalidFormat
 			}
+			haveData = true
+			if metaOnly {
+				break
+			}
 			if configOnly {
 				c, err := vp8l.DecodeConfig(chunkData)
 				return nil, c, err
 			}
-			m, err := vp8l.Decode(chunkData)
-			return m, image.Config{}, err
+			m, err := decodeVP8LChunk(chunkData, intoNRGBA, limits)
+			if err != nil {
+				return nil, image.Config{}, err
+			}
+			if meta == nil {
+				return convertToSRGB(m, iccProfile)
+			}
+			if result, _, err = convertToSRGB(m, iccProfile); err != nil {
+				return nil, image.Config{}, err
+			}
 
 		case fccVP8X:
 			if chunkLen != 10 {
@@ -126,35 +249,157 @@ func decode(r io.Reader, configOnly bool) (image.Image, image.Config, error) {
 				alphaBit        = 1 << 4
 				iccProfileBit   = 1 << 5
 			)
-			wantAlpha = (buf[0] & alphaBit) != 0
+			extended = true
+			animated = (buf[0] & animationBit) != 0
 			widthMinusOne = uint32(buf[4]) | uint32(buf[5])<<8 | uint32(buf[6])<<16
 			heightMinusOne = uint32(buf[7]) | uint32(buf[8])<<8 | uint32(buf[9])<<16
+			c := image.Config{
+				ColorModel: color.YCbCrModel,
+				Width:      int(widthMinusOne) + 1,
+				Height:     int(heightMinusOne) + 1,
+			}
+			if (buf[0] & alphaBit) != 0 {
+				c.ColorModel = color.NYCbCrAModel
+			}
+			if animated {
+				// The frames of an animation can be lossy or lossless, so
+				// there is no single color model for the whole image.
+				c.ColorModel = color.NRGBAModel
+			}
+			if metaOnly {
+				break
+			}
 			if configOnly {
-				if wantAlpha {
-					return nil, image.Config{
-						ColorModel: color.NYCbCrAModel,
-						Width:      int(widthMinusOne) + 1,
-						Height:     int(heightMinusOne) + 1,
-					}, nil
-				}
-				return nil, image.Config{
-					ColorModel: color.YCbCrModel,
-					Width:      int(widthMinusOne) + 1,
-					Height:     int(heightMinusOne) + 1,
-				}, nil
+				return nil, c, nil
+			}
+			if all != nil {
+				all.Config = c
+			}
+			// The VP8 and VP8L chunks are checked against the limits when
+			// they are decoded, but the alpha plane of an ALPH chunk, which
+			// may be present whatever the alpha flag says, and the canvas
+			// of an animation, are allocated from the canvas dimensions.
+			bits := 8
+			if animated {
+				bits = 32
+			}
+			if err := limits.Check(c.Width, c.Height, bits); err != nil {
+				return nil, image.Config{}, err
 			}
 		}
 	}
 }
 
-func readAlpha(chunkData io.Reader, widthMinusOne, heightMinusOne uint32, compression byte) (
+// decodeAlphaChunk decodes the alpha values of an ALPH chunk, for an image
+// with the given dimensions, into dst if it is large enough.
+func decodeAlphaChunk(chunkData io.Reader, widthMinusOne, heightMinusOne uint32, dst []byte) (
 	alpha []byte, alphaStride int, err error) {
 
+	// Read the Pre-processing | Filter | Compression byte.
+	var buf [1]byte
+	if _, err := io.ReadFull(chunkData, buf[:]); err != nil {
+		if err == io.EOF {
+			err = errInvalidFormat
+		}
+		return nil, 0, err
+	}
+	alpha, alphaStride, err = readAlpha(chunkData, widthMinusOne, heightMinusOne, buf[0]&0x03, dst)
+	if err != nil {
+		return nil, 0, err
+	}
+	unfilterAlpha(alpha, alphaStride, (buf[0]>>2)&0x03)
+	return alpha, alphaStride, nil
+}
+
+// decodeVP8FrameHeader decodes the frame header of a VP8 chunk.
+func decodeVP8FrameHeader(chunkData io.Reader, chunkLen uint32) (vp8.FrameHeader, error) {
+	if int32(chunkLen) < 0 {
+		return vp8.FrameHeader{}, errInvalidFormat
+	}
+	d := vp8.NewDecoder()
+	d.Init(chunkData, int(chunkLen))
+	return d.DecodeFrameHeader()
+}
+
+// decodeVP8Chunk decodes a VP8 chunk, combined with the alpha values of a
+// preceding ALPH chunk, if any. concurrent is whether to decode with two
+// goroutines. The frame is decoded into dst, if it is non-nil and can hold it.
+// Its dimensions are checked against limits, which may be nil.
+func decodeVP8Chunk(chunkData io.Reader, chunkLen uint32, alpha []byte, alphaStride int, concurrent bool, dst *image.YCbCr,
+	limits *imagelimit.Limits) (image.Image, error) {
+
+	if int32(chunkLen) < 0 {
+		return nil, errInvalidFormat
+	}
+	d := vp8.NewDecoder()
+	d.SetConcurrent(concurrent)
+	d.Init(chunkData, int(chunkLen))
+	fh, err := d.DecodeFrameHeader()
+	if err != nil {
+		return nil, err
+	}
+	// The frame is 4:2:0 Y'CbCr, with 12 bits per pixel, plus 8 for any
+	// alpha plane.
+	bits := 12
+	if alpha != nil {
+		bits += 8
+	}
+	if err := limits.Check(fh.Width, fh.Height, bits); err != nil {
+		return nil, err
+	}
+	m, err := d.DecodeFrameInto(dst)
+	if err != nil {
+		return nil, err
+	}
+	if alpha != nil {
+		// The alpha plane's dimensions are those of the VP8X chunk's canvas
+		// or the ANMF chunk's frame, which must match the VP8 frame's.
+		if b := m.Bounds(); alphaStride != b.Dx() || len(alpha) != b.Dx()*b.Dy() {
+			return nil, errInvalidFormat
+		}
+		return &image.NYCbCrA{
+			YCbCr:   *m,
+			A:       alpha,
+			AStride: alphaStride,
+		}, nil
+	}
+	return m, nil
+}
+
+// decodeVP8LChunk decodes a VP8L chunk into dst, if it is non-nil and can
+// hold the image. Its dimensions are checked against limits, which may be
+// nil.
+func decodeVP8LChunk(chunkData io.Reader, dst *image.NRGBA, limits *imagelimit.Limits) (*image.NRGBA, error) {
+	if limits != nil {
+		// The dimensions are in the 5 byte header, which is read ahead of
+		// the decoder and then given to it again.
+		var hdr [5]byte
+		if _, err := io.ReadFull(chunkData, hdr[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		c, err := vp8l.DecodeConfig(bytes.NewReader(hdr[:]))
+		if err != nil {
+			return nil, err
+		}
+		if err := limits.CheckConfig(c); err != nil {
+			return nil, err
+		}
+		chunkData = io.MultiReader(bytes.NewReader(hdr[:]), chunkData)
+	}
+	return vp8l.DecodeInto(dst, chunkData)
+}
+
+func readAlpha(chunkData io.Reader, widthMinusOne, heightMinusOne uint32, compression byte, dst []byte) (
+	alpha []byte, alphaStride int, err error) {
+
+	w := int(widthMinusOne) + 1
+	h := int(heightMinusOne) + 1
 	switch compression {
 	case 0:
-		w := int(widthMinusOne) + 1
-		h := int(heightMinusOne) + 1
-		alpha = make([]byte, w*h)
+		alpha = reuse(dst, w*h)
 		if _, err := io.ReadFull(chunkData, alpha); err != nil {
 			return nil, 0, err
 		}
@@ -186,15 +431,24 @@ func readAlpha(chunkData io.Reader, widthMinusOne, heightMinusOne uint32, compre
 		// The green values of the inner NRGBA image are the alpha values of the
 		// outer NYCbCrA image.
 		pix := alphaImage.(*image.NRGBA).Pix
-		alpha = make([]byte, len(pix)/4)
+		alpha = reuse(dst, len(pix)/4)
 		for i := range alpha {
 			alpha[i] = pix[4*i+1]
 		}
-		return alpha, int(widthMinusOne) + 1, nil
+		return alpha, w, nil
 	}
 	return nil, 0, errInvalidFormat
 }
 
+// reuse returns buf resliced to length n, if it has the capacity, or else a
+// newly allocated slice.
+func reuse(buf []byte, n int) []byte {
+	if cap(buf) >= n {
+		return buf[:n]
+	}
+	return make([]byte, n)
+}
+
 func unfilterAlpha(alpha []byte, alphaStride int, filter byte) {
 	if len(alpha) == 0 || alphaStride == 0 {
 		return
@@ -250,22 +504,150 @@ func unfilterAlpha(alpha []byte, alphaStride int, filter byte) {
 	}
 }
 
+// convertToSRGB converts m from the color space described by the ICC
+// profile to sRGB, returning an *image.NRGBA. It returns m unchanged if the
+// profile is nil.
+func convertToSRGB(m image.Image, profile []byte) (image.Image, image.Config, error) {
+	if profile == nil {
+		return m, image.Config{}, nil
+	}
+	p, err := icc.Parse(profile)
+	if err != nil {
+		return nil, image.Config{}, err
+	}
+	t, err := p.TransformToSRGB()
+	if err != nil {
+		return nil, image.Config{}, err
+	}
+	dst := toNRGBA(m)
+	t.ConvertNRGBA(dst)
+	return dst, image.Config{}, nil
+}
+
+// toNRGBA returns m as an *image.NRGBA, converting it if necessary. An
+// *image.NRGBA is returned as is.
+func toNRGBA(m image.Image) *image.NRGBA {
+	switch m := m.(type) {
+	case *image.NRGBA:
+		return m
+	case *image.NYCbCrA:
+		b := m.Bounds()
+		dst := image.NewNRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				yi, ci, ai := m.YOffset(x, y), m.COffset(x, y), m.AOffset(x, y)
+				r, g, bb := color.YCbCrToRGB(m.Y[yi], m.Cb[ci], m.Cr[ci])
+				i := dst.PixOffset(x, y)
+				dst.Pix[i+0], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = r, g, bb, m.A[ai]
+			}
+		}
+		return dst
+	case *image.YCbCr:
+		b := m.Bounds()
+		dst := image.NewNRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				yi, ci := m.YOffset(x, y), m.COffset(x, y)
+				r, g, bb := color.YCbCrToRGB(m.Y[yi], m.Cb[ci], m.Cr[ci])
+				i := dst.PixOffset(x, y)
+				dst.Pix[i+0], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = r, g, bb, 0xff
+			}
+		}
+		return dst
+	}
+	b := m.Bounds()
+	dst := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.Set(x, y, m.At(x, y))
+		}
+	}
+	return dst
+}
+
 // Decode reads a WEBP image from r and returns it as an image.Image.
 func Decode(r io.Reader) (image.Image, error) {
-	m, _, err := decode(r, false)
+	m, _, err := decode(r, false, nil, nil, nil)
 	if err != nil {
 		return nil, err
 	}
 	return m, err
 }
 
+// DecodeInto is like Decode but decodes into dst's pixel buffers, instead of
+// allocating new ones, if they can hold the decoded image, such as those of
+// an image returned by DecodeInto for an image of the same size and format.
+// This avoids allocating the pixels of each image when decoding a sequence
+// of images, such as the frames of a camera feed, into the same buffers. dst
+// may be nil.
+//
+// Lossy images reuse the Y, Cb and Cr planes of an *image.YCbCr or
+// *image.NYCbCrA, and the alpha plane of the latter, and lossless images
+// reuse the pixels of an *image.NRGBA. The returned image shares dst's
+// buffers, if they were used, but is otherwise new. If decoding fails, dst's
+// pixels may have been partially overwritten.
+func DecodeInto(dst image.Image, r io.Reader) (image.Image, error) {
+	m, _, err := decode(r, false, &DecodeOptions{into: dst}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DecodeOptions are optional parameters to DecodeWithOptions.
+type DecodeOptions struct {
+	// ConvertToSRGB is whether to convert the pixels of an image with an
+	// embedded ICC profile (an ICCP chunk) to sRGB, so that wide-gamut images
+	// display correctly in code that assumes sRGB. Such images are returned
+	// as an *image.NRGBA. Images without an ICC profile are unaffected.
+	//
+	// If the profile is not supported by the golang.org/x/image/icc package,
+	// DecodeWithOptions returns an error.
+	ConvertToSRGB bool
+	// Concurrent is whether to decode lossy (VP8) images with two goroutines,
+	// one applying the loop filter while the other reconstructs the pixels.
+	// The decoded image is the same either way.
+	Concurrent bool
+	// Limits, if non-nil, are the limits on the image to decode, which are
+	// checked before its pixels are allocated. DecodeWithOptions returns an
+	// *imagelimit.Error if the image exceeds them.
+	Limits *imagelimit.Limits
+
+	// into is the image passed to DecodeInto, whose buffers are reused.
+	into image.Image
+}
+
+// DecodeWithOptions is like Decode but with optional parameters. A nil opts
+// is equivalent to a zero DecodeOptions.
+func DecodeWithOptions(r io.Reader, opts *DecodeOptions) (image.Image, error) {
+	m, _, err := decode(r, false, opts, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DecodeConfig returns the color model and dimensions of a WEBP image without
 // decoding the entire image.
 func DecodeConfig(r io.Reader) (image.Config, error) {
-	_, c, err := decode(r, true)
+	_, c, err := decode(r, true, nil, nil, nil)
 	return c, err
 }
 
+// DecodeReaderAt is like DecodeWithOptions but reads the size bytes of a WEBP
+// image from r, such as an *os.File or a blob store supporting range reads.
+// Chunks that are not needed, such as EXIF and XMP metadata, are skipped
+// without being read.
+func DecodeReaderAt(r io.ReaderAt, size int64, opts *DecodeOptions) (image.Image, error) {
+	return DecodeWithOptions(io.NewSectionReader(r, 0, size), opts)
+}
+
+// DecodeConfigReaderAt is like DecodeConfig but reads the size bytes of a
+// WEBP image from r.
+func DecodeConfigReaderAt(r io.ReaderAt, size int64) (image.Config, error) {
+	return DecodeConfig(io.NewSectionReader(r, 0, size))
+}
+
 func init() {
 	image.RegisterFormat("webp", "RIFF????WEBPVP8", Decode, DecodeConfig)
 }
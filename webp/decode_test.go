@@ -6,13 +6,20 @@ package webp
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"image"
+	"image/color"
 	"image/png"
+	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+
+	"golang.org/x/image/icc"
+	"golang.org/x/image/imagelimit"
 )
 
 // hex is like fmt.Sprintf("% x", x) but also inserts dots every 16 bytes, to
@@ -169,6 +176,113 @@ func TestDecodeVP8XAlpha(t *testing.T) {
 	}
 }
 
+func TestDecodeConcurrent(t *testing.T) {
+	testCases := []string{
+		"blue-purple-pink.lossy",
+		"blue-purple-pink-large.no-filter.lossy",
+		"blue-purple-pink-large.simple-filter.lossy",
+		"blue-purple-pink-large.normal-filter.lossy",
+		"video-001.lossy",
+		"yellow_rose.lossy",
+		"yellow_rose.lossy-with-alpha",
+	}
+
+	for _, tc := range testCases {
+		data, err := ioutil.ReadFile("../testdata/" + tc + ".webp")
+		if err != nil {
+			t.Errorf("%s: %v", tc, err)
+			continue
+		}
+		want, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tc, err)
+			continue
+		}
+		got, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Concurrent: true})
+		if err != nil {
+			t.Errorf("%s: DecodeWithOptions: %v", tc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: concurrent and sequential decoding differ", tc)
+		}
+	}
+}
+
+// pixelBuffers returns the buffers that hold m's pixels.
+func pixelBuffers(m image.Image) [][]byte {
+	switch m := m.(type) {
+	case *image.YCbCr:
+		return [][]byte{m.Y, m.Cb, m.Cr}
+	case *image.NYCbCrA:
+		return [][]byte{m.Y, m.Cb, m.Cr, m.A}
+	case *image.NRGBA:
+		return [][]byte{m.Pix}
+	}
+	return nil
+}
+
+func TestDecodeInto(t *testing.T) {
+	testCases := []string{
+		"blue-purple-pink.lossy",
+		"video-001.lossy",
+		"yellow_rose.lossy-with-alpha",
+		"blue-purple-pink.lossless",
+		"gopher-doc.1bpp.lossless",
+		"gopher-doc.8bpp.lossless",
+		"tux.lossless",
+	}
+
+	for _, tc := range testCases {
+		data, err := ioutil.ReadFile("../testdata/" + tc + ".webp")
+		if err != nil {
+			t.Errorf("%s: %v", tc, err)
+			continue
+		}
+		want, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tc, err)
+			continue
+		}
+		// Decoding into nil, or into an image of another size, allocates.
+		var dst image.Image
+		for _, m := range []image.Image{nil, image.NewNRGBA(image.Rect(0, 0, 1, 1)), image.NewYCbCr(image.Rect(0, 0, 1, 1), image.YCbCrSubsampleRatio420)} {
+			dst, err = DecodeInto(m, bytes.NewReader(data))
+			if err != nil {
+				t.Errorf("%s: DecodeInto(%T): %v", tc, m, err)
+				continue
+			}
+			if !reflect.DeepEqual(dst, want) {
+				t.Errorf("%s: DecodeInto(%T) and Decode differ", tc, m)
+			}
+		}
+		if dst == nil {
+			continue
+		}
+		// Decoding into a previously decoded image, whose pixels are
+		// overwritten, reuses its buffers.
+		bufs := pixelBuffers(dst)
+		for _, b := range bufs {
+			for i := range b {
+				b[i] = 0x55
+			}
+		}
+		got, err := DecodeInto(dst, bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: DecodeInto(previous): %v", tc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: DecodeInto(previous) and Decode differ", tc)
+		}
+		for i, b := range pixelBuffers(got) {
+			if &b[0] != &bufs[i][0] {
+				t.Errorf("%s: DecodeInto(previous): buffer %d was not reused", tc, i)
+			}
+		}
+	}
+}
+
 func TestDecodeVP8L(t *testing.T) {
 	testCases := []string{
 		"blue-purple-pink",
@@ -271,6 +385,319 @@ func TestDecodePartitionTooLarge(t *testing.T) {
 	}
 }
 
+func TestDecodeLimits(t *testing.T) {
+	testCases := []struct {
+		filename string
+		// bits is the number of bits per pixel that the decoded image takes.
+		bits int
+	}{
+		{"blue-purple-pink.lossy.webp", 12},
+		{"blue-purple-pink.lossless.webp", 32},
+		{"yellow_rose.lossy-with-alpha.webp", 20},
+	}
+	for _, tc := range testCases {
+		data, err := ioutil.ReadFile("../testdata/" + tc.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: Decode: %v", tc.filename, err)
+		}
+		b := want.Bounds()
+		n := int64(b.Dx() * b.Dy())
+		mem := int64((b.Dx()*tc.bits + 7) / 8 * b.Dy())
+
+		got, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{
+			Limits: &imagelimit.Limits{MaxPixels: n, MaxMemory: mem},
+		})
+		if err != nil {
+			t.Errorf("%s: within limits: %v", tc.filename, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: within limits: decoded image differs", tc.filename)
+		}
+
+		for _, l := range []imagelimit.Limits{
+			{MaxPixels: n - 1},
+			{MaxMemory: mem - 1},
+			{MaxWidth: b.Dx() - 1},
+			{MaxHeight: b.Dy() - 1},
+		} {
+			_, err := DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{Limits: &l})
+			if _, ok := err.(*imagelimit.Error); !ok {
+				t.Errorf("%s: %+v: got %v, want an *imagelimit.Error", tc.filename, l, err)
+			}
+		}
+	}
+}
+
+// withICCProfile returns a copy of the VP8X based WEBP image src with an ICCP
+// chunk holding profile inserted after the VP8X chunk.
+func withICCProfile(src, profile []byte) []byte {
+	const vp8xEnd = 12 + 8 + 10
+	dst := append([]byte(nil), src[:vp8xEnd]...)
+	dst[20] |= 1 << 5 // The ICC profile bit.
+	dst = append(dst, "ICCP\x00\x00\x00\x00"...)
+	binary.LittleEndian.PutUint32(dst[len(dst)-4:], uint32(len(profile)))
+	dst = append(dst, profile...)
+	if len(profile)&1 != 0 {
+		dst = append(dst, 0)
+	}
+	dst = append(dst, src[vp8xEnd:]...)
+	binary.LittleEndian.PutUint32(dst[4:8], uint32(len(dst)-8))
+	return dst
+}
+
+func TestDecodeConvertToSRGB(t *testing.T) {
+	profile, err := ioutil.ReadFile("../testdata/linear-rgb.icc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := icc.Parse(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xform, err := p.TransformToSRGB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []string{
+		"yellow_rose.lossy-with-alpha",
+		"yellow_rose.lossless",
+	} {
+		src, err := ioutil.ReadFile("../testdata/" + tc + ".webp")
+		if err != nil {
+			t.Errorf("%s: ReadFile: %v", tc, err)
+			continue
+		}
+		if string(src[12:16]) != "VP8X" {
+			src = toVP8X(t, src)
+		}
+		want, err := Decode(bytes.NewReader(src))
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tc, err)
+			continue
+		}
+		wantNRGBA := toNRGBA(want)
+		if wantNRGBA == want {
+			wantNRGBA = image.NewNRGBA(want.Bounds())
+			copy(wantNRGBA.Pix, want.(*image.NRGBA).Pix)
+		}
+		xform.ConvertNRGBA(wantNRGBA)
+
+		data := withICCProfile(src, profile)
+		// Without the option, the profile is ignored.
+		got, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: Decode with profile: %v", tc, err)
+			continue
+		}
+		if fmt.Sprintf("%T", got) != fmt.Sprintf("%T", want) {
+			t.Errorf("%s: Decode with profile: got %T, want %T", tc, got, want)
+		}
+
+		got, err = DecodeWithOptions(bytes.NewReader(data), &DecodeOptions{ConvertToSRGB: true})
+		if err != nil {
+			t.Errorf("%s: DecodeWithOptions: %v", tc, err)
+			continue
+		}
+		gotNRGBA, ok := got.(*image.NRGBA)
+		if !ok {
+			t.Errorf("%s: DecodeWithOptions: got %T, want *image.NRGBA", tc, got)
+			continue
+		}
+		if !bytes.Equal(gotNRGBA.Pix, wantNRGBA.Pix) {
+			t.Errorf("%s: DecodeWithOptions: pixels differ", tc)
+		}
+	}
+}
+
+// toVP8X wraps the single VP8L chunk of the simple format WEBP image src in
+// the extended format.
+func toVP8X(t *testing.T, src []byte) []byte {
+	c, err := DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, h := c.Width-1, c.Height-1
+	dst := append([]byte(nil), src[:12]...)
+	dst = append(dst, "VP8X\x0a\x00\x00\x00"...)
+	dst = append(dst, 0, 0, 0, 0,
+		uint8(w), uint8(w>>8), uint8(w>>16),
+		uint8(h), uint8(h>>8), uint8(h>>16))
+	dst = append(dst, src[12:]...)
+	binary.LittleEndian.PutUint32(dst[4:8], uint32(len(dst)-8))
+	return dst
+}
+
+// countingReaderAt counts the bytes read from an io.ReaderAt.
+type countingReaderAt struct {
+	r io.ReaderAt
+	n int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.n += int64(n)
+	return n, err
+}
+
+func TestDecodeReaderAtSkipsChunks(t *testing.T) {
+	src, err := ioutil.ReadFile("../testdata/yellow_rose.lossy-with-alpha.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Decode(bytes.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Insert a large XMP chunk, which the decoder does not need, after the
+	// VP8X chunk.
+	const vp8xEnd, xmpLen = 12 + 8 + 10, 1 << 20
+	data := append([]byte(nil), src[:vp8xEnd]...)
+	data = append(data, "XMP \x00\x00\x10\x00"...)
+	data = append(data, make([]byte, xmpLen)...)
+	data = append(data, src[vp8xEnd:]...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+
+	c := &countingReaderAt{r: bytes.NewReader(data)}
+	got, err := DecodeReaderAt(c, int64(len(data)), nil)
+	if err != nil {
+		t.Fatalf("DecodeReaderAt: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("DecodeReaderAt: image differs from Decode")
+	}
+	if c.n >= xmpLen {
+		t.Fatalf("DecodeReaderAt: read %d bytes, want fewer than %d", c.n, xmpLen)
+	}
+}
+
+// filterAlpha applies the ALPH chunk's filtering method to the alpha values
+// of a w pixels wide image, the inverse of unfilterAlpha.
+func filterAlpha(alpha []byte, w int, filter byte) []byte {
+	dst := make([]byte, len(alpha))
+	for i := range alpha {
+		x, y := i%w, i/w
+		pred := 0
+		switch {
+		case filter == 0 || (x == 0 && y == 0):
+		case y == 0:
+			pred = int(alpha[i-1])
+		case x == 0:
+			pred = int(alpha[i-w])
+		case filter == 1:
+			pred = int(alpha[i-1])
+		case filter == 2:
+			pred = int(alpha[i-w])
+		case filter == 3:
+			pred = int(alpha[i-1]) + int(alpha[i-w]) - int(alpha[i-w-1])
+			if pred < 0 {
+				pred = 0
+			} else if pred > 255 {
+				pred = 255
+			}
+		}
+		dst[i] = alpha[i] - uint8(pred)
+	}
+	return dst
+}
+
+func TestDecodeAlphaFilters(t *testing.T) {
+	const w, h = 29, 17
+	rgb := image.NewRGBA(image.Rect(0, 0, w, h))
+	alpha := make([]byte, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			rgb.SetRGBA(x, y, color.RGBA{uint8(9 * x), uint8(15 * y), 0x80, 0xff})
+			alpha[y*w+x] = uint8(x*y + 4*x)
+		}
+	}
+	vp8Chunk := encodeChunks(t, rgb, nil)
+
+	for compression := byte(0); compression < 2; compression++ {
+		for filter := byte(0); filter < 4; filter++ {
+			filtered := filterAlpha(alpha, w, filter)
+			if compression == 1 {
+				var err error
+				if filtered, err = encodeAlpha(filtered, w, h); err != nil {
+					t.Fatal(err)
+				}
+				filtered = filtered[1:]
+			}
+			var chunks []byte
+			chunks = appendChunk(chunks, "VP8X", []byte{0x10, 0, 0, 0}, uint24(w-1), uint24(h-1))
+			chunks = appendChunk(chunks, "ALPH", []byte{filter<<2 | compression}, filtered)
+			chunks = append(chunks, vp8Chunk...)
+			data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), chunks...)
+			binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+
+			m, err := Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Errorf("compression %d, filter %d: %v", compression, filter, err)
+				continue
+			}
+			a, ok := m.(*image.NYCbCrA)
+			if !ok {
+				t.Errorf("compression %d, filter %d: got %T, want *image.NYCbCrA", compression, filter, m)
+				continue
+			}
+			if !bytes.Equal(a.A, alpha) {
+				t.Errorf("compression %d, filter %d: alpha values differ", compression, filter)
+			}
+		}
+	}
+}
+
+// TestDecodeVP8XAlphaFlag tests that the VP8X chunk's alpha flag is only a
+// hint, as for libwebp.
+func TestDecodeVP8XAlphaFlag(t *testing.T) {
+	const w, h = 16, 8
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range m.Pix {
+		m.Pix[i] = uint8(i)
+	}
+	opaque := image.NewRGBA(m.Rect)
+	for i := range opaque.Pix {
+		opaque.Pix[i] = 0xff
+	}
+	testCases := []struct {
+		desc   string
+		flags  byte
+		chunks []byte
+		want   string
+	}{
+		{"VP8L with alpha", 0x10, encodeChunks(t, m, &Options{Lossless: true}), "*image.NRGBA"},
+		{"VP8 without ALPH", 0x10, encodeChunks(t, opaque, nil), "*image.YCbCr"},
+		{"ALPH without alpha flag", 0x00, encodeChunks(t, m, nil), "*image.NYCbCrA"},
+	}
+	for _, tc := range testCases {
+		chunks := appendChunk(nil, "VP8X", []byte{tc.flags, 0, 0, 0}, uint24(w-1), uint24(h-1))
+		data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), append(chunks, tc.chunks...)...)
+		binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+		got, err := Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.desc, err)
+			continue
+		}
+		if s := fmt.Sprintf("%T", got); s != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.desc, s, tc.want)
+		}
+	}
+
+	// The alpha plane must have the same dimensions as the VP8 frame.
+	chunks := appendChunk(nil, "VP8X", []byte{0x10, 0, 0, 0}, uint24(w), uint24(h-1))
+	chunks = appendChunk(chunks, "ALPH", []byte{0}, make([]byte, (w+1)*h))
+	chunks = append(chunks, encodeChunks(t, opaque, nil)...)
+	data := append([]byte("RIFF\x00\x00\x00\x00WEBP"), chunks...)
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	if _, err := Decode(bytes.NewReader(data)); err == nil {
+		t.Error("mismatched alpha dimensions: got nil error")
+	}
+}
+
 func benchmarkDecode(b *testing.B, filename string) {
 	data, err := ioutil.ReadFile("../testdata/blue-purple-pink-large." + filename + ".webp")
 	if err != nil {
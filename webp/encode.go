@@ -0,0 +1,139 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io"
+
+	"golang.org/x/image/riff"
+	"golang.org/x/image/vp8"
+	"golang.org/x/image/vp8l"
+)
+
+// DefaultQuality is the default quality encoding parameter.
+const DefaultQuality = vp8.DefaultQuality
+
+// Options are the encoding parameters.
+type Options struct {
+	// Lossless is whether to encode the image with the lossless VP8L format
+	// instead of the lossy VP8 format.
+	Lossless bool
+	// Quality ranges from 1 to 100 inclusive, higher is better. It only
+	// applies to lossy encoding. Zero means DefaultQuality.
+	Quality int
+}
+
+// writeChunk writes a RIFF chunk, padded to an even length.
+func writeChunk(w *bytes.Buffer, id riff.FourCC, data []byte) {
+	var b [8]byte
+	copy(b[:4], id[:])
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(data)))
+	w.Write(b[:])
+	w.Write(data)
+	if len(data)&1 != 0 {
+		w.WriteByte(0)
+	}
+}
+
+// alphaValues returns the alpha values of m's pixels, or nil if m is opaque.
+func alphaValues(m image.Image) []byte {
+	if o, ok := m.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return nil
+	}
+	b := m.Bounds()
+	alpha, opaque := make([]byte, 0, b.Dx()*b.Dy()), true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			_, _, _, a := m.At(x, y).RGBA()
+			alpha = append(alpha, uint8(a>>8))
+			opaque = opaque && a == 0xffff
+		}
+	}
+	if opaque {
+		return nil
+	}
+	return alpha
+}
+
+// encodeAlpha returns the contents of an ALPH chunk for the alpha values of
+// an image w pixels wide. The values are compressed with VP8L, as the green
+// values of an image, without the VP8L header.
+func encodeAlpha(alpha []byte, w, h int) ([]byte, error) {
+	m := &image.Gray{
+		Pix:    alpha,
+		Stride: w,
+		Rect:   image.Rect(0, 0, w, h),
+	}
+	buf := &bytes.Buffer{}
+	// The Pre-processing | Filter | Compression byte: no pre-processing, no
+	// filtering and VP8L compression.
+	buf.WriteByte(0x01)
+	if err := vp8l.Encode(buf, m); err != nil {
+		return nil, err
+	}
+	const vp8lHeaderLen = 5
+	b := buf.Bytes()
+	return append(b[:1], b[1+vp8lHeaderLen:]...), nil
+}
+
+// Encode writes the image m to w in WEBP format. A nil opts means lossy
+// encoding with DefaultQuality.
+func Encode(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+
+	chunks := &bytes.Buffer{}
+	if o.Lossless {
+		data := &bytes.Buffer{}
+		if err := vp8l.Encode(data, m); err != nil {
+			return err
+		}
+		writeChunk(chunks, fccVP8L, data.Bytes())
+	} else {
+		data := &bytes.Buffer{}
+		if err := vp8.Encode(data, m, &vp8.EncodeOptions{Quality: o.Quality}); err != nil {
+			return err
+		}
+		// Images with transparency use the extended format, with the alpha
+		// values in an ALPH chunk.
+		if alpha := alphaValues(m); alpha != nil {
+			b := m.Bounds()
+			alphData, err := encodeAlpha(alpha, b.Dx(), b.Dy())
+			if err != nil {
+				return err
+			}
+			const alphaBit = 1 << 4
+			var vp8x [10]byte
+			vp8x[0] = alphaBit
+			putUint24(vp8x[4:], uint32(b.Dx()-1))
+			putUint24(vp8x[7:], uint32(b.Dy()-1))
+			writeChunk(chunks, fccVP8X, vp8x[:])
+			writeChunk(chunks, fccALPH, alphData)
+		}
+		writeChunk(chunks, fccVP8, data.Bytes())
+	}
+
+	var header [12]byte
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(4+chunks.Len()))
+	copy(header[8:12], fccWEBP[:])
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(chunks.Bytes())
+	return err
+}
+
+// putUint24 writes u as a 24-bit little-endian value.
+func putUint24(b []byte, u uint32) {
+	b[0] = uint8(u)
+	b[1] = uint8(u >> 8)
+	b[2] = uint8(u >> 16)
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"testing"
+
+	"golang.org/x/image/imgdiff"
+)
+
+func decodePNG(t *testing.T, filename string) image.Image {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestEncodeLossless(t *testing.T) {
+	testCases := []string{
+		"blue-purple-pink",
+		"gopher-doc.1bpp",
+		"gopher-doc.2bpp",
+		"gopher-doc.4bpp",
+		"gopher-doc.8bpp",
+		"tux",
+	}
+	for _, tc := range testCases {
+		m0 := decodePNG(t, "../testdata/"+tc+".png")
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, m0, &Options{Lossless: true}); err != nil {
+			t.Errorf("%s: Encode: %v", tc, err)
+			continue
+		}
+		m1, err := Decode(buf)
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tc, err)
+			continue
+		}
+		b := m0.Bounds()
+		if got := m1.Bounds(); got != b.Sub(b.Min) {
+			t.Errorf("%s: bounds: got %v, want %v", tc, got, b)
+			continue
+		}
+	loop:
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c0 := color.NRGBAModel.Convert(m0.At(x, y))
+				c1 := color.NRGBAModel.Convert(m1.At(x-b.Min.X, y-b.Min.Y))
+				if c0 != c1 {
+					t.Errorf("%s: pixel at (%d, %d): got %v, want %v", tc, x, y, c1, c0)
+					break loop
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeLossy(t *testing.T) {
+	m0 := decodePNG(t, "../testdata/video-001.png")
+	prevLen, prevPSNR := 0, 0.0
+	for _, quality := range []int{10, DefaultQuality, 100} {
+		buf := &bytes.Buffer{}
+		if err := Encode(buf, m0, &Options{Quality: quality}); err != nil {
+			t.Fatalf("quality=%d: Encode: %v", quality, err)
+		}
+		n := buf.Len()
+		m1, err := Decode(buf)
+		if err != nil {
+			t.Fatalf("quality=%d: Decode: %v", quality, err)
+		}
+		if _, ok := m1.(*image.YCbCr); !ok {
+			t.Fatalf("quality=%d: decoded image is a %T, want *image.YCbCr", quality, m1)
+		}
+		if got, want := m1.Bounds(), m0.Bounds(); got != want {
+			t.Fatalf("quality=%d: bounds: got %v, want %v", quality, got, want)
+		}
+		psnr, err := imgdiff.PSNR(m0, m1)
+		if err != nil {
+			t.Fatalf("quality=%d: PSNR: %v", quality, err)
+		}
+		if quality >= DefaultQuality {
+			if psnr < 30 {
+				t.Errorf("quality=%d: PSNR: got %.2f dB, want >= 30 dB", quality, psnr)
+			}
+			if ssim, _ := imgdiff.SSIM(m0, m1); ssim < 0.95 {
+				t.Errorf("quality=%d: SSIM: got %.4f, want >= 0.95", quality, ssim)
+			}
+		}
+		if prevLen != 0 && (n <= prevLen || psnr <= prevPSNR) {
+			t.Errorf("quality=%d: got %d bytes and PSNR %.2f dB, want more bytes and a higher "+
+				"PSNR than a lower quality's %d bytes and PSNR %.2f dB",
+				quality, n, psnr, prevLen, prevPSNR)
+		}
+		prevLen, prevPSNR = n, psnr
+	}
+}
+
+func TestEncodeLossyWithAlpha(t *testing.T) {
+	m0 := image.NewNRGBA(image.Rect(0, 0, 37, 21))
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 37; x++ {
+			m0.SetNRGBA(x, y, color.NRGBA{uint8(7 * x), uint8(11 * y), 0x80, uint8(x * y)})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := Encode(buf, m0, nil); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	m1, err := Decode(buf)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	a1, ok := m1.(*image.NYCbCrA)
+	if !ok {
+		t.Fatalf("decoded image is a %T, want *image.NYCbCrA", m1)
+	}
+	for y := 0; y < 21; y++ {
+		for x := 0; x < 37; x++ {
+			if got, want := a1.A[a1.AOffset(x, y)], m0.NRGBAAt(x, y).A; got != want {
+				t.Fatalf("alpha at (%d, %d): got %d, want %d", x, y, got, want)
+			}
+		}
+	}
+}
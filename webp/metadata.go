@@ -0,0 +1,54 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webp
+
+import (
+	"image"
+	"io"
+)
+
+// Metadata holds the metadata chunks of a WEBP image, which do not affect
+// the decoding of its pixels: its ICC color profile, EXIF data and XMP
+// data. The payloads are returned unchanged.
+//
+// Nil fields correspond to absent chunks.
+type Metadata struct {
+	// ICCProfile is the ICCP chunk's embedded ICC color profile, such as one
+	// parsed by the golang.org/x/image/icc package.
+	ICCProfile []byte
+	// EXIF is the EXIF chunk's data, which starts with a TIFF header.
+	EXIF []byte
+	// XMP is the XMP chunk's data, an XML document.
+	XMP []byte
+}
+
+// DecodeMetadata reads the metadata chunks of the WEBP image in r, without
+// decoding its pixels. It returns a nil *Metadata if the image has none of
+// them.
+func DecodeMetadata(r io.Reader) (*Metadata, error) {
+	meta := &Metadata{}
+	if _, _, err := decode(r, true, nil, nil, meta); err != nil {
+		return nil, err
+	}
+	if meta.ICCProfile == nil && meta.EXIF == nil && meta.XMP == nil {
+		return nil, nil
+	}
+	return meta, nil
+}
+
+// DecodeWithMetadata is like Decode but also returns the image's metadata
+// chunks, as DecodeMetadata does, reading r once. The metadata chunks usually
+// follow the image data, so all of r is read.
+func DecodeWithMetadata(r io.Reader) (image.Image, *Metadata, error) {
+	meta := &Metadata{}
+	m, _, err := decode(r, false, nil, nil, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta.ICCProfile == nil && meta.EXIF == nil && meta.XMP == nil {
+		meta = nil
+	}
+	return m, meta, nil
+}
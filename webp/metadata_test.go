@@ -0,0 +1,78 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package webp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeMetadata(t *testing.T) {
+	profile, err := ioutil.ReadFile("../testdata/linear-rgb.icc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Metadata{
+		ICCProfile: profile,
+		EXIF:       []byte("II*\x00\x08\x00\x00\x00\x00\x00\x00\x00\x00"),
+		XMP:        []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"/>`),
+	}
+	for _, tc := range []string{"yellow_rose.lossy-with-alpha", "yellow_rose.lossless", "blue-purple-pink.lossy"} {
+		src, err := ioutil.ReadFile("../testdata/" + tc + ".webp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantImage, err := Decode(bytes.NewReader(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(src[12:16]) != "VP8X" {
+			src = toVP8X(t, src)
+		}
+
+		// The EXIF and XMP chunks follow the image data.
+		data := withICCProfile(src, want.ICCProfile)
+		data[20] |= 1<<3 | 1<<2 // The EXIF and XMP bits.
+		data = append(data, "EXIF\x00\x00\x00\x00"...)
+		binary.LittleEndian.PutUint32(data[len(data)-4:], uint32(len(want.EXIF)))
+		data = append(data, want.EXIF...)
+		data = append(data, 0)
+		data = append(data, "XMP \x00\x00\x00\x00"...)
+		binary.LittleEndian.PutUint32(data[len(data)-4:], uint32(len(want.XMP)))
+		data = append(data, want.XMP...)
+		data = append(data, 0)
+		binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+
+		got, err := DecodeMetadata(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: DecodeMetadata: %v", tc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: DecodeMetadata: got %+v, want %+v", tc, got, want)
+		}
+
+		m, got, err := DecodeWithMetadata(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("%s: DecodeWithMetadata: %v", tc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: DecodeWithMetadata: got %+v, want %+v", tc, got, want)
+		}
+		// The pixels are not converted to sRGB.
+		if !reflect.DeepEqual(m, wantImage) {
+			t.Errorf("%s: DecodeWithMetadata: image differs from Decode", tc)
+		}
+
+		// Images without metadata chunks have nil metadata.
+		if got, err := DecodeMetadata(bytes.NewReader(src)); err != nil || got != nil {
+			t.Errorf("%s: DecodeMetadata without metadata: got %v, %v, want nil, nil", tc, got, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xbm implements a decoder and encoder for X BitMap (XBM) images.
+//
+// An XBM image is a fragment of C source code: #define lines that give the
+// image's width and height, and an array of bytes that holds its rows of
+// bits, least significant bit first, with each row padded to a whole byte.
+// A 1 bit is the foreground, which is black, and a 0 bit is the background,
+// which is white. The older X10 variant, whose array is of 16-bit shorts, is
+// also decoded.
+package xbm // import "golang.org/x/image/xbm"
+
+import (
+	"bufio"
+	"image"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/bitmap"
+)
+
+// A FormatError reports that the input is not a valid XBM image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "xbm: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "xbm: unsupported feature: " + string(e)
+}
+
+// maxPixels is the largest number of pixels, width × height, of an image
+// that Decode accepts.
+const maxPixels = 1 << 32
+
+// header is the decoded header of an XBM image.
+type header struct {
+	width, height int
+	// short is whether the array is of 16-bit values, as in X10 bitmaps.
+	short bool
+}
+
+// readHeader reads the #define lines of an XBM image, up to and including
+// the opening brace of its array. Lines other than #define lines, such as
+// comments, are skipped.
+func readHeader(r *bufio.Reader) (*header, error) {
+	h := &header{width: -1, height: -1}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasSuffix(line, "{") {
+			decl := strings.Fields(line[:len(line)-1])
+			if !isArrayDecl(decl) {
+				return nil, FormatError("bad array declaration")
+			}
+			for _, f := range decl {
+				if f == "short" {
+					h.short = true
+				}
+			}
+			break
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "#define" {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, FormatError("bad #define line")
+		}
+		var v *int
+		switch {
+		case strings.HasSuffix(fields[1], "width"):
+			v = &h.width
+		case strings.HasSuffix(fields[1], "height"):
+			v = &h.height
+		default:
+			// The hot spot, given by the x_hot and y_hot values, is only
+			// of use to cursors.
+			continue
+		}
+		n, err := strconv.Atoi(fields[2])
+		if err != nil || n < 0 || n > 1<<24 {
+			return nil, FormatError("bad dimension")
+		}
+		*v = n
+	}
+	if h.width < 0 || h.height < 0 {
+		return nil, FormatError("missing width or height")
+	}
+	if int64(h.width)*int64(h.height) > maxPixels {
+		return nil, UnsupportedError("image too large")
+	}
+	return h, nil
+}
+
+// readLine reads a line, up to and including its '\n', or up to and
+// including a '{', whichever comes first. The array's values may follow its
+// opening brace on the same line.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+		line = append(line, c)
+		if c == '\n' || c == '{' {
+			return string(line), nil
+		}
+	}
+}
+
+// isArrayDecl returns whether fields are those of a C declaration of an
+// array of chars or shorts that is followed by an initializer, such as
+// "static unsigned char foo_bits[] =".
+func isArrayDecl(fields []string) bool {
+	n := len(fields)
+	if n < 3 || fields[n-1] != "=" || !strings.HasSuffix(fields[n-2], "[]") {
+		return false
+	}
+	for _, f := range fields[:n-2] {
+		switch f {
+		case "static", "const", "unsigned", "signed", "char", "short":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// readValue reads the next value of the array, skipping the whitespace and
+// comma that separate it from the previous value.
+func readValue(r *bufio.Reader) (int, error) {
+	var buf []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				break
+			}
+			return 0, err
+		}
+		if c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			if len(buf) > 0 {
+				break
+			}
+			continue
+		}
+		if c == '}' {
+			if len(buf) > 0 {
+				r.UnreadByte()
+				break
+			}
+			return 0, FormatError("too few values")
+		}
+		buf = append(buf, c)
+	}
+	v, err := strconv.ParseUint(string(buf), 0, 16)
+	if err != nil {
+		return 0, FormatError("bad value")
+	}
+	return int(v), nil
+}
+
+// Decode reads an XBM image from r and returns it as a *bitmap.Image.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	m := bitmap.New(image.Rect(0, 0, h.width, h.height))
+	n := 1
+	if h.short {
+		n = 2
+	}
+	// Each value holds n bytes of a row, low byte first. The bits of each
+	// byte are reversed, to be most significant bit first, and inverted, as
+	// an XBM 1 is black.
+	rowValues := (m.Stride + n - 1) / n
+	for y := 0; y < h.height; y++ {
+		row := m.Pix[y*m.Stride : (y+1)*m.Stride]
+		for i := 0; i < rowValues; i++ {
+			v, err := readValue(br)
+			if err != nil {
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			if n == 1 && v > 0xff {
+				return nil, FormatError("bad value")
+			}
+			for j := 0; j < n && n*i+j < len(row); j++ {
+				row[n*i+j] = ^bits.Reverse8(uint8(v >> uint(8*j)))
+			}
+		}
+		if rem := h.width % 8; rem != 0 {
+			row[len(row)-1] &= 0xff << uint(8-rem)
+		}
+	}
+	return m, nil
+}
+
+// DecodeConfig returns the color model and dimensions of an XBM image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: bitmap.Model,
+		Width:      h.width,
+		Height:     h.height,
+	}, nil
+}
+
+func init() {
+	image.RegisterFormat("xbm", "#define", Decode, DecodeConfig)
+}
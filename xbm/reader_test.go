@@ -0,0 +1,108 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xbm
+
+import (
+	"image"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bitmap"
+)
+
+func TestDecode(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		want []string
+	}{{
+		"X11",
+		"#define test_width 10\n#define test_height 2\n" +
+			"static unsigned char test_bits[] = {\n   0x01, 0x02, 0xff, 0xff};\n",
+		[]string{
+			"X........X",
+			"XXXXXXXXXX",
+		},
+	}, {
+		"hot spot and comments",
+		"/* Created by hand. */\n#define cursor_width 3\n#define cursor_height 3\n" +
+			"#define cursor_x_hot 1\n#define cursor_y_hot 1\n" +
+			"static char cursor_bits[] = { 0x02,0x07, 0x02 };",
+		[]string{
+			".X.",
+			"XXX",
+			".X.",
+		},
+	}, {
+		"X10",
+		"#define old_width 20\n#define old_height 1\n" +
+			"static short old_bits[] = {\n   0x8001, 0x0008};\n",
+		[]string{
+			"X..............X...X",
+		},
+	}}
+	for _, tc := range testCases {
+		m, err := Decode(strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if m.ColorModel() != bitmap.Model {
+			t.Errorf("%s: color model: got %v, want bitmap.Model", tc.name, m.ColorModel())
+		}
+		if got, want := m.Bounds(), image.Rect(0, 0, len(tc.want[0]), len(tc.want)); got != want {
+			t.Errorf("%s: bounds: got %v, want %v", tc.name, got, want)
+			continue
+		}
+		for y, row := range tc.want {
+			for x := range row {
+				want := bitmap.White
+				if row[x] == 'X' {
+					want = bitmap.Black
+				}
+				if got := m.At(x, y); got != want {
+					t.Errorf("%s: (%d, %d): got %v, want %v", tc.name, x, y, got, want)
+				}
+			}
+		}
+		c, err := DecodeConfig(strings.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: DecodeConfig: %v", tc.name, err)
+		} else if c.ColorModel != bitmap.Model || c.Width != len(tc.want[0]) || c.Height != len(tc.want) {
+			t.Errorf("%s: DecodeConfig: got %v", tc.name, c)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"truncated header", "#define a_width 1\n", io.ErrUnexpectedEOF},
+		{"missing height", "#define a_width 1\nstatic char a_bits[] = {0x00};", FormatError("missing width or height")},
+		{"bad dimension", "#define a_width -1\n", FormatError("bad dimension")},
+		{"bad declaration", "#define a_width 1\n#define a_height 1\nint a_bits[] = {0x00};", FormatError("bad array declaration")},
+		{"too few values", "#define a_width 9\n#define a_height 1\nstatic char a_bits[] = {0x00};", FormatError("too few values")},
+		{"truncated data", "#define a_width 9\n#define a_height 1\nstatic char a_bits[] = {0x00,", io.ErrUnexpectedEOF},
+		{"bad value", "#define a_width 1\n#define a_height 1\nstatic char a_bits[] = {0x100};", FormatError("bad value")},
+		{"too large", "#define a_width 16777216\n#define a_height 16777216\nstatic char a_bits[] = {", UnsupportedError("image too large")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(strings.NewReader(tc.data)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestDecodeRegistered(t *testing.T) {
+	data := "#define a_width 1\n#define a_height 1\nstatic char a_bits[] = {0x01};"
+	_, format, err := image.Decode(strings.NewReader(data))
+	if err != nil || format != "xbm" {
+		t.Errorf("got %q, %v, want %q", format, err, "xbm")
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xbm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+
+	"golang.org/x/image/bitmap"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Name is the prefix of the names of the #define constants and of the
+	// array, which must be a C identifier. Empty means "image".
+	Name string
+}
+
+// isIdent returns whether s is a C identifier.
+func isIdent(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Encode writes the image m to w in XBM format. A nil opts is equivalent to
+// a zero Options.
+//
+// Images other than *bitmap.Image are converted by bitmap.Model, so that
+// pixels whose gray level is less than half are written as foreground, 1,
+// bits.
+func Encode(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	name := o.Name
+	if name == "" {
+		name = "image"
+	}
+	if !isIdent(name) {
+		return errors.New("xbm: name is not a C identifier")
+	}
+
+	b := m.Bounds()
+	bm, ok := m.(*bitmap.Image)
+	if !ok {
+		bm = bitmap.FromImage(m)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "#define %s_width %d\n#define %s_height %d\n", name, b.Dx(), name, b.Dy())
+	fmt.Fprintf(bw, "static unsigned char %s_bits[] = {", name)
+	// Values are written twelve to a line, as the X11 bitmap program does.
+	n, row := 0, make([]byte, (b.Dx()+7)/8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !bm.BitAt(x, y) {
+				i := x - b.Min.X
+				row[i/8] |= 1 << uint(i%8)
+			}
+		}
+		for _, v := range row {
+			switch {
+			case n == 0:
+				bw.WriteString("\n   ")
+			case n%12 == 0:
+				bw.WriteString(",\n   ")
+			default:
+				bw.WriteString(", ")
+			}
+			fmt.Fprintf(bw, "0x%02x", v)
+			n++
+		}
+	}
+	bw.WriteString("};\n")
+	return bw.Flush()
+}
@@ -0,0 +1,69 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xbm
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/bitmap"
+)
+
+func TestEncode(t *testing.T) {
+	m := bitmap.New(image.Rect(0, 0, 10, 2))
+	for x := 1; x < 10; x++ {
+		m.SetBit(x, 0, true)
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &Options{Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "#define test_width 10\n#define test_height 2\n" +
+		"static unsigned char test_bits[] = {\n   0x01, 0x00, 0xff, 0x03};\n"
+	if got := buf.String(); got != want {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+
+	if err := Encode(&buf, m, &Options{Name: "9lives"}); err == nil {
+		t.Error("bad name: got nil error")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := image.Rect(3, 5, 40, 20)
+	gray := image.NewGray(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			gray.SetGray(x, y, color.Gray{uint8(x*37 + y*11)})
+		}
+	}
+	bm := bitmap.FromImage(gray)
+	for _, m := range []image.Image{gray, bm, bm.SubImage(image.Rect(8, 6, 30, 19))} {
+		var buf bytes.Buffer
+		if err := Encode(&buf, m, nil); err != nil {
+			t.Errorf("%T: Encode: %v", m, err)
+			continue
+		}
+		got, err := Decode(&buf)
+		if err != nil {
+			t.Errorf("%T: Decode: %v", m, err)
+			continue
+		}
+		b := m.Bounds()
+		if got.Bounds() != b.Sub(b.Min) {
+			t.Errorf("%T: bounds: got %v, want %v", m, got.Bounds(), b.Sub(b.Min))
+			continue
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if g, w := got.At(x-b.Min.X, y-b.Min.Y), bitmap.Model.Convert(m.At(x, y)); g != w {
+					t.Fatalf("%T: (%d, %d): got %v, want %v", m, x, y, g, w)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xpm implements a decoder and encoder for X PixMap (XPM) images.
+//
+// An XPM image is a fragment of C source code: an array of strings that
+// give, in turn, the image's dimensions, its colors, each named by one or
+// more characters, and its rows of pixels, as sequences of those names.
+// Colors are given as hexadecimal RGB values, as color names, or as None for
+// transparent pixels. Only the XPM 3 variant is supported.
+//
+// The format is described at https://www.x.org/docs/XPM/xpm.pdf
+package xpm // import "golang.org/x/image/xpm"
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// A FormatError reports that the input is not a valid XPM image.
+type FormatError string
+
+func (e FormatError) Error() string {
+	return "xpm: invalid format: " + string(e)
+}
+
+// An UnsupportedError reports that the input uses a valid but unimplemented
+// feature.
+type UnsupportedError string
+
+func (e UnsupportedError) Error() string {
+	return "xpm: unsupported feature: " + string(e)
+}
+
+const (
+	// maxPixels is the largest number of pixels, width × height, of an image
+	// that Decode accepts.
+	maxPixels = 1 << 28
+	// maxColors is the largest number of colors of an image that Decode
+	// accepts.
+	maxColors = 1 << 20
+)
+
+// header is the decoded header of an XPM image.
+type header struct {
+	width, height int
+	// cpp is the number of characters per pixel.
+	cpp     int
+	palette color.Palette
+	// index maps the characters that name each color to its index in
+	// palette.
+	index map[string]int
+}
+
+// readString reads the next C string literal of the source, skipping any
+// other text, such as declarations, and comments, which may hold quotes.
+// XPM strings hold no escape sequences.
+func readString(r *bufio.Reader) (string, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if c == '/' {
+			if c, err := r.ReadByte(); err != nil {
+				return "", err
+			} else if c != '*' {
+				r.UnreadByte()
+				continue
+			}
+			for prev := byte(0); ; prev = c {
+				if c, err = r.ReadByte(); err != nil {
+					return "", err
+				}
+				if prev == '*' && c == '/' {
+					break
+				}
+			}
+			continue
+		}
+		if c == '"' {
+			s, err := r.ReadString('"')
+			if err != nil {
+				return "", err
+			}
+			return s[:len(s)-1], nil
+		}
+	}
+}
+
+// readHeader reads the values and colors strings of an XPM image.
+func readHeader(r *bufio.Reader) (*header, error) {
+	s, err := readString(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return nil, FormatError("bad values string")
+	}
+	var v [4]int
+	for i := range v {
+		v[i], err = strconv.Atoi(fields[i])
+		if err != nil || v[i] < 0 || v[i] > 1<<24 {
+			return nil, FormatError("bad values string")
+		}
+	}
+	// Any hot spot and extensions, which follow, are ignored.
+	h := &header{width: v[0], height: v[1], cpp: v[3]}
+	ncolors := v[2]
+	if h.cpp < 1 || h.cpp > 8 {
+		return nil, UnsupportedError("characters per pixel")
+	}
+	if int64(h.width)*int64(h.height) > maxPixels || ncolors > maxColors {
+		return nil, UnsupportedError("image too large")
+	}
+
+	h.palette = make(color.Palette, ncolors)
+	h.index = make(map[string]int, ncolors)
+	for i := range h.palette {
+		s, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(s) < h.cpp {
+			return nil, FormatError("bad color string")
+		}
+		c, err := parseColor(s[h.cpp:])
+		if err != nil {
+			return nil, err
+		}
+		h.palette[i] = c
+		h.index[s[:h.cpp]] = i
+	}
+	return h, nil
+}
+
+// parseColor parses the keys and colors that follow a color's characters,
+// such as "c #ff0000 m black". The color for the color visual, key c, is
+// preferred, then the grayscale and monochrome ones, keys g, g4 and m.
+func parseColor(s string) (color.RGBA, error) {
+	colors := map[string]string{}
+	key := ""
+	for _, f := range strings.Fields(s) {
+		switch f {
+		case "c", "g", "g4", "m", "s":
+			key = f
+			colors[key] = ""
+			continue
+		}
+		if key == "" {
+			return color.RGBA{}, FormatError("bad color string")
+		}
+		// Color names, such as "light blue", may hold spaces.
+		colors[key] += f
+	}
+	for _, key := range []string{"c", "g", "g4", "m"} {
+		if v, ok := colors[key]; ok {
+			return lookupColor(v)
+		}
+	}
+	return color.RGBA{}, FormatError("missing color")
+}
+
+// lookupColor returns the color given by a hexadecimal RGB value of 4, 8,
+// 12 or 16 bits per channel, or by a color name, which is matched without
+// regard to case.
+func lookupColor(s string) (color.RGBA, error) {
+	if s == "" {
+		return color.RGBA{}, FormatError("missing color")
+	}
+	if s[0] != '#' {
+		if strings.EqualFold(s, "none") {
+			return color.RGBA{}, nil
+		}
+		c, ok := colornames.Map[strings.ToLower(s)]
+		if !ok {
+			return color.RGBA{}, UnsupportedError("color name " + strconv.Quote(s))
+		}
+		return c, nil
+	}
+	s = s[1:]
+	n := len(s) / 3
+	if len(s)%3 != 0 || n < 1 || n > 4 {
+		return color.RGBA{}, FormatError("bad color value")
+	}
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := strconv.ParseUint(s[n*i:n*(i+1)], 16, 16)
+		if err != nil {
+			return color.RGBA{}, FormatError("bad color value")
+		}
+		// Scale the n hexadecimal digits to 8 bits.
+		switch n {
+		case 1:
+			rgb[i] = uint8(v * 0x11)
+		case 2:
+			rgb[i] = uint8(v)
+		default:
+			rgb[i] = uint8(v >> uint(4*n-8))
+		}
+	}
+	return color.RGBA{rgb[0], rgb[1], rgb[2], 0xff}, nil
+}
+
+// colorModel returns the palette of images of up to 256 colors, which are
+// decoded as *image.Paletted, and color.RGBAModel otherwise.
+func (h *header) colorModel() color.Model {
+	if len(h.palette) > 256 {
+		return color.RGBAModel
+	}
+	return h.palette
+}
+
+// Decode reads an XPM image from r and returns it as an image.Image. Images
+// of up to 256 colors are returned as an *image.Paletted, and others as an
+// *image.RGBA. Transparent pixels, whose color is None, are transparent
+// black.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	rect := image.Rect(0, 0, h.width, h.height)
+	var pm *image.Paletted
+	var rgba *image.RGBA
+	if len(h.palette) > 256 {
+		rgba = image.NewRGBA(rect)
+	} else {
+		pm = image.NewPaletted(rect, h.palette)
+	}
+	for y := 0; y < h.height; y++ {
+		s, err := readString(br)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if len(s) != h.width*h.cpp {
+			return nil, FormatError("bad pixel string length")
+		}
+		for x := 0; x < h.width; x++ {
+			i, ok := h.index[s[x*h.cpp:(x+1)*h.cpp]]
+			if !ok {
+				return nil, FormatError("undefined pixel characters")
+			}
+			if pm != nil {
+				pm.Pix[y*pm.Stride+x] = uint8(i)
+			} else {
+				rgba.SetRGBA(x, y, h.palette[i].(color.RGBA))
+			}
+		}
+	}
+	if pm != nil {
+		return pm, nil
+	}
+	return rgba, nil
+}
+
+// DecodeConfig returns the color model and dimensions of an XPM image
+// without decoding the entire image.
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return image.Config{}, err
+	}
+	return image.Config{
+		ColorModel: h.colorModel(),
+		Width:      h.width,
+		Height:     h.height,
+	}, nil
+}
+
+func init() {
+	image.RegisterFormat("xpm", "/* XPM */", Decode, DecodeConfig)
+}
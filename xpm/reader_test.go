@@ -0,0 +1,113 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xpm
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	data := `/* XPM */
+/* A comment that holds a "quote". */
+static char * test_xpm[] = {
+"4 2 4 2 0 0",
+"   c None",
+".. c #FF0000 m black",
+"Xx g4 gray c Light Blue",
+"o+ s mask m white",
+"  ..Xxo+",
+"o+o+..  "
+};`
+	m, err := Decode(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pm, ok := m.(*image.Paletted)
+	if !ok {
+		t.Fatalf("got %T, want *image.Paletted", m)
+	}
+	var (
+		none  = color.RGBA{}
+		red   = color.RGBA{0xff, 0x00, 0x00, 0xff}
+		blue  = color.RGBA{0xad, 0xd8, 0xe6, 0xff}
+		white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	)
+	want := [][]color.Color{
+		{none, red, blue, white},
+		{white, white, red, none},
+	}
+	if got, want := pm.Bounds(), image.Rect(0, 0, 4, 2); got != want {
+		t.Fatalf("bounds: got %v, want %v", got, want)
+	}
+	for y, row := range want {
+		for x, want := range row {
+			if got := pm.At(x, y); got != want {
+				t.Errorf("(%d, %d): got %v, want %v", x, y, got, want)
+			}
+		}
+	}
+
+	c, err := DecodeConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("DecodeConfig: %v", err)
+	}
+	if p, ok := c.ColorModel.(color.Palette); !ok || len(p) != 4 || c.Width != 4 || c.Height != 2 {
+		t.Errorf("DecodeConfig: got %v", c)
+	}
+}
+
+func TestLookupColor(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want color.RGBA
+	}{
+		{"#f08", color.RGBA{0xff, 0x00, 0x88, 0xff}},
+		{"#ff0080", color.RGBA{0xff, 0x00, 0x80, 0xff}},
+		{"#fff000800", color.RGBA{0xff, 0x00, 0x80, 0xff}},
+		{"#ffff00008000", color.RGBA{0xff, 0x00, 0x80, 0xff}},
+		{"NONE", color.RGBA{}},
+		{"DarkOrange", color.RGBA{0xff, 0x8c, 0x00, 0xff}},
+	} {
+		got, err := lookupColor(tc.s)
+		if err != nil || got != tc.want {
+			t.Errorf("%q: got %v, %v, want %v", tc.s, got, err, tc.want)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"truncated", `"1 1 1 1", ". c red"`, io.ErrUnexpectedEOF},
+		{"bad values", `"1 1 1"`, FormatError("bad values string")},
+		{"characters per pixel", `"1 1 1 9"`, UnsupportedError("characters per pixel")},
+		{"too large", `"65536 65536 1 1"`, UnsupportedError("image too large")},
+		{"missing color", `"1 1 1 1", ". s name", "."`, FormatError("missing color")},
+		{"bad color value", `"1 1 1 1", ". c #12345", "."`, FormatError("bad color value")},
+		{"color name", `"1 1 1 1", ". c gray50", "."`, UnsupportedError(`color name "gray50"`)},
+		{"bad length", `"2 1 1 1", ". c red", "."`, FormatError("bad pixel string length")},
+		{"undefined", `"1 1 1 1", ". c red", "x"`, FormatError("undefined pixel characters")},
+	}
+	for _, tc := range testCases {
+		if _, err := Decode(strings.NewReader(tc.data)); err != tc.err {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+	}
+}
+
+func TestDecodeRegistered(t *testing.T) {
+	data := "/* XPM */\nstatic char *a[] = {\"1 1 1 1\", \". c red\", \".\"};"
+	_, format, err := image.Decode(strings.NewReader(data))
+	if err != nil || format != "xpm" {
+		t.Errorf("got %q, %v, want %q", format, err, "xpm")
+	}
+}
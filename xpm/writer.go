@@ -0,0 +1,134 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xpm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// Options are the encoding parameters.
+type Options struct {
+	// Name is the name of the array, which must be a C identifier. Empty
+	// means "image".
+	Name string
+}
+
+// pixelChars are the characters that name colors, as chosen by libXpm: the
+// printable ASCII characters other than '"' and '\\'.
+const pixelChars = " .XoO+@#$%&*=-;:>,<1234567890qwertyuipasdfghjklzxcvbnm" +
+	"MNBVCZASDFGHJKLPIUYTREWQ!~^/()_`'][{}|"
+
+// isIdent returns whether s is a C identifier.
+func isIdent(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// xpmColor returns the color that c is written as. XPM colors are either
+// opaque or transparent, so colors whose alpha is less than half are
+// transparent, and the others are opaque, with their non-premultiplied RGB.
+func xpmColor(c color.Color) color.RGBA {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A < 0x80 {
+		return color.RGBA{}
+	}
+	return color.RGBA{n.R, n.G, n.B, 0xff}
+}
+
+// Encode writes the image m to w in XPM format. A nil opts is equivalent to
+// a zero Options.
+//
+// The colors of an *image.Paletted are those of its palette. Other images
+// are written with each of their distinct colors. As XPM colors are either
+// opaque or transparent, pixels whose alpha is less than half are written
+// as transparent, None, and the others as opaque.
+func Encode(w io.Writer, m image.Image, opts *Options) error {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	name := o.Name
+	if name == "" {
+		name = "image"
+	}
+	if !isIdent(name) {
+		return errors.New("xpm: name is not a C identifier")
+	}
+
+	// Each pixel is given by its index into palette.
+	b := m.Bounds()
+	var palette []color.RGBA
+	pix := make([]int, 0, b.Dx()*b.Dy())
+	if pm, ok := m.(*image.Paletted); ok {
+		for _, c := range pm.Palette {
+			palette = append(palette, xpmColor(c))
+		}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for _, i := range pm.Pix[pm.PixOffset(b.Min.X, y):][:b.Dx()] {
+				if int(i) >= len(palette) {
+					return errors.New("xpm: pixel index out of palette range")
+				}
+				pix = append(pix, int(i))
+			}
+		}
+	} else {
+		index := map[color.RGBA]int{}
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				c := xpmColor(m.At(x, y))
+				i, ok := index[c]
+				if !ok {
+					i = len(palette)
+					index[c] = i
+					palette = append(palette, c)
+				}
+				pix = append(pix, i)
+			}
+		}
+	}
+
+	// Each color is named by the fewest characters that name them all.
+	cpp := 1
+	for n := len(pixelChars); n < len(palette); n *= len(pixelChars) {
+		cpp++
+	}
+	names := make([]string, len(palette))
+	for i := range names {
+		s := make([]byte, cpp)
+		for j, v := cpp-1, i; j >= 0; j, v = j-1, v/len(pixelChars) {
+			s[j] = pixelChars[v%len(pixelChars)]
+		}
+		names[i] = string(s)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "/* XPM */\nstatic char *%s[] = {\n\"%d %d %d %d\"", name, b.Dx(), b.Dy(), len(palette), cpp)
+	for i, c := range palette {
+		if c.A == 0 {
+			fmt.Fprintf(bw, ",\n\"%s c None\"", names[i])
+		} else {
+			fmt.Fprintf(bw, ",\n\"%s c #%02x%02x%02x\"", names[i], c.R, c.G, c.B)
+		}
+	}
+	for y := 0; y < b.Dy(); y++ {
+		bw.WriteString(",\n\"")
+		for _, i := range pix[y*b.Dx() : (y+1)*b.Dx()] {
+			bw.WriteString(names[i])
+		}
+		bw.WriteByte('"')
+	}
+	bw.WriteString("\n};\n")
+	return bw.Flush()
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xpm
+
+import (
+	"bufio"
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	p := color.Palette{color.Transparent, color.RGBA{0x12, 0x34, 0x56, 0xff}}
+	m := image.NewPaletted(image.Rect(0, 0, 3, 2), p)
+	m.Pix = []uint8{0, 1, 0, 1, 1, 1}
+	var buf bytes.Buffer
+	if err := Encode(&buf, m, &Options{Name: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "/* XPM */\nstatic char *test[] = {\n" +
+		"\"3 2 2 1\",\n\"  c None\",\n\". c #123456\",\n\" . \",\n\"...\"\n};\n"
+	if got := buf.String(); got != want {
+		t.Errorf("\ngot  %q\nwant %q", got, want)
+	}
+
+	if err := Encode(&buf, m, &Options{Name: "a-b"}); err == nil {
+		t.Error("bad name: got nil error")
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	r := image.Rect(2, 3, 40, 20)
+	few := image.NewRGBA(r)
+	many := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			few.SetRGBA(x, y, color.RGBA{uint8(x % 3 * 0x70), 0x00, uint8(y % 5 * 0x30), 0xff})
+			many.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), uint8(x * y), 0xff})
+		}
+	}
+	few.SetRGBA(5, 5, color.RGBA{})
+	pm := image.NewPaletted(r, color.Palette{color.Black, color.White, color.Transparent})
+	for i := range pm.Pix {
+		pm.Pix[i] = uint8(i % 3)
+	}
+
+	testCases := []struct {
+		name  string
+		m     image.Image
+		model func(color.Model) bool
+		cpp   int
+	}{
+		{"few", few, isPalette, 1},
+		{"many", many, func(m color.Model) bool { return m == color.RGBAModel }, 2},
+		{"paletted", pm, isPalette, 1},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		if err := Encode(&buf, tc.m, nil); err != nil {
+			t.Errorf("%s: Encode: %v", tc.name, err)
+			continue
+		}
+		h, err := readHeader(bufio.NewReader(bytes.NewReader(buf.Bytes())))
+		if err != nil {
+			t.Errorf("%s: readHeader: %v", tc.name, err)
+			continue
+		}
+		if h.cpp != tc.cpp {
+			t.Errorf("%s: got %d characters per pixel, want %d", tc.name, h.cpp, tc.cpp)
+		}
+		m, err := Decode(&buf)
+		if err != nil {
+			t.Errorf("%s: Decode: %v", tc.name, err)
+			continue
+		}
+		if !tc.model(m.ColorModel()) {
+			t.Errorf("%s: got color model %T", tc.name, m.ColorModel())
+		}
+		for y := 0; y < r.Dy(); y++ {
+			for x := 0; x < r.Dx(); x++ {
+				got := color.RGBAModel.Convert(m.At(x, y))
+				want := color.RGBAModel.Convert(tc.m.At(r.Min.X+x, r.Min.Y+y))
+				if got != want {
+					t.Fatalf("%s: (%d, %d): got %v, want %v", tc.name, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func isPalette(m color.Model) bool {
+	_, ok := m.(color.Palette)
+	return ok
+}